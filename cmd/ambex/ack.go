@@ -0,0 +1,204 @@
+package ambex
+
+import (
+	"sync"
+	"time"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+// ACKStatus says whether Envoy has accepted ambex's configuration.
+type ACKStatus struct {
+	// Acked is whether Envoy has ACKed clusters and listeners from the configuration that ambex
+	// loaded, rather than from the empty cache that ambex starts with. Until it has, Envoy has
+	// no routes. Once it has, Acked stays true, even while Envoy catches up with later updates.
+	Acked bool `json:"acked"`
+	// Versions has the last version of each type that Envoy ACKed.
+	Versions map[string]string `json:"versions"`
+	// Errors has the error of each type that Envoy NACKed, if it hasn't ACKed a version since.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ackTypes are the types that Envoy has to ACK for Acked. Envoy asks for every cluster and
+// listener, so it gets a response whenever they change; it only asks for the endpoints and
+// routes that they refer to.
+var ackTypes = []string{envoyxds.ClusterType, envoyxds.ListenerType}
+
+type ackKey struct {
+	sid     int64
+	typeURL string
+}
+
+type sentResponse struct {
+	nonce   string
+	version string
+}
+
+// ackTracker follows the responses that ambex sends on each xDS stream, and Envoy's ACKs and
+// NACKs of them: a request whose nonce is that of the last response of its type on its stream
+// is an ACK, unless it has an error, which makes it a NACK.
+type ackTracker struct {
+	mu sync.Mutex
+	// initial is the version of the empty cache.
+	initial string
+	sent    map[ackKey]sentResponse
+	acked   map[string]string
+	nacked  map[string]string
+	ready   bool
+
+	// answeredVersions has the last version of each type that Envoy ACKed or NACKed.
+	answeredVersions map[string]string
+	// pending has the versions from the last push that Envoy hasn't ACKed or NACKed yet, and
+	// pushedAt is when that push was.
+	pending  map[string]string
+	pushedAt time.Time
+	// answered is how long Envoy took to answer everything from the last push, and whether it
+	// NACKed any of it, once it has; settled returns them once.
+	answered    time.Duration
+	anyNACK     bool
+	hasAnswered bool
+}
+
+func newACKTracker(initial string) *ackTracker {
+	return &ackTracker{
+		initial: initial,
+		sent:    map[ackKey]sentResponse{},
+		acked:   map[string]string{},
+		nacked:  map[string]string{},
+
+		answeredVersions: map[string]string{},
+	}
+}
+
+func (a *ackTracker) response(sid int64, res *v2.DiscoveryResponse) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sent[ackKey{sid, res.GetTypeUrl()}] = sentResponse{nonce: res.GetNonce(), version: res.GetVersionInfo()}
+}
+
+// request records an ACK or NACK, if req is one. It returns the version and error of a NACK, so
+// that the caller can log it, and "", "" otherwise.
+func (a *ackTracker) request(sid int64, req *v2.DiscoveryRequest) (nackedVersion, nackError string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	typeURL := req.GetTypeUrl()
+	sent, ok := a.sent[ackKey{sid, typeURL}]
+	if !ok || req.GetResponseNonce() != sent.nonce {
+		return "", ""
+	}
+	a.answeredVersions[typeURL] = sent.version
+	if version, ok := a.pending[typeURL]; ok && version == sent.version {
+		delete(a.pending, typeURL)
+		if req.GetErrorDetail() != nil {
+			a.anyNACK = true
+		}
+		if len(a.pending) == 0 {
+			a.answered = time.Since(a.pushedAt)
+			a.hasAnswered = true
+		}
+	}
+	if detail := req.GetErrorDetail(); detail != nil {
+		a.nacked[typeURL] = detail.GetMessage()
+		return sent.version, detail.GetMessage()
+	}
+
+	a.acked[typeURL] = sent.version
+	delete(a.nacked, typeURL)
+	if !a.ready {
+		a.ready = true
+		for _, t := range ackTypes {
+			if version := a.acked[t]; version == "" || version == a.initial {
+				a.ready = false
+			}
+		}
+	}
+	return "", ""
+}
+
+// pushed starts waiting for Envoy to answer a push, which gave each type in versions a new
+// version. Only the types that ambex has sent Envoy before count, since Envoy doesn't watch the
+// others; a push that changed none of them is answered already. So are the versions that Envoy
+// answered before pushed was called.
+func (a *ackTracker) pushed(versions map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = map[string]string{}
+	a.anyNACK = false
+	for key := range a.sent {
+		version, ok := versions[key.typeURL]
+		if !ok {
+			continue
+		}
+		if a.answeredVersions[key.typeURL] != version {
+			a.pending[key.typeURL] = version
+		} else if a.acked[key.typeURL] != version {
+			a.anyNACK = true
+		}
+	}
+	a.pushedAt = time.Now()
+	a.answered = 0
+	a.hasAnswered = len(a.pending) == 0
+}
+
+// settled returns how long Envoy took to ACK or NACK everything from the last push, and whether
+// it NACKed any of it, the first time it's called after Envoy has.
+func (a *ackTracker) settled() (d time.Duration, nacked, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.hasAnswered {
+		return 0, false, false
+	}
+	a.hasAnswered = false
+	return a.answered, a.anyNACK, true
+}
+
+func (a *ackTracker) closed(sid int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key := range a.sent {
+		if key.sid == sid {
+			delete(a.sent, key)
+		}
+	}
+}
+
+func (a *ackTracker) status() ACKStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ret := ACKStatus{Acked: a.ready, Versions: map[string]string{}}
+	for typeURL, version := range a.acked {
+		ret.Versions[typeURL] = version
+	}
+	if len(a.nacked) > 0 {
+		ret.Errors = map[string]string{}
+		for typeURL, err := range a.nacked {
+			ret.Errors[typeURL] = err
+		}
+	}
+	return ret
+}
+
+var (
+	acksMu sync.Mutex
+	acks   = newACKTracker("")
+)
+
+func setACKTracker(a *ackTracker) {
+	acksMu.Lock()
+	defer acksMu.Unlock()
+	acks = a
+}
+
+func currentACKTracker() *ackTracker {
+	acksMu.Lock()
+	defer acksMu.Unlock()
+	return acks
+}
+
+// GetACKStatus returns whether Envoy has accepted ambex's configuration. Before MainContext has
+// started serving, it hasn't.
+func GetACKStatus() ACKStatus {
+	return currentACKTracker().status()
+}
@@ -0,0 +1,220 @@
+package ambex
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+	"github.com/datawire/ambassador/pkg/envoyxds/xdstest"
+)
+
+func TestACKTracker(t *testing.T) {
+	a := newACKTracker("x-0")
+	respond := func(sid int64, typeURL, nonce, version string) {
+		a.response(sid, &v2.DiscoveryResponse{TypeUrl: typeURL, Nonce: nonce, VersionInfo: version})
+	}
+	ack := func(sid int64, typeURL, nonce, version string) (string, string) {
+		return a.request(sid, &v2.DiscoveryRequest{TypeUrl: typeURL, ResponseNonce: nonce, VersionInfo: version})
+	}
+
+	// Envoy connects before ambex has loaded anything, and ACKs the empty cache.
+	respond(1, envoyxds.ClusterType, "1", "x-0")
+	respond(1, envoyxds.ListenerType, "2", "x-0")
+	ack(1, envoyxds.ClusterType, "1", "x-0")
+	ack(1, envoyxds.ListenerType, "2", "x-0")
+	assert.False(t, a.status().Acked)
+
+	// Then it ACKs real clusters, but rejects the listeners.
+	respond(1, envoyxds.ClusterType, "3", "x-1")
+	respond(1, envoyxds.ListenerType, "4", "x-1")
+	ack(1, envoyxds.ClusterType, "3", "x-1")
+	version, nack := a.request(1, &v2.DiscoveryRequest{
+		TypeUrl:       envoyxds.ListenerType,
+		ResponseNonce: "4",
+		VersionInfo:   "x-0",
+		ErrorDetail:   &status.Status{Message: "duplicate listener"},
+	})
+	assert.Equal(t, "x-1", version)
+	assert.Equal(t, "duplicate listener", nack)
+	assert.Equal(t, ACKStatus{
+		Acked:    false,
+		Versions: map[string]string{envoyxds.ClusterType: "x-1", envoyxds.ListenerType: "x-0"},
+		Errors:   map[string]string{envoyxds.ListenerType: "duplicate listener"},
+	}, a.status())
+
+	// A request with a stale nonce, or on another stream, is neither.
+	ack(1, envoyxds.ListenerType, "2", "x-2")
+	ack(2, envoyxds.ListenerType, "4", "x-2")
+	assert.False(t, a.status().Acked)
+
+	respond(1, envoyxds.ListenerType, "5", "x-2")
+	version, nack = ack(1, envoyxds.ListenerType, "5", "x-2")
+	assert.Equal(t, "", version+nack)
+	assert.Equal(t, ACKStatus{
+		Acked:    true,
+		Versions: map[string]string{envoyxds.ClusterType: "x-1", envoyxds.ListenerType: "x-2"},
+	}, a.status())
+
+	// Once Envoy has ACKed, it stays that way, even if it reconnects.
+	a.closed(1)
+	respond(3, envoyxds.ListenerType, "1", "x-3")
+	ack(3, envoyxds.ListenerType, "1", "")
+	assert.True(t, a.status().Acked)
+	assert.Len(t, a.sent, 1)
+}
+
+func TestACKTrackerSettled(t *testing.T) {
+	a := newACKTracker("x-0")
+	respond := func(typeURL, nonce, version string) {
+		a.response(1, &v2.DiscoveryResponse{TypeUrl: typeURL, Nonce: nonce, VersionInfo: version})
+	}
+	ack := func(typeURL, nonce, version string) {
+		a.request(1, &v2.DiscoveryRequest{TypeUrl: typeURL, ResponseNonce: nonce, VersionInfo: version})
+	}
+	respond(envoyxds.ClusterType, "1", "x-0")
+	respond(envoyxds.ListenerType, "2", "x-0")
+	ack(envoyxds.ClusterType, "1", "x-0")
+	ack(envoyxds.ListenerType, "2", "x-0")
+
+	// A push that changes types Envoy doesn't watch is answered at once.
+	a.pushed(map[string]string{envoyxds.RuntimeType: "x-1"})
+	_, nacked, ok := a.settled()
+	assert.True(t, ok)
+	assert.False(t, nacked)
+	_, _, ok = a.settled()
+	assert.False(t, ok)
+
+	// Otherwise, it's answered once Envoy has answered every type it changed.
+	a.pushed(map[string]string{envoyxds.ClusterType: "x-1", envoyxds.ListenerType: "x-1"})
+	respond(envoyxds.ClusterType, "3", "x-1")
+	ack(envoyxds.ClusterType, "3", "x-1")
+	_, _, ok = a.settled()
+	assert.False(t, ok)
+	respond(envoyxds.ListenerType, "4", "x-1")
+	a.request(1, &v2.DiscoveryRequest{
+		TypeUrl:       envoyxds.ListenerType,
+		ResponseNonce: "4",
+		VersionInfo:   "x-0",
+		ErrorDetail:   &status.Status{Message: "duplicate listener"},
+	})
+	_, nacked, ok = a.settled()
+	assert.True(t, ok)
+	assert.True(t, nacked)
+
+	// Envoy can answer before the push is recorded.
+	respond(envoyxds.ClusterType, "5", "x-2")
+	ack(envoyxds.ClusterType, "5", "x-2")
+	a.pushed(map[string]string{envoyxds.ClusterType: "x-2"})
+	_, nacked, ok = a.settled()
+	assert.True(t, ok)
+	assert.False(t, nacked)
+}
+
+// serveACKs serves ambex's Cache at a new address, as MainContext does, with a fresh
+// ackTracker, and returns the Cache and the address.
+func serveACKs(ctx context.Context, t *testing.T) (envoyxds.Cache, string) {
+	t.Helper()
+	prefix := versionPrefix()
+	config := envoyxds.NewCache(prefix)
+	setACKTracker(newACKTracker(prefix + "0"))
+	srv := envoyxds.NewServer(ctx, config, logger{ctx})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcServer := grpc.NewServer()
+	envoyxds.Register(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	go func() {
+		<-ctx.Done()
+		grpcServer.Stop()
+	}()
+	return config, lis.Addr().String()
+}
+
+// loadConfig writes a cluster and a listener for update to load, and returns their directory.
+func loadConfig(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ambex-acks")
+	require.NoError(t, err)
+	writeResource(t, dir, "cluster.json", cluster("cluster_quote"))
+	writeResource(t, dir, "listener.json", &v2.Listener{
+		Name: "ambassador-listener-8080",
+		Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+			Address:       "0.0.0.0",
+			PortSpecifier: &core.SocketAddress_PortValue{PortValue: 8080},
+		}}},
+	})
+	return dir
+}
+
+func waitFor(t *testing.T, c *xdstest.Client, cond func(*xdstest.Client) bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, c.Wait(ctx, cond))
+}
+
+func TestACKsFromEnvoy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := loadConfig(t)
+	defer os.RemoveAll(dir)
+
+	config, addr := serveACKs(ctx, t)
+	envoy, err := xdstest.Dial(ctx, addr, xdstest.Options{})
+	require.NoError(t, err)
+	defer envoy.Close()
+
+	// Envoy connects before ambex has loaded anything, and ACKs the empty cache.
+	waitFor(t, envoy, func(c *xdstest.Client) bool {
+		return c.Responses(envoyxds.ClusterType) > 0 && c.Responses(envoyxds.ListenerType) > 0
+	})
+	assert.False(t, GetACKStatus().Acked)
+
+	update(ctx, config, newTapDiscoveryServer(ctx), &updateState{}, []string{dir})
+	waitFor(t, envoy, func(c *xdstest.Client) bool {
+		return len(c.Clusters()) == 1 && len(c.Listeners()) == 1
+	})
+	assert.Contains(t, envoy.Clusters(), "cluster_quote")
+	assert.Contains(t, envoy.Listeners(), "ambassador-listener-8080")
+	require.Eventually(t, func() bool { return GetACKStatus().Acked }, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, config.Version(envoyxds.ClusterType), GetACKStatus().Versions[envoyxds.ClusterType])
+}
+
+func TestNACKsFromEnvoy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := loadConfig(t)
+	defer os.RemoveAll(dir)
+
+	config, addr := serveACKs(ctx, t)
+	envoy, err := xdstest.Dial(ctx, addr, xdstest.Options{
+		Validate: func(typeURL string, resources []envoyxds.Resource) error {
+			if typeURL == envoyxds.ListenerType && len(resources) > 0 {
+				return errors.New("duplicate listener")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer envoy.Close()
+
+	update(ctx, config, newTapDiscoveryServer(ctx), &updateState{}, []string{dir})
+	waitFor(t, envoy, func(c *xdstest.Client) bool { return c.NACK(envoyxds.ListenerType) != "" })
+	require.Eventually(t, func() bool {
+		return GetACKStatus().Errors[envoyxds.ListenerType] == "duplicate listener"
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.False(t, GetACKStatus().Acked)
+}
@@ -5,41 +5,43 @@ package ambex
  *
  * Here's the deal.
  *
- * go-control-plane, several different classes manage this stuff:
+ * go-control-plane does the heavy lifting, but we only use it through
+ * pkg/envoyxds, so that a newer go-control-plane only means changing that.
  *
- * - The root of the world is a SnapshotCache.
- *   - import github.com/datawire/ambassador/pkg/envoy-control-plane/cache/v2, then refer
- *     to cache.SnapshotCache.
+ * - The root of the world is a Cache (envoyxds.Cache).
+ *   - import github.com/datawire/ambassador/pkg/envoyxds, then refer to
+ *     envoyxds.Cache.
  *   - A collection of internally consistent configuration objects is a
- *     Snapshot (cache.Snapshot).
- *   - Snapshots are collected in the SnapshotCache.
- *   - A given SnapshotCache can hold configurations for multiple Envoys,
- *     identified by the Envoy 'node ID', which must be configured for the
- *     Envoy.
- * - The SnapshotCache can only hold go-control-plane configuration objects,
- *   so you have to build these up to hand to the SnapshotCache.
- * - The gRPC stuff is handled by a Server.
- *   - import github.com/datawire/ambassador/pkg/envoy-control-plane/server, then refer
- *     to server.Server.
+ *     Snapshot (envoyxds.Snapshot); setting one in the Cache changes
+ *     whatever in it is different.
+ *   - Every Envoy gets the same configuration, so nothing is kept per
+ *     Envoy 'node ID', and ambex can serve thousands of Envoys.
+ * - The Cache can only hold go-control-plane configuration objects,
+ *   so you have to build these up to hand to the Cache.
+ * - The gRPC stuff is handled by a Server (envoyxds.Server).
  *   - Our runManagementServer (largely ripped off from the go-control-plane
- *     tests) gets this running. It takes a SnapshotCache (cleverly called a
- *     "config" for no reason I understand) and a gRPCServer as arguments.
+ *     tests) gets this running. It takes the Server and a gRPCServer as
+ *     arguments.
  *   - _ALL_ the gRPC madness is handled by the Server, with the assistance
  *     of the methods in a callback object.
  * - Once the Server is running, Envoy can open a gRPC stream to it.
- *   - On connection, Envoy will get handed the most recent Snapshot that
- *     the Server's SnapshotCache knows about.
- *   - Whenever a newer Snapshot is added to the SnapshotCache, that Snapshot
- *     will get sent to the Envoy.
- * - We manage the SnapshotCache by loading envoy configuration from
+ *   - On connection, Envoy will get handed everything that the Cache has.
+ *   - Whenever a resource changes, it will get sent to every Envoy that
+ *     watches it.
+ * - We manage the Cache by loading envoy configuration from
  *   json and/or protobuf files on disk.
  *   - By default when we get a SIGHUP, we reload configuration.
  *   - When passed the -watch argument we reload whenever any file in
  *     the directory changes.
+ * - TapResources can't go in the Cache, so we serve TapDS ourselves; see
+ *   tapds.go.
+ * - TapDS and SDS can also be served on their own, by busyambassador
+ *   serve-tapds and serve-sds; see serve.go.
  */
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -47,9 +49,9 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"reflect"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
@@ -61,10 +63,9 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 
-	// envoy control plane
-	ctypes "github.com/datawire/ambassador/pkg/envoy-control-plane/cache/types"
-	"github.com/datawire/ambassador/pkg/envoy-control-plane/cache/v2"
-	"github.com/datawire/ambassador/pkg/envoy-control-plane/server/v2"
+	"github.com/datawire/ambassador/pkg/dlog"
+	"github.com/datawire/ambassador/pkg/envoyvalidate"
+	"github.com/datawire/ambassador/pkg/envoyxds"
 
 	// envoy protobuf -- Be sure to import the package of any types that the Python
 	// emits a "@type" of in the generated config, even if that package is otherwise
@@ -78,7 +79,17 @@ import (
 	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/http/rate_limit/v2"
 	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/http/rbac/v2"
 	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/local_rate_limit/v2alpha"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/access_loggers/grpc/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/http/ext_authz/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/http/lua/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/http/ratelimit/v3"
 	discovery "github.com/datawire/ambassador/pkg/api/envoy/service/discovery/v2"
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+
+	// The Wasm filter's config is a TypedStruct, since its type isn't in the Envoy API that
+	// we have.
+	_ "github.com/cncf/udpa/go/udpa/type/v1"
 )
 
 const (
@@ -123,39 +134,32 @@ func (h Hasher) ID(node *core.Node) string {
 
 // end Hasher stuff
 
-// This feels kinda dumb.
+// logger is the envoyxds.Callbacks of the Server, which log to ctx.
 type logger struct {
-	*logrus.Logger
-}
-
-var log = &logger{
-	Logger: logrus.StandardLogger(),
+	ctx context.Context
 }
 
 // run stuff
 // RunManagementServer starts an xDS server at the given port.
-func runManagementServer(ctx context.Context, server server.Server, adsNetwork, adsAddress string) {
+func runManagementServer(ctx context.Context, server envoyxds.Server, tapds *tapDiscoveryServer, adsNetwork, adsAddress string) {
 	grpcServer := grpc.NewServer()
 
 	lis, err := net.Listen(adsNetwork, adsAddress)
 	if err != nil {
-		log.WithError(err).Panic("failed to listen")
+		panic(fmt.Errorf("failed to listen: %w", err))
 	}
 
 	// register services
-	discovery.RegisterAggregatedDiscoveryServiceServer(grpcServer, server)
-	v2.RegisterEndpointDiscoveryServiceServer(grpcServer, server)
-	v2.RegisterClusterDiscoveryServiceServer(grpcServer, server)
-	v2.RegisterRouteDiscoveryServiceServer(grpcServer, server)
-	v2.RegisterListenerDiscoveryServiceServer(grpcServer, server)
+	envoyxds.Register(grpcServer, server)
+	tapsvc.RegisterTapDiscoveryServiceServer(grpcServer, tapds)
 
-	log.WithFields(logrus.Fields{"addr": adsNetwork + ":" + adsAddress}).Info("Listening")
+	dlog.Info(dlog.WithField(ctx, "addr", adsNetwork+":"+adsAddress), "Listening")
 	go func() {
 		go func() {
 			err := grpcServer.Serve(lis)
 
 			if err != nil {
-				log.WithFields(logrus.Fields{"error": err}).Error("Management server exited")
+				dlog.Errorf(ctx, "Management server exited: %v", err)
 			}
 		}()
 
@@ -180,11 +184,23 @@ func isDecodable(name string) bool {
 	return ok
 }
 
-// Not sure if there is a better way to do this, but we cast to this
-// so we can call the generated Validate method.
-type Validatable interface {
-	proto.Message
-	Validate() error
+// decodableFiles lists the files in dirs that decode can decode.
+func decodableFiles(ctx context.Context, dirs []string) []string {
+	var filenames []string
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			dlog.Warnf(ctx, "Error listing %v: %v", dir, err)
+			continue
+		}
+		for _, file := range files {
+			name := file.Name()
+			if isDecodable(name) {
+				filenames = append(filenames, filepath.Join(dir, name))
+			}
+		}
+	}
+	return filenames
 }
 
 func decode(name string) (proto.Message, error) {
@@ -207,68 +223,102 @@ func decode(name string) (proto.Message, error) {
 		return nil, err
 	}
 
-	var v = m.Message.(Validatable)
-
-	err = v.Validate()
+	// Check the constraints from the .proto files, including those on what's in each Any,
+	// before anything that breaks them can get anywhere near Envoy.
+	err = envoyvalidate.Validate(proto.MessageV2(m.Message))
 	if err != nil {
 		return nil, err
 	}
-	log.Infof("Loaded file %s", name)
-	return v, nil
+	return m.Message, nil
 }
 
+// Merge merges from into to, as proto.Merge does.
 func Merge(to, from proto.Message) {
-	str, err := (&jsonpb.Marshaler{}).MarshalToString(from)
-	if err != nil {
-		panic(err)
+	proto.Merge(to, from)
+}
+
+// Clone returns a deep copy of a message, as proto.Clone does. Both work on the message
+// directly, where marshaling it to JSON and back used to take most of the CPU that an update
+// of a big configuration took.
+func Clone(src proto.Message) proto.Message {
+	return proto.Clone(src)
+}
+
+// sameResources returns whether two lists of resources are equal, in the same order.
+func sameResources(a, b []envoyxds.Resource) bool {
+	if len(a) != len(b) {
+		return false
 	}
-	err = jsonpb.UnmarshalString(str, to)
-	if err != nil {
-		panic(err)
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
 	}
+	return true
 }
 
-func Clone(src proto.Message) proto.Message {
-	in := reflect.ValueOf(src)
-	if in.IsNil() {
-		return src
+// updateState is what update keeps from one call to the next.
+type updateState struct {
+	generation int
+	// pushed has the resources of the last snapshot that was pushed, by type, so that an update
+	// that changes nothing doesn't push a new version that Envoy would have to fetch and apply.
+	pushed [][]envoyxds.Resource
+}
+
+// unchanged returns whether resources are the same as those of the last snapshot.
+func (s *updateState) unchanged(resources [][]envoyxds.Resource) bool {
+	if s.pushed == nil || len(s.pushed) != len(resources) {
+		return false
 	}
-	out := reflect.New(in.Type().Elem())
-	dst := out.Interface().(proto.Message)
-	Merge(dst, src)
-	return dst
+	for i := range resources {
+		if !sameResources(s.pushed[i], resources[i]) {
+			return false
+		}
+	}
+	return true
 }
 
-func update(config cache.SnapshotCache, generation *int, dirs []string) {
-	clusters := []ctypes.Resource{}  // v2.Cluster
-	endpoints := []ctypes.Resource{} // v2.ClusterLoadAssignment
-	routes := []ctypes.Resource{}    // v2.RouteConfiguration
-	listeners := []ctypes.Resource{} // v2.Listener
-	runtimes := []ctypes.Resource{}  // discovery.Runtime
+// OnPush, if set, is called after every update that leaves Envoy up to date, whether by pushing
+// a snapshot or by finding that nothing changed, with how long it took to load the
+// configuration and update the caches, and whether it pushed.
+var OnPush func(d time.Duration, pushed bool)
 
-	var filenames []string
+// OnACK, if set, is called once Envoy has ACKed or NACKed everything that a push sent it, with
+// how long after the push that was, and whether it NACKed any of it.
+var OnACK func(d time.Duration, nacked bool)
 
-	for _, dir := range dirs {
-		files, err := ioutil.ReadDir(dir)
-		if err != nil {
-			log.WithError(err).Warnf("Error listing %v", dir)
-			continue
-		}
-		for _, file := range files {
-			name := file.Name()
-			if isDecodable(name) {
-				filenames = append(filenames, filepath.Join(dir, name))
-			}
-		}
+func notifyACK(a *ackTracker) {
+	if d, nacked, ok := a.settled(); ok && OnACK != nil {
+		OnACK(d, nacked)
 	}
+}
 
-	for _, name := range filenames {
+func update(ctx context.Context, config envoyxds.Cache, tapds *tapDiscoveryServer, state *updateState, dirs []string) {
+	start := time.Now()
+
+	clusters := []envoyxds.Resource{}  // v2.Cluster
+	endpoints := []envoyxds.Resource{} // v2.ClusterLoadAssignment
+	routes := []envoyxds.Resource{}    // v2.RouteConfiguration
+	listeners := []envoyxds.Resource{} // v2.Listener
+	runtimes := []envoyxds.Resource{}  // discovery.Runtime
+	taps := []*tapsvc.TapResource{}    // served by TapDS, not the Cache
+
+	for _, name := range decodableFiles(ctx, dirs) {
 		m, e := decode(name)
+		var violations envoyvalidate.Violations
+		if errors.As(e, &violations) {
+			for _, v := range violations {
+				vctx := dlog.WithField(dlog.WithField(ctx, "path", v.Path), "type", v.Type)
+				dlog.Warnf(vctx, "%s: invalid: %s", name, v.Reason)
+			}
+			continue
+		}
 		if e != nil {
-			log.Warnf("%s: %v", name, e)
+			dlog.Warnf(ctx, "%s: %v", name, e)
 			continue
 		}
-		var dst *[]ctypes.Resource
+		dlog.Debugf(ctx, "Loaded file %s", name)
+		var dst *[]envoyxds.Resource
 		switch m.(type) {
 		case *v2.Cluster:
 			dst = &clusters
@@ -284,48 +334,86 @@ func update(config cache.SnapshotCache, generation *int, dirs []string) {
 			bs := m.(*bootstrap.Bootstrap)
 			sr := bs.StaticResources
 			for _, lst := range sr.Listeners {
-				listeners = append(listeners, Clone(lst).(ctypes.Resource))
+				listeners = append(listeners, Clone(lst).(envoyxds.Resource))
 			}
 			for _, cls := range sr.Clusters {
-				clusters = append(clusters, Clone(cls).(ctypes.Resource))
+				clusters = append(clusters, Clone(cls).(envoyxds.Resource))
 			}
 			continue
+		case *v2.DiscoveryResponse:
+			// diagd's TapResources, for TapDS.
+			resources, err := tapResources(m.(*v2.DiscoveryResponse))
+			if err != nil {
+				dlog.Warnf(ctx, "%s: %v", name, err)
+				continue
+			}
+			taps = append(taps, resources...)
+			continue
 		default:
-			log.Warnf("Unrecognized resource %s: %v", name, e)
+			dlog.Warnf(ctx, "Unrecognized resource %s: %v", name, e)
 			continue
 		}
-		*dst = append(*dst, m.(ctypes.Resource))
+		*dst = append(*dst, m.(envoyxds.Resource))
 	}
 
-	version := fmt.Sprintf("v%d", *generation)
-	*generation++
-	snapshot := cache.NewSnapshot(
-		version,
+	tapList := make([]envoyxds.Resource, 0, len(taps))
+	for _, tap := range taps {
+		tapList = append(tapList, tap)
+	}
+	resources := [][]envoyxds.Resource{endpoints, clusters, routes, listeners, runtimes, tapList}
+	if state.unchanged(resources) {
+		dlog.Infof(ctx, "Configuration unchanged, not pushing a new snapshot")
+		if OnPush != nil {
+			OnPush(time.Since(start), false)
+		}
+		return
+	}
+
+	version := fmt.Sprintf("v%d", state.generation)
+	state.generation++
+	ctx = dlog.WithField(ctx, "snapshot_version", version)
+	snapshot, err := envoyxds.NewSnapshot(
 		endpoints,
 		clusters,
 		routes,
 		listeners,
 		runtimes)
 
-	err := snapshot.Consistent()
-
+	before := map[string]string{}
+	for _, typeURL := range envoyxds.Types {
+		before[typeURL] = config.Version(typeURL)
+	}
 	if err != nil {
-		log.Errorf("Snapshot inconsistency: %+v", snapshot)
+		dlog.Errorf(ctx, "Snapshot inconsistency: %v", err)
 	} else {
-		err = config.SetSnapshot("test-id", snapshot)
+		err = config.Set(snapshot)
 	}
 
 	if err != nil {
-		log.Panicf("Snapshot error %q for %+v", err, snapshot)
+		panic(fmt.Errorf("Snapshot error %q for %+v", err, snapshot))
 	} else {
-		// log.Infof("Snapshot %+v", snapshot)
-		log.Infof("Pushing snapshot %+v", version)
+		dlog.Infof(ctx, "Pushing snapshot %+v", version)
+		tapds.set(version, taps)
+		state.pushed = resources
+
+		changed := map[string]string{}
+		for _, typeURL := range envoyxds.Types {
+			if v := config.Version(typeURL); v != before[typeURL] {
+				changed[typeURL] = v
+			}
+		}
+		if OnPush != nil {
+			OnPush(time.Since(start), true)
+		}
+		acks := currentACKTracker()
+		acks.pushed(changed)
+		notifyACK(acks)
 	}
 }
 
-func warn(err error) bool {
+func warn(ctx context.Context, err error) bool {
 	if err != nil {
-		log.Warn(err)
+		dlog.Warn(ctx, err)
 		return true
 	} else {
 		return false
@@ -334,39 +422,56 @@ func warn(err error) bool {
 
 // OnStreamOpen is called once an xDS stream is open with a stream ID and the type URL (or "" for ADS).
 func (l logger) OnStreamOpen(_ context.Context, sid int64, stype string) error {
-	l.Infof("Stream open[%v]: %v", sid, stype)
+	dlog.Debugf(l.ctx, "Stream open[%v]: %v", sid, stype)
 	return nil
 }
 
 // OnStreamClosed is called immediately prior to closing an xDS stream with a stream ID.
 func (l logger) OnStreamClosed(sid int64) {
-	l.Infof("Stream closed[%v]", sid)
+	dlog.Debugf(l.ctx, "Stream closed[%v]", sid)
+	streams.closed(sid)
+	currentACKTracker().closed(sid)
 }
 
 // OnStreamRequest is called once a request is received on a stream.
 func (l logger) OnStreamRequest(sid int64, req *v2.DiscoveryRequest) error {
-	l.Infof("Stream request[%v]: %v", sid, req)
+	dlog.Debugf(l.ctx, "Stream request[%v]: %v", sid, req)
+	streams.request(sid, req.GetNode(), req.GetTypeUrl())
+	acks := currentACKTracker()
+	if version, nack := acks.request(sid, req); nack != "" {
+		dlog.Warnf(l.ctx, "Envoy rejected %s version %s: %s", req.GetTypeUrl(), version, nack)
+	}
+	notifyACK(acks)
 	return nil
 }
 
 // OnStreamResponse is called immediately prior to sending a response on a stream.
 func (l logger) OnStreamResponse(sid int64, req *v2.DiscoveryRequest, res *v2.DiscoveryResponse) {
-	l.Infof("Stream response[%v]: %v -> %v", sid, req, res)
+	dlog.Debugf(l.ctx, "Stream response[%v]: %v -> %v", sid, req, res)
+	streams.response(res.GetTypeUrl())
+	currentACKTracker().response(sid, res)
 }
 
 // OnFetchRequest is called for each Fetch request
 func (l logger) OnFetchRequest(_ context.Context, r *v2.DiscoveryRequest) error {
-	l.Infof("Fetch request: %v", r)
+	dlog.Debugf(l.ctx, "Fetch request: %v", r)
 	return nil
 }
 
 // OnFetchResponse is called immediately prior to sending a response.
 func (l logger) OnFetchResponse(req *v2.DiscoveryRequest, res *v2.DiscoveryResponse) {
-	l.Infof("Fetch response: %v -> %v", req, res)
+	dlog.Debugf(l.ctx, "Fetch response: %v -> %v", req, res)
 }
 
 func Main() {
-	MainContext(context.Background())
+	flag.Parse()
+	logrusLogger := logrus.New()
+	if debug {
+		logrusLogger.SetLevel(logrus.DebugLevel)
+	} else {
+		logrusLogger.SetLevel(logrus.WarnLevel)
+	}
+	MainContext(dlog.WithLogger(context.Background(), dlog.WrapLogrus(logrusLogger)))
 }
 
 func MainContext(parent context.Context) {
@@ -377,17 +482,11 @@ func MainContext(parent context.Context) {
 		adsAddress = fmt.Sprintf(":%v", legacyAdsPort)
 	}
 
-	if debug {
-		log.SetLevel(logrus.DebugLevel)
-	} else {
-		log.SetLevel(logrus.WarnLevel)
-	}
-
-	log.Infof("Ambex %s starting...", Version)
+	dlog.Infof(parent, "Ambex %s starting...", Version)
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.WithError(err).Panic()
+		panic(err)
 	}
 	defer watcher.Close()
 
@@ -409,19 +508,26 @@ func MainContext(parent context.Context) {
 	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
-	config := cache.NewSnapshotCache(true, Hasher{}, log)
-	srv := server.NewServer(ctx, config, log)
+	prefix := versionPrefix()
+	config := envoyxds.NewCache(prefix)
+	// go-control-plane's LinearCaches start at version 0, before anything is loaded.
+	setACKTracker(newACKTracker(prefix + "0"))
+	setServing(config)
+	defer setServing(nil)
+	srv := envoyxds.NewServer(ctx, config, logger{ctx})
+
+	tapds := newTapDiscoveryServer(ctx)
 
-	runManagementServer(ctx, srv, adsNetwork, adsAddress)
+	runManagementServer(ctx, srv, tapds, adsNetwork, adsAddress)
 
 	pid := os.Getpid()
 	file := "ambex.pid"
-	if !warn(ioutil.WriteFile(file, []byte(fmt.Sprintf("%v", pid)), 0644)) {
-		log.WithFields(logrus.Fields{"pid": pid, "file": file}).Info("Wrote PID")
+	if !warn(ctx, ioutil.WriteFile(file, []byte(fmt.Sprintf("%v", pid)), 0644)) {
+		dlog.Info(dlog.WithField(dlog.WithField(ctx, "pid", pid), "file", file), "Wrote PID")
 	}
 
-	generation := 0
-	update(config, &generation, dirs)
+	state := &updateState{}
+	update(ctx, config, tapds, state, dirs)
 
 OUTER:
 	for {
@@ -430,19 +536,19 @@ OUTER:
 		case sig := <-ch:
 			switch sig {
 			case syscall.SIGHUP:
-				update(config, &generation, dirs)
+				update(ctx, config, tapds, state, dirs)
 			case os.Interrupt, syscall.SIGTERM:
 				break OUTER
 			}
 		case <-watcher.Events:
-			update(config, &generation, dirs)
+			update(ctx, config, tapds, state, dirs)
 		case err := <-watcher.Errors:
-			log.WithError(err).Warn("Watcher error")
+			dlog.Warnf(ctx, "Watcher error: %v", err)
 		case <-parent.Done():
 			break OUTER
 		}
 
 	}
 
-	log.Info("Done")
+	dlog.Info(ctx, "Done")
 }
@@ -0,0 +1,37 @@
+package ambex
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+func cluster(name string) *v2.Cluster {
+	return &v2.Cluster{Name: name, ConnectTimeout: ptypes.DurationProto(3e9)}
+}
+
+func TestClone(t *testing.T) {
+	src := cluster("cluster_quote")
+	dst := Clone(src).(*v2.Cluster)
+	assert.True(t, sameResources([]envoyxds.Resource{src}, []envoyxds.Resource{dst}))
+
+	dst.ConnectTimeout.Seconds = 5
+	assert.Equal(t, int64(3), src.GetConnectTimeout().GetSeconds())
+}
+
+func TestUpdateStateUnchanged(t *testing.T) {
+	state := &updateState{}
+	resources := [][]envoyxds.Resource{{cluster("cluster_quote")}, {}}
+	// Nothing has been pushed yet.
+	assert.False(t, state.unchanged(resources))
+
+	state.pushed = resources
+	assert.True(t, state.unchanged([][]envoyxds.Resource{{cluster("cluster_quote")}, {}}))
+	assert.False(t, state.unchanged([][]envoyxds.Resource{{cluster("cluster_other")}, {}}))
+	assert.False(t, state.unchanged([][]envoyxds.Resource{{cluster("cluster_quote"), cluster("cluster_other")}, {}}))
+	assert.False(t, state.unchanged([][]envoyxds.Resource{{}, {cluster("cluster_quote")}}))
+}
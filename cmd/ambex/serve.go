@@ -0,0 +1,288 @@
+package ambex
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	auth "github.com/datawire/ambassador/pkg/api/envoy/api/v2/auth"
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+	"github.com/datawire/ambassador/pkg/dlog"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+// serve-sds and serve-tapds serve SDS and TapDS on their own, from the same files that ambex
+// reads, so that they can run as a sidecar of an Envoy that gets everything else from ambex, or
+// as a service that many Envoys share. Each serves /healthz and /readyz on an HTTP address of its
+// own, so that Kubernetes can probe it without speaking gRPC.
+
+// A sidecar is one of those servers: what it registers with its gRPC server, and how it loads
+// what it serves.
+type sidecar struct {
+	name     string
+	register func(*grpc.Server)
+	// load loads what to serve from the files in dirs, and returns how many resources it's
+	// serving.
+	load func(ctx context.Context, dirs []string) (int, error)
+
+	mu     sync.Mutex
+	status sidecarStatus
+}
+
+// The sidecarStatus struct is what /readyz serves.
+type sidecarStatus struct {
+	Name      string    `json:"name"`
+	Ready     bool      `json:"ready"`
+	Resources int       `json:"resources"`
+	Loaded    time.Time `json:"loaded,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// reload loads what to serve from dirs. Until a load succeeds, the sidecar isn't ready; after
+// that, a load that fails leaves it serving what it had, and ready.
+func (s *sidecar) reload(ctx context.Context, dirs []string) {
+	count, err := s.load(ctx, dirs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Name = s.name
+	if err != nil {
+		dlog.Errorf(ctx, "%s: %v", s.name, err)
+		s.status.Error = err.Error()
+		return
+	}
+	s.status.Ready = true
+	s.status.Resources = count
+	s.status.Loaded = time.Now()
+	s.status.Error = ""
+}
+
+func (s *sidecar) current() sidecarStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := s.status
+	ret.Name = s.name
+	return ret
+}
+
+// ServeHTTP serves /healthz, which is OK for as long as the sidecar is running, and /readyz,
+// which is OK once it has loaded something to serve.
+func (s *sidecar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/healthz":
+		w.Write([]byte("ok\n"))
+	case "/readyz":
+		status := s.current()
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// run serves gRPC at listen and health at healthListen, and reloads from dirs on SIGHUP and,
+// if watch is set, whenever a file in them changes, until ctx is done or a SIGTERM.
+func (s *sidecar) run(ctx context.Context, listen, healthListen string, watch bool, dirs []string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if watch {
+		for _, d := range dirs {
+			watcher.Add(d)
+		}
+	}
+
+	grpcListener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	healthListener, err := net.Listen("tcp", healthListen)
+	if err != nil {
+		grpcListener.Close()
+		return err
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(ch)
+
+	s.reload(ctx, dirs)
+
+	grpcServer := grpc.NewServer()
+	s.register(grpcServer)
+	healthServer := &http.Server{Handler: s}
+
+	errs := make(chan error, 2)
+	go func() { errs <- grpcServer.Serve(grpcListener) }()
+	go func() { errs <- healthServer.Serve(healthListener) }()
+	dlog.Infof(ctx, "%s listening on %s, with health on %s", s.name, listen, healthListen)
+
+	defer func() {
+		grpcServer.GracefulStop()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		healthServer.Shutdown(shutdownCtx)
+	}()
+
+	for {
+		select {
+		case sig := <-ch:
+			if sig != syscall.SIGHUP {
+				return nil
+			}
+			s.reload(ctx, dirs)
+		case <-watcher.Events:
+			s.reload(ctx, dirs)
+		case err := <-watcher.Errors:
+			dlog.Warnf(ctx, "Watcher error: %v", err)
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// newSDS returns the sidecar that serves the Secrets in ambex's files over SDS.
+func newSDS(ctx context.Context) *sidecar {
+	prefix := versionPrefix()
+	config := envoyxds.NewCache(prefix)
+	setACKTracker(newACKTracker(prefix + "0"))
+	srv := envoyxds.NewServer(ctx, config, logger{ctx})
+	return &sidecar{
+		name:     "SDS",
+		register: func(grpcServer *grpc.Server) { envoyxds.RegisterSecrets(grpcServer, srv) },
+		load: func(ctx context.Context, dirs []string) (int, error) {
+			secrets := loadSecrets(ctx, dirs)
+			return len(secrets), config.Set(envoyxds.Snapshot{envoyxds.SecretType: secrets})
+		},
+	}
+}
+
+// loadSecrets loads the Secrets in the files in dirs, by name, skipping everything else.
+func loadSecrets(ctx context.Context, dirs []string) map[string]envoyxds.Resource {
+	secrets := map[string]envoyxds.Resource{}
+	for _, name := range decodableFiles(ctx, dirs) {
+		m, err := decode(name)
+		if err != nil {
+			dlog.Warnf(ctx, "%s: %v", name, err)
+			continue
+		}
+		if secret, ok := m.(*auth.Secret); ok {
+			secrets[secret.GetName()] = secret
+		}
+	}
+	return secrets
+}
+
+// newTapDS returns the sidecar that serves the TapResources in diagd's tapds.json over TapDS.
+func newTapDS(ctx context.Context) *sidecar {
+	tapds := newTapDiscoveryServer(ctx)
+	generation := 0
+	return &sidecar{
+		name:     "TapDS",
+		register: func(grpcServer *grpc.Server) { tapsvc.RegisterTapDiscoveryServiceServer(grpcServer, tapds) },
+		load: func(ctx context.Context, dirs []string) (int, error) {
+			taps, err := loadTaps(ctx, dirs)
+			if err != nil {
+				return 0, err
+			}
+			if _, current, _ := tapds.current(); generation > 0 && sameTaps(current, taps) {
+				return len(taps), nil
+			}
+			tapds.set(fmt.Sprintf("v%d", generation), taps)
+			generation++
+			return len(taps), nil
+		},
+	}
+}
+
+// loadTaps loads the TapResources in the files in dirs, skipping everything else.
+func loadTaps(ctx context.Context, dirs []string) ([]*tapsvc.TapResource, error) {
+	taps := []*tapsvc.TapResource{}
+	for _, name := range decodableFiles(ctx, dirs) {
+		m, err := decode(name)
+		if err != nil {
+			dlog.Warnf(ctx, "%s: %v", name, err)
+			continue
+		}
+		if resp, ok := m.(*v2.DiscoveryResponse); ok {
+			resources, err := tapResources(resp)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			taps = append(taps, resources...)
+		}
+	}
+	return taps, nil
+}
+
+// sameTaps returns whether taps are the TapResources that a tapDiscoveryServer has.
+func sameTaps(current map[string]*tapsvc.TapResource, taps []*tapsvc.TapResource) bool {
+	if len(current) != len(taps) {
+		return false
+	}
+	for _, tap := range taps {
+		if !proto.Equal(current[tap.GetName()], tap) {
+			return false
+		}
+	}
+	return true
+}
+
+// serveMain parses the flags of a sidecar, with its default addresses, and runs it.
+func serveMain(name, defaultListen, defaultHealth string, newSidecar func(context.Context) *sidecar) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	debug := flags.Bool("debug", false, "Use debug logging")
+	watch := flags.Bool("watch", false, "Watch for file changes")
+	listen := flags.String("listen", defaultListen, "address to serve gRPC on")
+	health := flags.String("health-listen", defaultHealth, "address to serve /healthz and /readyz on")
+	flags.Parse(os.Args[1:])
+
+	logrusLogger := logrus.New()
+	if *debug {
+		logrusLogger.SetLevel(logrus.DebugLevel)
+	} else {
+		logrusLogger.SetLevel(logrus.InfoLevel)
+	}
+	ctx := dlog.WithLogger(context.Background(), dlog.WrapLogrus(logrusLogger))
+
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	if err := newSidecar(ctx).run(ctx, *listen, *health, *watch, dirs); err != nil {
+		dlog.Errorf(ctx, "%s: %v", name, err)
+		os.Exit(1)
+	}
+}
+
+// ServeSDS is busyambassador serve-sds.
+func ServeSDS() {
+	serveMain("serve-sds", ":18001", ":18011", newSDS)
+}
+
+// ServeTapDS is busyambassador serve-tapds.
+func ServeTapDS() {
+	serveMain("serve-tapds", ":18002", ":18012", newTapDS)
+}
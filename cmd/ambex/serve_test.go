@@ -0,0 +1,138 @@
+package ambex
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	auth "github.com/datawire/ambassador/pkg/api/envoy/api/v2/auth"
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+// writeResource writes m to dir/name as ambex reads it: JSON, in an Any.
+func writeResource(t *testing.T, dir, name string, m proto.Message) {
+	t.Helper()
+	wrapped, err := ptypes.MarshalAny(m)
+	require.NoError(t, err)
+	text, err := (&jsonpb.Marshaler{}).MarshalToString(wrapped)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644))
+}
+
+// servedTap returns a TapResource as diagd writes it, with somewhere for the tap to go.
+func servedTap(name string) *tapsvc.TapResource {
+	tap := tapResource(name)
+	tap.Config.OutputConfig = &tapsvc.OutputConfig{Sinks: []*tapsvc.OutputSink{{
+		OutputSinkType: &tapsvc.OutputSink_StreamingAdmin{StreamingAdmin: &tapsvc.StreamingAdminSink{}},
+	}}}
+	return tap
+}
+
+func tapsResponse(t *testing.T, taps ...*tapsvc.TapResource) *v2.DiscoveryResponse {
+	t.Helper()
+	resp := &v2.DiscoveryResponse{}
+	for _, tap := range taps {
+		resource, err := ptypes.MarshalAny(tap)
+		require.NoError(t, err)
+		resp.Resources = append(resp.Resources, resource)
+	}
+	return resp
+}
+
+// badTaps returns a DiscoveryResponse for tapds.json with a Cluster in it.
+func badTaps(t *testing.T) *v2.DiscoveryResponse {
+	t.Helper()
+	resource, err := ptypes.MarshalAny(cluster("cluster_quote"))
+	require.NoError(t, err)
+	return &v2.DiscoveryResponse{Resources: []*any.Any{resource}}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ambex-sds")
+	require.NoError(t, err)
+
+	writeResource(t, dir, "quote-cert.json", &auth.Secret{Name: "quote-cert"})
+	writeResource(t, dir, "cluster.json", cluster("cluster_quote"))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644))
+
+	secrets := loadSecrets(context.Background(), []string{dir})
+	assert.Len(t, secrets, 1)
+	assert.Equal(t, "quote-cert", envoyxds.ResourceName(secrets["quote-cert"]))
+}
+
+func TestLoadTaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ambex-tapds")
+	require.NoError(t, err)
+
+	writeResource(t, dir, "tapds.json", tapsResponse(t, servedTap("quote-tap.default")))
+	writeResource(t, dir, "cluster.json", cluster("cluster_quote"))
+
+	taps, err := loadTaps(context.Background(), []string{dir})
+	require.NoError(t, err)
+	require.Len(t, taps, 1)
+	assert.Equal(t, "quote-tap.default", taps[0].GetName())
+
+	// Anything but a TapResource in tapds.json is an error.
+	writeResource(t, dir, "tapds.json", badTaps(t))
+	_, err = loadTaps(context.Background(), []string{dir})
+	assert.Error(t, err)
+}
+
+func TestTapDSSidecar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ambex-tapds")
+	require.NoError(t, err)
+	writeResource(t, dir, "tapds.json", tapsResponse(t, servedTap("quote-tap.default")))
+
+	s := newTapDS(context.Background())
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	// Healthy straight away, but not ready until it has loaded something.
+	code, _ := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	s.reload(context.Background(), []string{dir})
+	code, body := get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	var status sidecarStatus
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	assert.Equal(t, "TapDS", status.Name)
+	assert.Equal(t, 1, status.Resources)
+
+	// A load that fails leaves it ready, with what it had.
+	writeResource(t, dir, "tapds.json", badTaps(t))
+	s.reload(context.Background(), []string{dir})
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	assert.Equal(t, 1, status.Resources)
+	assert.NotEmpty(t, status.Error)
+
+	code, _ = get("/other")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestSameTaps(t *testing.T) {
+	current := map[string]*tapsvc.TapResource{"quote-tap.default": tapResource("quote-tap.default")}
+	assert.True(t, sameTaps(current, []*tapsvc.TapResource{tapResource("quote-tap.default")}))
+	assert.False(t, sameTaps(current, []*tapsvc.TapResource{tapResource("auth-tap.default")}))
+	assert.False(t, sameTaps(current, nil))
+}
@@ -0,0 +1,121 @@
+package ambex
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+// NodeHash groups the Envoys that are connected to ambex, for Stats. It has to be set
+// before MainContext is called; by default Envoys are grouped by their node ID.
+var NodeHash envoyxds.NodeHash = Hasher{}
+
+// TypeStats is what Stats reports about one type of resource.
+type TypeStats struct {
+	TypeURL   string
+	Resources int // how many resources of the type are being served
+	Streams   int // how many xDS streams have asked for the type
+	Responses int // how many responses of the type have been sent, in all
+}
+
+// CacheStats is what ambex is serving, and to whom.
+type CacheStats struct {
+	Types []TypeStats
+	// Nodes has the number of open xDS streams for each group of Envoys, by NodeHash.
+	Nodes map[string]int
+}
+
+// streamStats counts what the server callbacks see.
+type streamStats struct {
+	mu        sync.Mutex
+	nodes     map[int64]string          // the NodeHash group of each stream that has sent a request
+	types     map[int64]map[string]bool // the types that each stream has asked for
+	responses map[string]int
+}
+
+func newStreamStats() *streamStats {
+	return &streamStats{
+		nodes:     map[int64]string{},
+		types:     map[int64]map[string]bool{},
+		responses: map[string]int{},
+	}
+}
+
+func (s *streamStats) request(sid int64, node *core.Node, typeURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Only the first request on a stream has to have the node in it.
+	if _, ok := s.nodes[sid]; !ok {
+		s.nodes[sid] = NodeHash.ID(node)
+		s.types[sid] = map[string]bool{}
+	}
+	s.types[sid][typeURL] = true
+}
+
+func (s *streamStats) response(typeURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[typeURL]++
+}
+
+func (s *streamStats) closed(sid int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.nodes, sid)
+	delete(s.types, sid)
+}
+
+func (s *streamStats) stats(c envoyxds.Cache) CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret := CacheStats{Nodes: map[string]int{}}
+	for _, group := range s.nodes {
+		ret.Nodes[group]++
+	}
+	for _, typeURL := range envoyxds.Types {
+		ts := TypeStats{TypeURL: typeURL, Responses: s.responses[typeURL]}
+		for _, types := range s.types {
+			if types[typeURL] {
+				ts.Streams++
+			}
+		}
+		if c != nil {
+			ts.Resources = c.NumResources(typeURL)
+		}
+		ret.Types = append(ret.Types, ts)
+	}
+	sort.Slice(ret.Types, func(i, j int) bool { return ret.Types[i].TypeURL < ret.Types[j].TypeURL })
+	return ret
+}
+
+var (
+	streams = newStreamStats()
+
+	servingMu sync.Mutex
+	serving   envoyxds.Cache
+)
+
+// Stats returns what ambex is serving, and to whom. Before MainContext has started serving,
+// there are no resources.
+func Stats() CacheStats {
+	servingMu.Lock()
+	c := serving
+	servingMu.Unlock()
+	return streams.stats(c)
+}
+
+func setServing(c envoyxds.Cache) {
+	servingMu.Lock()
+	defer servingMu.Unlock()
+	serving = c
+}
+
+// versionPrefix returns the prefix for the versions of this run of ambex.
+func versionPrefix() string {
+	return fmt.Sprintf("%x-", time.Now().UnixNano())
+}
@@ -0,0 +1,45 @@
+package ambex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+func TestStreamStats(t *testing.T) {
+	c := envoyxds.NewCache("test-")
+	snapshot, err := envoyxds.NewSnapshot(nil, []envoyxds.Resource{cluster("cluster_quote")}, nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Set(snapshot))
+
+	s := newStreamStats()
+	s.request(1, &core.Node{Id: "test-id"}, envoyxds.ClusterType)
+	s.request(1, nil, envoyxds.ListenerType)
+	s.request(2, &core.Node{Id: "test-id"}, envoyxds.ClusterType)
+	s.request(3, nil, envoyxds.ClusterType)
+	s.response(envoyxds.ClusterType)
+	s.response(envoyxds.ClusterType)
+	s.closed(2)
+
+	stats := s.stats(c)
+	assert.Equal(t, map[string]int{"test-id": 1, "unknown": 1}, stats.Nodes)
+	byType := map[string]TypeStats{}
+	for _, ts := range stats.Types {
+		byType[ts.TypeURL] = ts
+	}
+	assert.Len(t, byType, len(envoyxds.Types))
+	assert.Equal(t, TypeStats{TypeURL: envoyxds.ClusterType, Resources: 1, Streams: 2, Responses: 2},
+		byType[envoyxds.ClusterType])
+	assert.Equal(t, TypeStats{TypeURL: envoyxds.ListenerType, Streams: 1},
+		byType[envoyxds.ListenerType])
+	assert.Equal(t, TypeStats{TypeURL: envoyxds.RouteType}, byType[envoyxds.RouteType])
+
+	// There's nothing being served before MainContext starts.
+	for _, ts := range s.stats(nil).Types {
+		assert.Equal(t, 0, ts.Resources)
+	}
+}
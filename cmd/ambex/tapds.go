@@ -0,0 +1,208 @@
+package ambex
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+const tapResourceType = "type.googleapis.com/envoy.service.tap.v2alpha.TapResource"
+
+// tapDiscoveryServer serves TapDS, the tap discovery service, so that taps can come and go
+// without changing any listeners. go-control-plane's SnapshotCache only knows about the
+// core xDS types, so this is a small state-of-the-world server of its own; incremental
+// TapDS isn't supported.
+//
+// diagd writes the TapResources for the TapPolicies in a DiscoveryResponse in tapds.json,
+// and update hands them to set.
+type tapDiscoveryServer struct {
+	tapsvc.UnimplementedTapDiscoveryServiceServer
+
+	ctx context.Context // for logging
+
+	mu      sync.Mutex
+	version string
+	taps    map[string]*tapsvc.TapResource
+	changed chan struct{} // closed, and replaced, by every call to set
+}
+
+func newTapDiscoveryServer(ctx context.Context) *tapDiscoveryServer {
+	return &tapDiscoveryServer{
+		ctx:     ctx,
+		taps:    map[string]*tapsvc.TapResource{},
+		changed: make(chan struct{}),
+	}
+}
+
+// set replaces the TapResources being served, and sends them to every stream that's
+// waiting for a new version.
+func (s *tapDiscoveryServer) set(version string, taps []*tapsvc.TapResource) {
+	byName := make(map[string]*tapsvc.TapResource, len(taps))
+	for _, tap := range taps {
+		byName[tap.GetName()] = tap
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+	s.taps = byName
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *tapDiscoveryServer) current() (string, map[string]*tapsvc.TapResource, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, s.taps, s.changed
+}
+
+// response builds the response for a version, with just the named taps, or all of them if
+// names is empty.
+func (s *tapDiscoveryServer) response(version string, taps map[string]*tapsvc.TapResource, names []string, nonce string) (*v2.DiscoveryResponse, error) {
+	if len(names) == 0 {
+		for name := range taps {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	resources := []*any.Any{}
+	for _, name := range names {
+		tap, ok := taps[name]
+		if !ok {
+			continue
+		}
+		resource, err := ptypes.MarshalAny(tap)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+
+	return &v2.DiscoveryResponse{
+		VersionInfo: version,
+		Resources:   resources,
+		TypeUrl:     tapResourceType,
+		Nonce:       nonce,
+	}, nil
+}
+
+// FetchTapConfigs implements the TapDiscoveryServiceServer interface.
+func (s *tapDiscoveryServer) FetchTapConfigs(_ context.Context, req *v2.DiscoveryRequest) (*v2.DiscoveryResponse, error) {
+	version, taps, _ := s.current()
+	return s.response(version, taps, req.GetResourceNames(), "")
+}
+
+// StreamTapConfigs implements the TapDiscoveryServiceServer interface. Envoy's first request
+// on a stream gets the current taps straight away; after that, each request ACKs or NACKs
+// the response before it, and the next response waits for a new version unless the request
+// changes which taps Envoy wants.
+func (s *tapDiscoveryServer) StreamTapConfigs(stream tapsvc.TapDiscoveryService_StreamTapConfigsServer) error {
+	ctx := stream.Context()
+
+	requests := make(chan *v2.DiscoveryRequest)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		names   []string
+		sent    string // the version in the last response
+		nonce   string // the nonce of the last response
+		nonces  int
+		waiting bool // whether Envoy is waiting for a response
+		now     bool // whether to respond even if the version hasn't changed
+	)
+
+	for {
+		version, taps, changed := s.current()
+
+		if waiting && (now || version != sent) {
+			nonces++
+			nonce = fmt.Sprint(nonces)
+			resp, err := s.response(version, taps, names, nonce)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+			sent = version
+			waiting = false
+			now = false
+		}
+
+		select {
+		case req := <-requests:
+			if req.GetTypeUrl() != "" && req.GetTypeUrl() != tapResourceType {
+				return fmt.Errorf("TapDS: unexpected type %q", req.GetTypeUrl())
+			}
+			if req.GetResponseNonce() != nonce {
+				// A response to something other than our last response; ignore it.
+				continue
+			}
+			if detail := req.GetErrorDetail(); detail != nil {
+				dlog.Warnf(s.ctx, "TapDS: Envoy rejected version %s: %s", sent, detail.GetMessage())
+			}
+			if req.GetResponseNonce() == "" || !sameNames(names, req.GetResourceNames()) {
+				now = true
+			}
+			names = req.GetResourceNames()
+			waiting = true
+		case <-changed:
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tapResources pulls the TapResources out of a DiscoveryResponse that diagd wrote.
+func tapResources(resp *v2.DiscoveryResponse) ([]*tapsvc.TapResource, error) {
+	var taps []*tapsvc.TapResource
+	for _, resource := range resp.GetResources() {
+		tap := &tapsvc.TapResource{}
+		if err := ptypes.UnmarshalAny(resource, tap); err != nil {
+			return nil, err
+		}
+		taps = append(taps, tap)
+	}
+	return taps, nil
+}
@@ -0,0 +1,103 @@
+package ambex
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+)
+
+func tapResource(name string) *tapsvc.TapResource {
+	return &tapsvc.TapResource{
+		Name: name,
+		Config: &tapsvc.TapConfig{
+			MatchConfig: &tapsvc.MatchPredicate{Rule: &tapsvc.MatchPredicate_AnyMatch{AnyMatch: true}},
+		},
+	}
+}
+
+func tapNames(t *testing.T, resp *v2.DiscoveryResponse) []string {
+	taps, err := tapResources(resp)
+	require.NoError(t, err)
+	names := []string{}
+	for _, tap := range taps {
+		names = append(names, tap.GetName())
+	}
+	return names
+}
+
+func TestTapDS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	tapds := newTapDiscoveryServer(context.Background())
+	tapds.set("v0", []*tapsvc.TapResource{tapResource("quote-tap.default"), tapResource("auth-tap.default")})
+
+	server := grpc.NewServer()
+	tapsvc.RegisterTapDiscoveryServiceServer(server, tapds)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := tapsvc.NewTapDiscoveryServiceClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Fetch
+	resp, err := client.FetchTapConfigs(ctx, &v2.DiscoveryRequest{ResourceNames: []string{"quote-tap.default"}})
+	require.NoError(t, err)
+	assert.Equal(t, "v0", resp.GetVersionInfo())
+	assert.Equal(t, []string{"quote-tap.default"}, tapNames(t, resp))
+
+	// Stream: the first request gets an answer straight away.
+	stream, err := client.StreamTapConfigs(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&v2.DiscoveryRequest{TypeUrl: tapResourceType}))
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "v0", resp.GetVersionInfo())
+	assert.Equal(t, tapResourceType, resp.GetTypeUrl())
+	assert.Equal(t, []string{"auth-tap.default", "quote-tap.default"}, tapNames(t, resp))
+
+	// An ACK waits for the next version, which drops a tap.
+	require.NoError(t, stream.Send(&v2.DiscoveryRequest{
+		TypeUrl:       tapResourceType,
+		VersionInfo:   "v0",
+		ResponseNonce: resp.GetNonce(),
+	}))
+	tapds.set("v1", []*tapsvc.TapResource{tapResource("quote-tap.default")})
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "v1", resp.GetVersionInfo())
+	assert.Equal(t, []string{"quote-tap.default"}, tapNames(t, resp))
+
+	// Asking for different taps gets an answer straight away.
+	require.NoError(t, stream.Send(&v2.DiscoveryRequest{
+		TypeUrl:       tapResourceType,
+		VersionInfo:   "v1",
+		ResourceNames: []string{"auth-tap.default"},
+		ResponseNonce: resp.GetNonce(),
+	}))
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "v1", resp.GetVersionInfo())
+	assert.Equal(t, []string{}, tapNames(t, resp))
+}
+
+func TestTapResourcesBadType(t *testing.T) {
+	cluster, err := ptypes.MarshalAny(&v2.Cluster{Name: "cluster_quote"})
+	require.NoError(t, err)
+	_, err = tapResources(&v2.DiscoveryResponse{Resources: []*any.Any{cluster}})
+	assert.Error(t, err)
+}
@@ -9,8 +9,17 @@ import (
 	"github.com/datawire/ambassador/pkg/busy"
 
 	"github.com/datawire/ambassador/cmd/ambex"
+	"github.com/datawire/ambassador/cmd/debug"
 	"github.com/datawire/ambassador/cmd/entrypoint"
+	"github.com/datawire/ambassador/cmd/envoydiff"
+	"github.com/datawire/ambassador/cmd/explain"
 	"github.com/datawire/ambassador/cmd/kubestatus"
+	"github.com/datawire/ambassador/cmd/loadgen"
+	"github.com/datawire/ambassador/cmd/ratelimit"
+	"github.com/datawire/ambassador/cmd/statsmap"
+	"github.com/datawire/ambassador/cmd/tap"
+	"github.com/datawire/ambassador/cmd/tapserver"
+	"github.com/datawire/ambassador/cmd/validate"
 	"github.com/datawire/ambassador/cmd/watt"
 )
 
@@ -18,14 +27,26 @@ var Version = "(unknown version)"
 
 func main() {
 	ambex.Version = Version
-	//entrypoint.Version = Version // Does not exist
+	entrypoint.Version = Version
 	//kubestatus.Version = Version // Does not exist
 	watt.Version = Version
+	tap.Version = Version
 
 	busy.Main("busyambassador", "Ambassador", map[string]func(){
-		"ambex":      ambex.Main,
-		"watt":       watt.Main,
-		"kubestatus": kubestatus.Main,
-		"entrypoint": entrypoint.Main,
+		"ambex":       ambex.Main,
+		"watt":        watt.Main,
+		"kubestatus":  kubestatus.Main,
+		"entrypoint":  entrypoint.Main,
+		"ratelimit":   ratelimit.Main,
+		"tapserver":   tapserver.Main,
+		"tap":         tap.Main,
+		"envoydiff":   envoydiff.Main,
+		"statsmap":    statsmap.Main,
+		"debug":       debug.Main,
+		"validate":    validate.Main,
+		"explain":     explain.Main,
+		"loadgen":     loadgen.Main,
+		"serve-sds":   ambex.ServeSDS,
+		"serve-tapds": ambex.ServeTapDS,
 	})
 }
@@ -0,0 +1,102 @@
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A source is one file of a collection, and where on the debug server it comes from.
+type source struct {
+	file string
+	path string
+}
+
+// sources are what collect fetches, besides the CPU profile.
+var sources = []source{
+	{"goroutines.txt", "/debug/goroutines"},
+	{"timers.json", "/debug/timers"},
+	{"memory.json", "/debug/memory"},
+	{"cmdline.txt", "/debug/pprof/cmdline"},
+	{"heap.pb.gz", "/debug/pprof/heap"},
+	{"allocs.pb.gz", "/debug/pprof/allocs"},
+	{"block.pb.gz", "/debug/pprof/block"},
+	{"mutex.pb.gz", "/debug/pprof/mutex"},
+	{"threadcreate.pb.gz", "/debug/pprof/threadcreate"},
+}
+
+// errNotServed is what fetch returns for an endpoint that the debug server doesn't serve, because
+// AMBASSADOR_DEBUG_ALLOW leaves it out.
+var errNotServed = fmt.Errorf("not served")
+
+// collect fetches everything that the debug server at baseURL serves to token, with a CPU profile
+// of cpuSeconds unless that's 0, and writes it to w as a gzipped tarball. It returns how many
+// files it wrote. Endpoints that the server doesn't serve are left out.
+func collect(ctx context.Context, baseURL, token string, cpuSeconds int, w io.Writer) (int, error) {
+	all := sources
+	if cpuSeconds > 0 {
+		all = append(append([]source{}, sources...),
+			source{"cpu.pb.gz", fmt.Sprintf("/debug/pprof/profile?seconds=%d", cpuSeconds)})
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	count := 0
+	for _, src := range all {
+		body, err := fetch(ctx, strings.TrimSuffix(baseURL, "/")+src.path, token)
+		if err == errNotServed {
+			log.Printf("skipping %s: the debug server doesn't serve %s", src.file, src.path)
+			continue
+		}
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", src.path, err)
+		}
+		header := &tar.Header{Name: src.file, Mode: 0644, Size: int64(len(body)), ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return count, err
+		}
+		if _, err := tw.Write(body); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := tw.Close(); err != nil {
+		return count, err
+	}
+	return count, gz.Close()
+}
+
+// fetch GETs url with token.
+func fetch(ctx context.Context, url, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, errNotServed
+	default:
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+}
@@ -0,0 +1,70 @@
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		paths = append(paths, r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/debug/goroutines":
+			io.WriteString(w, "goroutine 1 [running]:\n")
+		case "/debug/timers":
+			io.WriteString(w, `{"snapshot_build":{"count":1}}`)
+		default:
+			// As if AMBASSADOR_DEBUG_ALLOW left out pprof.
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	count, err := collect(context.Background(), server.URL+"/", "secret", 1, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Contains(t, paths, "/debug/pprof/profile?seconds=1")
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(body)
+	}
+	assert.Equal(t, map[string]string{
+		"goroutines.txt": "goroutine 1 [running]:\n",
+		"timers.json":    `{"snapshot_build":{"count":1}}`,
+	}, files)
+
+	paths = nil
+	_, err = collect(context.Background(), server.URL, "secret", 0, ioutil.Discard)
+	require.NoError(t, err)
+	assert.NotContains(t, paths, "/debug/pprof/profile?seconds=0")
+
+	_, err = collect(context.Background(), server.URL, "wrong", 0, ioutil.Discard)
+	assert.EqualError(t, err, "/debug/goroutines: 401 Unauthorized: unauthorized")
+}
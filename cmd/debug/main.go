@@ -0,0 +1,103 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Main is busyambassador debug, for the debug server that the entrypoint runs on localhost when
+// AMBASSADOR_DEBUG_PORT is set. Its collect subcommand snapshots what the server serves into a
+// tarball, to attach to a bug report.
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "debug",
+		Short:         "work with Ambassador's debug server",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	cmd.AddCommand(collectCommand())
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func collectCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "collect",
+		Short: "snapshot goroutines, profiles and timers from the debug server into a .tar.gz",
+		Args:  cobra.NoArgs,
+	}
+
+	url := cmd.Flags().String("url", debugURL(), "URL of the debug server")
+	tokenFile := cmd.Flags().String("token-file", tokenFile(), "file with the debug server's bearer token")
+	output := cmd.Flags().StringP("output", "o", "", "the file to write, - for stdout (default ambassador-debug-TIME.tar.gz)")
+	cpuSeconds := cmd.Flags().Int("cpu-seconds", 10, "how long to profile the CPU for; 0 skips the CPU profile")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *url == "" {
+			return fmt.Errorf("AMBASSADOR_DEBUG_PORT isn't set, so the debug server is off; set it, or use --url")
+		}
+		bytes, err := ioutil.ReadFile(*tokenFile)
+		if err != nil {
+			return err
+		}
+		token := strings.TrimSpace(string(bytes))
+
+		var w io.Writer = os.Stdout
+		name := *output
+		if name == "" {
+			name = "ambassador-debug-" + time.Now().UTC().Format("20060102T150405Z") + ".tar.gz"
+		}
+		if name != "-" {
+			file, err := os.Create(name)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			w = file
+		}
+
+		if *cpuSeconds > 0 {
+			log.Printf("profiling the CPU for %d seconds", *cpuSeconds)
+		}
+		count, err := collect(context.Background(), *url, token, *cpuSeconds, w)
+		if err != nil {
+			return err
+		}
+		if name != "-" {
+			log.Printf("wrote %d files to %s", count, name)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// debugURL returns the URL of the debug server, as the entrypoint sets it up, or "" if it's off.
+func debugURL() string {
+	if port := os.Getenv("AMBASSADOR_DEBUG_PORT"); port != "" {
+		return "http://127.0.0.1:" + port
+	}
+	return ""
+}
+
+// tokenFile returns the file with the debug server's token, as the entrypoint sets it up.
+func tokenFile() string {
+	if file := os.Getenv("AMBASSADOR_DEBUG_TOKEN_FILE"); file != "" {
+		return file
+	}
+	if dir := os.Getenv("AMBASSADOR_CONFIG_BASE_DIR"); dir != "" {
+		return path.Join(dir, "debug-token")
+	}
+	return "/ambassador/debug-token"
+}
@@ -0,0 +1,36 @@
+// demo-auth is a demo AuthService that checks requests against a
+// policy file (see pkg/authpolicy), reloading the policy whenever it
+// changes.  To drive it from a ConfigMap, mount the ConfigMap into the
+// pod and point -policy at the mounted file.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/datawire/ambassador/pkg/authpolicy"
+)
+
+func main() {
+	listen := flag.String("listen", ":5050", "address to listen on")
+	pathPrefix := flag.String("path-prefix", "/auth/v0/", "the AuthService's path_prefix")
+	policyFile := flag.String("policy", "/etc/demo-auth/policy.yaml", "policy file to load and watch")
+	flag.Parse()
+
+	server := authpolicy.NewServer(*pathPrefix)
+
+	if err := server.LoadFile(*policyFile); err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		if err := server.Watch(context.Background(), *policyFile); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	log.Printf("demo-auth listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, server))
+}
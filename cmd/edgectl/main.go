@@ -10,6 +10,7 @@ import (
 	"github.com/datawire/ambassador/internal/pkg/edgectl/client"
 	"github.com/datawire/ambassador/internal/pkg/edgectl/daemon"
 	install "github.com/datawire/ambassador/internal/pkg/edgectl/install"
+	"github.com/datawire/ambassador/pkg/k8s"
 )
 
 // Version is inserted at build using --ldflags -X
@@ -25,15 +26,15 @@ func main() {
 		cg = []client.CmdGroup{
 			{
 				GroupName: "Management Commands",
-				CmdNames:  []string{"install", "upgrade", "login", "license"},
+				CmdNames:  []string{"install", "upgrade", "login", "license", "config"},
 			},
 			{
 				GroupName: "Development Commands",
-				CmdNames:  []string{"status", "connect", "disconnect", "intercept"},
+				CmdNames:  []string{"status", "connect", "disconnect", "intercept", "forward", "proxy"},
 			},
 			{
 				GroupName: "Advanced Commands",
-				CmdNames:  []string{"daemon", "pause", "resume", "quit"},
+				CmdNames:  []string{"daemon", "doctor", "pause", "resume", "quit"},
 			},
 			{
 				GroupName: "Other Commands",
@@ -44,7 +45,11 @@ func main() {
 		cg = []client.CmdGroup{
 			{
 				GroupName: "Management Commands",
-				CmdNames:  []string{"install", "upgrade", "login", "license"},
+				CmdNames:  []string{"install", "upgrade", "login", "license", "config"},
+			},
+			{
+				GroupName: "Development Commands",
+				CmdNames:  []string{"proxy"},
 			},
 			{
 				GroupName: "Other Commands",
@@ -80,6 +85,10 @@ func getRootCommand() *cobra.Command {
 	_ = rootCmd.PersistentFlags().Bool(
 		"no-report", false, "turn off anonymous crash reports and log submission on failure",
 	)
+	_ = rootCmd.PersistentFlags().StringP(
+		"output", "o", "text", "output format: text or json",
+	)
+	rootCmd.PersistentPreRunE = client.CheckOutput
 
 	// Hidden/internal commands. These are called by Edge Control itself from
 	// the correct context and execute in-place immediately.
@@ -173,6 +182,12 @@ func getRootCommand() *cobra.Command {
 			Args:  cobra.ExactArgs(0),
 			RunE:  client.Disconnect,
 		})
+		rootCmd.AddCommand(&cobra.Command{
+			Use:   "doctor",
+			Short: "Check connectivity, from the daemon to the cluster",
+			Args:  cobra.ExactArgs(0),
+			RunE:  client.Doctor,
+		})
 		rootCmd.AddCommand(&cobra.Command{
 			Use:   "pause",
 			Short: "Turn off network overrides (to use a VPN)",
@@ -233,6 +248,7 @@ func getRootCommand() *cobra.Command {
 		}
 		interceptAddCmd.Flags().StringVarP(&intercept.Name, "name", "n", "", "a name for this intercept")
 		interceptAddCmd.Flags().StringVar(&intercept.Prefix, "prefix", "/", "prefix to intercept")
+		interceptAddCmd.Flags().StringVar(&intercept.Mapping, "mapping", "", "intercept only the requests that this Mapping routes, instead of a prefix")
 		interceptAddCmd.Flags().BoolVarP(&intercept.Preview, "preview", "p", true, "use a preview URL") // this default is unused
 		interceptAddCmd.Flags().BoolVarP(&intercept.GRPC, "grpc", "", false, "intercept GRPC traffic")
 		interceptAddCmd.Flags().StringVarP(&intercept.TargetHost, "target", "t", "", "the [HOST:]PORT to forward to")
@@ -249,6 +265,32 @@ func getRootCommand() *cobra.Command {
 		}
 		interceptCmd.SetUsageFunc(client.NewCmdUsage(interceptCmd, interceptCG))
 		rootCmd.AddCommand(interceptCmd)
+
+		forward := client.ForwardInfo{}
+		forwardCmd := &cobra.Command{
+			Use: "forward [flags] [TYPE/]NAME:PORT",
+			Long: "Manage port forwards. A port forward makes a port of a service, or other resource, in the " +
+				"cluster available on localhost. The daemon keeps it up, and reconnects it when it drops.",
+			Short: "Manage port forwards",
+			Args:  cobra.ExactArgs(1),
+			RunE:  forward.AddForward,
+		}
+		forwardCmd.Flags().StringVarP(&forward.Namespace, "namespace", "n", "", "the namespace of the resource")
+		forwardCmd.Flags().Int32VarP(&forward.LocalPort, "local-port", "l", 0, "the local port (defaults to PORT, if it's a number)")
+		forwardCmd.AddCommand(&cobra.Command{
+			Use:   "list",
+			Short: "List port forwards",
+			Args:  cobra.ExactArgs(0),
+			RunE:  client.ListForwards,
+		})
+		forwardCmd.AddCommand(&cobra.Command{
+			Use:     "remove NAME",
+			Aliases: []string{"delete"},
+			Short:   "Remove a port forward",
+			Args:    cobra.ExactArgs(1),
+			RunE:    client.RemoveForward,
+		})
+		rootCmd.AddCommand(forwardCmd)
 	} else {
 		rootCmd.AddCommand(&cobra.Command{
 			Use:   "version",
@@ -262,6 +304,33 @@ func getRootCommand() *cobra.Command {
 		})
 	}
 
+	proxyCmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Reach a cluster through a local SOCKS5 and HTTP proxy, without root",
+		Long: "Reach a cluster through a local SOCKS5 and HTTP proxy. Unlike connect, this needs neither root " +
+			"nor the daemon, and doesn't change the network: only programs that use the proxy reach the " +
+			"cluster. It runs until it's interrupted.",
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			context, _ := cmd.Flags().GetString("context")
+			namespace, _ := cmd.Flags().GetString("namespace")
+			socksAddr, _ := cmd.Flags().GetString("socks")
+			httpAddr, _ := cmd.Flags().GetString("http")
+			return daemon.RunAsProxy(k8s.NewKubeInfo("", context, namespace), socksAddr, httpAddr)
+		},
+	}
+	_ = proxyCmd.Flags().StringP(
+		"context", "c", "",
+		"The Kubernetes context to use. Defaults to the current kubectl context.",
+	)
+	_ = proxyCmd.Flags().StringP(
+		"namespace", "n", "",
+		"The Kubernetes namespace to use. Defaults to kubectl's default for the context.",
+	)
+	_ = proxyCmd.Flags().String("socks", "localhost:1080", "The address for the SOCKS5 proxy to listen on.")
+	_ = proxyCmd.Flags().String("http", "localhost:3128", "The address for the HTTP proxy to listen on, or empty for none.")
+	rootCmd.AddCommand(proxyCmd)
+
 	loginCmd := &cobra.Command{
 		Use:   "login [flags] HOSTNAME",
 		Short: "Log in to the Ambassador Edge Policy Console",
@@ -278,6 +347,12 @@ func getRootCommand() *cobra.Command {
 	)
 	_ = loginCmd.Flags().Bool("url", false, "Just show the URL (don't launch a browser)")
 	_ = loginCmd.Flags().Bool("token", false, "Also display the login token")
+	_ = loginCmd.Flags().String(
+		"oidc-issuer", "",
+		"Log in to this OpenID Connect provider with the device flow, and use the ID token to talk to the cluster.",
+	)
+	_ = loginCmd.Flags().String("oidc-client-id", "", "The OAuth client ID for --oidc-issuer.")
+	_ = loginCmd.Flags().StringSlice("oidc-scope", nil, "Scopes to request from --oidc-issuer, besides openid.")
 	rootCmd.AddCommand(loginCmd)
 	licenseCmd := &cobra.Command{
 		Use:   "license [flags] LICENSE_KEY",
@@ -295,6 +370,30 @@ func getRootCommand() *cobra.Command {
 	)
 	rootCmd.AddCommand(licenseCmd)
 
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with Ambassador configuration",
+	}
+	configDiffCmd := &cobra.Command{
+		Use:   "diff [flags] FILE...",
+		Short: "Show how applying Ambassador resources would change the cluster",
+		Long: "Compare the Ambassador resources in YAML files (or - for standard input) with the ones in the " +
+			"cluster, and show what applying them would change, including the routes of Mappings. " +
+			"Exits with an error if anything would change.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: client.ConfigDiff,
+	}
+	_ = configDiffCmd.Flags().StringP(
+		"context", "c", "",
+		"The Kubernetes context to use. Defaults to the current kubectl context.",
+	)
+	_ = configDiffCmd.Flags().StringP(
+		"namespace", "n", "",
+		"The namespace of resources that don't give one. Defaults to kubectl's default for the context.",
+	)
+	configCmd.AddCommand(configDiffCmd)
+	rootCmd.AddCommand(configCmd)
+
 	installCmd := &cobra.Command{
 		Use:   "install",
 		Short: "Install the Ambassador Edge Stack in your cluster",
@@ -309,6 +408,19 @@ func getRootCommand() *cobra.Command {
 		"verbose", "v", false,
 		"Show all output. Defaults to sending most output to the logfile.",
 	)
+	_ = installCmd.Flags().String(
+		"chart", "",
+		"A local Helm chart directory or archive to install, instead of downloading the latest chart.",
+	)
+	_ = installCmd.Flags().String(
+		"image", "",
+		"The AES image to install, as REPOSITORY[:TAG][@DIGEST]. With a digest, the installer verifies that the pods run it.",
+	)
+	_ = installCmd.Flags().StringArray("set", nil, "Set a chart value (KEY=VALUE). Can be repeated.")
+	_ = installCmd.Flags().Bool(
+		"air-gapped", false,
+		"Make no network calls, except to the cluster. Needs --chart, and --image with a digest.",
+	)
 	rootCmd.AddCommand(installCmd)
 
 	upgradeCmd := &cobra.Command{
@@ -0,0 +1,295 @@
+package entrypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// The control plane can write an audit trail of configuration changes for compliance: one event
+// every time the watcher hands diagd a new snapshot, and one every time it rejects a resource.
+// Events are JSON, and go to the sink named by AMBASSADOR_AUDIT_SINK:
+//
+//   - a file path, or a file:// URL, gets one event per line;
+//   - an http:// or https:// URL gets each event POSTed to it; and
+//   - a kafka+http:// or kafka+https:// URL names a topic on a Kafka REST proxy, like
+//     kafka+http://kafka-rest:8082/audit.
+//
+// Events are sent in order from a single goroutine, so a slow sink never holds up the watcher. If
+// the sink falls too far behind, events are dropped and logged.
+
+type auditChange struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	// The field manager that last changed the resource, as recorded by the Kubernetes API
+	// server. This is often the client (kubectl, helm, argocd...) rather than a person.
+	Manager string `json:"manager,omitempty"`
+}
+
+type auditEvent struct {
+	Time         time.Time `json:"time"`
+	Type         string    `json:"type"`
+	AmbassadorID string    `json:"ambassador_id"`
+	ClusterID    string    `json:"cluster_id,omitempty"`
+
+	// snapshot.applied: the source of the change ("kubernetes" or "consul"), every Kubernetes
+	// resource that changed, counts of changes by kind and type, and how many resources are
+	// currently invalid.
+	Source   string                    `json:"source,omitempty"`
+	Changes  []auditChange             `json:"changes,omitempty"`
+	Summary  map[string]map[string]int `json:"summary,omitempty"`
+	Rejected *int                      `json:"rejected,omitempty"`
+
+	// resource.rejected: the resource and why it was rejected.
+	Resource *auditChange `json:"resource,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+type auditSink interface {
+	write(event []byte) error
+}
+
+// The auditLog struct sends audit events to a sink. A nil *auditLog is valid, and does nothing;
+// that's what you get when auditing is off.
+type auditLog struct {
+	sink   auditSink
+	events chan []byte
+
+	mu       sync.Mutex
+	managers map[string]string
+}
+
+var controlPlaneAudit = newAuditLog(GetAuditSink())
+
+func newAuditLog(sinkURL string) *auditLog {
+	if sinkURL == "" {
+		return nil
+	}
+
+	sink, err := newAuditSink(sinkURL)
+	if err != nil {
+		log.Printf("audit log disabled: %v", err)
+		return nil
+	}
+
+	a := &auditLog{sink: sink, events: make(chan []byte, 1024), managers: map[string]string{}}
+	go a.run()
+	return a
+}
+
+func newAuditSink(sinkURL string) (auditSink, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("bad AMBASSADOR_AUDIT_SINK %q: %w", sinkURL, err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	switch u.Scheme {
+	case "":
+		return &fileAuditSink{path: sinkURL}, nil
+	case "file":
+		return &fileAuditSink{path: u.Path}, nil
+	case "http", "https":
+		return &webhookAuditSink{url: sinkURL, contentType: "application/json", client: client}, nil
+	case "kafka+http", "kafka+https":
+		topic := strings.Trim(u.Path, "/")
+		if topic == "" || strings.Contains(topic, "/") {
+			return nil, fmt.Errorf("bad AMBASSADOR_AUDIT_SINK %q: the path must be a Kafka topic", sinkURL)
+		}
+		proxy := *u
+		proxy.Scheme = strings.TrimPrefix(u.Scheme, "kafka+")
+		proxy.Path = path.Join("/topics", topic)
+		return &kafkaAuditSink{webhookAuditSink{
+			url:         proxy.String(),
+			contentType: "application/vnd.kafka.json.v2+json",
+			client:      client,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("bad AMBASSADOR_AUDIT_SINK %q: unknown scheme %q", sinkURL, u.Scheme)
+	}
+}
+
+func (a *auditLog) run() {
+	for event := range a.events {
+		if err := a.sink.write(event); err != nil {
+			log.Printf("error writing audit event: %v", err)
+		}
+	}
+}
+
+func (a *auditLog) send(event auditEvent) {
+	event.Time = time.Now().UTC()
+	event.AmbassadorID = GetAmbassadorId()
+	event.ClusterID = os.Getenv("AMBASSADOR_CLUSTER_ID")
+
+	bytes, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("error encoding audit event: %v", err)
+		return
+	}
+
+	select {
+	case a.events <- bytes:
+	default:
+		log.Printf("audit sink is falling behind, dropping event: %s", bytes)
+	}
+}
+
+func auditKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s %s/%s", kind, namespace, name)
+}
+
+// The noteManager method remembers who last changed a resource, to go with its delta. It's
+// called for every resource the watcher sees added or updated.
+func (a *auditLog) noteManager(un *kates.Unstructured) {
+	if a == nil {
+		return
+	}
+
+	var manager string
+	var latest time.Time
+	for _, field := range un.GetManagedFields() {
+		if field.Time != nil && !field.Time.Time.Before(latest) {
+			latest = field.Time.Time
+			manager = field.Manager
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.managers[auditKey(un.GetKind(), un.GetNamespace(), un.GetName())] = manager
+}
+
+// The resourceRejected method records that a resource failed validation.
+func (a *auditLog) resourceRejected(un *kates.Unstructured, err error) {
+	if a == nil {
+		return
+	}
+
+	a.noteManager(un)
+	a.send(auditEvent{
+		Type:     "resource.rejected",
+		Resource: a.change(un.GetKind(), un.GetNamespace(), un.GetName(), "reject"),
+		Error:    err.Error(),
+	})
+}
+
+// The snapshotApplied method records a snapshot that's been handed to diagd.
+func (a *auditLog) snapshotApplied(source string, deltas []*kates.Delta, rejected int) {
+	if a == nil {
+		return
+	}
+
+	event := auditEvent{
+		Type:     "snapshot.applied",
+		Source:   source,
+		Summary:  map[string]map[string]int{},
+		Rejected: &rejected,
+	}
+
+	for _, delta := range deltas {
+		deltaType := "add"
+		switch delta.DeltaType {
+		case kates.ObjectUpdate:
+			deltaType = "update"
+		case kates.ObjectDelete:
+			deltaType = "delete"
+		}
+
+		event.Changes = append(event.Changes, *a.change(delta.Kind, delta.GetNamespace(), delta.GetName(), deltaType))
+
+		if event.Summary[delta.Kind] == nil {
+			event.Summary[delta.Kind] = map[string]int{}
+		}
+		event.Summary[delta.Kind][deltaType]++
+
+		if deltaType == "delete" {
+			a.mu.Lock()
+			delete(a.managers, auditKey(delta.Kind, delta.GetNamespace(), delta.GetName()))
+			a.mu.Unlock()
+		}
+	}
+
+	a.send(event)
+}
+
+func (a *auditLog) change(kind, namespace, name, changeType string) *auditChange {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &auditChange{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Type:      changeType,
+		Manager:   a.managers[auditKey(kind, namespace, name)],
+	}
+}
+
+type fileAuditSink struct {
+	path string
+}
+
+func (s *fileAuditSink) write(event []byte) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(event, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type webhookAuditSink struct {
+	url         string
+	contentType string
+	client      *http.Client
+}
+
+func (s *webhookAuditSink) write(event []byte) error {
+	return s.post(event)
+}
+
+func (s *webhookAuditSink) post(body []byte) error {
+	resp, err := s.client.Post(s.url, s.contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// The kafkaAuditSink struct produces events to a Kafka topic through the Confluent REST proxy's
+// v2 API, since we don't have a Kafka client among our dependencies.
+type kafkaAuditSink struct {
+	webhookAuditSink
+}
+
+func (s *kafkaAuditSink) write(event []byte) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []interface{}{
+			map[string]interface{}{"value": json.RawMessage(event)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return s.post(body)
+}
@@ -0,0 +1,132 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func auditMapping(name, manager string) *kates.Unstructured {
+	un := &kates.Unstructured{}
+	un.SetKind("Mapping")
+	un.SetName(name)
+	un.SetNamespace("default")
+	un.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "helm", Time: &metav1.Time{Time: time.Unix(1000, 0)}},
+		{Manager: manager, Time: &metav1.Time{Time: time.Unix(2000, 0)}},
+	})
+	return un
+}
+
+func TestAuditDisabled(t *testing.T) {
+	a := newAuditLog("")
+	assert.Nil(t, a)
+
+	// A nil auditLog is safe to use.
+	a.noteManager(auditMapping("quote", "kubectl"))
+	a.snapshotApplied("kubernetes", nil, 0)
+}
+
+func TestAuditBadSink(t *testing.T) {
+	assert.Nil(t, newAuditLog("ftp://example.com/audit"))
+	assert.Nil(t, newAuditLog("kafka+http://kafka-rest:8082/"))
+}
+
+func TestAuditFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "audit.jsonl")
+
+	a := newAuditLog("file://" + file)
+	require.NotNil(t, a)
+
+	a.noteManager(auditMapping("quote", "kubectl"))
+	a.resourceRejected(auditMapping("broken", "argocd"), errors.New("spec.prefix: Required value"))
+	a.snapshotApplied("kubernetes", []*kates.Delta{
+		{
+			TypeMeta:   kates.TypeMeta{Kind: "Mapping"},
+			ObjectMeta: kates.ObjectMeta{Name: "quote", Namespace: "default"},
+			DeltaType:  kates.ObjectUpdate,
+		},
+		{
+			TypeMeta:   kates.TypeMeta{Kind: "Service"},
+			ObjectMeta: kates.ObjectMeta{Name: "quote", Namespace: "default"},
+			DeltaType:  kates.ObjectDelete,
+		},
+	}, 1)
+
+	var lines []string
+	require.Eventually(t, func() bool {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return false
+		}
+		lines = strings.Split(strings.TrimSpace(string(contents)), "\n")
+		return len(lines) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var rejected auditEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rejected))
+	assert.Equal(t, "resource.rejected", rejected.Type)
+	assert.Equal(t, "spec.prefix: Required value", rejected.Error)
+	assert.Equal(t, &auditChange{Kind: "Mapping", Namespace: "default", Name: "broken", Type: "reject", Manager: "argocd"},
+		rejected.Resource)
+
+	var applied auditEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &applied))
+	assert.Equal(t, "snapshot.applied", applied.Type)
+	assert.Equal(t, "kubernetes", applied.Source)
+	assert.Equal(t, 1, *applied.Rejected)
+	assert.Equal(t, []auditChange{
+		{Kind: "Mapping", Namespace: "default", Name: "quote", Type: "update", Manager: "kubectl"},
+		{Kind: "Service", Namespace: "default", Name: "quote", Type: "delete"},
+	}, applied.Changes)
+	assert.Equal(t, map[string]map[string]int{"Mapping": {"update": 1}, "Service": {"delete": 1}}, applied.Summary)
+}
+
+func TestAuditKafka(t *testing.T) {
+	type request struct {
+		path        string
+		contentType string
+		body        []byte
+	}
+	requests := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests <- request{r.URL.Path, r.Header.Get("Content-Type"), body}
+	}))
+	defer srv.Close()
+
+	a := newAuditLog(strings.Replace(srv.URL, "http://", "kafka+http://", 1) + "/ambassador-audit")
+	require.NotNil(t, a)
+	a.snapshotApplied("consul", nil, 0)
+
+	req := <-requests
+	assert.Equal(t, "/topics/ambassador-audit", req.path)
+	assert.Equal(t, "application/vnd.kafka.json.v2+json", req.contentType)
+
+	var records struct {
+		Records []struct {
+			Value auditEvent `json:"value"`
+		} `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(req.body, &records))
+	require.Len(t, records.Records, 1)
+	assert.Equal(t, "snapshot.applied", records.Records[0].Value.Type)
+	assert.Equal(t, "consul", records.Records[0].Value.Source)
+}
@@ -0,0 +1,35 @@
+package entrypoint
+
+import (
+	"io/ioutil"
+	"log"
+
+	"github.com/datawire/ambassador/pkg/bootstrap"
+)
+
+// writeEnvoyBootstrap writes the bootstrap that Envoy runs with: the one diagd wrote, with the
+// overrides applied. Overrides that can't be applied are logged and left out, rather than keeping
+// Envoy from starting at all.
+func writeEnvoyBootstrap() {
+	base, err := ioutil.ReadFile(GetEnvoyBootstrapFile())
+	if err != nil {
+		panic(err)
+	}
+
+	result := base
+	overrides, err := bootstrap.Load(GetEnvoyBootstrapOverridesFile())
+	if err != nil {
+		log.Printf("ignoring bootstrap overrides: %v", err)
+	} else if overrides != nil {
+		if result, err = overrides.Apply(base); err != nil {
+			log.Printf("ignoring bootstrap overrides: %v", err)
+			result = base
+		} else {
+			log.Printf("applied bootstrap overrides from %s", GetEnvoyBootstrapOverridesFile())
+		}
+	}
+
+	if err := ioutil.WriteFile(GetEnvoyRunBootstrapFile(), result, 0644); err != nil {
+		panic(err)
+	}
+}
@@ -3,11 +3,14 @@ package entrypoint
 import (
 	"context"
 	"fmt"
+	"log"
 	"reflect"
 	"sync"
+	"time"
 
 	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
 	"github.com/datawire/ambassador/pkg/consulwatch"
+	"github.com/datawire/ambassador/pkg/kates"
 	"github.com/datawire/ambassador/pkg/watt"
 	consulapi "github.com/hashicorp/consul/api"
 )
@@ -34,7 +37,7 @@ func (s *AmbassadorInputs) ReconcileConsul(ctx context.Context, consul *consul)
 		}
 	}
 
-	consul.reconcile(s.ConsulResolvers, mappings)
+	consul.reconcile(s.ConsulResolvers, mappings, s.AllSecrets)
 }
 
 type consul struct {
@@ -48,10 +51,17 @@ type consul struct {
 	// Individual watches write to this when new endpoint data is available. It is always being read
 	// by the implementation, so writing will never block.
 	endpointsCh chan consulwatch.Endpoints
+	// Connect watches write to this when a new leaf certificate or new CA roots are available. Like
+	// endpointsCh, it is always being read.
+	connectCh chan consulwatch.Connect
 
-	// The mutex protects access to endpoints, keysForBootstrap, and bootstrapped.
+	// The mutex protects access to endpoints, updated, connect, tokenFiles, keysForBootstrap, and
+	// bootstrapped.
 	mutex            sync.Mutex
 	endpoints        map[string]consulwatch.Endpoints
+	updated          map[string]time.Time
+	connect          map[string]consulwatch.Connect
+	tokenFiles       map[string]time.Time
 	keysForBootstrap []string
 	bootstrapped     bool
 }
@@ -62,13 +72,20 @@ func newConsul(ctx context.Context, watcher Watcher) *consul {
 		resolvers:      make(map[string]*resolver),
 		coalescedDirty: make(chan struct{}),
 		endpointsCh:    make(chan consulwatch.Endpoints),
+		connectCh:      make(chan consulwatch.Connect),
 		endpoints:      make(map[string]consulwatch.Endpoints),
+		updated:        make(map[string]time.Time),
+		connect:        make(map[string]consulwatch.Connect),
 	}
 	go result.run(ctx)
 	return result
 }
 
 func (c *consul) run(ctx context.Context) {
+	// We poll the resolvers' token files, since a Vault agent may rewrite them at any time.
+	tokenTicker := time.NewTicker(tokenFileInterval)
+	defer tokenTicker.Stop()
+
 	dirty := false
 	for {
 		if dirty {
@@ -78,6 +95,10 @@ func (c *consul) run(ctx context.Context) {
 			case ep := <-c.endpointsCh:
 				c.updateEndpoints(ep)
 				dirty = true
+			case cn := <-c.connectCh:
+				c.updateConnect(cn)
+				dirty = true
+			case <-tokenTicker.C:
 			case <-ctx.Done():
 				c.cleanup()
 				return
@@ -87,6 +108,12 @@ func (c *consul) run(ctx context.Context) {
 			case ep := <-c.endpointsCh:
 				c.updateEndpoints(ep)
 				dirty = true
+			case cn := <-c.connectCh:
+				c.updateConnect(cn)
+				dirty = true
+			case <-tokenTicker.C:
+				// A changed token file gets picked up by the next reconcile.
+				dirty = c.tokenFilesChanged()
 			case <-ctx.Done():
 				c.cleanup()
 				return
@@ -98,7 +125,25 @@ func (c *consul) run(ctx context.Context) {
 func (c *consul) updateEndpoints(endpoints consulwatch.Endpoints) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	c.endpoints[endpoints.Service] = endpoints
+	c.endpoints[endpoints.Key()] = endpoints
+	c.updated[endpoints.Key()] = time.Now()
+}
+
+// updateConnect merges a new leaf certificate or new CA roots into what we have for the resolver.
+func (c *consul) updateConnect(connect consulwatch.Connect) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := fmt.Sprintf("%s.%s", connect.Resolver, connect.Namespace)
+	merged := c.connect[key]
+	merged.Resolver = connect.Resolver
+	merged.Namespace = connect.Namespace
+	if connect.Certificate != nil {
+		merged.Certificate = connect.Certificate
+	}
+	if connect.CARoots != nil {
+		merged.CARoots = connect.CARoots
+	}
+	c.connect[key] = merged
 }
 
 func (c *consul) changed() chan struct{} {
@@ -112,6 +157,37 @@ func (c *consul) update(snap *watt.ConsulSnapshot) {
 	for k, v := range c.endpoints {
 		snap.Endpoints[k] = v
 	}
+	snap.Connect = make(map[string]consulwatch.Connect, len(c.connect))
+	for k, v := range c.connect {
+		snap.Connect[k] = v
+	}
+	c.reportStatuses()
+}
+
+// reportStatuses updates the resolver metrics from the resolvers' watches. The mutex must be held,
+// and like reconcile, it must only be called from the watcher goroutine.
+func (c *consul) reportStatuses() {
+	statuses := make([]ResolverStatus, 0, len(c.resolvers))
+	for _, r := range c.resolvers {
+		status := ResolverStatus{
+			Name:       r.resolver.GetName(),
+			Namespace:  r.resolver.GetNamespace(),
+			Services:   len(r.started),
+			LastUpdate: time.Now(),
+		}
+		for key, started := range r.started {
+			updated := started
+			if t, ok := c.updated[key]; ok && t.After(started) {
+				updated = t
+			}
+			if updated.Before(status.LastUpdate) {
+				status.LastUpdate = updated
+			}
+			status.Endpoints += len(c.endpoints[key].Endpoints)
+		}
+		statuses = append(statuses, status)
+	}
+	metrics.setResolverStatuses("ConsulResolver", statuses)
 }
 
 func (c *consul) isBootstrapped() bool {
@@ -145,12 +221,12 @@ func (c *consul) cleanup() {
 		w.Stop()
 	}()*/
 
-	c.reconcile(nil, nil)
+	c.reconcile(nil, nil, nil)
 }
 
 // Start and stop consul service watches as needed in order to match the supplied set of resolvers
-// and mappings.
-func (c *consul) reconcile(resolvers []*amb.ConsulResolver, mappings []*amb.Mapping) {
+// and mappings. The secrets are where resolvers find their ACL tokens.
+func (c *consul) reconcile(resolvers []*amb.ConsulResolver, mappings []*amb.Mapping, secrets []*kates.Secret) {
 	// ==First we compute resolvers and their related mappings without actualy changing anything.==
 	resolversByName := make(map[string]*amb.ConsulResolver)
 	for _, cr := range resolvers {
@@ -184,20 +260,39 @@ func (c *consul) reconcile(resolvers []*amb.ConsulResolver, mappings []*amb.Mapp
 
 	// ==Now we implement the changes implied by resolversByName and mappingsByResolver.==
 
-	// First we (re)create any new or modified resolvers.
+	// First we (re)create any new or modified resolvers. A resolver whose ACL token has rotated
+	// is recreated too. Its endpoints stay in the snapshot until the new watches replace them, so
+	// nothing is dropped.
+	tokenFiles := make(map[string]time.Time)
 	for name, cr := range resolversByName {
 		oldr, ok := c.resolvers[name]
+		token, err := resolverToken(cr, secrets, tokenFiles)
+		if err != nil {
+			log.Printf("ConsulResolver %s: %v", name, err)
+			metrics.countResolverError("ConsulResolver", cr.GetName(), cr.GetNamespace())
+			if ok {
+				// Keep using the token that we have until we can read the new one.
+				token = oldr.token
+			}
+		}
 		// The resolver hasn't change so continue. Make sure we only compare the spec, since we
 		// don't want to delete/recreate resolvers on things like label changes.
-		if ok && reflect.DeepEqual(oldr.resolver.Spec, cr.Spec) {
+		if ok && reflect.DeepEqual(oldr.resolver.Spec, cr.Spec) && oldr.token == token {
 			continue
 		}
 		// It exists, but is different, so we delete/recreate i.
 		if ok {
 			oldr.deleted()
 		}
-		c.resolvers[name] = newResolver(cr)
+		r := newResolver(cr, token)
+		if cr.Spec.Connect != nil {
+			r.connect = c.watcher.WatchConnect(cr, token, c.connectCh)
+		}
+		c.resolvers[name] = r
 	}
+	c.mutex.Lock()
+	c.tokenFiles = tokenFiles
+	c.mutex.Unlock()
 
 	// Now we delete unneeded resolvers.
 	for name, resolver := range c.resolvers {
@@ -213,15 +308,20 @@ func (c *consul) reconcile(resolvers []*amb.ConsulResolver, mappings []*amb.Mapp
 		res := c.resolvers[rname]
 		res.reconcile(c.watcher, mappings, c.endpointsCh)
 	}
+	c.mutex.Lock()
+	c.reportStatuses()
+	c.mutex.Unlock()
 
 	// If this is the first time we are reconciling, we need to compute conditions for being
 	// bootstrapped.
 	if !c.firstReconcileHasHappened {
 		c.firstReconcileHasHappened = true
 		var keysForBootstrap []string
-		for _, mappings := range mappingsByResolver {
+		for rname, mappings := range mappingsByResolver {
 			for _, m := range mappings {
-				keysForBootstrap = append(keysForBootstrap, m.Spec.Service)
+				for _, dc := range datacenters(resolversByName[rname]) {
+					keysForBootstrap = append(keysForBootstrap, consulwatch.EndpointsKey(m.Spec.Service, dc))
+				}
 			}
 		}
 		c.mutex.Lock()
@@ -232,68 +332,114 @@ func (c *consul) reconcile(resolvers []*amb.ConsulResolver, mappings []*amb.Mapp
 
 type resolver struct {
 	resolver *amb.ConsulResolver
+	token    string
 	watches  map[string]Stopper
+	// started is when each watch started, for the resolver metrics.
+	started map[string]time.Time
+	connect Stopper
 }
 
-func newResolver(spec *amb.ConsulResolver) *resolver {
-	return &resolver{resolver: spec, watches: make(map[string]Stopper)}
+func newResolver(spec *amb.ConsulResolver, token string) *resolver {
+	return &resolver{resolver: spec, token: token, watches: make(map[string]Stopper),
+		started: make(map[string]time.Time)}
 }
 
 func (r *resolver) deleted() {
 	for _, w := range r.watches {
 		w.Stop()
 	}
+	if r.connect != nil {
+		r.connect.Stop()
+	}
 }
 
 func (r *resolver) reconcile(watcher Watcher, mappings []*amb.Mapping, endpoints chan consulwatch.Endpoints) {
-	servicesByName := make(map[string]bool)
+	// A service is watched in each of the resolver's datacenters.
+	watchesByKey := make(map[string]bool)
 	for _, m := range mappings {
 		// XXX: how to parse this?
 		svc := m.Spec.Service
-		servicesByName[svc] = true
-		w, ok := r.watches[svc]
-		if !ok {
-			w = watcher.Watch(r.resolver, m, endpoints)
-			r.watches[svc] = w
+		for _, dc := range datacenters(r.resolver) {
+			key := consulwatch.EndpointsKey(svc, dc)
+			watchesByKey[key] = true
+			w, ok := r.watches[key]
+			if !ok {
+				w = watcher.Watch(r.resolver, r.token, m, dc, endpoints)
+				r.watches[key] = w
+				r.started[key] = time.Now()
+			}
 		}
 	}
 
-	for name, w := range r.watches {
-		_, ok := servicesByName[name]
+	for key, w := range r.watches {
+		_, ok := watchesByKey[key]
 		if !ok {
 			w.Stop()
-			delete(r.watches, name)
+			delete(r.watches, key)
+			delete(r.started, key)
 		}
 	}
 }
 
+// datacenters returns the names of the datacenters that a resolver resolves
+// services from.
+func datacenters(cr *amb.ConsulResolver) []string {
+	if len(cr.Spec.Datacenters) == 0 {
+		return []string{cr.Spec.Datacenter}
+	}
+	names := make([]string, 0, len(cr.Spec.Datacenters))
+	for _, dc := range cr.Spec.Datacenters {
+		names = append(names, dc.Name)
+	}
+	return names
+}
+
 type Watcher interface {
-	Watch(resolver *amb.ConsulResolver, mapping *amb.Mapping, endpoints chan consulwatch.Endpoints) Stopper
+	Watch(resolver *amb.ConsulResolver, token string, mapping *amb.Mapping, datacenter string,
+		endpoints chan consulwatch.Endpoints) Stopper
+	WatchConnect(resolver *amb.ConsulResolver, token string, connect chan consulwatch.Connect) Stopper
 }
 
 type Stopper interface {
 	Stop()
 }
 
+// stoppers stops several watches as one.
+type stoppers []Stopper
+
+func (s stoppers) Stop() {
+	for _, w := range s {
+		w.Stop()
+	}
+}
+
 type consulWatcher struct{}
 
-func (cw *consulWatcher) Watch(resolver *amb.ConsulResolver, mapping *amb.Mapping,
+func (cw *consulWatcher) Watch(resolver *amb.ConsulResolver, token string, mapping *amb.Mapping, datacenter string,
 	endpointsCh chan consulwatch.Endpoints) Stopper {
 	// XXX: should this part be shared?
 	consulConfig := consulapi.DefaultConfig()
 	consulConfig.Address = resolver.Spec.Address
+	if token != "" {
+		consulConfig.Token = token
+	}
 	consul, err := consulapi.NewClient(consulConfig)
 	if err != nil {
 		panic(err)
 	}
 
-	// this part is per service
+	// this part is per service and datacenter
 	svc := mapping.Spec.Service
-	w, err := consulwatch.New(consul, resolver.Spec.Datacenter, svc, true)
+	w, err := consulwatch.NewFiltered(consul, datacenter, svc, resolver.Spec.Filter, true)
 	if err != nil {
 		panic(err)
 	}
+	name, namespace := resolver.GetName(), resolver.GetNamespace()
 	w.Watch(func(endpoints consulwatch.Endpoints, e error) {
+		if e != nil {
+			log.Printf("ConsulResolver %s.%s: %s: %v", name, namespace, svc, e)
+			metrics.countResolverError("ConsulResolver", name, namespace)
+		}
 		endpointsCh <- endpoints
 	})
 
@@ -306,3 +452,61 @@ func (cw *consulWatcher) Watch(resolver *amb.ConsulResolver, mapping *amb.Mappin
 
 	return w
 }
+
+// WatchConnect watches the resolver's Connect leaf certificate and the Connect CA roots. Both are blocking queries, so
+// a rotated certificate or a new CA root comes down the channel as soon as Consul has it.
+func (cw *consulWatcher) WatchConnect(resolver *amb.ConsulResolver, token string, connectCh chan consulwatch.Connect) Stopper {
+	consulConfig := consulapi.DefaultConfig()
+	consulConfig.Address = resolver.Spec.Address
+	if token != "" {
+		consulConfig.Token = token
+	}
+	consul, err := consulapi.NewClient(consulConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	service := resolver.Spec.Connect.Service
+	if service == "" {
+		service = "ambassador"
+	}
+	leaf, err := consulwatch.NewConnectLeafWatcher(consul, service)
+	if err != nil {
+		panic(err)
+	}
+	roots, err := consulwatch.NewConnectCARootsWatcher(consul)
+	if err != nil {
+		panic(err)
+	}
+
+	name, namespace := resolver.GetName(), resolver.GetNamespace()
+	leaf.Watch(func(cert *consulwatch.Certificate, e error) {
+		if e != nil {
+			log.Printf("ConsulResolver %s.%s: Connect leaf certificate: %v", name, namespace, e)
+			return
+		}
+		connectCh <- consulwatch.Connect{Resolver: name, Namespace: namespace, Certificate: cert}
+	})
+	roots.Watch(func(caRoots *consulwatch.CARoots, e error) {
+		if e != nil {
+			log.Printf("ConsulResolver %s.%s: Connect CA roots: %v", name, namespace, e)
+			return
+		}
+		connectCh <- consulwatch.Connect{Resolver: name, Namespace: namespace, CARoots: caRoots}
+	})
+
+	go func() {
+		err := leaf.Start(context.TODO())
+		if err != nil {
+			panic(err)
+		}
+	}()
+	go func() {
+		err := roots.Start(context.TODO())
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	return stoppers{leaf, roots}
+}
@@ -0,0 +1,393 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// catalogSyncInterval is how often we make sure that the Consul agent still has our services,
+// e.g. after it restarts.
+var catalogSyncInterval = 30 * time.Second
+
+// The Meta keys of the services that we register. The owner is how we find the services that a
+// resolver registered, so that we can deregister them once they aren't routed anymore.
+const (
+	catalogOwnerMeta    = "ambassador-resolver"
+	catalogHostnameMeta = "ambassador-hostname"
+	catalogPrefixesMeta = "ambassador-prefixes"
+
+	// Consul rejects Meta values that are longer than this.
+	catalogMetaValueMax = 512
+)
+
+func (s *AmbassadorInputs) ReconcileCatalogSync(cs *catalogSync) {
+	var mappings []*amb.Mapping
+	for _, a := range s.annotations {
+		m, ok := a.(*amb.Mapping)
+		if ok && include(m.Spec.AmbassadorID) {
+			mappings = append(mappings, m)
+		}
+	}
+	for _, m := range s.Mappings {
+		if include(m.Spec.AmbassadorID) {
+			mappings = append(mappings, m)
+		}
+	}
+
+	var hosts []*amb.Host
+	for _, h := range s.Hosts {
+		if h.Spec != nil && include(h.Spec.AmbassadorID) {
+			hosts = append(hosts, h)
+		}
+	}
+
+	var resolvers []*amb.ConsulResolver
+	for _, cr := range s.ConsulResolvers {
+		if include(cr.Spec.AmbassadorID) && cr.Spec.CatalogSync != nil {
+			resolvers = append(resolvers, cr)
+		}
+	}
+
+	cs.reconcile(resolvers, hosts, mappings, s.AllSecrets)
+}
+
+// A catalogAgent is the part of the Consul agent API that catalog sync uses. It is
+// *consulapi.Agent, except in the tests.
+type catalogAgent interface {
+	Services() (map[string]*consulapi.AgentService, error)
+	ServiceRegister(service *consulapi.AgentServiceRegistration) error
+	ServiceDeregister(serviceID string) error
+}
+
+// A catalogAgentFunc connects to the Consul agent of a resolver.
+type catalogAgentFunc func(resolver *amb.ConsulResolver, token string) (catalogAgent, error)
+
+// catalogSync registers the hostnames that Ambassador routes as Consul services, for each
+// ConsulResolver with a catalog_sync. Unlike the consul watches, a resolver doesn't need any
+// Mappings of its own to sync.
+type catalogSync struct {
+	ctx      context.Context
+	newAgent catalogAgentFunc
+	// syncers is only touched by reconcile, which is only called from the watcher goroutine.
+	syncers map[string]*catalogSyncer
+}
+
+func newCatalogSync(ctx context.Context, newAgent catalogAgentFunc) *catalogSync {
+	return &catalogSync{
+		ctx:      ctx,
+		newAgent: newAgent,
+		syncers:  make(map[string]*catalogSyncer),
+	}
+}
+
+// A catalogSyncer keeps the services of one resolver registered.
+type catalogSyncer struct {
+	resolver *amb.ConsulResolver
+	token    string
+	agent    catalogAgent
+	cancel   context.CancelFunc
+	// wanted holds the latest registrations that the syncer hasn't picked up yet.
+	wanted chan []*consulapi.AgentServiceRegistration
+	// sent is the last registrations that we handed to the syncer.
+	sent []*consulapi.AgentServiceRegistration
+}
+
+func (cs *catalogSync) reconcile(resolvers []*amb.ConsulResolver, hosts []*amb.Host, mappings []*amb.Mapping,
+	secrets []*kates.Secret) {
+	wanted := make(map[string]*amb.ConsulResolver)
+	for _, cr := range resolvers {
+		wanted[fmt.Sprintf("%s.%s", cr.GetName(), cr.GetNamespace())] = cr
+	}
+
+	// Stop the syncers of resolvers that are gone. Their services aren't routed by this resolver
+	// anymore, so they come out of Consul too.
+	for name, s := range cs.syncers {
+		if _, ok := wanted[name]; !ok {
+			cs.stop(name, s)
+		}
+	}
+
+	for name, cr := range wanted {
+		// The token files are polled by the consul watches, so there's no need to record them.
+		token, err := resolverToken(cr, secrets, make(map[string]time.Time))
+		if err != nil {
+			log.Printf("ConsulResolver %s: catalog sync: %v", name, err)
+			metrics.countResolverError("ConsulResolver", cr.GetName(), cr.GetNamespace())
+			if s, ok := cs.syncers[name]; ok {
+				// Keep using the token that we have until we can read the new one.
+				token = s.token
+			}
+		}
+
+		s, ok := cs.syncers[name]
+		if ok && s.resolver.Spec.Address != cr.Spec.Address {
+			// The services move to the new Consul.
+			cs.stop(name, s)
+			ok = false
+		} else if ok && (!reflect.DeepEqual(s.resolver.Spec, cr.Spec) || s.token != token) {
+			// A new token or catalog_sync starts over. The services stay registered; the new
+			// syncer registers them again and deregisters any that it doesn't want.
+			s.cancel()
+			delete(cs.syncers, name)
+			ok = false
+		}
+		if !ok {
+			if cr.Spec.CatalogSync.Address == "" {
+				log.Printf("ConsulResolver %s: catalog sync: no address", name)
+				metrics.countResolverError("ConsulResolver", cr.GetName(), cr.GetNamespace())
+				continue
+			}
+			agent, err := cs.newAgent(cr, token)
+			if err != nil {
+				log.Printf("ConsulResolver %s: catalog sync: %v", name, err)
+				metrics.countResolverError("ConsulResolver", cr.GetName(), cr.GetNamespace())
+				continue
+			}
+			ctx, cancel := context.WithCancel(cs.ctx)
+			s = &catalogSyncer{
+				resolver: cr,
+				token:    token,
+				agent:    agent,
+				cancel:   cancel,
+				wanted:   make(chan []*consulapi.AgentServiceRegistration, 1),
+			}
+			cs.syncers[name] = s
+			go s.run(ctx)
+		}
+
+		registrations := catalogRegistrations(cr, hosts, mappings)
+		if s.sent != nil && reflect.DeepEqual(s.sent, registrations) {
+			continue
+		}
+		s.sent = registrations
+		// Replace whatever the syncer hasn't picked up yet.
+		select {
+		case <-s.wanted:
+		default:
+		}
+		s.wanted <- registrations
+	}
+}
+
+// stop stops the syncer of a resolver, and deregisters its services in the background.
+func (cs *catalogSync) stop(name string, s *catalogSyncer) {
+	s.cancel()
+	delete(cs.syncers, name)
+	go func() {
+		if err := syncCatalog(s.agent, name, nil, false); err != nil {
+			log.Printf("ConsulResolver %s: catalog sync: %v", name, err)
+			metrics.countResolverError("ConsulResolver", s.resolver.GetName(), s.resolver.GetNamespace())
+		}
+	}()
+}
+
+func (s *catalogSyncer) run(ctx context.Context) {
+	ticker := time.NewTicker(catalogSyncInterval)
+	defer ticker.Stop()
+
+	name := fmt.Sprintf("%s.%s", s.resolver.GetName(), s.resolver.GetNamespace())
+	var registrations []*consulapi.AgentServiceRegistration
+	// The first sync registers everything, since the catalog_sync may have changed the health
+	// checks of services that are already registered.
+	force := true
+	for {
+		select {
+		case registrations = <-s.wanted:
+		case <-ticker.C:
+			if registrations == nil {
+				continue
+			}
+		case <-ctx.Done():
+			return
+		}
+		if err := syncCatalog(s.agent, name, registrations, force); err != nil {
+			log.Printf("ConsulResolver %s: catalog sync: %v", name, err)
+			metrics.countResolverError("ConsulResolver", s.resolver.GetName(), s.resolver.GetNamespace())
+			continue
+		}
+		force = false
+	}
+}
+
+// syncCatalog registers the services that the owner wants with the agent, unless the agent already
+// has them (or force is set), and deregisters the services of the owner that it doesn't want.
+func syncCatalog(agent catalogAgent, owner string, wanted []*consulapi.AgentServiceRegistration, force bool) error {
+	services, err := agent.Services()
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]bool, len(wanted))
+	for _, reg := range wanted {
+		ids[reg.ID] = true
+		if svc, ok := services[reg.ID]; ok && !force && catalogRegistered(svc, reg) {
+			continue
+		}
+		if err := agent.ServiceRegister(reg); err != nil {
+			return fmt.Errorf("registering %s: %w", reg.Name, err)
+		}
+	}
+
+	for id, svc := range services {
+		if svc.Meta[catalogOwnerMeta] == owner && !ids[id] {
+			if err := agent.ServiceDeregister(id); err != nil {
+				return fmt.Errorf("deregistering %s: %w", svc.Service, err)
+			}
+		}
+	}
+	return nil
+}
+
+// catalogRegistered returns whether the agent has a service just like a registration.
+func catalogRegistered(svc *consulapi.AgentService, reg *consulapi.AgentServiceRegistration) bool {
+	return svc.Service == reg.Name && svc.Address == reg.Address && svc.Port == reg.Port &&
+		len(svc.Tags) == len(reg.Tags) && (len(reg.Tags) == 0 || reflect.DeepEqual(svc.Tags, reg.Tags)) &&
+		reflect.DeepEqual(svc.Meta, reg.Meta)
+}
+
+// catalogRegistrations returns a Consul service for each hostname that a resolver's Ambassador
+// routes: the hostnames of the Hosts, and the hosts of the Mappings. A Mapping without a host is
+// routed on every hostname. Wildcard hostnames and host regexes can't be looked up in Consul,
+// so they are left out.
+func catalogRegistrations(cr *amb.ConsulResolver, hosts []*amb.Host, mappings []*amb.Mapping) []*consulapi.AgentServiceRegistration {
+	prefixes := make(map[string]map[string]bool)
+	addPrefix := func(hostname, prefix string) {
+		if prefixes[hostname] == nil {
+			prefixes[hostname] = make(map[string]bool)
+		}
+		if prefix != "" {
+			prefixes[hostname][prefix] = true
+		}
+	}
+
+	for _, h := range hosts {
+		if hostname := catalogHostname(h.Spec.Hostname); hostname != "" {
+			addPrefix(hostname, "")
+		}
+	}
+
+	var everywhere []string
+	for _, m := range mappings {
+		if m.Spec.HostRegex {
+			continue
+		}
+		if m.Spec.Host == "" {
+			everywhere = append(everywhere, m.Spec.Prefix)
+			continue
+		}
+		if hostname := catalogHostname(m.Spec.Host); hostname != "" {
+			addPrefix(hostname, m.Spec.Prefix)
+		}
+	}
+	for hostname := range prefixes {
+		for _, prefix := range everywhere {
+			addPrefix(hostname, prefix)
+		}
+	}
+
+	spec := cr.Spec.CatalogSync
+	port := spec.Port
+	if port == 0 {
+		port = 443
+	}
+	checkURL := spec.CheckURL
+	if checkURL == "" {
+		checkURL = fmt.Sprintf("https://%s:%d/ambassador/v0/check_ready", spec.Address, port)
+	}
+	interval := spec.CheckInterval
+	if interval == "" {
+		interval = "10s"
+	}
+	owner := fmt.Sprintf("%s.%s", cr.GetName(), cr.GetNamespace())
+
+	hostnames := make([]string, 0, len(prefixes))
+	for hostname, routes := range prefixes {
+		// A hostname that routes nothing isn't worth finding.
+		if len(routes) > 0 {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	sort.Strings(hostnames)
+
+	registrations := make([]*consulapi.AgentServiceRegistration, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		routes := make([]string, 0, len(prefixes[hostname]))
+		for prefix := range prefixes[hostname] {
+			routes = append(routes, prefix)
+		}
+		sort.Strings(routes)
+
+		registrations = append(registrations, &consulapi.AgentServiceRegistration{
+			ID:      fmt.Sprintf("ambassador-%s-%s-%s", cr.GetName(), cr.GetNamespace(), hostname),
+			Name:    strings.ReplaceAll(hostname, ".", "-"),
+			Address: spec.Address,
+			Port:    port,
+			Tags:    spec.Tags,
+			Meta: map[string]string{
+				catalogOwnerMeta:    owner,
+				catalogHostnameMeta: hostname,
+				catalogPrefixesMeta: catalogPrefixes(routes),
+			},
+			Check: &consulapi.AgentServiceCheck{
+				Name:          fmt.Sprintf("Ambassador %s", hostname),
+				HTTP:          checkURL,
+				Header:        map[string][]string{"Host": {hostname}},
+				Interval:      interval,
+				Timeout:       "5s",
+				TLSSkipVerify: true,
+				// Clean up after an Ambassador that is gone for good.
+				DeregisterCriticalServiceAfter: "30m",
+			},
+		})
+	}
+	return registrations
+}
+
+// catalogHostname returns the hostname of a Host or a Mapping host, without its port, or "" if it
+// is a wildcard.
+func catalogHostname(host string) string {
+	hostname := dnsHostname(host)
+	if strings.Contains(hostname, "*") {
+		return ""
+	}
+	return hostname
+}
+
+// catalogPrefixes joins as many prefixes as fit in a Meta value.
+func catalogPrefixes(prefixes []string) string {
+	result := ""
+	for _, prefix := range prefixes {
+		next := prefix
+		if result != "" {
+			next = result + "," + prefix
+		}
+		if len(next) > catalogMetaValueMax {
+			break
+		}
+		result = next
+	}
+	return result
+}
+
+// consulCatalogAgent is the catalogAgentFunc that talks to Consul.
+func consulCatalogAgent(resolver *amb.ConsulResolver, token string) (catalogAgent, error) {
+	consulConfig := consulapi.DefaultConfig()
+	consulConfig.Address = resolver.Spec.Address
+	if token != "" {
+		consulConfig.Token = token
+	}
+	consul, err := consulapi.NewClient(consulConfig)
+	if err != nil {
+		return nil, err
+	}
+	return consul.Agent(), nil
+}
@@ -0,0 +1,152 @@
+package entrypoint
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent is a catalogAgent that keeps its services in memory.
+type fakeAgent struct {
+	mutex     sync.Mutex
+	services  map[string]*consulapi.AgentService
+	registers int
+}
+
+func newFakeAgent() *fakeAgent {
+	return &fakeAgent{services: make(map[string]*consulapi.AgentService)}
+}
+
+func (a *fakeAgent) Services() (map[string]*consulapi.AgentService, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	result := make(map[string]*consulapi.AgentService, len(a.services))
+	for id, svc := range a.services {
+		result[id] = svc
+	}
+	return result, nil
+}
+
+func (a *fakeAgent) ServiceRegister(reg *consulapi.AgentServiceRegistration) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.registers++
+	a.services[reg.ID] = &consulapi.AgentService{ID: reg.ID, Service: reg.Name, Address: reg.Address,
+		Port: reg.Port, Tags: reg.Tags, Meta: reg.Meta}
+	return nil
+}
+
+func (a *fakeAgent) ServiceDeregister(id string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.services, id)
+	return nil
+}
+
+func (a *fakeAgent) ids() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	var ids []string
+	for id := range a.services {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func catalogResolver() *amb.ConsulResolver {
+	cr := &amb.ConsulResolver{Spec: amb.ConsulResolverSpec{
+		Address:     "consul:8500",
+		CatalogSync: &amb.ConsulCatalogSync{Address: "203.0.113.10", Tags: []string{"edge"}},
+	}}
+	cr.SetName("consul")
+	cr.SetNamespace("default")
+	return cr
+}
+
+func TestCatalogRegistrations(t *testing.T) {
+	host := &amb.Host{Spec: &amb.HostSpec{Hostname: "api.example.com"}}
+	wildcard := &amb.Host{Spec: &amb.HostSpec{Hostname: "*.example.com"}}
+	mappings := []*amb.Mapping{
+		{Spec: amb.MappingSpec{Prefix: "/quote/"}},
+		{Spec: amb.MappingSpec{Prefix: "/admin/", Host: "Admin.Example.com:443"}},
+		{Spec: amb.MappingSpec{Prefix: "/regex/", Host: ".*", HostRegex: true}},
+	}
+
+	regs := catalogRegistrations(catalogResolver(), []*amb.Host{host, wildcard}, mappings)
+	require.Len(t, regs, 2)
+
+	admin := regs[0]
+	assert.Equal(t, "ambassador-consul-default-admin.example.com", admin.ID)
+	assert.Equal(t, "admin-example-com", admin.Name)
+	assert.Equal(t, "203.0.113.10", admin.Address)
+	assert.Equal(t, 443, admin.Port)
+	assert.Equal(t, []string{"edge"}, admin.Tags)
+	assert.Equal(t, map[string]string{
+		"ambassador-resolver": "consul.default",
+		"ambassador-hostname": "admin.example.com",
+		"ambassador-prefixes": "/admin/,/quote/",
+	}, admin.Meta)
+	assert.Equal(t, "https://203.0.113.10:443/ambassador/v0/check_ready", admin.Check.HTTP)
+	assert.Equal(t, []string{"admin.example.com"}, admin.Check.Header["Host"])
+	assert.Equal(t, "10s", admin.Check.Interval)
+
+	assert.Equal(t, "api-example-com", regs[1].Name)
+	assert.Equal(t, "/quote/", regs[1].Meta["ambassador-prefixes"])
+}
+
+func TestCatalogPrefixes(t *testing.T) {
+	assert.Equal(t, "/a/,/b/", catalogPrefixes([]string{"/a/", "/b/"}))
+
+	long := make([]string, 200)
+	for i := range long {
+		long[i] = "/prefix/"
+	}
+	assert.True(t, len(catalogPrefixes(long)) <= catalogMetaValueMax)
+}
+
+func TestSyncCatalog(t *testing.T) {
+	agent := newFakeAgent()
+	// Somebody else's service is left alone.
+	agent.services["web"] = &consulapi.AgentService{ID: "web", Service: "web"}
+
+	cr := catalogResolver()
+	mappings := []*amb.Mapping{{Spec: amb.MappingSpec{Prefix: "/", Host: "api.example.com"}}}
+	regs := catalogRegistrations(cr, nil, mappings)
+	require.NoError(t, syncCatalog(agent, "consul.default", regs, false))
+	assert.ElementsMatch(t, []string{"web", "ambassador-consul-default-api.example.com"}, agent.ids())
+	assert.Equal(t, 1, agent.registers)
+
+	// Services that the agent already has aren't registered again, unless we force it.
+	require.NoError(t, syncCatalog(agent, "consul.default", regs, false))
+	assert.Equal(t, 1, agent.registers)
+	require.NoError(t, syncCatalog(agent, "consul.default", regs, true))
+	assert.Equal(t, 2, agent.registers)
+
+	require.NoError(t, syncCatalog(agent, "consul.default", nil, false))
+	assert.Equal(t, []string{"web"}, agent.ids())
+}
+
+func TestReconcileCatalogSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newFakeAgent()
+	cs := newCatalogSync(ctx, func(*amb.ConsulResolver, string) (catalogAgent, error) {
+		return agent, nil
+	})
+
+	cr := catalogResolver()
+	mapping := &amb.Mapping{Spec: amb.MappingSpec{Prefix: "/", Host: "api.example.com"}}
+	cs.reconcile([]*amb.ConsulResolver{cr}, nil, []*amb.Mapping{mapping}, nil)
+	assert.Eventually(t, func() bool { return len(agent.ids()) == 1 }, 10*time.Second, 10*time.Millisecond)
+
+	cs.reconcile(nil, nil, []*amb.Mapping{mapping}, nil)
+	assert.Empty(t, cs.syncers)
+	assert.Eventually(t, func() bool { return len(agent.ids()) == 0 }, 10*time.Second, 10*time.Millisecond)
+}
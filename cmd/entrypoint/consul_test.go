@@ -8,6 +8,7 @@ import (
 	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
 	"github.com/datawire/ambassador/pkg/consulwatch"
 	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/watt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -55,8 +56,8 @@ secret: consultest-client-cert-secret
 
 func TestReconcile(t *testing.T) {
 	resolvers, mappings, c, tw := setup(t)
-	c.reconcile(resolvers, mappings)
-	tw.Assert("consultest-resolver.default:consultest-consul-service:watch")
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert("consultest-resolver.default:consultest-consul-service:dc1:watch")
 	extra := &amb.Mapping{
 		Spec: amb.MappingSpec{
 			Service:  "foo",
@@ -64,32 +65,104 @@ func TestReconcile(t *testing.T) {
 		},
 	}
 	extra.SetNamespace("default")
-	c.reconcile(resolvers, append(mappings, extra))
+	c.reconcile(resolvers, append(mappings, extra), nil)
 	tw.Assert(
-		"consultest-resolver.default:foo:watch",
+		"consultest-resolver.default:foo:dc1:watch",
 	)
-	c.reconcile(resolvers, nil)
+	c.reconcile(resolvers, nil, nil)
 	tw.Assert(
-		"consultest-resolver.default:consultest-consul-service:stop",
-		"consultest-resolver.default:foo:stop",
+		"consultest-resolver.default:consultest-consul-service:dc1:stop",
+		"consultest-resolver.default:foo:dc1:stop",
 	)
 }
 
+func TestReconcileDatacenters(t *testing.T) {
+	resolvers, mappings, c, tw := setup(t)
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert("consultest-resolver.default:consultest-consul-service:dc1:watch")
+
+	dr := resolvers[0].DeepCopy()
+	dr.Spec.Datacenters = []amb.ConsulDatacenter{{Name: "dc1", Weight: 90}, {Name: "dc2", Weight: 10}}
+	c.reconcile([]*amb.ConsulResolver{dr}, mappings, nil)
+	tw.Assert(
+		"consultest-resolver.default:consultest-consul-service:dc1:stop",
+		"consultest-resolver.default:consultest-consul-service:dc1:watch",
+		"consultest-resolver.default:consultest-consul-service:dc2:watch",
+	)
+
+	c.reconcile(nil, nil, nil)
+	tw.Assert(
+		"consultest-resolver.default:consultest-consul-service:dc1:stop",
+		"consultest-resolver.default:consultest-consul-service:dc2:stop",
+	)
+}
+
+func TestBootstrapDatacenters(t *testing.T) {
+	resolvers, mappings, c, _ := setup(t)
+	resolvers[0].Spec.Datacenters = []amb.ConsulDatacenter{{Name: "dc1"}, {Name: "dc2"}}
+	c.reconcile(resolvers, mappings, nil)
+	c.endpoints["consultest-consul-service-dc1"] = consulwatch.Endpoints{}
+	assert.False(t, c.isBootstrapped())
+	c.endpoints["consultest-consul-service-dc2"] = consulwatch.Endpoints{}
+	assert.True(t, c.isBootstrapped())
+}
+
+func TestReconcileConnect(t *testing.T) {
+	resolvers, mappings, c, tw := setup(t)
+	resolvers[0].Spec.Connect = &amb.ConsulConnect{}
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert(
+		"consultest-resolver.default:connect:watch",
+		"consultest-resolver.default:consultest-consul-service:dc1:watch",
+	)
+	c.reconcile(nil, nil, nil)
+	tw.Assert(
+		"consultest-resolver.default:connect:stop",
+		"consultest-resolver.default:consultest-consul-service:dc1:stop",
+	)
+}
+
+func TestUpdateConnect(t *testing.T) {
+	_, _, c, _ := setup(t)
+	cert := &consulwatch.Certificate{SerialNumber: "1"}
+	roots := &consulwatch.CARoots{ActiveRootID: "root"}
+	c.updateConnect(consulwatch.Connect{Resolver: "consultest-resolver", Namespace: "default", Certificate: cert})
+	c.updateConnect(consulwatch.Connect{Resolver: "consultest-resolver", Namespace: "default", CARoots: roots})
+
+	snap := &watt.ConsulSnapshot{}
+	c.update(snap)
+	assert.Equal(t, map[string]consulwatch.Connect{
+		"consultest-resolver.default": {
+			Resolver:    "consultest-resolver",
+			Namespace:   "default",
+			Certificate: cert,
+			CARoots:     roots,
+		},
+	}, snap.Connect)
+
+	// A rotated certificate replaces the old one, and keeps the roots.
+	rotated := &consulwatch.Certificate{SerialNumber: "2"}
+	c.updateConnect(consulwatch.Connect{Resolver: "consultest-resolver", Namespace: "default", Certificate: rotated})
+	c.update(snap)
+	assert.Equal(t, rotated, snap.Connect["consultest-resolver.default"].Certificate)
+	assert.Equal(t, roots, snap.Connect["consultest-resolver.default"].CARoots)
+}
+
 func TestCleanup(t *testing.T) {
 	resolvers, mappings, c, tw := setup(t)
-	c.reconcile(resolvers, mappings)
-	tw.Assert("consultest-resolver.default:consultest-consul-service:watch")
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert("consultest-resolver.default:consultest-consul-service:dc1:watch")
 	c.cleanup()
-	tw.Assert("consultest-resolver.default:consultest-consul-service:stop")
+	tw.Assert("consultest-resolver.default:consultest-consul-service:dc1:stop")
 }
 
 func TestBootstrap(t *testing.T) {
 	resolvers, mappings, c, _ := setup(t)
 	assert.False(t, c.isBootstrapped())
-	c.reconcile(resolvers, mappings)
+	c.reconcile(resolvers, mappings, nil)
 	assert.False(t, c.isBootstrapped())
 	// XXX: break this (maybe use a chan to replace uncoalesced dirties and passing con around?)
-	c.endpoints["consultest-consul-service"] = consulwatch.Endpoints{}
+	c.endpoints["consultest-consul-service-dc1"] = consulwatch.Endpoints{}
 	assert.True(t, c.isBootstrapped())
 }
 
@@ -143,19 +216,36 @@ func (tw *testWatcher) Assert(events ...string) {
 	tw.events = make(map[string]bool)
 }
 
-func (tw *testWatcher) Watch(resolver *amb.ConsulResolver, mapping *amb.Mapping, _ chan consulwatch.Endpoints) Stopper {
+func (tw *testWatcher) Watch(resolver *amb.ConsulResolver, _ string, mapping *amb.Mapping, datacenter string,
+	_ chan consulwatch.Endpoints) Stopper {
 	rname := fmt.Sprintf("%s.%s", resolver.GetName(), resolver.GetNamespace())
 	svc := mapping.Spec.Service
-	tw.Logf("%s:%s:watch", rname, svc)
-	return &testStopper{watcher: tw, resolver: rname, service: svc}
+	tw.Logf("%s:%s:%s:watch", rname, svc, datacenter)
+	return &testStopper{watcher: tw, resolver: rname, service: svc, datacenter: datacenter}
 }
 
-type testStopper struct {
+func (tw *testWatcher) WatchConnect(resolver *amb.ConsulResolver, _ string, _ chan consulwatch.Connect) Stopper {
+	rname := fmt.Sprintf("%s.%s", resolver.GetName(), resolver.GetNamespace())
+	tw.Logf("%s:connect:watch", rname)
+	return &testConnectStopper{watcher: tw, resolver: rname}
+}
+
+type testConnectStopper struct {
 	watcher  *testWatcher
 	resolver string
-	service  string
+}
+
+func (ts *testConnectStopper) Stop() {
+	ts.watcher.Logf("%s:connect:stop", ts.resolver)
+}
+
+type testStopper struct {
+	watcher    *testWatcher
+	resolver   string
+	service    string
+	datacenter string
 }
 
 func (ts *testStopper) Stop() {
-	ts.watcher.Logf("%s:%s:stop", ts.resolver, ts.service)
+	ts.watcher.Logf("%s:%s:%s:stop", ts.resolver, ts.service, ts.datacenter)
 }
@@ -0,0 +1,65 @@
+package entrypoint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// tokenFileInterval is how often we check the resolvers' token files for changes.
+var tokenFileInterval = 10 * time.Second
+
+// resolverToken returns the Consul ACL token of a resolver, from its token Secret or its token file. A resolver with
+// neither uses the token from the environment, if any, so that is an empty token and no error. The modification time
+// of a token file is recorded in tokenFiles, so that we notice when it changes.
+func resolverToken(cr *amb.ConsulResolver, secrets []*kates.Secret, tokenFiles map[string]time.Time) (string, error) {
+	switch {
+	case cr.Spec.TokenSecret != "":
+		for _, secret := range secrets {
+			if secret.GetName() != cr.Spec.TokenSecret || secret.GetNamespace() != cr.GetNamespace() {
+				continue
+			}
+			token, ok := secret.Data["token"]
+			if !ok {
+				return "", fmt.Errorf("secret %s.%s has no token", secret.GetName(), secret.GetNamespace())
+			}
+			return strings.TrimSpace(string(token)), nil
+		}
+		return "", fmt.Errorf("secret %s.%s not found", cr.Spec.TokenSecret, cr.GetNamespace())
+	case cr.Spec.TokenFile != "":
+		info, err := os.Stat(cr.Spec.TokenFile)
+		if err != nil {
+			// Record the file as missing, so that we notice when it shows up.
+			tokenFiles[cr.Spec.TokenFile] = time.Time{}
+			return "", err
+		}
+		tokenFiles[cr.Spec.TokenFile] = info.ModTime()
+		token, err := ioutil.ReadFile(cr.Spec.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+	return "", nil
+}
+
+// tokenFilesChanged returns whether any of the token files that the last reconcile read has changed since.
+func (c *consul) tokenFilesChanged() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for path, modTime := range c.tokenFiles {
+		var newModTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			newModTime = info.ModTime()
+		}
+		if !newModTime.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
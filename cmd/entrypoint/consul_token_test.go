@@ -0,0 +1,87 @@
+package entrypoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/consulwatch"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func tokenSecret(token string) *kates.Secret {
+	secret := &kates.Secret{Data: map[string][]byte{"token": []byte(token + "\n")}}
+	secret.SetName("consul-token")
+	secret.SetNamespace("default")
+	return secret
+}
+
+func TestReconcileTokenSecret(t *testing.T) {
+	resolvers, mappings, c, tw := setup(t)
+	resolvers[0].Spec.TokenSecret = "consul-token"
+
+	c.reconcile(resolvers, mappings, []*kates.Secret{tokenSecret("one")})
+	tw.Assert("consultest-resolver.default:consultest-consul-service:dc1:watch")
+	assert.Equal(t, "one", c.resolvers["consultest-resolver.default"].token)
+
+	// Nothing changed.
+	c.reconcile(resolvers, mappings, []*kates.Secret{tokenSecret("one")})
+	tw.Assert()
+
+	// The token rotated, so the watches restart with the new one.
+	c.endpoints["consultest-consul-service-dc1"] = consulwatch.Endpoints{Service: "consultest-consul-service", Id: "dc1"}
+	c.reconcile(resolvers, mappings, []*kates.Secret{tokenSecret("two")})
+	tw.Assert(
+		"consultest-resolver.default:consultest-consul-service:dc1:stop",
+		"consultest-resolver.default:consultest-consul-service:dc1:watch",
+	)
+	assert.Equal(t, "two", c.resolvers["consultest-resolver.default"].token)
+	assert.Contains(t, c.endpoints, "consultest-consul-service-dc1")
+
+	// The Secret went away; keep the token that we have.
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert()
+	assert.Equal(t, "two", c.resolvers["consultest-resolver.default"].token)
+}
+
+func TestReconcileTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "consul-token")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("one"), 0600))
+
+	resolvers, mappings, c, tw := setup(t)
+	resolvers[0].Spec.TokenFile = path
+
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert("consultest-resolver.default:consultest-consul-service:dc1:watch")
+	assert.Equal(t, "one", c.resolvers["consultest-resolver.default"].token)
+	assert.False(t, c.tokenFilesChanged())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("two"), 0600))
+	// Make sure that the modification time moves, even on coarse filesystems.
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.True(t, c.tokenFilesChanged())
+
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert(
+		"consultest-resolver.default:consultest-consul-service:dc1:stop",
+		"consultest-resolver.default:consultest-consul-service:dc1:watch",
+	)
+	assert.Equal(t, "two", c.resolvers["consultest-resolver.default"].token)
+	assert.False(t, c.tokenFilesChanged())
+
+	// The Vault agent removed the file; keep the token that we have.
+	require.NoError(t, os.Remove(path))
+	assert.True(t, c.tokenFilesChanged())
+	c.reconcile(resolvers, mappings, nil)
+	tw.Assert()
+	assert.Equal(t, "two", c.resolvers["consultest-resolver.default"].token)
+}
@@ -0,0 +1,143 @@
+package entrypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Version is Ambassador's version, for crash bundles.
+var Version = "(unknown version)"
+
+// When a goroutine in the entrypoint's Group panics, the Group writes a crash bundle before the
+// rest of the entrypoint shuts down: the panic and its stack, the versions, and what the last
+// snapshot looked like. Bundles go to GetCrashDir, one JSON file each, and are POSTed to
+// GetCrashWebhook too if it's set, so that a panic that only happens now and then leaves enough
+// behind to reproduce it.
+//
+// Bundles are meant to be attached to bug reports, so they never have the contents of any
+// resource, only counts, and anything in the panic that looks like a credential is redacted.
+
+type crashBundle struct {
+	Time      time.Time         `json:"time"`
+	Goroutine string            `json:"goroutine"`
+	Panic     string            `json:"panic"`
+	Stack     string            `json:"stack"`
+	Versions  map[string]string `json:"versions"`
+	Snapshot  *snapshotMetadata `json:"snapshot,omitempty"`
+	Reconfig  *reconfigReport   `json:"reconfig,omitempty"`
+}
+
+// The snapshotMetadata struct is what a crash bundle says about the last snapshot.
+type snapshotMetadata struct {
+	Time      time.Time      `json:"time"`
+	Source    string         `json:"source"`
+	Deltas    int            `json:"deltas"`
+	Invalid   int            `json:"invalid"`
+	Resources map[string]int `json:"resources"`
+}
+
+var lastSnapshot atomic.Value // *snapshotMetadata
+
+// noteSnapshot remembers the metadata of a snapshot from source for crash bundles.
+func noteSnapshot(sn *Snapshot, source string) {
+	lastSnapshot.Store(&snapshotMetadata{
+		Time:      time.Now(),
+		Source:    source,
+		Deltas:    len(sn.Deltas),
+		Invalid:   len(sn.Invalid),
+		Resources: sn.Kubernetes.Counts(),
+	})
+}
+
+// crashRedactions match credentials in a panic message; the first group of each is kept.
+var crashRedactions = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`),
+	regexp.MustCompile(`(?i)(authorization["']?\s*[:=]\s*["']?(?:\w+\s+)?)[^\s"',&]+`),
+	regexp.MustCompile(`(?i)((?:token|password|passwd|secret|api[_-]?key)["']?\s*[:=]\s*["']?)[^\s"',&]+`),
+	regexp.MustCompile(`(://[^/:@\s]+:)[^/@\s]+(@)`),
+}
+
+func redactCrash(s string) string {
+	for _, re := range crashRedactions {
+		s = re.ReplaceAllString(s, "${1}REDACTED$2")
+	}
+	return s
+}
+
+func newCrashBundle(goroutine string, value interface{}, stack []byte) *crashBundle {
+	bundle := &crashBundle{
+		Time:      time.Now().UTC(),
+		Goroutine: goroutine,
+		Panic:     redactCrash(fmt.Sprint(value)),
+		Stack:     string(stack),
+		Versions: map[string]string{
+			"ambassador": Version,
+			"go":         runtime.Version(),
+		},
+	}
+	if sn, ok := lastSnapshot.Load().(*snapshotMetadata); ok {
+		bundle.Snapshot = sn
+	}
+	if reports := controlPlaneReports.list(); len(reports) > 0 {
+		bundle.Reconfig = &reports[0]
+	}
+	return bundle
+}
+
+// reportCrash writes a crash bundle for the panic of a goroutine, and POSTs it to the crash
+// webhook if there is one. It logs its own errors, since the entrypoint is on its way out.
+func reportCrash(goroutine string, value interface{}, stack []byte) {
+	bundle := newCrashBundle(goroutine, value, stack)
+	body, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		log.Printf("crash bundle: %v", err)
+		return
+	}
+
+	file, err := writeCrashBundle(GetCrashDir(), bundle, body)
+	if err != nil {
+		log.Printf("crash bundle: %v", err)
+	} else {
+		log.Printf("crash bundle written to %s", file)
+	}
+
+	if webhook := GetCrashWebhook(); webhook != "" {
+		if err := postCrashBundle(webhook, body); err != nil {
+			log.Printf("crash bundle: %v", err)
+		}
+	}
+}
+
+func writeCrashBundle(dir string, bundle *crashBundle, body []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("crash-%s-%s.json", bundle.Time.Format("20060102T150405.000Z"),
+		strings.ReplaceAll(bundle.Goroutine, "/", "_"))
+	file := path.Join(dir, name)
+	return file, ioutil.WriteFile(file, body, 0600)
+}
+
+func postCrashBundle(webhook string, body []byte) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sending to %s: %w", webhook, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sending to %s: %s", webhook, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package entrypoint
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+)
+
+// The debug server's endpoints, which AMBASSADOR_DEBUG_ALLOW turns on and off:
+//   - pprof: the net/http/pprof profiles, at /debug/pprof/
+//   - goroutines: the stack of every goroutine, as text, at /debug/goroutines
+//   - timers: the control plane's timers, as JSON, at /debug/timers
+//   - memory: the memory watchdog's status, as JSON, at /debug/memory
+//   - loglevel: the log levels, which a PUT or DELETE changes, at /debug/loglevel
+//   - hot-restart: Envoy's restart epochs, and a hot restart on POST, at /debug/hot-restart
+var debugEndpoints = []string{"pprof", "goroutines", "timers", "memory", "loglevel", "hot-restart"}
+
+// debugServer serves the debug endpoints on localhost:port, to requests with the bearer token in
+// GetDebugTokenFile(). Only something in the pod, like busyambassador debug collect or kubectl
+// port-forward, can reach it. If it can't start, it says so and waits to be shut down, rather than
+// take the rest of Ambassador down with it.
+func debugServer(ctx context.Context, port string) {
+	allow, err := parseDebugAllow(GetDebugAllow())
+	if err == nil {
+		err = ensureDebugToken(GetDebugTokenFile())
+	}
+	var listener net.Listener
+	if err == nil {
+		listener, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+	}
+	if err != nil {
+		log.Printf("debug server disabled: %v", err)
+		<-ctx.Done()
+		return
+	}
+
+	s := &http.Server{Handler: debugHandler(GetDebugTokenFile(), allow)}
+	go func() {
+		log.Println(s.Serve(listener))
+	}()
+	log.Printf("debug server listening on %s, serving %s", listener.Addr(), GetDebugAllow())
+	<-ctx.Done()
+	tctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Shutdown(tctx); err != nil {
+		log.Printf("debug server: %v", err)
+	}
+}
+
+// parseDebugAllow parses AMBASSADOR_DEBUG_ALLOW, a comma-separated list of debugEndpoints.
+func parseDebugAllow(value string) (map[string]bool, error) {
+	allow := make(map[string]bool)
+	for _, endpoint := range strings.Split(value, ",") {
+		endpoint = strings.TrimSpace(endpoint)
+		if endpoint == "" {
+			continue
+		}
+		known := false
+		for _, e := range debugEndpoints {
+			known = known || e == endpoint
+		}
+		if !known {
+			return nil, fmt.Errorf("AMBASSADOR_DEBUG_ALLOW: unknown endpoint %q; expected some of %s",
+				endpoint, strings.Join(debugEndpoints, ", "))
+		}
+		allow[endpoint] = true
+	}
+	return allow, nil
+}
+
+// ensureDebugToken writes a random token to tokenFile, unless it already exists, e.g. because it's
+// a mounted Secret.
+func ensureDebugToken(tokenFile string) error {
+	if fileExists(tokenFile) {
+		return nil
+	}
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(tokenFile, []byte(hex.EncodeToString(token)+"\n"), 0600)
+}
+
+// readDebugToken reads the token from tokenFile. It's reread for every request, so rotating a
+// mounted Secret doesn't need a restart.
+func readDebugToken(tokenFile string) (string, error) {
+	bytes, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(bytes))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// debugHandler serves the allowed debug endpoints to requests with the token in tokenFile.
+func debugHandler(tokenFile string, allow map[string]bool) http.Handler {
+	mux := http.NewServeMux()
+	if allow["pprof"] {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if allow["goroutines"] {
+		mux.HandleFunc("/debug/goroutines", handleGoroutines)
+	}
+	if allow["timers"] {
+		mux.HandleFunc("/debug/timers", handleTimers)
+	}
+	if allow["memory"] {
+		mux.HandleFunc("/debug/memory", handleMemory)
+	}
+	if allow["loglevel"] {
+		mux.HandleFunc("/debug/loglevel", handleLogLevel)
+	}
+	if allow["hot-restart"] {
+		mux.HandleFunc("/debug/hot-restart", envoyRestarts.handleHotRestart)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want, err := readDebugToken(tokenFile)
+		if err != nil {
+			log.Printf("debug server: %v", err)
+			http.Error(w, "debug server is misconfigured", http.StatusInternalServerError)
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func handleGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := runtimepprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func handleTimers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(metrics.timers()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,193 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+func TestDebugHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "debug-token")
+	require.NoError(t, ensureDebugToken(tokenFile))
+	token, err := readDebugToken(tokenFile)
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+	info, err := os.Stat(tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// An existing token, e.g. from a Secret, is left alone.
+	require.NoError(t, ensureDebugToken(tokenFile))
+	again, err := readDebugToken(tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+
+	get := func(handler http.Handler, path, auth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	metrics.observeSnapshotBuild(2 * time.Second)
+	handler := debugHandler(tokenFile, map[string]bool{"goroutines": true, "timers": true})
+
+	w := get(handler, "/debug/timers", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, get(handler, "/debug/timers", "Bearer wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, get(handler, "/debug/timers", token).Code)
+
+	w = get(handler, "/debug/timers", "Bearer "+token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var timers map[string]Timer
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &timers))
+	assert.Contains(t, timers, "ambex_push")
+	assert.True(t, timers["snapshot_build"].Count > 0)
+	assert.True(t, timers["snapshot_build"].TotalSeconds >= 2)
+
+	w = get(handler, "/debug/goroutines", "Bearer "+token)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "goroutine "), w.Body.String())
+
+	// pprof isn't allowed.
+	assert.Equal(t, http.StatusNotFound, get(handler, "/debug/pprof/", "Bearer "+token).Code)
+
+	handler = debugHandler(tokenFile, map[string]bool{"pprof": true})
+	w = get(handler, "/debug/pprof/", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, get(handler, "/debug/timers", "Bearer "+token).Code)
+
+	// Without a token, nothing is served.
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("\n"), 0600))
+	assert.Equal(t, http.StatusInternalServerError, get(handler, "/debug/pprof/", "Bearer ").Code)
+}
+
+func TestParseDebugAllow(t *testing.T) {
+	allow, err := parseDebugAllow("pprof, timers")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"pprof": true, "timers": true}, allow)
+
+	allow, err = parseDebugAllow("")
+	require.NoError(t, err)
+	assert.Empty(t, allow)
+
+	_, err = parseDebugAllow("pprof,heap")
+	assert.EqualError(t, err, `AMBASSADOR_DEBUG_ALLOW: unknown endpoint "heap"; expected some of pprof, goroutines, timers, memory, loglevel, hot-restart`)
+}
+
+func TestDebugLogLevel(t *testing.T) {
+	defer withLogLevels(t)()
+
+	dir, err := ioutil.TempDir("", "debug")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "debug-token")
+	require.NoError(t, ensureDebugToken(tokenFile))
+	token, err := readDebugToken(tokenFile)
+	require.NoError(t, err)
+
+	put := func(handler http.Handler, path, auth string) int {
+		r := httptest.NewRequest(http.MethodPut, path, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	handler := debugHandler(tokenFile, map[string]bool{"loglevel": true})
+	assert.Equal(t, http.StatusUnauthorized, put(handler, "/debug/loglevel?level=debug", ""))
+	assert.Equal(t, http.StatusUnauthorized, put(handler, "/debug/loglevel?level=debug", "Bearer wrong"))
+	assert.Equal(t, dlog.LogLevelInfo, logLevels.Level(""))
+
+	assert.Equal(t, http.StatusOK, put(handler, "/debug/loglevel?level=debug", "Bearer "+token))
+	assert.Equal(t, dlog.LogLevelDebug, logLevels.Level(""))
+
+	// The snapshot server, which doesn't check for a token, doesn't change them.
+	assert.Equal(t, http.StatusNotFound, put(snapshotHandler(&snapshotHandoff{}), "/loglevel?level=warn", ""))
+	assert.Equal(t, dlog.LogLevelDebug, logLevels.Level(""))
+}
+
+func TestDebugHotRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "debug-token")
+	require.NoError(t, ensureDebugToken(tokenFile))
+	token, err := readDebugToken(tokenFile)
+	require.NoError(t, err)
+
+	request := func(handler http.Handler, method, path, auth string) int {
+		r := httptest.NewRequest(method, path, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	before := envoyRestarts.status()
+	handler := debugHandler(tokenFile, map[string]bool{"hot-restart": true})
+	assert.Equal(t, http.StatusUnauthorized, request(handler, http.MethodPost, "/debug/hot-restart", ""))
+	assert.Equal(t, http.StatusUnauthorized, request(handler, http.MethodPost, "/debug/hot-restart", "Bearer wrong"))
+	assert.Equal(t, before, envoyRestarts.status())
+
+	assert.Equal(t, http.StatusOK, request(handler, http.MethodGet, "/debug/hot-restart", "Bearer "+token))
+
+	// The snapshot server, which doesn't check for a token, doesn't restart Envoy.
+	assert.Equal(t, http.StatusNotFound, request(snapshotHandler(&snapshotHandoff{}), http.MethodPost, "/envoy/hot-restart", ""))
+	assert.Equal(t, before, envoyRestarts.status())
+}
+
+func TestSnapshotServerHasNoPprof(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		snapshotServer(ctx, &snapshotHandoff{})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = http.Get("http://localhost:9696/readiness")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NoError(t, err)
+
+	// The profiles are on the debug server, behind its token, and not on the snapshot server.
+	resp, err = http.Get("http://localhost:9696/debug/pprof/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
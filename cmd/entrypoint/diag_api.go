@@ -0,0 +1,426 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// /api/v2/diag on the snapshot server is the diagnostics that diagd shows in HTML, as JSON that
+// dashboards and CLIs can count on: the routes, the Hosts, the clusters, the errors, and the
+// resources that they all came from, each pointing at the others. It's built from the ir.json and
+// aconf.json that diagd writes to GetSnapshotDir after every reconfiguration, plus the resources
+// that the watcher found invalid, which never got to diagd at all.
+//
+// Only the types in this file are the API. diagd's files change whenever the IR does, so fields
+// are only ever added to these types, never renamed or removed; anything else is a v3.
+
+const diagAPIVersion = "v2"
+
+// The diagDocument struct is all of the diagnostics at once, from /api/v2/diag.
+type diagDocument struct {
+	APIVersion string `json:"apiVersion"`
+	// Generated is when diagd wrote the configuration that the document is about.
+	Generated time.Time     `json:"generated"`
+	Routes    []diagRoute   `json:"routes"`
+	Hosts     []diagHost    `json:"hosts"`
+	Clusters  []diagCluster `json:"clusters"`
+	Errors    []diagError   `json:"errors"`
+	Sources   []diagSource  `json:"sources"`
+}
+
+// The diagRoute struct is one route, which is a group of Mappings that match the same requests.
+type diagRoute struct {
+	ID          string             `json:"id"`
+	Kind        string             `json:"kind"` // "http" or "tcp"
+	Host        string             `json:"host,omitempty"`
+	Prefix      string             `json:"prefix,omitempty"`
+	PrefixRegex bool               `json:"prefixRegex,omitempty"`
+	Method      string             `json:"method,omitempty"`
+	Precedence  int                `json:"precedence"`
+	Mappings    []diagRouteMapping `json:"mappings"`
+}
+
+// The diagRouteMapping struct is one of the Mappings of a route, and the cluster it sends to.
+type diagRouteMapping struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Source    string `json:"source"`
+	Cluster   string `json:"cluster,omitempty"`
+	Weight    int    `json:"weight,omitempty"`
+}
+
+// The diagHost struct is one Host. Its Routes are the routes for its hostname, and the routes
+// for every host.
+type diagHost struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Hostname  string   `json:"hostname"`
+	Source    string   `json:"source"`
+	Routes    []string `json:"routes"`
+}
+
+// The diagCluster struct is one cluster, with the routes that send to it and the resources that
+// made it.
+type diagCluster struct {
+	Name    string   `json:"name"`
+	Service string   `json:"service"`
+	Type    string   `json:"type,omitempty"`
+	LBType  string   `json:"lbType,omitempty"`
+	URLs    []string `json:"urls"`
+	Routes  []string `json:"routes"`
+	Sources []string `json:"sources"`
+}
+
+// The diagError struct is one error. Source is the key of a diagSource for the errors that diagd
+// found, or "Kind namespace/name" for resources that the watcher found invalid.
+type diagError struct {
+	Source  string `json:"source"`
+	Kind    string `json:"kind"` // "config" from diagd, or "invalid" from the watcher
+	Message string `json:"message"`
+}
+
+// The diagSource struct is one of the resources that the configuration came from. It leaves out
+// the resource itself, which could have credentials in it.
+type diagSource struct {
+	Key        string   `json:"key"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Generation int64    `json:"generation,omitempty"`
+	Routes     []string `json:"routes"`
+	Clusters   []string `json:"clusters"`
+	Hosts      []string `json:"hosts"`
+	Errors     []string `json:"errors"`
+}
+
+// The irFile and aconfFile structs are the parts of diagd's files that the API uses.
+type irFile struct {
+	Groups []irGroup `json:"groups"`
+	Hosts  []struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		Hostname  string `json:"hostname"`
+		Location  string `json:"location"`
+	} `json:"hosts"`
+	// Clusters are a dict of name to cluster in some versions of diagd and a list in others.
+	Clusters json.RawMessage `json:"clusters"`
+}
+
+type irGroup struct {
+	GroupID     string      `json:"group_id"`
+	Kind        string      `json:"kind"`
+	Host        string      `json:"host"`
+	Prefix      string      `json:"prefix"`
+	PrefixRegex bool        `json:"prefix_regex"`
+	Method      string      `json:"method"`
+	Precedence  int         `json:"precedence"`
+	Headers     []irHeader  `json:"headers"`
+	Mappings    []irMapping `json:"mappings"`
+}
+
+type irHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type irMapping struct {
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace"`
+	Location  string     `json:"location"`
+	Prefix    *string    `json:"prefix"`
+	Weight    int        `json:"weight"`
+	Cluster   *irCluster `json:"cluster"`
+}
+
+type irCluster struct {
+	Name         string   `json:"name"`
+	EnvoyName    string   `json:"envoy_name"`
+	Service      string   `json:"service"`
+	Type         string   `json:"type"`
+	LBType       string   `json:"lb_type"`
+	URLs         []string `json:"urls"`
+	ReferencedBy []string `json:"_referenced_by"`
+}
+
+type aconfFile struct {
+	Errors map[string][]struct {
+		Error string `json:"error"`
+	} `json:"_errors"`
+	Sources map[string]struct {
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Generation int64  `json:"generation"`
+	} `json:"_sources"`
+}
+
+// loadDiagDocument builds the diagnostics from the files that diagd wrote to dir, and the
+// resources that the watcher found invalid.
+func loadDiagDocument(dir string, invalid []diagError) (*diagDocument, error) {
+	var ir irFile
+	generated, err := readDiagFile(path.Join(dir, "ir.json"), &ir)
+	if err != nil {
+		return nil, err
+	}
+	var aconf aconfFile
+	if _, err := readDiagFile(path.Join(dir, "aconf.json"), &aconf); err != nil {
+		return nil, err
+	}
+	clusters, err := irClusters(ir.Clusters)
+	if err != nil {
+		return nil, fmt.Errorf("ir.json: clusters: %w", err)
+	}
+
+	doc := &diagDocument{
+		APIVersion: diagAPIVersion,
+		Generated:  generated.UTC(),
+		Routes:     []diagRoute{},
+		Hosts:      []diagHost{},
+		Clusters:   []diagCluster{},
+		Errors:     []diagError{},
+		Sources:    []diagSource{},
+	}
+
+	sources := map[string]*diagSource{}
+	for key, src := range aconf.Sources {
+		sources[key] = &diagSource{
+			Key:        key,
+			Kind:       src.Kind,
+			Name:       src.Name,
+			Namespace:  src.Namespace,
+			Generation: src.Generation,
+			Routes:     []string{},
+			Clusters:   []string{},
+			Hosts:      []string{},
+			Errors:     []string{},
+		}
+	}
+	source := func(key string) *diagSource {
+		if src, ok := sources[key]; ok {
+			return src
+		}
+		return &diagSource{} // somewhere to put references to a source that diagd didn't save
+	}
+
+	clusterRoutes := map[string][]string{}
+	for _, group := range ir.Groups {
+		route := diagRoute{
+			ID:          group.GroupID,
+			Kind:        diagRouteKind(group.Kind),
+			Host:        group.Host,
+			Prefix:      group.Prefix,
+			PrefixRegex: group.PrefixRegex,
+			Method:      group.Method,
+			Precedence:  group.Precedence,
+			Mappings:    []diagRouteMapping{},
+		}
+		for _, m := range group.Mappings {
+			mapping := diagRouteMapping{
+				Name:      m.Name,
+				Namespace: m.Namespace,
+				Source:    m.Location,
+				Weight:    m.Weight,
+			}
+			if m.Cluster != nil {
+				mapping.Cluster = m.Cluster.Name
+				clusterRoutes[mapping.Cluster] = appendUnique(clusterRoutes[mapping.Cluster], route.ID)
+			}
+			route.Mappings = append(route.Mappings, mapping)
+			src := source(m.Location)
+			src.Routes = appendUnique(src.Routes, route.ID)
+		}
+		doc.Routes = append(doc.Routes, route)
+	}
+
+	for _, h := range ir.Hosts {
+		host := diagHost{
+			Name:      h.Name,
+			Namespace: h.Namespace,
+			Hostname:  h.Hostname,
+			Source:    h.Location,
+			Routes:    []string{},
+		}
+		for _, route := range doc.Routes {
+			if route.Host == "" || route.Host == "*" || route.Host == h.Hostname {
+				host.Routes = append(host.Routes, route.ID)
+			}
+		}
+		src := source(h.Location)
+		src.Hosts = appendUnique(src.Hosts, h.Name)
+		doc.Hosts = append(doc.Hosts, host)
+	}
+
+	for _, c := range clusters {
+		cluster := diagCluster{
+			Name:    c.Name,
+			Service: c.Service,
+			Type:    c.Type,
+			LBType:  c.LBType,
+			URLs:    append([]string{}, c.URLs...),
+			Routes:  append([]string{}, clusterRoutes[c.Name]...),
+			Sources: append([]string{}, c.ReferencedBy...),
+		}
+		for _, key := range c.ReferencedBy {
+			src := source(key)
+			src.Clusters = appendUnique(src.Clusters, c.Name)
+		}
+		doc.Clusters = append(doc.Clusters, cluster)
+	}
+	sort.Slice(doc.Clusters, func(i, j int) bool { return doc.Clusters[i].Name < doc.Clusters[j].Name })
+
+	for key, errs := range aconf.Errors {
+		for _, e := range errs {
+			doc.Errors = append(doc.Errors, diagError{Source: key, Kind: "config", Message: e.Error})
+			src := source(key)
+			src.Errors = append(src.Errors, e.Error)
+		}
+	}
+	doc.Errors = append(doc.Errors, invalid...)
+	sort.SliceStable(doc.Errors, func(i, j int) bool { return doc.Errors[i].Source < doc.Errors[j].Source })
+
+	for _, src := range sources {
+		doc.Sources = append(doc.Sources, *src)
+	}
+	sort.Slice(doc.Sources, func(i, j int) bool { return doc.Sources[i].Key < doc.Sources[j].Key })
+
+	return doc, nil
+}
+
+// readDiagFile decodes one of diagd's files into v, and returns when diagd wrote it.
+func readDiagFile(file string, v interface{}) (time.Time, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", path.Base(file), err)
+	}
+	return info.ModTime(), nil
+}
+
+func irClusters(raw json.RawMessage) ([]irCluster, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var list []irCluster
+	if raw[0] == '[' {
+		err := json.Unmarshal(raw, &list)
+		return list, err
+	}
+	var dict map[string]irCluster
+	if err := json.Unmarshal(raw, &dict); err != nil {
+		return nil, err
+	}
+	for _, c := range dict {
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func diagRouteKind(kind string) string {
+	switch kind {
+	case "IRHTTPMappingGroup":
+		return "http"
+	case "IRTCPMappingGroup":
+		return "tcp"
+	default:
+		return strings.ToLower(kind)
+	}
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, have := range list {
+		if have == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// snapshotInvalid returns the errors of the resources that the watcher found invalid in sn.
+func snapshotInvalid(sn *Snapshot) []diagError {
+	var errs []diagError
+	for _, un := range sn.Invalid {
+		message, _ := un.Object["errors"].(string)
+		errs = append(errs, diagError{
+			Source:  auditKey(un.GetKind(), un.GetNamespace(), un.GetName()),
+			Kind:    "invalid",
+			Message: message,
+		})
+	}
+	return errs
+}
+
+// handleDiagAPI serves /api/v2/diag, which is the whole diagDocument, and /api/v2/diag/routes,
+// /hosts, /clusters, /errors and /sources, which are each one list from it, as well as
+// /api/v2/diag/provenance and /api/v2/diag/explain.
+func handleDiagAPI(snapshot *snapshotHandoff) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		section := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/"+diagAPIVersion+"/diag"), "/")
+		switch section {
+		case "/provenance":
+			serveProvenance(w, r)
+			return
+		case "/explain":
+			serveExplanations(w, r)
+			return
+		}
+
+		var invalid []diagError
+		if sn := snapshot.load(); sn != nil {
+			invalid = snapshotInvalid(sn)
+		}
+		doc, err := loadDiagDocument(GetSnapshotDir(), invalid)
+		if os.IsNotExist(err) {
+			http.Error(w, "no configuration yet", http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var body interface{}
+		switch section {
+		case "":
+			body = doc
+		case "/routes":
+			body = doc.Routes
+		case "/hosts":
+			body = doc.Hosts
+		case "/clusters":
+			body = doc.Clusters
+		case "/errors":
+			body = doc.Errors
+		case "/sources":
+			body = doc.Sources
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		writeDiagJSON(w, body)
+	}
+}
+
+func writeDiagJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
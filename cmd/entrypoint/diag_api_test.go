@@ -0,0 +1,251 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+const diagTestIR = `{
+  "groups": [
+    {
+      "group_id": "g1", "kind": "IRHTTPMappingGroup", "prefix": "/foo/", "method": "GET", "precedence": 1,
+      "headers": [{"name": ":method", "value": "GET", "regex": false}],
+      "mappings": [
+        {"name": "foo", "namespace": "default", "location": "foo.default.1", "weight": 100,
+         "cluster": {"name": "cluster_foo_default"}}
+      ]
+    },
+    {
+      "group_id": "g2", "kind": "IRTCPMappingGroup", "host": "tcp.example.com",
+      "mappings": [
+        {"name": "tcp", "namespace": "default", "location": "tcp.default.1",
+         "cluster": {"name": "cluster_foo_default"}}
+      ]
+    }
+  ],
+  "hosts": [
+    {"name": "example", "namespace": "default", "hostname": "www.example.com", "location": "example.default.1"}
+  ],
+  "clusters": {
+    "cluster_foo_default": {"name": "cluster_foo_default", "service": "foo", "type": "strict_dns",
+      "lb_type": "round_robin", "urls": ["tcp://foo:80"], "_referenced_by": ["foo.default.1", "tcp.default.1"]}
+  }
+}`
+
+const diagTestAConf = `{
+  "_errors": {"tcp.default.1": [{"ok": false, "error": "TCPMapping needs a port"}]},
+  "_sources": {
+    "foo.default.1": {"kind": "Mapping", "name": "foo", "namespace": "default",
+      "serialization": "apiVersion: getambassador.io/v2\nkind: Mapping\nheaders:\n  authorization: Bearer hunter2\n"},
+    "tcp.default.1": {"kind": "TCPMapping", "name": "tcp", "namespace": "default", "generation": 3},
+    "example.default.1": {"kind": "Host", "name": "example", "namespace": "default"}
+  }
+}`
+
+const diagTestEConf = `{
+  "static_resources": {
+    "listeners": [
+      {
+        "name": "ambassador-listener-8080",
+        "filter_chains": [{"filters": [{"typed_config": {"route_config": {"virtual_hosts": [{
+          "name": "ambassador-listener-8080-*",
+          "routes": [
+            {"match": {"prefix": "/foo/", "headers": [
+               {"name": "x-forwarded-proto", "exact_match": "https"}, {"name": ":method", "exact_match": "GET"}]},
+             "route": {"cluster": "cluster_foo_default"}},
+            {"match": {"prefix": "/foo/"}, "route": {"cluster": "cluster_foo_default"}},
+            {"match": {"prefix": "/foo/", "headers": [{"name": ":method", "exact_match": "GET"}]},
+             "redirect": {"https_redirect": true}}
+          ]
+        }]}}}]}]
+      },
+      {
+        "name": "ambassador-listener-8443",
+        "filter_chains": [{"filter_chain_match": {"server_names": ["tcp.example.com"]},
+          "filters": [{"typed_config": {"cluster": "cluster_foo_default"}}]}]
+      }
+    ],
+    "clusters": [{"name": "cluster_foo_default"}, {"name": "cluster_extauth"}]
+  }
+}`
+
+// writeDiagFiles writes the files above to the snapshots directory of a new base directory.
+func writeDiagFiles(t *testing.T) string {
+	base, err := ioutil.TempDir("", "diag")
+	require.NoError(t, err)
+	dir := filepath.Join(base, "snapshots")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ir.json"), []byte(diagTestIR), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "aconf.json"), []byte(diagTestAConf), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "econf.json"), []byte(diagTestEConf), 0644))
+	return base
+}
+
+func TestLoadDiagDocument(t *testing.T) {
+	base := writeDiagFiles(t)
+	defer os.RemoveAll(base)
+
+	invalid := []diagError{{Source: "Mapping default/bad", Kind: "invalid", Message: "spec.prefix: Required"}}
+	doc, err := loadDiagDocument(filepath.Join(base, "snapshots"), invalid)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", doc.APIVersion)
+
+	require.Len(t, doc.Routes, 2)
+	assert.Equal(t, diagRoute{
+		ID: "g1", Kind: "http", Prefix: "/foo/", Method: "GET", Precedence: 1,
+		Mappings: []diagRouteMapping{{Name: "foo", Namespace: "default", Source: "foo.default.1",
+			Cluster: "cluster_foo_default", Weight: 100}},
+	}, doc.Routes[0])
+	assert.Equal(t, "tcp", doc.Routes[1].Kind)
+
+	// The Host gets the route for every host, but not the one for another host.
+	require.Len(t, doc.Hosts, 1)
+	assert.Equal(t, []string{"g1"}, doc.Hosts[0].Routes)
+
+	require.Len(t, doc.Clusters, 1)
+	assert.Equal(t, []string{"g1", "g2"}, doc.Clusters[0].Routes)
+	assert.Equal(t, []string{"foo.default.1", "tcp.default.1"}, doc.Clusters[0].Sources)
+
+	assert.Equal(t, []diagError{
+		invalid[0],
+		{Source: "tcp.default.1", Kind: "config", Message: "TCPMapping needs a port"},
+	}, doc.Errors)
+
+	require.Len(t, doc.Sources, 3)
+	assert.Equal(t, diagSource{
+		Key: "tcp.default.1", Kind: "TCPMapping", Name: "tcp", Namespace: "default", Generation: 3,
+		Routes: []string{"g2"}, Clusters: []string{"cluster_foo_default"}, Hosts: []string{},
+		Errors: []string{"TCPMapping needs a port"},
+	}, doc.Sources[2])
+	assert.Equal(t, []string{"example"}, doc.Sources[0].Hosts)
+
+	// Nothing of the resources themselves gets out.
+	body, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "hunter2")
+}
+
+func TestProvenanceIndex(t *testing.T) {
+	base := writeDiagFiles(t)
+	defer os.RemoveAll(base)
+
+	index, err := loadProvenanceIndex(filepath.Join(base, "snapshots"))
+	require.NoError(t, err)
+
+	foo := diagProvenance{Source: "foo.default.1", Kind: "Mapping", Name: "foo", Namespace: "default"}
+	tcp := diagProvenance{Source: "tcp.default.1", Kind: "TCPMapping", Name: "tcp", Namespace: "default", Generation: 3}
+
+	require.Len(t, index.Routes, 4)
+	// The listener's X-Forwarded-Proto header doesn't stop the route from matching its Mapping...
+	assert.Equal(t, "g1", index.Routes[0].Route)
+	assert.Equal(t, []diagProvenance{foo}, index.Routes[0].Sources)
+	// ...but a route without the Mapping's method didn't come from it.
+	assert.Equal(t, "", index.Routes[1].Route)
+	assert.Equal(t, []diagProvenance{}, index.Routes[1].Sources)
+	// A redirect has no cluster to go by.
+	assert.Equal(t, "g1", index.Routes[2].Route)
+	tcpRoute := index.Routes[3]
+	assert.JSONEq(t, `{"cluster": "cluster_foo_default"}`, string(tcpRoute.config))
+	tcpRoute.config = nil
+	assert.Equal(t, diagEnvoyRoute{
+		Listener: "ambassador-listener-8443", TCP: true, Clusters: []string{"cluster_foo_default"},
+		Route: "g2", Sources: []diagProvenance{tcp},
+	}, tcpRoute)
+
+	assert.Equal(t, []diagEnvoyCluster{
+		{Name: "cluster_extauth", Sources: []diagProvenance{}},
+		{Name: "cluster_foo_default", Sources: []diagProvenance{foo, tcp}},
+	}, index.Clusters)
+
+	// The index is only built again when diagd writes new files.
+	again, err := loadProvenanceIndex(filepath.Join(base, "snapshots"))
+	require.NoError(t, err)
+	assert.True(t, index == again)
+}
+
+func TestExplainResources(t *testing.T) {
+	base := writeDiagFiles(t)
+	defer os.RemoveAll(base)
+
+	explanations, err := explainResources(filepath.Join(base, "snapshots"), "mapping", "foo", "")
+	require.NoError(t, err)
+	require.Len(t, explanations.Explanations, 1)
+	e := explanations.Explanations[0]
+
+	assert.Equal(t, "foo.default.1", e.Source.Key)
+	assert.Equal(t, []map[string]interface{}{{
+		"name": "foo", "namespace": "default", "location": "foo.default.1", "weight": 100.0,
+		"cluster": map[string]interface{}{"name": "cluster_foo_default"},
+	}}, e.Config)
+	require.Len(t, e.Routes, 1)
+	assert.Equal(t, "g1", e.Routes[0].ID)
+
+	// The two routes that came from it, but not the one that didn't.
+	require.Len(t, e.Envoy, 2)
+	assert.Equal(t, 0, e.Envoy[0].Index)
+	assert.Equal(t, 2, e.Envoy[1].Index)
+	assert.Contains(t, string(e.Envoy[1].Config), "https_redirect")
+
+	explanations, err = explainResources(filepath.Join(base, "snapshots"), "Mapping", "foo", "elsewhere")
+	require.NoError(t, err)
+	assert.Empty(t, explanations.Explanations)
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	errs := snapshotInvalid(&Snapshot{Invalid: []*kates.Unstructured{{Object: map[string]interface{}{
+		"apiVersion": "getambassador.io/v2",
+		"kind":       "Mapping",
+		"metadata":   map[string]interface{}{"name": "bad", "namespace": "default"},
+		"errors":     "spec.prefix: Required",
+	}}}})
+	assert.Equal(t, []diagError{{Source: "Mapping default/bad", Kind: "invalid", Message: "spec.prefix: Required"}}, errs)
+}
+
+func TestHandleDiagAPI(t *testing.T) {
+	base := writeDiagFiles(t)
+	defer os.RemoveAll(base)
+	os.Setenv("AMBASSADOR_CONFIG_BASE_DIR", base)
+	defer os.Unsetenv("AMBASSADOR_CONFIG_BASE_DIR")
+
+	handler := handleDiagAPI(&snapshotHandoff{})
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/v2/diag")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var doc diagDocument
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Len(t, doc.Routes, 2)
+
+	rec = get("/api/v2/diag/clusters/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var clusters []diagCluster
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &clusters))
+	assert.Equal(t, "cluster_foo_default", clusters[0].Name)
+
+	assert.Equal(t, http.StatusNotFound, get("/api/v2/diag/nope").Code)
+
+	rec = get("/api/v2/diag/provenance?cluster=cluster_foo_default&prefix=/foo/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var index diagProvenanceIndex
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &index))
+	assert.Len(t, index.Routes, 2)
+	require.Len(t, index.Clusters, 1)
+	assert.Equal(t, "cluster_foo_default", index.Clusters[0].Name)
+
+	os.Setenv("AMBASSADOR_CONFIG_BASE_DIR", filepath.Join(base, "missing"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/api/v2/diag").Code)
+}
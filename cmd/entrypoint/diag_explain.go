@@ -0,0 +1,140 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// /api/v2/diag/explain?kind=Mapping&name=quote&namespace=default explains a resource: the
+// configuration that Ambassador computed from it, with the Module's defaults filled in, the route
+// it ended up in with whatever other Mappings share it, and the routes that Envoy has for it.
+// busyambassador explain prints it.
+//
+// Unlike the rest of the API, an explanation has what's in the resource, so that it can show
+// what Ambassador made of it.
+
+// The diagExplanation struct explains one resource.
+type diagExplanation struct {
+	Source diagSource `json:"source"`
+	// Config is what Ambassador computed from the resource: one Mapping or Host, usually, but an
+	// annotation can have more.
+	Config []map[string]interface{} `json:"config"`
+	// Routes are the routes that the resource's Mappings are in.
+	Routes []diagRoute `json:"routes"`
+	// Envoy is the routes and TCP proxies that Envoy has for the resource.
+	Envoy  []diagEnvoyConfig `json:"envoy"`
+	Errors []string          `json:"errors"`
+}
+
+// The diagEnvoyConfig struct is one of Envoy's routes, with its configuration.
+type diagEnvoyConfig struct {
+	diagEnvoyRoute
+	Config json.RawMessage `json:"config"`
+}
+
+// The diagExplanations struct is what /api/v2/diag/explain serves: the explanations of every
+// resource that the query matches.
+type diagExplanations struct {
+	APIVersion   string            `json:"apiVersion"`
+	Explanations []diagExplanation `json:"explanations"`
+}
+
+// explainResources explains the resources of kind with name, in namespace if it isn't "", with
+// the files that diagd wrote to dir.
+func explainResources(dir, kind, name, namespace string) (*diagExplanations, error) {
+	doc, err := loadDiagDocument(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	index, err := loadProvenanceIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ir struct {
+		Groups []struct {
+			Mappings []map[string]interface{} `json:"mappings"`
+		} `json:"groups"`
+		Hosts []map[string]interface{} `json:"hosts"`
+	}
+	if _, err := readDiagFile(path.Join(dir, "ir.json"), &ir); err != nil {
+		return nil, err
+	}
+	var computed []map[string]interface{}
+	for _, group := range ir.Groups {
+		computed = append(computed, group.Mappings...)
+	}
+	computed = append(computed, ir.Hosts...)
+
+	ret := &diagExplanations{APIVersion: diagAPIVersion, Explanations: []diagExplanation{}}
+	for _, src := range doc.Sources {
+		if !strings.EqualFold(src.Kind, kind) || src.Name != name || (namespace != "" && src.Namespace != namespace) {
+			continue
+		}
+		explanation := diagExplanation{
+			Source: src,
+			Config: []map[string]interface{}{},
+			Routes: []diagRoute{},
+			Envoy:  []diagEnvoyConfig{},
+			Errors: src.Errors,
+		}
+		for _, obj := range computed {
+			if location, _ := obj["location"].(string); location == src.Key {
+				explanation.Config = append(explanation.Config, effectiveConfig(obj))
+			}
+		}
+		for _, route := range doc.Routes {
+			if containsString(src.Routes, route.ID) {
+				explanation.Routes = append(explanation.Routes, route)
+			}
+		}
+		for _, route := range index.Routes {
+			for _, p := range route.Sources {
+				if p.Source == src.Key {
+					explanation.Envoy = append(explanation.Envoy, diagEnvoyConfig{route, route.config})
+					break
+				}
+			}
+		}
+		ret.Explanations = append(ret.Explanations, explanation)
+	}
+	return ret, nil
+}
+
+// effectiveConfig returns the fields of an IR object that say how it's configured, without its
+// bookkeeping: the fields that start with "_", and the serialization of the resource that it came
+// from. Its cluster gets the same treatment.
+func effectiveConfig(obj map[string]interface{}) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for k, v := range obj {
+		if strings.HasPrefix(k, "_") || k == "serialization" {
+			continue
+		}
+		if cluster, ok := v.(map[string]interface{}); ok && k == "cluster" {
+			v = effectiveConfig(cluster)
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+// serveExplanations serves the explanations of ?kind= and ?name=, in ?namespace= if it's there.
+func serveExplanations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	kind, name := query.Get("kind"), query.Get("name")
+	if kind == "" || name == "" {
+		http.Error(w, "explain needs a kind and a name", http.StatusBadRequest)
+		return
+	}
+	explanations, err := explainResources(GetSnapshotDir(), kind, name, query.Get("namespace"))
+	if os.IsNotExist(err) {
+		http.Error(w, "no configuration yet", http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeDiagJSON(w, explanations)
+}
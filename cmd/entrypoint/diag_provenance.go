@@ -0,0 +1,439 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// /api/v2/diag/provenance answers "which Mapping made this route?" for the configuration that
+// Envoy actually has: it indexes every route and cluster in the econf.json that diagd writes next
+// to ir.json, by the resources (and their generations) that they came from.
+//
+// Envoy's routes have no names, so each one is traced back to the IR Mapping whose prefix, headers
+// and cluster it has. That's one Mapping for nearly every route; the listeners add headers of their
+// own, like X-Forwarded-Proto, so a route may have more headers than its Mapping, and where more
+// than one Mapping could have made it, the one with the most headers did.
+
+// The diagProvenance struct is a resource that a route or cluster came from.
+type diagProvenance struct {
+	Source     string `json:"source"` // the key of the diagSource
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+// The diagEnvoyRoute struct is one of Envoy's routes, or one of its TCP proxies, which match
+// "tcp". Index is where it is in its virtual host, or in its listener for a TCP proxy.
+type diagEnvoyRoute struct {
+	Listener    string           `json:"listener"`
+	VirtualHost string           `json:"virtualHost,omitempty"`
+	Index       int              `json:"index"`
+	Prefix      string           `json:"prefix,omitempty"`
+	Path        string           `json:"path,omitempty"`
+	Regex       string           `json:"regex,omitempty"`
+	TCP         bool             `json:"tcp,omitempty"`
+	Clusters    []string         `json:"clusters"`
+	Route       string           `json:"route,omitempty"` // the ID of the diagRoute
+	Sources     []diagProvenance `json:"sources"`
+
+	// config is the route, or the TCP proxy's config, as Envoy has it, for explanations.
+	config json.RawMessage
+}
+
+// The diagEnvoyCluster struct is one of Envoy's clusters.
+type diagEnvoyCluster struct {
+	Name    string           `json:"name"`
+	Sources []diagProvenance `json:"sources"`
+}
+
+// The diagProvenanceIndex struct is what /api/v2/diag/provenance serves.
+type diagProvenanceIndex struct {
+	APIVersion string             `json:"apiVersion"`
+	Generated  time.Time          `json:"generated"`
+	Routes     []diagEnvoyRoute   `json:"routes"`
+	Clusters   []diagEnvoyCluster `json:"clusters"`
+}
+
+// The econfFile struct is the parts of econf.json that the index uses.
+type econfFile struct {
+	StaticResources struct {
+		Listeners []struct {
+			Name         string `json:"name"`
+			FilterChains []struct {
+				FilterChainMatch struct {
+					ServerNames []string `json:"server_names"`
+				} `json:"filter_chain_match"`
+				Filters []struct {
+					TypedConfig econfFilterConfig `json:"typed_config"`
+				} `json:"filters"`
+			} `json:"filter_chains"`
+		} `json:"listeners"`
+		Clusters []struct {
+			Name string `json:"name"`
+		} `json:"clusters"`
+	} `json:"static_resources"`
+}
+
+type econfFilterConfig struct {
+	Cluster     string `json:"cluster"` // for a TCP proxy
+	RouteConfig struct {
+		VirtualHosts []struct {
+			Name   string       `json:"name"`
+			Routes []econfRoute `json:"routes"`
+		} `json:"virtual_hosts"`
+	} `json:"route_config"`
+
+	raw json.RawMessage
+}
+
+func (c *econfFilterConfig) UnmarshalJSON(b []byte) error {
+	type plain econfFilterConfig
+	if err := json.Unmarshal(b, (*plain)(c)); err != nil {
+		return err
+	}
+	c.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+type econfRoute struct {
+	Match struct {
+		Prefix    string `json:"prefix"`
+		Path      string `json:"path"`
+		Regex     string `json:"regex"`
+		SafeRegex *struct {
+			Regex string `json:"regex"`
+		} `json:"safe_regex"`
+		Headers []struct {
+			Name           string  `json:"name"`
+			ExactMatch     *string `json:"exact_match"`
+			RegexMatch     *string `json:"regex_match"`
+			SafeRegexMatch *struct {
+				Regex string `json:"regex"`
+			} `json:"safe_regex_match"`
+		} `json:"headers"`
+	} `json:"match"`
+	Route *struct {
+		Cluster          string `json:"cluster"`
+		WeightedClusters *struct {
+			Clusters []struct {
+				Name string `json:"name"`
+			} `json:"clusters"`
+		} `json:"weighted_clusters"`
+	} `json:"route"`
+
+	raw json.RawMessage
+}
+
+func (r *econfRoute) UnmarshalJSON(b []byte) error {
+	type plain econfRoute
+	if err := json.Unmarshal(b, (*plain)(r)); err != nil {
+		return err
+	}
+	r.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// provenanceCache keeps the last index until diagd writes its files again.
+var provenanceCache struct {
+	sync.Mutex
+	stamp string
+	index *diagProvenanceIndex
+}
+
+// loadProvenanceIndex returns the index of the files that diagd wrote to dir, building it only if
+// they've changed since the last time.
+func loadProvenanceIndex(dir string) (*diagProvenanceIndex, error) {
+	var stamp string
+	for _, name := range []string{"ir.json", "aconf.json", "econf.json"} {
+		info, err := os.Stat(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		stamp += fmt.Sprintf("%s:%d:%d;", name, info.ModTime().UnixNano(), info.Size())
+	}
+
+	provenanceCache.Lock()
+	defer provenanceCache.Unlock()
+	if provenanceCache.index != nil && provenanceCache.stamp == stamp {
+		return provenanceCache.index, nil
+	}
+	index, err := buildProvenanceIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	provenanceCache.stamp = stamp
+	provenanceCache.index = index
+	return index, nil
+}
+
+func buildProvenanceIndex(dir string) (*diagProvenanceIndex, error) {
+	var ir irFile
+	if _, err := readDiagFile(path.Join(dir, "ir.json"), &ir); err != nil {
+		return nil, err
+	}
+	var aconf aconfFile
+	if _, err := readDiagFile(path.Join(dir, "aconf.json"), &aconf); err != nil {
+		return nil, err
+	}
+	var econf econfFile
+	generated, err := readDiagFile(path.Join(dir, "econf.json"), &econf)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := irClusters(ir.Clusters)
+	if err != nil {
+		return nil, fmt.Errorf("ir.json: clusters: %w", err)
+	}
+
+	provenance := func(keys ...string) []diagProvenance {
+		ret := []diagProvenance{}
+		seen := map[string]bool{}
+		for _, key := range keys {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			src := aconf.Sources[key]
+			ret = append(ret, diagProvenance{
+				Source:     key,
+				Kind:       src.Kind,
+				Name:       src.Name,
+				Namespace:  src.Namespace,
+				Generation: src.Generation,
+			})
+		}
+		return ret
+	}
+
+	index := &diagProvenanceIndex{
+		APIVersion: diagAPIVersion,
+		Generated:  generated.UTC(),
+		Routes:     []diagEnvoyRoute{},
+		Clusters:   []diagEnvoyCluster{},
+	}
+
+	for _, listener := range econf.StaticResources.Listeners {
+		tcpIndex := 0
+		for _, chain := range listener.FilterChains {
+			for _, filter := range chain.Filters {
+				if cluster := filter.TypedConfig.Cluster; cluster != "" {
+					route := diagEnvoyRoute{
+						Listener: listener.Name,
+						Index:    tcpIndex,
+						TCP:      true,
+						Clusters: []string{cluster},
+						config:   filter.TypedConfig.raw,
+					}
+					tcpIndex++
+					id, keys := tcpProvenance(ir.Groups, cluster, chain.FilterChainMatch.ServerNames)
+					route.Route = id
+					route.Sources = provenance(keys...)
+					index.Routes = append(index.Routes, route)
+					continue
+				}
+				for _, vhost := range filter.TypedConfig.RouteConfig.VirtualHosts {
+					for i, r := range vhost.Routes {
+						route := diagEnvoyRoute{
+							Listener:    listener.Name,
+							VirtualHost: vhost.Name,
+							Index:       i,
+							Prefix:      r.Match.Prefix,
+							Path:        r.Match.Path,
+							Regex:       r.Match.Regex,
+							Clusters:    r.clusters(),
+							config:      r.raw,
+						}
+						if r.Match.SafeRegex != nil {
+							route.Regex = r.Match.SafeRegex.Regex
+						}
+						id, keys := httpProvenance(ir.Groups, r, route.Clusters)
+						route.Route = id
+						route.Sources = provenance(keys...)
+						index.Routes = append(index.Routes, route)
+					}
+				}
+			}
+		}
+	}
+
+	byEnvoyName := map[string]irCluster{}
+	for _, c := range clusters {
+		byEnvoyName[c.envoyName()] = c
+	}
+	for _, c := range econf.StaticResources.Clusters {
+		index.Clusters = append(index.Clusters, diagEnvoyCluster{
+			Name:    c.Name,
+			Sources: provenance(byEnvoyName[c.Name].ReferencedBy...),
+		})
+	}
+	sort.Slice(index.Clusters, func(i, j int) bool { return index.Clusters[i].Name < index.Clusters[j].Name })
+
+	return index, nil
+}
+
+// The envoyName method returns the name of the cluster in Envoy, which is shorter than its name
+// in the IR if that's too long for Envoy.
+func (c irCluster) envoyName() string {
+	if c.EnvoyName != "" {
+		return c.EnvoyName
+	}
+	return c.Name
+}
+
+func (r econfRoute) clusters() []string {
+	clusters := []string{}
+	if r.Route == nil {
+		return clusters // a redirect
+	}
+	if r.Route.Cluster != "" {
+		clusters = append(clusters, r.Route.Cluster)
+	}
+	if r.Route.WeightedClusters != nil {
+		for _, c := range r.Route.WeightedClusters.Clusters {
+			clusters = append(clusters, c.Name)
+		}
+	}
+	return clusters
+}
+
+// httpProvenance returns the group and the sources of the Mappings that could have made the Envoy
+// route r, which sends to clusters.
+func httpProvenance(groups []irGroup, r econfRoute, clusters []string) (string, []string) {
+	match := r.Match.Prefix
+	if r.Match.Path != "" {
+		match = r.Match.Path
+	} else if r.Match.Regex != "" {
+		match = r.Match.Regex
+	} else if r.Match.SafeRegex != nil {
+		match = r.Match.SafeRegex.Regex
+	}
+	headers := map[string]bool{}
+	for _, h := range r.Match.Headers {
+		switch {
+		case h.ExactMatch != nil:
+			headers[h.Name+"="+*h.ExactMatch] = true
+		case h.RegexMatch != nil:
+			headers[h.Name+"="+*h.RegexMatch] = true
+		case h.SafeRegexMatch != nil:
+			headers[h.Name+"="+h.SafeRegexMatch.Regex] = true
+		}
+	}
+
+	best := -1
+	var ids, keys []string
+	for _, group := range groups {
+		if group.Kind != "IRHTTPMappingGroup" || !hasHeaders(headers, group.Headers) {
+			continue
+		}
+		for _, m := range group.Mappings {
+			prefix := group.Prefix
+			if m.Prefix != nil {
+				prefix = *m.Prefix
+			}
+			if prefix != match {
+				continue
+			}
+			if len(clusters) > 0 && (m.Cluster == nil || !containsString(clusters, m.Cluster.envoyName())) {
+				continue
+			}
+			if len(group.Headers) > best {
+				best, ids, keys = len(group.Headers), nil, nil
+			} else if len(group.Headers) < best {
+				continue
+			}
+			ids = appendUnique(ids, group.GroupID)
+			keys = append(keys, m.Location)
+		}
+	}
+	if len(ids) != 1 {
+		return "", keys
+	}
+	return ids[0], keys
+}
+
+// tcpProvenance returns the group and the sources of the TCPMappings that could have made the TCP
+// proxy to cluster, for the SNI serverNames.
+func tcpProvenance(groups []irGroup, cluster string, serverNames []string) (string, []string) {
+	var ids, keys []string
+	for _, group := range groups {
+		if group.Kind != "IRTCPMappingGroup" {
+			continue
+		}
+		if group.Host != "" && len(serverNames) > 0 && !containsString(serverNames, group.Host) {
+			continue
+		}
+		for _, m := range group.Mappings {
+			if m.Cluster != nil && m.Cluster.envoyName() == cluster {
+				ids = appendUnique(ids, group.GroupID)
+				keys = append(keys, m.Location)
+			}
+		}
+	}
+	if len(ids) != 1 {
+		return "", keys
+	}
+	return ids[0], keys
+}
+
+func hasHeaders(have map[string]bool, want []irHeader) bool {
+	for _, h := range want {
+		if !have[h.Name+"="+h.Value] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, have := range list {
+		if have == s {
+			return true
+		}
+	}
+	return false
+}
+
+// serveProvenance serves the provenance index, or only the routes and clusters for ?cluster=, and
+// only the routes for ?prefix= (or a path or regex).
+func serveProvenance(w http.ResponseWriter, r *http.Request) {
+	index, err := loadProvenanceIndex(GetSnapshotDir())
+	if os.IsNotExist(err) {
+		http.Error(w, "no configuration yet", http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	cluster, prefix := query.Get("cluster"), query.Get("prefix")
+	if cluster != "" || prefix != "" {
+		filtered := *index
+		filtered.Routes = []diagEnvoyRoute{}
+		for _, route := range index.Routes {
+			if cluster != "" && !containsString(route.Clusters, cluster) {
+				continue
+			}
+			if prefix != "" && route.Prefix != prefix && route.Path != prefix && route.Regex != prefix {
+				continue
+			}
+			filtered.Routes = append(filtered.Routes, route)
+		}
+		filtered.Clusters = []diagEnvoyCluster{}
+		for _, c := range index.Clusters {
+			if c.Name == cluster {
+				filtered.Clusters = append(filtered.Clusters, c)
+			}
+		}
+		index = &filtered
+	}
+
+	writeDiagJSON(w, index)
+}
@@ -0,0 +1,488 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+// The DNSSnapshot holds the endpoints that DNSResolvers have looked up for the services of their
+// mappings, keyed by dnsKey.
+type DNSSnapshot struct {
+	Endpoints map[string]DNSEndpoints `json:",omitempty"`
+}
+
+// DNSEndpoints are the endpoints of one service, as looked up by one DNSResolver.
+type DNSEndpoints struct {
+	Resolver  string
+	Namespace string
+	Service   string
+	Endpoints []DNSEndpoint
+}
+
+// A DNSEndpoint is an address from an SRV target or from an A or AAAA record. An A or AAAA record
+// has no port, so its Port is zero and the mapping's port is used.
+type DNSEndpoint struct {
+	Address string
+	Port    int
+	Weight  int
+}
+
+const (
+	defaultDNSMinRefresh    = 5 * time.Second
+	defaultDNSMaxRefresh    = 300 * time.Second
+	defaultDNSJitterPercent = 10
+)
+
+// dnsKey is what diagd looks the endpoints of a service up by.
+func dnsKey(resolver, hostname string) string {
+	return fmt.Sprintf("dns-%s-%s", resolver, hostname)
+}
+
+// dnsWatchKey identifies the watch of one service of one resolver.
+func dnsWatchKey(resolver, namespace, hostname string) string {
+	return fmt.Sprintf("%s.%s/%s", resolver, namespace, hostname)
+}
+
+// dnsHostname is the hostname of a mapping's service, the same way that diagd parses it.
+func dnsHostname(service string) string {
+	if idx := strings.Index(service, "://"); idx >= 0 {
+		service = service[idx+3:]
+	}
+	u, err := url.Parse("random://" + service)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func (s *AmbassadorInputs) ReconcileDNS(d *dnsResolvers) {
+	var mappings []*amb.Mapping
+	for _, a := range s.annotations {
+		m, ok := a.(*amb.Mapping)
+		if ok && include(m.Spec.AmbassadorID) {
+			mappings = append(mappings, m)
+		}
+	}
+	for _, m := range s.Mappings {
+		if include(m.Spec.AmbassadorID) {
+			mappings = append(mappings, m)
+		}
+	}
+
+	var resolvers []*amb.DNSResolver
+	for _, dr := range s.DNSResolvers {
+		if include(dr.Spec.AmbassadorID) {
+			resolvers = append(resolvers, dr)
+		}
+	}
+
+	d.reconcile(resolvers, mappings)
+}
+
+// A dnsLookup looks up the endpoints of a hostname, and returns how long they are good for.
+type dnsLookup func(ctx context.Context, server, hostname string) ([]DNSEndpoint, time.Duration, error)
+
+type dnsResolvers struct {
+	ctx     context.Context
+	lookup  dnsLookup
+	watches map[string]*dnsWatch
+
+	// The changed method returns this channel. We write down this channel to signal that a new
+	// snapshot is available since the last time the update method was invoked.
+	coalescedDirty chan struct{}
+	// Watches write to this when they have looked a service up. It is always being read by the
+	// implementation, so writing will never block.
+	endpointsCh chan DNSEndpoints
+
+	// The mutex protects access to watches and endpoints.
+	mutex     sync.Mutex
+	endpoints map[string]DNSEndpoints
+}
+
+type dnsWatch struct {
+	resolver  string
+	namespace string
+	hostname  string
+	spec      amb.DNSResolverSpec
+	cancel    context.CancelFunc
+	// updated is when the watch last looked the service up, or started if it never has.
+	updated time.Time
+}
+
+func newDNSResolvers(ctx context.Context, lookup dnsLookup) *dnsResolvers {
+	result := &dnsResolvers{
+		ctx:            ctx,
+		lookup:         lookup,
+		watches:        make(map[string]*dnsWatch),
+		coalescedDirty: make(chan struct{}),
+		endpointsCh:    make(chan DNSEndpoints),
+		endpoints:      make(map[string]DNSEndpoints),
+	}
+	go result.run(ctx)
+	return result
+}
+
+func (d *dnsResolvers) run(ctx context.Context) {
+	dirty := false
+	for {
+		if dirty {
+			select {
+			case d.coalescedDirty <- struct{}{}:
+				dirty = false
+			case eps := <-d.endpointsCh:
+				d.updateEndpoints(eps)
+			case <-ctx.Done():
+				return
+			}
+		} else {
+			select {
+			case eps := <-d.endpointsCh:
+				dirty = d.updateEndpoints(eps)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// updateEndpoints stores what a watch looked up, and returns whether that changed anything. Most
+// refreshes find the same records again, and there is no point in reconfiguring for those.
+func (d *dnsResolvers) updateEndpoints(eps DNSEndpoints) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	key := dnsKey(eps.Resolver, eps.Service)
+	w, ok := d.watches[dnsWatchKey(eps.Resolver, eps.Namespace, eps.Service)]
+	if !ok {
+		// The watch was stopped while it was looking the service up.
+		return false
+	}
+	w.updated = time.Now()
+	defer d.reportStatuses()
+	if old, ok := d.endpoints[key]; ok && reflect.DeepEqual(old, eps) {
+		return false
+	}
+	d.endpoints[key] = eps
+	return true
+}
+
+// reportStatuses updates the resolver metrics from the watches. The mutex must be held.
+func (d *dnsResolvers) reportStatuses() {
+	byResolver := make(map[string]*ResolverStatus)
+	for _, w := range d.watches {
+		name := fmt.Sprintf("%s.%s", w.resolver, w.namespace)
+		status, ok := byResolver[name]
+		if !ok {
+			status = &ResolverStatus{Name: w.resolver, Namespace: w.namespace, LastUpdate: w.updated}
+			byResolver[name] = status
+		}
+		status.Services++
+		if eps, ok := d.endpoints[dnsKey(w.resolver, w.hostname)]; ok && eps.Namespace == w.namespace {
+			status.Endpoints += len(eps.Endpoints)
+		}
+		if w.updated.Before(status.LastUpdate) {
+			status.LastUpdate = w.updated
+		}
+	}
+	statuses := make([]ResolverStatus, 0, len(byResolver))
+	for _, status := range byResolver {
+		statuses = append(statuses, *status)
+	}
+	metrics.setResolverStatuses("DNSResolver", statuses)
+}
+
+func (d *dnsResolvers) changed() chan struct{} {
+	return d.coalescedDirty
+}
+
+func (d *dnsResolvers) update(snap *DNSSnapshot) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	snap.Endpoints = make(map[string]DNSEndpoints, len(d.endpoints))
+	for k, v := range d.endpoints {
+		snap.Endpoints[k] = v
+	}
+}
+
+// Start and stop DNS watches as needed in order to match the supplied set of resolvers and
+// mappings. There is a watch for each service of each resolver.
+func (d *dnsResolvers) reconcile(resolvers []*amb.DNSResolver, mappings []*amb.Mapping) {
+	resolversByName := make(map[string]*amb.DNSResolver)
+	for _, dr := range resolvers {
+		resolversByName[fmt.Sprintf("%s.%s", dr.GetName(), dr.GetNamespace())] = dr
+	}
+
+	type wantedWatch struct {
+		resolver *amb.DNSResolver
+		hostname string
+	}
+	wanted := make(map[string]wantedWatch)
+	for _, m := range mappings {
+		if m.Spec.Resolver == "" {
+			continue
+		}
+		dr, ok := resolversByName[fmt.Sprintf("%s.%s", m.Spec.Resolver, m.GetNamespace())]
+		if !ok {
+			continue
+		}
+		hostname := dnsHostname(m.Spec.Service)
+		if hostname == "" {
+			continue
+		}
+		wanted[dnsWatchKey(dr.GetName(), dr.GetNamespace(), hostname)] = wantedWatch{dr, hostname}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, w := range d.watches {
+		if ww, ok := wanted[key]; !ok || !reflect.DeepEqual(ww.resolver.Spec, w.spec) {
+			w.cancel()
+			delete(d.watches, key)
+		}
+	}
+
+	// Drop the endpoints that no watch looks up anymore. The next snapshot is built right after
+	// this, so there is no need to signal a change.
+	for key, eps := range d.endpoints {
+		if _, ok := wanted[dnsWatchKey(eps.Resolver, eps.Namespace, eps.Service)]; !ok {
+			delete(d.endpoints, key)
+		}
+	}
+
+	for key, ww := range wanted {
+		if _, ok := d.watches[key]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(d.ctx)
+		d.watches[key] = &dnsWatch{
+			resolver:  ww.resolver.GetName(),
+			namespace: ww.resolver.GetNamespace(),
+			hostname:  ww.hostname,
+			spec:      ww.resolver.Spec,
+			cancel:    cancel,
+			updated:   time.Now(),
+		}
+		go d.watch(ctx, ww.resolver.DeepCopy(), ww.hostname)
+	}
+
+	d.reportStatuses()
+}
+
+// watch looks a service up again whenever its records expire, until it is stopped. A failed
+// lookup keeps the endpoints that we have, and is retried after the minimum refresh interval.
+func (d *dnsResolvers) watch(ctx context.Context, dr *amb.DNSResolver, hostname string) {
+	server := dr.Spec.Server
+	for {
+		endpoints, ttl, err := d.lookup(ctx, server, hostname)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("DNSResolver %s.%s: %s: %v", dr.GetName(), dr.GetNamespace(), hostname, err)
+			metrics.countResolverError("DNSResolver", dr.GetName(), dr.GetNamespace())
+			ttl = 0
+		} else {
+			select {
+			case d.endpointsCh <- DNSEndpoints{
+				Resolver:  dr.GetName(),
+				Namespace: dr.GetNamespace(),
+				Service:   hostname,
+				Endpoints: endpoints,
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		timer := time.NewTimer(refreshInterval(dr.Spec, ttl, rand.Float64()))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// refreshInterval is how long to wait before looking a service up again: the TTL of its records,
+// clamped to the resolver's refresh bounds, plus up to JitterPercent more. The jitter argument is
+// in [0, 1).
+func refreshInterval(spec amb.DNSResolverSpec, ttl time.Duration, jitter float64) time.Duration {
+	min, max := defaultDNSMinRefresh, defaultDNSMaxRefresh
+	if spec.MinRefreshMs > 0 {
+		min = time.Duration(spec.MinRefreshMs) * time.Millisecond
+	}
+	if spec.MaxRefreshMs > 0 {
+		max = time.Duration(spec.MaxRefreshMs) * time.Millisecond
+	}
+	if max < min {
+		max = min
+	}
+	percent := defaultDNSJitterPercent
+	if spec.JitterPercent != nil {
+		percent = *spec.JitterPercent
+	}
+
+	interval := ttl
+	if interval < min {
+		interval = min
+	}
+	if interval > max {
+		interval = max
+	}
+	return interval + time.Duration(float64(interval)*float64(percent)/100*jitter)
+}
+
+// lookupDNS looks a hostname up with SRV records, and falls back to A and AAAA records if it has
+// none. The server defaults to the first nameserver in /etc/resolv.conf.
+func lookupDNS(ctx context.Context, server, hostname string) ([]DNSEndpoint, time.Duration, error) {
+	if server == "" {
+		config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(config.Servers) == 0 {
+			return nil, 0, fmt.Errorf("no nameservers in /etc/resolv.conf")
+		}
+		server = net.JoinHostPort(config.Servers[0], config.Port)
+	}
+	name := dns.Fqdn(hostname)
+
+	srv, err := exchangeDNS(ctx, server, name, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	targets := srvTargets(srv.Answer)
+	records := append(srv.Answer, srv.Extra...)
+	if len(targets) == 0 {
+		records = nil
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			r, err := exchangeDNS(ctx, server, name, qtype)
+			if err != nil {
+				return nil, 0, err
+			}
+			records = append(records, r.Answer...)
+		}
+		addresses := recordAddresses(records, name)
+		endpoints := make([]DNSEndpoint, 0, len(addresses))
+		for _, address := range addresses {
+			endpoints = append(endpoints, DNSEndpoint{Address: address, Weight: 1})
+		}
+		return endpoints, minTTL(records), nil
+	}
+
+	var endpoints []DNSEndpoint
+	for _, target := range targets {
+		addresses := recordAddresses(records, target.Target)
+		if len(addresses) == 0 {
+			// The server didn't send the target's addresses along with the SRV records.
+			for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+				r, err := exchangeDNS(ctx, server, target.Target, qtype)
+				if err != nil {
+					return nil, 0, err
+				}
+				records = append(records, r.Answer...)
+			}
+			addresses = recordAddresses(records, target.Target)
+		}
+		weight := int(target.Weight)
+		if weight == 0 {
+			weight = 1
+		}
+		for _, address := range addresses {
+			endpoints = append(endpoints, DNSEndpoint{Address: address, Port: int(target.Port), Weight: weight})
+		}
+	}
+	return endpoints, minTTL(records), nil
+}
+
+// exchangeDNS sends one query, over TCP if the answer doesn't fit in UDP. A name that doesn't
+// exist isn't an error: it just has no records.
+func exchangeDNS(ctx context.Context, server, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	client := &dns.Client{}
+	r, _, err := client.ExchangeContext(ctx, m, server)
+	if err == nil && r.Truncated {
+		client.Net = "tcp"
+		r, _, err = client.ExchangeContext(ctx, m, server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch r.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+		return r, nil
+	}
+	return nil, fmt.Errorf("%s %s: %s", dns.TypeToString[qtype], name, dns.RcodeToString[r.Rcode])
+}
+
+// srvTargets returns the SRV records with the lowest priority. The others are backups that we
+// don't use.
+func srvTargets(answer []dns.RR) []*dns.SRV {
+	var targets []*dns.SRV
+	for _, rr := range answer {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		if len(targets) > 0 && srv.Priority > targets[0].Priority {
+			continue
+		}
+		if len(targets) > 0 && srv.Priority < targets[0].Priority {
+			targets = nil
+		}
+		targets = append(targets, srv)
+	}
+	return targets
+}
+
+// recordAddresses returns the addresses of the A and AAAA records of a name, following a few
+// CNAMEs.
+func recordAddresses(records []dns.RR, name string) []string {
+	for hops := 0; hops < 8; hops++ {
+		var addresses []string
+		cname := ""
+		for _, rr := range records {
+			if !strings.EqualFold(rr.Header().Name, name) {
+				continue
+			}
+			switch r := rr.(type) {
+			case *dns.A:
+				addresses = append(addresses, r.A.String())
+			case *dns.AAAA:
+				addresses = append(addresses, r.AAAA.String())
+			case *dns.CNAME:
+				cname = r.Target
+			}
+		}
+		if len(addresses) > 0 || cname == "" {
+			return addresses
+		}
+		name = cname
+	}
+	return nil
+}
+
+// minTTL is how long a set of records is good for.
+func minTTL(records []dns.RR) time.Duration {
+	var ttl uint32
+	for i, rr := range records {
+		if i == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
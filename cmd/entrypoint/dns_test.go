@@ -0,0 +1,114 @@
+package entrypoint
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+func TestDNSHostname(t *testing.T) {
+	assert.Equal(t, "legacy.example.com", dnsHostname("legacy.example.com"))
+	assert.Equal(t, "legacy.example.com", dnsHostname("Legacy.Example.com:8080"))
+	assert.Equal(t, "legacy.example.com", dnsHostname("https://legacy.example.com:8443"))
+	assert.Equal(t, "_http._tcp.legacy.example.com", dnsHostname("_http._tcp.legacy.example.com"))
+}
+
+func TestRefreshInterval(t *testing.T) {
+	spec := amb.DNSResolverSpec{}
+	assert.Equal(t, 5*time.Second, refreshInterval(spec, time.Second, 0))
+	assert.Equal(t, 60*time.Second, refreshInterval(spec, 60*time.Second, 0))
+	assert.Equal(t, 300*time.Second, refreshInterval(spec, time.Hour, 0))
+	assert.Equal(t, 66*time.Second, refreshInterval(spec, 60*time.Second, 1))
+
+	jitter := 50
+	spec = amb.DNSResolverSpec{MinRefreshMs: 1000, MaxRefreshMs: 10000, JitterPercent: &jitter}
+	assert.Equal(t, time.Second, refreshInterval(spec, 0, 0))
+	assert.Equal(t, 15*time.Second, refreshInterval(spec, time.Minute, 1))
+
+	jitter = 0
+	assert.Equal(t, 10*time.Second, refreshInterval(spec, time.Minute, 1))
+}
+
+func TestSRVTargets(t *testing.T) {
+	answer := []dns.RR{
+		&dns.SRV{Priority: 20, Weight: 1, Port: 80, Target: "backup.example.com."},
+		&dns.SRV{Priority: 10, Weight: 3, Port: 8080, Target: "vm1.example.com."},
+		&dns.SRV{Priority: 10, Weight: 1, Port: 8080, Target: "vm2.example.com."},
+		&dns.SRV{Priority: 30, Weight: 1, Port: 80, Target: "backup2.example.com."},
+	}
+	var targets []string
+	for _, srv := range srvTargets(answer) {
+		targets = append(targets, srv.Target)
+	}
+	assert.Equal(t, []string{"vm1.example.com.", "vm2.example.com."}, targets)
+}
+
+func TestRecordAddresses(t *testing.T) {
+	hdr := func(name string, rrtype uint16, ttl uint32) dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+	}
+	records := []dns.RR{
+		&dns.CNAME{Hdr: hdr("legacy.example.com.", dns.TypeCNAME, 300), Target: "vm1.example.com."},
+		&dns.A{Hdr: hdr("vm1.example.com.", dns.TypeA, 30), A: net.ParseIP("10.0.0.1")},
+		&dns.AAAA{Hdr: hdr("VM1.example.com.", dns.TypeAAAA, 60), AAAA: net.ParseIP("fd00::1")},
+		&dns.A{Hdr: hdr("vm2.example.com.", dns.TypeA, 30), A: net.ParseIP("10.0.0.2")},
+		&dns.CNAME{Hdr: hdr("loop.example.com.", dns.TypeCNAME, 300), Target: "loop.example.com."},
+	}
+	assert.Equal(t, []string{"10.0.0.1", "fd00::1"}, recordAddresses(records, "legacy.example.com."))
+	assert.Equal(t, []string{"10.0.0.2"}, recordAddresses(records, "vm2.example.com."))
+	assert.Empty(t, recordAddresses(records, "loop.example.com."))
+	assert.Equal(t, 30*time.Second, minTTL(records))
+}
+
+func TestReconcileDNS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lookups := make(chan string, 10)
+	d := newDNSResolvers(ctx, func(_ context.Context, _, hostname string) ([]DNSEndpoint, time.Duration, error) {
+		lookups <- hostname
+		return []DNSEndpoint{{Address: "10.0.0.1", Port: 8080, Weight: 1}}, time.Hour, nil
+	})
+
+	resolver := &amb.DNSResolver{}
+	resolver.SetName("legacy")
+	resolver.SetNamespace("default")
+	mapping := &amb.Mapping{Spec: amb.MappingSpec{Service: "vms.example.com:8080", Resolver: "legacy"}}
+	mapping.SetNamespace("default")
+	other := &amb.Mapping{Spec: amb.MappingSpec{Service: "vms.example.com", Resolver: "consul"}}
+	other.SetNamespace("default")
+
+	d.reconcile([]*amb.DNSResolver{resolver}, []*amb.Mapping{mapping, other})
+	assert.Equal(t, "vms.example.com", <-lookups)
+	select {
+	case <-d.changed():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the DNS lookup")
+	}
+
+	snap := &DNSSnapshot{}
+	d.update(snap)
+	require.Contains(t, snap.Endpoints, "dns-legacy-vms.example.com")
+	assert.Equal(t, DNSEndpoints{
+		Resolver:  "legacy",
+		Namespace: "default",
+		Service:   "vms.example.com",
+		Endpoints: []DNSEndpoint{{Address: "10.0.0.1", Port: 8080, Weight: 1}},
+	}, snap.Endpoints["dns-legacy-vms.example.com"])
+
+	// The same mapping again doesn't look the service up again.
+	d.reconcile([]*amb.DNSResolver{resolver}, []*amb.Mapping{mapping})
+	assert.Len(t, lookups, 0)
+
+	d.reconcile([]*amb.DNSResolver{resolver}, nil)
+	d.update(snap)
+	assert.Empty(t, snap.Endpoints)
+	assert.Empty(t, d.watches)
+}
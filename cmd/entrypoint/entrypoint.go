@@ -10,7 +10,6 @@ import (
 	"os/signal"
 	"path"
 	"strings"
-	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -67,6 +66,11 @@ import (
 // passes the complete snapshot of inputs along to diagd along with a list of
 // deltas and invalid objects. This snapshot is fully detailed in snapshot.go
 //
+// Only diagd gets the snapshot as JSON, encoded when it asks for it; the
+// entrypoint's own handlers use it as the watcher built it (see
+// snapshot_handoff.go). Ambex doesn't use the snapshot at all: it gets the Envoy
+// configuration that diagd makes from it, which still comes in diagd's files.
+//
 // The entrypoint goes to some trouble to ensure shared fate between all three
 // processes as well as all the goroutines it manages, i.e. if any one of them
 // dies for any reason, the whole process will shutdown and some larger process
@@ -81,18 +85,30 @@ func Main() {
 	//  - how to get errors to users?
 	//  - fork e2e tests
 
+	ctx := setupLogging()
+
 	log.Println("Started Ambassador")
 
-	clusterID := GetClusterID(context.Background())
+	clusterID := GetClusterID(ctx)
 	os.Setenv("AMBASSADOR_CLUSTER_ID", clusterID)
 	log.Printf("AMBASSADOR_CLUSTER_ID=%s", clusterID)
 
+	// diagd routes to the endpoints in our own zone first.
+	if zone := GetAmbassadorZone(ctx); zone != "" {
+		os.Setenv("AMBASSADOR_ZONE", zone)
+		log.Printf("AMBASSADOR_ZONE=%s", zone)
+	}
+
 	pec := "PYTHON_EGG_CACHE"
 	if os.Getenv(pec) == "" {
 		os.Setenv(pec, path.Join(GetAmbassadorConfigBaseDir(), ".cache"))
 	}
 	os.Setenv("PYTHONUNBUFFERED", "true")
 
+	// diagd's readiness check waits for Envoy to ACK its configuration. diagd can also run
+	// without us (under watt), so we tell it where to ask.
+	os.Setenv("AMBASSADOR_READINESS_URL", readinessURL)
+
 	ensureDir(GetAmbassadorConfigBaseDir())
 
 	// TODO: --demo
@@ -106,7 +122,7 @@ func Main() {
 	envoyHUP := make(chan os.Signal, 1)
 	signal.Notify(envoyHUP, syscall.SIGHUP)
 
-	group := NewGroup(context.Background(), 10*time.Second)
+	group := NewGroup(ctx, 10*time.Second)
 
 	group.Go("diagd", func(ctx context.Context) {
 		cmd := subcommand(ctx, "diagd", GetDiagdArgs()...)
@@ -119,16 +135,22 @@ func Main() {
 	})
 
 	group.Go("ambex", func(ctx context.Context) {
-		err := flag.CommandLine.Parse([]string{"--ads-listen-address", "127.0.0.1:8003", GetEnvoyDir()})
+		err := flag.CommandLine.Parse([]string{"--ads-listen-address", AmbexAddress, GetEnvoyDir()})
 		if err != nil {
 			panic(err)
 		}
+		ambex.OnPush = func(d time.Duration, pushed bool) {
+			metrics.observeAmbexPush(d)
+			controlPlaneTracer.observeAmbexPush(d)
+			controlPlaneReports.observeAmbexPush(d, pushed)
+		}
+		ambex.OnACK = controlPlaneReports.observeEnvoyACK
 		ambex.MainContext(ctx)
 	})
 
 	group.Go("envoy", func(ctx context.Context) { runEnvoy(ctx, envoyHUP) })
 
-	snapshot := &atomic.Value{}
+	snapshot := &snapshotHandoff{}
 	group.Go("snapshot_server", func(ctx context.Context) {
 		snapshotServer(ctx, snapshot)
 	})
@@ -136,6 +158,19 @@ func Main() {
 		watcher(ctx, snapshot)
 	})
 	group.Go("memory", watchMemory)
+	group.Go("log_signals", watchLogSignals)
+	group.Go("tapsampler", tapSamples.run)
+	group.Go("tapquota", tapUsage.run)
+	if port := GetDebugPort(); port != "" {
+		group.Go("debug_server", func(ctx context.Context) {
+			debugServer(ctx, port)
+		})
+	}
+	if storage := GetTapStorage(); storage != "" {
+		group.Go("tapserver", func(ctx context.Context) {
+			runTapServer(ctx, storage)
+		})
+	}
 
 	// Launch every file in the sidecar directory. Note that this is "bug compatible" with
 	// entrypoint.sh for now, e.g. we don't check execute bits or anything like that.
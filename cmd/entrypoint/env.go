@@ -5,7 +5,9 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func GetAgentService() string {
@@ -52,6 +54,18 @@ func GetEnvoyBootstrapFile() string {
 	return env("ENVOY_BOOTSTRAP_FILE", path.Join(GetAmbassadorConfigBaseDir(), "bootstrap-ads.json"))
 }
 
+// GetEnvoyBootstrapOverridesFile returns where the bootstrap overrides are, typically mounted from
+// a ConfigMap.
+func GetEnvoyBootstrapOverridesFile() string {
+	return env("AMBASSADOR_BOOTSTRAP_OVERRIDES", path.Join(GetAmbassadorConfigBaseDir(), "bootstrap-overrides.yaml"))
+}
+
+// GetEnvoyRunBootstrapFile returns where the bootstrap that Envoy runs with goes: diagd's, with the
+// overrides applied.
+func GetEnvoyRunBootstrapFile() string {
+	return path.Join(GetAmbassadorConfigBaseDir(), "bootstrap-envoy.json")
+}
+
 func GetEnvoyBaseId() string {
 	return env("AMBASSADOR_ENVOY_BASE_ID", "0")
 }
@@ -68,6 +82,12 @@ func GetSnapshotDir() string {
 	return env("snapshot_dir", path.Join(GetAmbassadorConfigBaseDir(), "snapshots"))
 }
 
+// GetWasmDir returns where the control plane keeps the modules of WasmFilters, named for their
+// SHA-256 checksums. diagd looks for them there too.
+func GetWasmDir() string {
+	return path.Join(GetAmbassadorConfigBaseDir(), "wasm")
+}
+
 func GetEnvoyConfigFile() string {
 	return env("envoy_config_file", path.Join(GetEnvoyDir(), "envoy.json"))
 }
@@ -81,19 +101,50 @@ func isDebug(name string) bool {
 }
 
 func GetEnvoyFlags() []string {
-	result := []string{"-c", GetEnvoyBootstrapFile(), "--base-id", GetEnvoyBaseId()}
-	svc := GetAgentService()
-	if svc != "" {
-		result = append(result, "--drain-time-s", "1")
-	} else {
-		result = append(result, "--drain-time-s", env("AMBASSADOR_DRAIN_TIME", "600"))
+	return []string{"-c", GetEnvoyRunBootstrapFile(), "--base-id", GetEnvoyBaseId(),
+		"--drain-time-s", GetEnvoyDrainTime(), "-l", GetEnvoyLogLevel()}
+}
+
+// GetEnvoyDrainTime returns how many seconds Envoy drains listeners for, when they're removed and
+// on a hot restart.
+func GetEnvoyDrainTime() string {
+	if GetAgentService() != "" {
+		return "1"
 	}
+	return env("AMBASSADOR_DRAIN_TIME", "600")
+}
+
+// GetEnvoyParentShutdownTime returns how many seconds an Envoy that's been hot restarted goes on
+// serving the connections it has before it exits. It has to be longer than the drain time, which
+// the default, like Envoy's own, is half as long again as.
+func GetEnvoyParentShutdownTime() string {
+	if s := os.Getenv("AMBASSADOR_ENVOY_PARENT_SHUTDOWN_TIME"); s != "" {
+		return s
+	}
+	drain, err := strconv.Atoi(GetEnvoyDrainTime())
+	if err != nil {
+		return "900"
+	}
+	return strconv.Itoa(drain + drain/2)
+}
+
+// GetEnvoyBinaryCheckInterval returns how often the entrypoint checks whether the Envoy binary has
+// been replaced, to hot restart Envoy when it has. Zero means it doesn't check.
+func GetEnvoyBinaryCheckInterval() time.Duration {
+	d, err := time.ParseDuration(env("AMBASSADOR_ENVOY_BINARY_CHECK_INTERVAL", "10s"))
+	if err != nil || d < 0 {
+		return 10 * time.Second
+	}
+	return d
+}
+
+// GetEnvoyLogLevel returns the log level that Envoy starts with, and goes back to when a temporary
+// change to it through /debug/loglevel reverts.
+func GetEnvoyLogLevel() string {
 	if isDebug("envoy") {
-		result = append(result, "-l", "debug")
-	} else {
-		result = append(result, "-l", "error")
+		return "debug"
 	}
-	return result
+	return "error"
 }
 
 func GetDiagdBindAddress() string {
@@ -190,3 +241,200 @@ func GetSidecarUrl() string {
 func IsKnativeEnabled() bool {
 	return strings.ToLower(env("AMBASSADOR_KNATIVE_SUPPORT", "")) == "true"
 }
+
+// GetOTLPEndpoint returns the OTLP/HTTP traces URL (like http://collector:4318/v1/traces) to send
+// control plane traces to. Tracing is off if it's empty.
+func GetOTLPEndpoint() string {
+	return env("AMBASSADOR_OTLP_ENDPOINT", "")
+}
+
+// GetAuditSink returns where to send audit events for configuration changes: a file, an
+// http(s):// webhook, or a kafka+http(s):// Kafka REST proxy topic. Auditing is off if it's empty.
+func GetAuditSink() string {
+	return env("AMBASSADOR_AUDIT_SINK", "")
+}
+
+// GetCrashDir returns where a goroutine that panics writes its crash bundle.
+func GetCrashDir() string {
+	return env("AMBASSADOR_CRASH_DIR", path.Join(GetAmbassadorConfigBaseDir(), "crashes"))
+}
+
+// GetCrashWebhook returns the URL to POST crash bundles to, as well as writing them. Nothing is
+// POSTed if it's empty.
+func GetCrashWebhook() string {
+	return env("AMBASSADOR_CRASH_WEBHOOK", "")
+}
+
+// GetTapStorage returns where the tap collector saves the traces that TapPolicies with a grpc
+// output sink stream to it: a directory, stdout:, or an s3:// bucket. The collector is off if
+// it's empty.
+func GetTapStorage() string {
+	return env("AMBASSADOR_TAP_STORAGE", "")
+}
+
+// GetTapRedaction returns the file with the rules for what the tap collector redacts from
+// traces before it saves them. If it's empty, credential headers are redacted.
+func GetTapRedaction() string {
+	return env("AMBASSADOR_TAP_REDACTION", "")
+}
+
+// GetTapMaxBytes returns how many bytes all the TapPolicies together may capture before the one
+// that goes over is disabled. There's no limit if it's empty.
+func GetTapMaxBytes() string {
+	return env("AMBASSADOR_TAP_MAX_BYTES", "")
+}
+
+// GetDebugPort returns the localhost port to serve pprof, goroutine dumps, the control plane's
+// timers, the log levels and Envoy hot restarts on. The debug server is off if it's empty.
+func GetDebugPort() string {
+	return env("AMBASSADOR_DEBUG_PORT", "")
+}
+
+// GetDebugTokenFile returns the file with the bearer token that the debug server requires. If the
+// file doesn't exist, the entrypoint writes a random token to it.
+func GetDebugTokenFile() string {
+	return env("AMBASSADOR_DEBUG_TOKEN_FILE", path.Join(GetAmbassadorConfigBaseDir(), "debug-token"))
+}
+
+// GetDebugAllow returns the comma-separated endpoints that the debug server serves, out of pprof,
+// goroutines, timers, memory, loglevel and hot-restart.
+func GetDebugAllow() string {
+	return env("AMBASSADOR_DEBUG_ALLOW", "pprof,goroutines,timers,memory,loglevel,hot-restart")
+}
+
+// GetLogFormat returns how the entrypoint and ambex write their logs: "text", or "json" for a JSON
+// object per line.
+func GetLogFormat() string {
+	return env("AMBASSADOR_LOG_FORMAT", "text")
+}
+
+// GetLogLevel returns the log levels of the entrypoint and ambex, in the form that
+// dlog.ParseLevels parses: a default level and levels for modules, such as "info,ambex=debug".
+func GetLogLevel() string {
+	return env("AMBASSADOR_LOG_LEVEL", "info")
+}
+
+// GetLogSignalDuration returns how long SIGUSR1 raises every log level, Envoy's included, to debug
+// for, before they revert.
+func GetLogSignalDuration() time.Duration {
+	d, err := time.ParseDuration(env("AMBASSADOR_LOG_SIGNAL_DURATION", "10m"))
+	if err != nil || d <= 0 {
+		return 10 * time.Minute
+	}
+	return d
+}
+
+// GetReconfigReportCount returns how many reconfiguration reports /reconfigs keeps.
+func GetReconfigReportCount() int {
+	n, err := strconv.Atoi(env("AMBASSADOR_RECONFIG_REPORTS", "20"))
+	if err != nil || n <= 0 {
+		return 20
+	}
+	return n
+}
+
+// GetMemoryThresholds returns the percentages of the cgroup's memory limit at which memory
+// pressure is high, and critical. Either can be 100 or more to never get there.
+func GetMemoryThresholds() (int, int) {
+	high, err := strconv.Atoi(env("AMBASSADOR_MEMORY_HIGH_PERCENT", "80"))
+	if err != nil || high <= 0 {
+		high = 80
+	}
+	critical, err := strconv.Atoi(env("AMBASSADOR_MEMORY_CRITICAL_PERCENT", "95"))
+	if err != nil || critical <= 0 {
+		critical = 95
+	}
+	return high, critical
+}
+
+// GetOPAURL returns the URL of the REST API of the OPA that evaluates Ambassador's resources
+// against policies before they go into the snapshot, like http://127.0.0.1:8181. Policy
+// enforcement is off if it's empty.
+func GetOPAURL() string {
+	return env("AMBASSADOR_OPA_URL", "")
+}
+
+// GetOPADecision returns the path, under OPA's /v1/data, of the rule whose result is the reasons
+// to deny a resource.
+func GetOPADecision() string {
+	return strings.Trim(env("AMBASSADOR_OPA_DECISION", "ambassador/deny"), "/")
+}
+
+// GetOPABundleURL returns the URL of an OPA bundle to load policies from, as well as from the
+// ConfigMaps labelled getambassador.io/opa-policy. No bundle is loaded if it's empty.
+func GetOPABundleURL() string {
+	return env("AMBASSADOR_OPA_BUNDLE_URL", "")
+}
+
+// GetOPABundleInterval returns how often to check the OPA bundle for changes.
+func GetOPABundleInterval() time.Duration {
+	d, err := time.ParseDuration(env("AMBASSADOR_OPA_BUNDLE_INTERVAL", "60s"))
+	if err != nil || d <= 0 {
+		return time.Minute
+	}
+	return d
+}
+
+// GetExternalDNS returns how to publish the Hosts' hostnames for external-dns: "dnsendpoint"
+// writes a DNSEndpoint for each Host, "annotation" annotates Ambassador's Service with them.
+// Nothing is published if it's empty.
+func GetExternalDNS() string {
+	return strings.ToLower(env("AMBASSADOR_EXTERNAL_DNS", ""))
+}
+
+// GetExternalDNSService returns the name of the Service, in Ambassador's namespace, whose load
+// balancer the Hosts' records point at. If it's empty, it's the Service labelled
+// app.kubernetes.io/component: ambassador-service.
+func GetExternalDNSService() string {
+	return env("AMBASSADOR_EXTERNAL_DNS_SERVICE", "")
+}
+
+// GetExternalDNSTTL returns the TTL, in seconds, of the DNSEndpoints' records. If it's zero,
+// external-dns uses its provider's default.
+func GetExternalDNSTTL() int64 {
+	ttl, err := strconv.ParseInt(env("AMBASSADOR_EXTERNAL_DNS_TTL", "0"), 10, 64)
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// GetAWSTargetGroups returns the JSON list of AWS target groups to register Ambassador's pods
+// in, each bound to a port of a Service in Ambassador's namespace. No pods are registered if
+// it's empty.
+func GetAWSTargetGroups() string {
+	return env("AMBASSADOR_AWS_TARGET_GROUPS", "")
+}
+
+// GetAWSDeregistrationDelay returns the deregistration delay to set on the AWS target groups: how
+// long the load balancer drains a pod's connections after it's deregistered. If it's zero, the
+// target groups' own delay is left alone.
+func GetAWSDeregistrationDelay() time.Duration {
+	d, err := time.ParseDuration(env("AMBASSADOR_AWS_DEREGISTRATION_DELAY", "0s"))
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetSharedState returns where the replicas of this Ambassador keep the state that they share:
+// "redis:HOST:PORT", "rediss:HOST:PORT" or "configmap:NAME". Nothing is shared if it's empty.
+func GetSharedState() string {
+	return env("AMBASSADOR_SHARED_STATE", "")
+}
+
+// GetSharedStateRedisPassword returns the password of the Redis server in GetSharedState(), or
+// "" if it has none.
+func GetSharedStateRedisPassword() string {
+	return env("AMBASSADOR_SHARED_STATE_REDIS_PASSWORD", "")
+}
+
+// GetSharedStateLeaseTTL returns how long the leader's Lease lasts without being renewed: how
+// long the other replicas wait for a leader that goes away without handing it over.
+func GetSharedStateLeaseTTL() time.Duration {
+	d, err := time.ParseDuration(env("AMBASSADOR_SHARED_STATE_LEASE_TTL", "15s"))
+	if err != nil || d <= 0 {
+		return 15 * time.Second
+	}
+	return d
+}
@@ -0,0 +1,426 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// Ambassador can publish the hostnames of its Hosts for external-dns
+// (https://github.com/kubernetes-sigs/external-dns), so that DNS records track them without
+// anyone writing the records by hand. GetExternalDNS() says how:
+//
+//   - "dnsendpoint" writes a DNSEndpoint, the resource of external-dns's crd source, for each
+//     Host, pointing at the load balancer addresses of Ambassador's Service. Each DNSEndpoint is
+//     owned by its Host, so Kubernetes deletes it along with the Host.
+//   - "annotation" sets external-dns's hostname annotation on Ambassador's Service to the Hosts'
+//     hostnames, for external-dns's service source, which finds the addresses itself.
+//
+// Ambassador's Service is GetExternalDNSService() in Ambassador's namespace or, by default, the
+// Service there labelled app.kubernetes.io/component: ambassador-service, as diagd finds it.
+
+const (
+	externalDNSEndpoints  = "dnsendpoint"
+	externalDNSAnnotation = "annotation"
+
+	// externalDNSLabel marks the DNSEndpoints that an Ambassador wrote, with its AMBASSADOR_ID,
+	// so that it finds the ones to delete when their Hosts go away.
+	externalDNSLabel = "getambassador.io/external-dns"
+	// externalDNSHostnameAnnotation is the annotation that external-dns's service source reads.
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+
+	externalDNSRetry = 30 * time.Second
+)
+
+// externalDNSRecords are what to publish: the hostnames of the Hosts, and the addresses of the
+// Service that they point at.
+type externalDNSRecords struct {
+	Service kates.ObjectMeta
+	Hosts   []externalDNSHost
+	Targets []string
+}
+
+type externalDNSHost struct {
+	Name      string
+	Namespace string
+	UID       kates.UID
+	Hostname  string
+}
+
+// ReconcileExternalDNS hands the records for the Hosts to external-dns. They're written in the
+// background, so a slow API server never delays reconfiguration.
+func (s *AmbassadorInputs) ReconcileExternalDNS(e *externalDNS) {
+	if e == nil {
+		return
+	}
+
+	svc := ambassadorService(s.Services, GetExternalDNSService(), GetAmbassadorNamespace())
+	if svc == nil {
+		e.update(nil, "Ambassador's Service wasn't found")
+		return
+	}
+	records := &externalDNSRecords{Service: kates.ObjectMeta{Name: svc.GetName(), Namespace: svc.GetNamespace()}}
+	if e.mode == externalDNSEndpoints {
+		// With the annotation, external-dns finds the addresses itself.
+		records.Targets = loadBalancerTargets(svc)
+		if len(records.Targets) == 0 {
+			e.update(nil, "Service "+svc.GetName()+"."+svc.GetNamespace()+" has no load balancer addresses yet")
+			return
+		}
+	}
+
+	for _, h := range s.Hosts {
+		if h.Spec == nil || !include(GetAmbId(h)) {
+			continue
+		}
+		hostname := strings.ToLower(strings.TrimSuffix(h.Spec.Hostname, "."))
+		if hostname == "" || hostname == "*" {
+			continue
+		}
+		records.Hosts = append(records.Hosts, externalDNSHost{
+			Name:      h.GetName(),
+			Namespace: h.GetNamespace(),
+			UID:       h.GetUID(),
+			Hostname:  hostname,
+		})
+	}
+	sort.Slice(records.Hosts, func(i, j int) bool {
+		a, b := records.Hosts[i], records.Hosts[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	e.update(records, "")
+}
+
+// ambassadorService returns the Service named name in namespace or, if name is empty, the first
+// one there labelled as Ambassador's.
+func ambassadorService(services []*kates.Service, name, namespace string) *kates.Service {
+	var found *kates.Service
+	for _, svc := range services {
+		if svc.GetNamespace() != namespace {
+			continue
+		}
+		if name != "" {
+			if svc.GetName() == name {
+				return svc
+			}
+			continue
+		}
+		if strings.ToLower(svc.GetLabels()["app.kubernetes.io/component"]) != "ambassador-service" {
+			continue
+		}
+		if found == nil || svc.GetName() < found.GetName() {
+			found = svc
+		}
+	}
+	return found
+}
+
+// loadBalancerTargets returns the addresses of a Service's load balancer, sorted.
+func loadBalancerTargets(svc *kates.Service) []string {
+	var targets []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			targets = append(targets, ingress.IP)
+		} else if ingress.Hostname != "" {
+			targets = append(targets, ingress.Hostname)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// externalDNSRecordSet returns the endpoints of a DNSEndpoint for hostname: A and AAAA records for
+// the IP addresses or, if there are none, a CNAME record for the first hostname, since a name
+// with a CNAME can't have other records.
+func externalDNSRecordSet(hostname string, targets []string, ttl int64) []interface{} {
+	byType := make(map[string][]interface{})
+	var cname string
+	for _, target := range targets {
+		ip := net.ParseIP(target)
+		switch {
+		case ip == nil:
+			if cname == "" {
+				cname = target
+			}
+		case ip.To4() != nil:
+			byType["A"] = append(byType["A"], target)
+		default:
+			byType["AAAA"] = append(byType["AAAA"], target)
+		}
+	}
+	if len(byType) == 0 && cname != "" {
+		byType["CNAME"] = []interface{}{cname}
+	}
+
+	var endpoints []interface{}
+	for _, recordType := range []string{"A", "AAAA", "CNAME"} {
+		if len(byType[recordType]) == 0 {
+			continue
+		}
+		endpoint := map[string]interface{}{
+			"dnsName":    hostname,
+			"recordType": recordType,
+			"targets":    byType[recordType],
+		}
+		if ttl > 0 {
+			endpoint["recordTTL"] = ttl
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// dnsEndpoints returns the DNSEndpoints for the records, one for each Host, named and namespaced
+// as it is.
+func dnsEndpoints(records *externalDNSRecords, ambassadorID string, ttl int64) []*kates.Unstructured {
+	var result []*kates.Unstructured
+	for _, h := range records.Hosts {
+		endpoint := kates.NewUnstructured("DNSEndpoint", "externaldns.k8s.io/v1alpha1")
+		endpoint.SetName(h.Name)
+		endpoint.SetNamespace(h.Namespace)
+		endpoint.SetLabels(map[string]string{externalDNSLabel: ambassadorID})
+		controller := true
+		endpoint.SetOwnerReferences([]kates.OwnerReference{{
+			APIVersion: "getambassador.io/v2",
+			Kind:       "Host",
+			Name:       h.Name,
+			UID:        h.UID,
+			Controller: &controller,
+		}})
+		endpoint.Object["spec"] = map[string]interface{}{
+			"endpoints": externalDNSRecordSet(h.Hostname, records.Targets, ttl),
+		}
+		result = append(result, endpoint)
+	}
+	return result
+}
+
+// planDNSEndpoints compares the DNSEndpoints there are with the ones there should be, and returns
+// the ones to create, the ones to update (with the resourceVersion of the ones there are), and
+// the ones to delete.
+func planDNSEndpoints(existing, wanted []*kates.Unstructured) (create, update, remove []*kates.Unstructured) {
+	byKey := make(map[string]*kates.Unstructured)
+	for _, obj := range existing {
+		byKey[obj.GetNamespace()+"/"+obj.GetName()] = obj
+	}
+	for _, obj := range wanted {
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		old, ok := byKey[key]
+		delete(byKey, key)
+		if !ok {
+			create = append(create, obj)
+			continue
+		}
+		if sameDNSEndpoint(old, obj) {
+			continue
+		}
+		obj = obj.DeepCopy()
+		obj.SetResourceVersion(old.GetResourceVersion())
+		update = append(update, obj)
+	}
+	for _, obj := range existing {
+		if _, ok := byKey[obj.GetNamespace()+"/"+obj.GetName()]; ok {
+			remove = append(remove, obj)
+		}
+	}
+	return create, update, remove
+}
+
+// sameDNSEndpoint returns whether a DNSEndpoint there is has what's wanted. They're compared as
+// JSON, since the one there is has come back from the API server.
+func sameDNSEndpoint(existing, wanted *kates.Unstructured) bool {
+	normal := func(obj *kates.Unstructured) string {
+		data, _ := json.Marshal(map[string]interface{}{
+			"labels": obj.GetLabels(),
+			"owners": obj.GetOwnerReferences(),
+			"spec":   obj.Object["spec"],
+		})
+		return string(data)
+	}
+	return normal(existing) == normal(wanted)
+}
+
+// externalDNS writes the records that ReconcileExternalDNS hands it, whenever they change.
+type externalDNS struct {
+	mode         string
+	client       *kates.Client
+	namespace    string
+	ambassadorID string
+	ttl          int64
+	leader       leadership
+
+	// dirty has room for one signal, so that update never blocks, and any number of updates
+	// between two writes make one write.
+	dirty chan struct{}
+
+	// The mutex protects access to records and waiting.
+	mutex   sync.Mutex
+	records *externalDNSRecords
+	waiting string
+}
+
+func newExternalDNS(ctx context.Context, mode string, client *kates.Client, namespace string, leader leadership) *externalDNS {
+	result := &externalDNS{
+		mode:         mode,
+		client:       client,
+		namespace:    namespace,
+		ambassadorID: GetAmbassadorId(),
+		ttl:          GetExternalDNSTTL(),
+		leader:       leader,
+		dirty:        make(chan struct{}, 1),
+	}
+	go result.run(ctx)
+	return result
+}
+
+// update sets the records to write. Nil records leave what's been written alone, for the reason
+// given, which is logged once.
+func (e *externalDNS) update(records *externalDNSRecords, reason string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if records == nil {
+		if reason != e.waiting {
+			log.Printf("external-dns: not updating records: %s", reason)
+			e.waiting = reason
+		}
+		return
+	}
+	e.waiting = ""
+	if reflect.DeepEqual(records, e.records) {
+		return
+	}
+	e.records = records
+	select {
+	case e.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (e *externalDNS) run(ctx context.Context) {
+	var written *externalDNSRecords
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-e.dirty:
+		case <-retry:
+		case <-ctx.Done():
+			return
+		}
+		retry = nil
+
+		e.mutex.Lock()
+		records := e.records
+		e.mutex.Unlock()
+		if records == nil || reflect.DeepEqual(records, written) {
+			continue
+		}
+		if !e.leader.leads() {
+			// Another replica writes the records. Check back, in case it goes away, and write
+			// them all over again if this one takes over.
+			written = nil
+			retry = time.After(externalDNSRetry)
+			continue
+		}
+
+		var err error
+		if e.mode == externalDNSAnnotation {
+			err = e.annotate(ctx, records)
+		} else {
+			err = e.writeDNSEndpoints(ctx, records)
+		}
+		if err != nil {
+			log.Printf("external-dns: %v (retrying in %v)", err, externalDNSRetry)
+			retry = time.After(externalDNSRetry)
+			continue
+		}
+		written = records
+	}
+}
+
+func (e *externalDNS) writeDNSEndpoints(ctx context.Context, records *externalDNSRecords) error {
+	var existing []*kates.Unstructured
+	err := e.client.List(ctx, kates.Query{
+		Kind:          "DNSEndpoint",
+		Namespace:     e.namespace,
+		LabelSelector: externalDNSLabel + "=" + e.ambassadorID,
+	}, &existing)
+	if err != nil {
+		return err
+	}
+
+	create, update, remove := planDNSEndpoints(existing, dnsEndpoints(records, e.ambassadorID, e.ttl))
+	for _, obj := range create {
+		if err := e.client.Create(ctx, obj, nil); err != nil {
+			return err
+		}
+	}
+	for _, obj := range update {
+		if err := e.client.Update(ctx, obj, nil); err != nil {
+			return err
+		}
+	}
+	for _, obj := range remove {
+		if err := e.client.Delete(ctx, obj, nil); err != nil && !kates.IsNotFound(err) {
+			return err
+		}
+	}
+	if len(create)+len(update)+len(remove) > 0 {
+		log.Printf("external-dns: %d DNSEndpoints created, %d updated, %d deleted",
+			len(create), len(update), len(remove))
+	}
+	return nil
+}
+
+// annotate sets the hostname annotation of Ambassador's Service to the Hosts' hostnames, or
+// removes it if there are none.
+func (e *externalDNS) annotate(ctx context.Context, records *externalDNSRecords) error {
+	var value interface{}
+	if hostnames := externalDNSHostnames(records); hostnames != "" {
+		value = hostnames
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{externalDNSHostnameAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	svc := &kates.Service{
+		TypeMeta:   kates.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: records.Service,
+	}
+	return e.client.Patch(ctx, svc, kates.MergePatchType, patch, nil)
+}
+
+// externalDNSHostnames returns the Hosts' hostnames as the hostname annotation has them: sorted,
+// without duplicates, and separated by commas.
+func externalDNSHostnames(records *externalDNSRecords) string {
+	seen := make(map[string]bool)
+	var hostnames []string
+	for _, h := range records.Hosts {
+		if !seen[h.Hostname] {
+			seen[h.Hostname] = true
+			hostnames = append(hostnames, h.Hostname)
+		}
+	}
+	sort.Strings(hostnames)
+	return strings.Join(hostnames, ",")
+}
+
+// isExternalDNSMode returns whether mode is a GetExternalDNS() that's understood.
+func isExternalDNSMode(mode string) bool {
+	return mode == externalDNSEndpoints || mode == externalDNSAnnotation
+}
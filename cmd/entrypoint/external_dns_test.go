@@ -0,0 +1,170 @@
+package entrypoint
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func externalDNSService(name string, labels map[string]string, addresses ...kates.LoadBalancerIngress) *kates.Service {
+	svc := &kates.Service{}
+	svc.SetName(name)
+	svc.SetNamespace("ambassador")
+	svc.SetLabels(labels)
+	svc.Status.LoadBalancer.Ingress = addresses
+	return svc
+}
+
+func externalDNSHostResource(name, namespace, hostname string, ids ...string) *amb.Host {
+	h := &amb.Host{Spec: &amb.HostSpec{Hostname: hostname, AmbassadorID: ids}}
+	h.SetName(name)
+	h.SetNamespace(namespace)
+	h.SetUID(kates.UID(name + "-uid"))
+	return h
+}
+
+func TestReconcileExternalDNS(t *testing.T) {
+	os.Setenv("AMBASSADOR_NAMESPACE", "ambassador")
+	defer os.Unsetenv("AMBASSADOR_NAMESPACE")
+
+	component := map[string]string{"app.kubernetes.io/component": "ambassador-service"}
+	s := &AmbassadorInputs{
+		Services: []*kates.Service{
+			externalDNSService("quote", nil, kates.LoadBalancerIngress{IP: "192.0.2.9"}),
+			externalDNSService("ambassador", component,
+				kates.LoadBalancerIngress{IP: "192.0.2.2"}, kates.LoadBalancerIngress{IP: "192.0.2.1"}),
+		},
+		Hosts: []*amb.Host{
+			externalDNSHostResource("shop", "web", "Shop.Example.com."),
+			externalDNSHostResource("api", "web", "api.example.com"),
+			externalDNSHostResource("everything", "web", "*"),
+			externalDNSHostResource("other", "web", "other.example.com", "someone-else"),
+			externalDNSHostResource("admin", "internal", "admin.example.com"),
+		},
+	}
+
+	e := &externalDNS{mode: externalDNSEndpoints, dirty: make(chan struct{}, 1)}
+	s.ReconcileExternalDNS(e)
+	require.NotNil(t, e.records)
+	assert.Equal(t, "ambassador", e.records.Service.Name)
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, e.records.Targets)
+	assert.Equal(t, []externalDNSHost{
+		{Name: "admin", Namespace: "internal", UID: "admin-uid", Hostname: "admin.example.com"},
+		{Name: "api", Namespace: "web", UID: "api-uid", Hostname: "api.example.com"},
+		{Name: "shop", Namespace: "web", UID: "shop-uid", Hostname: "shop.example.com"},
+	}, e.records.Hosts)
+	assert.Len(t, e.dirty, 1)
+
+	// The same records again don't need writing.
+	<-e.dirty
+	s.ReconcileExternalDNS(e)
+	assert.Len(t, e.dirty, 0)
+
+	// Without load balancer addresses, what's been written is left alone.
+	records := e.records
+	s.Services[1].Status.LoadBalancer.Ingress = nil
+	s.ReconcileExternalDNS(e)
+	assert.Equal(t, records, e.records)
+	assert.Contains(t, e.waiting, "no load balancer addresses")
+
+	// The annotation doesn't need them.
+	e = &externalDNS{mode: externalDNSAnnotation, dirty: make(chan struct{}, 1)}
+	s.ReconcileExternalDNS(e)
+	require.NotNil(t, e.records)
+	assert.Empty(t, e.records.Targets)
+	assert.Equal(t, "admin.example.com,api.example.com,shop.example.com", externalDNSHostnames(e.records))
+
+	os.Setenv("AMBASSADOR_EXTERNAL_DNS_SERVICE", "missing")
+	defer os.Unsetenv("AMBASSADOR_EXTERNAL_DNS_SERVICE")
+	e = &externalDNS{mode: externalDNSAnnotation, dirty: make(chan struct{}, 1)}
+	s.ReconcileExternalDNS(e)
+	assert.Nil(t, e.records)
+	assert.Equal(t, "Ambassador's Service wasn't found", e.waiting)
+
+	var off *externalDNS
+	s.ReconcileExternalDNS(off)
+}
+
+func TestAmbassadorService(t *testing.T) {
+	component := map[string]string{"app.kubernetes.io/component": "Ambassador-Service"}
+	services := []*kates.Service{
+		externalDNSService("edge", component),
+		externalDNSService("admin", nil),
+		externalDNSService("ambassador", component),
+	}
+	assert.Equal(t, "ambassador", ambassadorService(services, "", "ambassador").GetName())
+	assert.Equal(t, "admin", ambassadorService(services, "admin", "ambassador").GetName())
+	assert.Nil(t, ambassadorService(services, "", "default"))
+}
+
+func TestExternalDNSRecordSet(t *testing.T) {
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"dnsName": "shop.example.com", "recordType": "A",
+			"targets": []interface{}{"192.0.2.1", "192.0.2.2"}},
+		map[string]interface{}{"dnsName": "shop.example.com", "recordType": "AAAA",
+			"targets": []interface{}{"2001:db8::1"}},
+	}, externalDNSRecordSet("shop.example.com", []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "lb.example.net"}, 0))
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"dnsName": "shop.example.com", "recordType": "CNAME",
+			"targets": []interface{}{"a.elb.example.net"}, "recordTTL": int64(300)},
+	}, externalDNSRecordSet("shop.example.com", []string{"a.elb.example.net", "b.elb.example.net"}, 300))
+}
+
+func TestPlanDNSEndpoints(t *testing.T) {
+	records := &externalDNSRecords{
+		Targets: []string{"192.0.2.1"},
+		Hosts: []externalDNSHost{
+			{Name: "api", Namespace: "web", UID: "api-uid", Hostname: "api.example.com"},
+			{Name: "shop", Namespace: "web", UID: "shop-uid", Hostname: "shop.example.com"},
+		},
+	}
+	wanted := dnsEndpoints(records, "default", 0)
+	require.Len(t, wanted, 2)
+	assert.Equal(t, "DNSEndpoint", wanted[0].GetKind())
+	assert.Equal(t, "externaldns.k8s.io/v1alpha1", wanted[0].GetAPIVersion())
+	assert.Equal(t, map[string]string{externalDNSLabel: "default"}, wanted[0].GetLabels())
+	owners := wanted[0].GetOwnerReferences()
+	require.Len(t, owners, 1)
+	assert.Equal(t, "Host", owners[0].Kind)
+	assert.Equal(t, kates.UID("api-uid"), owners[0].UID)
+
+	create, update, remove := planDNSEndpoints(nil, wanted)
+	assert.Equal(t, wanted, create)
+	assert.Empty(t, update)
+	assert.Empty(t, remove)
+
+	// What's there has come back from the API server, with a resourceVersion.
+	var existing []*kates.Unstructured
+	for _, obj := range dnsEndpoints(&externalDNSRecords{
+		Targets: []string{"192.0.2.1"},
+		Hosts: []externalDNSHost{
+			{Name: "api", Namespace: "web", UID: "api-uid", Hostname: "api.example.com"},
+			{Name: "old", Namespace: "web", UID: "old-uid", Hostname: "old.example.com"},
+		},
+	}, "default", 0) {
+		var un kates.Unstructured
+		require.NoError(t, convert(obj, &un))
+		un.SetResourceVersion("7")
+		existing = append(existing, &un)
+	}
+
+	create, update, remove = planDNSEndpoints(existing, wanted)
+	require.Len(t, create, 1)
+	assert.Equal(t, "shop", create[0].GetName())
+	assert.Empty(t, update, "api hasn't changed")
+	require.Len(t, remove, 1)
+	assert.Equal(t, "old", remove[0].GetName())
+
+	records.Targets = []string{"192.0.2.2"}
+	create, update, _ = planDNSEndpoints(existing, dnsEndpoints(records, "default", 0))
+	assert.Len(t, create, 1)
+	require.Len(t, update, 1)
+	assert.Equal(t, "api", update[0].GetName())
+	assert.Equal(t, "7", update[0].GetResourceVersion())
+}
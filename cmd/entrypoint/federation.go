@@ -0,0 +1,422 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// The FederationSnapshot holds the endpoints that MultiClusterResolvers have found for the
+// services of their mappings in each of their clusters, keyed by federationKey.
+type FederationSnapshot struct {
+	Endpoints map[string]FederatedEndpoints `json:",omitempty"`
+}
+
+// FederatedEndpoints are the endpoints of one service in one cluster of one MultiClusterResolver.
+type FederatedEndpoints struct {
+	Resolver         string
+	Namespace        string
+	Cluster          string
+	Service          string
+	ServiceNamespace string
+	// Ports maps each TCP port of the service, by number, to the endpoints behind it. The
+	// endpoints of a service with a single port are also under "*".
+	Ports map[string][]FederatedEndpoint
+}
+
+// A FederatedEndpoint is a ready address of a service in another cluster, with its target port.
+type FederatedEndpoint struct {
+	Address string
+	Port    int
+}
+
+// How long to wait before watching a cluster again after we couldn't.
+const federationRetryInterval = 30 * time.Second
+
+// federationKey is what diagd looks the endpoints of a service in a cluster up by.
+func federationKey(resolver, cluster, service, namespace string) string {
+	return fmt.Sprintf("mc-%s-%s-%s-%s", resolver, cluster, service, namespace)
+}
+
+// federationWatchKey identifies the watch of one service in one cluster of one resolver.
+func federationWatchKey(resolver, namespace, cluster, service, serviceNamespace string) string {
+	return fmt.Sprintf("%s.%s/%s/%s.%s", resolver, namespace, cluster, service, serviceNamespace)
+}
+
+// federatedService is the name and namespace of a mapping's service, the same way that diagd
+// parses it: "svc.namespace" or "svc" in the mapping's namespace.
+func federatedService(m *amb.Mapping) (string, string) {
+	hostname := dnsHostname(m.Spec.Service)
+	if hostname == "" {
+		return "", ""
+	}
+	if parts := strings.SplitN(hostname, ".", 3); len(parts) > 1 {
+		return parts[0], parts[1]
+	}
+	namespace := m.GetNamespace()
+	if namespace == "" {
+		namespace = GetAmbassadorNamespace()
+	}
+	return hostname, namespace
+}
+
+func (s *AmbassadorInputs) ReconcileFederation(f *federation) {
+	var mappings []*amb.Mapping
+	for _, a := range s.annotations {
+		m, ok := a.(*amb.Mapping)
+		if ok && include(m.Spec.AmbassadorID) {
+			mappings = append(mappings, m)
+		}
+	}
+	for _, m := range s.Mappings {
+		if include(m.Spec.AmbassadorID) {
+			mappings = append(mappings, m)
+		}
+	}
+
+	var resolvers []*amb.MultiClusterResolver
+	for _, mr := range s.MultiClusterResolvers {
+		if include(mr.Spec.AmbassadorID) {
+			resolvers = append(resolvers, mr)
+		}
+	}
+
+	f.reconcile(resolvers, mappings)
+}
+
+// A federatedServiceWatch watches a service and its endpoints in one cluster, and calls update
+// with them whenever either changes, until the context is done. Either may be nil if it doesn't
+// exist.
+type federatedServiceWatch func(ctx context.Context, kubeconfig string, cluster amb.FederatedCluster,
+	service, namespace string, update func(*kates.Service, *kates.Endpoints)) error
+
+type federation struct {
+	ctx          context.Context
+	watchService federatedServiceWatch
+	watches      map[string]*federatedWatch
+
+	// The changed method returns this channel. We write down this channel to signal that a new
+	// snapshot is available since the last time the update method was invoked.
+	coalescedDirty chan struct{}
+	// Watches write to this when a service or its endpoints change. It is always being read by
+	// the implementation, so writing will never block.
+	endpointsCh chan FederatedEndpoints
+
+	// The mutex protects access to watches and endpoints.
+	mutex     sync.Mutex
+	endpoints map[string]FederatedEndpoints
+}
+
+type federatedWatch struct {
+	resolver         string
+	namespace        string
+	kubeconfig       string
+	cluster          amb.FederatedCluster
+	service          string
+	serviceNamespace string
+	cancel           context.CancelFunc
+	// updated is when the watch last saw a change, or started if it never has.
+	updated time.Time
+}
+
+func newFederation(ctx context.Context, watchService federatedServiceWatch) *federation {
+	result := &federation{
+		ctx:            ctx,
+		watchService:   watchService,
+		watches:        make(map[string]*federatedWatch),
+		coalescedDirty: make(chan struct{}),
+		endpointsCh:    make(chan FederatedEndpoints),
+		endpoints:      make(map[string]FederatedEndpoints),
+	}
+	go result.run(ctx)
+	return result
+}
+
+func (f *federation) run(ctx context.Context) {
+	dirty := false
+	for {
+		if dirty {
+			select {
+			case f.coalescedDirty <- struct{}{}:
+				dirty = false
+			case eps := <-f.endpointsCh:
+				f.updateEndpoints(eps)
+			case <-ctx.Done():
+				return
+			}
+		} else {
+			select {
+			case eps := <-f.endpointsCh:
+				dirty = f.updateEndpoints(eps)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// updateEndpoints stores what a watch saw, and returns whether that changed anything.
+func (f *federation) updateEndpoints(eps FederatedEndpoints) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	w, ok := f.watches[federationWatchKey(eps.Resolver, eps.Namespace, eps.Cluster, eps.Service, eps.ServiceNamespace)]
+	if !ok {
+		// The watch was stopped while it was sending.
+		return false
+	}
+	w.updated = time.Now()
+	defer f.reportStatuses()
+	key := federationKey(eps.Resolver, eps.Cluster, eps.Service, eps.ServiceNamespace)
+	if old, ok := f.endpoints[key]; ok && reflect.DeepEqual(old, eps) {
+		return false
+	}
+	f.endpoints[key] = eps
+	return true
+}
+
+// reportStatuses updates the resolver metrics from the watches. The mutex must be held.
+func (f *federation) reportStatuses() {
+	byResolver := make(map[string]*ResolverStatus)
+	for _, w := range f.watches {
+		name := fmt.Sprintf("%s.%s", w.resolver, w.namespace)
+		status, ok := byResolver[name]
+		if !ok {
+			status = &ResolverStatus{Name: w.resolver, Namespace: w.namespace, LastUpdate: w.updated}
+			byResolver[name] = status
+		}
+		status.Services++
+		key := federationKey(w.resolver, w.cluster.Name, w.service, w.serviceNamespace)
+		if eps, ok := f.endpoints[key]; ok && eps.Namespace == w.namespace {
+			status.Endpoints += federatedEndpointCount(eps)
+		}
+		if w.updated.Before(status.LastUpdate) {
+			status.LastUpdate = w.updated
+		}
+	}
+	statuses := make([]ResolverStatus, 0, len(byResolver))
+	for _, status := range byResolver {
+		statuses = append(statuses, *status)
+	}
+	metrics.setResolverStatuses("MultiClusterResolver", statuses)
+}
+
+// federatedEndpointCount counts the distinct addresses of a service, whatever their ports.
+func federatedEndpointCount(eps FederatedEndpoints) int {
+	addresses := make(map[string]bool)
+	for _, endpoints := range eps.Ports {
+		for _, ep := range endpoints {
+			addresses[ep.Address] = true
+		}
+	}
+	return len(addresses)
+}
+
+func (f *federation) changed() chan struct{} {
+	return f.coalescedDirty
+}
+
+func (f *federation) update(snap *FederationSnapshot) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	snap.Endpoints = make(map[string]FederatedEndpoints, len(f.endpoints))
+	for k, v := range f.endpoints {
+		snap.Endpoints[k] = v
+	}
+}
+
+// Start and stop watches as needed in order to match the supplied set of resolvers and mappings.
+// There is a watch for each service of each resolver in each of its clusters.
+func (f *federation) reconcile(resolvers []*amb.MultiClusterResolver, mappings []*amb.Mapping) {
+	resolversByName := make(map[string]*amb.MultiClusterResolver)
+	for _, mr := range resolvers {
+		resolversByName[fmt.Sprintf("%s.%s", mr.GetName(), mr.GetNamespace())] = mr
+	}
+
+	wanted := make(map[string]*federatedWatch)
+	for _, m := range mappings {
+		if m.Spec.Resolver == "" {
+			continue
+		}
+		mr, ok := resolversByName[fmt.Sprintf("%s.%s", m.Spec.Resolver, m.GetNamespace())]
+		if !ok {
+			continue
+		}
+		service, namespace := federatedService(m)
+		if service == "" {
+			continue
+		}
+		for _, cluster := range mr.Spec.Clusters {
+			key := federationWatchKey(mr.GetName(), mr.GetNamespace(), cluster.Name, service, namespace)
+			wanted[key] = &federatedWatch{
+				resolver:         mr.GetName(),
+				namespace:        mr.GetNamespace(),
+				kubeconfig:       mr.Spec.Kubeconfig,
+				cluster:          cluster,
+				service:          service,
+				serviceNamespace: namespace,
+			}
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	// A change to a cluster's weight or priority is up to diagd; only a change to where the
+	// cluster is restarts its watches.
+	for key, w := range f.watches {
+		ww, ok := wanted[key]
+		if !ok || ww.kubeconfig != w.kubeconfig || ww.cluster.Context != w.cluster.Context ||
+			ww.cluster.Local != w.cluster.Local {
+			w.cancel()
+			delete(f.watches, key)
+		}
+	}
+
+	// Drop the endpoints that no watch looks for anymore. The next snapshot is built right after
+	// this, so there is no need to signal a change.
+	for key, eps := range f.endpoints {
+		if _, ok := wanted[federationWatchKey(eps.Resolver, eps.Namespace, eps.Cluster, eps.Service, eps.ServiceNamespace)]; !ok {
+			delete(f.endpoints, key)
+		}
+	}
+
+	for key, ww := range wanted {
+		if _, ok := f.watches[key]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(f.ctx)
+		ww.cancel = cancel
+		ww.updated = time.Now()
+		f.watches[key] = ww
+		go f.watch(ctx, *ww)
+	}
+
+	f.reportStatuses()
+}
+
+// watch watches a service in a cluster until it is stopped. If the cluster can't be reached, we
+// keep the endpoints that we have, and try again later.
+func (f *federation) watch(ctx context.Context, w federatedWatch) {
+	update := func(svc *kates.Service, eps *kates.Endpoints) {
+		select {
+		case f.endpointsCh <- FederatedEndpoints{
+			Resolver:         w.resolver,
+			Namespace:        w.namespace,
+			Cluster:          w.cluster.Name,
+			Service:          w.service,
+			ServiceNamespace: w.serviceNamespace,
+			Ports:            federatedPorts(svc, eps),
+		}:
+		case <-ctx.Done():
+		}
+	}
+	for {
+		err := f.watchService(ctx, w.kubeconfig, w.cluster, w.service, w.serviceNamespace, update)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("MultiClusterResolver %s.%s: cluster %s: %s.%s: %v", w.resolver, w.namespace,
+			w.cluster.Name, w.service, w.serviceNamespace, err)
+		metrics.countResolverError("MultiClusterResolver", w.resolver, w.namespace)
+
+		timer := time.NewTimer(federationRetryInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// federatedPorts maps each TCP port of a service to the ready addresses behind it, with their
+// target ports. The endpoints of a port have the port's name, so that's how we match them up.
+func federatedPorts(svc *kates.Service, eps *kates.Endpoints) map[string][]FederatedEndpoint {
+	ports := make(map[string][]FederatedEndpoint)
+	if svc == nil || eps == nil {
+		return ports
+	}
+	var tcpPorts []kates.ServicePort
+	for _, sp := range svc.Spec.Ports {
+		if sp.Protocol == "" || sp.Protocol == "TCP" {
+			tcpPorts = append(tcpPorts, sp)
+		}
+	}
+	for _, sp := range tcpPorts {
+		var endpoints []FederatedEndpoint
+		for _, subset := range eps.Subsets {
+			for _, ep := range subset.Ports {
+				if ep.Name != sp.Name {
+					continue
+				}
+				for _, addr := range subset.Addresses {
+					endpoints = append(endpoints, FederatedEndpoint{Address: addr.IP, Port: int(ep.Port)})
+				}
+			}
+		}
+		ports[strconv.Itoa(int(sp.Port))] = endpoints
+		if len(tcpPorts) == 1 {
+			ports["*"] = endpoints
+		}
+	}
+	return ports
+}
+
+// watchFederatedService is the federatedServiceWatch that talks to the clusters.
+func watchFederatedService(ctx context.Context, kubeconfig string, cluster amb.FederatedCluster,
+	service, namespace string, update func(*kates.Service, *kates.Endpoints)) error {
+	options := kates.ClientOptions{}
+	if !cluster.Local {
+		options.Kubeconfig = kubeconfig
+		options.Context = cluster.Context
+	}
+	client, err := kates.NewClient(options)
+	if err != nil {
+		return err
+	}
+
+	fs := "metadata.name=" + service
+	queries := []kates.Query{
+		{Name: "Services", Kind: "Service", Namespace: namespace, FieldSelector: fs},
+		{Name: "Endpoints", Kind: "Endpoints", Namespace: namespace, FieldSelector: fs},
+	}
+
+	// The accumulator panics if it can't reach the cluster, so make sure that it can first.
+	var services []*kates.Service
+	if err := client.List(ctx, queries[0], &services); err != nil {
+		return err
+	}
+
+	acc := client.Watch(ctx, queries...)
+	var snapshot struct {
+		Services  []*kates.Service
+		Endpoints []*kates.Endpoints
+	}
+	for {
+		select {
+		case <-acc.Changed():
+			if !acc.Update(&snapshot) {
+				continue
+			}
+			var svc *kates.Service
+			if len(snapshot.Services) > 0 {
+				svc = snapshot.Services[0]
+			}
+			var eps *kates.Endpoints
+			if len(snapshot.Endpoints) > 0 {
+				eps = snapshot.Endpoints[0]
+			}
+			update(svc, eps)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
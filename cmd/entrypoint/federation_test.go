@@ -0,0 +1,101 @@
+package entrypoint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func TestFederatedService(t *testing.T) {
+	mapping := &amb.Mapping{Spec: amb.MappingSpec{Service: "quote.backends:8080"}}
+	mapping.SetNamespace("default")
+	svc, ns := federatedService(mapping)
+	assert.Equal(t, "quote", svc)
+	assert.Equal(t, "backends", ns)
+
+	mapping.Spec.Service = "http://quote"
+	svc, ns = federatedService(mapping)
+	assert.Equal(t, "quote", svc)
+	assert.Equal(t, "default", ns)
+}
+
+func TestFederatedPorts(t *testing.T) {
+	svc := &kates.Service{Spec: kates.ServiceSpec{Ports: []kates.ServicePort{
+		{Name: "http", Port: 80},
+		{Name: "grpc", Port: 9000},
+		{Name: "dns", Port: 53, Protocol: "UDP"},
+	}}}
+	eps := &kates.Endpoints{Subsets: []kates.EndpointSubset{{
+		Addresses:         []kates.EndpointAddress{{IP: "10.1.0.1"}, {IP: "10.1.0.2"}},
+		NotReadyAddresses: []kates.EndpointAddress{{IP: "10.1.0.3"}},
+		Ports:             []kates.EndpointPort{{Name: "http", Port: 8080}, {Name: "grpc", Port: 9090}},
+	}}}
+
+	ports := federatedPorts(svc, eps)
+	assert.Equal(t, []FederatedEndpoint{{"10.1.0.1", 8080}, {"10.1.0.2", 8080}}, ports["80"])
+	assert.Equal(t, []FederatedEndpoint{{"10.1.0.1", 9090}, {"10.1.0.2", 9090}}, ports["9000"])
+	assert.NotContains(t, ports, "53")
+	assert.NotContains(t, ports, "*")
+
+	svc.Spec.Ports = svc.Spec.Ports[:1]
+	ports = federatedPorts(svc, eps)
+	assert.Equal(t, ports["80"], ports["*"])
+
+	assert.Empty(t, federatedPorts(nil, eps))
+}
+
+func TestReconcileFederation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watched := make(chan string, 10)
+	f := newFederation(ctx, func(ctx context.Context, _ string, cluster amb.FederatedCluster, service, namespace string,
+		update func(*kates.Service, *kates.Endpoints)) error {
+		watched <- cluster.Name + "/" + service + "." + namespace
+		update(
+			&kates.Service{Spec: kates.ServiceSpec{Ports: []kates.ServicePort{{Port: 80}}}},
+			&kates.Endpoints{Subsets: []kates.EndpointSubset{{
+				Addresses: []kates.EndpointAddress{{IP: "10.1.0.1"}},
+				Ports:     []kates.EndpointPort{{Port: 8080}},
+			}}})
+		<-ctx.Done()
+		return nil
+	})
+
+	resolver := &amb.MultiClusterResolver{Spec: amb.MultiClusterResolverSpec{
+		Clusters: []amb.FederatedCluster{{Name: "east", Context: "east"}},
+	}}
+	resolver.SetName("federated")
+	resolver.SetNamespace("default")
+	mapping := &amb.Mapping{Spec: amb.MappingSpec{Service: "quote.backends", Resolver: "federated"}}
+	mapping.SetNamespace("default")
+
+	f.reconcile([]*amb.MultiClusterResolver{resolver}, []*amb.Mapping{mapping})
+	assert.Equal(t, "east/quote.backends", <-watched)
+	select {
+	case <-f.changed():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the endpoints")
+	}
+
+	snap := &FederationSnapshot{}
+	f.update(snap)
+	require.Contains(t, snap.Endpoints, "mc-federated-east-quote-backends")
+	assert.Equal(t, []FederatedEndpoint{{"10.1.0.1", 8080}}, snap.Endpoints["mc-federated-east-quote-backends"].Ports["*"])
+
+	// A new weight doesn't restart the watch.
+	resolver.Spec.Clusters[0].Weight = 3
+	f.reconcile([]*amb.MultiClusterResolver{resolver}, []*amb.Mapping{mapping})
+	assert.Len(t, watched, 0)
+
+	f.reconcile([]*amb.MultiClusterResolver{resolver}, nil)
+	f.update(snap)
+	assert.Empty(t, snap.Endpoints)
+	assert.Empty(t, f.watches)
+}
@@ -0,0 +1,75 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// The gatewayFeatureSupport type describes how much of a given Gateway API kind, filter, or field
+// this build of Ambassador understands.
+type gatewayFeatureSupport string
+
+const (
+	// The feature is translated into Envoy configuration with the semantics the Gateway API spec
+	// describes.
+	gatewaySupported gatewayFeatureSupport = "supported"
+	// The feature is translated, but the result differs from the Gateway API spec in some way
+	// that is described by the feature's Caveats.
+	gatewayCaveats gatewayFeatureSupport = "caveats"
+	// The feature is ignored.
+	gatewayUnsupported gatewayFeatureSupport = "unsupported"
+)
+
+// The GatewayFeature struct is a single entry in the feature discovery report.
+type GatewayFeature struct {
+	// Group and Kind identify the Gateway API resource that the feature belongs to.
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	// Field is the JSONPath (relative to the resource) of the field or filter that the entry
+	// describes. It is empty for entries that describe the kind as a whole.
+	Field   string                `json:"field,omitempty"`
+	Support gatewayFeatureSupport `json:"support"`
+	Caveats string                `json:"caveats,omitempty"`
+}
+
+// The GatewayFeatureReport struct is what gets served by the feature discovery endpoint.
+type GatewayFeatureReport struct {
+	// APIVersions is the list of Gateway API versions that the report applies to.
+	APIVersions []string         `json:"apiVersions"`
+	Features    []GatewayFeature `json:"features"`
+}
+
+const gatewayAPIGroup = "networking.x-k8s.io"
+
+// The gatewayFeatures variable is the single source of truth for what this build does with
+// Gateway API resources. Platform teams gate rollouts on this, so any change to Gateway API
+// translation needs a matching change here.
+//
+// This build does not watch or translate any Gateway API resources, so everything is reported
+// as unsupported.
+var gatewayFeatures = GatewayFeatureReport{
+	APIVersions: []string{gatewayAPIGroup + "/v1alpha1"},
+	Features: []GatewayFeature{
+		{Group: gatewayAPIGroup, Kind: "GatewayClass", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "Gateway", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "HTTPRoute", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "HTTPRoute", Field: ".spec.rules[].filters[?(@.type==\"RequestHeaderModifier\")]", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "HTTPRoute", Field: ".spec.rules[].filters[?(@.type==\"RequestMirror\")]", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "HTTPRoute", Field: ".spec.rules[].forwardTo[].weight", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "TCPRoute", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "TLSRoute", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "UDPRoute", Support: gatewayUnsupported},
+		{Group: gatewayAPIGroup, Kind: "BackendPolicy", Support: gatewayUnsupported},
+	},
+}
+
+// The handleGatewayFeatures function serves the gatewayFeatures report as JSON.
+func handleGatewayFeatures(w http.ResponseWriter, r *http.Request) {
+	bytes, err := json.MarshalIndent(gatewayFeatures, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
@@ -0,0 +1,39 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check that the feature report round trips through the handler.
+func TestGatewayFeaturesHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleGatewayFeatures(rec, httptest.NewRequest(http.MethodGet, "/gateway-api/features", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var report GatewayFeatureReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, gatewayFeatures, report)
+}
+
+// Check that every entry in the report is well formed.
+func TestGatewayFeaturesWellFormed(t *testing.T) {
+	for _, f := range gatewayFeatures.Features {
+		assert.NotEmpty(t, f.Group)
+		assert.NotEmpty(t, f.Kind)
+		switch f.Support {
+		case gatewaySupported, gatewayUnsupported:
+		case gatewayCaveats:
+			assert.NotEmpty(t, f.Caveats, "%s %s: caveats must be described", f.Kind, f.Field)
+		default:
+			assert.Fail(t, "bad support level", "%s %s: %q", f.Kind, f.Field, f.Support)
+		}
+	}
+}
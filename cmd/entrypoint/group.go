@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"runtime/debug"
 	"sync"
 	"time"
+
+	"github.com/datawire/ambassador/pkg/dlog"
 )
 
 // XXX: should we replace with Luke's stuff?
@@ -30,6 +33,10 @@ type Group struct {
 	mutex   sync.Mutex
 	running map[string]bool
 	paniced map[string]interface{}
+
+	// Called with the panic and stack of a goroutine that panics, before the others are told
+	// to exit.
+	onPanic func(name string, err interface{}, stack []byte)
 }
 
 func NewGroup(parent context.Context, grace time.Duration) *Group {
@@ -40,10 +47,11 @@ func NewGroup(parent context.Context, grace time.Duration) *Group {
 		cancel:  cancel,
 		running: map[string]bool{},
 		paniced: map[string]interface{}{},
+		onPanic: reportCrash,
 	}
 }
 
-// Launch a goroutine as part of the group.
+// Launch a goroutine as part of the group. It logs as the module of its name.
 func (g *Group) Go(name string, f func(context.Context)) {
 	g.do(func() {
 		_, ok := g.running[name]
@@ -56,7 +64,7 @@ func (g *Group) Go(name string, f func(context.Context)) {
 	g.wg.Add(1)
 	go func() {
 		// exit bookeeping:
-		//  1. updated running and paniced maps
+		//  1. updated running and paniced maps, and report a panic
 		//  2. call g.wg.Done() to signal we have exited
 		//  3. cancel the context so others know to exit
 		//  4. if we are the first to exit, start a watchdog in case others don't exit
@@ -68,12 +76,13 @@ func (g *Group) Go(name string, f func(context.Context)) {
 			} else {
 				g.do(func() { g.running[name] = false; g.paniced[name] = err })
 				log.Printf("EXIT %s panic: %v", name, err)
+				g.onPanic(name, err, debug.Stack())
 			}
 			g.wg.Done()
 			g.cancel()
 			g.once.Do(func() { go g.watchdog() })
 		}()
-		f(g.ctx)
+		f(dlog.WithField(g.ctx, dlog.ComponentField, name))
 	}()
 }
 
@@ -0,0 +1,283 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Envoy's hot restart hands its listening sockets from one Envoy process to the next: a new Envoy
+// is started with the same --base-id and the next --restart-epoch, takes the sockets over from the
+// one that's running (its parent), and tells the parent to drain. The parent goes on serving the
+// connections it has until they close, or until --parent-shutdown-time-s is up, then exits. So
+// upgrading the Envoy binary in place needn't drop long-lived connections, as restarting Envoy
+// outright would.
+
+// A hotRestarter runs Envoy, and hot restarts it on request.
+type hotRestarter struct {
+	// command returns the command that runs the Envoy of the given epoch.
+	command func(ctx context.Context, epoch int) *exec.Cmd
+	// hotRestartVersion returns the hot restart version of the Envoy binary, which has to be the
+	// same for the new Envoy as for the running one.
+	hotRestartVersion func(ctx context.Context) (string, error)
+
+	exited chan envoyExit
+
+	mu       sync.Mutex
+	ctx      context.Context // the context Envoy runs in; nil until run starts it
+	version  string          // the hot restart version of the Envoy that run started
+	running  map[int]*exec.Cmd
+	latest   int // the epoch of the newest Envoy that's running
+	restarts int
+	lastErr  error
+}
+
+// An envoyExit is an Envoy process exiting.
+type envoyExit struct {
+	epoch int
+	err   error
+}
+
+func newHotRestarter() *hotRestarter {
+	return &hotRestarter{
+		command: func(ctx context.Context, epoch int) *exec.Cmd {
+			cmd := subcommand(ctx, "envoy", GetEnvoyHotRestartFlags(epoch)...)
+			if envbool("DEV_SHUTUP_ENVOY") {
+				cmd.Stdout = nil
+				cmd.Stderr = nil
+			}
+			return cmd
+		},
+		hotRestartVersion: envoyHotRestartVersion,
+		exited:            make(chan envoyExit),
+		running:           map[int]*exec.Cmd{},
+	}
+}
+
+// envoyRestarts is the hotRestarter that runEnvoy runs Envoy with, when it runs it directly.
+var envoyRestarts = newHotRestarter()
+
+// envoyHotRestartVersion returns what `envoy --hot-restart-version` says.
+func envoyHotRestartVersion(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "envoy", "--hot-restart-version").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// start starts the Envoy of the given epoch. The caller must hold h.mu.
+func (h *hotRestarter) start(epoch int) error {
+	cmd := h.command(h.ctx, epoch)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	h.running[epoch] = cmd
+	h.latest = epoch
+	go func() {
+		h.exited <- envoyExit{epoch: epoch, err: cmd.Wait()}
+	}()
+	return nil
+}
+
+// run runs Envoy, hot restarting it whenever restart is called, until no Envoy is running: that
+// is, until the newest one exits other than by being replaced, or ctx is done.
+func (h *hotRestarter) run(ctx context.Context) error {
+	version, err := h.hotRestartVersion(ctx)
+	if err != nil {
+		log.Printf("envoy hot restart: can't get the hot restart version, so hot restarts are off: %v", err)
+	}
+
+	h.mu.Lock()
+	h.ctx = ctx
+	h.version = version
+	err = h.start(0)
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for {
+		exit := <-h.exited
+
+		h.mu.Lock()
+		delete(h.running, exit.epoch)
+		if len(h.running) == 0 {
+			h.mu.Unlock()
+			return exit.err
+		}
+		if exit.epoch < h.latest {
+			// The parent of a hot restart, done draining.
+			log.Printf("envoy hot restart: epoch %d has shut down (%v)", exit.epoch, exit.err)
+		} else {
+			// The child of a hot restart that didn't take: its parent never stopped serving, so
+			// it's the newest Envoy again.
+			h.latest = 0
+			for epoch := range h.running {
+				if epoch > h.latest {
+					h.latest = epoch
+				}
+			}
+			h.lastErr = fmt.Errorf("epoch %d exited: %v", exit.epoch, exit.err)
+			log.Printf("envoy hot restart: epoch %d exited (%v); epoch %d is still running", exit.epoch, exit.err, h.latest)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// restart hot restarts Envoy, and returns the epoch of the new Envoy. It only starts the new
+// Envoy: whether that goes on to take over from the running one is up to Envoy, and shows in the
+// epochs that are running afterwards.
+func (h *hotRestarter) restart() (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	epoch, err := h.restartLocked()
+	if err != nil {
+		h.lastErr = err
+		return 0, err
+	}
+	h.restarts++
+	h.lastErr = nil
+	return epoch, nil
+}
+
+func (h *hotRestarter) restartLocked() (int, error) {
+	if h.ctx == nil || len(h.running) == 0 {
+		return 0, fmt.Errorf("envoy isn't running")
+	}
+	if err := h.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if h.version == "" {
+		return 0, fmt.Errorf("the hot restart version of the running envoy isn't known")
+	}
+	version, err := h.hotRestartVersion(h.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getting the hot restart version: %w", err)
+	}
+	if version != h.version {
+		// Envoys whose shared memory or RPC layouts differ can't hand over to each other, and the
+		// new one would just fail to start.
+		return 0, fmt.Errorf("hot restart version has changed from %q to %q: envoy has to be restarted outright", h.version, version)
+	}
+	epoch := h.latest + 1
+	if err := h.start(epoch); err != nil {
+		return 0, err
+	}
+	log.Printf("envoy hot restart: started epoch %d", epoch)
+	return epoch, nil
+}
+
+// hotRestartStatus is what /debug/hot-restart returns.
+type hotRestartStatus struct {
+	Epoch             int    `json:"epoch"`
+	Running           []int  `json:"running"`
+	Restarts          int    `json:"restarts"`
+	HotRestartVersion string `json:"hot_restart_version,omitempty"`
+	LastError         string `json:"last_error,omitempty"`
+}
+
+func (h *hotRestarter) status() hotRestartStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := hotRestartStatus{
+		Epoch:             h.latest,
+		Running:           []int{},
+		Restarts:          h.restarts,
+		HotRestartVersion: h.version,
+	}
+	for epoch := 0; epoch <= h.latest; epoch++ {
+		if _, ok := h.running[epoch]; ok {
+			status.Running = append(status.Running, epoch)
+		}
+	}
+	if h.lastErr != nil {
+		status.LastError = h.lastErr.Error()
+	}
+	return status
+}
+
+// handleHotRestart serves the debug server's /debug/hot-restart, which says what epochs of Envoy
+// are running on GET, and hot restarts Envoy on POST.
+func (h *hotRestarter) handleHotRestart(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if _, err := h.restart(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.status()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// watchEnvoyBinary hot restarts Envoy when the Envoy binary at path is replaced. A change only
+// counts once the file has stayed the same for a whole interval, so that a binary that's still
+// being copied into place isn't run.
+func watchEnvoyBinary(ctx context.Context, h *hotRestarter, path string, interval time.Duration) {
+	stat := func() (os.FileInfo, bool) {
+		info, err := os.Stat(path)
+		return info, err == nil
+	}
+	same := func(a, b os.FileInfo) bool {
+		return a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+	}
+
+	current, ok := stat()
+	if !ok {
+		return
+	}
+	var pending os.FileInfo
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, ok := stat()
+		switch {
+		case !ok || same(info, current):
+			pending = nil
+		case pending == nil || !same(info, pending):
+			pending = info
+		default:
+			log.Printf("envoy hot restart: %s has changed", path)
+			if _, err := h.restart(); err != nil {
+				log.Printf("envoy hot restart: not restarting: %v", err)
+			}
+			// Either way, this is the binary now: there's no point trying it again until it
+			// changes again.
+			current, pending = info, nil
+		}
+	}
+}
+
+// GetEnvoyHotRestartFlags returns the flags that the Envoy of the given restart epoch runs with.
+func GetEnvoyHotRestartFlags(epoch int) []string {
+	return append(GetEnvoyFlags(),
+		"--restart-epoch", strconv.Itoa(epoch),
+		"--parent-shutdown-time-s", GetEnvoyParentShutdownTime())
+}
@@ -0,0 +1,188 @@
+package entrypoint
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEnvoys is a hotRestarter whose Envoys are shell commands.
+type fakeEnvoys struct {
+	*hotRestarter
+
+	mu      sync.Mutex
+	scripts map[int]string // by epoch; the default is to run until killed
+	version string
+}
+
+func newFakeEnvoys() *fakeEnvoys {
+	f := &fakeEnvoys{hotRestarter: newHotRestarter(), scripts: map[int]string{}, version: "11.104"}
+	f.command = func(ctx context.Context, epoch int) *exec.Cmd {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		script, ok := f.scripts[epoch]
+		if !ok {
+			script = "exec sleep 60"
+		}
+		return exec.CommandContext(ctx, "sh", "-c", script)
+	}
+	f.hotRestartVersion = func(context.Context) (string, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.version, nil
+	}
+	return f
+}
+
+// kill kills the Envoy of the given epoch, as it would exit once it's drained.
+func (f *fakeEnvoys) kill(t *testing.T, epoch int) {
+	f.hotRestarter.mu.Lock()
+	defer f.hotRestarter.mu.Unlock()
+	require.Contains(t, f.running, epoch)
+	require.NoError(t, f.running[epoch].Process.Kill())
+}
+
+func waitForRunning(t *testing.T, f *fakeEnvoys, epochs ...int) hotRestartStatus {
+	var status hotRestartStatus
+	require.Eventually(t, func() bool {
+		status = f.status()
+		return assert.ObjectsAreEqual(epochs, status.Running)
+	}, 5*time.Second, 10*time.Millisecond, "want %v running", epochs)
+	return status
+}
+
+func TestHotRestart(t *testing.T) {
+	f := newFakeEnvoys()
+
+	_, err := f.restart()
+	assert.EqualError(t, err, "envoy isn't running")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- f.run(ctx) }()
+	waitForRunning(t, f, 0)
+
+	// The new Envoy starts alongside the old one, which exits once it's drained.
+	epoch, err := f.restart()
+	require.NoError(t, err)
+	assert.Equal(t, 1, epoch)
+	waitForRunning(t, f, 0, 1)
+	f.kill(t, 0)
+	status := waitForRunning(t, f, 1)
+	assert.Equal(t, 1, status.Epoch)
+	assert.Equal(t, 1, status.Restarts)
+	assert.Empty(t, status.LastError)
+
+	// A new Envoy that fails leaves the old one serving, and the next try is the same epoch.
+	f.mu.Lock()
+	f.scripts[2] = "exit 1"
+	f.mu.Unlock()
+	epoch, err = f.restart()
+	require.NoError(t, err)
+	assert.Equal(t, 2, epoch)
+	require.Eventually(t, func() bool { return f.status().LastError != "" }, 5*time.Second, 10*time.Millisecond)
+	status = waitForRunning(t, f, 1)
+	assert.Equal(t, 1, status.Epoch)
+	assert.Contains(t, status.LastError, "epoch 2 exited")
+
+	// An Envoy that can't take over from the running one isn't started at all.
+	f.mu.Lock()
+	f.version = "12.104"
+	f.mu.Unlock()
+	_, err = f.restart()
+	assert.EqualError(t, err, `hot restart version has changed from "11.104" to "12.104": envoy has to be restarted outright`)
+	waitForRunning(t, f, 1)
+
+	// Once the newest Envoy exits, so does run.
+	cancel()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("run didn't return")
+	}
+	_, err = f.restart()
+	assert.Error(t, err)
+}
+
+func TestHotRestartNewestExits(t *testing.T) {
+	f := newFakeEnvoys()
+	f.scripts[0] = "exit 0"
+	assert.NoError(t, f.run(context.Background()))
+}
+
+func TestHandleHotRestart(t *testing.T) {
+	f := newFakeEnvoys()
+
+	rec := httptest.NewRecorder()
+	f.handleHotRestart(rec, httptest.NewRequest(http.MethodPost, "/debug/hot-restart", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.run(ctx)
+	waitForRunning(t, f, 0)
+
+	rec = httptest.NewRecorder()
+	f.handleHotRestart(rec, httptest.NewRequest(http.MethodPost, "/debug/hot-restart", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"epoch": 1, "running": [0, 1], "restarts": 1, "hot_restart_version": "11.104"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	f.handleHotRestart(rec, httptest.NewRequest(http.MethodDelete, "/debug/hot-restart", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestWatchEnvoyBinary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envoy-binary")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	binary := filepath.Join(dir, "envoy")
+	require.NoError(t, ioutil.WriteFile(binary, []byte("envoy 1"), 0755))
+
+	f := newFakeEnvoys()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.run(ctx)
+	waitForRunning(t, f, 0)
+	go watchEnvoyBinary(ctx, f.hotRestarter, binary, 10*time.Millisecond)
+
+	// Nothing's changed, so nothing happens.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, f.status().Restarts)
+
+	require.NoError(t, ioutil.WriteFile(binary, []byte("envoy 2, which is longer"), 0755))
+	waitForRunning(t, f, 0, 1)
+	assert.Equal(t, 1, f.status().Restarts)
+
+	// Once is enough.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, f.status().Restarts)
+}
+
+func TestGetEnvoyParentShutdownTime(t *testing.T) {
+	for _, name := range []string{"AMBASSADOR_DRAIN_TIME", "AMBASSADOR_ENVOY_PARENT_SHUTDOWN_TIME", "AGENT_SERVICE"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+
+	assert.Equal(t, "900", GetEnvoyParentShutdownTime())
+	os.Setenv("AMBASSADOR_DRAIN_TIME", "60")
+	assert.Equal(t, "90", GetEnvoyParentShutdownTime())
+	os.Setenv("AMBASSADOR_ENVOY_PARENT_SHUTDOWN_TIME", "120")
+	assert.Equal(t, "120", GetEnvoyParentShutdownTime())
+
+	flags := GetEnvoyHotRestartFlags(3)
+	assert.Equal(t, []string{"--restart-epoch", "3", "--parent-shutdown-time-s", "120"}, flags[len(flags)-4:])
+}
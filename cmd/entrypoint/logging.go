@@ -0,0 +1,323 @@
+package entrypoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+// logLevels are the log levels of the goroutines in the entrypoint's Group, each of which logs as
+// the module of its name, and of the entrypoint itself, which logs as "entrypoint". /debug/loglevel
+// on the debug server changes them.
+var logLevels = dlog.NewLevels(dlog.LogLevelInfo)
+
+// setupLogging sets up the logger that the entrypoint and ambex log to, as AMBASSADOR_LOG_FORMAT and
+// AMBASSADOR_LOG_LEVEL say, and returns a context with it. The log package logs to it too, as the
+// "entrypoint" module, since most of the entrypoint still uses that.
+func setupLogging() context.Context {
+	var logger dlog.Logger
+	if strings.EqualFold(GetLogFormat(), "json") {
+		logger = dlog.NewJSONLogger(os.Stderr)
+	} else {
+		text := logrus.New()
+		text.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+		text.SetLevel(logrus.TraceLevel)
+		logger = dlog.WrapLogrus(text)
+	}
+
+	levels, levelsErr := dlog.ParseLevels(GetLogLevel())
+	if levelsErr == nil {
+		logLevels = levels
+	}
+	logger = dlog.WithLevels(logger, logLevels)
+	dlog.SetFallbackLogger(logger)
+	ctx := dlog.WithLogger(context.Background(), logger)
+
+	log.SetFlags(0)
+	log.SetOutput(logWriter{dlog.WithField(ctx, dlog.ComponentField, "entrypoint")})
+	if levelsErr != nil {
+		log.Printf("Ignoring AMBASSADOR_LOG_LEVEL: %v", levelsErr)
+	}
+	return ctx
+}
+
+// logWriter logs each line that the log package writes to it as an Info entry. Unlike a
+// dlog.StdLogger, it logs before Write returns, so that log.Fatal's line isn't lost.
+type logWriter struct {
+	ctx context.Context
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	dlog.Print(w.ctx, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// envoyModule is the module whose level is Envoy's own log level, as well as that of the
+// goroutine that runs Envoy.
+const envoyModule = "envoy"
+
+// logLevelChanges makes the changes to logLevels that /debug/loglevel and the log signals ask for, and
+// reverts the ones that are only for a while.
+type logLevelChanges struct {
+	// setEnvoy sets Envoy's log level through its admin interface.
+	setEnvoy func(level string) error
+
+	mu      sync.Mutex
+	reverts map[string]*logRevert
+}
+
+// A logRevert is a pending revert of a module's level, to what it was before the first of the
+// temporary changes that it reverts.
+type logRevert struct {
+	timer *time.Timer
+	at    time.Time
+	level dlog.LogLevel
+	set   bool // whether the module had a level of its own
+}
+
+var logChanges = &logLevelChanges{
+	setEnvoy: func(level string) error { return setEnvoyLogLevel(envoyAdminURL, level) },
+	reverts:  map[string]*logRevert{},
+}
+
+// set sets the level of module, or the default level if module is "", and reverts it after d
+// unless d is 0.
+func (c *logLevelChanges) set(module string, level dlog.LogLevel, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if module == envoyModule {
+		if err := c.setEnvoy(envoyLogLevel(level)); err != nil {
+			return err
+		}
+	}
+
+	revert, ok := c.reverts[module]
+	if ok {
+		revert.timer.Stop()
+		delete(c.reverts, module)
+	} else {
+		revert = &logRevert{}
+		revert.level, revert.set = logLevels.Lookup(module)
+	}
+	logLevels.Set(module, level)
+
+	if d > 0 {
+		revert.at = time.Now().Add(d)
+		revert.timer = time.AfterFunc(d, func() { c.revert(module, revert) })
+		c.reverts[module] = revert
+		log.Printf("Log level of %s set to %s for %s", moduleName(module), level, d)
+	} else {
+		log.Printf("Log level of %s set to %s", moduleName(module), level)
+	}
+	return nil
+}
+
+// unset puts module back to the default level, cancelling any revert.
+func (c *logLevelChanges) unset(module string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if revert, ok := c.reverts[module]; ok {
+		revert.timer.Stop()
+		delete(c.reverts, module)
+	}
+	if err := c.restore(module, &logRevert{}); err != nil {
+		return err
+	}
+	log.Printf("Log level of %s reset", moduleName(module))
+	return nil
+}
+
+// revert reverts module, if revert is still the revert that's pending for it.
+func (c *logLevelChanges) revert(module string, revert *logRevert) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reverts[module] != revert {
+		return
+	}
+	delete(c.reverts, module)
+	if err := c.restore(module, revert); err != nil {
+		log.Printf("Reverting the log level of %s: %v", moduleName(module), err)
+		return
+	}
+	log.Printf("Log level of %s reverted", moduleName(module))
+}
+
+// revertAll reverts every module that has a revert pending now.
+func (c *logLevelChanges) revertAll() {
+	c.mu.Lock()
+	reverts := make(map[string]*logRevert, len(c.reverts))
+	for module, revert := range c.reverts {
+		revert.timer.Stop()
+		reverts[module] = revert
+	}
+	c.mu.Unlock()
+	for module, revert := range reverts {
+		c.revert(module, revert)
+	}
+}
+
+// restore sets module back to the level in revert, which is Envoy's startup level for Envoy if
+// it didn't have a level of its own.
+func (c *logLevelChanges) restore(module string, revert *logRevert) error {
+	if module == envoyModule {
+		envoyLevel := GetEnvoyLogLevel()
+		if revert.set {
+			envoyLevel = envoyLogLevel(revert.level)
+		}
+		if err := c.setEnvoy(envoyLevel); err != nil {
+			return err
+		}
+	}
+	if revert.set {
+		logLevels.Set(module, revert.level)
+	} else {
+		logLevels.Unset(module)
+	}
+	return nil
+}
+
+// revertTimes returns when each pending revert will happen.
+func (c *logLevelChanges) revertTimes() map[string]time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := make(map[string]time.Time, len(c.reverts))
+	for module, revert := range c.reverts {
+		ret[module] = revert.at
+	}
+	return ret
+}
+
+// envoyLogLevel returns the name of Envoy's log level for level.
+func envoyLogLevel(level dlog.LogLevel) string {
+	if level == dlog.LogLevelWarn {
+		return "warning"
+	}
+	return level.String()
+}
+
+// setEnvoyLogLevel sets the level of all of Envoy's loggers, through its admin interface at
+// adminURL.
+func setEnvoyLogLevel(adminURL, level string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(adminURL+"/logging?level="+url.QueryEscape(level), "text/plain", nil)
+	if err != nil {
+		return fmt.Errorf("setting Envoy's log level: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("setting Envoy's log level: %s", resp.Status)
+	}
+	return nil
+}
+
+// logLevelStatus is what /debug/loglevel responds with: the levels, and when the temporary ones revert.
+type logLevelStatus struct {
+	Default string               `json:"default"`
+	Modules map[string]string    `json:"modules"`
+	Reverts map[string]time.Time `json:"reverts,omitempty"`
+}
+
+// handleLogLevel serves the log levels. PUT ?module=ambex&level=debug sets the level of a module,
+// or the default level without a module, and &for=10m reverts it after ten minutes; the level of
+// the envoy module is Envoy's own too. DELETE ?module=ambex puts a module back to the default.
+// Every method responds with a logLevelStatus.
+func handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	module := query.Get("module")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		level, err := dlog.ParseLogLevel(query.Get("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var d time.Duration
+		if query.Get("for") != "" {
+			d, err = time.ParseDuration(query.Get("for"))
+			if err == nil && d <= 0 {
+				err = fmt.Errorf("time: invalid duration %q", query.Get("for"))
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := logChanges.set(module, level, d); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	case http.MethodDelete:
+		if module == "" {
+			http.Error(w, "DELETE needs a module", http.StatusBadRequest)
+			return
+		}
+		if err := logChanges.unset(module); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := logLevelStatus{
+		Default: logLevels.Level("").String(),
+		Modules: map[string]string{},
+		Reverts: logChanges.revertTimes(),
+	}
+	for module, level := range logLevels.Modules() {
+		status.Modules[module] = level.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// watchLogSignals raises every log level, Envoy's included, to debug on SIGUSR1, for as long as
+// GetLogSignalDuration says, and reverts them on SIGUSR2.
+func watchLogSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGUSR2 {
+				logChanges.revertAll()
+				continue
+			}
+			d := GetLogSignalDuration()
+			for _, module := range []string{"", envoyModule} {
+				if err := logChanges.set(module, dlog.LogLevelDebug, d); err != nil {
+					log.Printf("SIGUSR1: %v", err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func moduleName(module string) string {
+	if module == "" {
+		return "all modules"
+	}
+	return module
+}
@@ -0,0 +1,124 @@
+package entrypoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+// withLogLevels gives a test its own logLevels and logChanges, with an Envoy that records the
+// levels that it's set to, and returns a func that puts the real ones back.
+func withLogLevels(t *testing.T) func() {
+	savedLevels, savedChanges := logLevels, logChanges
+	logLevels = dlog.NewLevels(dlog.LogLevelInfo)
+	logChanges = &logLevelChanges{
+		setEnvoy: func(level string) error {
+			envoyLevels = append(envoyLevels, level)
+			return nil
+		},
+		reverts: map[string]*logRevert{},
+	}
+	envoyLevels = nil
+	return func() { logLevels, logChanges = savedLevels, savedChanges }
+}
+
+var envoyLevels []string
+
+func TestHandleLogLevel(t *testing.T) {
+	defer withLogLevels(t)()
+
+	request := func(method, query string) (int, string) {
+		w := httptest.NewRecorder()
+		handleLogLevel(w, httptest.NewRequest(method, "/debug/loglevel"+query, nil))
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	code, body := request(http.MethodPut, "?module=ambex&level=debug")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"default": "info", "modules": {"ambex": "debug"}}`, body)
+	assert.Equal(t, dlog.LogLevelDebug, logLevels.Level("ambex"))
+
+	code, body = request(http.MethodPut, "?level=warn")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"default": "warn", "modules": {"ambex": "debug"}}`, body)
+
+	code, body = request(http.MethodDelete, "?module=ambex")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"default": "warn", "modules": {}}`, body)
+
+	code, body = request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"default": "warn", "modules": {}}`, body)
+
+	code, body = request(http.MethodPut, "?module=envoy&level=debug&for=10m")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"reverts":{"envoy":`)
+	assert.Equal(t, []string{"debug"}, envoyLevels)
+	code, body = request(http.MethodPut, "?module=envoy&level=debug&for=-1s")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, `time: invalid duration "-1s"`, body)
+
+	code, body = request(http.MethodPut, "?module=ambex&level=loud")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, `invalid log level "loud"`, body)
+
+	code, _ = request(http.MethodDelete, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(http.MethodPost, "?level=debug")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestTemporaryLogLevels(t *testing.T) {
+	defer withLogLevels(t)()
+	logLevels.Set("watcher", dlog.LogLevelWarn)
+
+	require.NoError(t, logChanges.set("watcher", dlog.LogLevelDebug, time.Hour))
+	require.NoError(t, logChanges.set("watcher", dlog.LogLevelTrace, time.Hour))
+	require.NoError(t, logChanges.set("", dlog.LogLevelDebug, 10*time.Millisecond))
+	require.NoError(t, logChanges.set(envoyModule, dlog.LogLevelWarn, time.Hour))
+	assert.Equal(t, []string{"warning"}, envoyLevels)
+	assert.Len(t, logChanges.revertTimes(), 3)
+
+	// The default level reverts by itself.
+	for deadline := time.Now().Add(5 * time.Second); logLevels.Level("") != dlog.LogLevelInfo; time.Sleep(time.Millisecond) {
+		require.True(t, time.Now().Before(deadline), "the default level didn't revert")
+	}
+	assert.Equal(t, dlog.LogLevelTrace, logLevels.Level("watcher"))
+
+	// The rest revert to what they were before their first change.
+	logChanges.revertAll()
+	assert.Equal(t, dlog.LogLevelWarn, logLevels.Level("watcher"))
+	_, set := logLevels.Lookup(envoyModule)
+	assert.False(t, set)
+	assert.Equal(t, []string{"warning", GetEnvoyLogLevel()}, envoyLevels)
+	assert.Empty(t, logChanges.revertTimes())
+
+	// A permanent change cancels a revert.
+	require.NoError(t, logChanges.set("ambex", dlog.LogLevelDebug, time.Hour))
+	require.NoError(t, logChanges.set("ambex", dlog.LogLevelError, 0))
+	logChanges.revertAll()
+	assert.Equal(t, dlog.LogLevelError, logLevels.Level("ambex"))
+}
+
+func TestSetEnvoyLogLevel(t *testing.T) {
+	var got string
+	envoy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/logging" {
+			http.NotFound(w, r)
+			return
+		}
+		got = r.URL.Query().Get("level")
+	}))
+	defer envoy.Close()
+
+	require.NoError(t, setEnvoyLogLevel(envoy.URL, "debug"))
+	assert.Equal(t, "debug", got)
+	assert.EqualError(t, setEnvoyLogLevel(envoy.URL+"/nope", "debug"), "setting Envoy's log level: 404 Not Found")
+}
@@ -0,0 +1,59 @@
+package entrypoint
+
+import (
+	"encoding/json"
+
+	"github.com/datawire/ambassador/pkg/luacheck"
+)
+
+// luaScriptsLabel marks the ConfigMaps that hold Lua scripts for the ambassador Module and for
+// Mappings. Only ConfigMaps with it are watched, rather than every ConfigMap in the cluster.
+const luaScriptsLabel = "getambassador.io/lua-scripts"
+
+// luaErrorsAnnotation holds the syntax errors of the scripts that ReconcileLuaScripts left out of
+// a ConfigMap, as a JSON object from the key of each script to its error.
+const luaErrorsAnnotation = "getambassador.io/lua-script-errors"
+
+// luaScriptsSelector returns the label selector for the ConfigMaps with Lua scripts, within the
+// ones that ls selects.
+func luaScriptsSelector(ls string) string {
+	if ls == "" {
+		return luaScriptsLabel
+	}
+	return ls + "," + luaScriptsLabel
+}
+
+// ReconcileLuaScripts checks the syntax of the Lua scripts in AllLuaConfigMaps, and sets
+// LuaConfigMaps to copies of them with only the scripts that parse, and the errors of the rest in
+// luaErrorsAnnotation. diagd refuses a Module or Mapping whose script doesn't parse, rather than
+// hand it to Envoy, which would reject the whole configuration.
+func (s *AmbassadorInputs) ReconcileLuaScripts() {
+	s.LuaConfigMaps = nil
+	for _, cm := range s.AllLuaConfigMaps {
+		checked := cm.DeepCopy()
+		checked.BinaryData = nil
+
+		errors := make(map[string]string)
+		for key, script := range cm.Data {
+			if err := luacheck.Check(key, []byte(script)); err != nil {
+				errors[key] = err.Error()
+				delete(checked.Data, key)
+			}
+		}
+
+		if len(errors) > 0 {
+			bytes, err := json.Marshal(errors)
+			if err != nil {
+				panic(err)
+			}
+			annotations := checked.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[luaErrorsAnnotation] = string(bytes)
+			checked.SetAnnotations(annotations)
+		}
+
+		s.LuaConfigMaps = append(s.LuaConfigMaps, checked)
+	}
+}
@@ -0,0 +1,53 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func TestReconcileLuaScripts(t *testing.T) {
+	good := "function envoy_on_request(h)\n  h:headers():add(\"x-lua\", \"yes\")\nend\n"
+	s := &AmbassadorInputs{AllLuaConfigMaps: []*kates.ConfigMap{
+		{
+			ObjectMeta: kates.ObjectMeta{Name: "scripts", Namespace: "default"},
+			Data: map[string]string{
+				"good.lua": good,
+				"bad.lua":  "function envoy_on_request(h)\n",
+			},
+			BinaryData: map[string][]byte{"blob": {0x00}},
+		},
+		{
+			ObjectMeta: kates.ObjectMeta{Name: "more", Namespace: "default",
+				Annotations: map[string]string{"owner": "team"}},
+			Data: map[string]string{"good.lua": good},
+		},
+	}}
+
+	s.ReconcileLuaScripts()
+	require.Len(t, s.LuaConfigMaps, 2)
+
+	scripts := s.LuaConfigMaps[0]
+	assert.Equal(t, map[string]string{"good.lua": good}, scripts.Data)
+	assert.Nil(t, scripts.BinaryData)
+	var errors map[string]string
+	require.NoError(t, json.Unmarshal([]byte(scripts.GetAnnotations()[luaErrorsAnnotation]), &errors))
+	assert.Equal(t, map[string]string{
+		"bad.lua": "bad.lua:2: 'end' expected (to close 'function' at line 1) near '<eof>'",
+	}, errors)
+
+	more := s.LuaConfigMaps[1]
+	assert.Equal(t, map[string]string{"good.lua": good}, more.Data)
+	assert.Equal(t, map[string]string{"owner": "team"}, more.GetAnnotations())
+
+	// The inputs are left alone.
+	assert.Len(t, s.AllLuaConfigMaps[0].Data, 2)
+	assert.Nil(t, s.AllLuaConfigMaps[0].GetAnnotations())
+
+	assert.Equal(t, "getambassador.io/lua-scripts", luaScriptsSelector(""))
+	assert.Equal(t, "app=ambassador,getambassador.io/lua-scripts", luaScriptsSelector("app=ambassador"))
+}
@@ -17,8 +17,9 @@ import (
 
 // The watchMemory function will check memory usage every 10 seconds and log it if it jumps more
 // than 10Gi up or down. Additionally if memory usage exceeds 50% of the cgroup limit, it will log
-// usage every minute. Usage is also unconditionally logged before returning. This function only
-// returns if the context is canceled.
+// usage every minute. Every check also goes to the memory watchdog, which acts on it. Usage is
+// also unconditionally logged before returning. This function only returns if the context is
+// canceled.
 func watchMemory(ctx context.Context) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
@@ -28,6 +29,7 @@ func watchMemory(ctx context.Context) {
 		select {
 		case now := <-ticker.C:
 			usage.Refresh()
+			memoryWatch.observe(usage, now)
 			usage.maybeDo(now, func() {
 				log.Println(usage.String())
 			})
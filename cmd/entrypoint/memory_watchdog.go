@@ -0,0 +1,329 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The memory watchdog acts on what watchMemory sees, rather than just logging it. When the
+// cgroup's memory usage crosses AMBASSADOR_MEMORY_HIGH_PERCENT of its limit, it makes Go's GC work
+// harder and throws away history that's only there for debugging: the older reconfiguration
+// reports, and the snapshots that diagd rotates. When it crosses
+// AMBASSADOR_MEMORY_CRITICAL_PERCENT, it also hands memory back to the OS and marks Ambassador not
+// ready, so that traffic goes to the other replicas before the OOM killer gets here. Once usage
+// drops back below the high threshold, by a margin so it doesn't flap, everything goes back.
+
+// A memoryPressure is how close the cgroup is to its memory limit.
+type memoryPressure int
+
+const (
+	memoryNormal memoryPressure = iota
+	memoryHigh
+	memoryCritical
+)
+
+func (p memoryPressure) String() string {
+	switch p {
+	case memoryHigh:
+		return "high"
+	case memoryCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// memoryHysteresis is how many percentage points below a threshold usage has to drop to leave it.
+const memoryHysteresis = 5
+
+// The GC percents for each level of pressure; normal is whatever GOGC says.
+const (
+	highGCPercent     = 50
+	criticalGCPercent = 25
+)
+
+// The watchdog's actions, as counted in ambassador_memory_actions_total.
+const (
+	actionGCTuned           = "gc_tuned"
+	actionGCRestored        = "gc_restored"
+	actionHistoryShrunk     = "history_shrunk"
+	actionHistoryRestored   = "history_restored"
+	actionOSMemoryFreed     = "os_memory_freed"
+	actionReadinessDegraded = "readiness_degraded"
+)
+
+// rotatedSnapshot matches the older snapshots that diagd keeps in the snapshot directory, such as
+// snapshot-1.yaml and ir-3.json, but not the current ones that the diagnostics API reads.
+var rotatedSnapshot = regexp.MustCompile(`^(aconf|econf|ir|snapshot|diff)-[0-9]+\.(json|yaml|txt)$`)
+
+type memoryWatchdog struct {
+	mu sync.Mutex
+
+	high, critical int // percent of the limit
+
+	pressure memoryPressure
+	since    time.Time // when pressure last changed
+	usage    memory
+	limit    memory
+	percent  int
+	actions  map[string]uint64
+
+	// gcPercent is the GC percent now, and normalGCPercent what it was before the watchdog first
+	// changed it, or -1 if it hasn't.
+	gcPercent       int
+	normalGCPercent int
+
+	// these allow mocking for tests
+	setGCPercent  func(int) int
+	freeOSMemory  func()
+	snapshotDir   func() string
+	reports       *reconfigReports
+	reportCount   func() int
+	shrunkReports int
+}
+
+var memoryWatch = newMemoryWatchdog(GetMemoryThresholds())
+
+func newMemoryWatchdog(high, critical int) *memoryWatchdog {
+	return &memoryWatchdog{
+		high:            high,
+		critical:        critical,
+		since:           time.Now(),
+		actions:         map[string]uint64{},
+		gcPercent:       gcPercentFromEnv(),
+		normalGCPercent: -1,
+		setGCPercent:    debug.SetGCPercent,
+		freeOSMemory:    debug.FreeOSMemory,
+		snapshotDir:     GetSnapshotDir,
+		reports:         controlPlaneReports,
+		reportCount:     GetReconfigReportCount,
+		shrunkReports:   5,
+	}
+}
+
+// gcPercentFromEnv returns the GC percent that GOGC sets, as the runtime reads it.
+func gcPercentFromEnv() int {
+	value := os.Getenv("GOGC")
+	if value == "off" {
+		return -1
+	}
+	percent, err := strconv.Atoi(value)
+	if err != nil {
+		return 100
+	}
+	return percent
+}
+
+// pressureFor returns the pressure at percent of the limit, starting from the current pressure:
+// pressure goes up as soon as usage crosses a threshold, but only comes down once usage is
+// memoryHysteresis points below it.
+func (wd *memoryWatchdog) pressureFor(percent int) memoryPressure {
+	switch {
+	case percent >= wd.critical:
+		return memoryCritical
+	case wd.pressure == memoryCritical && percent > wd.critical-memoryHysteresis:
+		return memoryCritical
+	case percent >= wd.high:
+		return memoryHigh
+	case wd.pressure >= memoryHigh && percent > wd.high-memoryHysteresis:
+		return memoryHigh
+	default:
+		return memoryNormal
+	}
+}
+
+// The observe method acts on the latest memory usage. Without a limit there's nothing to be close
+// to, so pressure is always normal.
+func (wd *memoryWatchdog) observe(m *MemoryUsage, now time.Time) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	wd.usage, wd.limit = m.Usage, m.Limit
+	pressure := memoryNormal
+	wd.percent = 0
+	if m.Limit > 0 && m.Limit != unlimited {
+		wd.percent = m.PercentUsed()
+		pressure = wd.pressureFor(wd.percent)
+	}
+
+	if pressure != wd.pressure {
+		log.Printf("memory pressure %s: %s of %s (%d%%)", pressure, m.Usage, m.Limit, wd.percent)
+		wd.transition(wd.pressure, pressure)
+		wd.pressure = pressure
+		wd.since = now
+	}
+
+	// diagd keeps rotating snapshots in, so keep throwing them away.
+	if wd.pressure >= memoryHigh {
+		if removed := removeRotatedSnapshots(wd.snapshotDir()); removed > 0 {
+			wd.actions[actionHistoryShrunk]++
+		}
+	}
+}
+
+// The transition method does what changes between two levels of pressure.
+func (wd *memoryWatchdog) transition(from, to memoryPressure) {
+	switch to {
+	case memoryNormal:
+		if wd.normalGCPercent >= 0 {
+			wd.setGCPercent(wd.normalGCPercent)
+			wd.gcPercent = wd.normalGCPercent
+			wd.normalGCPercent = -1
+			wd.actions[actionGCRestored]++
+		}
+		wd.reports.resize(wd.reportCount())
+		wd.actions[actionHistoryRestored]++
+		return
+	case memoryHigh:
+		wd.tuneGC(highGCPercent)
+	case memoryCritical:
+		wd.tuneGC(criticalGCPercent)
+		wd.freeOSMemory()
+		wd.actions[actionOSMemoryFreed]++
+		wd.actions[actionReadinessDegraded]++
+	}
+	if from == memoryNormal {
+		if wd.shrunkReports < wd.reportCount() {
+			wd.reports.resize(wd.shrunkReports)
+		}
+		wd.actions[actionHistoryShrunk]++
+	}
+}
+
+func (wd *memoryWatchdog) tuneGC(percent int) {
+	previous := wd.setGCPercent(percent)
+	if wd.normalGCPercent < 0 {
+		wd.normalGCPercent = previous
+	}
+	wd.gcPercent = percent
+	wd.actions[actionGCTuned]++
+}
+
+// removeRotatedSnapshots removes diagd's older snapshots from dir, and returns how many it
+// removed.
+func removeRotatedSnapshots(dir string) int {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, file := range files {
+		if !rotatedSnapshot.MatchString(file.Name()) {
+			continue
+		}
+		if err := os.Remove(path.Join(dir, file.Name())); err != nil {
+			log.Printf("couldn't remove old snapshot: %v", err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// The degraded method returns whether memory pressure is critical enough that Ambassador
+// shouldn't be sent traffic.
+func (wd *memoryWatchdog) degraded() bool {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	return wd.pressure == memoryCritical
+}
+
+// The MemoryStatus struct is what the debug server's /debug/memory serves.
+type MemoryStatus struct {
+	Pressure string    `json:"pressure"`
+	Since    time.Time `json:"since"`
+	Usage    int64     `json:"usage_bytes"`
+	// Limit is 0 if the cgroup has no limit.
+	Limit           int64             `json:"limit_bytes"`
+	Percent         int               `json:"percent"`
+	HighPercent     int               `json:"high_percent"`
+	CriticalPercent int               `json:"critical_percent"`
+	GCPercent       int               `json:"gc_percent"`
+	Actions         map[string]uint64 `json:"actions"`
+
+	pressure memoryPressure
+}
+
+func (wd *memoryWatchdog) status() MemoryStatus {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	status := MemoryStatus{
+		Pressure:        wd.pressure.String(),
+		Since:           wd.since,
+		Usage:           int64(wd.usage),
+		Percent:         wd.percent,
+		HighPercent:     wd.high,
+		CriticalPercent: wd.critical,
+		GCPercent:       wd.gcPercent,
+		Actions:         map[string]uint64{},
+		pressure:        wd.pressure,
+	}
+	if wd.limit != unlimited {
+		status.Limit = int64(wd.limit)
+	}
+	for action, count := range wd.actions {
+		status.Actions[action] = count
+	}
+	return status
+}
+
+// The write method writes the watchdog's metrics in the Prometheus text format.
+func (wd *memoryWatchdog) write(w io.Writer) {
+	status := wd.status()
+
+	fmt.Fprintln(w, "# HELP ambassador_memory_usage_bytes Memory used by Ambassador's cgroup.")
+	fmt.Fprintln(w, "# TYPE ambassador_memory_usage_bytes gauge")
+	fmt.Fprintf(w, "ambassador_memory_usage_bytes %d\n", status.Usage)
+
+	if status.Limit > 0 {
+		fmt.Fprintln(w, "# HELP ambassador_memory_limit_bytes Memory limit of Ambassador's cgroup.")
+		fmt.Fprintln(w, "# TYPE ambassador_memory_limit_bytes gauge")
+		fmt.Fprintf(w, "ambassador_memory_limit_bytes %d\n", status.Limit)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_memory_pressure Memory pressure: 0 for normal, 1 for high, 2 for critical.")
+	fmt.Fprintln(w, "# TYPE ambassador_memory_pressure gauge")
+	fmt.Fprintf(w, "ambassador_memory_pressure %d\n", status.pressure)
+
+	fmt.Fprintln(w, "# HELP ambassador_memory_actions_total What the memory watchdog has done, by action.")
+	fmt.Fprintln(w, "# TYPE ambassador_memory_actions_total counter")
+	actions := make([]string, 0, len(status.Actions))
+	for action := range status.Actions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		fmt.Fprintf(w, "ambassador_memory_actions_total{action=%q} %d\n", action, status.Actions[action])
+	}
+}
+
+// handleMemory serves the watchdog's status. A POST hands memory back to the OS first.
+func handleMemory(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		memoryWatch.mu.Lock()
+		memoryWatch.freeOSMemory()
+		memoryWatch.actions[actionOSMemoryFreed]++
+		memoryWatch.mu.Unlock()
+	default:
+		http.Error(w, "GET or POST", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(memoryWatch.status()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
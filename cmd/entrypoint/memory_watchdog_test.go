@@ -0,0 +1,132 @@
+package entrypoint
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const GiB = 1024 * 1024 * 1024
+
+func TestMemoryWatchdog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"snapshot.yaml", "ir.json", "snapshot-1.yaml", "ir-2.json", "diff-1.txt"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644))
+	}
+
+	gcPercent := 100
+	freed := 0
+	reports := newReconfigReports(20)
+	for i := 0; i < 20; i++ {
+		reports.start("kubernetes", time.Now(), nil)
+	}
+
+	wd := newMemoryWatchdog(80, 95)
+	wd.setGCPercent = func(percent int) int {
+		previous := gcPercent
+		gcPercent = percent
+		return previous
+	}
+	wd.freeOSMemory = func() { freed++ }
+	wd.snapshotDir = func() string { return dir }
+	wd.reports = reports
+	wd.reportCount = func() int { return 20 }
+	wd.gcPercent = 100
+
+	start := time.Now()
+	observe := func(percent int, seconds int) {
+		wd.observe(&MemoryUsage{Usage: memory(percent) * GiB / 100, Limit: GiB}, start.Add(time.Duration(seconds)*time.Second))
+	}
+
+	// Nothing happens below the high threshold.
+	observe(50, 0)
+	assert.Equal(t, memoryNormal, wd.pressure)
+	assert.Equal(t, 100, gcPercent)
+	assert.Len(t, reports.reports, 20)
+
+	// Above it, the GC works harder, and history goes.
+	observe(85, 10)
+	assert.Equal(t, memoryHigh, wd.pressure)
+	assert.Equal(t, highGCPercent, gcPercent)
+	assert.Len(t, reports.reports, 5)
+	assert.False(t, wd.degraded())
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"ir.json", "snapshot.yaml"}, names)
+
+	// Critical marks Ambassador not ready.
+	observe(96, 20)
+	assert.Equal(t, memoryCritical, wd.pressure)
+	assert.Equal(t, criticalGCPercent, gcPercent)
+	assert.Equal(t, 1, freed)
+	assert.True(t, wd.degraded())
+
+	// Just below critical isn't enough to leave it.
+	observe(93, 30)
+	assert.True(t, wd.degraded())
+	observe(89, 40)
+	assert.Equal(t, memoryHigh, wd.pressure)
+	assert.Equal(t, highGCPercent, gcPercent)
+	assert.False(t, wd.degraded())
+
+	// Nor is just below high.
+	observe(77, 50)
+	assert.Equal(t, memoryHigh, wd.pressure)
+	observe(70, 60)
+	assert.Equal(t, memoryNormal, wd.pressure)
+	assert.Equal(t, 100, gcPercent)
+	assert.Equal(t, 20, reports.size)
+
+	status := wd.status()
+	assert.Equal(t, "normal", status.Pressure)
+	assert.Equal(t, start.Add(60*time.Second), status.Since)
+	assert.Equal(t, int64(GiB), status.Limit)
+	assert.Equal(t, 100, status.GCPercent)
+	assert.Equal(t, map[string]uint64{
+		actionGCTuned:           3,
+		actionGCRestored:        1,
+		actionHistoryShrunk:     2,
+		actionHistoryRestored:   1,
+		actionOSMemoryFreed:     1,
+		actionReadinessDegraded: 1,
+	}, status.Actions)
+
+	var out bytes.Buffer
+	wd.write(&out)
+	assert.Contains(t, out.String(), "ambassador_memory_limit_bytes 1073741824\n")
+	assert.Contains(t, out.String(), "ambassador_memory_pressure 0\n")
+	assert.Contains(t, out.String(), `ambassador_memory_actions_total{action="gc_tuned"} 3`+"\n")
+
+	// Without a limit, there's no pressure.
+	wd.observe(&MemoryUsage{Usage: 100 * GiB, Limit: unlimited}, start.Add(70*time.Second))
+	assert.Equal(t, memoryNormal, wd.pressure)
+	assert.Equal(t, int64(0), wd.status().Limit)
+}
+
+func TestReconfigReportsResize(t *testing.T) {
+	reports := newReconfigReports(3)
+	for i := 0; i < 3; i++ {
+		reports.start("kubernetes", time.Now(), nil)
+	}
+	reports.resize(1)
+	require.Len(t, reports.reports, 1)
+	assert.Equal(t, 3, reports.reports[0].ID)
+
+	reports.resize(3)
+	reports.start("kubernetes", time.Now(), nil)
+	assert.Len(t, reports.reports, 2)
+}
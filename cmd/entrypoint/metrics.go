@@ -0,0 +1,367 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datawire/ambassador/cmd/ambex"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// The controlPlaneMetrics struct holds the metrics for the Go side of the control plane, which
+// are served in the Prometheus text format at /metrics. Envoy's own stats don't say anything
+// about how long it takes for a change in the cluster to become Envoy configuration; these do.
+//
+// This is deliberately tiny rather than pulling in a Prometheus client: there are only counters
+// and summaries without quantiles here.
+type controlPlaneMetrics struct {
+	mu sync.Mutex
+
+	// snapshot build duration: from a change arriving to the snapshot being stored
+	snapshotBuildCount   uint64
+	snapshotBuildSeconds float64
+
+	// deltas, keyed by source ("kubernetes" or "consul"), kind, and delta type
+	deltas map[[3]string]uint64
+
+	// validation errors, keyed by kind
+	validationErrors map[string]uint64
+
+	// updates to Secrets used by Hosts that get their certificates from ACME
+	acmeRenewals uint64
+
+	// ambex pushes: from ambex noticing new configuration to the snapshot being set
+	ambexPushCount   uint64
+	ambexPushSeconds float64
+
+	// the watches of resolvers that watch services themselves, keyed by kind and "name.namespace"
+	resolvers map[[2]string]ResolverStatus
+	// resolution errors, keyed like resolvers; they outlive the resolver's status
+	resolverErrors map[[2]string]uint64
+}
+
+// A ResolverStatus says how the watches of one ConsulResolver or DNSResolver are doing. It is
+// served as JSON at /resolvers for the diagnostics overview.
+type ResolverStatus struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Services is the number of services that the resolver watches.
+	Services int `json:"services"`
+	// Endpoints is the number of endpoints that those services currently have.
+	Endpoints int `json:"endpoints"`
+	// LastUpdate is when the least recently updated watch last got an answer, or started if it
+	// never has.
+	LastUpdate time.Time `json:"last_update"`
+	// WatchAgeSeconds is how long ago LastUpdate was, as of the request.
+	WatchAgeSeconds float64 `json:"watch_age_seconds"`
+	Errors          uint64  `json:"errors"`
+}
+
+var metrics = newControlPlaneMetrics()
+
+func newControlPlaneMetrics() *controlPlaneMetrics {
+	return &controlPlaneMetrics{
+		deltas:           map[[3]string]uint64{},
+		validationErrors: map[string]uint64{},
+		resolvers:        map[[2]string]ResolverStatus{},
+		resolverErrors:   map[[2]string]uint64{},
+	}
+}
+
+func (m *controlPlaneMetrics) observeSnapshotBuild(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshotBuildCount++
+	m.snapshotBuildSeconds += d.Seconds()
+}
+
+func (m *controlPlaneMetrics) countKubernetesDeltas(deltas []*kates.Delta, inputs *AmbassadorInputs) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acmeSecrets := acmeSecretNames(inputs)
+
+	for _, delta := range deltas {
+		deltaType := "add"
+		switch delta.DeltaType {
+		case kates.ObjectUpdate:
+			deltaType = "update"
+		case kates.ObjectDelete:
+			deltaType = "delete"
+		}
+		m.deltas[[3]string{"kubernetes", delta.Kind, deltaType}]++
+
+		if delta.Kind == "Secret" && delta.DeltaType == kates.ObjectUpdate &&
+			acmeSecrets[delta.GetNamespace()+"/"+delta.GetName()] {
+			m.acmeRenewals++
+		}
+	}
+}
+
+func (m *controlPlaneMetrics) countConsulUpdate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deltas[[3]string{"consul", "Endpoints", "update"}]++
+}
+
+func (m *controlPlaneMetrics) countValidationError(kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.validationErrors[kind]++
+}
+
+// The observeAmbexPush method is hooked into ambex.
+func (m *controlPlaneMetrics) observeAmbexPush(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ambexPushCount++
+	m.ambexPushSeconds += d.Seconds()
+}
+
+// The setResolverStatuses method replaces the statuses of every resolver of a kind, so that
+// resolvers that are gone disappear from the metrics.
+func (m *controlPlaneMetrics) setResolverStatuses(kind string, statuses []ResolverStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.resolvers {
+		if key[0] == kind {
+			delete(m.resolvers, key)
+		}
+	}
+	for _, status := range statuses {
+		status.Kind = kind
+		m.resolvers[[2]string{kind, status.Name + "." + status.Namespace}] = status
+	}
+}
+
+func (m *controlPlaneMetrics) countResolverError(kind, name, namespace string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolverErrors[[2]string{kind, name + "." + namespace}]++
+}
+
+// The resolverStatuses method returns the status of every resolver, with its error count, sorted
+// by kind and name.
+func (m *controlPlaneMetrics) resolverStatuses() []ResolverStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]ResolverStatus, 0, len(m.resolvers))
+	for key, status := range m.resolvers {
+		status.Errors = m.resolverErrors[key]
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Kind != statuses[j].Kind {
+			return statuses[i].Kind < statuses[j].Kind
+		}
+		return statuses[i].Name+"."+statuses[i].Namespace < statuses[j].Name+"."+statuses[j].Namespace
+	})
+	return statuses
+}
+
+// A Timer is how many times the control plane did something, and how long it took in all. It is
+// served as JSON at the debug server's /debug/timers.
+type Timer struct {
+	Count        uint64  `json:"count"`
+	TotalSeconds float64 `json:"total_seconds"`
+	MeanSeconds  float64 `json:"mean_seconds"`
+}
+
+func newTimer(count uint64, seconds float64) Timer {
+	timer := Timer{Count: count, TotalSeconds: seconds}
+	if count > 0 {
+		timer.MeanSeconds = seconds / float64(count)
+	}
+	return timer
+}
+
+// The timers method returns the timers of the control plane, by what they time.
+func (m *controlPlaneMetrics) timers() map[string]Timer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return map[string]Timer{
+		"snapshot_build": newTimer(m.snapshotBuildCount, m.snapshotBuildSeconds),
+		"ambex_push":     newTimer(m.ambexPushCount, m.ambexPushSeconds),
+	}
+}
+
+// The acmeSecretNames function returns the "namespace/name" of the TLS Secret of every Host that
+// uses ACME. An update to one of those Secrets is what a certificate renewal looks like from here,
+// since the ACME client itself doesn't run in this process.
+func acmeSecretNames(inputs *AmbassadorInputs) map[string]bool {
+	names := map[string]bool{}
+	if inputs == nil {
+		return names
+	}
+	for _, host := range inputs.Hosts {
+		if host.Spec == nil || host.Spec.TLSSecret == nil || host.Spec.TLSSecret.Name == "" {
+			continue
+		}
+		if !hostUsesACME(host) {
+			continue
+		}
+		names[host.GetNamespace()+"/"+host.Spec.TLSSecret.Name] = true
+	}
+	return names
+}
+
+func hostUsesACME(host *amb.Host) bool {
+	acme := host.Spec.AcmeProvider
+	return acme != nil && !strings.EqualFold(acme.Authority, "none")
+}
+
+// The write method writes every metric in the Prometheus text format.
+func (m *controlPlaneMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP ambassador_snapshot_build_duration_seconds Time from a change being noticed to the snapshot being ready for diagd.")
+	fmt.Fprintln(w, "# TYPE ambassador_snapshot_build_duration_seconds summary")
+	fmt.Fprintf(w, "ambassador_snapshot_build_duration_seconds_sum %g\n", m.snapshotBuildSeconds)
+	fmt.Fprintf(w, "ambassador_snapshot_build_duration_seconds_count %d\n", m.snapshotBuildCount)
+
+	fmt.Fprintln(w, "# HELP ambassador_snapshot_deltas_total Changes seen by the watcher, by source, kind, and type.")
+	fmt.Fprintln(w, "# TYPE ambassador_snapshot_deltas_total counter")
+	keys := make([][3]string, 0, len(m.deltas))
+	for key := range m.deltas {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Join(keys[i][:], "\x00") < strings.Join(keys[j][:], "\x00")
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "ambassador_snapshot_deltas_total{source=%q,kind=%q,type=%q} %d\n",
+			key[0], key[1], key[2], m.deltas[key])
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_validation_errors_total Resources that failed validation, by kind.")
+	fmt.Fprintln(w, "# TYPE ambassador_validation_errors_total counter")
+	kinds := make([]string, 0, len(m.validationErrors))
+	for kind := range m.validationErrors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "ambassador_validation_errors_total{kind=%q} %d\n", kind, m.validationErrors[kind])
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_acme_renewals_total Updates to the TLS Secrets of Hosts that use ACME.")
+	fmt.Fprintln(w, "# TYPE ambassador_acme_renewals_total counter")
+	fmt.Fprintf(w, "ambassador_acme_renewals_total %d\n", m.acmeRenewals)
+
+	fmt.Fprintln(w, "# HELP ambassador_ambex_push_duration_seconds Time for ambex to load new configuration and push it to Envoy.")
+	fmt.Fprintln(w, "# TYPE ambassador_ambex_push_duration_seconds summary")
+	fmt.Fprintf(w, "ambassador_ambex_push_duration_seconds_sum %g\n", m.ambexPushSeconds)
+	fmt.Fprintf(w, "ambassador_ambex_push_duration_seconds_count %d\n", m.ambexPushCount)
+}
+
+// The writeResolvers method writes the resolver metrics. A resolver's watch age is how long ago
+// its least recently updated watch last got an answer. Consul only answers when a service changes,
+// so an old watch age on its own isn't a problem; an old watch age with errors is.
+func (m *controlPlaneMetrics) writeResolvers(w io.Writer, now time.Time) {
+	statuses := m.resolverStatuses()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP ambassador_resolver_watched_services Services watched by each resolver.")
+	fmt.Fprintln(w, "# TYPE ambassador_resolver_watched_services gauge")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "ambassador_resolver_watched_services{kind=%q,resolver=%q} %d\n",
+			s.Kind, s.Name+"."+s.Namespace, s.Services)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_resolver_watched_endpoints Endpoints of the services watched by each resolver.")
+	fmt.Fprintln(w, "# TYPE ambassador_resolver_watched_endpoints gauge")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "ambassador_resolver_watched_endpoints{kind=%q,resolver=%q} %d\n",
+			s.Kind, s.Name+"."+s.Namespace, s.Endpoints)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_resolver_watch_age_seconds Time since the least recently updated watch of each resolver got an answer.")
+	fmt.Fprintln(w, "# TYPE ambassador_resolver_watch_age_seconds gauge")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "ambassador_resolver_watch_age_seconds{kind=%q,resolver=%q} %g\n",
+			s.Kind, s.Name+"."+s.Namespace, now.Sub(s.LastUpdate).Seconds())
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_resolver_errors_total Failed lookups and watch errors, by resolver.")
+	fmt.Fprintln(w, "# TYPE ambassador_resolver_errors_total counter")
+	keys := make([][2]string, 0, len(m.resolverErrors))
+	for key := range m.resolverErrors {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0]+"\x00"+keys[i][1] < keys[j][0]+"\x00"+keys[j][1]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "ambassador_resolver_errors_total{kind=%q,resolver=%q} %d\n", key[0], key[1], m.resolverErrors[key])
+	}
+}
+
+// The writeAmbexStats function writes what ambex is serving, and to how many Envoys. An Envoy
+// has a stream for each type of resource, or one for all of them if it uses ADS.
+func writeAmbexStats(w io.Writer, stats ambex.CacheStats) {
+	fmt.Fprintln(w, "# HELP ambassador_ambex_resources Resources that ambex is serving, by type.")
+	fmt.Fprintln(w, "# TYPE ambassador_ambex_resources gauge")
+	for _, t := range stats.Types {
+		fmt.Fprintf(w, "ambassador_ambex_resources{type=%q} %d\n", shortTypeURL(t.TypeURL), t.Resources)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_ambex_streams Open xDS streams that have asked ambex for each type.")
+	fmt.Fprintln(w, "# TYPE ambassador_ambex_streams gauge")
+	for _, t := range stats.Types {
+		fmt.Fprintf(w, "ambassador_ambex_streams{type=%q} %d\n", shortTypeURL(t.TypeURL), t.Streams)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_ambex_responses_total Responses that ambex has sent, by type.")
+	fmt.Fprintln(w, "# TYPE ambassador_ambex_responses_total counter")
+	for _, t := range stats.Types {
+		fmt.Fprintf(w, "ambassador_ambex_responses_total{type=%q} %d\n", shortTypeURL(t.TypeURL), t.Responses)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_ambex_node_streams Open xDS streams, by group of Envoy nodes.")
+	fmt.Fprintln(w, "# TYPE ambassador_ambex_node_streams gauge")
+	groups := make([]string, 0, len(stats.Nodes))
+	for group := range stats.Nodes {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		fmt.Fprintf(w, "ambassador_ambex_node_streams{node_group=%q} %d\n", group, stats.Nodes[group])
+	}
+}
+
+// shortTypeURL returns the name of the type in a type URL, such as envoy.api.v2.Cluster.
+func shortTypeURL(typeURL string) string {
+	return typeURL[strings.LastIndex(typeURL, "/")+1:]
+}
+
+func handleResolvers(w http.ResponseWriter, r *http.Request) {
+	statuses := metrics.resolverStatuses()
+	now := time.Now()
+	for i := range statuses {
+		statuses[i].WatchAgeSeconds = now.Sub(statuses[i].LastUpdate).Seconds()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.write(w)
+	metrics.writeResolvers(w, time.Now())
+	writeAmbexStats(w, ambex.Stats())
+	tapUsage.write(w)
+	memoryWatch.write(w)
+}
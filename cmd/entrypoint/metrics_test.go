@@ -0,0 +1,123 @@
+package entrypoint
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/ambassador/cmd/ambex"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func secretDelta(name string, deltaType kates.DeltaType) *kates.Delta {
+	return &kates.Delta{
+		TypeMeta:   kates.TypeMeta{Kind: "Secret"},
+		ObjectMeta: kates.ObjectMeta{Name: name, Namespace: "default"},
+		DeltaType:  deltaType,
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := newControlPlaneMetrics()
+
+	inputs := &AmbassadorInputs{
+		Hosts: []*amb.Host{
+			{
+				ObjectMeta: kates.ObjectMeta{Name: "acme", Namespace: "default"},
+				Spec: &amb.HostSpec{
+					AcmeProvider: &amb.ACMEProviderSpec{Authority: "https://acme-v02.api.letsencrypt.org/directory"},
+					TLSSecret:    &kates.LocalObjectReference{Name: "acme-cert"},
+				},
+			},
+			{
+				ObjectMeta: kates.ObjectMeta{Name: "manual", Namespace: "default"},
+				Spec: &amb.HostSpec{
+					AcmeProvider: &amb.ACMEProviderSpec{Authority: "none"},
+					TLSSecret:    &kates.LocalObjectReference{Name: "manual-cert"},
+				},
+			},
+		},
+	}
+
+	// Only the update to the ACME Host's Secret counts as a renewal.
+	m.countKubernetesDeltas([]*kates.Delta{
+		secretDelta("acme-cert", kates.ObjectAdd),
+		secretDelta("acme-cert", kates.ObjectUpdate),
+		secretDelta("manual-cert", kates.ObjectUpdate),
+	}, inputs)
+	m.countConsulUpdate()
+	m.countValidationError("Mapping")
+	m.observeSnapshotBuild(2 * time.Second)
+	m.observeAmbexPush(500 * time.Millisecond)
+
+	var buf bytes.Buffer
+	m.write(&buf)
+	out := buf.String()
+
+	assert.Contains(t, out, "ambassador_snapshot_build_duration_seconds_sum 2\n")
+	assert.Contains(t, out, "ambassador_snapshot_build_duration_seconds_count 1\n")
+	assert.Contains(t, out, `ambassador_snapshot_deltas_total{source="kubernetes",kind="Secret",type="add"} 1`)
+	assert.Contains(t, out, `ambassador_snapshot_deltas_total{source="kubernetes",kind="Secret",type="update"} 2`)
+	assert.Contains(t, out, `ambassador_snapshot_deltas_total{source="consul",kind="Endpoints",type="update"} 1`)
+	assert.Contains(t, out, `ambassador_validation_errors_total{kind="Mapping"} 1`)
+	assert.Contains(t, out, "ambassador_acme_renewals_total 1\n")
+	assert.Contains(t, out, "ambassador_ambex_push_duration_seconds_sum 0.5\n")
+}
+
+func TestResolverMetrics(t *testing.T) {
+	m := newControlPlaneMetrics()
+	now := time.Now()
+
+	m.setResolverStatuses("DNSResolver", []ResolverStatus{
+		{Name: "legacy", Namespace: "default", Services: 2, Endpoints: 3, LastUpdate: now.Add(-30 * time.Second)},
+	})
+	m.setResolverStatuses("ConsulResolver", []ResolverStatus{
+		{Name: "consul", Namespace: "default", Services: 1, LastUpdate: now.Add(-time.Minute)},
+	})
+	m.countResolverError("ConsulResolver", "consul", "default")
+
+	statuses := m.resolverStatuses()
+	if assert.Len(t, statuses, 2) {
+		assert.Equal(t, "ConsulResolver", statuses[0].Kind)
+		assert.Equal(t, uint64(1), statuses[0].Errors)
+		assert.Equal(t, "DNSResolver", statuses[1].Kind)
+		assert.Equal(t, 3, statuses[1].Endpoints)
+	}
+
+	var buf bytes.Buffer
+	m.writeResolvers(&buf, now)
+	out := buf.String()
+
+	assert.Contains(t, out, `ambassador_resolver_watched_services{kind="DNSResolver",resolver="legacy.default"} 2`)
+	assert.Contains(t, out, `ambassador_resolver_watched_endpoints{kind="ConsulResolver",resolver="consul.default"} 0`)
+	assert.Contains(t, out, `ambassador_resolver_watch_age_seconds{kind="DNSResolver",resolver="legacy.default"} 30`)
+	assert.Contains(t, out, `ambassador_resolver_errors_total{kind="ConsulResolver",resolver="consul.default"} 1`)
+
+	// A resolver that is gone loses its status, but keeps its error count.
+	m.setResolverStatuses("ConsulResolver", nil)
+	buf.Reset()
+	m.writeResolvers(&buf, now)
+	out = buf.String()
+	assert.NotContains(t, out, `ambassador_resolver_watched_services{kind="ConsulResolver"`)
+	assert.Contains(t, out, `ambassador_resolver_errors_total{kind="ConsulResolver",resolver="consul.default"} 1`)
+	assert.Len(t, m.resolverStatuses(), 1)
+}
+
+func TestAmbexStatsMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	writeAmbexStats(&buf, ambex.CacheStats{
+		Types: []ambex.TypeStats{
+			{TypeURL: "type.googleapis.com/envoy.api.v2.Cluster", Resources: 3, Streams: 2, Responses: 7},
+		},
+		Nodes: map[string]int{"test-id": 2},
+	})
+	out := buf.String()
+
+	assert.Contains(t, out, `ambassador_ambex_resources{type="envoy.api.v2.Cluster"} 3`)
+	assert.Contains(t, out, `ambassador_ambex_streams{type="envoy.api.v2.Cluster"} 2`)
+	assert.Contains(t, out, `ambassador_ambex_responses_total{type="envoy.api.v2.Cluster"} 7`)
+	assert.Contains(t, out, `ambassador_ambex_node_streams{node_group="test-id"} 2`)
+}
@@ -12,7 +12,7 @@ import (
 	"time"
 )
 
-func notifyReconfigWebhooks(ctx context.Context) {
+func notifyReconfigWebhooks(ctx context.Context, trace *reconfigTrace, report *reconfigReport) {
 	// XXX: last N snapshots?
 	snapshotUrl := url.QueryEscape("http://localhost:9696/snapshot")
 
@@ -20,12 +20,15 @@ func notifyReconfigWebhooks(ctx context.Context) {
 	needSidecarNotify := true
 
 	for {
-		if notifyWebhookUrl(ctx, "diagd", fmt.Sprintf("%s?url=%s", GetEventUrl(), snapshotUrl)) {
+		start := time.Now()
+		if resp, ok := notifyWebhookUrl(ctx, "diagd", fmt.Sprintf("%s?url=%s", GetEventUrl(), snapshotUrl), trace.traceparent()); ok {
 			needDiagdNotify = false
+			trace.addSpan("diagd.reconfigure", start, time.Now(), nil)
+			controlPlaneReports.observeDiagd(report, resp, time.Since(start))
 		}
 
 		if IsEdgeStack() {
-			if notifyWebhookUrl(ctx, "edgestack sidecar", fmt.Sprintf("%s?url=%s", GetSidecarUrl(), snapshotUrl)) {
+			if _, ok := notifyWebhookUrl(ctx, "edgestack sidecar", fmt.Sprintf("%s?url=%s", GetSidecarUrl(), snapshotUrl), trace.traceparent()); ok {
 				needSidecarNotify = false
 			}
 		} else {
@@ -46,20 +49,25 @@ func notifyReconfigWebhooks(ctx context.Context) {
 	}
 }
 
-// posts to a webhook style url, logging any errors, and returning false if a retry is needed
-func notifyWebhookUrl(ctx context.Context, name, xurl string) bool {
+// posts to a webhook style url, logging any errors, and returning false if a retry is needed. If
+// traceparent isn't empty, it's passed along so the receiver can add its own spans to the trace.
+// The response is returned for its status and headers; its body is closed.
+func notifyWebhookUrl(ctx context.Context, name, xurl, traceparent string) (*http.Response, bool) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, xurl, nil)
 	if err != nil {
 		panic(err)
 	}
 	req.Header.Set("content-type", "application/json")
+	if traceparent != "" {
+		req.Header.Set("traceparent", traceparent)
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		if errors.Is(err, syscall.ECONNREFUSED) {
 			// We couldn't succesfully connect to the sidecar, probably because it hasn't
 			// started up yet, so we log the error and return false to signal retry.
 			log.Println(err)
-			return false
+			return nil, false
 		} else {
 			// If either of the sidecars cannot successfully handle a webhook request, we
 			// deliberately consider it a fatal error so that we can ensure shared fate between all
@@ -82,5 +90,5 @@ func notifyWebhookUrl(ctx context.Context, name, xurl string) bool {
 	// We assume the sidecars are idempotent. That means we don't want to retry even if we get
 	// back a non 200 response since we would get an error the next time also and just be stuck
 	// retrying forever.
-	return true
+	return resp, true
 }
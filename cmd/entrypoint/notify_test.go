@@ -13,7 +13,8 @@ import (
 func TestNotifyWebhookUrlConnectionRefused(t *testing.T) {
 	ctx := context.Background()
 
-	assert.False(t, notifyWebhookUrl(ctx, "test", "http://localhost:5555"))
+	_, ok := notifyWebhookUrl(ctx, "test", "http://localhost:5555", "")
+	assert.False(t, ok)
 }
 
 // Check that we panic if we do not get a properly formed http response of some kind such as an EOF.
@@ -31,7 +32,7 @@ func TestNotifyWebhookUrlEOF(t *testing.T) {
 			e := recover()
 			assert.Error(t, e.(error))
 		}()
-		notifyWebhookUrl(ctx, "test", srv.URL)
+		notifyWebhookUrl(ctx, "test", srv.URL, "")
 		assert.Fail(t, "did not panic on EOF")
 	}()
 }
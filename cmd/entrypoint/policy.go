@@ -0,0 +1,701 @@
+package entrypoint
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/dlog"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// Ambassador can check its resources against Rego policies before they go into the snapshot, so
+// that a platform team can enforce rules like "no prefix: / Mappings outside namespace X". OPA
+// evaluates the policies, through its REST API at GetOPAURL(). The policies are the .rego keys
+// of the ConfigMaps labelled policyLabel, and the .rego files of the bundle at
+// GetOPABundleURL(); Ambassador loads them into OPA as they change.
+//
+// Each of Ambassador's own resources that this Ambassador would use is the input of the
+// GetOPADecision() rule, as it is in Kubernetes. The rule's result is the reasons to deny it:
+//
+//	package ambassador
+//
+//	deny[msg] {
+//		input.kind == "Mapping"
+//		input.spec.prefix == "/"
+//		input.metadata.namespace != "edge"
+//		msg := "only the edge namespace may have prefix: / Mappings"
+//	}
+//
+// A denied resource is left out of the snapshot, and reported as Invalid in it; it gets a
+// PolicyDenied Event, and an Accepted condition that's False with the reasons. Decisions are
+// kept until the resource or the policies change. If OPA can't be reached, a resource keeps its
+// last decision, or is allowed if it has none, so that OPA going down doesn't take the
+// configuration down with it.
+
+// policyLabel marks the ConfigMaps with policies for OPA.
+const policyLabel = "getambassador.io/opa-policy"
+
+// policySelector returns the label selector for the ConfigMaps with policies, within the ones
+// that ls selects.
+func policySelector(ls string) string {
+	if ls == "" {
+		return policyLabel
+	}
+	return ls + "," + policyLabel
+}
+
+// ReconcilePolicy loads the policies of AllPolicyConfigMaps into OPA, and leaves the resources
+// that they deny out of the inputs. It returns the denied resources, with their reasons in
+// "errors", as the snapshot's Invalid resources are.
+//
+// Every slice of getambassador.io resources is checked. The ones that other Reconcile methods
+// compute are empty until they run, so they're checked through the resources that they're
+// computed from, like AllTapPolicies.
+func (s *AmbassadorInputs) ReconcilePolicy(ctx context.Context, p *opaPolicy) []*kates.Unstructured {
+	if p == nil {
+		return nil
+	}
+	p.sync(ctx, s.AllPolicyConfigMaps)
+
+	var denied []*kates.Unstructured
+	seen := make(map[string]bool)
+	v := reflect.ValueOf(s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !isAmbassadorResources(v.Type().Field(i)) {
+			continue
+		}
+		field := v.Field(i)
+		var allowed reflect.Value
+		for j := 0; j < field.Len(); j++ {
+			obj := field.Index(j).Interface().(kates.Object)
+			var reasons []string
+			if include(GetAmbId(obj)) {
+				seen[string(obj.GetUID())] = true
+				reasons = p.decide(ctx, obj)
+			}
+			if len(reasons) > 0 {
+				if !allowed.IsValid() {
+					allowed = reflect.AppendSlice(reflect.MakeSlice(field.Type(), 0, field.Len()), field.Slice(0, j))
+				}
+				denied = append(denied, policyDenied(obj, reasons))
+			} else if allowed.IsValid() {
+				allowed = reflect.Append(allowed, field.Index(j))
+			}
+		}
+		if allowed.IsValid() {
+			field.Set(allowed)
+		}
+	}
+	p.forget(seen)
+	return denied
+}
+
+var ambassadorResourcePkg = reflect.TypeOf(amb.Mapping{}).PkgPath()
+
+// isAmbassadorResources returns whether a field of AmbassadorInputs holds getambassador.io
+// resources.
+func isAmbassadorResources(f reflect.StructField) bool {
+	return f.PkgPath == "" && f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Ptr &&
+		f.Type.Elem().Elem().PkgPath() == ambassadorResourcePkg
+}
+
+// policyDenied returns a denied resource as an Invalid one.
+func policyDenied(obj kates.Object, reasons []string) *kates.Unstructured {
+	var un kates.Unstructured
+	if err := convert(obj, &un); err != nil {
+		panic(err)
+	}
+	un.Object["errors"] = "denied by policy: " + strings.Join(reasons, "; ")
+	return &un
+}
+
+// A policyEngine evaluates policies: OPA, through its REST API.
+type policyEngine interface {
+	// PutPolicy creates or replaces a policy module.
+	PutPolicy(ctx context.Context, id, module string) error
+	// DeletePolicy deletes a policy module.
+	DeletePolicy(ctx context.Context, id string) error
+	// PutData creates or replaces the document at a path under data.
+	PutData(ctx context.Context, path string, data json.RawMessage) error
+	// Deny returns the reasons to deny the input, if there are any.
+	Deny(ctx context.Context, input interface{}) ([]string, error)
+}
+
+// A policyBundle is what's in an OPA bundle: policy modules and data, by their path in it.
+type policyBundle struct {
+	Modules map[string]string
+	Data    map[string]json.RawMessage
+}
+
+// A policyBundleFetch gets the bundle at a URL, unless its ETag is still etag. It returns the
+// bundle, or nil if it hasn't changed, and its ETag.
+type policyBundleFetch func(ctx context.Context, url, etag string) (*policyBundle, string, error)
+
+// A policyReport reports that a resource was denied, and why.
+type policyReport func(ctx context.Context, obj kates.Object, reasons []string)
+
+type policyDecision struct {
+	version  string
+	revision int
+	reasons  []string
+}
+
+type opaPolicy struct {
+	engine policyEngine
+	report policyReport
+
+	// The changed method returns this channel. We write down this channel to signal that the
+	// bundle has changed since the last time the sync method was invoked.
+	coalescedDirty chan struct{}
+	// The bundle watch writes to this when the bundle changes. It is always being read by the
+	// implementation, so writing will never block.
+	bundleCh chan *policyBundle
+
+	// The mutex protects access to bundle.
+	mutex  sync.Mutex
+	bundle *policyBundle
+
+	// The rest are only used by the watcher.
+
+	// modules are the policy modules in OPA, by id, and failed are the ones that OPA refused.
+	modules map[string]string
+	failed  map[string]string
+	// loaded is the bundle whose data is in OPA.
+	loaded *policyBundle
+	// revision counts the changes to what's in OPA, so that decisions made before one are made
+	// again.
+	revision  int
+	decisions map[string]policyDecision
+	// unavailable is whether OPA couldn't be reached during this reconcile, so that it's not
+	// asked again until the next.
+	unavailable bool
+}
+
+func newPolicy(ctx context.Context, engine policyEngine, bundleURL string, interval time.Duration,
+	fetch policyBundleFetch, report policyReport) *opaPolicy {
+	result := &opaPolicy{
+		engine:         engine,
+		report:         report,
+		coalescedDirty: make(chan struct{}),
+		bundleCh:       make(chan *policyBundle),
+		modules:        make(map[string]string),
+		failed:         make(map[string]string),
+		decisions:      make(map[string]policyDecision),
+	}
+	go result.run(ctx)
+	if bundleURL != "" {
+		go result.watchBundle(ctx, bundleURL, interval, fetch)
+	}
+	return result
+}
+
+func (p *opaPolicy) run(ctx context.Context) {
+	dirty := false
+	for {
+		if dirty {
+			select {
+			case p.coalescedDirty <- struct{}{}:
+				dirty = false
+			case bundle := <-p.bundleCh:
+				p.storeBundle(bundle)
+			case <-ctx.Done():
+				return
+			}
+		} else {
+			select {
+			case bundle := <-p.bundleCh:
+				dirty = p.storeBundle(bundle)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (p *opaPolicy) storeBundle(bundle *policyBundle) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.bundle != nil && reflect.DeepEqual(*p.bundle, *bundle) {
+		return false
+	}
+	p.bundle = bundle
+	return true
+}
+
+func (p *opaPolicy) watchBundle(ctx context.Context, bundleURL string, interval time.Duration,
+	fetch policyBundleFetch) {
+	etag := ""
+	for {
+		bundle, newETag, err := fetch(ctx, bundleURL, etag)
+		if err != nil {
+			dlog.Warnf(ctx, "OPA bundle %s: %v", bundleURL, err)
+		} else if bundle != nil {
+			etag = newETag
+			select {
+			case p.bundleCh <- bundle:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// changed returns a channel that fires when the bundle changes. It's nil, and never fires, if
+// policy enforcement is off.
+func (p *opaPolicy) changed() chan struct{} {
+	if p == nil {
+		return nil
+	}
+	return p.coalescedDirty
+}
+
+// sync loads the policies of the ConfigMaps and the bundle into OPA, and removes the ones that
+// are gone.
+func (p *opaPolicy) sync(ctx context.Context, configMaps []*kates.ConfigMap) {
+	p.unavailable = false
+
+	modules := make(map[string]string)
+	for _, cm := range configMaps {
+		for key, module := range cm.Data {
+			if strings.HasSuffix(key, ".rego") {
+				modules[path.Join("configmap", cm.GetNamespace(), cm.GetName(), key)] = module
+			}
+		}
+	}
+	p.mutex.Lock()
+	bundle := p.bundle
+	p.mutex.Unlock()
+	if bundle != nil {
+		for file, module := range bundle.Modules {
+			modules[path.Join("bundle", file)] = module
+		}
+	}
+
+	changed := false
+	ids := make([]string, 0, len(modules))
+	for id := range modules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		module := modules[id]
+		if loaded, ok := p.modules[id]; (ok && loaded == module) || p.failed[id] == module {
+			continue
+		}
+		if err := p.engine.PutPolicy(ctx, id, module); err != nil {
+			dlog.Errorf(ctx, "OPA policy %s: %v", id, err)
+			p.failed[id] = module
+			continue
+		}
+		delete(p.failed, id)
+		p.modules[id] = module
+		changed = true
+	}
+	for id := range p.modules {
+		if _, ok := modules[id]; ok {
+			continue
+		}
+		if err := p.engine.DeletePolicy(ctx, id); err != nil {
+			dlog.Errorf(ctx, "OPA policy %s: %v", id, err)
+			continue
+		}
+		delete(p.modules, id)
+		changed = true
+	}
+	for id := range p.failed {
+		if _, ok := modules[id]; !ok {
+			delete(p.failed, id)
+		}
+	}
+
+	if bundle != nil && bundle != p.loaded {
+		for dataPath, data := range bundle.Data {
+			if err := p.engine.PutData(ctx, dataPath, data); err != nil {
+				dlog.Errorf(ctx, "OPA bundle data %s: %v", path.Join("/", dataPath), err)
+			}
+		}
+		p.loaded = bundle
+		changed = true
+	}
+
+	if changed {
+		p.revision++
+	}
+}
+
+// decide returns the reasons to deny a resource, asking OPA unless it's already decided on the
+// same version of the resource since the policies last changed.
+func (p *opaPolicy) decide(ctx context.Context, obj kates.Object) []string {
+	key := string(obj.GetUID())
+	version := obj.GetResourceVersion()
+	prev, hasPrev := p.decisions[key]
+	if hasPrev && prev.version == version && prev.revision == p.revision {
+		return prev.reasons
+	}
+	if p.unavailable {
+		return prev.reasons
+	}
+
+	reasons, err := p.engine.Deny(ctx, obj)
+	if err != nil {
+		dlog.Errorf(ctx, "OPA is unavailable, so resources keep their last decisions: %v", err)
+		p.unavailable = true
+		return prev.reasons
+	}
+	p.decisions[key] = policyDecision{version: version, revision: p.revision, reasons: reasons}
+
+	if len(reasons) > 0 && !(hasPrev && prev.version == version && reflect.DeepEqual(prev.reasons, reasons)) {
+		ctx := dlog.WithField(ctx, "resource", auditKey(obj.GetObjectKind().GroupVersionKind().Kind,
+			obj.GetNamespace(), obj.GetName()))
+		dlog.Warnf(ctx, "Denied by policy: %s", strings.Join(reasons, "; "))
+		un := policyDenied(obj, reasons)
+		controlPlaneAudit.resourceRejected(un, fmt.Errorf("%v", un.Object["errors"]))
+		if p.report != nil {
+			go p.report(ctx, obj, reasons)
+		}
+	}
+	return reasons
+}
+
+// forget drops the decisions about resources that are gone.
+func (p *opaPolicy) forget(seen map[string]bool) {
+	for key := range p.decisions {
+		if !seen[key] {
+			delete(p.decisions, key)
+		}
+	}
+}
+
+// opaClient is a policyEngine that's OPA's REST API at url, with the rule at decision.
+type opaClient struct {
+	url      string
+	decision string
+	client   *http.Client
+}
+
+func newOPAClient(opaURL, decision string) *opaClient {
+	return &opaClient{
+		url:      strings.TrimSuffix(opaURL, "/"),
+		decision: decision,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// opaError is the body of OPA's error responses.
+type opaError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Errors  []struct {
+		Message  string `json:"message"`
+		Location *struct {
+			File string `json:"file"`
+			Row  int    `json:"row"`
+		} `json:"location"`
+	} `json:"errors"`
+}
+
+func (e *opaError) Error() string {
+	var details []string
+	for _, detail := range e.Errors {
+		if detail.Location != nil {
+			details = append(details, fmt.Sprintf("%s:%d: %s", detail.Location.File, detail.Location.Row, detail.Message))
+		} else {
+			details = append(details, detail.Message)
+		}
+	}
+	if len(details) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(details, "; "))
+}
+
+func (c *opaClient) do(ctx context.Context, method, apiPath, contentType string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, c.url+(&url.URL{Path: apiPath}).EscapedPath(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var opaErr opaError
+		if json.Unmarshal(data, &opaErr) == nil && opaErr.Message != "" {
+			return &opaErr
+		}
+		return fmt.Errorf("%s %s: %s", method, apiPath, resp.Status)
+	}
+	if result != nil {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
+
+func (c *opaClient) PutPolicy(ctx context.Context, id, module string) error {
+	return c.do(ctx, http.MethodPut, "/v1/policies/"+id, "text/plain", []byte(module), nil)
+}
+
+func (c *opaClient) DeletePolicy(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodDelete, "/v1/policies/"+id, "", nil, nil)
+	if opaErr, ok := err.(*opaError); ok && opaErr.Code == "resource_not_found" {
+		return nil
+	}
+	return err
+}
+
+func (c *opaClient) PutData(ctx context.Context, dataPath string, data json.RawMessage) error {
+	return c.do(ctx, http.MethodPut, path.Join("/v1/data", dataPath), "application/json", data, nil)
+}
+
+func (c *opaClient) Deny(ctx context.Context, input interface{}) ([]string, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Result interface{} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/data/"+c.decision, "application/json", body, &response); err != nil {
+		return nil, err
+	}
+	return denyReasons(response.Result), nil
+}
+
+// denyReasons returns the reasons in the result of a deny rule: a set of messages, as a partial
+// rule like deny[msg] has; a message; or true, as a complete rule like deny { ... } has.
+// Messages that aren't strings are written as JSON.
+func denyReasons(result interface{}) []string {
+	switch result := result.(type) {
+	case nil:
+		return nil
+	case bool:
+		if result {
+			return []string{"denied"}
+		}
+		return nil
+	case string:
+		return []string{result}
+	case []interface{}:
+		var reasons []string
+		for _, reason := range result {
+			if s, ok := reason.(string); ok {
+				reasons = append(reasons, s)
+			} else {
+				data, _ := json.Marshal(reason)
+				reasons = append(reasons, string(data))
+			}
+		}
+		sort.Strings(reasons)
+		return reasons
+	default:
+		data, _ := json.Marshal(result)
+		return []string{string(data)}
+	}
+}
+
+// fetchPolicyBundle is the policyBundleFetch that gets a bundle over HTTP.
+func fetchPolicyBundle(ctx context.Context, bundleURL, etag string) (*policyBundle, string, error) {
+	req, err := http.NewRequest(http.MethodGet, bundleURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req = req.WithContext(ctx)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s", resp.Status)
+	}
+	bundle, err := readPolicyBundle(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return bundle, resp.Header.Get("ETag"), nil
+}
+
+// readPolicyBundle reads a bundle: a gzipped tarball of .rego modules and data.json documents,
+// each of which is the data at the directory that it's in.
+func readPolicyBundle(r io.Reader) (*policyBundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	bundle := &policyBundle{Modules: make(map[string]string), Data: make(map[string]json.RawMessage)}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return bundle, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		switch {
+		case strings.HasSuffix(name, ".rego"):
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			bundle.Modules[name] = string(data)
+		case path.Base(name) == "data.json":
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			if !json.Valid(data) {
+				return nil, fmt.Errorf("%s isn't JSON", name)
+			}
+			dir := path.Dir(name)
+			if dir == "." {
+				dir = ""
+			}
+			bundle.Data[dir] = data
+		}
+	}
+}
+
+// reportPolicyDenial is the policyReport that writes a PolicyDenied Event about the resource,
+// and sets its conditions to say that it wasn't accepted, and why. Only the leader writes them,
+// since every replica denies the same resources.
+func reportPolicyDenial(client *kates.Client, leader leadership) policyReport {
+	return func(ctx context.Context, obj kates.Object, reasons []string) {
+		if !leader.leads() {
+			return
+		}
+		message := "denied by policy: " + strings.Join(reasons, "; ")
+		if err := writePolicyEvent(ctx, client, obj, message); err != nil {
+			dlog.Warnf(ctx, "Writing the PolicyDenied Event: %v", err)
+		}
+		if err := writePolicyStatus(ctx, client, obj, message); err != nil {
+			dlog.Warnf(ctx, "Writing the status: %v", err)
+		}
+	}
+}
+
+func writePolicyEvent(ctx context.Context, client *kates.Client, obj kates.Object, message string) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	now := kates.Now()
+	event := &kates.Event{
+		TypeMeta:   kates.TypeMeta{APIVersion: "v1", Kind: "Event"},
+		ObjectMeta: kates.ObjectMeta{GenerateName: obj.GetName() + ".", Namespace: obj.GetNamespace()},
+		InvolvedObject: kates.ObjectReference{
+			APIVersion:      gvk.GroupVersion().String(),
+			Kind:            gvk.Kind,
+			Name:            obj.GetName(),
+			Namespace:       obj.GetNamespace(),
+			UID:             obj.GetUID(),
+			ResourceVersion: obj.GetResourceVersion(),
+		},
+		Reason:         "PolicyDenied",
+		Message:        message,
+		Type:           "Warning",
+		Source:         kates.EventSource{Component: "ambassador"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	return client.Create(ctx, event, nil)
+}
+
+// writePolicyStatus sets the conditions of a denied resource the way diagd sets them for a
+// resource that it doesn't accept, with PolicyDenied as the reason. The status of a resource
+// that's changed since it was denied is left alone, since it'll be decided on again.
+func writePolicyStatus(ctx context.Context, client *kates.Client, obj kates.Object, message string) error {
+	var un kates.Unstructured
+	if err := client.Get(ctx, obj, &un); err != nil {
+		return err
+	}
+	if un.GetResourceVersion() != obj.GetResourceVersion() {
+		return nil
+	}
+
+	status, _ := un.Object["status"].(map[string]interface{})
+	if status == nil {
+		status = make(map[string]interface{})
+	}
+	previous, _ := status["conditions"].([]interface{})
+	status["conditions"] = policyDeniedConditions(previous, message, un.GetGeneration(),
+		time.Now().UTC().Format(time.RFC3339))
+	un.Object["status"] = status
+	return client.UpdateStatus(ctx, &un, nil)
+}
+
+// policyDeniedConditions returns the conditions of a denied resource, keeping the ones of the
+// previous conditions that aren't Accepted, Programmed or Ready, and the lastTransitionTime of
+// the ones that haven't changed.
+func policyDeniedConditions(previous []interface{}, message string, generation int64, now string) []interface{} {
+	denied := []map[string]interface{}{
+		{"type": "Accepted", "status": "False", "reason": "PolicyDenied", "message": message},
+		{"type": "Programmed", "status": "False", "reason": "NotAccepted"},
+		{"type": "Ready", "status": "False", "reason": "NotAccepted"},
+	}
+
+	var conditions []interface{}
+	old := make(map[interface{}]map[string]interface{})
+	for _, c := range previous {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch cond["type"] {
+		case "Accepted", "Programmed", "Ready":
+			old[cond["type"]] = cond
+		default:
+			conditions = append(conditions, cond)
+		}
+	}
+
+	for _, cond := range denied {
+		cond["observedGeneration"] = generation
+		cond["lastTransitionTime"] = now
+		if was, ok := old[cond["type"]]; ok && was["status"] == cond["status"] && was["lastTransitionTime"] != nil {
+			cond["lastTransitionTime"] = was["lastTransitionTime"]
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions
+}
@@ -0,0 +1,311 @@
+package entrypoint
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// fakeOPA is a policyEngine that denies Mappings with prefix: / outside the edge namespace, once
+// it has a policy.
+type fakeOPA struct {
+	mutex    sync.Mutex
+	policies map[string]string
+	data     map[string]string
+	asked    int
+	down     bool
+}
+
+func (f *fakeOPA) PutPolicy(_ context.Context, id, module string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if module == "broken" {
+		return errors.New("rego_parse_error")
+	}
+	f.policies[id] = module
+	return nil
+}
+
+func (f *fakeOPA) DeletePolicy(_ context.Context, id string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.policies, id)
+	return nil
+}
+
+func (f *fakeOPA) PutData(_ context.Context, path string, data json.RawMessage) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.data[path] = string(data)
+	return nil
+}
+
+func (f *fakeOPA) Deny(_ context.Context, input interface{}) ([]string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.down {
+		return nil, errors.New("connection refused")
+	}
+	f.asked++
+	m, ok := input.(*amb.Mapping)
+	if len(f.policies) == 0 || !ok || m.Spec.Prefix != "/" || m.GetNamespace() == "edge" {
+		return nil, nil
+	}
+	return []string{"only the edge namespace may have prefix: / Mappings"}, nil
+}
+
+func policyMapping(name, namespace, prefix, version string) *amb.Mapping {
+	m := &amb.Mapping{Spec: amb.MappingSpec{Prefix: prefix, Service: "quote"}}
+	m.SetName(name)
+	m.SetNamespace(namespace)
+	m.SetUID(types.UID(name + "." + namespace))
+	m.SetResourceVersion(version)
+	return m
+}
+
+func policyConfigMap(module string) *kates.ConfigMap {
+	cm := &kates.ConfigMap{Data: map[string]string{"mappings.rego": module, "README": "not a policy"}}
+	cm.SetName("policies")
+	cm.SetNamespace("platform")
+	return cm
+}
+
+func TestReconcilePolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opa := &fakeOPA{policies: map[string]string{}, data: map[string]string{}}
+	reported := make(chan string, 10)
+	p := newPolicy(ctx, opa, "", time.Minute, nil, func(_ context.Context, obj kates.Object, reasons []string) {
+		reported <- obj.GetName()
+	})
+
+	root := policyMapping("root", "default", "/", "1")
+	edge := policyMapping("edge-root", "edge", "/", "1")
+	backend := policyMapping("backend", "default", "/backend/", "1")
+	other := policyMapping("other", "default", "/", "1")
+	other.Spec.AmbassadorID = amb.AmbassadorID{"someone-else"}
+	host := &amb.Host{Spec: &amb.HostSpec{Hostname: "example.com"}}
+	host.SetName("example")
+	host.SetUID("example")
+
+	s := &AmbassadorInputs{
+		AllPolicyConfigMaps: []*kates.ConfigMap{policyConfigMap("package ambassador")},
+		Mappings:            []*amb.Mapping{root, edge, backend, other},
+		Hosts:               []*amb.Host{host},
+	}
+	inputs := *s
+	denied := inputs.ReconcilePolicy(ctx, p)
+
+	assert.Equal(t, map[string]string{"configmap/platform/policies/mappings.rego": "package ambassador"}, opa.policies)
+	assert.Equal(t, []*amb.Mapping{edge, backend, other}, inputs.Mappings, "only Mappings for this Ambassador are checked")
+	assert.Equal(t, []*amb.Host{host}, inputs.Hosts)
+	assert.Len(t, s.Mappings, 4, "the snapshot keeps everything, in case the policy changes")
+	require.Len(t, denied, 1)
+	assert.Equal(t, "root", denied[0].GetName())
+	assert.Equal(t, "denied by policy: only the edge namespace may have prefix: / Mappings", denied[0].Object["errors"])
+	assert.Equal(t, 4, opa.asked)
+	assert.Equal(t, "root", <-reported)
+
+	// Nothing changed, so nothing is asked or reported again.
+	inputs = *s
+	assert.Len(t, inputs.ReconcilePolicy(ctx, p), 1)
+	assert.Equal(t, 4, opa.asked)
+
+	// A change to a resource is decided on again.
+	fixed := policyMapping("root", "default", "/default/", "2")
+	s.Mappings = []*amb.Mapping{fixed, edge, backend, other}
+	inputs = *s
+	assert.Empty(t, inputs.ReconcilePolicy(ctx, p))
+	assert.Len(t, inputs.Mappings, 4)
+	assert.Equal(t, 5, opa.asked)
+
+	// If OPA goes down, resources keep their last decisions, and new ones are allowed.
+	late := policyMapping("late", "default", "/", "1")
+	s.Mappings = []*amb.Mapping{fixed, edge, backend, other, late}
+	opa.down = true
+	p.revision++
+	inputs = *s
+	assert.Empty(t, inputs.ReconcilePolicy(ctx, p))
+	assert.Len(t, inputs.Mappings, 5)
+	opa.down = false
+	s.Mappings = []*amb.Mapping{fixed, edge, backend, other}
+
+	// A policy that OPA refuses isn't loaded, and isn't tried again until it changes.
+	s.AllPolicyConfigMaps = []*kates.ConfigMap{policyConfigMap("broken")}
+	inputs = *s
+	inputs.ReconcilePolicy(ctx, p)
+	assert.Equal(t, "package ambassador", opa.policies["configmap/platform/policies/mappings.rego"])
+	assert.Equal(t, "broken", p.failed["configmap/platform/policies/mappings.rego"])
+
+	// Without the policy, everything is decided on again, and allowed.
+	s.AllPolicyConfigMaps = nil
+	asked := opa.asked
+	inputs = *s
+	assert.Empty(t, inputs.ReconcilePolicy(ctx, p))
+	assert.Empty(t, opa.policies)
+	assert.Empty(t, p.failed)
+	assert.Equal(t, asked+4, opa.asked)
+	assert.Len(t, inputs.Mappings, 4)
+
+	select {
+	case name := <-reported:
+		t.Errorf("%s was reported again", name)
+	default:
+	}
+
+	// The decisions about resources that are gone are forgotten.
+	s.Mappings = []*amb.Mapping{backend}
+	inputs = *s
+	inputs.ReconcilePolicy(ctx, p)
+	assert.Len(t, p.decisions, 2)
+}
+
+func TestReconcilePolicyOff(t *testing.T) {
+	s := &AmbassadorInputs{Mappings: []*amb.Mapping{policyMapping("root", "default", "/", "1")}}
+	assert.Nil(t, s.ReconcilePolicy(context.Background(), nil))
+	assert.Len(t, s.Mappings, 1)
+	var p *opaPolicy
+	assert.Nil(t, p.changed())
+}
+
+func TestPolicyBundle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range map[string]string{
+		"/ambassador/mappings.rego": "package ambassador",
+		"ambassador/data.json":      `{"edge": ["edge"]}`,
+		".manifest":                 `{"revision": "1"}`,
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	bundle, etag, err := fetchPolicyBundle(ctx, server.URL, "")
+	require.NoError(t, err)
+	assert.Equal(t, `"1"`, etag)
+	assert.Equal(t, map[string]string{"ambassador/mappings.rego": "package ambassador"}, bundle.Modules)
+	assert.Equal(t, map[string]json.RawMessage{"ambassador": json.RawMessage(`{"edge": ["edge"]}`)}, bundle.Data)
+
+	unchanged, etag, err := fetchPolicyBundle(ctx, server.URL, etag)
+	require.NoError(t, err)
+	assert.Nil(t, unchanged)
+	assert.Equal(t, `"1"`, etag)
+
+	opa := &fakeOPA{policies: map[string]string{}, data: map[string]string{}}
+	p := newPolicy(ctx, opa, server.URL, 10*time.Millisecond, fetchPolicyBundle, nil)
+	select {
+	case <-p.changed():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the bundle")
+	}
+	s := &AmbassadorInputs{Mappings: []*amb.Mapping{policyMapping("root", "default", "/", "1")}}
+	assert.Len(t, s.ReconcilePolicy(ctx, p), 1)
+	opa.mutex.Lock()
+	assert.Equal(t, map[string]string{"bundle/ambassador/mappings.rego": "package ambassador"}, opa.policies)
+	assert.Equal(t, map[string]string{"ambassador": `{"edge": ["edge"]}`}, opa.data)
+	opa.mutex.Unlock()
+}
+
+func TestOPAClient(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		switch {
+		case r.Method == http.MethodPut && string(body) == "broken":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": "invalid_parameter", "message": "error(s) occurred while compiling module(s)",
+				"errors": [{"code": "rego_parse_error", "message": "var cannot be used for rule name",
+				"location": {"file": "configmap/platform/policies/mappings.rego", "row": 3}}]}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code": "resource_not_found", "message": "storage_not_found_error: policy id"}`))
+		case r.Method == http.MethodPost:
+			_, _ = w.Write([]byte(`{"result": ["b", "a"]}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := newOPAClient(server.URL+"/", "ambassador/deny")
+
+	require.NoError(t, c.PutPolicy(ctx, "configmap/platform/policies/mappings.rego", "package ambassador"))
+	err := c.PutPolicy(ctx, "configmap/platform/policies/mappings.rego", "broken")
+	assert.EqualError(t, err, "error(s) occurred while compiling module(s): "+
+		"configmap/platform/policies/mappings.rego:3: var cannot be used for rule name")
+	assert.NoError(t, c.DeletePolicy(ctx, "gone"), "deleting a policy that isn't there is fine")
+	require.NoError(t, c.PutData(ctx, "ambassador", json.RawMessage(`{"edge": ["edge"]}`)))
+
+	reasons, err := c.Deny(ctx, policyMapping("root", "default", "/", "1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, reasons)
+
+	require.Len(t, requests, 5)
+	assert.Equal(t, "PUT /v1/policies/configmap/platform/policies/mappings.rego package ambassador", requests[0])
+	assert.Equal(t, `PUT /v1/data/ambassador {"edge": ["edge"]}`, requests[3])
+	assert.Contains(t, requests[4], `POST /v1/data/ambassador/deny {"input":{`)
+	assert.Contains(t, requests[4], `"prefix":"/"`)
+}
+
+func TestDenyReasons(t *testing.T) {
+	assert.Nil(t, denyReasons(nil))
+	assert.Nil(t, denyReasons(false))
+	assert.Nil(t, denyReasons([]interface{}{}))
+	assert.Equal(t, []string{"denied"}, denyReasons(true))
+	assert.Equal(t, []string{"no"}, denyReasons("no"))
+	assert.Equal(t, []string{`{"msg":"no"}`, "yes"}, denyReasons([]interface{}{"yes", map[string]interface{}{"msg": "no"}}))
+}
+
+func TestPolicyDeniedConditions(t *testing.T) {
+	previous := []interface{}{
+		map[string]interface{}{"type": "Accepted", "status": "True", "reason": "Accepted", "lastTransitionTime": "then"},
+		map[string]interface{}{"type": "Ready", "status": "False", "reason": "NotProgrammed", "lastTransitionTime": "then"},
+		map[string]interface{}{"type": "Resolved", "status": "True", "reason": "Resolved"},
+	}
+	conditions := policyDeniedConditions(previous, "denied by policy: no", 3, "now")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "Resolved", "status": "True", "reason": "Resolved"},
+		map[string]interface{}{"type": "Accepted", "status": "False", "reason": "PolicyDenied",
+			"message": "denied by policy: no", "observedGeneration": int64(3), "lastTransitionTime": "now"},
+		map[string]interface{}{"type": "Programmed", "status": "False", "reason": "NotAccepted",
+			"observedGeneration": int64(3), "lastTransitionTime": "now"},
+		map[string]interface{}{"type": "Ready", "status": "False", "reason": "NotAccepted",
+			"observedGeneration": int64(3), "lastTransitionTime": "then"},
+	}, conditions)
+}
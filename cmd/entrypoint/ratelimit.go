@@ -0,0 +1,267 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/ratelimit"
+)
+
+// The validateRateLimitLabels function checks the rate limit labels of a Mapping, so that
+// malformed labels get a warning from the watcher as well as an error from diagd. Resources other
+// than Mappings are always OK.
+func validateRateLimitLabels(un *kates.Unstructured) error {
+	if un.GetKind() != "Mapping" {
+		return nil
+	}
+
+	bytes, err := json.Marshal(un.Object)
+	if err != nil {
+		return err
+	}
+
+	var mapping amb.Mapping
+	if err := json.Unmarshal(bytes, &mapping); err != nil {
+		return err
+	}
+
+	return ratelimit.ValidateMappingLabels(mapping.Spec.Labels)
+}
+
+// The rateLimitLabelErrors type holds the rate limit label errors that the watcher has logged,
+// by resource, so that each is only logged once. A resource's error is forgotten when its labels
+// are fixed or it's deleted.
+type rateLimitLabelErrors map[string]string
+
+// The check method checks a resource's rate limit labels, and returns the error if it's one that
+// hasn't been logged yet.
+func (e rateLimitLabelErrors) check(un *kates.Unstructured) error {
+	key := auditKey(un.GetKind(), un.GetNamespace(), un.GetName())
+	err := validateRateLimitLabels(un)
+	if err == nil {
+		delete(e, key)
+		return nil
+	}
+	if e[key] == err.Error() {
+		return nil
+	}
+	e[key] = err.Error()
+	return err
+}
+
+// The forget method forgets the errors of the resources that the deltas delete.
+func (e rateLimitLabelErrors) forget(deltas []*kates.Delta) {
+	for _, delta := range deltas {
+		if delta.DeltaType == kates.ObjectDelete {
+			delete(e, auditKey(delta.Kind, delta.GetNamespace(), delta.GetName()))
+		}
+	}
+}
+
+// The DescriptorRequest struct is the sample request that the rate limit descriptor diagnostics
+// endpoint works out descriptors for.
+type DescriptorRequest struct {
+	Method        string            `json:"method"`
+	Path          string            `json:"path"`
+	Host          string            `json:"host"`
+	Headers       map[string]string `json:"headers"`
+	RemoteAddress string            `json:"remote_address"`
+}
+
+// The LabelDescriptor struct is what one rate limit label produces for the sample request. A
+// label that produces no descriptor (because, say, a header it needs is missing) has a nil
+// Descriptor.
+type LabelDescriptor struct {
+	Label      string               `json:"label"`
+	Descriptor ratelimit.Descriptor `json:"descriptor"`
+}
+
+// The MappingDescriptors struct describes the descriptors that one Mapping would produce for the
+// sample request.
+type MappingDescriptors struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Prefix      string            `json:"prefix"`
+	Precedence  int               `json:"precedence"`
+	Descriptors []LabelDescriptor `json:"descriptors"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// The DescriptorReport struct is what gets served by the rate limit descriptor diagnostics
+// endpoint.
+type DescriptorReport struct {
+	// Domain is the domain of the RateLimitService; only labels for that domain are sent.
+	Domain string `json:"domain"`
+	// Mappings lists every Mapping that matches the sample request, with the one that Envoy is
+	// most likely to route to first. The order is by precedence and then by prefix length, which
+	// is an approximation of how diagd orders routes.
+	Mappings []MappingDescriptors `json:"mappings"`
+}
+
+// The handleRateLimitDescriptors function serves a DescriptorReport for the sample request that
+// is POSTed to it as JSON, using the most recent snapshot. Only Mapping labels are considered;
+// the Ambassador Module's default labels are not.
+func handleRateLimitDescriptors(snapshot *snapshotHandoff) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST a JSON sample request", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req DescriptorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		snap := snapshot.load()
+		if snap == nil {
+			http.Error(w, "no snapshot yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		report := describeRateLimits(snap.Kubernetes, req)
+
+		bytes, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(bytes)
+	}
+}
+
+// The describeRateLimits function works out the DescriptorReport for req.
+func describeRateLimits(inputs *AmbassadorInputs, req DescriptorRequest) DescriptorReport {
+	report := DescriptorReport{Domain: "ambassador", Mappings: []MappingDescriptors{}}
+	if inputs == nil {
+		return report
+	}
+
+	for _, rls := range inputs.RateLimitServices {
+		if rls.Spec.Domain != "" {
+			report.Domain = rls.Spec.Domain
+		}
+	}
+
+	headers := http.Header{}
+	for name, value := range req.Headers {
+		headers.Set(name, value)
+	}
+	rlreq := ratelimit.Request{Headers: headers, RemoteAddress: req.RemoteAddress}
+
+	for _, mapping := range inputs.Mappings {
+		if !mappingMatches(mapping, req, headers) {
+			continue
+		}
+
+		md := MappingDescriptors{
+			Name:        mapping.GetName(),
+			Namespace:   mapping.GetNamespace(),
+			Prefix:      mapping.Spec.Prefix,
+			Precedence:  mapping.Spec.Precedence,
+			Descriptors: []LabelDescriptor{},
+		}
+
+		labels, err := ratelimit.MappingLabels(mapping.Spec.Labels, report.Domain)
+		if err != nil {
+			md.Error = err.Error()
+		}
+		for _, label := range labels {
+			ld := LabelDescriptor{Label: label.Name}
+			if descriptor, ok := label.Descriptor(rlreq); ok {
+				ld.Descriptor = descriptor
+			}
+			md.Descriptors = append(md.Descriptors, ld)
+		}
+
+		report.Mappings = append(report.Mappings, md)
+	}
+
+	sort.SliceStable(report.Mappings, func(i, j int) bool {
+		a, b := report.Mappings[i], report.Mappings[j]
+		if a.Precedence != b.Precedence {
+			return a.Precedence > b.Precedence
+		}
+		return len(a.Prefix) > len(b.Prefix)
+	})
+
+	return report
+}
+
+// The mappingMatches function checks whether a Mapping would match req. It only looks at the
+// prefix, method, host, and headers.
+func mappingMatches(mapping *amb.Mapping, req DescriptorRequest, headers http.Header) bool {
+	spec := mapping.Spec
+
+	if spec.PrefixRegex {
+		if !regexMatches(spec.Prefix, req.Path) {
+			return false
+		}
+	} else if !strings.HasPrefix(req.Path, spec.Prefix) {
+		return false
+	}
+
+	if spec.Method != "" {
+		if spec.MethodRegex {
+			if !regexMatches(spec.Method, req.Method) {
+				return false
+			}
+		} else if !strings.EqualFold(spec.Method, req.Method) {
+			return false
+		}
+	}
+
+	if spec.Host != "" && spec.Host != "*" {
+		if spec.HostRegex {
+			if !regexMatches(spec.Host, req.Host) {
+				return false
+			}
+		} else if !strings.EqualFold(spec.Host, req.Host) {
+			return false
+		}
+	}
+
+	for name, want := range spec.Headers {
+		values, present := headers[http.CanonicalHeaderKey(name)]
+		switch {
+		case want.String != nil:
+			if !present || values[0] != *want.String {
+				return false
+			}
+		case want.Bool != nil:
+			if present != *want.Bool {
+				return false
+			}
+		}
+	}
+
+	for name, want := range spec.RegexHeaders {
+		if want.String == nil {
+			continue
+		}
+		values, present := headers[http.CanonicalHeaderKey(name)]
+		if !present || !regexMatches(*want.String, values[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The regexMatches function matches the way Envoy does, against the whole string. A bad regex
+// matches nothing.
+func regexMatches(pattern, s string) bool {
+	re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+	if err != nil {
+		return false
+	}
+	return re.MatchString(s)
+}
@@ -0,0 +1,140 @@
+package entrypoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/ratelimit"
+)
+
+func parseMapping(t *testing.T, input string) *amb.Mapping {
+	var mapping amb.Mapping
+	require.NoError(t, json.Unmarshal([]byte(input), &mapping))
+	return &mapping
+}
+
+// Check that the sample request picks up the right Mappings, in the right order, and that each
+// label's descriptor is worked out.
+func TestRateLimitDescriptors(t *testing.T) {
+	inputs := &AmbassadorInputs{
+		Mappings: []*amb.Mapping{
+			parseMapping(t, `{
+				"metadata": {"name": "catchall", "namespace": "default"},
+				"spec": {"prefix": "/", "service": "catchall"}
+			}`),
+			parseMapping(t, `{
+				"metadata": {"name": "qotm", "namespace": "default"},
+				"spec": {
+					"prefix": "/qotm/", "service": "qotm",
+					"labels": {"ambassador": [
+						{"backend": ["remote_address", "qotm"]},
+						{"user": [{"x-user": {"header": "x-user"}}]}
+					]}
+				}
+			}`),
+			parseMapping(t, `{
+				"metadata": {"name": "qotm-post", "namespace": "default"},
+				"spec": {"prefix": "/qotm/", "method": "POST", "service": "qotm"}
+			}`),
+			parseMapping(t, `{
+				"metadata": {"name": "qotm-canary", "namespace": "default"},
+				"spec": {"prefix": "/qotm/", "headers": {"x-canary": true}, "service": "qotm-canary"}
+			}`),
+		},
+	}
+
+	report := describeRateLimits(inputs, DescriptorRequest{
+		Method:        "GET",
+		Path:          "/qotm/quote",
+		RemoteAddress: "10.0.0.1",
+	})
+
+	assert.Equal(t, "ambassador", report.Domain)
+	require.Len(t, report.Mappings, 2)
+	assert.Equal(t, "catchall", report.Mappings[1].Name)
+	assert.Equal(t, MappingDescriptors{
+		Name:       "qotm",
+		Namespace:  "default",
+		Prefix:     "/qotm/",
+		Precedence: 0,
+		Descriptors: []LabelDescriptor{
+			{Label: "backend", Descriptor: ratelimit.Descriptor{{Key: "remote_address", Value: "10.0.0.1"}, {Key: "generic_key", Value: "qotm"}}},
+			{Label: "user"},
+		},
+	}, report.Mappings[0])
+
+	// Through the handler this time.
+	snapshot := &snapshotHandoff{}
+	rec := httptest.NewRecorder()
+	handleRateLimitDescriptors(snapshot)(rec, httptest.NewRequest(http.MethodPost, "/ratelimit/descriptors", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	snapshot.store(&Snapshot{Kubernetes: inputs})
+
+	body, err := json.Marshal(DescriptorRequest{
+		Method:  "GET",
+		Path:    "/qotm/",
+		Headers: map[string]string{"X-User": "alice", "X-Canary": "yes"},
+	})
+	require.NoError(t, err)
+
+	rec = httptest.NewRecorder()
+	handleRateLimitDescriptors(snapshot)(rec, httptest.NewRequest(http.MethodPost, "/ratelimit/descriptors", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report.Mappings, 3)
+	assert.Equal(t, ratelimit.Descriptor{{Key: "x-user", Value: "alice"}}, report.Mappings[0].Descriptors[1].Descriptor)
+}
+
+// Check that a label error is only reported once, and is forgotten when the labels are fixed or
+// the Mapping is deleted.
+func TestRateLimitLabelErrors(t *testing.T) {
+	mapping := func(labels string) *kates.Unstructured {
+		un := &kates.Unstructured{}
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"apiVersion": "getambassador.io/v2", "kind": "Mapping",
+			"metadata": {"name": "qotm", "namespace": "default"},
+			"spec": {"prefix": "/qotm/", "service": "qotm", "labels": `+labels+`}
+		}`), un))
+		return un
+	}
+	bad := mapping(`{"ambassador": [{"a": ["x"], "b": ["y"]}]}`)
+	good := mapping(`{"ambassador": [{"a": ["x"]}]}`)
+
+	errs := rateLimitLabelErrors{}
+	assert.Error(t, errs.check(bad))
+	assert.NoError(t, errs.check(bad))
+	assert.Len(t, errs, 1)
+
+	assert.NoError(t, errs.check(good))
+	assert.Empty(t, errs)
+	assert.Error(t, errs.check(bad))
+
+	errs.forget([]*kates.Delta{
+		{
+			TypeMeta:   kates.TypeMeta{Kind: "Mapping"},
+			ObjectMeta: kates.ObjectMeta{Name: "qotm", Namespace: "default"},
+			DeltaType:  kates.ObjectUpdate,
+		},
+	})
+	assert.Len(t, errs, 1)
+
+	errs.forget([]*kates.Delta{
+		{
+			TypeMeta:   kates.TypeMeta{Kind: "Mapping"},
+			ObjectMeta: kates.ObjectMeta{Name: "qotm", Namespace: "default"},
+			DeltaType:  kates.ObjectDelete,
+		},
+	})
+	assert.Empty(t, errs)
+	assert.Error(t, errs.check(bad))
+}
@@ -0,0 +1,35 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/datawire/ambassador/cmd/ambex"
+)
+
+// readinessURL is where diagd's check_ready asks whether Envoy has accepted its configuration.
+const readinessURL = "http://localhost:9696/readiness"
+
+// The readinessStatus struct is what /readiness serves.
+type readinessStatus struct {
+	ambex.ACKStatus
+	// MemoryPressure is "critical" when the memory watchdog has marked Ambassador not ready.
+	MemoryPressure string `json:"memory_pressure,omitempty"`
+}
+
+// handleReadiness serves whether Envoy has ACKed the configuration that ambex gave it, with a 503
+// until it has, so that Ambassador isn't marked ready (and sent traffic) while Envoy has no routes.
+// It's also a 503 while memory pressure is critical.
+func handleReadiness(w http.ResponseWriter, r *http.Request) {
+	status := readinessStatus{ACKStatus: ambex.GetACKStatus()}
+	if memoryWatch.degraded() {
+		status.MemoryPressure = memoryCritical.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Acked || status.MemoryPressure != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,195 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every reconfiguration gets a report of where its time went, from the change that started it to
+// Envoy's answer: the watcher's delta processing and validation, the snapshot build, each of
+// diagd's phases, the ambex push, and the wait for Envoy to ACK what ambex pushed. /reconfigs on
+// the snapshot server serves the last few reports.
+//
+// Reconfigurations overlap a little: the watcher starts the next one as soon as diagd answers,
+// while ambex and Envoy may still be busy with the last. So ambex's push goes to the oldest report
+// that's waiting for one, and Envoy's answer to the report of the latest push.
+
+// The reconfigPhase struct is how long one phase of a reconfiguration took.
+type reconfigPhase struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// The reconfigReport struct is the report of one reconfiguration.
+type reconfigReport struct {
+	ID     int       `json:"id"`
+	Source string    `json:"source"`
+	Start  time.Time `json:"start"`
+	// Status is "running" until Envoy has answered, then "acked" or "nacked". It's "unchanged" if
+	// ambex found nothing to push, "failed" if diagd couldn't reconfigure, and "superseded" if a
+	// later push came before Envoy answered.
+	Status string `json:"status"`
+	// Seconds is from Start to the end of the last phase so far.
+	Seconds float64         `json:"seconds"`
+	Phases  []reconfigPhase `json:"phases"`
+
+	pushed bool
+}
+
+func (r *reconfigReport) add(name string, d time.Duration) {
+	r.Phases = append(r.Phases, reconfigPhase{Name: name, Seconds: d.Seconds()})
+	r.Seconds = time.Since(r.Start).Seconds()
+}
+
+type reconfigReports struct {
+	mu      sync.Mutex
+	size    int
+	nextID  int
+	reports []*reconfigReport // oldest first
+}
+
+var controlPlaneReports = newReconfigReports(GetReconfigReportCount())
+
+func newReconfigReports(size int) *reconfigReports {
+	return &reconfigReports{size: size, nextID: 1}
+}
+
+// The resize method changes how many reports are kept, dropping the oldest if there are more.
+func (rs *reconfigReports) resize(size int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.size = size
+	if len(rs.reports) > rs.size {
+		rs.reports = append([]*reconfigReport(nil), rs.reports[len(rs.reports)-rs.size:]...)
+	}
+}
+
+// The start method starts the report of a reconfiguration, for a change from source at start,
+// with the phases that it's been through already.
+func (rs *reconfigReports) start(source string, start time.Time, phases []reconfigPhase) *reconfigReport {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r := &reconfigReport{ID: rs.nextID, Source: source, Start: start, Status: "running", Phases: phases}
+	r.Seconds = time.Since(start).Seconds()
+	rs.nextID++
+
+	rs.reports = append(rs.reports, r)
+	if len(rs.reports) > rs.size {
+		rs.reports = rs.reports[len(rs.reports)-rs.size:]
+	}
+	return r
+}
+
+// The observeDiagd method records diagd's answer to the webhook, which took d, and the phases
+// that its Server-Timing header lists. Whatever's left of d is "diagd.other".
+func (rs *reconfigReports) observeDiagd(r *reconfigReport, resp *http.Response, d time.Duration) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	other := d
+	for _, phase := range parseServerTiming(resp.Header.Get("Server-Timing")) {
+		r.Phases = append(r.Phases, phase)
+		other -= time.Duration(phase.Seconds * float64(time.Second))
+	}
+	if other < 0 {
+		other = 0
+	}
+	r.add("diagd.other", other)
+	if resp.StatusCode != http.StatusOK {
+		r.Status = "failed"
+	}
+}
+
+// The observeAmbexPush method is hooked into ambex. The push goes to the oldest report that's
+// waiting for one; the reports before it that are waiting for Envoy never will be now.
+func (rs *reconfigReports) observeAmbexPush(d time.Duration, pushed bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for _, r := range rs.reports {
+		if r.Status != "running" {
+			continue
+		}
+		if r.pushed {
+			r.Status = "superseded"
+			continue
+		}
+		r.pushed = true
+		r.add("ambex.push", d)
+		if !pushed {
+			r.Status = "unchanged"
+		}
+		return
+	}
+}
+
+// The observeEnvoyACK method is hooked into ambex: Envoy answered the latest push after d.
+func (rs *reconfigReports) observeEnvoyACK(d time.Duration, nacked bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for i := len(rs.reports) - 1; i >= 0; i-- {
+		r := rs.reports[i]
+		if r.Status == "running" && r.pushed {
+			r.add("envoy.ack", d)
+			r.Status = "acked"
+			if nacked {
+				r.Status = "nacked"
+			}
+			return
+		}
+	}
+}
+
+// The list method returns copies of the reports, newest first.
+func (rs *reconfigReports) list() []reconfigReport {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	ret := make([]reconfigReport, 0, len(rs.reports))
+	for i := len(rs.reports) - 1; i >= 0; i-- {
+		r := *rs.reports[i]
+		r.Phases = append([]reconfigPhase(nil), r.Phases...)
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+// parseServerTiming parses a Server-Timing header, like "diagd.ir;dur=1234.5, diagd.econf;dur=12",
+// whose durations are in milliseconds. Metrics without a duration are skipped.
+func parseServerTiming(header string) []reconfigPhase {
+	var phases []reconfigPhase
+	for _, metric := range strings.Split(header, ",") {
+		params := strings.Split(metric, ";")
+		name := strings.TrimSpace(params[0])
+		if name == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) != "dur" {
+				continue
+			}
+			ms, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil {
+				continue
+			}
+			phases = append(phases, reconfigPhase{Name: name, Seconds: ms / 1000})
+			break
+		}
+	}
+	return phases
+}
+
+// handleReconfigs serves the reconfiguration reports, newest first.
+func handleReconfigs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(controlPlaneReports.list()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
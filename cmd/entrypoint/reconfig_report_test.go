@@ -0,0 +1,85 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	assert.Equal(t, []reconfigPhase{
+		{Name: "diagd.ir", Seconds: 1.5},
+		{Name: "diagd.econf", Seconds: 0.012},
+	}, parseServerTiming(`diagd.ir;dur=1500, diagd.econf;desc="EConf";dur=12, cache;desc=hit, ;dur=3`))
+	assert.Nil(t, parseServerTiming(""))
+}
+
+func phaseNames(r reconfigReport) []string {
+	var names []string
+	for _, phase := range r.Phases {
+		names = append(names, phase.Name)
+	}
+	return names
+}
+
+func TestReconfigReports(t *testing.T) {
+	rs := newReconfigReports(3)
+	diagd := func(r *reconfigReport, status int) {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Server-Timing", "diagd.ir;dur=100, diagd.econf;dur=50")
+		rec.WriteHeader(status)
+		rs.observeDiagd(r, rec.Result(), 200*time.Millisecond)
+	}
+
+	// A reconfiguration that goes all the way to Envoy.
+	first := rs.start("kubernetes", time.Now(), []reconfigPhase{{Name: "watch", Seconds: 0.001}})
+	diagd(first, http.StatusOK)
+	rs.observeAmbexPush(10*time.Millisecond, true)
+	rs.observeEnvoyACK(20*time.Millisecond, false)
+
+	reports := rs.list()
+	require.Len(t, reports, 1)
+	assert.Equal(t, 1, reports[0].ID)
+	assert.Equal(t, "acked", reports[0].Status)
+	assert.Equal(t, []string{"watch", "diagd.ir", "diagd.econf", "diagd.other", "ambex.push", "envoy.ack"},
+		phaseNames(reports[0]))
+	assert.InDelta(t, 0.05, reports[0].Phases[3].Seconds, 1e-9)
+
+	// diagd fails, so there's no push for it; the next push is for the next reconfiguration,
+	// which ambex finds nothing to push for.
+	second := rs.start("consul", time.Now(), nil)
+	diagd(second, http.StatusInternalServerError)
+	third := rs.start("dns", time.Now(), nil)
+	diagd(third, http.StatusOK)
+	rs.observeAmbexPush(time.Millisecond, false)
+
+	// A push that Envoy hasn't answered yet is superseded by the next one.
+	fourth := rs.start("kubernetes", time.Now(), nil)
+	diagd(fourth, http.StatusOK)
+	fifth := rs.start("kubernetes", time.Now(), nil)
+	rs.observeAmbexPush(time.Millisecond, true)
+	diagd(fifth, http.StatusOK)
+	rs.observeAmbexPush(time.Millisecond, true)
+	rs.observeEnvoyACK(time.Millisecond, true)
+
+	var statuses []string
+	for _, r := range rs.list() {
+		statuses = append(statuses, r.Status)
+	}
+	assert.Equal(t, []string{"nacked", "superseded", "unchanged"}, statuses)
+	assert.Equal(t, "failed", second.Status)
+
+	rec := httptest.NewRecorder()
+	controlPlaneReports = rs
+	handleReconfigs(rec, httptest.NewRequest(http.MethodGet, "/reconfigs", nil))
+	var served []reconfigReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Len(t, served, 3)
+	assert.Equal(t, 5, served[0].ID)
+	assert.Equal(t, "kubernetes", served[0].Source)
+}
@@ -40,6 +40,9 @@ func (s *AmbassadorInputs) ReconcileSecrets() {
 	for _, i := range s.Ingresses {
 		resources = append(resources, i)
 	}
+	for _, i := range s.KNativeIngresses {
+		resources = append(resources, i)
+	}
 
 	secretNamespacing := true
 	for _, resource := range resources {
@@ -146,8 +149,35 @@ func traverseSecretRefs(resource kates.Object, secretNamespacing bool, action fu
 		if secs.Client.Secret != "" {
 			secretRef(r.GetNamespace(), secs.Client.Secret, secretNamespacing, action)
 		}
-	case *kates.Ingress:
-		for _, itls := range r.Spec.TLS {
+	case *kates.Unstructured:
+		if r.GetKind() != "Ingress" {
+			return
+		}
+		if r.GroupVersionKind().Group == knativeNetworkingGroup {
+			var kingress knativeIngressTLS
+			if err := convert(r, &kingress); err != nil {
+				log.Printf("error extracting secrets from knative ingress: %v", err)
+				return
+			}
+			for _, ktls := range kingress.Spec.TLS {
+				if ktls.SecretName == "" {
+					continue
+				}
+				namespace := ktls.SecretNamespace
+				if namespace == "" {
+					namespace = r.GetNamespace()
+				}
+				secretRef(namespace, ktls.SecretName, false, action)
+			}
+			return
+		}
+		// The TLS section of an Ingress is the same in every version.
+		var ingress kates.Ingress
+		if err := convert(r, &ingress); err != nil {
+			log.Printf("error extracting secrets from ingress: %v", err)
+			return
+		}
+		for _, itls := range ingress.Spec.TLS {
 			if itls.SecretName != "" {
 				secretRef(r.GetNamespace(), itls.SecretName, secretNamespacing, action)
 			}
@@ -169,6 +199,19 @@ func secretRef(namespace, name string, secretNamespacing bool, action func(Ref))
 	action(Ref{namespace, name})
 }
 
+const knativeNetworkingGroup = "networking.internal.knative.dev"
+
+// knativeIngressTLS is the TLS section of a Knative networking Ingress. Its secrets needn't be in the
+// namespace of the Ingress.
+type knativeIngressTLS struct {
+	Spec struct {
+		TLS []struct {
+			SecretName      string `json:"secretName"`
+			SecretNamespace string `json:"secretNamespace"`
+		} `json:"tls"`
+	} `json:"spec"`
+}
+
 type Ref struct {
 	Namespace string
 	Name      string
@@ -0,0 +1,78 @@
+package entrypoint
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/sharedstate"
+)
+
+// The replicas of an Ambassador can share state, so that work that only needs doing once for
+// the whole cluster is only done once. GetSharedState() says where it's kept:
+//
+//   - "redis:HOST:PORT" keeps it in the Redis server at HOST:PORT, and "rediss:HOST:PORT" does
+//     too, over TLS. GetSharedStateRedisPassword() is the server's password, if it has one.
+//   - "configmap:NAME" keeps it in the ConfigMap NAME in Ambassador's namespace.
+//
+// With shared state, the replicas pick a leader with a Lease, and only the leader writes the
+// external-dns records, and the Events and statuses of resources that policy denies. When the
+// leader shuts down, it hands the Lease over to another replica. Without shared state, every
+// replica leads, and they all write the same things.
+
+// newSharedState returns the Store that spec says to use, or nil if spec is empty.
+func newSharedState(spec string, client *kates.Client, namespace string) (sharedstate.Store, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("%q is not redis:HOST:PORT, rediss:HOST:PORT or configmap:NAME", spec)
+	}
+	switch parts[0] {
+	case "redis", "rediss":
+		options := sharedstate.RedisOptions{Password: GetSharedStateRedisPassword()}
+		if parts[0] == "rediss" {
+			options.TLS = &tls.Config{}
+		}
+		return sharedstate.NewRedisStore(parts[1], options), nil
+	case "configmap":
+		return sharedstate.NewConfigMapStore(client, parts[1], namespace), nil
+	default:
+		return nil, fmt.Errorf("%q is not redis:HOST:PORT, rediss:HOST:PORT or configmap:NAME", spec)
+	}
+}
+
+// leadership says whether this replica leads the others that share its state. A nil leadership
+// always leads.
+type leadership func() bool
+
+func (l leadership) leads() bool {
+	return l == nil || l()
+}
+
+// leaderKey is the key of the leader's Lease. Different Ambassadors can share a Store, so it
+// has the namespace and AMBASSADOR_ID in it.
+func leaderKey() string {
+	return "ambassador/" + GetAmbassadorNamespace() + "/" + GetAmbassadorId() + "/leader"
+}
+
+// newLeadership runs this replica's Lease on the leader key until ctx is done, and returns the
+// leadership that says whether it holds it. With no store, it returns nil, which always leads.
+func newLeadership(ctx context.Context, store sharedstate.Store) leadership {
+	if store == nil {
+		return nil
+	}
+
+	holder, err := os.Hostname()
+	if err != nil || holder == "" {
+		holder = fmt.Sprintf("ambassador-%d", os.Getpid())
+	}
+	lease := sharedstate.NewLease(store, leaderKey(), holder, GetSharedStateLeaseTTL())
+	go lease.Run(ctx)
+	return lease.Held
+}
@@ -0,0 +1,65 @@
+package entrypoint
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/sharedstate"
+)
+
+func TestNewSharedState(t *testing.T) {
+	store, err := newSharedState("", nil, "ambassador")
+	require.NoError(t, err)
+	assert.Nil(t, store)
+
+	store, err = newSharedState("redis:redis.ambassador:6379", nil, "ambassador")
+	require.NoError(t, err)
+	require.IsType(t, &sharedstate.RedisStore{}, store)
+	assert.Equal(t, "redis.ambassador:6379", store.(*sharedstate.RedisStore).Addr)
+	assert.Nil(t, store.(*sharedstate.RedisStore).Options.TLS)
+
+	os.Setenv("AMBASSADOR_SHARED_STATE_REDIS_PASSWORD", "hunter2")
+	defer os.Unsetenv("AMBASSADOR_SHARED_STATE_REDIS_PASSWORD")
+	store, err = newSharedState("rediss:redis.ambassador:6380", nil, "ambassador")
+	require.NoError(t, err)
+	require.IsType(t, &sharedstate.RedisStore{}, store)
+	assert.Equal(t, "redis.ambassador:6380", store.(*sharedstate.RedisStore).Addr)
+	assert.NotNil(t, store.(*sharedstate.RedisStore).Options.TLS)
+	assert.Equal(t, "hunter2", store.(*sharedstate.RedisStore).Options.Password)
+
+	store, err = newSharedState("configmap:ambassador-shared-state", nil, "ambassador")
+	require.NoError(t, err)
+	assert.IsType(t, &sharedstate.ConfigMapStore{}, store)
+
+	for _, spec := range []string{"redis", "redis:", "rediss:", "etcd:etcd:2379"} {
+		_, err = newSharedState(spec, nil, "ambassador")
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestLeadership(t *testing.T) {
+	var alone leadership
+	assert.True(t, alone.leads())
+	assert.Nil(t, newLeadership(context.Background(), nil))
+
+	os.Setenv("AMBASSADOR_SHARED_STATE_LEASE_TTL", "30ms")
+	defer os.Unsetenv("AMBASSADOR_SHARED_STATE_LEASE_TTL")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := sharedstate.NewMemoryStore()
+	leader := newLeadership(ctx, store)
+	require.Eventually(t, leader.leads, time.Second, time.Millisecond)
+
+	// Another Ambassador that shares the store has its own leader.
+	os.Setenv("AMBASSADOR_ID", "other")
+	defer os.Unsetenv("AMBASSADOR_ID")
+	other := newLeadership(ctx, store)
+	require.Eventually(t, other.leads, time.Second, time.Millisecond)
+	assert.True(t, leader.leads())
+}
@@ -20,6 +20,12 @@ type Snapshot struct {
 	// The Consul field contains endpoint data for any mappings setup to use a
 	// consul resolver.
 	Consul *watt.ConsulSnapshot
+	// The DNS field contains endpoint data for any mappings setup to use a DNS
+	// resolver.
+	DNS *DNSSnapshot
+	// The Federation field contains endpoint data from other clusters for any mappings setup to
+	// use a multi-cluster resolver.
+	Federation *FederationSnapshot
 	// The Deltas field contains a list of deltas to indicate what has changed
 	// since the prior snapshot. This is only computed for the Kubernetes
 	// portion of the snapshot. Changes in the Consul endpoint data are not
@@ -32,10 +38,14 @@ type Snapshot struct {
 
 type AmbassadorInputs struct {
 	// k8s resources
-	IngressClasses []*kates.IngressClass `json:"ingressclasses"`
-	Ingresses      []*kates.Ingress      `json:"ingresses"`
+	//
+	// Ingresses and IngressClasses are unstructured, since the cluster may serve them as
+	// networking.k8s.io/v1, whose backends our v1beta1 types can't represent.
+	IngressClasses []*kates.Unstructured `json:"ingressclasses"`
+	Ingresses      []*kates.Unstructured `json:"ingresses"`
 	Services       []*kates.Service      `json:"service"`
 	Endpoints      []*kates.Endpoints    `json:"Endpoints"`
+	EndpointSlices []*kates.Unstructured `json:"EndpointSlice,omitempty"`
 
 	// ambassador resources
 	Hosts       []*amb.Host       `json:"Host"`
@@ -44,16 +54,23 @@ type AmbassadorInputs struct {
 	Modules     []*amb.Module     `json:"Module"`
 	TLSContexts []*amb.TLSContext `json:"TLSContext"`
 
+	IngressClassParameters []*amb.IngressClassParameters `json:"IngressClassParameters"`
+	RouteDelegations       []*amb.RouteDelegation        `json:"RouteDelegation"`
+
 	// plugin services
 	AuthServices      []*amb.AuthService      `json:"AuthService"`
 	RateLimitServices []*amb.RateLimitService `json:"RateLimitService"`
 	LogServices       []*amb.LogService       `json:"LogService"`
 	TracingServices   []*amb.TracingService   `json:"TracingService"`
+	StatsSinks        []*amb.StatsSink        `json:"StatsSink"`
 
 	// resolvers
 	ConsulResolvers             []*amb.ConsulResolver             `json:"ConsulResolver"`
 	KubernetesEndpointResolvers []*amb.KubernetesEndpointResolver `json:"KubernetesEndpointResolver"`
 	KubernetesServiceResolvers  []*amb.KubernetesServiceResolver  `json:"KubernetesServiceResolver"`
+	DNSResolvers                []*amb.DNSResolver                `json:"DNSResolver"`
+	MultiClusterResolvers       []*amb.MultiClusterResolver       `json:"MultiClusterResolver"`
+	StaticResolvers             []*amb.StaticResolver             `json:"StaticResolver"`
 
 	// It is safe to ignore AmbassadorInstallation, ambassador doesn't need to look at those, just
 	// the operator.
@@ -61,9 +78,29 @@ type AmbassadorInputs struct {
 	KNativeClusterIngresses []*kates.Unstructured `json:"clusteringresses.networking.internal.knative.dev,omitempty"`
 	KNativeIngresses        []*kates.Unstructured `json:"ingresses.networking.internal.knative.dev,omitempty"`
 
+	// cert-manager Certificates, so that a Host can report how the certificate in its
+	// tlsSecret is doing.
+	Certificates []*kates.Unstructured `json:"certificates.cert-manager.io,omitempty"`
+
 	AllSecrets []*kates.Secret `json:"-"`
 	Secrets    []*kates.Secret `json:"secret"`
 
+	AllTapPolicies []*amb.TapPolicy `json:"-"`
+	TapPolicies    []*amb.TapPolicy `json:"TapPolicy"`
+
+	AllWasmFilters []*amb.WasmFilter `json:"-"`
+	WasmFilters    []*amb.WasmFilter `json:"WasmFilter"`
+
+	AllLuaConfigMaps []*kates.ConfigMap `json:"-"`
+	LuaConfigMaps    []*kates.ConfigMap `json:"ConfigMap"`
+
+	// AllPolicyConfigMaps hold the policies that ReconcilePolicy loads into OPA.
+	AllPolicyConfigMaps []*kates.ConfigMap `json:"-"`
+
+	// Namespaces are the ones that say which tenants may have resources in them.
+	AllNamespaces []*kates.Namespace `json:"-"`
+	Namespaces    []*kates.Namespace `json:"Namespace,omitempty"`
+
 	annotations []kates.Object `json:"-"`
 }
 
@@ -78,6 +115,24 @@ func (a *AmbassadorInputs) Render() string {
 	return result.String()
 }
 
+// Counts returns how many of each kind of input there are, by field name, leaving out the
+// empty ones.
+func (a *AmbassadorInputs) Counts() map[string]int {
+	result := map[string]int{}
+	v := reflect.Indirect(reflect.ValueOf(a))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Type.Kind() != reflect.Slice {
+			continue
+		}
+		if n := v.Field(i).Len(); n > 0 {
+			result[f.Name] = n
+		}
+	}
+	return result
+}
+
 // GetAmbId extracts the AmbassadorId from the kubernetes resource.
 func GetAmbId(resource kates.Object) amb.AmbassadorID {
 	switch r := resource.(type) {
@@ -108,12 +163,24 @@ func GetAmbId(resource kates.Object) amb.AmbassadorID {
 		return r.Spec.AmbassadorID
 	case *amb.TracingService:
 		return r.Spec.AmbassadorID
+	case *amb.StatsSink:
+		return r.Spec.AmbassadorID
+	case *amb.TapPolicy:
+		return r.Spec.AmbassadorID
+	case *amb.WasmFilter:
+		return r.Spec.AmbassadorID
 	case *amb.ConsulResolver:
 		return r.Spec.AmbassadorID
 	case *amb.KubernetesEndpointResolver:
 		return r.Spec.AmbassadorID
 	case *amb.KubernetesServiceResolver:
 		return r.Spec.AmbassadorID
+	case *amb.DNSResolver:
+		return r.Spec.AmbassadorID
+	case *amb.MultiClusterResolver:
+		return r.Spec.AmbassadorID
+	case *amb.StaticResolver:
+		return r.Spec.AmbassadorID
 	}
 
 	ann := resource.GetAnnotations()
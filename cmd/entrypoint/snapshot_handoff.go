@@ -0,0 +1,61 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// A snapshotHandoff is how the watcher hands each snapshot to the rest of the entrypoint. It
+// keeps the snapshot as the watcher built it, so that the goroutines in this process that need
+// it (the rate limit descriptors, the diagnostics API) use it as it is, and the watcher never
+// encodes it. The JSON is only for diagd and the edge stack sidecar, which are other processes:
+// it's encoded the first time one of them asks /snapshot for it, and kept, so that every request
+// for the same snapshot gets the same bytes.
+//
+// Ambex doesn't get a snapshot from here: what it serves is the Envoy configuration that diagd
+// makes from the snapshot, which comes to it in diagd's files.
+type snapshotHandoff struct {
+	mu      sync.Mutex
+	current *handedOffSnapshot
+}
+
+// A handedOffSnapshot is a snapshot and, once something has asked for it, its JSON.
+type handedOffSnapshot struct {
+	snapshot *Snapshot
+	once     sync.Once
+	encoded  []byte
+	err      error
+}
+
+// The store method hands over a snapshot. Whoever has it mustn't change it.
+func (h *snapshotHandoff) store(sn *Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current = &handedOffSnapshot{snapshot: sn}
+}
+
+// The load method returns the latest snapshot, or nil if there hasn't been one yet. It mustn't be
+// changed.
+func (h *snapshotHandoff) load() *Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.current == nil {
+		return nil
+	}
+	return h.current.snapshot
+}
+
+// The encoded method returns the latest snapshot's JSON, encoding it if nothing has asked for it
+// yet, or nil if there hasn't been a snapshot yet. It mustn't be changed.
+func (h *snapshotHandoff) encoded() ([]byte, error) {
+	h.mu.Lock()
+	current := h.current
+	h.mu.Unlock()
+	if current == nil {
+		return nil, nil
+	}
+	current.once.Do(func() {
+		current.encoded, current.err = json.MarshalIndent(current.snapshot, "", "  ")
+	})
+	return current.encoded, current.err
+}
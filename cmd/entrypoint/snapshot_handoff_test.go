@@ -0,0 +1,42 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+func TestSnapshotHandoff(t *testing.T) {
+	handoff := &snapshotHandoff{}
+	assert.Nil(t, handoff.load())
+	encoded, err := handoff.encoded()
+	require.NoError(t, err)
+	assert.Nil(t, encoded)
+
+	sn := &Snapshot{Kubernetes: &AmbassadorInputs{Mappings: []*amb.Mapping{{}}}}
+	handoff.store(sn)
+	assert.Same(t, sn, handoff.load())
+
+	encoded, err = handoff.encoded()
+	require.NoError(t, err)
+	want, err := json.MarshalIndent(sn, "", "  ")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(encoded))
+
+	// The same snapshot isn't encoded again, even if it were changed, which it mustn't be.
+	sn.Kubernetes.Mappings = nil
+	again, err := handoff.encoded()
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(again))
+
+	next := &Snapshot{Kubernetes: &AmbassadorInputs{}}
+	handoff.store(next)
+	assert.Same(t, next, handoff.load())
+	encoded, err = handoff.encoded()
+	require.NoError(t, err)
+	assert.NotEqual(t, string(want), string(encoded))
+}
@@ -4,15 +4,11 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"sync/atomic"
 	"time"
 )
 
-func snapshotServer(ctx context.Context, snapshot *atomic.Value) {
-	http.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
-		w.Write(snapshot.Load().([]byte))
-	})
-	s := &http.Server{Addr: "localhost:9696"}
+func snapshotServer(ctx context.Context, snapshot *snapshotHandoff) {
+	s := &http.Server{Addr: "localhost:9696", Handler: snapshotHandler(snapshot)}
 	go func() {
 		log.Println(s.ListenAndServe())
 	}()
@@ -23,3 +19,27 @@ func snapshotServer(ctx context.Context, snapshot *atomic.Value) {
 		panic(err)
 	}
 }
+
+// snapshotHandler serves the snapshot server's endpoints. It has a mux of its own, rather than
+// http.DefaultServeMux, which net/http/pprof registers the profiles on: those belong to the debug
+// server, behind its token.
+func snapshotHandler(snapshot *snapshotHandoff) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		encoded, err := snapshot.encoded()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(encoded)
+	})
+	mux.HandleFunc("/gateway-api/features", handleGatewayFeatures)
+	mux.HandleFunc("/ratelimit/descriptors", handleRateLimitDescriptors(snapshot))
+	mux.HandleFunc("/metrics", handleMetrics)
+	mux.HandleFunc("/resolvers", handleResolvers)
+	mux.HandleFunc("/readiness", handleReadiness)
+	mux.HandleFunc("/reconfigs", handleReconfigs)
+	mux.HandleFunc("/api/v2/diag", handleDiagAPI(snapshot))
+	mux.HandleFunc("/api/v2/diag/", handleDiagAPI(snapshot))
+	return mux
+}
@@ -0,0 +1,39 @@
+package entrypoint
+
+import (
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+// The ReconcileTapPolicies method sets TapPolicies to the TapPolicies that haven't run out their
+// duration by now, or been disabled for going over a quota, since Envoy's tap filter can't stop
+// tapping by itself. It returns when the next of those will expire, or the zero time if none of
+// them has a duration.
+func (s *AmbassadorInputs) ReconcileTapPolicies(now time.Time) time.Time {
+	var next time.Time
+
+	s.TapPolicies = make([]*amb.TapPolicy, 0, len(s.AllTapPolicies))
+	for _, tap := range s.AllTapPolicies {
+		if tapUsage.disabled(tap) {
+			continue
+		}
+
+		if tap.Spec.Duration == nil {
+			s.TapPolicies = append(s.TapPolicies, tap)
+			continue
+		}
+
+		expiry := tap.GetCreationTimestamp().Add(tap.Spec.Duration.Duration)
+		if !expiry.After(now) {
+			continue
+		}
+
+		s.TapPolicies = append(s.TapPolicies, tap)
+		if next.IsZero() || expiry.Before(next) {
+			next = expiry
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,49 @@
+package entrypoint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func tapPolicy(name string, created time.Time, duration time.Duration) *amb.TapPolicy {
+	tap := &amb.TapPolicy{
+		ObjectMeta: kates.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(created)},
+	}
+	if duration != 0 {
+		tap.Spec.Duration = &metav1.Duration{Duration: duration}
+	}
+	return tap
+}
+
+func TestReconcileTapPolicies(t *testing.T) {
+	now := time.Now()
+
+	inputs := &AmbassadorInputs{
+		AllTapPolicies: []*amb.TapPolicy{
+			tapPolicy("forever", now.Add(-time.Hour), 0),
+			tapPolicy("expired", now.Add(-time.Hour), 10*time.Minute),
+			tapPolicy("soon", now.Add(-time.Minute), 5*time.Minute),
+			tapPolicy("later", now, time.Hour),
+		},
+	}
+
+	next := inputs.ReconcileTapPolicies(now)
+	assert.Equal(t, now.Add(4*time.Minute).Unix(), next.Unix())
+
+	var names []string
+	for _, tap := range inputs.TapPolicies {
+		names = append(names, tap.GetName())
+	}
+	assert.Equal(t, []string{"forever", "soon", "later"}, names)
+
+	// Once they've all expired, there's nothing left to wait for.
+	next = inputs.ReconcileTapPolicies(now.Add(2 * time.Hour))
+	assert.True(t, next.IsZero())
+	assert.Len(t, inputs.TapPolicies, 1)
+}
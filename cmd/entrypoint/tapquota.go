@@ -0,0 +1,246 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+// tapUsage keeps track of how much every TapPolicy has captured, so that taps can't fill the
+// disk. A TapPolicy that goes over its max_bytes, or that would take all the TapPolicies over
+// AMBASSADOR_TAP_MAX_BYTES, is disabled until it's deleted and re-created, just like one that's
+// run out its duration.
+//
+// What the tap collector stores and what the tap sampler writes are counted as they happen.
+// Envoy writes the files for a file sink itself, so those are counted by looking at the files
+// every 10 seconds, and a TapPolicy can go a little over before it's stopped.
+var tapUsage = newTapMeter()
+
+// tapPolicyUsage is what one TapPolicy has captured.
+type tapPolicyUsage struct {
+	uid      string
+	maxBytes int64
+	// files is the path prefix of the files Envoy writes for a file sink, if it has one
+	files string
+	sizes map[string]int64
+
+	requests uint64
+	bytes    int64
+	// disabled is why the TapPolicy was disabled, "policy" or "global", if it was
+	disabled string
+}
+
+type tapMeter struct {
+	mu       sync.Mutex
+	maxBytes int64
+	taps     map[string]*tapPolicyUsage // by tap ID
+	changed  chan struct{}
+}
+
+func newTapMeter() *tapMeter {
+	return &tapMeter{
+		taps:    map[string]*tapPolicyUsage{},
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// tapPathPrefix returns where a TapPolicy's files go, which is the same default that diagd uses.
+func tapPathPrefix(tap *amb.TapPolicy) string {
+	if tap.Spec.Output != nil && tap.Spec.Output.PathPrefix != "" {
+		return tap.Spec.Output.PathPrefix
+	}
+	return fmt.Sprintf("/tmp/ambassador-tap/%s.%s", tap.GetName(), tap.GetNamespace())
+}
+
+// update sets which TapPolicies there are. It should be given all of them, including the ones
+// that are no longer tapping, so that a TapPolicy stays disabled until it's deleted; a deleted
+// TapPolicy no longer counts against AMBASSADOR_TAP_MAX_BYTES.
+func (m *tapMeter) update(policies []*amb.TapPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taps := map[string]*tapPolicyUsage{}
+	for _, tap := range policies {
+		id := tap.GetName() + "." + tap.GetNamespace()
+		usage := m.taps[id]
+		if usage == nil || usage.uid != string(tap.GetUID()) {
+			usage = &tapPolicyUsage{uid: string(tap.GetUID()), sizes: map[string]int64{}}
+		}
+
+		usage.maxBytes = tap.Spec.MaxBytes
+		usage.files = ""
+		if tap.Spec.Sample == nil && (tap.Spec.Output == nil || tap.Spec.Output.Sink != "grpc") {
+			usage.files = tapPathPrefix(tap)
+		}
+		taps[id] = usage
+	}
+	m.taps = taps
+}
+
+// disabled returns whether a TapPolicy has been disabled for going over a quota.
+func (m *tapMeter) disabled(tap *amb.TapPolicy) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := m.taps[tap.GetName()+"."+tap.GetNamespace()]
+	return usage != nil && usage.uid == string(tap.GetUID()) && usage.disabled != ""
+}
+
+// add counts requests and bytes that are about to be stored for a tap, and returns whether to
+// go ahead and store them: it returns false, and counts nothing, if the tap is disabled or if
+// they'd take it over a quota.
+func (m *tapMeter) add(tapID string, requests int, bytes int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := m.taps[tapID]
+	if usage == nil {
+		return true
+	}
+	if usage.disabled != "" {
+		return false
+	}
+	if reason := m.over(usage, bytes); reason != "" {
+		m.disable(tapID, usage, reason)
+		return false
+	}
+
+	usage.requests += uint64(requests)
+	usage.bytes += bytes
+	return true
+}
+
+// over returns which quota, if any, the tap would be over with more bytes.
+func (m *tapMeter) over(usage *tapPolicyUsage, more int64) string {
+	if usage.maxBytes > 0 && usage.bytes+more > usage.maxBytes {
+		return "policy"
+	}
+	if m.maxBytes > 0 {
+		total := more
+		for _, other := range m.taps {
+			total += other.bytes
+		}
+		if total > m.maxBytes {
+			return "global"
+		}
+	}
+	return ""
+}
+
+func (m *tapMeter) disable(tapID string, usage *tapPolicyUsage, reason string) {
+	usage.disabled = reason
+	if reason == "policy" {
+		log.Printf("TapPolicy %s disabled: it has captured %d bytes, and its max_bytes is %d", tapID, usage.bytes, usage.maxBytes)
+	} else {
+		log.Printf("TapPolicy %s disabled: TapPolicies have captured too much for AMBASSADOR_TAP_MAX_BYTES, %d bytes", tapID, m.maxBytes)
+	}
+
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// scan counts what Envoy has written to the files for file sinks since the last scan.
+func (m *tapMeter) scan() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, usage := range m.taps {
+		if usage.files == "" || usage.disabled != "" {
+			continue
+		}
+
+		names, err := filepath.Glob(usage.files + "_*")
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			info, err := os.Stat(name)
+			if err != nil {
+				continue
+			}
+			size, seen := usage.sizes[name]
+			if !seen {
+				usage.requests++
+			}
+			if info.Size() > size {
+				usage.bytes += info.Size() - size
+			}
+			usage.sizes[name] = info.Size()
+		}
+
+		if reason := m.over(usage, 0); reason != "" {
+			m.disable(id, usage, reason)
+		}
+	}
+}
+
+// run reads AMBASSADOR_TAP_MAX_BYTES, and then scans the files for file sinks every 10
+// seconds until ctx is done.
+func (m *tapMeter) run(ctx context.Context) {
+	if max := GetTapMaxBytes(); max != "" {
+		maxBytes, err := strconv.ParseInt(max, 10, 64)
+		if err != nil || maxBytes < 0 {
+			log.Printf("ignoring bad AMBASSADOR_TAP_MAX_BYTES %q", max)
+		} else {
+			m.mu.Lock()
+			m.maxBytes = maxBytes
+			m.mu.Unlock()
+		}
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.scan()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// The write method writes the tap metrics in the Prometheus text format.
+func (m *tapMeter) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.taps))
+	for id := range m.taps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	fmt.Fprintln(w, "# HELP ambassador_tap_captured_requests_total Requests, or connections, captured by each TapPolicy.")
+	fmt.Fprintln(w, "# TYPE ambassador_tap_captured_requests_total counter")
+	for _, id := range ids {
+		fmt.Fprintf(w, "ambassador_tap_captured_requests_total{tap=%q} %d\n", id, m.taps[id].requests)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_tap_captured_bytes_total Bytes captured by each TapPolicy.")
+	fmt.Fprintln(w, "# TYPE ambassador_tap_captured_bytes_total counter")
+	for _, id := range ids {
+		fmt.Fprintf(w, "ambassador_tap_captured_bytes_total{tap=%q} %d\n", id, m.taps[id].bytes)
+	}
+
+	fmt.Fprintln(w, "# HELP ambassador_tap_disabled Whether each TapPolicy has been disabled for going over a quota.")
+	fmt.Fprintln(w, "# TYPE ambassador_tap_disabled gauge")
+	for _, id := range ids {
+		disabled := 0
+		if m.taps[id].disabled != "" {
+			disabled = 1
+		}
+		fmt.Fprintf(w, "ambassador_tap_disabled{tap=%q} %d\n", id, disabled)
+	}
+}
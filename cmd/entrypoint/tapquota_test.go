@@ -0,0 +1,112 @@
+package entrypoint
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+func grpcTapPolicy(name string, maxBytes int64) *amb.TapPolicy {
+	tap := tapPolicy(name, time.Now(), 0)
+	tap.UID = types.UID(name + "-uid")
+	tap.Spec.MaxBytes = maxBytes
+	tap.Spec.Output = &amb.TapOutput{Sink: "grpc"}
+	return tap
+}
+
+func TestTapQuotas(t *testing.T) {
+	m := newTapMeter()
+	m.maxBytes = 250
+
+	small := grpcTapPolicy("small", 100)
+	big := grpcTapPolicy("big", 0)
+	m.update([]*amb.TapPolicy{small, big})
+
+	assert.True(t, m.add("small.default", 1, 60))
+	assert.True(t, m.add("small.default", 1, 40))
+	assert.False(t, m.disabled(small))
+
+	// Going over max_bytes disables the TapPolicy, and tells the watcher.
+	assert.False(t, m.add("small.default", 1, 1))
+	assert.True(t, m.disabled(small))
+	assert.False(t, m.add("small.default", 1, 0))
+	select {
+	case <-m.changed:
+	default:
+		t.Fatal("no change")
+	}
+
+	// Going over AMBASSADOR_TAP_MAX_BYTES disables whichever TapPolicy does it.
+	assert.True(t, m.add("big.default", 1, 150))
+	assert.False(t, m.add("big.default", 1, 1))
+	assert.True(t, m.disabled(big))
+
+	// Taps that aren't TapPolicies aren't limited.
+	assert.True(t, m.add("other.default", 1, 1000))
+
+	var buf bytes.Buffer
+	m.write(&buf)
+	out := buf.String()
+	assert.Contains(t, out, "ambassador_tap_captured_requests_total{tap=\"small.default\"} 2\n")
+	assert.Contains(t, out, "ambassador_tap_captured_bytes_total{tap=\"small.default\"} 100\n")
+	assert.Contains(t, out, "ambassador_tap_captured_bytes_total{tap=\"big.default\"} 150\n")
+	assert.Contains(t, out, "ambassador_tap_disabled{tap=\"big.default\"} 1\n")
+
+	// Re-creating a TapPolicy starts it again, and deleting one frees up its share of
+	// AMBASSADOR_TAP_MAX_BYTES.
+	recreated := grpcTapPolicy("small", 100)
+	recreated.UID = "small-uid-2"
+	m.update([]*amb.TapPolicy{recreated})
+	assert.False(t, m.disabled(recreated))
+	assert.True(t, m.add("small.default", 1, 100))
+}
+
+func TestTapQuotaFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tapquota")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tap := tapPolicy("files", time.Now(), 0)
+	tap.Spec.MaxBytes = 100
+	tap.Spec.Output = &amb.TapOutput{PathPrefix: filepath.Join(dir, "files")}
+
+	m := newTapMeter()
+	m.update([]*amb.TapPolicy{tap})
+
+	write := func(name string, size int) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644))
+	}
+
+	write("files_1.json", 30)
+	write("files_2.json", 30)
+	write("other_1.json", 1000)
+	m.scan()
+	assert.False(t, m.disabled(tap))
+
+	// A file that grows only counts once.
+	write("files_2.json", 50)
+	m.scan()
+	assert.Equal(t, uint64(2), m.taps["files.default"].requests)
+	assert.Equal(t, int64(80), m.taps["files.default"].bytes)
+	assert.False(t, m.disabled(tap))
+
+	write("files_3.json", 30)
+	m.scan()
+	assert.True(t, m.disabled(tap))
+
+	inputs := &AmbassadorInputs{AllTapPolicies: []*amb.TapPolicy{tap}}
+	saved := tapUsage
+	defer func() { tapUsage = saved }()
+	tapUsage = m
+	inputs.ReconcileTapPolicies(time.Now())
+	assert.Empty(t, inputs.TapPolicies)
+}
@@ -0,0 +1,427 @@
+package entrypoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	admin "github.com/datawire/ambassador/pkg/api/envoy/admin/v2alpha"
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/tapserver"
+)
+
+const (
+	// AmbexAddress is where ambex serves ADS, and TapDS, to Envoy.
+	AmbexAddress = "127.0.0.1:8003"
+
+	envoyAdminURL   = "http://127.0.0.1:8001"
+	tapResourceType = "type.googleapis.com/envoy.service.tap.v2alpha.TapResource"
+)
+
+// tapSamples runs the taps for TapPolicies with a sample. The watcher tells it which
+// TapPolicies those are.
+var tapSamples = newTapSampler()
+
+// A tapSample is what a TapPolicy's sample asks for.
+type tapSample struct {
+	perMinute  int
+	rotate     time.Duration
+	maxFiles   int
+	pathPrefix string
+}
+
+// tapSampleFor returns the tapSample for a TapPolicy, and whether it has one.
+func tapSampleFor(tap *amb.TapPolicy) (tapSample, bool) {
+	spec := tap.Spec.Sample
+	if spec == nil || spec.RequestsPerMinute <= 0 {
+		return tapSample{}, false
+	}
+
+	sample := tapSample{
+		perMinute:  spec.RequestsPerMinute,
+		rotate:     time.Hour,
+		maxFiles:   24,
+		pathPrefix: tapPathPrefix(tap),
+	}
+	if spec.RotateEvery != nil && spec.RotateEvery.Duration > 0 {
+		sample.rotate = spec.RotateEvery.Duration
+	}
+	if spec.MaxFiles > 0 {
+		sample.maxFiles = spec.MaxFiles
+	}
+	return sample, true
+}
+
+// tapSampler keeps an admin tap open for every TapPolicy with a sample, and writes a sample of
+// what it taps to rotating files. diagd turns a sampled TapPolicy into a tap filter that's
+// driven through Envoy's admin interface, and the config to drive it with comes from TapDS.
+type tapSampler struct {
+	mu      sync.Mutex
+	samples map[string]tapSample         // by tap ID, from the watcher
+	configs map[string]*tapsvc.TapConfig // by tap ID, from TapDS
+	changed chan struct{}
+}
+
+func newTapSampler() *tapSampler {
+	return &tapSampler{
+		samples: map[string]tapSample{},
+		configs: map[string]*tapsvc.TapConfig{},
+		changed: make(chan struct{}, 1),
+	}
+}
+
+func (s *tapSampler) poke() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// update sets which TapPolicies are sampled.
+func (s *tapSampler) update(policies []*amb.TapPolicy) {
+	samples := map[string]tapSample{}
+	for _, tap := range policies {
+		if sample, ok := tapSampleFor(tap); ok {
+			samples[tap.GetName()+"."+tap.GetNamespace()] = sample
+		}
+	}
+
+	s.mu.Lock()
+	s.samples = samples
+	s.mu.Unlock()
+	s.poke()
+}
+
+func (s *tapSampler) setConfigs(configs map[string]*tapsvc.TapConfig) {
+	s.mu.Lock()
+	s.configs = configs
+	s.mu.Unlock()
+	s.poke()
+}
+
+// run starts and stops sampled taps as TapPolicies and their configs change, until ctx is
+// done. Sampled traces are redacted following AMBASSADOR_TAP_REDACTION, like the tap
+// collector's.
+func (s *tapSampler) run(ctx context.Context) {
+	redactor, err := tapserver.LoadRedactor(GetTapRedaction())
+	if err != nil {
+		log.Printf("tap sampler disabled: %v", err)
+		return
+	}
+
+	go s.watchTapDS(ctx, AmbexAddress)
+
+	type running struct {
+		sample tapSample
+		config *tapsvc.TapConfig
+		cancel context.CancelFunc
+	}
+	runs := map[string]*running{}
+
+	for {
+		s.mu.Lock()
+		samples, configs := s.samples, s.configs
+		s.mu.Unlock()
+
+		for id, run := range runs {
+			sample, ok := samples[id]
+			config := configs[id]
+			if !ok || config == nil || sample != run.sample || !proto.Equal(config, run.config) {
+				run.cancel()
+				delete(runs, id)
+			}
+		}
+
+		for id, sample := range samples {
+			config := configs[id]
+			if runs[id] != nil || config == nil {
+				continue
+			}
+			runCtx, cancel := context.WithCancel(ctx)
+			runs[id] = &running{sample: sample, config: config, cancel: cancel}
+			go sampleTap(runCtx, envoyAdminURL, id, sample, config, redactor)
+		}
+
+		select {
+		case <-s.changed:
+		case <-ctx.Done():
+			for _, run := range runs {
+				run.cancel()
+			}
+			return
+		}
+	}
+}
+
+// watchTapDS follows the tap configs that ambex serves over TapDS, reconnecting when the
+// stream breaks, until ctx is done.
+func (s *tapSampler) watchTapDS(ctx context.Context, address string) {
+	for ctx.Err() == nil {
+		if err := s.streamTapDS(ctx, address); err != nil && ctx.Err() == nil {
+			log.Printf("tap sampler: TapDS: %v", err)
+		}
+		select {
+		case <-time.After(10 * time.Second):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (s *tapSampler) streamTapDS(ctx context.Context, address string) error {
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stream, err := tapsvc.NewTapDiscoveryServiceClient(conn).StreamTapConfigs(ctx)
+	if err != nil {
+		return err
+	}
+
+	node := &core.Node{Id: "tap-sampler"}
+	req := &v2.DiscoveryRequest{Node: node, TypeUrl: tapResourceType}
+	for {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		configs := map[string]*tapsvc.TapConfig{}
+		for _, resource := range resp.GetResources() {
+			tap := &tapsvc.TapResource{}
+			if err := ptypes.UnmarshalAny(resource, tap); err != nil {
+				return err
+			}
+			configs[tap.GetName()] = tap.GetConfig()
+		}
+		s.setConfigs(configs)
+
+		// ACK it, which asks for the next version.
+		req = &v2.DiscoveryRequest{
+			Node:          node,
+			TypeUrl:       tapResourceType,
+			VersionInfo:   resp.GetVersionInfo(),
+			ResponseNonce: resp.GetNonce(),
+		}
+	}
+}
+
+// sampleTap holds an admin tap open on the tap filter for tapID, reopening it if it closes,
+// and writes a sample of sample.perMinute of its traces every minute, until ctx is done.
+func sampleTap(ctx context.Context, adminURL, tapID string, sample tapSample, config *tapsvc.TapConfig, redactor *tapserver.Redactor) {
+	request, err := protojson.Marshal(&admin.TapRequest{ConfigId: tapID, TapConfig: config})
+	if err != nil {
+		log.Printf("tap sampler: %s: %v", tapID, err)
+		return
+	}
+
+	traces := make(chan *tapdata.TraceWrapper)
+	go func() {
+		for ctx.Err() == nil {
+			err := streamAdminTap(ctx, adminURL+"/tap", request, func(trace *tapdata.TraceWrapper) {
+				select {
+				case traces <- trace:
+				case <-ctx.Done():
+				}
+			})
+			if err != nil && ctx.Err() == nil {
+				log.Printf("tap sampler: %s: %v", tapID, err)
+			}
+			select {
+			case <-time.After(10 * time.Second):
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	files := &rotatingFiles{prefix: sample.pathPrefix, every: sample.rotate, keep: sample.maxFiles, now: time.Now}
+	defer files.close()
+
+	kept := &reservoir{size: sample.perMinute, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	log.Printf("tap sampler: sampling %d requests a minute from %s to %s-*.jsonl", sample.perMinute, tapID, sample.pathPrefix)
+	for {
+		select {
+		case trace := <-traces:
+			kept.add(trace)
+		case <-ticker.C:
+			var lines [][]byte
+			var size int64
+			for _, trace := range kept.take() {
+				redactor.Redact(trace)
+				line, err := protojson.Marshal(trace)
+				if err != nil {
+					log.Printf("tap sampler: %s: %v", tapID, err)
+					continue
+				}
+				lines = append(lines, line)
+				size += int64(len(line)) + 1
+			}
+			if !tapUsage.add(tapID, len(lines), size) {
+				continue
+			}
+			if err := files.write(lines); err != nil {
+				log.Printf("tap sampler: %s: %v", tapID, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// streamAdminTap POSTs a tap request to Envoy's admin /tap endpoint, and hands every trace
+// that Envoy streams back to handle, until the stream ends or ctx is done.
+func streamAdminTap(ctx context.Context, url string, request []byte, handle func(*tapdata.TraceWrapper)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, bytes.TrimSpace(body))
+	}
+
+	// Envoy sends each trace as a JSON object, with nothing between them.
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		trace := &tapdata.TraceWrapper{}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, trace); err != nil {
+			return err
+		}
+		handle(trace)
+	}
+}
+
+// A reservoir keeps a uniform random sample of up to size of the traces it's given, however
+// many there are (Vitter's Algorithm R).
+type reservoir struct {
+	size   int
+	seen   int
+	traces []*tapdata.TraceWrapper
+	rand   *rand.Rand
+}
+
+func (r *reservoir) add(trace *tapdata.TraceWrapper) {
+	r.seen++
+	if len(r.traces) < r.size {
+		r.traces = append(r.traces, trace)
+	} else if i := r.rand.Intn(r.seen); i < r.size {
+		r.traces[i] = trace
+	}
+}
+
+// take returns the sample, and starts a new one.
+func (r *reservoir) take() []*tapdata.TraceWrapper {
+	traces := r.traces
+	r.traces = nil
+	r.seen = 0
+	return traces
+}
+
+// rotatingFiles writes lines to <prefix>-<time>.jsonl, starting a new file every so often and
+// removing the oldest files beyond keep.
+type rotatingFiles struct {
+	prefix string
+	every  time.Duration
+	keep   int
+	now    func() time.Time
+
+	file   *os.File
+	opened time.Time
+}
+
+func (r *rotatingFiles) write(lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if now := r.now(); r.file == nil || now.Sub(r.opened) >= r.every {
+		if err := r.rotate(now); err != nil {
+			return err
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := r.file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *rotatingFiles) rotate(now time.Time) error {
+	r.close()
+
+	if err := os.MkdirAll(filepath.Dir(r.prefix), 0755); err != nil {
+		return err
+	}
+	name := r.prefix + "-" + now.UTC().Format("20060102T150405Z") + ".jsonl"
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.opened = now
+
+	// The times in the names sort, so the oldest files come first.
+	names, err := filepath.Glob(r.prefix + "-*.jsonl")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for len(names) > r.keep {
+		if err := os.Remove(names[0]); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+func (r *rotatingFiles) close() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
@@ -0,0 +1,117 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+func TestTapSampleFor(t *testing.T) {
+	tap := tapPolicy("qotm", time.Now(), 0)
+	_, ok := tapSampleFor(tap)
+	assert.False(t, ok)
+
+	tap.Spec.Sample = &amb.TapSample{RequestsPerMinute: 10}
+	sample, ok := tapSampleFor(tap)
+	require.True(t, ok)
+	assert.Equal(t, tapSample{
+		perMinute:  10,
+		rotate:     time.Hour,
+		maxFiles:   24,
+		pathPrefix: "/tmp/ambassador-tap/qotm.default",
+	}, sample)
+
+	tap.Spec.Sample.RotateEvery = &metav1.Duration{Duration: 10 * time.Minute}
+	tap.Spec.Sample.MaxFiles = 6
+	tap.Spec.Output = &amb.TapOutput{PathPrefix: "/corpus/qotm"}
+	sample, ok = tapSampleFor(tap)
+	require.True(t, ok)
+	assert.Equal(t, tapSample{
+		perMinute:  10,
+		rotate:     10 * time.Minute,
+		maxFiles:   6,
+		pathPrefix: "/corpus/qotm",
+	}, sample)
+}
+
+func TestReservoir(t *testing.T) {
+	kept := &reservoir{size: 3, rand: rand.New(rand.NewSource(1))}
+	for i := 0; i < 100; i++ {
+		kept.add(&tapdata.TraceWrapper{})
+	}
+	assert.Len(t, kept.take(), 3)
+	assert.Len(t, kept.take(), 0)
+
+	kept.add(&tapdata.TraceWrapper{})
+	assert.Len(t, kept.take(), 1)
+}
+
+func TestRotatingFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tapsample")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+	files := &rotatingFiles{
+		prefix: filepath.Join(dir, "sub", "qotm.default"),
+		every:  time.Hour,
+		keep:   2,
+		now:    func() time.Time { return now },
+	}
+	defer files.close()
+
+	for i := 0; i < 8; i++ {
+		require.NoError(t, files.write([][]byte{[]byte(fmt.Sprintf(`{"n":%d}`, i))}))
+		now = now.Add(30 * time.Minute)
+	}
+	require.NoError(t, files.write(nil))
+
+	names, err := filepath.Glob(filepath.Join(dir, "sub", "*"))
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+	assert.Equal(t, "qotm.default-20200901T140000Z.jsonl", filepath.Base(names[0]))
+	assert.Equal(t, "qotm.default-20200901T150000Z.jsonl", filepath.Base(names[1]))
+
+	contents, err := ioutil.ReadFile(names[1])
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":6}\n{\"n\":7}\n", string(contents))
+}
+
+func TestStreamAdminTap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"configId":"qotm.default"`) {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"http_buffered_trace":{"request":{}}}{"http_buffered_trace":{"response":{}}}`)
+	}))
+	defer server.Close()
+
+	var traces []*tapdata.TraceWrapper
+	handle := func(trace *tapdata.TraceWrapper) { traces = append(traces, trace) }
+
+	err := streamAdminTap(context.Background(), server.URL, []byte(`{"configId":"qotm.default"}`), handle)
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.NotNil(t, traces[0].GetHttpBufferedTrace().GetRequest())
+	assert.NotNil(t, traces[1].GetHttpBufferedTrace().GetResponse())
+
+	err = streamAdminTap(context.Background(), server.URL, []byte(`{}`), handle)
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package entrypoint
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/datawire/ambassador/pkg/tapserver"
+)
+
+// TapServerAddress is where the tap collector listens for the traces that Envoy streams from
+// TapPolicies with a grpc output sink.
+const TapServerAddress = "127.0.0.1:8006"
+
+// runTapServer runs the tap collector, which saves streamed traces to the backend named by
+// AMBASSADOR_TAP_STORAGE, until ctx is done. Traces are redacted first, following the rules in
+// AMBASSADOR_TAP_REDACTION.
+func runTapServer(ctx context.Context, storage string) {
+	backend, err := tapserver.NewBackend(storage)
+	if err != nil {
+		log.Printf("tap collector disabled: %v", err)
+		return
+	}
+
+	redactor, err := tapserver.LoadRedactor(GetTapRedaction())
+	if err != nil {
+		log.Printf("tap collector disabled: %v", err)
+		return
+	}
+
+	listener, err := net.Listen("tcp", TapServerAddress)
+	if err != nil {
+		log.Printf("tap collector disabled: %v", err)
+		return
+	}
+
+	server := grpc.NewServer()
+	tapServer := tapserver.NewServer(meteredBackend{backend})
+	tapServer.Redactor = redactor
+	tapServer.Register(server)
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	log.Printf("tap collector listening on %s, storing to %s", TapServerAddress, storage)
+	if err := server.Serve(listener); err != nil {
+		log.Printf("tap collector: %v", err)
+	}
+}
+
+// meteredBackend counts what the tap collector stores against each TapPolicy's quota, and drops
+// the traces of TapPolicies that are over it.
+type meteredBackend struct {
+	tapserver.Backend
+}
+
+func (b meteredBackend) Store(ctx context.Context, tapID string, traceID uint64, trace []byte) error {
+	if !tapUsage.add(tapID, 1, int64(len(trace))) {
+		return nil
+	}
+	return b.Backend.Store(ctx, tapID, traceID, trace)
+}
@@ -0,0 +1,586 @@
+package entrypoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/sigv4"
+)
+
+// Ambassador can register its own pods in AWS target groups, for an NLB or an ALB whose target
+// type is ip, so that the load balancer sends traffic straight to Ambassador instead of through a
+// node port and kube-proxy. GetAWSTargetGroups() binds each target group to a port of a Service
+// in Ambassador's namespace, the way the AWS Load Balancer Controller's TargetGroupBinding does:
+//
+//   [{"targetGroupARN": "arn:aws:elasticloadbalancing:...", "serviceRef": {"name": "ambassador", "port": 443}}]
+//
+// The targets are the ready endpoints of that Service, on the port that the Service's port sends
+// to. An endpoint that stops being ready, or starts terminating during a rollout, is deregistered
+// right away, so that the load balancer has drained it by the time its pod exits. If
+// GetAWSDeregistrationDelay() is set, it's the target groups' deregistration delay.
+
+const (
+	elbAPIVersion = "2015-12-01"
+
+	targetGroupRetry = 30 * time.Second
+)
+
+// targetGroupBinding binds an AWS target group to a port of a Service.
+type targetGroupBinding struct {
+	TargetGroupARN string `json:"targetGroupARN"`
+	ServiceRef     struct {
+		Name string            `json:"name"`
+		Port kates.IntOrString `json:"port"`
+	} `json:"serviceRef"`
+}
+
+// parseTargetGroupBindings parses GetAWSTargetGroups().
+func parseTargetGroupBindings(config string) ([]targetGroupBinding, error) {
+	var bindings []targetGroupBinding
+	if err := json.Unmarshal([]byte(config), &bindings); err != nil {
+		return nil, err
+	}
+	for _, b := range bindings {
+		if _, err := targetGroupRegion(b.TargetGroupARN); err != nil {
+			return nil, err
+		}
+		if b.ServiceRef.Name == "" {
+			return nil, fmt.Errorf("target group %s has no serviceRef.name", b.TargetGroupARN)
+		}
+	}
+	return bindings, nil
+}
+
+// targetGroupRegion returns the region of a target group from its ARN,
+// arn:partition:elasticloadbalancing:region:account:targetgroup/name/id.
+func targetGroupRegion(arn string) (string, error) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "elasticloadbalancing" || parts[3] == "" ||
+		!strings.HasPrefix(parts[5], "targetgroup/") {
+		return "", fmt.Errorf("%q is not the ARN of a target group", arn)
+	}
+	return parts[3], nil
+}
+
+// awsTarget is a target in a target group: a pod's IP address, and the port to send to there.
+type awsTarget struct {
+	ID   string
+	Port int32
+}
+
+// ReconcileTargetGroups hands the ready endpoints of the bound Services to the target groups.
+// They're registered in the background, so a slow AWS API never delays reconfiguration.
+func (s *AmbassadorInputs) ReconcileTargetGroups(tg *targetGroups) {
+	if tg == nil {
+		return
+	}
+
+	namespace := GetAmbassadorNamespace()
+	targets := make(map[string][]awsTarget)
+	waiting := make(map[string]string)
+	for _, b := range tg.bindings {
+		found, reason := s.bindingTargets(b, namespace)
+		if reason != "" {
+			waiting[b.TargetGroupARN] = reason
+			continue
+		}
+		targets[b.TargetGroupARN] = found
+	}
+	tg.update(targets, waiting)
+}
+
+// bindingTargets returns the targets for a binding or, if there are none to register yet, why
+// not. Registering nothing would deregister every pod there is, so, until there are ready
+// endpoints, what's registered is left alone.
+func (s *AmbassadorInputs) bindingTargets(b targetGroupBinding, namespace string) ([]awsTarget, string) {
+	name := b.ServiceRef.Name + "." + namespace
+	svc := ambassadorService(s.Services, b.ServiceRef.Name, namespace)
+	if svc == nil {
+		return nil, "Service " + name + " wasn't found"
+	}
+
+	portName, ok := "", false
+	for _, port := range svc.Spec.Ports {
+		match := port.Name == b.ServiceRef.Port.StrVal
+		if b.ServiceRef.Port.Type == kates.Int {
+			match = port.Port == b.ServiceRef.Port.IntVal
+		}
+		if match {
+			portName, ok = port.Name, true
+			break
+		}
+	}
+	if !ok {
+		return nil, fmt.Sprintf("Service %s has no port %s", name, b.ServiceRef.Port.String())
+	}
+
+	var targets []awsTarget
+	if len(s.EndpointSlices) > 0 {
+		targets = endpointSliceTargets(s.EndpointSlices, svc.GetName(), namespace, portName)
+	} else {
+		targets = endpointsTargets(s.Endpoints, svc.GetName(), namespace, portName)
+	}
+	if len(targets) == 0 {
+		return nil, "Service " + name + " has no ready endpoints"
+	}
+	return targets, ""
+}
+
+// endpointsTargets returns the ready addresses of a Service's Endpoints, with the port named
+// portName, sorted.
+func endpointsTargets(endpoints []*kates.Endpoints, service, namespace, portName string) []awsTarget {
+	seen := make(map[awsTarget]bool)
+	for _, ep := range endpoints {
+		if ep.GetName() != service || ep.GetNamespace() != namespace {
+			continue
+		}
+		for _, subset := range ep.Subsets {
+			for _, port := range subset.Ports {
+				if port.Name != portName {
+					continue
+				}
+				for _, addr := range subset.Addresses {
+					seen[awsTarget{ID: addr.IP, Port: port.Port}] = true
+				}
+			}
+		}
+	}
+	return sortedTargets(seen)
+}
+
+// endpointSlice is as much of an EndpointSlice as endpointSliceTargets needs, so that it reads
+// discovery.k8s.io/v1beta1 and v1 alike.
+type endpointSlice struct {
+	Metadata    kates.ObjectMeta `json:"metadata"`
+	AddressType string           `json:"addressType"`
+	Endpoints   []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready       *bool `json:"ready"`
+			Terminating *bool `json:"terminating"`
+		} `json:"conditions"`
+	} `json:"endpoints"`
+	Ports []struct {
+		Name *string `json:"name"`
+		Port *int32  `json:"port"`
+	} `json:"ports"`
+}
+
+// endpointSliceTargets returns the addresses of the ready endpoints of a Service's
+// EndpointSlices, with the port named portName, sorted. An endpoint that's terminating isn't
+// ready, even where the cluster still says it is.
+func endpointSliceTargets(slices []*kates.Unstructured, service, namespace, portName string) []awsTarget {
+	seen := make(map[awsTarget]bool)
+	for _, un := range slices {
+		var slice endpointSlice
+		if err := convert(un, &slice); err != nil {
+			continue
+		}
+		if slice.Metadata.Namespace != namespace || slice.Metadata.Labels["kubernetes.io/service-name"] != service {
+			continue
+		}
+		if slice.AddressType != "IPv4" && slice.AddressType != "IPv6" {
+			continue
+		}
+		for _, port := range slice.Ports {
+			name := ""
+			if port.Name != nil {
+				name = *port.Name
+			}
+			if name != portName || port.Port == nil {
+				continue
+			}
+			for _, ep := range slice.Endpoints {
+				if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+					continue
+				}
+				if ep.Conditions.Terminating != nil && *ep.Conditions.Terminating {
+					continue
+				}
+				for _, addr := range ep.Addresses {
+					seen[awsTarget{ID: addr, Port: *port.Port}] = true
+				}
+			}
+		}
+	}
+	return sortedTargets(seen)
+}
+
+func sortedTargets(set map[awsTarget]bool) []awsTarget {
+	var targets []awsTarget
+	for target := range set {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].ID != targets[j].ID {
+			return targets[i].ID < targets[j].ID
+		}
+		return targets[i].Port < targets[j].Port
+	})
+	return targets
+}
+
+// planTargets compares the targets that are registered with the ones that should be, and
+// returns the ones to register and the ones to deregister.
+func planTargets(registered, wanted []awsTarget) (register, deregister []awsTarget) {
+	have := make(map[awsTarget]bool)
+	for _, target := range registered {
+		have[target] = true
+	}
+	for _, target := range wanted {
+		if have[target] {
+			delete(have, target)
+			continue
+		}
+		register = append(register, target)
+	}
+	for _, target := range registered {
+		if have[target] {
+			deregister = append(deregister, target)
+		}
+	}
+	return register, deregister
+}
+
+// targetGroups registers the targets that ReconcileTargetGroups hands it, whenever they change.
+type targetGroups struct {
+	bindings []targetGroupBinding
+	elb      *elbClient
+	delay    time.Duration
+
+	// dirty has room for one signal, so that update never blocks, and any number of updates
+	// between two syncs make one sync.
+	dirty chan struct{}
+
+	// The mutex protects access to targets and waiting.
+	mutex   sync.Mutex
+	targets map[string][]awsTarget
+	waiting map[string]string
+}
+
+func newTargetGroups(ctx context.Context, bindings []targetGroupBinding, elb *elbClient, delay time.Duration) *targetGroups {
+	result := &targetGroups{
+		bindings: bindings,
+		elb:      elb,
+		delay:    delay,
+		dirty:    make(chan struct{}, 1),
+		targets:  make(map[string][]awsTarget),
+		waiting:  make(map[string]string),
+	}
+	go result.run(ctx)
+	return result
+}
+
+// update sets the targets of each target group. A target group with no targets is left alone,
+// for the reason in waiting, which is logged once.
+func (tg *targetGroups) update(targets map[string][]awsTarget, waiting map[string]string) {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+	for arn, reason := range waiting {
+		if reason != tg.waiting[arn] {
+			log.Printf("target groups: not updating %s: %s", arn, reason)
+		}
+	}
+	tg.waiting = waiting
+	changed := false
+	for arn, wanted := range targets {
+		if !reflect.DeepEqual(wanted, tg.targets[arn]) {
+			tg.targets[arn] = wanted
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	select {
+	case tg.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (tg *targetGroups) run(ctx context.Context) {
+	synced := make(map[string][]awsTarget)
+	delaySet := make(map[string]bool)
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-tg.dirty:
+		case <-retry:
+		case <-ctx.Done():
+			return
+		}
+		retry = nil
+
+		tg.mutex.Lock()
+		targets := make(map[string][]awsTarget, len(tg.targets))
+		for arn, wanted := range tg.targets {
+			targets[arn] = wanted
+		}
+		tg.mutex.Unlock()
+
+		for arn, wanted := range targets {
+			if tg.delay > 0 && !delaySet[arn] {
+				if err := tg.elb.setDeregistrationDelay(ctx, arn, tg.delay); err != nil {
+					log.Printf("target groups: %s: %v (retrying in %v)", arn, err, targetGroupRetry)
+					retry = time.After(targetGroupRetry)
+				} else {
+					delaySet[arn] = true
+				}
+			}
+			if reflect.DeepEqual(wanted, synced[arn]) {
+				continue
+			}
+			if err := tg.sync(ctx, arn, wanted); err != nil {
+				log.Printf("target groups: %s: %v (retrying in %v)", arn, err, targetGroupRetry)
+				retry = time.After(targetGroupRetry)
+				continue
+			}
+			synced[arn] = wanted
+		}
+	}
+}
+
+// sync registers the wanted targets in a target group, and deregisters the others. Targets that
+// are draining have been deregistered already.
+func (tg *targetGroups) sync(ctx context.Context, arn string, wanted []awsTarget) error {
+	registered, err := tg.elb.describeTargets(ctx, arn)
+	if err != nil {
+		return err
+	}
+	register, deregister := planTargets(registered, wanted)
+	if len(register) > 0 {
+		if err := tg.elb.call(ctx, arn, "RegisterTargets", targetParams(arn, register), nil); err != nil {
+			return err
+		}
+	}
+	if len(deregister) > 0 {
+		if err := tg.elb.call(ctx, arn, "DeregisterTargets", targetParams(arn, deregister), nil); err != nil {
+			return err
+		}
+	}
+	if len(register)+len(deregister) > 0 {
+		log.Printf("target groups: %s: %d targets registered, %d deregistered", arn, len(register), len(deregister))
+	}
+	return nil
+}
+
+func targetParams(arn string, targets []awsTarget) url.Values {
+	params := url.Values{"TargetGroupArn": {arn}}
+	for i, target := range targets {
+		params.Set(fmt.Sprintf("Targets.member.%d.Id", i+1), target.ID)
+		params.Set(fmt.Sprintf("Targets.member.%d.Port", i+1), strconv.Itoa(int(target.Port)))
+	}
+	return params
+}
+
+// elbClient calls the Elastic Load Balancing (v2) API, which is a query API: each call is a
+// form POSTed to the region's endpoint, answered with XML.
+type elbClient struct {
+	client      *http.Client
+	credentials func(ctx context.Context, region string) (sigv4.Credentials, error)
+	// endpoint returns the URL of the API in a region.
+	endpoint func(region string) string
+	now      func() time.Time
+}
+
+func newELBClient() *elbClient {
+	client := &http.Client{Timeout: 30 * time.Second}
+	return &elbClient{
+		client:      client,
+		credentials: awsCredentials(client),
+		endpoint: func(region string) string {
+			return "https://elasticloadbalancing." + region + "." + awsDomain(region)
+		},
+		now: time.Now,
+	}
+}
+
+// awsDomain returns the domain of the endpoints in a region.
+func awsDomain(region string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return "amazonaws.com.cn"
+	}
+	return "amazonaws.com"
+}
+
+type elbTargetHealth struct {
+	Targets []struct {
+		ID    string `xml:"Target>Id"`
+		Port  int32  `xml:"Target>Port"`
+		State string `xml:"TargetHealth>State"`
+	} `xml:"DescribeTargetHealthResult>TargetHealthDescriptions>member"`
+}
+
+// describeTargets returns the targets registered in a target group that aren't draining.
+func (c *elbClient) describeTargets(ctx context.Context, arn string) ([]awsTarget, error) {
+	var health elbTargetHealth
+	if err := c.call(ctx, arn, "DescribeTargetHealth", url.Values{"TargetGroupArn": {arn}}, &health); err != nil {
+		return nil, err
+	}
+	var targets []awsTarget
+	for _, t := range health.Targets {
+		if t.State == "draining" {
+			continue
+		}
+		targets = append(targets, awsTarget{ID: t.ID, Port: t.Port})
+	}
+	return targets, nil
+}
+
+func (c *elbClient) setDeregistrationDelay(ctx context.Context, arn string, delay time.Duration) error {
+	return c.call(ctx, arn, "ModifyTargetGroupAttributes", url.Values{
+		"TargetGroupArn":            {arn},
+		"Attributes.member.1.Key":   {"deregistration_delay.timeout_seconds"},
+		"Attributes.member.1.Value": {strconv.Itoa(int(delay / time.Second))},
+	}, nil)
+}
+
+type awsErrorResponse struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// call calls action for the target group arn, in its region, and decodes the response into
+// result, if it isn't nil.
+func (c *elbClient) call(ctx context.Context, arn, action string, params url.Values, result interface{}) error {
+	region, err := targetGroupRegion(arn)
+	if err != nil {
+		return err
+	}
+	creds, err := c.credentials(ctx, region)
+	if err != nil {
+		return err
+	}
+
+	params.Set("Action", action)
+	params.Set("Version", elbAPIVersion)
+	body := []byte(params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(region), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	sigv4.Sign(req, body, creds, region, "elasticloadbalancing", c.now())
+
+	return doAWS(c.client, req, action, result)
+}
+
+// doAWS sends a request to a query API, and decodes its response into result, if it isn't nil.
+func doAWS(client *http.Client, req *http.Request, action string, result interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var awsErr awsErrorResponse
+		if xml.Unmarshal(data, &awsErr) == nil && awsErr.Code != "" {
+			return fmt.Errorf("%s: %s: %s", action, awsErr.Code, awsErr.Message)
+		}
+		return fmt.Errorf("%s: %s: %s", action, resp.Status, bytes.TrimSpace(data))
+	}
+	if result == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, result)
+}
+
+// awsCredentials returns where an elbClient gets its credentials: from the web identity token
+// that EKS gives a pod whose service account has an IAM role, if there is one, or else from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment variables.
+func awsCredentials(client *http.Client) func(ctx context.Context, region string) (sigv4.Credentials, error) {
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	if tokenFile == "" || roleARN == "" {
+		creds := sigv4.CredentialsFromEnv()
+		return func(context.Context, string) (sigv4.Credentials, error) {
+			if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+				return creds, fmt.Errorf("no AWS credentials: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, " +
+					"or give Ambassador's service account an IAM role")
+			}
+			return creds, nil
+		}
+	}
+
+	var mutex sync.Mutex
+	var creds sigv4.Credentials
+	var expires time.Time
+	return func(ctx context.Context, region string) (sigv4.Credentials, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		// Renew a while before they expire, so that a call never goes out with credentials
+		// that expire on the way.
+		if time.Now().Add(5 * time.Minute).Before(expires) {
+			return creds, nil
+		}
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return creds, err
+		}
+		assumed, expiration, err := assumeRoleWithWebIdentity(ctx, client,
+			"https://sts."+region+"."+awsDomain(region), roleARN, strings.TrimSpace(string(token)))
+		if err != nil {
+			return creds, err
+		}
+		creds, expires = assumed, expiration
+		return creds, nil
+	}
+}
+
+type stsCredentials struct {
+	AccessKeyID     string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>AccessKeyId"`
+	SecretAccessKey string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SecretAccessKey"`
+	SessionToken    string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SessionToken"`
+	Expiration      time.Time `xml:"AssumeRoleWithWebIdentityResult>Credentials>Expiration"`
+}
+
+// assumeRoleWithWebIdentity trades a web identity token for temporary credentials for a role.
+// The token is what proves who's asking, so the request isn't signed.
+func assumeRoleWithWebIdentity(ctx context.Context, client *http.Client, endpoint, roleARN, token string) (sigv4.Credentials, time.Time, error) {
+	sessionName := "ambassador"
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		sessionName = hostname
+	}
+	body := []byte(url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {token},
+	}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return sigv4.Credentials{}, time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	var result stsCredentials
+	if err := doAWS(client, req, "AssumeRoleWithWebIdentity", &result); err != nil {
+		return sigv4.Credentials{}, time.Time{}, err
+	}
+	return sigv4.Credentials{
+		AccessKeyID:     result.AccessKeyID,
+		SecretAccessKey: result.SecretAccessKey,
+		SessionToken:    result.SessionToken,
+	}, result.Expiration, nil
+}
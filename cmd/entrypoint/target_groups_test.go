@@ -0,0 +1,237 @@
+package entrypoint
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/sigv4"
+)
+
+const testTargetGroupARN = "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/ambassador/73e2d6bc24d8a067"
+
+func TestParseTargetGroupBindings(t *testing.T) {
+	bindings, err := parseTargetGroupBindings(`[
+		{"targetGroupARN": "` + testTargetGroupARN + `", "serviceRef": {"name": "ambassador", "port": 443}},
+		{"targetGroupARN": "` + testTargetGroupARN + `", "serviceRef": {"name": "ambassador", "port": "http"}}
+	]`)
+	require.NoError(t, err)
+	require.Len(t, bindings, 2)
+	assert.Equal(t, "ambassador", bindings[0].ServiceRef.Name)
+	assert.Equal(t, kates.Int, bindings[0].ServiceRef.Port.Type)
+	assert.Equal(t, int32(443), bindings[0].ServiceRef.Port.IntVal)
+	assert.Equal(t, "http", bindings[1].ServiceRef.Port.StrVal)
+
+	_, err = parseTargetGroupBindings(`[{"targetGroupARN": "arn:aws:s3:::bucket", "serviceRef": {"name": "ambassador", "port": 443}}]`)
+	assert.Error(t, err)
+	_, err = parseTargetGroupBindings(`[{"targetGroupARN": "` + testTargetGroupARN + `", "serviceRef": {"port": 443}}]`)
+	assert.Error(t, err)
+	_, err = parseTargetGroupBindings(`{}`)
+	assert.Error(t, err)
+
+	region, err := targetGroupRegion(testTargetGroupARN)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", region)
+}
+
+func targetGroupService() *kates.Service {
+	svc := &kates.Service{}
+	svc.SetName("ambassador")
+	svc.SetNamespace("ambassador")
+	svc.Spec.Ports = []kates.ServicePort{
+		{Name: "http", Port: 80},
+		{Name: "https", Port: 443},
+	}
+	return svc
+}
+
+func TestReconcileTargetGroups(t *testing.T) {
+	os.Setenv("AMBASSADOR_NAMESPACE", "ambassador")
+	defer os.Unsetenv("AMBASSADOR_NAMESPACE")
+
+	bindings, err := parseTargetGroupBindings(`[{"targetGroupARN": "` + testTargetGroupARN + `",
+		"serviceRef": {"name": "ambassador", "port": 443}}]`)
+	require.NoError(t, err)
+	tg := &targetGroups{
+		bindings: bindings,
+		dirty:    make(chan struct{}, 1),
+		targets:  make(map[string][]awsTarget),
+		waiting:  make(map[string]string),
+	}
+
+	endpoints := &kates.Endpoints{}
+	endpoints.SetName("ambassador")
+	endpoints.SetNamespace("ambassador")
+	endpoints.Subsets = []kates.EndpointSubset{{
+		Addresses:         []kates.EndpointAddress{{IP: "10.0.0.2"}, {IP: "10.0.0.1"}},
+		NotReadyAddresses: []kates.EndpointAddress{{IP: "10.0.0.3"}},
+		Ports:             []kates.EndpointPort{{Name: "http", Port: 8080}, {Name: "https", Port: 8443}},
+	}}
+	s := &AmbassadorInputs{Services: []*kates.Service{targetGroupService()}, Endpoints: []*kates.Endpoints{endpoints}}
+
+	s.ReconcileTargetGroups(tg)
+	assert.Equal(t, []awsTarget{{ID: "10.0.0.1", Port: 8443}, {ID: "10.0.0.2", Port: 8443}}, tg.targets[testTargetGroupARN])
+	assert.Len(t, tg.dirty, 1)
+
+	// The same targets again don't need registering.
+	<-tg.dirty
+	s.ReconcileTargetGroups(tg)
+	assert.Len(t, tg.dirty, 0)
+
+	// Without ready endpoints, what's registered is left alone.
+	endpoints.Subsets[0].Addresses = nil
+	s.ReconcileTargetGroups(tg)
+	assert.Len(t, tg.targets[testTargetGroupARN], 2)
+	assert.Equal(t, "Service ambassador.ambassador has no ready endpoints", tg.waiting[testTargetGroupARN])
+
+	// EndpointSlices, where there are any, are what counts, and a terminating endpoint isn't
+	// ready.
+	slice := kates.NewUnstructured("EndpointSlice", "discovery.k8s.io/v1beta1")
+	slice.SetName("ambassador-x7k2p")
+	slice.SetNamespace("ambassador")
+	slice.SetLabels(map[string]string{"kubernetes.io/service-name": "ambassador"})
+	slice.Object["addressType"] = "IPv4"
+	slice.Object["ports"] = []interface{}{
+		map[string]interface{}{"name": "https", "port": int64(8443)},
+	}
+	slice.Object["endpoints"] = []interface{}{
+		map[string]interface{}{"addresses": []interface{}{"10.0.0.4"}},
+		map[string]interface{}{"addresses": []interface{}{"10.0.0.5"}, "conditions": map[string]interface{}{"ready": true}},
+		map[string]interface{}{"addresses": []interface{}{"10.0.0.6"}, "conditions": map[string]interface{}{"ready": false}},
+		map[string]interface{}{"addresses": []interface{}{"10.0.0.7"},
+			"conditions": map[string]interface{}{"ready": true, "terminating": true}},
+	}
+	s.EndpointSlices = []*kates.Unstructured{slice}
+	s.ReconcileTargetGroups(tg)
+	assert.Equal(t, []awsTarget{{ID: "10.0.0.4", Port: 8443}, {ID: "10.0.0.5", Port: 8443}}, tg.targets[testTargetGroupARN])
+
+	tg.bindings[0].ServiceRef.Port = kates.IntOrString{Type: kates.Int, IntVal: 8080}
+	s.ReconcileTargetGroups(tg)
+	assert.Equal(t, "Service ambassador.ambassador has no port 8080", tg.waiting[testTargetGroupARN])
+
+	var off *targetGroups
+	s.ReconcileTargetGroups(off)
+}
+
+func TestPlanTargets(t *testing.T) {
+	register, deregister := planTargets(
+		[]awsTarget{{ID: "10.0.0.1", Port: 8443}, {ID: "10.0.0.2", Port: 8443}},
+		[]awsTarget{{ID: "10.0.0.2", Port: 8443}, {ID: "10.0.0.3", Port: 8443}},
+	)
+	assert.Equal(t, []awsTarget{{ID: "10.0.0.3", Port: 8443}}, register)
+	assert.Equal(t, []awsTarget{{ID: "10.0.0.1", Port: 8443}}, deregister)
+
+	register, deregister = planTargets(nil, nil)
+	assert.Empty(t, register)
+	assert.Empty(t, deregister)
+}
+
+const describeTargetHealthResponse = `<DescribeTargetHealthResponse xmlns="http://elasticloadbalancing.amazonaws.com/doc/2015-12-01/">
+  <DescribeTargetHealthResult>
+    <TargetHealthDescriptions>
+      <member>
+        <Target><Id>10.0.0.1</Id><Port>8443</Port></Target>
+        <TargetHealth><State>healthy</State></TargetHealth>
+      </member>
+      <member>
+        <Target><Id>10.0.0.9</Id><Port>8443</Port></Target>
+        <TargetHealth><State>draining</State></TargetHealth>
+      </member>
+      <member>
+        <Target><Id>10.0.0.8</Id><Port>8443</Port></Target>
+        <TargetHealth><State>unhealthy</State></TargetHealth>
+      </member>
+    </TargetHealthDescriptions>
+  </DescribeTargetHealthResult>
+</DescribeTargetHealthResponse>`
+
+func TestTargetGroupsSync(t *testing.T) {
+	calls := make(chan url.Values, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20200901/eu-west-1/elasticloadbalancing/aws4_request, "))
+		params, err := url.ParseQuery(string(body))
+		assert.NoError(t, err)
+		calls <- params
+		switch params.Get("Action") {
+		case "DescribeTargetHealth":
+			w.Write([]byte(describeTargetHealthResponse))
+		case "ModifyTargetGroupAttributes":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code>` +
+				`<Message>not allowed</Message></Error></ErrorResponse>`))
+		default:
+			w.Write([]byte(`<Response/>`))
+		}
+	}))
+	defer srv.Close()
+
+	elb := &elbClient{
+		client: srv.Client(),
+		credentials: func(context.Context, string) (sigv4.Credentials, error) {
+			return sigv4.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+		},
+		endpoint: func(string) string { return srv.URL },
+		now:      func() time.Time { return time.Date(2020, 9, 1, 12, 30, 0, 0, time.UTC) },
+	}
+
+	registered, err := elb.describeTargets(context.Background(), testTargetGroupARN)
+	require.NoError(t, err)
+	assert.Equal(t, []awsTarget{{ID: "10.0.0.1", Port: 8443}, {ID: "10.0.0.8", Port: 8443}}, registered)
+	params := <-calls
+	assert.Equal(t, elbAPIVersion, params.Get("Version"))
+	assert.Equal(t, testTargetGroupARN, params.Get("TargetGroupArn"))
+
+	err = elb.setDeregistrationDelay(context.Background(), testTargetGroupARN, 45*time.Second)
+	assert.EqualError(t, err, "ModifyTargetGroupAttributes: AccessDenied: not allowed")
+	params = <-calls
+	assert.Equal(t, "deregistration_delay.timeout_seconds", params.Get("Attributes.member.1.Key"))
+	assert.Equal(t, "45", params.Get("Attributes.member.1.Value"))
+
+	tg := &targetGroups{elb: elb}
+	require.NoError(t, tg.sync(context.Background(), testTargetGroupARN,
+		[]awsTarget{{ID: "10.0.0.1", Port: 8443}, {ID: "10.0.0.2", Port: 8443}}))
+	assert.Equal(t, "DescribeTargetHealth", (<-calls).Get("Action"))
+	params = <-calls
+	assert.Equal(t, "RegisterTargets", params.Get("Action"))
+	assert.Equal(t, "10.0.0.2", params.Get("Targets.member.1.Id"))
+	assert.Equal(t, "8443", params.Get("Targets.member.1.Port"))
+	assert.Empty(t, params.Get("Targets.member.2.Id"))
+	params = <-calls
+	assert.Equal(t, "DeregisterTargets", params.Get("Action"))
+	assert.Equal(t, "10.0.0.8", params.Get("Targets.member.1.Id"))
+	assert.Len(t, calls, 0)
+}
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "AssumeRoleWithWebIdentity", r.PostForm.Get("Action"))
+		assert.Equal(t, "arn:aws:iam::123456789012:role/ambassador", r.PostForm.Get("RoleArn"))
+		assert.Equal(t, "token", r.PostForm.Get("WebIdentityToken"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte(`<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>` +
+			`<AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>` +
+			`<SessionToken>session</SessionToken><Expiration>2020-09-01T13:30:00Z</Expiration>` +
+			`</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+	}))
+	defer srv.Close()
+
+	creds, expires, err := assumeRoleWithWebIdentity(context.Background(), srv.Client(), srv.URL,
+		"arn:aws:iam::123456789012:role/ambassador", "token")
+	require.NoError(t, err)
+	assert.Equal(t, sigv4.Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}, creds)
+	assert.Equal(t, time.Date(2020, 9, 1, 13, 30, 0, 0, time.UTC), expires.UTC())
+}
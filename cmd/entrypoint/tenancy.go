@@ -0,0 +1,32 @@
+package entrypoint
+
+import (
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// tenantsAnnotation lists, comma-separated, the tenants whose resources may live in a
+// Namespace. diagd refuses a resource with the getambassador.io/tenant label in a Namespace
+// that doesn't list its tenant, and filters diagnostics and metrics by that label.
+const tenantsAnnotation = "getambassador.io/tenants"
+
+// ReconcileTenants sets Namespaces to the Namespaces in AllNamespaces that have
+// tenantsAnnotation, with nothing but their names and that annotation: diagd doesn't need
+// anything else, and a cluster can have a lot of Namespaces. The kind is set here, since
+// the watch doesn't always fill it in for typed objects.
+func (s *AmbassadorInputs) ReconcileTenants() {
+	s.Namespaces = nil
+	for _, ns := range s.AllNamespaces {
+		tenants, ok := ns.GetAnnotations()[tenantsAnnotation]
+		if !ok {
+			continue
+		}
+
+		s.Namespaces = append(s.Namespaces, &kates.Namespace{
+			TypeMeta: kates.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: kates.ObjectMeta{
+				Name:        ns.GetName(),
+				Annotations: map[string]string{tenantsAnnotation: tenants},
+			},
+		})
+	}
+}
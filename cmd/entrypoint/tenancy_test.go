@@ -0,0 +1,36 @@
+package entrypoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+func TestReconcileTenants(t *testing.T) {
+	s := &AmbassadorInputs{AllNamespaces: []*kates.Namespace{
+		{
+			ObjectMeta: kates.ObjectMeta{Name: "payments",
+				Labels:      map[string]string{"team": "payments"},
+				Annotations: map[string]string{tenantsAnnotation: "payments,billing", "owner": "someone"}},
+		},
+		{
+			ObjectMeta: kates.ObjectMeta{Name: "default"},
+		},
+	}}
+
+	s.ReconcileTenants()
+	assert.Equal(t, []*kates.Namespace{
+		{
+			TypeMeta: kates.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: kates.ObjectMeta{Name: "payments",
+				Annotations: map[string]string{tenantsAnnotation: "payments,billing"}},
+		},
+	}, s.Namespaces)
+
+	// A Namespace that loses the annotation drops out.
+	s.AllNamespaces[0].Annotations = nil
+	s.ReconcileTenants()
+	assert.Empty(t, s.Namespaces)
+}
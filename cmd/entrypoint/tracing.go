@@ -0,0 +1,211 @@
+package entrypoint
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The control plane traces every reconfiguration: one trace, with a root "reconfigure" span that
+// starts when the watcher notices a change and ends when ambex pushes the result to Envoy. Its
+// children are the snapshot build, the diagd webhook call, and the ambex push; diagd adds its own
+// spans (fetcher, aconf, IR, econf, validation) using the traceparent header it's sent. Traces
+// are sent to an OTLP/HTTP collector as JSON, so no OpenTelemetry SDK is needed.
+
+type span struct {
+	name       string
+	spanID     [8]byte
+	parentID   [8]byte
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+}
+
+// The reconfigTrace struct is the trace of one reconfiguration. A nil *reconfigTrace is valid,
+// and does nothing; that's what you get when tracing is off.
+type reconfigTrace struct {
+	mu      sync.Mutex
+	traceID [16]byte
+	root    span
+	spans   []span
+	done    bool
+}
+
+func (r *reconfigTrace) addSpan(name string, start, end time.Time, attributes map[string]string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span{
+		name:       name,
+		spanID:     newSpanID(),
+		parentID:   r.root.spanID,
+		start:      start,
+		end:        end,
+		attributes: attributes,
+	})
+}
+
+// The traceparent method returns the W3C traceparent header that makes a span a child of the
+// root span, or "" if tracing is off.
+func (r *reconfigTrace) traceparent() string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(r.traceID[:]), hex.EncodeToString(r.root.spanID[:]))
+}
+
+type tracer struct {
+	endpoint string
+	client   *http.Client
+
+	mu      sync.Mutex
+	current *reconfigTrace
+}
+
+var controlPlaneTracer = newTracer(GetOTLPEndpoint())
+
+func newTracer(endpoint string) *tracer {
+	return &tracer{endpoint: endpoint, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// The startReconfig method starts the trace of a reconfiguration that began at start. If the
+// previous reconfiguration never got as far as an ambex push, it's finished now.
+func (t *tracer) startReconfig(start time.Time) *reconfigTrace {
+	if t.endpoint == "" {
+		return nil
+	}
+
+	r := &reconfigTrace{root: span{name: "reconfigure", spanID: newSpanID(), start: start}}
+	if _, err := rand.Read(r.traceID[:]); err != nil {
+		panic(err)
+	}
+
+	t.mu.Lock()
+	previous := t.current
+	t.current = r
+	t.mu.Unlock()
+
+	t.finish(previous, start)
+	return r
+}
+
+// The observeAmbexPush method is hooked into ambex. A push finishes the current reconfiguration.
+func (t *tracer) observeAmbexPush(d time.Duration) {
+	t.mu.Lock()
+	r := t.current
+	t.current = nil
+	t.mu.Unlock()
+
+	if r == nil {
+		return
+	}
+
+	end := time.Now()
+	r.addSpan("ambex.push", end.Add(-d), end, nil)
+	t.finish(r, end)
+}
+
+func (t *tracer) finish(r *reconfigTrace, end time.Time) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	if r.done {
+		r.mu.Unlock()
+		return
+	}
+	r.done = true
+	r.root.end = end
+	body, err := json.Marshal(r.otlp())
+	r.mu.Unlock()
+
+	if err != nil {
+		log.Printf("error encoding trace: %v", err)
+		return
+	}
+
+	go func() {
+		resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("error sending trace to %s: %v", t.endpoint, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			log.Printf("error sending trace to %s: %s", t.endpoint, resp.Status)
+		}
+	}()
+}
+
+// The otlp method renders the trace as an OTLP/HTTP JSON ExportTraceServiceRequest. The caller
+// must hold r.mu.
+func (r *reconfigTrace) otlp() map[string]interface{} {
+	spans := []map[string]interface{}{r.otlpSpan(r.root, false)}
+	for _, s := range r.spans {
+		spans = append(spans, r.otlpSpan(s, true))
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]string{
+						"service.name":      "ambassador-control-plane",
+						"service.namespace": GetAmbassadorNamespace(),
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/datawire/ambassador/cmd/entrypoint"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *reconfigTrace) otlpSpan(s span, hasParent bool) map[string]interface{} {
+	result := map[string]interface{}{
+		"traceId":           hex.EncodeToString(r.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              1, // SPAN_KIND_INTERNAL
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes":        otlpAttributes(s.attributes),
+	}
+	if hasParent {
+		result["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	return result
+}
+
+func otlpAttributes(attributes map[string]string) []interface{} {
+	result := []interface{}{}
+	for key, value := range attributes {
+		result = append(result, map[string]interface{}{
+			"key":   key,
+			"value": map[string]interface{}{"stringValue": value},
+		})
+	}
+	return result
+}
+
+func newSpanID() [8]byte {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return id
+}
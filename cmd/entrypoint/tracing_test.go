@@ -0,0 +1,71 @@
+package entrypoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracingDisabled(t *testing.T) {
+	trace := newTracer("").startReconfig(time.Now())
+	assert.Nil(t, trace)
+
+	// A nil trace is safe to use.
+	trace.addSpan("snapshot.build", time.Now(), time.Now(), nil)
+	assert.Equal(t, "", trace.traceparent())
+}
+
+func TestTracing(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	tr := newTracer(srv.URL)
+	start := time.Now()
+	trace := tr.startReconfig(start)
+	trace.addSpan("snapshot.build", start, time.Now(), map[string]string{"ambassador.deltas": "3"})
+
+	parts := strings.Split(trace.traceparent(), "-")
+	require.Len(t, parts, 4)
+	assert.Equal(t, "00", parts[0])
+	assert.Len(t, parts[1], 32)
+	assert.Len(t, parts[2], 16)
+
+	tr.observeAmbexPush(10 * time.Millisecond)
+
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(<-bodies, &request))
+
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 3)
+	assert.Equal(t, "reconfigure", spans[0].Name)
+	assert.Equal(t, parts[2], spans[0].SpanID)
+	assert.Equal(t, "", spans[0].ParentSpanID)
+	for i, name := range []string{"snapshot.build", "ambex.push"} {
+		assert.Equal(t, name, spans[i+1].Name)
+		assert.Equal(t, parts[1], spans[i+1].TraceID)
+		assert.Equal(t, parts[2], spans[i+1].ParentSpanID)
+	}
+}
@@ -0,0 +1,323 @@
+package entrypoint
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/wasmfetch"
+)
+
+const (
+	wasmMinRetry = 5 * time.Second
+	wasmMaxRetry = 300 * time.Second
+)
+
+// ReconcileWasmFilters starts and stops fetching the modules of the WasmFilters, and sets
+// WasmFilters to the ones whose module we have, with Spec.Module.SHA256 set to its checksum. diagd
+// finds the module by that, in GetWasmDir(); a WasmFilter whose module isn't there yet is left
+// out, rather than handing Envoy a filter that it can't load.
+func (s *AmbassadorInputs) ReconcileWasmFilters(w *wasmModules) {
+	var filters []*amb.WasmFilter
+	for _, f := range s.AllWasmFilters {
+		if include(f.Spec.AmbassadorID) {
+			filters = append(filters, f)
+		}
+	}
+
+	secrets := make(map[string]*kates.Secret)
+	for _, f := range filters {
+		if f.Spec.Module.PullSecret == "" {
+			continue
+		}
+		for _, secret := range s.AllSecrets {
+			if secret.GetName() == f.Spec.Module.PullSecret && secret.GetNamespace() == f.GetNamespace() {
+				secrets[wasmKey(f)] = secret
+				break
+			}
+		}
+	}
+
+	s.WasmFilters = w.reconcile(filters, secrets)
+}
+
+// A wasmModuleFetch gets the module of a WasmFilter, with the filter's pull secret, if it has one
+// and it exists.
+type wasmModuleFetch func(ctx context.Context, f *amb.WasmFilter, pullSecret *kates.Secret) ([]byte, error)
+
+// wasmKey identifies a WasmFilter.
+func wasmKey(f *amb.WasmFilter) string {
+	return fmt.Sprintf("%s.%s", f.GetName(), f.GetNamespace())
+}
+
+type wasmModules struct {
+	ctx   context.Context
+	dir   string
+	fetch wasmModuleFetch
+
+	// The changed method returns this channel. We write down this channel to signal that a module
+	// has been fetched since the last time the reconcile method was invoked.
+	coalescedDirty chan struct{}
+	// Fetches write to this when they have a verified module. It is always being read by the
+	// implementation, so writing will never block.
+	fetchedCh chan wasmFetched
+
+	// The mutex protects access to fetches and secrets, and to the modules in dir.
+	mutex   sync.Mutex
+	fetches map[string]*wasmFetch
+	secrets map[string]*kates.Secret
+}
+
+type wasmFetch struct {
+	module amb.WasmModule
+	cancel context.CancelFunc
+	// sha256 is the checksum of the module, once it has been fetched.
+	sha256 string
+}
+
+type wasmFetched struct {
+	key    string
+	module amb.WasmModule
+	body   []byte
+	sha256 string
+}
+
+func newWasmModules(ctx context.Context, dir string, fetch wasmModuleFetch) *wasmModules {
+	result := &wasmModules{
+		ctx:            ctx,
+		dir:            dir,
+		fetch:          fetch,
+		coalescedDirty: make(chan struct{}),
+		fetchedCh:      make(chan wasmFetched),
+		fetches:        make(map[string]*wasmFetch),
+		secrets:        make(map[string]*kates.Secret),
+	}
+	go result.run(ctx)
+	return result
+}
+
+func (w *wasmModules) run(ctx context.Context) {
+	dirty := false
+	for {
+		if dirty {
+			select {
+			case w.coalescedDirty <- struct{}{}:
+				dirty = false
+			case fetched := <-w.fetchedCh:
+				w.store(fetched)
+			case <-ctx.Done():
+				return
+			}
+		} else {
+			select {
+			case fetched := <-w.fetchedCh:
+				dirty = w.store(fetched)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// store writes a fetched module to disk, and returns whether the fetch that got it still wants
+// it.
+func (w *wasmModules) store(fetched wasmFetched) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	f, ok := w.fetches[fetched.key]
+	if !ok || !reflect.DeepEqual(f.module, fetched.module) {
+		// The fetch was stopped while it was fetching the module.
+		return false
+	}
+
+	if err := writeWasmModule(w.dir, fetched.sha256, fetched.body); err != nil {
+		log.Printf("WasmFilter %s: %v", fetched.key, err)
+		return false
+	}
+	f.sha256 = fetched.sha256
+	return true
+}
+
+// writeWasmModule writes a module to dir, named for its checksum. It writes a temporary file and
+// renames it, so that nothing ever reads half a module.
+func writeWasmModule(dir, sha256 string, body []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".module-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path.Join(dir, sha256+".wasm"))
+}
+
+func (w *wasmModules) changed() chan struct{} {
+	return w.coalescedDirty
+}
+
+// Start and stop fetches as needed in order to match the supplied set of filters, and return
+// copies of the filters whose modules have been fetched. Modules on disk that no filter uses
+// anymore are removed.
+func (w *wasmModules) reconcile(filters []*amb.WasmFilter, secrets map[string]*kates.Secret) []*amb.WasmFilter {
+	wanted := make(map[string]*amb.WasmFilter)
+	for _, f := range filters {
+		wanted[wasmKey(f)] = f
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.secrets = secrets
+
+	for key, f := range w.fetches {
+		if wf, ok := wanted[key]; !ok || !reflect.DeepEqual(wf.Spec.Module, f.module) {
+			f.cancel()
+			delete(w.fetches, key)
+		}
+	}
+
+	var result []*amb.WasmFilter
+	for key, wf := range wanted {
+		f, ok := w.fetches[key]
+		if !ok {
+			ctx, cancel := context.WithCancel(w.ctx)
+			f = &wasmFetch{module: wf.Spec.Module, cancel: cancel}
+			w.fetches[key] = f
+			go w.pull(ctx, key, wf.DeepCopy())
+		}
+		if f.sha256 == "" {
+			continue
+		}
+		fetched := wf.DeepCopy()
+		fetched.Spec.Module.SHA256 = f.sha256
+		result = append(result, fetched)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return wasmKey(result[i]) < wasmKey(result[j]) })
+	w.removeUnused()
+	return result
+}
+
+// removeUnused removes the modules in dir that no fetch has. The mutex must be held.
+func (w *wasmModules) removeUnused() {
+	used := make(map[string]bool)
+	for _, f := range w.fetches {
+		if f.sha256 != "" {
+			used[f.sha256+".wasm"] = true
+		}
+	}
+	files, err := filepath.Glob(path.Join(w.dir, "*.wasm"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if !used[path.Base(file)] {
+			if err := os.Remove(file); err != nil {
+				log.Printf("WasmFilter: %v", err)
+			}
+		}
+	}
+}
+
+// pull fetches and verifies the module of one filter, retrying with backoff until it succeeds
+// or is stopped.
+func (w *wasmModules) pull(ctx context.Context, key string, f *amb.WasmFilter) {
+	retry := wasmMinRetry
+	for {
+		w.mutex.Lock()
+		secret := w.secrets[key]
+		w.mutex.Unlock()
+
+		body, err := w.fetch(ctx, f, secret)
+		var sha256 string
+		if err == nil {
+			sha256, err = wasmfetch.Verify(body, f.Spec.Module.SHA256)
+		}
+		if err == nil {
+			select {
+			case w.fetchedCh <- wasmFetched{key: key, module: f.Spec.Module, body: body, sha256: sha256}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("WasmFilter %s: %v (retrying in %v)", key, err, retry)
+
+		timer := time.NewTimer(retry)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		if retry *= 2; retry > wasmMaxRetry {
+			retry = wasmMaxRetry
+		}
+	}
+}
+
+// fetchWasmModule returns a wasmModuleFetch that pulls images from their registries, and reads
+// ConfigMaps with client.
+func fetchWasmModule(client *kates.Client) wasmModuleFetch {
+	puller := &wasmfetch.Puller{}
+	return func(ctx context.Context, f *amb.WasmFilter, pullSecret *kates.Secret) ([]byte, error) {
+		module := f.Spec.Module
+		switch {
+		case module.Image != "" && module.ConfigMap != nil:
+			return nil, fmt.Errorf("module has both an image and a config_map")
+		case module.Image != "":
+			var creds *wasmfetch.Credentials
+			if module.PullSecret != "" {
+				if pullSecret == nil {
+					return nil, fmt.Errorf("pull secret %s not found", module.PullSecret)
+				}
+				registry, err := wasmfetch.Registry(module.Image)
+				if err != nil {
+					return nil, err
+				}
+				creds, err = wasmfetch.CredentialsFromDockerConfig(pullSecret.Data[".dockerconfigjson"], registry)
+				if err != nil {
+					return nil, fmt.Errorf("pull secret %s: %w", module.PullSecret, err)
+				}
+			}
+			return puller.Pull(ctx, module.Image, creds)
+		case module.ConfigMap != nil:
+			cm := &kates.ConfigMap{
+				TypeMeta:   kates.TypeMeta{Kind: "ConfigMap"},
+				ObjectMeta: kates.ObjectMeta{Name: module.ConfigMap.Name, Namespace: f.GetNamespace()},
+			}
+			if err := client.Get(ctx, cm, cm); err != nil {
+				return nil, fmt.Errorf("config map %s: %w", module.ConfigMap.Name, err)
+			}
+			if body, ok := cm.BinaryData[module.ConfigMap.Key]; ok {
+				return body, nil
+			}
+			if body, ok := cm.Data[module.ConfigMap.Key]; ok {
+				return []byte(body), nil
+			}
+			return nil, fmt.Errorf("config map %s has no key %s", module.ConfigMap.Name, module.ConfigMap.Key)
+		default:
+			return nil, fmt.Errorf("module has neither an image nor a config_map")
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package entrypoint
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/wasmfetch"
+)
+
+func TestReconcileWasmFilters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "wasm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	module := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	fetches := make(chan string, 10)
+	w := newWasmModules(ctx, dir, func(_ context.Context, f *amb.WasmFilter, pullSecret *kates.Secret) ([]byte, error) {
+		fetches <- f.Spec.Module.Image
+		if f.Spec.Module.Image == "example/broken" {
+			return []byte("#!/bin/sh\n"), nil
+		}
+		return module, nil
+	})
+
+	filter := func(name, image string) *amb.WasmFilter {
+		f := &amb.WasmFilter{Spec: amb.WasmFilterSpec{Module: amb.WasmModule{Image: image}}}
+		f.SetName(name)
+		f.SetNamespace("default")
+		return f
+	}
+	s := &AmbassadorInputs{AllWasmFilters: []*amb.WasmFilter{filter("headers", "example/headers:v1")}}
+
+	// The module hasn't been fetched yet, so the filter is left out.
+	s.ReconcileWasmFilters(w)
+	assert.Empty(t, s.WasmFilters)
+	assert.Equal(t, "example/headers:v1", <-fetches)
+	select {
+	case <-w.changed():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the module")
+	}
+
+	s.ReconcileWasmFilters(w)
+	sum := wasmfetch.Checksum(module)
+	require.Len(t, s.WasmFilters, 1)
+	assert.Equal(t, sum, s.WasmFilters[0].Spec.Module.SHA256)
+	assert.Empty(t, s.AllWasmFilters[0].Spec.Module.SHA256)
+	body, err := ioutil.ReadFile(path.Join(dir, sum+".wasm"))
+	require.NoError(t, err)
+	assert.Equal(t, module, body)
+
+	// The same filter again doesn't fetch the module again; one whose module isn't a Wasm
+	// module never makes it in.
+	s.AllWasmFilters = append(s.AllWasmFilters, filter("broken", "example/broken"))
+	s.ReconcileWasmFilters(w)
+	assert.Equal(t, "example/broken", <-fetches)
+	require.Len(t, s.WasmFilters, 1)
+	assert.Equal(t, "headers", s.WasmFilters[0].GetName())
+
+	// Once no filter uses the module, it's removed.
+	s.AllWasmFilters = nil
+	s.ReconcileWasmFilters(w)
+	assert.Empty(t, s.WasmFilters)
+	assert.Empty(t, w.fetches)
+	_, err = os.Stat(path.Join(dir, sum+".wasm"))
+	assert.True(t, os.IsNotExist(err))
+}
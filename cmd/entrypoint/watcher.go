@@ -2,18 +2,18 @@ package entrypoint
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"strconv"
 	"strings"
-	"sync/atomic"
+	"time"
 
+	"github.com/datawire/ambassador/pkg/dlog"
 	"github.com/datawire/ambassador/pkg/kates"
 	"github.com/datawire/ambassador/pkg/watt"
 )
 
-func watcher(ctx context.Context, encoded *atomic.Value) {
+func watcher(ctx context.Context, handoff *snapshotHandoff) {
 	crdYAML, err := ioutil.ReadFile(findCRDFilename())
 	if err != nil {
 		panic(err)
@@ -58,22 +58,62 @@ func watcher(ctx context.Context, encoded *atomic.Value) {
 		crdNames[crd.GetName()] = true
 	}
 
-	for _, name := range []string{"Ingress", "Service", "Secret", "Endpoints"} {
+	for _, name := range []string{"Ingress", "Service", "Secret", "Endpoints", "ConfigMap"} {
 		crdNames[name] = true
 	}
 
+	// Where the cluster has EndpointSlices, and we may read them, we watch them instead of
+	// Endpoints: they carry the zones of the endpoints and topology hints.
+	var endpointSlices []*kates.Unstructured
+	err = client.List(ctx, kates.Query{Namespace: ns, Kind: "EndpointSlice", FieldSelector: endpointFs,
+		LabelSelector: ls}, &endpointSlices)
+	useEndpointSlices := err == nil
+	if useEndpointSlices {
+		crdNames["EndpointSlice"] = true
+	} else {
+		dlog.Printf(ctx, "Watching Endpoints instead of EndpointSlices: %v", err)
+	}
+
+	// IngressClasses only exist as of Kubernetes 1.18, and they aren't namespaced, so we may
+	// not be allowed to read them. Like their parameters, they're cluster-wide, so Ambassador's
+	// field and label selectors don't apply to them.
+	var ingressClasses []*kates.Unstructured
+	err = client.List(ctx, kates.Query{Kind: "IngressClass"}, &ingressClasses)
+	if err == nil {
+		crdNames["IngressClass"] = true
+	} else {
+		dlog.Printf(ctx, "Ignoring IngressClasses: %v", err)
+	}
+
+	// Namespaces say which tenants' resources may be in them. They aren't namespaced either, so
+	// we may not be allowed to read them, in which case every resource that claims a tenant is
+	// refused.
+	var namespaces []*kates.Namespace
+	err = client.List(ctx, kates.Query{Kind: "Namespace"}, &namespaces)
+	if err == nil {
+		crdNames["Namespace"] = true
+	} else {
+		dlog.Printf(ctx, "Ignoring Namespaces, so resources with a tenant will be refused: %v", err)
+	}
+
 	allQueries := []kates.Query{
-		//kates.Query{Name: "IngressClasses", Kind: "IngressClass"}, // XXX: what is an ingress class?
+		{Name: "AllNamespaces", Kind: "Namespace"},
+		{Name: "IngressClasses", Kind: "IngressClass"},
+		{Name: "IngressClassParameters", Kind: "IngressClassParameters"},
 		{Namespace: ns, Name: "Ingresses", Kind: "Ingress",
 			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "Services", Kind: "Service",
 			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "AllSecrets", Kind: "Secret",
 			FieldSelector: fs, LabelSelector: ls},
+		{Namespace: ns, Name: "AllLuaConfigMaps", Kind: "ConfigMap",
+			FieldSelector: fs, LabelSelector: luaScriptsSelector(ls)},
 		{Namespace: ns, Name: "Hosts", Kind: "Host",
 			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "Mappings", Kind: "Mapping",
 			FieldSelector: fs, LabelSelector: ls},
+		{Namespace: ns, Name: "RouteDelegations", Kind: "RouteDelegation",
+			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "TCPMappings", Kind: "TCPMapping",
 			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "TLSContexts", Kind: "TLSContext",
@@ -88,13 +128,48 @@ func watcher(ctx context.Context, encoded *atomic.Value) {
 			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "TracingServices", Kind: "TracingService",
 			FieldSelector: fs, LabelSelector: ls},
+		{Namespace: ns, Name: "StatsSinks", Kind: "StatsSink",
+			FieldSelector: fs, LabelSelector: ls},
+		{Namespace: ns, Name: "AllTapPolicies", Kind: "TapPolicy",
+			FieldSelector: fs, LabelSelector: ls},
+		{Namespace: ns, Name: "AllWasmFilters", Kind: "WasmFilter",
+			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "ConsulResolvers", Kind: "ConsulResolver",
 			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "KubernetesEndpointResolvers", Kind: "KubernetesEndpointResolver",
 			FieldSelector: fs, LabelSelector: ls},
 		{Namespace: ns, Name: "KubernetesServiceResolvers", Kind: "KubernetesServiceResolver",
 			FieldSelector: fs, LabelSelector: ls},
-		{Namespace: ns, Name: "Endpoints", Kind: "Endpoints", FieldSelector: endpointFs, LabelSelector: ls},
+		{Namespace: ns, Name: "DNSResolvers", Kind: "DNSResolver",
+			FieldSelector: fs, LabelSelector: ls},
+		{Namespace: ns, Name: "MultiClusterResolvers", Kind: "MultiClusterResolver",
+			FieldSelector: fs, LabelSelector: ls},
+		{Namespace: ns, Name: "StaticResolvers", Kind: "StaticResolver",
+			FieldSelector: fs, LabelSelector: ls},
+	}
+
+	if useEndpointSlices {
+		allQueries = append(allQueries,
+			kates.Query{Namespace: ns, Name: "EndpointSlices", Kind: "EndpointSlice", FieldSelector: endpointFs,
+				LabelSelector: ls})
+	} else {
+		allQueries = append(allQueries,
+			kates.Query{Namespace: ns, Name: "Endpoints", Kind: "Endpoints", FieldSelector: endpointFs,
+				LabelSelector: ls})
+	}
+
+	if GetOPAURL() != "" {
+		allQueries = append(allQueries,
+			kates.Query{Namespace: ns, Name: "AllPolicyConfigMaps", Kind: "ConfigMap",
+				FieldSelector: fs, LabelSelector: policySelector(ls)})
+	}
+
+	// Certificates are only watched where cert-manager is installed, rather than warning about
+	// them everywhere else.
+	if crdNames["certificates.cert-manager.io"] {
+		allQueries = append(allQueries,
+			kates.Query{Namespace: ns, Name: "Certificates", Kind: "certificates.cert-manager.io",
+				FieldSelector: fs, LabelSelector: ls})
 	}
 
 	if IsKnativeEnabled() {
@@ -111,7 +186,7 @@ func watcher(ctx context.Context, encoded *atomic.Value) {
 		if crdNames[q.Kind] {
 			queries = append(queries, q)
 		} else {
-			log.Printf("Warning, unable to watch %s, unknown kind.", q.Kind)
+			dlog.Warnf(ctx, "Unable to watch %s, unknown kind.", q.Kind)
 		}
 	}
 
@@ -120,46 +195,170 @@ func watcher(ctx context.Context, encoded *atomic.Value) {
 
 	consulSnapshot := &watt.ConsulSnapshot{}
 	consul := newConsul(ctx, &consulWatcher{})
+	catalog := newCatalogSync(ctx, consulCatalogAgent)
+
+	dnsSnapshot := &DNSSnapshot{}
+	dns := newDNSResolvers(ctx, lookupDNS)
+
+	wasm := newWasmModules(ctx, GetWasmDir(), fetchWasmModule(client))
+
+	federationSnapshot := &FederationSnapshot{}
+	federation := newFederation(ctx, watchFederatedService)
+
+	sharedState, err := newSharedState(GetSharedState(), client, GetAmbassadorNamespace())
+	if err != nil {
+		dlog.Errorf(ctx, "AMBASSADOR_SHARED_STATE: %v; every replica will lead", err)
+	}
+	leader := newLeadership(ctx, sharedState)
+
+	var policy *opaPolicy
+	if opaURL := GetOPAURL(); opaURL != "" {
+		policy = newPolicy(ctx, newOPAClient(opaURL, GetOPADecision()), GetOPABundleURL(),
+			GetOPABundleInterval(), fetchPolicyBundle, reportPolicyDenial(client, leader))
+	}
+
+	var hostDNS *externalDNS
+	switch mode := GetExternalDNS(); {
+	case mode == "":
+	case !isExternalDNSMode(mode):
+		dlog.Errorf(ctx, "AMBASSADOR_EXTERNAL_DNS is %q, not %q or %q; not publishing Hosts for external-dns",
+			mode, externalDNSEndpoints, externalDNSAnnotation)
+	default:
+		if mode == externalDNSEndpoints && !crdNames["DNSEndpoint"] {
+			dlog.Warnf(ctx, "The DNSEndpoint CRD isn't installed; external-dns's crd source installs it.")
+		}
+		hostDNS = newExternalDNS(ctx, mode, client, ns, leader)
+	}
+
+	var awsTargetGroups *targetGroups
+	if config := GetAWSTargetGroups(); config != "" {
+		bindings, err := parseTargetGroupBindings(config)
+		if err != nil {
+			dlog.Errorf(ctx, "AMBASSADOR_AWS_TARGET_GROUPS: %v; not registering pods in target groups", err)
+		} else {
+			awsTargetGroups = newTargetGroups(ctx, bindings, newELBClient(), GetAWSDeregistrationDelay())
+		}
+	}
 
 	var unsentDeltas []*kates.Delta
 
 	invalid := map[string]*kates.Unstructured{}
+	labelErrors := rateLimitLabelErrors{}
+	// validation is how long isValid has taken since it was last reset, for the reconfiguration
+	// report.
+	var validation time.Duration
 	isValid := func(un *kates.Unstructured) bool {
+		defer func(start time.Time) { validation += time.Since(start) }(time.Now())
 		key := string(un.GetUID())
 		err := validator.Validate(ctx, un)
+		// diagd drops malformed rate limit labels, and reports them, but keeps the rest of the
+		// Mapping; so bad labels are worth a warning, not worth dropping the Mapping over.
+		if lerr := labelErrors.check(un); lerr != nil {
+			dlog.Warnf(dlog.WithField(ctx, "resource", auditKey(un.GetKind(), un.GetNamespace(), un.GetName())), "Invalid rate limit labels: %v", lerr)
+		}
 		if err != nil {
+			metrics.countValidationError(un.GetKind())
+			if prev, ok := invalid[key]; !ok || prev.Object["errors"] != err.Error() {
+				dlog.Warnf(dlog.WithField(ctx, "resource", auditKey(un.GetKind(), un.GetNamespace(), un.GetName())), "Invalid: %v", err)
+			}
 			copy := un.DeepCopy()
 			copy.Object["errors"] = err.Error()
 			invalid[key] = copy
+			controlPlaneAudit.resourceRejected(un, err)
 			return false
 		} else {
 			delete(invalid, key)
+			controlPlaneAudit.noteManager(un)
 			return true
 		}
 	}
 
 	firstReconfig := true
 
+	// This fires when the next TapPolicy expires.
+	var tapExpiry <-chan time.Time
+
 	for {
+		var changed time.Time
+		var source string
+		// phases are the phases of the reconfiguration before the snapshot build.
+		var phases []reconfigPhase
+
 		select {
 		case <-acc.Changed():
+			changed = time.Now()
+			source = "kubernetes"
 			var deltas []*kates.Delta
+			validation = 0
 			// We could probably get a win in some scenarios by using this filtered update thing to
 			// pre-exclude based on ambassador-id.
 			if !acc.FilteredUpdate(snapshot, &deltas, isValid) {
 				continue
 			}
+			phases = append(phases,
+				reconfigPhase{Name: "watch", Seconds: (time.Since(changed) - validation).Seconds()},
+				reconfigPhase{Name: "validate", Seconds: validation.Seconds()})
+			labelErrors.forget(deltas)
 			unsentDeltas = append(unsentDeltas, deltas...)
+			metrics.countKubernetesDeltas(deltas, snapshot)
 		case <-consul.changed():
+			changed = time.Now()
+			source = "consul"
 			consul.update(consulSnapshot)
+			metrics.countConsulUpdate()
+		case <-dns.changed():
+			changed = time.Now()
+			source = "dns"
+		case <-wasm.changed():
+			changed = time.Now()
+			source = "wasm"
+		case <-federation.changed():
+			changed = time.Now()
+			source = "federation"
+		case <-tapExpiry:
+			changed = time.Now()
+			source = "tap_expiry"
+		case <-tapUsage.changed:
+			changed = time.Now()
+			source = "tap_quota"
+		case <-policy.changed():
+			changed = time.Now()
+			source = "policy"
 		case <-ctx.Done():
 			return
 		}
 
-		snapshot.parseAnnotations()
+		// The inputs are reconciled in a copy of the snapshot, since the accumulator only updates
+		// the fields that change: a resource that the policy denies has to still be there for the
+		// next round, in case the policy allows it then.
+		inputs := *snapshot
+		var denied []*kates.Unstructured
+		if policy != nil {
+			start := time.Now()
+			denied = inputs.ReconcilePolicy(ctx, policy)
+			phases = append(phases, reconfigPhase{Name: "policy", Seconds: time.Since(start).Seconds()})
+		}
+
+		inputs.parseAnnotations()
 
-		snapshot.ReconcileSecrets()
-		snapshot.ReconcileConsul(ctx, consul)
+		inputs.ReconcileSecrets()
+		inputs.ReconcileLuaScripts()
+		inputs.ReconcileTenants()
+		tapUsage.update(inputs.AllTapPolicies)
+		tapExpiry = nil
+		if next := inputs.ReconcileTapPolicies(time.Now()); !next.IsZero() {
+			tapExpiry = time.After(time.Until(next))
+		}
+		tapSamples.update(inputs.TapPolicies)
+		inputs.ReconcileWasmFilters(wasm)
+		inputs.ReconcileConsul(ctx, consul)
+		inputs.ReconcileCatalogSync(catalog)
+		inputs.ReconcileDNS(dns)
+		dns.update(dnsSnapshot)
+		inputs.ReconcileFederation(federation)
+		federation.update(federationSnapshot)
+		inputs.ReconcileExternalDNS(hostDNS)
+		inputs.ReconcileTargetGroups(awsTargetGroups)
 
 		if !consul.isBootstrapped() {
 			continue
@@ -169,25 +368,40 @@ func watcher(ctx context.Context, encoded *atomic.Value) {
 		for _, inv := range invalid {
 			invalidSlice = append(invalidSlice, inv)
 		}
+		invalidSlice = append(invalidSlice, denied...)
 
+		// The snapshot goes to other goroutines as it is. inputs is this round's own copy, and
+		// the Reconcile methods replace the slices they change rather than changing them in
+		// place, so the next round of reconciling won't touch it.
 		sn := &Snapshot{
-			Kubernetes: snapshot,
+			Kubernetes: &inputs,
 			Consul:     consulSnapshot,
+			DNS:        dnsSnapshot,
+			Federation: federationSnapshot,
 			Invalid:    invalidSlice,
 			Deltas:     unsentDeltas,
 		}
 		unsentDeltas = nil
 
-		bytes, err := json.MarshalIndent(sn, "", "  ")
-		if err != nil {
-			panic(err)
+		handoff.store(sn)
+		noteSnapshot(sn, source)
+		metrics.observeSnapshotBuild(time.Since(changed))
+		trace := controlPlaneTracer.startReconfig(changed)
+		trace.addSpan("snapshot.build", changed, time.Now(), map[string]string{
+			"ambassador.deltas": strconv.Itoa(len(sn.Deltas)),
+		})
+		build := time.Since(changed)
+		for _, phase := range phases {
+			build -= time.Duration(phase.Seconds * float64(time.Second))
 		}
-		encoded.Store(bytes)
+		phases = append(phases, reconfigPhase{Name: "snapshot", Seconds: build.Seconds()})
+		report := controlPlaneReports.start(source, changed, phases)
 		if firstReconfig {
-			log.Println("Bootstrapped! Computing initial configuration...")
+			dlog.Println(ctx, "Bootstrapped! Computing initial configuration...")
 			firstReconfig = false
 		}
-		notifyReconfigWebhooks(ctx)
+		notifyReconfigWebhooks(ctx, trace, report)
+		controlPlaneAudit.snapshotApplied(source, sn.Deltas, len(sn.Invalid))
 
 		// we really only need to be incremental for a subset of things:
 		//  - Mappings & Endpoints are the biggies
@@ -0,0 +1,66 @@
+package entrypoint
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// The labels that Kubernetes puts the zone of a node in, newest first.
+var zoneLabels = []string{
+	"topology.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/zone",
+}
+
+// GetAmbassadorZone returns the zone that this Ambassador pod runs in, for zone-aware routing to
+// endpoints. AMBASSADOR_ZONE wins if it is set; otherwise we read the zone label of our node. It
+// returns "" when the zone can't be found, e.g. if Ambassador isn't allowed to get pods and nodes.
+func GetAmbassadorZone(ctx context.Context) string {
+	if zone := env("AMBASSADOR_ZONE", ""); zone != "" {
+		return zone
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Printf("Unable to find the zone of this pod: %v", err)
+		return ""
+	}
+
+	client, err := kates.NewClient(kates.ClientOptions{})
+	if err != nil {
+		log.Printf("Unable to find the zone of this pod: %v", err)
+		return ""
+	}
+
+	pod := &kates.Pod{
+		TypeMeta:   kates.TypeMeta{Kind: "Pod"},
+		ObjectMeta: kates.ObjectMeta{Name: hostname, Namespace: GetAmbassadorNamespace()},
+	}
+	if err := client.Get(ctx, pod, pod); err != nil {
+		log.Printf("Unable to find the zone of this pod: %v", err)
+		return ""
+	}
+
+	node := &kates.Node{
+		TypeMeta:   kates.TypeMeta{Kind: "Node"},
+		ObjectMeta: kates.ObjectMeta{Name: pod.Spec.NodeName},
+	}
+	if err := client.Get(ctx, node, node); err != nil {
+		log.Printf("Unable to find the zone of this pod: %v", err)
+		return ""
+	}
+
+	return nodeZone(node.GetLabels())
+}
+
+// nodeZone returns the zone of a node from its labels, or "" if it has none.
+func nodeZone(labels map[string]string) string {
+	for _, label := range zoneLabels {
+		if zone := labels[label]; zone != "" {
+			return zone
+		}
+	}
+	return ""
+}
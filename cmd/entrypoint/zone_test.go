@@ -0,0 +1,19 @@
+package entrypoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeZone(t *testing.T) {
+	assert.Equal(t, "us-east-1a", nodeZone(map[string]string{
+		"topology.kubernetes.io/zone":            "us-east-1a",
+		"failure-domain.beta.kubernetes.io/zone": "us-east-1b",
+	}))
+	assert.Equal(t, "us-east-1b", nodeZone(map[string]string{
+		"failure-domain.beta.kubernetes.io/zone": "us-east-1b",
+	}))
+	assert.Equal(t, "", nodeZone(map[string]string{"kubernetes.io/hostname": "node-1"}))
+	assert.Equal(t, "", nodeZone(nil))
+}
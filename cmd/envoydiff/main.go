@@ -0,0 +1,82 @@
+package envoydiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/pkg/envoydiff"
+)
+
+// Main compares the configuration that Envoy is running with the configuration that ambex is
+// serving it, prints where they differ, and exits 1 if they do.
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "envoydiff",
+		Short:         "show where Envoy's configuration differs from what ambex is serving it",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	adminURL := cmd.Flags().String("admin", "http://127.0.0.1:8001", "URL of Envoy's admin interface")
+	dir := cmd.Flags().String("dir", envoyDir(), "the directory that ambex serves Envoy configuration from")
+	asJSON := cmd.Flags().Bool("json", false, "print the differences as JSON")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		expected, err := envoydiff.LoadSnapshot(*dir)
+		if err != nil {
+			return err
+		}
+		dump, err := envoydiff.FetchConfigDump(context.Background(), *adminURL)
+		if err != nil {
+			return err
+		}
+		actual, err := envoydiff.ParseConfigDump(dump)
+		if err != nil {
+			return err
+		}
+
+		diffs := envoydiff.Diff(expected, actual)
+		if *asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if diffs == nil {
+				diffs = []envoydiff.Difference{}
+			}
+			if err := encoder.Encode(diffs); err != nil {
+				return err
+			}
+		} else {
+			for _, diff := range diffs {
+				fmt.Println(diff)
+			}
+		}
+
+		if len(diffs) > 0 {
+			return fmt.Errorf("Envoy's configuration differs from ambex's in %d places", len(diffs))
+		}
+		log.Print("Envoy's configuration is what ambex is serving")
+		return nil
+	}
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// envoyDir returns where ambex serves Envoy configuration from, as the entrypoint sets it up.
+func envoyDir() string {
+	if dir := os.Getenv("ENVOY_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("AMBASSADOR_CONFIG_BASE_DIR"); dir != "" {
+		return path.Join(dir, "envoy")
+	}
+	return "/ambassador/envoy"
+}
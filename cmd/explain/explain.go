@@ -0,0 +1,194 @@
+package explain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+)
+
+// These are the parts of the entrypoint's /api/v2/diag/explain that explain prints.
+
+type explanations struct {
+	Explanations []explanation `json:"explanations"`
+}
+
+type explanation struct {
+	Source struct {
+		Key        string `json:"key"`
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Generation int64  `json:"generation"`
+	} `json:"source"`
+	Config []map[string]interface{} `json:"config"`
+	Routes []struct {
+		ID          string `json:"id"`
+		Kind        string `json:"kind"`
+		Host        string `json:"host"`
+		Prefix      string `json:"prefix"`
+		PrefixRegex bool   `json:"prefixRegex"`
+		Method      string `json:"method"`
+		Precedence  int    `json:"precedence"`
+		Mappings    []struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+			Cluster   string `json:"cluster"`
+			Weight    int    `json:"weight"`
+		} `json:"mappings"`
+	} `json:"routes"`
+	Envoy []struct {
+		Listener    string          `json:"listener"`
+		VirtualHost string          `json:"virtualHost"`
+		Index       int             `json:"index"`
+		TCP         bool            `json:"tcp"`
+		Config      json.RawMessage `json:"config"`
+	} `json:"envoy"`
+	Errors []string `json:"errors"`
+}
+
+// parseArgs returns the kind and name in args, which are "KIND NAME" or "KIND/NAME".
+func parseArgs(args []string) (string, string, error) {
+	if len(args) == 2 {
+		return args[0], args[1], nil
+	}
+	parts := strings.SplitN(args[0], "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%q isn't KIND/NAME", args[0])
+	}
+	return parts[0], parts[1], nil
+}
+
+// fetch fetches the explanations of the resources of kind with name, in namespace if it isn't
+// "", from the snapshot server at baseURL, as JSON.
+func fetch(ctx context.Context, baseURL, kind, name, namespace string) ([]byte, error) {
+	query := url.Values{"kind": {kind}, "name": {name}}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/v2/diag/explain?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// render writes the explanations in body to w, like kubectl explain, and returns how many there
+// were.
+func render(w io.Writer, body []byte) (int, error) {
+	var all explanations
+	if err := json.Unmarshal(body, &all); err != nil {
+		return 0, err
+	}
+	for i, e := range all.Explanations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := renderOne(w, e); err != nil {
+			return i, err
+		}
+	}
+	return len(all.Explanations), nil
+}
+
+func renderOne(w io.Writer, e explanation) error {
+	src := e.Source
+	fmt.Fprintf(w, "KIND:       %s\n", src.Kind)
+	fmt.Fprintf(w, "NAME:       %s/%s\n", src.Namespace, src.Name)
+	if src.Generation != 0 {
+		fmt.Fprintf(w, "GENERATION: %d\n", src.Generation)
+	}
+	fmt.Fprintf(w, "SOURCE:     %s\n", src.Key)
+
+	if len(e.Errors) > 0 {
+		fmt.Fprintf(w, "\nERRORS:\n")
+		for _, err := range e.Errors {
+			fmt.Fprintf(w, "  %s\n", err)
+		}
+	}
+
+	fmt.Fprintf(w, "\nCONFIGURATION:\n")
+	if len(e.Config) == 0 {
+		fmt.Fprintf(w, "  (none: Ambassador didn't make anything from it)\n")
+	}
+	for i, config := range e.Config {
+		if i > 0 {
+			fmt.Fprintf(w, "  ---\n")
+		}
+		if err := writeYAML(w, "  ", config); err != nil {
+			return err
+		}
+	}
+
+	for _, route := range e.Routes {
+		match := route.Prefix
+		if route.PrefixRegex {
+			match = "regex " + match
+		}
+		if route.Method != "" {
+			match = route.Method + " " + match
+		}
+		if route.Host != "" {
+			match = route.Host + " " + match
+		}
+		fmt.Fprintf(w, "\nROUTE %s (%s %s, precedence %d):\n", route.ID, route.Kind, match, route.Precedence)
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, m := range route.Mappings {
+			fmt.Fprintf(tw, "  %s/%s\t%s\tweight %d\n", m.Namespace, m.Name, m.Cluster, m.Weight)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "\nENVOY:\n")
+	if len(e.Envoy) == 0 {
+		fmt.Fprintf(w, "  (none: Envoy has no routes for it)\n")
+	}
+	for _, route := range e.Envoy {
+		if route.TCP {
+			fmt.Fprintf(w, "  listener %s, TCP proxy %d:\n", route.Listener, route.Index)
+		} else {
+			fmt.Fprintf(w, "  listener %s, virtual host %s, route %d:\n", route.Listener, route.VirtualHost, route.Index)
+		}
+		var config interface{}
+		if err := json.Unmarshal(route.Config, &config); err != nil {
+			return err
+		}
+		if err := writeYAML(w, "    ", config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeYAML writes v to w as YAML, with each line indented by indent.
+func writeYAML(w io.Writer, indent string, v interface{}) error {
+	bytes, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(bytes), "\n"), "\n") {
+		fmt.Fprintf(w, "%s%s\n", indent, line)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package explain
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const explainBody = `{
+  "apiVersion": "v2",
+  "explanations": [{
+    "source": {"key": "quote.default.1", "kind": "Mapping", "name": "quote", "namespace": "default", "generation": 3},
+    "config": [{"name": "quote", "prefix": "/backend/", "timeout_ms": 3000}],
+    "routes": [{
+      "id": "9eccc921", "kind": "http", "prefix": "/backend/", "method": "GET", "precedence": 0,
+      "mappings": [
+        {"name": "quote", "namespace": "default", "cluster": "cluster_quote_default", "weight": 90},
+        {"name": "quote-v2", "namespace": "default", "cluster": "cluster_quote_v2_default", "weight": 10}
+      ]
+    }],
+    "envoy": [{"listener": "ambassador-listener-8080", "virtualHost": "ambassador-listener-8080-*", "index": 6,
+      "config": {"match": {"prefix": "/backend/"}}}],
+    "errors": []
+  }]
+}`
+
+func TestExplain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/diag/explain" || r.URL.Query().Get("kind") != "mapping" ||
+			r.URL.Query().Get("name") != "quote" || r.URL.Query().Get("namespace") != "default" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(explainBody))
+	}))
+	defer server.Close()
+
+	body, err := fetch(context.Background(), server.URL+"/", "mapping", "quote", "default")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	count, err := render(&out, body)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	text := out.String()
+	assert.Contains(t, text, "KIND:       Mapping\nNAME:       default/quote\nGENERATION: 3\nSOURCE:     quote.default.1\n")
+	assert.Contains(t, text, "\nCONFIGURATION:\n")
+	assert.Contains(t, text, "  timeout_ms: 3000\n")
+	assert.Contains(t, text, "\nROUTE 9eccc921 (http GET /backend/, precedence 0):\n")
+	assert.Contains(t, text, "  default/quote-v2  cluster_quote_v2_default  weight 10\n")
+	assert.Contains(t, text, "\nENVOY:\n  listener ambassador-listener-8080, virtual host ambassador-listener-8080-*, route 6:\n")
+	assert.NotContains(t, text, "ERRORS:")
+
+	_, err = fetch(context.Background(), server.URL, "host", "quote", "")
+	assert.Error(t, err)
+}
+
+func TestParseArgs(t *testing.T) {
+	kind, name, err := parseArgs([]string{"mapping", "quote"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mapping", "quote"}, []string{kind, name})
+
+	kind, name, err = parseArgs([]string{"host/example"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host", "example"}, []string{kind, name})
+
+	_, _, err = parseArgs([]string{"quote"})
+	assert.Error(t, err)
+}
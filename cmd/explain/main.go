@@ -0,0 +1,55 @@
+package explain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Main explains a Mapping, Host or other resource, as the entrypoint's /api/v2/diag/explain
+// does: what Ambassador computed from it, the route it's in, and what Envoy has for it.
+func Main() {
+	var cmd = &cobra.Command{
+		Use:   "explain KIND NAME | KIND/NAME",
+		Short: "show what Ambassador and Envoy made of a resource",
+		Example: `  busyambassador explain mapping quote
+  busyambassador explain host/example -n ambassador`,
+		Args:          cobra.RangeArgs(1, 2),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	url := cmd.Flags().String("url", "http://localhost:9696", "URL of the entrypoint's snapshot server")
+	namespace := cmd.Flags().StringP("namespace", "n", "", "the namespace of the resource (default any namespace)")
+	asJSON := cmd.Flags().Bool("json", false, "print the explanations as JSON")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		kind, name, err := parseArgs(args)
+		if err != nil {
+			return err
+		}
+		body, err := fetch(context.Background(), *url, kind, name, *namespace)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			_, err := os.Stdout.Write(body)
+			return err
+		}
+		count, err := render(os.Stdout, body)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("Ambassador has no %s named %q", kind, name)
+		}
+		return nil
+	}
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
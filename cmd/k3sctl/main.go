@@ -39,30 +39,36 @@ func main() {
 
 	var up = &cobra.Command{
 		Use:   "up",
-		Short: "ensure the k3s and registry containers are up",
+		Short: "ensure the cluster and registry containers are up",
 	}
 
 	k3s.AddCommand(up)
 
 	up.RunE = func(cmd *cobra.Command, args []string) error {
 		regid := dtest.RegistryUp()
-		k3sid := dtest.K3sUp()
 		fmt.Printf("DOCKER_CONTAINER=%q\n", regid)
-		fmt.Printf("K3S_CONTAINER=%q\n", k3sid)
+		cluster := dtest.Cluster()
+		id := cluster.Up()
+		if cluster.Name() == "k3s" {
+			fmt.Printf("K3S_CONTAINER=%q\n", id)
+		} else {
+			fmt.Printf("CLUSTER=%q\n", id)
+		}
 		return nil
 	}
 
 	var down = &cobra.Command{
 		Use:   "down",
-		Short: "shutdown the k3s and registry containers",
+		Short: "shutdown the cluster and registry containers",
 	}
 
 	k3s.AddCommand(down)
 
 	down.RunE = func(cmd *cobra.Command, args []string) error {
-		k3sid := dtest.K3sDown()
+		cluster := dtest.Cluster()
+		id := cluster.Down()
 		regid := dtest.RegistryDown()
-		fmt.Printf("Shutdown k3s container: %s\n", k3sid)
+		fmt.Printf("Shutdown %s cluster: %s\n", cluster.Name(), id)
 		fmt.Printf("Shutdown registry container: %s\n", regid)
 		return nil
 	}
@@ -81,7 +87,7 @@ func main() {
 
 	var config = &cobra.Command{
 		Use:   "config",
-		Short: "print the k3s config",
+		Short: "print the cluster's kubeconfig",
 	}
 
 	output := config.Flags().StringP("output", "o", "", "path for kubeconfig file")
@@ -91,7 +97,7 @@ func main() {
 	config.RunE = func(cmd *cobra.Command, args []string) error {
 		kubeconfig := dtest.GetKubeconfig()
 		if kubeconfig == "" {
-			return errors.New("no k3s cluster is running")
+			return errors.New("no cluster is running")
 		}
 
 		if *output == "" {
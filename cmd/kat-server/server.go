@@ -46,12 +46,13 @@ func main() {
 
 	case "grpc_auth":
 		s = &srv.GRPCAUTH{
-			Port:          Port,
-			Backend:       os.Getenv("BACKEND"),
-			SecurePort:    SSLPort,
-			SecureBackend: os.Getenv("BACKEND"),
-			Cert:          Crt,
-			Key:           Key,
+			Port:            Port,
+			Backend:         os.Getenv("BACKEND"),
+			SecurePort:      SSLPort,
+			SecureBackend:   os.Getenv("BACKEND"),
+			Cert:            Crt,
+			Key:             Key,
+			ProtocolVersion: os.Getenv("GRPC_AUTH_PROTOCOL_VERSION"),
 		}
 
 		listeners = append(listeners, s)
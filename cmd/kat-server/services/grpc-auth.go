@@ -14,9 +14,12 @@ import (
 	"strings"
 
 	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	core_v3 "github.com/datawire/ambassador/pkg/api/envoy/config/core/v3"
 	pb "github.com/datawire/ambassador/pkg/api/envoy/service/auth/v2"
 	pb_legacy "github.com/datawire/ambassador/pkg/api/envoy/service/auth/v2alpha"
+	pb_v3 "github.com/datawire/ambassador/pkg/api/envoy/service/auth/v3"
 	envoy_type "github.com/datawire/ambassador/pkg/api/envoy/type"
+	envoy_type_v3 "github.com/datawire/ambassador/pkg/api/envoy/type/v3"
 
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"google.golang.org/genproto/googleapis/rpc/code"
@@ -24,7 +27,7 @@ import (
 	"google.golang.org/grpc"
 )
 
-// GRPCAUTH server object (all fields are required).
+// GRPCAUTH server object (all fields are required, except ProtocolVersion).
 type GRPCAUTH struct {
 	Port          int16
 	Backend       string
@@ -32,11 +35,24 @@ type GRPCAUTH struct {
 	SecureBackend string
 	Cert          string
 	Key           string
+
+	// ProtocolVersion selects the ext_authz protocol to serve: "v3", or
+	// the legacy v2alpha protocol if empty.
+	ProtocolVersion string
+}
+
+// register registers the authorization service for the configured protocol version.
+func (g *GRPCAUTH) register(s *grpc.Server) {
+	if g.ProtocolVersion == "v3" {
+		pb_v3.RegisterAuthorizationServer(s, &grpcAuthV3{g})
+	} else {
+		pb_legacy.RegisterAuthorizationServer(s, g)
+	}
 }
 
 // Start initializes the HTTP server.
 func (g *GRPCAUTH) Start() <-chan bool {
-	log.Printf("GRPCAUTH: %s listening on %d/%d", g.Backend, g.Port, g.SecurePort)
+	log.Printf("GRPCAUTH: %s listening on %d/%d (protocol %q)", g.Backend, g.Port, g.SecurePort, g.ProtocolVersion)
 
 	exited := make(chan bool)
 	proto := "tcp"
@@ -50,7 +66,7 @@ func (g *GRPCAUTH) Start() <-chan bool {
 		}
 
 		s := grpc.NewServer()
-		pb_legacy.RegisterAuthorizationServer(s, g)
+		g.register(s)
 		s.Serve(ln)
 
 		defer ln.Close()
@@ -73,7 +89,7 @@ func (g *GRPCAUTH) Start() <-chan bool {
 		}
 
 		s := grpc.NewServer()
-		pb_legacy.RegisterAuthorizationServer(s, g)
+		g.register(s)
 		s.Serve(ln)
 
 		defer ln.Close()
@@ -84,12 +100,40 @@ func (g *GRPCAUTH) Start() <-chan bool {
 	return exited
 }
 
+// httpAttributes is the subset of the AttributeContext_HttpRequest getters that
+// are common to every version of the ext_authz protocol.
+type httpAttributes interface {
+	GetHeaders() map[string]string
+	GetBody() string
+	GetFragment() string
+	GetHost() string
+	GetPath() string
+	GetQuery() string
+	GetScheme() string
+	GetMethod() string
+}
+
 // Check checks the request object.
 func (g *GRPCAUTH) Check(ctx context.Context, r *pb.CheckRequest) (*pb.CheckResponse, error) {
+	return g.check(r.GetAttributes().GetRequest().GetHttp()).GetResponse(), nil
+}
+
+// grpcAuthV3 serves the v3 ext_authz protocol on behalf of a GRPCAUTH.
+type grpcAuthV3 struct {
+	*GRPCAUTH
+}
+
+// Check checks the v3 request object.
+func (g *grpcAuthV3) Check(ctx context.Context, r *pb_v3.CheckRequest) (*pb_v3.CheckResponse, error) {
+	return g.check(r.GetAttributes().GetRequest().GetHttp()).GetResponseV3(), nil
+}
+
+// check builds the authorization response for a request.
+func (g *GRPCAUTH) check(r httpAttributes) *Response {
 	rs := &Response{}
 
-	rheader := r.GetAttributes().GetRequest().GetHttp().GetHeaders()
-	rbody := r.GetAttributes().GetRequest().GetHttp().GetBody()
+	rheader := r.GetHeaders()
+	rbody := r.GetBody()
 	if len(rbody) > 0 {
 		rheader["body"] = rbody
 	}
@@ -132,11 +176,11 @@ func (g *GRPCAUTH) Check(ctx context.Context, r *pb.CheckRequest) (*pb.CheckResp
 
 	// Parses request URL.
 	url := make(map[string]interface{})
-	url["fragment"] = r.GetAttributes().GetRequest().GetHttp().GetFragment()
-	url["host"] = r.GetAttributes().GetRequest().GetHttp().GetHost()
-	url["path"] = r.GetAttributes().GetRequest().GetHttp().GetPath()
-	url["query"] = r.GetAttributes().GetRequest().GetHttp().GetQuery()
-	url["scheme"] = r.GetAttributes().GetRequest().GetHttp().GetScheme()
+	url["fragment"] = r.GetFragment()
+	url["host"] = r.GetHost()
+	url["path"] = r.GetPath()
+	url["query"] = r.GetQuery()
+	url["scheme"] = r.GetScheme()
 
 	// Parses TLS info.
 	tls := make(map[string]interface{})
@@ -145,9 +189,9 @@ func (g *GRPCAUTH) Check(ctx context.Context, r *pb.CheckRequest) (*pb.CheckResp
 	// Sets request portion of the results body.
 	request := make(map[string]interface{})
 	request["url"] = url
-	request["method"] = r.GetAttributes().GetRequest().GetHttp().GetMethod()
+	request["method"] = r.GetMethod()
 	request["headers"] = headers
-	request["host"] = r.GetAttributes().GetRequest().GetHttp().GetHost()
+	request["host"] = r.GetHost()
 	request["tls"] = tls
 
 	// Sets results body.
@@ -169,7 +213,7 @@ func (g *GRPCAUTH) Check(ctx context.Context, r *pb.CheckRequest) (*pb.CheckResp
 	log.Printf("setting response body: %s", string(body))
 	rs.SetBody(string(body))
 
-	return rs.GetResponse(), nil
+	return rs
 }
 
 // Response constructs an authorization response object.
@@ -256,3 +300,45 @@ func (r *Response) GetResponse() *pb.CheckResponse {
 
 	return rs
 }
+
+// GetResponseV3 returns the v3 gRPC authorization response object. A denied
+// response carries the status, headers, and body through to the client.
+func (r *Response) GetResponseV3() *pb_v3.CheckResponse {
+	headers := make([]*core_v3.HeaderValueOption, 0, len(r.headers))
+	for _, h := range r.headers {
+		headers = append(headers, &core_v3.HeaderValueOption{
+			Header: &core_v3.HeaderValue{
+				Key:   h.Header.Key,
+				Value: h.Header.Value,
+			},
+			Append: h.Append,
+		})
+	}
+
+	rs := &pb_v3.CheckResponse{}
+	switch {
+	// Ok respose.
+	case r.status == http.StatusOK || r.status == 0:
+		rs.Status = &status.Status{Code: int32(code.Code_OK)}
+		rs.HttpResponse = &pb_v3.CheckResponse_OkResponse{
+			OkResponse: &pb_v3.OkHttpResponse{
+				Headers: headers,
+			},
+		}
+
+	// Denied response.
+	default:
+		rs.Status = &status.Status{Code: int32(code.Code_UNAUTHENTICATED)}
+		rs.HttpResponse = &pb_v3.CheckResponse_DeniedResponse{
+			DeniedResponse: &pb_v3.DeniedHttpResponse{
+				Status: &envoy_type_v3.HttpStatus{
+					Code: envoy_type_v3.StatusCode(r.status),
+				},
+				Headers: headers,
+				Body:    r.body,
+			},
+		}
+	}
+
+	return rs
+}
@@ -37,6 +37,8 @@ func main() {
 	dryRun := ka.Flags().Bool("dry-run", envBool("KUBEAPPLY_DRYRUN"), "enable dry-run mode")
 	timeout := ka.Flags().DurationP("timeout", "t", time.Minute,
 		"timeout to wait for each applied YAML phase to become ready")
+	parallel := ka.Flags().IntP("parallel", "p", kubeapply.Parallelism,
+		"how many files of each phase to apply at once")
 	showVersion := ka.Flags().Bool("version", false, "output version information and exit")
 	files := ka.Flags().StringSliceP("filename", "f", nil, "files to apply")
 
@@ -51,6 +53,7 @@ func main() {
 		if len(*files) == 0 {
 			return errors.Errorf("at least one file argument is required")
 		}
+		kubeapply.Parallelism = *parallel
 		return kubeapply.Kubeapply(k8s.NewKubeInfo(*kubeconfig, *context, *namespace), *timeout,
 			*debug, *dryRun, *files...)
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
@@ -18,7 +19,7 @@ func Main() {
 	var st = &cobra.Command{
 		Use:           "kubestatus <kind> [<name>]",
 		Short:         "get and set status of kubernetes resources",
-		Args:          cobra.RangeArgs(1, 2),
+		Args:          cobra.RangeArgs(0, 2),
 		SilenceErrors: true,
 		SilenceUsage:  true,
 	}
@@ -27,8 +28,20 @@ func Main() {
 	fields := st.Flags().StringP("field-selector", "f", "", "field selector")
 	labels := st.Flags().StringP("label-selector", "l", "", "label selector")
 	statusFile := st.Flags().StringP("update", "u", "", "update with new status from file (must be json)")
+	batchFile := st.Flags().StringP("batch", "b", "",
+		"update the statuses in file, a json list of {kind, name, namespace, status} objects (or {kind, name, namespace, event} objects to post Events), and report the result of each")
+	dryRun := st.Flags().Bool("dry-run", false, "show what would be updated without updating anything")
+	rate := st.Flags().Float64("rate", 10, "maximum status updates per second with --batch (0 for no limit)")
+	retries := st.Flags().Int("retries", 5, "how many times to retry an update that conflicts with another writer")
 
 	st.RunE = func(cmd *cobra.Command, args []string) error {
+		if *batchFile != "" {
+			return updateBatch(info, *batchFile, *rate, *retries, *dryRun)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("a kind is required unless --batch is given")
+		}
+
 		var status map[string]interface{}
 
 		if *statusFile != "" {
@@ -77,6 +90,7 @@ func Main() {
 		if err != nil {
 			return err
 		}
+		u := newUpdater(client, *rate, *retries, *dryRun, os.Stdout)
 
 		if name != "" {
 			obj := kates.NewUnstructured(kind, "")
@@ -95,8 +109,11 @@ func Main() {
 				fmt.Printf("  %v\n", obj.Object["status"])
 				return nil
 			} else {
-				obj.Object["status"] = status
-				return client.UpdateStatus(context.TODO(), obj, obj)
+				if *dryRun {
+					fmt.Println("Would update status of", obj.GetKind(), obj.GetName(), "in namespace",
+						obj.GetNamespace())
+				}
+				return u.update(context.TODO(), obj, status, false)
 			}
 		}
 
@@ -115,6 +132,7 @@ func Main() {
 			return err
 		}
 
+		failures := 0
 		for _, obj := range items {
 			if *statusFile == "" {
 				// The user is asking for the status, so print it.
@@ -123,22 +141,22 @@ func Main() {
 				fmt.Printf("  %v\n", obj.Object["status"])
 			} else {
 				// The user is asking for a status update.
-				// log.Debugf doesn't exist.
-				if false {
-					fmt.Println("Updating", obj.GetName(), "in namespace", obj.GetNamespace())
+				if *dryRun {
+					fmt.Println("Would update status of", obj.GetKind(), obj.GetName(), "in namespace",
+						obj.GetNamespace())
 				}
 
-				obj.Object["status"] = status
-				err = client.UpdateStatus(context.TODO(), obj, nil)
+				err = u.update(context.TODO(), obj, status, false)
 				if err != nil {
-					// log.Debugf doesn't exist.
-					if true {
-						log.Printf("error updating resource: %v", err)
-					}
+					failures++
+					log.Printf("error updating resource: %v", err)
 				}
 			}
 		}
 
+		if failures > 0 {
+			return fmt.Errorf("%d of %d status updates failed", failures, len(items))
+		}
 		return nil
 	}
 
@@ -148,3 +166,37 @@ func Main() {
 		os.Exit(1)
 	}
 }
+
+// updateBatch updates the statuses in a --batch file. Updates without a namespace are in the
+// namespace of the flags.
+func updateBatch(info *k8s.KubeInfo, batchFile string, rate float64, retries int, dryRun bool) error {
+	raw, err := ioutil.ReadFile(batchFile)
+	if err != nil {
+		return err
+	}
+	var updates []statusUpdate
+	if err := json.Unmarshal(raw, &updates); err != nil {
+		return err
+	}
+
+	namespace, err := info.Namespace()
+	if err != nil {
+		return err
+	}
+	for i := range updates {
+		if updates[i].Namespace == "" {
+			updates[i].Namespace = namespace
+		}
+	}
+
+	client, err := kates.NewClientFromConfigFlags(info.GetConfigFlags())
+	if err != nil {
+		return err
+	}
+
+	u := newUpdater(client, rate, retries, dryRun, os.Stdout)
+	if failures := u.updateBatch(context.TODO(), updates); failures > 0 {
+		return fmt.Errorf("%d of %d status updates failed", failures, len(updates))
+	}
+	return nil
+}
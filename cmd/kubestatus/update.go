@@ -0,0 +1,222 @@
+package kubestatus
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// A statusUpdate is one status to write, as read from a --batch file.
+type statusUpdate struct {
+	Kind      string                 `json:"kind"`
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Status    map[string]interface{} `json:"status"`
+	// Merge merges the status into the resource's status, for kinds whose status is partly
+	// written by another controller, instead of replacing it.
+	Merge bool `json:"merge,omitempty"`
+	// Event, if set, makes this an Event to post, named Name in Namespace, instead of a status
+	// to write.
+	Event *eventSpec `json:"event,omitempty"`
+}
+
+// An eventSpec is what an Event says about the resource that it's about.
+type eventSpec struct {
+	InvolvedObject map[string]interface{} `json:"involvedObject"`
+	Type           string                 `json:"type"`
+	Reason         string                 `json:"reason"`
+	Message        string                 `json:"message"`
+}
+
+// The results of a status write, as reported in batch mode.
+const (
+	resultOK       = "ok"
+	resultDryRun   = "dry-run"
+	resultConflict = "conflict"
+	resultError    = "error"
+)
+
+// An updateResult reports how a status write went. Batch mode prints one per line, as JSON, so
+// that the caller can tell which writes failed and why.
+type updateResult struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Result    string `json:"result"`
+	Error     string `json:"error,omitempty"`
+}
+
+// A statusClient is the part of the kates client that the updater uses.
+type statusClient interface {
+	Get(ctx context.Context, resource interface{}, target interface{}) error
+	UpdateStatus(ctx context.Context, resource interface{}, target interface{}) error
+	Create(ctx context.Context, resource interface{}, target interface{}) error
+}
+
+// An updater writes statuses, and retries writes that conflict with another writer with
+// exponential backoff. A batch is written at most rate writes per second.
+type updater struct {
+	client  statusClient
+	dryRun  bool
+	retries int
+	backoff time.Duration
+	// rate is the most writes per second that a batch makes; 0 is no limit.
+	rate float64
+	// limit ticks when the next write may go out; it's nil when writes aren't rate limited.
+	limit <-chan time.Time
+	out   io.Writer
+}
+
+func newUpdater(client statusClient, rate float64, retries int, dryRun bool, out io.Writer) *updater {
+	return &updater{
+		client:  client,
+		dryRun:  dryRun,
+		retries: retries,
+		backoff: 100 * time.Millisecond,
+		rate:    rate,
+		out:     out,
+	}
+}
+
+// update writes the status of a resource, or merges it into the resource's status. On a conflict
+// it fetches the resource again, to get its latest resourceVersion and status, and tries again, up
+// to u.retries times.
+func (u *updater) update(ctx context.Context, obj *kates.Unstructured, status map[string]interface{},
+	merge bool) error {
+	backoff := u.backoff
+	for attempt := 0; ; attempt++ {
+		if merge {
+			obj.Object["status"] = mergeStatus(obj.Object["status"], status)
+		} else {
+			obj.Object["status"] = status
+		}
+		if u.dryRun {
+			return nil
+		}
+
+		if err := u.wait(ctx); err != nil {
+			return err
+		}
+
+		err := u.client.UpdateStatus(ctx, obj, obj)
+		if err == nil || !kates.IsConflict(err) || attempt >= u.retries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+
+		if err := u.client.Get(ctx, obj, obj); err != nil {
+			return err
+		}
+	}
+}
+
+// wait waits until the next write may go out.
+func (u *updater) wait(ctx context.Context) error {
+	if u.limit == nil {
+		return nil
+	}
+	select {
+	case <-u.limit:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// postEvent posts an Event. The caller names Events after what they say, so posting one that
+// already exists does nothing.
+func (u *updater) postEvent(ctx context.Context, name, namespace string, event *eventSpec) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	obj := kates.NewUnstructured("Event", "v1")
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.Object["involvedObject"] = event.InvolvedObject
+	obj.Object["type"] = event.Type
+	obj.Object["reason"] = event.Reason
+	obj.Object["message"] = event.Message
+	obj.Object["source"] = map[string]interface{}{"component": "ambassador"}
+	obj.Object["firstTimestamp"] = now
+	obj.Object["lastTimestamp"] = now
+	obj.Object["count"] = 1
+	if u.dryRun {
+		return nil
+	}
+
+	if err := u.wait(ctx); err != nil {
+		return err
+	}
+
+	err := u.client.Create(ctx, obj, obj)
+	if kates.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// updateBatch writes a batch of statuses, reporting the result of each, and returns how many
+// failed. The writes are rate limited to u.rate per second.
+func (u *updater) updateBatch(ctx context.Context, updates []statusUpdate) int {
+	if u.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / u.rate))
+		defer ticker.Stop()
+		u.limit = ticker.C
+		defer func() { u.limit = nil }()
+	}
+
+	enc := json.NewEncoder(u.out)
+	failures := 0
+	for _, su := range updates {
+		result := updateResult{Kind: su.Kind, Name: su.Name, Namespace: su.Namespace, Result: resultOK}
+		if u.dryRun {
+			result.Result = resultDryRun
+		}
+
+		var err error
+		if su.Event != nil {
+			err = u.postEvent(ctx, su.Name, su.Namespace, su.Event)
+		} else {
+			obj := kates.NewUnstructured(su.Kind, "")
+			obj.SetName(su.Name)
+			obj.SetNamespace(su.Namespace)
+			err = u.client.Get(ctx, obj, obj)
+			if err == nil {
+				err = u.update(ctx, obj, su.Status, su.Merge)
+			}
+		}
+		if err != nil {
+			failures++
+			result.Result = resultError
+			if kates.IsConflict(err) {
+				result.Result = resultConflict
+			}
+			result.Error = err.Error()
+		}
+		_ = enc.Encode(result)
+	}
+	return failures
+}
+
+// mergeStatus returns the old status of a resource with the top-level fields of status in place
+// of its own.
+func mergeStatus(old interface{}, status map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	if oldStatus, ok := old.(map[string]interface{}); ok {
+		for k, v := range oldStatus {
+			merged[k] = v
+		}
+	}
+	for k, v := range status {
+		merged[k] = v
+	}
+	return merged
+}
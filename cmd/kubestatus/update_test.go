@@ -0,0 +1,167 @@
+package kubestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// fakeClient fails the first conflicts status updates of each resource with a conflict, and
+// fails every request for the resource named "broken".
+type fakeClient struct {
+	conflicts int
+	gets      int
+	updates   map[string]int
+	written   map[string]interface{}
+	created   map[string]int
+}
+
+func newFakeClient(conflicts int) *fakeClient {
+	return &fakeClient{conflicts: conflicts, updates: make(map[string]int), written: make(map[string]interface{}),
+		created: make(map[string]int)}
+}
+
+func (c *fakeClient) Get(_ context.Context, resource interface{}, _ interface{}) error {
+	c.gets++
+	if resource.(*kates.Unstructured).GetName() == "broken" {
+		return errors.New("broken")
+	}
+	return nil
+}
+
+func (c *fakeClient) UpdateStatus(_ context.Context, resource interface{}, _ interface{}) error {
+	obj := resource.(*kates.Unstructured)
+	c.updates[obj.GetName()]++
+	if c.updates[obj.GetName()] <= c.conflicts {
+		return apierrors.NewConflict(schema.GroupResource{Resource: "mappings"}, obj.GetName(),
+			errors.New("the object has been modified"))
+	}
+	c.written[obj.GetName()] = obj.Object["status"]
+	return nil
+}
+
+// Create fails with AlreadyExists for everything after the first, like the API server.
+func (c *fakeClient) Create(_ context.Context, resource interface{}, _ interface{}) error {
+	obj := resource.(*kates.Unstructured)
+	c.created[obj.GetName()]++
+	if c.created[obj.GetName()] > 1 {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "events"}, obj.GetName())
+	}
+	return nil
+}
+
+func TestUpdateRetriesConflicts(t *testing.T) {
+	client := newFakeClient(2)
+	u := newUpdater(client, 0, 5, false, &bytes.Buffer{})
+	u.backoff = 0
+
+	obj := kates.NewUnstructured("Mapping", "")
+	obj.SetName("quote")
+	status := map[string]interface{}{"state": "Running"}
+	require.NoError(t, u.update(context.Background(), obj, status, false))
+	assert.Equal(t, 3, client.updates["quote"])
+	// Each conflict fetches the resource again.
+	assert.Equal(t, 2, client.gets)
+	assert.Equal(t, status, client.written["quote"])
+
+	client = newFakeClient(10)
+	u.client = client
+	err := u.update(context.Background(), obj, status, false)
+	assert.True(t, kates.IsConflict(err))
+	assert.Equal(t, 6, client.updates["quote"])
+}
+
+func TestUpdateBatch(t *testing.T) {
+	client := newFakeClient(0)
+	out := &bytes.Buffer{}
+	u := newUpdater(client, 1000, 5, false, out)
+
+	failures := u.updateBatch(context.Background(), []statusUpdate{
+		{Kind: "Mapping", Name: "quote", Namespace: "default", Status: map[string]interface{}{"state": "Running"}},
+		{Kind: "Mapping", Name: "broken", Namespace: "default"},
+	})
+	assert.Equal(t, 1, failures)
+
+	var results []updateResult
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var result updateResult
+		require.NoError(t, json.Unmarshal([]byte(line), &result))
+		results = append(results, result)
+	}
+	assert.Equal(t, []updateResult{
+		{Kind: "Mapping", Name: "quote", Namespace: "default", Result: resultOK},
+		{Kind: "Mapping", Name: "broken", Namespace: "default", Result: resultError, Error: "broken"},
+	}, results)
+}
+
+// Only batches are rate limited: a single update doesn't wait for the rate limit, however low.
+func TestUpdateRateLimitsBatches(t *testing.T) {
+	client := newFakeClient(0)
+	u := newUpdater(client, 0.001, 5, false, &bytes.Buffer{})
+
+	obj := kates.NewUnstructured("Mapping", "")
+	obj.SetName("quote")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.NoError(t, u.update(ctx, obj, map[string]interface{}{"state": "Running"}, false))
+	assert.Equal(t, 1, client.updates["quote"])
+
+	// A batch waits for the first tick, which is 1000 seconds away.
+	failures := u.updateBatch(ctx, []statusUpdate{
+		{Kind: "Mapping", Name: "quote", Namespace: "default", Status: map[string]interface{}{"state": "Ready"}},
+	})
+	assert.Equal(t, 1, failures)
+	assert.Equal(t, 1, client.updates["quote"])
+	assert.Nil(t, u.limit)
+}
+
+func TestUpdateDryRun(t *testing.T) {
+	client := newFakeClient(0)
+	out := &bytes.Buffer{}
+	u := newUpdater(client, 0, 5, true, out)
+
+	failures := u.updateBatch(context.Background(), []statusUpdate{
+		{Kind: "Mapping", Name: "quote", Namespace: "default", Status: map[string]interface{}{"state": "Running"}},
+	})
+	assert.Equal(t, 0, failures)
+	assert.Empty(t, client.updates)
+	assert.Contains(t, out.String(), `"result":"dry-run"`)
+}
+
+func TestMergeStatus(t *testing.T) {
+	old := map[string]interface{}{"state": "Ready", "conditions": []interface{}{"old"}}
+	assert.Equal(t, map[string]interface{}{"state": "Ready", "conditions": []interface{}{"new"}},
+		mergeStatus(old, map[string]interface{}{"conditions": []interface{}{"new"}}))
+	assert.Equal(t, map[string]interface{}{"state": "Running"},
+		mergeStatus(nil, map[string]interface{}{"state": "Running"}))
+}
+
+func TestUpdateBatchEvents(t *testing.T) {
+	client := newFakeClient(0)
+	out := &bytes.Buffer{}
+	u := newUpdater(client, 0, 5, false, out)
+
+	event := statusUpdate{Kind: "Event", Name: "quote.1234", Namespace: "default", Event: &eventSpec{
+		InvolvedObject: map[string]interface{}{"kind": "Ingress", "name": "quote", "namespace": "default"},
+		Type:           "Warning",
+		Reason:         "UnsupportedAnnotation",
+		Message:        "nginx.ingress.kubernetes.io/cors: Ambassador has no equivalent of this annotation",
+	}}
+	// Posting the same Event again is fine.
+	failures := u.updateBatch(context.Background(), []statusUpdate{event, event})
+	assert.Equal(t, 0, failures)
+	assert.Equal(t, 2, client.created["quote.1234"])
+	assert.Empty(t, client.gets)
+	assert.Empty(t, client.updates)
+}
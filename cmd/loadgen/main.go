@@ -0,0 +1,224 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/loadgen"
+)
+
+// Main generates synthetic Mappings, Hosts, and the Services and Endpoints that they route to,
+// and churns them at a steady rate, for benchmarking how the control plane scales. It creates
+// them in the cluster, or writes them as snapshots for diagd.
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "loadgen",
+		Short:         "generate synthetic Ambassador configuration, for benchmarking",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	var opts loadgen.Options
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&opts.Namespace, "namespace", "n", "default", "the namespace of the generated resources")
+	flags.StringVar(&opts.AmbassadorID, "ambassador-id", "", "the ambassador_id of the generated Ambassador resources")
+	flags.IntVar(&opts.Services, "services", 10, "how many Services, each with its Endpoints, to generate")
+	flags.IntVar(&opts.Endpoints, "endpoints", 3, "how many addresses each Service's Endpoints have")
+	flags.IntVar(&opts.Mappings, "mappings", 100, "how many Mappings to generate")
+	flags.IntVar(&opts.Hosts, "hosts", 0, "how many Hosts to generate")
+	flags.Int64Var(&opts.Seed, "seed", 1, "the random seed; the same seed generates, and churns, the same resources")
+
+	cmd.AddCommand(applyCommand(&opts), deleteCommand(&opts), snapshotCommand(&opts))
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func applyCommand(opts *loadgen.Options) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "apply",
+		Short: "create the generated resources in the cluster, then churn them",
+		Args:  cobra.NoArgs,
+	}
+
+	churn := cmd.Flags().Float64("churn", 0, "how many resources to change per second; 0 exits once they're created")
+	duration := cmd.Flags().Duration("duration", 0, "how long to churn for; 0 churns until interrupted")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		g, err := loadgen.New(*opts)
+		if err != nil {
+			return err
+		}
+		client, err := kates.NewClient(kates.ClientOptions{})
+		if err != nil {
+			return err
+		}
+		ctx, cancel := interruptible()
+		defer cancel()
+
+		start := time.Now()
+		objects := g.Objects()
+		for _, obj := range objects {
+			if err := apply(ctx, client, obj); err != nil {
+				return err
+			}
+		}
+		log.Printf("applied %d resources in %v", len(objects), time.Since(start))
+
+		if *churn <= 0 {
+			return nil
+		}
+		if *duration > 0 {
+			var cancelDuration context.CancelFunc
+			ctx, cancelDuration = context.WithTimeout(ctx, *duration)
+			defer cancelDuration()
+		}
+
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / *churn))
+		defer ticker.Stop()
+		report := time.NewTicker(10 * time.Second)
+		defer report.Stop()
+		changes := 0
+		for {
+			select {
+			case <-ticker.C:
+				obj := g.Churn()
+				if obj == nil {
+					return fmt.Errorf("there's nothing to churn")
+				}
+				if err := apply(ctx, client, obj); err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					return err
+				}
+				changes++
+			case <-report.C:
+				log.Printf("%d changes", changes)
+			case <-ctx.Done():
+				log.Printf("%d changes in all", changes)
+				return nil
+			}
+		}
+	}
+
+	return cmd
+}
+
+// apply updates the object in the cluster, or creates it if it isn't there. It's updated with
+// what the cluster returns, so that the next update of it is against the right resourceVersion.
+func apply(ctx context.Context, client *kates.Client, obj kates.Object) error {
+	err := client.Update(ctx, obj, obj)
+	if kates.IsNotFound(err) {
+		err = client.Create(ctx, obj, obj)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s.%s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), obj.GetNamespace(), err)
+	}
+	return nil
+}
+
+func deleteCommand(opts *loadgen.Options) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "delete",
+		Short: "delete the resources that apply, with the same options, created",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		g, err := loadgen.New(*opts)
+		if err != nil {
+			return err
+		}
+		client, err := kates.NewClient(kates.ClientOptions{})
+		if err != nil {
+			return err
+		}
+		ctx, cancel := interruptible()
+		defer cancel()
+
+		deleted := 0
+		for _, obj := range g.Objects() {
+			err := client.Delete(ctx, obj, nil)
+			if kates.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			deleted++
+		}
+		log.Printf("deleted %d resources", deleted)
+		return nil
+	}
+
+	return cmd
+}
+
+func snapshotCommand(opts *loadgen.Options) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "snapshot FILE",
+		Short: "write the generated resources as a snapshot, as the entrypoint hands it to diagd",
+		Long: "Write the generated resources as a snapshot, as the entrypoint hands it to diagd. With --steps, " +
+			"also write that many more snapshots, each --churn changes on from the one before, numbered " +
+			"after the first: with FILE snapshot.json, snapshot-1.json, snapshot-2.json, and so on.",
+		Args: cobra.ExactArgs(1),
+	}
+
+	steps := cmd.Flags().Int("steps", 0, "how many churned snapshots to write after the first")
+	churn := cmd.Flags().Int("churn", 1, "how many resources to change between snapshots")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		g, err := loadgen.New(*opts)
+		if err != nil {
+			return err
+		}
+
+		path := args[0]
+		ext := filepath.Ext(path)
+		for step := 0; step <= *steps; step++ {
+			if step > 0 {
+				for i := 0; i < *churn; i++ {
+					if g.Churn() == nil {
+						return fmt.Errorf("there's nothing to churn")
+					}
+				}
+				path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(args[0], ext), step, ext)
+			}
+			snapshot, err := g.Snapshot()
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path, append(snapshot, '\n'), 0644); err != nil {
+				return err
+			}
+		}
+		log.Printf("wrote %d snapshots", *steps+1)
+		return nil
+	}
+
+	return cmd
+}
+
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+		<-ch
+		cancel()
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+	rl "github.com/datawire/ambassador/pkg/ratelimit"
+)
+
+// The policies struct is the snapshot that the RateLimitPolicy watch fills in.
+type policies struct {
+	RateLimitPolicies []*amb.RateLimitPolicy
+}
+
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "ratelimit",
+		Short:         "run a rate limit service for the limits in RateLimitPolicy resources",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	listen := cmd.Flags().String("listen", ":8081", "address to serve gRPC on")
+	redis := cmd.Flags().String("redis", "", "address of a Redis server to keep counters in; if unset, counters are kept in memory. $REDIS_PASSWORD, if set, is its password")
+	redisTLS := cmd.Flags().Bool("redis-tls", false, "connect to the Redis server over TLS")
+	namespace := cmd.Flags().StringP("namespace", "n", "", "only watch RateLimitPolicies in this namespace")
+	headers := cmd.Flags().Bool("x-ratelimit-headers", false, "ask Envoy to send X-RateLimit headers to clients")
+	statsListen := cmd.Flags().String("stats-listen", "", "address to serve per-limit stats, as JSON at /stats, on; if unset, stats aren't served")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var store rl.Store
+		if *redis != "" {
+			options := rl.RedisOptions{Password: os.Getenv("REDIS_PASSWORD")}
+			if *redisTLS {
+				options.TLS = &tls.Config{}
+			}
+			store = rl.NewRedisStore(*redis, options)
+		} else {
+			store = rl.NewMemoryStore()
+		}
+
+		service := rl.NewService(store)
+		service.SendXRateLimitHeaders = *headers
+
+		client, err := kates.NewClient(kates.ClientOptions{})
+		if err != nil {
+			return err
+		}
+
+		acc := client.Watch(ctx, kates.Query{Namespace: *namespace, Name: "RateLimitPolicies", Kind: "RateLimitPolicy"})
+
+		go func() {
+			snapshot := &policies{}
+			for {
+				select {
+				case <-acc.Changed():
+					if !acc.Update(snapshot) {
+						continue
+					}
+					if err := service.SetPolicies(snapshot.RateLimitPolicies); err != nil {
+						log.Printf("keeping the old limits: %v", err)
+					} else {
+						log.Printf("loaded %d RateLimitPolicies", len(snapshot.RateLimitPolicies))
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		if *statsListen != "" {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if err := json.NewEncoder(w).Encode(service.Stats()); err != nil {
+					log.Printf("stats: %v", err)
+				}
+			})
+			go func() {
+				log.Printf("serving stats on %s", *statsListen)
+				if err := http.ListenAndServe(*statsListen, mux); err != nil {
+					log.Printf("stats: %v", err)
+				}
+			}()
+		}
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return err
+		}
+
+		server := grpc.NewServer()
+		service.Register(server)
+
+		go func() {
+			ch := make(chan os.Signal, 1)
+			signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+			<-ch
+			server.GracefulStop()
+		}()
+
+		log.Printf("rate limit service listening on %s", *listen)
+		return server.Serve(listener)
+	}
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
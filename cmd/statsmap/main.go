@@ -0,0 +1,78 @@
+package statsmap
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/pkg/statsmap"
+)
+
+// Main writes a file that says which Envoy statistics belong to which Mapping and host, and a
+// Grafana dashboard of the request rate, latency and errors of each Mapping, from the
+// configuration that diagd is running now.
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "statsmap",
+		Short:         "write which statistics belong to which Mapping, and a Grafana dashboard of them",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	diagURL := cmd.Flags().String("diag", "http://127.0.0.1:8877", "URL of diagd")
+	input := cmd.Flags().String("input", "", "read diagd's diagnostics JSON from this file instead of fetching it")
+	outDir := cmd.Flags().StringP("output", "o", ".", "the directory to write stats-mappings.json and grafana-dashboard.json to")
+	title := cmd.Flags().String("title", "Ambassador Mappings", "the title of the dashboard")
+	datasource := cmd.Flags().String("datasource", "Prometheus", "the Grafana datasource of the dashboard's graphs")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if *input != "" {
+			data, err = ioutil.ReadFile(*input)
+		} else {
+			data, err = statsmap.FetchDiag(context.Background(), *diagURL)
+		}
+		if err != nil {
+			return err
+		}
+
+		entries, err := statsmap.ParseDiag(data)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			log.Print("no Mapping has per-Mapping statistics; set stats_name on Mappings, or per_mapping_stats in the ambassador Module")
+		}
+		if entries == nil {
+			entries = []statsmap.Entry{}
+		}
+
+		if err := writeJSON(filepath.Join(*outDir, "stats-mappings.json"), entries); err != nil {
+			return err
+		}
+		if err := writeJSON(filepath.Join(*outDir, "grafana-dashboard.json"), statsmap.NewDashboard(*title, *datasource, entries)); err != nil {
+			return err
+		}
+		log.Printf("wrote %d stat prefixes, and a dashboard, to %s", len(entries), *outDir)
+		return nil
+	}
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeJSON writes v to path as indented JSON.
+func writeJSON(path string, v interface{}) error {
+	bytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(bytes, '\n'), 0644)
+}
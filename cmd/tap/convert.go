@@ -0,0 +1,93 @@
+package tap
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+	"github.com/datawire/ambassador/pkg/tapconvert"
+)
+
+// harCommand converts tap files to a HAR file.  Buffered HTTP traces
+// don't say when they happened, so each one is dated with its file's
+// modification time.
+func harCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "har FILE...",
+		Short: "convert the HTTP traces in tap files to a HAR file",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	output := cmd.Flags().StringP("output", "o", "-", "where to write the HAR file")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		harlog := tapconvert.NewHAR(Version)
+		for _, path := range args {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			traces, err := tapconvert.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, trace := range traces {
+				if buffered := trace.GetHttpBufferedTrace(); buffered != nil {
+					harlog.Add(info.ModTime(), buffered)
+				}
+			}
+		}
+
+		return writeOutput(*output, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(harlog)
+		})
+	}
+	return cmd
+}
+
+// pcapngCommand converts tap files to a pcapng file.
+func pcapngCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pcapng FILE...",
+		Short: "convert the socket traces in tap files to a pcapng file",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	output := cmd.Flags().StringP("output", "o", "-", "where to write the pcapng file")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var traces []*tapdata.TraceWrapper
+		for _, path := range args {
+			more, err := tapconvert.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			traces = append(traces, more...)
+		}
+
+		return writeOutput(*output, func(w io.Writer) error {
+			return tapconvert.WritePcapng(w, traces)
+		})
+	}
+	return cmd
+}
+
+// writeOutput calls write with the named file, or with stdout if path is "-".
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,178 @@
+package tap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	admin "github.com/datawire/ambassador/pkg/api/envoy/admin/v2alpha"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+	tapcfg "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+	"github.com/datawire/ambassador/pkg/tapconvert"
+)
+
+// Version is inserted at build using --ldflags -X
+var Version = "(unknown version)"
+
+// Main taps the requests for a Mapping through Envoy's admin /tap endpoint, which needs the
+// tap filter that the Ambassador Module's tap_admin setting adds. The tap lasts as long as
+// the request to /tap does, so Envoy tears it down when we exit. Its har and pcapng
+// subcommands convert the files that taps leave behind instead.
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "tap MAPPING",
+		Short:         "show the requests to a Mapping, and their responses, as they happen",
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	namespace := cmd.Flags().StringP("namespace", "n", "default", "the Mapping's namespace")
+	adminURL := cmd.Flags().String("admin", "http://127.0.0.1:8001", "URL of Envoy's admin interface, or of Ambassador's tap proxy, http://HOST:8877/ambassador/v0")
+	token := cmd.Flags().String("token", "", "bearer token for Ambassador's tap proxy")
+	configID := cmd.Flags().String("config-id", "ambassador", "the tap filter's admin config_id")
+	maxBytes := cmd.Flags().Uint32("max-bytes", 1024, "how much of each body to show")
+	har := cmd.Flags().Bool("har", false, "write a HAR file to stdout on exit, instead of printing requests as they happen")
+
+	cmd.AddCommand(harCommand(), pcapngCommand())
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			ch := make(chan os.Signal, 1)
+			signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+			<-ch
+			cancel()
+		}()
+
+		client, err := kates.NewClient(kates.ClientOptions{})
+		if err != nil {
+			return err
+		}
+
+		mapping := &amb.Mapping{
+			TypeMeta:   kates.TypeMeta{Kind: "Mapping"},
+			ObjectMeta: kates.ObjectMeta{Name: args[0], Namespace: *namespace},
+		}
+		if err := client.Get(ctx, mapping, mapping); err != nil {
+			return err
+		}
+
+		match, err := mappingMatch(mapping)
+		if err != nil {
+			return err
+		}
+
+		request, err := protojson.Marshal(&admin.TapRequest{
+			ConfigId: *configID,
+			TapConfig: &tapcfg.TapConfig{
+				MatchConfig: match,
+				OutputConfig: &tapcfg.OutputConfig{
+					Sinks: []*tapcfg.OutputSink{{
+						Format:         tapcfg.OutputSink_JSON_BODY_AS_STRING,
+						OutputSinkType: &tapcfg.OutputSink_StreamingAdmin{StreamingAdmin: &tapcfg.StreamingAdminSink{}},
+					}},
+					MaxBufferedRxBytes: &wrappers.UInt32Value{Value: *maxBytes},
+					MaxBufferedTxBytes: &wrappers.UInt32Value{Value: *maxBytes},
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		var handle func(time.Time, *tapdata.HttpBufferedTrace)
+		var harlog *tapconvert.HAR
+		if *har {
+			harlog = tapconvert.NewHAR(Version)
+			handle = harlog.Add
+			log.Printf("tapping Mapping %s.%s; interrupt to write the HAR file", args[0], *namespace)
+		} else {
+			handle = func(received time.Time, trace *tapdata.HttpBufferedTrace) {
+				printTrace(os.Stdout, received, trace)
+			}
+			log.Printf("tapping Mapping %s.%s; interrupt to stop", args[0], *namespace)
+		}
+
+		err = streamTaps(ctx, strings.TrimSuffix(*adminURL, "/")+"/tap", *token, request, handle)
+
+		if harlog != nil {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if eerr := encoder.Encode(harlog); eerr != nil && err == nil {
+				err = eerr
+			}
+		}
+		return err
+	}
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// streamTaps POSTs a tap request to Envoy's admin /tap endpoint, or to Ambassador's proxy for
+// it if there's a token, and hands every trace that Envoy streams back to handle, until ctx is
+// done.
+func streamTaps(ctx context.Context, url, token string, request []byte, handle func(time.Time, *tapdata.HttpBufferedTrace)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, bytes.TrimSpace(body))
+	}
+
+	// Envoy sends each trace as a JSON object, with nothing between them.
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		trace := &tapdata.TraceWrapper{}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, trace); err != nil {
+			return err
+		}
+		if buffered := trace.GetHttpBufferedTrace(); buffered != nil {
+			handle(time.Now(), buffered)
+		}
+	}
+}
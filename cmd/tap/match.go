@@ -0,0 +1,90 @@
+package tap
+
+import (
+	"fmt"
+	"sort"
+
+	route "github.com/datawire/ambassador/pkg/api/envoy/api/v2/route"
+	tapcfg "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+	matcher "github.com/datawire/ambassador/pkg/api/envoy/type/matcher"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+// mappingMatch builds a tap match predicate for the requests that a Mapping routes, the same
+// way diagd does for a TapPolicy: the tap filter can't be configured per route, so the
+// Mapping's route match becomes a match on the :path header, along with its other header
+// matches.
+func mappingMatch(mapping *amb.Mapping) (*tapcfg.MatchPredicate, error) {
+	spec := mapping.Spec
+	if spec.Prefix == "" {
+		return nil, fmt.Errorf("Mapping %s.%s has no prefix", mapping.GetName(), mapping.GetNamespace())
+	}
+
+	var path *route.HeaderMatcher
+	switch {
+	case spec.PrefixRegex:
+		path = regexHeader(":path", spec.Prefix)
+	case spec.PrefixExact:
+		path = &route.HeaderMatcher{Name: ":path", HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: spec.Prefix}}
+	default:
+		path = &route.HeaderMatcher{Name: ":path", HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{PrefixMatch: spec.Prefix}}
+	}
+	headers := []*route.HeaderMatcher{path}
+
+	for _, name := range sortedKeys(spec.Headers) {
+		value := spec.Headers[name]
+		if value.String != nil {
+			headers = append(headers, &route.HeaderMatcher{Name: name, HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: *value.String}})
+		} else {
+			headers = append(headers, &route.HeaderMatcher{Name: name, HeaderMatchSpecifier: &route.HeaderMatcher_PresentMatch{PresentMatch: true}})
+		}
+	}
+	for _, name := range sortedKeys(spec.RegexHeaders) {
+		if value := spec.RegexHeaders[name]; value.String != nil {
+			headers = append(headers, regexHeader(name, *value.String))
+		}
+	}
+
+	if spec.Host != "" {
+		if spec.HostRegex {
+			headers = append(headers, regexHeader(":authority", spec.Host))
+		} else {
+			headers = append(headers, &route.HeaderMatcher{Name: ":authority", HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: spec.Host}})
+		}
+	}
+
+	if spec.Method != "" {
+		if spec.MethodRegex {
+			headers = append(headers, regexHeader(":method", spec.Method))
+		} else {
+			headers = append(headers, &route.HeaderMatcher{Name: ":method", HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: spec.Method}})
+		}
+	}
+
+	return &tapcfg.MatchPredicate{
+		Rule: &tapcfg.MatchPredicate_HttpRequestHeadersMatch{
+			HttpRequestHeadersMatch: &tapcfg.HttpHeadersMatch{Headers: headers},
+		},
+	}, nil
+}
+
+func regexHeader(name, regex string) *route.HeaderMatcher {
+	return &route.HeaderMatcher{
+		Name: name,
+		HeaderMatchSpecifier: &route.HeaderMatcher_SafeRegexMatch{
+			SafeRegexMatch: &matcher.RegexMatcher{
+				EngineType: &matcher.RegexMatcher_GoogleRe2{GoogleRe2: &matcher.RegexMatcher_GoogleRE2{}},
+				Regex:      regex,
+			},
+		},
+	}
+}
+
+func sortedKeys(m map[string]amb.BoolOrString) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package tap
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+	"github.com/datawire/ambassador/pkg/tapconvert"
+)
+
+// printTrace writes a request and its response for a person to read, curl -v style.
+func printTrace(w io.Writer, received time.Time, trace *tapdata.HttpBufferedTrace) {
+	req := trace.GetRequest()
+	resp := trace.GetResponse()
+
+	fmt.Fprintf(w, "--- %s\n", received.Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "> %s %s\n", tapconvert.Header(req.GetHeaders(), ":method"), tapconvert.Header(req.GetHeaders(), ":path"))
+	printMessage(w, ">", req)
+	fmt.Fprintf(w, "< %s\n", tapconvert.Header(resp.GetHeaders(), ":status"))
+	printMessage(w, "<", resp)
+	fmt.Fprintln(w)
+}
+
+func printMessage(w io.Writer, prefix string, msg *tapdata.HttpBufferedTrace_Message) {
+	for _, h := range msg.GetHeaders() {
+		if !strings.HasPrefix(h.GetKey(), ":") {
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, h.GetKey(), h.GetValue())
+		}
+	}
+	fmt.Fprintln(w, prefix)
+
+	text, encoded := tapconvert.BodyText(msg.GetBody())
+	if text != "" {
+		if encoded {
+			fmt.Fprintln(w, "[base64]")
+		}
+		fmt.Fprintln(w, text)
+	}
+	if msg.GetBody().GetTruncated() {
+		fmt.Fprintln(w, "[truncated]")
+	}
+	for _, h := range msg.GetTrailers() {
+		fmt.Fprintf(w, "%s %s: %s\n", prefix, h.GetKey(), h.GetValue())
+	}
+}
@@ -0,0 +1,142 @@
+package tap
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/tapconvert"
+)
+
+func TestMappingMatch(t *testing.T) {
+	value := "v1"
+	present := true
+	mapping := &amb.Mapping{Spec: amb.MappingSpec{
+		Prefix:       "/qotm/",
+		Host:         "quote.example.com",
+		Method:       "GET",
+		Headers:      map[string]amb.BoolOrString{"x-version": {String: &value}, "x-debug": {Bool: &present}},
+		RegexHeaders: map[string]amb.BoolOrString{"x-user": {String: &value}},
+	}}
+
+	match, err := mappingMatch(mapping)
+	require.NoError(t, err)
+
+	out, err := protojson.Marshal(match)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"httpRequestHeadersMatch": {"headers": [
+			{"name": ":path", "prefixMatch": "/qotm/"},
+			{"name": "x-debug", "presentMatch": true},
+			{"name": "x-version", "exactMatch": "v1"},
+			{"name": "x-user", "safeRegexMatch": {"googleRe2": {}, "regex": "v1"}},
+			{"name": ":authority", "exactMatch": "quote.example.com"},
+			{"name": ":method", "exactMatch": "GET"}
+		]}
+	}`, string(out))
+
+	mapping = &amb.Mapping{Spec: amb.MappingSpec{Prefix: "/qotm/[0-9]+", PrefixRegex: true}}
+	match, err = mappingMatch(mapping)
+	require.NoError(t, err)
+	out, err = protojson.Marshal(match)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"httpRequestHeadersMatch": {"headers": [
+			{"name": ":path", "safeRegexMatch": {"googleRe2": {}, "regex": "/qotm/[0-9]+"}}
+		]}
+	}`, string(out))
+
+	_, err = mappingMatch(&amb.Mapping{})
+	assert.Error(t, err)
+}
+
+func testTrace() *tapdata.HttpBufferedTrace {
+	return &tapdata.HttpBufferedTrace{
+		Request: &tapdata.HttpBufferedTrace_Message{
+			Headers: []*core.HeaderValue{
+				{Key: ":method", Value: "POST"},
+				{Key: ":path", Value: "/qotm/quote?n=5"},
+				{Key: ":authority", Value: "quote.example.com"},
+				{Key: "x-forwarded-proto", Value: "https"},
+				{Key: "content-type", Value: "text/plain"},
+			},
+			Body: &tapdata.Body{BodyType: &tapdata.Body_AsString{AsString: "hi"}},
+		},
+		Response: &tapdata.HttpBufferedTrace_Message{
+			Headers: []*core.HeaderValue{
+				{Key: ":status", Value: "404"},
+				{Key: "content-type", Value: "application/json"},
+			},
+			Body: &tapdata.Body{BodyType: &tapdata.Body_AsBytes{AsBytes: []byte(`{"error":`)}, Truncated: true},
+		},
+	}
+}
+
+func TestPrintTrace(t *testing.T) {
+	var out bytes.Buffer
+	printTrace(&out, time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC), testTrace())
+	assert.Equal(t, `--- 2020-09-01T12:00:00Z
+> POST /qotm/quote?n=5
+> x-forwarded-proto: https
+> content-type: text/plain
+>
+hi
+< 404
+< content-type: application/json
+<
+[base64]
+eyJlcnJvciI6
+[truncated]
+
+`, out.String())
+}
+
+func TestStreamTaps(t *testing.T) {
+	trace, err := protojson.Marshal(&tapdata.TraceWrapper{
+		Trace: &tapdata.TraceWrapper_HttpBufferedTrace{HttpBufferedTrace: testTrace()},
+	})
+	require.NoError(t, err)
+
+	requests := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests <- body
+		// Two traces, back to back.
+		w.Write(trace)
+		w.Write(trace)
+	}))
+	defer srv.Close()
+
+	var traces []*tapdata.HttpBufferedTrace
+	err = streamTaps(context.Background(), srv.URL+"/tap", "s3cret", []byte(`{"config_id":"ambassador"}`),
+		func(_ time.Time, trace *tapdata.HttpBufferedTrace) { traces = append(traces, trace) })
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"config_id":"ambassador"}`, string(<-requests))
+	require.Len(t, traces, 2)
+	assert.Equal(t, "/qotm/quote?n=5", tapconvert.Header(traces[1].GetRequest().GetHeaders(), ":path"))
+}
+
+func TestStreamTapsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unknown config id 'ambassador'. No extension has registered with this id.", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	err := streamTaps(context.Background(), srv.URL+"/tap", "", nil, func(time.Time, *tapdata.HttpBufferedTrace) {})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unknown config id")
+}
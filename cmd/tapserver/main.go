@@ -0,0 +1,64 @@
+package tapserver
+
+import (
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/datawire/ambassador/pkg/tapserver"
+)
+
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "tapserver",
+		Short:         "run a tap collector that stores the taps Envoy streams to it",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	listen := cmd.Flags().String("listen", ":8006", "address to serve gRPC on")
+	storage := cmd.Flags().String("storage", "/tmp/ambassador-tap", "where to store taps: a directory, stdout:, or s3://bucket/prefix")
+	redaction := cmd.Flags().String("redaction", "", "YAML file of rules for what to redact from taps; if unset, credential headers are redacted")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		backend, err := tapserver.NewBackend(*storage)
+		if err != nil {
+			return err
+		}
+
+		redactor, err := tapserver.LoadRedactor(*redaction)
+		if err != nil {
+			return err
+		}
+
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return err
+		}
+
+		server := grpc.NewServer()
+		service := tapserver.NewServer(backend)
+		service.Redactor = redactor
+		service.Register(server)
+
+		go func() {
+			ch := make(chan os.Signal, 1)
+			signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+			<-ch
+			server.GracefulStop()
+		}()
+
+		log.Printf("tap collector listening on %s, storing to %s", *listen, *storage)
+		return server.Serve(listener)
+	}
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
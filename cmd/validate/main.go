@@ -0,0 +1,85 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/pkg/validate"
+)
+
+// Main checks a directory of Ambassador's resources, without a cluster, prints their problems,
+// and exits 1 if there are any.
+func Main() {
+	var cmd = &cobra.Command{
+		Use:           "validate DIR",
+		Short:         "check the Ambassador resources in a directory, without a cluster",
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	crds := cmd.Flags().String("crds", crdFile(), "the file with Ambassador's CRDs")
+	namespace := cmd.Flags().String("namespace", "default", "the namespace of resources that don't have one")
+	ambassadorID := cmd.Flags().String("ambassador-id", ambassadorID(), "check the resources for the Ambassador with this ambassador_id")
+	asJSON := cmd.Flags().Bool("json", false, "print the problems as JSON")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *crds == "" {
+			return fmt.Errorf("can't find Ambassador's CRDs; use --crds")
+		}
+		problems, err := validate.Validate(context.Background(), args[0], *crds, *namespace, *ambassadorID)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if problems == nil {
+				problems = []validate.Problem{}
+			}
+			if err := encoder.Encode(problems); err != nil {
+				return err
+			}
+		} else {
+			for _, problem := range problems {
+				fmt.Println(problem)
+			}
+		}
+
+		if len(problems) > 0 {
+			return fmt.Errorf("found %d problems in %s", len(problems), args[0])
+		}
+		log.Printf("%s has no problems", args[0])
+		return nil
+	}
+
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// crdFile returns where Ambassador's CRDs are: in the image, or in a checkout of Ambassador.
+func crdFile() string {
+	for _, candidate := range []string{
+		"/opt/ambassador/etc/crds.yaml",
+		"docs/yaml/ambassador/ambassador-crds.yaml",
+	} {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func ambassadorID() string {
+	if id := os.Getenv("AMBASSADOR_ID"); id != "" {
+		return id
+	}
+	return "default"
+}
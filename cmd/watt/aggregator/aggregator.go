@@ -189,7 +189,7 @@ func (a *Aggregator) updateConsulResources(event thingconsul.ConsulEvent) {
 	a.resourcesMu.Lock()
 	defer a.resourcesMu.Unlock()
 	a.ids[event.WatchId] = true
-	a.consulEndpoints[event.Endpoints.Service] = event.Endpoints
+	a.consulEndpoints[event.Endpoints.Key()] = event.Endpoints
 }
 
 func (a *Aggregator) setKubernetesResources(event thingkube.K8sEvent) {
@@ -187,6 +187,7 @@ func TestAggregatorBootstrap(t *testing.T) {
 	iso.aggregator.ConsulEvents <- thingconsul.ConsulEvent{
 		WATCH.WatchId(),
 		consulwatch.Endpoints{
+			Id:      "dc1",
 			Service: "bar",
 			Endpoints: []consulwatch.Endpoint{
 				{
@@ -204,7 +205,7 @@ func TestAggregatorBootstrap(t *testing.T) {
 		if err != nil {
 			return false
 		}
-		_, ok := s.Consul.Endpoints["bar"]
+		_, ok := s.Consul.Endpoints["bar-dc1"]
 		return ok
 	})
 }
@@ -58,7 +58,7 @@ func (m *ConsulWatchMaker) MakeConsulWatch(spec watchapi.ConsulWatchSpec) (*supe
 	worker := &supervisor.Worker{
 		Name: fmt.Sprintf("consul:%s", spec.WatchId()),
 		Work: func(p *supervisor.Process) error {
-			w, err := consulwatch.New(consul, spec.Datacenter, spec.ServiceName, true)
+			w, err := consulwatch.NewFiltered(consul, spec.Datacenter, spec.ServiceName, spec.Filter, true)
 			if err != nil {
 				p.Logf("failed to setup new consul watch %v", err)
 				return err
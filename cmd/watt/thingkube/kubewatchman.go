@@ -36,10 +36,16 @@ func (m *KubernetesWatchMaker) MakeKubernetesWatch(spec watchapi.KubernetesWatch
 	var worker *supervisor.Worker
 	var err error
 
+	// The supervisor retries a failed worker every few seconds, forever, which floods the
+	// logs when the watched kind's CRD isn't installed or RBAC forbids watching it. So
+	// failures to set up the watch go through a circuit breaker instead, which logs once
+	// and backs off.
+	breaker := k8s.NewCircuitBreaker(fmt.Sprintf("kubernetes:%s", spec.WatchId()), nil)
+
 	worker = &supervisor.Worker{
 		Name: fmt.Sprintf("kubernetes:%s", spec.WatchId()),
 		Work: func(p *supervisor.Process) error {
-			watcher := m.kubeAPI.Watcher()
+			var watcher *k8s.Watcher
 			watchFunc := func(watchId, ns, kind string) func(watcher *k8s.Watcher) {
 				return func(watcher *k8s.Watcher) {
 					resources := watcher.List(kind)
@@ -49,15 +55,24 @@ func (m *KubernetesWatchMaker) MakeKubernetesWatch(spec watchapi.KubernetesWatch
 				}
 			}
 
-			watcherErr := watcher.WatchQuery(k8s.Query{
-				Namespace:     spec.Namespace,
-				Kind:          spec.Kind,
-				FieldSelector: spec.FieldSelector,
-				LabelSelector: spec.LabelSelector,
-			}, watchFunc(spec.WatchId(), spec.Namespace, spec.Kind))
+			for {
+				if !breaker.Wait(p.Shutdown()) {
+					return nil
+				}
 
-			if watcherErr != nil {
-				return watcherErr
+				watcher = m.kubeAPI.Watcher()
+				watcherErr := watcher.WatchQuery(k8s.Query{
+					Namespace:     spec.Namespace,
+					Kind:          spec.Kind,
+					FieldSelector: spec.FieldSelector,
+					LabelSelector: spec.LabelSelector,
+					Transform:     k8s.StripApplyMetadata,
+				}, watchFunc(spec.WatchId(), spec.Namespace, spec.Kind))
+
+				breaker.Record(watcherErr)
+				if watcherErr == nil {
+					break
+				}
 			}
 
 			watcher.Start()
@@ -214,6 +229,7 @@ func (b *kubebootstrap) Work(p *supervisor.Process) error {
 			Kind:          kind,
 			FieldSelector: b.fieldSelector,
 			LabelSelector: b.labelSelector,
+			Transform:     k8s.StripApplyMetadata,
 		}, watcherFunc(b.namespace, kind))
 
 		if err != nil {
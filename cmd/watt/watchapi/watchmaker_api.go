@@ -29,6 +29,7 @@ func (w *WatchSet) Interpolate() WatchSet {
 				Id:            s.Id,
 				ServiceName:   s.ServiceName,
 				Datacenter:    s.Datacenter,
+				Filter:        s.Filter,
 				ConsulAddress: os.ExpandEnv(s.ConsulAddress),
 			})
 		}
@@ -63,10 +64,15 @@ type ConsulWatchSpec struct {
 	ConsulAddress string `json:"consul-address"`
 	Datacenter    string `json:"datacenter"`
 	ServiceName   string `json:"service-name"`
+	Filter        string `json:"filter,omitempty"`
 }
 
 func (c ConsulWatchSpec) WatchId() string {
-	return fmt.Sprintf("%s|%s|%s", c.ConsulAddress, c.Datacenter, c.ServiceName)
+	id := fmt.Sprintf("%s|%s|%s", c.ConsulAddress, c.Datacenter, c.ServiceName)
+	if c.Filter != "" {
+		id += "|" + c.Filter
+	}
+	return id
 }
 
 // IKubernetesWatchMaker is an interface for KubernetesWatchMaker implementations. It mostly exists to facilitate the
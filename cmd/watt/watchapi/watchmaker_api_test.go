@@ -18,6 +18,7 @@ func TestWatchSet_Interpolate(t *testing.T) {
 			{ConsulAddress: "${HOST_IP}", ServiceName: "foo-in-consul", Datacenter: "dc1"},
 			{ConsulAddress: "$ANOTHER_IP", ServiceName: "bar-in-consul", Datacenter: "dc1"},
 			{ConsulAddress: "127.0.0.1", ServiceName: "baz-in-consul", Datacenter: "dc1"},
+			{ConsulAddress: "127.0.0.1", ServiceName: "qux-in-consul", Datacenter: "dc1", Filter: `"canary" not in Service.Tags`},
 		},
 	}
 
@@ -33,4 +34,8 @@ func TestWatchSet_Interpolate(t *testing.T) {
 	assert.Equal(t,
 		ConsulWatchSpec{ConsulAddress: "127.0.0.1", ServiceName: "baz-in-consul", Datacenter: "dc1"},
 		interpolated.ConsulWatches[2])
+
+	assert.Equal(t,
+		ConsulWatchSpec{ConsulAddress: "127.0.0.1", ServiceName: "qux-in-consul", Datacenter: "dc1", Filter: `"canary" not in Service.Tags`},
+		interpolated.ConsulWatches[3])
 }
@@ -23,6 +23,18 @@ rules:
 - apiGroups: [""]
   resources: [ "endpoints", "namespaces", "secrets", "services" ]
   verbs: ["get", "list", "watch"]
+- apiGroups: [""]
+  resources: [ "nodes", "pods" ]
+  verbs: ["get"]
+- apiGroups: [""]
+  resources: [ "configmaps" ]
+  verbs: ["get", "list", "watch"]
+- apiGroups: [ "discovery.k8s.io" ]
+  resources: [ "endpointslices" ]
+  verbs: ["get", "list", "watch"]
+- apiGroups: [ "cert-manager.io" ]
+  resources: [ "certificates" ]
+  verbs: ["get", "list", "watch"]
 - apiGroups: [ "getambassador.io" ]
   resources: [ "*" ]
   verbs: ["get", "list", "watch"]
@@ -35,12 +47,15 @@ rules:
 - apiGroups: [ "networking.internal.knative.dev" ]
   resources: [ "ingresses/status", "clusteringresses/status" ]
   verbs: ["update"]
-- apiGroups: [ "extensions" ]
-  resources: [ "ingresses" ]
+- apiGroups: [ "extensions", "networking.k8s.io" ]
+  resources: [ "ingresses", "ingressclasses" ]
   verbs: ["get", "list", "watch"]
-- apiGroups: [ "extensions" ]
+- apiGroups: [ "extensions", "networking.k8s.io" ]
   resources: [ "ingresses/status" ]
   verbs: ["update"]
+- apiGroups: [""]
+  resources: [ "events" ]
+  verbs: ["create"]
 ---
 apiVersion: v1
 kind: ServiceAccount
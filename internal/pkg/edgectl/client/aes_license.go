@@ -31,6 +31,9 @@ func AESLicense(cmd *cobra.Command, args []string) error {
 		return errors.Wrapf(err, "kubectl apply: %s", output)
 	}
 
+	if OutputJSON(cmd) {
+		return PrintJSON(cmd.OutOrStdout(), &licenseOutput{Applied: true, Namespace: namespace})
+	}
 	fmt.Println("License applied!")
 	return nil
 }
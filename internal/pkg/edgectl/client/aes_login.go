@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gookit/color"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/datawire/ambassador/internal/pkg/edgectl"
@@ -25,5 +26,20 @@ func AESLogin(cmd *cobra.Command, args []string) error {
 	// Prepare to talk to the cluster
 	kubeinfo := k8s.NewKubeInfo("", context, namespace) // Default namespace is "ambassador"
 
+	// With an OIDC provider, log in to it with the device flow, and talk to
+	// the cluster with the ID token instead of kubeconfig's credentials
+	if issuer, _ := cmd.Flags().GetString("oidc-issuer"); issuer != "" {
+		clientID, _ := cmd.Flags().GetString("oidc-client-id")
+		scopes, _ := cmd.Flags().GetStringSlice("oidc-scope")
+		if clientID == "" {
+			return errors.New("--oidc-issuer needs --oidc-client-id")
+		}
+		idToken, err := edgectl.DeviceLogin(cmd.OutOrStdout(), issuer, clientID, scopes)
+		if err != nil {
+			return err
+		}
+		kubeinfo.GetConfigFlags().BearerToken = &idToken
+	}
+
 	return edgectl.DoLogin(kubeinfo, context, namespace, hostname, !justShowURL, justShowURL, showToken, false)
 }
@@ -28,11 +28,24 @@ var daemonIsNotRunning = errors.New("Daemon is not running (see \"edgectl help d
 // Version requests version info from the daemon and prints both client and daemon version.
 func Version(cmd *cobra.Command, _ []string) error {
 	av, dv, err := daemonVersion()
-	if err == nil {
+	switch {
+	case OutputJSON(cmd):
+		out := &versionOutput{Client: edgectl.DisplayVersion()}
+		if err == nil {
+			out.Daemon = "v" + dv
+			out.APIVersion = av
+		}
+		if perr := PrintJSON(cmd.OutOrStdout(), out); perr != nil {
+			return perr
+		}
+	case err == nil:
 		fmt.Fprintf(cmd.OutOrStdout(), "Client %s\nDaemon v%s (api v%d)\n", edgectl.DisplayVersion(), dv, av)
+	default:
+		fmt.Fprintf(cmd.OutOrStdout(), "Client %s\n", edgectl.DisplayVersion())
+	}
+	if err == nil {
 		return nil
 	}
-	fmt.Fprintf(cmd.OutOrStdout(), "Client %s\n", edgectl.DisplayVersion())
 	if err != daemonIsNotRunning {
 		// Socket exists but connection failed anyway.
 		err = fmt.Errorf("Unable to connect to daemon: %s", err)
@@ -127,6 +140,9 @@ func Status(cmd *cobra.Command, _ []string) error {
 		if err != nil {
 			return err
 		}
+		if OutputJSON(cmd) {
+			return PrintJSON(out, makeStatusOutput(s))
+		}
 		switch s.Error {
 		case rpc.StatusResponse_Ok:
 			cl := s.Cluster
@@ -275,6 +291,10 @@ func (x *InterceptInfo) AddIntercept(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(stderr, "Failed to parse %q as HOST:PORT: %v\n", x.TargetHost, err)
 		os.Exit(1)
 	}
+	if x.Mapping != "" && cmd.Flags().Changed("prefix") {
+		fmt.Fprintln(stderr, "Error: Cannot use --mapping and --prefix at the same time")
+		os.Exit(1)
+	}
 	x.TargetHost = host
 	x.TargetPort = int32(port)
 
@@ -354,6 +374,13 @@ func AvailableIntercepts(cmd *cobra.Command, _ []string) error {
 		os.Exit(1)
 	}
 	stdout := cmd.OutOrStdout()
+	if OutputJSON(cmd) {
+		out := &availableInterceptsOutput{Deployments: make([]deploymentOutput, len(r.Intercepts))}
+		for idx, cept := range r.Intercepts {
+			out.Deployments[idx] = deploymentOutput{Namespace: cept.Namespace, Deployment: cept.Deployment}
+		}
+		return PrintJSON(stdout, out)
+	}
 	if len(r.Intercepts) == 0 {
 		fmt.Fprintln(stdout, "No interceptable deployments")
 		return nil
@@ -381,6 +408,9 @@ func ListIntercepts(cmd *cobra.Command, _ []string) error {
 		os.Exit(1)
 	}
 	stdout := cmd.OutOrStdout()
+	if OutputJSON(cmd) {
+		return PrintJSON(stdout, makeInterceptListOutput(r))
+	}
 	if len(r.Intercepts) == 0 {
 		fmt.Fprintln(stdout, "No intercepts")
 		return nil
@@ -392,7 +422,11 @@ func ListIntercepts(cmd *cobra.Command, _ []string) error {
 			previewURL = cept.PreviewURL
 			fmt.Fprintln(stdout, "      (preview URL available)")
 		}
-		fmt.Fprintf(stdout, "      Intercepting requests to %s when\n", cept.Deployment)
+		if cept.Mapping != "" {
+			fmt.Fprintf(stdout, "      Intercepting requests to %s through Mapping %s when\n", cept.Deployment, cept.Mapping)
+		} else {
+			fmt.Fprintf(stdout, "      Intercepting requests to %s when\n", cept.Deployment)
+		}
 		for k, v := range cept.Patterns {
 			fmt.Fprintf(stdout, "      - %s: %s\n", k, v)
 		}
@@ -479,7 +513,8 @@ func interceptMessage(ie rpc.InterceptError, txt string) string {
 	case rpc.InterceptError_InterceptOk:
 	case rpc.InterceptError_NoPreviewHost:
 		msg = `Your cluster is not configured for Preview URLs.
-(Could not find a Host resource that enables Path-type Preview URLs.)
+(Could not find a Host resource that enables Path-type Preview URLs,
+and edgectl can't make a preview route for an intercept with --mapping.)
 Please specify one or more header matches using --match.`
 	case rpc.InterceptError_NoConnection:
 		msg = "Not connected (use 'edgectl connect' to connect to your cluster)"
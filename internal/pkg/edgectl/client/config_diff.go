@@ -0,0 +1,224 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/pkg/k8s"
+)
+
+// ConfigDiff compares the Ambassador resources in local YAML files with the
+// ones in the cluster, and shows what applying the files would change.
+func ConfigDiff(cmd *cobra.Command, args []string) error {
+	context, _ := cmd.Flags().GetString("context")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	kubeinfo := k8s.NewKubeInfo("", context, namespace)
+	if namespace == "" {
+		var err error
+		if namespace, err = kubeinfo.Namespace(); err != nil {
+			return errors.Wrap(err, "cluster access")
+		}
+	}
+
+	var local []k8s.Resource
+	for _, name := range args {
+		resources, err := readResources(name)
+		if err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			if strings.HasPrefix(k8s.Map(resource).GetString("apiVersion"), "getambassador.io/") {
+				local = append(local, resource)
+			}
+		}
+	}
+	if len(local) == 0 {
+		return errors.New("no Ambassador resources found")
+	}
+
+	changed := 0
+	for _, resource := range local {
+		ns := resource.Namespace()
+		if ns == "" {
+			ns = namespace
+		}
+		live, err := getLiveResource(kubeinfo, resource.QKind(), resource.Name(), ns)
+		if err != nil {
+			return err
+		}
+		if printResourceDiff(os.Stdout, fmt.Sprintf("%s %s.%s", resource.Kind(), resource.Name(), ns), live, resource) {
+			changed++
+		}
+	}
+
+	if changed > 0 {
+		return errors.Errorf("%d of %d resource(s) would change", changed, len(local))
+	}
+	fmt.Printf("No changes to %d resource(s)\n", len(local))
+	return nil
+}
+
+// readResources reads the resources in a YAML file, or standard input for "-".
+func readResources(name string) ([]k8s.Resource, error) {
+	var input []byte
+	var err error
+	if name == "-" {
+		input, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		input, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k8s.ParseResources(name, string(input))
+}
+
+// getLiveResource fetches a resource from the cluster, or returns nil if
+// there isn't one.
+func getLiveResource(kubeinfo *k8s.KubeInfo, qkind, name, namespace string) (k8s.Resource, error) {
+	kargs, err := kubeinfo.GetKubectlArray("get", "-n", namespace, qkind, name, "-o", "yaml", "--ignore-not-found")
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster access")
+	}
+	output, err := exec.Command("kubectl", kargs...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, errors.Wrapf(err, "kubectl get %s %s: %s", qkind, name, exitErr.Stderr)
+		}
+		return nil, errors.Wrapf(err, "kubectl get %s %s", qkind, name)
+	}
+	resources, err := k8s.ParseResources("kubectl get", string(output))
+	if err != nil || len(resources) == 0 {
+		return nil, err
+	}
+	return resources[0], nil
+}
+
+// printResourceDiff prints how applying local would change live, which is nil
+// if the resource doesn't exist yet, and returns whether it would change.
+func printResourceDiff(w io.Writer, title string, live, local k8s.Resource) bool {
+	var changes []string
+	if live == nil {
+		fmt.Fprintf(w, "%s: new\n", title)
+	} else {
+		changes = diffValues("spec", map[string]interface{}(live.Spec()), map[string]interface{}(local.Spec()), nil)
+		changes = diffValues("metadata.labels", map[string]interface{}(k8s.Map(live.Metadata()).GetMap("labels")),
+			map[string]interface{}(k8s.Map(local.Metadata()).GetMap("labels")), changes)
+		if len(changes) == 0 {
+			fmt.Fprintf(w, "%s: unchanged\n", title)
+			return false
+		}
+		fmt.Fprintf(w, "%s: changed\n", title)
+	}
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+
+	if local.Kind() == "Mapping" || local.Kind() == "TCPMapping" {
+		after := mappingRoute(local.Spec())
+		if live == nil {
+			fmt.Fprintf(w, "  route: + %s\n", after)
+		} else if before := mappingRoute(live.Spec()); before != after {
+			fmt.Fprintf(w, "  route: - %s\n", before)
+			fmt.Fprintf(w, "  route: + %s\n", after)
+		}
+	}
+	return true
+}
+
+// mappingRoute describes the route that a Mapping or TCPMapping makes.
+func mappingRoute(spec k8s.Map) string {
+	var match []string
+	if method := spec.GetString("method"); method != "" {
+		match = append(match, method)
+	}
+	if host := spec.GetString("host"); host != "" {
+		match = append(match, host)
+	}
+	if prefix := spec.GetString("prefix"); prefix != "" {
+		match = append(match, prefix)
+	}
+	if port, ok := spec["port"]; ok {
+		match = append(match, fmt.Sprintf("port %v", port))
+	}
+	for _, kind := range []string{"headers", "regex_headers"} {
+		headers := spec.GetMap(kind)
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			match = append(match, fmt.Sprintf("%s=%v", name, headers[name]))
+		}
+	}
+	return fmt.Sprintf("%s -> %s", strings.Join(match, " "), spec.GetString("service"))
+}
+
+// diffValues appends the differences between before and after, at path, to
+// changes: "+" for what's added, "-" for what's removed, and "~" for what's
+// changed.
+func diffValues(path string, before, after interface{}, changes []string) []string {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := map[string]bool{}
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			b, inBefore := beforeMap[key]
+			a, inAfter := afterMap[key]
+			switch {
+			case !inBefore:
+				changes = append(changes, fmt.Sprintf("+ %s.%s: %s", path, key, showValue(a)))
+			case !inAfter:
+				changes = append(changes, fmt.Sprintf("- %s.%s: %s", path, key, showValue(b)))
+			default:
+				changes = diffValues(path+"."+key, b, a, changes)
+			}
+		}
+		return changes
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList && len(beforeList) == len(afterList) {
+		for i := range beforeList {
+			changes = diffValues(fmt.Sprintf("%s[%d]", path, i), beforeList[i], afterList[i], changes)
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", path, showValue(before), showValue(after)))
+	}
+	return changes
+}
+
+func showValue(value interface{}) string {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bytes)
+}
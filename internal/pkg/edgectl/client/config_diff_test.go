@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/k8s"
+)
+
+func parseOne(t *testing.T, input string) k8s.Resource {
+	resources, err := k8s.ParseResources("test", input)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	return resources[0]
+}
+
+func TestConfigDiff(t *testing.T) {
+	live := parseOne(t, `
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+  resourceVersion: "1234"
+spec:
+  prefix: /backend/
+  service: quote
+  timeout_ms: 3000
+  ambassador_id: [ one, two ]
+  cors:
+    origins: "*"
+`)
+	local := parseOne(t, `
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  labels:
+    team: quotes
+spec:
+  prefix: /backend/v2/
+  service: quote
+  ambassador_id: [ one, three ]
+  cors:
+    origins: "*"
+    methods: GET
+`)
+
+	var out bytes.Buffer
+	assert.True(t, printResourceDiff(&out, "Mapping quote.default", live, local))
+	assert.Equal(t, `Mapping quote.default: changed
+  ~ spec.ambassador_id[1]: "two" -> "three"
+  + spec.cors.methods: "GET"
+  ~ spec.prefix: "/backend/" -> "/backend/v2/"
+  - spec.timeout_ms: 3000
+  + metadata.labels.team: "quotes"
+  route: - /backend/ -> quote
+  route: + /backend/v2/ -> quote
+`, out.String())
+
+	out.Reset()
+	assert.False(t, printResourceDiff(&out, "Mapping quote.default", live, live))
+	assert.Equal(t, "Mapping quote.default: unchanged\n", out.String())
+
+	out.Reset()
+	assert.True(t, printResourceDiff(&out, "Mapping quote.default", nil, local))
+	assert.Equal(t, "Mapping quote.default: new\n  route: + /backend/v2/ -> quote\n", out.String())
+}
+
+func TestMappingRoute(t *testing.T) {
+	spec := parseOne(t, `
+spec:
+  method: POST
+  host: example.com
+  prefix: /api/
+  headers:
+    x-b: two
+    x-a: one
+  service: api:8080
+`).Spec()
+	assert.Equal(t, "POST example.com /api/ x-a=one x-b=two -> api:8080", mappingRoute(spec))
+
+	spec = parseOne(t, "spec: { port: 6379, service: redis }").Spec()
+	assert.Equal(t, "port 6379 -> redis", mappingRoute(spec))
+}
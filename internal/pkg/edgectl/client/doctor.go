@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/internal/pkg/edgectl"
+	"github.com/datawire/ambassador/pkg/api/edgectl/rpc"
+)
+
+// Doctor checks the daemon itself, then asks it to check everything from the
+// network overrides to the cluster, and prints the results.
+func Doctor(cmd *cobra.Command, _ []string) error {
+	daemonCheck := &rpc.DoctorResponse_Check{Name: "Daemon"}
+	checks := []*rpc.DoctorResponse_Check{daemonCheck}
+	av, dv, err := daemonVersion()
+	switch {
+	case err != nil:
+		daemonCheck.Result = rpc.DoctorResponse_Fail
+		daemonCheck.Text = err.Error()
+		daemonCheck.Advice = "Launch the daemon with 'sudo edgectl daemon'."
+	case av != edgectl.ApiVersion:
+		daemonCheck.Result = rpc.DoctorResponse_Fail
+		daemonCheck.Text = fmt.Sprintf("daemon v%s (api v%d) doesn't match edgectl %s", dv, av, edgectl.DisplayVersion())
+		daemonCheck.Advice = "Run 'edgectl quit' and 'sudo edgectl daemon' to launch the daemon from this edgectl."
+	default:
+		daemonCheck.Text = fmt.Sprintf("v%s (api v%d)", dv, av)
+		err = withDaemon(func(c rpc.DaemonClient) error {
+			r, err := c.Doctor(context.Background(), &rpc.Empty{})
+			if err != nil {
+				return err
+			}
+			checks = append(checks, r.Checks...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if OutputJSON(cmd) {
+		if err := PrintJSON(cmd.OutOrStdout(), makeDoctorOutput(checks)); err != nil {
+			return err
+		}
+	} else {
+		printChecks(cmd.OutOrStdout(), checks)
+	}
+	failed := 0
+	for _, check := range checks {
+		if check.Result == rpc.DoctorResponse_Fail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d check(s) failed", failed, len(checks))
+	}
+	return nil
+}
+
+func printChecks(out io.Writer, checks []*rpc.DoctorResponse_Check) {
+	for _, check := range checks {
+		var tag string
+		switch check.Result {
+		case rpc.DoctorResponse_Pass:
+			tag = "[ OK ]"
+		case rpc.DoctorResponse_Fail:
+			tag = "[FAIL]"
+		default:
+			tag = "[SKIP]"
+		}
+		fmt.Fprintf(out, "%s %s: %s\n", tag, check.Name, check.Text)
+		if check.Advice != "" {
+			fmt.Fprintf(out, "       %s\n", check.Advice)
+		}
+	}
+}
+
+func makeDoctorOutput(checks []*rpc.DoctorResponse_Check) *doctorOutput {
+	out := &doctorOutput{Checks: make([]checkOutput, len(checks))}
+	for idx, check := range checks {
+		out.Checks[idx] = checkOutput{
+			Name:   check.Name,
+			Result: strings.ToLower(check.Result.String()),
+			Text:   check.Text,
+			Advice: check.Advice,
+		}
+	}
+	return out
+}
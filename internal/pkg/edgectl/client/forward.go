@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/pkg/api/edgectl/rpc"
+)
+
+// A ForwardInfo contains all information needed to add a port forward.
+type ForwardInfo struct {
+	rpc.ForwardRequest
+}
+
+// parseForward splits [TYPE/]NAME:PORT into the resource and the port to
+// forward to. A NAME without a TYPE is a service.
+func parseForward(arg string) (resource, port string, err error) {
+	colon := strings.LastIndexByte(arg, ':')
+	if colon <= 0 || colon == len(arg)-1 {
+		return "", "", errors.Errorf("%q isn't [TYPE/]NAME:PORT", arg)
+	}
+	resource, port = arg[:colon], arg[colon+1:]
+	if !strings.Contains(resource, "/") {
+		resource = "svc/" + resource
+	}
+	return resource, port, nil
+}
+
+// AddForward tells the daemon to add a port forward.
+func (x *ForwardInfo) AddForward(cmd *cobra.Command, args []string) error {
+	var err error
+	x.Resource, x.Port, err = parseForward(args[0])
+	if err != nil {
+		return err
+	}
+
+	var r *rpc.ForwardResponse
+	err = withDaemon(func(c rpc.DaemonClient) error {
+		var err error
+		r, err = c.AddForward(context.Background(), &x.ForwardRequest)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	switch r.Error {
+	case rpc.ForwardResponse_Ok:
+		fmt.Fprintf(cmd.OutOrStdout(), "Forwarding localhost:%d to %s\n", r.LocalPort, r.Name)
+	case rpc.ForwardResponse_AlreadyExists:
+		fmt.Fprintf(cmd.OutOrStdout(), "Already forwarding localhost:%d to %s\n", r.LocalPort, r.Name)
+	default:
+		fmt.Fprintln(cmd.OutOrStderr(), forwardMessage(r))
+		os.Exit(1)
+	}
+	return nil
+}
+
+// ListForwards requests a list of port forwards from the daemon
+func ListForwards(cmd *cobra.Command, _ []string) error {
+	var r *rpc.ListForwardsResponse
+	err := withDaemon(func(c rpc.DaemonClient) error {
+		var err error
+		r, err = c.ListForwards(context.Background(), &rpc.Empty{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	stdout := cmd.OutOrStdout()
+	if OutputJSON(cmd) {
+		out := &forwardListOutput{Forwards: make([]forwardOutput, len(r.Forwards))}
+		for idx, fwd := range r.Forwards {
+			out.Forwards[idx] = forwardOutput{
+				Name:      fwd.Name,
+				Namespace: fwd.Namespace,
+				Resource:  fwd.Resource,
+				Port:      fwd.Port,
+				LocalPort: fwd.LocalPort,
+				Up:        fwd.Okay,
+			}
+		}
+		return PrintJSON(stdout, out)
+	}
+	if len(r.Forwards) == 0 {
+		fmt.Fprintln(stdout, "No port forwards")
+		return nil
+	}
+	for idx, fwd := range r.Forwards {
+		state := "up"
+		if !fwd.Okay {
+			state = "reconnecting"
+		}
+		fmt.Fprintf(stdout, "%4d. localhost:%d -> %s (%s)\n", idx+1, fwd.LocalPort, fwd.Name, state)
+	}
+	return nil
+}
+
+// RemoveForward tells the daemon to remove a port forward
+func RemoveForward(cmd *cobra.Command, args []string) error {
+	name := strings.TrimSpace(args[0])
+	var r *rpc.ForwardResponse
+	err := withDaemon(func(c rpc.DaemonClient) error {
+		var err error
+		r, err = c.RemoveForward(context.Background(), &rpc.RemoveForwardRequest{Name: name})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if r.Error != rpc.ForwardResponse_Ok {
+		fmt.Fprintln(cmd.OutOrStderr(), forwardMessage(r))
+		os.Exit(1)
+	}
+	return nil
+}
+
+func forwardMessage(r *rpc.ForwardResponse) string {
+	switch r.Error {
+	case rpc.ForwardResponse_NoConnection:
+		return "Not connected (use 'edgectl connect' to connect to your cluster)"
+	case rpc.ForwardResponse_NotFound:
+		return fmt.Sprintf("Port forward %q not found (use 'edgectl forward list' to list them)", r.Name)
+	default:
+		return fmt.Sprintf("Failed to forward to %s: %s", r.Name, r.ErrorText)
+	}
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForward(t *testing.T) {
+	for arg, expected := range map[string][2]string{
+		"quote:80":          {"svc/quote", "80"},
+		"svc/quote:http":    {"svc/quote", "http"},
+		"deploy/redis:6379": {"deploy/redis", "6379"},
+	} {
+		resource, port, err := parseForward(arg)
+		assert.NoError(t, err, arg)
+		assert.Equal(t, expected[0], resource, arg)
+		assert.Equal(t, expected[1], port, arg)
+	}
+
+	for _, arg := range []string{"quote", "quote:", ":80"} {
+		_, _, err := parseForward(arg)
+		assert.Error(t, err, arg)
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/datawire/ambassador/pkg/api/edgectl/rpc"
+)
+
+// CheckOutput makes sure that the --output flag names a format that edgectl
+// knows how to produce.
+func CheckOutput(cmd *cobra.Command, _ []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	switch output {
+	case "", "text", "json":
+		return nil
+	}
+	return errors.Errorf("unknown output format %q (use text or json)", output)
+}
+
+// OutputJSON returns whether the --output flag asks for JSON instead of text.
+func OutputJSON(cmd *cobra.Command) bool {
+	output, _ := cmd.Flags().GetString("output")
+	return output == "json"
+}
+
+// PrintJSON writes v to w as indented JSON.
+func PrintJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// The types below are the JSON output of edgectl commands. Scripts depend on
+// them, so add fields rather than rename or remove them.
+
+type versionOutput struct {
+	Client     string `json:"client"`
+	Daemon     string `json:"daemon,omitempty"`
+	APIVersion int    `json:"api_version,omitempty"`
+}
+
+type statusOutput struct {
+	// Status is one of connected, reconnecting, paused, no_network and
+	// disconnected.
+	Status     string            `json:"status"`
+	Context    string            `json:"context,omitempty"`
+	Server     string            `json:"server,omitempty"`
+	Proxy      bool              `json:"proxy"`
+	Intercepts *interceptsOutput `json:"intercepts,omitempty"`
+}
+
+type interceptsOutput struct {
+	Connected         bool   `json:"connected"`
+	Interceptable     int32  `json:"interceptable"`
+	ClusterIntercepts int32  `json:"cluster_intercepts"`
+	LocalIntercepts   int32  `json:"local_intercepts"`
+	LicenseInfo       string `json:"license_info,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+type interceptOutput struct {
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace"`
+	Deployment string            `json:"deployment"`
+	Mapping    string            `json:"mapping,omitempty"`
+	Patterns   map[string]string `json:"patterns"`
+	TargetHost string            `json:"target_host"`
+	TargetPort int32             `json:"target_port"`
+	PreviewURL string            `json:"preview_url,omitempty"`
+}
+
+type interceptListOutput struct {
+	Intercepts []interceptOutput `json:"intercepts"`
+}
+
+type deploymentOutput struct {
+	Namespace  string `json:"namespace"`
+	Deployment string `json:"deployment"`
+}
+
+type availableInterceptsOutput struct {
+	Deployments []deploymentOutput `json:"deployments"`
+}
+
+type forwardOutput struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Resource  string `json:"resource"`
+	Port      string `json:"port"`
+	LocalPort int32  `json:"local_port"`
+	Up        bool   `json:"up"`
+}
+
+type forwardListOutput struct {
+	Forwards []forwardOutput `json:"forwards"`
+}
+
+type licenseOutput struct {
+	Applied   bool   `json:"applied"`
+	Namespace string `json:"namespace"`
+}
+
+type checkOutput struct {
+	Name string `json:"name"`
+	// Result is one of pass, fail and skipped.
+	Result string `json:"result"`
+	Text   string `json:"text"`
+	Advice string `json:"advice,omitempty"`
+}
+
+type doctorOutput struct {
+	Checks []checkOutput `json:"checks"`
+}
+
+// makeStatusOutput translates the daemon's status to JSON output.
+func makeStatusOutput(s *rpc.StatusResponse) *statusOutput {
+	out := &statusOutput{Proxy: s.Bridge}
+	switch s.Error {
+	case rpc.StatusResponse_Paused:
+		out.Status = "paused"
+		return out
+	case rpc.StatusResponse_NoNetwork:
+		out.Status = "no_network"
+		return out
+	case rpc.StatusResponse_Disconnected:
+		out.Status = "disconnected"
+		return out
+	}
+	out.Status = "reconnecting"
+	if cl := s.Cluster; cl != nil {
+		if cl.Connected {
+			out.Status = "connected"
+		}
+		out.Context = cl.Context
+		out.Server = cl.Server
+	}
+	if ic := s.Intercepts; ic != nil {
+		out.Intercepts = &interceptsOutput{
+			Connected:         ic.Connected,
+			Interceptable:     ic.InterceptableCount,
+			ClusterIntercepts: ic.ClusterIntercepts,
+			LocalIntercepts:   ic.LocalIntercepts,
+			LicenseInfo:       ic.LicenseInfo,
+		}
+		if !ic.Connected {
+			out.Intercepts.Error = s.ErrorText
+		}
+	}
+	return out
+}
+
+// makeInterceptListOutput translates the daemon's intercept list to JSON
+// output.
+func makeInterceptListOutput(r *rpc.ListInterceptsResponse) *interceptListOutput {
+	out := &interceptListOutput{Intercepts: make([]interceptOutput, len(r.Intercepts))}
+	for idx, cept := range r.Intercepts {
+		patterns := cept.Patterns
+		if patterns == nil {
+			patterns = map[string]string{}
+		}
+		out.Intercepts[idx] = interceptOutput{
+			Name:       cept.Name,
+			Namespace:  cept.Namespace,
+			Deployment: cept.Deployment,
+			Mapping:    cept.Mapping,
+			Patterns:   patterns,
+			TargetHost: cept.TargetHost,
+			TargetPort: cept.TargetPort,
+			PreviewURL: cept.PreviewURL,
+		}
+	}
+	return out
+}
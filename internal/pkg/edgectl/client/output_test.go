@@ -0,0 +1,68 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/ambassador/pkg/api/edgectl/rpc"
+)
+
+func TestStatusOutput(t *testing.T) {
+	for expected, s := range map[string]*rpc.StatusResponse{
+		"disconnected": {Error: rpc.StatusResponse_Disconnected},
+		"paused":       {Error: rpc.StatusResponse_Paused},
+		"no_network":   {Error: rpc.StatusResponse_NoNetwork},
+		"reconnecting": {Cluster: &rpc.StatusResponse_ClusterInfo{Context: "dev"}},
+		"connected":    {Cluster: &rpc.StatusResponse_ClusterInfo{Connected: true, Context: "dev"}},
+	} {
+		assert.Equal(t, expected, makeStatusOutput(s).Status)
+	}
+
+	s := &rpc.StatusResponse{
+		Bridge:     true,
+		ErrorText:  "no traffic manager",
+		Cluster:    &rpc.StatusResponse_ClusterInfo{Connected: true, Context: "dev", Server: "https://k8s:6443"},
+		Intercepts: &rpc.StatusResponse_InterceptsInfo{},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, PrintJSON(&buf, makeStatusOutput(s)))
+	assert.JSONEq(t, `{
+		"status": "connected",
+		"context": "dev",
+		"server": "https://k8s:6443",
+		"proxy": true,
+		"intercepts": {
+			"connected": false,
+			"interceptable": 0,
+			"cluster_intercepts": 0,
+			"local_intercepts": 0,
+			"error": "no traffic manager"
+		}
+	}`, buf.String())
+}
+
+func TestInterceptListOutput(t *testing.T) {
+	r := &rpc.ListInterceptsResponse{Intercepts: []*rpc.ListInterceptsResponse_ListEntry{{
+		Name:       "hello",
+		Namespace:  "default",
+		Deployment: "hello",
+		TargetHost: "localhost",
+		TargetPort: 9000,
+	}}}
+	var buf bytes.Buffer
+	assert.NoError(t, PrintJSON(&buf, makeInterceptListOutput(r)))
+	assert.JSONEq(t, `{"intercepts": [{
+		"name": "hello",
+		"namespace": "default",
+		"deployment": "hello",
+		"patterns": {},
+		"target_host": "localhost",
+		"target_port": 9000
+	}]}`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, PrintJSON(&buf, makeInterceptListOutput(&rpc.ListInterceptsResponse{})))
+	assert.JSONEq(t, `{"intercepts": []}`, buf.String())
+}
@@ -128,6 +128,8 @@ func (d *daemon) connect(p *supervisor.Process, cr *rpc.ConnectRequest) *rpc.Con
 	}
 	tmgr.previewHost = previewHost
 	d.trafficMgr = tmgr
+
+	removeStaleMappings(p, d.cluster, cr.InstallID)
 	return r
 }
 
@@ -140,6 +142,7 @@ func (d *daemon) disconnect(p *supervisor.Process) *rpc.DisconnectResponse {
 		return r
 	}
 	_ = d.ClearIntercepts(p)
+	d.ClearForwards(p)
 	if d.bridge != nil {
 		d.cluster.SetBridgeCheck(nil) // Stop depending on this bridge
 		_ = d.bridge.Close()
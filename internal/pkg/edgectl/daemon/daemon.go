@@ -31,6 +31,7 @@ type daemon struct {
 	bridge     Resource
 	trafficMgr *TrafficManager
 	intercepts []*Intercept
+	forwards   []*Forward
 	dns        string
 	fallback   string
 }
@@ -99,6 +100,10 @@ func (s *grpcService) Status(_ context.Context, _ *rpc.Empty) (*rpc.StatusRespon
 	return s.d.status(s.p), nil
 }
 
+func (s *grpcService) Doctor(_ context.Context, _ *rpc.Empty) (*rpc.DoctorResponse, error) {
+	return s.d.doctor(s.p), nil
+}
+
 func (s *grpcService) Connect(_ context.Context, cr *rpc.ConnectRequest) (*rpc.ConnectResponse, error) {
 	return s.d.connect(s.p, cr), nil
 }
@@ -123,6 +128,18 @@ func (s *grpcService) ListIntercepts(_ context.Context, _ *rpc.Empty) (*rpc.List
 	return s.d.listIntercepts(s.p), nil
 }
 
+func (s *grpcService) AddForward(_ context.Context, fr *rpc.ForwardRequest) (*rpc.ForwardResponse, error) {
+	return s.d.addForward(s.p, fr), nil
+}
+
+func (s *grpcService) RemoveForward(_ context.Context, rr *rpc.RemoveForwardRequest) (*rpc.ForwardResponse, error) {
+	return s.d.removeForward(s.p, rr.Name), nil
+}
+
+func (s *grpcService) ListForwards(_ context.Context, _ *rpc.Empty) (*rpc.ListForwardsResponse, error) {
+	return s.d.listForwards(s.p), nil
+}
+
 func (s *grpcService) Pause(ctx context.Context, empty *rpc.Empty) (*rpc.PauseResponse, error) {
 	return s.d.pause(s.p), nil
 }
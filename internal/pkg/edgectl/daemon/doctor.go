@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/datawire/ambassador/internal/pkg/edgectl"
+	"github.com/datawire/ambassador/pkg/api/edgectl/rpc"
+	"github.com/datawire/ambassador/pkg/supervisor"
+)
+
+// translatorName is the name of teleproxy's firewall chain (iptables) or
+// anchor (pf).
+const translatorName = "teleproxy"
+
+// doctor runs connectivity checks, from the machine up to the cluster
+func (d *daemon) doctor(p *supervisor.Process) *rpc.DoctorResponse {
+	return &rpc.DoctorResponse{
+		Checks: []*rpc.DoctorResponse_Check{
+			d.checkNetwork(p),
+			d.checkFirewall(p),
+			d.checkCluster(p),
+			d.checkClusterDNS(p),
+			d.checkVersionSkew(p),
+		},
+	}
+}
+
+func checkPass(name, text string) *rpc.DoctorResponse_Check {
+	return &rpc.DoctorResponse_Check{Name: name, Result: rpc.DoctorResponse_Pass, Text: text}
+}
+
+func checkFail(name, text, advice string) *rpc.DoctorResponse_Check {
+	return &rpc.DoctorResponse_Check{Name: name, Result: rpc.DoctorResponse_Fail, Text: text, Advice: advice}
+}
+
+func checkSkip(name, text string) *rpc.DoctorResponse_Check {
+	return &rpc.DoctorResponse_Check{Name: name, Result: rpc.DoctorResponse_Skipped, Text: text}
+}
+
+// checkNetwork checks that teleproxy intercept is up and answering
+func (d *daemon) checkNetwork(p *supervisor.Process) *rpc.DoctorResponse_Check {
+	const name = "Network overrides"
+	if d.network == nil {
+		return checkFail(name, "paused", "Run 'edgectl resume' to turn them back on.")
+	}
+	if err := checkNetOverride(p); err != nil {
+		return checkFail(name, fmt.Sprintf("teleproxy is not answering: %v", err),
+			fmt.Sprintf("The daemon restarts teleproxy by itself; if this persists, see %s.", edgectl.Logfile))
+	}
+	return checkPass(name, "teleproxy is running")
+}
+
+// checkFirewall checks that teleproxy's firewall rules are in place
+func (d *daemon) checkFirewall(p *supervisor.Process) *rpc.DoctorResponse_Check {
+	const name = "Firewall rules"
+	if d.network == nil {
+		return checkSkip(name, "network overrides are paused")
+	}
+	var cmd *supervisor.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = p.Command("iptables", "-t", "nat", "-S", translatorName)
+	case "darwin":
+		cmd = p.Command("pfctl", "-a", translatorName, "-s", "nat")
+	default:
+		return checkSkip(name, fmt.Sprintf("not supported on %s", runtime.GOOS))
+	}
+	output, err := cmd.CaptureErr(nil)
+	advice := "Run 'edgectl pause' and 'edgectl resume' to set them up again, and check that no other " +
+		"program (such as a VPN client) is replacing the firewall rules."
+	if err != nil {
+		return checkFail(name, fmt.Sprintf("%s: %s", err, strings.TrimSpace(output)), advice)
+	}
+	if !strings.Contains(output, "rdr") && !strings.Contains(output, "REDIRECT") {
+		return checkFail(name, fmt.Sprintf("no redirect rules in %s", translatorName), advice)
+	}
+	return checkPass(name, fmt.Sprintf("redirect rules are in %s", translatorName))
+}
+
+// checkCluster checks that the cluster's API server answers
+func (d *daemon) checkCluster(p *supervisor.Process) *rpc.DoctorResponse_Check {
+	const name = "Cluster"
+	if d.cluster == nil {
+		return checkFail(name, "not connected", "Run 'edgectl connect' to connect to your cluster.")
+	}
+	cmd := d.cluster.GetKubectlCmdNoNamespace(p, "version", "-o", "json")
+	output, err := cmd.Capture(nil)
+	if err != nil {
+		return checkFail(name, fmt.Sprintf("%s (%s) is unreachable: %v", d.cluster.Context(), d.cluster.Server(), err),
+			fmt.Sprintf("Check that 'kubectl --context %s get pods' works.", d.cluster.Context()))
+	}
+	var version struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if err := json.Unmarshal([]byte(output), &version); err != nil {
+		p.Logf("kubectl version: %v", err)
+	}
+	text := fmt.Sprintf("%s (%s) is reachable", d.cluster.Context(), d.cluster.Server())
+	if gitVersion := version.ServerVersion.GitVersion; gitVersion != "" {
+		text += ", Kubernetes " + gitVersion
+	}
+	return checkPass(name, text)
+}
+
+// checkClusterDNS checks that cluster names resolve on this machine
+func (d *daemon) checkClusterDNS(_ *supervisor.Process) *rpc.DoctorResponse_Check {
+	const name = "Cluster DNS"
+	if d.cluster == nil || d.bridge == nil {
+		return checkSkip(name, "not connected")
+	}
+	const host = "kubernetes.default"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		advice := "Cluster names resolve through teleproxy; check that the network overrides are up " +
+			"and that nothing else (such as a VPN client) manages DNS on this machine."
+		if !d.bridge.IsOkay() {
+			advice = "The connection to the cluster is down; the daemon reconnects by itself, or run " +
+				"'edgectl disconnect' and 'edgectl connect'."
+		}
+		return checkFail(name, fmt.Sprintf("%s does not resolve: %v", host, err), advice)
+	}
+	return checkPass(name, fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", ")))
+}
+
+// checkVersionSkew checks that edgectl and the cluster's traffic manager are
+// from the same release
+func (d *daemon) checkVersionSkew(p *supervisor.Process) *rpc.DoctorResponse_Check {
+	const name = "Version skew"
+	if d.cluster == nil || d.trafficMgr == nil {
+		return checkSkip(name, "no traffic manager")
+	}
+	cmd := d.cluster.GetKubectlCmdNoNamespace(p, "get", "-n", d.trafficMgr.namespace, "deploy/telepresence-proxy",
+		"-o", "jsonpath={.spec.template.spec.containers[0].image}")
+	image, err := cmd.Capture(nil)
+	if err != nil {
+		return checkSkip(name, fmt.Sprintf("traffic manager image: %v", err))
+	}
+	image = strings.TrimSpace(image)
+	colon := strings.LastIndexByte(image, ':')
+	if colon < 0 || strings.Contains(image[colon:], "/") {
+		return checkSkip(name, fmt.Sprintf("traffic manager image %q has no version", image))
+	}
+	clusterVersion := image[colon+1:]
+	text := fmt.Sprintf("edgectl v%s, traffic manager %s", edgectl.Version, clusterVersion)
+	same, ok := sameMinorVersion(edgectl.Version, clusterVersion)
+	switch {
+	case !ok:
+		return checkSkip(name, text)
+	case !same:
+		return checkFail(name, text, "Install the edgectl release that matches the Ambassador Edge Stack in your cluster.")
+	}
+	return checkPass(name, text)
+}
+
+// sameMinorVersion returns whether two versions have the same major and minor
+// versions, and whether both look like versions at all.
+func sameMinorVersion(a, b string) (same, ok bool) {
+	majorMinor := func(version string) string {
+		parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" ||
+			strings.Trim(parts[0]+parts[1], "0123456789") != "" {
+			return ""
+		}
+		return parts[0] + "." + parts[1]
+	}
+	mmA, mmB := majorMinor(a), majorMinor(b)
+	if mmA == "" || mmB == "" {
+		return false, false
+	}
+	return mmA == mmB, true
+}
@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameMinorVersion(t *testing.T) {
+	for _, versions := range [][2]string{
+		{"1.9.0", "1.9.1"},
+		{"1.9.0-rc.2", "v1.9"},
+	} {
+		same, ok := sameMinorVersion(versions[0], versions[1])
+		assert.True(t, ok, versions)
+		assert.True(t, same, versions)
+	}
+
+	same, ok := sameMinorVersion("1.8.1", "1.9.0")
+	assert.True(t, ok)
+	assert.False(t, same)
+
+	// Development builds and untagged images can't be compared.
+	for _, versions := range [][2]string{
+		{"(unknown version)", "1.9.0"},
+		{"1.9.0", "latest"},
+		{"1.9.0", "1"},
+	} {
+		_, ok := sameMinorVersion(versions[0], versions[1])
+		assert.False(t, ok, versions)
+	}
+}
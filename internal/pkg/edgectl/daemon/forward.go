@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/datawire/ambassador/pkg/api/edgectl/rpc"
+	"github.com/datawire/ambassador/pkg/supervisor"
+)
+
+// Forward is a port forward to a resource in the cluster, kept up by
+// restarting kubectl port-forward whenever it quits or stops answering.
+type Forward struct {
+	*rpc.ForwardRequest
+	name string
+	crc  Resource
+}
+
+// forwardName returns the name of a port forward
+func forwardName(fr *rpc.ForwardRequest) string {
+	return fmt.Sprintf("%s/%s:%s", fr.Namespace, fr.Resource, fr.Port)
+}
+
+// addForward adds one port forward
+func (d *daemon) addForward(p *supervisor.Process, fr *rpc.ForwardRequest) *rpc.ForwardResponse {
+	r := &rpc.ForwardResponse{}
+	if d.cluster == nil {
+		r.Error = rpc.ForwardResponse_NoConnection
+		return r
+	}
+	if fr.Namespace == "" {
+		fr.Namespace = d.cluster.namespace
+	}
+	r.Name = forwardName(fr)
+	for _, fwd := range d.forwards {
+		if fwd.name == r.Name {
+			r.Error = rpc.ForwardResponse_AlreadyExists
+			r.LocalPort = fwd.LocalPort
+			return r
+		}
+	}
+
+	if fr.LocalPort == 0 {
+		if port, err := strconv.Atoi(fr.Port); err == nil {
+			fr.LocalPort = int32(port)
+		} else if port, err = GetFreePort(); err == nil {
+			fr.LocalPort = int32(port)
+		} else {
+			r.Error = rpc.ForwardResponse_FailedToEstablish
+			r.ErrorText = err.Error()
+			return r
+		}
+	}
+	for _, fwd := range d.forwards {
+		if fwd.LocalPort == fr.LocalPort {
+			r.Error = rpc.ForwardResponse_FailedToEstablish
+			r.ErrorText = fmt.Sprintf("local port %d is already forwarded to %s", fr.LocalPort, fwd.name)
+			return r
+		}
+	}
+
+	fwd, err := MakeForward(p, d.cluster, fr)
+	if err != nil {
+		r.Error = rpc.ForwardResponse_FailedToEstablish
+		r.ErrorText = err.Error()
+		return r
+	}
+	d.forwards = append(d.forwards, fwd)
+	r.LocalPort = fr.LocalPort
+	return r
+}
+
+// removeForward removes one port forward by name
+func (d *daemon) removeForward(_ *supervisor.Process, name string) *rpc.ForwardResponse {
+	r := &rpc.ForwardResponse{Name: name}
+	for idx, fwd := range d.forwards {
+		if fwd.name == name {
+			d.forwards = append(d.forwards[:idx], d.forwards[idx+1:]...)
+			r.LocalPort = fwd.LocalPort
+			_ = fwd.crc.Close()
+			return r
+		}
+	}
+	r.Error = rpc.ForwardResponse_NotFound
+	return r
+}
+
+// listForwards lists port forwards
+func (d *daemon) listForwards(_ *supervisor.Process) *rpc.ListForwardsResponse {
+	r := &rpc.ListForwardsResponse{}
+	r.Forwards = make([]*rpc.ListForwardsResponse_ListEntry, len(d.forwards))
+	for idx, fwd := range d.forwards {
+		r.Forwards[idx] = &rpc.ListForwardsResponse_ListEntry{
+			Name:      fwd.name,
+			Namespace: fwd.Namespace,
+			Resource:  fwd.Resource,
+			Port:      fwd.Port,
+			LocalPort: fwd.LocalPort,
+			Okay:      fwd.crc.IsOkay(),
+		}
+	}
+	return r
+}
+
+// ClearForwards removes all port forwards
+func (d *daemon) ClearForwards(p *supervisor.Process) {
+	for _, fwd := range d.forwards {
+		if err := fwd.crc.Close(); err != nil {
+			p.Logf("Closing port forward %q: %v", fwd.name, err)
+		}
+	}
+	d.forwards = d.forwards[:0]
+}
+
+// MakeForward starts a port forward. kubectl port-forward resolves a service
+// to one of its pods when it starts, so if that pod goes away, the forward
+// is restarted, and picks another.
+func MakeForward(p *supervisor.Process, cluster *KCluster, fr *rpc.ForwardRequest) (*Forward, error) {
+	fwd := &Forward{ForwardRequest: fr, name: forwardName(fr)}
+	args := cluster.GetKubectlArgsNoNamespace(
+		"port-forward", "--namespace", fr.Namespace, fr.Resource, fmt.Sprintf("%d:%s", fr.LocalPort, fr.Port))
+	addr := fmt.Sprintf("127.0.0.1:%d", fr.LocalPort)
+	check := func(_ *supervisor.Process) error {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			return errors.Wrap(err, "port forward")
+		}
+		return conn.Close()
+	}
+
+	crc, err := CheckedRetryingCommand(p, "forward/"+fwd.name, args, cluster.RAI(), check, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	fwd.crc = crc
+	return fwd, nil
+}
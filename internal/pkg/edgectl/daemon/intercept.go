@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -11,9 +12,14 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/datawire/ambassador/pkg/api/edgectl/rpc"
+	"github.com/datawire/ambassador/pkg/k8s"
 	"github.com/datawire/ambassador/pkg/supervisor"
 )
 
+// interceptOwnerLabel labels the intercept Mappings with the install ID of the
+// edgectl that made them, so that it can clean up after itself.
+const interceptOwnerLabel = "getambassador.io/edgectl-install-id"
+
 func (d *daemon) interceptStatus() (rpc.InterceptError, string) {
 	ie := rpc.InterceptError_InterceptOk
 	msg := ""
@@ -134,10 +140,11 @@ func (d *daemon) listIntercepts(_ *supervisor.Process) *rpc.ListInterceptsRespon
 			Name:       ii.Name,
 			Namespace:  ii.Namespace,
 			Deployment: ii.Deployment,
-			PreviewURL: ii.PreviewURL(d.trafficMgr.previewHost),
+			PreviewURL: cept.PreviewURL(),
 			Patterns:   ii.Patterns,
 			TargetHost: ii.TargetHost,
 			TargetPort: ii.TargetPort,
+			Mapping:    ii.Mapping,
 		}
 	}
 	return r
@@ -173,16 +180,27 @@ func (d *daemon) availableIntercepts(_ *supervisor.Process) *rpc.AvailableInterc
 // addIntercept adds one intercept
 func (d *daemon) addIntercept(p *supervisor.Process, ir *rpc.InterceptRequest) *rpc.InterceptResponse {
 	r := &rpc.InterceptResponse{}
-	if ir.Preview && d.trafficMgr.previewHost == "" {
-		r.Error = rpc.InterceptError_NoPreviewHost
-		return r
-	}
-
 	r.Error, r.Text = d.interceptStatus()
 	if r.Error != rpc.InterceptError_InterceptOk {
 		return r
 	}
 
+	if ir.Preview && d.trafficMgr.previewHost == "" {
+		// Without a Host that has Preview URLs enabled, edgectl makes the
+		// preview route itself, under a random token.
+		if ir.Mapping != "" {
+			r.Error = rpc.InterceptError_NoPreviewHost
+			return r
+		}
+		token, err := newPreviewToken()
+		if err != nil {
+			r.Error = rpc.InterceptError_FailedToEstablish
+			r.Text = err.Error()
+			return r
+		}
+		ir.Patterns = map[string]string{"x-service-preview": token}
+	}
+
 	for _, ic := range d.intercepts {
 		if ic.ii.Name == ir.Name {
 			r.Error = rpc.InterceptError_AlreadyExists
@@ -233,9 +251,7 @@ func (d *daemon) addIntercept(p *supervisor.Process, ir *rpc.InterceptRequest) *
 		return r
 	}
 
-	if d.trafficMgr.previewHost != "" {
-		r.PreviewURL = ii.PreviewURL(d.trafficMgr.previewHost)
-	}
+	r.PreviewURL = ic.PreviewURL()
 
 	d.intercepts = append(d.intercepts, ic)
 
@@ -284,9 +300,22 @@ type Intercept struct {
 	port          int
 	crc           Resource
 	mappingExists bool
+	// previewMapping and previewURL are set if edgectl made the intercept's
+	// preview route itself
+	previewMapping string
+	previewURL     string
 	ResourceBase
 }
 
+// PreviewURL returns the intercept's preview URL, or the empty string if it
+// doesn't have one.
+func (cept *Intercept) PreviewURL() string {
+	if cept.previewURL != "" {
+		return cept.previewURL
+	}
+	return cept.ii.PreviewURL(cept.tm.previewHost)
+}
+
 // removeMapping drops an Intercept's mapping if needed (and possible).
 func (cept *Intercept) removeMapping(p *supervisor.Process) error {
 	var err error
@@ -303,23 +332,89 @@ func (cept *Intercept) removeMapping(p *supervisor.Process) error {
 		return errors.Wrap(err, "Intercept: mapping could not be deleted")
 	}
 
+	if cept.previewMapping != "" {
+		del := cept.cluster.GetKubectlCmd(p, "delete", "-n", cept.ii.Namespace, "mapping", cept.previewMapping)
+		if err := del.Run(); err != nil {
+			return errors.Wrap(err, "Intercept: preview mapping could not be deleted")
+		}
+	}
+
 	return nil
 }
 
+// removeStaleMappings deletes the intercept Mappings that this machine left
+// behind in an earlier session, if it went away without removing them.
+func removeStaleMappings(p *supervisor.Process, cluster *KCluster, installID string) {
+	if installID == "" {
+		return
+	}
+	del := cluster.GetKubectlCmdNoNamespace(p, "delete", "mapping", "--all-namespaces", "-l", interceptOwnerLabel+"="+installID)
+	if err := del.Run(); err != nil {
+		p.Logf("Removing stale intercept mappings: %v", err)
+	}
+}
+
+// getMapping fetches the spec of a Mapping.
+func getMapping(p *supervisor.Process, cluster *KCluster, namespace, name string) (k8s.Map, error) {
+	get := cluster.GetKubectlCmdNoNamespace(p, "get", "-n", namespace, "mapping", name, "-o", "yaml")
+	outBytes, err := get.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "mapping %q in namespace %q: %s", name, namespace, strings.TrimSpace(string(outBytes)))
+	}
+	mappings, err := k8s.ParseResources("get mapping", string(outBytes))
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) != 1 {
+		return nil, errors.Errorf("weird result with length %d", len(mappings))
+	}
+	return mappings[0].Spec(), nil
+}
+
+// mappingMatch returns the prefix, whether it's a regex, and any header
+// regexes that pick out the requests a Mapping routes, as the deployment
+// behind it sees them. Ambassador rewrites the path to the Mapping's
+// rewrite, "/" by default; when it does, Envoy keeps the original path in
+// x-envoy-original-path, which is what tells this Mapping's requests apart
+// from other Mappings' for the same service.
+func mappingMatch(spec k8s.Map) (prefix string, prefixRegex bool, headers map[string]string) {
+	prefix = spec.GetString("prefix")
+	prefixRegex = spec.GetBool("prefix_regex")
+
+	rewrite := "/"
+	if _, ok := spec["rewrite"]; ok {
+		rewrite = spec.GetString("rewrite")
+	}
+	if rewrite == "" {
+		return prefix, prefixRegex, nil
+	}
+
+	// Header regexes have to match the whole value, and the original path
+	// includes the query string.
+	original := regexp.QuoteMeta(prefix) + ".*"
+	if prefixRegex {
+		original = "(" + prefix + ")(\\?.*)?"
+	}
+	return rewrite, false, map[string]string{"x-envoy-original-path": original}
+}
+
 type mappingMetadata struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels,omitempty"`
 }
 
 type mappingSpec struct {
-	AmbassadorID  []string          `json:"ambassador_id"`
-	Prefix        string            `json:"prefix"`
-	Rewrite       string            `json:"rewrite"`
-	Service       string            `json:"service"`
-	RegexHeaders  map[string]string `json:"regex_headers"`
-	GRPC          bool              `json:"grpc"`
-	TimeoutMs     int               `json:"timeout_ms"`
-	IdleTimeoutMs int               `json:"idle_timeout_ms"`
+	AmbassadorID      []string          `json:"ambassador_id,omitempty"`
+	Prefix            string            `json:"prefix"`
+	PrefixRegex       bool              `json:"prefix_regex,omitempty"`
+	Rewrite           string            `json:"rewrite"`
+	Service           string            `json:"service"`
+	RegexHeaders      map[string]string `json:"regex_headers,omitempty"`
+	AddRequestHeaders map[string]string `json:"add_request_headers,omitempty"`
+	GRPC              bool              `json:"grpc"`
+	TimeoutMs         int               `json:"timeout_ms"`
+	IdleTimeoutMs     int               `json:"idle_timeout_ms"`
 }
 
 type interceptMapping struct {
@@ -332,6 +427,23 @@ type interceptMapping struct {
 // MakeIntercept acquires an intercept and returns a Resource handle
 // for it
 func MakeIntercept(p *supervisor.Process, tm *TrafficManager, cluster *KCluster, ii *InterceptInfo) (*Intercept, error) {
+	prefix, prefixRegex, headers := ii.Prefix, false, ii.Patterns
+	if ii.Mapping != "" {
+		spec, err := getMapping(p, cluster, ii.Namespace, ii.Mapping)
+		if err != nil {
+			return nil, err
+		}
+		var mappingHeaders map[string]string
+		prefix, prefixRegex, mappingHeaders = mappingMatch(spec)
+		headers = make(map[string]string, len(ii.Patterns)+len(mappingHeaders))
+		for header, regex := range ii.Patterns {
+			headers[header] = regex
+		}
+		for header, regex := range mappingHeaders {
+			headers[header] = regex
+		}
+	}
+
 	port, err := ii.Acquire(p, tm)
 	if err != nil {
 		return nil, err
@@ -345,19 +457,32 @@ func MakeIntercept(p *supervisor.Process, tm *TrafficManager, cluster *KCluster,
 
 	p.Logf("%s: Intercepting via port %v, grpc %v, using namespace %v", ii.Name, port, ii.GRPC, ii.Namespace)
 
+	// The intercept passes the path through as it is.
+	rewrite := prefix
+	if prefixRegex {
+		rewrite = ""
+	}
+
+	var labels map[string]string
+	if tm.installID != "" {
+		labels = map[string]string{interceptOwnerLabel: tm.installID}
+	}
+
 	mapping := interceptMapping{
 		APIVersion: "getambassador.io/v2",
 		Kind:       "Mapping",
 		Metadata: mappingMetadata{
 			Name:      fmt.Sprintf("%s-mapping", ii.Name),
 			Namespace: ii.Namespace,
+			Labels:    labels,
 		},
 		Spec: mappingSpec{
 			AmbassadorID:  []string{fmt.Sprintf("intercept-%s", ii.Deployment)},
-			Prefix:        ii.Prefix,
-			Rewrite:       ii.Prefix,
+			Prefix:        prefix,
+			PrefixRegex:   prefixRegex,
+			Rewrite:       rewrite,
 			Service:       fmt.Sprintf("telepresence-proxy.%s:%d", tm.namespace, port),
-			RegexHeaders:  ii.Patterns,
+			RegexHeaders:  headers,
 			GRPC:          ii.GRPC, // Set the grpc flag on the Intercept mapping
 			TimeoutMs:     60000,   // Making sure we don't have shorter timeouts on intercepts than the original Mapping
 			IdleTimeoutMs: 60000,
@@ -382,6 +507,15 @@ func MakeIntercept(p *supervisor.Process, tm *TrafficManager, cluster *KCluster,
 
 	cept.mappingExists = true
 
+	if ii.Preview && tm.previewHost == "" {
+		cept.previewMapping, cept.previewURL, err = makePreviewMapping(p, cluster, ii, tm.installID)
+		if err != nil {
+			p.Logf("%v: %v", ii.Name, err)
+			_ = cept.Close()
+			return nil, err
+		}
+	}
+
 	sshCmd := []string{
 		"ssh", "-C", "-N", "telepresence@localhost",
 		"-oConnectTimeout=10", "-oExitOnForwardFailure=yes",
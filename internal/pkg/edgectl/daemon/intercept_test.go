@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/ambassador/pkg/k8s"
+)
+
+func TestMappingMatch(t *testing.T) {
+	// The default rewrite is "/".
+	prefix, prefixRegex, headers := mappingMatch(k8s.Map{"prefix": "/hello/v2/"})
+	assert.Equal(t, "/", prefix)
+	assert.False(t, prefixRegex)
+	original := regexp.MustCompile("^(?:" + headers["x-envoy-original-path"] + ")$")
+	assert.True(t, original.MatchString("/hello/v2/greet?name=jane"))
+	assert.False(t, original.MatchString("/hello/v1/greet"))
+
+	prefix, _, headers = mappingMatch(k8s.Map{"prefix": "/hello/", "rewrite": "/api/"})
+	assert.Equal(t, "/api/", prefix)
+	assert.Equal(t, `/hello/.*`, headers["x-envoy-original-path"])
+
+	// Without a rewrite, the path is all there is to go on.
+	prefix, prefixRegex, headers = mappingMatch(k8s.Map{"prefix": "/hello/[0-9]+", "prefix_regex": true, "rewrite": ""})
+	assert.Equal(t, "/hello/[0-9]+", prefix)
+	assert.True(t, prefixRegex)
+	assert.Nil(t, headers)
+
+	_, _, headers = mappingMatch(k8s.Map{"prefix": "/hello/[0-9]+", "prefix_regex": true})
+	original = regexp.MustCompile("^(?:" + headers["x-envoy-original-path"] + ")$")
+	assert.True(t, original.MatchString("/hello/42"))
+	assert.True(t, original.MatchString("/hello/42?x=1"))
+	assert.False(t, original.MatchString("/hello/42/more"))
+}
@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/datawire/ambassador/pkg/k8s"
+	"github.com/datawire/ambassador/pkg/supervisor"
+)
+
+// previewPrefix is where the preview routes that edgectl makes itself go.
+// They're for clusters without a Host that has Preview URLs enabled, whose
+// /.ambassador/service-preview/ routes need the Ambassador Edge Stack.
+const previewPrefix = "/.edgectl/preview/"
+
+// newPreviewToken returns a random token for a preview route.
+func newPreviewToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// makePreviewMapping creates a Mapping in the cluster's Ambassador that sends
+// requests under previewPrefix and the intercept's token to the service in
+// front of the intercepted deployment, with the header that the intercept
+// matches. It returns the Mapping's name and the preview URL.
+func makePreviewMapping(p *supervisor.Process, cluster *KCluster, ii *InterceptInfo, installID string) (name, url string, err error) {
+	token := ii.Patterns["x-service-preview"]
+	if token == "" {
+		return "", "", errors.New("preview route: no preview token")
+	}
+	service, err := findDeploymentService(p, cluster, ii.Namespace, ii.Deployment)
+	if err != nil {
+		return "", "", errors.Wrap(err, "preview route")
+	}
+
+	var labels map[string]string
+	if installID != "" {
+		labels = map[string]string{interceptOwnerLabel: installID}
+	}
+	rewrite := ii.Prefix
+	if rewrite == "" {
+		rewrite = "/"
+	}
+	name = fmt.Sprintf("%s-preview", ii.Name)
+	mapping := interceptMapping{
+		APIVersion: "getambassador.io/v2",
+		Kind:       "Mapping",
+		Metadata: mappingMetadata{
+			Name:      name,
+			Namespace: ii.Namespace,
+			Labels:    labels,
+		},
+		Spec: mappingSpec{
+			Prefix:            previewPrefix + token + "/",
+			Rewrite:           rewrite,
+			Service:           service,
+			AddRequestHeaders: map[string]string{"x-service-preview": token},
+			GRPC:              ii.GRPC,
+			TimeoutMs:         60000,
+			IdleTimeoutMs:     60000,
+		},
+	}
+	manifest, err := json.MarshalIndent(&mapping, "", "  ")
+	if err != nil {
+		return "", "", errors.Wrap(err, "preview route")
+	}
+	apply := cluster.GetKubectlCmdNoNamespace(p, "apply", "-f", "-")
+	apply.Stdin = strings.NewReader(string(manifest))
+	if err := apply.Run(); err != nil {
+		return "", "", errors.Wrap(err, "preview route: kubectl apply")
+	}
+
+	url = previewPrefix + token + "/"
+	if hostname := getClusterHostname(p, cluster); hostname != "" {
+		url = "https://" + hostname + url
+	}
+	return name, url, nil
+}
+
+// findDeploymentService returns the service, as name.namespace:port, whose
+// selector picks out a deployment's pods.
+func findDeploymentService(p *supervisor.Process, cluster *KCluster, namespace, deployment string) (string, error) {
+	get := cluster.GetKubectlCmdNoNamespace(p, "get", "-n", namespace, "deployment", deployment, "-o", "yaml")
+	outBytes, err := get.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "deployment %q: %s", deployment, strings.TrimSpace(string(outBytes)))
+	}
+	deployments, err := k8s.ParseResources("get deployment", string(outBytes))
+	if err != nil {
+		return "", err
+	}
+	if len(deployments) != 1 {
+		return "", errors.Errorf("weird result with length %d", len(deployments))
+	}
+	podLabels := k8s.Map(deployments[0].Spec().GetMap("template")).GetMap("metadata")
+	labels := k8s.Map(podLabels).GetMap("labels")
+
+	get = cluster.GetKubectlCmdNoNamespace(p, "get", "-n", namespace, "service", "-o", "yaml")
+	if outBytes, err = get.CombinedOutput(); err != nil {
+		return "", errors.Wrapf(err, "services: %s", strings.TrimSpace(string(outBytes)))
+	}
+	lists, err := k8s.ParseResources("get services", string(outBytes))
+	if err != nil {
+		return "", err
+	}
+	if len(lists) != 1 {
+		return "", errors.Errorf("weird result with length %d", len(lists))
+	}
+	for _, item := range k8s.Map(lists[0]).GetMaps("items") {
+		svc := k8s.Resource(item)
+		if !selects(svc.Spec().GetMap("selector"), labels) {
+			continue
+		}
+		ports := svc.Spec().GetMaps("ports")
+		if len(ports) == 0 {
+			continue
+		}
+		return fmt.Sprintf("%s.%s:%v", svc.Name(), namespace, ports[0]["port"]), nil
+	}
+	return "", errors.Errorf("no service selects the pods of deployment %q", deployment)
+}
+
+// selects returns whether a service selector picks out pods with labels.
+func selects(selector, labels map[string]interface{}) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// getClusterHostname returns the hostname of the first Host resource that
+// has one, or the empty string.
+func getClusterHostname(p *supervisor.Process, cluster *KCluster) string {
+	get := cluster.GetKubectlCmdNoNamespace(p, "get", "host", "-o", "yaml", "--all-namespaces")
+	outBytes, err := get.CombinedOutput()
+	if err != nil {
+		p.Logf("get hosts: %v", err)
+		return ""
+	}
+	lists, err := k8s.ParseResources("get hosts", string(outBytes))
+	if err != nil || len(lists) != 1 {
+		return ""
+	}
+	for _, item := range k8s.Map(lists[0]).GetMaps("items") {
+		hostname := k8s.Resource(item).Spec().GetString("hostname")
+		if hostname != "" && !strings.Contains(hostname, "*") {
+			return hostname
+		}
+	}
+	return ""
+}
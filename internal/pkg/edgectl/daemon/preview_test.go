@@ -0,0 +1,16 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelects(t *testing.T) {
+	labels := map[string]interface{}{"app": "quote", "tier": "backend"}
+	assert.True(t, selects(map[string]interface{}{"app": "quote"}, labels))
+	assert.True(t, selects(map[string]interface{}{"app": "quote", "tier": "backend"}, labels))
+	assert.False(t, selects(map[string]interface{}{"app": "quote", "tier": "frontend"}, labels))
+	// A service without a selector doesn't select any pods.
+	assert.False(t, selects(nil, labels))
+}
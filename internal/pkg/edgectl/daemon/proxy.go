@@ -0,0 +1,273 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+
+	"github.com/datawire/ambassador/internal/pkg/edgectl"
+	"github.com/datawire/ambassador/pkg/k8s"
+	"github.com/datawire/ambassador/pkg/supervisor"
+)
+
+// proxyPod is the in-cluster end of the proxy: an SSH server whose dynamic
+// port forwarding resolves and dials cluster names from inside the cluster.
+// It's the same pod that teleproxy's bridge uses.
+const proxyPod = `
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: teleproxy
+  labels:
+    name: teleproxy
+spec:
+  hostname: traffic-proxy
+  containers:
+  - name: proxy
+    image: docker.io/datawire/telepresence-k8s:0.75
+    ports:
+    - protocol: TCP
+      containerPort: 8022
+`
+
+// RunAsProxy is the main function when executing as the proxy. It doesn't
+// need root: instead of overriding the network, it gives access to the
+// cluster through a SOCKS5 proxy at socksAddr and, unless httpAddr is
+// empty, an HTTP proxy at httpAddr, until it's interrupted.
+func RunAsProxy(kubeinfo *k8s.KubeInfo, socksAddr, httpAddr string) error {
+	kubectl := func(p *supervisor.Process, args ...string) (*supervisor.Cmd, error) {
+		kargs, err := kubeinfo.GetKubectlArray(args...)
+		if err != nil {
+			return nil, err
+		}
+		return p.Command("kubectl", kargs...), nil
+	}
+
+	sshPort, err := getLocalPort()
+	if err != nil {
+		return err
+	}
+
+	sup := supervisor.WithContext(context.Background())
+	sup.Supervise(&supervisor.Worker{
+		Name: "signal",
+		Work: WaitForSignal,
+	})
+	sup.Supervise(&supervisor.Worker{
+		Name: "apply",
+		Work: func(p *supervisor.Process) error {
+			apply, err := kubectl(p, "apply", "-f", "-")
+			if err != nil {
+				return err
+			}
+			apply.Stdin = strings.NewReader(proxyPod)
+			if err := apply.Start(); err != nil {
+				return err
+			}
+			if err := p.DoClean(apply.Wait, apply.Process.Kill); err != nil {
+				return err
+			}
+			wait, err := kubectl(p, "wait", "--for=condition=Ready", "--timeout=120s", "pod/teleproxy")
+			if err != nil {
+				return err
+			}
+			if err := wait.Run(); err != nil {
+				return errors.Wrap(err, "waiting for the teleproxy pod")
+			}
+			p.Ready()
+			// stay alive so that the workers that require this one can start
+			<-p.Shutdown()
+			return nil
+		},
+	})
+	sup.Supervise(&supervisor.Worker{
+		Name:     "port-forward",
+		Requires: []string{"apply"},
+		Retry:    true,
+		Work: func(p *supervisor.Process) error {
+			pf, err := kubectl(p, "port-forward", "pod/teleproxy", fmt.Sprintf("%d:8022", sshPort))
+			if err != nil {
+				return err
+			}
+			if err := pf.Start(); err != nil {
+				return err
+			}
+			p.Ready()
+			return p.DoClean(pf.Wait, pf.Process.Kill)
+		},
+	})
+	sup.Supervise(&supervisor.Worker{
+		Name:     "ssh",
+		Requires: []string{"port-forward"},
+		Retry:    true,
+		Work: func(p *supervisor.Process) error {
+			ssh := p.Command("ssh", "-D", socksAddr, "-C", "-N", "-oConnectTimeout=5",
+				"-oExitOnForwardFailure=yes", "-oStrictHostKeyChecking=no",
+				"-oUserKnownHostsFile=/dev/null", "telepresence@localhost", "-p", fmt.Sprint(sshPort))
+			if err := ssh.Start(); err != nil {
+				return err
+			}
+			p.Ready()
+			p.Logf("SOCKS5 proxy listening on %s", socksAddr)
+			return p.DoClean(ssh.Wait, ssh.Process.Kill)
+		},
+	})
+	if httpAddr != "" {
+		sup.Supervise(&supervisor.Worker{
+			Name:     "http",
+			Requires: []string{"ssh"},
+			Work: func(p *supervisor.Process) error {
+				socks, err := proxy.SOCKS5("tcp", socksAddr, nil, proxy.Direct)
+				if err != nil {
+					return err
+				}
+				srv := &http.Server{
+					Addr:    httpAddr,
+					Handler: newHTTPProxy(socks.Dial),
+				}
+				p.Ready()
+				p.Logf("HTTP proxy listening on %s", httpAddr)
+				return p.DoClean(func() error {
+					if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+						return err
+					}
+					return nil
+				}, func() error {
+					return srv.Shutdown(context.Background())
+				})
+			},
+		})
+	}
+
+	sup.Logger.Printf("Edge Control proxy %s starting...", edgectl.DisplayVersion())
+	runErrors := sup.Run()
+	if len(runErrors) > 0 {
+		sup.Logger.Printf("proxy has exited with %d error(s):", len(runErrors))
+		for _, err := range runErrors {
+			sup.Logger.Printf("- %v", err)
+		}
+		return errors.New("edgectl proxy has exited")
+	}
+	return nil
+}
+
+// getLocalPort returns a port on localhost that's free right now.
+func getLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// hopHeaders are the headers that apply to one connection, and so aren't
+// passed along by a proxy.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// httpProxy is an HTTP proxy that makes its connections with dial. It
+// tunnels CONNECT requests, and forwards requests for absolute URLs.
+type httpProxy struct {
+	dial      func(network, addr string) (net.Conn, error)
+	transport *http.Transport
+}
+
+func newHTTPProxy(dial func(network, addr string) (net.Conn, error)) *httpProxy {
+	return &httpProxy{
+		dial: dial,
+		transport: &http.Transport{
+			Dial:                dial,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+func (h *httpProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		h.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "this is a proxy: request an absolute URL, or use CONNECT", http.StatusBadRequest)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, header := range hopHeaders {
+		out.Header.Del(header)
+	}
+	resp, err := h.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range hopHeaders {
+		resp.Header.Del(header)
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func (h *httpProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "CONNECT is not supported", http.StatusInternalServerError)
+		return
+	}
+	upstream, err := h.dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		return
+	}
+
+	// The client may have sent more than the CONNECT request already, so
+	// read through the buffer that came with it.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(upstream, buffered)
+		if tcp, ok := upstream.(interface{ CloseWrite() error }); ok {
+			_ = tcp.CloseWrite()
+		}
+	}()
+	_, _ = io.Copy(client, upstream)
+	_ = client.Close()
+	wg.Wait()
+}
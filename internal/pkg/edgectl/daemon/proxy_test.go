@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProxy(t *testing.T) {
+	backend := func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Proxy-Connection"))
+		fmt.Fprintf(w, "hello from %s", r.URL.Path)
+	}
+	plain := httptest.NewServer(http.HandlerFunc(backend))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(backend))
+	defer secure.Close()
+
+	var dialed []string
+	prx := httptest.NewServer(newHTTPProxy(func(network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return net.Dial(network, addr)
+	}))
+	defer prx.Close()
+	prxURL, err := url.Parse(prx.URL)
+	require.NoError(t, err)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(prxURL),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	get := func(url string) string {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// Plain HTTP is forwarded, and HTTPS is tunneled with CONNECT.
+	assert.Equal(t, "hello from /plain", get(plain.URL+"/plain"))
+	assert.Equal(t, "hello from /secure", get(secure.URL+"/secure"))
+	assert.Equal(t, []string{plain.Listener.Addr().String(), secure.Listener.Addr().String()}, dialed)
+
+	// It's only a proxy.
+	resp, err := http.Get(prx.URL + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,144 @@
+package edgectl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pollIntervalUnit is what the provider's polling intervals, which are in
+// seconds, are multiplied by. Tests shorten it.
+var pollIntervalUnit = time.Second
+
+var oidcClient = &http.Client{Timeout: 15 * time.Second}
+
+type oidcDiscovery struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// DeviceLogin logs in to an OpenID Connect provider with the device
+// authorization flow (RFC 8628) and returns the ID token. The user visits a
+// URL and enters a code on any device, so this works on machines without a
+// browser, such as jump hosts.
+func DeviceLogin(out io.Writer, issuer, clientID string, scopes []string) (string, error) {
+	var discovery oidcDiscovery
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(wellKnown, &discovery); err != nil {
+		return "", errors.Wrap(err, "OIDC discovery")
+	}
+	if discovery.DeviceAuthorizationEndpoint == "" {
+		return "", errors.Errorf("OIDC provider %s doesn't support the device authorization flow", issuer)
+	}
+
+	var auth deviceAuthorization
+	err := postForm(discovery.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {clientID},
+		"scope":     {strings.Join(append([]string{"openid"}, scopes...), " ")},
+	}, &auth)
+	if err != nil {
+		return "", errors.Wrap(err, "OIDC device authorization")
+	}
+	if auth.DeviceCode == "" || auth.VerificationURI == "" {
+		return "", errors.New("OIDC device authorization: incomplete response from the provider")
+	}
+
+	if auth.VerificationURIComplete != "" {
+		fmt.Fprintf(out, "To log in, visit the following URL on any device:\n    %s\n", auth.VerificationURIComplete)
+		fmt.Fprintf(out, "and check that it shows the code %s\n", auth.UserCode)
+	} else {
+		fmt.Fprintf(out, "To log in, visit the following URL on any device:\n    %s\n", auth.VerificationURI)
+		fmt.Fprintf(out, "and enter the code %s\n", auth.UserCode)
+	}
+
+	interval := time.Duration(auth.Interval) * pollIntervalUnit
+	if auth.Interval <= 0 {
+		interval = 5 * pollIntervalUnit
+	}
+	expiresIn := auth.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = 600
+	}
+	deadline := time.Now().Add(time.Duration(expiresIn) * pollIntervalUnit)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		var token tokenResponse
+		err := postForm(discovery.TokenEndpoint, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {auth.DeviceCode},
+			"client_id":   {clientID},
+		}, &token)
+		switch token.Error {
+		case "":
+			if err != nil {
+				return "", errors.Wrap(err, "OIDC token")
+			}
+			if token.IDToken == "" {
+				return "", errors.New("OIDC token: the provider returned no ID token")
+			}
+			return token.IDToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * pollIntervalUnit
+		case "access_denied":
+			return "", errors.New("OIDC login was denied")
+		case "expired_token":
+			return "", errors.New("OIDC login code expired; please try again")
+		default:
+			return "", errors.Errorf("OIDC token: %s: %s", token.Error, token.ErrorDescription)
+		}
+	}
+	return "", errors.New("OIDC login code expired; please try again")
+}
+
+// getJSON fetches a URL and decodes the JSON response into v
+func getJSON(u string, v interface{}) error {
+	res, err := oidcClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("GET %s: %s", u, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// postForm posts a form and decodes the JSON response into v. OAuth errors
+// come in the JSON of 4xx responses, so v is decoded even when the status
+// isn't OK, and the error says so.
+func postForm(u string, form url.Values, v interface{}) error {
+	res, err := oidcClient.PostForm(u, form)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return errors.Wrapf(err, "POST %s: %s", u, res.Status)
+	}
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("POST %s: %s", u, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package edgectl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newDeviceFlowServer returns an OIDC provider whose token endpoint answers
+// with the given responses in turn.
+func newDeviceFlowServer(t *testing.T, tokenResponses ...map[string]string) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"device_authorization_endpoint": server.URL + "/device",
+			"token_endpoint":                server.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "edgectl", r.FormValue("client_id"))
+		assert.Equal(t, "openid email", r.FormValue("scope"))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": server.URL + "/activate",
+			"expires_in":       100,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:device_code", r.FormValue("grant_type"))
+		assert.Equal(t, "device-code", r.FormValue("device_code"))
+		if !assert.NotEmpty(t, tokenResponses) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response := tokenResponses[0]
+		tokenResponses = tokenResponses[1:]
+		if response["error"] != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	})
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestDeviceLogin(t *testing.T) {
+	pollIntervalUnit = time.Millisecond
+	defer func() { pollIntervalUnit = time.Second }()
+
+	server := newDeviceFlowServer(t,
+		map[string]string{"error": "authorization_pending"},
+		map[string]string{"error": "slow_down"},
+		map[string]string{"id_token": "id-token", "access_token": "access-token"},
+	)
+	defer server.Close()
+	token, err := DeviceLogin(ioutil.Discard, server.URL, "edgectl", []string{"email"})
+	assert.NoError(t, err)
+	assert.Equal(t, "id-token", token)
+
+	server = newDeviceFlowServer(t, map[string]string{"error": "access_denied"})
+	defer server.Close()
+	_, err = DeviceLogin(ioutil.Discard, server.URL, "edgectl", []string{"email"})
+	assert.EqualError(t, err, "OIDC login was denied")
+}
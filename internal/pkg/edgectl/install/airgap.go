@@ -0,0 +1,86 @@
+package edgectl
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	k8sTypesMetaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var validDigest = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// imageRef is an image reference, REPOSITORY[:TAG][@DIGEST]
+type imageRef struct {
+	repo   string
+	tag    string
+	digest string
+}
+
+// parseImageRef splits an image reference into its parts. A registry host
+// may have a port, so the tag is after the last colon that comes after the
+// last slash.
+func parseImageRef(ref string) (imageRef, error) {
+	var image imageRef
+	rest := ref
+	if at := strings.IndexByte(ref, '@'); at >= 0 {
+		rest, image.digest = ref[:at], ref[at+1:]
+		if !validDigest.MatchString(image.digest) {
+			return imageRef{}, errors.Errorf("image %q: the digest isn't sha256:<64 hex digits>", ref)
+		}
+	}
+	slash := strings.LastIndexByte(rest, '/')
+	if colon := strings.LastIndexByte(rest, ':'); colon > slash {
+		rest, image.tag = rest[:colon], rest[colon+1:]
+		if image.tag == "" {
+			return imageRef{}, errors.Errorf("image %q: empty tag", ref)
+		}
+	}
+	if rest == "" || strings.HasSuffix(rest, "/") {
+		return imageRef{}, errors.Errorf("image %q: no repository", ref)
+	}
+	image.repo = rest
+	return image, nil
+}
+
+// aesImage returns the image of the AES container, as it is in the pod spec
+func (i *Installer) aesImage() string {
+	aesImage := i.imageRepo + ":" + i.version
+	if i.image.digest != "" {
+		aesImage += "@" + i.image.digest
+	}
+	return aesImage
+}
+
+// VerifyAESImageDigest checks that the container runtime started the AES
+// containers from the image with the digest given with --image.
+func (i *Installer) VerifyAESImageDigest() error {
+	aesImage := i.aesImage()
+	pods, err := i.coreClient.Pods(defInstallNamespace).List(context.TODO(), k8sTypesMetaV1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	verified := 0
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			if container.Image != aesImage {
+				continue
+			}
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Name != container.Name {
+					continue
+				}
+				i.log.Printf("Pod %s container %s: image ID %q", pod.Name, status.Name, status.ImageID)
+				if !strings.HasSuffix(status.ImageID, "@"+i.image.digest) {
+					return errors.Errorf("pod %s runs image %q, not %s", pod.Name, status.ImageID, i.image.digest)
+				}
+				verified++
+			}
+		}
+	}
+	if verified == 0 {
+		return errors.Errorf("no running AES containers with image %s", aesImage)
+	}
+	return nil
+}
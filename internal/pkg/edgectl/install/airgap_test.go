@@ -0,0 +1,32 @@
+package edgectl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseImageRef(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("0123456789abcdef", 4)
+	for ref, expected := range map[string]imageRef{
+		"datawire/aes":                                     {repo: "datawire/aes"},
+		"docker.io/datawire/aes:1.9.0":                     {repo: "docker.io/datawire/aes", tag: "1.9.0"},
+		"registry.example.com:5000/datawire/aes:1.9.0":     {repo: "registry.example.com:5000/datawire/aes", tag: "1.9.0"},
+		"registry.example.com:5000/datawire/aes@" + digest: {repo: "registry.example.com:5000/datawire/aes", digest: digest},
+		"datawire/aes:1.9.0@" + digest:                     {repo: "datawire/aes", tag: "1.9.0", digest: digest},
+	} {
+		image, err := parseImageRef(ref)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", ref, err)
+			continue
+		}
+		if image != expected {
+			t.Errorf("%q: expected %+v, got %+v", ref, expected, image)
+		}
+	}
+
+	for _, ref := range []string{"", "datawire/aes:", "registry.example.com/", "datawire/aes@sha256:1234", ":1.9.0"} {
+		if _, err := parseImageRef(ref); err == nil {
+			t.Errorf("%q: expected an error", ref)
+		}
+	}
+}
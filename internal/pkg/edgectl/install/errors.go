@@ -122,6 +122,32 @@ func (i *Installer) resDownloadError(err error) Result {
 	}
 }
 
+// A --set value that isn't KEY=VALUE
+func (i *Installer) resChartSettingError(setting string, err error) Result {
+	return Result{
+		ShortMessage: fmt.Sprintf("The chart value %q is not KEY=VALUE", setting),
+		Err:          errors.Wrap(err, "--set"),
+	}
+}
+
+// The AES pods don't run the image with the digest given with --image.
+func (i *Installer) resImageDigestError(err error) Result {
+	url := "https://www.getambassador.io/docs/latest/topics/install/help/air-gapped"
+
+	message := "<bold>The Ambassador Edge Stack pods are not running the image that you gave with --image.</>"
+	message += "\n\n"
+	message += "Check that your registry serves the image with that digest, and that no other Ambassador is installed in the ambassador namespace.\n"
+	message += fmt.Sprintf("Find a more detailed explanation and step-by-step instructions at %v", url)
+
+	return Result{
+		Report:       "fail_image_digest",
+		ShortMessage: "The AES image digest does not match",
+		Message:      message,
+		URL:          url,
+		Err:          errors.Wrap(err, "image digest"),
+	}
+}
+
 func (i *Installer) resCantReplaceExistingInstallationError(installedVersion string) Result {
 	url := "https://www.getambassador.io/docs/latest/topics/install/help/existing-installation"
 
@@ -281,6 +307,28 @@ func (i *Installer) resKnownLocalClusterResult(ci clusterInfo) Result {
 	}
 }
 
+// Air-gapped installation: no automatic TLS, and no DNS name.
+func (i *Installer) resAirGappedResult() Result {
+	url := "https://www.getambassador.io/docs/latest/topics/install/help/air-gapped"
+
+	message := "<bold>You've successfully installed the Ambassador Edge Stack in your air-gapped Kubernetes cluster. Without the Internet, we could not configure TLS automatically.</>"
+	message += "\n\n"
+	message += "Create a Host for your hostname, with a TLS certificate from your own certificate authority.\n"
+	message += "Determine the IP address and port number of your Ambassador service, e.g.\n"
+	message += "<bold>$ kubectl get services -n ambassador ambassador</>\n\n"
+	message += "The following command will show the URL of the Edge Policy Console.\n"
+	message += "<bold>$ edgectl login --url -n ambassador HOSTNAME_OR_IP_ADDRESS</>"
+	message += "\n\n"
+	message += fmt.Sprintf("Find a more detailed explanation and step-by-step instructions at %v", url)
+
+	return Result{
+		Report:  "air_gapped",
+		Message: message,
+		URL:     url,
+		Err:     nil,
+	}
+}
+
 // Unable to provision a load balancer (failed to retrieve the IP address)
 func (i *Installer) resLoadBalancerError(err error) Result {
 	url := "https://www.getambassador.io/docs/latest/topics/install/help/load-balancer"
@@ -78,13 +78,36 @@ func AESInstall(cmd *cobra.Command, args []string) error {
 	skipReport, _ := cmd.Flags().GetBool("no-report")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	kcontext, _ := cmd.Flags().GetString("context")
-	i := NewInstaller(verbose)
+	jsonOutput := client.OutputJSON(cmd)
+	i := NewInstaller(verbose, jsonOutput)
+
+	// Options for air-gapped installations
+	i.airGapped, _ = cmd.Flags().GetBool("air-gapped")
+	i.chartPath, _ = cmd.Flags().GetString("chart")
+	i.chartSettings, _ = cmd.Flags().GetStringArray("set")
+	if ref, _ := cmd.Flags().GetString("image"); ref != "" {
+		image, err := parseImageRef(ref)
+		if err != nil {
+			return err
+		}
+		i.image = image
+	}
+	if i.airGapped {
+		if i.chartPath == "" || i.image.digest == "" {
+			return errors.New("--air-gapped needs a local --chart and an --image with a digest")
+		}
+		skipReport = true
+	}
 
 	// If Scout is disabled (environment variable set to non-null), inform the user.
 	if metriton.IsDisabledByUser() {
 		i.ShowScoutDisabled()
 	}
 
+	if i.airGapped {
+		i.ShowAirGapped()
+	}
+
 	// Both printed and logged when verbose (Installer.log is responsible for --verbose)
 	i.log.Printf("INFO: install_id = %v; trace_id = %v",
 		i.scout.Reporter.InstallID(),
@@ -108,12 +131,13 @@ func AESInstall(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 	})
+	var result Result
 	sup.Supervise(&supervisor.Worker{
 		Name:     "install",
 		Requires: []string{"signal"},
 		Work: func(p *supervisor.Process) error {
 			defer i.Quit()
-			result := i.Perform(kcontext)
+			result = i.Perform(kcontext)
 			i.ShowResult(result)
 			return result.Err
 		},
@@ -125,6 +149,11 @@ func AESInstall(cmd *cobra.Command, args []string) error {
 	browser.Stderr = ioutil.Discard
 
 	runErrors := sup.Run()
+	if jsonOutput {
+		if err := client.PrintJSON(os.Stdout, i.makeInstallOutput(result, runErrors)); err != nil {
+			i.log.Printf("Failed to write JSON output: %+v", err)
+		}
+	}
 	if len(runErrors) > 1 { // This shouldn't happen...
 		for _, err := range runErrors {
 			i.show.Printf(err.Error())
@@ -140,12 +169,44 @@ func AESInstall(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// installOutput is the JSON output of "edgectl install". Scripts depend on it,
+// so add fields rather than rename or remove them.
+type installOutput struct {
+	Success   bool   `json:"success"`
+	Version   string `json:"version,omitempty"`
+	Address   string `json:"address,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	ClusterID string `json:"cluster_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	DocsURL   string `json:"docs_url,omitempty"`
+	LogFile   string `json:"log_file"`
+}
+
+// makeInstallOutput summarizes an installation attempt for JSON output.
+func (i *Installer) makeInstallOutput(result Result, runErrors []error) *installOutput {
+	out := &installOutput{
+		Success:   len(runErrors) == 0,
+		Version:   i.version,
+		Address:   i.address,
+		Hostname:  i.hostname,
+		ClusterID: i.clusterID,
+		Reason:    result.Report,
+		DocsURL:   result.URL,
+		LogFile:   i.logName,
+	}
+	if len(runErrors) > 0 {
+		out.Error = runErrors[0].Error()
+	}
+	return out
+}
+
 // GrabAESInstallID uses "kubectl exec" to ask the AES pod for the cluster's ID,
 // which we uses as the AES install ID. This has the side effect of making sure
 // the Pod is Running (though not necessarily Ready). This should be good enough
 // to report the "deploy" status to metrics.
 func (i *Installer) GrabAESInstallID() error {
-	aesImage := i.imageRepo + ":" + i.version
+	aesImage := i.aesImage()
 	i.log.Printf("> aesImage = %s", aesImage)
 	podName := ""
 	containerName := ""
@@ -370,9 +431,14 @@ func (i *Installer) Perform(kcontext string) Result {
 	// Bold: Installing the Ambassador Edge Stack
 	i.ShowFirstInstalling()
 
-	emailAddress, result := i.AskEmail()
-	if result.Err != nil {
-		return result
+	// The email address is for ACME, which an air-gapped cluster can't use
+	var emailAddress string
+	var result Result
+	if !i.airGapped {
+		emailAddress, result = i.AskEmail()
+		if result.Err != nil {
+			return result
+		}
 	}
 
 	// Beginning the AES Installation
@@ -489,6 +555,10 @@ func (i *Installer) Perform(kcontext string) Result {
 		i.ShowOverridingHelmRepo(defEnvVarHelmRepo, u)
 		helmDownloaderOptions.URL = u
 	}
+	if i.chartPath != "" {
+		i.ShowUsingLocalChart(i.chartPath)
+		helmDownloaderOptions.URL = i.chartPath
+	}
 
 	// create a new manager for the remote Helm repo URL
 	chartDown, err := helm.NewHelmDownloader(helmDownloaderOptions)
@@ -502,11 +572,33 @@ func (i *Installer) Perform(kcontext string) Result {
 	}
 	defer func() { _ = chartDown.Cleanup() }()
 
+	if i.image.repo != "" {
+		i.ShowUsingImage(i.image)
+		i.imageRepo = i.image.repo
+		i.version = i.image.tag
+	}
+
 	if i.version == "" {
 		// set the AES version to the version in the Chart we have downloaded
 		i.version = strings.Trim(chartDown.GetChart().AppVersion, "\n")
 	}
 
+	if i.image.repo != "" {
+		// The tag is ignored when there's a digest, but the chart needs one
+		strvals.ParseInto(fmt.Sprintf("image.repository=%s", i.image.repo), chartValues)
+		if i.image.digest != "" {
+			strvals.ParseInto(fmt.Sprintf("image.tag=%s@%s", i.version, i.image.digest), chartValues)
+		} else {
+			strvals.ParseInto(fmt.Sprintf("image.tag=%s", i.version), chartValues)
+		}
+	}
+
+	for _, setting := range i.chartSettings {
+		if err := strvals.ParseInto(setting, chartValues); err != nil {
+			return i.resChartSettingError(setting, err)
+		}
+	}
+
 	if installedInfo.Method == instHelm || installedInfo.Method == instEdgectl {
 		// if a previous installation was found, check that the installed version matches
 		// the downloaded chart version, because we do not support upgrades
@@ -552,6 +644,20 @@ func (i *Installer) Perform(kcontext string) Result {
 	}
 	i.Report("deploy")
 
+	if i.image.digest != "" {
+		i.ShowVerifyingImageDigest()
+		if err := i.VerifyAESImageDigest(); err != nil {
+			return i.resImageDigestError(err)
+		}
+	}
+
+	// An air-gapped cluster can't get a DNS name or a certificate from the
+	// Internet, so this is as far as the installer goes.
+	if i.airGapped {
+		i.ShowAESInstallationPartiallyComplete()
+		return i.resAirGappedResult()
+	}
+
 	// Don't proceed any further if we know we are using a local (not publicly
 	// accessible) cluster. There's no point wasting the user's time on
 	// timeouts.
@@ -640,6 +746,13 @@ type Installer struct {
 	coreClient  *k8sClientCoreV1.CoreV1Client
 	clusterinfo clusterInfo
 
+	// Air-gapped installation
+
+	airGapped     bool     // make no network calls, except to the cluster
+	chartPath     string   // local chart directory or archive
+	chartSettings []string // extra chart values, KEY=VALUE
+	image         imageRef // AES image to install
+
 	// Reporting
 
 	scout *client.Scout
@@ -649,6 +762,7 @@ type Installer struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	show    *log.Logger
+	json    bool // stdout is for the JSON result
 	log     *log.Logger
 	cmdOut  *log.Logger
 	cmdErr  *log.Logger
@@ -664,8 +778,9 @@ type Installer struct {
 	clusterID  string            // the Ambassador unique clusterID
 }
 
-// NewInstaller returns an Installer object after setting up logging.
-func NewInstaller(verbose bool) *Installer {
+// NewInstaller returns an Installer object after setting up logging. With
+// jsonOutput, messages for the user go to stderr, keeping stdout for JSON.
+func NewInstaller(verbose, jsonOutput bool) *Installer {
 	// Although log, cmdOut, and cmdErr *can* go to different files and/or have
 	// different prefixes, they'll probably all go to the same file, possibly
 	// with different prefixes, for most cases.
@@ -682,8 +797,12 @@ func NewInstaller(verbose bool) *Installer {
 		ctx:     ctx,
 		cancel:  cancel,
 		show:    log.New(io.MultiWriter(os.Stdout, logfile), "", 0),
+		json:    jsonOutput,
 		logName: logfileName,
 	}
+	if jsonOutput {
+		i.show = log.New(io.MultiWriter(os.Stderr, logfile), "", 0)
+	}
 
 	if verbose {
 		i.log = log.New(io.MultiWriter(logfile, NewLoggingWriter(log.New(os.Stderr, "== ", 0))), "", log.Ltime)
@@ -714,6 +833,9 @@ func (i *Installer) SetMetadatum(name, key string, value interface{}) {
 // Report sends an event to Metriton
 func (i *Installer) Report(eventName string, meta ...client.ScoutMeta) {
 	i.log.Println("[Metrics]", eventName)
+	if i.airGapped {
+		return
+	}
 	if err := i.scout.Report(eventName, meta...); err != nil {
 		i.log.Println("[Metrics]", eventName, err)
 	}
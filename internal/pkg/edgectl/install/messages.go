@@ -21,6 +21,10 @@ func (i *Installer) ShowScoutDisabled() {
 	i.show.Printf("INFO: phone-home is disabled by environment variable")
 }
 
+func (i *Installer) ShowAirGapped() {
+	i.show.Println("INFO: air-gapped installation: no network calls except to the cluster")
+}
+
 func (i *Installer) ShowRequestEmail() {
 	i.show.Println()
 	i.ShowWrapped("Please enter an email address for us to notify you before your TLS certificate and domain name expire. In order to acquire the TLS certificate, we share this email with Let’s Encrypt.")
@@ -83,6 +87,18 @@ func (i *Installer) ShowOverridingHelmRepo(aesHelmRepo string, repo string) {
 	i.show.Println(fmt.Sprintf("   Overriding Helm repo using %s = %s", aesHelmRepo, repo))
 }
 
+func (i *Installer) ShowUsingLocalChart(path string) {
+	i.show.Println(fmt.Sprintf("   Using the local chart in %s", path))
+}
+
+func (i *Installer) ShowUsingImage(image imageRef) {
+	if image.digest != "" {
+		i.show.Println(fmt.Sprintf("   Using image %s with digest %s", image.repo, image.digest))
+	} else {
+		i.show.Println(fmt.Sprintf("   Using image %s", image.repo))
+	}
+}
+
 func (i *Installer) ShowAESCRDsButNoAESInstallation() {
 	i.show.Println("-> Found Ambassador CRDs in your cluster, but no Edge Stack installation.")
 }
@@ -103,6 +119,10 @@ func (i *Installer) ShowCheckingAESPodDeployment() {
 	i.show.Println("-> Checking the AES pod deployment")
 }
 
+func (i *Installer) ShowVerifyingImageDigest() {
+	i.show.Println("-> Verifying the digest of the AES image")
+}
+
 func (i *Installer) ShowLocalClusterDetected() {
 	i.show.Println("-> Local cluster detected. Not configuring automatic TLS.")
 }
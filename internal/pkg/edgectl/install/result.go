@@ -107,7 +107,7 @@ func (i *Installer) ShowResult(r Result) {
 			i.show.Println()
 			i.ShowTemplated(r.Message, templateData...)
 
-			if r.URL != "" {
+			if r.URL != "" && !i.json && !i.airGapped {
 				i.show.Println()
 
 				if err := browser.OpenURL(r.URL); err != nil {
@@ -126,7 +126,7 @@ func (i *Installer) ShowResult(r Result) {
 			i.show.Println()
 			i.ShowTemplated(r.Message, templateData...)
 
-			if r.URL != "" {
+			if r.URL != "" && !i.json && !i.airGapped {
 				i.show.Println()
 
 				if err := browser.OpenURL(r.URL); err != nil {
@@ -18,7 +18,7 @@ import (
 
 var validEmailAddress = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
-func getEmailAddress(defaultEmail string, log *log.Logger) string {
+func getEmailAddress(defaultEmail string, out io.Writer, log *log.Logger) string {
 	prompt := fmt.Sprintf("Email address [%s]: ", defaultEmail)
 	errorFallback := defaultEmail
 	if defaultEmail == "" {
@@ -27,7 +27,7 @@ func getEmailAddress(defaultEmail string, log *log.Logger) string {
 	}
 
 	for {
-		fmt.Print(prompt)
+		fmt.Fprint(out, prompt)
 		scanner := bufio.NewScanner(os.Stdin)
 		scanner.Scan()
 		text := scanner.Text()
@@ -45,7 +45,7 @@ func getEmailAddress(defaultEmail string, log *log.Logger) string {
 			return text
 		}
 
-		fmt.Printf("Sorry, %q does not appear to be a valid email address.  Please check it and try again.\n", text)
+		fmt.Fprintf(out, "Sorry, %q does not appear to be a valid email address.  Please check it and try again.\n", text)
 	}
 }
 
@@ -69,7 +69,11 @@ func (i *Installer) AskEmail() (string, Result) {
 	gotEmail := make(chan string)
 	var emailAddress string
 	go func() {
-		gotEmail <- getEmailAddress(defaultEmail, i.log)
+		out := io.Writer(os.Stdout)
+		if i.json {
+			out = os.Stderr
+		}
+		gotEmail <- getEmailAddress(defaultEmail, out, i.log)
 		close(gotEmail)
 	}()
 	select {
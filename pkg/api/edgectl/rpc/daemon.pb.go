@@ -375,6 +375,110 @@ func (StatusResponse_ErrType) EnumDescriptor() ([]byte, []int) {
 	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{7, 0}
 }
 
+type DoctorResponse_Result int32
+
+const (
+	DoctorResponse_Pass    DoctorResponse_Result = 0
+	DoctorResponse_Fail    DoctorResponse_Result = 1
+	DoctorResponse_Skipped DoctorResponse_Result = 2
+)
+
+// Enum value maps for DoctorResponse_Result.
+var (
+	DoctorResponse_Result_name = map[int32]string{
+		0: "Pass",
+		1: "Fail",
+		2: "Skipped",
+	}
+	DoctorResponse_Result_value = map[string]int32{
+		"Pass":    0,
+		"Fail":    1,
+		"Skipped": 2,
+	}
+)
+
+func (x DoctorResponse_Result) Enum() *DoctorResponse_Result {
+	p := new(DoctorResponse_Result)
+	*p = x
+	return p
+}
+
+func (x DoctorResponse_Result) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DoctorResponse_Result) Descriptor() protoreflect.EnumDescriptor {
+	return file_edgectl_rpc_daemon_proto_enumTypes[6].Descriptor()
+}
+
+func (DoctorResponse_Result) Type() protoreflect.EnumType {
+	return &file_edgectl_rpc_daemon_proto_enumTypes[6]
+}
+
+func (x DoctorResponse_Result) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DoctorResponse_Result.Descriptor instead.
+func (DoctorResponse_Result) EnumDescriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{8, 0}
+}
+
+type ForwardResponse_ErrType int32
+
+const (
+	ForwardResponse_Ok                ForwardResponse_ErrType = 0
+	ForwardResponse_NoConnection      ForwardResponse_ErrType = 1
+	ForwardResponse_AlreadyExists     ForwardResponse_ErrType = 2
+	ForwardResponse_FailedToEstablish ForwardResponse_ErrType = 3
+	ForwardResponse_NotFound          ForwardResponse_ErrType = 4
+)
+
+// Enum value maps for ForwardResponse_ErrType.
+var (
+	ForwardResponse_ErrType_name = map[int32]string{
+		0: "Ok",
+		1: "NoConnection",
+		2: "AlreadyExists",
+		3: "FailedToEstablish",
+		4: "NotFound",
+	}
+	ForwardResponse_ErrType_value = map[string]int32{
+		"Ok":                0,
+		"NoConnection":      1,
+		"AlreadyExists":     2,
+		"FailedToEstablish": 3,
+		"NotFound":          4,
+	}
+)
+
+func (x ForwardResponse_ErrType) Enum() *ForwardResponse_ErrType {
+	p := new(ForwardResponse_ErrType)
+	*p = x
+	return p
+}
+
+func (x ForwardResponse_ErrType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ForwardResponse_ErrType) Descriptor() protoreflect.EnumDescriptor {
+	return file_edgectl_rpc_daemon_proto_enumTypes[7].Descriptor()
+}
+
+func (ForwardResponse_ErrType) Type() protoreflect.EnumType {
+	return &file_edgectl_rpc_daemon_proto_enumTypes[7]
+}
+
+func (x ForwardResponse_ErrType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ForwardResponse_ErrType.Descriptor instead.
+func (ForwardResponse_ErrType) EnumDescriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{16, 0}
+}
+
 // ConnectRequest contains the information needed to connect ot a cluster.
 type ConnectRequest struct {
 	state         protoimpl.MessageState
@@ -887,6 +991,53 @@ func (x *StatusResponse) GetIntercepts() *StatusResponse_InterceptsInfo {
 	return nil
 }
 
+type DoctorResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Checks []*DoctorResponse_Check `protobuf:"bytes,1,rep,name=Checks,proto3" json:"Checks,omitempty"`
+}
+
+func (x *DoctorResponse) Reset() {
+	*x = DoctorResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DoctorResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DoctorResponse) ProtoMessage() {}
+
+func (x *DoctorResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DoctorResponse.ProtoReflect.Descriptor instead.
+func (*DoctorResponse) Descriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DoctorResponse) GetChecks() []*DoctorResponse_Check {
+	if x != nil {
+		return x.Checks
+	}
+	return nil
+}
+
 // InterceptRequest contains the information needed to add a deployment intercept.
 type InterceptRequest struct {
 	state         protoimpl.MessageState
@@ -908,12 +1059,15 @@ type InterceptRequest struct {
 	GRPC     bool              `protobuf:"varint,7,opt,name=GRPC,proto3" json:"GRPC,omitempty"`
 	Preview  bool              `protobuf:"varint,8,opt,name=Preview,proto3" json:"Preview,omitempty"`
 	Patterns map[string]string `protobuf:"bytes,9,rep,name=Patterns,proto3" json:"Patterns,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Mapping, if set, is the name of a Mapping in Namespace whose requests
+	// to intercept, instead of everything under Prefix.
+	Mapping string `protobuf:"bytes,10,opt,name=Mapping,proto3" json:"Mapping,omitempty"`
 }
 
 func (x *InterceptRequest) Reset() {
 	*x = InterceptRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[8]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -926,7 +1080,7 @@ func (x *InterceptRequest) String() string {
 func (*InterceptRequest) ProtoMessage() {}
 
 func (x *InterceptRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[8]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -939,7 +1093,7 @@ func (x *InterceptRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InterceptRequest.ProtoReflect.Descriptor instead.
 func (*InterceptRequest) Descriptor() ([]byte, []int) {
-	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{8}
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *InterceptRequest) GetName() string {
@@ -1005,6 +1159,13 @@ func (x *InterceptRequest) GetPatterns() map[string]string {
 	return nil
 }
 
+func (x *InterceptRequest) GetMapping() string {
+	if x != nil {
+		return x.Mapping
+	}
+	return ""
+}
+
 type RemoveInterceptRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1017,7 +1178,7 @@ type RemoveInterceptRequest struct {
 func (x *RemoveInterceptRequest) Reset() {
 	*x = RemoveInterceptRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[9]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1030,7 +1191,7 @@ func (x *RemoveInterceptRequest) String() string {
 func (*RemoveInterceptRequest) ProtoMessage() {}
 
 func (x *RemoveInterceptRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[9]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1043,7 +1204,7 @@ func (x *RemoveInterceptRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RemoveInterceptRequest.ProtoReflect.Descriptor instead.
 func (*RemoveInterceptRequest) Descriptor() ([]byte, []int) {
-	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{9}
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *RemoveInterceptRequest) GetName() string {
@@ -1066,7 +1227,7 @@ type InterceptResponse struct {
 func (x *InterceptResponse) Reset() {
 	*x = InterceptResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[10]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1079,7 +1240,7 @@ func (x *InterceptResponse) String() string {
 func (*InterceptResponse) ProtoMessage() {}
 
 func (x *InterceptResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[10]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1092,7 +1253,7 @@ func (x *InterceptResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InterceptResponse.ProtoReflect.Descriptor instead.
 func (*InterceptResponse) Descriptor() ([]byte, []int) {
-	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{10}
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *InterceptResponse) GetError() InterceptError {
@@ -1129,7 +1290,7 @@ type ListInterceptsResponse struct {
 func (x *ListInterceptsResponse) Reset() {
 	*x = ListInterceptsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[11]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1142,7 +1303,7 @@ func (x *ListInterceptsResponse) String() string {
 func (*ListInterceptsResponse) ProtoMessage() {}
 
 func (x *ListInterceptsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[11]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1155,7 +1316,7 @@ func (x *ListInterceptsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListInterceptsResponse.ProtoReflect.Descriptor instead.
 func (*ListInterceptsResponse) Descriptor() ([]byte, []int) {
-	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{11}
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *ListInterceptsResponse) GetError() InterceptError {
@@ -1192,7 +1353,7 @@ type AvailableInterceptsResponse struct {
 func (x *AvailableInterceptsResponse) Reset() {
 	*x = AvailableInterceptsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[12]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1205,7 +1366,7 @@ func (x *AvailableInterceptsResponse) String() string {
 func (*AvailableInterceptsResponse) ProtoMessage() {}
 
 func (x *AvailableInterceptsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[12]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1218,7 +1379,7 @@ func (x *AvailableInterceptsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AvailableInterceptsResponse.ProtoReflect.Descriptor instead.
 func (*AvailableInterceptsResponse) Descriptor() ([]byte, []int) {
-	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{12}
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *AvailableInterceptsResponse) GetError() InterceptError {
@@ -1242,6 +1403,249 @@ func (x *AvailableInterceptsResponse) GetIntercepts() []*AvailableInterceptsResp
 	return nil
 }
 
+// ForwardRequest contains the information needed to add a port forward.
+type ForwardRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Namespace of the resource (default: the connected namespace)
+	Namespace string `protobuf:"bytes,1,opt,name=Namespace,proto3" json:"Namespace,omitempty"`
+	// Resource to forward to, such as svc/name, deploy/name or pod/name
+	Resource string `protobuf:"bytes,2,opt,name=Resource,proto3" json:"Resource,omitempty"`
+	// Port of the resource, by number or name
+	Port string `protobuf:"bytes,3,opt,name=Port,proto3" json:"Port,omitempty"`
+	// Local port to listen on (default: Port, if it's a number, or a free port)
+	LocalPort int32 `protobuf:"varint,4,opt,name=LocalPort,proto3" json:"LocalPort,omitempty"`
+}
+
+func (x *ForwardRequest) Reset() {
+	*x = ForwardRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ForwardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForwardRequest) ProtoMessage() {}
+
+func (x *ForwardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForwardRequest.ProtoReflect.Descriptor instead.
+func (*ForwardRequest) Descriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ForwardRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ForwardRequest) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *ForwardRequest) GetPort() string {
+	if x != nil {
+		return x.Port
+	}
+	return ""
+}
+
+func (x *ForwardRequest) GetLocalPort() int32 {
+	if x != nil {
+		return x.LocalPort
+	}
+	return 0
+}
+
+type RemoveForwardRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the port forward
+	Name string `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+}
+
+func (x *RemoveForwardRequest) Reset() {
+	*x = RemoveForwardRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RemoveForwardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveForwardRequest) ProtoMessage() {}
+
+func (x *RemoveForwardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveForwardRequest.ProtoReflect.Descriptor instead.
+func (*RemoveForwardRequest) Descriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RemoveForwardRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ForwardResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Error     ForwardResponse_ErrType `protobuf:"varint,1,opt,name=Error,proto3,enum=edgectl.ForwardResponse_ErrType" json:"Error,omitempty"`
+	ErrorText string                  `protobuf:"bytes,2,opt,name=ErrorText,proto3" json:"ErrorText,omitempty"`
+	// Name of the port forward, which is NAMESPACE/RESOURCE:PORT
+	Name      string `protobuf:"bytes,3,opt,name=Name,proto3" json:"Name,omitempty"`
+	LocalPort int32  `protobuf:"varint,4,opt,name=LocalPort,proto3" json:"LocalPort,omitempty"`
+}
+
+func (x *ForwardResponse) Reset() {
+	*x = ForwardResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ForwardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForwardResponse) ProtoMessage() {}
+
+func (x *ForwardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForwardResponse.ProtoReflect.Descriptor instead.
+func (*ForwardResponse) Descriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ForwardResponse) GetError() ForwardResponse_ErrType {
+	if x != nil {
+		return x.Error
+	}
+	return ForwardResponse_Ok
+}
+
+func (x *ForwardResponse) GetErrorText() string {
+	if x != nil {
+		return x.ErrorText
+	}
+	return ""
+}
+
+func (x *ForwardResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ForwardResponse) GetLocalPort() int32 {
+	if x != nil {
+		return x.LocalPort
+	}
+	return 0
+}
+
+type ListForwardsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Forwards []*ListForwardsResponse_ListEntry `protobuf:"bytes,1,rep,name=Forwards,proto3" json:"Forwards,omitempty"`
+}
+
+func (x *ListForwardsResponse) Reset() {
+	*x = ListForwardsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListForwardsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListForwardsResponse) ProtoMessage() {}
+
+func (x *ListForwardsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListForwardsResponse.ProtoReflect.Descriptor instead.
+func (*ListForwardsResponse) Descriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListForwardsResponse) GetForwards() []*ListForwardsResponse_ListEntry {
+	if x != nil {
+		return x.Forwards
+	}
+	return nil
+}
+
 // UserInfo contains information needed when the daemon shells out as another user.
 // This is subject to change in the near future and this message type will be removed.
 type ConnectRequest_UserInfo struct {
@@ -1257,7 +1661,7 @@ type ConnectRequest_UserInfo struct {
 func (x *ConnectRequest_UserInfo) Reset() {
 	*x = ConnectRequest_UserInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[13]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1270,7 +1674,7 @@ func (x *ConnectRequest_UserInfo) String() string {
 func (*ConnectRequest_UserInfo) ProtoMessage() {}
 
 func (x *ConnectRequest_UserInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[13]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1320,7 +1724,7 @@ type StatusResponse_ClusterInfo struct {
 func (x *StatusResponse_ClusterInfo) Reset() {
 	*x = StatusResponse_ClusterInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[14]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1333,7 +1737,7 @@ func (x *StatusResponse_ClusterInfo) String() string {
 func (*StatusResponse_ClusterInfo) ProtoMessage() {}
 
 func (x *StatusResponse_ClusterInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[14]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1385,7 +1789,7 @@ type StatusResponse_InterceptsInfo struct {
 func (x *StatusResponse_InterceptsInfo) Reset() {
 	*x = StatusResponse_InterceptsInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[15]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1398,7 +1802,7 @@ func (x *StatusResponse_InterceptsInfo) String() string {
 func (*StatusResponse_InterceptsInfo) ProtoMessage() {}
 
 func (x *StatusResponse_InterceptsInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[15]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1449,6 +1853,79 @@ func (x *StatusResponse_InterceptsInfo) GetLicenseInfo() string {
 	return ""
 }
 
+type DoctorResponse_Check struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name   string                `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Result DoctorResponse_Result `protobuf:"varint,2,opt,name=Result,proto3,enum=edgectl.DoctorResponse_Result" json:"Result,omitempty"`
+	// Text is what the check found
+	Text string `protobuf:"bytes,3,opt,name=Text,proto3" json:"Text,omitempty"`
+	// Advice is what to do about a failure
+	Advice string `protobuf:"bytes,4,opt,name=Advice,proto3" json:"Advice,omitempty"`
+}
+
+func (x *DoctorResponse_Check) Reset() {
+	*x = DoctorResponse_Check{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DoctorResponse_Check) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DoctorResponse_Check) ProtoMessage() {}
+
+func (x *DoctorResponse_Check) ProtoReflect() protoreflect.Message {
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DoctorResponse_Check.ProtoReflect.Descriptor instead.
+func (*DoctorResponse_Check) Descriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{8, 0}
+}
+
+func (x *DoctorResponse_Check) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DoctorResponse_Check) GetResult() DoctorResponse_Result {
+	if x != nil {
+		return x.Result
+	}
+	return DoctorResponse_Pass
+}
+
+func (x *DoctorResponse_Check) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *DoctorResponse_Check) GetAdvice() string {
+	if x != nil {
+		return x.Advice
+	}
+	return ""
+}
+
 type ListInterceptsResponse_ListEntry struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1461,12 +1938,13 @@ type ListInterceptsResponse_ListEntry struct {
 	Patterns   map[string]string `protobuf:"bytes,5,rep,name=Patterns,proto3" json:"Patterns,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	TargetHost string            `protobuf:"bytes,6,opt,name=TargetHost,proto3" json:"TargetHost,omitempty"`
 	TargetPort int32             `protobuf:"varint,7,opt,name=TargetPort,proto3" json:"TargetPort,omitempty"`
+	Mapping    string            `protobuf:"bytes,8,opt,name=Mapping,proto3" json:"Mapping,omitempty"`
 }
 
 func (x *ListInterceptsResponse_ListEntry) Reset() {
 	*x = ListInterceptsResponse_ListEntry{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[17]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1479,7 +1957,7 @@ func (x *ListInterceptsResponse_ListEntry) String() string {
 func (*ListInterceptsResponse_ListEntry) ProtoMessage() {}
 
 func (x *ListInterceptsResponse_ListEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[17]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1492,7 +1970,7 @@ func (x *ListInterceptsResponse_ListEntry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListInterceptsResponse_ListEntry.ProtoReflect.Descriptor instead.
 func (*ListInterceptsResponse_ListEntry) Descriptor() ([]byte, []int) {
-	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{11, 0}
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{12, 0}
 }
 
 func (x *ListInterceptsResponse_ListEntry) GetName() string {
@@ -1544,6 +2022,13 @@ func (x *ListInterceptsResponse_ListEntry) GetTargetPort() int32 {
 	return 0
 }
 
+func (x *ListInterceptsResponse_ListEntry) GetMapping() string {
+	if x != nil {
+		return x.Mapping
+	}
+	return ""
+}
+
 type AvailableInterceptsResponse_ListEntry struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1556,7 +2041,7 @@ type AvailableInterceptsResponse_ListEntry struct {
 func (x *AvailableInterceptsResponse_ListEntry) Reset() {
 	*x = AvailableInterceptsResponse_ListEntry{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_edgectl_rpc_daemon_proto_msgTypes[19]
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[25]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1569,7 +2054,7 @@ func (x *AvailableInterceptsResponse_ListEntry) String() string {
 func (*AvailableInterceptsResponse_ListEntry) ProtoMessage() {}
 
 func (x *AvailableInterceptsResponse_ListEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_edgectl_rpc_daemon_proto_msgTypes[19]
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[25]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1582,7 +2067,7 @@ func (x *AvailableInterceptsResponse_ListEntry) ProtoReflect() protoreflect.Mess
 
 // Deprecated: Use AvailableInterceptsResponse_ListEntry.ProtoReflect.Descriptor instead.
 func (*AvailableInterceptsResponse_ListEntry) Descriptor() ([]byte, []int) {
-	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{12, 0}
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{13, 0}
 }
 
 func (x *AvailableInterceptsResponse_ListEntry) GetNamespace() string {
@@ -1599,6 +2084,94 @@ func (x *AvailableInterceptsResponse_ListEntry) GetDeployment() string {
 	return ""
 }
 
+type ListForwardsResponse_ListEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name      string `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Namespace string `protobuf:"bytes,2,opt,name=Namespace,proto3" json:"Namespace,omitempty"`
+	Resource  string `protobuf:"bytes,3,opt,name=Resource,proto3" json:"Resource,omitempty"`
+	Port      string `protobuf:"bytes,4,opt,name=Port,proto3" json:"Port,omitempty"`
+	LocalPort int32  `protobuf:"varint,5,opt,name=LocalPort,proto3" json:"LocalPort,omitempty"`
+	// Okay is whether the port forward is up right now
+	Okay bool `protobuf:"varint,6,opt,name=Okay,proto3" json:"Okay,omitempty"`
+}
+
+func (x *ListForwardsResponse_ListEntry) Reset() {
+	*x = ListForwardsResponse_ListEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edgectl_rpc_daemon_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListForwardsResponse_ListEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListForwardsResponse_ListEntry) ProtoMessage() {}
+
+func (x *ListForwardsResponse_ListEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_edgectl_rpc_daemon_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListForwardsResponse_ListEntry.ProtoReflect.Descriptor instead.
+func (*ListForwardsResponse_ListEntry) Descriptor() ([]byte, []int) {
+	return file_edgectl_rpc_daemon_proto_rawDescGZIP(), []int{17, 0}
+}
+
+func (x *ListForwardsResponse_ListEntry) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ListForwardsResponse_ListEntry) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ListForwardsResponse_ListEntry) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *ListForwardsResponse_ListEntry) GetPort() string {
+	if x != nil {
+		return x.Port
+	}
+	return ""
+}
+
+func (x *ListForwardsResponse_ListEntry) GetLocalPort() int32 {
+	if x != nil {
+		return x.LocalPort
+	}
+	return 0
+}
+
+func (x *ListForwardsResponse_ListEntry) GetOkay() bool {
+	if x != nil {
+		return x.Okay
+	}
+	return false
+}
+
 var File_edgectl_rpc_daemon_proto protoreflect.FileDescriptor
 
 var file_edgectl_rpc_daemon_proto_rawDesc = []byte{
@@ -1726,26 +2299,43 @@ var file_edgectl_rpc_daemon_proto_rawDesc = []byte{
 	0x79, 0x70, 0x65, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x6b, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x50,
 	0x61, 0x75, 0x73, 0x65, 0x64, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x4e, 0x6f, 0x4e, 0x65, 0x74,
 	0x77, 0x6f, 0x72, 0x6b, 0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x6e,
-	0x6e, 0x65, 0x63, 0x74, 0x65, 0x64, 0x10, 0x03, 0x22, 0xec, 0x02, 0x0a, 0x10, 0x49, 0x6e, 0x74,
-	0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a,
-	0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d,
-	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12,
-	0x1e, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x12,
-	0x16, 0x0a, 0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x1e, 0x0a, 0x0a, 0x54, 0x61, 0x72, 0x67, 0x65,
-	0x74, 0x48, 0x6f, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x54, 0x61, 0x72,
-	0x67, 0x65, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x54, 0x61, 0x72, 0x67, 0x65,
-	0x74, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x54, 0x61, 0x72,
-	0x67, 0x65, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x47, 0x52, 0x50, 0x43, 0x18,
-	0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x47, 0x52, 0x50, 0x43, 0x12, 0x18, 0x0a, 0x07, 0x50,
-	0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x50, 0x72,
-	0x65, 0x76, 0x69, 0x65, 0x77, 0x12, 0x43, 0x0a, 0x08, 0x50, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e,
-	0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74,
-	0x6c, 0x2e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x2e, 0x50, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
-	0x52, 0x08, 0x50, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x73, 0x1a, 0x3b, 0x0a, 0x0d, 0x50, 0x61,
+	0x6e, 0x65, 0x63, 0x74, 0x65, 0x64, 0x10, 0x03, 0x22, 0xf3, 0x01, 0x0a, 0x0e, 0x44, 0x6f, 0x63,
+	0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x06, 0x43,
+	0x68, 0x65, 0x63, 0x6b, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x65, 0x64,
+	0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x44, 0x6f, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x06, 0x43, 0x68, 0x65, 0x63,
+	0x6b, 0x73, 0x1a, 0x7f, 0x0a, 0x05, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x12, 0x0a, 0x04, 0x4e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12,
+	0x36, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x1e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x44, 0x6f, 0x63, 0x74, 0x6f, 0x72,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52,
+	0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x78, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x54, 0x65, 0x78, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x41,
+	0x64, 0x76, 0x69, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x41, 0x64, 0x76,
+	0x69, 0x63, 0x65, 0x22, 0x29, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x08, 0x0a,
+	0x04, 0x50, 0x61, 0x73, 0x73, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x46, 0x61, 0x69, 0x6c, 0x10,
+	0x01, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x6b, 0x69, 0x70, 0x70, 0x65, 0x64, 0x10, 0x02, 0x22, 0x86,
+	0x03, 0x0a, 0x10, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73,
+	0x70, 0x61, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x4e, 0x61, 0x6d, 0x65,
+	0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f,
+	0x79, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x1e, 0x0a,
+	0x0a, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x12, 0x1e, 0x0a,
+	0x0a, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0a, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x47, 0x52, 0x50, 0x43, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x47, 0x52, 0x50,
+	0x43, 0x12, 0x18, 0x0a, 0x07, 0x50, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x07, 0x50, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x12, 0x43, 0x0a, 0x08, 0x50,
+	0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e,
+	0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x50, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x73,
+	0x12, 0x18, 0x0a, 0x07, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x1a, 0x3b, 0x0a, 0x0d, 0x50, 0x61,
 	0x74, 0x74, 0x65, 0x72, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
 	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
 	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
@@ -1759,7 +2349,7 @@ var file_edgectl_rpc_daemon_proto_rawDesc = []byte{
 	0x6f, 0x72, 0x52, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x50, 0x72, 0x65,
 	0x76, 0x69, 0x65, 0x77, 0x55, 0x52, 0x4c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x50,
 	0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x55, 0x52, 0x4c, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x78,
-	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x54, 0x65, 0x78, 0x74, 0x22, 0xf8, 0x03,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x54, 0x65, 0x78, 0x74, 0x22, 0x92, 0x04,
 	0x0a, 0x16, 0x4c, 0x69, 0x73, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73,
 	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f,
 	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74,
@@ -1770,7 +2360,7 @@ var file_edgectl_rpc_daemon_proto_rawDesc = []byte{
 	0x29, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x49, 0x6e,
 	0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
 	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x49, 0x6e, 0x74, 0x65,
-	0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x1a, 0xcf, 0x02, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x45,
+	0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x1a, 0xe9, 0x02, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x45,
 	0x6e, 0x74, 0x72, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65,
 	0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x4e, 0x61, 0x6d,
@@ -1787,51 +2377,98 @@ var file_edgectl_rpc_daemon_proto_rawDesc = []byte{
 	0x61, 0x72, 0x67, 0x65, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52,
 	0x0a, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x54,
 	0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x0a, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x1a, 0x3b, 0x0a, 0x0d, 0x50,
-	0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
-	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14,
-	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xfb, 0x01, 0x0a, 0x1b, 0x41, 0x76, 0x61,
-	0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f,
-	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74,
-	0x6c, 0x2e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x45, 0x72, 0x72, 0x6f, 0x72,
-	0x52, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x78, 0x74, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x54, 0x65, 0x78, 0x74, 0x12, 0x4e, 0x0a, 0x0a, 0x49,
-	0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x2e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61,
-	0x62, 0x6c, 0x65, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
-	0x0a, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x1a, 0x49, 0x0a, 0x09, 0x4c,
-	0x69, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65,
-	0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x4e, 0x61, 0x6d,
-	0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79,
-	0x6d, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x44, 0x65, 0x70, 0x6c,
-	0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x2a, 0x8f, 0x02, 0x0a, 0x0e, 0x49, 0x6e, 0x74, 0x65, 0x72,
-	0x63, 0x65, 0x70, 0x74, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x0f, 0x0a, 0x0b, 0x49, 0x6e, 0x74,
-	0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x4f, 0x6b, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x4e, 0x6f,
-	0x50, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x48, 0x6f, 0x73, 0x74, 0x10, 0x01, 0x12, 0x10, 0x0a,
-	0x0c, 0x4e, 0x6f, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x10, 0x02, 0x12,
-	0x14, 0x0a, 0x10, 0x4e, 0x6f, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x4d, 0x61, 0x6e, 0x61,
-	0x67, 0x65, 0x72, 0x10, 0x03, 0x12, 0x1c, 0x0a, 0x18, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63,
-	0x4d, 0x61, 0x6e, 0x61, 0x67, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6e,
-	0x67, 0x10, 0x04, 0x12, 0x17, 0x0a, 0x13, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x4d, 0x61,
-	0x6e, 0x61, 0x67, 0x65, 0x72, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x05, 0x12, 0x11, 0x0a, 0x0d,
-	0x41, 0x6c, 0x72, 0x65, 0x61, 0x64, 0x79, 0x45, 0x78, 0x69, 0x73, 0x74, 0x73, 0x10, 0x06, 0x12,
-	0x1a, 0x0a, 0x16, 0x4e, 0x6f, 0x41, 0x63, 0x63, 0x65, 0x70, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x44,
-	0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x10, 0x07, 0x12, 0x12, 0x0a, 0x0e, 0x41,
-	0x6d, 0x62, 0x69, 0x67, 0x75, 0x6f, 0x75, 0x73, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x10, 0x08, 0x12,
-	0x15, 0x0a, 0x11, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x54, 0x6f, 0x45, 0x73, 0x74, 0x61, 0x62,
-	0x6c, 0x69, 0x73, 0x68, 0x10, 0x09, 0x12, 0x12, 0x0a, 0x0e, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64,
-	0x54, 0x6f, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x10, 0x0a, 0x12, 0x0c, 0x0a, 0x08, 0x4e, 0x6f,
-	0x74, 0x46, 0x6f, 0x75, 0x6e, 0x64, 0x10, 0x0b, 0x32, 0x9c, 0x05, 0x0a, 0x06, 0x44, 0x61, 0x65,
-	0x6d, 0x6f, 0x6e, 0x12, 0x33, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e,
-	0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x18,
-	0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74,
-	0x75, 0x73, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70,
-	0x74, 0x79, 0x1a, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3c, 0x0a, 0x07, 0x43,
+	0x0a, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x4d,
+	0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4d, 0x61,
+	0x70, 0x70, 0x69, 0x6e, 0x67, 0x1a, 0x3b, 0x0a, 0x0d, 0x50, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02,
+	0x38, 0x01, 0x22, 0xfb, 0x01, 0x0a, 0x1b, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65,
+	0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x2d, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x49, 0x6e, 0x74, 0x65,
+	0x72, 0x63, 0x65, 0x70, 0x74, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x45, 0x72, 0x72, 0x6f,
+	0x72, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x65, 0x78, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x54, 0x65, 0x78, 0x74, 0x12, 0x4e, 0x0a, 0x0a, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65,
+	0x70, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x65, 0x64, 0x67, 0x65,
+	0x63, 0x74, 0x6c, 0x2e, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x6e, 0x74,
+	0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x49, 0x6e, 0x74, 0x65, 0x72,
+	0x63, 0x65, 0x70, 0x74, 0x73, 0x1a, 0x49, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65,
+	0x12, 0x1e, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x44, 0x65, 0x70, 0x6c, 0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74,
+	0x22, 0x7c, 0x0a, 0x0e, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x50, 0x6f, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x50, 0x6f, 0x72, 0x74,
+	0x12, 0x1c, 0x0a, 0x09, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x09, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x50, 0x6f, 0x72, 0x74, 0x22, 0x2a,
+	0x0a, 0x14, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0xf6, 0x01, 0x0a, 0x0f, 0x46,
+	0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x36,
+	0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x20, 0x2e,
+	0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x45, 0x72, 0x72, 0x54, 0x79, 0x70, 0x65, 0x52,
+	0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x54,
+	0x65, 0x78, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x45, 0x72, 0x72, 0x6f, 0x72,
+	0x54, 0x65, 0x78, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x4c, 0x6f, 0x63, 0x61,
+	0x6c, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x4c, 0x6f, 0x63,
+	0x61, 0x6c, 0x50, 0x6f, 0x72, 0x74, 0x22, 0x5b, 0x0a, 0x07, 0x45, 0x72, 0x72, 0x54, 0x79, 0x70,
+	0x65, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x6b, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x4e, 0x6f, 0x43,
+	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x41,
+	0x6c, 0x72, 0x65, 0x61, 0x64, 0x79, 0x45, 0x78, 0x69, 0x73, 0x74, 0x73, 0x10, 0x02, 0x12, 0x15,
+	0x0a, 0x11, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x54, 0x6f, 0x45, 0x73, 0x74, 0x61, 0x62, 0x6c,
+	0x69, 0x73, 0x68, 0x10, 0x03, 0x12, 0x0c, 0x0a, 0x08, 0x4e, 0x6f, 0x74, 0x46, 0x6f, 0x75, 0x6e,
+	0x64, 0x10, 0x04, 0x22, 0xfd, 0x01, 0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6f, 0x72, 0x77,
+	0x61, 0x72, 0x64, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x43, 0x0a, 0x08,
+	0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27,
+	0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6f, 0x72,
+	0x77, 0x61, 0x72, 0x64, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64,
+	0x73, 0x1a, 0x9f, 0x01, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x50, 0x6f, 0x72,
+	0x74, 0x12, 0x1c, 0x0a, 0x09, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x50, 0x6f, 0x72, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x50, 0x6f, 0x72, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x4f, 0x6b, 0x61, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x4f,
+	0x6b, 0x61, 0x79, 0x2a, 0x8f, 0x02, 0x0a, 0x0e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70,
+	0x74, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x0f, 0x0a, 0x0b, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63,
+	0x65, 0x70, 0x74, 0x4f, 0x6b, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x4e, 0x6f, 0x50, 0x72, 0x65,
+	0x76, 0x69, 0x65, 0x77, 0x48, 0x6f, 0x73, 0x74, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x4e, 0x6f,
+	0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x10, 0x02, 0x12, 0x14, 0x0a, 0x10,
+	0x4e, 0x6f, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x65, 0x72,
+	0x10, 0x03, 0x12, 0x1c, 0x0a, 0x18, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x4d, 0x61, 0x6e,
+	0x61, 0x67, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6e, 0x67, 0x10, 0x04,
+	0x12, 0x17, 0x0a, 0x13, 0x54, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x4d, 0x61, 0x6e, 0x61, 0x67,
+	0x65, 0x72, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x05, 0x12, 0x11, 0x0a, 0x0d, 0x41, 0x6c, 0x72,
+	0x65, 0x61, 0x64, 0x79, 0x45, 0x78, 0x69, 0x73, 0x74, 0x73, 0x10, 0x06, 0x12, 0x1a, 0x0a, 0x16,
+	0x4e, 0x6f, 0x41, 0x63, 0x63, 0x65, 0x70, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x44, 0x65, 0x70, 0x6c,
+	0x6f, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x10, 0x07, 0x12, 0x12, 0x0a, 0x0e, 0x41, 0x6d, 0x62, 0x69,
+	0x67, 0x75, 0x6f, 0x75, 0x73, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x10, 0x08, 0x12, 0x15, 0x0a, 0x11,
+	0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x54, 0x6f, 0x45, 0x73, 0x74, 0x61, 0x62, 0x6c, 0x69, 0x73,
+	0x68, 0x10, 0x09, 0x12, 0x12, 0x0a, 0x0e, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x54, 0x6f, 0x52,
+	0x65, 0x6d, 0x6f, 0x76, 0x65, 0x10, 0x0a, 0x12, 0x0c, 0x0a, 0x08, 0x4e, 0x6f, 0x74, 0x46, 0x6f,
+	0x75, 0x6e, 0x64, 0x10, 0x0b, 0x32, 0x99, 0x07, 0x0a, 0x06, 0x44, 0x61, 0x65, 0x6d, 0x6f, 0x6e,
+	0x12, 0x33, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x65, 0x64,
+	0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x18, 0x2e, 0x65, 0x64,
+	0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
+	0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a,
+	0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x06, 0x44, 0x6f, 0x63, 0x74,
+	0x6f, 0x72, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70,
+	0x74, 0x79, 0x1a, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x44, 0x6f, 0x63,
+	0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3c, 0x0a, 0x07, 0x43,
 	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c,
 	0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
 	0x18, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
@@ -1857,20 +2494,33 @@ var file_edgectl_rpc_daemon_proto_rawDesc = []byte{
 	0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65, 0x70, 0x74, 0x73, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67,
 	0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x1f, 0x2e, 0x65, 0x64, 0x67,
 	0x65, 0x63, 0x74, 0x6c, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x63, 0x65,
-	0x70, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x05, 0x50,
-	0x61, 0x75, 0x73, 0x65, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45,
-	0x6d, 0x70, 0x74, 0x79, 0x1a, 0x16, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x50,
-	0x61, 0x75, 0x73, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x06,
-	0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c,
-	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c,
-	0x2e, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
-	0x26, 0x0a, 0x04, 0x51, 0x75, 0x69, 0x74, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74,
-	0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74,
-	0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x39, 0x0a, 0x1b, 0x69, 0x6f, 0x2e, 0x64, 0x61,
-	0x74, 0x61, 0x77, 0x69, 0x72, 0x65, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63,
-	0x74, 0x6c, 0x2e, 0x72, 0x70, 0x63, 0x42, 0x0b, 0x44, 0x61, 0x65, 0x6d, 0x6f, 0x6e, 0x50, 0x72,
-	0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x0b, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2f, 0x72,
-	0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x70, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3f, 0x0a, 0x0a, 0x41,
+	0x64, 0x64, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x12, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65,
+	0x63, 0x74, 0x6c, 0x2e, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x18, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x46, 0x6f, 0x72,
+	0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x48, 0x0a, 0x0d,
+	0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x12, 0x1d, 0x2e,
+	0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x46, 0x6f,
+	0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x65,
+	0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3d, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6f,
+	0x72, 0x77, 0x61, 0x72, 0x64, 0x73, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x1d, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x46, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2f, 0x0a, 0x05, 0x50, 0x61, 0x75, 0x73, 0x65, 0x12, 0x0e,
+	0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x16,
+	0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x50, 0x61, 0x75, 0x73, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65,
+	0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x1a, 0x17, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6d,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x26, 0x0a, 0x04, 0x51, 0x75, 0x69,
+	0x74, 0x12, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x1a, 0x0e, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x42, 0x39, 0x0a, 0x1b, 0x69, 0x6f, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x77, 0x69, 0x72, 0x65,
+	0x2e, 0x61, 0x70, 0x69, 0x2e, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2e, 0x72, 0x70, 0x63,
+	0x42, 0x0b, 0x44, 0x61, 0x65, 0x6d, 0x6f, 0x6e, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a,
+	0x0b, 0x65, 0x64, 0x67, 0x65, 0x63, 0x74, 0x6c, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1885,8 +2535,8 @@ func file_edgectl_rpc_daemon_proto_rawDescGZIP() []byte {
 	return file_edgectl_rpc_daemon_proto_rawDescData
 }
 
-var file_edgectl_rpc_daemon_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
-var file_edgectl_rpc_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_edgectl_rpc_daemon_proto_enumTypes = make([]protoimpl.EnumInfo, 8)
+var file_edgectl_rpc_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
 var file_edgectl_rpc_daemon_proto_goTypes = []interface{}{
 	(InterceptError)(0),                           // 0: edgectl.InterceptError
 	(ConnectResponse_ErrType)(0),                  // 1: edgectl.ConnectResponse.ErrType
@@ -1894,70 +2544,91 @@ var file_edgectl_rpc_daemon_proto_goTypes = []interface{}{
 	(PauseResponse_ErrType)(0),                    // 3: edgectl.PauseResponse.ErrType
 	(ResumeResponse_ErrType)(0),                   // 4: edgectl.ResumeResponse.ErrType
 	(StatusResponse_ErrType)(0),                   // 5: edgectl.StatusResponse.ErrType
-	(*ConnectRequest)(nil),                        // 6: edgectl.ConnectRequest
-	(*ConnectResponse)(nil),                       // 7: edgectl.ConnectResponse
-	(*DisconnectResponse)(nil),                    // 8: edgectl.DisconnectResponse
-	(*PauseResponse)(nil),                         // 9: edgectl.PauseResponse
-	(*ResumeResponse)(nil),                        // 10: edgectl.ResumeResponse
-	(*Empty)(nil),                                 // 11: edgectl.Empty
-	(*VersionResponse)(nil),                       // 12: edgectl.VersionResponse
-	(*StatusResponse)(nil),                        // 13: edgectl.StatusResponse
-	(*InterceptRequest)(nil),                      // 14: edgectl.InterceptRequest
-	(*RemoveInterceptRequest)(nil),                // 15: edgectl.RemoveInterceptRequest
-	(*InterceptResponse)(nil),                     // 16: edgectl.InterceptResponse
-	(*ListInterceptsResponse)(nil),                // 17: edgectl.ListInterceptsResponse
-	(*AvailableInterceptsResponse)(nil),           // 18: edgectl.AvailableInterceptsResponse
-	(*ConnectRequest_UserInfo)(nil),               // 19: edgectl.ConnectRequest.UserInfo
-	(*StatusResponse_ClusterInfo)(nil),            // 20: edgectl.StatusResponse.ClusterInfo
-	(*StatusResponse_InterceptsInfo)(nil),         // 21: edgectl.StatusResponse.InterceptsInfo
-	nil,                                           // 22: edgectl.InterceptRequest.PatternsEntry
-	(*ListInterceptsResponse_ListEntry)(nil),      // 23: edgectl.ListInterceptsResponse.ListEntry
-	nil,                                           // 24: edgectl.ListInterceptsResponse.ListEntry.PatternsEntry
-	(*AvailableInterceptsResponse_ListEntry)(nil), // 25: edgectl.AvailableInterceptsResponse.ListEntry
+	(DoctorResponse_Result)(0),                    // 6: edgectl.DoctorResponse.Result
+	(ForwardResponse_ErrType)(0),                  // 7: edgectl.ForwardResponse.ErrType
+	(*ConnectRequest)(nil),                        // 8: edgectl.ConnectRequest
+	(*ConnectResponse)(nil),                       // 9: edgectl.ConnectResponse
+	(*DisconnectResponse)(nil),                    // 10: edgectl.DisconnectResponse
+	(*PauseResponse)(nil),                         // 11: edgectl.PauseResponse
+	(*ResumeResponse)(nil),                        // 12: edgectl.ResumeResponse
+	(*Empty)(nil),                                 // 13: edgectl.Empty
+	(*VersionResponse)(nil),                       // 14: edgectl.VersionResponse
+	(*StatusResponse)(nil),                        // 15: edgectl.StatusResponse
+	(*DoctorResponse)(nil),                        // 16: edgectl.DoctorResponse
+	(*InterceptRequest)(nil),                      // 17: edgectl.InterceptRequest
+	(*RemoveInterceptRequest)(nil),                // 18: edgectl.RemoveInterceptRequest
+	(*InterceptResponse)(nil),                     // 19: edgectl.InterceptResponse
+	(*ListInterceptsResponse)(nil),                // 20: edgectl.ListInterceptsResponse
+	(*AvailableInterceptsResponse)(nil),           // 21: edgectl.AvailableInterceptsResponse
+	(*ForwardRequest)(nil),                        // 22: edgectl.ForwardRequest
+	(*RemoveForwardRequest)(nil),                  // 23: edgectl.RemoveForwardRequest
+	(*ForwardResponse)(nil),                       // 24: edgectl.ForwardResponse
+	(*ListForwardsResponse)(nil),                  // 25: edgectl.ListForwardsResponse
+	(*ConnectRequest_UserInfo)(nil),               // 26: edgectl.ConnectRequest.UserInfo
+	(*StatusResponse_ClusterInfo)(nil),            // 27: edgectl.StatusResponse.ClusterInfo
+	(*StatusResponse_InterceptsInfo)(nil),         // 28: edgectl.StatusResponse.InterceptsInfo
+	(*DoctorResponse_Check)(nil),                  // 29: edgectl.DoctorResponse.Check
+	nil,                                           // 30: edgectl.InterceptRequest.PatternsEntry
+	(*ListInterceptsResponse_ListEntry)(nil),      // 31: edgectl.ListInterceptsResponse.ListEntry
+	nil,                                           // 32: edgectl.ListInterceptsResponse.ListEntry.PatternsEntry
+	(*AvailableInterceptsResponse_ListEntry)(nil), // 33: edgectl.AvailableInterceptsResponse.ListEntry
+	(*ListForwardsResponse_ListEntry)(nil),        // 34: edgectl.ListForwardsResponse.ListEntry
 }
 var file_edgectl_rpc_daemon_proto_depIdxs = []int32{
-	19, // 0: edgectl.ConnectRequest.User:type_name -> edgectl.ConnectRequest.UserInfo
+	26, // 0: edgectl.ConnectRequest.User:type_name -> edgectl.ConnectRequest.UserInfo
 	1,  // 1: edgectl.ConnectResponse.Error:type_name -> edgectl.ConnectResponse.ErrType
 	2,  // 2: edgectl.DisconnectResponse.Error:type_name -> edgectl.DisconnectResponse.ErrType
 	3,  // 3: edgectl.PauseResponse.Error:type_name -> edgectl.PauseResponse.ErrType
 	4,  // 4: edgectl.ResumeResponse.Error:type_name -> edgectl.ResumeResponse.ErrType
 	5,  // 5: edgectl.StatusResponse.Error:type_name -> edgectl.StatusResponse.ErrType
-	20, // 6: edgectl.StatusResponse.Cluster:type_name -> edgectl.StatusResponse.ClusterInfo
-	21, // 7: edgectl.StatusResponse.Intercepts:type_name -> edgectl.StatusResponse.InterceptsInfo
-	22, // 8: edgectl.InterceptRequest.Patterns:type_name -> edgectl.InterceptRequest.PatternsEntry
-	0,  // 9: edgectl.InterceptResponse.Error:type_name -> edgectl.InterceptError
-	0,  // 10: edgectl.ListInterceptsResponse.Error:type_name -> edgectl.InterceptError
-	23, // 11: edgectl.ListInterceptsResponse.Intercepts:type_name -> edgectl.ListInterceptsResponse.ListEntry
-	0,  // 12: edgectl.AvailableInterceptsResponse.Error:type_name -> edgectl.InterceptError
-	25, // 13: edgectl.AvailableInterceptsResponse.Intercepts:type_name -> edgectl.AvailableInterceptsResponse.ListEntry
-	24, // 14: edgectl.ListInterceptsResponse.ListEntry.Patterns:type_name -> edgectl.ListInterceptsResponse.ListEntry.PatternsEntry
-	11, // 15: edgectl.Daemon.Version:input_type -> edgectl.Empty
-	11, // 16: edgectl.Daemon.Status:input_type -> edgectl.Empty
-	6,  // 17: edgectl.Daemon.Connect:input_type -> edgectl.ConnectRequest
-	11, // 18: edgectl.Daemon.Disconnect:input_type -> edgectl.Empty
-	14, // 19: edgectl.Daemon.AddIntercept:input_type -> edgectl.InterceptRequest
-	15, // 20: edgectl.Daemon.RemoveIntercept:input_type -> edgectl.RemoveInterceptRequest
-	11, // 21: edgectl.Daemon.AvailableIntercepts:input_type -> edgectl.Empty
-	11, // 22: edgectl.Daemon.ListIntercepts:input_type -> edgectl.Empty
-	11, // 23: edgectl.Daemon.Pause:input_type -> edgectl.Empty
-	11, // 24: edgectl.Daemon.Resume:input_type -> edgectl.Empty
-	11, // 25: edgectl.Daemon.Quit:input_type -> edgectl.Empty
-	12, // 26: edgectl.Daemon.Version:output_type -> edgectl.VersionResponse
-	13, // 27: edgectl.Daemon.Status:output_type -> edgectl.StatusResponse
-	7,  // 28: edgectl.Daemon.Connect:output_type -> edgectl.ConnectResponse
-	8,  // 29: edgectl.Daemon.Disconnect:output_type -> edgectl.DisconnectResponse
-	16, // 30: edgectl.Daemon.AddIntercept:output_type -> edgectl.InterceptResponse
-	16, // 31: edgectl.Daemon.RemoveIntercept:output_type -> edgectl.InterceptResponse
-	18, // 32: edgectl.Daemon.AvailableIntercepts:output_type -> edgectl.AvailableInterceptsResponse
-	17, // 33: edgectl.Daemon.ListIntercepts:output_type -> edgectl.ListInterceptsResponse
-	9,  // 34: edgectl.Daemon.Pause:output_type -> edgectl.PauseResponse
-	10, // 35: edgectl.Daemon.Resume:output_type -> edgectl.ResumeResponse
-	11, // 36: edgectl.Daemon.Quit:output_type -> edgectl.Empty
-	26, // [26:37] is the sub-list for method output_type
-	15, // [15:26] is the sub-list for method input_type
-	15, // [15:15] is the sub-list for extension type_name
-	15, // [15:15] is the sub-list for extension extendee
-	0,  // [0:15] is the sub-list for field type_name
+	27, // 6: edgectl.StatusResponse.Cluster:type_name -> edgectl.StatusResponse.ClusterInfo
+	28, // 7: edgectl.StatusResponse.Intercepts:type_name -> edgectl.StatusResponse.InterceptsInfo
+	29, // 8: edgectl.DoctorResponse.Checks:type_name -> edgectl.DoctorResponse.Check
+	30, // 9: edgectl.InterceptRequest.Patterns:type_name -> edgectl.InterceptRequest.PatternsEntry
+	0,  // 10: edgectl.InterceptResponse.Error:type_name -> edgectl.InterceptError
+	0,  // 11: edgectl.ListInterceptsResponse.Error:type_name -> edgectl.InterceptError
+	31, // 12: edgectl.ListInterceptsResponse.Intercepts:type_name -> edgectl.ListInterceptsResponse.ListEntry
+	0,  // 13: edgectl.AvailableInterceptsResponse.Error:type_name -> edgectl.InterceptError
+	33, // 14: edgectl.AvailableInterceptsResponse.Intercepts:type_name -> edgectl.AvailableInterceptsResponse.ListEntry
+	7,  // 15: edgectl.ForwardResponse.Error:type_name -> edgectl.ForwardResponse.ErrType
+	34, // 16: edgectl.ListForwardsResponse.Forwards:type_name -> edgectl.ListForwardsResponse.ListEntry
+	6,  // 17: edgectl.DoctorResponse.Check.Result:type_name -> edgectl.DoctorResponse.Result
+	32, // 18: edgectl.ListInterceptsResponse.ListEntry.Patterns:type_name -> edgectl.ListInterceptsResponse.ListEntry.PatternsEntry
+	13, // 19: edgectl.Daemon.Version:input_type -> edgectl.Empty
+	13, // 20: edgectl.Daemon.Status:input_type -> edgectl.Empty
+	13, // 21: edgectl.Daemon.Doctor:input_type -> edgectl.Empty
+	8,  // 22: edgectl.Daemon.Connect:input_type -> edgectl.ConnectRequest
+	13, // 23: edgectl.Daemon.Disconnect:input_type -> edgectl.Empty
+	17, // 24: edgectl.Daemon.AddIntercept:input_type -> edgectl.InterceptRequest
+	18, // 25: edgectl.Daemon.RemoveIntercept:input_type -> edgectl.RemoveInterceptRequest
+	13, // 26: edgectl.Daemon.AvailableIntercepts:input_type -> edgectl.Empty
+	13, // 27: edgectl.Daemon.ListIntercepts:input_type -> edgectl.Empty
+	22, // 28: edgectl.Daemon.AddForward:input_type -> edgectl.ForwardRequest
+	23, // 29: edgectl.Daemon.RemoveForward:input_type -> edgectl.RemoveForwardRequest
+	13, // 30: edgectl.Daemon.ListForwards:input_type -> edgectl.Empty
+	13, // 31: edgectl.Daemon.Pause:input_type -> edgectl.Empty
+	13, // 32: edgectl.Daemon.Resume:input_type -> edgectl.Empty
+	13, // 33: edgectl.Daemon.Quit:input_type -> edgectl.Empty
+	14, // 34: edgectl.Daemon.Version:output_type -> edgectl.VersionResponse
+	15, // 35: edgectl.Daemon.Status:output_type -> edgectl.StatusResponse
+	16, // 36: edgectl.Daemon.Doctor:output_type -> edgectl.DoctorResponse
+	9,  // 37: edgectl.Daemon.Connect:output_type -> edgectl.ConnectResponse
+	10, // 38: edgectl.Daemon.Disconnect:output_type -> edgectl.DisconnectResponse
+	19, // 39: edgectl.Daemon.AddIntercept:output_type -> edgectl.InterceptResponse
+	19, // 40: edgectl.Daemon.RemoveIntercept:output_type -> edgectl.InterceptResponse
+	21, // 41: edgectl.Daemon.AvailableIntercepts:output_type -> edgectl.AvailableInterceptsResponse
+	20, // 42: edgectl.Daemon.ListIntercepts:output_type -> edgectl.ListInterceptsResponse
+	24, // 43: edgectl.Daemon.AddForward:output_type -> edgectl.ForwardResponse
+	24, // 44: edgectl.Daemon.RemoveForward:output_type -> edgectl.ForwardResponse
+	25, // 45: edgectl.Daemon.ListForwards:output_type -> edgectl.ListForwardsResponse
+	11, // 46: edgectl.Daemon.Pause:output_type -> edgectl.PauseResponse
+	12, // 47: edgectl.Daemon.Resume:output_type -> edgectl.ResumeResponse
+	13, // 48: edgectl.Daemon.Quit:output_type -> edgectl.Empty
+	34, // [34:49] is the sub-list for method output_type
+	19, // [19:34] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_edgectl_rpc_daemon_proto_init() }
@@ -2063,7 +2734,7 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InterceptRequest); i {
+			switch v := v.(*DoctorResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2075,7 +2746,7 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RemoveInterceptRequest); i {
+			switch v := v.(*InterceptRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2087,7 +2758,7 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InterceptResponse); i {
+			switch v := v.(*RemoveInterceptRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2099,7 +2770,7 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListInterceptsResponse); i {
+			switch v := v.(*InterceptResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2111,7 +2782,7 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*AvailableInterceptsResponse); i {
+			switch v := v.(*ListInterceptsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2123,7 +2794,7 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ConnectRequest_UserInfo); i {
+			switch v := v.(*AvailableInterceptsResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2135,7 +2806,7 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*StatusResponse_ClusterInfo); i {
+			switch v := v.(*ForwardRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2147,7 +2818,19 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*StatusResponse_InterceptsInfo); i {
+			switch v := v.(*RemoveForwardRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edgectl_rpc_daemon_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ForwardResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2159,7 +2842,19 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListInterceptsResponse_ListEntry); i {
+			switch v := v.(*ListForwardsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edgectl_rpc_daemon_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConnectRequest_UserInfo); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2171,6 +2866,54 @@ func file_edgectl_rpc_daemon_proto_init() {
 			}
 		}
 		file_edgectl_rpc_daemon_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusResponse_ClusterInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edgectl_rpc_daemon_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusResponse_InterceptsInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edgectl_rpc_daemon_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DoctorResponse_Check); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edgectl_rpc_daemon_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListInterceptsResponse_ListEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edgectl_rpc_daemon_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*AvailableInterceptsResponse_ListEntry); i {
 			case 0:
 				return &v.state
@@ -2182,14 +2925,26 @@ func file_edgectl_rpc_daemon_proto_init() {
 				return nil
 			}
 		}
+		file_edgectl_rpc_daemon_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListForwardsResponse_ListEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_edgectl_rpc_daemon_proto_rawDesc,
-			NumEnums:      6,
-			NumMessages:   20,
+			NumEnums:      8,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -2220,6 +2975,8 @@ type DaemonClient interface {
 	Version(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionResponse, error)
 	// Returns the current connectivity status
 	Status(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	// Runs connectivity checks and returns their results
+	Doctor(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DoctorResponse, error)
 	// Connects the daemon to a cluster
 	Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error)
 	// Disconnects the daemon from a connected cluster
@@ -2232,6 +2989,12 @@ type DaemonClient interface {
 	AvailableIntercepts(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*AvailableInterceptsResponse, error)
 	// Returns a list of currently active intercepts.
 	ListIntercepts(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListInterceptsResponse, error)
+	// Adds a port forward to a service (or other resource) in the cluster.
+	AddForward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*ForwardResponse, error)
+	// Removes a port forward.
+	RemoveForward(ctx context.Context, in *RemoveForwardRequest, opts ...grpc.CallOption) (*ForwardResponse, error)
+	// Returns a list of port forwards.
+	ListForwards(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListForwardsResponse, error)
 	// Turns network overrides off.
 	Pause(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PauseResponse, error)
 	// Turns network overrides back on (after using Pause)
@@ -2266,6 +3029,15 @@ func (c *daemonClient) Status(ctx context.Context, in *Empty, opts ...grpc.CallO
 	return out, nil
 }
 
+func (c *daemonClient) Doctor(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DoctorResponse, error) {
+	out := new(DoctorResponse)
+	err := c.cc.Invoke(ctx, "/edgectl.Daemon/Doctor", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *daemonClient) Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error) {
 	out := new(ConnectResponse)
 	err := c.cc.Invoke(ctx, "/edgectl.Daemon/Connect", in, out, opts...)
@@ -2320,6 +3092,33 @@ func (c *daemonClient) ListIntercepts(ctx context.Context, in *Empty, opts ...gr
 	return out, nil
 }
 
+func (c *daemonClient) AddForward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*ForwardResponse, error) {
+	out := new(ForwardResponse)
+	err := c.cc.Invoke(ctx, "/edgectl.Daemon/AddForward", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) RemoveForward(ctx context.Context, in *RemoveForwardRequest, opts ...grpc.CallOption) (*ForwardResponse, error) {
+	out := new(ForwardResponse)
+	err := c.cc.Invoke(ctx, "/edgectl.Daemon/RemoveForward", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonClient) ListForwards(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListForwardsResponse, error) {
+	out := new(ListForwardsResponse)
+	err := c.cc.Invoke(ctx, "/edgectl.Daemon/ListForwards", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *daemonClient) Pause(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PauseResponse, error) {
 	out := new(PauseResponse)
 	err := c.cc.Invoke(ctx, "/edgectl.Daemon/Pause", in, out, opts...)
@@ -2353,6 +3152,8 @@ type DaemonServer interface {
 	Version(context.Context, *Empty) (*VersionResponse, error)
 	// Returns the current connectivity status
 	Status(context.Context, *Empty) (*StatusResponse, error)
+	// Runs connectivity checks and returns their results
+	Doctor(context.Context, *Empty) (*DoctorResponse, error)
 	// Connects the daemon to a cluster
 	Connect(context.Context, *ConnectRequest) (*ConnectResponse, error)
 	// Disconnects the daemon from a connected cluster
@@ -2365,6 +3166,12 @@ type DaemonServer interface {
 	AvailableIntercepts(context.Context, *Empty) (*AvailableInterceptsResponse, error)
 	// Returns a list of currently active intercepts.
 	ListIntercepts(context.Context, *Empty) (*ListInterceptsResponse, error)
+	// Adds a port forward to a service (or other resource) in the cluster.
+	AddForward(context.Context, *ForwardRequest) (*ForwardResponse, error)
+	// Removes a port forward.
+	RemoveForward(context.Context, *RemoveForwardRequest) (*ForwardResponse, error)
+	// Returns a list of port forwards.
+	ListForwards(context.Context, *Empty) (*ListForwardsResponse, error)
 	// Turns network overrides off.
 	Pause(context.Context, *Empty) (*PauseResponse, error)
 	// Turns network overrides back on (after using Pause)
@@ -2383,6 +3190,9 @@ func (*UnimplementedDaemonServer) Version(context.Context, *Empty) (*VersionResp
 func (*UnimplementedDaemonServer) Status(context.Context, *Empty) (*StatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
 }
+func (*UnimplementedDaemonServer) Doctor(context.Context, *Empty) (*DoctorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Doctor not implemented")
+}
 func (*UnimplementedDaemonServer) Connect(context.Context, *ConnectRequest) (*ConnectResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
 }
@@ -2401,6 +3211,15 @@ func (*UnimplementedDaemonServer) AvailableIntercepts(context.Context, *Empty) (
 func (*UnimplementedDaemonServer) ListIntercepts(context.Context, *Empty) (*ListInterceptsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListIntercepts not implemented")
 }
+func (*UnimplementedDaemonServer) AddForward(context.Context, *ForwardRequest) (*ForwardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddForward not implemented")
+}
+func (*UnimplementedDaemonServer) RemoveForward(context.Context, *RemoveForwardRequest) (*ForwardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveForward not implemented")
+}
+func (*UnimplementedDaemonServer) ListForwards(context.Context, *Empty) (*ListForwardsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListForwards not implemented")
+}
 func (*UnimplementedDaemonServer) Pause(context.Context, *Empty) (*PauseResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
 }
@@ -2451,6 +3270,24 @@ func _Daemon_Status_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Daemon_Doctor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).Doctor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/edgectl.Daemon/Doctor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).Doctor(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Daemon_Connect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ConnectRequest)
 	if err := dec(in); err != nil {
@@ -2559,6 +3396,60 @@ func _Daemon_ListIntercepts_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Daemon_AddForward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).AddForward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/edgectl.Daemon/AddForward",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).AddForward(ctx, req.(*ForwardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_RemoveForward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveForwardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).RemoveForward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/edgectl.Daemon/RemoveForward",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).RemoveForward(ctx, req.(*RemoveForwardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Daemon_ListForwards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServer).ListForwards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/edgectl.Daemon/ListForwards",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServer).ListForwards(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Daemon_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Empty)
 	if err := dec(in); err != nil {
@@ -2625,6 +3516,10 @@ var _Daemon_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Status",
 			Handler:    _Daemon_Status_Handler,
 		},
+		{
+			MethodName: "Doctor",
+			Handler:    _Daemon_Doctor_Handler,
+		},
 		{
 			MethodName: "Connect",
 			Handler:    _Daemon_Connect_Handler,
@@ -2649,6 +3544,18 @@ var _Daemon_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListIntercepts",
 			Handler:    _Daemon_ListIntercepts_Handler,
 		},
+		{
+			MethodName: "AddForward",
+			Handler:    _Daemon_AddForward_Handler,
+		},
+		{
+			MethodName: "RemoveForward",
+			Handler:    _Daemon_RemoveForward_Handler,
+		},
+		{
+			MethodName: "ListForwards",
+			Handler:    _Daemon_ListForwards_Handler,
+		},
 		{
 			MethodName: "Pause",
 			Handler:    _Daemon_Pause_Handler,
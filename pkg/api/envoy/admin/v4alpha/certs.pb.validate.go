@@ -1,3 +1,5 @@
+// +build envoy_v4alpha
+
 // Code generated by protoc-gen-validate. DO NOT EDIT.
 // source: envoy/admin/v4alpha/certs.proto
 
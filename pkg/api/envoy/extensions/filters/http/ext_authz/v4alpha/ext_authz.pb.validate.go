@@ -1,3 +1,5 @@
+// +build envoy_v4alpha
+
 // Code generated by protoc-gen-validate. DO NOT EDIT.
 // source: envoy/extensions/filters/http/ext_authz/v4alpha/ext_authz.proto
 
@@ -140,9 +140,30 @@ type AmbassadorConfigSpec struct {
 	// run a custom lua script on every request. see below for more details.
 	LuaScripts string `json:"lua_scripts,omitempty"`
 
-	// +kubebuilder:validation:Enum={"text", "json"}
+	// lua_script runs a Lua script from a ConfigMap on every request,
+	// instead of lua_scripts.
+	LuaScript *LuaScriptRef `json:"lua_script,omitempty"`
+
+	// istio_mtls originates mTLS to upstreams with the Istio workload
+	// certificate, so that Ambassador can route into a strict-mTLS
+	// mesh without a sidecar.
+	IstioMTLS *IstioMTLSConfig `json:"istio_mtls,omitempty"`
+
+	// +kubebuilder:validation:Enum={"text", "json", "typed_json"}
 	EnvoyLogType string `json:"envoy_log_type,omitempty"`
 
+	// envoy_log_fields builds a json or typed_json access log format field by field,
+	// instead of writing Envoy command operators in envoy_log_format.
+	EnvoyLogFields map[string]AccessLogField `json:"envoy_log_fields,omitempty"`
+
+	// envoy_access_logs replaces the single envoy_log_path access log with
+	// any number of file and stderr sinks, each with its own filter.
+	EnvoyAccessLogs []AccessLogSink `json:"envoy_access_logs,omitempty"`
+
+	// access_log_sampling logs only a percentage of requests, by response
+	// status, for every Mapping that doesn't have its own.
+	AccessLogSampling *AccessLogSampling `json:"access_log_sampling,omitempty"`
+
 	// envoy_log_path defines the path of log envoy will use. By default this is standard output
 	EnvoyLogPath string `json:"envoy_log_path,omitempty"`
 
@@ -150,6 +171,17 @@ type AmbassadorConfigSpec struct {
 
 	CircuitBreakers *CircuitBreaker `json:"circuit_breakers,omitempty"`
 
+	// health_check is the default health check for every Mapping.
+	HealthCheck *HealthCheck `json:"health_check,omitempty"`
+
+	// per_mapping_stats gives every Mapping an Envoy virtual cluster, for
+	// per-Mapping latency and response code stats.
+	PerMappingStats bool `json:"per_mapping_stats,omitempty"`
+
+	// tap_admin adds a tap filter that can be driven through Envoy's admin
+	// /tap endpoint, for busyambassador tap.
+	TapAdmin bool `json:"tap_admin,omitempty"`
+
 	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
 
 	Cors *CORS `json:"cors,omitempty"`
@@ -164,6 +196,108 @@ type AmbassadorConfigSpec struct {
 	// This field controls the RE2 “program size” which is a rough estimate of how complex a compiled regex is to
 	// evaluate.  A regex that has a program size greater than the configured value will fail to compile.
 	RegexMaxSize int `json:"regex_max_size,omitempty"`
+
+	// jwt configures built-in JWT validation.  Mappings opt in to it
+	// with `jwt_requirement`.
+	JWT *JWTConfig `json:"jwt,omitempty"`
+
+	// grpc_json_transcoder lets REST clients call gRPC services, by
+	// transcoding JSON requests and responses using the HTTP
+	// annotations in the services' protos.
+	GRPCJSONTranscoder *GRPCJSONTranscoderConfig `json:"grpc_json_transcoder,omitempty"`
+}
+
+// GRPCJSONTranscoderConfig configures Envoy's gRPC-JSON transcoder.
+// Exactly one of ProtoDescriptor and ProtoDescriptorBin must be set.
+type GRPCJSONTranscoderConfig struct {
+	// ProtoDescriptor is the path, in the Ambassador container, of a
+	// protobuf descriptor set for the services; typically it's in a
+	// ConfigMap mounted into the Ambassador pod.
+	ProtoDescriptor string `json:"proto_descriptor,omitempty"`
+
+	// ProtoDescriptorBin is a base64-encoded protobuf descriptor set
+	// for the services.
+	ProtoDescriptorBin string `json:"proto_descriptor_bin,omitempty"`
+
+	// Services lists the fully-qualified names of the gRPC services
+	// to transcode.  Requests for other services are passed through
+	// untouched.
+	//
+	// +kubebuilder:validation:Required
+	Services []string `json:"services,omitempty"`
+
+	PrintOptions              *GRPCJSONTranscoderPrintOptions `json:"print_options,omitempty"`
+	MatchIncomingRequestRoute bool                            `json:"match_incoming_request_route,omitempty"`
+	IgnoredQueryParameters    []string                        `json:"ignored_query_parameters,omitempty"`
+	AutoMapping               bool                            `json:"auto_mapping,omitempty"`
+	ConvertGRPCStatus         bool                            `json:"convert_grpc_status,omitempty"`
+}
+
+// GRPCJSONTranscoderPrintOptions controls how responses are rendered
+// as JSON.
+type GRPCJSONTranscoderPrintOptions struct {
+	AddWhitespace              bool `json:"add_whitespace,omitempty"`
+	AlwaysPrintPrimitiveFields bool `json:"always_print_primitive_fields,omitempty"`
+	AlwaysPrintEnumsAsInts     bool `json:"always_print_enums_as_ints,omitempty"`
+	PreserveProtoFieldNames    bool `json:"preserve_proto_field_names,omitempty"`
+}
+
+// IstioMTLSConfig says where the Istio workload certificate comes
+// from: files that the Istio agent writes out, or the Istio agent's
+// SDS socket.
+type IstioMTLSConfig struct {
+	// Enabled makes every Mapping that doesn't set istio_mtls itself
+	// originate Istio mTLS.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// CertDir has cert-chain.pem, key.pem, and root-cert.pem;
+	// defaults to /etc/istio-certs.
+	CertDir string `json:"cert_dir,omitempty"`
+
+	// SDSSocket is the Istio agent's SDS socket, instead of CertDir.
+	// Envoy only picks up a change to it when it restarts.
+	SDSSocket string `json:"sds_socket,omitempty"`
+}
+
+type JWTConfig struct {
+	// +kubebuilder:validation:Required
+	Providers []JWTProvider `json:"providers,omitempty"`
+}
+
+// A JWTProvider describes an issuer of JWTs, and where to find the
+// keys to validate them.
+type JWTProvider struct {
+	// Name is how Mappings refer to this provider.
+	//
+	// +kubebuilder:validation:Required
+	Name string `json:"name,omitempty"`
+
+	// If set, the `iss` claim must match Issuer.
+	Issuer string `json:"issuer,omitempty"`
+
+	// If set, the `aud` claim must contain one of Audiences.
+	Audiences []string `json:"audiences,omitempty"`
+
+	// JWKSURI is the absolute http:// or https:// URL of the
+	// provider's JSON Web Key Set.
+	//
+	// +kubebuilder:validation:Required
+	JWKSURI string `json:"jwks_uri,omitempty"`
+
+	// How long to cache the fetched key set for; defaults to 300.
+	JWKSCacheDurationS int `json:"jwks_cache_duration_s,omitempty"`
+
+	// How long to wait when fetching the key set; defaults to 5000.
+	JWKSTimeoutMs int `json:"jwks_timeout_ms,omitempty"`
+
+	// Whether to forward the JWT on to the upstream service; defaults
+	// to true.
+	Forward *bool `json:"forward,omitempty"`
+
+	// If set, the (base64url-encoded) JWT payload is sent to the
+	// upstream service in this header, so that it doesn't need to
+	// decode the JWT itself.
+	ForwardPayloadHeader string `json:"forward_payload_header,omitempty"`
 }
 
 // AmbassadorConfigStatus defines the observed state of AmbassadorConfig
@@ -196,3 +330,51 @@ func init() {
 	SchemeBuilder.Register(&Module{}, &ModuleList{})
 	//SchemeBuilder.Register(&AmbassadorConfig{}, &AmbassadorConfigList{})
 }
+
+// AccessLogField is one field of envoy_log_fields. Exactly one of Command,
+// RequestHeader, ResponseHeader, ResponseTrailer, or DynamicMetadata must be set.
+type AccessLogField struct {
+	// Command is an Envoy command operator, like RESPONSE_CODE.
+	Command string `json:"command,omitempty"`
+	// Format is a strftime-style format for START_TIME and the
+	// DOWNSTREAM_PEER_CERT_V_* commands.
+	Format string `json:"format,omitempty"`
+
+	RequestHeader   string                  `json:"request_header,omitempty"`
+	FallbackHeader  string                  `json:"fallback_header,omitempty"`
+	ResponseHeader  string                  `json:"response_header,omitempty"`
+	ResponseTrailer string                  `json:"response_trailer,omitempty"`
+	DynamicMetadata *TraceCustomTagMetadata `json:"dynamic_metadata,omitempty"`
+
+	// MaxLength truncates the value of a header or metadata field.
+	MaxLength int `json:"max_length,omitempty"`
+}
+
+// AccessLogSink is one entry of envoy_access_logs.
+type AccessLogSink struct {
+	// +kubebuilder:validation:Enum={"file","stderr","opentelemetry"}
+	Sink string `json:"sink,omitempty"`
+	// Path is the file to log to, for the "file" sink.
+	Path   string           `json:"path,omitempty"`
+	Filter *AccessLogFilter `json:"filter,omitempty"`
+}
+
+// AccessLogSampling is the percentage of requests to log for each class of
+// response status. A class that isn't given is always logged.
+type AccessLogSampling struct {
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Status1xx *int `json:"1xx,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Status2xx *int `json:"2xx,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Status3xx *int `json:"3xx,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Status4xx *int `json:"4xx,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Status5xx *int `json:"5xx,omitempty"`
+}
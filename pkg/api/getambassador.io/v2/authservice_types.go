@@ -23,12 +23,25 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AuthServiceIncludeBody controls sending the request body to the
+// AuthService along with the headers.
 type AuthServiceIncludeBody struct {
+	// Both fields are required (by Ambassador as well as by the CRD),
+	// so they must not be "omitempty"; otherwise `allow_partial: false`
+	// would get lost on the way to Ambassador.
+
+	// MaxBytes is the most of the body to buffer and send to the
+	// AuthService.
+	//
 	// +kubebuilder:validation:Required
-	MaxBytes int `json:"max_bytes,omitempty"`
+	MaxBytes int `json:"max_bytes"`
 
+	// AllowPartial says what to do with bodies larger than
+	// MaxBytes: if true, the first MaxBytes are sent; if false, the
+	// request is rejected with a 413.
+	//
 	// +kubebuilder:validation:Required
-	AllowPartial bool `json:"allow_partial,omitempty"`
+	AllowPartial bool `json:"allow_partial"`
 }
 
 // Why isn't this just an int??
@@ -54,6 +67,48 @@ type AuthServiceSpec struct {
 	FailureModeAllow            bool                      `json:"failure_mode_allow,omitempty"`
 	IncludeBody                 *AuthServiceIncludeBody   `json:"include_body,omitempty"`
 	StatusOnError               *AuthServiceStatusOnError `json:"status_on_error,omitempty"`
+
+	// ProtocolVersion is the version of the ext_authz gRPC protocol
+	// to speak to the AuthService with; it is ignored when proto is
+	// "http".  "v2" speaks the legacy envoy.service.auth.v2alpha
+	// protocol, and is the default for compatibility with existing
+	// auth services.
+	//
+	// +kubebuilder:validation:Enum={"v2","v3"}
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+
+	// AllowedClientHeaders lists the headers from a denied response
+	// from an HTTP AuthService that should be passed through to the
+	// client along with the response body.  For gRPC AuthServices,
+	// the DeniedHttpResponse headers and body are always passed
+	// through.
+	AllowedClientHeaders []string `json:"allowed_client_headers,omitempty"`
+
+	// MetadataContextNamespaces lists the dynamic metadata
+	// namespaces that are sent to the AuthService in the
+	// CheckRequest metadata_context, so that filters earlier in the
+	// chain can emit metadata for the auth decision.
+	MetadataContextNamespaces []string `json:"metadata_context_namespaces,omitempty"`
+
+	// CircuitBreakers limit the connections and requests that Envoy
+	// will have outstanding to the AuthService.  Requests that trip
+	// a circuit breaker are treated as AuthService errors, and so
+	// are subject to failure_mode_allow and status_on_error.
+	CircuitBreakers []*CircuitBreaker `json:"circuit_breakers,omitempty"`
+
+	// Precedence chains this AuthService with the others, rather than
+	// merging it into the single default AuthService.  Each chained
+	// AuthService is its own ext_authz filter, and the filters run in
+	// order of descending precedence; the default AuthService has
+	// precedence 0.  A request must be allowed by every filter that
+	// checks it.
+	Precedence *int `json:"precedence,omitempty"`
+
+	// MappingPrefixes limits a chained AuthService to Mappings whose
+	// prefix starts with one of the given prefixes.  Mappings with
+	// regex prefixes are always checked.  Setting MappingPrefixes
+	// also chains the AuthService.
+	MappingPrefixes []string `json:"mapping_prefixes,omitempty"`
 }
 
 // AuthService is the Schema for the authservices API
@@ -0,0 +1,106 @@
+// Copyright 2020 Datawire.  All rights reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+///////////////////////////////////////////////////////////////////////////
+// Important: Run "make update-yaml" to regenerate code after modifying
+// this file.
+///////////////////////////////////////////////////////////////////////////
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType is the type of a status condition. Every kind that
+// Ambassador writes the status of reports the same conditions.
+type ConditionType string
+
+const (
+	// ConditionAccepted is True when the resource is valid, and Ambassador
+	// accepted it into its configuration.
+	ConditionAccepted ConditionType = "Accepted"
+	// ConditionProgrammed is True when Envoy's configuration has the
+	// resource.
+	ConditionProgrammed ConditionType = "Programmed"
+	// ConditionReady is True when the resource is Accepted and Programmed,
+	// so traffic can flow through it.
+	ConditionReady ConditionType = "Ready"
+)
+
+// ConditionStatus is the status of a condition.
+// +kubebuilder:validation:Enum={"True","False","Unknown"}
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// Condition is one aspect of the state of a resource, as of the
+// generation of the resource in ObservedGeneration.
+type Condition struct {
+	// +kubebuilder:validation:Required
+	Type ConditionType `json:"type"`
+	// +kubebuilder:validation:Required
+	Status ConditionStatus `json:"status"`
+
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is when Status last changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a CamelCase word that explains Status.
+	Reason string `json:"reason,omitempty"`
+	// Message explains Status for humans.
+	Message string `json:"message,omitempty"`
+}
+
+// FindCondition returns the condition of a type, or nil if there is none.
+func FindCondition(conditions []Condition, conditionType ConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue returns whether the condition of a type is True.
+func IsConditionTrue(conditions []Condition, conditionType ConditionType) bool {
+	c := FindCondition(conditions, conditionType)
+	return c != nil && c.Status == ConditionTrue
+}
+
+// SetCondition adds a condition, or replaces the one of the same type. The
+// LastTransitionTime stays the same unless the status changes; if it does,
+// and the new condition has none, it is now.
+func SetCondition(conditions *[]Condition, condition Condition) {
+	existing := FindCondition(*conditions, condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, condition)
+		return
+	}
+
+	if existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	} else if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	*existing = condition
+}
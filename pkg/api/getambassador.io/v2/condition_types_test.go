@@ -0,0 +1,47 @@
+package v2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ambV2 "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+func TestSetCondition(t *testing.T) {
+	t.Parallel()
+	then := metav1.NewTime(time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	var conditions []ambV2.Condition
+	ambV2.SetCondition(&conditions, ambV2.Condition{
+		Type:               ambV2.ConditionAccepted,
+		Status:             ambV2.ConditionTrue,
+		ObservedGeneration: 1,
+		LastTransitionTime: then,
+	})
+	assert.True(t, ambV2.IsConditionTrue(conditions, ambV2.ConditionAccepted))
+	assert.False(t, ambV2.IsConditionTrue(conditions, ambV2.ConditionReady))
+	assert.Nil(t, ambV2.FindCondition(conditions, ambV2.ConditionReady))
+
+	// The same status keeps its transition time.
+	ambV2.SetCondition(&conditions, ambV2.Condition{
+		Type:               ambV2.ConditionAccepted,
+		Status:             ambV2.ConditionTrue,
+		ObservedGeneration: 2,
+	})
+	assert.Len(t, conditions, 1)
+	assert.Equal(t, int64(2), conditions[0].ObservedGeneration)
+	assert.Equal(t, then, conditions[0].LastTransitionTime)
+
+	// A new status gets a new one.
+	ambV2.SetCondition(&conditions, ambV2.Condition{
+		Type:   ambV2.ConditionAccepted,
+		Status: ambV2.ConditionFalse,
+		Reason: "Invalid",
+	})
+	assert.Len(t, conditions, 1)
+	assert.False(t, ambV2.IsConditionTrue(conditions, ambV2.ConditionAccepted))
+	assert.True(t, conditions[0].LastTransitionTime.After(then.Time))
+}
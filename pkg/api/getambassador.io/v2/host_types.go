@@ -184,6 +184,10 @@ type HostStatus struct {
 	ErrorReason    string           `json:"errorReason,omitempty"`
 	ErrorTimestamp *metav1.Time     `json:"errorTimestamp,omitempty"`
 	ErrorBackoff   *metav1.Duration `json:"errorBackoff,omitempty"`
+
+	// conditions are written by Ambassador; the rest of the status is
+	// written by the ACME controller.
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:validation:Enum={"Unknown","None","Other","ACME"}
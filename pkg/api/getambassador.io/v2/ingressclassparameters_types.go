@@ -0,0 +1,66 @@
+// Copyright 2020 Datawire.  All rights reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+///////////////////////////////////////////////////////////////////////////
+// Important: Run "make update-yaml" to regenerate code after modifying
+// this file.
+///////////////////////////////////////////////////////////////////////////
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IngressClassParametersSpec defines the desired state of IngressClassParameters
+type IngressClassParametersSpec struct {
+	// Rewrite, TimeoutMs, ConnectTimeoutMs and Resolver are set on every
+	// Mapping that Ambassador generates for an Ingress of the class.
+	Rewrite          *string `json:"rewrite,omitempty"`
+	TimeoutMs        int     `json:"timeout_ms,omitempty"`
+	ConnectTimeoutMs int     `json:"connect_timeout_ms,omitempty"`
+	Resolver         string  `json:"resolver,omitempty"`
+
+	// InsecureAction is what the Hosts that Ambassador generates for the
+	// TLS hosts of an Ingress of the class do with cleartext requests. The
+	// default is to Route them.
+	//
+	// +kubebuilder:validation:Enum={"Redirect","Reject","Route"}
+	InsecureAction string `json:"insecure_action,omitempty"`
+}
+
+// IngressClassParameters holds the Ambassador-specific settings of an
+// IngressClass, which refers to it as its parameters.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+type IngressClassParameters struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IngressClassParametersSpec `json:"spec,omitempty"`
+}
+
+// IngressClassParametersList contains a list of IngressClassParameters.
+//
+// +kubebuilder:object:root=true
+type IngressClassParametersList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IngressClassParameters `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IngressClassParameters{}, &IngressClassParametersList{})
+}
@@ -34,17 +34,44 @@ type DriverConfig struct {
 	AdditionalLogHeaders []*AdditionalLogHeaders `json:"additional_log_headers,omitempty"`
 }
 
+// AccessLogFilter limits which requests get logged. If more than one
+// field is set, a request must match all of them.
+type AccessLogFilter struct {
+	// StatusCodeMin only logs responses with at least this status code.
+	StatusCodeMin *int `json:"status_code_min,omitempty"`
+	// DurationMinMs only logs requests that took at least this many
+	// milliseconds.
+	DurationMinMs *int `json:"duration_min_ms,omitempty"`
+	// Header only logs requests that have this header.
+	Header string `json:"header,omitempty"`
+}
+
 // LogServiceSpec defines the desired state of LogService
 type LogServiceSpec struct {
 	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
 
 	Service string `json:"service,omitempty"`
 	// +kubebuilder:validation:Enum={"tcp","http"}
-	Driver                string        `json:"driver,omitempty"`
-	DriverConfig          *DriverConfig `json:"driver_config,omitempty"`
-	FlushIntervalTime     int           `json:"flush_interval_time,omitempty"`
-	FlushIntervalByteSize int           `json:"flush_interval_byte_size,omitempty"`
-	GRPC                  bool          `json:"grpc,omitempty"`
+	Driver       string        `json:"driver,omitempty"`
+	DriverConfig *DriverConfig `json:"driver_config,omitempty"`
+	// FlushIntervalTime is how often, in seconds, Envoy flushes
+	// buffered log entries; defaults to 1.
+	FlushIntervalTime int `json:"flush_interval_time,omitempty"`
+	// FlushIntervalByteSize is how many bytes of log entries Envoy
+	// buffers before flushing them; defaults to 16384.
+	FlushIntervalByteSize int  `json:"flush_interval_byte_size,omitempty"`
+	GRPC                  bool `json:"grpc,omitempty"`
+
+	// ProtocolVersion is the version of the access log service gRPC
+	// protocol to speak: "v2" (the default) speaks
+	// envoy.service.accesslog.v2, and "v3" speaks
+	// envoy.service.accesslog.v3.
+	//
+	// +kubebuilder:validation:Enum={"v2","v3"}
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+
+	// Filter limits which entries are sent to the log service.
+	Filter *AccessLogFilter `json:"filter,omitempty"`
 }
 
 // LogService is the Schema for the logservices API
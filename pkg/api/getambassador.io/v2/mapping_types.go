@@ -33,6 +33,7 @@ type MappingSpec struct {
 	PrefixRegex           bool                    `json:"prefix_regex,omitempty"`
 	PrefixExact           bool                    `json:"prefix_exact,omitempty"`
 	Service               string                  `json:"service,omitempty"`
+	AccessLogSampling     *AccessLogSampling      `json:"access_log_sampling,omitempty"`
 	AddRequestHeaders     map[string]AddedHeader  `json:"add_request_headers,omitempty"`
 	AddResponseHeaders    map[string]AddedHeader  `json:"add_response_headers,omitempty"`
 	AddLinkerdHeaders     bool                    `json:"add_linkerd_headers,omitempty"`
@@ -42,6 +43,7 @@ type MappingSpec struct {
 	EnableIPv6            bool                    `json:"enable_ipv6,omitempty"`
 	CircuitBreakers       []*CircuitBreaker       `json:"circuit_breakers,omitempty"`
 	KeepAlive             *KeepAlive              `json:"keepalive,omitempty"`
+	HealthCheck           *HealthCheck            `json:"health_check,omitempty"`
 	CORS                  *CORS                   `json:"cors,omitempty"`
 	RetryPolicy           *RetryPolicy            `json:"retry_policy,omitempty"`
 	GRPC                  bool                    `json:"grpc,omitempty"`
@@ -60,6 +62,7 @@ type MappingSpec struct {
 	Rewrite               *string                 `json:"rewrite,omitempty"`
 	RegexRewrite          map[string]BoolOrString `json:"regex_rewrite,omitempty"`
 	Shadow                bool                    `json:"shadow,omitempty"`
+	StatsName             string                  `json:"stats_name,omitempty"`
 	ConnectTimeoutMs      int                     `json:"connect_timeout_ms,omitempty"`
 	ClusterIdleTimeoutMs  int                     `json:"cluster_idle_timeout_ms,omitempty"`
 	TimeoutMs             int                     `json:"timeout_ms,omitempty"`
@@ -104,6 +107,62 @@ type MappingSpec struct {
 	LoadBalancer         *LoadBalancer           `json:"load_balancer,omitempty"`
 	QueryParameters      map[string]BoolOrString `json:"query_parameters,omitempty"`
 	RegexQueryParameters map[string]BoolOrString `json:"regex_query_parameters,omitempty"`
+
+	// AuthContextExtensions are extra key/value pairs that are sent
+	// to the AuthService (as the CheckRequest's
+	// context_extensions) for requests that match this Mapping.
+	// They're ignored if bypass_auth is set.
+	AuthContextExtensions map[string]string `json:"auth_context_extensions,omitempty"`
+
+	// JWTRequirement makes requests that match this Mapping carry a
+	// JWT that is valid according to one of the providers
+	// configured in the `jwt` section of the ambassador Module.
+	JWTRequirement *JWTRequirement `json:"jwt_requirement,omitempty"`
+
+	// Tracing overrides the TracingService's sampling, and adds
+	// custom tags, for requests that match this Mapping.
+	Tracing *MappingTracing `json:"tracing,omitempty"`
+
+	// BypassLua keeps the ambassador Module's Lua script from
+	// running for requests that match this Mapping.
+	BypassLua bool `json:"bypass_lua,omitempty"`
+
+	// LuaScript runs a Lua script from a ConfigMap for requests
+	// that match this Mapping, instead of the ambassador Module's.
+	LuaScript *LuaScriptRef `json:"lua_script,omitempty"`
+
+	// IstioMTLS originates mTLS to this Mapping's service with the
+	// Istio workload certificate, or doesn't, whatever the ambassador
+	// Module's istio_mtls says. A tls context wins over it.
+	IstioMTLS *bool `json:"istio_mtls,omitempty"`
+}
+
+type MappingTracing struct {
+	Sampling   *TraceSampling   `json:"sampling,omitempty"`
+	CustomTags []TraceCustomTag `json:"custom_tags,omitempty"`
+}
+
+type JWTRequirement struct {
+	// Providers is the list of provider names (from the ambassador
+	// Module) that may have issued the JWT; any one of them will do.
+	//
+	// +kubebuilder:validation:Required
+	Providers []string `json:"providers,omitempty"`
+
+	// AllowMissing lets requests without a JWT through, while still
+	// rejecting requests with an invalid one.
+	AllowMissing bool `json:"allow_missing,omitempty"`
+}
+
+// A LuaScriptRef names a Lua script in a ConfigMap, in the namespace
+// of the resource that refers to it. The ConfigMap has to have the
+// getambassador.io/lua-scripts label.
+type LuaScriptRef struct {
+	// +kubebuilder:validation:Required
+	ConfigMap string `json:"config_map,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Key string `json:"key,omitempty"`
 }
 
 type DomainMap map[string]MappingLabelsArray
@@ -114,14 +173,22 @@ type MappingLabelsArray []MappingLabels
 type MappingLabels map[string]StringOrMappingLabels
 
 // StringOrMapping labels is the `Union[str,'MappingLabels']` part of
-// the MappingLabels type.
+// the MappingLabels type.  In practice lists, objects, booleans, and
+// integers show up too: a label group is a list of actions, a
+// request_headers action is an object (`{"x-user": {"header":
+// "x-user"}}`), and people write `skip_if_absent: true` and
+// masked_remote_address prefix lengths, which need Envoy's v3 route
+// config, so pkg/ratelimit and diagd reject them.
 //
 // See the remarks about schema on custom types in `./common.go`.
 //
 // +kubebuilder:validation:Type=""
 type StringOrMappingLabels struct {
 	String *string
+	Bool   *bool
+	Int    *int
 	Labels []StringOrMappingLabels
+	Object MappingLabels
 }
 
 // MarshalJSON is important both so that we generate the proper
@@ -129,17 +196,28 @@ type StringOrMappingLabels struct {
 // jsonschema for our sub-fields:
 // https://github.com/kubernetes-sigs/controller-tools/pull/427
 func (o StringOrMappingLabels) MarshalJSON() ([]byte, error) {
+	nonNil := 0
+	for _, isSet := range []bool{o.String != nil, o.Bool != nil, o.Int != nil, o.Labels != nil, o.Object != nil} {
+		if isSet {
+			nonNil++
+		}
+	}
+
 	switch {
-	case o.String == nil && o.Labels == nil:
-		return json.Marshal(nil)
-	case o.String == nil && o.Labels != nil:
-		return json.Marshal(o.Labels)
-	case o.String != nil && o.Labels == nil:
-		return json.Marshal(o.String)
-	case o.String != nil && o.Labels != nil:
+	case nonNil > 1:
 		panic("invalid StringOrMappingLabels")
+	case o.String != nil:
+		return json.Marshal(o.String)
+	case o.Bool != nil:
+		return json.Marshal(o.Bool)
+	case o.Int != nil:
+		return json.Marshal(o.Int)
+	case o.Labels != nil:
+		return json.Marshal(o.Labels)
+	case o.Object != nil:
+		return json.Marshal(o.Object)
 	}
-	panic("not reached")
+	return json.Marshal(nil)
 }
 
 func (o *StringOrMappingLabels) UnmarshalJSON(data []byte) error {
@@ -156,6 +234,24 @@ func (o *StringOrMappingLabels) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
+	var object MappingLabels
+	if err = json.Unmarshal(data, &object); err == nil {
+		*o = StringOrMappingLabels{Object: object}
+		return nil
+	}
+
+	var b bool
+	if err = json.Unmarshal(data, &b); err == nil {
+		*o = StringOrMappingLabels{Bool: &b}
+		return nil
+	}
+
+	var i int
+	if err = json.Unmarshal(data, &i); err == nil {
+		*o = StringOrMappingLabels{Int: &i}
+		return nil
+	}
+
 	var str string
 	if err = json.Unmarshal(data, &str); err == nil {
 		*o = StringOrMappingLabels{String: &str}
@@ -218,6 +314,45 @@ func (o *AddedHeader) UnmarshalJSON(data []byte) error {
 	return err
 }
 
+// HealthCheck configures active health checking of a Mapping's upstream.
+// Exactly one of HTTP, TCP, and GRPC must be set.
+type HealthCheck struct {
+	TimeoutMs          int `json:"timeout_ms,omitempty"`
+	IntervalMs         int `json:"interval_ms,omitempty"`
+	UnhealthyThreshold int `json:"unhealthy_threshold,omitempty"`
+	HealthyThreshold   int `json:"healthy_threshold,omitempty"`
+
+	HTTP *HTTPHealthCheck `json:"http,omitempty"`
+	TCP  *TCPHealthCheck  `json:"tcp,omitempty"`
+	GRPC *GRPCHealthCheck `json:"grpc,omitempty"`
+}
+
+type HTTPHealthCheck struct {
+	Path string `json:"path,omitempty"`
+	Host string `json:"host,omitempty"`
+	// ExpectedStatuses are the status codes that count as healthy;
+	// both ends of each range are included. Defaults to just 200.
+	ExpectedStatuses []HealthCheckStatusRange `json:"expected_statuses,omitempty"`
+}
+
+type HealthCheckStatusRange struct {
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	Min int `json:"min"`
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	Max int `json:"max"`
+}
+
+// TCPHealthCheck only checks that a connection can be made.
+type TCPHealthCheck struct {
+}
+
+type GRPCHealthCheck struct {
+	ServiceName string `json:"service_name,omitempty"`
+	Authority   string `json:"authority,omitempty"`
+}
+
 type KeepAlive struct {
 	Probes   int `json:"probes,omitempty"`
 	IdleTime int `json:"idle_time,omitempty"`
@@ -262,6 +397,8 @@ type MappingStatus struct {
 	State string `json:"state,omitempty"`
 
 	Reason string `json:"reason,omitempty"`
+
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // Mapping is the Schema for the mappings API
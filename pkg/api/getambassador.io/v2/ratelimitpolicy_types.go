@@ -0,0 +1,93 @@
+// Copyright 2020 Datawire.  All rights reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+///////////////////////////////////////////////////////////////////////////
+// Important: Run "make update-yaml" to regenerate code after modifying
+// this file.
+///////////////////////////////////////////////////////////////////////////
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RateLimitPolicyEntry is one key/value pair of a descriptor.  An
+// empty Value matches any value, and every distinct value gets its own
+// counter.
+type RateLimitPolicyEntry struct {
+	// +kubebuilder:validation:Required
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// RateLimitPolicyLimit limits the requests with a matching descriptor.
+type RateLimitPolicyLimit struct {
+	// Name is a human-readable name for the limit.
+	Name string `json:"name,omitempty"`
+
+	// Descriptor matches descriptors with exactly these entries, in
+	// this order.
+	//
+	// +kubebuilder:validation:Required
+	Descriptor []RateLimitPolicyEntry `json:"descriptor,omitempty"`
+
+	// +kubebuilder:validation:Required
+	RequestsPerUnit uint32 `json:"requests_per_unit,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum={"second","minute","hour","day"}
+	Unit string `json:"unit,omitempty"`
+}
+
+// RateLimitPolicySpec defines the desired state of RateLimitPolicy
+type RateLimitPolicySpec struct {
+	// Domain is the rate limit domain that the limits apply to;
+	// defaults to "ambassador".
+	Domain string `json:"domain,omitempty"`
+
+	// Limits are checked in order; the first one that matches a
+	// descriptor applies.
+	Limits []RateLimitPolicyLimit `json:"limits,omitempty"`
+
+	// ShadowMode makes the limits count and log the requests that
+	// they would limit, without limiting them, so that they can be
+	// tuned before they're enforced.
+	ShadowMode bool `json:"shadow_mode,omitempty"`
+}
+
+// RateLimitPolicy is the Schema for the ratelimitpolicies API.  It
+// configures the built-in rate limit service (`busyambassador
+// ratelimit`), not Ambassador itself.
+//
+// +kubebuilder:object:root=true
+type RateLimitPolicy struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RateLimitPolicySpec `json:"spec,omitempty"`
+}
+
+// RateLimitPolicyList contains a list of RateLimitPolicies.
+//
+// +kubebuilder:object:root=true
+type RateLimitPolicyList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RateLimitPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RateLimitPolicy{}, &RateLimitPolicyList{})
+}
@@ -32,6 +32,22 @@ type RateLimitServiceSpec struct {
 	TimeoutMs int           `json:"timeout_ms,omitempty"`
 	Domain    string        `json:"domain,omitempty"`
 	TLS       *BoolOrString `json:"tls,omitempty"`
+
+	// ProtocolVersion is the version of the rate limit service gRPC
+	// protocol to speak: "v2" (the default) speaks
+	// envoy.service.ratelimit.v2, and "v3" speaks
+	// envoy.service.ratelimit.v3.
+	//
+	// +kubebuilder:validation:Enum={"v2","v3"}
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+
+	// FailureModeDeny makes Envoy reject requests (with a 500) when it
+	// can't reach the rate limit service, rather than allowing them.
+	FailureModeDeny bool `json:"failure_mode_deny,omitempty"`
+
+	// StatPrefix replaces the name of the rate limit service's cluster
+	// in Envoy's cluster stats.
+	StatPrefix string `json:"stat_prefix,omitempty"`
 }
 
 // RateLimitService is the Schema for the ratelimitservices API
@@ -83,6 +83,74 @@ type ConsulResolverSpec struct {
 
 	Address    string `json:"address,omitempty"`
 	Datacenter string `json:"datacenter,omitempty"`
+
+	// Datacenters resolves services from several Consul datacenters at
+	// once. When it is set, Datacenter is ignored.
+	Datacenters []ConsulDatacenter `json:"datacenters,omitempty"`
+
+	// Filter is a Consul filter expression that the instances of a
+	// service must match, such as `"canary" not in Service.Tags`. Only
+	// instances that pass their health checks are ever used.
+	Filter string `json:"filter,omitempty"`
+
+	// TokenSecret names a Secret, in the resolver's namespace, whose
+	// "token" key is the Consul ACL token. Ambassador picks up a rotated
+	// token without dropping endpoints.
+	TokenSecret string `json:"token_secret,omitempty"`
+
+	// TokenFile is a file with the Consul ACL token, such as the one that
+	// a Vault agent sidecar writes. Ambassador rereads it when it changes.
+	// TokenSecret takes precedence over it.
+	TokenFile string `json:"token_file,omitempty"`
+
+	// Connect makes Ambassador fetch a Consul Connect leaf certificate and
+	// the Connect CA roots, and originate mTLS with them to the services
+	// that it resolves.
+	Connect *ConsulConnect `json:"connect,omitempty"`
+
+	// CatalogSync makes Ambassador register the hostnames that it routes
+	// as Consul services, so that users of Consul service discovery can
+	// find the APIs that Ambassador exposes.
+	CatalogSync *ConsulCatalogSync `json:"catalog_sync,omitempty"`
+}
+
+// ConsulConnect configures Consul Connect mTLS for a ConsulResolver.
+type ConsulConnect struct {
+	// Service is the Consul service that Ambassador's leaf certificate is
+	// issued for. The default is "ambassador".
+	Service string `json:"service,omitempty"`
+}
+
+// ConsulCatalogSync configures the Consul services that a ConsulResolver
+// registers for Ambassador's hostnames.
+type ConsulCatalogSync struct {
+	// Address is the address that clients reach Ambassador at, such as
+	// the IP address or hostname of its load balancer.
+	//
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+	// Port is the port that clients reach Ambassador at. The default is
+	// 443.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port,omitempty"`
+	// Tags are added to each of the services.
+	Tags []string `json:"tags,omitempty"`
+	// CheckURL is the URL that Consul checks the health of the services
+	// at. The default is https://{address}:{port}/ambassador/v0/check_ready.
+	CheckURL string `json:"check_url,omitempty"`
+	// CheckInterval is how often Consul checks the health of the
+	// services, as a duration such as "10s". The default is "10s".
+	CheckInterval string `json:"check_interval,omitempty"`
+}
+
+// ConsulDatacenter is one of the datacenters of a ConsulResolver. Envoy
+// sends each datacenter a share of the traffic proportional to its weight.
+type ConsulDatacenter struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// +kubebuilder:validation:Minimum=1
+	Weight int `json:"weight,omitempty"`
 }
 
 // ConsulResolver is the Schema for the ConsulResolver API
@@ -104,8 +172,162 @@ type ConsulResolverList struct {
 	Items           []ConsulResolver `json:"items"`
 }
 
+// DNSResolver tells Ambassador to use DNS to resolve services, for
+// routing to VMs and other systems outside of Kubernetes and Consul. SRV
+// records are preferred; a service without any falls back to A and AAAA
+// records. Records are looked up again when their TTL runs out.
+type DNSResolverSpec struct {
+	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
+
+	// Server is the DNS server to query, as host:port. The default is
+	// the first nameserver in /etc/resolv.conf.
+	Server string `json:"server,omitempty"`
+
+	// MinRefreshMs and MaxRefreshMs bound how long a record's TTL is
+	// trusted for. The defaults are 5000 and 300000.
+	// +kubebuilder:validation:Minimum=1
+	MinRefreshMs int `json:"min_refresh_ms,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	MaxRefreshMs int `json:"max_refresh_ms,omitempty"`
+
+	// JitterPercent spreads refreshes out by up to this percentage of the
+	// refresh interval, so that services with the same TTL aren't all
+	// looked up at once. The default is 10.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	JitterPercent *int `json:"jitter_percent,omitempty"`
+}
+
+// DNSResolver is the Schema for the DNSResolver API
+//
+// +kubebuilder:object:root=true
+type DNSResolver struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DNSResolverSpec `json:"spec,omitempty"`
+}
+
+// DNSResolverList contains a list of DNSResolvers.
+//
+// +kubebuilder:object:root=true
+type DNSResolverList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DNSResolver `json:"items"`
+}
+
+// MultiClusterResolver tells Ambassador to merge the Kubernetes endpoints of
+// a service from several clusters, so that it can route across clusters.
+// Each cluster is a locality with a weight, and clusters at a higher
+// priority only get traffic when the lower ones run short of healthy
+// endpoints.
+type MultiClusterResolverSpec struct {
+	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
+
+	// Kubeconfig is the path of a kubeconfig file with the contexts of
+	// the clusters, usually mounted from a Secret.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+
+	Clusters []FederatedCluster `json:"clusters,omitempty"`
+}
+
+// FederatedCluster is one of the clusters of a MultiClusterResolver.
+type FederatedCluster struct {
+	// Name names the cluster's locality in Envoy.
+	//
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Context is the kubeconfig context of the cluster. The default is
+	// the kubeconfig's current context.
+	Context string `json:"context,omitempty"`
+	// Local is the cluster that Ambassador runs in; it needs no
+	// kubeconfig.
+	Local bool `json:"local,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	Weight int `json:"weight,omitempty"`
+	// Priority 0 is the highest.
+	// +kubebuilder:validation:Minimum=0
+	Priority int `json:"priority,omitempty"`
+}
+
+// MultiClusterResolver is the Schema for the MultiClusterResolver API
+//
+// +kubebuilder:object:root=true
+type MultiClusterResolver struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MultiClusterResolverSpec `json:"spec,omitempty"`
+}
+
+// MultiClusterResolverList contains a list of MultiClusterResolvers.
+//
+// +kubebuilder:object:root=true
+type MultiClusterResolverList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MultiClusterResolver `json:"items"`
+}
+
+// StaticResolver tells Ambassador to route to endpoints that are listed in
+// the resolver itself, such as databases and legacy appliances that no
+// service discovery knows about.
+type StaticResolverSpec struct {
+	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
+
+	// Services are the groups of endpoints that Mappings can route to,
+	// by the name in their service field.
+	Services []StaticService `json:"services,omitempty"`
+}
+
+// StaticService is a named group of endpoints of a StaticResolver.
+type StaticService struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// +kubebuilder:validation:Required
+	Endpoints []StaticEndpoint `json:"endpoints"`
+}
+
+// StaticEndpoint is one endpoint of a StaticService. Without a port, the
+// port of the Mapping is used. Envoy splits the traffic between endpoints
+// by their weights, and a locality whose endpoints fail its health checks
+// loses its share of the traffic to the others.
+type StaticEndpoint struct {
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	Weight   int    `json:"weight,omitempty"`
+	Locality string `json:"locality,omitempty"`
+}
+
+// StaticResolver is the Schema for the StaticResolver API
+//
+// +kubebuilder:object:root=true
+type StaticResolver struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StaticResolverSpec `json:"spec,omitempty"`
+}
+
+// StaticResolverList contains a list of StaticResolvers.
+//
+// +kubebuilder:object:root=true
+type StaticResolverList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StaticResolver `json:"items"`
+}
+
 func init() {
 	SchemeBuilder.Register(&KubernetesServiceResolver{}, &KubernetesServiceResolverList{})
 	SchemeBuilder.Register(&KubernetesEndpointResolver{}, &KubernetesEndpointResolverList{})
 	SchemeBuilder.Register(&ConsulResolver{}, &ConsulResolverList{})
+	SchemeBuilder.Register(&DNSResolver{}, &DNSResolverList{})
+	SchemeBuilder.Register(&MultiClusterResolver{}, &MultiClusterResolverList{})
+	SchemeBuilder.Register(&StaticResolver{}, &StaticResolverList{})
 }
@@ -20,6 +20,11 @@ func TestMappingRoundTrip(t *testing.T) {
 	checkRoundtrip(t, "mappings.json", &m)
 }
 
+func TestAuthServiceRoundTrip(t *testing.T) {
+	var a []AuthService
+	checkRoundtrip(t, "authservices.json", &a)
+}
+
 func checkRoundtrip(t *testing.T, filename string, ptr interface{}) {
 	bytes, err := ioutil.ReadFile(path.Join("testdata", filename))
 	require.NoError(t, err)
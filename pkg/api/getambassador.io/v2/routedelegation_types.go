@@ -0,0 +1,70 @@
+// Copyright 2020 Datawire.  All rights reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+///////////////////////////////////////////////////////////////////////////
+// Important: Run "make update-yaml" to regenerate code after modifying
+// this file.
+///////////////////////////////////////////////////////////////////////////
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RouteDelegationSpec defines the desired state of RouteDelegation
+type RouteDelegationSpec struct {
+	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
+
+	// Hostname is the hostname that the namespace of the RouteDelegation
+	// owns. Once any RouteDelegation names a hostname, only its owners may
+	// have Hosts for it, and only its owners and the Namespaces delegated to
+	// may have Mappings for it.
+	//
+	// +kubebuilder:validation:Required
+	Hostname string `json:"hostname"`
+
+	// Prefix is the path prefix that Mappings in the Namespaces may route
+	// on the hostname. The default is "/", the whole hostname.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Namespaces are the namespaces that the prefix is delegated to.
+	//
+	// +kubebuilder:validation:Required
+	Namespaces []string `json:"namespaces"`
+}
+
+// RouteDelegation lets the namespace that owns a hostname delegate a path
+// prefix on it to other namespaces.
+//
+// +kubebuilder:object:root=true
+type RouteDelegation struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RouteDelegationSpec `json:"spec,omitempty"`
+}
+
+// RouteDelegationList contains a list of RouteDelegations.
+//
+// +kubebuilder:object:root=true
+type RouteDelegationList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RouteDelegation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RouteDelegation{}, &RouteDelegationList{})
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 Datawire.  All rights reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+///////////////////////////////////////////////////////////////////////////
+// Important: Run "make update-yaml" to regenerate code after modifying
+// this file.
+///////////////////////////////////////////////////////////////////////////
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StatsMatcher chooses which of Envoy's stats get created at all. Only one of
+// InclusionRegex and ExclusionRegex may be set.
+type StatsMatcher struct {
+	// InclusionRegex keeps only the stats whose names match one of these
+	// regexes.
+	InclusionRegex []string `json:"inclusion_regex,omitempty"`
+	// ExclusionRegex drops the stats whose names match one of these
+	// regexes.
+	ExclusionRegex []string `json:"exclusion_regex,omitempty"`
+}
+
+// StatsTag extracts a tag from stat names. Exactly one of Regex and
+// FixedValue must be set.
+type StatsTag struct {
+	TagName    string `json:"tag_name,omitempty"`
+	Regex      string `json:"regex,omitempty"`
+	FixedValue string `json:"fixed_value,omitempty"`
+}
+
+// StatsSinkSpec defines the desired state of StatsSink
+type StatsSinkSpec struct {
+	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
+
+	// +kubebuilder:validation:Enum={"statsd","dog_statsd","metrics_service","opentelemetry"}
+	Driver string `json:"driver,omitempty"`
+	// Service is where to send stats: the host:port of a statsd or
+	// DogStatsD server, or of a gRPC metrics service.
+	Service string `json:"service,omitempty"`
+	// Prefix is prepended to every stat name, for the statsd drivers.
+	Prefix string `json:"prefix,omitempty"`
+	// FlushInterval is how often, in seconds, Envoy flushes stats to
+	// its sinks; defaults to 5. This applies to every sink.
+	FlushInterval int `json:"flush_interval,omitempty"`
+	// StatsMatcher and StatsTags apply to all of Envoy's stats, not
+	// just the ones sent to this sink.
+	StatsMatcher *StatsMatcher `json:"stats_matcher,omitempty"`
+	StatsTags    []StatsTag    `json:"stats_tags,omitempty"`
+}
+
+// StatsSink is the Schema for the statssinks API
+//
+// +kubebuilder:object:root=true
+type StatsSink struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StatsSinkSpec `json:"spec,omitempty"`
+}
+
+// StatsSinkList contains a list of StatsSinks.
+//
+// +kubebuilder:object:root=true
+type StatsSinkList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StatsSink `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StatsSink{}, &StatsSinkList{})
+}
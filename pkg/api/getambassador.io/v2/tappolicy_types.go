@@ -0,0 +1,129 @@
+// Copyright 2020 Datawire.  All rights reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+///////////////////////////////////////////////////////////////////////////
+// Important: Run "make update-yaml" to regenerate code after modifying
+// this file.
+///////////////////////////////////////////////////////////////////////////
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TapHeaderMatch matches a request or response header. At most one of
+// Exact, Prefix, and Regex may be set; if none is, the header only has to
+// be present.
+type TapHeaderMatch struct {
+	// +kubebuilder:validation:Required
+	Name   string `json:"name"`
+	Exact  string `json:"exact,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+	// Invert matches requests that do not match the rest of this
+	// TapHeaderMatch.
+	Invert bool `json:"invert,omitempty"`
+}
+
+// TapMatch narrows down which requests to the TapPolicy's Mappings are
+// tapped. Every condition given has to match.
+type TapMatch struct {
+	PathPrefix      string           `json:"path_prefix,omitempty"`
+	RequestHeaders  []TapHeaderMatch `json:"request_headers,omitempty"`
+	ResponseHeaders []TapHeaderMatch `json:"response_headers,omitempty"`
+}
+
+// TapOutput says where Envoy writes the requests and responses it taps.
+type TapOutput struct {
+	// Sink is "file", to have Envoy write the taps to files itself, or
+	// "grpc", to have Envoy stream them to Ambassador's tap collector,
+	// which stores them wherever AMBASSADOR_TAP_STORAGE says.
+	//
+	// +kubebuilder:validation:Enum={"file","grpc"}
+	Sink string `json:"sink,omitempty"`
+	// +kubebuilder:validation:Enum={"json_body_as_string","json_body_as_bytes","proto_binary"}
+	Format string `json:"format,omitempty"`
+	// PathPrefix is where Envoy writes one file per tapped request, for
+	// the file sink; defaults to /tmp/ambassador-tap/<name>.<namespace>.
+	PathPrefix string `json:"path_prefix,omitempty"`
+}
+
+// TapSample makes a TapPolicy tap continuously, but keep only a sample
+// of the requests, in rotating files, for things like a corpus of
+// traffic to replay.
+type TapSample struct {
+	// RequestsPerMinute is how many requests to keep each minute.
+	//
+	// +kubebuilder:validation:Required
+	RequestsPerMinute int `json:"requests_per_minute"`
+	// RotateEvery is how often to start a new file; defaults to 1h.
+	RotateEvery *metav1.Duration `json:"rotate_every,omitempty"`
+	// MaxFiles is how many files to keep; defaults to 24.
+	MaxFiles int `json:"max_files,omitempty"`
+}
+
+// TapPolicySpec defines the desired state of TapPolicy
+type TapPolicySpec struct {
+	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
+
+	// Mappings are the names of the Mappings, in the TapPolicy's
+	// namespace, whose requests are tapped.
+	Mappings []string `json:"mappings,omitempty"`
+	// TCPMappings are the names of the TCPMappings, in the TapPolicy's
+	// namespace, whose connections are tapped. A TapPolicy can tap
+	// Mappings or TCPMappings, but not both.
+	TCPMappings []string `json:"tcp_mappings,omitempty"`
+	// Match only applies to Mappings.
+	Match *TapMatch `json:"match,omitempty"`
+	// MaxBufferedBytes caps how much of each request body and each
+	// response body is kept, or, for TCPMappings, how much of what's
+	// read and what's written on each connection; defaults to 1KiB,
+	// Envoy's default.
+	MaxBufferedBytes int `json:"max_buffered_bytes,omitempty"`
+	// Duration is how long after the TapPolicy is created to keep
+	// tapping. If it isn't set, the TapPolicy taps until it's deleted.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// MaxBytes is how much the TapPolicy may capture in all before
+	// Ambassador disables it. If it isn't set, only
+	// AMBASSADOR_TAP_MAX_BYTES limits it.
+	MaxBytes int64      `json:"max_bytes,omitempty"`
+	Output   *TapOutput `json:"output,omitempty"`
+	// Sample, if set, keeps a sample of the requests instead of all of
+	// them. Only Mappings can be sampled.
+	Sample *TapSample `json:"sample,omitempty"`
+}
+
+// TapPolicy is the Schema for the tappolicies API
+//
+// +kubebuilder:object:root=true
+type TapPolicy struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TapPolicySpec `json:"spec,omitempty"`
+}
+
+// TapPolicyList contains a list of TapPolicies.
+//
+// +kubebuilder:object:root=true
+type TapPolicyList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TapPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TapPolicy{}, &TapPolicyList{})
+}
@@ -42,6 +42,23 @@ type TCPMappingSpec struct {
 	TLS        BoolOrString `json:"tls,omitempty"`
 	Weight     int          `json:"weight,omitempty"`
 	ClusterTag string       `json:"cluster_tag,omitempty"`
+
+	ConnectionRateLimit *TCPMappingConnectionRateLimit `json:"connection_rate_limit,omitempty"`
+}
+
+// TCPMappingConnectionRateLimit limits the rate at which a TCPMapping's
+// listener accepts connections, using Envoy's local rate limit network
+// filter.  Connections over the limit are closed immediately.  The
+// limit is shared by every client of the listener; all the TCPMappings
+// on the same port and host must agree on it.
+type TCPMappingConnectionRateLimit struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	ConnectionsPerSecond int `json:"connections_per_second,omitempty"`
+
+	// Burst is how many connections can be accepted at once; defaults
+	// to ConnectionsPerSecond.
+	Burst int `json:"burst,omitempty"`
 }
 
 // TCPMapping is the Schema for the tcpmappings API
@@ -46,14 +46,21 @@ type TLSContextSpec struct {
 	SNI                   string   `json:"sni,omitempty"`
 }
 
+// TLSContextStatus defines the observed state of TLSContext
+type TLSContextStatus struct {
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
 // TLSContext is the Schema for the tlscontexts API
 //
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 type TLSContext struct {
 	metav1.TypeMeta   `json:""`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec TLSContextSpec `json:"spec,omitempty"`
+	Spec   TLSContextSpec   `json:"spec,omitempty"`
+	Status TLSContextStatus `json:"status,omitempty"`
 }
 
 // TLSContextList contains a list of TLSContexts.
@@ -40,16 +40,47 @@ type TraceConfig struct {
 	ServiceName              string `json:"service_name,omitempty"`
 }
 
+// TraceCustomTag adds a tag to every span.  Exactly one of Literal,
+// Header, and Metadata says where the tag's value comes from.
+type TraceCustomTag struct {
+	// +kubebuilder:validation:Required
+	Tag string `json:"tag,omitempty"`
+
+	Literal  string                  `json:"literal,omitempty"`
+	Header   string                  `json:"header,omitempty"`
+	Metadata *TraceCustomTagMetadata `json:"metadata,omitempty"`
+
+	// Default is the value to use if the header or metadata is
+	// missing.
+	Default string `json:"default,omitempty"`
+}
+
+// TraceCustomTagMetadata locates a value in the request's dynamic
+// metadata: the metadata set by Filter, at Path.
+type TraceCustomTagMetadata struct {
+	// +kubebuilder:validation:Required
+	Filter string   `json:"filter,omitempty"`
+	Path   []string `json:"path,omitempty"`
+}
+
 // TracingServiceSpec defines the desired state of TracingService
 type TracingServiceSpec struct {
 	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
 
-	// +kubebuilder:validation:Enum={"lightstep","zipkin","datadog"}
+	// The "opentelemetry" driver sends spans to an OpenTelemetry
+	// Collector's OpenCensus receiver, over gRPC, and propagates W3C
+	// trace context.
+	//
+	// +kubebuilder:validation:Enum={"lightstep","zipkin","datadog","opentelemetry"}
 	Driver     string         `json:"driver,omitempty"`
 	Service    string         `json:"service,omitempty"`
 	Sampling   *TraceSampling `json:"sampling,omitempty"`
 	TagHeaders []string       `json:"tag_headers,omitempty"`
 	Config     *TraceConfig   `json:"config,omitempty"`
+
+	// CustomTags are added to every span; a Mapping's tracing can
+	// add more.
+	CustomTags []TraceCustomTag `json:"custom_tags,omitempty"`
 }
 
 // TracingService is the Schema for the tracingservices API
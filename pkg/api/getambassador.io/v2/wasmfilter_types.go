@@ -0,0 +1,114 @@
+// Copyright 2020 Datawire.  All rights reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+///////////////////////////////////////////////////////////////////////////
+// Important: Run "make update-yaml" to regenerate code after modifying
+// this file.
+///////////////////////////////////////////////////////////////////////////
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WasmConfigMapSource names the key of a ConfigMap, in the WasmFilter's
+// namespace, that holds a module.
+type WasmConfigMapSource struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+}
+
+// WasmModule says where the control plane gets a module from. Exactly one
+// of Image and ConfigMap has to be set.
+type WasmModule struct {
+	// Image is an OCI image that has the module either as a layer of type
+	// application/vnd.module.wasm.content.layer.v1+wasm, or as plugin.wasm
+	// in its only layer.
+	Image string `json:"image,omitempty"`
+
+	// PullSecret names a kubernetes.io/dockerconfigjson Secret, in the
+	// WasmFilter's namespace, to log in to the image's registry with.
+	PullSecret string `json:"pull_secret,omitempty"`
+
+	// ConfigMap is a ConfigMap key, preferably in binaryData, that has
+	// the module.
+	ConfigMap *WasmConfigMapSource `json:"config_map,omitempty"`
+
+	// SHA256 is the hex SHA-256 checksum that the module must have. A
+	// module with any other checksum is never handed to Envoy.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// WasmVMConfig configures the Wasm VM that runs a module.
+type WasmVMConfig struct {
+	// Runtime is the Wasm runtime. The default is envoy.wasm.runtime.v8.
+	// +kubebuilder:validation:Enum={"envoy.wasm.runtime.v8","envoy.wasm.runtime.wavm","envoy.wasm.runtime.null"}
+	Runtime string `json:"runtime,omitempty"`
+
+	// VMID is the ID of the VM. WasmFilters with the same VMID and module
+	// share a VM. The default is the WasmFilter's name and namespace.
+	VMID string `json:"vm_id,omitempty"`
+
+	AllowPrecompiled bool `json:"allow_precompiled,omitempty"`
+
+	// Configuration is handed to the VM when it starts.
+	Configuration string `json:"configuration,omitempty"`
+}
+
+// WasmFilterSpec defines the desired state of WasmFilter
+type WasmFilterSpec struct {
+	AmbassadorID AmbassadorID `json:"ambassador_id,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Module   WasmModule    `json:"module"`
+	VMConfig *WasmVMConfig `json:"vm_config,omitempty"`
+
+	// RootID is the root context of the module that the filter uses.
+	RootID string `json:"root_id,omitempty"`
+
+	// Configuration is handed to the filter when it's configured.
+	Configuration string `json:"configuration,omitempty"`
+
+	// Mappings are the names of the Mappings, in the WasmFilter's
+	// namespace, that the filter applies to. If there are none, it applies
+	// to every request.
+	Mappings []string `json:"mappings,omitempty"`
+}
+
+// WasmFilter adds an Envoy Wasm HTTP filter, whose module the control plane
+// fetches from an OCI image or a ConfigMap.
+//
+// +kubebuilder:object:root=true
+type WasmFilter struct {
+	metav1.TypeMeta   `json:""`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WasmFilterSpec `json:"spec,omitempty"`
+}
+
+// WasmFilterList contains a list of WasmFilters.
+//
+// +kubebuilder:object:root=true
+type WasmFilterList struct {
+	metav1.TypeMeta `json:""`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WasmFilter `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WasmFilter{}, &WasmFilterList{})
+}
@@ -30,6 +30,111 @@ func (in *ACMEProviderSpec) DeepCopy() *ACMEProviderSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessLogField) DeepCopyInto(out *AccessLogField) {
+	*out = *in
+	if in.DynamicMetadata != nil {
+		in, out := &in.DynamicMetadata, &out.DynamicMetadata
+		*out = new(TraceCustomTagMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessLogField.
+func (in *AccessLogField) DeepCopy() *AccessLogField {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessLogField)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessLogFilter) DeepCopyInto(out *AccessLogFilter) {
+	*out = *in
+	if in.StatusCodeMin != nil {
+		in, out := &in.StatusCodeMin, &out.StatusCodeMin
+		*out = new(int)
+		**out = **in
+	}
+	if in.DurationMinMs != nil {
+		in, out := &in.DurationMinMs, &out.DurationMinMs
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessLogFilter.
+func (in *AccessLogFilter) DeepCopy() *AccessLogFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessLogFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessLogSampling) DeepCopyInto(out *AccessLogSampling) {
+	*out = *in
+	if in.Status1xx != nil {
+		in, out := &in.Status1xx, &out.Status1xx
+		*out = new(int)
+		**out = **in
+	}
+	if in.Status2xx != nil {
+		in, out := &in.Status2xx, &out.Status2xx
+		*out = new(int)
+		**out = **in
+	}
+	if in.Status3xx != nil {
+		in, out := &in.Status3xx, &out.Status3xx
+		*out = new(int)
+		**out = **in
+	}
+	if in.Status4xx != nil {
+		in, out := &in.Status4xx, &out.Status4xx
+		*out = new(int)
+		**out = **in
+	}
+	if in.Status5xx != nil {
+		in, out := &in.Status5xx, &out.Status5xx
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessLogSampling.
+func (in *AccessLogSampling) DeepCopy() *AccessLogSampling {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessLogSampling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessLogSink) DeepCopyInto(out *AccessLogSink) {
+	*out = *in
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(AccessLogFilter)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessLogSink.
+func (in *AccessLogSink) DeepCopy() *AccessLogSink {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessLogSink)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AddedHeader) DeepCopyInto(out *AddedHeader) {
 	*out = *in
@@ -88,6 +193,35 @@ func (in *AmbassadorConfigSpec) DeepCopyInto(out *AmbassadorConfigSpec) {
 		*out = new(Features)
 		**out = **in
 	}
+	if in.LuaScript != nil {
+		in, out := &in.LuaScript, &out.LuaScript
+		*out = new(LuaScriptRef)
+		**out = **in
+	}
+	if in.IstioMTLS != nil {
+		in, out := &in.IstioMTLS, &out.IstioMTLS
+		*out = new(IstioMTLSConfig)
+		**out = **in
+	}
+	if in.EnvoyLogFields != nil {
+		in, out := &in.EnvoyLogFields, &out.EnvoyLogFields
+		*out = make(map[string]AccessLogField, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.EnvoyAccessLogs != nil {
+		in, out := &in.EnvoyAccessLogs, &out.EnvoyAccessLogs
+		*out = make([]AccessLogSink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AccessLogSampling != nil {
+		in, out := &in.AccessLogSampling, &out.AccessLogSampling
+		*out = new(AccessLogSampling)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LoadBalancer != nil {
 		in, out := &in.LoadBalancer, &out.LoadBalancer
 		*out = new(LoadBalancer)
@@ -98,6 +232,11 @@ func (in *AmbassadorConfigSpec) DeepCopyInto(out *AmbassadorConfigSpec) {
 		*out = new(CircuitBreaker)
 		**out = **in
 	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RetryPolicy != nil {
 		in, out := &in.RetryPolicy, &out.RetryPolicy
 		*out = new(RetryPolicy)
@@ -108,6 +247,16 @@ func (in *AmbassadorConfigSpec) DeepCopyInto(out *AmbassadorConfigSpec) {
 		*out = new(CORS)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.JWT != nil {
+		in, out := &in.JWT, &out.JWT
+		*out = new(JWTConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GRPCJSONTranscoder != nil {
+		in, out := &in.GRPCJSONTranscoder, &out.GRPCJSONTranscoder
+		*out = new(GRPCJSONTranscoderConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AmbassadorConfigSpec.
@@ -267,6 +416,37 @@ func (in *AuthServiceSpec) DeepCopyInto(out *AuthServiceSpec) {
 		*out = new(AuthServiceStatusOnError)
 		**out = **in
 	}
+	if in.AllowedClientHeaders != nil {
+		in, out := &in.AllowedClientHeaders, &out.AllowedClientHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MetadataContextNamespaces != nil {
+		in, out := &in.MetadataContextNamespaces, &out.MetadataContextNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CircuitBreakers != nil {
+		in, out := &in.CircuitBreakers, &out.CircuitBreakers
+		*out = make([]*CircuitBreaker, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(CircuitBreaker)
+				**out = **in
+			}
+		}
+	}
+	if in.Precedence != nil {
+		in, out := &in.Precedence, &out.Precedence
+		*out = new(int)
+		**out = **in
+	}
+	if in.MappingPrefixes != nil {
+		in, out := &in.MappingPrefixes, &out.MappingPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthServiceSpec.
@@ -369,6 +549,72 @@ func (in *CircuitBreaker) DeepCopy() *CircuitBreaker {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsulCatalogSync) DeepCopyInto(out *ConsulCatalogSync) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsulCatalogSync.
+func (in *ConsulCatalogSync) DeepCopy() *ConsulCatalogSync {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsulCatalogSync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsulConnect) DeepCopyInto(out *ConsulConnect) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsulConnect.
+func (in *ConsulConnect) DeepCopy() *ConsulConnect {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsulConnect)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsulDatacenter) DeepCopyInto(out *ConsulDatacenter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsulDatacenter.
+func (in *ConsulDatacenter) DeepCopy() *ConsulDatacenter {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsulDatacenter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConsulResolver) DeepCopyInto(out *ConsulResolver) {
 	*out = *in
@@ -435,6 +681,21 @@ func (in *ConsulResolverSpec) DeepCopyInto(out *ConsulResolverSpec) {
 		*out = make(AmbassadorID, len(*in))
 		copy(*out, *in)
 	}
+	if in.Datacenters != nil {
+		in, out := &in.Datacenters, &out.Datacenters
+		*out = make([]ConsulDatacenter, len(*in))
+		copy(*out, *in)
+	}
+	if in.Connect != nil {
+		in, out := &in.Connect, &out.Connect
+		*out = new(ConsulConnect)
+		**out = **in
+	}
+	if in.CatalogSync != nil {
+		in, out := &in.CatalogSync, &out.CatalogSync
+		*out = new(ConsulCatalogSync)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsulResolverSpec.
@@ -447,6 +708,89 @@ func (in *ConsulResolverSpec) DeepCopy() *ConsulResolverSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSResolver) DeepCopyInto(out *DNSResolver) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSResolver.
+func (in *DNSResolver) DeepCopy() *DNSResolver {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSResolver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DNSResolver) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSResolverList) DeepCopyInto(out *DNSResolverList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DNSResolver, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSResolverList.
+func (in *DNSResolverList) DeepCopy() *DNSResolverList {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSResolverList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DNSResolverList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSResolverSpec) DeepCopyInto(out *DNSResolverSpec) {
+	*out = *in
+	if in.AmbassadorID != nil {
+		in, out := &in.AmbassadorID, &out.AmbassadorID
+		*out = make(AmbassadorID, len(*in))
+		copy(*out, *in)
+	}
+	if in.JitterPercent != nil {
+		in, out := &in.JitterPercent, &out.JitterPercent
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSResolverSpec.
+func (in *DNSResolverSpec) DeepCopy() *DNSResolverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSResolverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in DomainMap) DeepCopyInto(out *DomainMap) {
 	{
@@ -526,61 +870,201 @@ func (in *Features) DeepCopy() *Features {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Host) DeepCopyInto(out *Host) {
+func (in *GRPCHealthCheck) DeepCopyInto(out *GRPCHealthCheck) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	if in.Spec != nil {
-		in, out := &in.Spec, &out.Spec
-		*out = new(HostSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Host.
-func (in *Host) DeepCopy() *Host {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCHealthCheck.
+func (in *GRPCHealthCheck) DeepCopy() *GRPCHealthCheck {
 	if in == nil {
 		return nil
 	}
-	out := new(Host)
+	out := new(GRPCHealthCheck)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Host) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HostList) DeepCopyInto(out *HostList) {
+func (in *FederatedCluster) DeepCopyInto(out *FederatedCluster) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Host, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostList.
-func (in *HostList) DeepCopy() *HostList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederatedCluster.
+func (in *FederatedCluster) DeepCopy() *FederatedCluster {
 	if in == nil {
 		return nil
 	}
-	out := new(HostList)
+	out := new(FederatedCluster)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCJSONTranscoderConfig) DeepCopyInto(out *GRPCJSONTranscoderConfig) {
+	*out = *in
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PrintOptions != nil {
+		in, out := &in.PrintOptions, &out.PrintOptions
+		*out = new(GRPCJSONTranscoderPrintOptions)
+		**out = **in
+	}
+	if in.IgnoredQueryParameters != nil {
+		in, out := &in.IgnoredQueryParameters, &out.IgnoredQueryParameters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCJSONTranscoderConfig.
+func (in *GRPCJSONTranscoderConfig) DeepCopy() *GRPCJSONTranscoderConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCJSONTranscoderConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCJSONTranscoderPrintOptions) DeepCopyInto(out *GRPCJSONTranscoderPrintOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCJSONTranscoderPrintOptions.
+func (in *GRPCJSONTranscoderPrintOptions) DeepCopy() *GRPCJSONTranscoderPrintOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCJSONTranscoderPrintOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPHealthCheck) DeepCopyInto(out *HTTPHealthCheck) {
+	*out = *in
+	if in.ExpectedStatuses != nil {
+		in, out := &in.ExpectedStatuses, &out.ExpectedStatuses
+		*out = make([]HealthCheckStatusRange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHealthCheck.
+func (in *HTTPHealthCheck) DeepCopy() *HTTPHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TCP != nil {
+		in, out := &in.TCP, &out.TCP
+		*out = new(TCPHealthCheck)
+		**out = **in
+	}
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(GRPCHealthCheck)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckStatusRange) DeepCopyInto(out *HealthCheckStatusRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckStatusRange.
+func (in *HealthCheckStatusRange) DeepCopy() *HealthCheckStatusRange {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckStatusRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Host) DeepCopyInto(out *Host) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec != nil {
+		in, out := &in.Spec, &out.Spec
+		*out = new(HostSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Host.
+func (in *Host) DeepCopy() *Host {
+	if in == nil {
+		return nil
+	}
+	out := new(Host)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Host) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostList) DeepCopyInto(out *HostList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Host, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostList.
+func (in *HostList) DeepCopy() *HostList {
+	if in == nil {
+		return nil
+	}
+	out := new(HostList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
 func (in *HostList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
@@ -660,6 +1144,13 @@ func (in *HostStatus) DeepCopyInto(out *HostStatus) {
 		*out = new(metav1.Duration)
 		**out = **in
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostStatus.
@@ -672,6 +1163,84 @@ func (in *HostStatus) DeepCopy() *HostStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressClassParameters) DeepCopyInto(out *IngressClassParameters) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressClassParameters.
+func (in *IngressClassParameters) DeepCopy() *IngressClassParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressClassParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IngressClassParameters) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressClassParametersList) DeepCopyInto(out *IngressClassParametersList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IngressClassParameters, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressClassParametersList.
+func (in *IngressClassParametersList) DeepCopy() *IngressClassParametersList {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressClassParametersList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IngressClassParametersList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressClassParametersSpec) DeepCopyInto(out *IngressClassParametersSpec) {
+	*out = *in
+	if in.Rewrite != nil {
+		in, out := &in.Rewrite, &out.Rewrite
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressClassParametersSpec.
+func (in *IngressClassParametersSpec) DeepCopy() *IngressClassParametersSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressClassParametersSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InsecureRequestPolicy) DeepCopyInto(out *InsecureRequestPolicy) {
 	*out = *in
@@ -687,6 +1256,88 @@ func (in *InsecureRequestPolicy) DeepCopy() *InsecureRequestPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioMTLSConfig) DeepCopyInto(out *IstioMTLSConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioMTLSConfig.
+func (in *IstioMTLSConfig) DeepCopy() *IstioMTLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioMTLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTConfig) DeepCopyInto(out *JWTConfig) {
+	*out = *in
+	if in.Providers != nil {
+		in, out := &in.Providers, &out.Providers
+		*out = make([]JWTProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTConfig.
+func (in *JWTConfig) DeepCopy() *JWTConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTProvider) DeepCopyInto(out *JWTProvider) {
+	*out = *in
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Forward != nil {
+		in, out := &in.Forward, &out.Forward
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTProvider.
+func (in *JWTProvider) DeepCopy() *JWTProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTRequirement) DeepCopyInto(out *JWTRequirement) {
+	*out = *in
+	if in.Providers != nil {
+		in, out := &in.Providers, &out.Providers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTRequirement.
+func (in *JWTRequirement) DeepCopy() *JWTRequirement {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTRequirement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KeepAlive) DeepCopyInto(out *KeepAlive) {
 	*out = *in
@@ -964,6 +1615,11 @@ func (in *LogServiceSpec) DeepCopyInto(out *LogServiceSpec) {
 		*out = new(DriverConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(AccessLogFilter)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogServiceSpec.
@@ -977,15 +1633,30 @@ func (in *LogServiceSpec) DeepCopy() *LogServiceSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Mapping) DeepCopyInto(out *Mapping) {
+func (in *LuaScriptRef) DeepCopyInto(out *LuaScriptRef) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LuaScriptRef.
+func (in *LuaScriptRef) DeepCopy() *LuaScriptRef {
+	if in == nil {
+		return nil
+	}
+	out := new(LuaScriptRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Mapping) DeepCopyInto(out *Mapping) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 	if in.Status != nil {
 		in, out := &in.Status, &out.Status
 		*out = new(MappingStatus)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -1095,6 +1766,11 @@ func (in *MappingSpec) DeepCopyInto(out *MappingSpec) {
 		*out = make(AmbassadorID, len(*in))
 		copy(*out, *in)
 	}
+	if in.AccessLogSampling != nil {
+		in, out := &in.AccessLogSampling, &out.AccessLogSampling
+		*out = new(AccessLogSampling)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AddRequestHeaders != nil {
 		in, out := &in.AddRequestHeaders, &out.AddRequestHeaders
 		*out = make(map[string]AddedHeader, len(*in))
@@ -1125,6 +1801,11 @@ func (in *MappingSpec) DeepCopyInto(out *MappingSpec) {
 		*out = new(KeepAlive)
 		**out = **in
 	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CORS != nil {
 		in, out := &in.CORS, &out.CORS
 		*out = new(CORS)
@@ -1235,6 +1916,33 @@ func (in *MappingSpec) DeepCopyInto(out *MappingSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.AuthContextExtensions != nil {
+		in, out := &in.AuthContextExtensions, &out.AuthContextExtensions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.JWTRequirement != nil {
+		in, out := &in.JWTRequirement, &out.JWTRequirement
+		*out = new(JWTRequirement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tracing != nil {
+		in, out := &in.Tracing, &out.Tracing
+		*out = new(MappingTracing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LuaScript != nil {
+		in, out := &in.LuaScript, &out.LuaScript
+		*out = new(LuaScriptRef)
+		**out = **in
+	}
+	if in.IstioMTLS != nil {
+		in, out := &in.IstioMTLS, &out.IstioMTLS
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MappingSpec.
@@ -1250,6 +1958,13 @@ func (in *MappingSpec) DeepCopy() *MappingSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MappingStatus) DeepCopyInto(out *MappingStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MappingStatus.
@@ -1262,6 +1977,33 @@ func (in *MappingStatus) DeepCopy() *MappingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MappingTracing) DeepCopyInto(out *MappingTracing) {
+	*out = *in
+	if in.Sampling != nil {
+		in, out := &in.Sampling, &out.Sampling
+		*out = new(TraceSampling)
+		**out = **in
+	}
+	if in.CustomTags != nil {
+		in, out := &in.CustomTags, &out.CustomTags
+		*out = make([]TraceCustomTag, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MappingTracing.
+func (in *MappingTracing) DeepCopy() *MappingTracing {
+	if in == nil {
+		return nil
+	}
+	out := new(MappingTracing)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Module) DeepCopyInto(out *Module) {
 	*out = *in
@@ -1342,40 +2084,25 @@ func (in *ModuleSpec) DeepCopy() *ModuleSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PreviewURLSpec) DeepCopyInto(out *PreviewURLSpec) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewURLSpec.
-func (in *PreviewURLSpec) DeepCopy() *PreviewURLSpec {
-	if in == nil {
-		return nil
-	}
-	out := new(PreviewURLSpec)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RateLimitService) DeepCopyInto(out *RateLimitService) {
+func (in *MultiClusterResolver) DeepCopyInto(out *MultiClusterResolver) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitService.
-func (in *RateLimitService) DeepCopy() *RateLimitService {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiClusterResolver.
+func (in *MultiClusterResolver) DeepCopy() *MultiClusterResolver {
 	if in == nil {
 		return nil
 	}
-	out := new(RateLimitService)
+	out := new(MultiClusterResolver)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RateLimitService) DeepCopyObject() runtime.Object {
+func (in *MultiClusterResolver) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1383,31 +2110,31 @@ func (in *RateLimitService) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RateLimitServiceList) DeepCopyInto(out *RateLimitServiceList) {
+func (in *MultiClusterResolverList) DeepCopyInto(out *MultiClusterResolverList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]RateLimitService, len(*in))
+		*out = make([]MultiClusterResolver, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitServiceList.
-func (in *RateLimitServiceList) DeepCopy() *RateLimitServiceList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiClusterResolverList.
+func (in *MultiClusterResolverList) DeepCopy() *MultiClusterResolverList {
 	if in == nil {
 		return nil
 	}
-	out := new(RateLimitServiceList)
+	out := new(MultiClusterResolverList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RateLimitServiceList) DeepCopyObject() runtime.Object {
+func (in *MultiClusterResolverList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1415,127 +2142,180 @@ func (in *RateLimitServiceList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RateLimitServiceSpec) DeepCopyInto(out *RateLimitServiceSpec) {
+func (in *MultiClusterResolverSpec) DeepCopyInto(out *MultiClusterResolverSpec) {
 	*out = *in
 	if in.AmbassadorID != nil {
 		in, out := &in.AmbassadorID, &out.AmbassadorID
 		*out = make(AmbassadorID, len(*in))
 		copy(*out, *in)
 	}
-	if in.TLS != nil {
-		in, out := &in.TLS, &out.TLS
-		*out = new(BoolOrString)
-		(*in).DeepCopyInto(*out)
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]FederatedCluster, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitServiceSpec.
-func (in *RateLimitServiceSpec) DeepCopy() *RateLimitServiceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiClusterResolverSpec.
+func (in *MultiClusterResolverSpec) DeepCopy() *MultiClusterResolverSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RateLimitServiceSpec)
+	out := new(MultiClusterResolverSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RequestPolicy) DeepCopyInto(out *RequestPolicy) {
+func (in *PreviewURLSpec) DeepCopyInto(out *PreviewURLSpec) {
 	*out = *in
-	out.Insecure = in.Insecure
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestPolicy.
-func (in *RequestPolicy) DeepCopy() *RequestPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviewURLSpec.
+func (in *PreviewURLSpec) DeepCopy() *PreviewURLSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RequestPolicy)
+	out := new(PreviewURLSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+func (in *RateLimitPolicy) DeepCopyInto(out *RateLimitPolicy) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
-func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicy.
+func (in *RateLimitPolicy) DeepCopy() *RateLimitPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(RetryPolicy)
+	out := new(RateLimitPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RateLimitPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StringOrMappingLabels) DeepCopyInto(out *StringOrMappingLabels) {
+func (in *RateLimitPolicyEntry) DeepCopyInto(out *RateLimitPolicyEntry) {
 	*out = *in
-	if in.String != nil {
-		in, out := &in.String, &out.String
-		*out = new(string)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicyEntry.
+func (in *RateLimitPolicyEntry) DeepCopy() *RateLimitPolicyEntry {
+	if in == nil {
+		return nil
 	}
-	if in.Labels != nil {
-		in, out := &in.Labels, &out.Labels
-		*out = make([]StringOrMappingLabels, len(*in))
+	out := new(RateLimitPolicyEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitPolicyLimit) DeepCopyInto(out *RateLimitPolicyLimit) {
+	*out = *in
+	if in.Descriptor != nil {
+		in, out := &in.Descriptor, &out.Descriptor
+		*out = make([]RateLimitPolicyEntry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicyLimit.
+func (in *RateLimitPolicyLimit) DeepCopy() *RateLimitPolicyLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitPolicyLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitPolicyList) DeepCopyInto(out *RateLimitPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RateLimitPolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StringOrMappingLabels.
-func (in *StringOrMappingLabels) DeepCopy() *StringOrMappingLabels {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicyList.
+func (in *RateLimitPolicyList) DeepCopy() *RateLimitPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(StringOrMappingLabels)
+	out := new(RateLimitPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RateLimitPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in StringOrStringList) DeepCopyInto(out *StringOrStringList) {
-	{
-		in := &in
-		*out = make(StringOrStringList, len(*in))
-		copy(*out, *in)
+func (in *RateLimitPolicySpec) DeepCopyInto(out *RateLimitPolicySpec) {
+	*out = *in
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make([]RateLimitPolicyLimit, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StringOrStringList.
-func (in StringOrStringList) DeepCopy() StringOrStringList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitPolicySpec.
+func (in *RateLimitPolicySpec) DeepCopy() *RateLimitPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(StringOrStringList)
+	out := new(RateLimitPolicySpec)
 	in.DeepCopyInto(out)
-	return *out
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TCPMapping) DeepCopyInto(out *TCPMapping) {
+func (in *RateLimitService) DeepCopyInto(out *RateLimitService) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMapping.
-func (in *TCPMapping) DeepCopy() *TCPMapping {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitService.
+func (in *RateLimitService) DeepCopy() *RateLimitService {
 	if in == nil {
 		return nil
 	}
-	out := new(TCPMapping)
+	out := new(RateLimitService)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *TCPMapping) DeepCopyObject() runtime.Object {
+func (in *RateLimitService) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1543,31 +2323,31 @@ func (in *TCPMapping) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TCPMappingList) DeepCopyInto(out *TCPMappingList) {
+func (in *RateLimitServiceList) DeepCopyInto(out *RateLimitServiceList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]TCPMapping, len(*in))
+		*out = make([]RateLimitService, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMappingList.
-func (in *TCPMappingList) DeepCopy() *TCPMappingList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitServiceList.
+func (in *RateLimitServiceList) DeepCopy() *RateLimitServiceList {
 	if in == nil {
 		return nil
 	}
-	out := new(TCPMappingList)
+	out := new(RateLimitServiceList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *TCPMappingList) DeepCopyObject() runtime.Object {
+func (in *RateLimitServiceList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1575,76 +2355,81 @@ func (in *TCPMappingList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TCPMappingSpec) DeepCopyInto(out *TCPMappingSpec) {
+func (in *RateLimitServiceSpec) DeepCopyInto(out *RateLimitServiceSpec) {
 	*out = *in
 	if in.AmbassadorID != nil {
 		in, out := &in.AmbassadorID, &out.AmbassadorID
 		*out = make(AmbassadorID, len(*in))
 		copy(*out, *in)
 	}
-	if in.CircuitBreakers != nil {
-		in, out := &in.CircuitBreakers, &out.CircuitBreakers
-		*out = make([]CircuitBreaker, len(*in))
-		copy(*out, *in)
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(BoolOrString)
+		(*in).DeepCopyInto(*out)
 	}
-	in.TLS.DeepCopyInto(&out.TLS)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMappingSpec.
-func (in *TCPMappingSpec) DeepCopy() *TCPMappingSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitServiceSpec.
+func (in *RateLimitServiceSpec) DeepCopy() *RateLimitServiceSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(TCPMappingSpec)
+	out := new(RateLimitServiceSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
+func (in *RequestPolicy) DeepCopyInto(out *RequestPolicy) {
 	*out = *in
-	if in.CipherSuites != nil {
-		in, out := &in.CipherSuites, &out.CipherSuites
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.ECDHCurves != nil {
-		in, out := &in.ECDHCurves, &out.ECDHCurves
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	out.Insecure = in.Insecure
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestPolicy.
+func (in *RequestPolicy) DeepCopy() *RequestPolicy {
+	if in == nil {
+		return nil
 	}
+	out := new(RequestPolicy)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfig.
-func (in *TLSConfig) DeepCopy() *TLSConfig {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(TLSConfig)
+	out := new(RetryPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TLSContext) DeepCopyInto(out *TLSContext) {
+func (in *RouteDelegation) DeepCopyInto(out *RouteDelegation) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContext.
-func (in *TLSContext) DeepCopy() *TLSContext {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteDelegation.
+func (in *RouteDelegation) DeepCopy() *RouteDelegation {
 	if in == nil {
 		return nil
 	}
-	out := new(TLSContext)
+	out := new(RouteDelegation)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *TLSContext) DeepCopyObject() runtime.Object {
+func (in *RouteDelegation) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1652,31 +2437,570 @@ func (in *TLSContext) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *TLSContextList) DeepCopyInto(out *TLSContextList) {
+func (in *RouteDelegationList) DeepCopyInto(out *RouteDelegationList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]TLSContext, len(*in))
+		*out = make([]RouteDelegation, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContextList.
-func (in *TLSContextList) DeepCopy() *TLSContextList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteDelegationList.
+func (in *RouteDelegationList) DeepCopy() *RouteDelegationList {
 	if in == nil {
 		return nil
 	}
-	out := new(TLSContextList)
+	out := new(RouteDelegationList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *TLSContextList) DeepCopyObject() runtime.Object {
+func (in *RouteDelegationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteDelegationSpec) DeepCopyInto(out *RouteDelegationSpec) {
+	*out = *in
+	if in.AmbassadorID != nil {
+		in, out := &in.AmbassadorID, &out.AmbassadorID
+		*out = make(AmbassadorID, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteDelegationSpec.
+func (in *RouteDelegationSpec) DeepCopy() *RouteDelegationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteDelegationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsMatcher) DeepCopyInto(out *StatsMatcher) {
+	*out = *in
+	if in.InclusionRegex != nil {
+		in, out := &in.InclusionRegex, &out.InclusionRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExclusionRegex != nil {
+		in, out := &in.ExclusionRegex, &out.ExclusionRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsMatcher.
+func (in *StatsMatcher) DeepCopy() *StatsMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticEndpoint) DeepCopyInto(out *StaticEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticEndpoint.
+func (in *StaticEndpoint) DeepCopy() *StaticEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticResolver) DeepCopyInto(out *StaticResolver) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticResolver.
+func (in *StaticResolver) DeepCopy() *StaticResolver {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticResolver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StaticResolver) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticResolverList) DeepCopyInto(out *StaticResolverList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StaticResolver, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticResolverList.
+func (in *StaticResolverList) DeepCopy() *StaticResolverList {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticResolverList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StaticResolverList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticResolverSpec) DeepCopyInto(out *StaticResolverSpec) {
+	*out = *in
+	if in.AmbassadorID != nil {
+		in, out := &in.AmbassadorID, &out.AmbassadorID
+		*out = make(AmbassadorID, len(*in))
+		copy(*out, *in)
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]StaticService, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticResolverSpec.
+func (in *StaticResolverSpec) DeepCopy() *StaticResolverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticResolverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticService) DeepCopyInto(out *StaticService) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]StaticEndpoint, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticService.
+func (in *StaticService) DeepCopy() *StaticService {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsSink) DeepCopyInto(out *StatsSink) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsSink.
+func (in *StatsSink) DeepCopy() *StatsSink {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsSink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StatsSink) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsSinkList) DeepCopyInto(out *StatsSinkList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StatsSink, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsSinkList.
+func (in *StatsSinkList) DeepCopy() *StatsSinkList {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsSinkList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StatsSinkList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsSinkSpec) DeepCopyInto(out *StatsSinkSpec) {
+	*out = *in
+	if in.AmbassadorID != nil {
+		in, out := &in.AmbassadorID, &out.AmbassadorID
+		*out = make(AmbassadorID, len(*in))
+		copy(*out, *in)
+	}
+	if in.StatsMatcher != nil {
+		in, out := &in.StatsMatcher, &out.StatsMatcher
+		*out = new(StatsMatcher)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StatsTags != nil {
+		in, out := &in.StatsTags, &out.StatsTags
+		*out = make([]StatsTag, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsSinkSpec.
+func (in *StatsSinkSpec) DeepCopy() *StatsSinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsSinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsTag) DeepCopyInto(out *StatsTag) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsTag.
+func (in *StatsTag) DeepCopy() *StatsTag {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StringOrMappingLabels) DeepCopyInto(out *StringOrMappingLabels) {
+	*out = *in
+	if in.String != nil {
+		in, out := &in.String, &out.String
+		*out = new(string)
+		**out = **in
+	}
+	if in.Bool != nil {
+		in, out := &in.Bool, &out.Bool
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Int != nil {
+		in, out := &in.Int, &out.Int
+		*out = new(int)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]StringOrMappingLabels, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Object != nil {
+		in, out := &in.Object, &out.Object
+		*out = make(MappingLabels, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StringOrMappingLabels.
+func (in *StringOrMappingLabels) DeepCopy() *StringOrMappingLabels {
+	if in == nil {
+		return nil
+	}
+	out := new(StringOrMappingLabels)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in StringOrStringList) DeepCopyInto(out *StringOrStringList) {
+	{
+		in := &in
+		*out = make(StringOrStringList, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StringOrStringList.
+func (in StringOrStringList) DeepCopy() StringOrStringList {
+	if in == nil {
+		return nil
+	}
+	out := new(StringOrStringList)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPHealthCheck) DeepCopyInto(out *TCPHealthCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPHealthCheck.
+func (in *TCPHealthCheck) DeepCopy() *TCPHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPMapping) DeepCopyInto(out *TCPMapping) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMapping.
+func (in *TCPMapping) DeepCopy() *TCPMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TCPMapping) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPMappingConnectionRateLimit) DeepCopyInto(out *TCPMappingConnectionRateLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMappingConnectionRateLimit.
+func (in *TCPMappingConnectionRateLimit) DeepCopy() *TCPMappingConnectionRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPMappingConnectionRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPMappingList) DeepCopyInto(out *TCPMappingList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TCPMapping, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMappingList.
+func (in *TCPMappingList) DeepCopy() *TCPMappingList {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPMappingList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TCPMappingList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPMappingSpec) DeepCopyInto(out *TCPMappingSpec) {
+	*out = *in
+	if in.AmbassadorID != nil {
+		in, out := &in.AmbassadorID, &out.AmbassadorID
+		*out = make(AmbassadorID, len(*in))
+		copy(*out, *in)
+	}
+	if in.CircuitBreakers != nil {
+		in, out := &in.CircuitBreakers, &out.CircuitBreakers
+		*out = make([]CircuitBreaker, len(*in))
+		copy(*out, *in)
+	}
+	in.TLS.DeepCopyInto(&out.TLS)
+	if in.ConnectionRateLimit != nil {
+		in, out := &in.ConnectionRateLimit, &out.ConnectionRateLimit
+		*out = new(TCPMappingConnectionRateLimit)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMappingSpec.
+func (in *TCPMappingSpec) DeepCopy() *TCPMappingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPMappingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
+	*out = *in
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ECDHCurves != nil {
+		in, out := &in.ECDHCurves, &out.ECDHCurves
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfig.
+func (in *TLSConfig) DeepCopy() *TLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSContext) DeepCopyInto(out *TLSContext) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContext.
+func (in *TLSContext) DeepCopy() *TLSContext {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TLSContext) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSContextList) DeepCopyInto(out *TLSContextList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TLSContext, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContextList.
+func (in *TLSContextList) DeepCopy() *TLSContextList {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSContextList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TLSContextList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1691,34 +3015,239 @@ func (in *TLSContextSpec) DeepCopyInto(out *TLSContextSpec) {
 		*out = make(AmbassadorID, len(*in))
 		copy(*out, *in)
 	}
-	if in.Hosts != nil {
-		in, out := &in.Hosts, &out.Hosts
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ECDHCurves != nil {
+		in, out := &in.ECDHCurves, &out.ECDHCurves
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretNamespacing != nil {
+		in, out := &in.SecretNamespacing, &out.SecretNamespacing
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContextSpec.
+func (in *TLSContextSpec) DeepCopy() *TLSContextSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSContextSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSContextStatus) DeepCopyInto(out *TLSContextStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContextStatus.
+func (in *TLSContextStatus) DeepCopy() *TLSContextStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSContextStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TapHeaderMatch) DeepCopyInto(out *TapHeaderMatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TapHeaderMatch.
+func (in *TapHeaderMatch) DeepCopy() *TapHeaderMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(TapHeaderMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TapMatch) DeepCopyInto(out *TapMatch) {
+	*out = *in
+	if in.RequestHeaders != nil {
+		in, out := &in.RequestHeaders, &out.RequestHeaders
+		*out = make([]TapHeaderMatch, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResponseHeaders != nil {
+		in, out := &in.ResponseHeaders, &out.ResponseHeaders
+		*out = make([]TapHeaderMatch, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TapMatch.
+func (in *TapMatch) DeepCopy() *TapMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(TapMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TapOutput) DeepCopyInto(out *TapOutput) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TapOutput.
+func (in *TapOutput) DeepCopy() *TapOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(TapOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TapPolicy) DeepCopyInto(out *TapPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TapPolicy.
+func (in *TapPolicy) DeepCopy() *TapPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TapPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TapPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TapPolicyList) DeepCopyInto(out *TapPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TapPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TapPolicyList.
+func (in *TapPolicyList) DeepCopy() *TapPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(TapPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TapPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TapPolicySpec) DeepCopyInto(out *TapPolicySpec) {
+	*out = *in
+	if in.AmbassadorID != nil {
+		in, out := &in.AmbassadorID, &out.AmbassadorID
+		*out = make(AmbassadorID, len(*in))
+		copy(*out, *in)
+	}
+	if in.Mappings != nil {
+		in, out := &in.Mappings, &out.Mappings
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.CipherSuites != nil {
-		in, out := &in.CipherSuites, &out.CipherSuites
+	if in.TCPMappings != nil {
+		in, out := &in.TCPMappings, &out.TCPMappings
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.ECDHCurves != nil {
-		in, out := &in.ECDHCurves, &out.ECDHCurves
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.Match != nil {
+		in, out := &in.Match, &out.Match
+		*out = new(TapMatch)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.SecretNamespacing != nil {
-		in, out := &in.SecretNamespacing, &out.SecretNamespacing
-		*out = new(bool)
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(TapOutput)
 		**out = **in
 	}
+	if in.Sample != nil {
+		in, out := &in.Sample, &out.Sample
+		*out = new(TapSample)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSContextSpec.
-func (in *TLSContextSpec) DeepCopy() *TLSContextSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TapPolicySpec.
+func (in *TapPolicySpec) DeepCopy() *TapPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(TLSContextSpec)
+	out := new(TapPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TapSample) DeepCopyInto(out *TapSample) {
+	*out = *in
+	if in.RotateEvery != nil {
+		in, out := &in.RotateEvery, &out.RotateEvery
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TapSample.
+func (in *TapSample) DeepCopy() *TapSample {
+	if in == nil {
+		return nil
+	}
+	out := new(TapSample)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1738,6 +3267,46 @@ func (in *TraceConfig) DeepCopy() *TraceConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceCustomTag) DeepCopyInto(out *TraceCustomTag) {
+	*out = *in
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(TraceCustomTagMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceCustomTag.
+func (in *TraceCustomTag) DeepCopy() *TraceCustomTag {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceCustomTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceCustomTagMetadata) DeepCopyInto(out *TraceCustomTagMetadata) {
+	*out = *in
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceCustomTagMetadata.
+func (in *TraceCustomTagMetadata) DeepCopy() *TraceCustomTagMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceCustomTagMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TraceSampling) DeepCopyInto(out *TraceSampling) {
 	*out = *in
@@ -1834,6 +3403,13 @@ func (in *TracingServiceSpec) DeepCopyInto(out *TracingServiceSpec) {
 		*out = new(TraceConfig)
 		**out = **in
 	}
+	if in.CustomTags != nil {
+		in, out := &in.CustomTags, &out.CustomTags
+		*out = make([]TraceCustomTag, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingServiceSpec.
@@ -1882,3 +3458,142 @@ func (in *UntypedValue) DeepCopy() *UntypedValue {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WasmConfigMapSource) DeepCopyInto(out *WasmConfigMapSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WasmConfigMapSource.
+func (in *WasmConfigMapSource) DeepCopy() *WasmConfigMapSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WasmConfigMapSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WasmFilter) DeepCopyInto(out *WasmFilter) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WasmFilter.
+func (in *WasmFilter) DeepCopy() *WasmFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(WasmFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WasmFilter) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WasmFilterList) DeepCopyInto(out *WasmFilterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WasmFilter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WasmFilterList.
+func (in *WasmFilterList) DeepCopy() *WasmFilterList {
+	if in == nil {
+		return nil
+	}
+	out := new(WasmFilterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WasmFilterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WasmFilterSpec) DeepCopyInto(out *WasmFilterSpec) {
+	*out = *in
+	if in.AmbassadorID != nil {
+		in, out := &in.AmbassadorID, &out.AmbassadorID
+		*out = make(AmbassadorID, len(*in))
+		copy(*out, *in)
+	}
+	in.Module.DeepCopyInto(&out.Module)
+	if in.VMConfig != nil {
+		in, out := &in.VMConfig, &out.VMConfig
+		*out = new(WasmVMConfig)
+		**out = **in
+	}
+	if in.Mappings != nil {
+		in, out := &in.Mappings, &out.Mappings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WasmFilterSpec.
+func (in *WasmFilterSpec) DeepCopy() *WasmFilterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WasmFilterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WasmModule) DeepCopyInto(out *WasmModule) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(WasmConfigMapSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WasmModule.
+func (in *WasmModule) DeepCopy() *WasmModule {
+	if in == nil {
+		return nil
+	}
+	out := new(WasmModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WasmVMConfig) DeepCopyInto(out *WasmVMConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WasmVMConfig.
+func (in *WasmVMConfig) DeepCopy() *WasmVMConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WasmVMConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,159 @@
+// Package authpolicy implements a small, rule-driven auth service that
+// speaks Envoy's ext_authz HTTP protocol.  It's meant for demos and
+// for local testing of AuthService setups, not for production use:
+// credentials live in the policy in plain text.
+//
+// A policy is a list of rules, each of which applies to the paths
+// starting with its prefix:
+//
+//	rules:
+//	- prefix: /ambassador/
+//	  realm: Ambassador Diagnostics
+//	  basic_auth:
+//	    admin: admin
+//	- prefix: /api/
+//	  header: x-api-key
+//	  values: [ "s3cr3t" ]
+//	  add_headers:
+//	    x-authenticated-as: api-client
+//
+// The rule with the longest matching prefix wins; requests that match
+// no rule are allowed.
+package authpolicy
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// A Rule describes the credentials needed for requests whose path
+// starts with Prefix.  A rule with neither Header nor BasicAuth set
+// allows every request, which is useful to exempt a path under a
+// stricter rule.
+type Rule struct {
+	Prefix string `json:"prefix"`
+
+	// If Header is set, requests must have that header; if Values is
+	// also set, the header must have one of those values.
+	Header string   `json:"header,omitempty"`
+	Values []string `json:"values,omitempty"`
+
+	// If BasicAuth is set, requests must use HTTP Basic auth with one
+	// of the username/password pairs in it.  Realm is sent back in
+	// the WWW-Authenticate header when they don't.
+	BasicAuth map[string]string `json:"basic_auth,omitempty"`
+	Realm     string            `json:"realm,omitempty"`
+
+	// AddHeaders are added to allowed requests on their way to the
+	// upstream service.  The AuthService has to list them in
+	// allowed_authorization_headers.
+	AddHeaders map[string]string `json:"add_headers,omitempty"`
+}
+
+// A Policy is a validated list of rules, sorted so that longer
+// prefixes come first.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// A Decision is the result of checking a request against a Policy.
+type Decision struct {
+	Allowed bool
+	// Status is the HTTP status to reply with.
+	Status int
+	// Headers are the headers to reply with.
+	Headers http.Header
+	// Reason explains the decision, for logs and response bodies.
+	Reason string
+}
+
+// Parse parses and validates a YAML or JSON policy.
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i, rule := range policy.Rules {
+		if !strings.HasPrefix(rule.Prefix, "/") {
+			return nil, fmt.Errorf("rule %d: prefix %q must start with /", i, rule.Prefix)
+		}
+		if seen[rule.Prefix] {
+			return nil, fmt.Errorf("rule %d: prefix %q appears more than once", i, rule.Prefix)
+		}
+		seen[rule.Prefix] = true
+		if len(rule.Values) > 0 && rule.Header == "" {
+			return nil, fmt.Errorf("rule %d: values requires header", i)
+		}
+	}
+
+	sort.SliceStable(policy.Rules, func(i, j int) bool {
+		return len(policy.Rules[i].Prefix) > len(policy.Rules[j].Prefix)
+	})
+
+	return &policy, nil
+}
+
+// Match returns the rule that applies to path, or nil if no rule
+// does.
+func (p *Policy) Match(path string) *Rule {
+	for i := range p.Rules {
+		if strings.HasPrefix(path, p.Rules[i].Prefix) {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Check decides whether the request r, for path, is allowed.  The path
+// is passed separately because the AuthService's path_prefix has to
+// be removed from r.URL.Path first.
+func (p *Policy) Check(r *http.Request, path string) Decision {
+	rule := p.Match(path)
+	if rule == nil {
+		return Decision{Allowed: true, Status: http.StatusOK, Headers: http.Header{}, Reason: "no rule"}
+	}
+
+	if rule.Header != "" {
+		value := r.Header.Get(rule.Header)
+		if value == "" {
+			return deny(http.StatusUnauthorized, fmt.Sprintf("%s requires %s", rule.Prefix, rule.Header))
+		}
+		if len(rule.Values) > 0 && !contains(rule.Values, value) {
+			return deny(http.StatusForbidden, fmt.Sprintf("%s: bad %s", rule.Prefix, rule.Header))
+		}
+	}
+
+	if len(rule.BasicAuth) > 0 {
+		username, password, ok := r.BasicAuth()
+		if !ok || rule.BasicAuth[username] == "" || rule.BasicAuth[username] != password {
+			decision := deny(http.StatusUnauthorized, fmt.Sprintf("%s requires basic auth", rule.Prefix))
+			decision.Headers.Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", rule.Realm))
+			return decision
+		}
+	}
+
+	headers := http.Header{}
+	for k, v := range rule.AddHeaders {
+		headers.Set(k, v)
+	}
+	return Decision{Allowed: true, Status: http.StatusOK, Headers: headers, Reason: "allowed by " + rule.Prefix}
+}
+
+func deny(status int, reason string) Decision {
+	return Decision{Allowed: false, Status: status, Headers: http.Header{}, Reason: reason}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
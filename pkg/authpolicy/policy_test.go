@@ -0,0 +1,97 @@
+package authpolicy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+rules:
+- prefix: /api/
+  header: x-api-key
+  values: [ "s3cr3t" ]
+  add_headers:
+    x-authenticated-as: api-client
+- prefix: /api/health
+- prefix: /ambassador/
+  realm: Ambassador Diagnostics
+  basic_auth:
+    admin: admin
+`
+
+func TestParse(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+
+	// Longest prefix first.
+	assert.Equal(t, "/ambassador/", policy.Rules[0].Prefix)
+	assert.Equal(t, "/api/health", policy.Rules[1].Prefix)
+	assert.Equal(t, "/api/", policy.Rules[2].Prefix)
+
+	for _, bad := range []string{
+		"rules:\n- prefix: api/\n",
+		"rules:\n- prefix: /a\n- prefix: /a\n",
+		"rules:\n- prefix: /a\n  values: [ x ]\n",
+		"rules:\n- prefix: /a\n  unknown: x\n",
+	} {
+		_, err := Parse([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestServer(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+
+	s := NewServer("/extauth/")
+	s.SetPolicy(policy)
+
+	testcases := []struct {
+		path     string
+		setup    func(r *http.Request)
+		status   int
+		upstream string
+	}{
+		{path: "/extauth/open", status: http.StatusOK},
+		{path: "/extauth/api/users", status: http.StatusUnauthorized},
+		{
+			path:   "/extauth/api/users",
+			setup:  func(r *http.Request) { r.Header.Set("X-Api-Key", "wrong") },
+			status: http.StatusForbidden,
+		},
+		{
+			path:     "/extauth/api/users",
+			setup:    func(r *http.Request) { r.Header.Set("X-Api-Key", "s3cr3t") },
+			status:   http.StatusOK,
+			upstream: "api-client",
+		},
+		{path: "/extauth/api/health", status: http.StatusOK},
+		{path: "/extauth/ambassador/v0/diag/", status: http.StatusUnauthorized},
+		{
+			path:   "/extauth/ambassador/v0/diag/",
+			setup:  func(r *http.Request) { r.SetBasicAuth("admin", "admin") },
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testcases {
+		r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.setup != nil {
+			tc.setup(r)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		assert.Equal(t, tc.upstream, w.Header().Get("X-Authenticated-As"), tc.path)
+	}
+
+	// Failing basic auth asks for credentials.
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/extauth/ambassador/", nil))
+	assert.Equal(t, `Basic realm="Ambassador Diagnostics"`, w.Header().Get("WWW-Authenticate"))
+}
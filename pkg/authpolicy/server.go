@@ -0,0 +1,117 @@
+package authpolicy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+// A Server is an http.Handler that checks requests from Envoy's
+// ext_authz filter against the current Policy.
+type Server struct {
+	// PathPrefix is the AuthService's path_prefix; it's removed from
+	// the request path before the policy sees it.
+	PathPrefix string
+
+	policy atomic.Value // *Policy
+}
+
+// NewServer returns a Server with an empty policy, which allows
+// everything.
+func NewServer(pathPrefix string) *Server {
+	s := &Server{PathPrefix: pathPrefix}
+	s.SetPolicy(&Policy{})
+	return s
+}
+
+// SetPolicy replaces the Server's policy.  It's safe to call while
+// the Server is handling requests.
+func (s *Server) SetPolicy(p *Policy) {
+	s.policy.Store(p)
+}
+
+// Policy returns the Server's current policy.
+func (s *Server) Policy() *Policy {
+	return s.policy.Load().(*Policy)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(s.PathPrefix, "/"))
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	decision := s.Policy().Check(r, path)
+	dlog.Debugf(r.Context(), "%s %s: %d %s", r.Method, path, decision.Status, decision.Reason)
+
+	for k, v := range decision.Headers {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(decision.Status)
+	if !decision.Allowed {
+		_, _ = w.Write([]byte(decision.Reason + "\n"))
+	}
+}
+
+// LoadFile reads and parses the policy in filename, and makes it the
+// Server's policy.
+func (s *Server) LoadFile(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	policy, err := Parse(data)
+	if err != nil {
+		return errors.Wrap(err, filename)
+	}
+	s.SetPolicy(policy)
+	return nil
+}
+
+// Watch loads the policy in filename, then reloads it whenever it
+// changes, until ctx is canceled.  A policy that fails to load is
+// logged and otherwise ignored, so the last good policy stays in
+// effect.
+//
+// Watch watches the directory holding filename rather than the file
+// itself, so that it sees the updates Kubernetes makes to a mounted
+// ConfigMap (which swap out a symlink).
+func (s *Server) Watch(ctx context.Context, filename string) error {
+	if err := s.LoadFile(filename); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(filename)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-watcher.Events:
+			dlog.Debugf(ctx, "policy watch: %v", event)
+			if err := s.LoadFile(filename); err != nil {
+				dlog.Errorf(ctx, "keeping the old policy: %v", err)
+			} else {
+				dlog.Infof(ctx, "reloaded policy from %s", filename)
+			}
+		case err := <-watcher.Errors:
+			dlog.Errorf(ctx, "policy watch: %v", err)
+		}
+	}
+}
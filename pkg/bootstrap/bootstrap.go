@@ -0,0 +1,266 @@
+// Package bootstrap applies an operator's overrides to the Envoy bootstrap configuration that diagd
+// writes. diagd works out the parts of the bootstrap that come from Ambassador's resources; the
+// overrides are for what Ambassador has no resource for, such as Envoy's overload manager, and
+// come from a ConfigMap rather than from editing the bootstrap by hand after the fact.
+//
+// An Override is typed, and checked against Envoy's own API, so that a mistake in it is caught
+// when it's loaded rather than when Envoy refuses to start.
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"sigs.k8s.io/yaml"
+
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	bootstrapv2 "github.com/datawire/ambassador/pkg/api/envoy/config/bootstrap/v2"
+	metricsv2 "github.com/datawire/ambassador/pkg/api/envoy/config/metrics/v2"
+	overloadv2alpha "github.com/datawire/ambassador/pkg/api/envoy/config/overload/v2alpha"
+)
+
+// An Override is what the bootstrap overrides ConfigMap holds, such as:
+//
+//	admin:
+//	  access_log_path: /tmp/admin_access_log
+//	stats_sinks:
+//	- name: envoy.statsd
+//	  config:
+//	    address: {socket_address: {address: statsd, port_value: 8125, protocol: UDP}}
+//	overload_manager:
+//	  refresh_interval: 0.25s
+//	  resource_monitors: ...
+//	  actions: ...
+//	static_clusters:
+//	- name: statsd
+//	  ...
+type Override struct {
+	// Admin's fields replace those of Envoy's admin settings, if they're set.
+	Admin *bootstrapv2.Admin
+	// StatsSinks are added to any that diagd sets up.
+	StatsSinks []*metricsv2.StatsSink
+	// OverloadManager replaces Envoy's overload manager, which diagd leaves out.
+	OverloadManager *overloadv2alpha.OverloadManager
+	// StaticClusters are added to the static clusters, typically for the stats sinks to send to.
+	// They mustn't have the same name as one of diagd's.
+	StaticClusters []*apiv2.Cluster
+}
+
+// validator is what protoc-gen-validate gives each of Envoy's messages.
+type validator interface {
+	Validate() error
+}
+
+// Parse parses an Override from YAML or JSON.
+func Parse(data []byte) (*Override, error) {
+	jsonBytes, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &fields); err != nil {
+		return nil, err
+	}
+
+	o := &Override{}
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "admin":
+			o.Admin = &bootstrapv2.Admin{}
+			err = unmarshal(raw, o.Admin)
+		case "stats_sinks":
+			err = unmarshalList(raw, func() proto.Message {
+				sink := &metricsv2.StatsSink{}
+				o.StatsSinks = append(o.StatsSinks, sink)
+				return sink
+			})
+		case "overload_manager":
+			o.OverloadManager = &overloadv2alpha.OverloadManager{}
+			err = unmarshal(raw, o.OverloadManager)
+		case "static_clusters":
+			err = unmarshalList(raw, func() proto.Message {
+				cluster := &apiv2.Cluster{}
+				o.StaticClusters = append(o.StaticClusters, cluster)
+				return cluster
+			})
+		default:
+			err = fmt.Errorf("unknown field (want admin, stats_sinks, overload_manager or static_clusters)")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	return o, nil
+}
+
+// unmarshal unmarshals raw into m, and checks it.
+func unmarshal(raw json.RawMessage, m proto.Message) error {
+	if err := jsonpb.Unmarshal(bytes.NewReader(raw), m); err != nil {
+		return err
+	}
+	if v, ok := m.(validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// unmarshalList unmarshals each element of the list raw into a new message from next.
+func unmarshalList(raw json.RawMessage, next func() proto.Message) error {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return err
+	}
+	for i, item := range list {
+		if err := unmarshal(item, next()); err != nil {
+			return fmt.Errorf("%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Load loads the Override in the file at path. If there's no such file, there's nothing to
+// override, and it returns nil.
+func Load(path string) (*Override, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	o, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return o, nil
+}
+
+// toMap returns m as JSON, decoded into a map, with the field names in Envoy's snake case.
+func toMap(m proto.Message) (map[string]interface{}, error) {
+	text, err := (&jsonpb.Marshaler{OrigName: true}).MarshalToString(m)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	err = json.Unmarshal([]byte(text), &result)
+	return result, err
+}
+
+// childMap returns parent[key] as a map, adding it if it isn't there.
+func childMap(parent map[string]interface{}, key string) (map[string]interface{}, error) {
+	switch child := parent[key].(type) {
+	case nil:
+		result := map[string]interface{}{}
+		parent[key] = result
+		return result, nil
+	case map[string]interface{}:
+		return child, nil
+	default:
+		return nil, fmt.Errorf("%s: not an object", key)
+	}
+}
+
+// appendList appends each of ms to parent[key], which must be a list if it's there.
+func appendList(parent map[string]interface{}, key string, ms []proto.Message) error {
+	list, ok := parent[key].([]interface{})
+	if !ok && parent[key] != nil {
+		return fmt.Errorf("%s: not a list", key)
+	}
+	for _, m := range ms {
+		item, err := toMap(m)
+		if err != nil {
+			return err
+		}
+		list = append(list, item)
+	}
+	parent[key] = list
+	return nil
+}
+
+// The Apply method applies the Override to base, which is an Envoy bootstrap configuration in
+// JSON, such as diagd writes, and returns the result. A nil Override returns base as it is.
+func (o *Override) Apply(base []byte) ([]byte, error) {
+	if o == nil {
+		return base, nil
+	}
+
+	var bootstrap map[string]interface{}
+	if err := json.Unmarshal(base, &bootstrap); err != nil {
+		return nil, err
+	}
+
+	if o.Admin != nil {
+		admin, err := childMap(bootstrap, "admin")
+		if err != nil {
+			return nil, err
+		}
+		fields, err := toMap(o.Admin)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fields {
+			admin[key] = value
+		}
+	}
+
+	if len(o.StatsSinks) > 0 {
+		var sinks []proto.Message
+		for _, sink := range o.StatsSinks {
+			sinks = append(sinks, sink)
+		}
+		if err := appendList(bootstrap, "stats_sinks", sinks); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.OverloadManager != nil {
+		overload, err := toMap(o.OverloadManager)
+		if err != nil {
+			return nil, err
+		}
+		bootstrap["overload_manager"] = overload
+	}
+
+	if len(o.StaticClusters) > 0 {
+		static, err := childMap(bootstrap, "static_resources")
+		if err != nil {
+			return nil, err
+		}
+		existing := map[string]bool{}
+		if list, ok := static["clusters"].([]interface{}); ok {
+			for _, item := range list {
+				if cluster, ok := item.(map[string]interface{}); ok {
+					name, _ := cluster["name"].(string)
+					existing[name] = true
+				}
+			}
+		}
+		var clusters []proto.Message
+		var clashes []string
+		for _, cluster := range o.StaticClusters {
+			if existing[cluster.Name] {
+				clashes = append(clashes, cluster.Name)
+			}
+			existing[cluster.Name] = true
+			clusters = append(clusters, cluster)
+		}
+		if len(clashes) > 0 {
+			sort.Strings(clashes)
+			return nil, fmt.Errorf("static_clusters: already defined: %s", strings.Join(clashes, ", "))
+		}
+		if err := appendList(static, "clusters", clusters); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.MarshalIndent(bootstrap, "", "    ")
+}
@@ -0,0 +1,148 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// base is a cut-down bootstrap, as diagd writes it.
+const base = `{
+    "admin": {
+        "access_log_path": "/tmp/admin_access_log",
+        "address": {"socket_address": {"address": "127.0.0.1", "port_value": 8001}}
+    },
+    "node": {"cluster": "ambassador-default", "id": "test-id"},
+    "static_resources": {
+        "clusters": [{"name": "xds_cluster", "connect_timeout": "1s"}]
+    },
+    "stats_sinks": [{"name": "envoy.statsd", "config": {"address": {"socket_address": {"address": "127.0.0.1", "port_value": 8125, "protocol": "UDP"}}}}]
+}`
+
+const overrides = `
+admin:
+  access_log_path: /dev/stdout
+stats_sinks:
+- name: envoy.dog_statsd
+  config:
+    address:
+      socket_address: {address: 10.0.0.1, port_value: 8125, protocol: UDP}
+overload_manager:
+  refresh_interval: 0.25s
+  resource_monitors:
+  - name: envoy.resource_monitors.fixed_heap
+    config:
+      max_heap_size_bytes: 1073741824
+  actions:
+  - name: envoy.overload_actions.shrink_heap
+    triggers:
+    - name: envoy.resource_monitors.fixed_heap
+      threshold: {value: 0.95}
+static_clusters:
+- name: statsd_exporter
+  connect_timeout: 1s
+  type: STRICT_DNS
+  load_assignment:
+    cluster_name: statsd_exporter
+    endpoints:
+    - lb_endpoints:
+      - endpoint:
+          address:
+            socket_address: {address: statsd-exporter, port_value: 9125}
+`
+
+func apply(t *testing.T, o *Override) map[string]interface{} {
+	t.Helper()
+	result, err := o.Apply([]byte(base))
+	require.NoError(t, err)
+	var bootstrap map[string]interface{}
+	require.NoError(t, json.Unmarshal(result, &bootstrap))
+	return bootstrap
+}
+
+func TestApply(t *testing.T) {
+	o, err := Parse([]byte(overrides))
+	require.NoError(t, err)
+	bootstrap := apply(t, o)
+
+	// The admin fields that are set replace diagd's; the rest are left alone.
+	admin := bootstrap["admin"].(map[string]interface{})
+	assert.Equal(t, "/dev/stdout", admin["access_log_path"])
+	assert.NotNil(t, admin["address"])
+
+	sinks := bootstrap["stats_sinks"].([]interface{})
+	require.Len(t, sinks, 2)
+	assert.Equal(t, "envoy.statsd", sinks[0].(map[string]interface{})["name"])
+	assert.Equal(t, "envoy.dog_statsd", sinks[1].(map[string]interface{})["name"])
+
+	overload := bootstrap["overload_manager"].(map[string]interface{})
+	assert.Equal(t, "0.250s", overload["refresh_interval"])
+
+	clusters := bootstrap["static_resources"].(map[string]interface{})["clusters"].([]interface{})
+	require.Len(t, clusters, 2)
+	assert.Equal(t, "statsd_exporter", clusters[1].(map[string]interface{})["name"])
+
+	// Everything else is as diagd had it.
+	assert.Equal(t, "test-id", bootstrap["node"].(map[string]interface{})["id"])
+}
+
+func TestApplyNothing(t *testing.T) {
+	var o *Override
+	result, err := o.Apply([]byte(base))
+	require.NoError(t, err)
+	assert.Equal(t, base, string(result))
+
+	bootstrap := apply(t, &Override{})
+	assert.Len(t, bootstrap["stats_sinks"], 1)
+	assert.Nil(t, bootstrap["overload_manager"])
+}
+
+func TestApplyClash(t *testing.T) {
+	o, err := Parse([]byte(`
+static_clusters:
+- name: xds_cluster
+  connect_timeout: 1s
+`))
+	require.NoError(t, err)
+	_, err = o.Apply([]byte(base))
+	assert.EqualError(t, err, "static_clusters: already defined: xds_cluster")
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"unknown field":   "listeners: []\n",
+		"not Envoy's API": "admin:\n  access_log: /dev/stdout\n",
+		// A cluster has to have a name, and a connect_timeout that's more than zero.
+		"invalid":    "static_clusters:\n- name: ''\n",
+		"not a list": "stats_sinks:\n  name: envoy.statsd\n",
+	} {
+		_, err := Parse([]byte(input))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bootstrap")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// No file, no overrides.
+	o, err := Load(filepath.Join(dir, "bootstrap-overrides.yaml"))
+	require.NoError(t, err)
+	assert.Nil(t, o)
+
+	file := filepath.Join(dir, "bootstrap-overrides.yaml")
+	require.NoError(t, ioutil.WriteFile(file, []byte("admin:\n  access_log_path: /dev/stdout\n"), 0644))
+	o, err = Load(file)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/stdout", o.Admin.AccessLogPath)
+
+	require.NoError(t, ioutil.WriteFile(file, []byte("nope: 1\n"), 0644))
+	_, err = Load(file)
+	assert.Error(t, err)
+}
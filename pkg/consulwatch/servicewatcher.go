@@ -12,8 +12,10 @@ import (
 
 type ServiceWatcher struct {
 	ServiceName string
+	Datacenter  string
 	consul      *consulapi.Client
 	plan        *watch.Plan
+	cancel      context.CancelFunc
 }
 
 func New(client *consulapi.Client, datacenter string, service string, onlyHealthy bool) (*ServiceWatcher, error) {
@@ -36,12 +38,40 @@ func New(client *consulapi.Client, datacenter string, service string, onlyHealth
 		return nil, err
 	}
 
-	return &ServiceWatcher{consul: client, ServiceName: service, plan: plan}, nil
+	return &ServiceWatcher{consul: client, ServiceName: service, Datacenter: datacenter, plan: plan}, nil
+}
+
+// NewFiltered is like New, but only watches the instances of the service that match a Consul filter expression
+// (https://www.consul.io/api-docs/features/filtering), such as `"canary" not in Service.Tags` or
+// `Node.Meta.rack == "r1"`. An empty filter matches every instance.
+func NewFiltered(client *consulapi.Client, datacenter string, service string, filter string, onlyHealthy bool) (*ServiceWatcher, error) {
+	w, err := New(client, datacenter, service, onlyHealthy)
+	if err != nil || filter == "" {
+		return w, err
+	}
+
+	// The service watch type doesn't take a filter, so we make the same blocking query as it does, with the filter
+	// added. Stop cancels the query that is in flight.
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	var index uint64
+	w.plan.Watcher = func(_ *watch.Plan) (watch.BlockingParamVal, interface{}, error) {
+		opts := &consulapi.QueryOptions{Datacenter: datacenter, Filter: filter, WaitIndex: index}
+		entries, meta, err := client.Health().Service(service, "", onlyHealthy, opts.WithContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+		index = meta.LastIndex
+		return watch.WaitIndexVal(meta.LastIndex), entries, nil
+	}
+
+	return w, nil
 }
 
 func (w *ServiceWatcher) Watch(handler func(endpoints Endpoints, err error)) {
 	w.plan.HybridHandler = func(val watch.BlockingParamVal, raw interface{}) {
-		endpoints := Endpoints{Service: w.ServiceName, Endpoints: []Endpoint{}}
+		endpoints := Endpoints{Id: w.Datacenter, Service: w.ServiceName, Endpoints: []Endpoint{}}
 
 		if raw == nil {
 			handler(endpoints, fmt.Errorf("unexpected empty/nil response from consul"))
@@ -81,4 +111,7 @@ func (w *ServiceWatcher) Start(ctx context.Context) error {
 
 func (w *ServiceWatcher) Stop() {
 	w.plan.Stop()
+	if w.cancel != nil {
+		w.cancel()
+	}
 }
@@ -3,13 +3,24 @@ package consulwatch
 import "time"
 
 // Endpoints contains an Array of Endpoint structs and meta information about the Service that the contained endpoints
-// are associated with.
+// are associated with. Id is the name of the datacenter that the endpoints are in.
 type Endpoints struct {
 	Id        string     `json:""`
 	Service   string     `json:""`
 	Endpoints []Endpoint `json:""`
 }
 
+// Key returns the key of the endpoints in a ConsulSnapshot. A service can be
+// in several datacenters, so the key has both.
+func (e *Endpoints) Key() string {
+	return EndpointsKey(e.Service, e.Id)
+}
+
+// EndpointsKey returns the key of the endpoints of a service in a datacenter.
+func EndpointsKey(service, datacenter string) string {
+	return service + "-" + datacenter
+}
+
 // GroupByTags returns a map of tag name to array of Endpoint structs.
 func (e *Endpoints) GroupByTags() map[string][]Endpoint {
 	result := make(map[string][]Endpoint)
@@ -37,6 +48,15 @@ type Endpoint struct {
 	Tags     []string `json:""`
 }
 
+// Connect holds the Consul Connect leaf certificate and CA roots that were fetched for a ConsulResolver. Resolver and
+// Namespace name the resolver.
+type Connect struct {
+	Resolver    string       `json:""`
+	Namespace   string       `json:""`
+	Certificate *Certificate `json:",omitempty"`
+	CARoots     *CARoots     `json:",omitempty"`
+}
+
 type Certificate struct {
 	SerialNumber  string    `json:",omitempty"`
 	PEM           string    `json:",omitempty"`
@@ -0,0 +1,349 @@
+package dlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ComponentField is the field that says which module of a program
+// logged an entry.  WithLevels filters entries by it.
+const ComponentField = "component"
+
+var logLevelNames = map[LogLevel]string{
+	LogLevelError: "error",
+	LogLevelWarn:  "warn",
+	LogLevelInfo:  "info",
+	LogLevelDebug: "debug",
+	LogLevelTrace: "trace",
+}
+
+func (level LogLevel) String() string {
+	if name, ok := logLevelNames[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", uint32(level))
+}
+
+// ParseLogLevel parses the name of a LogLevel: "error", "warn",
+// "info", "debug" or "trace".  "warning" is the same as "warn".
+func ParseLogLevel(name string) (LogLevel, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warning" {
+		name = "warn"
+	}
+	for level, levelName := range logLevelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return 0, errors.Errorf("invalid log level %q", name)
+}
+
+// Levels are the log levels of the modules of a program: each module
+// logs at the level that is set for it, or at the default level if
+// none is.  Levels may be changed while the program runs.
+type Levels struct {
+	mu       sync.RWMutex
+	fallback LogLevel
+	modules  map[string]LogLevel
+}
+
+// NewLevels returns Levels with the default level def and no
+// per-module levels.
+func NewLevels(def LogLevel) *Levels {
+	return &Levels{fallback: def, modules: map[string]LogLevel{}}
+}
+
+// ParseLevels parses a comma-separated list of levels, such as
+// "info,ambex=debug,watcher=trace".  An item without a module sets
+// the default level, which is info if no item sets it.
+func ParseLevels(spec string) (*Levels, error) {
+	levels := NewLevels(LogLevelInfo)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var module, name string
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			module, name = strings.TrimSpace(item[:i]), item[i+1:]
+			if module == "" {
+				return nil, errors.Errorf("invalid log level %q: no module before '='", item)
+			}
+		} else {
+			name = item
+		}
+		level, err := ParseLogLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		levels.Set(module, level)
+	}
+	return levels, nil
+}
+
+// Level returns the level of module.
+func (l *Levels) Level(module string) LogLevel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.modules[module]; ok {
+		return level
+	}
+	return l.fallback
+}
+
+// Lookup returns the level of module, and whether it is set for
+// module rather than being the default.  The default level is always
+// set for "".
+func (l *Levels) Lookup(module string) (LogLevel, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.modules[module]; ok {
+		return level, true
+	}
+	return l.fallback, module == ""
+}
+
+// Modules returns the levels that are set for modules.
+func (l *Levels) Modules() map[string]LogLevel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ret := make(map[string]LogLevel, len(l.modules))
+	for module, level := range l.modules {
+		ret[module] = level
+	}
+	return ret
+}
+
+// Set sets the level of module, or the default level if module is "".
+func (l *Levels) Set(module string, level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if module == "" {
+		l.fallback = level
+	} else {
+		l.modules[module] = level
+	}
+}
+
+// Unset makes module log at the default level again.
+func (l *Levels) Unset(module string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.modules, module)
+}
+
+// String returns the levels in the form that ParseLevels parses.
+func (l *Levels) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	items := make([]string, 0, len(l.modules))
+	for module, level := range l.modules {
+		items = append(items, module+"="+level.String())
+	}
+	sort.Strings(items)
+	return strings.Join(append([]string{l.fallback.String()}, items...), ",")
+}
+
+// MarshalJSON returns the levels as {"default": "info", "modules":
+// {"ambex": "debug"}}.
+func (l *Levels) MarshalJSON() ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	modules := make(map[string]string, len(l.modules))
+	for module, level := range l.modules {
+		modules[module] = level.String()
+	}
+	return json.Marshal(map[string]interface{}{
+		"default": l.fallback.String(),
+		"modules": modules,
+	})
+}
+
+// WithLevels returns a Logger that drops the entries that are below
+// the level of their module, according to levels.  An entry's module
+// is its ComponentField.  Print entries are Info entries.
+//
+// You should only really ever call WithLevels from the initial
+// process set up (i.e. directly inside your 'main()' function), and
+// you should pass the result directly to WithLogger.  The Logger that
+// you wrap should log every level; see NewJSONLogger.
+func WithLevels(in Logger, levels *Levels) Logger {
+	return levelLogger{in: in, levels: levels}
+}
+
+type levelLogger struct {
+	in        Logger
+	levels    *Levels
+	component string
+}
+
+func (l levelLogger) enabled(level LogLevel) bool {
+	return level <= l.levels.Level(l.component)
+}
+
+func (l levelLogger) Helper() { l.in.Helper() }
+
+func (l levelLogger) WithField(key string, value interface{}) Logger {
+	ret := levelLogger{in: l.in.WithField(key, value), levels: l.levels, component: l.component}
+	if key == ComponentField {
+		ret.component = fmt.Sprint(value)
+	}
+	return ret
+}
+
+// StdLogger checks the level when each line is written, rather than
+// when the *log.Logger is made, so that it follows changes to the
+// levels.
+func (l levelLogger) StdLogger(level LogLevel) *log.Logger {
+	return log.New(levelWriter{l: l, level: level, out: l.in.StdLogger(level).Writer()}, "", 0)
+}
+
+type levelWriter struct {
+	l     levelLogger
+	level LogLevel
+	out   io.Writer
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	if !w.l.enabled(w.level) {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+func (l levelLogger) Tracef(f string, a ...interface{}) {
+	if l.enabled(LogLevelTrace) {
+		l.in.Helper()
+		l.in.Tracef(f, a...)
+	}
+}
+func (l levelLogger) Debugf(f string, a ...interface{}) {
+	if l.enabled(LogLevelDebug) {
+		l.in.Helper()
+		l.in.Debugf(f, a...)
+	}
+}
+func (l levelLogger) Infof(f string, a ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.in.Helper()
+		l.in.Infof(f, a...)
+	}
+}
+func (l levelLogger) Printf(f string, a ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.in.Helper()
+		l.in.Printf(f, a...)
+	}
+}
+func (l levelLogger) Warnf(f string, a ...interface{}) {
+	if l.enabled(LogLevelWarn) {
+		l.in.Helper()
+		l.in.Warnf(f, a...)
+	}
+}
+func (l levelLogger) Warningf(f string, a ...interface{}) {
+	if l.enabled(LogLevelWarn) {
+		l.in.Helper()
+		l.in.Warningf(f, a...)
+	}
+}
+func (l levelLogger) Errorf(f string, a ...interface{}) {
+	if l.enabled(LogLevelError) {
+		l.in.Helper()
+		l.in.Errorf(f, a...)
+	}
+}
+
+func (l levelLogger) Trace(a ...interface{}) {
+	if l.enabled(LogLevelTrace) {
+		l.in.Helper()
+		l.in.Trace(a...)
+	}
+}
+func (l levelLogger) Debug(a ...interface{}) {
+	if l.enabled(LogLevelDebug) {
+		l.in.Helper()
+		l.in.Debug(a...)
+	}
+}
+func (l levelLogger) Info(a ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.in.Helper()
+		l.in.Info(a...)
+	}
+}
+func (l levelLogger) Print(a ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.in.Helper()
+		l.in.Print(a...)
+	}
+}
+func (l levelLogger) Warn(a ...interface{}) {
+	if l.enabled(LogLevelWarn) {
+		l.in.Helper()
+		l.in.Warn(a...)
+	}
+}
+func (l levelLogger) Warning(a ...interface{}) {
+	if l.enabled(LogLevelWarn) {
+		l.in.Helper()
+		l.in.Warning(a...)
+	}
+}
+func (l levelLogger) Error(a ...interface{}) {
+	if l.enabled(LogLevelError) {
+		l.in.Helper()
+		l.in.Error(a...)
+	}
+}
+
+func (l levelLogger) Traceln(a ...interface{}) {
+	if l.enabled(LogLevelTrace) {
+		l.in.Helper()
+		l.in.Traceln(a...)
+	}
+}
+func (l levelLogger) Debugln(a ...interface{}) {
+	if l.enabled(LogLevelDebug) {
+		l.in.Helper()
+		l.in.Debugln(a...)
+	}
+}
+func (l levelLogger) Infoln(a ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.in.Helper()
+		l.in.Infoln(a...)
+	}
+}
+func (l levelLogger) Println(a ...interface{}) {
+	if l.enabled(LogLevelInfo) {
+		l.in.Helper()
+		l.in.Println(a...)
+	}
+}
+func (l levelLogger) Warnln(a ...interface{}) {
+	if l.enabled(LogLevelWarn) {
+		l.in.Helper()
+		l.in.Warnln(a...)
+	}
+}
+func (l levelLogger) Warningln(a ...interface{}) {
+	if l.enabled(LogLevelWarn) {
+		l.in.Helper()
+		l.in.Warningln(a...)
+	}
+}
+func (l levelLogger) Errorln(a ...interface{}) {
+	if l.enabled(LogLevelError) {
+		l.in.Helper()
+		l.in.Errorln(a...)
+	}
+}
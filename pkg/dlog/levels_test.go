@@ -0,0 +1,112 @@
+package dlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := dlog.ParseLevels("warn, ambex=debug,watcher=TRACE")
+	require.NoError(t, err)
+	assert.Equal(t, dlog.LogLevelWarn, levels.Level(""))
+	assert.Equal(t, dlog.LogLevelWarn, levels.Level("consul"))
+	assert.Equal(t, dlog.LogLevelDebug, levels.Level("ambex"))
+	assert.Equal(t, dlog.LogLevelTrace, levels.Level("watcher"))
+	assert.Equal(t, "warn,ambex=debug,watcher=trace", levels.String())
+	assert.Equal(t, map[string]dlog.LogLevel{"ambex": dlog.LogLevelDebug, "watcher": dlog.LogLevelTrace}, levels.Modules())
+	level, set := levels.Lookup("consul")
+	assert.Equal(t, dlog.LogLevelWarn, level)
+	assert.False(t, set)
+	level, set = levels.Lookup("ambex")
+	assert.Equal(t, dlog.LogLevelDebug, level)
+	assert.True(t, set)
+	_, set = levels.Lookup("")
+	assert.True(t, set)
+
+	levels, err = dlog.ParseLevels("")
+	require.NoError(t, err)
+	assert.Equal(t, "info", levels.String())
+
+	_, err = dlog.ParseLevels("ambex=loud")
+	assert.EqualError(t, err, `invalid log level "loud"`)
+	_, err = dlog.ParseLevels("=debug")
+	assert.EqualError(t, err, `invalid log level "=debug": no module before '='`)
+}
+
+// logBuffer is a bytes.Buffer that StdLoggers can write to from
+// their own goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines decodes the lines that a JSON logger has written, without
+// their times, once there are n of them, and resets the buffer.
+func (b *logBuffer) lines(t *testing.T, n int) []map[string]interface{} {
+	var ret []map[string]interface{}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		b.mu.Lock()
+		text := strings.TrimSpace(b.buf.String())
+		if (text != "" && strings.Count(text, "\n")+1 >= n) || time.Now().After(deadline) {
+			b.buf.Reset()
+			b.mu.Unlock()
+			for _, line := range strings.Split(text, "\n") {
+				var entry map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(line), &entry))
+				delete(entry, "time")
+				ret = append(ret, entry)
+			}
+			return ret
+		}
+		b.mu.Unlock()
+	}
+}
+
+func TestWithLevels(t *testing.T) {
+	var buf logBuffer
+	levels := dlog.NewLevels(dlog.LogLevelInfo)
+	levels.Set("ambex", dlog.LogLevelDebug)
+	ctx := dlog.WithLogger(context.Background(), dlog.WithLevels(dlog.NewJSONLogger(&buf), levels))
+	ambex := dlog.WithField(ctx, dlog.ComponentField, "ambex")
+	watcher := dlog.WithField(ctx, dlog.ComponentField, "watcher")
+
+	dlog.Debugf(ambex, "pushing %s", "v1")
+	dlog.Debugf(watcher, "hidden")
+	dlog.Infoln(dlog.WithField(watcher, "resource", "Mapping/quote.default"), "invalid")
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "debug", "msg": "pushing v1", "component": "ambex"},
+		{"level": "info", "msg": "invalid", "component": "watcher", "resource": "Mapping/quote.default"},
+	}, buf.lines(t, 2))
+
+	// Levels can change at runtime, including for StdLoggers that
+	// already exist.
+	std := dlog.StdLogger(watcher, dlog.LogLevelDebug)
+	std.Print("hidden")
+	levels.Set("watcher", dlog.LogLevelDebug)
+	levels.Unset("ambex")
+	std.Print("shown")
+	dlog.Debugf(ambex, "hidden")
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "debug", "msg": "shown", "component": "watcher"},
+	}, buf.lines(t, 1))
+
+	encoded, err := json.Marshal(levels)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"default": "info", "modules": {"watcher": "debug"}}`, string(encoded))
+}
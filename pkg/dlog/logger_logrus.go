@@ -5,6 +5,7 @@ import (
 	"log"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -74,6 +75,18 @@ func WrapLogrus(in *logrus.Logger) Logger {
 	return logrusWrapper{in}
 }
 
+// NewJSONLogger returns a Logger that writes each entry to out as a
+// line of JSON, with "time", "level" and "msg" keys besides the
+// entry's fields.  It logs every level; use WithLevels to filter
+// them.
+func NewJSONLogger(out io.Writer) Logger {
+	logger := logrus.New()
+	logger.SetOutput(out)
+	logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	logger.SetLevel(logrus.TraceLevel)
+	return WrapLogrus(logger)
+}
+
 type logrusFixCallerHook struct{}
 
 func (logrusFixCallerHook) Levels() []logrus.Level {
@@ -0,0 +1,313 @@
+package dtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+
+	"github.com/datawire/ambassador/pkg/supervisor"
+)
+
+// A ClusterProvider runs the Kubernetes cluster that tests run against.
+type ClusterProvider interface {
+	// Name returns the name that DTEST_CLUSTER selects the provider by.
+	Name() string
+	// Up starts the cluster if it isn't running, and returns what identifies it.
+	Up() string
+	// Down shuts the cluster down, and returns what identified it, or the empty string if it
+	// wasn't running.
+	Down() string
+	// Kubeconfig returns the kubeconfig contents for the cluster, or the empty string if it
+	// isn't running.
+	Kubeconfig() string
+	// KubeconfigPath returns the path of a kubeconfig file for the cluster, or the empty string
+	// if it isn't running.
+	KubeconfigPath() string
+	// Ready returns whether the cluster that the kubeconfig file refers to is ready for tests.
+	Ready(kubeconfig string) bool
+}
+
+const dtestCluster = "DTEST_CLUSTER"
+
+var providers = map[string]ClusterProvider{
+	"k3s":    k3sProvider{},
+	"kind":   kindProvider{},
+	"k3d":    k3dProvider{},
+	"remote": remoteProvider{},
+}
+
+const clusterMsg = `
+%v
+
+  Set DTEST_CLUSTER to one of: %s.
+
+`
+
+// Cluster returns the ClusterProvider that the DTEST_CLUSTER environment variable selects:
+//
+//	k3s     k3s in a docker container (the default)
+//	kind    a kind cluster
+//	k3d     a k3d cluster
+//	remote  the existing cluster that DTEST_KUBECONFIG refers to
+//
+// If DTEST_CLUSTER isn't set but DTEST_KUBECONFIG is, the provider is "remote".
+func Cluster() ClusterProvider {
+	provider, err := clusterProvider(os.Getenv(dtestCluster), os.Getenv(dtestKubeconfig))
+	if err != nil {
+		fmt.Printf(clusterMsg, err, strings.Join(providerNames(), ", "))
+		os.Exit(1)
+	}
+	return provider
+}
+
+func clusterProvider(name, kubeconfig string) (ClusterProvider, error) {
+	if name == "" {
+		if kubeconfig != "" {
+			name = "remote"
+		} else {
+			name = "k3s"
+		}
+	}
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s: %q", dtestCluster, name)
+	}
+	if name == "remote" && kubeconfig == "" {
+		return nil, fmt.Errorf("%s=remote needs %s to be set", dtestCluster, dtestKubeconfig)
+	}
+	return provider, nil
+}
+
+func providerNames() []string {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeKubeconfig writes the kubeconfig contents to a file named for the user and name, and
+// returns its path, or the empty string if there are no contents.
+func writeKubeconfig(name, contents string) string {
+	if contents == "" {
+		return ""
+	}
+
+	user, err := user.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	kubeconfig := fmt.Sprintf("/tmp/dtest-kubeconfig-%s-%s.yaml", user.Username, name)
+	err = ioutil.WriteFile(kubeconfig, []byte(contents), 0644)
+	if err != nil {
+		panic(err)
+	}
+
+	return kubeconfig
+}
+
+// isClusterReady returns whether the cluster that the kubeconfig file refers to can run pods,
+// which it can once the default service account exists.
+func isClusterReady(kubeconfig string) bool {
+	cmd := supervisor.Command(prefix, "kubectl", "--kubeconfig", kubeconfig, "get", "serviceaccount", "default",
+		"--namespace", "default")
+	_, err := cmd.Capture(nil)
+	return err == nil
+}
+
+// The kind and k3d clusters pull images that are pushed to the registry at localhost:5000 from
+// the registry container, which they reach by name on their docker network.
+var registryName = fmt.Sprintf("%s-registry", scope)
+var registryMirror = fmt.Sprintf("http://%s:%s", registryName, registryPort)
+
+// connectRegistry connects the registry container to the named docker network, if it isn't
+// connected already.
+func connectRegistry(network string) {
+	cmd := supervisor.Command(prefix, "docker", "inspect", "-f", "{{range $name, $_ := .NetworkSettings.Networks}}{{$name}} {{end}}",
+		registryName)
+	for _, connected := range strings.Fields(cmd.MustCapture(nil)) {
+		if connected == network {
+			return
+		}
+	}
+	supervisor.Command(prefix, "docker", "network", "connect", network, registryName).MustCapture(nil)
+}
+
+// withTempFile writes contents to a temporary file, and calls body with its path.
+func withTempFile(pattern, contents string, body func(path string)) {
+	file, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(contents); err != nil {
+		panic(err)
+	}
+	if err := file.Close(); err != nil {
+		panic(err)
+	}
+	body(file.Name())
+}
+
+const clusterName = "dtest"
+
+const dtestKindImage = "DTEST_KIND_IMAGE"
+
+var kindConfig = fmt.Sprintf(`kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors."localhost:%s"]
+    endpoint = ["%s"]
+`, registryPort, registryMirror)
+
+// kindProvider runs a kind cluster, with the node image in DTEST_KIND_IMAGE if that's set.
+type kindProvider struct{}
+
+func (kindProvider) Name() string { return "kind" }
+
+func (kindProvider) running() bool {
+	cmd := supervisor.Command(prefix, "kind", "get", "clusters")
+	for _, name := range lines(cmd.MustCapture(nil)) {
+		if name == clusterName {
+			return true
+		}
+	}
+	return false
+}
+
+func (p kindProvider) Up() string {
+	RegistryUp()
+	WithNamedMachineLock("kind", func() {
+		if !p.running() {
+			withTempFile("dtest-kind-*.yaml", kindConfig, func(config string) {
+				args := []string{"create", "cluster", "--name", clusterName, "--config", config, "--wait", "5m"}
+				if image := os.Getenv(dtestKindImage); image != "" {
+					args = append(args, "--image", image)
+				}
+				supervisor.Command(prefix, "kind", args...).MustCapture(nil)
+			})
+		}
+		connectRegistry("kind")
+	})
+	return "kind-" + clusterName
+}
+
+func (p kindProvider) Down() string {
+	if !p.running() {
+		return ""
+	}
+	supervisor.Command(prefix, "kind", "delete", "cluster", "--name", clusterName).MustCapture(nil)
+	return "kind-" + clusterName
+}
+
+func (p kindProvider) Kubeconfig() string {
+	if !p.running() {
+		return ""
+	}
+	return supervisor.Command(prefix, "kind", "get", "kubeconfig", "--name", clusterName).MustCapture(nil)
+}
+
+func (p kindProvider) KubeconfigPath() string {
+	return writeKubeconfig("kind-"+clusterName, p.Kubeconfig())
+}
+
+func (kindProvider) Ready(kubeconfig string) bool { return isClusterReady(kubeconfig) }
+
+const dtestK3dImage = "DTEST_K3D_IMAGE"
+
+var k3dRegistries = fmt.Sprintf(`mirrors:
+  "localhost:%s":
+    endpoint:
+      - "%s"
+`, registryPort, registryMirror)
+
+// k3dProvider runs a k3d cluster, with the k3s image in DTEST_K3D_IMAGE if that's set.
+type k3dProvider struct{}
+
+func (k3dProvider) Name() string { return "k3d" }
+
+func (k3dProvider) running() bool {
+	_, err := supervisor.Command(prefix, "k3d", "cluster", "get", clusterName).Capture(nil)
+	return err == nil
+}
+
+func (p k3dProvider) Up() string {
+	RegistryUp()
+	WithNamedMachineLock("k3d", func() {
+		if !p.running() {
+			withTempFile("dtest-k3d-*.yaml", k3dRegistries, func(registries string) {
+				args := []string{"cluster", "create", clusterName, "--wait", "--registry-config", registries,
+					"--k3s-arg", "--disable=traefik@server:0"}
+				if image := os.Getenv(dtestK3dImage); image != "" {
+					args = append(args, "--image", image)
+				}
+				supervisor.Command(prefix, "k3d", args...).MustCapture(nil)
+			})
+		}
+		connectRegistry("k3d-" + clusterName)
+	})
+	return "k3d-" + clusterName
+}
+
+func (p k3dProvider) Down() string {
+	if !p.running() {
+		return ""
+	}
+	supervisor.Command(prefix, "k3d", "cluster", "delete", clusterName).MustCapture(nil)
+	return "k3d-" + clusterName
+}
+
+func (p k3dProvider) Kubeconfig() string {
+	if !p.running() {
+		return ""
+	}
+	return supervisor.Command(prefix, "k3d", "kubeconfig", "get", clusterName).MustCapture(nil)
+}
+
+func (p k3dProvider) KubeconfigPath() string {
+	return writeKubeconfig("k3d-"+clusterName, p.Kubeconfig())
+}
+
+func (k3dProvider) Ready(kubeconfig string) bool { return isClusterReady(kubeconfig) }
+
+const kubeconfigMsg = `
+kubeconfig does not exist: %s
+
+  Make sure DTEST_KUBECONFIG is either unset or points to a valid kubeconfig file.
+
+`
+
+// remoteProvider is the existing cluster that DTEST_KUBECONFIG refers to. It's never started or
+// shut down, and it's assumed to be ready.
+type remoteProvider struct{}
+
+func (remoteProvider) Name() string { return "remote" }
+
+func (remoteProvider) Up() string {
+	kubeconfig := os.Getenv(dtestKubeconfig)
+	if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
+		fmt.Printf(kubeconfigMsg, kubeconfig)
+		os.Exit(1)
+	}
+	return kubeconfig
+}
+
+func (remoteProvider) Down() string { return "" }
+
+func (remoteProvider) Kubeconfig() string {
+	contents, err := ioutil.ReadFile(os.Getenv(dtestKubeconfig))
+	if err != nil {
+		return ""
+	}
+	return string(contents)
+}
+
+func (remoteProvider) KubeconfigPath() string { return os.Getenv(dtestKubeconfig) }
+
+func (remoteProvider) Ready(string) bool { return true }
@@ -0,0 +1,34 @@
+package dtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterProvider(t *testing.T) {
+	for _, tc := range []struct {
+		cluster    string
+		kubeconfig string
+		provider   string
+	}{
+		{"", "", "k3s"},
+		{"", "/tmp/kubeconfig.yaml", "remote"},
+		{"k3s", "", "k3s"},
+		{"kind", "", "kind"},
+		{"kind", "/tmp/kubeconfig.yaml", "kind"},
+		{"k3d", "", "k3d"},
+		{"remote", "/tmp/kubeconfig.yaml", "remote"},
+	} {
+		provider, err := clusterProvider(tc.cluster, tc.kubeconfig)
+		if assert.NoError(t, err, tc) {
+			assert.Equal(t, tc.provider, provider.Name(), tc)
+		}
+	}
+
+	_, err := clusterProvider("minikube", "")
+	assert.EqualError(t, err, `unknown DTEST_CLUSTER: "minikube"`)
+
+	_, err = clusterProvider("remote", "")
+	assert.EqualError(t, err, "DTEST_CLUSTER=remote needs DTEST_KUBECONFIG to be set")
+}
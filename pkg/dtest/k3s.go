@@ -2,10 +2,8 @@ package dtest
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net"
 	"os"
-	"os/user"
 	"strings"
 	"time"
 
@@ -155,13 +153,7 @@ var requiredResources = []string{
 	"volumeattachments.storage.k8s.io",
 }
 
-func isK3sReady() bool {
-	kubeconfig := getKubeconfigPath()
-
-	if kubeconfig == "" {
-		return false
-	}
-
+func isK3sReady(kubeconfig string) bool {
 	cmd := supervisor.Command(prefix, "kubectl", "--kubeconfig", kubeconfig, "api-resources", "-o", "name")
 	output, err := cmd.Capture(nil)
 	if err != nil {
@@ -190,10 +182,14 @@ func isK3sReady() bool {
 
 const k3sConfigPath = "/etc/rancher/k3s/k3s.yaml"
 
-// GetKubeconfig returns the kubeconfig contents for the running k3s
+// GetKubeconfig returns the kubeconfig contents for the running
 // cluster as a string. It will return the empty string if no cluster
 // is running.
 func GetKubeconfig() string {
+	return Cluster().Kubeconfig()
+}
+
+func k3sKubeconfig() string {
 	if !isKubeconfigReady() {
 		return ""
 	}
@@ -210,28 +206,14 @@ func GetKubeconfig() string {
 	return kubeconfig
 }
 
-func getKubeconfigPath() string {
+func k3sKubeconfigPath() string {
 	id := tag2id("k3s")
 
 	if id == "" {
 		return ""
 	}
 
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
-	}
-
-	kubeconfig := fmt.Sprintf("/tmp/dtest-kubeconfig-%s-%s.yaml", user.Username, id)
-	contents := GetKubeconfig()
-
-	err = ioutil.WriteFile(kubeconfig, []byte(contents), 0644)
-
-	if err != nil {
-		panic(err)
-	}
-
-	return kubeconfig
+	return writeKubeconfig(id, k3sKubeconfig())
 }
 
 const dtestRegistry = "DTEST_REGISTRY"
@@ -267,36 +249,19 @@ const dtestKubeconfig = "DTEST_KUBECONFIG"
 const k3sPort = "6443"
 const k3sImage = "rancher/k3s:v0.6.1"
 
-const k3sMsg = `
-kubeconfig does not exist: %s
-
-  Make sure DTEST_KUBECONFIG is either unset or points to a valid kubeconfig file.
-
-`
-
-// Kubeconfig returns a path referencing a kubeconfig file suitable for use in tests.
+// Kubeconfig returns a path referencing a kubeconfig file suitable for use in tests. The
+// cluster it refers to is the one that DTEST_CLUSTER selects; see Cluster.
 func Kubeconfig() string {
-	kubeconfig := os.Getenv(dtestKubeconfig)
-	if kubeconfig != "" {
-		if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
-			fmt.Printf(k3sMsg, kubeconfig)
-			os.Exit(1)
-		}
-
-		return kubeconfig
-	}
-
-	K3sUp()
+	cluster := Cluster()
+	cluster.Up()
 
 	for {
-		if isK3sReady() {
-			break
-		} else {
-			time.Sleep(time.Second)
+		kubeconfig := cluster.KubeconfigPath()
+		if kubeconfig != "" && cluster.Ready(kubeconfig) {
+			return kubeconfig
 		}
+		time.Sleep(time.Second)
 	}
-
-	return getKubeconfigPath()
 }
 
 // K3sUp will launch if necessary and return the docker id of a
@@ -324,3 +289,13 @@ func RegistryDown() string {
 	}
 	return id
 }
+
+// k3sProvider runs k3s in a docker container that shares the registry container's network.
+type k3sProvider struct{}
+
+func (k3sProvider) Name() string                 { return "k3s" }
+func (k3sProvider) Up() string                   { return K3sUp() }
+func (k3sProvider) Down() string                 { return K3sDown() }
+func (k3sProvider) Kubeconfig() string           { return k3sKubeconfig() }
+func (k3sProvider) KubeconfigPath() string       { return k3sKubeconfigPath() }
+func (k3sProvider) Ready(kubeconfig string) bool { return isK3sReady(kubeconfig) }
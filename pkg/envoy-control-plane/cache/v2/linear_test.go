@@ -0,0 +1,234 @@
+// Copyright 2020 Envoyproxy Authors
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	endpoint "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/cache/types"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/resource/v2"
+)
+
+const testType = resource.EndpointType
+
+func testResource(s string) types.Resource {
+	return &endpoint.ClusterLoadAssignment{ClusterName: s}
+}
+
+func verifyResponse(t *testing.T, ch <-chan Response, version string, num int) {
+	t.Helper()
+	var r Response
+	select {
+	case r = <-ch:
+	default:
+		t.Fatal("no response")
+	}
+	if r.GetRequest().TypeUrl != testType {
+		t.Errorf("unexpected empty request type URL: %q", r.GetRequest().TypeUrl)
+	}
+	out, err := r.GetDiscoveryResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.VersionInfo == "" {
+		t.Error("unexpected response empty version")
+	}
+	if n := len(out.Resources); n != num {
+		t.Errorf("unexpected number of responses: got %d, want %d", n, num)
+	}
+	if version != "" && out.VersionInfo != version {
+		t.Errorf("unexpected version: got %q, want %q", out.VersionInfo, version)
+	}
+}
+
+func mustBlock(t *testing.T, w <-chan Response) {
+	t.Helper()
+	select {
+	case <-w:
+		t.Error("watch must block")
+	default:
+	}
+}
+
+func TestLinearInitialResources(t *testing.T) {
+	c := NewLinearCache(testType, WithInitialResources(map[string]types.Resource{"a": testResource("a"), "b": testResource("b")}))
+	w, _ := c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType})
+	verifyResponse(t, w, "0", 1)
+	w, _ = c.CreateWatch(Request{TypeUrl: testType})
+	verifyResponse(t, w, "0", 2)
+}
+
+func TestLinearCornerCases(t *testing.T) {
+	c := NewLinearCache(testType)
+	err := c.UpdateResource("a", nil)
+	if err == nil {
+		t.Error("expected error on nil resource")
+	}
+	// create an incorrect type URL request
+	w, _ := c.CreateWatch(Request{TypeUrl: "test"})
+	select {
+	case _, more := <-w:
+		if more {
+			t.Error("should be closed by the producer")
+		}
+	default:
+		t.Error("channel should be closed")
+	}
+	if _, err := c.Fetch(context.Background(), Request{TypeUrl: testType}); err == nil {
+		t.Error("expected Fetch to be unsupported")
+	}
+}
+
+func TestLinearBasic(t *testing.T) {
+	c := NewLinearCache(testType)
+
+	// Create watches before a resource is ready
+	w1, _ := c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType, VersionInfo: "0"})
+	mustBlock(t, w1)
+	w, _ := c.CreateWatch(Request{TypeUrl: testType, VersionInfo: "0"})
+	mustBlock(t, w)
+	if n := c.NumWatches("a"); n != 2 {
+		t.Errorf("NumWatches(a) => got %d, want 2", n)
+	}
+
+	// Update with wildcard and named watches
+	if err := c.UpdateResource("a", testResource("a")); err != nil {
+		t.Fatal(err)
+	}
+	verifyResponse(t, w1, "1", 1)
+	verifyResponse(t, w, "1", 1)
+	if n := c.NumWatches("a"); n != 0 {
+		t.Errorf("NumWatches(a) => got %d, want 0", n)
+	}
+
+	// Request for a version that is up to date blocks; an irrelevant update doesn't answer it
+	w, _ = c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType, VersionInfo: "1"})
+	mustBlock(t, w)
+	if err := c.UpdateResource("b", testResource("b")); err != nil {
+		t.Fatal(err)
+	}
+	mustBlock(t, w)
+
+	// A request for a stale version is answered right away
+	w, _ = c.CreateWatch(Request{ResourceNames: []string{"b"}, TypeUrl: testType, VersionInfo: "1"})
+	verifyResponse(t, w, "2", 1)
+	w, _ = c.CreateWatch(Request{TypeUrl: testType, VersionInfo: "1"})
+	verifyResponse(t, w, "2", 2)
+	if n := c.NumResources(); n != 2 {
+		t.Errorf("NumResources() => got %d, want 2", n)
+	}
+}
+
+func TestLinearVersionPrefix(t *testing.T) {
+	c := NewLinearCache(testType, WithVersionPrefix("instance1-"))
+
+	w, _ := c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType, VersionInfo: "instance1-"})
+	verifyResponse(t, w, "instance1-0", 0)
+
+	if err := c.UpdateResource("a", testResource("a")); err != nil {
+		t.Fatal(err)
+	}
+	w, _ = c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType, VersionInfo: "instance1-0"})
+	verifyResponse(t, w, "instance1-1", 1)
+
+	w, _ = c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType, VersionInfo: "instance1-1"})
+	mustBlock(t, w)
+}
+
+func TestLinearDeletion(t *testing.T) {
+	c := NewLinearCache(testType, WithInitialResources(map[string]types.Resource{"a": testResource("a"), "b": testResource("b")}))
+	w, _ := c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType, VersionInfo: "0"})
+	mustBlock(t, w)
+	if err := c.DeleteResource("a"); err != nil {
+		t.Fatal(err)
+	}
+	verifyResponse(t, w, "1", 0)
+
+	w, _ = c.CreateWatch(Request{TypeUrl: testType, VersionInfo: "0"})
+	verifyResponse(t, w, "1", 1)
+}
+
+func TestLinearUpdateResources(t *testing.T) {
+	c := NewLinearCache(testType, WithInitialResources(map[string]types.Resource{"a": testResource("a"), "b": testResource("b")}))
+
+	// A watch for several names is answered once, with everything that changed.
+	w, _ := c.CreateWatch(Request{ResourceNames: []string{"a", "b", "c"}, TypeUrl: testType, VersionInfo: "0"})
+	mustBlock(t, w)
+	if err := c.UpdateResources(map[string]types.Resource{"a": testResource("a"), "c": testResource("c")}, []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	verifyResponse(t, w, "1", 2)
+	if n := c.NumWatches("b"); n != 0 {
+		t.Errorf("NumWatches(b) => got %d, want 0", n)
+	}
+
+	// The answered watch is gone from the sets of all of its names, so another
+	// update doesn't try to answer it again.
+	if err := c.UpdateResource("b", testResource("b")); err != nil {
+		t.Fatal(err)
+	}
+	mustBlock(t, w)
+
+	if err := c.UpdateResources(map[string]types.Resource{"d": nil}, nil); err == nil {
+		t.Error("expected error on nil resource")
+	}
+}
+
+func TestLinearCancel(t *testing.T) {
+	c := NewLinearCache(testType)
+	if err := c.UpdateResource("a", testResource("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// cancel watch-all
+	w, cancel := c.CreateWatch(Request{TypeUrl: testType, VersionInfo: "1"})
+	mustBlock(t, w)
+	if n := c.NumWildcardWatches(); n != 1 {
+		t.Errorf("NumWildcardWatches() => got %d, want 1", n)
+	}
+	cancel()
+	if n := c.NumWildcardWatches(); n != 0 {
+		t.Errorf("NumWildcardWatches() => got %d, want 0", n)
+	}
+
+	// cancel watch for "a"
+	w, cancel = c.CreateWatch(Request{ResourceNames: []string{"a"}, TypeUrl: testType, VersionInfo: "1"})
+	mustBlock(t, w)
+	cancel()
+	if n := c.NumWatches("a"); n != 0 {
+		t.Errorf("NumWatches(a) => got %d, want 0", n)
+	}
+}
+
+func TestMux(t *testing.T) {
+	c := NewLinearCache(testType, WithInitialResources(map[string]types.Resource{"a": testResource("a")}))
+	mux := &MuxCache{
+		Classify: func(req Request) string { return req.TypeUrl },
+		Caches:   map[string]Cache{testType: c},
+	}
+
+	w, _ := mux.CreateWatch(Request{TypeUrl: testType})
+	verifyResponse(t, w, "0", 1)
+
+	w, _ = mux.CreateWatch(Request{TypeUrl: resource.ClusterType})
+	if _, more := <-w; more {
+		t.Error("should be closed by the producer")
+	}
+	if _, err := mux.Fetch(context.Background(), Request{TypeUrl: resource.ClusterType}); err == nil {
+		t.Error("expected an error for a type without a cache")
+	}
+}
@@ -0,0 +1,41 @@
+// Package envoybuilders builds Envoy v2 resources with sane defaults, so that code that needs a
+// Cluster, a Listener or a RouteConfiguration doesn't have to spell out every nested message and
+// oneof by hand. Every builder's Build validates what it built, using the validation rules that
+// come with the Envoy protos, so a mistake shows up as an error where the resource is made
+// rather than as a rejected xDS update.
+package envoybuilders
+
+import (
+	"time"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+)
+
+// DefaultConnectTimeout is the connect timeout of the clusters that the builders make, unless
+// they say otherwise. It's the same as Ambassador's default.
+const DefaultConnectTimeout = 3 * time.Second
+
+// SocketAddress returns the TCP address of host (a hostname or an IP address) and port.
+func SocketAddress(host string, port uint32) *core.Address {
+	return &core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Protocol: core.SocketAddress_TCP,
+				Address:  host,
+				PortSpecifier: &core.SocketAddress_PortValue{
+					PortValue: port,
+				},
+			},
+		},
+	}
+}
+
+// adsConfigSource returns a ConfigSource that fetches resources over ADS, which is how Envoy
+// gets everything from ambex.
+func adsConfigSource() *core.ConfigSource {
+	return &core.ConfigSource{
+		ConfigSourceSpecifier: &core.ConfigSource_Ads{
+			Ads: &core.AggregatedConfigSource{},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package envoybuilders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	hcm "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/wellknown"
+)
+
+func TestCluster(t *testing.T) {
+	cluster, err := NewEdsCluster("cluster_quote").Build()
+	require.NoError(t, err)
+	assert.Equal(t, apiv2.Cluster_EDS, cluster.GetType())
+	assert.NotNil(t, cluster.GetEdsClusterConfig().GetEdsConfig().GetAds())
+	timeout, err := ptypes.Duration(cluster.GetConnectTimeout())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConnectTimeout, timeout)
+
+	cluster, err = NewStrictDNSCluster("cluster_auth", "auth.default", 8080).
+		ConnectTimeout(time.Second).
+		LbPolicy(apiv2.Cluster_LEAST_REQUEST).
+		HTTP2().
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, apiv2.Cluster_STRICT_DNS, cluster.GetType())
+	assert.Equal(t, apiv2.Cluster_LEAST_REQUEST, cluster.GetLbPolicy())
+	assert.NotNil(t, cluster.GetHttp2ProtocolOptions())
+	endpoints := cluster.GetLoadAssignment().GetEndpoints()[0].GetLbEndpoints()
+	require.Len(t, endpoints, 1)
+	address := endpoints[0].GetEndpoint().GetAddress().GetSocketAddress()
+	assert.Equal(t, "auth.default", address.GetAddress())
+	assert.Equal(t, uint32(8080), address.GetPortValue())
+
+	// Envoy rejects a cluster without a name.
+	_, err = NewStaticCluster("", SocketAddress("127.0.0.1", 8500)).Build()
+	assert.Error(t, err)
+}
+
+func TestLoadAssignment(t *testing.T) {
+	assignment, err := NewLoadAssignment("cluster_quote").
+		Endpoint(SocketAddress("10.0.0.1", 8080)).
+		Endpoint(SocketAddress("10.0.0.2", 8080)).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, "cluster_quote", assignment.GetClusterName())
+	assert.Len(t, assignment.GetEndpoints()[0].GetLbEndpoints(), 2)
+
+	_, err = NewLoadAssignment("").Build()
+	assert.Error(t, err)
+}
+
+func TestRouteConfiguration(t *testing.T) {
+	config, err := NewRouteConfiguration("ambassador-listener-8080-routeconfig-0").
+		VirtualHost(NewVirtualHost("quote", "quote.example.com").
+			PrefixRoute("/backend/", "cluster_quote").
+			PrefixRoute("/", "cluster_fallback")).
+		Build()
+	require.NoError(t, err)
+	routes := config.GetVirtualHosts()[0].GetRoutes()
+	require.Len(t, routes, 2)
+	assert.Equal(t, "/backend/", routes[0].GetMatch().GetPrefix())
+	assert.Equal(t, "cluster_quote", routes[0].GetRoute().GetCluster())
+
+	// A virtual host needs at least one domain.
+	_, err = NewRouteConfiguration("bad").VirtualHost(NewVirtualHost("quote")).Build()
+	assert.Error(t, err)
+}
+
+func TestHTTPListener(t *testing.T) {
+	listener, err := NewHTTPListener("ambassador-listener-8080", "0.0.0.0", 8080, "ambassador-listener-8080-routeconfig-0").
+		HTTPFilter(wellknown.Gzip, nil).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(8080), listener.GetAddress().GetSocketAddress().GetPortValue())
+
+	filters := listener.GetFilterChains()[0].GetFilters()
+	require.Len(t, filters, 1)
+	assert.Equal(t, wellknown.HTTPConnectionManager, filters[0].GetName())
+
+	manager := &hcm.HttpConnectionManager{}
+	require.NoError(t, ptypes.UnmarshalAny(filters[0].GetTypedConfig(), manager))
+	assert.Equal(t, "ambassador-listener-8080", manager.GetStatPrefix())
+	assert.Equal(t, "ambassador-listener-8080-routeconfig-0", manager.GetRds().GetRouteConfigName())
+	assert.NotNil(t, manager.GetRds().GetConfigSource().GetAds())
+	// The router always comes last.
+	require.Len(t, manager.GetHttpFilters(), 2)
+	assert.Equal(t, wellknown.Gzip, manager.GetHttpFilters()[0].GetName())
+	assert.Equal(t, wellknown.Router, manager.GetHttpFilters()[1].GetName())
+
+	// Envoy rejects a connection manager without a stat prefix.
+	_, err = NewHTTPListener("ambassador-listener-8080", "0.0.0.0", 8080, "routes").StatPrefix("").Build()
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package envoybuilders
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+)
+
+// A ClusterBuilder builds a Cluster.
+type ClusterBuilder struct {
+	cluster *apiv2.Cluster
+}
+
+func newCluster(name string, discoveryType apiv2.Cluster_DiscoveryType) *ClusterBuilder {
+	return &ClusterBuilder{cluster: &apiv2.Cluster{
+		Name:                 name,
+		ConnectTimeout:       ptypes.DurationProto(DefaultConnectTimeout),
+		ClusterDiscoveryType: &apiv2.Cluster_Type{Type: discoveryType},
+		LbPolicy:             apiv2.Cluster_ROUND_ROBIN,
+	}}
+}
+
+// NewEdsCluster starts a Cluster whose endpoints come from EDS, over ADS, in a
+// ClusterLoadAssignment with the Cluster's name.
+func NewEdsCluster(name string) *ClusterBuilder {
+	b := newCluster(name, apiv2.Cluster_EDS)
+	b.cluster.EdsClusterConfig = &apiv2.Cluster_EdsClusterConfig{
+		EdsConfig: adsConfigSource(),
+	}
+	return b
+}
+
+// NewStaticCluster starts a Cluster with a fixed set of endpoints.
+func NewStaticCluster(name string, endpoints ...*core.Address) *ClusterBuilder {
+	b := newCluster(name, apiv2.Cluster_STATIC)
+	b.cluster.LoadAssignment = newLoadAssignment(name, endpoints).assignment
+	return b
+}
+
+// NewStrictDNSCluster starts a Cluster whose endpoints are whatever host resolves to in DNS.
+func NewStrictDNSCluster(name, host string, port uint32) *ClusterBuilder {
+	b := newCluster(name, apiv2.Cluster_STRICT_DNS)
+	b.cluster.LoadAssignment = newLoadAssignment(name, []*core.Address{SocketAddress(host, port)}).assignment
+	return b
+}
+
+// ConnectTimeout sets how long a connection to an endpoint may take.
+func (b *ClusterBuilder) ConnectTimeout(timeout time.Duration) *ClusterBuilder {
+	b.cluster.ConnectTimeout = ptypes.DurationProto(timeout)
+	return b
+}
+
+// LbPolicy sets the load balancing policy.
+func (b *ClusterBuilder) LbPolicy(policy apiv2.Cluster_LbPolicy) *ClusterBuilder {
+	b.cluster.LbPolicy = policy
+	return b
+}
+
+// HTTP2 makes the Cluster speak HTTP/2 to its endpoints, e.g. for gRPC.
+func (b *ClusterBuilder) HTTP2() *ClusterBuilder {
+	b.cluster.Http2ProtocolOptions = &core.Http2ProtocolOptions{}
+	return b
+}
+
+// Build returns the Cluster, or an error if it isn't valid.
+func (b *ClusterBuilder) Build() (*apiv2.Cluster, error) {
+	if err := b.cluster.Validate(); err != nil {
+		return nil, err
+	}
+	return b.cluster, nil
+}
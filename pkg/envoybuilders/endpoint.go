@@ -0,0 +1,49 @@
+package envoybuilders
+
+import (
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	endpoint "github.com/datawire/ambassador/pkg/api/envoy/api/v2/endpoint"
+)
+
+// A LoadAssignmentBuilder builds a ClusterLoadAssignment, i.e. the endpoints of a Cluster, as
+// EDS serves them.
+type LoadAssignmentBuilder struct {
+	assignment *apiv2.ClusterLoadAssignment
+}
+
+// NewLoadAssignment starts a ClusterLoadAssignment for the named Cluster, with all its endpoints
+// in one locality.
+func NewLoadAssignment(clusterName string) *LoadAssignmentBuilder {
+	return newLoadAssignment(clusterName, nil)
+}
+
+func newLoadAssignment(clusterName string, endpoints []*core.Address) *LoadAssignmentBuilder {
+	b := &LoadAssignmentBuilder{assignment: &apiv2.ClusterLoadAssignment{
+		ClusterName: clusterName,
+		Endpoints:   []*endpoint.LocalityLbEndpoints{{}},
+	}}
+	for _, address := range endpoints {
+		b.Endpoint(address)
+	}
+	return b
+}
+
+// Endpoint adds an endpoint.
+func (b *LoadAssignmentBuilder) Endpoint(address *core.Address) *LoadAssignmentBuilder {
+	locality := b.assignment.Endpoints[0]
+	locality.LbEndpoints = append(locality.LbEndpoints, &endpoint.LbEndpoint{
+		HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+			Endpoint: &endpoint.Endpoint{Address: address},
+		},
+	})
+	return b
+}
+
+// Build returns the ClusterLoadAssignment, or an error if it isn't valid.
+func (b *LoadAssignmentBuilder) Build() (*apiv2.ClusterLoadAssignment, error) {
+	if err := b.assignment.Validate(); err != nil {
+		return nil, err
+	}
+	return b.assignment, nil
+}
@@ -0,0 +1,94 @@
+package envoybuilders
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	listener "github.com/datawire/ambassador/pkg/api/envoy/api/v2/listener"
+	hcm "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/wellknown"
+)
+
+// An HTTPListenerBuilder builds a Listener that handles HTTP with an HTTP connection manager.
+type HTTPListenerBuilder struct {
+	listener *apiv2.Listener
+	manager  *hcm.HttpConnectionManager
+	filters  []*hcm.HttpFilter
+	err      error
+}
+
+// NewHTTPListener starts a Listener on address and port that gets its routes from the
+// RouteConfiguration named routeConfigName, over ADS, and routes with the router filter.
+func NewHTTPListener(name, address string, port uint32, routeConfigName string) *HTTPListenerBuilder {
+	return &HTTPListenerBuilder{
+		listener: &apiv2.Listener{
+			Name:    name,
+			Address: SocketAddress(address, port),
+		},
+		manager: &hcm.HttpConnectionManager{
+			CodecType:  hcm.HttpConnectionManager_AUTO,
+			StatPrefix: name,
+			RouteSpecifier: &hcm.HttpConnectionManager_Rds{
+				Rds: &hcm.Rds{
+					ConfigSource:    adsConfigSource(),
+					RouteConfigName: routeConfigName,
+				},
+			},
+		},
+	}
+}
+
+// StatPrefix sets the prefix of the connection manager's stats; it defaults to the Listener's
+// name.
+func (b *HTTPListenerBuilder) StatPrefix(prefix string) *HTTPListenerBuilder {
+	b.manager.StatPrefix = prefix
+	return b
+}
+
+// HTTPFilter adds an HTTP filter, with its typed config, which may be nil. Filters run in the
+// order they're added, before the router.
+func (b *HTTPListenerBuilder) HTTPFilter(name string, config proto.Message) *HTTPListenerBuilder {
+	filter := &hcm.HttpFilter{Name: name}
+	if config != nil {
+		typed, err := ptypes.MarshalAny(config)
+		if err != nil {
+			if b.err == nil {
+				b.err = err
+			}
+			return b
+		}
+		filter.ConfigType = &hcm.HttpFilter_TypedConfig{TypedConfig: typed}
+	}
+	b.filters = append(b.filters, filter)
+	return b
+}
+
+// Build returns the Listener, or an error if it or its connection manager isn't valid.
+func (b *HTTPListenerBuilder) Build() (*apiv2.Listener, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	manager := proto.Clone(b.manager).(*hcm.HttpConnectionManager)
+	manager.HttpFilters = append(append([]*hcm.HttpFilter{}, b.filters...), &hcm.HttpFilter{Name: wellknown.Router})
+	if err := manager.Validate(); err != nil {
+		return nil, err
+	}
+	typed, err := ptypes.MarshalAny(manager)
+	if err != nil {
+		return nil, err
+	}
+
+	l := proto.Clone(b.listener).(*apiv2.Listener)
+	l.FilterChains = []*listener.FilterChain{{
+		Filters: []*listener.Filter{{
+			Name:       wellknown.HTTPConnectionManager,
+			ConfigType: &listener.Filter_TypedConfig{TypedConfig: typed},
+		}},
+	}}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
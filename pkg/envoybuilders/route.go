@@ -0,0 +1,62 @@
+package envoybuilders
+
+import (
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	route "github.com/datawire/ambassador/pkg/api/envoy/api/v2/route"
+)
+
+// A RouteConfigurationBuilder builds a RouteConfiguration, as RDS serves it.
+type RouteConfigurationBuilder struct {
+	config *apiv2.RouteConfiguration
+}
+
+// NewRouteConfiguration starts a RouteConfiguration with no virtual hosts.
+func NewRouteConfiguration(name string) *RouteConfigurationBuilder {
+	return &RouteConfigurationBuilder{config: &apiv2.RouteConfiguration{Name: name}}
+}
+
+// VirtualHost adds a virtual host. Envoy picks the first one whose domains match a request, so
+// the order matters.
+func (b *RouteConfigurationBuilder) VirtualHost(vhost *VirtualHostBuilder) *RouteConfigurationBuilder {
+	b.config.VirtualHosts = append(b.config.VirtualHosts, vhost.vhost)
+	return b
+}
+
+// Build returns the RouteConfiguration, or an error if it isn't valid.
+func (b *RouteConfigurationBuilder) Build() (*apiv2.RouteConfiguration, error) {
+	if err := b.config.Validate(); err != nil {
+		return nil, err
+	}
+	return b.config, nil
+}
+
+// A VirtualHostBuilder builds a VirtualHost for a RouteConfigurationBuilder.
+type VirtualHostBuilder struct {
+	vhost *route.VirtualHost
+}
+
+// NewVirtualHost starts a VirtualHost for domains, which may use wildcards ("*" or
+// "*.example.com"), with no routes.
+func NewVirtualHost(name string, domains ...string) *VirtualHostBuilder {
+	return &VirtualHostBuilder{vhost: &route.VirtualHost{Name: name, Domains: domains}}
+}
+
+// Route adds a route. Envoy uses the first route that matches a request, so the order matters.
+func (b *VirtualHostBuilder) Route(r *route.Route) *VirtualHostBuilder {
+	b.vhost.Routes = append(b.vhost.Routes, r)
+	return b
+}
+
+// PrefixRoute adds a route that sends requests whose path starts with prefix to a cluster.
+func (b *VirtualHostBuilder) PrefixRoute(prefix, cluster string) *VirtualHostBuilder {
+	return b.Route(&route.Route{
+		Match: &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{Prefix: prefix},
+		},
+		Action: &route.Route_Route{
+			Route: &route.RouteAction{
+				ClusterSpecifier: &route.RouteAction_Cluster{Cluster: cluster},
+			},
+		},
+	})
+}
@@ -0,0 +1,150 @@
+// Package envoyconvert converts Envoy configuration between JSON or YAML and the Go protos in
+// pkg/api/envoy, so that tools and tests can keep Envoy configuration in readable files instead
+// of building it in code.
+//
+// google.protobuf.Any fields, such as typed_config, are decoded as whatever type their "@type"
+// names, so the package of every type that a configuration names has to be linked in, just as
+// for ambex. This package links in the xDS resources and the HTTP connection manager, v2 and v3.
+//
+// Envoy is dropping the v2 API, so a Decoder can also upgrade v2 configuration as it reads it:
+// each "@type" that names a v2 type is changed to name the v3 type that replaces it. The v3
+// protos record which type they replace, so the upgrade knows about every v3 type that's linked
+// in. The fields of each upgraded message are matched up by number, so that renamed fields are
+// renamed; fields that v3 deprecated or dropped come with a Warning.
+package envoyconvert
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	annotations "github.com/cncf/udpa/go/udpa/annotations"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"sigs.k8s.io/yaml"
+)
+
+const typeURLPrefix = "type.googleapis.com/"
+
+// A Decoder decodes Envoy configuration from JSON or YAML. The zero Decoder decodes
+// configuration as it is, and fails on fields that the protos don't have.
+type Decoder struct {
+	// Upgrade upgrades v2 configuration to v3 as UpgradeJSON does.
+	Upgrade bool
+	// Warn, if set, is called with each Warning from the upgrade.
+	Warn func(Warning)
+	// AllowUnknownFields ignores fields that the protos don't have, instead of failing.
+	AllowUnknownFields bool
+}
+
+// Unmarshal decodes JSON or YAML into msg.
+func (d Decoder) Unmarshal(data []byte, msg proto.Message) error {
+	data, err := d.prepare(data, msg.ProtoReflect().Descriptor())
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: d.AllowUnknownFields}.Unmarshal(data, msg)
+}
+
+// UnmarshalAny decodes JSON or YAML that says what type it is with an "@type", the way that
+// diagd writes the files that ambex reads, and returns a message of that type.
+func (d Decoder) UnmarshalAny(data []byte) (proto.Message, error) {
+	var any anypb.Any
+	if err := d.Unmarshal(data, &any); err != nil {
+		return nil, err
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(any.GetTypeUrl())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", any.GetTypeUrl(), err)
+	}
+	msg := mt.New().Interface()
+	if err := proto.Unmarshal(any.GetValue(), msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// prepare converts YAML for a message of type desc to JSON, which leaves JSON alone, and
+// upgrades the JSON if the Decoder says to.
+func (d Decoder) prepare(data []byte, desc protoreflect.MessageDescriptor) ([]byte, error) {
+	if !d.Upgrade {
+		return yaml.YAMLToJSON(data)
+	}
+
+	data, warnings, err := upgradeJSON(data, desc)
+	if err != nil {
+		return nil, err
+	}
+	if d.Warn != nil {
+		for _, warning := range warnings {
+			d.Warn(warning)
+		}
+	}
+	return data, nil
+}
+
+// Unmarshal decodes JSON or YAML into msg, as the zero Decoder does.
+func Unmarshal(data []byte, msg proto.Message) error {
+	return Decoder{}.Unmarshal(data, msg)
+}
+
+// UnmarshalAny decodes JSON or YAML with an "@type", as the zero Decoder does.
+func UnmarshalAny(data []byte) (proto.Message, error) {
+	return Decoder{}.UnmarshalAny(data)
+}
+
+// MarshalJSON encodes msg as indented JSON, with the field names from the .proto files, as
+// Envoy's documentation writes them.
+func MarshalJSON(msg proto.Message) ([]byte, error) {
+	return protojson.MarshalOptions{Multiline: true, Indent: "  ", UseProtoNames: true}.Marshal(msg)
+}
+
+// MarshalYAML encodes msg as YAML, with the field names from the .proto files.
+func MarshalYAML(msg proto.Message) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(data)
+}
+
+var (
+	upgradesOnce sync.Once
+	// upgrades maps the full name of each v2 type to that of the v3 type that replaces it.
+	upgrades map[protoreflect.FullName]protoreflect.FullName
+)
+
+// UpgradeTypeURL returns the type URL of the v3 type that replaces the type that typeURL names,
+// or typeURL itself if that isn't a v2 type, or the v3 type isn't linked in.
+func UpgradeTypeURL(typeURL string) string {
+	upgradesOnce.Do(func() {
+		upgrades = make(map[protoreflect.FullName]protoreflect.FullName)
+		protoregistry.GlobalTypes.RangeMessages(func(mt protoreflect.MessageType) bool {
+			desc := mt.Descriptor()
+			// The v4alpha types replace the v3 types in turn; only the v3 ones matter here.
+			if !strings.HasSuffix(string(desc.ParentFile().Package()), ".v3") {
+				return true
+			}
+			opts := desc.Options()
+			if opts == nil || !proto.HasExtension(opts, annotations.E_Versioning) {
+				return true
+			}
+			versioning, _ := proto.GetExtension(opts, annotations.E_Versioning).(*annotations.VersioningAnnotation)
+			if previous := versioning.GetPreviousMessageType(); previous != "" {
+				upgrades[protoreflect.FullName(previous)] = desc.FullName()
+			}
+			return true
+		})
+	})
+
+	if !strings.HasPrefix(typeURL, typeURLPrefix) {
+		return typeURL
+	}
+	if upgraded, ok := upgrades[protoreflect.FullName(strings.TrimPrefix(typeURL, typeURLPrefix))]; ok {
+		return typeURLPrefix + string(upgraded)
+	}
+	return typeURL
+}
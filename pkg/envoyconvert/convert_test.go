@@ -0,0 +1,89 @@
+package envoyconvert
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	hcmv2 "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	listenerv3 "github.com/datawire/ambassador/pkg/api/envoy/config/listener/v3"
+	hcmv3 "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+)
+
+const listenerYAML = `
+"@type": type.googleapis.com/envoy.api.v2.Listener
+name: ambassador-listener-8080
+address:
+  socket_address:
+    address: 0.0.0.0
+    port_value: 8080
+filter_chains:
+- filters:
+  - name: envoy.filters.network.http_connection_manager
+    typed_config:
+      "@type": type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager
+      stat_prefix: ingress_http
+      rds:
+        route_config_name: ambassador-listener-8080-routeconfig-0
+        config_source:
+          ads: {}
+`
+
+func TestUnmarshalAny(t *testing.T) {
+	msg, err := UnmarshalAny([]byte(listenerYAML))
+	require.NoError(t, err)
+	listener, ok := msg.(*apiv2.Listener)
+	require.True(t, ok)
+	assert.Equal(t, uint32(8080), listener.GetAddress().GetSocketAddress().GetPortValue())
+
+	manager := &hcmv2.HttpConnectionManager{}
+	require.NoError(t, ptypes.UnmarshalAny(listener.GetFilterChains()[0].GetFilters()[0].GetTypedConfig(), manager))
+	assert.Equal(t, "ingress_http", manager.GetStatPrefix())
+	assert.Equal(t, "ambassador-listener-8080-routeconfig-0", manager.GetRds().GetRouteConfigName())
+}
+
+func TestUpgrade(t *testing.T) {
+	msg, err := Decoder{Upgrade: true}.UnmarshalAny([]byte(listenerYAML))
+	require.NoError(t, err)
+	listener, ok := msg.(*listenerv3.Listener)
+	require.True(t, ok)
+	assert.Equal(t, "ambassador-listener-8080", listener.GetName())
+
+	typed := listener.GetFilterChains()[0].GetFilters()[0].GetTypedConfig()
+	assert.Equal(t, "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+		typed.GetTypeUrl())
+	manager := &hcmv3.HttpConnectionManager{}
+	require.NoError(t, ptypes.UnmarshalAny(typed, manager))
+	assert.Equal(t, "ingress_http", manager.GetStatPrefix())
+
+	// Types that aren't v2, or that v3 has nothing to replace, stay as they are.
+	assert.Equal(t, "type.googleapis.com/envoy.config.listener.v3.Listener",
+		UpgradeTypeURL("type.googleapis.com/envoy.config.listener.v3.Listener"))
+	assert.Equal(t, "type.googleapis.com/example.Unknown", UpgradeTypeURL("type.googleapis.com/example.Unknown"))
+}
+
+func TestUnmarshal(t *testing.T) {
+	cluster := &apiv2.Cluster{}
+	require.NoError(t, Unmarshal([]byte(`{"name": "cluster_quote", "connect_timeout": "3s"}`), cluster))
+	assert.Equal(t, "cluster_quote", cluster.GetName())
+
+	assert.Error(t, Unmarshal([]byte("name: cluster_quote\nbogus: true\n"), cluster))
+	require.NoError(t, Decoder{AllowUnknownFields: true}.Unmarshal([]byte("name: cluster_quote\nbogus: true\n"), cluster))
+	assert.Equal(t, "cluster_quote", cluster.GetName())
+}
+
+func TestMarshalYAML(t *testing.T) {
+	msg, err := UnmarshalAny([]byte(listenerYAML))
+	require.NoError(t, err)
+
+	data, err := MarshalYAML(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "port_value: 8080")
+
+	listener := &apiv2.Listener{}
+	require.NoError(t, Unmarshal(data, listener))
+	assert.Equal(t, "ambassador-listener-8080", listener.GetName())
+}
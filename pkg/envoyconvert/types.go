@@ -0,0 +1,15 @@
+package envoyconvert
+
+// The types that Envoy configuration usually names in an "@type": the xDS resources and the
+// HTTP connection manager, v2 and v3.
+import (
+	_ "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/bootstrap/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/bootstrap/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/cluster/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/endpoint/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/listener/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/route/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+)
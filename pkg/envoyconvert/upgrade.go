@@ -0,0 +1,241 @@
+package envoyconvert
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"sigs.k8s.io/yaml"
+)
+
+// deprecatedPrefix is how the v3 protos name the v2 fields that v3 deprecated. Envoy still
+// honors them, so an upgrade keeps them under that name.
+const deprecatedPrefix = "hidden_envoy_deprecated_"
+
+// A Warning is something in v2 configuration that an upgrade couldn't carry over to v3 as it
+// was.
+type Warning struct {
+	// Path is where the field is in the configuration, as in
+	// "filter_chains[0].filters[0].typed_config.idle_timeout".
+	Path string
+	// Message says what happened to it.
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Path == "" {
+		return w.Message
+	}
+	return w.Path + ": " + w.Message
+}
+
+// UpgradeJSON upgrades v2 configuration, in JSON or YAML, to v3, and returns it as JSON. Each
+// "@type" that names a v2 type is changed to name the v3 type that replaces it, and the fields
+// of the v2 message are matched to the v3 fields with the same number:
+//
+//   - a field that v3 renamed is renamed;
+//   - a field that v3 deprecated keeps its value, under its "hidden_envoy_deprecated_" name,
+//     with a warning;
+//   - a field that v3 dropped is dropped, with a warning.
+//
+// Fields that the v2 message doesn't have either are left alone, for the decoder to complain
+// about. The top level of the configuration has to have an "@type" to be upgraded; anything
+// under it that does is upgraded wherever it is.
+func UpgradeJSON(data []byte) ([]byte, []Warning, error) {
+	return upgradeJSON(data, nil)
+}
+
+// UpgradeAny upgrades a v2 message in an Any, such as the typed_config of a filter, to the v3
+// message that replaces it, as UpgradeJSON does. An Any that isn't v2 is returned as it is,
+// apart from any v2 Anys inside it.
+func UpgradeAny(any *anypb.Any) (*anypb.Any, []Warning, error) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(any)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, warnings, err := upgradeJSON(data, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var upgraded anypb.Any
+	if err := protojson.Unmarshal(data, &upgraded); err != nil {
+		return nil, nil, err
+	}
+	return &upgraded, warnings, nil
+}
+
+// upgradeJSON upgrades JSON or YAML configuration of type desc, or that says what type it is
+// with an "@type" if desc is nil.
+func upgradeJSON(data []byte, desc protoreflect.MessageDescriptor) ([]byte, []Warning, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, nil, err
+	}
+	var u upgrader
+	u.value("", tree, nil, desc)
+	data, err = json.Marshal(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, u.warnings, nil
+}
+
+// An upgrader walks decoded JSON, with the descriptors of the v2 message that the JSON was
+// written for and of the v3 message that it's being upgraded to. While it's outside anything v2,
+// the v2 descriptor is nil, and it only looks for v2 Anys; where it doesn't know the type at all,
+// both are nil.
+type upgrader struct {
+	warnings []Warning
+}
+
+func (u *upgrader) warn(path, format string, args ...interface{}) {
+	u.warnings = append(u.warnings, Warning{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (u *upgrader) value(path string, value interface{}, v2, v3 protoreflect.MessageDescriptor) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		u.object(path, value, v2, v3)
+	case []interface{}:
+		for i, elem := range value {
+			u.value(fmt.Sprintf("%s[%d]", path, i), elem, v2, v3)
+		}
+	}
+}
+
+func (u *upgrader) object(path string, obj map[string]interface{}, v2, v3 protoreflect.MessageDescriptor) {
+	if typeURL, ok := obj["@type"].(string); ok {
+		v2, v3 = u.resolve(path, obj, typeURL)
+	}
+	if v3 != nil && strings.HasPrefix(string(v3.FullName()), "google.protobuf.") {
+		// Well-known types have their own JSON form, with nothing of ours in it.
+		return
+	}
+
+	for _, key := range sortedKeys(obj) {
+		if key == "@type" {
+			continue
+		}
+		value := obj[key]
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+
+		var v2Field, v3Field protoreflect.FieldDescriptor
+		if v2 != nil {
+			v2Field = findField(v2, key)
+		}
+		if v3 != nil {
+			v3Field = findField(v3, key)
+		}
+
+		if v2Field != nil && v3Field == nil {
+			v3Field = v3.Fields().ByNumber(v2Field.Number())
+			if v3Field == nil || v3Field.Kind() != v2Field.Kind() || v3Field.Cardinality() != v2Field.Cardinality() {
+				u.warn(fieldPath, "%s has no v3 counterpart in %s; dropped", v2Field.FullName(), v3.FullName())
+				delete(obj, key)
+				continue
+			}
+			delete(obj, key)
+			obj[string(v3Field.Name())] = value
+			if strings.HasPrefix(string(v3Field.Name()), deprecatedPrefix) {
+				u.warn(fieldPath, "%s is deprecated in v3, as %s", v2Field.FullName(), v3Field.FullName())
+			}
+		}
+
+		switch {
+		case v3Field == nil || v3Field.Message() == nil:
+			// Unknown fields, and scalars, may still have Anys of their own inside.
+			u.value(fieldPath, value, nil, nil)
+		case v3Field.IsMap():
+			v2Value, v3Value := messageOf(v2Field, true), v3Field.MapValue().Message()
+			if entries, ok := value.(map[string]interface{}); ok {
+				for _, mapKey := range sortedKeys(entries) {
+					u.value(fmt.Sprintf("%s[%q]", fieldPath, mapKey), entries[mapKey], v2Value, v3Value)
+				}
+			}
+		default:
+			u.value(fieldPath, value, messageOf(v2Field, false), v3Field.Message())
+		}
+	}
+}
+
+// resolve upgrades the "@type" of an object, and returns the descriptors of the v2 message that
+// it was and the v3 message that it is now. The v2 descriptor is nil if it wasn't v2.
+func (u *upgrader) resolve(path string, obj map[string]interface{}, typeURL string) (v2, v3 protoreflect.MessageDescriptor) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(typeURL)
+	if err != nil {
+		// Not linked in, so there's nothing to go on.
+		return nil, nil
+	}
+
+	upgraded := UpgradeTypeURL(typeURL)
+	if upgraded == typeURL {
+		if isV2(mt.Descriptor()) {
+			u.warn(path, "no v3 type replaces %s; left as it is", mt.Descriptor().FullName())
+		}
+		return nil, mt.Descriptor()
+	}
+	obj["@type"] = upgraded
+
+	upgradedType, err := protoregistry.GlobalTypes.FindMessageByURL(upgraded)
+	if err != nil {
+		return nil, nil
+	}
+	return mt.Descriptor(), upgradedType.Descriptor()
+}
+
+// sortedKeys returns the keys of a JSON object in order, so that warnings come out in the same
+// order every time.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// findField returns the field of a message that a JSON key names, by either its name in the
+// .proto file or its JSON name.
+func findField(desc protoreflect.MessageDescriptor, key string) protoreflect.FieldDescriptor {
+	if field := desc.Fields().ByName(protoreflect.Name(key)); field != nil {
+		return field
+	}
+	return desc.Fields().ByJSONName(key)
+}
+
+// messageOf returns the message type of a field, or of the values of a map field, or nil.
+func messageOf(field protoreflect.FieldDescriptor, mapValue bool) protoreflect.MessageDescriptor {
+	switch {
+	case field == nil:
+		return nil
+	case mapValue && field.IsMap():
+		return field.MapValue().Message()
+	case mapValue:
+		return nil
+	default:
+		return field.Message()
+	}
+}
+
+// isV2 returns whether a message is part of Envoy's v2 API, which is everything of Envoy's that
+// isn't in a versioned package of v3 or later.
+func isV2(desc protoreflect.MessageDescriptor) bool {
+	pkg := string(desc.ParentFile().Package())
+	if !strings.HasPrefix(pkg, "envoy.") {
+		return false
+	}
+	return !strings.HasSuffix(pkg, ".v3") && !strings.Contains(pkg, ".v3.") &&
+		!strings.HasSuffix(pkg, ".v4alpha") && !strings.Contains(pkg, ".v4alpha.")
+}
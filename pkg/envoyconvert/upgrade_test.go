@@ -0,0 +1,100 @@
+package envoyconvert
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clusterv3 "github.com/datawire/ambassador/pkg/api/envoy/config/cluster/v3"
+	hcmv2 "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	listenerv3 "github.com/datawire/ambassador/pkg/api/envoy/config/listener/v3"
+	hcmv3 "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/network/http_connection_manager/v3"
+)
+
+const routesYAML = `
+"@type": type.googleapis.com/envoy.api.v2.Listener
+name: ambassador-listener-8080
+filter_chains:
+- filters:
+  - name: envoy.filters.network.http_connection_manager
+    typed_config:
+      "@type": type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager
+      stat_prefix: ingress_http
+      route_config:
+        virtual_hosts:
+        - name: backend
+          domains: ["*"]
+          routes:
+          - match:
+              regex: /quote/.*
+            route:
+              cluster: cluster_quote
+              hostRewrite: quote.example.com
+`
+
+func TestUpgradeFields(t *testing.T) {
+	var warnings []Warning
+	decoder := Decoder{Upgrade: true, Warn: func(w Warning) { warnings = append(warnings, w) }}
+	msg, err := decoder.UnmarshalAny([]byte(routesYAML))
+	require.NoError(t, err)
+	listener, ok := msg.(*listenerv3.Listener)
+	require.True(t, ok)
+
+	manager := &hcmv3.HttpConnectionManager{}
+	require.NoError(t, ptypes.UnmarshalAny(listener.GetFilterChains()[0].GetFilters()[0].GetTypedConfig(), manager))
+	route := manager.GetRouteConfig().GetVirtualHosts()[0].GetRoutes()[0]
+	// v3 renamed host_rewrite, and deprecated regex.
+	assert.Equal(t, "quote.example.com", route.GetRoute().GetHostRewriteLiteral())
+	assert.Equal(t, "/quote/.*", route.GetMatch().GetHiddenEnvoyDeprecatedRegex())
+
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "filter_chains[0].filters[0].typed_config.route_config.virtual_hosts[0].routes[0].match.regex: "+
+		"envoy.api.v2.route.RouteMatch.regex is deprecated in v3, as envoy.config.route.v3.RouteMatch.hidden_envoy_deprecated_regex",
+		warnings[0].String())
+}
+
+func TestUpgradeJSON(t *testing.T) {
+	data, warnings, err := UpgradeJSON([]byte(`{
+  "@type": "type.googleapis.com/envoy.api.v2.Cluster",
+  "name": "cluster_quote",
+  "drain_connections_on_host_removal": true,
+  "hosts": [{"socket_address": {"address": "quote", "port_value": 80}}],
+  "bogus": true
+}`))
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "hosts", warnings[0].Path)
+
+	// Fields that v2 doesn't have either are left for the decoder to reject.
+	_, err = UnmarshalAny(data)
+	assert.Error(t, err)
+
+	msg, err := Decoder{AllowUnknownFields: true}.UnmarshalAny(data)
+	require.NoError(t, err)
+	cluster, ok := msg.(*clusterv3.Cluster)
+	require.True(t, ok)
+	assert.True(t, cluster.GetIgnoreHealthOnHostRemoval())
+	assert.Equal(t, "quote", cluster.GetHiddenEnvoyDeprecatedHosts()[0].GetSocketAddress().GetAddress())
+}
+
+func TestUpgradeAny(t *testing.T) {
+	typed, err := ptypes.MarshalAny(&hcmv2.HttpConnectionManager{StatPrefix: "ingress_http"})
+	require.NoError(t, err)
+
+	upgraded, warnings, err := UpgradeAny(typed)
+	require.NoError(t, err)
+	assert.Len(t, warnings, 0)
+	assert.Equal(t, "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+		upgraded.GetTypeUrl())
+	manager := &hcmv3.HttpConnectionManager{}
+	require.NoError(t, ptypes.UnmarshalAny(upgraded, manager))
+	assert.Equal(t, "ingress_http", manager.GetStatPrefix())
+
+	// v3 is left as it is.
+	again, warnings, err := UpgradeAny(upgraded)
+	require.NoError(t, err)
+	assert.Len(t, warnings, 0)
+	assert.Equal(t, upgraded.GetTypeUrl(), again.GetTypeUrl())
+}
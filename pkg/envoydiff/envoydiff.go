@@ -0,0 +1,491 @@
+// Package envoydiff compares the configuration that Envoy is running, as its admin /config_dump
+// reports it, with the configuration that ambex is serving it, and reports where they differ.
+// That's the first thing to check when Envoy isn't doing what Ambassador's diagnostics say it
+// should.
+//
+// Both sides are compared as JSON, after normalizing away the differences that don't matter:
+// Envoy reports v2 resources as the v3 resources that replace them, leaves out fields with
+// default values, and formats numbers and durations its own way.
+package envoydiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/datawire/ambassador/pkg/envoyconvert"
+)
+
+// The kinds of resource that ambex serves.
+const (
+	KindCluster               = "Cluster"
+	KindListener              = "Listener"
+	KindRouteConfiguration    = "RouteConfiguration"
+	KindClusterLoadAssignment = "ClusterLoadAssignment"
+)
+
+// A Snapshot is a set of Envoy resources, normalized for comparison.
+type Snapshot struct {
+	// Resources maps a kind, and then a name, to a resource.
+	Resources map[string]map[string]interface{}
+	// Rejected maps a kind, and then a name, to why Envoy rejected the last update of a
+	// resource.
+	Rejected map[string]map[string]string
+	// kinds are the kinds that the Snapshot knows about. Envoy only reports endpoints when
+	// asked to, and older Envoys don't at all.
+	kinds map[string]bool
+}
+
+func newSnapshot(kinds ...string) *Snapshot {
+	s := &Snapshot{
+		Resources: make(map[string]map[string]interface{}),
+		Rejected:  make(map[string]map[string]string),
+		kinds:     make(map[string]bool),
+	}
+	for _, kind := range kinds {
+		s.addKind(kind)
+	}
+	return s
+}
+
+func (s *Snapshot) addKind(kind string) {
+	if !s.kinds[kind] {
+		s.kinds[kind] = true
+		s.Resources[kind] = make(map[string]interface{})
+		s.Rejected[kind] = make(map[string]string)
+	}
+}
+
+// add adds a resource of the named type, unless it has no name, or the Snapshot already has it.
+// If typeName is empty, the resource's "@type" names its type.
+func (s *Snapshot) add(kind, typeName string, resource interface{}) {
+	obj, ok := resource.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if typeName == "" {
+		typeName = fullTypeName(obj["@type"])
+	}
+	delete(obj, "@type")
+	name := resourceName(kind, obj)
+	if name == "" {
+		return
+	}
+	s.addKind(kind)
+	if _, ok := s.Resources[kind][name]; !ok {
+		s.Resources[kind][name] = normalize(canonical(typeName, obj))
+	}
+}
+
+// canonical returns a resource as the protos for its type write it, which is without fields
+// that have default values, or the resource as it is if its type isn't linked in, or it
+// doesn't decode.
+func canonical(typeName string, obj map[string]interface{}) map[string]interface{} {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return obj
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	msg := mt.New().Interface()
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return obj
+	}
+	data, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return obj
+	}
+	var canonical map[string]interface{}
+	if err := decodeJSON(data, &canonical); err != nil {
+		return obj
+	}
+	return canonical
+}
+
+func resourceName(kind string, obj map[string]interface{}) string {
+	key := "name"
+	if kind == KindClusterLoadAssignment {
+		key = "cluster_name"
+	}
+	name, _ := obj[key].(string)
+	return name
+}
+
+// LoadSnapshot reads the resources that ambex serves from the JSON files in dirs, the way ambex
+// does: Clusters, Listeners, RouteConfigurations and ClusterLoadAssignments, and the Clusters
+// and Listeners of Bootstraps.
+func LoadSnapshot(dirs ...string) (*Snapshot, error) {
+	s := newSnapshot(KindCluster, KindListener, KindRouteConfiguration, KindClusterLoadAssignment)
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := file.Name()
+			if file.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			if err := s.addFile(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// ParseSnapshot reads the resources in one file, as LoadSnapshot reads each of its files.
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	s := newSnapshot(KindCluster, KindListener, KindRouteConfiguration, KindClusterLoadAssignment)
+	if err := s.addFile(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// addFile adds the resources in a file that ambex would read.
+func (s *Snapshot) addFile(data []byte) error {
+	var obj map[string]interface{}
+	if err := decodeJSON(data, &obj); err != nil {
+		return err
+	}
+
+	switch kind := shortTypeName(obj["@type"]); kind {
+	case "Bootstrap":
+		clusterType, listenerType := "envoy.config.cluster.v3.Cluster", "envoy.config.listener.v3.Listener"
+		if fullTypeName(obj["@type"]) == "envoy.config.bootstrap.v2.Bootstrap" {
+			clusterType, listenerType = "envoy.api.v2.Cluster", "envoy.api.v2.Listener"
+		}
+		static, _ := obj["static_resources"].(map[string]interface{})
+		for _, cluster := range list(static["clusters"]) {
+			s.add(KindCluster, clusterType, cluster)
+		}
+		for _, listener := range list(static["listeners"]) {
+			s.add(KindListener, listenerType, listener)
+		}
+	case KindCluster, KindListener, KindRouteConfiguration, KindClusterLoadAssignment:
+		s.add(kind, "", obj)
+	}
+	return nil
+}
+
+// FetchConfigDump fetches /config_dump, endpoints included, from Envoy's admin interface.
+func FetchConfigDump(ctx context.Context, adminURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(adminURL, "/")+"/config_dump?include_eds", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// ParseConfigDump reads the resources that Envoy got over xDS from a /config_dump. The resources
+// in Envoy's bootstrap, which don't come from ambex, are left out.
+func ParseConfigDump(data []byte) (*Snapshot, error) {
+	var dump struct {
+		Configs []map[string]interface{} `json:"configs"`
+	}
+	if err := decodeJSON(data, &dump); err != nil {
+		return nil, err
+	}
+
+	s := newSnapshot()
+	for _, config := range dump.Configs {
+		switch shortTypeName(config["@type"]) {
+		case "ClustersConfigDump":
+			s.addKind(KindCluster)
+			for _, key := range []string{"dynamic_active_clusters", "dynamic_warming_clusters"} {
+				for _, cluster := range list(config[key]) {
+					s.add(KindCluster, "", field(cluster, "cluster"))
+				}
+			}
+		case "ListenersConfigDump":
+			s.addKind(KindListener)
+			for _, listener := range list(config["dynamic_listeners"]) {
+				for _, state := range []string{"active_state", "warming_state"} {
+					s.add(KindListener, "", field(field(listener, state), "listener"))
+				}
+				if details, ok := field(field(listener, "error_state"), "details").(string); ok {
+					name, _ := field(listener, "name").(string)
+					s.Rejected[KindListener][name] = details
+				}
+			}
+		case "RoutesConfigDump":
+			s.addKind(KindRouteConfiguration)
+			for _, route := range list(config["dynamic_route_configs"]) {
+				s.add(KindRouteConfiguration, "", field(route, "route_config"))
+			}
+		case "EndpointsConfigDump":
+			s.addKind(KindClusterLoadAssignment)
+			for _, endpoints := range list(config["dynamic_endpoint_configs"]) {
+				s.add(KindClusterLoadAssignment, "", field(endpoints, "endpoint_config"))
+			}
+		}
+	}
+	return s, nil
+}
+
+// A Difference is one place where Envoy's configuration isn't what ambex is serving.
+type Difference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Path is where in the resource the difference is, e.g. "filter_chains[0].filters[0].name",
+	// or empty if the difference is in the whole resource.
+	Path string `json:"path,omitempty"`
+	// Expected is what ambex is serving, or nil if it isn't serving anything.
+	Expected interface{} `json:"expected,omitempty"`
+	// Actual is what Envoy has, or nil if it has nothing.
+	Actual interface{} `json:"actual,omitempty"`
+	// Rejected is why Envoy rejected the resource, if it did.
+	Rejected string `json:"rejected,omitempty"`
+}
+
+func (d Difference) String() string {
+	where := d.Kind + " " + d.Name
+	if d.Path != "" {
+		where += ": " + d.Path
+	}
+	switch {
+	case d.Rejected != "":
+		return fmt.Sprintf("%s: Envoy rejected it: %s", where, d.Rejected)
+	case d.Actual == nil:
+		return fmt.Sprintf("%s: missing from Envoy", where)
+	case d.Expected == nil:
+		return fmt.Sprintf("%s: not served by ambex", where)
+	default:
+		return fmt.Sprintf("%s: expected %s, Envoy has %s", where, compact(d.Expected), compact(d.Actual))
+	}
+}
+
+// Diff returns where actual differs from expected, sorted by kind, name and path. Kinds that
+// either Snapshot doesn't know about are skipped.
+func Diff(expected, actual *Snapshot) []Difference {
+	var diffs []Difference
+	for kind := range expected.kinds {
+		if !actual.kinds[kind] {
+			continue
+		}
+		for _, name := range unionKeys(expected.Resources[kind], actual.Resources[kind]) {
+			if rejected, ok := actual.Rejected[kind][name]; ok {
+				diffs = append(diffs, Difference{Kind: kind, Name: name, Rejected: rejected})
+			}
+			diffValues(kind, name, "", expected.Resources[kind][name], actual.Resources[kind][name], &diffs)
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		a, b := diffs[i], diffs[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Path < b.Path
+	})
+	return diffs
+}
+
+func diffValues(kind, name, path string, expected, actual interface{}, diffs *[]Difference) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		if a, ok := actual.(map[string]interface{}); ok {
+			for _, key := range unionKeys(e, a) {
+				diffValues(kind, name, joinPath(path, key), e[key], a[key], diffs)
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := actual.([]interface{}); ok {
+			for i := 0; i < len(e) || i < len(a); i++ {
+				var ei, ai interface{}
+				if i < len(e) {
+					ei = e[i]
+				}
+				if i < len(a) {
+					ai = a[i]
+				}
+				diffValues(kind, name, fmt.Sprintf("%s[%d]", path, i), ei, ai, diffs)
+			}
+			return
+		}
+	default:
+		if expected == nil && actual == nil {
+			return
+		}
+		if expected != nil && actual != nil && fmt.Sprint(expected) == fmt.Sprint(actual) {
+			return
+		}
+	}
+	*diffs = append(*diffs, Difference{Kind: kind, Name: name, Path: path, Expected: expected, Actual: actual})
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// durationRE matches a duration as protobuf JSON writes it, e.g. "3s" or "0.250s".
+var durationRE = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?s$`)
+
+// normalize returns a JSON value with the differences that don't matter taken out:
+//
+//   - "@type"s name the type without a URL prefix, and v3 types in place of v2 types.
+//   - v2 fields that v3 deprecated lose the "hidden_envoy_deprecated_" that Envoy adds.
+//   - Fields with default values are left out, as Envoy leaves them out.
+//   - Numbers, including 64-bit numbers that protobuf JSON writes as strings, and durations are
+//     written the same way every time.
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			if key == "@type" {
+				normalized[key] = canonicalType(v)
+				continue
+			}
+			v = normalize(v)
+			if isDefault(v) {
+				continue
+			}
+			normalized[strings.TrimPrefix(key, "hidden_envoy_deprecated_")] = v
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(value))
+		for i, v := range value {
+			normalized[i] = normalize(v)
+		}
+		return normalized
+	case json.Number:
+		return canonicalNumber(string(value))
+	case string:
+		if durationRE.MatchString(value) {
+			return canonicalNumber(strings.TrimSuffix(value, "s")) + "s"
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return canonicalNumber(value)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func canonicalNumber(number string) string {
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return number
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func isDefault(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !value
+	case string:
+		return value == "" || value == "0"
+	case []interface{}:
+		return len(value) == 0
+	default:
+		return false
+	}
+}
+
+// canonicalType returns the full name of the type that a type URL names, upgraded to v3.
+func canonicalType(typeURL interface{}) interface{} {
+	if _, ok := typeURL.(string); !ok {
+		return typeURL
+	}
+	upgraded := envoyconvert.UpgradeTypeURL("type.googleapis.com/" + fullTypeName(typeURL))
+	return strings.TrimPrefix(upgraded, "type.googleapis.com/")
+}
+
+// fullTypeName returns the full name of the type that a type URL names, e.g.
+// "envoy.config.bootstrap.v2.Bootstrap".
+func fullTypeName(typeURL interface{}) string {
+	url, _ := typeURL.(string)
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+// shortTypeName returns the name of the type that a type URL names, without its package, e.g.
+// "Bootstrap".
+func shortTypeName(typeURL interface{}) string {
+	url, _ := typeURL.(string)
+	return url[strings.LastIndex(url, ".")+1:]
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func list(value interface{}) []interface{} {
+	l, _ := value.([]interface{})
+	return l
+}
+
+func field(value interface{}, key string) interface{} {
+	obj, _ := value.(map[string]interface{})
+	return obj[key]
+}
+
+func compact(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
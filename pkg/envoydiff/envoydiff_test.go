@@ -0,0 +1,114 @@
+package envoydiff
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// What diagd writes for ambex: v2 resources, with some defaults spelled out.
+const bootstrapJSON = `{
+  "@type": "/envoy.config.bootstrap.v2.Bootstrap",
+  "static_resources": {
+    "clusters": [
+      {"name": "cluster_quote", "connect_timeout": "3.000s", "lb_policy": "ROUND_ROBIN",
+       "type": "STRICT_DNS", "dns_lookup_family": "V4_ONLY", "respect_dns_ttl": false,
+       "load_assignment": {"cluster_name": "cluster_quote", "endpoints": [{"lb_endpoints": [
+         {"endpoint": {"address": {"socket_address": {"address": "quote", "port_value": 80}}}}]}]}},
+      {"name": "cluster_auth", "connect_timeout": "3.000s", "type": "STRICT_DNS"}
+    ],
+    "listeners": [
+      {"name": "ambassador-listener-8080",
+       "address": {"socket_address": {"address": "0.0.0.0", "port_value": 8080}},
+       "filter_chains": [{"filters": [{"name": "envoy.filters.network.http_connection_manager",
+         "typed_config": {
+           "@type": "type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
+           "stat_prefix": "ingress_http",
+           "route_config": {"virtual_hosts": [{"name": "backend", "domains": ["*"], "routes": [
+             {"match": {"prefix": "/backend/"}, "route": {"cluster": "cluster_quote", "timeout": "3.000s"}}]}]}}}]}]}
+    ]
+  }
+}`
+
+// What Envoy reports: v3 resources, without defaults, one cluster still warming and one
+// missing, and a route that points somewhere else.
+const configDumpJSON = `{
+  "configs": [
+    {"@type": "type.googleapis.com/envoy.admin.v3.BootstrapConfigDump"},
+    {"@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+     "static_clusters": [{"cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+       "name": "xds_cluster"}}],
+     "dynamic_warming_clusters": [{"version_info": "v3", "cluster": {
+       "@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+       "name": "cluster_quote", "connect_timeout": "3s", "type": "STRICT_DNS", "dns_lookup_family": "V4_ONLY",
+       "load_assignment": {"cluster_name": "cluster_quote", "endpoints": [{"lb_endpoints": [
+         {"endpoint": {"address": {"socket_address": {"address": "quote", "port_value": 80}}}}]}]}}}]},
+    {"@type": "type.googleapis.com/envoy.admin.v3.ListenersConfigDump",
+     "dynamic_listeners": [{"name": "ambassador-listener-8080",
+       "active_state": {"version_info": "v3", "listener": {
+         "@type": "type.googleapis.com/envoy.config.listener.v3.Listener",
+         "name": "ambassador-listener-8080",
+         "address": {"socket_address": {"address": "0.0.0.0", "port_value": 8080}},
+         "filter_chains": [{"filters": [{"name": "envoy.filters.network.http_connection_manager",
+           "typed_config": {
+             "@type": "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+             "stat_prefix": "ingress_http",
+             "route_config": {"virtual_hosts": [{"name": "backend", "domains": ["*"], "routes": [
+               {"match": {"prefix": "/backend/"}, "route": {"cluster": "cluster_old", "timeout": "3s"}}]}]}}}]}]}},
+       "error_state": {"details": "error adding listener: duplicate listener"}}]},
+    {"@type": "type.googleapis.com/envoy.admin.v3.RoutesConfigDump"}
+  ]
+}`
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envoydiff")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "envoy.json"), []byte(bootstrapJSON), 0644))
+	// ambex ignores everything else, and so do we.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("hi"), 0644))
+
+	expected, err := LoadSnapshot(dir)
+	require.NoError(t, err)
+	assert.Len(t, expected.Resources[KindCluster], 2)
+	assert.Len(t, expected.Resources[KindListener], 1)
+
+	actual, err := ParseConfigDump([]byte(configDumpJSON))
+	require.NoError(t, err)
+	// The bootstrap's own clusters don't come from ambex.
+	assert.NotContains(t, actual.Resources[KindCluster], "xds_cluster")
+
+	var diffs []string
+	for _, diff := range Diff(expected, actual) {
+		diffs = append(diffs, diff.String())
+	}
+	// Endpoints aren't compared, since the dump doesn't have them.
+	assert.Equal(t, []string{
+		"Cluster cluster_auth: missing from Envoy",
+		"Listener ambassador-listener-8080: Envoy rejected it: error adding listener: duplicate listener",
+		`Listener ambassador-listener-8080: filter_chains[0].filters[0].typed_config.route_config.virtual_hosts[0].routes[0].route.cluster: expected "cluster_quote", Envoy has "cluster_old"`,
+	}, diffs)
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{
+		"@type":           "envoy.config.cluster.v3.Cluster",
+		"connect_timeout": "0.25s",
+		"hosts":           []interface{}{"quote"},
+		"max_requests":    "1024",
+		"empty":           map[string]interface{}{},
+	}, normalize(map[string]interface{}{
+		"@type":                             "type.googleapis.com/envoy.api.v2.Cluster",
+		"connect_timeout":                   "0.250s",
+		"hidden_envoy_deprecated_hosts":     []interface{}{"quote"},
+		"max_requests":                      "1024",
+		"empty":                             map[string]interface{}{},
+		"respect_dns_ttl":                   false,
+		"per_connection_buffer_limit_bytes": "0",
+		"filters":                           []interface{}{},
+	}))
+}
@@ -0,0 +1,20 @@
+package envoydiff
+
+// The types that Ambassador's Envoy configuration names in an "@type", v2 as diagd writes them
+// and v3 as Envoy reports them, so that canonical can decode them. The xDS resources themselves
+// come with envoyconvert.
+import (
+	_ "github.com/datawire/ambassador/pkg/api/envoy/api/v2/auth"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/accesslog/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/http/ext_authz/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/http/rate_limit/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/http/rbac/v2"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/local_rate_limit/v2alpha"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/access_loggers/file/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/access_loggers/grpc/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/http/ext_authz/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/http/ratelimit/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/http/rbac/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/filters/network/local_ratelimit/v3"
+	_ "github.com/datawire/ambassador/pkg/api/envoy/extensions/transport_sockets/tls/v3"
+)
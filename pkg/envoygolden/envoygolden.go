@@ -0,0 +1,236 @@
+// Package envoygolden is a regression test framework for the Envoy configuration that Ambassador
+// generates. A fixture is a snapshot, as the entrypoint hands it to diagd to translate, in
+// testdata/<name>.snapshot.json, and beside it, in <name>.golden.json, the Envoy configuration
+// that it should translate to.
+//
+// The translation is compared with the golden the way envoydiff compares Envoy with ambex: as
+// JSON, resource by resource, after normalizing away what doesn't matter. So reordering fields,
+// or spelling a duration or a default differently, isn't a regression; and a test that fails says
+// which resource changed, and where in it.
+//
+// When a change to the translation is intended, rewrite the goldens with
+//
+//	go test ./... -update
+//
+// and review the difference in the goldens along with the change.
+package envoygolden
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/datawire/ambassador/pkg/envoydiff"
+)
+
+// Update is whether Run writes the goldens, rather than comparing with them.
+var Update = flag.Bool("update", false, "update the golden Envoy configuration")
+
+const (
+	snapshotSuffix = ".snapshot.json"
+	goldenSuffix   = ".golden.json"
+)
+
+// A Translator translates a snapshot into Envoy configuration: a Bootstrap, in JSON, with
+// the clusters and listeners in its static_resources, as diagd writes it for ambex.
+type Translator func(ctx context.Context, snapshot []byte) ([]byte, error)
+
+// DiagdCommand is the command that Diagd runs, with the path of the snapshot appended.
+var DiagdCommand = []string{"ambassador", "dump", "--watt", "--v2", "--nopretty"}
+
+// Diagd is the Translator that runs the translation that Ambassador runs: diagd's, through
+// `ambassador dump`.
+func Diagd(ctx context.Context, snapshot []byte) ([]byte, error) {
+	file, err := ioutil.TempFile("", "snapshot-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(snapshot); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	args := append(append([]string{}, DiagdCommand[1:]...), file.Name())
+	cmd := exec.CommandContext(ctx, DiagdCommand[0], args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w\n%s", strings.Join(DiagdCommand, " "), err, stderr.String())
+	}
+
+	var dump struct {
+		V2 json.RawMessage `json:"v2"`
+	}
+	if err := json.Unmarshal(out, &dump); err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.Join(DiagdCommand, " "), err)
+	}
+	if len(dump.V2) == 0 {
+		return nil, fmt.Errorf("%s: no Envoy configuration", strings.Join(DiagdCommand, " "))
+	}
+	return dump.V2, nil
+}
+
+// A Fixture is a snapshot, and the golden Envoy configuration that it translates to.
+type Fixture struct {
+	Name     string
+	Snapshot string // the path of the snapshot
+	Golden   string // the path of the golden, which needn't exist yet
+}
+
+// Fixtures returns the fixtures in dir, sorted by name.
+func Fixtures(dir string) ([]Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+snapshotSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var fixtures []Fixture
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), snapshotSuffix)
+		fixtures = append(fixtures, Fixture{
+			Name:     name,
+			Snapshot: path,
+			Golden:   filepath.Join(dir, name+goldenSuffix),
+		})
+	}
+	return fixtures, nil
+}
+
+func (f Fixture) translate(ctx context.Context, translate Translator) ([]byte, error) {
+	snapshot, err := ioutil.ReadFile(f.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	config, err := translate(ctx, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("translating %s: %w", f.Snapshot, err)
+	}
+	return config, nil
+}
+
+// Check translates the fixture's snapshot, and returns where the result differs from the golden.
+func Check(ctx context.Context, f Fixture, translate Translator) ([]envoydiff.Difference, error) {
+	golden, err := ioutil.ReadFile(f.Golden)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s doesn't exist: run the test with -update to write it", f.Golden)
+	}
+	if err != nil {
+		return nil, err
+	}
+	expected, err := envoydiff.ParseSnapshot(golden)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Golden, err)
+	}
+
+	config, err := f.translate(ctx, translate)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := envoydiff.ParseSnapshot(config)
+	if err != nil {
+		return nil, fmt.Errorf("translating %s: %w", f.Snapshot, err)
+	}
+	return envoydiff.Diff(expected, actual), nil
+}
+
+// Write translates the fixture's snapshot, and writes the result as its golden. Only the
+// resources that Check compares are written, so that the golden is no longer than it has to be.
+func Write(ctx context.Context, f Fixture, translate Translator) error {
+	config, err := f.translate(ctx, translate)
+	if err != nil {
+		return err
+	}
+	var bootstrap map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.UseNumber()
+	if err := decoder.Decode(&bootstrap); err != nil {
+		return fmt.Errorf("translating %s: %w", f.Snapshot, err)
+	}
+	static, _ := bootstrap["static_resources"].(map[string]interface{})
+	golden := map[string]interface{}{
+		"@type": bootstrap["@type"],
+		"static_resources": map[string]interface{}{
+			"clusters":  static["clusters"],
+			"listeners": static["listeners"],
+		},
+	}
+
+	data, err := json.MarshalIndent(golden, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.Golden, append(data, '\n'), 0644)
+}
+
+// Run checks every fixture in dir, each in a subtest; or, with -update, writes their goldens.
+func Run(t *testing.T, dir string, translate Translator) {
+	t.Helper()
+	fixtures, err := Fixtures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no fixtures (*%s) in %s", snapshotSuffix, dir)
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.Name, func(t *testing.T) {
+			ctx := context.Background()
+			if *Update {
+				if err := Write(ctx, f, translate); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			diffs, err := Check(ctx, f, translate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range diffs {
+				t.Error(Describe(d))
+			}
+			if len(diffs) > 0 {
+				t.Logf("if the change is intended, run the test with -update, and check the difference in %s", f.Golden)
+			}
+		})
+	}
+}
+
+// Describe describes a Difference between a golden and a translation.
+func Describe(d envoydiff.Difference) string {
+	where := d.Kind + " " + d.Name
+	if d.Path != "" {
+		where += ": " + d.Path
+	}
+	switch {
+	case d.Actual == nil:
+		return fmt.Sprintf("%s: in the golden, but not translated", where)
+	case d.Expected == nil:
+		return fmt.Sprintf("%s: translated, but not in the golden", where)
+	default:
+		return fmt.Sprintf("%s: the golden has %s, the translation has %s", where, compact(d.Expected), compact(d.Actual))
+	}
+}
+
+func compact(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,101 @@
+package envoygolden
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGolden checks the fixtures in testdata against diagd's translation.
+func TestGolden(t *testing.T) {
+	if _, err := exec.LookPath(DiagdCommand[0]); err != nil {
+		t.Skipf("can't run diagd's translation: %v", err)
+	}
+	Run(t, "testdata", Diagd)
+}
+
+const translation = `{
+  "@type": "/envoy.config.bootstrap.v2.Bootstrap",
+  "layered_runtime": {"layers": [{"name": "static_layer"}]},
+  "static_resources": {
+    "clusters": [{
+      "name": "cluster_quote_default",
+      "connect_timeout": "3.000s",
+      "type": "STRICT_DNS",
+      "lb_policy": "ROUND_ROBIN",
+      "load_assignment": {"cluster_name": "cluster_quote_default", "endpoints": [{"lb_endpoints": [{"endpoint": {"address": {"socket_address": {"address": "quote.default", "port_value": 80, "protocol": "TCP"}}}}]}]}
+    }],
+    "listeners": [{
+      "name": "ambassador-listener-8080",
+      "address": {"socket_address": {"address": "0.0.0.0", "port_value": 8080, "protocol": "TCP"}}
+    }]
+  }
+}`
+
+func translator(config string) Translator {
+	return func(ctx context.Context, snapshot []byte) ([]byte, error) {
+		return []byte(config), nil
+	}
+}
+
+func fixture(t *testing.T, dir string) Fixture {
+	t.Helper()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "quote.snapshot.json"), []byte(`{"Kubernetes": {}}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a fixture"), 0644))
+
+	fixtures, err := Fixtures(dir)
+	require.NoError(t, err)
+	require.Len(t, fixtures, 1)
+	assert.Equal(t, "quote", fixtures[0].Name)
+	assert.Equal(t, filepath.Join(dir, "quote.golden.json"), fixtures[0].Golden)
+	return fixtures[0]
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envoygolden")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	f := fixture(t, dir)
+
+	_, err = Check(ctx, f, translator(translation))
+	assert.Error(t, err, "there's no golden yet")
+
+	require.NoError(t, Write(ctx, f, translator(translation)))
+	golden, err := ioutil.ReadFile(f.Golden)
+	require.NoError(t, err)
+	assert.NotContains(t, string(golden), "layered_runtime", "only what's compared is written")
+	assert.Contains(t, string(golden), `"port_value": 8080`)
+
+	diffs, err := Check(ctx, f, translator(translation))
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// The same configuration, written differently, is the same.
+	same := strings.NewReplacer(`"3.000s"`, `"3s"`, `, "protocol": "TCP"`, ``).Replace(translation)
+	diffs, err = Check(ctx, f, translator(same))
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// A different one isn't.
+	different := strings.NewReplacer(`"port_value": 80,`, `"port_value": 8080,`, `"ambassador-listener-8080"`, `"ambassador-listener-8443"`).Replace(translation)
+	diffs, err = Check(ctx, f, translator(different))
+	require.NoError(t, err)
+	var described []string
+	for _, d := range diffs {
+		described = append(described, Describe(d))
+	}
+	assert.Equal(t, []string{
+		"Cluster cluster_quote_default: load_assignment.endpoints[0].lb_endpoints[0].endpoint.address.socket_address.port_value: the golden has \"80\", the translation has \"8080\"",
+		"Listener ambassador-listener-8080: in the golden, but not translated",
+		"Listener ambassador-listener-8443: translated, but not in the golden",
+	}, described)
+}
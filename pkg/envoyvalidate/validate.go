@@ -0,0 +1,210 @@
+// Package envoyvalidate checks Envoy configuration against the protoc-gen-validate constraints
+// in Envoy's .proto files, and says which fields break them.
+//
+// The Validate method that protoc-gen-validate generates for each message checks the message
+// and everything in it, except what's inside a google.protobuf.Any: it can't know what type
+// that is. Since Envoy puts the configuration of every filter, access logger and transport
+// socket in an Any, that leaves most of the interesting configuration unchecked. Validate here
+// unpacks each Any it finds and checks that too, so long as the package of its type is linked
+// in.
+//
+// The generated Validate methods stop at the first field that breaks a constraint, so there's
+// at most one Violation for the resource itself, and one for each Any in it.
+package envoyvalidate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// A Violation is a field that breaks a constraint.
+type Violation struct {
+	// Path is where the field is in the resource, with the field names from the .proto
+	// files, as in "filter_chains[0].filters[0].typed_config.stat_prefix".
+	Path string
+	// Type is the message type that the constraint is on.
+	Type string
+	// Reason says what the constraint is.
+	Reason string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Reason)
+}
+
+// Violations is the error that Validate returns.
+type Violations []Violation
+
+func (vs Violations) Error() string {
+	msgs := make([]string, 0, len(vs))
+	for _, v := range vs {
+		msgs = append(msgs, v.String())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks a message, and what's in each Any in it, against their constraints. It
+// returns nil, or Violations.
+func Validate(msg proto.Message) error {
+	var vs Violations
+	validate(&vs, "", msg.ProtoReflect())
+	if len(vs) == 0 {
+		return nil
+	}
+	return vs
+}
+
+// validatable is what protoc-gen-validate generates for each message.
+type validatable interface {
+	Validate() error
+}
+
+// validate checks a message, and then looks for Anys in it.
+func validate(vs *Violations, path string, msg protoreflect.Message) {
+	if v, ok := msg.Interface().(validatable); ok {
+		if err := v.Validate(); err != nil {
+			*vs = append(*vs, violation(path, msg.Descriptor(), err))
+		}
+	}
+	findAnys(vs, path, msg)
+}
+
+// findAnys validates the contents of each Any in a message.
+func findAnys(vs *Violations, path string, msg protoreflect.Message) {
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		fieldPath := join(path, string(field.Name()))
+		switch {
+		case field.IsMap():
+			if field.MapValue().Message() == nil {
+				break
+			}
+			value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				message(vs, fmt.Sprintf("%s[%v]", fieldPath, key.Interface()), value.Message())
+				return true
+			})
+		case field.Message() == nil:
+		case field.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				message(vs, fmt.Sprintf("%s[%d]", fieldPath, i), list.Get(i).Message())
+			}
+		default:
+			message(vs, fieldPath, value.Message())
+		}
+		return true
+	})
+}
+
+// message validates what's in a message field if it's an Any, or looks for Anys in it if it
+// isn't.
+func message(vs *Violations, path string, msg protoreflect.Message) {
+	any, ok := msg.Interface().(*anypb.Any)
+	if !ok {
+		findAnys(vs, path, msg)
+		return
+	}
+
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(any.GetTypeUrl())
+	if err != nil {
+		// Not linked in, so there's nothing to check it against.
+		return
+	}
+	inner := mt.New().Interface()
+	if err := proto.Unmarshal(any.GetValue(), inner); err != nil {
+		*vs = append(*vs, Violation{Path: path, Type: string(mt.Descriptor().FullName()), Reason: err.Error()})
+		return
+	}
+	validate(vs, path, inner.ProtoReflect())
+}
+
+// validationError is what the Validate methods return, for each message in the chain from the
+// one that was validated to the one with the field that breaks a constraint.
+type validationError interface {
+	error
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// embedded is the Reason of an error whose Cause is the error from a message in a field.
+const embedded = "embedded message failed validation"
+
+// fieldIndex splits a field name from protoc-gen-validate into the Go name of the field and
+// the index or key after it, as in "Endpoints[0]".
+var fieldIndex = regexp.MustCompile(`^([^\[]*)(\[.*\])?$`)
+
+// violation follows the chain of errors from a Validate method down to the field that breaks a
+// constraint.
+func violation(path string, desc protoreflect.MessageDescriptor, err error) Violation {
+	for {
+		verr, ok := err.(validationError)
+		if !ok {
+			return Violation{Path: path, Type: typeName(desc), Reason: err.Error()}
+		}
+
+		var field protoreflect.FieldDescriptor
+		name := verr.Field()
+		if parts := fieldIndex.FindStringSubmatch(name); parts != nil {
+			field = findField(desc, parts[1])
+			if field != nil {
+				name = string(field.Name()) + parts[2]
+			}
+		}
+		fieldPath := join(path, name)
+
+		cause := verr.Cause()
+		if verr.Reason() != embedded || cause == nil {
+			reason := verr.Reason()
+			if cause != nil {
+				reason += ": " + cause.Error()
+			}
+			return Violation{Path: fieldPath, Type: typeName(desc), Reason: reason}
+		}
+
+		path, err = fieldPath, cause
+		switch {
+		case field == nil:
+			desc = nil
+		case field.IsMap():
+			desc = field.MapValue().Message()
+		default:
+			desc = field.Message()
+		}
+	}
+}
+
+// findField returns the field of a message that a Go field name names, or nil. The Go name is
+// the name in the .proto file in CamelCase, so comparing them without underscores or case is
+// enough.
+func findField(desc protoreflect.MessageDescriptor, goName string) protoreflect.FieldDescriptor {
+	if desc == nil {
+		return nil
+	}
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if strings.EqualFold(strings.ReplaceAll(string(fields.Get(i).Name()), "_", ""), goName) {
+			return fields.Get(i)
+		}
+	}
+	return nil
+}
+
+func typeName(desc protoreflect.MessageDescriptor) string {
+	if desc == nil {
+		return ""
+	}
+	return string(desc.FullName())
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,75 @@
+package envoyvalidate
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	listener "github.com/datawire/ambassador/pkg/api/envoy/api/v2/listener"
+	hcm "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+)
+
+func TestValidate(t *testing.T) {
+	cluster := &apiv2.Cluster{
+		Name:           "cluster_quote",
+		ConnectTimeout: ptypes.DurationProto(3e9),
+		LoadAssignment: &apiv2.ClusterLoadAssignment{ClusterName: "cluster_quote"},
+	}
+	assert.NoError(t, Validate(cluster))
+
+	cluster.Name = ""
+	assert.Equal(t, Violations{{
+		Path:   "name",
+		Type:   "envoy.api.v2.Cluster",
+		Reason: "value length must be at least 1 bytes",
+	}}, Validate(cluster))
+
+	cluster.Name = "cluster_quote"
+	cluster.LoadAssignment.ClusterName = ""
+	assert.Equal(t, Violations{{
+		Path:   "load_assignment.cluster_name",
+		Type:   "envoy.api.v2.ClusterLoadAssignment",
+		Reason: "value length must be at least 1 bytes",
+	}}, Validate(cluster))
+}
+
+func TestValidateAny(t *testing.T) {
+	typed, err := ptypes.MarshalAny(&hcm.HttpConnectionManager{})
+	require.NoError(t, err)
+	l := &apiv2.Listener{
+		Name: "ambassador-listener-8080",
+		Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+			Address:       "0.0.0.0",
+			PortSpecifier: &core.SocketAddress_PortValue{PortValue: 8080},
+		}}},
+		FilterChains: []*listener.FilterChain{{
+			Filters: []*listener.Filter{{
+				Name:       "envoy.filters.network.http_connection_manager",
+				ConfigType: &listener.Filter_TypedConfig{TypedConfig: typed},
+			}},
+		}},
+	}
+
+	// The generated Validate method doesn't look inside the Any.
+	require.NoError(t, l.Validate())
+
+	err = Validate(l)
+	assert.Equal(t, Violations{{
+		Path:   "filter_chains[0].filters[0].typed_config.stat_prefix",
+		Type:   "envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
+		Reason: "value length must be at least 1 bytes",
+	}}, err)
+	assert.Equal(t, "filter_chains[0].filters[0].typed_config.stat_prefix: value length must be at least 1 bytes",
+		err.Error())
+
+	// Types that aren't linked in can't be checked.
+	l.FilterChains[0].Filters[0].ConfigType = &listener.Filter_TypedConfig{TypedConfig: &any.Any{
+		TypeUrl: "type.googleapis.com/example.Unknown",
+	}}
+	assert.NoError(t, Validate(l))
+}
@@ -0,0 +1,123 @@
+package envoyxds
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/cache/v2"
+)
+
+// A Cache holds what a Server serves.
+type Cache interface {
+	// Set replaces everything in the Cache with what's in a Snapshot. Only the resources
+	// that are new, gone, or different are sent to the Envoys that watch them.
+	Set(Snapshot) error
+
+	// NumResources returns how many resources of a type the Cache has.
+	NumResources(typeURL string) int
+
+	// Version returns the version of a type that the Cache serves now.
+	Version(typeURL string) string
+
+	// cache returns what go-control-plane's server serves from.
+	cache() cache.Cache
+}
+
+// linearCache is a Cache with a go-control-plane LinearCache for each type of resource,
+// behind a MuxCache. Unlike a SnapshotCache, it doesn't keep a snapshot per node, so the cost
+// of an Envoy is just its watches.
+type linearCache struct {
+	mux    *cache.MuxCache
+	linear map[string]*cache.LinearCache
+
+	mu sync.Mutex
+	// current is what each LinearCache has, so that Set can tell what changed.
+	current Snapshot
+	// versions counts the updates of each LinearCache, which is how it numbers its versions.
+	versions      map[string]uint64
+	versionPrefix string
+}
+
+// NewCache returns an empty Cache. Its versions start with versionPrefix, so that an Envoy
+// that reconnects after a restart gets everything again, rather than having its version
+// compared to versions from before.
+//
+// A Cache serves an empty set of Secrets, even though a Snapshot never has any: a request for
+// a type that the Cache doesn't serve would end the whole ADS stream.
+func NewCache(versionPrefix string) Cache {
+	c := &linearCache{
+		mux: &cache.MuxCache{
+			Classify: func(req cache.Request) string { return req.TypeUrl },
+			Caches:   map[string]cache.Cache{},
+		},
+		linear:        map[string]*cache.LinearCache{},
+		current:       Snapshot{},
+		versions:      map[string]uint64{},
+		versionPrefix: versionPrefix,
+	}
+	for _, typeURL := range Types {
+		linear := cache.NewLinearCache(typeURL, cache.WithVersionPrefix(versionPrefix))
+		c.linear[typeURL] = linear
+		c.mux.Caches[typeURL] = linear
+	}
+	return c
+}
+
+func (c *linearCache) Set(s Snapshot) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, typeURL := range Types {
+		if err := c.set(typeURL, s[typeURL]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *linearCache) set(typeURL string, byName map[string]Resource) error {
+	old := c.current[typeURL]
+	toUpdate := map[string]Resource{}
+	for name, r := range byName {
+		if prev, ok := old[name]; !ok || !proto.Equal(prev, r) {
+			toUpdate[name] = r
+		}
+	}
+	var toDelete []string
+	for name := range old {
+		if _, ok := byName[name]; !ok {
+			toDelete = append(toDelete, name)
+		}
+	}
+	if len(toUpdate) == 0 && len(toDelete) == 0 {
+		return nil
+	}
+
+	err := c.linear[typeURL].UpdateResources(toUpdate, toDelete)
+	if err != nil {
+		return fmt.Errorf("%s: %w", typeURL, err)
+	}
+	c.current[typeURL] = byName
+	c.versions[typeURL]++
+	return nil
+}
+
+func (c *linearCache) NumResources(typeURL string) int {
+	linear, ok := c.linear[typeURL]
+	if !ok {
+		return 0
+	}
+	return linear.NumResources()
+}
+
+func (c *linearCache) Version(typeURL string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.versionPrefix + strconv.FormatUint(c.versions[typeURL], 10)
+}
+
+func (c *linearCache) cache() cache.Cache {
+	return c.mux
+}
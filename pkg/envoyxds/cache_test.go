@@ -0,0 +1,97 @@
+package envoyxds
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/cache/v2"
+)
+
+func cluster(name string) *v2.Cluster {
+	return &v2.Cluster{Name: name, ConnectTimeout: ptypes.DurationProto(3e9)}
+}
+
+func edsCluster(name string) *v2.Cluster {
+	c := cluster(name)
+	c.ClusterDiscoveryType = &v2.Cluster_Type{Type: v2.Cluster_EDS}
+	return c
+}
+
+func snapshot(t *testing.T, clusters ...Resource) Snapshot {
+	t.Helper()
+	s, err := NewSnapshot(nil, clusters, nil, nil, nil)
+	require.NoError(t, err)
+	return s
+}
+
+func watch(t *testing.T, c Cache, typeURL, version string) chan cache.Response {
+	t.Helper()
+	w, _ := c.cache().CreateWatch(cache.Request{TypeUrl: typeURL, VersionInfo: version})
+	return w
+}
+
+func receive(t *testing.T, w chan cache.Response) *v2.DiscoveryResponse {
+	t.Helper()
+	res := <-w
+	out, err := res.GetDiscoveryResponse()
+	require.NoError(t, err)
+	return out
+}
+
+func TestNewSnapshot(t *testing.T) {
+	s, err := NewSnapshot(nil, []Resource{cluster("cluster_quote"), cluster("cluster_quote")}, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, s[ClusterType], 1)
+	assert.Len(t, s, len(Types))
+
+	// Endpoints for an EDS cluster have to be there.
+	_, err = NewSnapshot(nil, []Resource{edsCluster("cluster_eds")}, nil, nil, nil)
+	assert.Error(t, err)
+	_, err = NewSnapshot([]Resource{&v2.ClusterLoadAssignment{ClusterName: "cluster_eds"}},
+		[]Resource{edsCluster("cluster_eds")}, nil, nil, nil)
+	assert.NoError(t, err)
+}
+
+func TestCacheSet(t *testing.T) {
+	c := NewCache("test-")
+
+	// A watch for all the clusters, from an Envoy that has none yet.
+	w := watch(t, c, ClusterType, "test-0")
+	require.NoError(t, c.Set(snapshot(t, cluster("cluster_quote"))))
+	out := receive(t, w)
+	assert.Equal(t, "test-1", out.VersionInfo)
+	assert.Len(t, out.Resources, 1)
+	assert.Equal(t, 1, c.NumResources(ClusterType))
+	assert.Equal(t, "test-1", c.Version(ClusterType))
+	assert.Equal(t, "test-0", c.Version(ListenerType))
+
+	// Setting the same clusters again doesn't change anything, so the next watch waits.
+	w = watch(t, c, ClusterType, "test-1")
+	require.NoError(t, c.Set(snapshot(t, cluster("cluster_quote"))))
+	select {
+	case <-w:
+		t.Error("unchanged clusters were pushed")
+	default:
+	}
+
+	// Replacing the cluster updates one and deletes the other, as one change.
+	require.NoError(t, c.Set(snapshot(t, cluster("cluster_other"))))
+	out = receive(t, w)
+	assert.Equal(t, "test-2", out.VersionInfo)
+	assert.Len(t, out.Resources, 1)
+	assert.Equal(t, "test-2", c.Version(ClusterType))
+
+	// An Envoy with a version from another run gets everything.
+	out = receive(t, watch(t, c, ClusterType, "other-2"))
+	assert.Len(t, out.Resources, 1)
+
+	// Secrets are served, though there never are any.
+	out = receive(t, watch(t, c, SecretType, ""))
+	assert.Len(t, out.Resources, 0)
+	assert.Equal(t, 0, c.NumResources(SecretType))
+	assert.Equal(t, 0, c.NumResources("type.googleapis.com/example.Unknown"))
+}
@@ -0,0 +1,101 @@
+// Package envoyxds is the part of go-control-plane that ambex uses, behind a small API of its
+// own. pkg/envoy-control-plane is regenerated from whichever go-control-plane release goes with
+// our Envoy, and its API moves between releases; this package is the only thing that should
+// have to change when it does. Ambex, and anything else that serves xDS, should import this
+// instead of pkg/envoy-control-plane.
+//
+// Resources are still the v2 API's protobuf messages.
+package envoyxds
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	discovery "github.com/datawire/ambassador/pkg/api/envoy/service/discovery/v2"
+	ctypes "github.com/datawire/ambassador/pkg/envoy-control-plane/cache/types"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/cache/v2"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/resource/v2"
+	"github.com/datawire/ambassador/pkg/envoy-control-plane/server/v2"
+)
+
+// A Resource is a resource that can be served: a Cluster, ClusterLoadAssignment,
+// RouteConfiguration, Listener, Runtime, or Secret.
+type Resource = ctypes.Resource
+
+// A NodeHash groups Envoys by their node.
+type NodeHash = cache.NodeHash
+
+// Callbacks are called by a Server as xDS requests come in and responses go out.
+type Callbacks = server.Callbacks
+
+// A Server serves xDS from a Cache; Register hooks it up to a gRPC server.
+type Server = server.Server
+
+// The type URLs of the resources that a Cache serves.
+const (
+	ClusterType  = resource.ClusterType
+	EndpointType = resource.EndpointType
+	ListenerType = resource.ListenerType
+	RouteType    = resource.RouteType
+	RuntimeType  = resource.RuntimeType
+	SecretType   = resource.SecretType
+)
+
+// Types lists the type URLs of the resources that a Cache serves, in the order that Set
+// changes them: clusters and their endpoints before the listeners and routes that refer to
+// them.
+var Types = []string{
+	ClusterType,
+	EndpointType,
+	ListenerType,
+	RouteType,
+	RuntimeType,
+	SecretType,
+}
+
+// ResourceName returns the name of a resource, which is what Envoy asks for it by.
+func ResourceName(r Resource) string {
+	return cache.GetResourceName(r)
+}
+
+// A Snapshot is a complete configuration: resources by type URL, then by name.
+type Snapshot map[string]map[string]Resource
+
+// NewSnapshot returns a Snapshot of resources, or an error if they aren't consistent: every
+// ClusterLoadAssignment has to be for a Cluster that uses EDS, and every RouteConfiguration
+// has to be used by a Listener. If more than one resource of a type has the same name, the
+// last one wins.
+func NewSnapshot(endpoints, clusters, routes, listeners, runtimes []Resource) (Snapshot, error) {
+	s := cache.NewSnapshot("", endpoints, clusters, routes, listeners, runtimes)
+	if err := s.Consistent(); err != nil {
+		return nil, err
+	}
+	ret := make(Snapshot, len(Types))
+	for _, typeURL := range Types {
+		ret[typeURL] = s.GetResources(typeURL)
+	}
+	return ret, nil
+}
+
+// NewServer returns a Server that serves what's in a Cache.
+func NewServer(ctx context.Context, c Cache, callbacks Callbacks) Server {
+	return server.NewServer(ctx, c.cache(), callbacks)
+}
+
+// Register registers a Server with a gRPC server, for ADS and for each of the xDS services
+// that Envoy can use without ADS.
+func Register(grpcServer *grpc.Server, srv Server) {
+	discovery.RegisterAggregatedDiscoveryServiceServer(grpcServer, srv)
+	v2.RegisterEndpointDiscoveryServiceServer(grpcServer, srv)
+	v2.RegisterClusterDiscoveryServiceServer(grpcServer, srv)
+	v2.RegisterRouteDiscoveryServiceServer(grpcServer, srv)
+	v2.RegisterListenerDiscoveryServiceServer(grpcServer, srv)
+}
+
+// RegisterSecrets registers a Server with a gRPC server for SDS alone, for a Server whose Cache
+// has nothing but Secrets.
+func RegisterSecrets(grpcServer *grpc.Server, srv Server) {
+	discovery.RegisterSecretDiscoveryServiceServer(grpcServer, srv)
+}
@@ -0,0 +1,435 @@
+// Package xdstest is a fake Envoy, for testing what an xDS server such as ambex serves without
+// running Envoy. A Client opens an ADS stream, asks for what Envoy would ask for, ACKs or NACKs
+// each response as Envoy would, and keeps what it has ACKed as Go structs, for a test to look at.
+//
+// Like Envoy, a Client asks for every cluster and listener, and then for the endpoints of each
+// EDS cluster and the route configurations of each listener's HTTP connection managers, as it
+// learns of them.
+package xdstest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	hcm "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	discovery "github.com/datawire/ambassador/pkg/api/envoy/service/discovery/v2"
+	"github.com/datawire/ambassador/pkg/envoyvalidate"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+// DefaultNode is the node that a Client says it is, unless Options says otherwise: the one that
+// Ambassador's Envoy is, with the default AMBASSADOR_ID.
+var DefaultNode = &core.Node{Id: "test-id", Cluster: "ambassador-default"}
+
+// Options are the options of a Client.
+type Options struct {
+	// Node is who the Client says it is. If it's nil, it's DefaultNode.
+	Node *core.Node
+	// Validate decides whether the Client ACKs a response: if it returns an error, the Client
+	// NACKs the response with it, and keeps what it had. If it's nil, the Client checks each
+	// resource against the constraints in Envoy's .proto files, as Envoy does.
+	Validate func(typeURL string, resources []envoyxds.Resource) error
+}
+
+// A Client is a fake Envoy on an ADS stream.
+type Client struct {
+	stream   discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	conn     *grpc.ClientConn // if Dial opened it
+	cancel   context.CancelFunc
+	node     *core.Node
+	validate func(typeURL string, resources []envoyxds.Resource) error
+
+	mu sync.Mutex
+	// changed is closed, and replaced, whenever anything below changes.
+	changed  chan struct{}
+	accepted envoyxds.Snapshot
+	versions map[string]string
+	nonces   map[string]string
+	// names has the names of the endpoints and route configurations that the Client asks for.
+	names  map[string][]string
+	nacks  map[string]string
+	counts map[string]int
+	err    error
+}
+
+// Dial connects to the ADS server at target, with an insecure connection, as Ambassador's Envoy
+// does to ambex, and starts a Client on it. Closing the Client closes the connection.
+func Dial(ctx context.Context, target string, opts Options) (*Client, error) {
+	conn, err := grpc.DialContext(ctx, target, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	c, err := Connect(ctx, conn, opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.conn = conn
+	return c, nil
+}
+
+// Connect opens an ADS stream on conn, and starts a Client on it. The stream ends when ctx is
+// done, or the Client is closed.
+func Connect(ctx context.Context, conn grpc.ClientConnInterface, opts Options) (*Client, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := discovery.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	c := &Client{
+		stream:   stream,
+		cancel:   cancel,
+		node:     opts.Node,
+		validate: opts.Validate,
+		changed:  make(chan struct{}),
+		accepted: envoyxds.Snapshot{},
+		versions: map[string]string{},
+		nonces:   map[string]string{},
+		names:    map[string][]string{},
+		nacks:    map[string]string{},
+		counts:   map[string]int{},
+	}
+	if c.node == nil {
+		c.node = DefaultNode
+	}
+	if c.validate == nil {
+		c.validate = validate
+	}
+
+	// Envoy asks for every cluster and listener straight away; the node only goes on the first
+	// request of a stream.
+	first := &v2.DiscoveryRequest{Node: c.node, TypeUrl: envoyxds.ClusterType}
+	if err := stream.Send(first); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := stream.Send(&v2.DiscoveryRequest{TypeUrl: envoyxds.ListenerType}); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go c.run()
+	return c, nil
+}
+
+// validate checks each resource against the constraints in Envoy's .proto files.
+func validate(_ string, resources []envoyxds.Resource) error {
+	for _, r := range resources {
+		if err := envoyvalidate.Validate(proto.MessageV2(r)); err != nil {
+			return fmt.Errorf("%s: %w", envoyxds.ResourceName(r), err)
+		}
+	}
+	return nil
+}
+
+// Close ends the stream, and closes the connection if Dial opened it.
+func (c *Client) Close() error {
+	c.cancel()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// run handles each response until the stream ends.
+func (c *Client) run() {
+	for {
+		res, err := c.stream.Recv()
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.notify()
+			c.mu.Unlock()
+			return
+		}
+		if err := c.handle(res); err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.notify()
+			c.mu.Unlock()
+			c.cancel()
+			return
+		}
+	}
+}
+
+// notify wakes up anything that's waiting for a change. The caller must hold c.mu.
+func (c *Client) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// handle ACKs or NACKs res, and asks for any endpoints or route configurations that what it's
+// ACKed refers to.
+func (c *Client) handle(res *v2.DiscoveryResponse) error {
+	typeURL := res.GetTypeUrl()
+	resources, err := decode(res)
+	if err == nil {
+		err = c.validate(typeURL, resources)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.notify()
+
+	c.nonces[typeURL] = res.GetNonce()
+	c.counts[typeURL]++
+	var requests []*v2.DiscoveryRequest
+	if err != nil {
+		c.nacks[typeURL] = err.Error()
+		requests = append(requests, c.request(typeURL, &status.Status{
+			Code:    int32(codes.InvalidArgument),
+			Message: err.Error(),
+		}))
+	} else {
+		byName := make(map[string]envoyxds.Resource, len(resources))
+		for _, r := range resources {
+			byName[envoyxds.ResourceName(r)] = r
+		}
+		c.accepted[typeURL] = byName
+		c.versions[typeURL] = res.GetVersionInfo()
+		delete(c.nacks, typeURL)
+		requests = append(requests, c.request(typeURL, nil))
+
+		switch typeURL {
+		case envoyxds.ClusterType:
+			requests = append(requests, c.subscribe(envoyxds.EndpointType, edsNames(byName))...)
+		case envoyxds.ListenerType:
+			requests = append(requests, c.subscribe(envoyxds.RouteType, rdsNames(byName))...)
+		}
+	}
+
+	for _, req := range requests {
+		if err := c.stream.Send(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// request returns the request that answers the last response of typeURL: an ACK of it, or, if
+// errorDetail isn't nil, a NACK. The caller must hold c.mu.
+func (c *Client) request(typeURL string, errorDetail *status.Status) *v2.DiscoveryRequest {
+	return &v2.DiscoveryRequest{
+		VersionInfo:   c.versions[typeURL],
+		ResponseNonce: c.nonces[typeURL],
+		TypeUrl:       typeURL,
+		ResourceNames: c.names[typeURL],
+		ErrorDetail:   errorDetail,
+	}
+}
+
+// subscribe changes the names of typeURL that the Client asks for, and returns the request that
+// asks for them, if they've changed. Anything it had that it no longer asks for is dropped, as
+// Envoy drops it. The caller must hold c.mu.
+func (c *Client) subscribe(typeURL string, names []string) []*v2.DiscoveryRequest {
+	if equal(names, c.names[typeURL]) {
+		return nil
+	}
+	c.names[typeURL] = names
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	for name := range c.accepted[typeURL] {
+		if !wanted[name] {
+			delete(c.accepted[typeURL], name)
+		}
+	}
+	// Asking for no names would be asking for all of them.
+	if len(names) == 0 {
+		return nil
+	}
+	return []*v2.DiscoveryRequest{c.request(typeURL, nil)}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// decode unpacks the resources in res.
+func decode(res *v2.DiscoveryResponse) ([]envoyxds.Resource, error) {
+	resources := make([]envoyxds.Resource, 0, len(res.GetResources()))
+	for _, resource := range res.GetResources() {
+		if resource.GetTypeUrl() != res.GetTypeUrl() {
+			return nil, fmt.Errorf("a %s in a response of %s", resource.GetTypeUrl(), res.GetTypeUrl())
+		}
+		var m ptypes.DynamicAny
+		if err := ptypes.UnmarshalAny(resource, &m); err != nil {
+			return nil, err
+		}
+		resources = append(resources, m.Message)
+	}
+	return resources, nil
+}
+
+// edsNames returns the names of the endpoints that clusters use, sorted.
+func edsNames(clusters map[string]envoyxds.Resource) []string {
+	var names []string
+	for _, r := range clusters {
+		cluster, ok := r.(*v2.Cluster)
+		if !ok || cluster.GetType() != v2.Cluster_EDS {
+			continue
+		}
+		name := cluster.GetEdsClusterConfig().GetServiceName()
+		if name == "" {
+			name = cluster.GetName()
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rdsNames returns the names of the route configurations that the HTTP connection managers of
+// listeners get over RDS, sorted.
+func rdsNames(listeners map[string]envoyxds.Resource) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, r := range listeners {
+		listener, ok := r.(*v2.Listener)
+		if !ok {
+			continue
+		}
+		for _, chain := range listener.GetFilterChains() {
+			for _, filter := range chain.GetFilters() {
+				manager := &hcm.HttpConnectionManager{}
+				if filter.GetTypedConfig() == nil || !ptypes.Is(filter.GetTypedConfig(), manager) {
+					continue
+				}
+				if err := ptypes.UnmarshalAny(filter.GetTypedConfig(), manager); err != nil {
+					continue
+				}
+				name := manager.GetRds().GetRouteConfigName()
+				if name != "" && !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Snapshot returns what the Client has ACKed, by type URL, then by name.
+func (c *Client) Snapshot() envoyxds.Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := make(envoyxds.Snapshot, len(c.accepted))
+	for typeURL, byName := range c.accepted {
+		ret[typeURL] = make(map[string]envoyxds.Resource, len(byName))
+		for name, r := range byName {
+			ret[typeURL][name] = r
+		}
+	}
+	return ret
+}
+
+// Clusters returns the clusters that the Client has ACKed, by name.
+func (c *Client) Clusters() map[string]*v2.Cluster {
+	ret := map[string]*v2.Cluster{}
+	for name, r := range c.Snapshot()[envoyxds.ClusterType] {
+		ret[name] = r.(*v2.Cluster)
+	}
+	return ret
+}
+
+// Endpoints returns the endpoints that the Client has ACKed, by cluster name.
+func (c *Client) Endpoints() map[string]*v2.ClusterLoadAssignment {
+	ret := map[string]*v2.ClusterLoadAssignment{}
+	for name, r := range c.Snapshot()[envoyxds.EndpointType] {
+		ret[name] = r.(*v2.ClusterLoadAssignment)
+	}
+	return ret
+}
+
+// Listeners returns the listeners that the Client has ACKed, by name.
+func (c *Client) Listeners() map[string]*v2.Listener {
+	ret := map[string]*v2.Listener{}
+	for name, r := range c.Snapshot()[envoyxds.ListenerType] {
+		ret[name] = r.(*v2.Listener)
+	}
+	return ret
+}
+
+// Routes returns the route configurations that the Client has ACKed, by name.
+func (c *Client) Routes() map[string]*v2.RouteConfiguration {
+	ret := map[string]*v2.RouteConfiguration{}
+	for name, r := range c.Snapshot()[envoyxds.RouteType] {
+		ret[name] = r.(*v2.RouteConfiguration)
+	}
+	return ret
+}
+
+// Version returns the version of typeURL that the Client last ACKed.
+func (c *Client) Version(typeURL string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.versions[typeURL]
+}
+
+// NACK returns the error that the Client NACKed the last response of typeURL with, or "" if
+// it ACKed it.
+func (c *Client) NACK(typeURL string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nacks[typeURL]
+}
+
+// Responses returns how many responses of typeURL the Client has had, whether it ACKed them or
+// not.
+func (c *Client) Responses(typeURL string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[typeURL]
+}
+
+// Err returns what ended the stream, or nil if it hasn't ended.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Wait waits until cond returns true, which it checks each time the Client gets a response. It
+// returns an error if ctx is done, or the stream ends, first.
+func (c *Client) Wait(ctx context.Context, cond func(*Client) bool) error {
+	for {
+		c.mu.Lock()
+		changed, err := c.changed, c.err
+		c.mu.Unlock()
+
+		if cond(c) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package xdstest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	v2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2"
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	endpoint "github.com/datawire/ambassador/pkg/api/envoy/api/v2/endpoint"
+	listener "github.com/datawire/ambassador/pkg/api/envoy/api/v2/listener"
+	route "github.com/datawire/ambassador/pkg/api/envoy/api/v2/route"
+	hcm "github.com/datawire/ambassador/pkg/api/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/datawire/ambassador/pkg/envoyxds"
+)
+
+var ads = &core.ConfigSource{ConfigSourceSpecifier: &core.ConfigSource_Ads{Ads: &core.AggregatedConfigSource{}}}
+
+func address(port uint32) *core.Address {
+	return &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+		Address:       "127.0.0.1",
+		PortSpecifier: &core.SocketAddress_PortValue{PortValue: port},
+	}}}
+}
+
+func edsCluster(name string) *v2.Cluster {
+	return &v2.Cluster{
+		Name:                 name,
+		ConnectTimeout:       ptypes.DurationProto(3 * time.Second),
+		ClusterDiscoveryType: &v2.Cluster_Type{Type: v2.Cluster_EDS},
+		EdsClusterConfig:     &v2.Cluster_EdsClusterConfig{EdsConfig: ads},
+	}
+}
+
+func endpoints(cluster string, port uint32) *v2.ClusterLoadAssignment {
+	return &v2.ClusterLoadAssignment{
+		ClusterName: cluster,
+		Endpoints: []*endpoint.LocalityLbEndpoints{{LbEndpoints: []*endpoint.LbEndpoint{{
+			HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{Address: address(port)}},
+		}}}},
+	}
+}
+
+func httpListener(t *testing.T, name, routeConfig string) *v2.Listener {
+	t.Helper()
+	manager, err := ptypes.MarshalAny(&hcm.HttpConnectionManager{
+		StatPrefix: "ingress_http",
+		RouteSpecifier: &hcm.HttpConnectionManager_Rds{Rds: &hcm.Rds{
+			ConfigSource:    ads,
+			RouteConfigName: routeConfig,
+		}},
+	})
+	require.NoError(t, err)
+	return &v2.Listener{
+		Name:    name,
+		Address: address(8080),
+		FilterChains: []*listener.FilterChain{{Filters: []*listener.Filter{{
+			Name:       "envoy.filters.network.http_connection_manager",
+			ConfigType: &listener.Filter_TypedConfig{TypedConfig: manager},
+		}}}},
+	}
+}
+
+func routes(name, cluster string) *v2.RouteConfiguration {
+	return &v2.RouteConfiguration{
+		Name: name,
+		VirtualHosts: []*route.VirtualHost{{
+			Name:    "backend",
+			Domains: []string{"*"},
+			Routes: []*route.Route{{
+				Match:  &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"}},
+				Action: &route.Route_Route{Route: &route.RouteAction{ClusterSpecifier: &route.RouteAction_Cluster{Cluster: cluster}}},
+			}},
+		}},
+	}
+}
+
+// serve serves cache over ADS, and returns its address.
+func serve(t *testing.T, ctx context.Context, cache envoyxds.Cache) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcServer := grpc.NewServer()
+	envoyxds.Register(grpcServer, envoyxds.NewServer(ctx, cache, nil))
+	go grpcServer.Serve(lis)
+	go func() {
+		<-ctx.Done()
+		grpcServer.Stop()
+	}()
+	return lis.Addr().String()
+}
+
+func set(t *testing.T, cache envoyxds.Cache, cluster *v2.Cluster, l *v2.Listener) {
+	t.Helper()
+	s, err := envoyxds.NewSnapshot(
+		[]envoyxds.Resource{endpoints("cluster_quote", 8081)},
+		[]envoyxds.Resource{cluster},
+		[]envoyxds.Resource{routes("ambassador-listener-8080-routeconfig", "cluster_quote")},
+		[]envoyxds.Resource{l},
+		nil)
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(s))
+}
+
+func wait(t *testing.T, c *Client, cond func(*Client) bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, c.Wait(ctx, cond))
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := envoyxds.NewCache("test-")
+	set(t, cache, edsCluster("cluster_quote"), httpListener(t, "ambassador-listener-8080", "ambassador-listener-8080-routeconfig"))
+	c, err := Dial(ctx, serve(t, ctx, cache), Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// The Client follows the clusters to their endpoints, and the listeners to their routes.
+	wait(t, c, func(c *Client) bool { return len(c.Endpoints()) == 1 && len(c.Routes()) == 1 })
+	assert.Contains(t, c.Clusters(), "cluster_quote")
+	assert.Contains(t, c.Listeners(), "ambassador-listener-8080")
+	assert.Equal(t, uint32(8081), c.Endpoints()["cluster_quote"].GetEndpoints()[0].GetLbEndpoints()[0].
+		GetEndpoint().GetAddress().GetSocketAddress().GetPortValue())
+	assert.Equal(t, "cluster_quote", c.Routes()["ambassador-listener-8080-routeconfig"].
+		GetVirtualHosts()[0].GetRoutes()[0].GetRoute().GetCluster())
+	for _, typeURL := range []string{envoyxds.ClusterType, envoyxds.EndpointType, envoyxds.ListenerType, envoyxds.RouteType} {
+		assert.Equal(t, cache.Version(typeURL), c.Version(typeURL), typeURL)
+		assert.Empty(t, c.NACK(typeURL), typeURL)
+	}
+
+	// A cluster that Envoy would reject is NACKed, and the Client keeps what it had.
+	version := c.Version(envoyxds.ClusterType)
+	bad := edsCluster("cluster_quote")
+	bad.ConnectTimeout = ptypes.DurationProto(-time.Second)
+	set(t, cache, bad, httpListener(t, "ambassador-listener-8080", "ambassador-listener-8080-routeconfig"))
+	wait(t, c, func(c *Client) bool { return c.NACK(envoyxds.ClusterType) != "" })
+	assert.Contains(t, c.NACK(envoyxds.ClusterType), "connect_timeout")
+	assert.Equal(t, version, c.Version(envoyxds.ClusterType))
+	assert.Equal(t, int64(3), c.Clusters()["cluster_quote"].GetConnectTimeout().GetSeconds())
+
+	// A good one after that is ACKed.
+	good := edsCluster("cluster_quote")
+	good.ConnectTimeout = ptypes.DurationProto(5 * time.Second)
+	set(t, cache, good, httpListener(t, "ambassador-listener-8080", "ambassador-listener-8080-routeconfig"))
+	wait(t, c, func(c *Client) bool { return c.Version(envoyxds.ClusterType) == cache.Version(envoyxds.ClusterType) })
+	assert.Empty(t, c.NACK(envoyxds.ClusterType))
+	assert.Equal(t, int64(5), c.Clusters()["cluster_quote"].GetConnectTimeout().GetSeconds())
+
+	// Once the stream ends, so does a Wait.
+	cancel()
+	assert.Error(t, c.Wait(context.Background(), func(*Client) bool { return false }))
+}
+
+func TestClientValidate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := envoyxds.NewCache("test-")
+	set(t, cache, edsCluster("cluster_quote"), httpListener(t, "ambassador-listener-8080", "ambassador-listener-8080-routeconfig"))
+	c, err := Dial(ctx, serve(t, ctx, cache), Options{
+		Validate: func(typeURL string, resources []envoyxds.Resource) error {
+			if typeURL == envoyxds.ListenerType {
+				return errors.New("no listeners today")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	wait(t, c, func(c *Client) bool {
+		return c.NACK(envoyxds.ListenerType) != "" && len(c.Endpoints()) == 1
+	})
+	assert.Equal(t, "no listeners today", c.NACK(envoyxds.ListenerType))
+	assert.Empty(t, c.Listeners())
+	// Without a listener, there's nothing to ask for routes for.
+	assert.Empty(t, c.Routes())
+	assert.Equal(t, 0, c.Responses(envoyxds.RouteType))
+}
+
+func TestNames(t *testing.T) {
+	static := edsCluster("cluster_static")
+	static.ClusterDiscoveryType = &v2.Cluster_Type{Type: v2.Cluster_STRICT_DNS}
+	named := edsCluster("cluster_named")
+	named.EdsClusterConfig.ServiceName = "quote.default"
+	assert.Equal(t, []string{"cluster_quote", "quote.default"}, edsNames(map[string]envoyxds.Resource{
+		"cluster_quote":  edsCluster("cluster_quote"),
+		"cluster_static": static,
+		"cluster_named":  named,
+	}))
+
+	assert.Equal(t, []string{"a-routes", "b-routes"}, rdsNames(map[string]envoyxds.Resource{
+		"b":  httpListener(t, "b", "b-routes"),
+		"a":  httpListener(t, "a", "a-routes"),
+		"a2": httpListener(t, "a2", "a-routes"),
+		"tcp": &v2.Listener{Name: "tcp", FilterChains: []*listener.FilterChain{{Filters: []*listener.Filter{{
+			Name: "envoy.filters.network.tcp_proxy",
+		}}}}},
+	}))
+}
@@ -69,7 +69,7 @@ func fileIsArchive(u url.URL) bool {
 	ext := filepath.Ext(path)
 
 	switch ext {
-	case ".tar.gz", ".gz", ".zip":
+	case ".tar.gz", ".gz", ".tgz", ".zip":
 		return true
 	default:
 		return false
@@ -174,8 +174,15 @@ func (lc *HelmDownloader) Download() error {
 		}
 
 	case "file", "":
-		lc.downDir = lc.URL.String()
-		lc.log.Printf("Finding chart in %s", lc.URL.String())
+		if fileIsArchive(*lc.URL) {
+			lc.log.Printf("URL points to a local archive: uncompressing")
+			if err := lc.unarchiveChartFile(lc.URL.Path); err != nil {
+				return err
+			}
+		} else {
+			lc.downDir = lc.URL.String()
+		}
+		lc.log.Printf("Finding chart in %s", lc.downDir)
 		if err = lc.lookupChart(); err != nil {
 			return err
 		}
@@ -251,14 +258,6 @@ func (lc *HelmDownloader) Cleanup() error {
 
 // downloadChartFile downloads a Chart archive from a URL
 func (lc *HelmDownloader) downloadChartFile(url *url.URL) error {
-	// creates/erases the downloads directory, ignoring any error (just in case it does not exist)
-	d, err := ioutil.TempDir("", "chart-download")
-	if err != nil {
-		return err
-	}
-	lc.downDir = d
-	lc.downDirCleanup = true
-
 	filename := filepath.Base(url.Path)
 
 	// generates a random filename in /tmp (but it does not create the file)
@@ -266,14 +265,27 @@ func (lc *HelmDownloader) downloadChartFile(url *url.URL) error {
 	rand.Read(randBytes)
 	tempFilename := filepath.Join(os.TempDir(), fmt.Sprintf("%s-%s", hex.EncodeToString(randBytes), filename))
 
-	lc.log.Printf("Downloading file %q (temp=%q) (dest=%q)", url, tempFilename, lc.downDir)
+	lc.log.Printf("Downloading file %q (temp=%q)", url, tempFilename)
 	if err := downloadFile(tempFilename, url.String()); err != nil {
 		return err
 	}
 	defer func() { _ = os.Remove(tempFilename) }()
 
-	lc.log.Printf("Uncompressing file")
-	if err := archiver.Unarchive(tempFilename, lc.downDir); err != nil {
+	return lc.unarchiveChartFile(tempFilename)
+}
+
+// unarchiveChartFile uncompresses a Chart archive into a new downloads directory
+func (lc *HelmDownloader) unarchiveChartFile(filename string) error {
+	// creates/erases the downloads directory, ignoring any error (just in case it does not exist)
+	d, err := ioutil.TempDir("", "chart-download")
+	if err != nil {
+		return err
+	}
+	lc.downDir = d
+	lc.downDirCleanup = true
+
+	lc.log.Printf("Uncompressing file %q (dest=%q)", filename, lc.downDir)
+	if err := archiver.Unarchive(filename, lc.downDir); err != nil {
 		return err
 	}
 	lc.log.Printf("File uncompressed")
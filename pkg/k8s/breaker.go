@@ -0,0 +1,190 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// WatchErrorKind classifies why listing or watching a resource failed.
+type WatchErrorKind string
+
+const (
+	// WatchErrorTransient is a failure that may well go away by itself: a timeout, a dropped
+	// connection, an overloaded API server.
+	WatchErrorTransient WatchErrorKind = "Transient"
+	// WatchErrorForbidden means that RBAC doesn't allow us to list or watch the resource.
+	WatchErrorForbidden WatchErrorKind = "Forbidden"
+	// WatchErrorNotFound means that the cluster doesn't have the resource type, usually
+	// because its CRD isn't installed.
+	WatchErrorNotFound WatchErrorKind = "NotFound"
+)
+
+// ClassifyWatchError returns the kind of a list or watch failure.
+func ClassifyWatchError(err error) WatchErrorKind {
+	var notFound *resourceTypeNotFoundError
+	switch {
+	case errors.As(err, &notFound) || meta.IsNoMatchError(err) || apierrors.IsNotFound(err):
+		return WatchErrorNotFound
+	case apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err):
+		return WatchErrorForbidden
+	default:
+		return WatchErrorTransient
+	}
+}
+
+// resourceTypeNotFoundError is what ResolveResourceType returns for a resource type that the
+// cluster doesn't have.
+type resourceTypeNotFoundError struct {
+	resource string
+	err      error
+}
+
+func (e *resourceTypeNotFoundError) Error() string {
+	return fmt.Sprintf("the server doesn't have a resource type %q", e.resource)
+}
+
+func (e *resourceTypeNotFoundError) Unwrap() error {
+	return e.err
+}
+
+// WatchStatus reports the health of a watch, or of anything else guarded by a CircuitBreaker.
+type WatchStatus struct {
+	Name string
+	// Open is true while the circuit is open, i.e. while calls are held off until RetryAt.
+	Open bool
+	// ErrorKind, Error and Failures describe the failures since the last success.
+	ErrorKind WatchErrorKind
+	Error     string
+	Failures  int
+	RetryAt   time.Time
+}
+
+const (
+	// breakerThreshold is how many transient failures in a row open the circuit. Forbidden
+	// and NotFound failures open it right away, since retrying won't help until somebody
+	// changes the cluster.
+	breakerThreshold = 5
+	// breakerMinDelay is how long a closed circuit holds off a retry after a failure.
+	breakerMinDelay = time.Second
+	// An open circuit holds off retries for breakerOpenDelay at first, doubling each time
+	// the retry fails, up to breakerMaxDelay.
+	breakerOpenDelay = 5 * time.Second
+	breakerMaxDelay  = 5 * time.Minute
+)
+
+// A CircuitBreaker holds off retries of a call that keeps failing. Client-go's informers retry a
+// failed list or watch every second, forever, logging each failure; when the resource type
+// doesn't exist, or RBAC doesn't allow watching it, that floods the logs and the API server
+// without ever succeeding. A CircuitBreaker opens after a failure that won't go away by itself,
+// or after several transient failures in a row, logs why once, and then backs off
+// exponentially until a call succeeds.
+type CircuitBreaker struct {
+	name string
+	logf func(format string, args ...interface{})
+	now  func() time.Time
+
+	mutex  sync.Mutex
+	status WatchStatus
+	delay  time.Duration
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker that logs with logf, or with the standard
+// logger if logf is nil.
+func NewCircuitBreaker(name string, logf func(format string, args ...interface{})) *CircuitBreaker {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return &CircuitBreaker{
+		name:   name,
+		logf:   logf,
+		now:    time.Now,
+		status: WatchStatus{Name: name},
+	}
+}
+
+// Wait blocks until the next call may go out. It returns false if stop is closed first.
+func (b *CircuitBreaker) Wait(stop <-chan struct{}) bool {
+	b.mutex.Lock()
+	delay := b.status.RetryAt.Sub(b.now())
+	b.mutex.Unlock()
+
+	if delay <= 0 {
+		return true
+	}
+
+	select {
+	case <-time.After(delay):
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// Record records the result of a call.
+func (b *CircuitBreaker) Record(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err == nil {
+		if b.status.Open {
+			b.logf("%s: recovered after %d failures", b.name, b.status.Failures)
+		}
+		b.status = WatchStatus{Name: b.name}
+		b.delay = 0
+		return
+	}
+
+	kind := ClassifyWatchError(err)
+	wasOpen, oldKind := b.status.Open, b.status.ErrorKind
+	b.status.Failures++
+	b.status.ErrorKind = kind
+	b.status.Error = err.Error()
+
+	if kind == WatchErrorTransient && b.status.Failures < breakerThreshold {
+		b.status.RetryAt = b.now().Add(breakerMinDelay)
+		return
+	}
+
+	switch {
+	case b.delay <= 0:
+		b.delay = breakerOpenDelay
+	case b.delay < breakerMaxDelay:
+		b.delay *= 2
+		if b.delay > breakerMaxDelay {
+			b.delay = breakerMaxDelay
+		}
+	}
+	b.status.Open = true
+	b.status.RetryAt = b.now().Add(b.delay)
+
+	// Only say something when the circuit opens, or fails for a new reason, so that a watch
+	// that fails for hours logs a handful of lines instead of thousands.
+	if !wasOpen || kind != oldKind {
+		b.logf("%s: %s: %v; backing off retries for up to %s", b.name, explainWatchError(kind, b.status.Failures),
+			err, breakerMaxDelay)
+	}
+}
+
+// Status returns the current status of the breaker.
+func (b *CircuitBreaker) Status() WatchStatus {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.status
+}
+
+func explainWatchError(kind WatchErrorKind, failures int) string {
+	switch kind {
+	case WatchErrorNotFound:
+		return "the resource type doesn't exist (is its CRD installed?)"
+	case WatchErrorForbidden:
+		return "RBAC doesn't allow watching it"
+	default:
+		return fmt.Sprintf("%d failures in a row", failures)
+	}
+}
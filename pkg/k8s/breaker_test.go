@@ -0,0 +1,74 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassifyWatchError(t *testing.T) {
+	gr := schema.GroupResource{Group: "getambassador.io", Resource: "mappings"}
+
+	assert.Equal(t, WatchErrorNotFound, ClassifyWatchError(&resourceTypeNotFoundError{resource: "mappings"}))
+	assert.Equal(t, WatchErrorNotFound, ClassifyWatchError(apierrors.NewNotFound(gr, "")))
+	assert.Equal(t, WatchErrorForbidden, ClassifyWatchError(apierrors.NewForbidden(gr, "", errors.New("no"))))
+	assert.Equal(t, WatchErrorForbidden, ClassifyWatchError(apierrors.NewUnauthorized("no")))
+	assert.Equal(t, WatchErrorTransient, ClassifyWatchError(apierrors.NewServerTimeout(gr, "list", 1)))
+	assert.Equal(t, WatchErrorTransient, ClassifyWatchError(errors.New("connection refused")))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	var logged []string
+	b := NewCircuitBreaker("watch mappings", func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+	b.now = func() time.Time { return now }
+
+	// Transient failures hold off retries briefly, and open the circuit once there are
+	// enough of them in a row.
+	for i := 1; i < breakerThreshold; i++ {
+		b.Record(errors.New("connection refused"))
+		assert.False(t, b.Status().Open)
+		assert.Equal(t, now.Add(breakerMinDelay), b.Status().RetryAt)
+	}
+	b.Record(errors.New("connection refused"))
+	assert.True(t, b.Status().Open)
+	assert.Equal(t, now.Add(breakerOpenDelay), b.Status().RetryAt)
+	assert.Len(t, logged, 1)
+
+	// Failing again backs off further, without logging again...
+	b.Record(errors.New("connection refused"))
+	assert.Equal(t, now.Add(2*breakerOpenDelay), b.Status().RetryAt)
+	assert.Len(t, logged, 1)
+
+	// ...unless the reason changes.
+	b.Record(&resourceTypeNotFoundError{resource: "mappings"})
+	assert.Equal(t, WatchErrorNotFound, b.Status().ErrorKind)
+	assert.Len(t, logged, 2)
+	assert.Contains(t, logged[1], "is its CRD installed?")
+
+	for i := 0; i < 20; i++ {
+		b.Record(&resourceTypeNotFoundError{resource: "mappings"})
+	}
+	assert.Equal(t, now.Add(breakerMaxDelay), b.Status().RetryAt)
+
+	b.Record(nil)
+	assert.Equal(t, WatchStatus{Name: "watch mappings"}, b.Status())
+	assert.Len(t, logged, 3)
+	assert.True(t, b.Wait(nil))
+
+	// A permanent failure opens the circuit right away.
+	b.Record(apierrors.NewForbidden(schema.GroupResource{Resource: "mappings"}, "", errors.New("no")))
+	assert.True(t, b.Status().Open)
+	assert.Equal(t, now.Add(breakerOpenDelay), b.Status().RetryAt)
+
+	stop := make(chan struct{})
+	close(stop)
+	assert.False(t, b.Wait(stop))
+}
@@ -281,7 +281,7 @@ func mappingFor(resourceOrKindArg string, restMapper meta.RESTMapper) (*meta.RES
 		// if the error is _not_ a *meta.NoKindMatchError, then we had trouble doing discovery,
 		// so we should return the original error since it may help a user diagnose what is actually wrong
 		if meta.IsNoMatchError(err) {
-			return nil, fmt.Errorf("the server doesn't have a resource type %q", groupResource.Resource)
+			return nil, &resourceTypeNotFoundError{resource: groupResource.Resource, err: err} // MODIFIED: classifiable error
 		}
 		return nil, err
 	}
@@ -324,9 +324,33 @@ type Query struct {
 	FieldSelector string
 	LabelSelector string
 
+	// The Transform function, if set, is applied to each resource that the query returns before
+	// it is cached or handed back, e.g. to drop fields that nobody reads.
+	Transform TransformFunc
+
 	resourceType ResourceType
 }
 
+// A TransformFunc modifies a resource in place.
+type TransformFunc func(Resource)
+
+// lastAppliedConfigAnnotation is where `kubectl apply` keeps the last configuration it applied.
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// StripApplyMetadata is a TransformFunc that drops the managedFields and the
+// last-applied-configuration annotation that server-side and client-side apply keep in the
+// metadata of a resource. Between them they are often bigger than the rest of the resource.
+func StripApplyMetadata(r Resource) {
+	md, ok := r["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	delete(md, "managedFields")
+	if annotations, ok := md["annotations"].(map[string]interface{}); ok {
+		delete(annotations, lastAppliedConfigAnnotation)
+	}
+}
+
 func (q *Query) resolve(c *Client) error {
 	if q.resourceType != (ResourceType{}) {
 		return nil
@@ -379,6 +403,9 @@ func (c *Client) ListQuery(query Query) ([]Resource, error) {
 	result := make([]Resource, len(uns.Items))
 	for idx, un := range uns.Items {
 		result[idx] = un.UnstructuredContent()
+		if query.Transform != nil {
+			query.Transform(result[idx])
+		}
 	}
 	return result, nil
 }
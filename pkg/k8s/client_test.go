@@ -53,3 +53,28 @@ func TestList(t *testing.T) {
 		t.Errorf("did not find xmas")
 	}
 }
+
+func TestStripApplyMetadata(t *testing.T) {
+	r := k8s.Resource{
+		"metadata": map[string]interface{}{
+			"name": "quote",
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+				"getambassador.io/config":                          "---",
+			},
+			"managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}},
+		},
+	}
+	k8s.StripApplyMetadata(r)
+
+	if _, ok := r.Metadata()["managedFields"]; ok {
+		t.Error("managedFields not stripped")
+	}
+	annotations := r.Metadata().Annotations()
+	if len(annotations) != 1 || annotations["getambassador.io/config"] != "---" {
+		t.Errorf("expected only the getambassador.io/config annotation, got %v", annotations)
+	}
+
+	// Resources without metadata are left alone.
+	k8s.StripApplyMetadata(k8s.Resource{"kind": "Service"})
+}
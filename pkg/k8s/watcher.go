@@ -2,7 +2,9 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,24 +20,58 @@ import (
 	"k8s.io/client-go/tools/cache"
 )
 
+var errWatcherStopped = errors.New("watcher stopped")
+
 type listWatchAdapter struct {
 	resource      dynamic.ResourceInterface
 	fieldSelector string
 	labelSelector string
+	transform     TransformFunc
+	breaker       *CircuitBreaker
+	stop          <-chan struct{}
 }
 
 func (lw listWatchAdapter) List(options v1.ListOptions) (runtime.Object, error) {
+	if !lw.breaker.Wait(lw.stop) {
+		return nil, errWatcherStopped
+	}
 	options.FieldSelector = lw.fieldSelector
 	options.LabelSelector = lw.labelSelector
-	// silently coerce the returned *unstructured.UnstructuredList
-	// struct to a runtime.Object interface.
-	return lw.resource.List(context.TODO(), options)
+	list, err := lw.resource.List(context.TODO(), options)
+	lw.breaker.Record(err)
+	if err != nil {
+		return nil, err
+	}
+	if lw.transform == nil {
+		// silently coerce the returned *unstructured.UnstructuredList
+		// struct to a runtime.Object interface.
+		return list, err
+	}
+	for idx := range list.Items {
+		lw.transform(list.Items[idx].Object)
+	}
+	return list, nil
 }
 
 func (lw listWatchAdapter) Watch(options v1.ListOptions) (pwatch.Interface, error) {
+	if !lw.breaker.Wait(lw.stop) {
+		return nil, errWatcherStopped
+	}
 	options.FieldSelector = lw.fieldSelector
 	options.LabelSelector = lw.labelSelector
-	return lw.resource.Watch(context.TODO(), options)
+	watcher, err := lw.resource.Watch(context.TODO(), options)
+	lw.breaker.Record(err)
+	if err != nil || lw.transform == nil {
+		return watcher, err
+	}
+	// Transform each object on its way into the informer's cache, so that
+	// the cache never holds the untransformed object.
+	return pwatch.Filter(watcher, func(event pwatch.Event) (pwatch.Event, bool) {
+		if uns, ok := event.Object.(*unstructured.Unstructured); ok {
+			lw.transform(uns.Object)
+		}
+		return event, true
+	}), nil
 }
 
 // Watcher is a kubernetes watcher that can watch multiple queries simultaneously
@@ -54,6 +90,7 @@ type watch struct {
 	query    Query
 	resource dynamic.NamespaceableResourceInterface
 	store    cache.Store
+	breaker  *CircuitBreaker
 	invoke   func()
 	runner   func()
 }
@@ -89,7 +126,10 @@ func (c *Client) Watcher() *Watcher {
 }
 
 // WatchQuery watches the set of resources identified by the supplied
-// query and invokes the supplied listener whenever they change.
+// query and invokes the supplied listener whenever they change. Only the
+// resources that match the query's field and label selectors are watched,
+// and the query's Transform, if any, is applied to each of them before it
+// is cached.
 func (w *Watcher) WatchQuery(query Query, listener func(*Watcher)) error {
 	err := query.resolve(w.Client)
 	if err != nil {
@@ -121,8 +161,17 @@ func (w *Watcher) WatchQuery(query Query, listener func(*Watcher)) error {
 		listener(w)
 	}
 
+	breaker := NewCircuitBreaker("watch "+ri.String(), nil)
+
 	store, controller := cache.NewInformer(
-		listWatchAdapter{watched, query.FieldSelector, query.LabelSelector},
+		listWatchAdapter{
+			resource:      watched,
+			fieldSelector: query.FieldSelector,
+			labelSelector: query.LabelSelector,
+			transform:     query.Transform,
+			breaker:       breaker,
+			stop:          w.stop,
+		},
 		nil,
 		5*time.Minute,
 		cache.ResourceEventHandlerFuncs{
@@ -167,6 +216,7 @@ func (w *Watcher) WatchQuery(query Query, listener func(*Watcher)) error {
 		query:    query,
 		resource: resource,
 		store:    store,
+		breaker:  breaker,
 		invoke:   invoke,
 		runner:   runner,
 	}
@@ -201,8 +251,11 @@ func (w *Watcher) Start() {
 func (w *Watcher) sync(kind ResourceType) {
 	watch := w.watches[kind]
 	resources, err := w.Client.ListQuery(watch.query)
+	watch.breaker.Record(err)
 	if err != nil {
-		panic(err)
+		// The informer will keep trying, as often as the breaker lets it, and
+		// invoke the listener once it gets the resources.
+		return
 	}
 	for _, rsrc := range resources {
 		var uns unstructured.Unstructured
@@ -258,11 +311,25 @@ func (w *Watcher) UpdateStatus(resource Resource) (Resource, error) {
 	if err != nil {
 		return nil, err
 	} else {
+		if watch.query.Transform != nil {
+			watch.query.Transform(result.Object)
+		}
 		watch.store.Update(result)
 		return result.UnstructuredContent(), nil
 	}
 }
 
+// Statuses returns the status of each watch, sorted by name, so that
+// callers can report watches that are failing.
+func (w *Watcher) Statuses() []WatchStatus {
+	result := make([]WatchStatus, 0, len(w.watches))
+	for _, watch := range w.watches {
+		result = append(result, watch.breaker.Status())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
 // Get gets the `qname` resource (of kind `kind`)
 func (w *Watcher) Get(kind, qname string) Resource {
 	resources := w.List(kind)
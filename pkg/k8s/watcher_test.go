@@ -119,3 +119,30 @@ func TestWatchQuery(t *testing.T) {
 	w.Wait()
 	require.Equal(t, services, []string{"kubernetes.default"})
 }
+
+func TestWatchQueryTransform(t *testing.T) {
+	w := k8s.MustNewWatcher(info())
+
+	var labels []map[string]interface{}
+	err := w.WatchQuery(k8s.Query{
+		Kind:          "services",
+		Namespace:     k8s.NamespaceAll,
+		FieldSelector: "metadata.name=kubernetes",
+		Transform: func(r k8s.Resource) {
+			k8s.StripApplyMetadata(r)
+			delete(r.Metadata(), "labels")
+		},
+	}, func(w *k8s.Watcher) {
+		for _, r := range w.List("services") {
+			labels = append(labels, k8s.Map(r.Metadata()).GetMap("labels"))
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+	time.AfterFunc(1*time.Second, func() {
+		w.Stop()
+	})
+	w.Wait()
+	require.Equal(t, []map[string]interface{}{{}}, labels)
+}
@@ -165,44 +165,44 @@ func newAccumulator(ctx context.Context, client *Client, queries ...Query) *Accu
 	}
 
 	acc := &Accumulator{client, fields, map[string]bool{}, 0, changed, sync.Mutex{}}
+	go acc.coalesce(ctx, rawUpdateCh)
+	return acc
+}
 
-	// This coalesces reads from rawUpdateCh to notifications that changes are available to be
-	// processed. This loop along with the logic in storeField guarantees the 3
-	// Goals/Requirements listed in the documentation for the Accumulator struct, i.e. Ensuring
-	// all Kinds are bootstrapped before any notification occurs, as well as ensuring that we
-	// continue to coalesce updates in the background while business logic is executing in order
-	// to ensure graceful load shedding.
-	go func() {
-		canSend := false
-
-		for {
-			var rawUp rawUpdate
-			if canSend {
-				select {
-				case changed <- struct{}{}:
-					canSend = false
-					continue
-				case rawUp = <-rawUpdateCh:
-				case <-ctx.Done():
-					return
-				}
-			} else {
-				select {
-				case rawUp = <-rawUpdateCh:
-				case <-ctx.Done():
-					return
-				}
+// The coalesce method coalesces reads from rawUpdateCh to notifications that changes are
+// available to be processed, until ctx is done. This loop along with the logic in storeUpdate
+// guarantees the 3 Goals/Requirements listed in the documentation for the Accumulator struct,
+// i.e. Ensuring all Kinds are bootstrapped before any notification occurs, as well as ensuring
+// that we continue to coalesce updates in the background while business logic is executing in
+// order to ensure graceful load shedding.
+func (a *Accumulator) coalesce(ctx context.Context, rawUpdateCh <-chan rawUpdate) {
+	canSend := false
+
+	for {
+		var rawUp rawUpdate
+		if canSend {
+			select {
+			case a.changed <- struct{}{}:
+				canSend = false
+				continue
+			case rawUp = <-rawUpdateCh:
+			case <-ctx.Done():
+				return
 			}
-
-			// Don't overwrite canSend if storeField returns false. We may not yet have
-			// had a chance to send a notification down the changed channel.
-			if acc.storeUpdate(rawUp) {
-				canSend = true
+		} else {
+			select {
+			case rawUp = <-rawUpdateCh:
+			case <-ctx.Done():
+				return
 			}
 		}
-	}()
 
-	return acc
+		// Don't overwrite canSend if storeUpdate returns false. We may not yet have
+		// had a chance to send a notification down the changed channel.
+		if a.storeUpdate(rawUp) {
+			canSend = true
+		}
+	}
 }
 
 func (a *Accumulator) Changed() chan struct{} {
@@ -301,11 +301,6 @@ func (a *Accumulator) updateField(target reflect.Value, name string, field *fiel
 		items = append(items, un)
 	}
 
-	jsonBytes, err := json.Marshal(items)
-	if err != nil {
-		panic(err)
-	}
-
 	fieldEntry, ok := target.Type().Elem().FieldByName(name)
 	if !ok {
 		panic(fmt.Sprintf("no such field: %q", name))
@@ -313,11 +308,19 @@ func (a *Accumulator) updateField(target reflect.Value, name string, field *fiel
 
 	var val reflect.Value
 	if fieldEntry.Type.Kind() == reflect.Slice {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := json.NewEncoder(buf).Encode(items); err != nil {
+			panic(err)
+		}
 		val = reflect.New(fieldEntry.Type)
-		err := json.Unmarshal(jsonBytes, val.Interface())
+		err := json.Unmarshal(buf.Bytes(), val.Interface())
 		if err != nil {
 			panic(err)
 		}
+		for i := 0; i < val.Elem().Len(); i++ {
+			internValue(val.Elem().Index(i))
+		}
 	} else if fieldEntry.Type.Kind() == reflect.Map {
 		val = reflect.MakeMap(fieldEntry.Type)
 		for _, item := range items {
@@ -326,6 +329,7 @@ func (a *Accumulator) updateField(target reflect.Value, name string, field *fiel
 			if err != nil {
 				panic(err)
 			}
+			internValue(innerVal.Elem())
 			val.SetMapIndex(reflect.ValueOf(item.GetName()), reflect.Indirect(innerVal))
 		}
 	} else {
@@ -352,6 +356,19 @@ func (a *Accumulator) update(target reflect.Value, deltas *[]*Delta, predicate f
 	return updated
 }
 
+// internValue interns the metadata of v, if it's an Object (or the address of one).
+func internValue(v reflect.Value) {
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		v = v.Addr()
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return
+	}
+	if obj, ok := v.Interface().(Object); ok {
+		InternMeta(obj)
+	}
+}
+
 func unKeySort(items []*Unstructured) {
 	sort.Slice(items, func(i, j int) bool {
 		ik := unKey(items[i])
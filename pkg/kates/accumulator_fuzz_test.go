@@ -0,0 +1,441 @@
+package kates
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestAccumulatorFuzz throws random sequences of watch events at an Accumulator, the way the
+// entrypoint's watcher uses it: adds, updates and deletes, from the cluster and from the
+// Accumulator's own Client, some of them of resources that fail validation, and all of them
+// arriving late and out of step with each other. It fails if the Accumulator panics, stops taking
+// events, notifies before every watch has synced, or ends up with a snapshot other than the one
+// that applying the end state of the cluster all at once gets.
+//
+// Each run is a subtest named for its seed. Rerun one with -fuzz-seed=<seed> -fuzz-runs=1: the
+// events are the same, though how they interleave with the watcher's updates may not be.
+func TestAccumulatorFuzz(t *testing.T) {
+	objs, err := ParseManifests(CRD)
+	require.NoError(t, err)
+	validator, err := NewValidator(nil, objs)
+	require.NoError(t, err)
+	isValid := func(un *Unstructured) bool {
+		return validator.Validate(context.Background(), un) == nil
+	}
+
+	seed := *fuzzSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	runs := *fuzzRuns
+	if testing.Short() && runs > 10 {
+		runs = 10
+	}
+	for i := 0; i < runs; i++ {
+		seed := seed + int64(i)
+		t.Run(strconv.FormatInt(seed, 10), func(t *testing.T) {
+			fuzzAccumulator(t, seed, isValid)
+		})
+	}
+}
+
+var (
+	fuzzSeed = flag.Int64("fuzz-seed", 0, "the seed of TestAccumulatorFuzz's first run (default: the time)")
+	fuzzRuns = flag.Int("fuzz-runs", 100, "the number of TestAccumulatorFuzz runs")
+)
+
+const (
+	fuzzSteps   = 300
+	fuzzTimeout = 10 * time.Second
+)
+
+// The kinds the fuzzed Accumulator watches, by the name of their query: one with a CRD to
+// validate against, and one without.
+var fuzzKinds = map[string]schema.GroupVersionKind{
+	"Mappings":   {Group: "test.io", Version: "v1", Kind: "TestValidation"},
+	"ConfigMaps": {Version: "v1", Kind: "ConfigMap"},
+}
+
+var (
+	fuzzQueries    = []string{"ConfigMaps", "Mappings"}
+	fuzzNamespaces = []string{"default", "other"}
+	fuzzNames      = []string{"a", "b", "c", "d"}
+)
+
+type fuzzSnapshot struct {
+	Mappings   []*Unstructured
+	ConfigMaps []*Unstructured
+}
+
+// describe describes the resources in a snapshot, one line each, sorted.
+func (s fuzzSnapshot) describe() []string {
+	var lines []string
+	for query, items := range map[string][]*Unstructured{"Mappings": s.Mappings, "ConfigMaps": s.ConfigMaps} {
+		for _, un := range items {
+			data, err := json.Marshal(un)
+			if err != nil {
+				panic(err)
+			}
+			lines = append(lines, query+" "+string(data))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// A fakeInformer is the informer of a watch, as far as the Accumulator can tell.
+type fakeInformer struct {
+	cache.SharedInformer
+	synced int32
+}
+
+func (f *fakeInformer) HasSynced() bool {
+	return atomic.LoadInt32(&f.synced) != 0
+}
+
+// newFakeAccumulator returns an Accumulator of fuzzKinds that isn't backed by watches of a
+// cluster: what's sent to the returned channel is what the watches would have seen.
+func newFakeAccumulator(ctx context.Context, client *Client) (*Accumulator, chan<- rawUpdate) {
+	fields := make(map[string]*field)
+	for name, gvk := range fuzzKinds {
+		sel, err := ParseSelector("")
+		if err != nil {
+			panic(err)
+		}
+		fields[name] = &field{
+			query:    Query{Name: name, Kind: gvk.Kind},
+			selector: sel,
+			mapping:  &meta.RESTMapping{GroupVersionKind: gvk},
+			values:   make(map[string]*Unstructured),
+			deltas:   make(map[string]*Delta),
+		}
+	}
+	acc := &Accumulator{client, fields, map[string]bool{}, 0, make(chan struct{}), sync.Mutex{}}
+	updates := make(chan rawUpdate)
+	go acc.coalesce(ctx, updates)
+	return acc, updates
+}
+
+func send(updates chan<- rawUpdate, update rawUpdate) error {
+	select {
+	case updates <- update:
+		return nil
+	case <-time.After(fuzzTimeout):
+		return fmt.Errorf("deadlock: the Accumulator has stopped taking updates")
+	}
+}
+
+// A fuzzCluster is a cluster that's changed at random. Its changes are queued as watch events,
+// to be delivered whenever the fuzzer likes.
+type fuzzCluster struct {
+	rand      *rand.Rand
+	client    *Client
+	version   int
+	objects   map[string]*Unstructured // by query, namespace and name
+	pending   map[string][]rawUpdate   // by query
+	informers map[string]*fakeInformer // by query
+}
+
+func newFuzzCluster(rnd *rand.Rand, client *Client) *fuzzCluster {
+	c := &fuzzCluster{
+		rand:      rnd,
+		client:    client,
+		objects:   make(map[string]*Unstructured),
+		pending:   make(map[string][]rawUpdate),
+		informers: make(map[string]*fakeInformer),
+	}
+	for name := range fuzzKinds {
+		c.informers[name] = &fakeInformer{}
+	}
+	return c
+}
+
+// object returns a new version of a resource; a quarter of Mappings are invalid.
+func (c *fuzzCluster) object(query, namespace, name, uid string) *Unstructured {
+	c.version++
+	gvk := fuzzKinds[query]
+	un := &Unstructured{Object: map[string]interface{}{
+		"apiVersion": gvk.GroupVersion().String(),
+		"kind":       gvk.Kind,
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"namespace":       namespace,
+			"uid":             uid,
+			"resourceVersion": strconv.Itoa(c.version),
+		},
+	}}
+	switch query {
+	case "Mappings":
+		spec := map[string]interface{}{
+			"prefix":  "/" + name + "/",
+			"service": "svc-" + strconv.Itoa(c.rand.Intn(3)),
+		}
+		if c.rand.Intn(4) == 0 {
+			spec["add_linkerd_headers"] = "yes"
+		}
+		un.Object["spec"] = spec
+	case "ConfigMaps":
+		un.Object["data"] = map[string]interface{}{"n": strconv.Itoa(c.rand.Intn(3))}
+	}
+	return un
+}
+
+// step makes one change to the cluster. If local, the change may be made through the
+// Accumulator's Client, which sees it before the watch does.
+func (c *fuzzCluster) step(local bool) {
+	query := fuzzQueries[c.rand.Intn(len(fuzzQueries))]
+	namespace := fuzzNamespaces[c.rand.Intn(len(fuzzNamespaces))]
+	name := fuzzNames[c.rand.Intn(len(fuzzNames))]
+	key := query + " " + namespace + "/" + name
+	local = local && c.rand.Intn(4) == 0
+
+	old := c.objects[key]
+	switch r := c.rand.Intn(10); {
+	case old == nil:
+		new := c.object(query, namespace, name, "uid-"+strconv.Itoa(c.version+1))
+		c.objects[key] = new
+		c.write(local, unKey(new), new)
+		c.pending[query] = append(c.pending[query], rawUpdate{query, c.informers[query], nil, new})
+	case r < 6:
+		new := c.object(query, namespace, name, string(old.GetUID()))
+		c.objects[key] = new
+		c.write(local, unKey(new), new)
+		c.pending[query] = append(c.pending[query], rawUpdate{query, c.informers[query], old, new})
+	case r < 7:
+		// A resync, in which the informer hands over what it already has.
+		c.pending[query] = append(c.pending[query], rawUpdate{query, c.informers[query], old, old})
+	default:
+		delete(c.objects, key)
+		c.write(local, unKey(old), nil)
+		c.pending[query] = append(c.pending[query], rawUpdate{query, c.informers[query], old, nil})
+	}
+}
+
+// write records a change made through the Client, as its Create, Update and Delete do.
+func (c *fuzzCluster) write(local bool, key string, un *Unstructured) {
+	if !local {
+		return
+	}
+	c.client.mutex.Lock()
+	defer c.client.mutex.Unlock()
+	if un != nil {
+		un = un.DeepCopy()
+	}
+	c.client.canonical[key] = un
+}
+
+// deliver delivers up to n of the pending watch events, taking the watches in a random order.
+func (c *fuzzCluster) deliver(updates chan<- rawUpdate, n int) error {
+	for ; n > 0; n-- {
+		var queries []string
+		for _, query := range fuzzQueries {
+			if len(c.pending[query]) > 0 {
+				queries = append(queries, query)
+			}
+		}
+		if len(queries) == 0 {
+			return nil
+		}
+		query := queries[c.rand.Intn(len(queries))]
+		update := c.pending[query][0]
+		c.pending[query] = c.pending[query][1:]
+		if update.new == nil && update.old != nil {
+			// As watchRaw does for deletes.
+			c.client.mutex.Lock()
+			delete(c.client.canonical, unKey(update.old))
+			c.client.mutex.Unlock()
+		}
+		if err := send(updates, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *fuzzCluster) deliverAll(updates chan<- rawUpdate) error {
+	return c.deliver(updates, math.MaxInt32)
+}
+
+// sync tells the Accumulator that every watch has synced, in a random order.
+func (c *fuzzCluster) sync(updates chan<- rawUpdate, synced *int32) error {
+	order := c.rand.Perm(len(fuzzQueries))
+	for i, j := range order {
+		query := fuzzQueries[j]
+		if i == len(order)-1 {
+			atomic.StoreInt32(synced, 1)
+		}
+		atomic.StoreInt32(&c.informers[query].synced, 1)
+		if err := send(updates, rawUpdate{query, c.informers[query], nil, nil}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// want describes the valid resources in the cluster, as describe does.
+func (c *fuzzCluster) want(isValid func(*Unstructured) bool) []string {
+	snapshot := fuzzSnapshot{}
+	for key, un := range c.objects {
+		if !isValid(un) {
+			continue
+		}
+		switch strings.Fields(key)[0] {
+		case "Mappings":
+			snapshot.Mappings = append(snapshot.Mappings, un)
+		case "ConfigMaps":
+			snapshot.ConfigMaps = append(snapshot.ConfigMaps, un)
+		}
+	}
+	return snapshot.describe()
+}
+
+// A fuzzWatcher updates a snapshot from an Accumulator whenever it changes, as the entrypoint's
+// watcher does, and keeps the latest.
+type fuzzWatcher struct {
+	mu     sync.Mutex
+	latest []string
+	err    error
+}
+
+func (w *fuzzWatcher) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *fuzzWatcher) state() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.latest, w.err
+}
+
+func watchFuzz(ctx context.Context, acc *Accumulator, isValid func(*Unstructured) bool, synced *int32, seed int64) *fuzzWatcher {
+	w := &fuzzWatcher{}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				w.fail(fmt.Errorf("panic: %v\n%s", r, debug.Stack()))
+			}
+		}()
+		rnd := rand.New(rand.NewSource(seed))
+		snapshot := &fuzzSnapshot{}
+		for {
+			select {
+			case <-acc.Changed():
+			case <-ctx.Done():
+				return
+			}
+			if atomic.LoadInt32(synced) == 0 {
+				w.fail(fmt.Errorf("notified before every watch had synced"))
+			}
+			// The business logic takes its time, and more updates pile up meanwhile.
+			time.Sleep(time.Duration(rnd.Intn(200)) * time.Microsecond)
+
+			var deltas []*Delta
+			if !acc.FilteredUpdate(snapshot, &deltas, isValid) {
+				continue
+			}
+			if _, err := json.Marshal(deltas); err != nil {
+				w.fail(err)
+			}
+			latest := snapshot.describe()
+			w.mu.Lock()
+			w.latest = latest
+			w.mu.Unlock()
+		}
+	}()
+	return w
+}
+
+// batch applies the resources in the cluster to a new Accumulator all at once, and returns the
+// snapshot that it gets.
+func batch(ctx context.Context, c *fuzzCluster, isValid func(*Unstructured) bool) ([]string, error) {
+	acc, updates := newFakeAccumulator(ctx, &Client{canonical: make(map[string]*Unstructured)})
+	informers := map[string]*fakeInformer{}
+	for query := range fuzzKinds {
+		informers[query] = &fakeInformer{synced: 1}
+	}
+	for key, un := range c.objects {
+		query := strings.Fields(key)[0]
+		if err := send(updates, rawUpdate{query, informers[query], nil, un}); err != nil {
+			return nil, err
+		}
+	}
+	for _, query := range fuzzQueries {
+		if err := send(updates, rawUpdate{query, informers[query], nil, nil}); err != nil {
+			return nil, err
+		}
+	}
+	select {
+	case <-acc.Changed():
+	case <-time.After(fuzzTimeout):
+		return nil, fmt.Errorf("deadlock: no notification from the batch Accumulator")
+	}
+	snapshot := &fuzzSnapshot{}
+	acc.FilteredUpdate(snapshot, nil, isValid)
+	return snapshot.describe(), nil
+}
+
+func fuzzAccumulator(t *testing.T, seed int64, isValid func(*Unstructured) bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rnd := rand.New(rand.NewSource(seed))
+	client := &Client{canonical: make(map[string]*Unstructured)}
+	acc, updates := newFakeAccumulator(ctx, client)
+	c := newFuzzCluster(rnd, client)
+	var synced int32
+	w := watchFuzz(ctx, acc, isValid, &synced, seed)
+
+	// What's there to begin with, which each watch lists before it syncs.
+	for i := rnd.Intn(10); i > 0; i-- {
+		c.step(false)
+	}
+	require.NoError(t, c.deliverAll(updates))
+	require.NoError(t, c.sync(updates, &synced))
+
+	// Then changes, with the watches lagging behind.
+	for i := 0; i < fuzzSteps; i++ {
+		c.step(true)
+		if rnd.Intn(3) == 0 {
+			require.NoError(t, c.deliver(updates, rnd.Intn(5)))
+		}
+	}
+	require.NoError(t, c.deliverAll(updates))
+
+	want, err := batch(ctx, c, isValid)
+	require.NoError(t, err)
+	require.Equal(t, c.want(isValid), want, "the batch Accumulator has the wrong resources")
+
+	deadline := time.Now().Add(fuzzTimeout)
+	for {
+		got, err := w.state()
+		require.NoError(t, err)
+		if strings.Join(got, "\n") == strings.Join(want, "\n") {
+			return
+		}
+		if time.Now().After(deadline) {
+			require.Equal(t, want, got, "the Accumulator didn't converge on the snapshot of the cluster")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
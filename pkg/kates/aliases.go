@@ -44,12 +44,20 @@ import (
 
 type TypeMeta = metav1.TypeMeta
 type ObjectMeta = metav1.ObjectMeta
+type OwnerReference = metav1.OwnerReference
+type UID = types.UID
+
+type Time = metav1.Time
+
+var Now = metav1.Now
 
 type Namespace = corev1.Namespace
 
 type LocalObjectReference = corev1.LocalObjectReference
 
 type Event = corev1.Event
+type EventSource = corev1.EventSource
+type ObjectReference = corev1.ObjectReference
 type ConfigMap = corev1.ConfigMap
 
 type Secret = corev1.Secret
@@ -61,6 +69,10 @@ type Service = corev1.Service
 type ServiceSpec = corev1.ServiceSpec
 type ServicePort = corev1.ServicePort
 type Endpoints = corev1.Endpoints
+type EndpointSubset = corev1.EndpointSubset
+type EndpointAddress = corev1.EndpointAddress
+type EndpointPort = corev1.EndpointPort
+type LoadBalancerIngress = corev1.LoadBalancerIngress
 
 var ServiceTypeLoadBalancer = corev1.ServiceTypeLoadBalancer
 
@@ -150,6 +162,7 @@ var ParseSelector = labels.Parse
 
 var IsNotFound = apierrors.IsNotFound
 var IsConflict = apierrors.IsConflict
+var IsAlreadyExists = apierrors.IsAlreadyExists
 
 //
 
@@ -783,7 +783,7 @@ func (c *Client) patchWatch(field *field) {
 				// remove it.
 				log.Println("Patching delete", field.mapping.GroupVersionKind.Kind, key)
 				delete(field.values, key)
-				field.deltas[key] = newDelta(ObjectDelete, can)
+				field.deltas[key] = newDelta(ObjectDelete, item)
 			} else if gteq(item.GetResourceVersion(), can.GetResourceVersion()) {
 				// The object in the watch result is the same or newer than our canonical value, so
 				// no need to track it anymore.
@@ -927,12 +927,15 @@ func convert(in interface{}, out interface{}) error {
 		return nil
 	}
 
-	jsonBytes, err := json.Marshal(in)
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	err := json.NewEncoder(buf).Encode(in)
 	if err != nil {
 		return err
 	}
 
-	err = json.Unmarshal(jsonBytes, out)
+	err = json.Unmarshal(buf.Bytes(), out)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,84 @@
+package kates
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Every object the Accumulator hands out is decoded from JSON, and so gets its own copy of every
+// string in it. In a large cluster many of those strings are the same few namespaces, repeated in
+// every one of many thousands of objects, for as long as the objects are in a snapshot. Interning
+// them keeps one copy of each.
+
+// An Interner returns one copy of each string it's given. It only learns so many strings: after
+// that, it still returns the copies it has, but returns any other string as it is, so that a
+// stream of unique strings can't grow it without bound.
+type Interner struct {
+	mu      sync.Mutex
+	strings map[string]string
+	max     int
+}
+
+// NewInterner returns an Interner that learns up to max strings.
+func NewInterner(max int) *Interner {
+	return &Interner{strings: make(map[string]string), max: max}
+}
+
+// The Intern method returns the Interner's copy of s.
+func (in *Interner) Intern(s string) string {
+	if s == "" {
+		return s
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, ok := in.strings[s]; ok {
+		return interned
+	}
+	if len(in.strings) < in.max {
+		in.strings[s] = s
+	}
+	return s
+}
+
+// The Len method returns how many strings the Interner has learned.
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.strings)
+}
+
+// metaStrings interns the metadata of the objects that the Accumulator decodes.
+var metaStrings = NewInterner(1 << 16)
+
+// InternMeta replaces obj's name and namespace with interned copies. The rest of its metadata is
+// left alone: replacing the labels or annotations would mean allocating new maps for them on
+// every update, which costs more than the strings in them save.
+func InternMeta(obj Object) {
+	internMeta(metaStrings, obj)
+}
+
+func internMeta(in *Interner, obj Object) {
+	obj.SetName(in.Intern(obj.GetName()))
+	obj.SetNamespace(in.Intern(obj.GetNamespace()))
+}
+
+// bufferPool holds the buffers that objects are encoded into on their way to being decoded as
+// some other type, so that every update doesn't allocate (and throw away) a buffer the size of
+// everything it's updating.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	// Don't keep a buffer that one huge update grew, or it'll be kept for good.
+	if buf.Cap() > 64<<20 {
+		return
+	}
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,116 @@
+package kates
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sameString returns whether a and b share their bytes, rather than just being equal.
+func sameString(a, b string) bool {
+	return (*reflect.StringHeader)(unsafe.Pointer(&a)).Data == (*reflect.StringHeader)(unsafe.Pointer(&b)).Data
+}
+
+func TestInterner(t *testing.T) {
+	in := NewInterner(2)
+
+	a := in.Intern(string([]byte("default")))
+	b := in.Intern(string([]byte("default")))
+	assert.Equal(t, "default", b)
+	assert.True(t, sameString(a, b))
+
+	in.Intern("ambassador")
+	assert.Equal(t, 2, in.Len())
+
+	// Full, so anything new comes back as it is, and isn't learned.
+	assert.Equal(t, "kube-system", in.Intern("kube-system"))
+	assert.Equal(t, 2, in.Len())
+	assert.True(t, sameString(a, in.Intern(string([]byte("default")))))
+
+	assert.Equal(t, "", in.Intern(""))
+	assert.Equal(t, 2, in.Len())
+}
+
+func TestInternMeta(t *testing.T) {
+	in := NewInterner(100)
+
+	decode := func() *Service {
+		var svc Service
+		require.NoError(t, json.Unmarshal([]byte(`{"apiVersion": "v1", "kind": "Service", "metadata": {
+			"name": "quote", "namespace": "default", "labels": {"app": "quote"},
+			"annotations": {"getambassador.io/config": "---"},
+			"managedFields": [{"manager": "kubectl", "operation": "Update", "apiVersion": "v1", "fieldsType": "FieldsV1"}]}}`), &svc))
+		internMeta(in, &svc)
+		return &svc
+	}
+
+	a, b := decode(), decode()
+	assert.Equal(t, a, b)
+	assert.True(t, sameString(a.Name, b.Name))
+	assert.True(t, sameString(a.Namespace, b.Namespace))
+
+	// The labels are left where they are, not copied into a new map.
+	labels := a.Labels
+	internMeta(in, a)
+	assert.Equal(t, reflect.ValueOf(labels).Pointer(), reflect.ValueOf(a.Labels).Pointer())
+}
+
+// benchmarkServices decodes n Services, spread over a few namespaces with the same labels, as
+// the Accumulator would, and reports how much heap they take up.
+func benchmarkServices(b *testing.B, n int, intern bool) {
+	var items []*Unstructured
+	for i := 0; i < n; i++ {
+		items = append(items, &Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata": map[string]interface{}{
+				"name":      fmt.Sprintf("svc-%d", i),
+				"namespace": fmt.Sprintf("namespace-%d", i%10),
+				"labels": map[string]interface{}{
+					"app.kubernetes.io/name":       "quote",
+					"app.kubernetes.io/part-of":    "ambassador-benchmark",
+					"app.kubernetes.io/managed-by": "kates",
+				},
+				"annotations": map[string]interface{}{
+					"getambassador.io/config": fmt.Sprintf("---\nprefix: /svc-%d/\n", i),
+				},
+				"managedFields": []interface{}{
+					map[string]interface{}{"manager": "kubectl", "operation": "Update", "apiVersion": "v1", "fieldsType": "FieldsV1"},
+				},
+			},
+		}})
+	}
+
+	var heap int64
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		var services []*Service
+		require.NoError(b, convert(items, &services))
+		if intern {
+			in := NewInterner(1 << 16)
+			for _, svc := range services {
+				internMeta(in, svc)
+			}
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		heap += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		runtime.KeepAlive(services)
+	}
+	b.ReportMetric(float64(heap)/float64(b.N)/float64(n), "heap-bytes/object")
+}
+
+func BenchmarkDecodeServices(b *testing.B) {
+	b.Run("plain", func(b *testing.B) { benchmarkServices(b, 10000, false) })
+	b.Run("interned", func(b *testing.B) { benchmarkServices(b, 10000, true) })
+}
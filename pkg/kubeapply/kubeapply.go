@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -25,6 +26,10 @@ var errorDeadlineExceeded = errors.New("timeout exceeded")
 // look in the standard default places for cluster configuration.  If
 // any phase takes longer than perPhaseTimeout to become ready, then
 // it returns early with an error.
+//
+// Phases are applied in order of their files' number prefixes (such
+// as "00-"); within a phase, CRDs are applied first, then namespaces,
+// then everything else, each waiting for the one before to be ready.
 func Kubeapply(kubeinfo *k8s.KubeInfo, perPhaseTimeout time.Duration, debug, dryRun bool, files ...string) error {
 	collection, err := CollectYAML(files...)
 	if err != nil {
@@ -117,12 +122,76 @@ func (collection YAMLCollection) ApplyAndWait(
 	return nil
 }
 
+// Within a phase, resources are applied in tiers, each of which only
+// starts once the one before it is ready: first CRDs, since nothing of
+// their kinds can be applied until they're established, then
+// namespaces, for everything that goes in them, then everything else.
+const (
+	tierCRDs = iota
+	tierNamespaces
+	tierRest
+	numTiers
+)
+
+var tierNames = [numTiers]string{"crds", "namespaces", "rest"}
+
+func tierOf(r k8s.Resource) int {
+	switch r.Kind() {
+	case "CustomResourceDefinition":
+		return tierCRDs
+	case "Namespace":
+		return tierNamespaces
+	default:
+		return tierRest
+	}
+}
+
+// Parallelism is how many files of a phase's last tier are applied at
+// once. They're independent of each other, and kubectl spends most of
+// its time waiting on the cluster.
+var Parallelism = 4
+
 func applyAndWait(kubeinfo *k8s.KubeInfo, deadline time.Time, debug, dryRun bool, filenames []string) error {
-	expanded, err := expand(filenames)
+	tiers, err := expand(filenames)
+	// Expanded files that don't scan are left for a look at what's
+	// wrong with them; in debug mode, they all are.
+	invalid := make(map[string]bool)
+	if !debug {
+		defer func() {
+			for _, expanded := range tiers {
+				for _, n := range expanded {
+					if invalid[n] {
+						continue
+					}
+					if err := os.Remove(n); err != nil {
+						log.Print(err)
+					}
+				}
+			}
+		}()
+	}
 	if err != nil {
 		return err
 	}
 
+	for tier, expanded := range tiers {
+		if len(expanded) == 0 {
+			continue
+		}
+		fmt.Printf("applying %s\n", tierNames[tier])
+		if err := applyTier(kubeinfo, deadline, dryRun, expanded, invalid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyTier applies the files of one tier, and waits for what's in
+// them to be ready. Since the tiers before it are ready, it can
+// resolve the kinds of everything in them. It marks the files that
+// don't scan in invalid.
+func applyTier(kubeinfo *k8s.KubeInfo, deadline time.Time, dryRun bool, expanded []string, invalid map[string]bool) error {
 	cli, err := k8s.NewClient(kubeinfo)
 	if err != nil {
 		return errors.Wrapf(err, "kubeapply: error connecting to cluster %v", kubeinfo)
@@ -132,41 +201,24 @@ func applyAndWait(kubeinfo *k8s.KubeInfo, deadline time.Time, debug, dryRun bool
 		return err
 	}
 
-	valid := make(map[string]bool)
 	var msgs []string
 	for _, n := range expanded {
-		err := waiter.Scan(n)
-		if err != nil {
+		if err := waiter.Scan(n); err != nil {
 			msgs = append(msgs, fmt.Sprintf("%s: %v\n", n, err))
-			valid[n] = false
-		} else {
-			valid[n] = true
+			invalid[n] = true
 		}
 	}
-
-	if len(msgs) == 0 {
-		err = kubectlApply(kubeinfo, dryRun, expanded)
-	}
-
-	if !debug {
-		for _, n := range expanded {
-			if valid[n] {
-				err := os.Remove(n)
-				if err != nil {
-					log.Print(err)
-				}
-			}
-		}
+	if len(msgs) > 0 {
+		return errors.Errorf("errors expanding templates:\n  %s", strings.Join(msgs, "\n  "))
 	}
 
+	err = inParallel(expanded, Parallelism, func(n string) error {
+		return kubectlApply(kubeinfo, dryRun, []string{n})
+	})
 	if err != nil {
 		return err
 	}
 
-	if len(msgs) > 0 {
-		return errors.Errorf("errors expanding templates:\n  %s", strings.Join(msgs, "\n  "))
-	}
-
 	if !waiter.Wait(deadline) {
 		return errorDeadlineExceeded
 	}
@@ -174,22 +226,72 @@ func applyAndWait(kubeinfo *k8s.KubeInfo, deadline time.Time, debug, dryRun bool
 	return nil
 }
 
-func expand(names []string) ([]string, error) {
+// inParallel calls fn on each of names, up to n at a time, and
+// returns the errors that any of them return.
+func inParallel(names []string, n int, fn func(name string) error) error {
+	if n < 1 {
+		n = 1
+	}
+	sem := make(chan struct{}, n)
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	var msgs []string
+	for i, err := range errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", names[i], err))
+		}
+	}
+	switch len(msgs) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New(msgs[0])
+	default:
+		return errors.Errorf("%d errors:\n  %s", len(msgs), strings.Join(msgs, "\n  "))
+	}
+}
+
+// expand expands each of names, and splits what's in it by tier. It
+// returns the names of the files it's written for each tier: one for
+// each of names that has anything in that tier.
+func expand(names []string) (tiers [numTiers][]string, err error) {
 	fmt.Printf("expanding %s\n", strings.Join(names, " "))
-	var result []string
 	for _, n := range names {
 		resources, err := LoadResources(n)
 		if err != nil {
-			return nil, err
+			return tiers, err
 		}
-		out := n + ".o"
-		err = SaveResources(out, resources)
-		if err != nil {
-			return nil, err
+		var split [numTiers][]k8s.Resource
+		for _, r := range resources {
+			if r.Empty() {
+				continue
+			}
+			tier := tierOf(r)
+			split[tier] = append(split[tier], r)
+		}
+		for tier, resources := range split {
+			if len(resources) == 0 {
+				continue
+			}
+			out := fmt.Sprintf("%s.%s.o", n, tierNames[tier])
+			if err := SaveResources(out, resources); err != nil {
+				return tiers, err
+			}
+			tiers[tier] = append(tiers[tier], out)
 		}
-		result = append(result, out)
 	}
-	return result, nil
+	return tiers, nil
 }
 
 func kubectlApply(info *k8s.KubeInfo, dryRun bool, filenames []string) error {
@@ -0,0 +1,179 @@
+package kubeapply
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/k8s"
+)
+
+func parse(t *testing.T, input string) k8s.Resource {
+	t.Helper()
+	resources, err := k8s.ParseResources("test", input)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	return resources[0]
+}
+
+func TestReadyCheck(t *testing.T) {
+	deployment := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: quote
+  annotations:
+    kubeapply.datawire.io/wait-for: condition=Available
+spec:
+  replicas: 1
+status:
+  readyReplicas: 1
+  conditions:
+  - type: Progressing
+    status: "True"
+  - type: Available
+    status: "False"
+`
+	check, err := readyCheck(parse(t, deployment))
+	require.NoError(t, err)
+	r := parse(t, deployment)
+	assert.True(t, Ready(r), "a ready replica is enough by default")
+	assert.False(t, check(r), "but not once it has to be Available")
+	r.Status().GetMaps("conditions")[1]["status"] = "True"
+	assert.True(t, check(r))
+
+	host := `
+apiVersion: getambassador.io/v2
+kind: Host
+metadata:
+  name: example
+status:
+  state: Pending
+`
+	r = parse(t, host)
+	assert.False(t, Ready(r))
+	r["status"] = map[string]interface{}{"state": "Ready"}
+	assert.True(t, Ready(r))
+
+	crd := `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: hosts.getambassador.io
+status:
+  conditions:
+  - type: Established
+    status: "True"
+  - type: NamesAccepted
+    status: "False"
+`
+	assert.True(t, Ready(parse(t, crd)), "the last condition isn't the one that matters")
+
+	check, err = readyCheck(parse(t, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: slow
+  annotations:
+    kubeapply.datawire.io/wait-for: none
+`))
+	require.NoError(t, err)
+	assert.True(t, check(parse(t, "kind: Pod\nstatus:\n  containerStatuses:\n  - ready: false\n")))
+	assert.False(t, check(nil), "it still has to exist")
+
+	for _, waitFor := range []string{"Available", "condition=", "phase=Running"} {
+		r := parse(t, "kind: Pod\nmetadata:\n  name: bad\n")
+		r.Metadata()["annotations"] = map[string]interface{}{WaitForAnnotation: waitFor}
+		_, err := readyCheck(r)
+		assert.Error(t, err, waitFor)
+	}
+}
+
+func TestExpandTiers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeapply")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "ambassador.yaml")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ambassador
+  namespace: ambassador
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ambassador
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: hosts.getambassador.io
+---
+apiVersion: getambassador.io/v2
+kind: Host
+metadata:
+  name: example
+  namespace: ambassador
+---
+`), 0644))
+	other := filepath.Join(dir, "other.yaml")
+	require.NoError(t, ioutil.WriteFile(other, []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: quote\n"), 0644))
+
+	tiers, err := expand([]string{file, other})
+	require.NoError(t, err)
+	assert.Equal(t, []string{file + ".crds.o"}, tiers[tierCRDs])
+	assert.Equal(t, []string{file + ".namespaces.o"}, tiers[tierNamespaces])
+	assert.Equal(t, []string{file + ".rest.o", other + ".rest.o"}, tiers[tierRest])
+
+	kinds := func(path string) []string {
+		resources, err := LoadResources(path)
+		require.NoError(t, err)
+		var result []string
+		for _, r := range resources {
+			result = append(result, r.Kind())
+		}
+		return result
+	}
+	assert.Equal(t, []string{"CustomResourceDefinition"}, kinds(tiers[tierCRDs][0]))
+	assert.Equal(t, []string{"Namespace"}, kinds(tiers[tierNamespaces][0]))
+	assert.Equal(t, []string{"Deployment", "Host"}, kinds(tiers[tierRest][0]))
+}
+
+func TestInParallel(t *testing.T) {
+	var mu sync.Mutex
+	running, most := 0, 0
+	names := []string{"a", "b", "c", "d", "e", "f"}
+	err := inParallel(names, 3, func(name string) error {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if name == "b" || name == "e" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "2 errors:\n  b: failed\n  e: failed")
+	assert.Equal(t, 3, most)
+
+	assert.NoError(t, inParallel(nil, 3, func(string) error { return errors.New("unused") }))
+	assert.EqualError(t, inParallel([]string{"a"}, 0, func(string) error { return errors.New("failed") }), "a: failed")
+}
@@ -60,15 +60,63 @@ var readyChecks = map[string]func(k8s.Resource) bool{
 		return true
 	},
 	"CustomResourceDefinition": func(r k8s.Resource) bool {
-		conditions := r.Status().GetMaps("conditions")
-		if len(conditions) == 0 {
-			return false
-		}
-		last := conditions[len(conditions)-1]
-		return last["status"] == "True"
+		return hasCondition(r, "Established")
+	},
+	"Host": func(r k8s.Resource) bool {
+		return r.Status().GetString("state") == "Ready"
 	},
 }
 
+// WaitForAnnotation is the annotation that says what a resource has to
+// get to before kubeapply counts it as ready, instead of what
+// readyChecks says for its kind. It's one of:
+//
+//	condition=<type>  status.conditions has a condition of that type
+//	                  whose status is "True", e.g. "condition=Available"
+//	state=<state>     status.state is that, e.g. "state=Ready"
+//	none              it's ready as soon as it exists
+const WaitForAnnotation = "kubeapply.datawire.io/wait-for"
+
+// hasCondition returns whether r's status has a condition of the
+// given type that's true.
+func hasCondition(r k8s.Resource, conditionType string) bool {
+	for _, condition := range r.Status().GetMaps("conditions") {
+		if condition["type"] == conditionType {
+			return condition["status"] == "True"
+		}
+	}
+	return false
+}
+
+// readyCheck returns the check that r, as it is in a manifest, has
+// to pass to be ready, which is what its WaitForAnnotation says, if
+// it has one, and Ready otherwise.
+func readyCheck(r k8s.Resource) (func(k8s.Resource) bool, error) {
+	waitFor, _ := r.Metadata().Annotations()[WaitForAnnotation].(string)
+	if waitFor == "" {
+		return Ready, nil
+	}
+	if waitFor == "none" {
+		return func(r k8s.Resource) bool { return !r.Empty() }, nil
+	}
+
+	parts := strings.SplitN(waitFor, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.Errorf("%s: %s: %q: want condition=<type>, state=<state> or none",
+			r.QName(), WaitForAnnotation, waitFor)
+	}
+	value := parts[1]
+	switch parts[0] {
+	case "condition":
+		return func(r k8s.Resource) bool { return hasCondition(r, value) }, nil
+	case "state":
+		return func(r k8s.Resource) bool { return r.Status().GetString("state") == value }, nil
+	default:
+		return nil, errors.Errorf("%s: %s: %q: want condition=<type>, state=<state> or none",
+			r.QName(), WaitForAnnotation, waitFor)
+	}
+}
+
 // ReadyImplemented returns whether or not this package knows how to
 // wait for this resource to be ready.
 func ReadyImplemented(r k8s.Resource) bool {
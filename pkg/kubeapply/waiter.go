@@ -12,7 +12,9 @@ import (
 // in it to be ready.
 type Waiter struct {
 	watcher *k8s.Watcher
-	kinds   map[k8s.ResourceType]map[string]struct{}
+	// kinds holds the check that each resource that's still to be
+	// ready has to pass, by type and name.
+	kinds map[k8s.ResourceType]map[string]func(k8s.Resource) bool
 }
 
 // NewWaiter constructs a Waiter object based on the supplied Watcher.
@@ -26,11 +28,16 @@ func NewWaiter(watcher *k8s.Watcher) (w *Waiter, err error) {
 	}
 	return &Waiter{
 		watcher: watcher,
-		kinds:   make(map[k8s.ResourceType]map[string]struct{}),
+		kinds:   make(map[k8s.ResourceType]map[string]func(k8s.Resource) bool),
 	}, nil
 }
 
 func (w *Waiter) add(resource k8s.Resource) error {
+	check, err := readyCheck(resource)
+	if err != nil {
+		return err
+	}
+
 	resourceType, err := w.watcher.Client.ResolveResourceType(resource.QKind())
 	if err != nil {
 		return err
@@ -46,9 +53,9 @@ func (w *Waiter) add(resource k8s.Resource) error {
 	}
 
 	if _, ok := w.kinds[resourceType]; !ok {
-		w.kinds[resourceType] = make(map[string]struct{})
+		w.kinds[resourceType] = make(map[string]func(k8s.Resource) bool)
 	}
-	w.kinds[resourceType][resourceName] = struct{}{}
+	w.kinds[resourceType][resourceName] = check
 	return nil
 }
 
@@ -117,10 +124,10 @@ func (w *Waiter) Wait(deadline time.Time) bool {
 
 	listener := func(watcher *k8s.Watcher) {
 		for kind, names := range w.kinds {
-			for name := range names {
+			for name, check := range names {
 				r := watcher.Get(kind.String(), name)
-				if Ready(r) {
-					if ReadyImplemented(r) {
+				if check(r) {
+					if ReadyImplemented(r) || r.Metadata().Annotations()[WaitForAnnotation] != nil {
 						fmt.Printf("ready: %s/%s\n", r.QKind(), r.QName())
 					} else {
 						fmt.Printf("ready: %s/%s (UNIMPLEMENTED)\n",
@@ -0,0 +1,230 @@
+// Package loadgen generates synthetic Ambassador configuration, for benchmarking how the control
+// plane scales: Services with their Endpoints, Mappings to them, and Hosts. It's deterministic
+// for a given seed, both in what it generates and in how it then churns what it generated, so
+// that a benchmark can be run again against the same load.
+package loadgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// Label is the label on everything that loadgen generates, so that it can be found and cleaned
+// up with a label selector.
+const Label = "getambassador.io/loadgen"
+
+// ResolverName is the name of the KubernetesEndpointResolver that the generated Mappings use, so
+// that Ambassador routes to their Endpoints, and churning the Endpoints churns Envoy's
+// configuration.
+const ResolverName = "loadgen-endpoint"
+
+// Options is how much to generate, and where.
+type Options struct {
+	Namespace    string
+	AmbassadorID string // if set, the ambassador_id of the Ambassador resources
+	Services     int
+	Endpoints    int // the addresses of each Service
+	Mappings     int // spread across the Services, and the Hosts if there are any
+	Hosts        int
+	Seed         int64
+}
+
+// A Generator holds generated configuration, and changes it at random.
+type Generator struct {
+	opts      Options
+	rand      *rand.Rand
+	resolver  *amb.KubernetesEndpointResolver
+	services  []*kates.Service
+	endpoints []*kates.Endpoints
+	mappings  []*amb.Mapping
+	hosts     []*amb.Host
+}
+
+// New generates configuration as the options say.
+func New(opts Options) (*Generator, error) {
+	if opts.Services < 1 && opts.Mappings > 0 {
+		return nil, fmt.Errorf("the Mappings need at least one Service")
+	}
+	if opts.Services < 0 || opts.Endpoints < 0 || opts.Mappings < 0 || opts.Hosts < 0 {
+		return nil, fmt.Errorf("can't generate a negative number of resources")
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+	}
+
+	g := &Generator{opts: opts, rand: rand.New(rand.NewSource(opts.Seed))}
+
+	g.resolver = &amb.KubernetesEndpointResolver{
+		TypeMeta:   kates.TypeMeta{APIVersion: "getambassador.io/v2", Kind: "KubernetesEndpointResolver"},
+		ObjectMeta: g.meta(ResolverName),
+		Spec:       amb.KubernetesEndpointResolverSpec{AmbassadorID: g.ambassadorID()},
+	}
+
+	for i := 0; i < opts.Services; i++ {
+		name := fmt.Sprintf("loadgen-%d", i)
+		g.services = append(g.services, &kates.Service{
+			TypeMeta:   kates.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: g.meta(name),
+			Spec: kates.ServiceSpec{
+				Type:     "ClusterIP",
+				Selector: map[string]string{"app": name},
+				Ports:    []kates.ServicePort{{Name: "http", Port: 80, Protocol: "TCP", TargetPort: kates.Int(8080)}},
+			},
+		})
+
+		var addresses []kates.EndpointAddress
+		for j := 0; j < opts.Endpoints; j++ {
+			addresses = append(addresses, kates.EndpointAddress{IP: g.ip()})
+		}
+		endpoints := &kates.Endpoints{
+			TypeMeta:   kates.TypeMeta{APIVersion: "v1", Kind: "Endpoints"},
+			ObjectMeta: g.meta(name),
+		}
+		if len(addresses) > 0 {
+			endpoints.Subsets = []kates.EndpointSubset{{
+				Addresses: addresses,
+				Ports:     []kates.EndpointPort{{Name: "http", Port: 8080, Protocol: "TCP"}},
+			}}
+		}
+		g.endpoints = append(g.endpoints, endpoints)
+	}
+
+	for i := 0; i < opts.Hosts; i++ {
+		g.hosts = append(g.hosts, &amb.Host{
+			TypeMeta:   kates.TypeMeta{APIVersion: "getambassador.io/v2", Kind: "Host"},
+			ObjectMeta: g.meta(fmt.Sprintf("loadgen-%d", i)),
+			Spec: &amb.HostSpec{
+				AmbassadorID:  g.ambassadorID(),
+				Hostname:      fmt.Sprintf("loadgen-%d.example.com", i),
+				AcmeProvider:  &amb.ACMEProviderSpec{Authority: "none"},
+				RequestPolicy: &amb.RequestPolicy{Insecure: amb.InsecureRequestPolicy{Action: "Route"}},
+			},
+		})
+	}
+
+	for i := 0; i < opts.Mappings; i++ {
+		spec := amb.MappingSpec{
+			AmbassadorID: g.ambassadorID(),
+			Prefix:       fmt.Sprintf("/loadgen-%d/", i),
+			Service:      fmt.Sprintf("loadgen-%d", i%opts.Services),
+			Resolver:     ResolverName,
+			TimeoutMs:    g.timeout(),
+		}
+		if opts.Hosts > 0 {
+			spec.Host = fmt.Sprintf("loadgen-%d.example.com", i%opts.Hosts)
+		}
+		g.mappings = append(g.mappings, &amb.Mapping{
+			TypeMeta:   kates.TypeMeta{APIVersion: "getambassador.io/v2", Kind: "Mapping"},
+			ObjectMeta: g.meta(fmt.Sprintf("loadgen-%d", i)),
+			Spec:       spec,
+		})
+	}
+
+	return g, nil
+}
+
+func (g *Generator) meta(name string) kates.ObjectMeta {
+	return kates.ObjectMeta{Name: name, Namespace: g.opts.Namespace, Labels: map[string]string{Label: "true"}}
+}
+
+func (g *Generator) ambassadorID() amb.AmbassadorID {
+	if g.opts.AmbassadorID == "" {
+		return nil
+	}
+	return amb.AmbassadorID{g.opts.AmbassadorID}
+}
+
+// ip returns a random address in 10.0.0.0/8.
+func (g *Generator) ip() string {
+	return fmt.Sprintf("10.%d.%d.%d", g.rand.Intn(256), g.rand.Intn(256), 1+g.rand.Intn(254))
+}
+
+func (g *Generator) timeout() int {
+	return 1000 * (1 + g.rand.Intn(30))
+}
+
+// Objects returns everything that's been generated, in the order to create it in: the resolver,
+// the Services and their Endpoints, then the Hosts and the Mappings.
+func (g *Generator) Objects() []kates.Object {
+	objects := []kates.Object{g.resolver}
+	for i := range g.services {
+		objects = append(objects, g.services[i], g.endpoints[i])
+	}
+	for _, host := range g.hosts {
+		objects = append(objects, host)
+	}
+	for _, mapping := range g.mappings {
+		objects = append(objects, mapping)
+	}
+	return objects
+}
+
+// Churn changes one generated resource at random, the way a busy cluster would, and returns it:
+// it moves an address of an Endpoints, as a rolling deployment does; changes the timeout of a
+// Mapping; or changes what a Host does with insecure requests. It returns nil if there's nothing
+// to change.
+func (g *Generator) Churn() kates.Object {
+	var endpoints []*kates.Endpoints
+	for _, e := range g.endpoints {
+		if len(e.Subsets) > 0 {
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	total := len(endpoints) + len(g.mappings) + len(g.hosts)
+	if total == 0 {
+		return nil
+	}
+	n := g.rand.Intn(total)
+
+	if n < len(endpoints) {
+		e := endpoints[n]
+		addresses := e.Subsets[0].Addresses
+		addresses[g.rand.Intn(len(addresses))] = kates.EndpointAddress{IP: g.ip()}
+		return e
+	}
+	n -= len(endpoints)
+
+	if n < len(g.mappings) {
+		m := g.mappings[n]
+		for timeout := m.Spec.TimeoutMs; timeout == m.Spec.TimeoutMs; {
+			m.Spec.TimeoutMs = g.timeout()
+		}
+		return m
+	}
+	n -= len(g.mappings)
+
+	h := g.hosts[n]
+	if h.Spec.RequestPolicy.Insecure.Action == "Route" {
+		h.Spec.RequestPolicy.Insecure.Action = "Redirect"
+	} else {
+		h.Spec.RequestPolicy.Insecure.Action = "Route"
+	}
+	return h
+}
+
+// snapshot is the part of the entrypoint's snapshot that loadgen generates.
+type snapshot struct {
+	Kubernetes struct {
+		Services                    []*kates.Service                  `json:"service"`
+		Endpoints                   []*kates.Endpoints                `json:"Endpoints"`
+		Hosts                       []*amb.Host                       `json:"Host"`
+		Mappings                    []*amb.Mapping                    `json:"Mapping"`
+		KubernetesEndpointResolvers []*amb.KubernetesEndpointResolver `json:"KubernetesEndpointResolver"`
+	}
+}
+
+// Snapshot returns what's been generated as a snapshot, as the entrypoint hands it to diagd.
+func (g *Generator) Snapshot() ([]byte, error) {
+	var snap snapshot
+	snap.Kubernetes.Services = g.services
+	snap.Kubernetes.Endpoints = g.endpoints
+	snap.Kubernetes.Hosts = g.hosts
+	snap.Kubernetes.Mappings = g.mappings
+	snap.Kubernetes.KubernetesEndpointResolvers = []*amb.KubernetesEndpointResolver{g.resolver}
+	return json.MarshalIndent(snap, "", "  ")
+}
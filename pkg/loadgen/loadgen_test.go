@@ -0,0 +1,97 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+var options = Options{Namespace: "bench", Services: 3, Endpoints: 2, Mappings: 7, Hosts: 2, Seed: 42}
+
+func TestGenerate(t *testing.T) {
+	g, err := New(options)
+	require.NoError(t, err)
+
+	kinds := map[string]int{}
+	for _, obj := range g.Objects() {
+		kinds[obj.GetObjectKind().GroupVersionKind().Kind]++
+		assert.Equal(t, "bench", obj.GetNamespace())
+		assert.Equal(t, "true", obj.GetLabels()[Label])
+	}
+	assert.Equal(t, map[string]int{
+		"KubernetesEndpointResolver": 1,
+		"Service":                    3,
+		"Endpoints":                  3,
+		"Host":                       2,
+		"Mapping":                    7,
+	}, kinds)
+
+	m := g.mappings[4]
+	assert.Equal(t, "/loadgen-4/", m.Spec.Prefix)
+	assert.Equal(t, "loadgen-1", m.Spec.Service)
+	assert.Equal(t, "loadgen-0.example.com", m.Spec.Host)
+	assert.Equal(t, ResolverName, m.Spec.Resolver)
+	for _, e := range g.endpoints {
+		assert.Len(t, e.Subsets[0].Addresses, 2)
+	}
+
+	_, err = New(Options{Mappings: 1})
+	assert.Error(t, err, "Mappings without Services")
+}
+
+func TestDeterministic(t *testing.T) {
+	snapshots := func() []string {
+		g, err := New(options)
+		require.NoError(t, err)
+		var snapshots []string
+		for i := 0; i < 10; i++ {
+			snapshot, err := g.Snapshot()
+			require.NoError(t, err)
+			snapshots = append(snapshots, string(snapshot))
+			require.NotNil(t, g.Churn())
+		}
+		return snapshots
+	}
+
+	first := snapshots()
+	assert.Equal(t, first, snapshots(), "the same seed generates and churns the same")
+	for i := 1; i < len(first); i++ {
+		assert.NotEqual(t, first[i-1], first[i], "every churn changes something")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	g, err := New(options)
+	require.NoError(t, err)
+	data, err := g.Snapshot()
+	require.NoError(t, err)
+
+	// It's read back as the entrypoint's snapshot would be.
+	var snapshot struct {
+		Kubernetes struct {
+			Services  []*kates.Service                  `json:"service"`
+			Endpoints []*kates.Endpoints                `json:"Endpoints"`
+			Hosts     []*amb.Host                       `json:"Host"`
+			Mappings  []*amb.Mapping                    `json:"Mapping"`
+			Resolvers []*amb.KubernetesEndpointResolver `json:"KubernetesEndpointResolver"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(data, &snapshot))
+	assert.Len(t, snapshot.Kubernetes.Services, 3)
+	assert.Len(t, snapshot.Kubernetes.Endpoints, 3)
+	assert.Len(t, snapshot.Kubernetes.Hosts, 2)
+	assert.Len(t, snapshot.Kubernetes.Mappings, 7)
+	assert.Len(t, snapshot.Kubernetes.Resolvers, 1)
+	assert.Equal(t, "Mapping", snapshot.Kubernetes.Mappings[0].Kind)
+}
+
+func TestChurnNothing(t *testing.T) {
+	g, err := New(Options{Services: 2})
+	require.NoError(t, err)
+	assert.Nil(t, g.Churn(), "Services without addresses, Mappings, or Hosts can't churn")
+}
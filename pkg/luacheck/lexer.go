@@ -0,0 +1,272 @@
+package luacheck
+
+import (
+	"regexp"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tName
+	tNumber
+	tString
+	tKeyword
+	tOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// near is how an error shows the token, the way luac does.
+func (t token) near() string {
+	if t.kind == tEOF {
+		return "<eof>"
+	}
+	return t.text
+}
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true,
+	"false": true, "for": true, "function": true, "goto": true, "if": true, "in": true,
+	"local": true, "nil": true, "not": true, "or": true, "repeat": true, "return": true,
+	"then": true, "true": true, "until": true, "while": true,
+}
+
+// LuaJIT takes hex fractions and binary exponents, and the LL, ULL and i suffixes for 64-bit
+// integers and imaginary numbers, as well as everything Lua 5.1 does.
+var numberRE = regexp.MustCompile(`^(0[xX]([0-9a-fA-F]+\.?[0-9a-fA-F]*|\.[0-9a-fA-F]+)([pP][+-]?[0-9]+)?|([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?)(i|[uU]?[lL][lL])?$`)
+
+type lexer struct {
+	name string
+	src  string
+	pos  int
+	line int
+}
+
+func (l *lexer) errorf(line int, near, msg string) error {
+	return &Error{Name: l.name, Line: line, Msg: msg, Near: near}
+}
+
+func (l *lexer) peekByte(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+// newline skips a newline, counting "\r\n" and "\n\r" as one, like Lua does.
+func (l *lexer) newline() {
+	c := l.src[l.pos]
+	l.pos++
+	if n := l.peekByte(0); (n == '\n' || n == '\r') && n != c {
+		l.pos++
+	}
+	l.line++
+}
+
+func isNewline(c byte) bool { return c == '\n' || c == '\r' }
+func isDigit(c byte) bool   { return c >= '0' && c <= '9' }
+func isAlpha(c byte) bool   { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isAlnum(c byte) bool   { return isAlpha(c) || isDigit(c) }
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case isNewline(c):
+			l.newline()
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			l.pos++
+		case c == '-' && l.peekByte(1) == '-':
+			l.pos += 2
+			if l.peekByte(0) == '[' {
+				if level, ok := l.longBracket(); ok {
+					line := l.line
+					if _, err := l.longString(level); err != nil {
+						return token{}, l.errorf(line, "<eof>", "unfinished long comment")
+					}
+					continue
+				}
+			}
+			for l.pos < len(l.src) && !isNewline(l.src[l.pos]) {
+				l.pos++
+			}
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: tEOF, line: l.line}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	start, line := l.pos, l.line
+	c := l.src[l.pos]
+	switch {
+	case isAlpha(c):
+		for l.pos < len(l.src) && isAlnum(l.src[l.pos]) {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		if keywords[text] {
+			return token{kind: tKeyword, text: text, line: line}, nil
+		}
+		return token{kind: tName, text: text, line: line}, nil
+	case isDigit(c) || (c == '.' && isDigit(l.peekByte(1))):
+		return l.number()
+	case c == '"' || c == '\'':
+		return l.shortString(c)
+	case c == '[':
+		level, ok := l.longBracket()
+		if !ok {
+			if level > 0 {
+				return token{}, l.errorf(line, l.src[start:start+1+level], "invalid long string delimiter")
+			}
+			l.pos++
+			return token{kind: tOp, text: "[", line: line}, nil
+		}
+		text, err := l.longString(level)
+		if err != nil {
+			return token{}, l.errorf(line, "<eof>", "unfinished long string")
+		}
+		return token{kind: tString, text: text, line: line}, nil
+	}
+
+	for _, op := range []string{"...", "..", "==", "<=", ">=", "~=", "::"} {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tOp, text: op, line: line}, nil
+		}
+	}
+	if strings.IndexByte("+-*/%^#=<>(){}[];:,.", c) >= 0 {
+		l.pos++
+		return token{kind: tOp, text: string(c), line: line}, nil
+	}
+	return token{}, l.errorf(line, string(c), "unexpected symbol")
+}
+
+func (l *lexer) number() (token, error) {
+	start, line := l.pos, l.line
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if strings.IndexByte("eEpP", c) >= 0 && strings.IndexByte("+-", l.peekByte(1)) >= 0 {
+			l.pos += 2
+		} else if isAlnum(c) || c == '.' {
+			l.pos++
+		} else {
+			break
+		}
+	}
+	text := l.src[start:l.pos]
+	if !numberRE.MatchString(text) {
+		return token{}, l.errorf(line, text, "malformed number")
+	}
+	return token{kind: tNumber, text: text, line: line}, nil
+}
+
+func (l *lexer) shortString(quote byte) (token, error) {
+	start, line := l.pos, l.line
+	l.pos++
+	for {
+		if l.pos >= len(l.src) || isNewline(l.src[l.pos]) {
+			return token{}, l.errorf(line, l.src[start:l.pos], "unfinished string")
+		}
+		c := l.src[l.pos]
+		if c == quote {
+			l.pos++
+			return token{kind: tString, text: l.src[start:l.pos], line: line}, nil
+		}
+		if c != '\\' {
+			l.pos++
+			continue
+		}
+
+		l.pos++
+		e := l.peekByte(0)
+		switch {
+		case l.pos >= len(l.src):
+			// The loop reports it.
+		case isNewline(e):
+			l.newline()
+		case strings.IndexByte(`abfnrtv\"'`, e) >= 0:
+			l.pos++
+		case e == 'x':
+			if !isHex(l.peekByte(1)) || !isHex(l.peekByte(2)) {
+				return token{}, l.errorf(line, l.src[start:l.pos+1], "invalid escape sequence")
+			}
+			l.pos += 3
+		case e == 'z':
+			l.pos++
+			for l.pos < len(l.src) && strings.IndexByte(" \t\v\f\r\n", l.src[l.pos]) >= 0 {
+				if isNewline(l.src[l.pos]) {
+					l.newline()
+				} else {
+					l.pos++
+				}
+			}
+		case e == 'u' && l.peekByte(1) == '{':
+			l.pos += 2
+			digits := 0
+			for isHex(l.peekByte(0)) {
+				l.pos++
+				digits++
+			}
+			if digits == 0 || l.peekByte(0) != '}' {
+				return token{}, l.errorf(line, l.src[start:l.pos], "invalid escape sequence")
+			}
+			l.pos++
+		case isDigit(e):
+			value := 0
+			for i := 0; i < 3 && isDigit(l.peekByte(0)); i++ {
+				value = value*10 + int(l.src[l.pos]-'0')
+				l.pos++
+			}
+			if value > 255 {
+				return token{}, l.errorf(line, l.src[start:l.pos], "escape sequence too large")
+			}
+		default:
+			return token{}, l.errorf(line, l.src[start:l.pos+1], "invalid escape sequence")
+		}
+	}
+}
+
+// longBracket checks for the opening bracket of a long string or comment, "[[", "[=[" and so
+// on, at pos. If there is one, it skips it, and returns its level; if there's only "[=" or "[==",
+// it returns the number of "="s, and false.
+func (l *lexer) longBracket() (int, bool) {
+	level := 0
+	for l.peekByte(1+level) == '=' {
+		level++
+	}
+	if l.peekByte(1+level) != '[' {
+		return level, false
+	}
+	l.pos += 2 + level
+	return level, true
+}
+
+// longString skips to the end of a long string or comment whose opening bracket was at the given
+// level, and returns all of it.
+func (l *lexer) longString(level int) (string, error) {
+	start := l.pos
+	closing := "]" + strings.Repeat("=", level) + "]"
+	for l.pos < len(l.src) {
+		if isNewline(l.src[l.pos]) {
+			l.newline()
+			continue
+		}
+		if strings.HasPrefix(l.src[l.pos:], closing) {
+			l.pos += len(closing)
+			return l.src[start:l.pos], nil
+		}
+		l.pos++
+	}
+	return "", errUnfinished
+}
@@ -0,0 +1,540 @@
+// Package luacheck checks the syntax of Lua scripts before they're handed to Envoy. Envoy runs
+// them with LuaJIT, so it takes Lua 5.1, with LuaJIT's goto, labels, and number and escape
+// syntax. A script that doesn't parse would otherwise only fail once Envoy loads it, and make
+// Envoy reject the whole configuration that it's in.
+//
+// Only syntax is checked: a script that parses can still fail when it runs.
+package luacheck
+
+import (
+	"errors"
+	"fmt"
+)
+
+// An Error is a syntax error in a script. It reads like the errors of luac.
+type Error struct {
+	// Name is the name of the script.
+	Name string
+	Line int
+	Msg  string
+	// Near is the token where the error was found.
+	Near string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s near '%s'", e.Name, e.Line, e.Msg, e.Near)
+}
+
+var errUnfinished = errors.New("unfinished")
+
+// Check parses a script, and returns the first syntax error in it, as an *Error. name is what
+// the error calls the script.
+func Check(name string, src []byte) error {
+	p := &parser{lex: &lexer{name: name, src: string(src), line: 1}}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	// The main chunk is a vararg function.
+	p.fs = &funcState{vararg: true}
+	if err := p.block(); err != nil {
+		return err
+	}
+	if p.tok.kind != tEOF {
+		return p.errorf("'<eof>' expected")
+	}
+	return nil
+}
+
+type funcState struct {
+	vararg bool
+	// loops is how many loops deep we are in this function, for break.
+	loops int
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+	// ahead is the token after tok, if it's been looked at.
+	ahead    *token
+	lastLine int
+	fs       *funcState
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &Error{Name: p.lex.name, Line: p.tok.line, Msg: fmt.Sprintf(format, args...), Near: p.tok.near()}
+}
+
+func (p *parser) advance() error {
+	p.lastLine = p.tok.line
+	if p.ahead != nil {
+		p.tok, p.ahead = *p.ahead, nil
+		return nil
+	}
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) lookahead() (token, error) {
+	if p.ahead == nil {
+		tok, err := p.lex.next()
+		if err != nil {
+			return token{}, err
+		}
+		p.ahead = &tok
+	}
+	return *p.ahead, nil
+}
+
+// is reports whether the current token is the keyword or operator s.
+func (p *parser) is(s string) bool {
+	return (p.tok.kind == tKeyword || p.tok.kind == tOp) && p.tok.text == s
+}
+
+// accept skips the current token if it's s.
+func (p *parser) accept(s string) (bool, error) {
+	if !p.is(s) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(s string) error {
+	if !p.is(s) {
+		return p.errorf("'%s' expected", s)
+	}
+	return p.advance()
+}
+
+// expectMatch expects what, which closes who, opened at line.
+func (p *parser) expectMatch(what, who string, line int) error {
+	if p.is(what) {
+		return p.advance()
+	}
+	if line == p.tok.line {
+		return p.errorf("'%s' expected", what)
+	}
+	return p.errorf("'%s' expected (to close '%s' at line %d)", what, who, line)
+}
+
+func (p *parser) name() error {
+	if p.tok.kind != tName {
+		return p.errorf("<name> expected")
+	}
+	return p.advance()
+}
+
+func (p *parser) blockFollows() bool {
+	return p.tok.kind == tEOF || p.is("else") || p.is("elseif") || p.is("end") || p.is("until")
+}
+
+func (p *parser) block() error {
+	for !p.blockFollows() {
+		last, err := p.statement()
+		if err != nil {
+			return err
+		}
+		if _, err := p.accept(";"); err != nil {
+			return err
+		}
+		if last {
+			break
+		}
+	}
+	return nil
+}
+
+// loopBlock parses the body of a loop.
+func (p *parser) loopBlock() error {
+	p.fs.loops++
+	defer func() { p.fs.loops-- }()
+	return p.block()
+}
+
+// statement parses a statement, and returns whether it has to be the last one in its block.
+func (p *parser) statement() (bool, error) {
+	line := p.tok.line
+	switch {
+	case p.is("if"):
+		return false, p.ifStatement(line)
+	case p.is("while"):
+		return false, p.all(p.advance, p.expr, func() error { return p.expect("do") }, p.loopBlock,
+			func() error { return p.expectMatch("end", "while", line) })
+	case p.is("do"):
+		return false, p.all(p.advance, p.block, func() error { return p.expectMatch("end", "do", line) })
+	case p.is("for"):
+		return false, p.forStatement(line)
+	case p.is("repeat"):
+		return false, p.all(p.advance, p.loopBlock, func() error { return p.expectMatch("until", "repeat", line) },
+			p.expr)
+	case p.is("function"):
+		if err := p.advance(); err != nil {
+			return false, err
+		}
+		return false, p.all(p.funcName, func() error { return p.body(line) })
+	case p.is("local"):
+		if err := p.advance(); err != nil {
+			return false, err
+		}
+		if ok, err := p.accept("function"); err != nil || ok {
+			if err != nil {
+				return false, err
+			}
+			return false, p.all(p.name, func() error { return p.body(line) })
+		}
+		return false, p.localStatement()
+	case p.is("::"):
+		return false, p.all(p.advance, p.name, func() error { return p.expect("::") })
+	case p.is("goto"):
+		return false, p.all(p.advance, p.name)
+	case p.is("return"):
+		if err := p.advance(); err != nil {
+			return false, err
+		}
+		if !p.blockFollows() && !p.is(";") {
+			if err := p.exprList(); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case p.is("break"):
+		if p.fs.loops == 0 {
+			return false, p.errorf("no loop to break")
+		}
+		return true, p.advance()
+	default:
+		return false, p.exprStatement()
+	}
+}
+
+// all runs steps until one of them fails.
+func (p *parser) all(steps ...func() error) error {
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) ifStatement(line int) error {
+	// The "if" or "elseif", the condition, and the block after it.
+	thenBlock := func() error {
+		return p.all(p.advance, p.expr, func() error { return p.expect("then") }, p.block)
+	}
+	if err := thenBlock(); err != nil {
+		return err
+	}
+	for p.is("elseif") {
+		if err := thenBlock(); err != nil {
+			return err
+		}
+	}
+	if p.is("else") {
+		if err := p.all(p.advance, p.block); err != nil {
+			return err
+		}
+	}
+	return p.expectMatch("end", "if", line)
+}
+
+func (p *parser) forStatement(line int) error {
+	if err := p.all(p.advance, p.name); err != nil {
+		return err
+	}
+	switch {
+	case p.is("="):
+		if err := p.all(p.advance, p.expr, func() error { return p.expect(",") }, p.expr); err != nil {
+			return err
+		}
+		if ok, err := p.accept(","); err != nil {
+			return err
+		} else if ok {
+			if err := p.expr(); err != nil {
+				return err
+			}
+		}
+	case p.is(",") || p.is("in"):
+		for p.is(",") {
+			if err := p.all(p.advance, p.name); err != nil {
+				return err
+			}
+		}
+		if err := p.all(func() error { return p.expect("in") }, p.exprList); err != nil {
+			return err
+		}
+	default:
+		return p.errorf("'=' or 'in' expected")
+	}
+	return p.all(func() error { return p.expect("do") }, p.loopBlock,
+		func() error { return p.expectMatch("end", "for", line) })
+}
+
+// funcName parses the name of a function statement, such as "a.b.c" or "a.b:c".
+func (p *parser) funcName() error {
+	if err := p.name(); err != nil {
+		return err
+	}
+	for p.is(".") {
+		if err := p.all(p.advance, p.name); err != nil {
+			return err
+		}
+	}
+	if p.is(":") {
+		return p.all(p.advance, p.name)
+	}
+	return nil
+}
+
+func (p *parser) localStatement() error {
+	if err := p.name(); err != nil {
+		return err
+	}
+	for p.is(",") {
+		if err := p.all(p.advance, p.name); err != nil {
+			return err
+		}
+	}
+	if ok, err := p.accept("="); err != nil || !ok {
+		return err
+	}
+	return p.exprList()
+}
+
+// The kinds of expressions that matter to statements.
+type exprKind int
+
+const (
+	exprOther exprKind = iota
+	exprVar
+	exprCall
+)
+
+func (p *parser) exprStatement() error {
+	kind, err := p.suffixedExpr()
+	if err != nil {
+		return err
+	}
+	if !p.is("=") && !p.is(",") {
+		if kind != exprCall {
+			return p.errorf("syntax error")
+		}
+		return nil
+	}
+	for {
+		if kind != exprVar {
+			return p.errorf("syntax error")
+		}
+		if !p.is(",") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if kind, err = p.suffixedExpr(); err != nil {
+			return err
+		}
+	}
+	return p.all(func() error { return p.expect("=") }, p.exprList)
+}
+
+func (p *parser) exprList() error {
+	if err := p.expr(); err != nil {
+		return err
+	}
+	for p.is(",") {
+		if err := p.all(p.advance, p.expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) primaryExpr() (exprKind, error) {
+	switch {
+	case p.tok.kind == tName:
+		return exprVar, p.advance()
+	case p.is("("):
+		line := p.tok.line
+		return exprOther, p.all(p.advance, p.expr, func() error { return p.expectMatch(")", "(", line) })
+	default:
+		return exprOther, p.errorf("unexpected symbol")
+	}
+}
+
+func (p *parser) suffixedExpr() (exprKind, error) {
+	kind, err := p.primaryExpr()
+	if err != nil {
+		return kind, err
+	}
+	for {
+		switch {
+		case p.is("."):
+			kind, err = exprVar, p.all(p.advance, p.name)
+		case p.is("["):
+			kind, err = exprVar, p.all(p.advance, p.expr, func() error { return p.expect("]") })
+		case p.is(":"):
+			kind, err = exprCall, p.all(p.advance, p.name, p.funcArgs)
+		case p.is("(") || p.is("{") || p.tok.kind == tString:
+			kind, err = exprCall, p.funcArgs()
+		default:
+			return kind, nil
+		}
+		if err != nil {
+			return kind, err
+		}
+	}
+}
+
+func (p *parser) funcArgs() error {
+	switch {
+	case p.tok.kind == tString:
+		return p.advance()
+	case p.is("{"):
+		return p.table()
+	case p.is("("):
+		line := p.tok.line
+		if line != p.lastLine {
+			return p.errorf("ambiguous syntax (function call x new statement)")
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if !p.is(")") {
+			if err := p.exprList(); err != nil {
+				return err
+			}
+		}
+		return p.expectMatch(")", "(", line)
+	default:
+		return p.errorf("function arguments expected")
+	}
+}
+
+func (p *parser) table() error {
+	line := p.tok.line
+	if err := p.advance(); err != nil {
+		return err
+	}
+	for !p.is("}") {
+		if err := p.field(); err != nil {
+			return err
+		}
+		if !p.is(",") && !p.is(";") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	return p.expectMatch("}", "{", line)
+}
+
+func (p *parser) field() error {
+	switch {
+	case p.tok.kind == tName:
+		next, err := p.lookahead()
+		if err != nil {
+			return err
+		}
+		if next.kind == tOp && next.text == "=" {
+			return p.all(p.advance, p.advance, p.expr)
+		}
+		return p.expr()
+	case p.is("["):
+		return p.all(p.advance, p.expr, func() error { return p.expect("]") },
+			func() error { return p.expect("=") }, p.expr)
+	default:
+		return p.expr()
+	}
+}
+
+func (p *parser) body(line int) error {
+	fs := &funcState{}
+	outer := p.fs
+	p.fs = fs
+	defer func() { p.fs = outer }()
+
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	if !p.is(")") {
+		for {
+			if p.is("...") {
+				fs.vararg = true
+				if err := p.advance(); err != nil {
+					return err
+				}
+				break
+			}
+			if err := p.name(); err != nil {
+				return err
+			}
+			if ok, err := p.accept(","); err != nil {
+				return err
+			} else if !ok {
+				break
+			}
+		}
+	}
+	return p.all(func() error { return p.expect(")") }, p.block,
+		func() error { return p.expectMatch("end", "function", line) })
+}
+
+// Binary operators, with the priorities of their left and right operands, as in lparser.c.
+var binaryPriority = map[string][2]int{
+	"+": {6, 6}, "-": {6, 6}, "*": {7, 7}, "/": {7, 7}, "%": {7, 7},
+	"^":  {10, 9},
+	"..": {5, 4},
+	"==": {3, 3}, "~=": {3, 3}, "<": {3, 3}, "<=": {3, 3}, ">": {3, 3}, ">=": {3, 3},
+	"and": {2, 2},
+	"or":  {1, 1},
+}
+
+const unaryPriority = 8
+
+func (p *parser) expr() error {
+	return p.subExpr(0)
+}
+
+func (p *parser) subExpr(limit int) error {
+	if p.is("not") || p.is("-") || p.is("#") {
+		if err := p.all(p.advance, func() error { return p.subExpr(unaryPriority) }); err != nil {
+			return err
+		}
+	} else if err := p.simpleExpr(); err != nil {
+		return err
+	}
+	for {
+		prio, ok := binaryPriority[p.tok.text]
+		if !ok || (p.tok.kind != tOp && p.tok.kind != tKeyword) || prio[0] <= limit {
+			return nil
+		}
+		if err := p.all(p.advance, func() error { return p.subExpr(prio[1]) }); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) simpleExpr() error {
+	switch {
+	case p.tok.kind == tNumber || p.tok.kind == tString || p.is("nil") || p.is("true") || p.is("false"):
+		return p.advance()
+	case p.is("..."):
+		if !p.fs.vararg {
+			return p.errorf("cannot use '...' outside a vararg function")
+		}
+		return p.advance()
+	case p.is("{"):
+		return p.table()
+	case p.is("function"):
+		line := p.tok.line
+		return p.all(p.advance, func() error { return p.body(line) })
+	default:
+		_, err := p.suffixedExpr()
+		return err
+	}
+}
@@ -0,0 +1,85 @@
+package luacheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	valid := []string{
+		``,
+		`-- nothing but a comment`,
+		`function envoy_on_request(request_handle)
+  request_handle:headers():add("x-lua", "yes")
+end`,
+		`function envoy_on_response(response_handle)
+  local body = response_handle:body()
+  local size = body:length()
+  response_handle:headers():add("x-body-size", tostring(size))
+  if size > 1024 then
+    response_handle:logWarn("big body: " .. size)
+  elseif size == 0 then
+    return
+  else
+    response_handle:logDebug([[small
+body]])
+  end
+end`,
+		`local t = { 1, 2; x = 3, ["y"] = 4, f = function(...) return select("#", ...) end, }
+for i = 1, #t, 2 do t[i] = -t[i] ^ 2 end
+for k, v in pairs(t) do print(k, v) end
+while true do break end
+repeat local x = 1 until x == 1
+do local a, b = 0x1F, 1.5e-3 end`,
+		`local n = 0x1p-2 + 1LL + 2ULL + 3i
+local s = "tab\t\"quoted\" \65\x41\u{1F600}\z
+           continued"
+local l = [==[ a ]] b ]==]
+--[[ a
+long comment ]]
+--[=[ another ]=]
+goto done
+::done::
+a.b.c = f{1} .. g"x"
+obj:method "arg"`,
+	}
+	for _, src := range valid {
+		assert.NoError(t, Check("valid.lua", []byte(src)), src)
+	}
+
+	invalid := map[string]string{
+		"function envoy_on_request(h)\n  h:headers()\n": "bad.lua:3: 'end' expected (to close 'function' at line 1) near '<eof>'",
+		"if x then y() end end":                         "bad.lua:1: '<eof>' expected near 'end'",
+		"x = = 1":                                       "bad.lua:1: unexpected symbol near '='",
+		"f() = 1":                                       "bad.lua:1: syntax error near '='",
+		"x":                                             "bad.lua:1: syntax error near '<eof>'",
+		"local s = \"unterminated\nx = 1":               "bad.lua:1: unfinished string near '\"unterminated'",
+		"local s = [[ no end":                           "bad.lua:1: unfinished long string near '<eof>'",
+		"--[[ no end":                                   "bad.lua:1: unfinished long comment near '<eof>'",
+		"local n = 3x":                                  "bad.lua:1: malformed number near '3x'",
+		"local s = \"\\q\"":                             "bad.lua:1: invalid escape sequence near '\"\\q'",
+		"local s = \"\\300\"":                           "bad.lua:1: escape sequence too large near '\"\\300'",
+		"break":                                         "bad.lua:1: no loop to break near 'break'",
+		"function f() return ... end":                   "bad.lua:1: cannot use '...' outside a vararg function near '...'",
+		"for i do end":                                  "bad.lua:1: '=' or 'in' expected near 'do'",
+		"local function 1() end":                        "bad.lua:1: <name> expected near '1'",
+		"x = a\n(f)()":                                  "bad.lua:2: ambiguous syntax (function call x new statement) near '('",
+		"x = 1 ~ 2":                                     "bad.lua:1: unexpected symbol near '~'",
+		"t = { 1, 2\nx = 1":                             "bad.lua:2: '}' expected (to close '{' at line 1) near 'x'",
+		"return 1\nx = 2":                               "bad.lua:2: '<eof>' expected near 'x'",
+		"local s = [=x":                                 "bad.lua:1: invalid long string delimiter near '[='",
+		"while true do\n  if x then\n    y()\n  end\n": "bad.lua:5: 'end' expected (to close 'while' at line 1) near '<eof>'",
+		"repeat x() until":                                         "bad.lua:1: unexpected symbol near '<eof>'",
+		"function a.b:c(x, ..., y) end":                            "bad.lua:1: ')' expected near ','",
+		"local s = [[\nline 2\n]] x":                               "bad.lua:3: syntax error near '<eof>'",
+		"function envoy_on_request(h)\n  h:headers():add(\"a\")\n": "bad.lua:3: 'end' expected (to close 'function' at line 1) near '<eof>'",
+	}
+	for src, want := range invalid {
+		err := Check("bad.lua", []byte(src))
+		if assert.Error(t, err, src) {
+			assert.Equal(t, want, err.Error(), src)
+			assert.IsType(t, &Error{}, err, src)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package ratelimit turns the rate limit labels on Mappings into typed
+// descriptor builders, mirroring what Ambassador tells Envoy to do.
+// It's used to reject malformed labels when the snapshot is built
+// (rather than have diagd drop them later), and to work out which
+// descriptors a given request will produce.
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+// An ActionKind is the kind of an Envoy rate limit action.
+type ActionKind string
+
+const (
+	SourceCluster      ActionKind = "source_cluster"
+	DestinationCluster ActionKind = "destination_cluster"
+	RemoteAddress      ActionKind = "remote_address"
+	GenericKey         ActionKind = "generic_key"
+	RequestHeaders     ActionKind = "request_headers"
+)
+
+// An Entry is one key/value pair of a descriptor.
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// A Descriptor is what gets sent to the rate limit service.
+type Descriptor []Entry
+
+// An Action produces one Entry of a Descriptor.
+type Action struct {
+	Kind ActionKind `json:"kind"`
+	// DescriptorKey is the key of a RequestHeaders entry.
+	DescriptorKey string `json:"descriptor_key,omitempty"`
+	// HeaderName is the header that a RequestHeaders entry takes its
+	// value from.
+	HeaderName string `json:"header_name,omitempty"`
+	// Value is the value of a GenericKey entry.
+	Value string `json:"value,omitempty"`
+}
+
+// A Label is a named group of actions, all of which go into a single
+// Descriptor.
+type Label struct {
+	Domain  string   `json:"domain"`
+	Name    string   `json:"name"`
+	Actions []Action `json:"actions"`
+}
+
+// A Request holds the attributes of a request that actions draw on.
+type Request struct {
+	Headers            http.Header
+	RemoteAddress      string
+	SourceCluster      string
+	DestinationCluster string
+}
+
+// Descriptor builds the label's descriptor for r.  Like Envoy, it
+// produces no descriptor at all if a RequestHeaders action's header is
+// missing.
+func (l Label) Descriptor(r Request) (Descriptor, bool) {
+	descriptor := make(Descriptor, 0, len(l.Actions))
+	for _, action := range l.Actions {
+		var entry Entry
+		switch action.Kind {
+		case SourceCluster:
+			entry = Entry{Key: string(action.Kind), Value: r.SourceCluster}
+		case DestinationCluster:
+			entry = Entry{Key: string(action.Kind), Value: r.DestinationCluster}
+		case RemoteAddress:
+			entry = Entry{Key: string(action.Kind), Value: r.RemoteAddress}
+		case GenericKey:
+			entry = Entry{Key: string(action.Kind), Value: action.Value}
+		case RequestHeaders:
+			value := r.Headers.Get(action.HeaderName)
+			if value == "" {
+				return nil, false
+			}
+			entry = Entry{Key: action.DescriptorKey, Value: value}
+		}
+		descriptor = append(descriptor, entry)
+	}
+	return descriptor, true
+}
+
+// MappingLabels returns the labels for the given rate limit domain in
+// a Mapping's labels, in order.  It fails if any label is malformed.
+func MappingLabels(labels amb.DomainMap, domain string) ([]Label, error) {
+	var ret []Label
+	for i, group := range labels[domain] {
+		if len(group) != 1 {
+			return nil, fmt.Errorf("%s label %d: must have exactly one name, not %d", domain, i, len(group))
+		}
+		for name, rawActions := range group {
+			if rawActions.Labels == nil {
+				return nil, fmt.Errorf("%s label %q: must be a list of actions", domain, name)
+			}
+			label := Label{Domain: domain, Name: name}
+			for j, rawAction := range rawActions.Labels {
+				action, err := parseAction(rawAction)
+				if err != nil {
+					return nil, fmt.Errorf("%s label %q action %d: %v", domain, name, j, err)
+				}
+				label.Actions = append(label.Actions, action)
+			}
+			ret = append(ret, label)
+		}
+	}
+	return ret, nil
+}
+
+// ValidateMappingLabels checks the labels for every domain in a
+// Mapping's labels.
+func ValidateMappingLabels(labels amb.DomainMap) error {
+	domains := make([]string, 0, len(labels))
+	for domain := range labels {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	for _, domain := range domains {
+		if _, err := MappingLabels(labels, domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseAction parses an action in any of the forms that
+// V2RateLimitAction accepts.
+func parseAction(raw amb.StringOrMappingLabels) (Action, error) {
+	switch {
+	case raw.String != nil:
+		switch kind := ActionKind(*raw.String); kind {
+		case SourceCluster, DestinationCluster, RemoteAddress:
+			return Action{Kind: kind}, nil
+		default:
+			// Shorthand for a generic_key.
+			return Action{Kind: GenericKey, Value: *raw.String}, nil
+		}
+	case raw.Object != nil:
+		if len(raw.Object) != 1 {
+			return Action{}, fmt.Errorf("must have exactly one key, not %d", len(raw.Object))
+		}
+		for key, value := range raw.Object {
+			if key == string(GenericKey) {
+				if value.String == nil {
+					return Action{}, fmt.Errorf("generic_key must be a string")
+				}
+				return Action{Kind: GenericKey, Value: *value.String}, nil
+			}
+			// These need Envoy's v3 route config, which V2RateLimitAction
+			// doesn't generate, so it drops labels that use them.
+			if key == "masked_remote_address" {
+				return Action{}, fmt.Errorf("masked_remote_address is not supported by this Envoy")
+			}
+			if value.Object == nil {
+				return Action{}, fmt.Errorf("%q must be {header: name}", key)
+			}
+			if _, ok := value.Object["metadata"]; ok {
+				return Action{}, fmt.Errorf("dynamic metadata label %q is not supported by this Envoy", key)
+			}
+			if skip, ok := value.Object["skip_if_absent"]; ok && !(skip.Bool != nil && !*skip.Bool) {
+				return Action{}, fmt.Errorf("skip_if_absent on label %q is not supported by this Envoy", key)
+			}
+			header, ok := value.Object["header"]
+			if !ok || header.String == nil || *header.String == "" {
+				return Action{}, fmt.Errorf("%q must name a header", key)
+			}
+			return Action{Kind: RequestHeaders, DescriptorKey: key, HeaderName: *header.String}, nil
+		}
+	}
+	return Action{}, fmt.Errorf("must be a string or an object")
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+func parseLabels(t *testing.T, input string) amb.DomainMap {
+	var labels amb.DomainMap
+	require.NoError(t, json.Unmarshal([]byte(input), &labels))
+	return labels
+}
+
+func TestMappingLabels(t *testing.T) {
+	labels := parseLabels(t, `{
+		"ambassador": [
+			{"backend": ["remote_address", "qotm"]},
+			{"user": [
+				{"x-user": {"header": "x-user", "omit_if_not_present": true}},
+				{"generic_key": "per-user"}
+			]}
+		]
+	}`)
+
+	parsed, err := MappingLabels(labels, "ambassador")
+	require.NoError(t, err)
+	require.Len(t, parsed, 2)
+
+	assert.Equal(t, Label{
+		Domain: "ambassador",
+		Name:   "backend",
+		Actions: []Action{
+			{Kind: RemoteAddress},
+			{Kind: GenericKey, Value: "qotm"},
+		},
+	}, parsed[0])
+
+	assert.Equal(t, Label{
+		Domain: "ambassador",
+		Name:   "user",
+		Actions: []Action{
+			{Kind: RequestHeaders, DescriptorKey: "x-user", HeaderName: "x-user"},
+			{Kind: GenericKey, Value: "per-user"},
+		},
+	}, parsed[1])
+
+	req := Request{Headers: http.Header{}, RemoteAddress: "10.0.0.1"}
+
+	descriptor, ok := parsed[0].Descriptor(req)
+	assert.True(t, ok)
+	assert.Equal(t, Descriptor{{"remote_address", "10.0.0.1"}, {"generic_key", "qotm"}}, descriptor)
+
+	_, ok = parsed[1].Descriptor(req)
+	assert.False(t, ok)
+
+	req.Headers.Set("X-User", "alice")
+	descriptor, ok = parsed[1].Descriptor(req)
+	assert.True(t, ok)
+	assert.Equal(t, Descriptor{{"x-user", "alice"}, {"generic_key", "per-user"}}, descriptor)
+
+	// Labels for other domains aren't looked at.
+	parsed, err = MappingLabels(labels, "other")
+	assert.NoError(t, err)
+	assert.Empty(t, parsed)
+}
+
+func TestMappingLabelsMaskedRemoteAddressShorthand(t *testing.T) {
+	// A bare string that isn't an action is a generic_key, even if it
+	// names an action this Envoy doesn't have.
+	labels := parseLabels(t, `{"ambassador": [{"subnet": ["masked_remote_address"]}]}`)
+
+	parsed, err := MappingLabels(labels, "ambassador")
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	assert.Equal(t, []Action{{Kind: GenericKey, Value: "masked_remote_address"}}, parsed[0].Actions)
+}
+
+func TestValidateMappingLabels(t *testing.T) {
+	for _, bad := range []string{
+		`{"ambassador": [{"a": ["x"], "b": ["y"]}]}`,
+		`{"ambassador": [{"a": "not-a-list"}]}`,
+		`{"ambassador": [{"a": [{"x-user": {"heder": "x-user"}}]}]}`,
+		`{"ambassador": [{"a": [{"x-user": "x-user"}]}]}`,
+		`{"ambassador": [{"a": [{"generic_key": ["x"]}]}]}`,
+		`{"ambassador": [{"a": [{"x": {"header": "x"}, "y": {"header": "y"}}]}]}`,
+		// These need Envoy's v3 route config.
+		`{"ambassador": [{"a": [{"x": {"header": "x", "skip_if_absent": true}}]}]}`,
+		`{"ambassador": [{"a": [{"x": {"metadata": {"filter": "f", "path": ["x"]}}}]}]}`,
+		`{"ambassador": [{"a": [{"masked_remote_address": {"v4_prefix_mask_len": 24}}]}]}`,
+	} {
+		assert.Error(t, ValidateMappingLabels(parseLabels(t, bad)), bad)
+	}
+
+	assert.NoError(t, ValidateMappingLabels(nil))
+	assert.NoError(t, ValidateMappingLabels(parseLabels(t,
+		`{"ambassador": [{"a": [{"x": {"header": "x", "skip_if_absent": false}}]}]}`)))
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/config/core/v3"
+	rls "github.com/datawire/ambassador/pkg/api/envoy/service/ratelimit/v3"
+)
+
+// The X-RateLimit headers from draft 03 of "RateLimit Header Fields for
+// HTTP" (draft-polli-ratelimit-headers-03).
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderReset     = "X-RateLimit-Reset"
+)
+
+var unitSeconds = map[rls.RateLimitResponse_RateLimit_Unit]int64{
+	rls.RateLimitResponse_RateLimit_SECOND: 1,
+	rls.RateLimitResponse_RateLimit_MINUTE: 60,
+	rls.RateLimitResponse_RateLimit_HOUR:   60 * 60,
+	rls.RateLimitResponse_RateLimit_DAY:    24 * 60 * 60,
+}
+
+// XRateLimitHeaders returns the draft 03 X-RateLimit headers for the
+// descriptor statuses of a rate limit service response, or nil if no
+// status has a current limit.
+//
+// The Envoy we ship can't generate these headers itself, but it does
+// add a RateLimitResponse's response_headers_to_add to the response
+// to the client, so a rate limit service can use this to send them.
+// As in Envoy, the headers describe the status closest to being
+// limited, and windows are assumed to be aligned to the unit, since
+// the v3 protocol doesn't say when a limit resets.
+func XRateLimitHeaders(statuses []*rls.RateLimitResponse_DescriptorStatus, now time.Time) []*core.HeaderValue {
+	var closest *rls.RateLimitResponse_DescriptorStatus
+	var policies []string
+
+	for _, status := range statuses {
+		limit := status.GetCurrentLimit()
+		window, ok := unitSeconds[limit.GetUnit()]
+		if !ok {
+			continue
+		}
+		policies = append(policies, fmt.Sprintf("%d;w=%d", limit.GetRequestsPerUnit(), window))
+		if closest == nil || status.GetLimitRemaining() < closest.GetLimitRemaining() {
+			closest = status
+		}
+	}
+
+	if closest == nil {
+		return nil
+	}
+
+	window := unitSeconds[closest.GetCurrentLimit().GetUnit()]
+	reset := window - now.Unix()%window
+
+	return []*core.HeaderValue{
+		{
+			Key:   HeaderLimit,
+			Value: strings.Join(append([]string{strconv.FormatUint(uint64(closest.GetCurrentLimit().GetRequestsPerUnit()), 10)}, policies...), ", "),
+		},
+		{Key: HeaderRemaining, Value: strconv.FormatUint(uint64(closest.GetLimitRemaining()), 10)},
+		{Key: HeaderReset, Value: strconv.FormatInt(reset, 10)},
+	}
+}
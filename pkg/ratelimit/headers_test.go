@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/config/core/v3"
+	rls "github.com/datawire/ambassador/pkg/api/envoy/service/ratelimit/v3"
+)
+
+func TestXRateLimitHeaders(t *testing.T) {
+	// 20 seconds into a minute.
+	now := time.Unix(1600000040, 0)
+
+	statuses := []*rls.RateLimitResponse_DescriptorStatus{
+		{
+			Code: rls.RateLimitResponse_OK,
+			CurrentLimit: &rls.RateLimitResponse_RateLimit{
+				RequestsPerUnit: 100,
+				Unit:            rls.RateLimitResponse_RateLimit_HOUR,
+			},
+			LimitRemaining: 50,
+		},
+		{
+			Code: rls.RateLimitResponse_OK,
+			CurrentLimit: &rls.RateLimitResponse_RateLimit{
+				RequestsPerUnit: 10,
+				Unit:            rls.RateLimitResponse_RateLimit_MINUTE,
+			},
+			LimitRemaining: 3,
+		},
+		// No limit applies to this one.
+		{Code: rls.RateLimitResponse_OK},
+	}
+
+	assert.Equal(t, []*core.HeaderValue{
+		{Key: HeaderLimit, Value: "10, 100;w=3600, 10;w=60"},
+		{Key: HeaderRemaining, Value: "3"},
+		{Key: HeaderReset, Value: "40"},
+	}, XRateLimitHeaders(statuses, now))
+
+	assert.Nil(t, XRateLimitHeaders(statuses[2:], now))
+}
@@ -0,0 +1,271 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	ratelimitv3 "github.com/datawire/ambassador/pkg/api/envoy/extensions/common/ratelimit/v3"
+	rlsv2 "github.com/datawire/ambassador/pkg/api/envoy/service/ratelimit/v2"
+	rls "github.com/datawire/ambassador/pkg/api/envoy/service/ratelimit/v3"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+var units = map[string]rls.RateLimitResponse_RateLimit_Unit{
+	"second": rls.RateLimitResponse_RateLimit_SECOND,
+	"minute": rls.RateLimitResponse_RateLimit_MINUTE,
+	"hour":   rls.RateLimitResponse_RateLimit_HOUR,
+	"day":    rls.RateLimitResponse_RateLimit_DAY,
+}
+
+type limit struct {
+	name            string
+	descriptor      []amb.RateLimitPolicyEntry
+	requestsPerUnit uint32
+	unit            rls.RateLimitResponse_RateLimit_Unit
+	shadow          bool
+}
+
+// LimitStats counts the requests that a limit has seen.
+type LimitStats struct {
+	// Name is "policy.namespace/limit", or "policy.namespace/N" for
+	// the Nth limit of a policy if the limit has no name.
+	Name string `json:"name"`
+	// Hits is the number of requests that matched the limit.
+	Hits uint64 `json:"hits"`
+	// OverLimit is the number of requests that were limited.
+	OverLimit uint64 `json:"over_limit"`
+	// ShadowOverLimit is the number of requests that would have been
+	// limited if the limit weren't in shadow mode.
+	ShadowOverLimit uint64 `json:"shadow_over_limit"`
+}
+
+func (l *limit) matches(descriptor *ratelimitv3.RateLimitDescriptor) bool {
+	entries := descriptor.GetEntries()
+	if len(entries) != len(l.descriptor) {
+		return false
+	}
+	for i, entry := range entries {
+		if entry.GetKey() != l.descriptor[i].Key {
+			return false
+		}
+		if l.descriptor[i].Value != "" && entry.GetValue() != l.descriptor[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// Service is a rate limit service, which speaks both the v2 and v3
+// protocols, for the limits in a set of RateLimitPolicies.
+type Service struct {
+	// SendXRateLimitHeaders makes the service ask Envoy to send
+	// X-RateLimit headers to clients; see XRateLimitHeaders.
+	SendXRateLimitHeaders bool
+
+	store  Store
+	limits atomic.Value // map[string][]*limit, by domain
+	now    func() time.Time
+
+	statsMu sync.Mutex
+	stats   map[string]*LimitStats // by limit name; kept across SetPolicies
+}
+
+// NewService returns a Service, with no limits, that keeps its counters
+// in store.
+func NewService(store Store) *Service {
+	s := &Service{store: store, now: time.Now, stats: map[string]*LimitStats{}}
+	s.limits.Store(map[string][]*limit{})
+	return s
+}
+
+// SetPolicies replaces the Service's limits with the ones in policies.
+// Policies for the same domain are checked in order of name and
+// namespace.  If any policy is invalid, the limits are left alone.
+// Limits in policies with ShadowMode set never limit anything; they
+// just log and count the requests that they would have limited.
+func (s *Service) SetPolicies(policies []*amb.RateLimitPolicy) error {
+	sorted := make([]*amb.RateLimitPolicy, len(policies))
+	copy(sorted, policies)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].GetName() != sorted[j].GetName() {
+			return sorted[i].GetName() < sorted[j].GetName()
+		}
+		return sorted[i].GetNamespace() < sorted[j].GetNamespace()
+	})
+
+	limits := map[string][]*limit{}
+	for _, policy := range sorted {
+		domain := policy.Spec.Domain
+		if domain == "" {
+			domain = "ambassador"
+		}
+		for i, l := range policy.Spec.Limits {
+			unit, ok := units[l.Unit]
+			if !ok {
+				return fmt.Errorf("RateLimitPolicy %s.%s: limit %d: bad unit %q", policy.GetName(), policy.GetNamespace(), i, l.Unit)
+			}
+			if len(l.Descriptor) == 0 {
+				return fmt.Errorf("RateLimitPolicy %s.%s: limit %d: empty descriptor", policy.GetName(), policy.GetNamespace(), i)
+			}
+			name := l.Name
+			if name == "" {
+				name = fmt.Sprint(i)
+			}
+			limits[domain] = append(limits[domain], &limit{
+				name:            fmt.Sprintf("%s.%s/%s", policy.GetName(), policy.GetNamespace(), name),
+				descriptor:      l.Descriptor,
+				requestsPerUnit: l.RequestsPerUnit,
+				unit:            unit,
+				shadow:          policy.Spec.ShadowMode,
+			})
+		}
+	}
+
+	s.limits.Store(limits)
+	return nil
+}
+
+// ShouldRateLimit implements the v3 RateLimitServiceServer interface.
+func (s *Service) ShouldRateLimit(ctx context.Context, req *rls.RateLimitRequest) (*rls.RateLimitResponse, error) {
+	hits := req.GetHitsAddend()
+	if hits == 0 {
+		hits = 1
+	}
+
+	limits := s.limits.Load().(map[string][]*limit)[req.GetDomain()]
+	now := s.now()
+
+	resp := &rls.RateLimitResponse{OverallCode: rls.RateLimitResponse_OK}
+	for _, descriptor := range req.GetDescriptors() {
+		status := &rls.RateLimitResponse_DescriptorStatus{Code: rls.RateLimitResponse_OK}
+		resp.Statuses = append(resp.Statuses, status)
+
+		var l *limit
+		for _, candidate := range limits {
+			if candidate.matches(descriptor) {
+				l = candidate
+				break
+			}
+		}
+		if l == nil {
+			continue
+		}
+
+		window := unitSeconds[l.unit]
+		start := now.Unix() - now.Unix()%window
+		count, err := s.store.Incr(ctx, counterKey(req.GetDomain(), descriptor, start), hits, time.Duration(window)*time.Second)
+		if err != nil {
+			// Envoy's failure_mode_deny decides what happens now.
+			return nil, err
+		}
+
+		status.CurrentLimit = &rls.RateLimitResponse_RateLimit{RequestsPerUnit: l.requestsPerUnit, Unit: l.unit}
+		over := count > uint64(l.requestsPerUnit)
+		s.count(l, over)
+		switch {
+		case over && l.shadow:
+			dlog.Infof(ctx, "shadow mode: limit %s would have limited %s", l.name, counterKey(req.GetDomain(), descriptor, start))
+		case over:
+			status.Code = rls.RateLimitResponse_OVER_LIMIT
+			resp.OverallCode = rls.RateLimitResponse_OVER_LIMIT
+		default:
+			status.LimitRemaining = l.requestsPerUnit - uint32(count)
+		}
+	}
+
+	if s.SendXRateLimitHeaders {
+		resp.ResponseHeadersToAdd = XRateLimitHeaders(resp.Statuses, now)
+	}
+
+	return resp, nil
+}
+
+func (s *Service) count(l *limit, over bool) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	stats, ok := s.stats[l.name]
+	if !ok {
+		stats = &LimitStats{Name: l.name}
+		s.stats[l.name] = stats
+	}
+	stats.Hits++
+	switch {
+	case over && l.shadow:
+		stats.ShadowOverLimit++
+	case over:
+		stats.OverLimit++
+	}
+}
+
+// Stats returns the counts for every limit that has seen a request,
+// sorted by name.
+func (s *Service) Stats() []LimitStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	ret := make([]LimitStats, 0, len(s.stats))
+	for _, stats := range s.stats {
+		ret = append(ret, *stats)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+func counterKey(domain string, descriptor *ratelimitv3.RateLimitDescriptor, start int64) string {
+	parts := []string{domain}
+	for _, entry := range descriptor.GetEntries() {
+		parts = append(parts, entry.GetKey()+"="+entry.GetValue())
+	}
+	parts = append(parts, fmt.Sprint(start))
+	return strings.Join(parts, "|")
+}
+
+// Register registers the Service with server for both the v2 and v3
+// protocols.
+func (s *Service) Register(server *grpc.Server) {
+	rls.RegisterRateLimitServiceServer(server, s)
+	rlsv2.RegisterRateLimitServiceServer(server, v2Service{s})
+}
+
+// v2Service adapts a Service to the v2 protocol.  The v2 and v3
+// messages are wire-compatible, so it converts by round-tripping
+// through the wire format.
+type v2Service struct {
+	*Service
+}
+
+func (s v2Service) ShouldRateLimit(ctx context.Context, req *rlsv2.RateLimitRequest) (*rlsv2.RateLimitResponse, error) {
+	var reqV3 rls.RateLimitRequest
+	if err := convertMessage(req, &reqV3); err != nil {
+		return nil, err
+	}
+
+	respV3, err := s.Service.ShouldRateLimit(ctx, &reqV3)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp rlsv2.RateLimitResponse
+	if err := convertMessage(respV3, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func convertMessage(in, out proto.Message) error {
+	bytes, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(bytes, out)
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ratelimitv2 "github.com/datawire/ambassador/pkg/api/envoy/api/v2/ratelimit"
+	ratelimitv3 "github.com/datawire/ambassador/pkg/api/envoy/extensions/common/ratelimit/v3"
+	rlsv2 "github.com/datawire/ambassador/pkg/api/envoy/service/ratelimit/v2"
+	rls "github.com/datawire/ambassador/pkg/api/envoy/service/ratelimit/v3"
+	amb "github.com/datawire/ambassador/pkg/api/getambassador.io/v2"
+)
+
+func descriptor(kv ...string) *ratelimitv3.RateLimitDescriptor {
+	d := &ratelimitv3.RateLimitDescriptor{}
+	for i := 0; i < len(kv); i += 2 {
+		d.Entries = append(d.Entries, &ratelimitv3.RateLimitDescriptor_Entry{Key: kv[i], Value: kv[i+1]})
+	}
+	return d
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1600000040, 0)
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	s := NewService(store)
+	s.now = func() time.Time { return now }
+
+	policy := &amb.RateLimitPolicy{
+		Spec: amb.RateLimitPolicySpec{
+			Limits: []amb.RateLimitPolicyLimit{
+				{
+					Descriptor:      []amb.RateLimitPolicyEntry{{Key: "generic_key", Value: "backend"}, {Key: "x-user"}},
+					RequestsPerUnit: 2,
+					Unit:            "minute",
+				},
+			},
+		},
+	}
+	policy.SetName("per-user")
+	require.NoError(t, s.SetPolicies([]*amb.RateLimitPolicy{policy}))
+
+	check := func(user string) *rls.RateLimitResponse {
+		resp, err := s.ShouldRateLimit(ctx, &rls.RateLimitRequest{
+			Domain: "ambassador",
+			Descriptors: []*ratelimitv3.RateLimitDescriptor{
+				descriptor("generic_key", "backend", "x-user", user),
+				descriptor("generic_key", "other"),
+			},
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := check("alice")
+	assert.Equal(t, rls.RateLimitResponse_OK, resp.OverallCode)
+	assert.Equal(t, uint32(1), resp.Statuses[0].LimitRemaining)
+	assert.Nil(t, resp.Statuses[1].CurrentLimit)
+
+	assert.Equal(t, rls.RateLimitResponse_OK, check("alice").OverallCode)
+	assert.Equal(t, rls.RateLimitResponse_OVER_LIMIT, check("alice").OverallCode)
+
+	// Every user gets their own counter...
+	assert.Equal(t, rls.RateLimitResponse_OK, check("bob").OverallCode)
+
+	// ...and every window starts over.
+	now = now.Add(time.Minute)
+	assert.Equal(t, rls.RateLimitResponse_OK, check("alice").OverallCode)
+
+	// The v2 protocol works too.
+	s.SendXRateLimitHeaders = true
+	respV2, err := v2Service{s}.ShouldRateLimit(ctx, &rlsv2.RateLimitRequest{
+		Domain: "ambassador",
+		Descriptors: []*ratelimitv2.RateLimitDescriptor{
+			{Entries: []*ratelimitv2.RateLimitDescriptor_Entry{
+				{Key: "generic_key", Value: "backend"},
+				{Key: "x-user", Value: "carol"},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rlsv2.RateLimitResponse_OK, respV2.OverallCode)
+	assert.Len(t, respV2.Headers, 3)
+
+	assert.Equal(t, []LimitStats{{Name: "per-user./0", Hits: 6, OverLimit: 1}}, s.Stats())
+
+	// In shadow mode, nothing gets limited, but it still gets counted.
+	policy.Spec.ShadowMode = true
+	require.NoError(t, s.SetPolicies([]*amb.RateLimitPolicy{policy}))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, rls.RateLimitResponse_OK, check("alice").OverallCode)
+	}
+	assert.Equal(t, []LimitStats{{Name: "per-user./0", Hits: 9, OverLimit: 1, ShadowOverLimit: 2}}, s.Stats())
+
+	bad := &amb.RateLimitPolicy{Spec: amb.RateLimitPolicySpec{
+		Limits: []amb.RateLimitPolicyLimit{{Descriptor: policy.Spec.Limits[0].Descriptor, Unit: "fortnight"}},
+	}}
+	assert.Error(t, s.SetPolicies([]*amb.RateLimitPolicy{bad}))
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/sharedstate"
+)
+
+// A Store keeps the counters for the built-in rate limit service.
+// Counters are for fixed windows: the caller puts the window's start in
+// the key, and the Store forgets the key once the window is over.
+type Store interface {
+	// Incr adds hits to the counter for key, creating it if need be,
+	// and returns the new count.  The counter expires after window.
+	Incr(ctx context.Context, key string, hits uint32, window time.Duration) (uint64, error)
+}
+
+// MemoryStore is a Store for a single replica of the rate limit
+// service.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*memoryCounter
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+type memoryCounter struct {
+	count   uint64
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*memoryCounter),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Incr(_ context.Context, key string, hits uint32, window time.Duration) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.nextSweep) {
+		for k, counter := range s.counters {
+			if now.After(counter.expires) {
+				delete(s.counters, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Second)
+	}
+
+	counter, ok := s.counters[key]
+	if !ok || now.After(counter.expires) {
+		counter = &memoryCounter{expires: now.Add(window)}
+		s.counters[key] = counter
+	}
+	counter.count += uint64(hits)
+	return counter.count, nil
+}
+
+// RedisStore is a Store that keeps its counters in Redis, so that they
+// can be shared by several replicas of the rate limit service.
+type RedisStore = sharedstate.RedisStore
+
+// RedisOptions say how a RedisStore connects: with a password, over
+// TLS, and how many idle connections it keeps.
+type RedisOptions = sharedstate.RedisOptions
+
+// NewRedisStore returns a RedisStore for the Redis server at addr.  It
+// doesn't connect until it's first used.
+func NewRedisStore(addr string, options RedisOptions) *RedisStore {
+	return sharedstate.NewRedisStore(addr, options)
+}
@@ -0,0 +1,210 @@
+package sharedstate
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// configMapAttempts is how many times a ConfigMapStore tries a change that conflicts with
+// another replica's.
+const configMapAttempts = 5
+
+// configMapClient is the part of a *kates.Client that a ConfigMapStore uses.
+type configMapClient interface {
+	Get(ctx context.Context, resource interface{}, target interface{}) error
+	Create(ctx context.Context, resource interface{}, target interface{}) error
+	Update(ctx context.Context, resource interface{}, target interface{}) error
+}
+
+// ConfigMapStore is a Store that keeps its state in a Kubernetes ConfigMap, which it creates if
+// need be. Every change is a read and a write of the whole ConfigMap, and the API server's
+// resourceVersion check keeps replicas from overwriting each other's changes.
+type ConfigMapStore struct {
+	client    configMapClient
+	name      string
+	namespace string
+	now       func() time.Time
+}
+
+// configMapEntry is a value in the ConfigMap's data, as JSON. Keys are base64-encoded, since
+// ConfigMaps only allow some characters in theirs.
+type configMapEntry struct {
+	Value   string `json:"value"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// NewConfigMapStore returns a ConfigMapStore that keeps its state in the ConfigMap named name in
+// namespace.
+func NewConfigMapStore(client *kates.Client, name, namespace string) *ConfigMapStore {
+	return &ConfigMapStore{client: client, name: name, namespace: namespace, now: time.Now}
+}
+
+func (s *ConfigMapStore) configMap() *kates.ConfigMap {
+	return &kates.ConfigMap{
+		TypeMeta:   kates.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: kates.ObjectMeta{Name: s.name, Namespace: s.namespace},
+	}
+}
+
+func configMapKey(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// entry returns the unexpired entry at key in data.
+func (s *ConfigMapStore) entry(data map[string]string, key string, now time.Time) (configMapEntry, bool) {
+	var entry configMapEntry
+	raw, ok := data[configMapKey(key)]
+	if !ok || json.Unmarshal([]byte(raw), &entry) != nil {
+		return configMapEntry{}, false
+	}
+	if entry.Expires != "" {
+		expires, err := time.Parse(time.RFC3339Nano, entry.Expires)
+		if err != nil || !now.Before(expires) {
+			return configMapEntry{}, false
+		}
+	}
+	return entry, true
+}
+
+func (s *ConfigMapStore) setEntry(data map[string]string, key, value string, ttl time.Duration, now time.Time) {
+	putConfigMapEntry(data, key, newConfigMapEntry(value, ttl, now))
+}
+
+func newConfigMapEntry(value string, ttl time.Duration, now time.Time) configMapEntry {
+	entry := configMapEntry{Value: value}
+	if ttl > 0 {
+		entry.Expires = now.Add(ttl).UTC().Format(time.RFC3339Nano)
+	}
+	return entry
+}
+
+func putConfigMapEntry(data map[string]string, key string, entry configMapEntry) {
+	raw, _ := json.Marshal(entry)
+	data[configMapKey(key)] = string(raw)
+}
+
+// modify reads the ConfigMap, drops its expired entries, hands its data to change, and writes it
+// back if anything changed, starting over if another replica changed it first.
+func (s *ConfigMapStore) modify(ctx context.Context, change func(data map[string]string, now time.Time) (bool, error)) error {
+	for attempt := 1; ; attempt++ {
+		cm := s.configMap()
+		err := s.client.Get(ctx, cm, cm)
+		exists := err == nil
+		if err != nil {
+			if !kates.IsNotFound(err) {
+				return err
+			}
+			cm = s.configMap()
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+
+		now := s.now()
+		changed := false
+		for k := range cm.Data {
+			key, err := base64.RawURLEncoding.DecodeString(k)
+			if err != nil {
+				continue
+			}
+			if _, ok := s.entry(cm.Data, string(key), now); !ok {
+				delete(cm.Data, k)
+				changed = true
+			}
+		}
+		c, err := change(cm.Data, now)
+		if err != nil {
+			return err
+		}
+		if !c && !changed {
+			return nil
+		}
+
+		if exists {
+			err = s.client.Update(ctx, cm, nil)
+		} else {
+			err = s.client.Create(ctx, cm, nil)
+		}
+		if err == nil || attempt == configMapAttempts || !(kates.IsConflict(err) || kates.IsAlreadyExists(err)) {
+			return err
+		}
+	}
+}
+
+func (s *ConfigMapStore) Incr(ctx context.Context, key string, delta uint32, ttl time.Duration) (uint64, error) {
+	var count uint64
+	err := s.modify(ctx, func(data map[string]string, now time.Time) (bool, error) {
+		entry, ok := s.entry(data, key, now)
+		if !ok {
+			entry = newConfigMapEntry("0", ttl, now)
+		}
+		var err error
+		count, err = strconv.ParseUint(entry.Value, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("%s is not a counter", key)
+		}
+		count += uint64(delta)
+		entry.Value = strconv.FormatUint(count, 10)
+		putConfigMapEntry(data, key, entry)
+		return true, nil
+	})
+	return count, err
+}
+
+func (s *ConfigMapStore) Get(ctx context.Context, key string) (string, bool, error) {
+	cm := s.configMap()
+	if err := s.client.Get(ctx, cm, cm); err != nil {
+		if kates.IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	entry, ok := s.entry(cm.Data, key, s.now())
+	return entry.Value, ok, nil
+}
+
+func (s *ConfigMapStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.modify(ctx, func(data map[string]string, now time.Time) (bool, error) {
+		s.setEntry(data, key, value, ttl, now)
+		return true, nil
+	})
+}
+
+func (s *ConfigMapStore) Delete(ctx context.Context, key string) error {
+	return s.modify(ctx, func(data map[string]string, now time.Time) (bool, error) {
+		if _, ok := data[configMapKey(key)]; !ok {
+			return false, nil
+		}
+		delete(data, configMapKey(key))
+		return true, nil
+	})
+}
+
+func (s *ConfigMapStore) Claim(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	var claimed bool
+	err := s.modify(ctx, func(data map[string]string, now time.Time) (bool, error) {
+		entry, ok := s.entry(data, key, now)
+		claimed = !ok || entry.Value == holder
+		if claimed {
+			s.setEntry(data, key, holder, ttl, now)
+		}
+		return claimed, nil
+	})
+	return claimed && err == nil, err
+}
+
+func (s *ConfigMapStore) Release(ctx context.Context, key, holder string) error {
+	return s.modify(ctx, func(data map[string]string, now time.Time) (bool, error) {
+		if entry, ok := s.entry(data, key, now); !ok || entry.Value != holder {
+			return false, nil
+		}
+		delete(data, configMapKey(key))
+		return true, nil
+	})
+}
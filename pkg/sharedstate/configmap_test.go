@@ -0,0 +1,123 @@
+package sharedstate
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// fakeConfigMaps keeps one ConfigMap, and checks resourceVersions like the API server. Before
+// each write, it calls interfere, which can change the ConfigMap as another replica would.
+type fakeConfigMaps struct {
+	cm        *kates.ConfigMap
+	writes    int
+	interfere func(cm *kates.ConfigMap)
+}
+
+var configMapsResource = schema.GroupResource{Resource: "configmaps"}
+
+func (c *fakeConfigMaps) Get(_ context.Context, resource interface{}, target interface{}) error {
+	if c.cm == nil {
+		return apierrors.NewNotFound(configMapsResource, resource.(*kates.ConfigMap).GetName())
+	}
+	*target.(*kates.ConfigMap) = *c.cm.DeepCopy()
+	return nil
+}
+
+func (c *fakeConfigMaps) write(cm *kates.ConfigMap) {
+	c.writes++
+	cm = cm.DeepCopy()
+	cm.SetResourceVersion(strconv.Itoa(c.writes))
+	c.cm = cm
+}
+
+func (c *fakeConfigMaps) beforeWrite() {
+	if c.interfere != nil && c.cm != nil {
+		interfere := c.interfere
+		c.interfere = nil
+		cm := c.cm.DeepCopy()
+		interfere(cm)
+		c.write(cm)
+	}
+}
+
+func (c *fakeConfigMaps) Create(_ context.Context, resource interface{}, _ interface{}) error {
+	c.beforeWrite()
+	cm := resource.(*kates.ConfigMap)
+	if c.cm != nil {
+		return apierrors.NewAlreadyExists(configMapsResource, cm.GetName())
+	}
+	c.write(cm)
+	return nil
+}
+
+func (c *fakeConfigMaps) Update(_ context.Context, resource interface{}, _ interface{}) error {
+	c.beforeWrite()
+	cm := resource.(*kates.ConfigMap)
+	if c.cm == nil {
+		return apierrors.NewNotFound(configMapsResource, cm.GetName())
+	}
+	if cm.GetResourceVersion() != c.cm.GetResourceVersion() {
+		return apierrors.NewConflict(configMapsResource, cm.GetName(), errors.New("the object has been modified"))
+	}
+	c.write(cm)
+	return nil
+}
+
+func newTestConfigMapStore(client *fakeConfigMaps, clock *fakeClock) *ConfigMapStore {
+	return &ConfigMapStore{client: client, name: "ambassador-shared-state", namespace: "ambassador", now: clock.Now}
+}
+
+func TestConfigMapStore(t *testing.T) {
+	clock := newFakeClock()
+	testStore(t, newTestConfigMapStore(&fakeConfigMaps{}, clock), clock)
+}
+
+func TestConfigMapStoreConflicts(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	client := &fakeConfigMaps{}
+	a := newTestConfigMapStore(client, clock)
+	b := newTestConfigMapStore(client, clock)
+
+	_, err := a.Incr(ctx, "counter", 1, time.Minute)
+	require.NoError(t, err)
+
+	// b claims the lease between a's read and a's write, so a has to read it again, and finds
+	// that b holds it.
+	client.interfere = func(cm *kates.ConfigMap) {
+		b.setEntry(cm.Data, "leader", "pod-b", time.Minute, clock.Now())
+	}
+	claimed, err := a.Claim(ctx, "leader", "pod-a", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	// Neither replica's changes are lost.
+	count, err := b.Incr(ctx, "counter", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+	value, _, err := a.Get(ctx, "leader")
+	require.NoError(t, err)
+	assert.Equal(t, "pod-b", value)
+
+	// Nothing is written if nothing changes.
+	writes := client.writes
+	require.NoError(t, a.Release(ctx, "leader", "pod-a"))
+	assert.Equal(t, writes, client.writes)
+
+	// Keys that ConfigMaps don't allow are fine.
+	require.NoError(t, a.Set(ctx, "ratelimit:x-user=alice/1600000000", "1", 0))
+	value, ok, err := b.Get(ctx, "ratelimit:x-user=alice/1600000000")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+}
@@ -0,0 +1,75 @@
+package sharedstate
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+// A Lease picks one of the replicas that share a Store to do something that only one of them
+// should. Each replica runs a Lease for the same key, with its own holder; the one that claims
+// the key first holds the Lease, and renews it every third of its ttl. If it goes away without
+// releasing the Lease, another replica gets it once it expires.
+type Lease struct {
+	store  Store
+	key    string
+	holder string
+	ttl    time.Duration
+
+	held int32
+}
+
+// NewLease returns a Lease on key for holder. Nothing is claimed until it's Run.
+func NewLease(store Store, key, holder string, ttl time.Duration) *Lease {
+	return &Lease{store: store, key: key, holder: holder, ttl: ttl}
+}
+
+// Held returns whether the Lease was held when it was last claimed. It isn't held if the claim
+// failed, so that no two replicas think that they hold it when the Store can't be reached.
+func (l *Lease) Held() bool {
+	return atomic.LoadInt32(&l.held) == 1
+}
+
+// Run claims the Lease every third of its ttl until ctx is done, then releases it, so that
+// another replica can take over without waiting for it to expire.
+func (l *Lease) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		held, err := l.store.Claim(ctx, l.key, l.holder, l.ttl)
+		if err != nil {
+			dlog.Warnf(ctx, "Claiming lease %s: %v", l.key, err)
+			held = false
+		}
+		l.setHeld(ctx, held)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			l.setHeld(ctx, false)
+			release, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+			defer cancel()
+			if err := l.store.Release(release, l.key, l.holder); err != nil {
+				dlog.Warnf(ctx, "Releasing lease %s: %v", l.key, err)
+			}
+			return
+		}
+	}
+}
+
+func (l *Lease) setHeld(ctx context.Context, held bool) {
+	var value int32
+	if held {
+		value = 1
+	}
+	if atomic.SwapInt32(&l.held, value) != value {
+		if held {
+			dlog.Infof(ctx, "%s now holds lease %s", l.holder, l.key)
+		} else {
+			dlog.Infof(ctx, "%s no longer holds lease %s", l.holder, l.key)
+		}
+	}
+}
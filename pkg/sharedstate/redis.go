@@ -0,0 +1,302 @@
+package sharedstate
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The scripts that make Incr, Claim and Release atomic: Redis runs a script without running
+// anything else in the middle of it. Incr sets the counter's expiry only if it has none, which
+// is when INCRBY has just created it, so that the counter resets ttl after it was created.
+const (
+	redisIncrScript = `local count = redis.call('INCRBY', KEYS[1], ARGV[1])
+if ARGV[2] ~= '0' and redis.call('PTTL', KEYS[1]) == -1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return count`
+
+	redisClaimScript = `local v = redis.call('GET', KEYS[1])
+if v == false or v == ARGV[1] then
+  if ARGV[2] == '0' then
+    redis.call('SET', KEYS[1], ARGV[1])
+  else
+    redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  end
+  return 1
+end
+return 0`
+
+	redisReleaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+// DefaultRedisPoolSize is how many idle connections a RedisStore keeps, unless its RedisOptions
+// say otherwise.
+const DefaultRedisPoolSize = 8
+
+// RedisOptions say how a RedisStore connects to Redis.
+type RedisOptions struct {
+	// Password, if it's set, is sent with AUTH on every new connection.
+	Password string
+
+	// TLS, if it's set, is the TLS configuration to connect with. If it has no ServerName,
+	// the host in the address is used.
+	TLS *tls.Config
+
+	// PoolSize is how many idle connections to keep for later commands. Zero means
+	// DefaultRedisPoolSize.
+	PoolSize int
+}
+
+// RedisStore is a Store that keeps its state in Redis, so that it can be shared by any number
+// of replicas.  It speaks just enough of the Redis protocol for the commands that it needs.
+// Each command gets a connection of its own, from a pool of idle ones, so that goroutines don't
+// wait on one another.
+type RedisStore struct {
+	Addr    string
+	Options RedisOptions
+
+	idle chan *redisConn
+}
+
+// A redisConn is one connection to Redis.
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewRedisStore returns a RedisStore for the Redis server at addr.  It doesn't connect until
+// it's first used.
+func NewRedisStore(addr string, options RedisOptions) *RedisStore {
+	size := options.PoolSize
+	if size <= 0 {
+		size = DefaultRedisPoolSize
+	}
+	return &RedisStore{Addr: addr, Options: options, idle: make(chan *redisConn, size)}
+}
+
+func (s *RedisStore) Incr(ctx context.Context, key string, delta uint32, ttl time.Duration) (uint64, error) {
+	ms := "0"
+	if ttl > 0 {
+		ms = redisMilliseconds(ttl)
+	}
+	replies, err := s.do(ctx, []string{"EVAL", redisIncrScript, "1", key, strconv.FormatUint(uint64(delta), 10), ms})
+	if err != nil {
+		return 0, err
+	}
+	count, ok := replies[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: INCRBY: unexpected reply %v", replies[0])
+	}
+	return uint64(count), nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (string, bool, error) {
+	replies, err := s.do(ctx, []string{"GET", key})
+	if err != nil {
+		return "", false, err
+	}
+	if replies[0] == nil {
+		return "", false, nil
+	}
+	value, ok := replies[0].(string)
+	if !ok {
+		return "", false, fmt.Errorf("redis: GET: unexpected reply %v", replies[0])
+	}
+	return value, true, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	cmd := []string{"SET", key, value}
+	if ttl > 0 {
+		cmd = append(cmd, "PX", redisMilliseconds(ttl))
+	}
+	_, err := s.do(ctx, cmd)
+	return err
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, []string{"DEL", key})
+	return err
+}
+
+func (s *RedisStore) Claim(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	ms := "0"
+	if ttl > 0 {
+		ms = redisMilliseconds(ttl)
+	}
+	replies, err := s.do(ctx, []string{"EVAL", redisClaimScript, "1", key, holder, ms})
+	if err != nil {
+		return false, err
+	}
+	return replies[0] == int64(1), nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key, holder string) error {
+	_, err := s.do(ctx, []string{"EVAL", redisReleaseScript, "1", key, holder})
+	return err
+}
+
+// redisMilliseconds returns ttl in milliseconds, rounded up, since Redis doesn't take zero.
+func redisMilliseconds(ttl time.Duration) string {
+	return strconv.FormatInt(int64((ttl+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+// do sends the commands, all at once, on a connection from the pool, and returns their replies.
+// A reply is an int64, a string, or nil for Redis's null.
+func (s *RedisStore) do(ctx context.Context, cmds ...[]string) ([]interface{}, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := conn.roundTrip(ctx, cmds)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// We don't know what state the connection is in, so don't use it again.
+		conn.conn.Close()
+	} else {
+		s.release(conn)
+	}
+	return replies, err
+}
+
+// conn returns an idle connection, or a new one if there aren't any.
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.idle:
+		return conn, nil
+	default:
+	}
+
+	var dialer net.Dialer
+	raw, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: raw}
+	if s.Options.TLS != nil {
+		config := s.Options.TLS.Clone()
+		if config.ServerName == "" {
+			host, _, err := net.SplitHostPort(s.Addr)
+			if err != nil {
+				raw.Close()
+				return nil, err
+			}
+			config.ServerName = host
+		}
+		conn.conn = tls.Client(raw, config)
+	}
+	conn.rd = bufio.NewReader(conn.conn)
+
+	if s.Options.Password != "" {
+		if _, err := conn.roundTrip(ctx, [][]string{{"AUTH", s.Options.Password}}); err != nil {
+			conn.conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// release puts conn back in the pool, or closes it if the pool is full.
+func (s *RedisStore) release(conn *redisConn) {
+	select {
+	case s.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (c *redisConn) roundTrip(ctx context.Context, cmds [][]string) ([]interface{}, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	} else if err := c.conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	var buf strings.Builder
+	for _, cmd := range cmds {
+		buf.WriteString(redisCommand(cmd...))
+	}
+	if _, err := c.conn.Write([]byte(buf.String())); err != nil {
+		return nil, err
+	}
+
+	// Read every reply, even after an error reply, so that the connection can be used again.
+	replies := make([]interface{}, len(cmds))
+	var replyErr error
+	for i := range cmds {
+		reply, err := readRedisReply(c.rd)
+		if err != nil {
+			if _, ok := err.(redisError); !ok {
+				return nil, err
+			}
+			if replyErr == nil {
+				replyErr = err
+			}
+		}
+		replies[i] = reply
+	}
+	return replies, replyErr
+}
+
+func redisCommand(args ...string) string {
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return cmd.String()
+}
+
+// redisError is an error reply from Redis, which leaves the connection usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func readRedisReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk reply %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,263 @@
+package sharedstate
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis answers the commands that a RedisStore sends from a MemoryStore, on a random port,
+// until it's closed. If password isn't empty, connections have to AUTH with it first. If
+// config isn't nil, they have to use TLS.
+func fakeRedis(t *testing.T, store *MemoryStore, password string, config *tls.Config) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if config != nil {
+		listener = tls.NewListener(listener, config)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeRedis(conn, store, password)
+		}
+	}()
+	return listener
+}
+
+func serveFakeRedis(conn net.Conn, store *MemoryStore, password string) {
+	defer conn.Close()
+	ctx := context.Background()
+	rd := bufio.NewReader(conn)
+	authed := password == ""
+
+	for {
+		cmd, err := readFakeRedisCommand(rd)
+		if err != nil {
+			return
+		}
+		ms := func(arg string) time.Duration {
+			n, _ := strconv.ParseInt(arg, 10, 64)
+			return time.Duration(n) * time.Millisecond
+		}
+
+		var reply string
+		switch {
+		case cmd[0] == "AUTH":
+			authed = len(cmd) == 2 && cmd[1] == password
+			if authed {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid username-password pair\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd[0] == "GET":
+			if value, ok, _ := store.Get(ctx, cmd[1]); ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case cmd[0] == "SET":
+			var ttl time.Duration
+			if len(cmd) == 5 {
+				ttl = ms(cmd[4])
+			}
+			store.Set(ctx, cmd[1], cmd[2], ttl)
+			reply = "+OK\r\n"
+		case cmd[0] == "DEL":
+			store.Delete(ctx, cmd[1])
+			reply = ":1\r\n"
+		case cmd[0] == "EVAL":
+			switch cmd[1] {
+			case redisIncrScript:
+				delta, _ := strconv.ParseUint(cmd[4], 10, 32)
+				count, _ := store.Incr(ctx, cmd[3], uint32(delta), ms(cmd[5]))
+				reply = fmt.Sprintf(":%d\r\n", count)
+			case redisClaimScript:
+				claimed, _ := store.Claim(ctx, cmd[3], cmd[4], ms(cmd[5]))
+				if claimed {
+					reply = ":1\r\n"
+				} else {
+					reply = ":0\r\n"
+				}
+			case redisReleaseScript:
+				store.Release(ctx, cmd[3], cmd[4])
+				reply = ":0\r\n"
+			default:
+				reply = "-NOSCRIPT unknown script\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readFakeRedisCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	cmd := make([]string, n)
+	for i := range cmd {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(data[:size])
+	}
+	return cmd, nil
+}
+
+func TestRedisStore(t *testing.T) {
+	clock := newFakeClock()
+	backing := NewMemoryStore()
+	backing.now = clock.Now
+
+	redis := fakeRedis(t, backing, "", nil)
+	defer redis.Close()
+
+	store := NewRedisStore(redis.Addr().String(), RedisOptions{})
+	testStore(t, store, clock)
+}
+
+func TestRedisStoreErrorReply(t *testing.T) {
+	ctx := context.Background()
+	redis := fakeRedis(t, NewMemoryStore(), "", nil)
+	defer redis.Close()
+
+	store := NewRedisStore(redis.Addr().String(), RedisOptions{})
+
+	// An error reply fails the command, and leaves the connection usable.
+	_, err := store.do(ctx, []string{"FLUSHALL"})
+	require.EqualError(t, err, "redis: ERR unknown command")
+	require.Len(t, store.idle, 1)
+	conn := <-store.idle
+	store.idle <- conn
+	require.NoError(t, store.Set(ctx, "key", "value", 0))
+	require.True(t, conn == <-store.idle, "the connection was dropped")
+}
+
+func TestRedisStorePool(t *testing.T) {
+	ctx := context.Background()
+	redis := fakeRedis(t, NewMemoryStore(), "", nil)
+	defer redis.Close()
+
+	store := NewRedisStore(redis.Addr().String(), RedisOptions{PoolSize: 2})
+
+	// Goroutines don't share a connection, and the pool keeps only PoolSize of them.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Incr(ctx, "counter", 1, time.Minute)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	count, err := store.Incr(ctx, "counter", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(21), count)
+	assert.True(t, len(store.idle) >= 1 && len(store.idle) <= 2, "%d idle connections", len(store.idle))
+}
+
+func TestRedisStoreAuth(t *testing.T) {
+	ctx := context.Background()
+	redis := fakeRedis(t, NewMemoryStore(), "hunter2", nil)
+	defer redis.Close()
+
+	store := NewRedisStore(redis.Addr().String(), RedisOptions{Password: "hunter2"})
+	require.NoError(t, store.Set(ctx, "key", "value", 0))
+	value, ok, err := store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+
+	// A connection that can't AUTH isn't kept.
+	wrong := NewRedisStore(redis.Addr().String(), RedisOptions{Password: "hunter3"})
+	_, _, err = wrong.Get(ctx, "key")
+	assert.EqualError(t, err, "redis: WRONGPASS invalid username-password pair")
+	assert.Len(t, wrong.idle, 0)
+
+	none := NewRedisStore(redis.Addr().String(), RedisOptions{})
+	_, _, err = none.Get(ctx, "key")
+	assert.EqualError(t, err, "redis: NOAUTH Authentication required.")
+}
+
+func TestRedisStoreTLS(t *testing.T) {
+	ctx := context.Background()
+	cert, pool := selfSignedCert(t, "127.0.0.1")
+	redis := fakeRedis(t, NewMemoryStore(), "", &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer redis.Close()
+
+	store := NewRedisStore(redis.Addr().String(), RedisOptions{TLS: &tls.Config{RootCAs: pool}})
+	require.NoError(t, store.Set(ctx, "key", "value", 0))
+	value, _, err := store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	// The server's certificate is checked against the host in the address.
+	untrusted := NewRedisStore(redis.Addr().String(), RedisOptions{TLS: &tls.Config{}})
+	_, _, err = untrusted.Get(ctx, "key")
+	assert.Error(t, err)
+}
+
+// selfSignedCert returns a certificate for host, and a pool that trusts it.
+func selfSignedCert(t *testing.T, host string) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		IPAddresses:  []net.IP{net.ParseIP(host)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
@@ -0,0 +1,150 @@
+// Package sharedstate keeps state that the replicas of an Ambassador share, so that they can
+// divide up work instead of each doing all of it: counters, like the rate limit service's,
+// values that expire, and leases that pick the one replica that does something.
+//
+// A MemoryStore is only shared within one replica. A RedisStore keeps its state in Redis. A
+// ConfigMapStore keeps it in a Kubernetes ConfigMap, so it needs nothing but the API server, but
+// every change is a write to the API server: it's for leases and the like, not for counting
+// requests.
+package sharedstate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Store is state shared by the replicas that use it. Every method is atomic, however many
+// replicas call it at once. A ttl of zero means that a value never expires.
+type Store interface {
+	// Incr adds delta to the counter at key, starting it at zero if need be, and returns the
+	// new count. The counter expires ttl after it's started, however often it's incremented
+	// in the meantime, so that it counts one window of time.
+	Incr(ctx context.Context, key string, delta uint32, ttl time.Duration) (uint64, error)
+
+	// Get returns the value at key, and whether there is one.
+	Get(ctx context.Context, key string) (string, bool, error)
+
+	// Set sets the value at key, to expire after ttl.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+
+	// Delete removes the value at key, if there is one.
+	Delete(ctx context.Context, key string) error
+
+	// Claim sets the value at key to holder, to expire after ttl, if there is no value or the
+	// value is already holder, and returns whether it did.
+	Claim(ctx context.Context, key, holder string, ttl time.Duration) (bool, error)
+
+	// Release removes the value at key if it's holder, so that someone else can claim it
+	// without waiting for it to expire.
+	Release(ctx context.Context, key, holder string) error
+}
+
+// MemoryStore is a Store for a single replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		now:     time.Now,
+	}
+}
+
+// get returns the entry at key, forgetting it if it's expired. The caller must hold mu.
+func (s *MemoryStore) get(key string, now time.Time) (memoryEntry, bool) {
+	entry, ok := s.entries[key]
+	if ok && !entry.expires.IsZero() && !now.Before(entry.expires) {
+		delete(s.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, ok
+}
+
+// set sets the entry at key. The caller must hold mu.
+func (s *MemoryStore) set(key, value string, ttl time.Duration, now time.Time) {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+	s.entries[key] = entry
+}
+
+func (s *MemoryStore) Incr(_ context.Context, key string, delta uint32, ttl time.Duration) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	entry, ok := s.get(key, now)
+	var count uint64
+	if ok {
+		var err error
+		count, err = strconv.ParseUint(entry.value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a counter", key)
+		}
+	} else if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+	count += uint64(delta)
+	entry.value = strconv.FormatUint(count, 10)
+	s.entries[key] = entry
+	return count, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.get(key, s.now())
+	return entry.value, ok, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(key, value, ttl, s.now())
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) Claim(_ context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if entry, ok := s.get(key, now); ok && entry.value != holder {
+		return false, nil
+	}
+	s.set(key, holder, ttl, now)
+	return true, nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.get(key, s.now()); ok && entry.value == holder {
+		delete(s.entries, key)
+	}
+	return nil
+}
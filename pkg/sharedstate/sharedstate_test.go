@@ -0,0 +1,144 @@
+package sharedstate
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock for stores that the test moves. Stores can read it from other
+// goroutines, like fakeRedis's.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1600000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// testStore checks the behavior that every Store has to have, with clock as the store's clock.
+func testStore(t *testing.T, store Store, clock *fakeClock) {
+	ctx := context.Background()
+
+	_, ok, err := store.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set(ctx, "acme/token", "key-authorization", time.Minute))
+	value, ok, err := store.Get(ctx, "acme/token")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "key-authorization", value)
+
+	count, err := store.Incr(ctx, "counter", 2, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+	count, err = store.Incr(ctx, "counter", 3, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), count)
+
+	claimed, err := store.Claim(ctx, "leader", "pod-a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = store.Claim(ctx, "leader", "pod-b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed, "pod-b claimed pod-a's lease")
+	claimed, err = store.Claim(ctx, "leader", "pod-a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed, "pod-a couldn't renew its lease")
+
+	// Only the holder can release.
+	require.NoError(t, store.Release(ctx, "leader", "pod-b"))
+	value, _, err = store.Get(ctx, "leader")
+	require.NoError(t, err)
+	assert.Equal(t, "pod-a", value)
+	require.NoError(t, store.Release(ctx, "leader", "pod-a"))
+	claimed, err = store.Claim(ctx, "leader", "pod-b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	require.NoError(t, store.Delete(ctx, "acme/token"))
+	_, ok, err = store.Get(ctx, "acme/token")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Everything expires.
+	require.NoError(t, store.Set(ctx, "forever", "yes", 0))
+	clock.Add(2 * time.Minute)
+	_, ok, err = store.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	claimed, err = store.Claim(ctx, "leader", "pod-a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed, "pod-b's lease didn't expire")
+	_, ok, err = store.Get(ctx, "forever")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// A counter expires ttl after it's started, not after it's last incremented.
+	count, err = store.Incr(ctx, "window", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+	clock.Add(40 * time.Second)
+	count, err = store.Incr(ctx, "window", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+	clock.Add(40 * time.Second)
+	count, err = store.Incr(ctx, "window", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), count, "the counter didn't reset")
+}
+
+func TestMemoryStore(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMemoryStore()
+	store.now = clock.Now
+
+	testStore(t, store, clock)
+}
+
+func TestLease(t *testing.T) {
+	store := NewMemoryStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := NewLease(store, "leader", "pod-a", 30*time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, a.Held, time.Second, time.Millisecond)
+
+	bctx, bcancel := context.WithCancel(context.Background())
+	defer bcancel()
+	b := NewLease(store, "leader", "pod-b", 30*time.Millisecond)
+	go b.Run(bctx)
+
+	// pod-a keeps renewing, so pod-b never gets it.
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, a.Held())
+	assert.False(t, b.Held())
+
+	// When pod-a goes away, it hands the Lease over.
+	cancel()
+	<-done
+	assert.False(t, a.Held())
+	require.Eventually(t, b.Held, time.Second, time.Millisecond)
+}
@@ -0,0 +1,169 @@
+// Package sigv4 signs requests to AWS with Signature Version 4.  We
+// don't have the AWS SDK among our dependencies, and the few AWS APIs
+// that we call don't need more than this.
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Credentials are what a request is signed with.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// CredentialsFromEnv returns the credentials in the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN
+// environment variables.
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// Sign adds a Signature Version 4 Authorization header to req, whose
+// body is body, for service in region.  The only headers that it
+// signs are Host and the X-Amz-* headers that it sets itself.
+func Sign(req *http.Request, body []byte, creds Credentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := HashHex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := []string{req.URL.Host, payloadHash, amzDate}
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+		headers = append(headers, "x-amz-security-token")
+		values = append(values, creds.SessionToken)
+	}
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	signature := signature(creds.SecretAccessKey, amzDate, region, service,
+		canonicalRequest(req, headers, values, payloadHash))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, strings.Join(headers, ";"), signature))
+}
+
+// canonicalRequest returns the canonical form of req, which signs the
+// headers (lowercase, and sorted) with the values.
+func canonicalRequest(req *http.Request, headers, values []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for i, header := range headers {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", header, values[i])
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.RawQuery),
+		canonicalHeaders.String(),
+		strings.Join(headers, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// signature signs a canonical request made at amzDate.
+func signature(secret, amzDate, region, service, canonicalRequest string) string {
+	date := amzDate[:len("20060102")]
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service),
+		HashHex([]byte(canonicalRequest)),
+	}, "\n")
+	return hex.EncodeToString(hmacSHA256(SigningKey(secret, date, region, service), stringToSign))
+}
+
+// canonicalQuery returns a query as Signature Version 4 signs it:
+// every name and value URI-encoded, and sorted by name, then by value.
+// Whatever the query has already encoded is decoded first, so that it
+// isn't encoded twice.
+func canonicalQuery(rawQuery string) string {
+	var params [][2]string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, value := param, ""
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			name, value = param[:i], param[i+1:]
+		}
+		params = append(params, [2]string{uriEncode(queryUnescape(name)), uriEncode(queryUnescape(value))})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+	pairs := make([]string, len(params))
+	for i, param := range params {
+		pairs[i] = param[0] + "=" + param[1]
+	}
+	return strings.Join(pairs, "&")
+}
+
+func queryUnescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// uriEncode percent-encodes every byte of s but the unreserved
+// characters, A-Z, a-z, 0-9, "-", ".", "_" and "~", in uppercase hex.
+func uriEncode(s string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// SigningKey derives the key that signs requests for service in
+// region on date (as YYYYMMDD) from a secret access key.
+func SigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// HashHex returns the SHA-256 of data in hex, as the
+// X-Amz-Content-Sha256 header has it.
+func HashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
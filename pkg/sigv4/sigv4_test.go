@@ -0,0 +1,73 @@
+package sigv4
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningKey(t *testing.T) {
+	// The example from AWS's documentation for deriving a signing key.
+	key := SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+func TestSign(t *testing.T) {
+	body := []byte("Action=DescribeTargetHealth")
+	req, err := http.NewRequest(http.MethodPost, "https://elasticloadbalancing.eu-west-1.amazonaws.com", nil)
+	require.NoError(t, err)
+
+	now := time.Date(2020, 9, 1, 12, 30, 0, 0, time.UTC)
+	Sign(req, body, Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, "eu-west-1", "elasticloadbalancing", now)
+
+	assert.Equal(t, "20200901T123000Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, HashHex(body), req.Header.Get("X-Amz-Content-Sha256"))
+	assert.Empty(t, req.Header.Get("X-Amz-Security-Token"))
+
+	auth := req.Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(auth,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20200901/eu-west-1/elasticloadbalancing/aws4_request, "+
+			"SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="), auth)
+
+	// The same request signed again has the same signature.
+	again, err := http.NewRequest(http.MethodPost, "https://elasticloadbalancing.eu-west-1.amazonaws.com", nil)
+	require.NoError(t, err)
+	Sign(again, body, Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, "eu-west-1", "elasticloadbalancing", now)
+	assert.Equal(t, auth, again.Header.Get("Authorization"))
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	for query, canonical := range map[string]string{
+		"":                                   "",
+		"Version=2015-12-01&Action=Describe": "Action=Describe&Version=2015-12-01",
+		"a=2&a=1&a-b=0":                      "a=1&a=2&a-b=0",
+		"Names.member.1=my%20tg&x=a+b":       "Names.member.1=my%20tg&x=a%20b",
+		"arn=arn:aws:elb/tg/1&empty":         "arn=arn%3Aaws%3Aelb%2Ftg%2F1&empty=",
+	} {
+		assert.Equal(t, canonical, canonicalQuery(query), query)
+	}
+}
+
+// The get-vanilla and get-vanilla-query-* requests of AWS's Signature Version 4 test
+// suite, which sign only Host and X-Amz-Date, with the suite's
+// credentials.
+func TestSignatureTestSuite(t *testing.T) {
+	for path, want := range map[string]string{
+		"/":                             "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		"/?Param2=value2&Param1=value1": "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		"/?ሴ=bar":                       "2cdec8eed098649ff3a119c94853b13c643bcf08f8b0a1d91e12c9027818dd04",
+		"/?-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz=-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz": "9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197",
+	} {
+		req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com"+path, nil)
+		require.NoError(t, err)
+		canonical := canonicalRequest(req, []string{"host", "x-amz-date"},
+			[]string{"example.amazonaws.com", "20150830T123600Z"}, HashHex(nil))
+		assert.Equal(t, want, signature(
+			"wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830T123600Z", "us-east-1", "service", canonical), path)
+	}
+}
@@ -0,0 +1,191 @@
+package statsmap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Dashboard is a Grafana dashboard, as Grafana imports it.
+type Dashboard struct {
+	Title         string            `json:"title"`
+	Description   string            `json:"description"`
+	Tags          []string          `json:"tags"`
+	Editable      bool              `json:"editable"`
+	Refresh       string            `json:"refresh"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Time          map[string]string `json:"time"`
+	Panels        []Panel           `json:"panels"`
+}
+
+// A Panel is a row or a graph on a Dashboard.
+type Panel struct {
+	ID         int      `json:"id"`
+	Type       string   `json:"type"`
+	Title      string   `json:"title"`
+	Datasource string   `json:"datasource,omitempty"`
+	GridPos    GridPos  `json:"gridPos"`
+	Collapsed  *bool    `json:"collapsed,omitempty"`
+	Targets    []Target `json:"targets,omitempty"`
+	YAxes      []YAxis  `json:"yaxes,omitempty"`
+	Legend     *Legend  `json:"legend,omitempty"`
+	Tooltip    *Tooltip `json:"tooltip,omitempty"`
+	Lines      bool     `json:"lines,omitempty"`
+	Linewidth  int      `json:"linewidth,omitempty"`
+	Fill       int      `json:"fill,omitempty"`
+	XAxis      *XAxis   `json:"xaxis,omitempty"`
+}
+
+// GridPos is where a Panel is on a Dashboard, which is 24 units wide.
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// A Target is a Prometheus query of a graph.
+type Target struct {
+	Expr         string `json:"expr"`
+	Format       string `json:"format"`
+	LegendFormat string `json:"legendFormat"`
+	RefID        string `json:"refId"`
+}
+
+// A YAxis is one of the two y axes of a graph.
+type YAxis struct {
+	Format  string `json:"format"`
+	Label   string `json:"label,omitempty"`
+	LogBase int    `json:"logBase"`
+	Show    bool   `json:"show"`
+}
+
+// An XAxis is the x axis of a graph.
+type XAxis struct {
+	Mode string `json:"mode"`
+	Show bool   `json:"show"`
+}
+
+// A Legend is the legend of a graph.
+type Legend struct {
+	Show bool `json:"show"`
+}
+
+// A Tooltip is how a graph shows the values under the mouse.
+type Tooltip struct {
+	Shared    bool   `json:"shared"`
+	ValueType string `json:"value_type"`
+}
+
+// NewDashboard builds a Dashboard with a row for each virtual cluster in entries, with graphs of
+// its request rate, its latency, and its 4xx and 5xx responses, from the Prometheus datasource.
+// Every Mapping that shares a virtual cluster, e.g. the Mappings of a canary group, shares its
+// row, as does every host that a Mapping is served on.
+func NewDashboard(title, datasource string, entries []Entry) *Dashboard {
+	mappings := make(map[string][]string)
+	hosts := make(map[string][]string)
+	var vclusters []string
+	for _, entry := range entries {
+		if _, ok := mappings[entry.VirtualCluster]; !ok {
+			vclusters = append(vclusters, entry.VirtualCluster)
+		}
+		mappings[entry.VirtualCluster] = appendNew(mappings[entry.VirtualCluster], entry.Mapping)
+		hosts[entry.VirtualCluster] = appendNew(hosts[entry.VirtualCluster], entry.Host)
+	}
+	sort.Strings(vclusters)
+
+	d := &Dashboard{
+		Title:         title,
+		Description:   "Request rate, latency and errors of each Ambassador Mapping with per-Mapping statistics",
+		Tags:          []string{"ambassador"},
+		Editable:      true,
+		Refresh:       "30s",
+		SchemaVersion: 20,
+		Time:          map[string]string{"from": "now-1h", "to": "now"},
+	}
+
+	id := 0
+	nextID := func() int {
+		id++
+		return id
+	}
+	collapsed := false
+	for i, vcluster := range vclusters {
+		y := i * 9
+		selector := fmt.Sprintf(`envoy_virtual_cluster=%q`, vcluster)
+
+		d.Panels = append(d.Panels, Panel{
+			ID:        nextID(),
+			Type:      "row",
+			Title:     fmt.Sprintf("%s (%s)", strings.Join(mappings[vcluster], ", "), strings.Join(hosts[vcluster], ", ")),
+			GridPos:   GridPos{H: 1, W: 24, X: 0, Y: y},
+			Collapsed: &collapsed,
+		})
+		d.Panels = append(d.Panels,
+			graph(nextID(), "Requests per second", datasource, GridPos{H: 8, W: 8, X: 0, Y: y + 1}, "reqps",
+				Target{
+					Expr:         fmt.Sprintf(`sum(rate(envoy_vhost_vcluster_upstream_rq_total{%s}[1m]))`, selector),
+					LegendFormat: "requests",
+				}),
+			graph(nextID(), "Latency", datasource, GridPos{H: 8, W: 8, X: 8, Y: y + 1}, "ms",
+				latency(selector, "0.5", "p50"), latency(selector, "0.95", "p95"), latency(selector, "0.99", "p99")),
+			graph(nextID(), "Errors per second", datasource, GridPos{H: 8, W: 8, X: 16, Y: y + 1}, "reqps",
+				errors(selector, "4"), errors(selector, "5")),
+		)
+	}
+	return d
+}
+
+// graph returns a graph Panel of targets, in unit.
+func graph(id int, title, datasource string, pos GridPos, unit string, targets ...Target) Panel {
+	for i := range targets {
+		targets[i].Format = "time_series"
+		targets[i].RefID = string(rune('A' + i))
+	}
+	return Panel{
+		ID:         id,
+		Type:       "graph",
+		Title:      title,
+		Datasource: datasource,
+		GridPos:    pos,
+		Targets:    targets,
+		YAxes: []YAxis{
+			{Format: unit, LogBase: 1, Show: true},
+			{Format: "short", LogBase: 1, Show: false},
+		},
+		Legend:    &Legend{Show: true},
+		Tooltip:   &Tooltip{Shared: true, ValueType: "individual"},
+		Lines:     true,
+		Linewidth: 1,
+		Fill:      1,
+		XAxis:     &XAxis{Mode: "time", Show: true},
+	}
+}
+
+// latency returns a Target for a quantile of the request latency of selector.
+func latency(selector, quantile, legend string) Target {
+	return Target{
+		Expr: fmt.Sprintf(`histogram_quantile(%s, sum(rate(envoy_vhost_vcluster_upstream_rq_time_bucket{%s}[5m])) by (le))`,
+			quantile, selector),
+		LegendFormat: legend,
+	}
+}
+
+// errors returns a Target for the rate of the responses of selector in a class, like "5" for 5xx.
+func errors(selector, class string) Target {
+	return Target{
+		Expr: fmt.Sprintf(`sum(rate(envoy_vhost_vcluster_upstream_rq_xx{%s, envoy_response_code_class="%s"}[1m]))`,
+			selector, class),
+		LegendFormat: class + "xx",
+	}
+}
+
+// appendNew appends s to list, unless it's already there.
+func appendNew(list []string, s string) []string {
+	for _, have := range list {
+		if have == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
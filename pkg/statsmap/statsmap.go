@@ -0,0 +1,141 @@
+// Package statsmap works out which of Envoy's statistics belong to which Mapping, from the
+// per-Mapping statistics in diagd's diagnostics, and builds a Grafana dashboard with the request
+// rate, latency and errors of each Mapping from them.
+//
+// Per-Mapping statistics come from Envoy virtual clusters, named by a Mapping's stats_name or by
+// the ambassador Module's per_mapping_stats. Envoy's default tag extraction turns their
+// statistics, vhost.<virtual host>.vcluster.<virtual cluster>.<stat>, into Prometheus metrics
+// named envoy_vhost_vcluster_<stat>, with envoy_virtual_host and envoy_virtual_cluster labels.
+package statsmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// An Entry says which Mapping, on which host, a set of Envoy statistics belongs to.
+type Entry struct {
+	// StatPrefix is the prefix of the statistics in Envoy, e.g.
+	// "vhost.ambassador-listener-8080-*.vcluster.quote-backend.".
+	StatPrefix string `json:"stat_prefix"`
+	// VirtualHost and VirtualCluster are the envoy_virtual_host and envoy_virtual_cluster
+	// labels of the statistics in Prometheus.
+	VirtualHost    string `json:"virtual_host"`
+	VirtualCluster string `json:"virtual_cluster"`
+	// Mapping is the Mapping, as <name>.<namespace>.
+	Mapping string `json:"mapping"`
+	// Host is the host that the virtual host serves, "*" for any.
+	Host string `json:"host"`
+	// Prefix and Service are the Mapping's, if diagd knows them.
+	Prefix  string `json:"prefix,omitempty"`
+	Service string `json:"service,omitempty"`
+}
+
+// diag is the part of diagd's diagnostics that we need.
+type diag struct {
+	MappingStats map[string][]string `json:"mapping_stats"`
+	Groups       map[string]struct {
+		Mappings []struct {
+			Name           string `json:"name"`
+			Namespace      string `json:"namespace"`
+			Prefix         string `json:"prefix"`
+			ClusterService string `json:"cluster_service"`
+		} `json:"mappings"`
+	} `json:"groups"`
+}
+
+// FetchDiag fetches the diagnostics, as JSON, from diagd at diagURL, e.g. http://127.0.0.1:8877.
+func FetchDiag(ctx context.Context, diagURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(diagURL, "/")+"/ambassador/v0/diag/?json=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// The listener name that diagd puts in front of the host in a virtual host's name, and the
+// suffix it adds when it has to make a virtual host serve "*" instead of its own host.
+var (
+	listenerPrefixRE = regexp.MustCompile(`^ambassador-listener-\d+-`)
+	forcedStarSuffix = "-forced-star"
+)
+
+// ParseDiag reads the Entries from diagd's diagnostics, sorted by Mapping and then by host.
+// Mappings without per-Mapping statistics have none.
+func ParseDiag(data []byte) ([]Entry, error) {
+	var d diag
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+
+	type route struct{ prefix, service string }
+	routes := make(map[string]route)
+	for _, group := range d.Groups {
+		for _, m := range group.Mappings {
+			routes[m.Name+"."+m.Namespace] = route{prefix: m.Prefix, service: m.ClusterService}
+		}
+	}
+
+	var entries []Entry
+	for mapping, prefixes := range d.MappingStats {
+		for _, prefix := range prefixes {
+			entry, err := parseStatPrefix(prefix)
+			if err != nil {
+				return nil, fmt.Errorf("Mapping %s: %w", mapping, err)
+			}
+			entry.Mapping = mapping
+			entry.Prefix = routes[mapping].prefix
+			entry.Service = routes[mapping].service
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Mapping != entries[j].Mapping {
+			return entries[i].Mapping < entries[j].Mapping
+		}
+		return entries[i].StatPrefix < entries[j].StatPrefix
+	})
+	return entries, nil
+}
+
+// parseStatPrefix splits a stat prefix, vhost.<virtual host>.vcluster.<virtual cluster>., into
+// an Entry. Virtual cluster names never have dots in them, but virtual host names can.
+func parseStatPrefix(prefix string) (Entry, error) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(prefix, "vhost."), ".")
+	i := strings.LastIndex(rest, ".vcluster.")
+	if !strings.HasPrefix(prefix, "vhost.") || !strings.HasSuffix(prefix, ".") || i < 0 {
+		return Entry{}, fmt.Errorf("malformed stat prefix %q", prefix)
+	}
+
+	vhost := rest[:i]
+	host := listenerPrefixRE.ReplaceAllString(vhost, "")
+	if strings.HasSuffix(host, forcedStarSuffix) {
+		host = "*"
+	}
+	return Entry{
+		StatPrefix:     prefix,
+		VirtualHost:    vhost,
+		VirtualCluster: rest[i+len(".vcluster."):],
+		Host:           host,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package statsmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// What diagd reports, cut down: two Mappings with statistics, one of them on two hosts, and one
+// without.
+const diagJSON = `{
+  "mapping_stats": {
+    "quote-backend.default": [
+      "vhost.ambassador-listener-8080-*.vcluster.quote-backend.",
+      "vhost.ambassador-listener-8443-quote.example.com.vcluster.quote-backend."
+    ],
+    "health.ops": [
+      "vhost.ambassador-listener-8080-ops.example.com-forced-star.vcluster.health_ops."
+    ]
+  },
+  "groups": {
+    "grp-1": {"kind": "IRHTTPMappingGroup", "mappings": [
+      {"name": "quote-backend", "namespace": "default", "prefix": "/backend/", "cluster_service": "quote"}]},
+    "grp-2": {"kind": "IRHTTPMappingGroup", "mappings": [
+      {"name": "health", "namespace": "ops", "prefix": "/health/", "cluster_service": "health.ops"}]},
+    "grp-3": {"kind": "IRHTTPMappingGroup", "mappings": [
+      {"name": "other", "namespace": "default", "prefix": "/other/", "cluster_service": "other"}]}
+  }
+}`
+
+func TestParseDiag(t *testing.T) {
+	entries, err := ParseDiag([]byte(diagJSON))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			StatPrefix:     "vhost.ambassador-listener-8080-ops.example.com-forced-star.vcluster.health_ops.",
+			VirtualHost:    "ambassador-listener-8080-ops.example.com-forced-star",
+			VirtualCluster: "health_ops",
+			Mapping:        "health.ops",
+			Host:           "*",
+			Prefix:         "/health/",
+			Service:        "health.ops",
+		},
+		{
+			StatPrefix:     "vhost.ambassador-listener-8080-*.vcluster.quote-backend.",
+			VirtualHost:    "ambassador-listener-8080-*",
+			VirtualCluster: "quote-backend",
+			Mapping:        "quote-backend.default",
+			Host:           "*",
+			Prefix:         "/backend/",
+			Service:        "quote",
+		},
+		{
+			StatPrefix:     "vhost.ambassador-listener-8443-quote.example.com.vcluster.quote-backend.",
+			VirtualHost:    "ambassador-listener-8443-quote.example.com",
+			VirtualCluster: "quote-backend",
+			Mapping:        "quote-backend.default",
+			Host:           "quote.example.com",
+			Prefix:         "/backend/",
+			Service:        "quote",
+		},
+	}, entries)
+
+	_, err = ParseDiag([]byte(`{"mapping_stats": {"x.default": ["cluster.x."]}}`))
+	assert.EqualError(t, err, `Mapping x.default: malformed stat prefix "cluster.x."`)
+}
+
+func TestNewDashboard(t *testing.T) {
+	entries, err := ParseDiag([]byte(diagJSON))
+	require.NoError(t, err)
+
+	d := NewDashboard("Mappings", "Prometheus", entries)
+	assert.Equal(t, "Mappings", d.Title)
+	require.Len(t, d.Panels, 8)
+
+	// A row, then three graphs, for each virtual cluster.
+	assert.Equal(t, "row", d.Panels[0].Type)
+	assert.Equal(t, "health.ops (*)", d.Panels[0].Title)
+	assert.Equal(t, "row", d.Panels[4].Type)
+	assert.Equal(t, "quote-backend.default (*, quote.example.com)", d.Panels[4].Title)
+	assert.Equal(t, GridPos{H: 8, W: 8, X: 16, Y: 10}, d.Panels[7].GridPos)
+
+	rps := d.Panels[5]
+	assert.Equal(t, "Prometheus", rps.Datasource)
+	assert.Equal(t, []Target{{
+		Expr:         `sum(rate(envoy_vhost_vcluster_upstream_rq_total{envoy_virtual_cluster="quote-backend"}[1m]))`,
+		Format:       "time_series",
+		LegendFormat: "requests",
+		RefID:        "A",
+	}}, rps.Targets)
+
+	latency := d.Panels[6]
+	require.Len(t, latency.Targets, 3)
+	assert.Equal(t, `histogram_quantile(0.99, sum(rate(envoy_vhost_vcluster_upstream_rq_time_bucket{envoy_virtual_cluster="quote-backend"}[5m])) by (le))`,
+		latency.Targets[2].Expr)
+	assert.Equal(t, "C", latency.Targets[2].RefID)
+
+	errors := d.Panels[7]
+	require.Len(t, errors.Targets, 2)
+	assert.Equal(t, `sum(rate(envoy_vhost_vcluster_upstream_rq_xx{envoy_virtual_cluster="quote-backend", envoy_response_code_class="5"}[1m]))`,
+		errors.Targets[1].Expr)
+
+	ids := make(map[int]bool)
+	for _, panel := range d.Panels {
+		assert.False(t, ids[panel.ID], "duplicate panel ID %d", panel.ID)
+		ids[panel.ID] = true
+	}
+}
@@ -0,0 +1,216 @@
+package tapconvert
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+// Header returns the value of the named header, or "" if it's missing.
+func Header(headers []*core.HeaderValue, name string) string {
+	for _, h := range headers {
+		if h.GetKey() == name {
+			return h.GetValue()
+		}
+	}
+	return ""
+}
+
+// BodyText returns a tapped body as text, and whether it's base64-encoded because Envoy
+// sent it as bytes.
+func BodyText(body *tapdata.Body) (string, bool) {
+	if bytes := body.GetAsBytes(); len(bytes) > 0 {
+		return base64.StdEncoding.EncodeToString(bytes), true
+	}
+	return body.GetAsString(), false
+}
+
+// HAR is a HAR 1.2 (http://www.softwareishard.com/blog/har-12-spec/) log of tapped requests,
+// for browser developer tools. It's just enough of HAR to hold what Envoy taps; Envoy doesn't
+// tap timings or the HTTP version, so those are left empty.
+type HAR struct {
+	Log harLogBody `json:"log"`
+}
+
+type harLogBody struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// NewHAR returns an empty HAR log, created by busyambassador version.
+func NewHAR(version string) *HAR {
+	return &HAR{Log: harLogBody{
+		Version: "1.2",
+		Creator: harCreator{Name: "busyambassador tap", Version: version},
+		Entries: []harEntry{},
+	}}
+}
+
+func harHeaders(headers []*core.HeaderValue) []harNameValue {
+	result := []harNameValue{}
+	for _, h := range headers {
+		if !strings.HasPrefix(h.GetKey(), ":") {
+			result = append(result, harNameValue{Name: h.GetKey(), Value: h.GetValue()})
+		}
+	}
+	return result
+}
+
+func truncatedComment(body *tapdata.Body) string {
+	if body.GetTruncated() {
+		return "truncated"
+	}
+	return ""
+}
+
+// Add adds a tapped request and its response to the log.
+func (l *HAR) Add(received time.Time, trace *tapdata.HttpBufferedTrace) {
+	req := trace.GetRequest()
+	resp := trace.GetResponse()
+
+	scheme := Header(req.GetHeaders(), "x-forwarded-proto")
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := Header(req.GetHeaders(), ":path")
+	u := &url.URL{Scheme: scheme, Host: Header(req.GetHeaders(), ":authority")}
+	if parsed, err := url.ParseRequestURI(path); err == nil {
+		u.Path = parsed.Path
+		u.RawQuery = parsed.RawQuery
+	} else {
+		u.Path = path
+	}
+
+	query := []harNameValue{}
+	for name, values := range u.Query() {
+		for _, value := range values {
+			query = append(query, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	entry := harEntry{
+		StartedDateTime: received.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Request: harRequest{
+			Method:      Header(req.GetHeaders(), ":method"),
+			URL:         u.String(),
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.GetHeaders()),
+			QueryString: query,
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(resp.GetHeaders()),
+			HeadersSize: -1,
+			BodySize:    -1,
+			Content: harContent{
+				MimeType: Header(resp.GetHeaders(), "content-type"),
+				Comment:  truncatedComment(resp.GetBody()),
+			},
+		},
+	}
+
+	if body := req.GetBody(); len(body.GetAsBytes()) > 0 || body.GetAsString() != "" {
+		// HAR has no way to mark a request body as base64, so binary request bodies don't
+		// survive the trip.
+		text := body.GetAsString()
+		if bytes := body.GetAsBytes(); len(bytes) > 0 {
+			text = string(bytes)
+		}
+		entry.Request.BodySize = len(text)
+		entry.Request.PostData = &harPostData{
+			MimeType: Header(req.GetHeaders(), "content-type"),
+			Text:     text,
+			Comment:  truncatedComment(req.GetBody()),
+		}
+	}
+
+	if status, err := strconv.Atoi(Header(resp.GetHeaders(), ":status")); err == nil {
+		entry.Response.Status = status
+		entry.Response.StatusText = http.StatusText(status)
+	}
+
+	if text, encoded := BodyText(resp.GetBody()); text != "" {
+		entry.Response.Content.Text = text
+		if encoded {
+			entry.Response.Content.Encoding = "base64"
+			entry.Response.Content.Size = len(resp.GetBody().GetAsBytes())
+		} else {
+			entry.Response.Content.Size = len(text)
+		}
+		entry.Response.BodySize = entry.Response.Content.Size
+	}
+
+	l.Log.Entries = append(l.Log.Entries, entry)
+}
@@ -0,0 +1,62 @@
+package tapconvert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+func httpTrace() *tapdata.HttpBufferedTrace {
+	return &tapdata.HttpBufferedTrace{
+		Request: &tapdata.HttpBufferedTrace_Message{
+			Headers: []*core.HeaderValue{
+				{Key: ":method", Value: "POST"},
+				{Key: ":path", Value: "/qotm/quote?n=5"},
+				{Key: ":authority", Value: "quote.example.com"},
+				{Key: "x-forwarded-proto", Value: "https"},
+				{Key: "content-type", Value: "text/plain"},
+			},
+			Body: &tapdata.Body{BodyType: &tapdata.Body_AsString{AsString: "hi"}},
+		},
+		Response: &tapdata.HttpBufferedTrace_Message{
+			Headers: []*core.HeaderValue{
+				{Key: ":status", Value: "404"},
+				{Key: "content-type", Value: "application/json"},
+			},
+			Body: &tapdata.Body{BodyType: &tapdata.Body_AsBytes{AsBytes: []byte(`{"error":`)}, Truncated: true},
+		},
+	}
+}
+
+func TestHAR(t *testing.T) {
+	har := NewHAR("test")
+	har.Add(time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC), httpTrace())
+
+	require.Len(t, har.Log.Entries, 1)
+	entry := har.Log.Entries[0]
+	assert.Equal(t, "2020-09-01T12:00:00.000Z", entry.StartedDateTime)
+	assert.Equal(t, "POST", entry.Request.Method)
+	assert.Equal(t, "https://quote.example.com/qotm/quote?n=5", entry.Request.URL)
+	assert.Equal(t, []harNameValue{{Name: "n", Value: "5"}}, entry.Request.QueryString)
+	assert.Equal(t, &harPostData{MimeType: "text/plain", Text: "hi"}, entry.Request.PostData)
+	assert.Equal(t, 404, entry.Response.Status)
+	assert.Equal(t, "Not Found", entry.Response.StatusText)
+	assert.Equal(t, harContent{
+		Size:     9,
+		MimeType: "application/json",
+		Text:     "eyJlcnJvciI6",
+		Encoding: "base64",
+		Comment:  "truncated",
+	}, entry.Response.Content)
+}
+
+func TestHeader(t *testing.T) {
+	headers := httpTrace().GetRequest().GetHeaders()
+	assert.Equal(t, "POST", Header(headers, ":method"))
+	assert.Equal(t, "", Header(headers, "x-missing"))
+}
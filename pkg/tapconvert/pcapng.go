@@ -0,0 +1,279 @@
+package tapconvert
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+// Addresses that Envoy didn't tap, or that aren't IP, are filled in with
+// these, from the documentation range.
+var (
+	defaultClient = net.IPv4(192, 0, 2, 1)
+	defaultServer = net.IPv4(192, 0, 2, 2)
+)
+
+const (
+	tcpMSS = 1460
+
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpPSH = 0x08
+	tcpACK = 0x10
+
+	// linktypeRaw is LINKTYPE_RAW: packets start with their IPv4 or IPv6 header.
+	linktypeRaw = 101
+)
+
+type packet struct {
+	time time.Time
+	data []byte
+}
+
+// WritePcapng writes the socket traces among traces to w, as a pcapng
+// file that Wireshark can open.  Other traces are skipped.
+//
+// A socket trace only has what was read and written, so WritePcapng
+// makes up the packets that would have carried it: a TCP handshake when
+// the trace starts, segments of at most tcpMSS bytes for every read and
+// write, and FINs when the connection closes.  Reads come from the
+// downstream (remote) address, and writes from Envoy's (local) one.
+func WritePcapng(w io.Writer, traces []*tapdata.TraceWrapper) error {
+	var packets []packet
+	for _, trace := range traces {
+		if socket := trace.GetSocketBufferedTrace(); socket != nil {
+			packets = append(packets, socketPackets(socket)...)
+		}
+	}
+	sort.SliceStable(packets, func(i, j int) bool { return packets[i].time.Before(packets[j].time) })
+
+	// Section Header Block: byte-order magic, version 1.0, and an unknown section length.
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], 0x1A2B3C4D)
+	binary.LittleEndian.PutUint16(shb[4:], 1)
+	binary.LittleEndian.PutUint16(shb[6:], 0)
+	binary.LittleEndian.PutUint64(shb[8:], 0xFFFFFFFFFFFFFFFF)
+	if err := writeBlock(w, 0x0A0D0D0A, shb); err != nil {
+		return err
+	}
+
+	// Interface Description Block: raw IP, no snap length, and the default microsecond
+	// timestamps.
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], linktypeRaw)
+	if err := writeBlock(w, 1, idb); err != nil {
+		return err
+	}
+
+	for _, p := range packets {
+		// Enhanced Packet Block.
+		epb := make([]byte, 20, 20+len(p.data)+3)
+		usec := uint64(p.time.UnixNano() / int64(time.Microsecond))
+		binary.LittleEndian.PutUint32(epb[0:], 0)
+		binary.LittleEndian.PutUint32(epb[4:], uint32(usec>>32))
+		binary.LittleEndian.PutUint32(epb[8:], uint32(usec))
+		binary.LittleEndian.PutUint32(epb[12:], uint32(len(p.data)))
+		binary.LittleEndian.PutUint32(epb[16:], uint32(len(p.data)))
+		epb = append(epb, p.data...)
+		if err := writeBlock(w, 6, epb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBlock writes a pcapng block, padding its body out to 32 bits.
+func writeBlock(w io.Writer, blockType uint32, body []byte) error {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	length := 12 + len(body)
+
+	block := make([]byte, length)
+	binary.LittleEndian.PutUint32(block[0:], blockType)
+	binary.LittleEndian.PutUint32(block[4:], uint32(length))
+	copy(block[8:], body)
+	binary.LittleEndian.PutUint32(block[length-4:], uint32(length))
+	_, err := w.Write(block)
+	return err
+}
+
+type endpoint struct {
+	ip   net.IP
+	port uint16
+	seq  uint32
+	fin  bool
+}
+
+func tapEndpoint(address *core.Address, ip net.IP, port uint16) *endpoint {
+	if socket := address.GetSocketAddress(); socket != nil {
+		if parsed := net.ParseIP(socket.GetAddress()); parsed != nil {
+			ip = parsed
+		}
+		if socket.GetPortValue() != 0 {
+			port = uint16(socket.GetPortValue())
+		}
+	}
+	return &endpoint{ip: ip, port: port}
+}
+
+// tcpFlow builds the packets for one connection.
+type tcpFlow struct {
+	client, server *endpoint
+	packets        []packet
+}
+
+func (f *tcpFlow) send(at time.Time, from *endpoint, flags byte, payload []byte) {
+	to := f.server
+	if from == f.server {
+		to = f.client
+	}
+	f.packets = append(f.packets, packet{time: at, data: ipPacket(from, to, flags, payload)})
+
+	from.seq += uint32(len(payload))
+	if flags&(tcpSYN|tcpFIN) != 0 {
+		from.seq++
+	}
+}
+
+func (f *tcpFlow) data(at time.Time, from *endpoint, payload []byte) {
+	for len(payload) > 0 {
+		n := len(payload)
+		if n > tcpMSS {
+			n = tcpMSS
+		}
+		f.send(at, from, tcpPSH|tcpACK, payload[:n])
+		payload = payload[n:]
+	}
+}
+
+func (f *tcpFlow) close(at time.Time, from *endpoint) {
+	if !from.fin {
+		from.fin = true
+		f.send(at, from, tcpFIN|tcpACK, nil)
+	}
+}
+
+func socketPackets(trace *tapdata.SocketBufferedTrace) []packet {
+	// Each connection gets its own client port, so Wireshark doesn't mix up connections
+	// from a client whose address Envoy didn't tap.
+	flow := &tcpFlow{
+		client: tapEndpoint(trace.GetConnection().GetRemoteAddress(), defaultClient, uint16(49152+trace.GetTraceId()%16384)),
+		server: tapEndpoint(trace.GetConnection().GetLocalAddress(), defaultServer, 80),
+	}
+
+	var at time.Time
+	for i, event := range trace.GetEvents() {
+		if ts, err := ptypes.Timestamp(event.GetTimestamp()); err == nil {
+			at = ts
+		}
+		if i == 0 {
+			flow.send(at, flow.client, tcpSYN, nil)
+			flow.send(at, flow.server, tcpSYN|tcpACK, nil)
+			flow.send(at, flow.client, tcpACK, nil)
+		}
+
+		switch {
+		case event.GetRead() != nil:
+			flow.data(at, flow.client, bodyBytes(event.GetRead().GetData()))
+		case event.GetWrite() != nil:
+			flow.data(at, flow.server, bodyBytes(event.GetWrite().GetData()))
+			if event.GetWrite().GetEndStream() {
+				flow.close(at, flow.server)
+			}
+		case event.GetClosed() != nil:
+			flow.close(at, flow.server)
+			flow.close(at, flow.client)
+		}
+	}
+	if flow.client.fin && flow.server.fin {
+		flow.send(at, flow.server, tcpACK, nil)
+	}
+	return flow.packets
+}
+
+func bodyBytes(body *tapdata.Body) []byte {
+	if bytes := body.GetAsBytes(); len(bytes) > 0 {
+		return bytes
+	}
+	return []byte(body.GetAsString())
+}
+
+// ipPacket returns an IPv4 or IPv6 packet holding a TCP segment.  The
+// ACK number is whatever the other end has sent so far.
+func ipPacket(from, to *endpoint, flags byte, payload []byte) []byte {
+	tcp := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:], from.port)
+	binary.BigEndian.PutUint16(tcp[2:], to.port)
+	binary.BigEndian.PutUint32(tcp[4:], from.seq)
+	if flags&tcpACK != 0 {
+		binary.BigEndian.PutUint32(tcp[8:], to.seq)
+	}
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 65535)
+	copy(tcp[20:], payload)
+
+	var header, pseudo []byte
+	if src, dst := from.ip.To4(), to.ip.To4(); src != nil && dst != nil {
+		header = make([]byte, 20)
+		header[0] = 0x45
+		binary.BigEndian.PutUint16(header[2:], uint16(20+len(tcp)))
+		header[6] = 0x40 // don't fragment
+		header[8] = 64
+		header[9] = 6 // TCP
+		copy(header[12:], src)
+		copy(header[16:], dst)
+		binary.BigEndian.PutUint16(header[10:], checksum(0, header))
+
+		pseudo = make([]byte, 12)
+		copy(pseudo[0:], src)
+		copy(pseudo[4:], dst)
+		pseudo[9] = 6
+		binary.BigEndian.PutUint16(pseudo[10:], uint16(len(tcp)))
+	} else {
+		// If only one end is IPv4, it's written as an IPv4-mapped IPv6 address.
+		header = make([]byte, 40)
+		header[0] = 0x60
+		binary.BigEndian.PutUint16(header[4:], uint16(len(tcp)))
+		header[6] = 6 // TCP
+		header[7] = 64
+		copy(header[8:], from.ip.To16())
+		copy(header[24:], to.ip.To16())
+
+		pseudo = make([]byte, 40)
+		copy(pseudo[0:], from.ip.To16())
+		copy(pseudo[16:], to.ip.To16())
+		binary.BigEndian.PutUint32(pseudo[32:], uint32(len(tcp)))
+		pseudo[39] = 6
+	}
+	binary.BigEndian.PutUint16(tcp[16:], checksum(sum(0, pseudo), tcp))
+
+	return append(header, tcp...)
+}
+
+// sum adds data to an Internet checksum (RFC 1071) in progress.
+func sum(acc uint32, data []byte) uint32 {
+	for i := 0; i+1 < len(data); i += 2 {
+		acc += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		acc += uint32(data[len(data)-1]) << 8
+	}
+	return acc
+}
+
+func checksum(acc uint32, data []byte) uint16 {
+	acc = sum(acc, data)
+	for acc>>16 != 0 {
+		acc = (acc & 0xFFFF) + (acc >> 16)
+	}
+	return ^uint16(acc)
+}
@@ -0,0 +1,168 @@
+package tapconvert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+var socketStart = time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+
+func socketAddress(ip string, port uint32) *core.Address {
+	return &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+		Address:       ip,
+		PortSpecifier: &core.SocketAddress_PortValue{PortValue: port},
+	}}}
+}
+
+func socketEvent(offset time.Duration, event *tapdata.SocketEvent) *tapdata.SocketEvent {
+	event.Timestamp, _ = ptypes.TimestampProto(socketStart.Add(offset))
+	return event
+}
+
+func socketTrace() *tapdata.SocketBufferedTrace {
+	return &tapdata.SocketBufferedTrace{
+		TraceId: 12,
+		Connection: &tapdata.Connection{
+			LocalAddress:  socketAddress("10.0.0.9", 8080),
+			RemoteAddress: socketAddress("10.0.0.5", 40000),
+		},
+		Events: []*tapdata.SocketEvent{
+			socketEvent(0, &tapdata.SocketEvent{EventSelector: &tapdata.SocketEvent_Read_{Read: &tapdata.SocketEvent_Read{
+				Data: &tapdata.Body{BodyType: &tapdata.Body_AsBytes{AsBytes: []byte("PING\r\n")}},
+			}}}),
+			socketEvent(time.Millisecond, &tapdata.SocketEvent{EventSelector: &tapdata.SocketEvent_Write_{Write: &tapdata.SocketEvent_Write{
+				Data: &tapdata.Body{BodyType: &tapdata.Body_AsString{AsString: strings.Repeat("x", 2000)}},
+			}}}),
+			socketEvent(2*time.Millisecond, &tapdata.SocketEvent{EventSelector: &tapdata.SocketEvent_Closed_{Closed: &tapdata.SocketEvent_Closed{}}}),
+		},
+	}
+}
+
+type pcapngBlock struct {
+	blockType uint32
+	body      []byte
+}
+
+func readBlocks(t *testing.T, data []byte) []pcapngBlock {
+	var blocks []pcapngBlock
+	for len(data) > 0 {
+		require.True(t, len(data) >= 12)
+		length := binary.LittleEndian.Uint32(data[4:])
+		require.Equal(t, uint32(0), length%4)
+		require.Equal(t, length, binary.LittleEndian.Uint32(data[length-4:]))
+		blocks = append(blocks, pcapngBlock{
+			blockType: binary.LittleEndian.Uint32(data),
+			body:      data[8 : length-4],
+		})
+		data = data[length:]
+	}
+	return blocks
+}
+
+func TestWritePcapng(t *testing.T) {
+	traces := []*tapdata.TraceWrapper{
+		{Trace: &tapdata.TraceWrapper_HttpBufferedTrace{HttpBufferedTrace: httpTrace()}},
+		{Trace: &tapdata.TraceWrapper_SocketBufferedTrace{SocketBufferedTrace: socketTrace()}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, WritePcapng(&out, traces))
+	blocks := readBlocks(t, out.Bytes())
+
+	require.Len(t, blocks, 11)
+	assert.Equal(t, uint32(0x0A0D0D0A), blocks[0].blockType)
+	assert.Equal(t, uint32(0x1A2B3C4D), binary.LittleEndian.Uint32(blocks[0].body))
+	assert.Equal(t, uint32(1), blocks[1].blockType)
+	assert.Equal(t, uint16(linktypeRaw), binary.LittleEndian.Uint16(blocks[1].body))
+
+	type segment struct {
+		from    string
+		flags   byte
+		seq     uint32
+		ack     uint32
+		payload int
+	}
+	var segments []segment
+	for _, block := range blocks[2:] {
+		require.Equal(t, uint32(6), block.blockType)
+		usec := uint64(binary.LittleEndian.Uint32(block.body[4:]))<<32 | uint64(binary.LittleEndian.Uint32(block.body[8:]))
+		assert.False(t, time.Unix(0, int64(usec)*int64(time.Microsecond)).Before(socketStart))
+
+		length := binary.LittleEndian.Uint32(block.body[12:])
+		pkt := block.body[20 : 20+length]
+		require.Equal(t, byte(0x45), pkt[0])
+		assert.Equal(t, uint16(0), checksum(0, pkt[:20]), "IP checksum")
+
+		tcp := pkt[20:]
+		pseudo := make([]byte, 12)
+		copy(pseudo, pkt[12:20])
+		pseudo[9] = 6
+		binary.BigEndian.PutUint16(pseudo[10:], uint16(len(tcp)))
+		assert.Equal(t, uint16(0), checksum(sum(0, pseudo), tcp), "TCP checksum")
+
+		segments = append(segments, segment{
+			from:    net.IP(pkt[12:16]).String(),
+			flags:   tcp[13],
+			seq:     binary.BigEndian.Uint32(tcp[4:]),
+			ack:     binary.BigEndian.Uint32(tcp[8:]),
+			payload: len(tcp) - 20,
+		})
+	}
+
+	client, server := "10.0.0.5", "10.0.0.9"
+	assert.Equal(t, []segment{
+		{client, tcpSYN, 0, 0, 0},
+		{server, tcpSYN | tcpACK, 0, 1, 0},
+		{client, tcpACK, 1, 1, 0},
+		{client, tcpPSH | tcpACK, 1, 1, 6},
+		{server, tcpPSH | tcpACK, 1, 7, 1460},
+		{server, tcpPSH | tcpACK, 1461, 7, 540},
+		{server, tcpFIN | tcpACK, 2001, 7, 0},
+		{client, tcpFIN | tcpACK, 7, 2002, 0},
+		{server, tcpACK, 2002, 8, 0},
+	}, segments)
+}
+
+func TestPcapngDefaultAddresses(t *testing.T) {
+	trace := socketTrace()
+	trace.Connection = nil
+
+	var out bytes.Buffer
+	require.NoError(t, WritePcapng(&out, []*tapdata.TraceWrapper{
+		{Trace: &tapdata.TraceWrapper_SocketBufferedTrace{SocketBufferedTrace: trace}},
+	}))
+	blocks := readBlocks(t, out.Bytes())
+
+	pkt := blocks[2].body[20:]
+	assert.Equal(t, "192.0.2.1", net.IP(pkt[12:16]).String())
+	assert.Equal(t, "192.0.2.2", net.IP(pkt[16:20]).String())
+	assert.Equal(t, uint16(49152+12), binary.BigEndian.Uint16(pkt[20:]))
+	assert.Equal(t, uint16(80), binary.BigEndian.Uint16(pkt[22:]))
+}
+
+func TestPcapngIPv6(t *testing.T) {
+	trace := socketTrace()
+	trace.Connection.RemoteAddress = socketAddress("2001:db8::5", 40000)
+
+	var out bytes.Buffer
+	require.NoError(t, WritePcapng(&out, []*tapdata.TraceWrapper{
+		{Trace: &tapdata.TraceWrapper_SocketBufferedTrace{SocketBufferedTrace: trace}},
+	}))
+	blocks := readBlocks(t, out.Bytes())
+
+	pkt := blocks[2].body[20:]
+	assert.Equal(t, byte(0x60), pkt[0])
+	assert.Equal(t, "2001:db8::5", net.IP(pkt[8:24]).String())
+	assert.Equal(t, "10.0.0.9", net.IP(pkt[24:40]).String())
+}
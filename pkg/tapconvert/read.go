@@ -0,0 +1,94 @@
+// Package tapconvert turns the traces that Envoy's tap filter writes
+// into formats other tools can open: HAR, for the HTTP traces, which
+// browser developer tools read, and pcapng, for the socket traces,
+// which Wireshark reads.
+package tapconvert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+// ReadFile reads the traces in a tap file.  Which format the file is in
+// comes from its extension, the way Envoy's file_per_tap sink names
+// them: .pb files hold one binary trace, .pb_length_delimited files hold
+// a series of length-delimited binary traces, and anything else is read
+// as JSON, with ReadJSON.
+func ReadFile(path string) ([]*tapdata.TraceWrapper, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var traces []*tapdata.TraceWrapper
+	switch filepath.Ext(path) {
+	case ".pb":
+		trace := &tapdata.TraceWrapper{}
+		if err := proto.Unmarshal(contents, trace); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		traces = append(traces, trace)
+	case ".pb_length_delimited":
+		for len(contents) > 0 {
+			msg, n := protowire.ConsumeBytes(contents)
+			if n < 0 {
+				return nil, fmt.Errorf("%s: %w", path, protowire.ParseError(n))
+			}
+			trace := &tapdata.TraceWrapper{}
+			if err := proto.Unmarshal(msg, trace); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			traces = append(traces, trace)
+			contents = contents[n:]
+		}
+	case ".pb_text":
+		return nil, fmt.Errorf("%s: text protos aren't supported; use the JSON or a binary format", path)
+	default:
+		traces, err = ReadJSON(bytes.NewReader(contents))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return traces, nil
+}
+
+// ReadJSON reads a series of JSON traces.  That covers a file that
+// Envoy's file_per_tap sink wrote as JSON, which holds one trace, and
+// what the tap collector stores, which is one trace per line, either
+// bare or, from its stdout: backend, wrapped up with its tap ID.
+func ReadJSON(r io.Reader) ([]*tapdata.TraceWrapper, error) {
+	var traces []*tapdata.TraceWrapper
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return traces, nil
+			}
+			return nil, err
+		}
+
+		var wrapped struct {
+			Trace json.RawMessage `json:"trace"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err == nil && len(wrapped.Trace) > 0 {
+			raw = wrapped.Trace
+		}
+
+		trace := &tapdata.TraceWrapper{}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, trace); err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+}
@@ -0,0 +1,69 @@
+package tapconvert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+func TestReadJSON(t *testing.T) {
+	trace, err := protojson.Marshal(&tapdata.TraceWrapper{
+		Trace: &tapdata.TraceWrapper_HttpBufferedTrace{HttpBufferedTrace: httpTrace()},
+	})
+	require.NoError(t, err)
+
+	// A bare trace, as Envoy and the collector's file backend write them, then one from the
+	// collector's stdout: backend.
+	input := string(trace) + "\n" + `{"tap_id":"qotm.default","trace_id":7,"trace":` + string(trace) + "}\n"
+	traces, err := ReadJSON(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	for _, trace := range traces {
+		assert.Equal(t, "POST", Header(trace.GetHttpBufferedTrace().GetRequest().GetHeaders(), ":method"))
+	}
+
+	_, err = ReadJSON(strings.NewReader(`{"http_buffered_trace":`))
+	assert.Error(t, err)
+}
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tapconvert")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	trace, err := proto.Marshal(&tapdata.TraceWrapper{
+		Trace: &tapdata.TraceWrapper_SocketBufferedTrace{SocketBufferedTrace: socketTrace()},
+	})
+	require.NoError(t, err)
+
+	single := filepath.Join(dir, "tap_1.pb")
+	require.NoError(t, ioutil.WriteFile(single, trace, 0644))
+	traces, err := ReadFile(single)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, uint64(12), traces[0].GetSocketBufferedTrace().GetTraceId())
+
+	var delimited []byte
+	delimited = protowire.AppendBytes(delimited, trace)
+	delimited = protowire.AppendBytes(delimited, trace)
+	several := filepath.Join(dir, "tap_1.pb_length_delimited")
+	require.NoError(t, ioutil.WriteFile(several, delimited, 0644))
+	traces, err = ReadFile(several)
+	require.NoError(t, err)
+	assert.Len(t, traces, 2)
+
+	text := filepath.Join(dir, "tap_1.pb_text")
+	require.NoError(t, ioutil.WriteFile(text, []byte("socket_buffered_trace {}"), 0644))
+	_, err = ReadFile(text)
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package tapserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// A Backend saves the traces that the Server receives.
+type Backend interface {
+	// Store saves one trace, as JSON, for the tap named tapID.
+	Store(ctx context.Context, tapID string, traceID uint64, trace []byte) error
+}
+
+// NewBackend returns the Backend for a storage URL:
+//
+//   - a path, or a file:// URL, is a directory for a FileBackend;
+//   - "stdout:" is a StdoutBackend; and
+//   - s3://bucket/prefix is an S3Backend, which gets its region and
+//     credentials from the usual AWS_* environment variables.  An
+//     "endpoint" query parameter points it at an S3-compatible store
+//     other than AWS, like s3://taps?endpoint=http://minio:9000.
+func NewBackend(storage string) (Backend, error) {
+	u, err := url.Parse(storage)
+	if err != nil {
+		return nil, fmt.Errorf("bad tap storage %q: %w", storage, err)
+	}
+
+	switch u.Scheme {
+	case "":
+		return NewFileBackend(storage), nil
+	case "file":
+		return NewFileBackend(u.Path), nil
+	case "stdout":
+		return NewStdoutBackend(os.Stdout), nil
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("bad tap storage %q: no bucket", storage)
+		}
+		backend := NewS3Backend(u.Host, strings.Trim(u.Path, "/"))
+		if endpoint := u.Query().Get("endpoint"); endpoint != "" {
+			backend.Endpoint = endpoint
+		}
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("bad tap storage %q: unknown scheme %q", storage, u.Scheme)
+	}
+}
+
+var unsafeTapID = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// tapName turns a tap ID into something that's safe to use as a file
+// name or an S3 key.
+func tapName(tapID string) string {
+	name := unsafeTapID.ReplaceAllString(tapID, "_")
+	if name == "" || name == "." || name == ".." {
+		name = "_"
+	}
+	return name
+}
+
+// FileBackend is a Backend that appends the traces for each tap, one
+// per line, to a file named after the tap in a directory.
+type FileBackend struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewFileBackend returns a FileBackend that writes to dir, which it
+// creates if need be.
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{Dir: dir}
+}
+
+func (b *FileBackend) Store(_ context.Context, tapID string, _ uint64, trace []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.MkdirAll(b.Dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(b.Dir, tapName(tapID)+".jsonl"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(trace, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// StdoutBackend is a Backend that writes every trace to a Writer as a
+// line of JSON, for log collectors to pick up.
+type StdoutBackend struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutBackend returns a StdoutBackend that writes to w.
+func NewStdoutBackend(w io.Writer) *StdoutBackend {
+	return &StdoutBackend{w: w}
+}
+
+type stdoutTrace struct {
+	TapID   string          `json:"tap_id"`
+	TraceID uint64          `json:"trace_id"`
+	Trace   json.RawMessage `json:"trace"`
+}
+
+func (b *StdoutBackend) Store(_ context.Context, tapID string, traceID uint64, trace []byte) error {
+	line, err := json.Marshal(stdoutTrace{TapID: tapID, TraceID: traceID, Trace: trace})
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err = b.w.Write(append(line, '\n'))
+	return err
+}
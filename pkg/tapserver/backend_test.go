@@ -0,0 +1,124 @@
+package tapserver
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/datawire/ambassador/pkg/sigv4"
+)
+
+func TestNewBackend(t *testing.T) {
+	backend, err := NewBackend("/tmp/taps")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/taps", backend.(*FileBackend).Dir)
+
+	backend, err = NewBackend("file:///var/taps")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/taps", backend.(*FileBackend).Dir)
+
+	backend, err = NewBackend("stdout:")
+	require.NoError(t, err)
+	assert.IsType(t, &StdoutBackend{}, backend)
+
+	backend, err = NewBackend("s3://taps/ambassador/?endpoint=http://minio:9000")
+	require.NoError(t, err)
+	assert.Equal(t, "taps", backend.(*S3Backend).Bucket)
+	assert.Equal(t, "ambassador", backend.(*S3Backend).Prefix)
+	assert.Equal(t, "http://minio:9000", backend.(*S3Backend).Endpoint)
+
+	_, err = NewBackend("s3:///ambassador")
+	assert.Error(t, err)
+	_, err = NewBackend("ftp://example.com/taps")
+	assert.Error(t, err)
+}
+
+func TestFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "taps")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	backend := NewFileBackend(filepath.Join(dir, "new"))
+	ctx := context.Background()
+	require.NoError(t, backend.Store(ctx, "quote-tap.default", 1, []byte(`{"n":1}`)))
+	require.NoError(t, backend.Store(ctx, "quote-tap.default", 2, []byte(`{"n":2}`)))
+	require.NoError(t, backend.Store(ctx, "../escape", 3, []byte(`{"n":3}`)))
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "new", "quote-tap.default.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n", string(contents))
+
+	contents, err = ioutil.ReadFile(filepath.Join(dir, "new", ".._escape.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"n\":3}\n", string(contents))
+}
+
+func TestStdoutBackend(t *testing.T) {
+	var out bytes.Buffer
+	backend := NewStdoutBackend(&out)
+	require.NoError(t, backend.Store(context.Background(), "quote-tap.default", 7, []byte(`{"n":7}`)))
+	assert.Equal(t, `{"tap_id":"quote-tap.default","trace_id":7,"trace":{"n":7}}`+"\n", out.String())
+}
+
+func TestS3Backend(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests <- request{r.Method, r.URL.Path, r.Header, body}
+	}))
+	defer srv.Close()
+
+	backend := NewS3Backend("taps", "ambassador")
+	backend.Endpoint = srv.URL
+	backend.Region = "eu-west-1"
+	backend.AccessKeyID = "AKIDEXAMPLE"
+	backend.SecretAccessKey = "secret"
+	backend.SessionToken = "token"
+	backend.now = func() time.Time { return time.Date(2020, 9, 1, 12, 30, 0, 0, time.UTC) }
+
+	require.NoError(t, backend.Store(context.Background(), "quote-tap.default", 42, []byte(`{"n":42}`)))
+
+	req := <-requests
+	assert.Equal(t, http.MethodPut, req.method)
+	assert.Equal(t, "/taps/ambassador/quote-tap.default/20200901T123000.000000000Z-42.json", req.path)
+	assert.Equal(t, `{"n":42}`, string(req.body))
+	assert.Equal(t, "20200901T123000Z", req.header.Get("X-Amz-Date"))
+	assert.Equal(t, sigv4.HashHex([]byte(`{"n":42}`)), req.header.Get("X-Amz-Content-Sha256"))
+	assert.Equal(t, "token", req.header.Get("X-Amz-Security-Token"))
+
+	auth := req.header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(auth,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20200901/eu-west-1/s3/aws4_request, "+
+			"SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature="), auth)
+}
+
+func TestS3BackendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	backend := NewS3Backend("taps", "")
+	backend.Endpoint = srv.URL
+
+	err := backend.Store(context.Background(), "quote-tap.default", 1, []byte(`{}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AccessDenied")
+}
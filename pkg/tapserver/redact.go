@@ -0,0 +1,173 @@
+package tapserver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"sigs.k8s.io/yaml"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+// Redacted replaces whatever a Redactor takes out of a trace.
+const Redacted = "[REDACTED]"
+
+// RedactionRules are what a Redactor takes out of traces, as they appear in a redaction
+// rules file.
+type RedactionRules struct {
+	// AllowHeaders, if it isn't empty, lists the only headers whose values are kept.
+	// Pseudo-headers, like :path, are always kept.
+	AllowHeaders []string `json:"allow_headers,omitempty"`
+	// DenyHeaders lists headers whose values are redacted.
+	DenyHeaders []string `json:"deny_headers,omitempty"`
+	// MaxBodyBytes, if it isn't zero, is how much of each body, or body chunk, to keep.
+	MaxBodyBytes int `json:"max_body_bytes,omitempty"`
+	// Scrub lists regular expressions to redact from header values and bodies, for things
+	// like bearer tokens and card numbers.
+	Scrub []string `json:"scrub,omitempty"`
+}
+
+// DefaultRedactionRules redact the headers that carry credentials.
+var DefaultRedactionRules = RedactionRules{
+	DenyHeaders: []string{"authorization", "proxy-authorization", "cookie", "set-cookie", "x-api-key"},
+}
+
+// A Redactor takes things out of traces, following a set of RedactionRules, so that
+// they're safe to store and share.
+type Redactor struct {
+	allow        map[string]bool
+	deny         map[string]bool
+	maxBodyBytes int
+	scrub        []*regexp.Regexp
+}
+
+// NewRedactor returns a Redactor for rules.
+func NewRedactor(rules RedactionRules) (*Redactor, error) {
+	r := &Redactor{
+		allow:        map[string]bool{},
+		deny:         map[string]bool{},
+		maxBodyBytes: rules.MaxBodyBytes,
+	}
+	for _, name := range rules.AllowHeaders {
+		r.allow[strings.ToLower(name)] = true
+	}
+	for _, name := range rules.DenyHeaders {
+		r.deny[strings.ToLower(name)] = true
+	}
+	for _, expr := range rules.Scrub {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("bad scrub regex %q: %w", expr, err)
+		}
+		r.scrub = append(r.scrub, re)
+	}
+	return r, nil
+}
+
+// LoadRedactor returns a Redactor for the rules in a YAML file, or for the
+// DefaultRedactionRules if path is empty.
+func LoadRedactor(path string) (*Redactor, error) {
+	if path == "" {
+		return NewRedactor(DefaultRedactionRules)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules RedactionRules
+	if err := yaml.UnmarshalStrict(contents, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewRedactor(rules)
+}
+
+// Redact takes things out of a trace, in place.
+func (r *Redactor) Redact(trace *tapdata.TraceWrapper) {
+	if buffered := trace.GetHttpBufferedTrace(); buffered != nil {
+		for _, msg := range []*tapdata.HttpBufferedTrace_Message{buffered.GetRequest(), buffered.GetResponse()} {
+			if msg == nil {
+				continue
+			}
+			r.headers(msg.Headers)
+			r.body(msg.Body)
+			r.headers(msg.Trailers)
+		}
+	}
+
+	if segment := trace.GetHttpStreamedTraceSegment(); segment != nil {
+		for _, headers := range []*core.HeaderMap{
+			segment.GetRequestHeaders(), segment.GetRequestTrailers(),
+			segment.GetResponseHeaders(), segment.GetResponseTrailers(),
+		} {
+			if headers != nil {
+				r.headers(headers.Headers)
+			}
+		}
+		r.body(segment.GetRequestBodyChunk())
+		r.body(segment.GetResponseBodyChunk())
+	}
+
+	if buffered := trace.GetSocketBufferedTrace(); buffered != nil {
+		for _, event := range buffered.GetEvents() {
+			r.socketEvent(event)
+		}
+	}
+
+	if segment := trace.GetSocketStreamedTraceSegment(); segment != nil {
+		r.socketEvent(segment.GetEvent())
+	}
+}
+
+func (r *Redactor) socketEvent(event *tapdata.SocketEvent) {
+	r.body(event.GetRead().GetData())
+	r.body(event.GetWrite().GetData())
+}
+
+func (r *Redactor) headers(headers []*core.HeaderValue) {
+	for _, h := range headers {
+		name := strings.ToLower(h.Key)
+		pseudo := strings.HasPrefix(name, ":")
+		if !pseudo && (r.deny[name] || (len(r.allow) > 0 && !r.allow[name])) {
+			h.Value = Redacted
+			continue
+		}
+		for _, re := range r.scrub {
+			h.Value = re.ReplaceAllLiteralString(h.Value, Redacted)
+		}
+	}
+}
+
+func (r *Redactor) body(body *tapdata.Body) {
+	if body == nil {
+		return
+	}
+
+	switch b := body.BodyType.(type) {
+	case *tapdata.Body_AsBytes:
+		for _, re := range r.scrub {
+			b.AsBytes = re.ReplaceAllLiteral(b.AsBytes, []byte(Redacted))
+		}
+		if r.maxBodyBytes > 0 && len(b.AsBytes) > r.maxBodyBytes {
+			b.AsBytes = b.AsBytes[:r.maxBodyBytes]
+			body.Truncated = true
+		}
+	case *tapdata.Body_AsString:
+		for _, re := range r.scrub {
+			b.AsString = re.ReplaceAllLiteralString(b.AsString, Redacted)
+		}
+		if r.maxBodyBytes > 0 && len(b.AsString) > r.maxBodyBytes {
+			// Cut at the start of a rune, since the string has to stay valid UTF-8.
+			n := r.maxBodyBytes
+			for n > 0 && !utf8.RuneStart(b.AsString[n]) {
+				n--
+			}
+			b.AsString = b.AsString[:n]
+			body.Truncated = true
+		}
+	}
+}
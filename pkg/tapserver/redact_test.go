@@ -0,0 +1,138 @@
+package tapserver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+)
+
+func redactTrace() *tapdata.TraceWrapper {
+	return &tapdata.TraceWrapper{
+		Trace: &tapdata.TraceWrapper_HttpBufferedTrace{
+			HttpBufferedTrace: &tapdata.HttpBufferedTrace{
+				Request: &tapdata.HttpBufferedTrace_Message{
+					Headers: []*core.HeaderValue{
+						{Key: ":path", Value: "/qotm/?token=abc123"},
+						{Key: "Authorization", Value: "Bearer abc123"},
+						{Key: "user-agent", Value: "curl/7.64"},
+						{Key: "x-request-id", Value: "1234"},
+					},
+					Body: &tapdata.Body{BodyType: &tapdata.Body_AsString{AsString: `{"card":"4111 1111 1111 1111","note":"héllo"}`}},
+				},
+				Response: &tapdata.HttpBufferedTrace_Message{
+					Headers: []*core.HeaderValue{{Key: ":status", Value: "200"}, {Key: "set-cookie", Value: "session=xyz"}},
+					Body:    &tapdata.Body{BodyType: &tapdata.Body_AsBytes{AsBytes: []byte("token=abc123; more")}},
+				},
+			},
+		},
+	}
+}
+
+func headerValues(headers []*core.HeaderValue) map[string]string {
+	values := map[string]string{}
+	for _, h := range headers {
+		values[h.GetKey()] = h.GetValue()
+	}
+	return values
+}
+
+func TestRedactDefault(t *testing.T) {
+	r, err := LoadRedactor("")
+	require.NoError(t, err)
+
+	trace := redactTrace()
+	r.Redact(trace)
+	buffered := trace.GetHttpBufferedTrace()
+
+	assert.Equal(t, map[string]string{
+		":path":         "/qotm/?token=abc123",
+		"Authorization": Redacted,
+		"user-agent":    "curl/7.64",
+		"x-request-id":  "1234",
+	}, headerValues(buffered.GetRequest().GetHeaders()))
+	assert.Equal(t, Redacted, headerValues(buffered.GetResponse().GetHeaders())["set-cookie"])
+	assert.Equal(t, `{"card":"4111 1111 1111 1111","note":"héllo"}`, buffered.GetRequest().GetBody().GetAsString())
+}
+
+func TestRedactRules(t *testing.T) {
+	r, err := NewRedactor(RedactionRules{
+		AllowHeaders: []string{"User-Agent"},
+		MaxBodyBytes: 31,
+		Scrub:        []string{`abc[0-9]+`, `\b(?:\d{4} ){3}\d{4}\b`},
+	})
+	require.NoError(t, err)
+
+	trace := redactTrace()
+	r.Redact(trace)
+	buffered := trace.GetHttpBufferedTrace()
+
+	assert.Equal(t, map[string]string{
+		":path":         "/qotm/?token=" + Redacted,
+		"Authorization": Redacted,
+		"user-agent":    "curl/7.64",
+		"x-request-id":  Redacted,
+	}, headerValues(buffered.GetRequest().GetHeaders()))
+
+	// The cap lands in the middle of the "é", so the cut backs up to before it.
+	body := buffered.GetRequest().GetBody()
+	assert.Equal(t, `{"card":"[REDACTED]","note":"h`, body.GetAsString())
+	assert.True(t, body.GetTruncated())
+
+	body = buffered.GetResponse().GetBody()
+	assert.Equal(t, "token=[REDACTED]; more", string(body.GetAsBytes()))
+	assert.False(t, body.GetTruncated())
+}
+
+func TestRedactSocket(t *testing.T) {
+	r, err := NewRedactor(RedactionRules{MaxBodyBytes: 4, Scrub: []string{`secret`}})
+	require.NoError(t, err)
+
+	trace := &tapdata.TraceWrapper{
+		Trace: &tapdata.TraceWrapper_SocketBufferedTrace{
+			SocketBufferedTrace: &tapdata.SocketBufferedTrace{
+				Events: []*tapdata.SocketEvent{
+					{EventSelector: &tapdata.SocketEvent_Read_{Read: &tapdata.SocketEvent_Read{
+						Data: &tapdata.Body{BodyType: &tapdata.Body_AsBytes{AsBytes: []byte("AUTH secret")}},
+					}}},
+				},
+			},
+		},
+	}
+	r.Redact(trace)
+
+	data := trace.GetSocketBufferedTrace().GetEvents()[0].GetRead().GetData()
+	assert.Equal(t, "AUTH", string(data.GetAsBytes()))
+	assert.True(t, data.GetTruncated())
+}
+
+func TestLoadRedactor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redact")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.yaml")
+	require.NoError(t, ioutil.WriteFile(good, []byte("deny_headers: [x-secret]\nmax_body_bytes: 10\nscrub: ['Bearer \\S+']\n"), 0644))
+	r, err := LoadRedactor(good)
+	require.NoError(t, err)
+	assert.True(t, r.deny["x-secret"])
+	assert.False(t, r.deny["authorization"])
+	assert.Equal(t, 10, r.maxBodyBytes)
+	assert.Len(t, r.scrub, 1)
+
+	typo := filepath.Join(dir, "typo.yaml")
+	require.NoError(t, ioutil.WriteFile(typo, []byte("deny_header: [x-secret]\n"), 0644))
+	_, err = LoadRedactor(typo)
+	assert.Error(t, err)
+
+	badRegex := filepath.Join(dir, "regex.yaml")
+	require.NoError(t, ioutil.WriteFile(badRegex, []byte("scrub: ['(']\n"), 0644))
+	_, err = LoadRedactor(badRegex)
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package tapserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/datawire/ambassador/pkg/sigv4"
+)
+
+// S3Backend is a Backend that saves each trace as an object in an S3
+// bucket, at prefix/tap/time-traceid.json.  We don't have the AWS SDK
+// among our dependencies, so it signs its own PUTs with package sigv4.
+type S3Backend struct {
+	Bucket string
+	Prefix string
+	Region string
+	// Endpoint, if set, is the URL of an S3-compatible store to use
+	// instead of AWS.  Objects are addressed by path, like
+	// Endpoint/Bucket/key, rather than by virtual host.
+	Endpoint string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Backend returns an S3Backend for bucket, with the region and
+// credentials in the AWS_REGION, AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment variables.
+func NewS3Backend(bucket, prefix string) *S3Backend {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Backend{
+		Bucket:          bucket,
+		Prefix:          prefix,
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		client:          &http.Client{Timeout: 30 * time.Second},
+		now:             time.Now,
+	}
+}
+
+func (b *S3Backend) Store(ctx context.Context, tapID string, traceID uint64, trace []byte) error {
+	now := b.now().UTC()
+	key := path.Join(b.Prefix, tapName(tapID), fmt.Sprintf("%s-%d.json", now.Format("20060102T150405.000000000Z"), traceID))
+
+	objectURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", b.Bucket, b.Region, key)
+	if b.Endpoint != "" {
+		objectURL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(b.Endpoint, "/"), b.Bucket, key)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(trace))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	b.sign(req, trace, now)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("PUT %s: %s: %s", objectURL, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// sign adds a Signature Version 4 Authorization header to req.
+func (b *S3Backend) sign(req *http.Request, body []byte, now time.Time) {
+	creds := sigv4.Credentials{
+		AccessKeyID:     b.AccessKeyID,
+		SecretAccessKey: b.SecretAccessKey,
+		SessionToken:    b.SessionToken,
+	}
+	sigv4.Sign(req, body, creds, b.Region, "s3", now)
+}
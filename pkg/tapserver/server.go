@@ -0,0 +1,76 @@
+// Package tapserver implements Envoy's TapSinkService, which Envoy's
+// tap filter uses to stream traces out when a tap's output sink is
+// streaming_grpc, and saves the traces to a Backend.
+package tapserver
+
+import (
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+	"github.com/datawire/ambassador/pkg/dlog"
+)
+
+// Server is a TapSinkService that hands every trace it's sent to a
+// Backend.
+type Server struct {
+	// Redactor, if set, takes things out of every trace before it's
+	// stored.
+	Redactor *Redactor
+
+	backend Backend
+}
+
+// NewServer returns a Server that saves traces to backend.
+func NewServer(backend Backend) *Server {
+	return &Server{backend: backend}
+}
+
+// Register registers the Server with a gRPC server.
+func (s *Server) Register(server *grpc.Server) {
+	tapsvc.RegisterTapSinkServiceServer(server, s)
+}
+
+// StreamTaps implements the TapSinkServiceServer interface.  Envoy only
+// sends the tap's identifier in the first message on a stream, so we
+// hang on to it for the rest of the stream.  Traces that the Backend
+// can't save are logged and dropped; failing the stream would just make
+// Envoy drop the traces behind them too.
+func (s *Server) StreamTaps(stream tapsvc.TapSinkService_StreamTapsServer) error {
+	ctx := stream.Context()
+
+	var tapID string
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&tapsvc.StreamTapsResponse{})
+		}
+		if err != nil {
+			return err
+		}
+
+		if id := req.GetIdentifier().GetTapId(); id != "" {
+			tapID = id
+		}
+
+		if req.GetTrace() == nil {
+			continue
+		}
+
+		if s.Redactor != nil {
+			s.Redactor.Redact(req.GetTrace())
+		}
+
+		trace, err := protojson.Marshal(req.GetTrace())
+		if err != nil {
+			dlog.Errorf(ctx, "tap %q: trace %d: %v", tapID, req.GetTraceId(), err)
+			continue
+		}
+
+		if err := s.backend.Store(ctx, tapID, req.GetTraceId(), trace); err != nil {
+			dlog.Errorf(ctx, "tap %q: trace %d: %v", tapID, req.GetTraceId(), err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package tapserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	core "github.com/datawire/ambassador/pkg/api/envoy/api/v2/core"
+	tapdata "github.com/datawire/ambassador/pkg/api/envoy/data/tap/v2alpha"
+	tapsvc "github.com/datawire/ambassador/pkg/api/envoy/service/tap/v2alpha"
+)
+
+type storedTrace struct {
+	tapID   string
+	traceID uint64
+	trace   []byte
+}
+
+type memoryBackend struct {
+	mu     sync.Mutex
+	traces []storedTrace
+}
+
+func (b *memoryBackend) Store(_ context.Context, tapID string, traceID uint64, trace []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.traces = append(b.traces, storedTrace{tapID, traceID, trace})
+	return nil
+}
+
+func bufferedTrace(path string, status string) *tapdata.TraceWrapper {
+	return &tapdata.TraceWrapper{
+		Trace: &tapdata.TraceWrapper_HttpBufferedTrace{
+			HttpBufferedTrace: &tapdata.HttpBufferedTrace{
+				Request: &tapdata.HttpBufferedTrace_Message{
+					Headers: []*core.HeaderValue{{Key: ":path", Value: path}},
+				},
+				Response: &tapdata.HttpBufferedTrace_Message{
+					Headers: []*core.HeaderValue{{Key: ":status", Value: status}},
+					Body:    &tapdata.Body{BodyType: &tapdata.Body_AsString{AsString: "hello"}},
+				},
+			},
+		},
+	}
+}
+
+func TestStreamTaps(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	backend := &memoryBackend{}
+	server := grpc.NewServer()
+	NewServer(backend).Register(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := tapsvc.NewTapSinkServiceClient(conn).StreamTaps(context.Background())
+	require.NoError(t, err)
+
+	// Only the first message on the stream has the identifier.
+	require.NoError(t, stream.Send(&tapsvc.StreamTapsRequest{
+		Identifier: &tapsvc.StreamTapsRequest_Identifier{
+			Node:  &core.Node{Id: "test-id"},
+			TapId: "quote-tap.default",
+		},
+		TraceId: 1,
+		Trace:   bufferedTrace("/qotm/", "200"),
+	}))
+	require.NoError(t, stream.Send(&tapsvc.StreamTapsRequest{
+		TraceId: 2,
+		Trace:   bufferedTrace("/qotm/quote/5", "404"),
+	}))
+	_, err = stream.CloseAndRecv()
+	require.NoError(t, err)
+
+	require.Len(t, backend.traces, 2)
+	assert.Equal(t, "quote-tap.default", backend.traces[0].tapID)
+	assert.Equal(t, uint64(1), backend.traces[0].traceID)
+	assert.Equal(t, "quote-tap.default", backend.traces[1].tapID)
+	assert.Equal(t, uint64(2), backend.traces[1].traceID)
+
+	var trace struct {
+		HTTPBufferedTrace struct {
+			Request struct {
+				Headers []struct {
+					Key   string `json:"key"`
+					Value string `json:"value"`
+				} `json:"headers"`
+			} `json:"request"`
+			Response struct {
+				Body struct {
+					AsString string `json:"asString"`
+				} `json:"body"`
+			} `json:"response"`
+		} `json:"httpBufferedTrace"`
+	}
+	require.NoError(t, json.Unmarshal(backend.traces[1].trace, &trace))
+	assert.Equal(t, "/qotm/quote/5", trace.HTTPBufferedTrace.Request.Headers[0].Value)
+	assert.Equal(t, "hello", trace.HTTPBufferedTrace.Response.Body.AsString)
+}
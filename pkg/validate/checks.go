@@ -0,0 +1,166 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// CheckResources checks the resources that are for the Ambassador with ambassadorID against each
+// other. Mappings for the same route, which Ambassador splits traffic between, are a mistake
+// unless they set a weight; Mappings and Hosts can only use TLSContexts that are there; and only
+// one Host can have a hostname.
+func CheckResources(resources []Resource, ambassadorID string) []Problem {
+	var mine []Resource
+	for _, r := range resources {
+		if r.IsAmbassador() && r.hasAmbassadorID(ambassadorID) {
+			mine = append(mine, r)
+		}
+	}
+
+	var problems []Problem
+	problems = append(problems, checkRoutes(mine)...)
+	problems = append(problems, checkTLSContexts(mine)...)
+	problems = append(problems, checkHosts(mine)...)
+	return problems
+}
+
+// hasAmbassadorID returns whether the resource is for the Ambassador with id, as its
+// ambassador_id says: a string, or a list of them, or "default" if it doesn't have one.
+func (r Resource) hasAmbassadorID(id string) bool {
+	switch ids := field(r.Object, "spec", "ambassador_id").(type) {
+	case string:
+		return ids == id
+	case []interface{}:
+		for _, have := range ids {
+			if have == id {
+				return true
+			}
+		}
+		return false
+	default:
+		return id == "default"
+	}
+}
+
+// checkRoutes finds Mappings that match the same requests.
+func checkRoutes(resources []Resource) []Problem {
+	routes := map[string][]Resource{}
+	var keys []string
+	for _, r := range resources {
+		if r.Kind() != "Mapping" {
+			continue
+		}
+		spec := r.Object["spec"]
+		key, _ := json.Marshal([]interface{}{
+			field(spec, "host"), field(spec, "host_regex"),
+			field(spec, "prefix"), field(spec, "prefix_regex"),
+			field(spec, "method"), field(spec, "headers"), field(spec, "regex_headers"),
+			field(spec, "query_parameters"), field(spec, "regex_query_parameters"),
+			field(spec, "precedence"),
+		})
+		if _, ok := routes[string(key)]; !ok {
+			keys = append(keys, string(key))
+		}
+		routes[string(key)] = append(routes[string(key)], r)
+	}
+
+	var problems []Problem
+	for _, key := range keys {
+		mappings := routes[key]
+		if len(mappings) < 2 {
+			continue
+		}
+		weighted := false
+		for _, m := range mappings {
+			if field(m.Object, "spec", "weight") != nil {
+				weighted = true
+			}
+		}
+		if weighted {
+			continue
+		}
+		for _, m := range mappings[1:] {
+			problems = append(problems, problem(m,
+				"matches the same requests as %s (%s), and Ambassador will split traffic between them; set weight on both if that's what you want",
+				mappings[0].Key(), where(mappings[0])))
+		}
+	}
+	return problems
+}
+
+// checkTLSContexts finds references to TLSContexts that aren't there, which Ambassador looks up
+// by name alone.
+func checkTLSContexts(resources []Resource) []Problem {
+	contexts := map[string]bool{}
+	for _, r := range resources {
+		if r.Kind() == "TLSContext" {
+			contexts[r.Name()] = true
+		}
+	}
+
+	var problems []Problem
+	for _, r := range resources {
+		var name string
+		switch r.Kind() {
+		case "Mapping", "TCPMapping":
+			// tls is true to originate TLS without a TLSContext.
+			name, _ = field(r.Object, "spec", "tls").(string)
+		case "Host":
+			name = r.str("spec", "tlsContext", "name")
+		}
+		if name != "" && !contexts[name] {
+			problems = append(problems, problem(r, "TLSContext %q does not exist", name))
+		}
+	}
+	return problems
+}
+
+// checkHosts finds Hosts with the same hostname. A Host without a hostname uses its name.
+func checkHosts(resources []Resource) []Problem {
+	hosts := map[string]Resource{}
+	var problems []Problem
+	for _, r := range resources {
+		if r.Kind() != "Host" {
+			continue
+		}
+		hostname := r.str("spec", "hostname")
+		if hostname == "" {
+			hostname = r.Name()
+		}
+		if other, ok := hosts[hostname]; ok {
+			problems = append(problems, problem(r, "hostname %q is already the hostname of %s (%s)",
+				hostname, other.Key(), where(other)))
+			continue
+		}
+		hosts[hostname] = r
+	}
+	return problems
+}
+
+// field returns the field of obj at path, or nil if there isn't one.
+func field(obj interface{}, path ...string) interface{} {
+	for _, name := range path {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj = m[name]
+	}
+	return obj
+}
+
+func where(r Resource) string {
+	return fmt.Sprintf("%s, document %d", r.File, r.Document)
+}
+
+// sortProblems sorts problems by file and document, keeping the order of the problems of each
+// document.
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Document < problems[j].Document
+	})
+}
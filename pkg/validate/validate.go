@@ -0,0 +1,195 @@
+// Package validate checks a directory of Ambassador's resources without a cluster, so that CI
+// can catch mistakes before they're applied: each resource is checked against the schema in
+// Ambassador's CRDs, and then the resources are checked against each other for the mistakes that
+// a schema can't catch, like two Mappings for the same route, a reference to a TLSContext that
+// isn't there, or two Hosts for the same hostname.
+package validate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/datawire/ambassador/pkg/kates"
+)
+
+// A Resource is one of the documents in a manifest file.
+type Resource struct {
+	File     string
+	Document int // from 1, in its file
+	Object   map[string]interface{}
+}
+
+func (r Resource) str(key string, fields ...string) string {
+	s, _ := field(r.Object, append([]string{key}, fields...)...).(string)
+	return s
+}
+
+// Kind returns the kind of the resource.
+func (r Resource) Kind() string { return r.str("kind") }
+
+// Name returns the name of the resource.
+func (r Resource) Name() string { return r.str("metadata", "name") }
+
+// Namespace returns the namespace of the resource.
+func (r Resource) Namespace() string { return r.str("metadata", "namespace") }
+
+// Key returns "Kind namespace/name", as the entrypoint names resources in its logs.
+func (r Resource) Key() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind(), r.Namespace(), r.Name())
+}
+
+// IsAmbassador returns whether the resource is one of Ambassador's.
+func (r Resource) IsAmbassador() bool {
+	group := strings.SplitN(r.str("apiVersion"), "/", 2)[0]
+	return group == "getambassador.io" || strings.HasSuffix(group, ".getambassador.io")
+}
+
+// A Problem is something wrong with a resource, or with a file if Resource is "".
+type Problem struct {
+	File     string `json:"file"`
+	Document int    `json:"document,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	where := p.File
+	if p.Document > 0 {
+		where = fmt.Sprintf("%s (document %d)", p.File, p.Document)
+	}
+	if p.Resource != "" {
+		return fmt.Sprintf("%s: %s: %s", where, p.Resource, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", where, p.Message)
+}
+
+func problem(r Resource, format string, args ...interface{}) Problem {
+	return Problem{File: r.File, Document: r.Document, Resource: r.Key(), Message: fmt.Sprintf(format, args...)}
+}
+
+// LoadDir loads the resources in every .yaml, .yml and .json file under dir. Resources without a
+// namespace get namespace. A file that doesn't parse is a Problem, not an error.
+func LoadDir(dir, namespace string) ([]Resource, []Problem, error) {
+	var resources []Resource
+	var problems []Problem
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		text, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rs, err := parseManifests(file, text, namespace)
+		if err != nil {
+			problems = append(problems, Problem{File: file, Message: err.Error()})
+		}
+		resources = append(resources, rs...)
+		return nil
+	})
+	return resources, problems, err
+}
+
+// parseManifests parses the documents in text, and returns the ones that parsed, and the first
+// error if any didn't.
+func parseManifests(file string, text []byte, namespace string) ([]Resource, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(text)))
+	var resources []Resource
+	var firstErr error
+	for doc := 1; ; doc++ {
+		bs, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return resources, err
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(bs, &obj); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("document %d: %w", doc, err)
+			}
+			continue
+		}
+		if obj == nil {
+			continue // an empty document
+		}
+		r := Resource{File: file, Document: doc, Object: obj}
+		if r.Namespace() == "" {
+			metadata, _ := obj["metadata"].(map[string]interface{})
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+				obj["metadata"] = metadata
+			}
+			metadata["namespace"] = namespace
+		}
+		resources = append(resources, r)
+	}
+	return resources, firstErr
+}
+
+// CheckSchemas checks each of Ambassador's resources against the schema in crds, which are
+// Ambassador's CustomResourceDefinitions.
+func CheckSchemas(ctx context.Context, crds []kates.Object, resources []Resource) ([]Problem, error) {
+	validator, err := kates.NewValidator(nil, crds)
+	if err != nil {
+		return nil, err
+	}
+	var problems []Problem
+	for _, r := range resources {
+		if !r.IsAmbassador() {
+			continue
+		}
+		if err := validator.Validate(ctx, &kates.Unstructured{Object: r.Object}); err != nil {
+			for _, line := range strings.Split(strings.TrimSpace(err.Error()), "\n") {
+				problems = append(problems, problem(r, "%s", line))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// Validate loads the resources in dir and checks them, with the CRDs in crdFile, for the
+// Ambassador with ambassadorID. It returns the Problems in order of file and document.
+func Validate(ctx context.Context, dir, crdFile, namespace, ambassadorID string) ([]Problem, error) {
+	crdYAML, err := ioutil.ReadFile(crdFile)
+	if err != nil {
+		return nil, err
+	}
+	crds, err := kates.ParseManifests(string(crdYAML))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", crdFile, err)
+	}
+
+	resources, problems, err := LoadDir(dir, namespace)
+	if err != nil {
+		return nil, err
+	}
+	schemaProblems, err := CheckSchemas(ctx, crds, resources)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, schemaProblems...)
+	problems = append(problems, CheckResources(resources, ambassadorID)...)
+
+	sortProblems(problems)
+	return problems, nil
+}
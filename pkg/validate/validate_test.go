@@ -0,0 +1,68 @@
+package validate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resource(doc int, kind, name string, spec map[string]interface{}) Resource {
+	return Resource{File: "test.yaml", Document: doc, Object: map[string]interface{}{
+		"apiVersion": "getambassador.io/v2",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec":       spec,
+	}}
+}
+
+func TestCheckResources(t *testing.T) {
+	resources := []Resource{
+		resource(1, "Mapping", "a", map[string]interface{}{"prefix": "/a/", "method": "GET"}),
+		resource(2, "Mapping", "b", map[string]interface{}{"prefix": "/a/", "method": "GET"}),
+		resource(3, "Mapping", "c", map[string]interface{}{"prefix": "/a/", "method": "POST"}),
+		resource(4, "Mapping", "d", map[string]interface{}{"prefix": "/a/", "method": "GET", "ambassador_id": "other"}),
+		resource(5, "TCPMapping", "e", map[string]interface{}{"port": 2222.0, "tls": true}),
+		resource(6, "Host", "f", map[string]interface{}{"tlsContext": map[string]interface{}{"name": "f-tls"}}),
+		resource(7, "Host", "g", map[string]interface{}{"hostname": "f"}),
+	}
+
+	assert.Equal(t, []Problem{
+		{File: "test.yaml", Document: 2, Resource: "Mapping default/b",
+			Message: "matches the same requests as Mapping default/a (test.yaml, document 1), and Ambassador will split traffic between them; set weight on both if that's what you want"},
+		{File: "test.yaml", Document: 6, Resource: "Host default/f", Message: `TLSContext "f-tls" does not exist`},
+		{File: "test.yaml", Document: 7, Resource: "Host default/g",
+			Message: `hostname "f" is already the hostname of Host default/f (test.yaml, document 6)`},
+	}, CheckResources(resources, "default"))
+
+	// For another Ambassador, only d counts.
+	assert.Equal(t, []Problem(nil), CheckResources(resources, "other"))
+}
+
+func TestValidate(t *testing.T) {
+	problems, err := Validate(context.Background(), "testdata/manifests",
+		"../../docs/yaml/ambassador/ambassador-crds.yaml", "default", "default")
+	require.NoError(t, err)
+
+	var where []string
+	for _, p := range problems {
+		where = append(where, p.String())
+	}
+	require.Len(t, problems, 5, where)
+
+	assert.Equal(t, "testdata/manifests/broken.yaml", problems[0].File)
+	assert.Equal(t, "", problems[0].Resource)
+
+	assert.Equal(t, Problem{File: "testdata/manifests/hosts.yaml", Document: 2, Resource: "Host default/example-again",
+		Message: `hostname "example.com" is already the hostname of Host default/example (testdata/manifests/hosts.yaml, document 1)`},
+		problems[1])
+
+	assert.Equal(t, "Mapping default/quote-copy", problems[2].Resource)
+	assert.Equal(t, Problem{File: "testdata/manifests/mappings.yaml", Document: 5, Resource: "Mapping backends/upstream",
+		Message: `TLSContext "upstream-tls" does not exist`}, problems[3])
+
+	// The schema catches what the other checks can't.
+	assert.Equal(t, "Mapping default/bad-prefix", problems[4].Resource)
+	assert.Contains(t, problems[4].Message, "prefix")
+}
@@ -0,0 +1,370 @@
+package wasmfetch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Media types of the manifests that a Puller understands.
+const (
+	ociManifestType    = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexType       = "application/vnd.oci.image.index.v1+json"
+	dockerManifestType = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerListType     = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// WasmLayerType is the media type of a layer that is just a module, as in the images that
+// "wasme build" and the WebAssembly Hub make.
+const WasmLayerType = "application/vnd.module.wasm.content.layer.v1+wasm"
+
+// A Puller pulls modules out of images in OCI registries. An image either has a layer of type
+// WasmLayerType, or just one layer, a tarball (gzipped or not) with a plugin.wasm or a single
+// .wasm file in it, as "FROM scratch; COPY plugin.wasm ." builds.
+type Puller struct {
+	// Client makes the requests; if it's nil, http.DefaultClient does.
+	Client *http.Client
+	// PlainHTTP talks to registries over HTTP rather than HTTPS.
+	PlainHTTP bool
+}
+
+// Pull returns the module in image, logging in to its registry with creds if they aren't nil.
+// It checks that each blob has the digest that its manifest says it does, but not that the
+// module is a Wasm module with a particular checksum; that's Verify's job.
+func (p *Puller) Pull(ctx context.Context, image string, creds *Credentials) ([]byte, error) {
+	ref, err := parseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{puller: p, ref: ref, creds: creds}
+	module, err := s.pull(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", image, err)
+	}
+	return module, nil
+}
+
+// Registry returns the registry that image is pulled from, which is what its credentials are
+// looked up by in a docker config.
+func Registry(image string) (string, error) {
+	ref, err := parseReference(image)
+	if err != nil {
+		return "", err
+	}
+	return ref.registry, nil
+}
+
+// A reference is an image's name, split up.
+type reference struct {
+	registry   string
+	repository string
+	// reference is a tag or a digest.
+	reference string
+}
+
+// parseReference parses an image name the way docker does: the registry defaults to Docker
+// Hub, where a repository without a "/" is in library/, and the tag defaults to "latest".
+func parseReference(image string) (reference, error) {
+	var ref reference
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.reference = name[:i], name[i+1:]
+	} else {
+		ref.reference = "latest"
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.registry, ref.repository = parts[0], parts[1]
+	} else {
+		ref.registry, ref.repository = "docker.io", name
+	}
+	if registryHost(ref.registry) == "registry-1.docker.io" && !strings.Contains(ref.repository, "/") {
+		ref.repository = "library/" + ref.repository
+	}
+
+	if ref.repository == "" || ref.reference == "" {
+		return reference{}, fmt.Errorf("invalid image %q", image)
+	}
+	return ref, nil
+}
+
+// A session is one Pull, which logs in at most once.
+type session struct {
+	puller        *Puller
+	ref           reference
+	creds         *Credentials
+	authorization string
+	loggedIn      bool
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+	// Manifests is set if this is an index rather than an image manifest.
+	Manifests []descriptor `json:"manifests"`
+}
+
+func (s *session) pull(ctx context.Context) ([]byte, error) {
+	m, err := s.manifest(ctx, s.ref.reference)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Manifests) > 0 {
+		// A module doesn't care what platform it's on, so any of them will do.
+		if m, err = s.manifest(ctx, m.Manifests[0].Digest); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, layer := range m.Layers {
+		if layer.MediaType == WasmLayerType {
+			return s.blob(ctx, layer)
+		}
+	}
+	if len(m.Layers) != 1 {
+		return nil, fmt.Errorf("image has %d layers and none of them is a %s", len(m.Layers), WasmLayerType)
+	}
+	layer, err := s.blob(ctx, m.Layers[0])
+	if err != nil {
+		return nil, err
+	}
+	return moduleFromLayer(layer)
+}
+
+func (s *session) manifest(ctx context.Context, reference string) (*manifest, error) {
+	body, err := s.get(ctx, "manifests/"+reference,
+		ociManifestType, ociIndexType, dockerManifestType, dockerListType)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", reference, err)
+	}
+	return &m, nil
+}
+
+func (s *session) blob(ctx context.Context, d descriptor) ([]byte, error) {
+	if !strings.HasPrefix(d.Digest, "sha256:") {
+		return nil, fmt.Errorf("blob %s: unsupported digest", d.Digest)
+	}
+	body, err := s.get(ctx, "blobs/"+d.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if sum := "sha256:" + Checksum(body); sum != d.Digest {
+		return nil, fmt.Errorf("blob %s: digest is %s", d.Digest, sum)
+	}
+	return body, nil
+}
+
+// get GETs a path under the repository, logging in if the registry asks it to.
+func (s *session) get(ctx context.Context, subpath string, accept ...string) ([]byte, error) {
+	scheme := "https"
+	if s.puller.PlainHTTP {
+		scheme = "http"
+	}
+	u := (&url.URL{
+		Scheme: scheme,
+		Host:   registryHost(s.ref.registry),
+		Path:   path.Join("/v2", s.ref.repository, subpath),
+	}).String()
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, mediaType := range accept {
+			req.Header.Add("Accept", mediaType)
+		}
+		if s.authorization != "" {
+			req.Header.Set("Authorization", s.authorization)
+		}
+		res, err := s.puller.client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := readLimited(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", subpath, err)
+		}
+
+		switch {
+		case res.StatusCode == http.StatusUnauthorized && !s.loggedIn:
+			s.loggedIn = true
+			if err := s.login(ctx, res.Header.Get("WWW-Authenticate")); err != nil {
+				return nil, err
+			}
+		case res.StatusCode != http.StatusOK:
+			return nil, fmt.Errorf("%s: %s", subpath, res.Status)
+		default:
+			return body, nil
+		}
+	}
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// login sets the session's authorization to answer a WWW-Authenticate challenge: a Bearer
+// challenge needs a token from the registry's token service, a Basic one just the creds.
+func (s *session) login(ctx context.Context, challenge string) error {
+	scheme := strings.SplitN(challenge, " ", 2)[0]
+	params := map[string]string{}
+	for _, match := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if s.creds == nil {
+			return errors.New("registry needs credentials")
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(s.creds.Username, s.creds.Password)
+		s.authorization = req.Header.Get("Authorization")
+		return nil
+	case "bearer":
+		token, err := s.token(ctx, params)
+		if err != nil {
+			return fmt.Errorf("getting a token: %w", err)
+		}
+		s.authorization = "Bearer " + token
+		return nil
+	default:
+		return fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+}
+
+func (s *session) token(ctx context.Context, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+s.ref.repository+":pull")
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if s.creds != nil {
+		req.SetBasicAuth(s.creds.Username, s.creds.Password)
+	}
+	res, err := s.puller.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", errors.New(res.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("no token in response")
+}
+
+func (p *Puller) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return http.DefaultClient
+}
+
+// readLimited reads all of r, or fails if there's more than MaxModuleSize of it.
+func readLimited(r io.Reader) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, MaxModuleSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MaxModuleSize {
+		return nil, fmt.Errorf("more than %d bytes", MaxModuleSize)
+	}
+	return body, nil
+}
+
+// moduleFromLayer returns the plugin.wasm in a layer, or its only .wasm file if it doesn't
+// have a plugin.wasm.
+func moduleFromLayer(layer []byte) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(layer)
+	if bytes.HasPrefix(layer, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("layer: %w", err)
+		}
+		r = gz
+	}
+
+	var module []byte
+	var found []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("layer: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Ext(hdr.Name) != ".wasm" {
+			continue
+		}
+		if hdr.Size > MaxModuleSize {
+			return nil, fmt.Errorf("layer: %s is %d bytes, more than the limit of %d", hdr.Name, hdr.Size, MaxModuleSize)
+		}
+		body, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("layer: %s: %w", hdr.Name, err)
+		}
+		if path.Base(hdr.Name) == "plugin.wasm" {
+			return body, nil
+		}
+		module = body
+		found = append(found, hdr.Name)
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, errors.New("layer has no .wasm file")
+	case 1:
+		return module, nil
+	default:
+		return nil, fmt.Errorf("layer has no plugin.wasm and more than one .wasm file: %s", strings.Join(found, ", "))
+	}
+}
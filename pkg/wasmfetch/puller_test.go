@@ -0,0 +1,178 @@
+package wasmfetch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	testcases := map[string]reference{
+		"filter":                          {"docker.io", "library/filter", "latest"},
+		"example/filter:v1":               {"docker.io", "example/filter", "v1"},
+		"ghcr.io/example/filter":          {"ghcr.io", "example/filter", "latest"},
+		"localhost/filter@sha256:abc":     {"localhost", "filter", "sha256:abc"},
+		"registry:5000/team/filter:1.0.0": {"registry:5000", "team/filter", "1.0.0"},
+	}
+	for image, want := range testcases {
+		ref, err := parseReference(image)
+		require.NoError(t, err, image)
+		assert.Equal(t, want, ref, image)
+	}
+
+	_, err := parseReference("ghcr.io/")
+	assert.Error(t, err)
+}
+
+// registry is a fake registry, serving one repository, "example/filter".
+type registry struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	// token, if it's set, is what requests have to be authorized with.
+	token string
+}
+
+func (r *registry) addBlob(blob []byte) descriptor {
+	digest := "sha256:" + Checksum(blob)
+	r.blobs[digest] = blob
+	return descriptor{Digest: digest, Size: int64(len(blob))}
+}
+
+func (r *registry) addManifest(ref string, m manifest) {
+	body, _ := json.Marshal(m)
+	r.manifests[ref] = body
+	r.manifests["sha256:"+Checksum(body)] = body
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if req.URL.Query().Get("scope") != "repository:example/filter:pull" {
+			http.Error(w, "wrong scope", http.StatusForbidden)
+			return
+		}
+		if user, pass, _ := req.BasicAuth(); user != "user" || pass != "pass" {
+			http.Error(w, "wrong credentials", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": r.token})
+		return
+	}
+
+	if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.Header().Set("WWW-Authenticate",
+			`Bearer realm="http://`+req.Host+`/token",service="test"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/example/filter/")
+	var body []byte
+	switch {
+	case strings.HasPrefix(path, "manifests/"):
+		body = r.manifests[strings.TrimPrefix(path, "manifests/")]
+	case strings.HasPrefix(path, "blobs/"):
+		body = r.blobs[strings.TrimPrefix(path, "blobs/")]
+	}
+	if body == nil {
+		http.NotFound(w, req)
+		return
+	}
+	_, _ = w.Write(body)
+}
+
+func newRegistry() (*registry, string, func()) {
+	r := &registry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	srv := httptest.NewServer(r)
+	return r, strings.TrimPrefix(srv.URL, "http://"), srv.Close
+}
+
+func tarball(t *testing.T, gzipped bool, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if gzipped {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	for name, body := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(body)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	if gz != nil {
+		require.NoError(t, gz.Close())
+	}
+	return buf.Bytes()
+}
+
+func TestPull(t *testing.T) {
+	r, host, closeRegistry := newRegistry()
+	defer closeRegistry()
+	puller := &Puller{PlainHTTP: true}
+	ctx := context.Background()
+
+	// A layer that's just the module.
+	layer := r.addBlob(module)
+	layer.MediaType = WasmLayerType
+	config := r.addBlob([]byte("{}"))
+	r.addManifest("wasm", manifest{MediaType: ociManifestType, Layers: []descriptor{config, layer}})
+
+	// A single layer, as docker builds it.
+	layer = r.addBlob(tarball(t, true, map[string][]byte{"plugin.wasm": module, "README": []byte("hi")}))
+	layer.MediaType = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	r.addManifest("docker", manifest{MediaType: dockerManifestType, Layers: []descriptor{layer}})
+
+	// An index with that in it.
+	r.addManifest("index", manifest{MediaType: ociIndexType,
+		Manifests: []descriptor{{MediaType: dockerManifestType, Digest: "sha256:" + Checksum(r.manifests["docker"])}}})
+
+	for _, tag := range []string{"wasm", "docker", "index"} {
+		got, err := puller.Pull(ctx, host+"/example/filter:"+tag, nil)
+		require.NoError(t, err, tag)
+		assert.Equal(t, module, got, tag)
+	}
+
+	_, err := puller.Pull(ctx, host+"/example/filter:missing", nil)
+	assert.Error(t, err)
+
+	// A layer without a module.
+	layer = r.addBlob(tarball(t, false, map[string][]byte{"README": []byte("hi")}))
+	r.addManifest("nomodule", manifest{Layers: []descriptor{layer}})
+	_, err = puller.Pull(ctx, host+"/example/filter:nomodule", nil)
+	assert.EqualError(t, err, host+"/example/filter:nomodule: layer has no .wasm file")
+
+	// A blob that isn't what its digest says.
+	layer = r.addBlob(module)
+	layer.MediaType = WasmLayerType
+	r.blobs[layer.Digest] = []byte("tampered")
+	r.addManifest("tampered", manifest{Layers: []descriptor{layer}})
+	_, err = puller.Pull(ctx, host+"/example/filter:tampered", nil)
+	assert.Error(t, err)
+}
+
+func TestPullWithToken(t *testing.T) {
+	r, host, closeRegistry := newRegistry()
+	defer closeRegistry()
+	r.token = "sesame"
+	layer := r.addBlob(module)
+	layer.MediaType = WasmLayerType
+	r.addManifest("v1", manifest{Layers: []descriptor{layer}})
+
+	puller := &Puller{PlainHTTP: true}
+	got, err := puller.Pull(context.Background(), host+"/example/filter:v1", &Credentials{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+	assert.Equal(t, module, got)
+
+	_, err = puller.Pull(context.Background(), host+"/example/filter:v1", &Credentials{Username: "user", Password: "wrong"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Package wasmfetch gets the modules of WasmFilters for the control plane: it pulls them out of
+// OCI images, and checks that what it got is a Wasm module with the checksum that the
+// WasmFilter asked for. Envoy is only ever handed a module that's on disk and verified.
+package wasmfetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxModuleSize is the biggest module that Verify accepts, and the most that a Puller reads of
+// any one blob.
+const MaxModuleSize = 64 << 20
+
+// wasmMagic starts every Wasm binary.
+var wasmMagic = []byte{0x00, 'a', 's', 'm'}
+
+// Checksum returns the hex SHA-256 checksum of a module.
+func Checksum(module []byte) string {
+	sum := sha256.Sum256(module)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that module is a Wasm binary and, if want isn't empty, that its checksum is
+// want. It returns the module's checksum.
+func Verify(module []byte, want string) (string, error) {
+	if len(module) > MaxModuleSize {
+		return "", fmt.Errorf("module is %d bytes, more than the limit of %d", len(module), MaxModuleSize)
+	}
+	if !bytes.HasPrefix(module, wasmMagic) {
+		return "", errors.New("not a Wasm module")
+	}
+	sum := Checksum(module)
+	if want != "" && !strings.EqualFold(want, sum) {
+		return "", fmt.Errorf("module has checksum %s, not %s", sum, want)
+	}
+	return sum, nil
+}
+
+// Credentials are what a Puller logs in to a registry with.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialsFromDockerConfig returns the credentials for registry from the contents of a
+// .dockerconfigjson, as in a kubernetes.io/dockerconfigjson Secret. It returns nil if there
+// aren't any for registry.
+func CredentialsFromDockerConfig(dockerConfig []byte, registry string) (*Credentials, error) {
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return nil, fmt.Errorf("docker config: %w", err)
+	}
+	for server, auth := range config.Auths {
+		if registryHost(server) != registryHost(registry) {
+			continue
+		}
+		creds := &Credentials{Username: auth.Username, Password: auth.Password}
+		if auth.Auth != "" {
+			userpass, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("docker config: %s: %w", server, err)
+			}
+			parts := strings.SplitN(string(userpass), ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("docker config: %s: auth isn't user:password", server)
+			}
+			creds.Username, creds.Password = parts[0], parts[1]
+		}
+		return creds, nil
+	}
+	return nil, nil
+}
+
+// registryHost returns the host that a registry, as named in an image or in a docker config,
+// is served from.
+func registryHost(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	registry = strings.SplitN(registry, "/", 2)[0]
+	switch registry {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return "registry-1.docker.io"
+	}
+	return registry
+}
@@ -0,0 +1,48 @@
+package wasmfetch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// module is the smallest Wasm module there is: the magic number and version 1.
+var module = []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+
+func TestVerify(t *testing.T) {
+	sum, err := Verify(module, "")
+	require.NoError(t, err)
+	assert.Equal(t, Checksum(module), sum)
+
+	_, err = Verify(module, sum)
+	assert.NoError(t, err)
+
+	_, err = Verify(module, Checksum([]byte("something else")))
+	assert.Error(t, err)
+
+	_, err = Verify([]byte("#!/bin/sh\n"), "")
+	assert.EqualError(t, err, "not a Wasm module")
+}
+
+func TestCredentialsFromDockerConfig(t *testing.T) {
+	config := []byte(`{"auths": {
+		"https://index.docker.io/v1/": {"auth": "aHViOnNlY3JldA=="},
+		"ghcr.io": {"username": "gh", "password": "token"}
+	}}`)
+
+	creds, err := CredentialsFromDockerConfig(config, "docker.io")
+	require.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "hub", Password: "secret"}, creds)
+
+	creds, err = CredentialsFromDockerConfig(config, "ghcr.io")
+	require.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "gh", Password: "token"}, creds)
+
+	creds, err = CredentialsFromDockerConfig(config, "quay.io")
+	require.NoError(t, err)
+	assert.Nil(t, creds)
+
+	_, err = CredentialsFromDockerConfig([]byte(`{"auths": {"quay.io": {"auth": "bm9jb2xvbg=="}}}`), "quay.io")
+	assert.Error(t, err)
+}
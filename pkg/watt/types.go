@@ -11,6 +11,7 @@ import (
 
 type ConsulSnapshot struct {
 	Endpoints map[string]consulwatch.Endpoints `json:",omitempty"`
+	Connect   map[string]consulwatch.Connect   `json:",omitempty"`
 }
 
 func (s *ConsulSnapshot) DeepCopy() (*ConsulSnapshot, error) {
@@ -61,11 +61,16 @@ class Config:
     single_namespace: ClassVar[bool] = bool(os.environ.get('AMBASSADOR_SINGLE_NAMESPACE'))
     certs_single_namespace: ClassVar[bool] = bool(os.environ.get('AMBASSADOR_CERTS_SINGLE_NAMESPACE', os.environ.get('AMBASSADOR_SINGLE_NAMESPACE')))
     enable_endpoints: ClassVar[bool] = not bool(os.environ.get('AMBASSADOR_DISABLE_ENDPOINTS'))
+    # The zone that this Ambassador runs in, if we know it, for zone-aware routing to endpoints.
+    ambassador_zone: ClassVar[str] = os.environ.get('AMBASSADOR_ZONE', '')
     fast_validation: ClassVar[bool] = bool(os.environ.get('AMBASSADOR_FAST_VALIDATION'))
 
     StorageByKind: ClassVar[Dict[str, str]] = {
         'authservice': "auth_configs",
         'consulresolver': "resolvers",
+        'dnsresolver': "resolvers",
+        'multiclusterresolver': "resolvers",
+        'staticresolver': "resolvers",
         'host': "hosts",
         'mapping': "mappings",
         'kubernetesendpointresolver': "resolvers",
@@ -75,6 +80,9 @@ class Config:
         'tlscontext': "tls_contexts",
         'tracingservice': "tracing_configs",
         'logservice': "log_services",
+        'statssink': "stats_sinks",
+        'tappolicy': "tap_policies",
+        'wasmfilter': "wasm_filters",
     }
 
     SupportedVersions: ClassVar[Dict[str, str]] = {
@@ -84,11 +92,15 @@ class Config:
     }
 
     NoSchema: ClassVar = {
+        'configmap',
         'secret',
         'service',
         'consulresolver',
+        'dnsresolver',
         'kubernetesendpointresolver',
-        'kubernetesserviceresolver'
+        'kubernetesserviceresolver',
+        'multiclusterresolver',
+        'staticresolver'
     }
 
     # INSTANCE VARIABLES
@@ -149,6 +161,9 @@ class Config:
         self.k8s_status_updates: Dict[str, Tuple[str, str, Optional[Dict[str, Any]]]] = {}  # Tuple is (name, namespace, status_json)
         self.k8s_ingresses: Dict[str, Any] = {}
         self.k8s_ingress_classes: Dict[str, Any] = {}
+        self.k8s_ingress_class_parameters: Dict[str, Any] = {}
+        self.k8s_events: List[Tuple[str, str, str, str, str]] = []  # Tuple is (kind, name, namespace, reason, message)
+        self.k8s_certificates: Dict[str, Dict[str, Any]] = {}  # cert-manager Certificates, by secret_name.namespace
         self.pod_labels: Dict[str, str] = {}
         self._reset()
 
@@ -774,6 +789,23 @@ class Config:
 
         storage[key] = resource
 
+    def handle_configmap(self, resource: ACResource) -> None:
+        """
+        Handles a ConfigMap resource, which holds Lua scripts. We need a handler for this
+        because the key needs to be the name and namespace, which is how Modules and Mappings
+        refer to it.
+        """
+
+        storage = self.config.setdefault('config_maps', {})
+        key = f'{resource.name}.{resource.namespace}'
+
+        if key in storage:
+            self.post_error("%s defines %s %s, which is already defined by %s" %
+                            (resource, resource.kind, key, storage[key].location),
+                            resource=resource)
+
+        storage[key] = resource
+
     def handle_ingress(self, resource: ACResource) -> None:
         storage = self.config.setdefault('ingresses', {})
         key = resource.rkey
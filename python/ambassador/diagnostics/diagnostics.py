@@ -12,7 +12,7 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
-from typing import Any, Dict, List, Optional, Tuple
+from typing import Any, Dict, List, Optional, Set, Tuple
 from typing import cast as typecast
 
 import json
@@ -23,6 +23,7 @@ from ..ir import IR
 from ..ir.irbasemappinggroup import IRBaseMappingGroup
 from ..ir.irhttpmappinggroup import IRHTTPMappingGroup
 from ..envoy import EnvoyConfig
+from ..fetch.tenancy import resource_tenant
 from .envoy_stats import EnvoyStats
 
 
@@ -108,14 +109,15 @@ class DiagResult:
     A DiagResult is the result of a diagnostics request, whether for an
     overview or for a particular key.
     """
-    def __init__(self, diag: 'Diagnostics', estat: EnvoyStats, request) -> None:
+    def __init__(self, diag: 'Diagnostics', estat: EnvoyStats, request, tenant: Optional[str]=None) -> None:
         self.diag = diag
         self.logger = self.diag.logger
         self.estat = estat
 
-        # Go ahead and grab Envoy cluster stats for all possible clusters.
+        # Go ahead and grab Envoy cluster stats for all possible clusters, or all of a tenant's.
         # XXX This might be a bit silly.
-        self.cluster_names = [ cluster.envoy_name for cluster in self.diag.clusters.values() ]
+        self.cluster_names = [ cluster.envoy_name for key, cluster in self.diag.clusters.items()
+                               if (tenant is None) or self.diag.owned_by(tenant, key) ]
         self.cstats = { name: self.estat.cluster_stats(name) for name in self.cluster_names }
 
         # Save the request host and scheme. We'll need them later.
@@ -342,6 +344,11 @@ class Diagnostics:
     source_map: Dict[str, Dict[str, bool]]
 
     reKeyIndex = re.compile(r'\.(\d+)$')
+    reVClusterName = re.compile(r'\.vcluster\.(.+)\.$')
+
+    # The labels that Envoy's Prometheus metrics have for clusters and per-Mapping stats.
+    reClusterLabel = re.compile(r'envoy_cluster_name="([^"]*)"')
+    reVClusterLabel = re.compile(r'envoy_virtual_cluster="([^"]*)"')
 
     filter_map = {
         'IRAuth': 'AuthService',
@@ -376,6 +383,9 @@ class Diagnostics:
         self.ambassador_services: List[dict] = []
         self.ambassador_resolvers: List[dict] = []
 
+        # self.tenants has the tenant that each resource claims, by fully-qualified key.
+        self.tenants: Dict[str, str] = {}
+
         # Warn people about upcoming deprecations.
 
         warn_auth = False
@@ -460,6 +470,12 @@ class Diagnostics:
 
             self.remember_source(uqkey, fqkey, location, rsrc.rkey)
 
+            tenant = resource_tenant(rsrc)
+
+            if tenant:
+                self.tenants[rsrc.rkey] = tenant
+                self.tenants[fqkey] = tenant
+
             ambassador_element: dict = self.ambassador_elements.setdefault(
                 fqkey,
                 {
@@ -495,6 +511,20 @@ class Diagnostics:
         self.clusters = { cluster.name: cluster for cluster in self.ir.clusters.values()
                           if cluster.location != "--diagnostics--" }
 
+        # Which Mappings use each cluster, and which Mapping each set of per-Mapping stats
+        # belongs to, so that we know which of them belong to a tenant.
+        self.cluster_rkeys: Dict[str, Set[str]] = {}
+        self.mapping_rkeys: Dict[str, str] = {}
+
+        for group in self.groups.values():
+            for mapping in group.mappings:
+                cluster = mapping.get('cluster', None)
+
+                if cluster:
+                    self.cluster_rkeys.setdefault(cluster.name, set()).add(mapping.rkey)
+
+                self.mapping_rkeys[f"{mapping.name}.{mapping.namespace}"] = mapping.rkey
+
         # Build up our Ambassador services too (auth, ratelimit, tracing).
         self.ambassador_services = []
 
@@ -551,11 +581,17 @@ class Diagnostics:
         :param group_list: list of groups that use this resolver
         """
 
+        services = self.ir.resolved_services.get(resolver.name, {})
+
         self.ambassador_resolvers.append({
             'kind': resolver.kind,
             '_source': resolver.location,
             'name': resolver.name,
-            'groups': group_list
+            'namespace': resolver.namespace,
+            'groups': group_list,
+            'services': len(services),
+            'endpoints': sum(services.values()),
+            'empty_services': sorted([ svc for svc, count in services.items() if not count ])
         })
 
     @staticmethod
@@ -578,21 +614,119 @@ class Diagnostics:
 
         return key_base, key_index
 
-    def as_dict(self) -> dict:
+    def owned_by(self, tenant: str, key: str) -> bool:
+        """
+        Whether everything that a key covers belongs to a tenant. The key can be anything that
+        lookup() takes, or the key of an error or a notice.
+
+        :param tenant: the tenant
+        :param key: the key of a resource, a source, a group, or a cluster
+        :return: True if the key covers something, and all of it claims the tenant
+        """
+
+        if key in self.tenants:
+            return self.tenants[key] == tenant
+
+        keys: List[str] = []
+
+        if key in self.groups:
+            keys = [ mapping.rkey for mapping in self.groups[key].mappings ]
+        elif key in self.cluster_rkeys:
+            keys = sorted(self.cluster_rkeys[key])
+        elif key in self.source_map:
+            keys = list(self.source_map[key].keys())
+
+        return bool(keys) and all(self.tenants.get(k) == tenant for k in keys)
+
+    def as_dict(self, tenant: Optional[str]=None) -> dict:
+        """
+        Return the whole diagnostics, or only what belongs to a tenant. A tenant sees none of
+        the Ambassador-wide services and resolvers, or anything else that it doesn't own.
+
+        :param tenant: the tenant to filter for, if any
+        :return: the diagnostics
+        """
+
+        mapping_stats = getattr(self.econf, 'mapping_stats', {})
+
+        if tenant is None:
+            return {
+                'source_map': self.source_map,
+                'ambassador_services': self.ambassador_services,
+                'ambassador_resolvers': self.ambassador_resolvers,
+                'ambassador_elements': self.ambassador_elements,
+                'envoy_elements': self.envoy_elements,
+                'errors': self.errors,
+                'notices': self.notices,
+                'fast_validation_disagreements': self.fast_validation_disagreements,
+                'groups': { key: self.flattened(value) for key, value in self.groups.items() },
+                # Which Envoy stat prefixes hold each Mapping's per-Mapping stats.
+                'mapping_stats': mapping_stats,
+                # 'clusters': { key: value.as_dict() for key, value in self.clusters.items() },
+                'tlscontexts': [ x.as_dict() for x in self.ir.tls_contexts.values() ]
+            }
+
+        def owned(d: Dict[str, Any]) -> Dict[str, Any]:
+            return { key: value for key, value in d.items() if self.owned_by(tenant, key) }
+
         return {
-            'source_map': self.source_map,
-            'ambassador_services': self.ambassador_services,
-            'ambassador_resolvers': self.ambassador_resolvers,
-            'ambassador_elements': self.ambassador_elements,
-            'envoy_elements': self.envoy_elements,
-            'errors': self.errors,
-            'notices': self.notices,
-            'fast_validation_disagreements': self.fast_validation_disagreements,
-            'groups': { key: self.flattened(value) for key, value in self.groups.items() },
-            # 'clusters': { key: value.as_dict() for key, value in self.clusters.items() },
-            'tlscontexts': [ x.as_dict() for x in self.ir.tls_contexts.values() ]
+            'source_map': owned(self.source_map),
+            'ambassador_services': [],
+            'ambassador_resolvers': [],
+            'ambassador_elements': owned(self.ambassador_elements),
+            'envoy_elements': owned(self.envoy_elements),
+            'errors': owned(self.errors),
+            'notices': owned(self.notices),
+            'fast_validation_disagreements': {},
+            'groups': { key: self.flattened(value) for key, value in self.groups.items()
+                        if self.owned_by(tenant, key) },
+            'mapping_stats': { key: value for key, value in mapping_stats.items()
+                               if self.owned_by(tenant, self.mapping_rkeys.get(key, key)) },
+            'tlscontexts': [ x.as_dict() for x in self.ir.tls_contexts.values()
+                             if self.owned_by(tenant, x.rkey) ]
         }
 
+    def tenant_metrics(self, tenant: str, text: str) -> str:
+        """
+        Filter Envoy's Prometheus metrics down to the samples for a tenant's Envoy clusters
+        and per-Mapping stats, keeping the HELP and TYPE lines of the metrics that have any
+        samples left.
+
+        :param tenant: the tenant
+        :param text: Envoy's Prometheus metrics
+        :return: the tenant's Prometheus metrics
+        """
+
+        cluster_names = { cluster.envoy_name for key, cluster in self.clusters.items()
+                          if self.owned_by(tenant, key) }
+        stats_names: Set[str] = set()
+
+        for key, prefixes in getattr(self.econf, 'mapping_stats', {}).items():
+            if self.owned_by(tenant, self.mapping_rkeys.get(key, key)):
+                for prefix in prefixes:
+                    m = Diagnostics.reVClusterName.search(prefix)
+
+                    if m:
+                        stats_names.add(m.group(1))
+
+        kept: List[str] = []
+        header: List[str] = []
+
+        for line in text.splitlines():
+            if line.startswith('#'):
+                header.append(line)
+                continue
+
+            c = Diagnostics.reClusterLabel.search(line)
+            v = Diagnostics.reVClusterLabel.search(line)
+
+            if (c and (c.group(1) in cluster_names)) or (v and (v.group(1) in stats_names)):
+                kept.extend(header)
+                header = []
+                kept.append(line)
+
+        return ''.join(line + '\n' for line in kept)
+
     def flattened(self, group: IRBaseMappingGroup) -> dict:
         flattened = { k: v for k, v in group.as_dict().items() if k != 'mappings' }
         flattened_mappings = []
@@ -604,6 +738,7 @@ class Diagnostics:
                 "_rkey": m['rkey'],
                 "location": m['location'],
                 "name": m['name'],
+                "namespace": m.get('namespace'),
                 "cluster_service": m.get('cluster', {}).get("service"),
                 "cluster_name": m.get('cluster', {}).get("envoy_name"),
             }
@@ -663,26 +798,30 @@ class Diagnostics:
         if location and (location != uqkey) and (location != fqkey):
             self._remember_source(location, dest_key)
 
-    def overview(self, request, estat: EnvoyStats) -> Dict[str, Any]:
+    def overview(self, request, estat: EnvoyStats, tenant: Optional[str]=None) -> Dict[str, Any]:
         """
         Generate overview data describing the whole Ambassador setup, most
         notably the routing table. Returns the dictionary form of a DiagResult.
 
         :param request: the Flask request being handled
         :param estat: current EnvoyStats
+        :param tenant: if set, describe only the groups that belong to this tenant
         :return: the dictionary form of a DiagResult
         """
 
-        result = DiagResult(self, estat, request)
+        result = DiagResult(self, estat, request, tenant=tenant)
 
         for group in self.ir.ordered_groups():
+            if (tenant is not None) and not self.owned_by(tenant, 'grp-%s' % group.group_id):
+                continue
+
             # TCPMappings are currently handled elsewhere.
             if isinstance(group, IRHTTPMappingGroup):
                 result.include_httpgroup(group)
 
         return result.as_dict()
 
-    def lookup(self, request, key: str, estat: EnvoyStats) -> Optional[Dict[str, Any]]:
+    def lookup(self, request, key: str, estat: EnvoyStats, tenant: Optional[str]=None) -> Optional[Dict[str, Any]]:
         """
         Generate data describing a specific key in the Ambassador setup, and all
         the things connected to it. Returns the dictionary form of a DiagResult.
@@ -693,10 +832,14 @@ class Diagnostics:
         :param request: the Flask request being handled
         :param key: the key of the thing we want
         :param estat: current EnvoyStats
+        :param tenant: if set, the key must belong to this tenant
         :return: the dictionary form of a DiagResult
         """
 
-        result = DiagResult(self, estat, request)
+        if (tenant is not None) and not self.owned_by(tenant, key):
+            return None
+
+        result = DiagResult(self, estat, request, tenant=tenant)
 
         # Typically we'll get handed a group identifier here, but we might get
         # other stuff too, and we have to look for all of it.
@@ -71,6 +71,10 @@ class EnvoyConfig:
     def split_config(self) -> Tuple[Dict[str, Any], Dict[str, Any]]:
         pass
 
+    @abstractmethod
+    def tapds_config(self) -> Dict[str, Any]:
+        pass
+
     @abstractmethod
     def as_dict(self) -> Dict[str, Any]:
         pass
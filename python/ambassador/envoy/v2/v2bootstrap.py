@@ -1,9 +1,12 @@
-from typing import TYPE_CHECKING
+from typing import Any, Dict, List, TYPE_CHECKING
 from typing import cast as typecast
 
+from ...config import Config
 from ...ir.ircluster import IRCluster
+from ...ir.iristio import IstioMTLS
 from ...ir.irlogservice import IRLogService
 from ...ir.irratelimit import IRRateLimit
+from ...ir.irstatssink import IRStatsSink
 from ...ir.irtracing import IRTracing
 
 from .v2cluster import V2Cluster
@@ -59,6 +62,10 @@ class V2Bootstrap(dict):
             }
         })
 
+        # Envoy compares its own zone with the zones of endpoints for zone-aware routing.
+        if Config.ambassador_zone:
+            self['node']['locality'] = { 'zone': Config.ambassador_zone }
+
         clusters = [{
             "name": "xds_cluster",
             "connect_timeout": "1s",
@@ -88,6 +95,35 @@ class V2Bootstrap(dict):
             }
         }]
 
+        # Originating Istio mTLS with certificates from the Istio agent's SDS needs a cluster
+        # for its socket, and bootstrap clusters only change when Envoy restarts.
+        istio_ctx = config.ir.get_tls_context(IstioMTLS.ContextName)
+
+        if istio_ctx and istio_ctx.get('_istio_sds', None):
+            clusters.append({
+                "name": IstioMTLS.SDSCluster,
+                "connect_timeout": "1s",
+                "http2_protocol_options": {},
+                "load_assignment": {
+                    "cluster_name": IstioMTLS.SDSCluster,
+                    "endpoints": [
+                        {
+                            "lb_endpoints": [
+                                {
+                                    "endpoint": {
+                                        "address": {
+                                            "pipe": {
+                                                "path": istio_ctx['_istio_sds']
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    ]
+                }
+            })
+
         if config.tracing:
             self['tracing'] = dict(config.tracing)
 
@@ -110,27 +146,93 @@ class V2Bootstrap(dict):
         #     assert ratelimit.cluster
         #     clusters.append(V2Cluster(config, ratelimit.cluster))
 
+        stats_sinks: List[Dict[str, Any]] = []
+        flush_intervals: List[int] = []
+
         if config.ir.statsd['enabled']:
             name = 'envoy.dog_statsd' if config.ir.statsd['dogstatsd'] else 'envoy.statsd'
-            self['stats_sinks'] = [
-                {
-                    'name': name,
+            stats_sinks.append({
+                'name': name,
+                'config': {
+                    'address': {
+                        'socket_address': {
+                            'protocol': 'UDP',
+                            'address': config.ir.statsd['ip'],
+                            'port_value': 8125
+                        }
+                    }
+                }
+            })
+
+            flush_intervals.append(int(config.ir.statsd['interval']))
+
+        stats_matcher: Dict[str, List[Dict[str, Any]]] = {}
+        stats_tags: List[Dict[str, str]] = []
+
+        for sink in config.ir.stats_sinks.values():
+            stats_sink = typecast(IRStatsSink, sink)
+
+            if stats_sink.driver == 'metrics_service':
+                assert stats_sink.cluster
+                clusters.append(V2Cluster(config, typecast(IRCluster, stats_sink.cluster)))
+
+                stats_sinks.append({
+                    'name': 'envoy.metrics_service',
                     'config': {
-                        'address': {
-                            'socket_address': {
-                                'protocol': 'UDP',
-                                'address': config.ir.statsd['ip'],
-                                'port_value': 8125
+                        'grpc_service': {
+                            'envoy_grpc': {
+                                'cluster_name': stats_sink.cluster.envoy_name
                             }
                         }
                     }
-                }
-            ]
+                })
+            else:
+                sink_config: Dict[str, Any] = { 'address': stats_sink.address }
+
+                if stats_sink.prefix:
+                    sink_config['prefix'] = stats_sink.prefix
+
+                stats_sinks.append({
+                    'name': 'envoy.dog_statsd' if stats_sink.driver == 'dog_statsd' else 'envoy.statsd',
+                    'config': sink_config
+                })
+
+            if stats_sink.flush_interval:
+                flush_intervals.append(stats_sink.flush_interval)
+
+            # The factory has already made sure that the matchers don't mix inclusion and
+            # exclusion, so we can just pile up the patterns.
+            for key, envoy_key in [ ('inclusion_regex', 'inclusion_list'), ('exclusion_regex', 'exclusion_list') ]:
+                for regex in (stats_sink.stats_matcher or {}).get(key, []):
+                    stats_matcher.setdefault(envoy_key, []).append({
+                        'safe_regex': { 'google_re2': {}, 'regex': regex }
+                    })
 
+            for tag in stats_sink.stats_tags:
+                stats_tags.append({ key: value for key, value in tag.items()
+                                    if key in [ 'tag_name', 'regex', 'fixed_value' ] })
+
+        if stats_sinks:
+            self['stats_sinks'] = stats_sinks
+
+        if flush_intervals:
             self['stats_flush_interval'] = {
-                'seconds': config.ir.statsd['interval']
+                'seconds': min(flush_intervals)
             }
 
+        if stats_matcher or stats_tags:
+            stats_config: Dict[str, Any] = {}
+
+            if stats_matcher:
+                stats_config['stats_matcher'] = {
+                    key: { 'patterns': patterns } for key, patterns in stats_matcher.items()
+                }
+
+            if stats_tags:
+                stats_config['stats_tags'] = stats_tags
+
+            self['stats_config'] = stats_config
+
         self['static_resources']['clusters'] = clusters
 
     @classmethod
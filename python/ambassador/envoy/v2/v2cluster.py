@@ -13,7 +13,7 @@
 # limitations under the License
 
 import urllib
-from typing import Dict, List, Union, TYPE_CHECKING
+from typing import Any, Dict, List, Union, TYPE_CHECKING
 
 from ...cache import Cacheable
 from ...ir.ircluster import IRCluster
@@ -53,15 +53,18 @@ class V2Cluster(Cacheable):
             'connect_timeout':"%0.3fs" % (float(cluster.connect_timeout_ms) / 1000.0),
             'load_assignment': {
                 'cluster_name': cluster.envoy_name,
-                'endpoints': [
-                    {
-                        'lb_endpoints': self.get_endpoints(cluster)
-                    }
-                ]
+                'endpoints': self.get_locality_endpoints(cluster)
             },
             'dns_lookup_family': dns_lookup_family
         }
 
+        if any('load_balancing_weight' in e for e in fields['load_assignment']['endpoints']):
+            # The targets came from several Consul datacenters or static localities;
+            # spread the traffic by the localities' weights.
+            fields['common_lb_config'] = {
+                'locality_weighted_lb_config': {}
+            }
+
         if cluster.cluster_idle_timeout_ms:
             cluster_idle_timeout_ms = cluster.cluster_idle_timeout_ms
         else:
@@ -71,10 +74,17 @@ class V2Cluster(Cacheable):
                 'idle_timeout': "%0.3fs" % (float(cluster_idle_timeout_ms) / 1000.0)
             }
 
+        if cluster.get('alt_stat_name', None):
+            fields['alt_stat_name'] = cluster.alt_stat_name
+
         circuit_breakers = self.get_circuit_breakers(cluster)
         if circuit_breakers is not None:
             fields['circuit_breakers'] = circuit_breakers
 
+        health_check = cluster.get('health_check', None)
+        if health_check:
+            fields['health_checks'] = [ self.get_health_check(health_check) ]
+
         if cluster.get('grpc', False):
             self["http2_protocol_options"] = {}
 
@@ -129,6 +139,110 @@ class V2Cluster(Cacheable):
 
         self.update(fields)
 
+    def get_health_check(self, health_check: dict) -> dict:
+        envoy_hc = {
+            'timeout': "%0.3fs" % (float(health_check.get('timeout_ms', 1000)) / 1000.0),
+            'interval': "%0.3fs" % (float(health_check.get('interval_ms', 10000)) / 1000.0),
+            'unhealthy_threshold': health_check.get('unhealthy_threshold', 2),
+            'healthy_threshold': health_check.get('healthy_threshold', 1)
+        }
+
+        if 'http' in health_check:
+            http = health_check['http']
+            http_hc = { 'path': http['path'] }
+
+            if http.get('host', None):
+                http_hc['host'] = http['host']
+
+            # Envoy's ranges are half-open; ours include both ends.
+            statuses = http.get('expected_statuses', [])
+            if statuses:
+                http_hc['expected_statuses'] = [ { 'start': status['min'], 'end': status['max'] + 1 }
+                                                 for status in statuses ]
+
+            envoy_hc['http_health_check'] = http_hc
+        elif 'grpc' in health_check:
+            grpc = health_check['grpc'] or {}
+            envoy_hc['grpc_health_check'] = { key: grpc[key] for key in [ 'service_name', 'authority' ]
+                                              if grpc.get(key, None) }
+        else:
+            # A TCP health check with no payload just checks that we can connect.
+            envoy_hc['tcp_health_check'] = {}
+
+        return envoy_hc
+
+    def get_locality_endpoints(self, cluster: IRCluster):
+        # Targets from a Consul resolver with several datacenters, from a StaticResolver
+        # with localities, or from a MultiClusterResolver carry a locality and a weight, and
+        # maybe a priority.
+        # Those go into one weighted group of endpoints per locality; everything else goes
+        # into a single group.
+        targetlist = cluster.get('targets', [])
+
+        if not any(target.get('locality') for target in targetlist):
+            if any('priority' in target for target in targetlist):
+                return self.get_zone_endpoints(targetlist)
+
+            return [ { 'lb_endpoints': self.get_endpoints(cluster) } ]
+
+        result: List[dict] = []
+        localities: Dict[str, dict] = {}
+
+        for target in targetlist:
+            locality = target.get('locality') or ''
+
+            if locality not in localities:
+                localities[locality] = {
+                    'locality': { 'zone': locality },
+                    'load_balancing_weight': target.get('locality_weight', 1),
+                    'lb_endpoints': []
+                }
+
+                if 'priority' in target:
+                    localities[locality]['priority'] = target['priority']
+
+                result.append(localities[locality])
+
+            localities[locality]['lb_endpoints'].append(self.get_endpoint(target))
+
+        return result
+
+    def get_zone_endpoints(self, targetlist: List[dict]):
+        # Kubernetes endpoints with zones go into one locality per zone, at the priority that
+        # the resolver gave the zone. Envoy only sends traffic to a lower priority when the
+        # higher ones don't have enough healthy endpoints.
+        result: List[dict] = []
+        localities: Dict[str, dict] = {}
+
+        for target in targetlist:
+            target_zone = target.get('zone') or ''
+
+            if target_zone not in localities:
+                localities[target_zone] = {
+                    'locality': { 'zone': target_zone },
+                    'priority': target.get('priority', 0),
+                    'lb_endpoints': []
+                }
+                result.append(localities[target_zone])
+
+            localities[target_zone]['lb_endpoints'].append(self.get_endpoint(target))
+
+        return result
+
+    def get_endpoint(self, target: dict):
+        address = {
+            'address': target['ip'],
+            'port_value': target['port'],
+            'protocol': 'TCP'  # Yes, really. Envoy uses the TLS context to determine whether to originate TLS.
+        }
+        endpoint: Dict[str, Any] = {'endpoint': {'address': {'socket_address': address}}}
+
+        # SRV records weight their targets.
+        if target.get('weight'):
+            endpoint['load_balancing_weight'] = target['weight']
+
+        return endpoint
+
     def get_endpoints(self, cluster: IRCluster):
         result = []
 
@@ -136,12 +250,7 @@ class V2Cluster(Cacheable):
 
         if len(targetlist) > 0:
             for target in targetlist:
-                address = {
-                    'address': target['ip'],
-                    'port_value': target['port'],
-                    'protocol': 'TCP'  # Yes, really. Envoy uses the TLS context to determine whether to originate TLS.
-                }
-                result.append({'endpoint': {'address': {'socket_address': address}}})
+                result.append(self.get_endpoint(target))
         else:
             for u in cluster.urls:
                 p = urllib.parse.urlparse(u)
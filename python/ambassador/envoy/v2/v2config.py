@@ -45,6 +45,8 @@ class V2Config (EnvoyConfig):
     listeners: List[Union[V2Listener,V2TCPListener]]
     clusters: List[V2Cluster]
     static_resources: V2StaticResources
+    mapping_stats: Dict[str, List[str]]
+    tap_resources: Dict[str, Dict[str, Any]]
 
     def __init__(self, ir: 'IR', cache: Optional[Cache]=None) -> None:
         # Init our superclass...
@@ -53,6 +55,13 @@ class V2Config (EnvoyConfig):
         # ...then make sure we have a cache (which might be a NullCache).
         self.cache = cache or NullCache(self.ir.logger)
 
+        # The stat prefixes of each Mapping's virtual clusters, for diagnostics. V2Listener
+        # fills this in.
+        self.mapping_stats = {}
+
+        # The TapConfig for each TapPolicy, by tap ID, for TapDS. V2Listener fills this in too.
+        self.tap_resources = {}
+
         V2Admin.generate(self)
         V2Tracing.generate(self)
 
@@ -107,3 +116,22 @@ class V2Config (EnvoyConfig):
 
         return bootstrap_config, ads_config
 
+    def tapds_config(self) -> Dict[str, Any]:
+        """
+        The TapResources for ambex to serve over TapDS, wrapped in a DiscoveryResponse
+        so that they fit in one file.
+        """
+
+        return {
+            '@type': '/envoy.api.v2.DiscoveryResponse',
+            'type_url': 'type.googleapis.com/envoy.service.tap.v2alpha.TapResource',
+            'resources': [
+                {
+                    '@type': '/envoy.service.tap.v2alpha.TapResource',
+                    'name': tap_id,
+                    'config': self.tap_resources[tap_id]
+                }
+                for tap_id in sorted(self.tap_resources.keys())
+            ]
+        }
+
@@ -11,7 +11,7 @@
 # WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 # See the License for the specific language governing permissions and
 # limitations under the License
-from typing import Any, Dict, List, Optional, Tuple, Union, TYPE_CHECKING
+from typing import Any, Dict, List, Optional, Set, Tuple, Union, TYPE_CHECKING
 from typing import cast as typecast
 
 from os import environ
@@ -24,8 +24,13 @@ from ...ir.irlistener import IRListener
 from ...ir.irauth import IRAuth
 from ...ir.irbuffer import IRBuffer
 from ...ir.irgzip import IRGzip
+from ...ir.irjwt import IRJWT
+from ...ir.irlogservice import IRLogService
+from ...ir.iraccesslog import access_log_sampling_key, combine_access_log_filters, envoy_access_log_sampling
 from ...ir.irfilter import IRFilter
 from ...ir.irratelimit import IRRateLimit
+from ...ir.irtap import IRTapPolicy
+from ...ir.irwasm import IRWasmFilter
 from ...ir.ircors import IRCORS
 from ...ir.ircluster import IRCluster
 from ...ir.irtcpmappinggroup import IRTCPMappingGroup
@@ -33,8 +38,9 @@ from ...ir.irtlscontext import IRTLSContext
 
 from ...utils import ParsedService as Service
 
-from .v2route import V2Route
+from .v2route import V2Route, regex_matcher
 from .v2tls import V2TLSContext
+from .v2tracing import v2_custom_tags
 
 if TYPE_CHECKING:
     from . import V2Config
@@ -192,6 +198,56 @@ def v2filter_gzip(gzip: IRGzip, v2config: 'V2Config'):
         }
     }
 
+@v2filter.when("IRJWT")
+def v2filter_jwt(jwt: IRJWT, v2config: 'V2Config'):
+    # The jwt_authn filter picks the first rule that matches, so emit a rule for every
+    # group, in route order, even if it doesn't need a JWT: that way a more specific
+    # unprotected Mapping isn't swallowed by a less specific protected one. For the same
+    # reason, each rule matches everything its route does, not just the path: Mappings with
+    # the same prefix on different hosts, or with different headers or methods (which the
+    # group's headers include, as :authority and :method), need different rules.
+    rules = []
+
+    for group in v2config.ir.ordered_groups():
+        if group.get('host_redirect') or (group.get('kind') != 'IRHTTPMappingGroup'):
+            continue
+
+        prefix = group.get('prefix')
+
+        if group.get('prefix_regex'):
+            match = { 'safe_regex': { 'google_re2': {}, 'regex': prefix } }
+        elif group.get('prefix_exact'):
+            match = { 'path': prefix }
+        else:
+            match = { 'prefix': prefix }
+
+        headers = V2Route.generate_headers(v2config, group)
+        if headers:
+            match['headers'] = headers
+
+        query_parameters = V2Route.generate_query_parameters(v2config, group)
+        if query_parameters:
+            match['query_parameters'] = query_parameters
+
+        rule: Dict[str, Any] = { 'match': match }
+
+        for mapping in group.get('mappings', []):
+            jwt_requirement = mapping.get('jwt_requirement', None)
+
+            if jwt_requirement:
+                rule['requires'] = IRJWT.requirement(jwt_requirement)
+                break
+
+        rules.append(rule)
+
+    return {
+        'name': 'envoy.filters.http.jwt_authn',
+        'config': {
+            'providers': { name: jwt.provider_config(name) for name in sorted(jwt.providers.keys()) },
+            'rules': rules
+        }
+    }
+
 @v2filter.when("ir.grpc_http1_bridge")
 def v2filter_grpc_http1_bridge(irfilter: IRFilter, v2config: 'V2Config'):
     del irfilter  # silence unused-variable warning
@@ -212,6 +268,24 @@ def v2filter_grpc_web(irfilter: IRFilter, v2config: 'V2Config'):
         'config': {},
     }
 
+@v2filter.when("ir.grpc_json_transcoder")
+def v2filter_grpc_json_transcoder(irfilter: IRFilter, v2config: 'V2Config'):
+    del v2config  # silence unused-variable warning
+
+    return {
+        'name': 'envoy.filters.http.grpc_json_transcoder',
+        'config': irfilter.config_dict(),
+    }
+
+@v2filter.when("ir.tap_admin")
+def v2filter_tap_admin(irfilter: IRFilter, v2config: 'V2Config'):
+    del v2config  # silence unused-variable warning
+
+    return {
+        'name': 'envoy.filters.http.tap',
+        'config': irfilter.config_dict(),
+    }
+
 @v2filter.when("ir.grpc_stats")
 def v2filter_grpc_stats(irfilter: IRFilter, v2config: 'V2Config'):
     del v2config  # silence unused-variable warning
@@ -342,6 +416,13 @@ def v2filter_authv1(auth: IRAuth, v2config: 'V2Config'):
         for key in list(set(auth.allowed_authorization_headers).union(AllowedAuthorizationHeaders)):
             allowed_authorization_headers.append({"exact": key})
 
+        # allowed_client_headers defaults to the same set as allowed_authorization_headers,
+        # for backward compatibility.
+        allowed_client_headers = allowed_authorization_headers
+
+        if auth.get('allowed_client_headers'):
+            allowed_client_headers = [ { "exact": key } for key in auth.allowed_client_headers ]
+
         allowed_request_headers = []
 
         for key in list(set(auth.allowed_request_headers).union(AllowedRequestHeaders)):
@@ -375,7 +456,7 @@ def v2filter_authv1(auth: IRAuth, v2config: 'V2Config'):
                             'patterns': sorted(allowed_authorization_headers, key=header_pattern_key)
                         },
                         'allowed_client_headers': {
-                            'patterns': sorted(allowed_authorization_headers, key=header_pattern_key)
+                            'patterns': sorted(allowed_client_headers, key=header_pattern_key)
                         }
                     }
                 },
@@ -383,6 +464,8 @@ def v2filter_authv1(auth: IRAuth, v2config: 'V2Config'):
         }
 
     if auth.proto == "grpc":
+        protocol_version = auth.get('protocol_version', 'v2')
+
         auth_info = {
             'name': 'envoy.ext_authz',
             'config': {
@@ -396,7 +479,17 @@ def v2filter_authv1(auth: IRAuth, v2config: 'V2Config'):
             }
         }
 
+        if protocol_version == 'v3':
+            # The v3 ext_authz protocol can only be selected with the v3 filter config, which
+            # has to be given as a typed_config.
+            auth_info['config'] = {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz',
+                'grpc_service': auth_info['config']['grpc_service'],
+                'transport_api_version': 'V3'
+            }
+
     if auth_info:
+        auth_info['name'] = auth.filter_name
         auth_info['config']['clear_route_cache'] = True
 
         if body_info:
@@ -409,6 +502,17 @@ def v2filter_authv1(auth: IRAuth, v2config: 'V2Config'):
             status_on_error: Optional[Dict[str, int]] = auth.get('status_on_error')
             auth_info['config']["status_on_error"] = status_on_error
 
+        if auth.get('metadata_context_namespaces'):
+            auth_info['config']['metadata_context_namespaces'] = auth.metadata_context_namespaces
+
+        if (auth.filter_name != 'envoy.ext_authz') and ('@type' not in auth_info['config']):
+            # A chained filter's name isn't a registered filter name, so Envoy has to find
+            # the filter by the type of its config instead.
+            auth_info['config']['@type'] = 'type.googleapis.com/envoy.config.filter.http.ext_authz.v2.ExtAuthz'
+
+        if '@type' in auth_info['config']:
+            auth_info['typed_config'] = auth_info.pop('config')
+
         return auth_info
 
     # If here, something's gone horribly wrong.
@@ -429,6 +533,16 @@ def v2filter_ratelimit(ratelimit: IRRateLimit, v2config: 'V2Config'):
     assert v2config.ratelimit
     config['rate_limit_service'] = dict(v2config.ratelimit)
 
+    if ratelimit.protocol_version == 'v3':
+        # As with ext_authz, the v3 protocol can only be selected with the v3 filter
+        # config, which has to be given as a typed_config.
+        config['@type'] = 'type.googleapis.com/envoy.extensions.filters.http.ratelimit.v3.RateLimit'
+
+        return {
+            'name': 'envoy.rate_limit',
+            'typed_config': config,
+        }
+
     return {
         'name': 'envoy.rate_limit',
         'config': config,
@@ -511,12 +625,259 @@ def v2filter_router(router: IRFilter, v2config: 'V2Config'):
 def v2filter_lua(irfilter: IRFilter, v2config: 'V2Config'):
     del v2config  # silence unused-variable warning
 
+    config = irfilter.config_dict() or {}
+
+    if config.get('source_codes'):
+        # Only the v3 Lua filter has named scripts.
+        return {
+            'name': 'envoy.lua',
+            'typed_config': {
+                '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua',
+                **config
+            }
+        }
+
     return {
         'name': 'envoy.lua',
-        'config': irfilter.config_dict(),
+        'config': config,
+    }
+
+
+def v2_tap_header_matcher(v2config: 'V2Config', header: Dict[str, Any]) -> Dict[str, Any]:
+    matcher: Dict[str, Any] = { 'name': header['name'] }
+
+    if 'exact' in header:
+        matcher['exact_match'] = header['exact']
+    elif 'prefix' in header:
+        matcher['prefix_match'] = header['prefix']
+    elif 'regex' in header:
+        matcher.update(regex_matcher(v2config, header['regex'], key='regex_match'))
+    else:
+        matcher['present_match'] = True
+
+    if header.get('invert', False):
+        matcher['invert_match'] = True
+
+    return matcher
+
+def v2_tap_match_all(predicates: List[dict], key: str = 'and_match') -> dict:
+    if len(predicates) == 1:
+        return predicates[0]
+
+    return { key: { 'rules': predicates } }
+
+def v2_tap_output_config(tap: IRTapPolicy) -> Dict[str, Any]:
+    output_config: Dict[str, Any] = {
+        'sinks': [
+            {
+                'format': tap.output_format.upper(),
+                'file_per_tap': { 'path_prefix': tap.output_path_prefix }
+            }
+        ]
+    }
+
+    if tap.max_buffered_bytes is not None:
+        output_config['max_buffered_rx_bytes'] = tap.max_buffered_bytes
+        output_config['max_buffered_tx_bytes'] = tap.max_buffered_bytes
+
+    return output_config
+
+
+def v2_tap_transport_socket(v2config: 'V2Config', tap: IRTapPolicy, transport_socket: Dict[str, Any]) -> Dict[str, Any]:
+    # A transport socket tap sees connections, not requests, so it taps everything. For
+    # each connection, it writes what was read and written, up to max_buffered_bytes
+    # each way.
+    tap_config = {
+        'match_config': { 'any_match': True },
+        'output_config': v2_tap_output_config(tap)
+    }
+
+    v2config.tap_resources[tap.tap_id] = tap_config
+
+    return {
+        'name': 'envoy.transport_sockets.tap',
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.config.transport_socket.tap.v2alpha.Tap',
+            'common_config': {
+                'static_config': tap_config
+            },
+            'transport_socket': transport_socket
+        }
+    }
+
+
+@v2filter.when("IRTapPolicy")
+def v2filter_tap(tap: IRTapPolicy, v2config: 'V2Config'):
+    # The tap filter can't be configured per route, so it has to match the requests
+    # for the TapPolicy's Mappings itself. A Mapping's route match becomes a match on
+    # the :path header, along with the Mapping's own header matches.
+    mapping_matches = []
+
+    for group in tap.groups:
+        prefix = group.get('prefix')
+
+        if group.get('prefix_regex'):
+            path_match = { 'name': ':path', **regex_matcher(v2config, prefix, key='regex_match') }
+        elif group.get('prefix_exact'):
+            path_match = { 'name': ':path', 'exact_match': prefix }
+        else:
+            path_match = { 'name': ':path', 'prefix_match': prefix }
+
+        headers = [ path_match ] + V2Route.generate_headers(v2config, group)
+        mapping_matches.append({ 'http_request_headers_match': { 'headers': headers } })
+
+    if not mapping_matches:
+        # None of the TapPolicy's Mappings exist, which has already been reported.
+        return None
+
+    predicates = [ v2_tap_match_all(mapping_matches, key='or_match') ]
+
+    request_headers = [ v2_tap_header_matcher(v2config, h) for h in tap.request_headers ]
+
+    if tap.match_path_prefix:
+        request_headers.insert(0, { 'name': ':path', 'prefix_match': tap.match_path_prefix })
+
+    if request_headers:
+        predicates.append({ 'http_request_headers_match': { 'headers': request_headers } })
+
+    if tap.response_headers:
+        response_headers = [ v2_tap_header_matcher(v2config, h) for h in tap.response_headers ]
+        predicates.append({ 'http_response_headers_match': { 'headers': response_headers } })
+
+    tap_config = {
+        'match_config': v2_tap_match_all(predicates),
+        'output_config': v2_tap_output_config(tap)
+    }
+
+    if tap.sample:
+        # The tap sampler gets this config from TapDS, and hands it to Envoy over the
+        # admin interface, which streams the traces back to it.
+        tap_config['output_config']['sinks'] = [
+            {
+                'format': tap.output_format.upper(),
+                'streaming_admin': {}
+            }
+        ]
+
+        v2config.tap_resources[tap.tap_id] = tap_config
+
+        return {
+            'name': 'envoy.filters.http.tap',
+            'config': {
+                'common_config': {
+                    'admin_config': { 'config_id': tap.tap_id }
+                }
+            }
+        }
+
+    # The same tap is also served by TapDS, from ambex. The Envoy we ship can't use
+    # tapds_config yet, so the filter still carries it statically.
+    v2config.tap_resources[tap.tap_id] = tap_config
+
+    return {
+        'name': 'envoy.filters.http.tap',
+        'config': {
+            'common_config': {
+                'static_config': tap_config
+            }
+        }
     }
 
 
+def v2_wasm_string_value(value: str) -> Dict[str, Any]:
+    return {
+        '@type': 'type.googleapis.com/google.protobuf.StringValue',
+        'value': value
+    }
+
+
+@v2filter.when("IRWasmFilter")
+def v2filter_wasm(wasm: IRWasmFilter, v2config: 'V2Config'):
+    del v2config  # silence unused-variable warning
+
+    vm_config: Dict[str, Any] = {
+        'vm_id': wasm.vm_id,
+        'runtime': wasm.runtime,
+        'code': { 'local': { 'filename': wasm.module_path } },
+        'allow_precompiled': wasm.allow_precompiled
+    }
+
+    if wasm.vm_configuration:
+        vm_config['configuration'] = v2_wasm_string_value(wasm.vm_configuration)
+
+    plugin_config: Dict[str, Any] = {
+        'name': wasm.filter_id,
+        'vm_config': vm_config
+    }
+
+    if wasm.root_id:
+        plugin_config['root_id'] = wasm.root_id
+
+    if wasm.configuration:
+        plugin_config['configuration'] = v2_wasm_string_value(wasm.configuration)
+
+    # The Wasm filter's config isn't in the Envoy API that we have, so it goes in a
+    # TypedStruct, which Envoy converts when it loads the filter. Its fields are those of
+    # the Envoy that has the filter, not of our envoy.extensions.wasm.v3.
+    return {
+        'name': 'envoy.filters.http.wasm',
+        'typed_config': {
+            '@type': 'type.googleapis.com/udpa.type.v1.TypedStruct',
+            'type_url': 'type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm',
+            'value': {
+                'config': plugin_config
+            }
+        }
+    }
+
+
+def v2_grpc_access_log(al: IRLogService) -> Dict[str, Any]:
+    """
+    Build the gRPC access log for a LogService: HTTP entries for an 'http' LogService,
+    TCP entries for a 'tcp' LogService.
+    """
+
+    access_log_obj: Dict[str, Any] = { "common_config": al.get_common_config() }
+
+    if al.driver == 'http':
+        req_headers = []
+        resp_headers = []
+        trailer_headers = []
+
+        for additional_header in al.get_additional_headers():
+            if additional_header.get('during_request', True):
+                req_headers.append(additional_header.get('header_name'))
+            if additional_header.get('during_response', True):
+                resp_headers.append(additional_header.get('header_name'))
+            if additional_header.get('during_trailer', True):
+                trailer_headers.append(additional_header.get('header_name'))
+
+        access_log_obj['additional_request_headers_to_log'] = req_headers
+        access_log_obj['additional_response_headers_to_log'] = resp_headers
+        access_log_obj['additional_response_trailers_to_log'] = trailer_headers
+
+        name = "envoy.http_grpc_access_log"
+        v3_type = "envoy.extensions.access_loggers.grpc.v3.HttpGrpcAccessLogConfig"
+    else:
+        # tcp loggers do not support additional headers
+        name = "envoy.tcp_grpc_access_log"
+        v3_type = "envoy.extensions.access_loggers.grpc.v3.TcpGrpcAccessLogConfig"
+
+    if al.protocol_version == 'v3':
+        # As with ext_authz, the v3 transport can only be selected with the v3 config,
+        # which has to be given as a typed_config.
+        access_log_obj['@type'] = 'type.googleapis.com/' + v3_type
+
+        access_log = { "name": name, "typed_config": access_log_obj }
+    else:
+        access_log = { "name": name, "config": access_log_obj }
+
+    if al.filter:
+        access_log['filter'] = al.filter
+
+    return access_log
+
+
 class V2TCPListener(dict):
     def __init__(self, config: 'V2Config', group: IRTCPMappingGroup) -> None:
         super().__init__()
@@ -569,6 +930,13 @@ class V2TCPListener(dict):
             }
         }
 
+        # TCP LogServices get to see TCPMapping connections, too.
+        tcp_access_log = [ v2_grpc_access_log(al)
+                           for al in config.ir.log_services.values() if al.driver == 'tcp' ]
+
+        if tcp_access_log:
+            tcp_filter['config']['access_log'] = tcp_access_log
+
         # OK. Basic filter chain entry next.
         chain_entry: Dict[str, Any] = {
             'filters': [
@@ -576,6 +944,26 @@ class V2TCPListener(dict):
             ]
         }
 
+        # If we're limiting the connection rate, that has to happen before the tcp_proxy
+        # gets a chance to connect upstream.
+        connection_rate_limit = group.get('connection_rate_limit', None)
+
+        if connection_rate_limit:
+            per_second = connection_rate_limit['connections_per_second']
+
+            chain_entry['filters'].insert(0, {
+                'name': 'envoy.filters.network.local_ratelimit',
+                'typed_config': {
+                    '@type': 'type.googleapis.com/envoy.config.filter.network.local_rate_limit.v2alpha.LocalRateLimit',
+                    'stat_prefix': 'ingress_tcp_%d' % group.port,
+                    'token_bucket': {
+                        'max_tokens': connection_rate_limit.get('burst', per_second),
+                        'tokens_per_fill': per_second,
+                        'fill_interval': '1s'
+                    }
+                }
+            })
+
         # Then, if SNI is a thing, update the chain entry with the appropriate chain match.
         if self.tls_context:
             # Apply the context to the chain...
@@ -590,6 +978,29 @@ class V2TCPListener(dict):
                     'server_names': [ host_wanted ]
                 }
 
+        # TapPolicies for this group's TCPMappings wrap the chain's transport socket, so
+        # they see the bytes after TLS is terminated. Envoy ignores tls_context once there's
+        # a transport_socket, so the TLS context moves into the innermost transport socket.
+        taps = [ irfilter for irfilter in config.ir.filters
+                 if isinstance(irfilter, IRTapPolicy) and irfilter.taps_tcp_group(group) ]
+
+        if taps:
+            transport_socket: Dict[str, Any] = { 'name': 'envoy.transport_sockets.raw_buffer' }
+
+            if 'tls_context' in chain_entry:
+                transport_socket = {
+                    'name': 'envoy.transport_sockets.tls',
+                    'typed_config': {
+                        '@type': 'type.googleapis.com/envoy.api.v2.auth.DownstreamTlsContext',
+                        **chain_entry.pop('tls_context')
+                    }
+                }
+
+            for tap in taps:
+                transport_socket = v2_tap_transport_socket(config, tap, transport_socket)
+
+            chain_entry['transport_socket'] = transport_socket
+
         # OK, once that's done, stick this into our filter chains.
         self['filter_chains'].append(chain_entry)
 
@@ -612,10 +1023,40 @@ class V2VirtualHost:
 
         self.tls_context = V2TLSContext(ctx)
         self.routes: List[DictifiedV2Route] = []
+        self.virtual_clusters: List[dict] = []
+        self._virtual_cluster_names: Set[str] = set()
 
     def needs_redirect(self) -> None:
         self._needs_redirect = True
 
+    def add_virtual_cluster(self, name: str, match: Dict[str, Any]) -> None:
+        """
+        Add a virtual cluster that matches the same requests as a route's match, so that
+        Envoy keeps latency and response code stats for them. Envoy uses the first virtual
+        cluster that matches, which is why the routes' order matters here too. Does nothing
+        if this VirtualHost already has a virtual cluster with this name.
+        """
+
+        if name in self._virtual_cluster_names:
+            return
+
+        # Virtual clusters only match headers, so the path match turns into a :path
+        # match. Note that :path includes the query string.
+        if 'prefix' in match:
+            path_match = { 'prefix_match': match['prefix'] }
+        elif 'path' in match:
+            path_match = { 'exact_match': match['path'] }
+        elif 'safe_regex' in match:
+            path_match = { 'safe_regex_match': match['safe_regex'] }
+        else:
+            path_match = { 'regex_match': match['regex'] }
+
+        self._virtual_cluster_names.add(name)
+        self.virtual_clusters.append({
+            'name': name,
+            'headers': [ { 'name': ':path', **path_match } ] + match.get('headers', [])
+        })
+
     def finalize(self) -> None:
         # It's important from a performance perspective to wrap debug log statements
         # with this check so we don't end up generating log strings (or even JSON
@@ -770,31 +1211,12 @@ class V2Listener(dict):
 
         # Get Access Log Rules
         for al in self.config.ir.log_services.values():
-            access_log_obj: Dict[str, Any] = { "common_config": al.get_common_config() }
-            req_headers = []
-            resp_headers = []
-            trailer_headers = []
-
-            for additional_header in al.get_additional_headers():
-                if additional_header.get('during_request', True):
-                    req_headers.append(additional_header.get('header_name'))
-                if additional_header.get('during_response', True):
-                    resp_headers.append(additional_header.get('header_name'))
-                if additional_header.get('during_trailer', True):
-                    trailer_headers.append(additional_header.get('header_name'))
-
-            if al.driver == 'http':
-                access_log_obj['additional_request_headers_to_log'] = req_headers
-                access_log_obj['additional_response_headers_to_log'] = resp_headers
-                access_log_obj['additional_response_trailers_to_log'] = trailer_headers
-                self.access_log.append({"name": "envoy.http_grpc_access_log", "config": access_log_obj})
-            else:
-                # inherently TCP right now
-                # tcp loggers do not support additional headers
-                self.access_log.append({"name": "envoy.tcp_grpc_access_log", "config": access_log_obj})
+            self.access_log.append(v2_grpc_access_log(al))
 
         # Use sane access log spec in JSON
-        if self.config.ir.ambassador_module.envoy_log_type.lower() == "json":
+        envoy_log_type = self.config.ir.ambassador_module.envoy_log_type.lower()
+
+        if envoy_log_type in [ "json", "typed_json" ]:
             log_format = self.config.ir.ambassador_module.get('envoy_log_format', None)
             if log_format is None:
                 log_format = {
@@ -827,14 +1249,8 @@ class V2Listener(dict):
                     log_format['dd.trace_id'] = '%REQ(X-DATADOG-TRACE-ID)%'
                     log_format['dd.span_id'] = '%REQ(X-DATADOG-PARENT-ID)%'
 
-            self.access_log.append({
-                'name': 'envoy.file_access_log',
-                'typed_config': {
-                    '@type': 'type.googleapis.com/envoy.config.accesslog.v2.FileAccessLog',
-                    'path': self.config.ir.ambassador_module.envoy_log_path,
-                    'json_format': log_format
-                }
-            })
+            # typed_json keeps numbers as numbers in the log; json makes everything a string.
+            file_format = { ('typed_json_format' if envoy_log_type == 'typed_json' else 'json_format'): log_format }
         else:
             # Use a sane access log spec
             log_format = self.config.ir.ambassador_module.get('envoy_log_format', None)
@@ -844,14 +1260,47 @@ class V2Listener(dict):
 
             if log_debug:
                 self.config.ir.logger.debug("V2Listener: Using log_format '%s'" % log_format)
-            self.access_log.append({
+
+            file_format = { 'format': log_format + '\n' }
+
+        # Every file sink gets the same format, but can have its own filter. Without
+        # envoy_access_logs, there's just the one sink at envoy_log_path.
+        file_sinks = self.config.ir.ambassador_module.get('envoy_access_logs', None)
+
+        if file_sinks is None:
+            file_sinks = [ { 'path': self.config.ir.ambassador_module.envoy_log_path } ]
+
+        for sink in file_sinks:
+            file_access_log: Dict[str, Any] = {
                 'name': 'envoy.file_access_log',
                 'typed_config': {
                     '@type': 'type.googleapis.com/envoy.config.accesslog.v2.FileAccessLog',
-                    'path': self.config.ir.ambassador_module.envoy_log_path,
-                    'format': log_format + '\n'
+                    'path': sink['path'],
+                    **file_format
                 }
-            })
+            }
+
+            if sink.get('filter', None):
+                file_access_log['filter'] = sink['filter']
+
+            self.access_log.append(file_access_log)
+
+        # access_log_sampling applies on top of every access log's own filter.
+        mapping_samplings = {}
+
+        for group in self.config.ir.groups.values():
+            sampling = group.get('access_log_sampling', None)
+
+            if sampling:
+                mapping_samplings[access_log_sampling_key(sampling)] = sampling
+
+        sampling_filter = envoy_access_log_sampling(self.config.ir.ambassador_module.get('access_log_sampling', None),
+                                                    mapping_samplings)
+
+        if sampling_filter:
+            for access_log in self.access_log:
+                access_log['filter'] = combine_access_log_filters('and_filter',
+                                                                  [ access_log.get('filter', None), sampling_filter ])
 
         # Start by building our base HTTP config...
         self.base_http_config: Dict[str, Any] = {
@@ -894,6 +1343,11 @@ class V2Listener(dict):
             if req_hdrs:
                 self.base_http_config["tracing"]["request_headers_for_tags"] = req_hdrs
 
+            custom_tags = self.config.ir.tracing.get('custom_tags', [])
+
+            if custom_tags:
+                self.base_http_config["tracing"]["custom_tags"] = v2_custom_tags(custom_tags)
+
             sampling = self.config.ir.tracing.get('sampling', {})
             if sampling:
                 client_sampling = sampling.get('client', None)
@@ -1006,14 +1460,17 @@ class V2Listener(dict):
                 need_tcp_inspector = True
 
             http_config = dict(self.base_http_config)
+            envoy_vhost: Dict[str, Any] = {
+                "name": f"{self.name}-{vhost._name}",
+                "domains": domains,
+                "routes": vhost.routes
+            }
+
+            if vhost.virtual_clusters:
+                envoy_vhost["virtual_clusters"] = vhost.virtual_clusters
+
             http_config["route_config"] = {
-                "virtual_hosts": [
-                    {
-                        "name": f"{self.name}-{vhost._name}",
-                        "domains": domains,
-                        "routes": vhost.routes
-                    }
-                ]
+                "virtual_hosts": [ envoy_vhost ]
             }
 
             filter_chain["filters"] = [
@@ -1178,6 +1635,8 @@ class V2Listener(dict):
             insecure_route: DictifiedV2Route = dict(c_route)
             insecure_route.pop('_sni', None)
             insecure_route.pop('_precedence', None)
+            stats_name = insecure_route.pop('_stats_name', None)
+            stats_mapping = insecure_route.pop('_stats_mapping', None)
 
             # ...then copy _that_ so we can make a secured version with an explicit XFP check.
             #
@@ -1279,6 +1738,18 @@ class V2Listener(dict):
                                 logger.debug(
                                     f"V2Listeners: {listener.name} {vhostname} {variant}: Accept as {action}")
                             vhost.routes.append(route)
+
+                            # Build the virtual cluster from the insecure route, since the secure
+                            # route's extra XFP check would hide requests from it. Every Mapping in
+                            # a group shares the group's virtual cluster.
+                            if stats_name:
+                                vhost.add_virtual_cluster(stats_name, insecure_route["match"])
+
+                                stat_prefix = f"vhost.{listener.name}-{vhost._name}.vcluster.{stats_name}."
+                                stat_prefixes = config.mapping_stats.setdefault(stats_mapping, [])
+
+                                if stat_prefix not in stat_prefixes:
+                                    stat_prefixes.append(stat_prefix)
                         else:
                             if log_debug:
                                 logger.debug(
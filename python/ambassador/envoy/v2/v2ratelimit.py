@@ -32,13 +32,17 @@ class V2RateLimit(dict):
 
         assert(ratelimit.cluster.envoy_name)
 
-        self['use_alpha'] = True
         self['grpc_service'] = {
             'envoy_grpc': {
                 'cluster_name': ratelimit.cluster.envoy_name
             }
         }
 
+        if ratelimit.protocol_version == 'v3':
+            self['transport_api_version'] = 'V3'
+        else:
+            self['use_alpha'] = True
+
     @classmethod
     def generate(cls, config: 'V2Config') -> None:
         config.ratelimit = None
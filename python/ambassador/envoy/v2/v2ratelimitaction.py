@@ -34,6 +34,7 @@ class V2RateLimitAction(dict):
         super().__init__()
 
         self.valid = False
+        self.errored = False
         self.stage = 0
         # self.actions is a list of `envoy.api.v2.route.RateLimit.Action`s
         self.actions: List[dict] = []
@@ -76,14 +77,30 @@ class V2RateLimitAction(dict):
                             'descriptor_value': action[dkey]
                         }
                     })
+                elif dkey == 'masked_remote_address':
+                    # Envoy doesn't have masked_remote_address until API v3.
+                    self.save_error(config, "masked_remote_address is not supported by this Envoy", rate_limit)
+                elif not isinstance(action[dkey], dict):
+                    self.save_error(config, "Label for RateLimit has invalid custom header '%s'" % action,
+                                    rate_limit)
+                elif 'metadata' in action[dkey]:
+                    # Likewise dynamic_metadata.
+                    self.save_error(config, "dynamic metadata label '%s' is not supported by this Envoy" % dkey,
+                                    rate_limit)
                 else:
                     # This is a header block.
                     hdr_action = action[dkey]
 
                     hdr_name = hdr_action['header']
-                    # Envoy API v3 adds a "skip_if_absent"
-                    # setting--but we don't have access to it because
-                    # we're still using API v2.
+
+                    # Envoy API v3 adds a "skip_if_absent" setting--but we don't have
+                    # access to it because we're still using API v2, where a missing
+                    # header always means no descriptor at all.
+                    if hdr_action.get('skip_if_absent', False):
+                        self.save_error(config, "skip_if_absent on label '%s' is not supported by this Envoy" % dkey,
+                                        rate_limit)
+                        continue
+
                     #hdr_omit = hdr_action.get('omit_if_not_present', False)
 
                     self.save_action({
@@ -124,7 +141,14 @@ class V2RateLimitAction(dict):
 
     def save_action(self, action):
         self.actions.append(action)
-        self.valid = True
+        self.valid = not self.errored
+
+    def save_error(self, config: 'V2Config', error: str, rate_limit: Dict[str, Any]):
+        # A label that we can only partly translate would send the wrong descriptor, so
+        # drop the whole thing.
+        config.ir.post_error("%s (%s)" % (error, rate_limit))
+        self.errored = True
+        self.valid = False
 
     def to_dict(self):
         return {
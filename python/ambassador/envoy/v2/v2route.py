@@ -12,15 +12,20 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
-from typing import Any, Dict, List, Set, Union, TYPE_CHECKING
+import re
+
+from typing import Any, Dict, List, Optional, Set, Union, TYPE_CHECKING
 from typing import cast as typecast
 
 from ..common import EnvoyRoute
 from ...cache import Cacheable
 from ...ir.irhttpmappinggroup import IRHTTPMappingGroup
+from ...ir.irauth import IRAuth
 from ...ir.irbasemapping import IRBaseMapping
+from ...ir.irwasm import IRWasmFilter
 
 from .v2ratelimitaction import V2RateLimitAction
+from .v2tracing import v2_custom_tags
 
 if TYPE_CHECKING:
     from . import V2Config
@@ -80,6 +85,17 @@ class V2Route(Cacheable):
         if group.get('precedence'):
             self['_precedence'] = group['precedence']
 
+        # Likewise the name of the virtual cluster that collects this route's stats, if any.
+        stats_name = group.get('stats_name', None)
+
+        if not stats_name and config.ir.ambassador_module.get('per_mapping_stats', False) and group.get('mappings'):
+            first_mapping = group['mappings'][0]
+            stats_name = f"{first_mapping.name}.{first_mapping.namespace}"
+
+        if stats_name and (len(mapping) > 0):
+            self['_stats_name'] = re.sub(r'[^A-Za-z0-9_-]', '_', stats_name)
+            self['_stats_mapping'] = f"{mapping.name}.{mapping.namespace}"
+
         envoy_route = EnvoyRoute(group).envoy_route
 
         mapping_prefix = mapping.get('prefix', None)
@@ -134,13 +150,71 @@ class V2Route(Cacheable):
 
         # `per_filter_config` is used for customization of an Envoy filter
         per_filter_config = {}
+        per_filter_types: Dict[str, str] = {}
+
+        for auth in config.ir.filters:
+            if auth.kind != 'IRAuth':
+                continue
+
+            auth = typecast(IRAuth, auth)
+
+            if auth.get('protocol_version', 'v2') == 'v3':
+                per_filter_types[auth.filter_name] = 'type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute'
+            else:
+                per_filter_types[auth.filter_name] = 'type.googleapis.com/envoy.config.filter.http.ext_authz.v2.ExtAuthzPerRoute'
+
+            if mapping.get('bypass_auth', False) or \
+               not auth.applies_to(route_prefix, envoy_route == 'regex'):
+                per_filter_config[auth.filter_name] = {'disabled': True}
+            elif mapping.get('auth_context_extensions', None):
+                per_filter_config[auth.filter_name] = {
+                    'check_settings': {
+                        'context_extensions': mapping['auth_context_extensions']
+                    }
+                }
+
+        # A route can't have both per_filter_config and typed_per_filter_config. Only the v3
+        # Lua filter can run a named script, and Envoy can only find a chained ext_authz
+        # filter's per-route config by its type, since the filter's name isn't one Envoy
+        # knows; so if this route needs either, all of its config is typed.
+        lua_per_route: Optional[Dict[str, Any]] = None
+
+        if config.ir.ambassador_module.get('lua_scripts', None):
+            if mapping.get('bypass_lua', False):
+                lua_per_route = { 'disabled': True }
+            elif mapping.get('lua_script_name', None):
+                lua_per_route = { 'name': mapping['lua_script_name'] }
+
+        chained_auth = any(name != 'envoy.ext_authz' for name in per_filter_config.keys())
 
-        if mapping.get('bypass_auth', False):
-            per_filter_config['envoy.ext_authz'] = {'disabled': True}
+        if lua_per_route or chained_auth:
+            typed_per_filter_config = {
+                name: { '@type': per_filter_types[name], **cfg }
+                for name, cfg in per_filter_config.items()
+            }
+
+            if lua_per_route:
+                typed_per_filter_config['envoy.lua'] = {
+                    '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+                    **lua_per_route
+                }
 
-        if per_filter_config:
+            self['typed_per_filter_config'] = typed_per_filter_config
+        elif per_filter_config:
             self['per_filter_config'] = per_filter_config
 
+        # Wasm filters run for every route, so the routes of a WasmFilter's Mappings say
+        # which filters are for them, and the filters check.
+        wasm_filters = [ f.filter_id for f in config.ir.filters
+                         if (f.kind == 'IRWasmFilter') and typecast(IRWasmFilter, f).applies_to_group(group) ]
+
+        if wasm_filters:
+            self['metadata'] = {
+                'filter_metadata': {
+                    'getambassador.io/wasm': { 'filters': wasm_filters }
+                }
+            }
+
         request_headers_to_add = group.get('add_request_headers', None)
         if request_headers_to_add:
             self['request_headers_to_add'] = self.generate_headers_to_add(request_headers_to_add)
@@ -274,6 +348,26 @@ class V2Route(Cacheable):
 
         self['route'] = route
 
+        # Per-route tracing only means anything if there's a TracingService.
+        tracing = group.get('tracing', None)
+
+        if tracing and config.ir.tracing:
+            route_tracing: Dict[str, Any] = {}
+
+            for kind, value in tracing.get('sampling', {}).items():
+                route_tracing['%s_sampling' % kind] = {
+                    'numerator': value,
+                    'denominator': 'HUNDRED'
+                }
+
+            custom_tags = tracing.get('custom_tags', [])
+
+            if custom_tags:
+                route_tracing['custom_tags'] = v2_custom_tags(custom_tags)
+
+            if route_tracing:
+                self['tracing'] = route_tracing
+
 
     def host_constraints(self, prune_unreachable_routes: bool) -> Set[str]:
         """Return a set of hostglobs that match (a superset of) all
@@ -18,6 +18,7 @@ from typing import cast as typecast
 import os
 
 from ...ir.irtlscontext import IRTLSContext
+from ...ir.iristio import IstioMTLS
 
 # This stuff isn't really accurate, but it'll do for now.
 #
@@ -111,6 +112,25 @@ class V2TLSContext(Dict):
         src: EnvoyCoreSource = { 'filename': value }
         validation[key] = src
 
+    def update_istio_sds(self) -> None:
+        # The Istio agent serves our workload certificate and the mesh's roots over SDS, on
+        # the sds-grpc cluster in our bootstrap.
+        sds_config = {
+            'api_config_source': {
+                'api_type': 'GRPC',
+                'grpc_services': [ { 'envoy_grpc': { 'cluster_name': IstioMTLS.SDSCluster } } ]
+            }
+        }
+
+        common = self.get_common()
+        common['tls_certificate_sds_secret_configs'] = [
+            { 'name': IstioMTLS.SDSCertName, 'sds_config': sds_config }
+        ]
+        common['combined_validation_context'] = {
+            'default_validation_context': {},
+            'validation_context_sds_secret_config': { 'name': IstioMTLS.SDSRootName, 'sds_config': sds_config }
+        }
+
     def add_context(self, ctx: IRTLSContext) -> None:
         if TYPE_CHECKING:
             # This is needed because otherwise self.__setitem__ confuses things.
@@ -127,6 +147,9 @@ class V2TLSContext(Dict):
             if secretinfokey in ctx['secret_info']:
                 handler(hkey, ctx['secret_info'][secretinfokey])
 
+        if ctx.get('_istio_sds', None):
+            self.update_istio_sds()
+
         for ctxkey, handler, hkey in [
             ( 'alpn_protocols', self.update_alpn, 'alpn_protocols' ),
             ( 'cert_required', self.__setitem__, 'require_client_certificate' ),
@@ -12,7 +12,7 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
-from typing import TYPE_CHECKING
+from typing import Any, Dict, List, TYPE_CHECKING
 from typing import cast as typecast
 
 from ...ir.irtracing import IRTracing
@@ -60,3 +60,39 @@ class V2Tracing(dict):
 
         if config.ir.tracing:
             config.tracing = config.save_element('tracing', config.ir.tracing, V2Tracing(config))
+
+
+def v2_custom_tags(custom_tags: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """
+    Translate TracingService or Mapping custom_tags into Envoy CustomTags. The schema
+    has already made sure that each tag has exactly one of literal, header, or metadata.
+    """
+
+    tags: List[Dict[str, Any]] = []
+
+    for custom_tag in custom_tags:
+        tag: Dict[str, Any] = { 'tag': custom_tag['tag'] }
+
+        if 'literal' in custom_tag:
+            tag['literal'] = { 'value': custom_tag['literal'] }
+        elif 'header' in custom_tag:
+            tag['request_header'] = { 'name': custom_tag['header'] }
+        else:
+            metadata = custom_tag['metadata']
+
+            tag['metadata'] = {
+                'kind': { 'request': {} },
+                'metadata_key': {
+                    'key': metadata['filter'],
+                    'path': [ { 'key': key } for key in metadata.get('path', []) ]
+                }
+            }
+
+        if 'default' in custom_tag:
+            for source in [ 'request_header', 'metadata' ]:
+                if source in tag:
+                    tag[source]['default_value'] = custom_tag['default']
+
+        tags.append(tag)
+
+    return tags
@@ -16,16 +16,22 @@ class AmbassadorProcessor (ManagedKubernetesProcessor):
         kinds = [
             'AuthService',
             'ConsulResolver',
+            'DNSResolver',
             'Host',
             'KubernetesEndpointResolver',
             'KubernetesServiceResolver',
             'LogService',
             'Mapping',
             'Module',
+            'MultiClusterResolver',
             'RateLimitService',
+            'StaticResolver',
+            'StatsSink',
+            'TapPolicy',
             'TCPMapping',
             'TLSContext',
             'TracingService',
+            'WasmFilter',
         ]
 
         return frozenset([
@@ -0,0 +1,50 @@
+from typing import Any, Dict, FrozenSet, Optional
+
+from .k8sobject import KubernetesGVK, KubernetesObject
+from .k8sprocessor import ManagedKubernetesProcessor
+
+
+class CertificateProcessor (ManagedKubernetesProcessor):
+    """
+    A Kubernetes object processor that remembers the state of cert-manager Certificates, by the
+    Secret that each of them writes, so that a Host using that Secret can report whether its
+    certificate is ready and whether it's being renewed.
+
+    Certificates don't configure anything by themselves, so nothing is emitted for them.
+    """
+
+    VERSIONS = ('v1', 'v1beta1', 'v1alpha3', 'v1alpha2')
+
+    def kinds(self) -> FrozenSet[KubernetesGVK]:
+        return frozenset([KubernetesGVK(f'cert-manager.io/{version}', 'Certificate') for version in self.VERSIONS])
+
+    @staticmethod
+    def _condition(status: Dict[str, Any], ctype: str) -> Optional[Dict[str, Any]]:
+        for cond in status.get('conditions') or []:
+            if cond.get('type') == ctype:
+                return cond
+
+        return None
+
+    def _process(self, obj: KubernetesObject) -> None:
+        secret_name = obj.spec.get('secretName')
+
+        if not secret_name:
+            self.logger.debug(f"Certificate {obj.name}.{obj.namespace} has no secretName, ignoring")
+            return
+
+        status = obj.status or {}
+        ready = self._condition(status, 'Ready')
+        issuing = self._condition(status, 'Issuing')
+
+        self.aconf.k8s_certificates[f'{secret_name}.{obj.namespace}'] = {
+            'name': obj.name,
+            'ready': (ready.get('status') == 'True') if ready else None,
+            'reason': (ready or {}).get('reason', ''),
+            'message': (ready or {}).get('message', ''),
+            'issuing': (issuing.get('status') == 'True') if issuing else None,
+            'issuing_reason': (issuing or {}).get('reason', ''),
+            'issuing_message': (issuing or {}).get('message', ''),
+            'not_after': status.get('notAfter', ''),
+            'renewal_time': status.get('renewalTime', ''),
+        }
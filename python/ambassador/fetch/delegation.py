@@ -0,0 +1,123 @@
+from typing import Dict, FrozenSet, List, Optional
+
+import dataclasses
+
+from ..config import ACResource, Config
+
+from .k8sobject import KubernetesGVK, KubernetesObject
+from .k8sprocessor import ManagedKubernetesProcessor
+from .resource import ResourceManager
+
+
+@dataclasses.dataclass(frozen=True)
+class Delegation:
+    """
+    A path prefix on a hostname that the owner namespace delegates to other namespaces.
+    """
+
+    name: str
+    owner: str
+    prefix: str
+    namespaces: FrozenSet[str]
+
+
+class RouteDelegationProcessor (ManagedKubernetesProcessor):
+    """
+    A Kubernetes object processor that enforces RouteDelegations. Once everything has been
+    fetched, it drops the Hosts and Mappings for delegated hostnames that their namespaces aren't
+    allowed to have, and posts an Event on each of them that came from a CRD.
+
+    Hostnames that no RouteDelegation names, and Mappings with no host or a host_regex, are left
+    alone.
+    """
+
+    delegations: Dict[str, List[Delegation]]
+
+    def __init__(self, manager: ResourceManager) -> None:
+        super().__init__(manager)
+
+        self.delegations = {}
+
+    def kinds(self) -> FrozenSet[KubernetesGVK]:
+        return frozenset([KubernetesGVK.for_ambassador('RouteDelegation')])
+
+    def _process(self, obj: KubernetesObject) -> None:
+        if not self.aconf.good_ambassador_id(obj.spec):
+            self.logger.debug(f"RouteDelegation {obj.name}.{obj.namespace} has mismatched ambassador_id, ignoring")
+            return
+
+        hostname = obj.spec.get('hostname')
+
+        if not hostname:
+            self.aconf.post_error(f"RouteDelegation {obj.name}.{obj.namespace} has no hostname, ignoring")
+            return
+
+        self.delegations.setdefault(hostname.lower(), []).append(Delegation(
+            name=f"{obj.name}.{obj.namespace}",
+            owner=obj.namespace or Config.ambassador_namespace,
+            prefix=obj.spec.get('prefix') or '/',
+            namespaces=frozenset(obj.spec.get('namespaces') or [])
+        ))
+
+    def _violation(self, element: ACResource) -> Optional[str]:
+        """
+        Return why a resource may not exist, or None if it may.
+        """
+
+        if element.kind == 'Host':
+            hostname = element.get('hostname')
+        elif (element.kind == 'Mapping') and not element.get('host_regex'):
+            hostname = element.get('host')
+        else:
+            return None
+
+        delegations = self.delegations.get((hostname or '').lower())
+
+        if not delegations:
+            return None
+
+        namespace = element.get('namespace') or Config.ambassador_namespace
+        owners = sorted({ d.owner for d in delegations })
+
+        if namespace in owners:
+            return None
+
+        if element.kind == 'Host':
+            return f"only namespace {', '.join(owners)} may have Hosts for {hostname}"
+
+        prefixes = sorted(d.prefix for d in delegations if namespace in d.namespaces)
+
+        if not prefixes:
+            return f"no RouteDelegation delegates {hostname} to namespace {namespace}"
+
+        if element.get('prefix_regex'):
+            return f"a regex prefix can't be checked against the prefixes delegated to namespace {namespace} on {hostname}"
+
+        prefix = element.get('prefix') or ''
+
+        if any(prefix.startswith(p) for p in prefixes):
+            return None
+
+        return f"{prefix} is not under the prefixes delegated to namespace {namespace} on {hostname}: {', '.join(prefixes)}"
+
+    def finalize(self) -> None:
+        if not self.delegations:
+            return
+
+        allowed: List[ACResource] = []
+
+        for element in self.manager.elements:
+            violation = self._violation(element)
+
+            if violation is None:
+                allowed.append(element)
+                continue
+
+            self.aconf.post_error(f"{element.kind} {element.name} violates RouteDelegation: {violation}", resource=element)
+
+            if (element.get('metadata_labels') or {}).get('ambassador_crd'):
+                self.aconf.k8s_events.append((element.kind, element.name,
+                                              element.get('namespace') or Config.ambassador_namespace,
+                                              'DelegationViolation', violation))
+
+        self.manager.elements[:] = allowed
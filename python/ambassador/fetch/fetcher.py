@@ -20,6 +20,9 @@ from .k8sprocessor import (
 from .ambassador import AmbassadorProcessor
 from .service import ServiceProcessor
 from .knative import KnativeIngressProcessor
+from .delegation import RouteDelegationProcessor
+from .tenancy import TenantProcessor
+from .certmanager import CertificateProcessor
 
 AnyDict = Dict[str, Any]
 HandlerResult = Optional[Tuple[str, List[AnyDict]]]
@@ -51,6 +54,18 @@ HandlerResult = Optional[Tuple[str, List[AnyDict]]]
 
 k8sLabelMatcher = re.compile(r'([\w\-_./]+)=\"(.+)\"')
 
+# Ambassador translates the most common ingress-nginx annotations on Ingresses, to ease migrating
+# from ingress-nginx. It has no equivalent of the rest, so it posts an Event on the Ingress for each
+# of those rather than silently ignoring it.
+NGINX_ANNOTATION_PREFIX = 'nginx.ingress.kubernetes.io/'
+
+NGINX_ANNOTATIONS = {
+    'rewrite-target', 'use-regex',
+    'ssl-redirect', 'force-ssl-redirect',
+    'proxy-body-size',
+    'affinity', 'session-cookie-name', 'session-cookie-path', 'session-cookie-max-age'
+}
+
 
 class ResourceFetcher:
     manager: ResourceManager
@@ -67,6 +82,11 @@ class ResourceFetcher:
             AmbassadorProcessor(self.manager),
             ServiceProcessor(self.manager, watch_only=watch_only),
             KnativeIngressProcessor(self.manager),
+            CertificateProcessor(self.manager),
+            # Tenant claims and RouteDelegations are enforced when everything else has been
+            # fetched, so these must come last.
+            TenantProcessor(self.manager),
+            RouteDelegationProcessor(self.manager),
         ]))
 
         self.alerted_about_labels = False
@@ -225,7 +245,7 @@ class ResourceFetcher:
 
             # These objects have to be processed first, in order, as they depend
             # on each other.
-            watt_k8s_keys = ['service', 'endpoints', 'secret', 'ingressclasses', 'ingresses']
+            watt_k8s_keys = ['service', 'endpoints', 'secret', 'IngressClassParameters', 'ingressclasses', 'ingresses']
 
             # Then we add everything else to be processed.
             watt_k8s_keys += watt_k8s.keys()
@@ -248,6 +268,23 @@ class ResourceFetcher:
                     rkey, parsed_objects = result
 
                     self.parse_object(parsed_objects, k8s=False, rkey=rkey)
+
+            consul_connect = watt_consul.get('Connect', {})
+
+            for consul_rkey, consul_object in consul_connect.items():
+                self.handle_consul_connect(consul_rkey, consul_object)
+
+            watt_dns = watt_dict.get('DNS') or {}
+            dns_endpoints = watt_dns.get('Endpoints') or {}
+
+            for dns_rkey, dns_object in dns_endpoints.items():
+                self.handle_dns_service(dns_rkey, dns_object)
+
+            watt_federation = watt_dict.get('Federation') or {}
+            federated_endpoints = watt_federation.get('Endpoints') or {}
+
+            for mc_rkey, mc_object in federated_endpoints.items():
+                self.handle_federated_service(mc_rkey, mc_object)
         except json.decoder.JSONDecodeError as e:
             self.aconf.post_error("%s: could not parse WATT: %s" % (self.location, e))
 
@@ -309,7 +346,8 @@ class ResourceFetcher:
             self.logger.debug(f"{self.location}: skipping K8s {obj.gvk}")
             return
 
-        if not self.check_k8s_dup(obj.kind, obj.namespace, obj.name):
+        # Cluster-scoped objects, like IngressClasses, have no namespace.
+        if not self.check_k8s_dup(obj.kind, obj.key.namespace, obj.name):
             return
 
         result = handler(raw_obj)
@@ -343,6 +381,185 @@ class ResourceFetcher:
     def sorted(self, key=lambda x: x.rkey):  # returns an iterator, probably
         return sorted(self.elements, key=key)
 
+    def handle_k8s_ingressclassparameters(self, k8s_object: AnyDict) -> HandlerResult:
+        metadata = k8s_object.get('metadata', None)
+        name = metadata.get('name') if metadata else None
+
+        if not name:
+            self.logger.debug('ignoring K8s IngressClassParameters with no name')
+            return None
+
+        # Like the IngressClasses that refer to them, IngressClassParameters are not namespaced.
+        # Save them for handle_k8s_ingress, just like IngressClasses.
+        self.aconf.k8s_ingress_class_parameters[name] = k8s_object.get('spec') or {}
+
+        return None
+
+    def ingress_class_parameters(self, ingress_class_name: str, parameters: Optional[AnyDict]) -> AnyDict:
+        """
+        Return the spec of the IngressClassParameters that an IngressClass refers to, or an empty
+        dict if it refers to none.
+        """
+
+        if not parameters:
+            return {}
+
+        api_group = parameters.get('apiGroup') or ''
+        kind = parameters.get('kind')
+        name = parameters.get('name')
+
+        if (api_group != 'getambassador.io') or (kind != 'IngressClassParameters'):
+            self.logger.debug(f'IngressClass {ingress_class_name} has parameters of unknown kind {kind}.{api_group}, ignoring them')
+            return {}
+
+        spec = self.aconf.k8s_ingress_class_parameters.get(name)
+
+        if spec is None:
+            self.aconf.post_error(f'IngressClass {ingress_class_name} refers to IngressClassParameters {name}, which do not exist')
+            return {}
+
+        return spec
+
+    def ingress_backend(self, ingress_name: str, backend: AnyDict) -> Optional[Tuple[str, Any]]:
+        """
+        Return the service name and port of an Ingress backend, or None if it has none.
+        """
+
+        if not backend:
+            return None
+
+        # networking.k8s.io/v1: service.name, and service.port.number or service.port.name.
+        service = backend.get('service')
+
+        if service is not None:
+            port = service.get('port') or {}
+            service_name = service.get('name')
+            service_port = port.get('number') or port.get('name')
+        elif backend.get('resource') is not None:
+            self.aconf.post_error(f'Ingress {ingress_name}: resource backends are not supported, ignoring')
+            return None
+        else:
+            # networking.k8s.io/v1beta1 and extensions/v1beta1.
+            service_name = backend.get('serviceName')
+            service_port = backend.get('servicePort')
+
+        if not service_name or not service_port:
+            return None
+
+        return service_name, service_port
+
+    @staticmethod
+    def ingress_mapping_spec(class_parameters: AnyDict, spec: AnyDict) -> AnyDict:
+        """
+        Add the Mapping settings of an Ingress's IngressClassParameters to the spec of a
+        Mapping generated for it.
+        """
+
+        for key in [ 'rewrite', 'timeout_ms', 'connect_timeout_ms', 'resolver' ]:
+            if key in class_parameters:
+                spec[key] = class_parameters[key]
+
+        return spec
+
+    def nginx_annotations(self, kind: str, ingress_name: str, ingress_namespace: str,
+                          annotations: AnyDict) -> AnyDict:
+        """
+        Translate the ingress-nginx annotations of an Ingress into settings for the Hosts and
+        Mappings generated for it, and post an Event on the Ingress for each annotation that
+        Ambassador has no equivalent of.
+        """
+
+        nginx = { key[len(NGINX_ANNOTATION_PREFIX):]: str(value).strip()
+                  for key, value in annotations.items() if key.startswith(NGINX_ANNOTATION_PREFIX) }
+
+        def unsupported(annotation: str, why: str) -> None:
+            message = f'{NGINX_ANNOTATION_PREFIX}{annotation}: {why}'
+
+            self.aconf.post_notice(f'Ingress {ingress_name}: {message}')
+            self.aconf.k8s_events.append((kind, ingress_name, ingress_namespace, 'UnsupportedAnnotation', message))
+
+        for annotation in sorted(set(nginx.keys()) - NGINX_ANNOTATIONS):
+            unsupported(annotation, 'Ambassador has no equivalent of this annotation, ignoring it')
+
+        settings: AnyDict = {}
+
+        # rewrite-target with capture groups ($1, $2...) only makes sense with a regex path. Like
+        # ingress-nginx, use-regex makes every path of the Ingress a regex.
+        rewrite_target = nginx.get('rewrite-target')
+
+        if rewrite_target is not None:
+            settings['rewrite_target'] = rewrite_target
+
+        if (nginx.get('use-regex', '').lower() == 'true') or re.search(r'\$\d', rewrite_target or ''):
+            settings['regex'] = True
+
+        if (nginx.get('ssl-redirect', '').lower() == 'true') or (nginx.get('force-ssl-redirect', '').lower() == 'true'):
+            settings['insecure_action'] = 'Redirect'
+        elif nginx.get('ssl-redirect', '').lower() == 'false':
+            settings['insecure_action'] = 'Route'
+
+        # Ambassador doesn't limit request bodies unless the ambassador Module turns on
+        # buffering, which is the same as proxy-body-size 0.
+        proxy_body_size = nginx.get('proxy-body-size')
+
+        if proxy_body_size not in ( None, '0' ):
+            unsupported('proxy-body-size',
+                        'Ambassador has no per-Ingress request size limit, ignoring it (use buffer.max_request_bytes in the ambassador Module)')
+
+        affinity = nginx.get('affinity')
+
+        if affinity == 'cookie':
+            cookie = { 'name': nginx.get('session-cookie-name') or 'INGRESSCOOKIE' }
+
+            if nginx.get('session-cookie-path'):
+                cookie['path'] = nginx['session-cookie-path']
+
+            max_age = nginx.get('session-cookie-max-age')
+
+            if max_age:
+                if max_age.isdigit():
+                    cookie['ttl'] = f'{max_age}s'
+                else:
+                    unsupported('session-cookie-max-age', f'{max_age} is not a number of seconds, ignoring it')
+
+            settings['load_balancer'] = {
+                'policy': 'ring_hash',
+                'cookie': cookie
+            }
+        elif affinity:
+            unsupported('affinity', f'only cookie affinity is supported, ignoring {affinity}')
+
+        return settings
+
+    @staticmethod
+    def nginx_mapping_spec(settings: AnyDict, spec: AnyDict) -> AnyDict:
+        """
+        Add the settings translated from an Ingress's ingress-nginx annotations to the spec of a
+        Mapping generated for one of its paths.
+        """
+
+        rewrite_target = settings.get('rewrite_target')
+
+        if settings.get('regex'):
+            spec['prefix_regex'] = True
+
+            if rewrite_target is not None:
+                # Envoy writes capture groups as \1 instead of $1.
+                spec['regex_rewrite'] = {
+                    'pattern': spec['prefix'],
+                    'substitution': re.sub(r'\$(\d)', r'\\\1', rewrite_target)
+                }
+        elif rewrite_target is not None:
+            spec['rewrite'] = rewrite_target
+
+        if 'load_balancer' in settings:
+            spec['load_balancer'] = settings['load_balancer']
+
+            # Sticky sessions need endpoint routing.
+            spec.setdefault('resolver', 'kubernetes-endpoint')
+
+        return spec
+
     def handle_k8s_ingressclass(self, k8s_object: AnyDict) -> HandlerResult:
         metadata = k8s_object.get('metadata', None)
         ingress_class_name = metadata.get('name') if metadata else None
@@ -467,6 +684,13 @@ class ResourceFetcher:
         # but keep a reference to the k8s resource in aconf for debugging and stats
         self.aconf.k8s_ingresses[resource_identifier] = k8s_object
 
+        # Ambassador-specific settings for every Ingress of the class.
+        class_parameters = self.ingress_class_parameters(ingress_class_name, ingress_class)
+
+        # Settings translated from ingress-nginx annotations, which win over the class's.
+        nginx_settings = self.nginx_annotations(k8s_object.get('kind') or 'Ingress', ingress_name,
+                                                ingress_namespace, annotations)
+
         ingress_tls = ingress_spec.get('tls', [])
         for tls_count, tls in enumerate(ingress_tls):
 
@@ -494,7 +718,8 @@ class ResourceFetcher:
                             },
                             'requestPolicy': {
                                 'insecure': {
-                                    'action': 'Route'
+                                    'action': nginx_settings.get('insecure_action',
+                                                                 class_parameters.get('insecure_action', 'Route'))
                                 }
                             }
                         }
@@ -508,10 +733,9 @@ class ResourceFetcher:
 
         # parse ingress.spec.defaultBackend
         # using ingress.spec.backend as a fallback, for older versions of the Ingress resource.
-        default_backend = ingress_spec.get('defaultBackend', ingress_spec.get('backend', {}))
-        db_service_name = default_backend.get('serviceName', None)
-        db_service_port = default_backend.get('servicePort', None)
-        if db_service_name is not None and db_service_port is not None:
+        default_backend = self.ingress_backend(ingress_name, ingress_spec.get('defaultBackend', ingress_spec.get('backend', {})))
+        if default_backend is not None:
+            db_service_name, db_service_port = default_backend
             db_mapping_identifier = f"{ingress_name}-default-backend"
 
             default_backend_mapping: AnyDict = {
@@ -521,11 +745,11 @@ class ResourceFetcher:
                     'name': db_mapping_identifier,
                     'namespace': ingress_namespace
                 },
-                'spec': {
+                'spec': self.ingress_mapping_spec(class_parameters, {
                     'ambassador_id': ambassador_id,
                     'prefix': '/',
                     'service': f'{db_service_name}.{ingress_namespace}:{db_service_port}'
-                }
+                })
             }
 
             if metadata_labels:
@@ -543,56 +767,75 @@ class ResourceFetcher:
 
             http_paths = rule_http.get('paths', [])
             for path_count, path in enumerate(http_paths):
-                path_backend = path.get('backend', {})
+                path_backend = self.ingress_backend(ingress_name, path.get('backend', {}))
                 path_type = path.get('pathType', 'ImplementationSpecific')
-
-                service_name = path_backend.get('serviceName', None)
-                service_port = path_backend.get('servicePort', None)
                 path_location = path.get('path', '/')
 
-                if not service_name or not service_port or not path_location:
+                if path_backend is None or not path_location:
                     continue
 
+                service_name, service_port = path_backend
+
                 unique_suffix = f"{rule_count}-{path_count}"
                 mapping_identifier = f"{ingress_name}-{unique_suffix}"
 
-                # For cases where `pathType: Exact`,
-                # otherwise `Prefix` and `ImplementationSpecific` are handled as regular Mapping prefixes
-                is_exact_prefix = True if path_type == 'Exact' else False
-
-                path_mapping: Dict[str, Any] = {
-                    'apiVersion': 'getambassador.io/v2',
-                    'kind': 'Mapping',
-                    'metadata': {
-                        'name': mapping_identifier,
-                        'namespace': ingress_namespace
-                    },
-                    'spec': {
-                        'ambassador_id': ambassador_id,
-                        'prefix': path_location,
-                        'prefix_exact': is_exact_prefix,
-                        'precedence': 1 if is_exact_prefix else 0,  # Make sure exact paths are evaluated before prefix
-                        'service': f'{service_name}.{ingress_namespace}:{service_port}'
+                # Each path becomes one or two Mappings, as (name, prefix, exact):
+                #
+                # - `Exact` matches the path exactly. Exact paths are evaluated before prefixes.
+                # - `Prefix` matches the path element by element: `/foo` (or `/foo/`) matches `/foo`
+                #   and `/foo/bar`, but not `/foobar`. That takes an exact Mapping for `/foo` and a
+                #   prefix Mapping for `/foo/`.
+                # - `ImplementationSpecific` is a regular Mapping prefix, as it always has been.
+                #
+                # Regex paths (see nginx_annotations) are always a single regex Mapping.
+                if nginx_settings.get('regex'):
+                    path_prefixes = [ (mapping_identifier, path_location, False) ]
+                elif path_type == 'Exact':
+                    path_prefixes = [ (mapping_identifier, path_location, True) ]
+                elif path_type == 'Prefix' and path_location.rstrip('/'):
+                    element_prefix = path_location.rstrip('/')
+
+                    path_prefixes = [
+                        (mapping_identifier, element_prefix + '/', False),
+                        (f"{mapping_identifier}-exact", element_prefix, True)
+                    ]
+                else:
+                    path_prefixes = [ (mapping_identifier, path_location, False) ]
+
+                for path_mapping_identifier, path_prefix, is_exact_prefix in path_prefixes:
+                    path_mapping: Dict[str, Any] = {
+                        'apiVersion': 'getambassador.io/v2',
+                        'kind': 'Mapping',
+                        'metadata': {
+                            'name': path_mapping_identifier,
+                            'namespace': ingress_namespace
+                        },
+                        'spec': self.nginx_mapping_spec(nginx_settings, self.ingress_mapping_spec(class_parameters, {
+                            'ambassador_id': ambassador_id,
+                            'prefix': path_prefix,
+                            'prefix_exact': is_exact_prefix,
+                            'precedence': 1 if path_type == 'Exact' else 0,  # Make sure exact paths are evaluated before prefix
+                            'service': f'{service_name}.{ingress_namespace}:{service_port}'
+                        }))
                     }
-                }
-
-                if metadata_labels:
-                    path_mapping['metadata']['labels'] = metadata_labels
-
-                if rule_host is not None:
-                    if rule_host.startswith('*.'):
-                        # Ingress allow specifying hosts with a single wildcard as the first label in the hostname.
-                        # Transform the rule_host into a host_regex:
-                        # *.star.com  becomes  ^[a-z0-9]([-a-z0-9]*[a-z0-9])?\.star\.com$
-                        path_mapping['spec']['host'] = rule_host\
-                            .replace('.', '\\.')\
-                            .replace('*', '^[a-z0-9]([-a-z0-9]*[a-z0-9])?', 1) + '$'
-                        path_mapping['spec']['host_regex'] = True
-                    else:
-                        path_mapping['spec']['host'] = rule_host
 
-                self.logger.debug(f"Generated mapping from Ingress {ingress_name}: {path_mapping}")
-                self.handle_k8s(path_mapping)
+                    if metadata_labels:
+                        path_mapping['metadata']['labels'] = metadata_labels
+
+                    if rule_host is not None:
+                        if rule_host.startswith('*.'):
+                            # Ingress allow specifying hosts with a single wildcard as the first label in the hostname.
+                            # Transform the rule_host into a host_regex:
+                            # *.star.com  becomes  ^[a-z0-9]([-a-z0-9]*[a-z0-9])?\.star\.com$
+                            path_mapping['spec']['host'] = rule_host\
+                                .replace('.', '\\.')\
+                                .replace('*', '^[a-z0-9]([-a-z0-9]*[a-z0-9])?', 1) + '$'
+                            path_mapping['spec']['host_regex'] = True
+                        else:
+                            path_mapping['spec']['host'] = rule_host
+
+                    self.logger.debug(f"Generated mapping from Ingress {ingress_name}: {path_mapping}")
+                    self.handle_k8s(path_mapping)
 
         # let's make arrangements to update Ingress' status now
         if not self.manager.ambassador_service:
@@ -695,6 +938,40 @@ class ResourceFetcher:
 
         return resource_identifier, [ secret_info ]
 
+    # Handler for K8s ConfigMap resources. We only watch the ones that hold Lua scripts, and
+    # the entrypoint has already checked their syntax: it leaves out the scripts that don't parse,
+    # and says why in an annotation.
+    def handle_k8s_configmap(self, k8s_object: AnyDict) -> HandlerResult:
+        metadata = k8s_object.get('metadata', None) or {}
+        resource_name = metadata.get('name')
+        resource_namespace = metadata.get('namespace', 'default')
+        annotations = metadata.get('annotations', None) or {}
+
+        if not resource_name:
+            self.logger.debug("ignoring K8s ConfigMap with no name")
+            return None
+
+        errors: Dict[str, str] = {}
+        raw_errors = annotations.get('getambassador.io/lua-script-errors', None)
+
+        if raw_errors:
+            try:
+                errors = json.loads(raw_errors)
+            except json.decoder.JSONDecodeError as e:
+                self.logger.warning(f"ConfigMap {resource_name}.{resource_namespace}: could not parse Lua script errors: {e}")
+
+        resource_identifier = f'{resource_name}.{resource_namespace}'
+
+        return resource_identifier, [ {
+            'apiVersion': 'getambassador.io/v2',
+            'ambassador_id': Config.ambassador_id,
+            'kind': 'ConfigMap',
+            'name': resource_name,
+            'namespace': resource_namespace,
+            'data': k8s_object.get('data', None) or {},
+            'errors': errors
+        } ]
+
     # Handler for Consul services
     def handle_consul_service(self,
                               consul_rkey: str, consul_object: AnyDict) -> HandlerResult:
@@ -748,5 +1025,116 @@ class ResourceFetcher:
 
         return None
 
+    # Handler for services looked up by a DNSResolver
+    def handle_dns_service(self,
+                           dns_rkey: str, dns_object: AnyDict) -> HandlerResult:
+        endpoints = dns_object.get('Endpoints') or []
+        name = dns_object.get('Service', dns_rkey)
+
+        if len(endpoints) < 1:
+            self.logger.debug(f"ignoring DNS service {name} with no Endpoints")
+            return None
+
+        # Like Consul, DNS has no indirection between service ports and endpoint ports, so
+        # all the endpoints go under '*'. A port of 0 is from an A or AAAA record, which
+        # doesn't have one; the resolver fills in the Mapping's port.
+        targets = [ {
+            'ip': ep['Address'],
+            'port': ep.get('Port') or 0,
+            'weight': ep.get('Weight') or 1,
+            'target_kind': 'DNS'
+        } for ep in endpoints if ep.get('Address') ]
+
+        spec = {
+            'ambassador_id': Config.ambassador_id,
+            'endpoints': { '*': targets },
+        }
+
+        self.manager.emit(NormalizedResource.from_data(
+            kind='Service',
+            name=name,
+            namespace=dns_object.get('Namespace') or Config.ambassador_namespace,
+            spec=spec,
+            rkey=dns_rkey,
+        ))
+
+        return None
+
+    # Handler for services watched in other clusters by a MultiClusterResolver
+    def handle_federated_service(self,
+                                 mc_rkey: str, mc_object: AnyDict) -> HandlerResult:
+        ports = mc_object.get('Ports') or {}
+        name = mc_object.get('Service', mc_rkey)
+
+        # The watcher already matched the service's ports up with its endpoints' target
+        # ports, so this is just like the endpoints of a service in our own cluster.
+        endpoints: Dict[Any, List[AnyDict]] = {}
+
+        for port, eps in ports.items():
+            targets = [ {
+                'ip': ep['Address'],
+                'port': ep['Port'],
+                'target_kind': 'MultiCluster'
+            } for ep in eps or [] if ep.get('Address') ]
+
+            if targets:
+                endpoints[port if (port == '*') else int(port)] = targets
+
+        if not endpoints:
+            self.logger.debug(f"ignoring federated service {name} in cluster {mc_object.get('Cluster')} with no endpoints")
+            return None
+
+        spec = {
+            'ambassador_id': Config.ambassador_id,
+            'endpoints': endpoints,
+        }
+
+        self.manager.emit(NormalizedResource.from_data(
+            kind='Service',
+            name=name,
+            namespace=mc_object.get('ServiceNamespace') or Config.ambassador_namespace,
+            spec=spec,
+            rkey=mc_rkey,
+        ))
+
+        return None
+
+    # Handler for Consul Connect certificates
+    def handle_consul_connect(self,
+                              consul_rkey: str, consul_object: AnyDict) -> HandlerResult:
+        resolver = consul_object.get('Resolver')
+        namespace = consul_object.get('Namespace') or Config.ambassador_namespace
+        certificate = consul_object.get('Certificate') or {}
+        ca_roots = consul_object.get('CARoots') or {}
+
+        if not resolver or not certificate.get('PEM') or not certificate.get('PrivateKeyPEM'):
+            # Bzzt. Consul hasn't issued us a leaf certificate yet.
+            self.logger.debug(f"ignoring Consul Connect {consul_rkey} with no leaf certificate")
+            return None
+
+        # Trust all the roots, not just the active one, so that upstream certificates keep
+        # validating while Consul rotates its CA.
+        roots = [ root['PEM'] for root in (ca_roots.get('Roots') or {}).values() if root.get('PEM') ]
+
+        spec = {
+            'ambassador_id': Config.ambassador_id,
+            'secret_type': 'kubernetes.io/tls',
+            'tls_crt': certificate['PEM'],
+            'tls_key': certificate['PrivateKeyPEM'],
+        }
+
+        if roots:
+            spec['root-cert_pem'] = '\n'.join(roots)
+
+        # The ConsulResolver synthesizes a TLSContext for a Secret by this name.
+        self.manager.emit(NormalizedResource.from_data(
+            kind='Secret',
+            name=f'{resolver}-consul-connect',
+            namespace=namespace,
+            spec=spec,
+        ))
+
+        return None
+
     def finalize(self) -> None:
         self.k8s_processor.finalize()
@@ -2,7 +2,9 @@ from __future__ import annotations
 from typing import Any, ClassVar, Dict, FrozenSet, List, Optional
 
 import datetime
+import hashlib
 import itertools
+import json
 
 import durationpy
 
@@ -20,6 +22,12 @@ class KnativeIngressProcessor (ManagedKubernetesProcessor):
 
     INGRESS_CLASS: ClassVar[str] = 'ambassador.ingress.networking.knative.dev'
 
+    # Knative probes an Ingress with a request carrying the K-Network-Probe and K-Network-Hash
+    # headers; the Knative backend answers it, echoing K-Network-Hash back. Every Mapping
+    # overwrites K-Network-Hash with the hash of the Ingress it came from, so that the answer
+    # tells the prober which version of the Ingress Envoy is routing with.
+    PROBE_HASH_HEADER: ClassVar[str] = 'K-Network-Hash'
+
     def kinds(self) -> FrozenSet[KubernetesGVK]:
         return frozenset([KubernetesGVK.for_knative_networking('Ingress')])
 
@@ -43,35 +51,73 @@ class KnativeIngressProcessor (ManagedKubernetesProcessor):
 
         return True
 
-    def _emit_mapping(self, obj: KubernetesObject, rule_count: int, rule: Dict[str, Any]) -> None:
+    @staticmethod
+    def _probe_hash(obj: KubernetesObject) -> str:
+        """
+        Return the hash of the spec of a Knative Ingress that its Mappings send to probes.
+        """
+
+        h = hashlib.sha256(json.dumps(obj.spec, sort_keys=True).encode('utf-8'))
+        h.update(f'{obj.namespace}/{obj.name}'.encode('utf-8'))
+
+        return h.hexdigest()
+
+    def _emit_mapping(self, obj: KubernetesObject, rule_count: int, rule: Dict[str, Any],
+                      probe_hash: str) -> List[str]:
+        """
+        Emit the Mappings for a rule of a Knative Ingress, and return what's wrong with it, if
+        anything.
+        """
+
         hosts = rule.get('hosts', [])
+        problems: List[str] = []
 
         split_mapping_specs: List[Dict[str, Any]] = []
 
         paths = rule.get('http', {}).get('paths', [])
-        for path in paths:
+        for path_count, path in enumerate(paths):
             global_headers = path.get('appendHeaders', {})
 
+            # Knative only matches headers exactly.
+            match_headers: Dict[str, str] = {}
+
+            for header_name, match in (path.get('headers') or {}).items():
+                if 'exact' not in (match or {}):
+                    problems.append(f'rule {rule_count} path {path_count}: header {header_name} has no exact match')
+                    continue
+
+                match_headers[header_name] = match['exact']
+
             splits = path.get('splits', [])
             for split in splits:
                 service_name = split.get('serviceName')
                 if not service_name:
+                    problems.append(f'rule {rule_count} path {path_count}: split with no serviceName')
                     continue
 
                 service_namespace = split.get('serviceNamespace', obj.namespace)
                 service_port = split.get('servicePort', 80)
 
-                headers = split.get('appendHeaders', {})
+                headers: Dict[str, Any] = split.get('appendHeaders', {})
                 headers = {**global_headers, **headers}
+                headers[self.PROBE_HASH_HEADER] = { 'value': probe_hash, 'append': False }
 
-                split_mapping_specs.append({
+                split_mapping_spec = {
                     'service': f"{service_name}.{service_namespace}:{service_port}",
                     'add_request_headers': headers,
                     'weight': split.get('percent', 100),
                     'prefix': path.get('path', '/'),
                     'prefix_regex': True,
                     'timeout_ms': int(durationpy.from_str(path.get('timeout', '15s')).total_seconds() * 1000),
-                })
+                }
+
+                if match_headers:
+                    split_mapping_spec['headers'] = match_headers
+
+                if path.get('rewriteHost'):
+                    split_mapping_spec['host_rewrite'] = path['rewriteHost']
+
+                split_mapping_specs.append(split_mapping_spec)
 
         for split_count, (host, split_mapping_spec) in enumerate(itertools.product(hosts, split_mapping_specs)):
             mapping_identifier = f"{obj.name}-{rule_count}-{split_count}"
@@ -94,8 +140,67 @@ class KnativeIngressProcessor (ManagedKubernetesProcessor):
             self.logger.debug(f"Generated mapping from Knative {obj.kind}: {mapping}")
             self.manager.emit(mapping)
 
-    def _make_status(self, generation: int = 1, lb_domain: Optional[str] = None) -> Dict[str, Any]:
+        return problems
+
+    def _emit_hosts(self, obj: KubernetesObject) -> None:
+        """
+        Emit a Host for each host of the tls section of a Knative Ingress. The Host goes in the
+        namespace of its secret, which needn't be that of the Ingress, so its name includes the
+        namespace of the Ingress.
+        """
+
+        # Knative either routes cleartext requests, or redirects them to HTTPS.
+        insecure_action = 'Redirect' if obj.spec.get('httpOption') == 'Redirected' else 'Route'
+
+        for tls_count, tls in enumerate(obj.spec.get('tls', [])):
+            secret_name = tls.get('secretName')
+
+            if not secret_name:
+                continue
+
+            for host_count, host in enumerate(tls.get('hosts', [])):
+                host_resource = NormalizedResource.from_data(
+                    'Host',
+                    f"{obj.name}-{obj.namespace}-{tls_count}-{host_count}",
+                    namespace=tls.get('secretNamespace') or obj.namespace,
+                    generation=obj.generation,
+                    labels=obj.labels,
+                    spec={
+                        'ambassador_id': [ obj.ambassador_id ],
+                        'hostname': host,
+                        'acmeProvider': {
+                            'authority': 'none'
+                        },
+                        'tlsSecret': {
+                            'name': secret_name
+                        },
+                        'requestPolicy': {
+                            'insecure': {
+                                'action': insecure_action
+                            }
+                        }
+                    }
+                )
+
+                self.logger.debug(f"Generated Host from Knative {obj.kind}: {host_resource}")
+                self.manager.emit(host_resource)
+
+    def _make_status(self, generation: int = 1, lb_domain: Optional[str] = None,
+                     public_lb: Optional[List[Dict[str, Any]]] = None,
+                     problems: Optional[List[str]] = None) -> Dict[str, Any]:
         utcnow = datetime.datetime.utcnow().strftime("%Y-%m-%dT%H:%M:%SZ")
+
+        # An Ingress with problems still gets whatever Mappings we could make of it, but Knative
+        # shouldn't send traffic through it.
+        configured: Dict[str, str] = { "status": "True" }
+
+        if problems:
+            configured = {
+                "status": "False",
+                "reason": "InvalidIngress",
+                "message": "; ".join(problems)
+            }
+
         status = {
             "observedGeneration": generation,
             "conditions": [
@@ -106,13 +211,13 @@ class KnativeIngressProcessor (ManagedKubernetesProcessor):
                 },
                 {
                     "lastTransitionTime": utcnow,
-                    "status": "True",
-                    "type": "NetworkConfigured"
+                    "type": "NetworkConfigured",
+                    **configured
                 },
                 {
                     "lastTransitionTime": utcnow,
-                    "status": "True",
-                    "type": "Ready"
+                    "type": "Ready",
+                    **configured
                 }
             ]
         }
@@ -129,11 +234,45 @@ class KnativeIngressProcessor (ManagedKubernetesProcessor):
             status['loadBalancer'] = load_balancer
             status['privateLoadBalancer'] = load_balancer
 
+            # Clients outside the cluster reach ExternalIP rules through the Ambassador service's
+            # load balancer, if it has one.
+            status['publicLoadBalancer'] = { "ingress": public_lb } if public_lb else load_balancer
+
         return status
 
-    def _update_status(self, obj: KubernetesObject) -> None:
-        has_new_generation = obj.generation > obj.status.get('observedGeneration', 0)
+    def _public_load_balancer(self) -> List[Dict[str, Any]]:
+        """
+        Return the load balancer of the Ambassador service as a Knative LoadBalancerStatus.
+        """
+
+        if not self.manager.ambassador_service:
+            return []
+
+        public_lb: List[Dict[str, Any]] = []
+
+        for lb_ingress in self.manager.ambassador_service.status.get('loadBalancer', {}).get('ingress', []):
+            if lb_ingress.get('ip'):
+                public_lb.append({ "ip": lb_ingress['ip'] })
+            elif lb_ingress.get('hostname'):
+                public_lb.append({ "domain": lb_ingress['hostname'] })
+
+        return public_lb
 
+    @staticmethod
+    def _status_summary(status: Dict[str, Any]) -> Dict[str, Any]:
+        """
+        Return the parts of a status that matter for deciding whether it needs writing.
+        """
+
+        return {
+            'observedGeneration': status.get('observedGeneration', 0),
+            'privateLoadBalancer': status.get('privateLoadBalancer'),
+            'publicLoadBalancer': status.get('publicLoadBalancer'),
+            'conditions': sorted((c.get('type'), c.get('status'), c.get('message'))
+                                 for c in status.get('conditions', []))
+        }
+
+    def _update_status(self, obj: KubernetesObject, problems: List[str]) -> None:
         # Knative expects the load balancer information on the ingress, which it
         # then propagates to an ExternalName service for intra-cluster use. We
         # pull that information here. Otherwise, it will continue to use the DNS
@@ -151,27 +290,32 @@ class KnativeIngressProcessor (ManagedKubernetesProcessor):
             # code as well and probably should just be fixed all at once.
             current_lb_domain = f"{self.manager.ambassador_service.name}.{self.manager.ambassador_service.namespace}.svc.cluster.local"
 
-        observed_ingress: Dict[str, Any] = next(iter(obj.status.get('privateLoadBalancer', {}).get('ingress', [])), {})
-        observed_lb_domain = observed_ingress.get('domainInternal')
-
-        has_new_lb_domain = current_lb_domain != observed_lb_domain
+        status = self._make_status(generation=obj.generation, lb_domain=current_lb_domain,
+                                   public_lb=self._public_load_balancer(), problems=problems)
 
-        if has_new_generation or has_new_lb_domain:
-            status = self._make_status(generation=obj.generation, lb_domain=current_lb_domain)
-
-            if status:
-                status_update = (obj.gvk.domain, obj.namespace or 'default', status)
-                self.logger.info(f"Updating Knative {obj.kind} {obj.name} status to {status_update}")
-                self.aconf.k8s_status_updates[f"{obj.name}.{obj.namespace}"] = status_update
+        # Only write the status if it says something new: a new generation, a new load
+        # balancer, or a change in the conditions.
+        if self._status_summary(status) != self._status_summary(obj.status):
+            status_update = (obj.gvk.domain, obj.namespace or 'default', status)
+            self.logger.info(f"Updating Knative {obj.kind} {obj.name} status to {status_update}")
+            self.aconf.k8s_status_updates[f"{obj.name}.{obj.namespace}"] = status_update
         else:
-            self.logger.debug(f"Not reconciling Knative {obj.kind} {obj.name}: observed and current generations are in sync")
+            self.logger.debug(f"Not reconciling Knative {obj.kind} {obj.name}: observed and current status are in sync")
 
     def _process(self, obj: KubernetesObject) -> None:
         if not self._has_required_annotations(obj):
             return
 
+        probe_hash = self._probe_hash(obj)
+        problems: List[str] = []
+
         rules = obj.spec.get('rules', [])
         for rule_count, rule in enumerate(rules):
-            self._emit_mapping(obj, rule_count, rule)
+            problems.extend(self._emit_mapping(obj, rule_count, rule, probe_hash))
+
+        self._emit_hosts(obj)
+
+        for problem in problems:
+            self.aconf.post_error(f"Knative {obj.kind} {obj.name}.{obj.namespace}: {problem}")
 
-        self._update_status(obj)
+        self._update_status(obj, problems)
@@ -1,4 +1,4 @@
-from typing import Dict, FrozenSet, List, Optional
+from typing import Dict, FrozenSet, List, Optional, Tuple
 
 import dataclasses
 
@@ -19,6 +19,10 @@ class EndpointAddress:
     ip: str
     node: Optional[str]
     target: Optional[KubernetesObjectKey]
+    # EndpointSlices tell us the zone of an endpoint and, with topology-aware hints, the zones
+    # that should route to it.
+    zone: Optional[str] = None
+    hints: Optional[Tuple[str, ...]] = None
 
 
 @dataclasses.dataclass(frozen=True)
@@ -183,14 +187,106 @@ class InternalEndpointsProcessor (ManagedKubernetesProcessor):
             self.discovered_endpoints[obj.key] = Endpoints(addresses, port_dict, obj.labels)
 
 
+class InternalEndpointSliceProcessor (ManagedKubernetesProcessor):
+    """
+    This processor discovers EndpointSlices and merges the slices of each service
+    into the same Endpoints that the InternalEndpointsProcessor stores, with zone
+    and hint info for each address.
+    """
+
+    ZONE_LABELS = [ 'topology.kubernetes.io/zone', 'failure-domain.beta.kubernetes.io/zone' ]
+
+    discovered_endpoints: Dict[KubernetesObjectKey, Endpoints]
+
+    def __init__(self, manager: ResourceManager) -> None:
+        super().__init__(manager)
+
+        self.discovered_endpoints = {}
+
+    def kinds(self) -> FrozenSet[KubernetesGVK]:
+        return frozenset([ KubernetesGVK(f'discovery.k8s.io/{version}', 'EndpointSlice')
+                           for version in [ 'v1beta1', 'v1' ] ])
+
+    def _process(self, obj: KubernetesObject) -> None:
+        svc_name = obj.labels.get('kubernetes.io/service-name')
+
+        if not svc_name:
+            self.logger.debug(f"ignoring EndpointSlice {obj.name}.{obj.namespace} with no service")
+            return
+
+        if obj.get('addressType', 'IPv4') not in [ 'IPv4', 'IPv6' ]:
+            # FQDN slices don't have addresses that we can route to.
+            return
+
+        addresses: List[EndpointAddress] = []
+
+        for endpoint in obj.get('endpoints') or []:
+            # A ready condition of null means ready. Terminating endpoints aren't ready.
+            if (endpoint.get('conditions') or {}).get('ready') is False:
+                continue
+
+            target_ref: Optional[KubernetesObjectKey] = None
+            try:
+                target_ref = KubernetesObjectKey.from_object_reference(endpoint.get('targetRef', {}))
+            except KeyError:
+                pass
+
+            # discovery.k8s.io/v1 has a zone field; v1beta1 has topology labels.
+            topology = endpoint.get('topology') or {}
+            zone = endpoint.get('zone') or next((topology[label] for label in self.ZONE_LABELS if topology.get(label)), None)
+
+            hints: Optional[Tuple[str, ...]] = None
+            for_zones = (endpoint.get('hints') or {}).get('forZones')
+
+            if for_zones:
+                hints = tuple(z['name'] for z in for_zones if z.get('name'))
+
+            node = endpoint.get('nodeName') or topology.get('kubernetes.io/hostname')
+
+            for ip in endpoint.get('addresses') or []:
+                addresses.append(EndpointAddress(ip, node=node, target=target_ref, zone=zone, hints=hints))
+
+        port_dict: Dict[str, int] = {}
+
+        for port in obj.get('ports') or []:
+            port_name = port.get('name', None)
+            port_number = port.get('port', None)
+            port_proto = (port.get('protocol') or 'TCP').upper()
+
+            if (port_proto != 'TCP') or (port_number is None):
+                continue
+
+            port_dict[str(port_number)] = port_number
+
+            if port_name:
+                port_dict[port_name] = port_number
+
+        if not addresses or not port_dict:
+            self.logger.debug(f"ignoring EndpointSlice {obj.name}.{obj.namespace} with no routable endpoints")
+            return
+
+        # A service can have many slices. Merge them into one Endpoints, keyed like the
+        # service's Endpoints would be. (Slices of one service almost always have the same
+        # ports; during a rollout that changes them, we route to the union for a while.)
+        key = KubernetesObjectKey(KubernetesGVK('v1', 'Endpoints'), obj.namespace, svc_name)
+        merged = self.discovered_endpoints.get(key)
+
+        if merged:
+            addresses = merged.addresses + addresses
+            port_dict = dict(merged.ports, **port_dict)
+
+        self.discovered_endpoints[key] = Endpoints(addresses, port_dict, obj.labels)
+
+
 class ServiceProcessor (ManagedKubernetesProcessor):
     """
-    This processor handles Service and Endpoints objects and creates relevant
-    Ambassador service resources.
+    This processor handles Service, Endpoints, and EndpointSlice objects and
+    creates relevant Ambassador service resources.
     """
 
     services: InternalServiceProcessor
     endpoints: InternalEndpointsProcessor
+    endpoint_slices: InternalEndpointSliceProcessor
     delegate: AggregateKubernetesProcessor
     watch_only: bool
 
@@ -199,7 +295,8 @@ class ServiceProcessor (ManagedKubernetesProcessor):
 
         self.services = InternalServiceProcessor(manager)
         self.endpoints = InternalEndpointsProcessor(manager)
-        self.delegate = AggregateKubernetesProcessor([self.services, self.endpoints])
+        self.endpoint_slices = InternalEndpointSliceProcessor(manager)
+        self.delegate = AggregateKubernetesProcessor([self.services, self.endpoints, self.endpoint_slices])
         self.watch_only = watch_only
 
     def kinds(self) -> FrozenSet[KubernetesGVK]:
@@ -246,12 +343,14 @@ class ServiceProcessor (ManagedKubernetesProcessor):
 
             target_ports = {}
             target_addrs = []
+            target_zones: Dict[str, str] = {}
             svc_endpoints = {}
 
             if not self.watch_only:
                 # If we're not in watch mode, try to find endpoints for this service.
                 k8s_ep_key = KubernetesObjectKey(KubernetesGVK('v1', 'Endpoints'), k8s_svc.namespace, k8s_svc.name)
-                k8s_ep = self.endpoints.discovered_endpoints.get(k8s_ep_key)
+                k8s_ep = self.endpoints.discovered_endpoints.get(k8s_ep_key) or \
+                         self.endpoint_slices.discovered_endpoints.get(k8s_ep_key)
 
                 # OK, Kube is weird. The way all this works goes like this:
                 #
@@ -357,9 +456,12 @@ class ServiceProcessor (ManagedKubernetesProcessor):
                     # OK. Once _that's_ done we have to take the endpoint addresses into
                     # account, or just use the service name if we don't have that.
 
-                    for addr in k8s_ep.addresses:
+                    for addr in self.zone_addresses(k8s_ep.addresses):
                         target_addrs.append(addr.ip)
 
+                        if addr.zone:
+                            target_zones[addr.ip] = addr.zone
+
             # OK! If we have no target addresses, just use service routing.
             if not target_addrs:
                 if not self.watch_only:
@@ -372,6 +474,11 @@ class ServiceProcessor (ManagedKubernetesProcessor):
                     'port': target_port
                 } for target_addr in target_addrs]
 
+                # Endpoints in known zones carry them, so that the cluster can prefer our own.
+                for target in svc_endpoints[src_port]:
+                    if target['ip'] in target_zones:
+                        target['zone'] = target_zones[target['ip']]
+
             spec = {
                 'ambassador_id': Config.ambassador_id,
                 'endpoints': svc_endpoints,
@@ -390,3 +497,17 @@ class ServiceProcessor (ManagedKubernetesProcessor):
             ))
 
         # self.logger.debug("==== FINALIZE END\n%s" % json.dumps(od, sort_keys=True, indent=4))
+
+    def zone_addresses(self, addresses: List[EndpointAddress]) -> List[EndpointAddress]:
+        # Topology-aware hints work like they do for kube-proxy: they only count if every
+        # endpoint has them, and if some are for our zone, we route to just those. Otherwise
+        # we route to every endpoint, and the zones of the endpoints still let Envoy prefer
+        # our zone.
+        zone = Config.ambassador_zone
+
+        if not zone or not addresses or not all(addr.hints for addr in addresses):
+            return addresses
+
+        hinted = [ addr for addr in addresses if zone in (addr.hints or ()) ]
+
+        return hinted or addresses
@@ -0,0 +1,82 @@
+from typing import Dict, FrozenSet, List, Optional
+
+from ..config import ACResource, Config
+
+from .k8sobject import KubernetesGVK, KubernetesObject
+from .k8sprocessor import ManagedKubernetesProcessor
+from .resource import ResourceManager
+
+# The label that claims a resource for a tenant, and the Namespace annotation that lists,
+# comma-separated, the tenants that may claim resources in the Namespace.
+TenantLabel = 'getambassador.io/tenant'
+TenantsAnnotation = 'getambassador.io/tenants'
+
+
+def resource_tenant(resource: ACResource) -> Optional[str]:
+    """
+    Return the tenant that a resource claims, if any.
+    """
+
+    return (resource.get('metadata_labels') or {}).get(TenantLabel) or None
+
+
+class TenantProcessor (ManagedKubernetesProcessor):
+    """
+    A Kubernetes object processor that enforces tenant claims. It remembers which tenants each
+    Namespace allows, and once everything has been fetched, it drops the resources that claim a
+    tenant their Namespace doesn't allow, and posts an Event on each of them that came from a CRD.
+
+    Resources that claim no tenant are left alone. The watcher only hands us the Namespaces with
+    the tenants annotation, so a Namespace that we don't hear about allows no tenants.
+    """
+
+    tenants: Dict[str, FrozenSet[str]]
+
+    def __init__(self, manager: ResourceManager) -> None:
+        super().__init__(manager)
+
+        self.tenants = {}
+
+    def kinds(self) -> FrozenSet[KubernetesGVK]:
+        return frozenset([KubernetesGVK('v1', 'Namespace')])
+
+    def _process(self, obj: KubernetesObject) -> None:
+        tenants = obj.annotations.get(TenantsAnnotation) or ''
+
+        self.tenants[obj.name] = frozenset(t.strip() for t in tenants.split(',') if t.strip())
+
+    def _violation(self, element: ACResource) -> Optional[str]:
+        """
+        Return why a resource may not claim its tenant, or None if it may.
+        """
+
+        tenant = resource_tenant(element)
+
+        if tenant is None:
+            return None
+
+        namespace = element.get('namespace') or Config.ambassador_namespace
+
+        if tenant in self.tenants.get(namespace, frozenset()):
+            return None
+
+        return f"namespace {namespace} does not allow tenant {tenant}"
+
+    def finalize(self) -> None:
+        allowed: List[ACResource] = []
+
+        for element in self.manager.elements:
+            violation = self._violation(element)
+
+            if violation is None:
+                allowed.append(element)
+                continue
+
+            self.aconf.post_error(f"{element.kind} {element.name} violates tenancy: {violation}", resource=element)
+
+            if (element.get('metadata_labels') or {}).get('ambassador_crd'):
+                self.aconf.k8s_events.append((element.kind, element.name,
+                                              element.get('namespace') or Config.ambassador_namespace,
+                                              'TenantViolation', violation))
+
+        self.manager.elements[:] = allowed
@@ -40,6 +40,9 @@ from .irratelimit import IRRateLimit
 from .irtls import TLSModuleFactory, IRAmbassadorTLS
 from .irlistener import ListenerFactory, IRListener
 from .irlogservice import IRLogService, IRLogServiceFactory
+from .irstatssink import IRStatsSink, IRStatsSinkFactory
+from .irtap import IRTapPolicyFactory
+from .irwasm import IRWasmFilterFactory
 from .irtracing import IRTracing
 from .irtlscontext import IRTLSContext, TLSContextFactory
 from .irserviceresolver import IRServiceResolver, IRServiceResolverFactory, SvcEndpointSet
@@ -89,8 +92,10 @@ class IR:
     hosts: Dict[str, IRHost]
     listeners: List[IRListener]
     log_services: Dict[str, IRLogService]
+    stats_sinks: Dict[str, IRStatsSink]
     ratelimit: Optional[IRRateLimit]
     redirect_cleartext_from: Optional[int]
+    resolved_services: Dict[str, Dict[str, int]]
     resolvers: Dict[str, IRServiceResolver]
     router_config: Dict[str, Any]
     saved_resources: Dict[str, IRResource]
@@ -168,9 +173,11 @@ class IR:
         # self.k8s_status_updates is handled below.
         self.listeners = []
         self.log_services = {}
+        self.stats_sinks = {}
         self.outliers = {}
         self.ratelimit = None
         self.redirect_cleartext_from = None
+        self.resolved_services = {}
         self.resolvers = {}
         self.saved_secrets = {}
         self.secret_info = {}
@@ -183,6 +190,9 @@ class IR:
         # Copy k8s_status_updates from our aconf.
         self.k8s_status_updates = aconf.k8s_status_updates
 
+        # ...and the Kubernetes Events to post.
+        self.k8s_events = aconf.k8s_events
+
         # Check on the intercept agent and edge stack. Note that the Edge Stack touchfile is _not_
         # within $AMBASSADOR_CONFIG_BASE_DIR: it stays in /ambassador no matter what.
 
@@ -262,11 +272,23 @@ class IR:
         self.tracing = typecast(IRTracing, self.save_resource(IRTracing(self, aconf)))
         self.ratelimit = typecast(IRRateLimit, self.save_resource(IRRateLimit(self, aconf)))
         IRLogServiceFactory.load_all(self, aconf)
+        IRStatsSinkFactory.load_all(self, aconf)
 
         # After the Ambassador and TLS modules are done, we need to set up the
-        # filter chains. Note that order of the filters matters. Start with auth,
-        # since it needs to be able to override everything...
-        self.save_filter(IRAuth(self, aconf))
+        # filter chains. Note that order of the filters matters. Taps go first, so that
+        # they see requests that auth rejects...
+        IRTapPolicyFactory.load_all(self, aconf)
+
+        # ...then auth, since it needs to be able to override everything. Chained
+        # AuthServices get filters of their own, run in order of descending precedence...
+        auths = [ IRAuth(self, aconf) ]
+
+        for config in IRAuth.chained_configs(aconf):
+            auths.append(IRAuth(self, aconf, rkey="ir.auth.%s" % config.rkey,
+                                name=config.name, chained_config=config))
+
+        for auth in sorted(auths, key=lambda a: (-a.precedence, a.name)):
+            self.save_filter(auth)
 
         # ...then deal with the non-configurable cors filter...
         self.save_filter(IRFilter(ir=self, aconf=aconf,
@@ -277,6 +299,9 @@ class IR:
         if self.ratelimit:
             self.save_filter(self.ratelimit, already_saved=True)
 
+        # ...then the Wasm filters, so that they only see requests that got this far...
+        IRWasmFilterFactory.load_all(self, aconf)
+
         # ...and, finally, the barely-configurable router filter.
         router_config = {}
 
@@ -298,6 +323,8 @@ class IR:
         TLSModuleFactory.finalize(self, aconf)
         ListenerFactory.finalize(self, aconf)
         MappingFactory.finalize(self, aconf)
+        IRTapPolicyFactory.finalize(self, aconf)
+        IRWasmFilterFactory.finalize(self, aconf)
 
         # At this point we should know the full set of clusters, so we can generate
         # appropriate envoy names.
@@ -517,6 +544,7 @@ class IR:
                                 timeout_ms=60000,
                                 idle_timeout_ms=60000,
                                 tls=ctx_name,
+                                istio_mtls=False,
                                 precedence=-999999) # No, really. See comment above.
 
         mapping.referenced_by(self.ambassador_module)
@@ -707,7 +735,14 @@ class IR:
 
         # OK, ask the resolver for the target list. Understanding the mechanics of resolution
         # and the load balancer policy and all that is up to the resolver.
-        return resolver.resolve(self, cluster, hostname, namespace, port)
+        targets = resolver.resolve(self, cluster, hostname, namespace, port)
+
+        # Remember how many endpoints each service resolved to, so that the diagnostics can
+        # say why a cluster is missing.
+        service = hostname if ('.' in hostname) else f'{hostname}.{namespace}'
+        self.resolved_services.setdefault(resolver.name, {})[f'{service}:{port}'] = len(targets or [])
+
+        return targets
 
     def save_filter(self, resource: IRFilter, already_saved=False) -> None:
         if resource.is_active():
@@ -824,6 +859,9 @@ class IR:
         if self.log_services:
             od['log_services'] = [ srv.as_dict() for srv in self.log_services.values() ]
 
+        if self.stats_sinks:
+            od['stats_sinks'] = [ sink.as_dict() for sink in self.stats_sinks.values() ]
+
         if self.tracing:
             od['tracing'] = self.tracing.as_dict()
 
@@ -1054,6 +1092,9 @@ class IR:
         group_resolver_kube_service = 0   # groups using the KubernetesServiceResolver
         group_resolver_kube_endpoint = 0  # groups using the KubernetesServiceResolver
         group_resolver_consul = 0         # groups using the ConsulResolver
+        group_resolver_dns = 0            # groups using the DNSResolver
+        group_resolver_multicluster = 0   # groups using the MultiClusterResolver
+        group_resolver_static = 0         # groups using the StaticResolver
         mapping_count = 0                 # total mappings
 
         for group in self.ordered_groups():
@@ -1110,6 +1151,12 @@ class IR:
                     group_resolver_kube_endpoint += 1
                 elif resolver.kind == 'ConsulResolver':
                     group_resolver_consul += 1
+                elif resolver.kind == 'DNSResolver':
+                    group_resolver_dns += 1
+                elif resolver.kind == 'MultiClusterResolver':
+                    group_resolver_multicluster += 1
+                elif resolver.kind == 'StaticResolver':
+                    group_resolver_static += 1
 
         od['group_count'] = group_count
         od['group_http_count'] = group_http_count
@@ -1126,6 +1173,9 @@ class IR:
         od['group_resolver_kube_service'] = group_resolver_kube_service
         od['group_resolver_kube_endpoint'] = group_resolver_kube_endpoint
         od['group_resolver_consul'] = group_resolver_consul
+        od['group_resolver_dns'] = group_resolver_dns
+        od['group_resolver_multicluster'] = group_resolver_multicluster
+        od['group_resolver_static'] = group_resolver_static
         od['mapping_count'] = mapping_count
 
         od['listener_count'] = len(self.listeners)
@@ -0,0 +1,359 @@
+from typing import Any, Dict, List, Optional, Tuple
+
+# The Envoy command operators that take no argument, for envoy_log_fields. See
+# https://www.envoyproxy.io/docs/envoy/v1.15.0/configuration/observability/access_log/usage#command-operators
+SimpleCommands = {
+    'BYTES_RECEIVED',
+    'BYTES_SENT',
+    'CONNECTION_ID',
+    'DOWNSTREAM_DIRECT_REMOTE_ADDRESS',
+    'DOWNSTREAM_DIRECT_REMOTE_ADDRESS_WITHOUT_PORT',
+    'DOWNSTREAM_LOCAL_ADDRESS',
+    'DOWNSTREAM_LOCAL_ADDRESS_WITHOUT_PORT',
+    'DOWNSTREAM_LOCAL_PORT',
+    'DOWNSTREAM_LOCAL_SUBJECT',
+    'DOWNSTREAM_LOCAL_URI_SAN',
+    'DOWNSTREAM_PEER_CERT',
+    'DOWNSTREAM_PEER_FINGERPRINT_256',
+    'DOWNSTREAM_PEER_ISSUER',
+    'DOWNSTREAM_PEER_SERIAL',
+    'DOWNSTREAM_PEER_SUBJECT',
+    'DOWNSTREAM_PEER_URI_SAN',
+    'DOWNSTREAM_REMOTE_ADDRESS',
+    'DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT',
+    'DOWNSTREAM_TLS_CIPHER',
+    'DOWNSTREAM_TLS_SESSION_ID',
+    'DOWNSTREAM_TLS_VERSION',
+    'DURATION',
+    'HOSTNAME',
+    'PROTOCOL',
+    'REQUEST_DURATION',
+    'REQUESTED_SERVER_NAME',
+    'RESPONSE_CODE',
+    'RESPONSE_CODE_DETAILS',
+    'RESPONSE_DURATION',
+    'RESPONSE_FLAGS',
+    'RESPONSE_TX_DURATION',
+    'ROUTE_NAME',
+    'UPSTREAM_CLUSTER',
+    'UPSTREAM_HOST',
+    'UPSTREAM_LOCAL_ADDRESS',
+    'UPSTREAM_TRANSPORT_FAILURE_REASON',
+}
+
+# ...and the ones that take an optional strftime-style format.
+TimeCommands = {
+    'DOWNSTREAM_PEER_CERT_V_END',
+    'DOWNSTREAM_PEER_CERT_V_START',
+    'START_TIME',
+}
+
+
+def envoy_log_format_from_fields(fields: Dict[str, Dict[str, Any]]) -> Tuple[Dict[str, str], List[str]]:
+    """
+    Turn the Ambassador Module's envoy_log_fields into an Envoy JSON log format, which
+    maps each field name to an Envoy format string. Each field is an object with exactly
+    one of:
+
+    - command: an Envoy command operator, like RESPONSE_CODE. START_TIME and the
+      DOWNSTREAM_PEER_CERT_V_* commands also take an optional format.
+    - request_header: a request header, with an optional fallback_header to use if
+      it's missing.
+    - response_header or response_trailer.
+    - dynamic_metadata: { filter: name, path: [ keys ] }.
+
+    Headers and metadata can also set max_length.
+
+    Returns the format and a list of errors. Fields with errors are left out.
+    """
+
+    log_format: Dict[str, str] = {}
+    errors: List[str] = []
+
+    for name, field in sorted(fields.items()):
+        sources = [ key for key in [ 'command', 'request_header', 'response_header',
+                                     'response_trailer', 'dynamic_metadata' ] if key in field ]
+
+        if len(sources) != 1:
+            errors.append("envoy_log_fields %s: must have exactly one of command, request_header, "
+                          "response_header, response_trailer, or dynamic_metadata" % name)
+            continue
+
+        source = sources[0]
+        max_length = field.get('max_length', None)
+
+        if source == 'command':
+            command = field['command'].upper()
+            time_format = field.get('format', None)
+
+            if command in TimeCommands:
+                log_format[name] = '%%%s(%s)%%' % (command, time_format) if time_format else '%%%s%%' % command
+            elif command in SimpleCommands and not time_format:
+                log_format[name] = '%%%s%%' % command
+            elif command in SimpleCommands:
+                errors.append("envoy_log_fields %s: %s doesn't take a format" % (name, command))
+            else:
+                errors.append("envoy_log_fields %s: unknown command %s" % (name, command))
+
+            if max_length is not None:
+                errors.append("envoy_log_fields %s: max_length can't be used with a command" % name)
+                log_format.pop(name, None)
+
+            continue
+
+        if source == 'dynamic_metadata':
+            metadata = field['dynamic_metadata']
+            arg = ':'.join([ metadata['filter'] ] + metadata.get('path', []))
+            operator = 'DYNAMIC_METADATA'
+        else:
+            arg = field[source]
+            operator = { 'request_header': 'REQ',
+                         'response_header': 'RESP',
+                         'response_trailer': 'TRAILER' }[source]
+
+            if source == 'request_header' and field.get('fallback_header'):
+                arg = '%s?%s' % (arg, field['fallback_header'])
+
+        log_format[name] = '%%%s(%s)%s%%' % (operator, arg, ':%d' % max_length if max_length else '')
+
+    return log_format, errors
+
+
+def envoy_access_log_filter(flt: Dict[str, Any]) -> Tuple[Optional[Dict[str, Any]], List[str]]:
+    """
+    Turn an Ambassador access log filter into an Envoy AccessLogFilter. The filter can
+    have any of:
+
+    - status_code_min: only log responses with at least this status code.
+    - duration_min_ms: only log requests that took at least this long.
+    - header: only log requests that have this header.
+
+    If more than one is given, all of them must match. Returns None if the filter is
+    empty, along with a list of errors.
+    """
+
+    filters: List[Dict[str, Any]] = []
+    errors: List[str] = []
+
+    for key in sorted(flt.keys()):
+        if key not in [ 'status_code_min', 'duration_min_ms', 'header' ]:
+            errors.append("access log filter: unknown key %s" % key)
+
+    for key, envoy_filter in [ ('status_code_min', 'status_code_filter'),
+                               ('duration_min_ms', 'duration_filter') ]:
+        value = flt.get(key, None)
+
+        if value is None:
+            continue
+
+        if not isinstance(value, int) or (value < 0):
+            errors.append("access log filter: %s must be a non-negative integer" % key)
+            continue
+
+        filters.append({
+            envoy_filter: {
+                'comparison': {
+                    'op': 'GE',
+                    'value': {
+                        'default_value': value,
+                        'runtime_key': 'access_log.%s' % key
+                    }
+                }
+            }
+        })
+
+    header = flt.get('header', None)
+
+    if header is not None:
+        if not isinstance(header, str) or not header:
+            errors.append("access log filter: header must be a header name")
+        else:
+            filters.append({
+                'header_filter': {
+                    'header': {
+                        'name': header,
+                        'present_match': True
+                    }
+                }
+            })
+
+    if errors or not filters:
+        return None, errors
+
+    if len(filters) == 1:
+        return filters[0], errors
+
+    return { 'and_filter': { 'filters': filters } }, errors
+
+
+# access_log_sampling is a percentage to log for each class of response status. Classes
+# that aren't mentioned are always logged. 1xx also covers requests that never got a
+# response at all (status 0).
+SamplingStatusClasses = [ '1xx', '2xx', '3xx', '4xx', '5xx' ]
+
+# Mappings with their own access_log_sampling mark their requests with this header, so
+# that the listener-wide access log filter can tell which policy applies.
+AccessLogSamplingHeader = 'x-ambassador-access-log-sampling'
+
+
+def access_log_sampling_from_config(sampling: Any) -> Tuple[Optional[Dict[str, int]], List[str]]:
+    """
+    Check an access_log_sampling dictionary, and fill in 100% for any status class
+    it doesn't mention. Returns None if it's invalid, along with a list of errors.
+    """
+
+    if not isinstance(sampling, dict):
+        return None, [ "access_log_sampling must be a dictionary" ]
+
+    errors: List[str] = []
+
+    for key in sorted(sampling.keys()):
+        if key not in SamplingStatusClasses:
+            errors.append("access_log_sampling: unknown status class %s" % key)
+        elif isinstance(sampling[key], bool) or not isinstance(sampling[key], int) or not (0 <= sampling[key] <= 100):
+            errors.append("access_log_sampling: %s must be a percentage from 0 to 100" % key)
+
+    if errors:
+        return None, errors
+
+    return { cls: sampling.get(cls, 100) for cls in SamplingStatusClasses }, errors
+
+
+def access_log_sampling_key(sampling: Dict[str, int]) -> str:
+    """
+    Name a (checked) access_log_sampling policy. Mappings with the same policy share a name.
+    """
+
+    return ",".join([ "%s=%d" % (cls, sampling[cls]) for cls in SamplingStatusClasses ])
+
+
+def _status_comparison(op: str, value: int) -> Dict[str, Any]:
+    return {
+        'status_code_filter': {
+            'comparison': {
+                'op': op,
+                'value': {
+                    'default_value': value,
+                    'runtime_key': 'access_log.sampling.status_%s_%d' % (op.lower(), value)
+                }
+            }
+        }
+    }
+
+
+def envoy_access_log_sampling_filter(sampling: Dict[str, int]) -> Optional[Dict[str, Any]]:
+    """
+    Turn a (checked) access_log_sampling policy into an Envoy AccessLogFilter: an
+    or_filter with a branch for each status class, pairing the status range with a
+    runtime_filter for the percentage. Returns None if everything is logged.
+    """
+
+    if all([ sampling[cls] == 100 for cls in SamplingStatusClasses ]):
+        return None
+
+    branches: List[Dict[str, Any]] = []
+
+    for cls in SamplingStatusClasses:
+        percent = sampling[cls]
+
+        if percent == 0:
+            continue
+
+        base = int(cls[0]) * 100
+
+        if cls == '1xx':
+            filters = [ _status_comparison('LE', base + 99) ]
+        elif cls == '5xx':
+            filters = [ _status_comparison('GE', base) ]
+        else:
+            filters = [ _status_comparison('GE', base), _status_comparison('LE', base + 99) ]
+
+        if percent < 100:
+            filters.append({
+                'runtime_filter': {
+                    'runtime_key': 'access_log.sampling.%s' % cls,
+                    'percent_sampled': {
+                        'numerator': percent,
+                        'denominator': 'HUNDRED'
+                    }
+                }
+            })
+
+        branches.append(combine_access_log_filters('and_filter', filters))
+
+    if not branches:
+        # Nothing is logged at all. Envoy has no "never" filter, so sample 0%.
+        return {
+            'runtime_filter': {
+                'runtime_key': 'access_log.sampling.none',
+                'percent_sampled': {
+                    'numerator': 0,
+                    'denominator': 'HUNDRED'
+                }
+            }
+        }
+
+    return combine_access_log_filters('or_filter', branches)
+
+
+def combine_access_log_filters(kind: str, filters: List[Optional[Dict[str, Any]]]) -> Optional[Dict[str, Any]]:
+    """
+    Combine Envoy AccessLogFilters with an and_filter or an or_filter, skipping any that
+    are None. Envoy insists on at least two filters in either, so a single filter is
+    returned as-is, and None is returned if there's nothing to combine.
+    """
+
+    present = [ f for f in filters if f is not None ]
+
+    if not present:
+        return None
+
+    if len(present) == 1:
+        return present[0]
+
+    return { kind: { 'filters': present } }
+
+
+def envoy_access_log_sampling(module_sampling: Optional[Dict[str, int]],
+                              mapping_samplings: Dict[str, Dict[str, int]]) -> Optional[Dict[str, Any]]:
+    """
+    Build the access log filter for the Ambassador module's access_log_sampling policy
+    and the policies of individual Mappings (keyed by access_log_sampling_key). Requests
+    to a Mapping with its own policy carry AccessLogSamplingHeader naming it; everything
+    else gets the module's policy. Returns None if nothing is sampled.
+    """
+
+    module_filter = envoy_access_log_sampling_filter(module_sampling) if module_sampling else None
+
+    if not mapping_samplings:
+        return module_filter
+
+    branches: List[Optional[Dict[str, Any]]] = []
+
+    for key in sorted(mapping_samplings.keys()):
+        branches.append(combine_access_log_filters('and_filter', [
+            {
+                'header_filter': {
+                    'header': {
+                        'name': AccessLogSamplingHeader,
+                        'exact_match': key
+                    }
+                }
+            },
+            envoy_access_log_sampling_filter(mapping_samplings[key])
+        ]))
+
+    branches.append(combine_access_log_filters('and_filter', [
+        {
+            'header_filter': {
+                'header': {
+                    'name': AccessLogSamplingHeader,
+                    'present_match': True,
+                    'invert_match': True
+                }
+            }
+        },
+        module_filter
+    ]))
+
+    return combine_access_log_filters('or_filter', branches)
@@ -14,7 +14,11 @@ from .ircors import IRCORS
 from .irretrypolicy import IRRetryPolicy
 from .irbuffer import IRBuffer
 from .irgzip import IRGzip
+from .irjwt import IRJWT
 from .irfilter import IRFilter
+from .irlua import IRLuaScripts, lua_script_source
+from .iristio import istio_mtls_context
+from .iraccesslog import access_log_sampling_from_config, envoy_access_log_filter, envoy_log_format_from_fields
 
 if TYPE_CHECKING:
     from .ir import IR
@@ -28,6 +32,7 @@ class IRAmbassador (IRResource):
     # PLEASE KEEP THIS LIST SORTED.
 
     AModTransparentKeys: ClassVar = [
+        'access_log_sampling',
         'add_linkerd_headers',
         'admin_port',
         'auth_enabled',
@@ -41,15 +46,19 @@ class IRAmbassador (IRResource):
         'enable_http10',
         'enable_ipv4',
         'enable_ipv6',
+        'envoy_access_logs',
+        'envoy_log_fields',
         'envoy_log_format',
         'envoy_log_path',
         'envoy_log_type',
         # Do not include envoy_validation_timeout; we let finalize() type-check it.
         # Do not include ip_allow or ip_deny; we let finalize() type-check them.
+        'health_check',
         'keepalive',
         'listener_idle_timeout_ms',
         'liveness_probe',
         'load_balancer',
+        'per_mapping_stats',
         'preserve_external_request_id'
         'proper_case',
         'prune_unreachable_routes',
@@ -125,6 +134,7 @@ class IRAmbassador (IRResource):
             x_forwarded_proto_redirect=False,
             load_balancer=None,
             circuit_breakers=None,
+            health_check=None,
             xff_num_trusted_hops=0,
             use_ambassador_namespace_for_service_resolution=False,
             server_name="envoy",
@@ -271,12 +281,84 @@ class IRAmbassador (IRResource):
             self.grpc_stats.sourced_by(amod)
             ir.save_filter(self.grpc_stats)
 
+        if amod and ('grpc_json_transcoder' in amod):
+            transcoder = amod.grpc_json_transcoder
+
+            # The descriptor set can come from a file (typically a ConfigMap mounted into
+            # the Ambassador pod) or be given inline, base64-encoded. Exactly one is needed.
+            descriptors = [ key for key in [ 'proto_descriptor', 'proto_descriptor_bin' ]
+                            if transcoder.get(key) ]
+
+            if len(descriptors) != 1:
+                self.post_error("grpc_json_transcoder needs exactly one of proto_descriptor or proto_descriptor_bin")
+            elif not transcoder.get('services'):
+                self.post_error("grpc_json_transcoder needs at least one service")
+            else:
+                config = {
+                    descriptors[0]: transcoder[descriptors[0]],
+                    'services': transcoder['services']
+                }
+
+                for key in [ 'print_options', 'match_incoming_request_route',
+                             'ignored_query_parameters', 'auto_mapping', 'convert_grpc_status' ]:
+                    if key in transcoder:
+                        config[key] = transcoder[key]
+
+                self.grpc_json_transcoder = IRFilter(ir=ir, aconf=aconf,
+                                                     kind='ir.grpc_json_transcoder',
+                                                     name='grpc_json_transcoder',
+                                                     config=config)
+                self.grpc_json_transcoder.sourced_by(amod)
+                ir.save_filter(self.grpc_json_transcoder)
+
+        if amod and amod.get('tap_admin', False):
+            # An idle admin tap costs next to nothing; busyambassador tap attaches to it
+            # through Envoy's admin /tap endpoint.
+            self.tap_admin = IRFilter(ir=ir, aconf=aconf, kind='ir.tap_admin', name='tap_admin',
+                                      config={ 'common_config': { 'admin_config': { 'config_id': 'ambassador' } } })
+            self.tap_admin.sourced_by(amod)
+            ir.save_filter(self.tap_admin)
+
+        # Lua. The Module's script, inline or from a ConfigMap, runs for every route, unless
+        # a Mapping has a script of its own, so Mappings with scripts need the filter too.
+        lua_inline_code: Optional[str] = None
+
+        if amod and ('lua_scripts' in amod) and ('lua_script' in amod):
+            self.post_error("lua_scripts and lua_script may not both be set; using lua_scripts")
+
         if amod and ('lua_scripts' in amod):
-            self.lua_scripts = IRFilter(ir=ir, aconf=aconf, kind='ir.lua_scripts', name='lua_scripts',
-                                        config={'inline_code': amod.lua_scripts})
-            self.lua_scripts.sourced_by(amod)
+            lua_inline_code = amod.lua_scripts
+        elif amod and ('lua_script' in amod):
+            found = lua_script_source(self, amod.get('namespace', None) or Config.ambassador_namespace,
+                                      amod.lua_script)
+
+            if found:
+                lua_inline_code = found[1]
+
+        mappings = aconf.get_config('mappings') or {}
+
+        if (lua_inline_code is not None) or any(m.get('lua_script', None) for m in mappings.values()):
+            self.lua_scripts = IRLuaScripts(ir=ir, aconf=aconf, inline_code=lua_inline_code)
+
+            if amod:
+                self.lua_scripts.sourced_by(amod)
+
             ir.save_filter(self.lua_scripts)
 
+        # Istio mTLS. The Module says where the Istio certificate comes from, and whether
+        # Mappings originate mTLS with it by default; a Mapping can ask for it either way.
+        istio_mtls = amod.get('istio_mtls', None) if amod else None
+
+        if (istio_mtls is not None) and not isinstance(istio_mtls, dict):
+            self.post_error("istio_mtls must be a dictionary")
+            istio_mtls = None
+
+        istio_mtls = istio_mtls or {}
+        self.istio_mtls_default = bool(istio_mtls.get('enabled', False))
+
+        if self.istio_mtls_default or any(m.get('istio_mtls', False) for m in mappings.values()):
+            istio_mtls_context(ir, aconf, self, istio_mtls)
+
         # Gzip.
         if amod and ('gzip' in amod):
             self.gzip = IRGzip(ir=ir, aconf=aconf, location=self.location, **amod.gzip)
@@ -295,6 +377,20 @@ class IRAmbassador (IRResource):
             else:
                 return False
 
+        # JWT validation.
+        if amod and ('jwt' in amod):
+            self.jwt = IRJWT(ir=ir, aconf=aconf, location=self.location, **amod.jwt)
+
+            if self.jwt:
+                ir.save_filter(self.jwt)
+            else:
+                return False
+
+        # The Envoy that we ship predates the oauth2 filter, so there's nothing we can program
+        # for an `oauth2` element. Say so, rather than silently leaving the routes unprotected.
+        if amod and ('oauth2' in amod):
+            self.post_error("oauth2 is not supported by this version of Ambassador's Envoy; use an AuthService for browser SSO")
+
         if amod and ('keepalive' in amod):
             self.keepalive = amod['keepalive']
 
@@ -339,6 +435,13 @@ class IRAmbassador (IRResource):
                 self.post_error("Invalid circuit_breakers specified: {}".format(self['circuit_breakers']))
                 return False
 
+        if self.get('health_check', None) is not None:
+            error = IRBaseMapping.validate_health_check(self['health_check'])
+
+            if error:
+                self.post_error("Invalid health_check specified: {}".format(error))
+                return False
+
         if self.get('envoy_log_type') == 'text':
             if self.get('envoy_log_format', None) is not None and not isinstance(self.get('envoy_log_format'), str):
                 self.post_error(
@@ -346,17 +449,84 @@ class IRAmbassador (IRResource):
                         self.get('envoy_log_format')))
                 self['envoy_log_format'] = ""
                 return False
-        elif self.get('envoy_log_type') == 'json':
+        elif self.get('envoy_log_type') in [ 'json', 'typed_json' ]:
             if self.get('envoy_log_format', None) is not None and not isinstance(self.get('envoy_log_format'), dict):
                 self.post_error(
-                    "envoy_log_type 'json' requires a dictionary in envoy_log_format: {}, invalidating...".format(
-                        self.get('envoy_log_format')))
+                    "envoy_log_type '{}' requires a dictionary in envoy_log_format: {}, invalidating...".format(
+                        self.get('envoy_log_type'), self.get('envoy_log_format')))
                 self['envoy_log_format'] = {}
                 return False
         else:
-            self.post_error("Invalid log_type specified: {}. Supported: json, text".format(self.get('envoy_log_type')))
+            self.post_error("Invalid log_type specified: {}. Supported: json, typed_json, text".format(self.get('envoy_log_type')))
             return False
 
+        if self.get('envoy_log_fields', None) is not None:
+            fields = self.pop('envoy_log_fields')
+
+            if self.get('envoy_log_type') == 'text':
+                self.post_error("envoy_log_fields requires envoy_log_type json or typed_json, ignoring")
+            elif self.get('envoy_log_format', None) is not None:
+                self.post_error("envoy_log_fields and envoy_log_format cannot both be set, ignoring envoy_log_fields")
+            elif not isinstance(fields, dict):
+                self.post_error("envoy_log_fields must be a dictionary: {}, ignoring".format(fields))
+            else:
+                log_format, errors = envoy_log_format_from_fields(fields)
+
+                for error in errors:
+                    self.post_error(error)
+
+                self['envoy_log_format'] = log_format
+
+        if self.get('envoy_access_logs', None) is not None:
+            sinks = self.pop('envoy_access_logs')
+            access_logs = []
+
+            if not isinstance(sinks, list):
+                sinks = []
+                self.post_error("envoy_access_logs must be a list, ignoring")
+
+            for sink in sinks:
+                sink_type = sink.get('sink', 'file')
+
+                if sink_type == 'file':
+                    path = sink.get('path', None)
+
+                    if not path:
+                        self.post_error("envoy_access_logs: a file sink needs a path, ignoring")
+                        continue
+                elif sink_type == 'stderr':
+                    path = '/dev/stderr'
+                elif sink_type == 'opentelemetry':
+                    # Envoy's OpenTelemetry access logger is newer than the Envoy we ship.
+                    self.post_error("envoy_access_logs: the opentelemetry sink is not supported by this version of Envoy, ignoring")
+                    continue
+                else:
+                    self.post_error("envoy_access_logs: unknown sink %s, ignoring" % sink_type)
+                    continue
+
+                access_log: Dict[str, Any] = { 'path': path }
+
+                if sink.get('filter', None) is not None:
+                    access_log['filter'], errors = envoy_access_log_filter(sink['filter'])
+
+                    if errors:
+                        for error in errors:
+                            self.post_error("envoy_access_logs %s: %s" % (path, error))
+                        continue
+
+                access_logs.append(access_log)
+
+            self['envoy_access_logs'] = access_logs
+
+        if self.get('access_log_sampling', None) is not None:
+            sampling, errors = access_log_sampling_from_config(self.pop('access_log_sampling'))
+
+            for error in errors:
+                self.post_error("%s, ignoring" % error)
+
+            if sampling:
+                self['access_log_sampling'] = sampling
+
         return True
 
     def add_mappings(self, ir: 'IR', aconf: Config):
@@ -369,7 +539,7 @@ class IRAmbassador (IRResource):
                 name = "internal_%s_probe_mapping" % name
 
                 mapping = IRHTTPMapping(ir, aconf, rkey=self.rkey, name=name, location=self.location,
-                                        timeout_ms=10000, **cur)
+                                        timeout_ms=10000, istio_mtls=False, **cur)
                 mapping.referenced_by(self)
                 ir.add_mapping(aconf, mapping)
 
@@ -385,6 +555,7 @@ class IRAmbassador (IRResource):
                                         service="127.0.0.1:8500",
                                         precedence=1000000,
                                         timeout_ms=60000,
+                                        istio_mtls=False,
                                         add_response_headers=edge_stack_response_header)
                 mapping.referenced_by(self)
                 ir.add_mapping(aconf, mapping)
@@ -397,6 +568,7 @@ class IRAmbassador (IRResource):
                                         service="127.0.0.1:8500",
                                         precedence=-1000000,
                                         timeout_ms=60000,
+                                        istio_mtls=False,
                                         add_response_headers=edge_stack_response_header)
                 mapping.referenced_by(self)
                 ir.add_mapping(aconf, mapping)
@@ -1,4 +1,4 @@
-from typing import Optional, TYPE_CHECKING
+from typing import List, Optional, TYPE_CHECKING
 from typing import cast as typecast
 
 from ..config import Config
@@ -7,6 +7,7 @@ from ..resource import Resource
 
 from .irfilter import IRFilter
 from .ircluster import IRCluster
+from .irbasemapping import IRBaseMapping
 from .irretrypolicy import IRRetryPolicy
 
 if TYPE_CHECKING:
@@ -14,6 +15,15 @@ if TYPE_CHECKING:
 
 
 class IRAuth (IRFilter):
+    """
+    IRAuth is an ext_authz filter. Most AuthServices are merged into the single default
+    IRAuth, as they always have been. An AuthService that sets `precedence` or
+    `mapping_prefixes` is chained instead: it gets an IRAuth of its own, and so an ext_authz
+    filter of its own. The ext_authz filters run in order of descending precedence (the
+    default IRAuth has precedence 0), and a chained AuthService only checks requests for
+    Mappings whose prefix starts with one of its `mapping_prefixes`, if it has any.
+    """
+
     cluster: Optional[IRCluster]
 
     def __init__(self, ir: 'IR', aconf: Config,
@@ -37,19 +47,49 @@ class IRAuth (IRFilter):
             allowed_authorization_headers=[],
             hosts={},
             type=type,
+            precedence=0,
+            mapping_prefixes=[],
+            filter_name='envoy.ext_authz',
             **kwargs)
 
+    @staticmethod
+    def is_chained(config: Resource) -> bool:
+        return ('precedence' in config) or ('mapping_prefixes' in config)
+
+    @staticmethod
+    def chained_configs(aconf: Config) -> List[Resource]:
+        """
+        Return the AuthServices that need an IRAuth of their own.
+        """
+
+        config_info = aconf.get_config("auth_configs") or {}
+
+        return [ config for config in config_info.values() if IRAuth.is_chained(config) ]
+
     def setup(self, ir: 'IR', aconf: Config) -> bool:
-        module_info = aconf.get_module("authentication")
+        chained_config = self.pop('chained_config', None)
+
+        if chained_config:
+            self._load_auth(chained_config, ir)
+
+            self.precedence = chained_config.get('precedence', 0)
+            self.mapping_prefixes = chained_config.get('mapping_prefixes', [])
+
+            # Per-route config is keyed by filter name, so every chained filter needs a
+            # name of its own.
+            self.filter_name = 'envoy.ext_authz.%s.%s' % (chained_config.name, chained_config.namespace)
+        else:
+            module_info = aconf.get_module("authentication")
 
-        if module_info:
-            self._load_auth(module_info, ir)
+            if module_info:
+                self._load_auth(module_info, ir)
 
-        config_info = aconf.get_config("auth_configs")
+            config_info = aconf.get_config("auth_configs")
 
-        if config_info:
-            for config in config_info.values():
-                self._load_auth(config, ir)
+            if config_info:
+                for config in config_info.values():
+                    if not IRAuth.is_chained(config):
+                        self._load_auth(config, ir)
 
         if not self.hosts:
             self.logger.debug("IRAuth: found no hosts! going inactive")
@@ -59,6 +99,17 @@ class IRAuth (IRFilter):
 
         return True
 
+    def applies_to(self, prefix: Optional[str], prefix_regex: bool) -> bool:
+        """
+        Should this filter check requests for a Mapping with the given prefix? Regex
+        prefixes can't be compared with mapping_prefixes, so they're always checked.
+        """
+
+        if not self.mapping_prefixes or prefix_regex or (prefix is None):
+            return True
+
+        return any(prefix.startswith(mp) for mp in self.mapping_prefixes)
+
     def add_mappings(self, ir: 'IR', aconf: Config):
         cluster_hosts = self.get('hosts', { '127.0.0.1:5000': ( 100, None, '-internal-' ) })
 
@@ -77,7 +128,8 @@ class IRAuth (IRFilter):
                 host_rewrite=self.get('host_rewrite', False),
                 ctx_name=ctx_name,
                 grpc=grpc,
-                marker='extauth'
+                marker='extauth',
+                circuit_breakers=self.get('circuit_breakers', None)
             )
 
             cluster.referenced_by(self)
@@ -145,6 +197,27 @@ class IRAuth (IRFilter):
         if failure_mode_allow:
             self['failure_mode_allow'] = failure_mode_allow
 
+        protocol_version = module.get('protocol_version', None)
+        if protocol_version:
+            self['protocol_version'] = protocol_version
+
+        allowed_client_headers = module.get('allowed_client_headers', None)
+        if allowed_client_headers:
+            self['allowed_client_headers'] = sorted(set(hdr.lower() for hdr in allowed_client_headers))
+
+        metadata_context_namespaces = module.get('metadata_context_namespaces', None)
+        if metadata_context_namespaces:
+            self['metadata_context_namespaces'] = metadata_context_namespaces
+
+        # Circuit breakers apply to the cluster for the auth service, so that a slow or
+        # dead auth service can't tie up every connection in Envoy.
+        circuit_breakers = module.get('circuit_breakers', None)
+        if circuit_breakers:
+            if IRBaseMapping.validate_circuit_breakers(ir, circuit_breakers):
+                self['circuit_breakers'] = circuit_breakers
+            else:
+                self.post_error("Invalid circuit_breakers specified: {}".format(circuit_breakers))
+
         # Required fields check.
         if self["api_version"] == None:
             self.post_error(RichStatus.fromError("AuthService config requires apiVersion field"))
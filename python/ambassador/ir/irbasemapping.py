@@ -78,8 +78,8 @@ class IRBaseMapping (IRResource):
     host: str
     route_weight: List[Union[str, int]]
     sni: bool
-    cached_status: Optional[Dict[str, str]]
-    status_update: Optional[Dict[str, str]]
+    cached_status: Optional[Dict[str, Any]]
+    status_update: Optional[Dict[str, Any]]
     cluster_key: Optional[str]
 
     def __init__(self, ir: 'IR', aconf: Config,
@@ -153,8 +153,51 @@ class IRBaseMapping (IRResource):
                 self.post_error("Invalid circuit_breakers specified: {}, invalidating mapping".format(self['circuit_breakers']))
                 return False
 
+        if self.get('health_check', None) is not None:
+            error = self.validate_health_check(self['health_check'])
+
+            if error:
+                self.post_error("Invalid health_check specified: {}, invalidating mapping".format(error))
+                return False
+
         return True
 
+    @staticmethod
+    def validate_health_check(health_check) -> Optional[str]:
+        """
+        Check a health_check, returning a description of what's wrong with it, or None if
+        it's OK. A health_check needs exactly one of http, tcp, or grpc.
+        """
+
+        if not isinstance(health_check, dict):
+            return "health_check must be a dictionary"
+
+        checkers = [ key for key in [ 'http', 'tcp', 'grpc' ] if key in health_check ]
+
+        if len(checkers) != 1:
+            return "health_check must have exactly one of http, tcp, or grpc"
+
+        if checkers[0] == 'http':
+            http = health_check['http']
+
+            if not http.get('path', None):
+                return "an http health_check needs a path"
+
+            for status_range in http.get('expected_statuses', []):
+                low = status_range.get('min', None)
+                high = status_range.get('max', None)
+
+                if (low is None) or (high is None) or not (100 <= low <= high <= 599):
+                    return "expected_statuses needs min and max between 100 and 599: {}".format(status_range)
+
+        for key in [ 'timeout_ms', 'interval_ms', 'unhealthy_threshold', 'healthy_threshold' ]:
+            value = health_check.get(key, None)
+
+            if (value is not None) and (not isinstance(value, int) or (value < 1)):
+                return "{} must be a positive integer".format(key)
+
+        return None
+
     @staticmethod
     def validate_circuit_breakers(ir: 'IR', circuit_breakers) -> bool:
         if not isinstance(circuit_breakers, (list, tuple)):
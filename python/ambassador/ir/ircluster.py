@@ -15,6 +15,7 @@
 from typing import Any, ClassVar, Dict, List, Optional, Union, TYPE_CHECKING
 from typing import cast as typecast
 
+import hashlib
 import json
 import re
 import urllib.parse
@@ -59,6 +60,8 @@ class IRCluster (IRResource):
                  load_balancer: Optional[dict] = None,
                  keepalive: Optional[dict] = None,
                  circuit_breakers: Optional[list] = None,
+                 health_check: Optional[dict] = None,
+                 alt_stat_name: Optional[str] = None,
 
                  rkey: str="-override-",
                  kind: str="IRCluster",
@@ -212,6 +215,13 @@ class IRCluster (IRResource):
                     name_fields.append(f'cbu{unknown_breakers}')
                     unknown_breakers += 1
 
+        # Clusters with different health checks can't be shared, so the health check
+        # goes into the name too. (A health check inherited from the Ambassador module
+        # is the same for everyone, so it doesn't need to.)
+        if health_check and (health_check != ir.ambassador_module.get('health_check', None)):
+            hc_hash = hashlib.sha1(json.dumps(health_check, sort_keys=True).encode('utf-8')).hexdigest()[:8]
+            name_fields.append(f'hc{hc_hash}')
+
         # The Ambassador module will always have a load_balancer (which may be None).
         global_load_balancer = ir.ambassador_module.load_balancer
 
@@ -289,6 +299,12 @@ class IRCluster (IRResource):
         if host_rewrite:
             new_args['host_rewrite'] = host_rewrite
 
+        if alt_stat_name:
+            new_args['alt_stat_name'] = alt_stat_name
+
+        if health_check:
+            new_args['health_check'] = health_check
+
         if originate_tls:
             if ctx:
                 new_args['tls_context'] = typecast(IRTLSContext, ctx)
@@ -370,7 +386,8 @@ class IRCluster (IRResource):
         mismatches = []
 
         for key in [ 'type', 'lb_type', 'host_rewrite',
-                     'tls_context', 'originate_tls', 'grpc', 'connect_timeout_ms', 'cluster_idle_timeout_ms' ]:
+                     'tls_context', 'originate_tls', 'grpc', 'connect_timeout_ms', 'cluster_idle_timeout_ms',
+                     'alt_stat_name', 'health_check' ]:
             if self.get(key, None) != other.get(key, None):
                 mismatches.append(key)
 
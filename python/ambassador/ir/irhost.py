@@ -1,12 +1,13 @@
 import copy
-from typing import Dict, List, Optional, TYPE_CHECKING
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
 
 import os
 
 from ..utils import SavedSecret
-from ..config import Config
+from ..config import ACResource, Config
 from .irresource import IRResource
 from .irtlscontext import IRTLSContext
+from .irstatus import certificate_condition, post_conditions, resource_conditions
 
 if TYPE_CHECKING:
     from .ir import IR
@@ -305,6 +306,20 @@ class IRHost(IRResource):
 
         return ir.resolve_secret(self, secret_name, namespace)
 
+    def certificate(self, ir: 'IR') -> Optional[Dict[str, Any]]:
+        """
+        Return the cert-manager Certificate that writes our tlsSecret, if there is one.
+        """
+
+        tls_name = self.get('tlsSecret', {}).get('name', None)
+
+        if not tls_name:
+            return None
+
+        namespace = self.namespace or ir.ambassador_namespace
+
+        return ir.aconf.k8s_certificates.get(f"{tls_name}.{namespace}")
+
 
 class HostFactory:
     @classmethod
@@ -318,6 +333,25 @@ class HostFactory:
                 ir.logger.debug("HostFactory: creating host for %s" % repr(config.as_dict()))
 
                 host = IRHost(ir, aconf, **config)
+                cert = host.certificate(ir)
+
+                if cert and not cert['ready'] and not host.is_active():
+                    # The Host is waiting for cert-manager to issue its certificate, so it's the
+                    # certificate that isn't ready rather than the Host that's wrong.
+                    message = cert['message'] or f"Certificate {cert['name']} has not been issued yet"
+                    conditions = resource_conditions(ir, config, True, programmed=False,
+                                                     not_programmed_reason='CertificateNotReady', message=message)
+                else:
+                    conditions = resource_conditions(ir, config, host.is_active())
+
+                if cert:
+                    cert_condition = certificate_condition(cert, config.get('generation'))
+                    conditions.append(cert_condition)
+
+                    if cert_condition['reason'] == 'RenewalFailed' or not cert['ready']:
+                        cls.post_certificate_event(ir, config, cert_condition)
+
+                post_conditions(ir, 'Host', config, conditions)
 
                 if host.is_active():
                     host.referenced_by(config)
@@ -328,6 +362,17 @@ class HostFactory:
                 else:
                     ir.logger.debug(f"HostFactory: not saving inactive host {host.pretty()}")
 
+    @classmethod
+    def post_certificate_event(cls, ir: 'IR', config: ACResource, cert_condition: Dict[str, Any]) -> None:
+        # Like the status, the Event only goes to Hosts that came from CRDs.
+        if not (config.get('metadata_labels') or {}).get('ambassador_crd'):
+            return
+
+        reason = 'CertificateRenewalFailed' if cert_condition['status'] == 'True' else 'CertificateNotReady'
+
+        ir.k8s_events.append(('Host', config.name, config.get('namespace') or ir.ambassador_namespace,
+                              reason, cert_condition['message']))
+
     @classmethod
     def finalize(cls, ir: 'IR', aconf: Config) -> None:
         if ir.edge_stack_allowed:
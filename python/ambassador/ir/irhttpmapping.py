@@ -10,6 +10,10 @@ from .irbasemappinggroup import IRBaseMappingGroup
 from .irhttpmappinggroup import IRHTTPMappingGroup
 from .ircors import IRCORS
 from .irretrypolicy import IRRetryPolicy
+from .iraccesslog import AccessLogSamplingHeader, access_log_sampling_from_config, access_log_sampling_key
+from .irlua import lua_script_source
+from .iristio import IstioMTLS
+from .irstatus import resource_conditions
 
 import hashlib
 
@@ -73,8 +77,10 @@ class IRHTTPMapping (IRBaseMapping):
     AllowedKeys: ClassVar[Dict[str, bool]] = {
         "add_linkerd_headers": False,
         # Do not include add_request_headers and add_response_headers
+        "auth_context_extensions": False,
         "auto_host_rewrite": False,
         "bypass_auth": False,
+        "bypass_lua": False,
         "case_sensitive": False,
         "circuit_breakers": False,
         "cluster_idle_timeout_ms": False,
@@ -85,14 +91,18 @@ class IRHTTPMapping (IRBaseMapping):
         "enable_ipv6": False,
         "grpc": False,
         # Do not include headers
+        "health_check": False,
         "host": False,          # See notes above
         "host_redirect": False,
         "host_regex": False,
         "host_rewrite": False,
         "idle_timeout_ms": False,
+        "istio_mtls": False,
+        "jwt_requirement": False,
         "keepalive": False,
         "labels": False,        # Not supported in v0; requires v1+; handled in setup
         "load_balancer": False,
+        "lua_script": False,
         # Do not include method
         "method_regex": False,
         "path_redirect": False,
@@ -110,14 +120,23 @@ class IRHTTPMapping (IRBaseMapping):
         # Do not include rewrite
         "service": False,       # See notes above
         "shadow": False,
+        "stats_name": False,
         "timeout_ms": False,
         "tls": False,
+        "tracing": False,
         "use_websocket": False,
         "allow_upgrade": False,
         "weight": False,
 
+        # access_log_sampling isn't defaulted from the Ambassador module, since the
+        # module's policy already applies to every Mapping without its own.
+        "access_log_sampling": False,
+
         # Include the serialization, too.
         "serialization": False,
+
+        # ...and the generation, for the observedGeneration of our status conditions.
+        "generation": False,
     }
 
     def __init__(self, ir: 'IR', aconf: Config,
@@ -177,15 +196,15 @@ class IRHTTPMapping (IRBaseMapping):
         regex_rewrite = kwargs.get('regex_rewrite', {})
 
         if 'headers' in kwargs:
-            for name, value in kwargs.get('headers', {}).items():
+            for hdr_name, value in kwargs.get('headers', {}).items():
                 if value is True:
-                    hdrs.append(KeyValueDecorator(name))
+                    hdrs.append(KeyValueDecorator(hdr_name))
                 else:
-                    hdrs.append(KeyValueDecorator(name, value))
+                    hdrs.append(KeyValueDecorator(hdr_name, value))
 
         if 'regex_headers' in kwargs:
-            for name, value in kwargs.get('regex_headers', {}).items():
-                hdrs.append(KeyValueDecorator(name, value, regex=True))
+            for hdr_name, value in kwargs.get('regex_headers', {}).items():
+                hdrs.append(KeyValueDecorator(hdr_name, value, regex=True))
 
         if 'host' in kwargs:
             hdrs.append(KeyValueDecorator(":authority", kwargs['host'], kwargs.get('host_regex', False)))
@@ -244,6 +263,15 @@ class IRHTTPMapping (IRBaseMapping):
         if add_linkerd_headers:
             add_request_hdrs['l5d-dst-override'] = svc.hostname_port
 
+        # A Mapping that asks for Istio mTLS gets it, unless it names a TLS context of its own.
+        istio_mtls = new_args.get('istio_mtls', None)
+
+        if istio_mtls and ('tls' in new_args):
+            self.ir.aconf.post_notice(f"Mapping {name}: tls is set, so istio_mtls is ignored")
+        elif istio_mtls:
+            ir.logger.debug(f"Mapping {name}: using Istio mTLS context {IstioMTLS.ContextName}")
+            new_args['tls'] = IstioMTLS.ContextName
+
         # XXX BRUTAL HACK HERE:
         # If we _don't_ have an origination context, but our IR has an agent_origination_ctx,
         # force TLS origination because it's the agent. I know, I know. It's a hack.
@@ -251,16 +279,27 @@ class IRHTTPMapping (IRBaseMapping):
             ir.logger.debug(f"Mapping {name}: Agent forcing origination TLS context to {ir.agent_origination_ctx.name}")
             new_args['tls'] = ir.agent_origination_ctx.name
 
+        # A ConsulResolver with Connect turned on originates mTLS with its Connect certificate.
+        if ('tls' not in new_args) and resolver and resolver.get('connect_context'):
+            ir.logger.debug(f"Mapping {name}: using Consul Connect TLS context {resolver.connect_context}")
+            new_args['tls'] = resolver.connect_context
+
+        # Failing all that, the ambassador Module may turn Istio mTLS on for every Mapping that
+        # doesn't turn it off.
+        if ('tls' not in new_args) and (istio_mtls is None) and ir.ambassador_module.get('istio_mtls_default', False):
+            ir.logger.debug(f"Mapping {name}: using Istio mTLS context {IstioMTLS.ContextName} by default")
+            new_args['tls'] = IstioMTLS.ContextName
+
         if 'query_parameters' in kwargs:
-            for name, value in kwargs.get('query_parameters', {}).items():
+            for qp_name, value in kwargs.get('query_parameters', {}).items():
                 if value is True:
-                    query_parameters.append(KeyValueDecorator(name))
+                    query_parameters.append(KeyValueDecorator(qp_name))
                 else:
-                    query_parameters.append(KeyValueDecorator(name, value))
+                    query_parameters.append(KeyValueDecorator(qp_name, value))
 
         if 'regex_query_parameters' in kwargs:
-            for name, value in kwargs.get('regex_query_parameters', {}).items():
-                query_parameters.append(KeyValueDecorator(name, value, regex=True))
+            for qp_name, value in kwargs.get('regex_query_parameters', {}).items():
+                query_parameters.append(KeyValueDecorator(qp_name, value, regex=True))
 
         if 'regex_rewrite' in kwargs:
             if rewrite and rewrite != "/":
@@ -292,6 +331,12 @@ class IRHTTPMapping (IRBaseMapping):
         if not super().setup(ir, aconf):
             return False
 
+        # If we're to originate Istio mTLS but the Istio certificates aren't usable, drop
+        # the Mapping rather than send cleartext into the mesh.
+        if (self.get('tls', None) == IstioMTLS.ContextName) and not ir.has_tls_context(IstioMTLS.ContextName):
+            self.post_error("istio_mtls: the Istio certificates are not available")
+            return False
+
         # If we have CORS stuff, normalize it.
         if 'cors' in self:
             self.cors = IRCORS(ir=ir, aconf=aconf, location=self.location, **self.cors)
@@ -362,11 +407,59 @@ class IRHTTPMapping (IRBaseMapping):
                 domain = 'ambassador' if not ir.ratelimit else ir.ratelimit.domain
                 self['labels'] = { domain: labels }
 
+        # A Mapping's own Lua script runs, by name, instead of the ambassador Module's. The
+        # Module set up the Lua filter, since it saw that we have one.
+        if self.get('lua_script', None) is not None:
+            found = lua_script_source(self, self.namespace, self['lua_script'])
+
+            if not found:
+                return False
+
+            lua_scripts = ir.ambassador_module.get('lua_scripts', None)
+
+            if not lua_scripts:
+                self.post_error("lua_script: the Lua filter is not configured")
+                return False
+
+            self['lua_script_name'], source = found
+            lua_scripts.add_script(self['lua_script_name'], source)
+
+        # Likewise, a jwt_requirement needs the ambassador Module's jwt element, and can only
+        # name its providers.
+        if self.get('jwt_requirement', None) is not None:
+            jwt = ir.ambassador_module.get('jwt', None)
+
+            if not jwt:
+                self.post_error("jwt_requirement: JWT validation is not configured")
+                return False
+
+            if not jwt.check_requirement(self, self['jwt_requirement']):
+                return False
+
         if self.get('load_balancer', None) is not None:
             if not self.validate_load_balancer(self['load_balancer']):
                 self.post_error("Invalid load_balancer specified: {}, invalidating mapping".format(self['load_balancer']))
                 return False
 
+        # Access logs belong to the listener, not the route, so a Mapping with its own
+        # access_log_sampling tags its requests with the name of its policy, and the
+        # listener's access log filter picks the policy from that.
+        if self.get('access_log_sampling', None) is not None:
+            sampling, errors = access_log_sampling_from_config(self['access_log_sampling'])
+
+            if errors:
+                for error in errors:
+                    self.post_error(error)
+
+                return False
+
+            self['access_log_sampling'] = sampling
+            self['add_request_headers'] = dict(self.get('add_request_headers', {}))
+            self['add_request_headers'][AccessLogSamplingHeader] = {
+                'value': access_log_sampling_key(sampling),
+                'append': False
+            }
+
         return True
 
     @staticmethod
@@ -452,8 +545,10 @@ class IRHTTPMapping (IRBaseMapping):
 
         return errstr
 
-    def status(self) -> Dict[str, str]:
+    def status(self) -> Dict[str, Any]:
+        conditions = resource_conditions(self.ir, self, self.is_active())
+
         if not self.is_active():
-            return { 'state': 'Inactive', 'reason': self.summarize_errors() }
+            return { 'state': 'Inactive', 'reason': self.summarize_errors(), 'conditions': conditions }
         else:
-            return { 'state': 'Running' }
+            return { 'state': 'Running', 'conditions': conditions }
@@ -32,6 +32,7 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         'cluster_idle_timeout_ms': True,
         'group_id': True,
         'headers': True,
+        'health_check': True,
         # 'host_rewrite': True,
         # 'idle_timeout_ms': True,
         'keepalive': True,
@@ -210,6 +211,14 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
         if not cluster:
             # OK, we have to actually do some work.
             self.ir.logger.debug(f"IRHTTPMappingGroup: synthesizing Cluster for {mapping.name}")
+
+            # The Ambassador module's health_check is the default, except for the Mappings
+            # that Ambassador makes for itself.
+            health_check = mapping.get('health_check', None)
+
+            if (health_check is None) and (mapping.rkey != self.ir.ambassador_module.rkey):
+                health_check = self.ir.ambassador_module.get('health_check', None)
+
             cluster = IRCluster(ir=self.ir, aconf=self.ir.aconf,
                                 parent_ir_resource=mapping,
                                 location=mapping.location,
@@ -225,6 +234,7 @@ class IRHTTPMappingGroup (IRBaseMappingGroup):
                                 connect_timeout_ms=mapping.get('connect_timeout_ms', 3000),
                                 cluster_idle_timeout_ms=mapping.get('cluster_idle_timeout_ms', None),
                                 circuit_breakers=mapping.get('circuit_breakers', None),
+                                health_check=health_check,
                                 marker=marker)
 
         # Make sure that the cluster is actually in our IR...
@@ -0,0 +1,72 @@
+from typing import Any, ClassVar, Dict, Optional, TYPE_CHECKING
+
+from ..config import Config
+
+from .irresource import IRResource
+from .irtlscontext import IRTLSContext
+
+if TYPE_CHECKING:
+    from .ir import IR
+
+
+class IstioMTLS:
+    """
+    Where Ambassador gets the Istio workload certificate that it originates mTLS into the mesh
+    with: either files that the Istio agent writes out (cert_dir), or the Istio agent's SDS
+    socket (sds_socket).
+    """
+
+    # The TLSContext that Mappings using Istio mTLS originate with.
+    ContextName: ClassVar[str] = 'istio-mtls'
+
+    # The files that the Istio agent writes with OUTPUT_CERTS, and where we mount them by default.
+    DefaultCertDir: ClassVar[str] = '/etc/istio-certs'
+    CertChainFile: ClassVar[str] = 'cert-chain.pem'
+    PrivateKeyFile: ClassVar[str] = 'key.pem'
+    RootCertFile: ClassVar[str] = 'root-cert.pem'
+
+    # The Istio agent's SDS secrets, and the bootstrap cluster that we fetch them over.
+    SDSCluster: ClassVar[str] = 'sds-grpc'
+    SDSCertName: ClassVar[str] = 'default'
+    SDSRootName: ClassVar[str] = 'ROOTCA'
+
+    # Istio sidecars only treat a connection as ISTIO_MUTUAL if they see these.
+    ALPNProtocols: ClassVar[str] = 'istio-peer-exchange,istio'
+
+
+def istio_mtls_context(ir: 'IR', aconf: Config, owner: IRResource, config: Dict[str, Any]) -> Optional[IRTLSContext]:
+    """
+    Synthesize and save the TLSContext for the istio_mtls config of owner, the ambassador
+    Module. If config doesn't make sense, or the certificates aren't there, post an error on
+    owner and return None: Mappings still name the context, so that they fail closed instead
+    of sending cleartext into a strict-mTLS mesh.
+    """
+
+    if ('cert_dir' in config) and ('sds_socket' in config):
+        owner.post_error("istio_mtls: cert_dir and sds_socket may not both be set")
+        return None
+
+    sds_socket = config.get('sds_socket', None)
+
+    if sds_socket:
+        ctx = IRTLSContext(ir, aconf, rkey=owner.rkey, location=owner.location,
+                           name=IstioMTLS.ContextName, namespace=owner.namespace,
+                           alpn_protocols=IstioMTLS.ALPNProtocols, _istio_sds=sds_socket)
+    else:
+        cert_dir = (config.get('cert_dir', None) or IstioMTLS.DefaultCertDir).rstrip('/')
+
+        ctx = IRTLSContext(ir, aconf, rkey=owner.rkey, location=owner.location,
+                           name=IstioMTLS.ContextName, namespace=owner.namespace,
+                           alpn_protocols=IstioMTLS.ALPNProtocols,
+                           cert_chain_file=f'{cert_dir}/{IstioMTLS.CertChainFile}',
+                           private_key_file=f'{cert_dir}/{IstioMTLS.PrivateKeyFile}',
+                           cacert_chain_file=f'{cert_dir}/{IstioMTLS.RootCertFile}')
+
+    if not (ctx.is_active() and ctx.resolve()):
+        owner.post_error("istio_mtls: could not use the Istio certificates")
+        return None
+
+    ctx.referenced_by(owner)
+    ir.save_tls_context(ctx)
+
+    return ctx
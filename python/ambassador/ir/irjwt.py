@@ -0,0 +1,160 @@
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
+from typing import cast as typecast
+
+from urllib.parse import urlparse
+
+from ..config import Config
+from ..utils import RichStatus
+
+from .irresource import IRResource
+from .irfilter import IRFilter
+from .ircluster import IRCluster
+
+if TYPE_CHECKING:
+    from .ir import IR
+
+
+class IRJWT (IRFilter):
+    """
+    IRJWT is the built-in JWT validation filter, configured by the `jwt` element of the
+    Ambassador module:
+
+        jwt:
+          providers:
+          - name: auth0
+            issuer: https://example.auth0.com/
+            audiences: [ "my-api" ]
+            jwks_uri: https://example.auth0.com/.well-known/jwks.json
+            jwks_cache_duration_s: 600
+            forward_payload_header: x-jwt-payload
+
+    Mappings opt in with `jwt_requirement`; see V2Listener for how those become rules.
+    """
+
+    providers: Dict[str, Dict[str, Any]]
+    clusters: Dict[str, IRCluster]
+
+    def __init__(self, ir: 'IR', aconf: Config,
+                 rkey: str="ir.jwt",
+                 name: str="ir.jwt",
+                 kind: str="IRJWT",
+                 **kwargs) -> None:
+
+        super().__init__(
+            ir=ir, aconf=aconf, rkey=rkey, kind=kind, name=name, **kwargs)
+
+    def setup(self, ir: 'IR', aconf: Config) -> bool:
+        raw_providers = self.pop('providers', None)
+
+        if not raw_providers:
+            self.post_error(RichStatus.fromError("jwt requires at least one provider"))
+            return False
+
+        self.providers = {}
+        self.clusters = {}
+
+        for provider in raw_providers:
+            name = provider.get('name', None)
+
+            if not name:
+                self.post_error(RichStatus.fromError("jwt provider requires a name"))
+                return False
+
+            if name in self.providers:
+                self.post_error(RichStatus.fromError("jwt provider %s is defined more than once" % name))
+                return False
+
+            jwks_uri = provider.get('jwks_uri', None)
+            parsed = urlparse(jwks_uri or '')
+
+            if not parsed.scheme or not parsed.netloc:
+                self.post_error(RichStatus.fromError("jwt provider %s requires an absolute jwks_uri" % name))
+                return False
+
+            self.providers[name] = provider
+
+        return True
+
+    def add_mappings(self, ir: 'IR', aconf: Config):
+        for name, provider in self.providers.items():
+            parsed = urlparse(provider['jwks_uri'])
+
+            cluster = ir.add_cluster(
+                IRCluster(
+                    ir=ir,
+                    aconf=aconf,
+                    parent_ir_resource=self,
+                    location=self.location,
+                    service="%s://%s" % (parsed.scheme, parsed.netloc),
+                    marker='jwks'
+                )
+            )
+
+            cluster.referenced_by(self)
+            self.clusters[name] = cluster
+
+    def provider_config(self, name: str) -> Dict[str, Any]:
+        """
+        Return the Envoy JwtProvider config for the provider called name.
+        """
+
+        provider = self.providers[name]
+        cluster = typecast(IRCluster, self.clusters[name])
+
+        config: Dict[str, Any] = {
+            'remote_jwks': {
+                'http_uri': {
+                    'uri': provider['jwks_uri'],
+                    'cluster': cluster.envoy_name,
+                    'timeout': "%0.3fs" % (float(provider.get('jwks_timeout_ms', 5000)) / 1000.0)
+                },
+                'cache_duration': "%ds" % provider.get('jwks_cache_duration_s', 300)
+            },
+            'forward': provider.get('forward', True)
+        }
+
+        for key in [ 'issuer', 'audiences', 'forward_payload_header' ]:
+            if key in provider:
+                config[key] = provider[key]
+
+        return config
+
+    def check_requirement(self, mapping: IRResource, jwt_requirement: Dict[str, Any]) -> bool:
+        """
+        Check a Mapping's jwt_requirement against our providers, posting errors on the
+        Mapping if it's no good. Envoy rejects the whole listener over a requirement that
+        names a provider it doesn't have, so the Mapping has to go instead.
+        """
+
+        providers: List[str] = jwt_requirement.get('providers', [])
+
+        if not providers:
+            mapping.post_error(RichStatus.fromError("jwt_requirement requires at least one provider"))
+            return False
+
+        unknown = [ p for p in providers if p not in self.providers ]
+
+        if unknown:
+            mapping.post_error(RichStatus.fromError("jwt_requirement: unknown jwt provider %s" %
+                                                    ", ".join(unknown)))
+            return False
+
+        return True
+
+    @staticmethod
+    def requirement(jwt_requirement: Dict[str, Any]) -> Dict[str, Any]:
+        """
+        Turn a Mapping's jwt_requirement, which check_requirement has accepted, into an
+        Envoy JwtRequirement.
+        """
+
+        providers: List[str] = jwt_requirement.get('providers', [])
+        requirements: List[Dict[str, Any]] = [ { 'provider_name': p } for p in providers ]
+
+        if jwt_requirement.get('allow_missing', False):
+            requirements.append({ 'allow_missing': {} })
+
+        if len(requirements) == 1:
+            return requirements[0]
+
+        return { 'requires_any': { 'requirements': requirements } }
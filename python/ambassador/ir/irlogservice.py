@@ -1,10 +1,11 @@
-from typing import Optional, TYPE_CHECKING
+from typing import Any, Dict, Optional, TYPE_CHECKING
 
 from ..config import Config
 from ..utils import RichStatus
 
 from .irresource import IRResource
 from .ircluster import IRCluster
+from .iraccesslog import envoy_access_log_filter
 
 if TYPE_CHECKING:
     from .ir import IR
@@ -18,6 +19,8 @@ class IRLogService(IRResource):
     flush_interval_byte_size: int
     flush_interval_time: int
     grpc: bool
+    protocol_version: str
+    filter: Optional[Dict[str, Any]]
 
     def __init__(self, ir: 'IR', config,
                  rkey: str = "ir.logservice",
@@ -46,6 +49,21 @@ class IRLogService(IRResource):
         self.flush_interval_byte_size = config.get('flush_interval_byte_size', 16384)
         self.flush_interval_time = config.get('flush_interval_time', 1)
 
+        # Which version of the ALS protocol should we speak? v2 is the default, for
+        # compatibility with existing log services.
+        self.protocol_version = config.get('protocol_version', 'v2')
+
+        # Which entries should we send? By default, all of them.
+        self.filter = None
+
+        if config.get('filter', None) is not None:
+            self.filter, errors = envoy_access_log_filter(config['filter'])
+
+            if errors:
+                for error in errors:
+                    self.post_error(error)
+                return False
+
         self.driver_config = config.get('driver_config')
         if 'additional_log_headers' in self.driver_config:
             if self.driver != 'http' and self.driver_config['additional_log_headers']:
@@ -85,7 +103,7 @@ class IRLogService(IRResource):
         # of paranoia.
         assert(self.cluster)
 
-        return {
+        common_config = {
             "log_name": self.name,
             "grpc_service": {
                 "envoy_grpc": {
@@ -96,6 +114,11 @@ class IRLogService(IRResource):
             "buffer_size_bytes": self.flush_interval_byte_size,
         }
 
+        if self.protocol_version == 'v3':
+            common_config['transport_api_version'] = 'V3'
+
+        return common_config
+
     def get_additional_headers(self) -> list:
         if 'additional_log_headers' in self.driver_config:
             return self.driver_config.get('additional_log_headers', [])
@@ -114,6 +137,6 @@ class IRLogServiceFactory:
 
                 if extant_srv:
                     ir.post_error("Duplicate LogService %s; keeping definition from %s" % (srv.name, extant_srv.location))
-                else:
+                elif srv.is_active():
                     ir.log_services[srv.name] = srv
                     ir.save_resource(srv)
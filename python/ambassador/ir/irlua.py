@@ -0,0 +1,77 @@
+from typing import Any, ClassVar, Dict, Optional, Tuple, TYPE_CHECKING
+
+from ..config import Config
+
+from .irfilter import IRFilter
+from .irresource import IRResource
+
+if TYPE_CHECKING:
+    from .ir import IR
+
+
+class IRLuaScripts (IRFilter):
+    """
+    The Lua filter. Its inline_code is the ambassador Module's script, which runs for every
+    route. Its source_codes are the named scripts of Mappings: the route of a Mapping with a
+    script of its own runs that instead, by name.
+    """
+
+    # Envoy insists on a script for every route, so when the Module doesn't have one, routes
+    # run this unless their Mapping has a script.
+    NoScript: ClassVar[str] = "-- No Lua script for this route.\n"
+
+    def __init__(self, ir: 'IR', aconf: Config, inline_code: Optional[str] = None, **kwargs) -> None:
+        super().__init__(
+            ir=ir, aconf=aconf, rkey="ir.lua_scripts", kind="ir.lua_scripts", name="lua_scripts",
+            config={ 'inline_code': inline_code if inline_code is not None else IRLuaScripts.NoScript },
+            **kwargs)
+
+        self.source_codes: Dict[str, str] = {}
+
+    def add_script(self, name: str, source: str) -> None:
+        self.source_codes[name] = source
+
+    def config_dict(self) -> Optional[dict]:
+        config = dict(self.config)
+
+        if self.source_codes:
+            config['source_codes'] = { name: { 'inline_string': source }
+                                       for name, source in sorted(self.source_codes.items()) }
+
+        return config
+
+
+def lua_script_source(owner: IRResource, namespace: str, ref: Any) -> Optional[Tuple[str, str]]:
+    """
+    Find the Lua script that ref, a lua_script of owner, names, and return the name of the
+    script and its source. The script lives in a ConfigMap in namespace. If there's no such
+    script, or it doesn't parse, post an error on owner and return None.
+    """
+
+    if not isinstance(ref, dict) or not ref.get('config_map') or not ref.get('key'):
+        owner.post_error("lua_script must have a config_map and a key")
+        return None
+
+    cm_name = ref['config_map']
+    key = ref['key']
+    config_maps = owner.ir.aconf.get_config('config_maps') or {}
+    config_map = config_maps.get(f'{cm_name}.{namespace}', None)
+
+    if not config_map:
+        owner.post_error("lua_script: no ConfigMap %s in namespace %s with the getambassador.io/lua-scripts label" %
+                         (cm_name, namespace))
+        return None
+
+    error = (config_map.get('errors', None) or {}).get(key, None)
+
+    if error:
+        owner.post_error("lua_script: %s in ConfigMap %s does not parse: %s" % (key, cm_name, error))
+        return None
+
+    source = (config_map.get('data', None) or {}).get(key, None)
+
+    if source is None:
+        owner.post_error("lua_script: ConfigMap %s has no key %s" % (cm_name, key))
+        return None
+
+    return f'{cm_name}.{namespace}/{key}', source
@@ -63,6 +63,14 @@ class IRRateLimit (IRFilter):
         # XXX Needs to be configurable.
         self.data_plane_proto = False
 
+        # Which version of the RLS protocol should we speak? v2 is the default, for
+        # compatibility with existing rate limit services.
+        self.protocol_version = config.get('protocol_version', 'v2')
+
+        # Envoy's HTTP rate limit filter has no stat_prefix of its own, so we use
+        # stat_prefix to name the RLS cluster's stats instead.
+        self.stat_prefix = config.get('stat_prefix', None)
+
         # Filter config.
         self.config = {
             "domain": self.domain,
@@ -70,6 +78,9 @@ class IRRateLimit (IRFilter):
             "request_type": "both"  # XXX configurability!
         }
 
+        if config.get('failure_mode_deny', False):
+            self.config['failure_mode_deny'] = True
+
         self.sourced_by(config)
         self.referenced_by(config)
 
@@ -85,7 +96,8 @@ class IRRateLimit (IRFilter):
                 service=self.service,
                 grpc=True,
                 host_rewrite=self.get('host_rewrite', None),
-                ctx_name=self.get('ctx_name', None)
+                ctx_name=self.get('ctx_name', None),
+                alt_stat_name=self.get('stat_prefix', None)
             )
         )
 
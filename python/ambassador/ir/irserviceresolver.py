@@ -1,4 +1,4 @@
-from typing import Dict, List, Optional, Union, TYPE_CHECKING
+from typing import Dict, List, Optional, Set, Union, TYPE_CHECKING
 
 import json
 import logging
@@ -55,9 +55,52 @@ class IRServiceResolver(IRResource):
         if self.kind == 'ConsulResolver':
             self.resolve_with = 'consul'
 
-            if not self.get('datacenter'):
+            datacenters = self.get('datacenters')
+
+            if datacenters:
+                # Resolving from several datacenters: each needs a name, and Envoy weights
+                # the datacenters as localities.
+                for dc in datacenters:
+                    if not isinstance(dc, dict) or not dc.get('name'):
+                        self.post_error("ConsulResolver datacenters must each have a name")
+                        return False
+
+                    weight = dc.get('weight', 1)
+
+                    if not isinstance(weight, int) or (weight < 1):
+                        self.post_error(f"ConsulResolver datacenter {dc['name']} weight must be a positive integer")
+                        return False
+            elif not self.get('datacenter'):
                 self.post_error("ConsulResolver is required to have a datacenter")
                 return False
+
+            if self.get('connect'):
+                self.setup_connect(ir, aconf)
+        elif self.kind == 'DNSResolver':
+            self.resolve_with = 'dns'
+
+            for key in [ 'min_refresh_ms', 'max_refresh_ms' ]:
+                value = self.get(key)
+
+                if (value is not None) and (not isinstance(value, int) or (value < 1)):
+                    self.post_error(f"DNSResolver {key} must be a positive integer")
+                    return False
+
+            jitter = self.get('jitter_percent')
+
+            if (jitter is not None) and (not isinstance(jitter, int) or (jitter < 0) or (jitter > 100)):
+                self.post_error("DNSResolver jitter_percent must be an integer from 0 to 100")
+                return False
+        elif self.kind == 'MultiClusterResolver':
+            self.resolve_with = 'multicluster'
+
+            if not self.setup_multicluster():
+                return False
+        elif self.kind == 'StaticResolver':
+            self.resolve_with = 'static'
+
+            if not self.setup_static():
+                return False
         elif self.kind == 'KubernetesServiceResolver':
             self.resolve_with = 'k8s'
         elif self.kind == 'KubernetesEndpointResolver':
@@ -68,6 +111,112 @@ class IRServiceResolver(IRResource):
 
         return True
 
+    def setup_connect(self, ir: 'IR', aconf: Config) -> None:
+        # The watcher fetches our Consul Connect leaf certificate and CA roots into a Secret
+        # named after us. Synthesize a TLSContext for it: Mappings that use us originate mTLS
+        # with it unless they name a context of their own.
+        #
+        # XXX What if they already have a context with this name?
+        self.connect_context = f'{self.name}-consul-connect'
+        secret_name = f'{self.name}-consul-connect'
+
+        if not ir.secret_info.get(f'{secret_name}.{self.namespace}'):
+            # The certificate isn't here yet. Mappings still use the context, so that they
+            # fail closed instead of sending cleartext to a Connect-enabled service.
+            aconf.post_notice(f"ConsulResolver {self.name}: waiting for the Consul Connect certificate",
+                              resource=self)
+            return
+
+        ctx = IRTLSContext(ir, aconf, rkey=self.rkey, location=self.location,
+                           name=self.connect_context, namespace=self.namespace,
+                           secret=f'{secret_name}.{self.namespace}')
+
+        if ctx.is_active() and ctx.resolve():
+            ctx.referenced_by(self)
+            ir.save_tls_context(ctx)
+        else:
+            self.post_error(f"ConsulResolver {self.name}: could not use the Consul Connect certificate")
+
+    def setup_multicluster(self) -> bool:
+        # Each cluster becomes a locality, so it needs a name of its own, and the watcher
+        # can only reach clusters other than ours through the kubeconfig.
+        clusters = self.get('clusters')
+
+        if not clusters or not isinstance(clusters, list):
+            self.post_error("MultiClusterResolver must have clusters")
+            return False
+
+        names: Set[str] = set()
+
+        for mc in clusters:
+            if not isinstance(mc, dict) or not mc.get('name'):
+                self.post_error("MultiClusterResolver clusters must each have a name")
+                return False
+
+            name = mc['name']
+
+            if name in names:
+                self.post_error(f"MultiClusterResolver cluster {name} is listed more than once")
+                return False
+
+            names.add(name)
+
+            if not mc.get('local') and not self.get('kubeconfig'):
+                self.post_error(f"MultiClusterResolver cluster {name} needs a kubeconfig unless it is local")
+                return False
+
+            weight = mc.get('weight', 1)
+
+            if not isinstance(weight, int) or (weight < 1):
+                self.post_error(f"MultiClusterResolver cluster {name} weight must be a positive integer")
+                return False
+
+            priority = mc.get('priority', 0)
+
+            if not isinstance(priority, int) or (priority < 0):
+                self.post_error(f"MultiClusterResolver cluster {name} priority must be a non-negative integer")
+                return False
+
+        return True
+
+    def setup_static(self) -> bool:
+        # Check the endpoints now, so that resolving can trust them. Service names are
+        # matched like hostnames, without regard to case.
+        self.static_services: Dict[str, List[dict]] = {}
+
+        for svc in self.get('services') or []:
+            if not isinstance(svc, dict) or not svc.get('name'):
+                self.post_error("StaticResolver services must each have a name")
+                return False
+
+            name = svc['name']
+            endpoints = svc.get('endpoints')
+
+            if not endpoints or not isinstance(endpoints, list):
+                self.post_error(f"StaticResolver service {name} must have endpoints")
+                return False
+
+            for ep in endpoints:
+                if not isinstance(ep, dict) or not ep.get('address'):
+                    self.post_error(f"StaticResolver service {name}: endpoints must each have an address")
+                    return False
+
+                port = ep.get('port')
+
+                if (port is not None) and (not isinstance(port, int) or (port < 1) or (port > 65535)):
+                    self.post_error(f"StaticResolver service {name}: endpoint {ep['address']} port must be from 1 to 65535")
+                    return False
+
+                weight = ep.get('weight', 1)
+
+                if not isinstance(weight, int) or (weight < 1):
+                    self.post_error(f"StaticResolver service {name}: endpoint {ep['address']} weight must be a positive integer")
+                    return False
+
+            self.static_services[name.lower()] = endpoints
+
+        return True
+
     @multi
     def valid_mapping(self, ir: 'IR', mapping: 'IRBaseMapping') -> str:
         del ir
@@ -107,6 +256,22 @@ class IRServiceResolver(IRResource):
 
         return valid
 
+    @valid_mapping.when("DNSResolver")
+    def _dns_valid_mapping(self, ir: 'IR', mapping: 'IRBaseMapping'):
+        # Any hostname can be looked up. SRV records bring their own ports, so the port of
+        # the Mapping only matters for services with A and AAAA records.
+        return True
+
+    @valid_mapping.when("MultiClusterResolver")
+    def _multicluster_valid_mapping(self, ir: 'IR', mapping: 'IRBaseMapping'):
+        # Services are named just like for the KubernetesEndpointResolver.
+        return True
+
+    @valid_mapping.when("StaticResolver")
+    def _static_valid_mapping(self, ir: 'IR', mapping: 'IRBaseMapping'):
+        # The endpoints were checked when we were set up.
+        return True
+
     @multi
     def resolve(self, ir: 'IR', cluster: 'IRCluster', svc_name: str, svc_namespace: str, port: int) -> str:
         del ir      # silence warnings
@@ -146,7 +311,21 @@ class IRServiceResolver(IRResource):
             ir.logger.debug("KubernetesEndpointResolver use_ambassador_namespace_for_service_resolution %s, upstream key %s" % (ir.ambassador_module.use_ambassador_namespace_for_service_resolution, f'{svc}-{namespace}'))
 
         # Find endpoints, and try for a port match!
-        return self.get_endpoints(ir, f'k8s-{svc}-{namespace}', port)
+        return self.zone_priorities(self.get_endpoints(ir, f'k8s-{svc}-{namespace}', port))
+
+    def zone_priorities(self, targets: Optional[SvcEndpointSet]) -> Optional[SvcEndpointSet]:
+        # When we know our zone and the zones of the endpoints, endpoints in our zone get
+        # priority 0 and the others priority 1, so that traffic only leaves our zone when it
+        # runs short of healthy endpoints. If our zone has no endpoints, all get priority 0.
+        zone = Config.ambassador_zone
+
+        if not zone or not targets or not any(target.get('zone') for target in targets):
+            return targets
+
+        local = any(target.get('zone') == zone for target in targets)
+
+        return [ dict(target, priority=0 if (not local or target.get('zone') == zone) else 1)
+                 for target in targets ]
 
     @resolve.when("ConsulResolver")
     def _consul_resolver(self, ir: 'IR', cluster: 'IRCluster', svc_name: str, svc_namespace: str, port: int) -> Optional[SvcEndpointSet]:
@@ -154,7 +333,94 @@ class IRServiceResolver(IRResource):
         # We ignore the port in the lookup (we should've already posted a warning about the port
         # being present, actually).
 
-        return self.get_endpoints(ir, f'consul-{svc_name}-{self.datacenter}', None)
+        datacenters = self.get('datacenters')
+
+        if not datacenters:
+            return self.get_endpoints(ir, f'consul-{svc_name}-{self.datacenter}', None)
+
+        # With several datacenters, each target remembers its datacenter and weight, so
+        # that the cluster can group them into weighted localities. A datacenter where the
+        # service isn't registered just doesn't get any traffic.
+        targets: SvcEndpointSet = []
+
+        for dc in datacenters:
+            dc_targets = self.get_endpoints(ir, f'consul-{svc_name}-{dc["name"]}', None)
+
+            if dc_targets:
+                targets.extend([ dict(target, locality=dc['name'], locality_weight=dc.get('weight', 1))
+                                 for target in dc_targets ])
+
+        return targets or None
+
+    @resolve.when("DNSResolver")
+    def _dns_resolver(self, ir: 'IR', cluster: 'IRCluster', svc_name: str, svc_namespace: str, port: int) -> Optional[SvcEndpointSet]:
+        # The watcher looks the service up by hostname. SRV records come with their own ports,
+        # but A and AAAA records don't, so those get the port of the Mapping.
+        targets = self.get_endpoints(ir, f'dns-{self.name}-{svc_name}', None)
+
+        if not targets:
+            return None
+
+        return [ dict(target, port=target['port'] or port) for target in targets ]
+
+    @resolve.when("MultiClusterResolver")
+    def _multicluster_resolver(self, ir: 'IR', cluster: 'IRCluster', svc_name: str, svc_namespace: str, port: int) -> Optional[SvcEndpointSet]:
+        # The watcher watches 'svc.namespace', or 'svc' in the Mapping's namespace, in each of
+        # our clusters. Each cluster's targets remember its name, weight, and priority, so that
+        # the cluster can group them into localities. A cluster without the service just
+        # doesn't get any traffic.
+        svc = svc_name.lower()
+        namespace = svc_namespace or Config.ambassador_namespace
+
+        if '.' in svc:
+            (svc, namespace) = svc.split(".", 2)[0:2]
+
+        targets: SvcEndpointSet = []
+
+        for mc in self.get('clusters'):
+            key = f'mc-{self.name}-{mc["name"]}-{svc}-{namespace}'
+
+            if key not in ir.services:
+                self.logger.debug(f'Resolver {self.name}: cluster {mc["name"]} has no endpoints for {svc}.{namespace}')
+                continue
+
+            mc_targets = self.get_endpoints(ir, key, port)
+
+            if mc_targets:
+                targets.extend([ dict(target, locality=mc['name'], locality_weight=mc.get('weight', 1),
+                                      priority=mc.get('priority', 0))
+                                 for target in mc_targets ])
+
+        return targets or None
+
+    @resolve.when("StaticResolver")
+    def _static_resolver(self, ir: 'IR', cluster: 'IRCluster', svc_name: str, svc_namespace: str, port: int) -> Optional[SvcEndpointSet]:
+        endpoints = self.static_services.get(svc_name)
+
+        if not endpoints:
+            self.logger.debug(f'Resolver {self.name}: {svc_name} matches no StaticResolver service')
+            return None
+
+        targets: SvcEndpointSet = [ {
+            'ip': ep['address'],
+            'port': ep.get('port') or port,
+            'weight': ep.get('weight', 1),
+            'target_kind': 'Static'
+        } for ep in endpoints ]
+
+        # With localities, each locality gets the sum of the weights of its endpoints, so
+        # that Envoy still splits the traffic by endpoint weight when all are healthy.
+        if any(ep.get('locality') for ep in endpoints):
+            locality_weights: Dict[str, int] = {}
+
+            for ep, target in zip(endpoints, targets):
+                target['locality'] = ep.get('locality') or ''
+                locality_weights[target['locality']] = locality_weights.get(target['locality'], 0) + target['weight']
+
+            for target in targets:
+                target['locality_weight'] = locality_weights[target['locality']]
+
+        return targets
 
     def get_endpoints(self, ir: 'IR', key: str, port: Optional[int]) -> Optional[SvcEndpointSet]:
         # OK. Do we have a Service by this key?
@@ -0,0 +1,138 @@
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
+
+import socket
+
+from ..config import Config
+
+from .irresource import IRResource
+from .ircluster import IRCluster
+
+if TYPE_CHECKING:
+    from .ir import IR
+
+
+class IRStatsSink(IRResource):
+    cluster: Optional[IRCluster]
+    service: str
+    driver: str
+    address: Optional[Dict[str, Any]]
+    prefix: Optional[str]
+    flush_interval: Optional[int]
+    stats_matcher: Optional[Dict[str, List[str]]]
+    stats_tags: List[Dict[str, str]]
+
+    def __init__(self, ir: 'IR', config,
+                 rkey: str = "ir.statssink",
+                 kind: str = "ir.statssink",
+                 name: str = "statssink",
+                 namespace: Optional[str] = None,
+                 **kwargs) -> None:
+        del kwargs  # silence unused-variable warning
+
+        super().__init__(
+            ir=ir, aconf=config, rkey=rkey, kind=kind, name=name, namespace=namespace
+        )
+
+    def setup(self, ir: 'IR', config) -> bool:
+        self.namespace = config.get("namespace", self.namespace)
+        self.cluster = None
+        self.address = None
+        self.driver = config.get('driver')
+
+        if self.driver == 'opentelemetry':
+            # Envoy's OpenTelemetry stats sink is newer than the Envoy we ship.
+            self.post_error("driver opentelemetry is not supported by this version of Envoy")
+            return False
+
+        self.service = config.get('service')
+        if not self.service:
+            self.post_error("service must be present for a stats sink!")
+            return False
+
+        if self.driver in [ 'statsd', 'dog_statsd' ]:
+            # The statsd sinks need an IP address, not a cluster. As with STATSD_HOST,
+            # resolve it now.
+            host, _, port = self.service.partition(':')
+
+            try:
+                ip = socket.gethostbyname(host)
+            except socket.gaierror as e:
+                self.post_error("unable to resolve %s: %s" % (host, e))
+                return False
+
+            self.address = {
+                'socket_address': {
+                    'protocol': 'UDP',
+                    'address': ip,
+                    'port_value': int(port) if port else 8125
+                }
+            }
+
+        self.prefix = config.get('prefix', None)
+        self.flush_interval = config.get('flush_interval', None)
+
+        self.stats_matcher = config.get('stats_matcher', None)
+        if self.stats_matcher and self.stats_matcher.get('inclusion_regex') and self.stats_matcher.get('exclusion_regex'):
+            self.post_error("stats_matcher cannot have both inclusion_regex and exclusion_regex")
+            return False
+
+        self.stats_tags = config.get('stats_tags', [])
+        for tag in self.stats_tags:
+            if bool(tag.get('regex')) == bool(tag.get('fixed_value')):
+                self.post_error("stats_tags %s: must have exactly one of regex or fixed_value" % tag.get('tag_name'))
+                return False
+
+        self.sourced_by(config)
+        self.referenced_by(config)
+
+        return True
+
+    def add_mappings(self, ir: 'IR', aconf: Config):
+        if self.driver != 'metrics_service':
+            return
+
+        self.cluster = ir.add_cluster(
+            IRCluster(
+                ir=ir,
+                aconf=aconf,
+                parent_ir_resource=self,
+                location=self.location,
+                service=self.service,
+                marker='stats',
+                grpc=True
+            )
+        )
+
+        self.cluster.referenced_by(self)
+
+
+class IRStatsSinkFactory:
+    @classmethod
+    def load_all(cls, ir: 'IR', aconf: Config) -> None:
+        sinks = aconf.get_config('stats_sinks')
+        if sinks is not None:
+            for config in sinks.values():
+                sink = IRStatsSink(ir, config)
+                extant_sink = ir.stats_sinks.get(sink.name, None)
+
+                if extant_sink:
+                    ir.post_error("Duplicate StatsSink %s; keeping definition from %s" % (sink.name, extant_sink.location))
+                elif sink.is_active():
+                    ir.stats_sinks[sink.name] = sink
+                    ir.save_resource(sink)
+
+        # The flush interval and the stats matcher are global to Envoy, so every
+        # StatsSink that sets them has to agree.
+        flush_intervals = { sink.flush_interval for sink in ir.stats_sinks.values() if sink.flush_interval }
+
+        if len(flush_intervals) > 1:
+            ir.post_error("StatsSinks disagree about flush_interval (%s); using the smallest" %
+                          ", ".join([ str(x) for x in sorted(flush_intervals) ]))
+
+        matchers = [ sink.stats_matcher for sink in ir.stats_sinks.values() if sink.stats_matcher ]
+
+        if any(m.get('inclusion_regex') for m in matchers) and any(m.get('exclusion_regex') for m in matchers):
+            ir.post_error("StatsSinks cannot mix inclusion_regex and exclusion_regex; ignoring every stats_matcher")
+
+            for sink in ir.stats_sinks.values():
+                sink.stats_matcher = None
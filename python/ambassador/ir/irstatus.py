@@ -0,0 +1,171 @@
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
+
+from ..resource import Resource
+
+if TYPE_CHECKING:
+    from .ir import IR
+
+
+# Status conditions for getambassador.io resources.
+#
+# Every kind that Ambassador writes the status of reports the same three conditions, each with
+# the generation of the resource that it describes:
+#
+# - Accepted: the resource is valid, and Ambassador accepted it into its configuration.
+# - Programmed: Envoy's configuration has the resource.
+# - Ready: the resource is Accepted and Programmed, so traffic can flow through it.
+#
+# The IR computes the conditions without a lastTransitionTime. stamp_conditions adds it when the
+# status is written, keeping the old time for any condition that hasn't changed, so that a status
+# that hasn't changed is never written again.
+
+ACCEPTED = 'Accepted'
+PROGRAMMED = 'Programmed'
+READY = 'Ready'
+
+# A Host whose tlsSecret is written by a cert-manager Certificate also reports CertificateReady.
+CERTIFICATE_READY = 'CertificateReady'
+
+# Another controller owns the rest of the status of these kinds, so we only merge our conditions
+# into their status instead of replacing it.
+SHARED_STATUS_KINDS = { 'Host' }
+
+
+def condition(ctype: str, status: bool, reason: str, message: str='',
+              generation: Optional[int]=None) -> Dict[str, Any]:
+    cond: Dict[str, Any] = {
+        'type': ctype,
+        'status': 'True' if status else 'False',
+        'reason': reason
+    }
+
+    if message:
+        cond['message'] = message
+
+    if generation is not None:
+        cond['observedGeneration'] = generation
+
+    return cond
+
+
+def summarize_errors(ir: 'IR', rkey: str) -> str:
+    errors = ir.aconf.errors.get(rkey, [])
+
+    if not errors:
+        return ''
+
+    errstr = errors[0].get('error') or 'unknown error?'
+
+    if len(errors) > 1:
+        errstr += " (and more)"
+
+    return errstr
+
+
+def resource_conditions(ir: 'IR', source: Resource, accepted: bool, programmed: bool=True,
+                        not_programmed_reason: str='NotProgrammed', message: str='') -> List[Dict[str, Any]]:
+    """
+    Return the conditions of a resource.
+
+    :param ir: the IR
+    :param source: the resource as Ambassador read it, for its rkey and generation
+    :param accepted: whether the resource was accepted
+    :param programmed: whether Envoy's configuration has the resource, if it was accepted
+    :param not_programmed_reason: the reason when it wasn't programmed
+    :param message: why it wasn't programmed
+    """
+
+    generation = source.get('generation')
+
+    if not accepted:
+        errors = summarize_errors(ir, source.rkey) or 'not accepted'
+
+        return [
+            condition(ACCEPTED, False, 'Invalid', errors, generation),
+            condition(PROGRAMMED, False, 'NotAccepted', generation=generation),
+            condition(READY, False, 'NotAccepted', generation=generation)
+        ]
+
+    if not programmed:
+        return [
+            condition(ACCEPTED, True, 'Accepted', generation=generation),
+            condition(PROGRAMMED, False, not_programmed_reason, message, generation),
+            condition(READY, False, not_programmed_reason, message, generation)
+        ]
+
+    return [
+        condition(ACCEPTED, True, 'Accepted', generation=generation),
+        condition(PROGRAMMED, True, 'Programmed', generation=generation),
+        condition(READY, True, 'Ready', generation=generation)
+    ]
+
+
+def certificate_condition(cert: Dict[str, Any], generation: Optional[int]=None) -> Dict[str, Any]:
+    """
+    Return the CertificateReady condition for the state of a cert-manager Certificate, as the
+    CertificateProcessor saved it.
+    """
+
+    name = cert['name']
+
+    if not cert['ready']:
+        message = cert['message'] or f"Certificate {name} has not been issued yet"
+        return condition(CERTIFICATE_READY, False, cert['reason'] or 'Pending', message, generation)
+
+    expiry = f"Certificate {name} is valid until {cert['not_after'] or 'an unknown time'}"
+
+    if cert['issuing']:
+        return condition(CERTIFICATE_READY, True, 'Renewing', f"{expiry}; it is being renewed", generation)
+
+    if (cert['issuing'] is False) and (cert['issuing_reason'] == 'Failed'):
+        message = f"{expiry}; renewing it failed: {cert['issuing_message'] or 'unknown error'}"
+        return condition(CERTIFICATE_READY, True, 'RenewalFailed', message, generation)
+
+    if cert['renewal_time']:
+        expiry += f" and will be renewed at {cert['renewal_time']}"
+
+    return condition(CERTIFICATE_READY, True, 'Ready', expiry, generation)
+
+
+def post_conditions(ir: 'IR', kind: str, source: Resource, conditions: List[Dict[str, Any]]) -> None:
+    """
+    Queue a status update with the conditions of a resource, if it came from a CRD. Resources
+    from annotations or files have no status to write.
+    """
+
+    crd_name = (source.get('metadata_labels') or {}).get('ambassador_crd')
+
+    if not crd_name:
+        return
+
+    ir.k8s_status_updates[crd_name] = (kind, source.get('namespace') or ir.ambassador_namespace,
+                                       { 'conditions': conditions })
+
+
+def stamp_conditions(status: Dict[str, Any], previous: Optional[Dict[str, Any]], now: str) -> Dict[str, Any]:
+    """
+    Return status with a lastTransitionTime on each of its conditions: the one from the
+    previous status if the condition has the same status there, or now.
+    """
+
+    conditions = status.get('conditions')
+
+    if not conditions:
+        return status
+
+    old: Dict[str, Dict[str, Any]] = {}
+
+    for cond in (previous or {}).get('conditions') or []:
+        old[cond.get('type')] = cond
+
+    stamped = []
+
+    for cond in conditions:
+        was = old.get(cond['type'])
+
+        if was and (was.get('status') == cond['status']) and was.get('lastTransitionTime'):
+            stamped.append(dict(cond, lastTransitionTime=was['lastTransitionTime']))
+        else:
+            stamped.append(dict(cond, lastTransitionTime=now))
+
+    return dict(status, conditions=stamped)
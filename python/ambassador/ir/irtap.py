@@ -0,0 +1,175 @@
+from typing import Any, Dict, List, Optional, TYPE_CHECKING
+
+from ..config import Config
+
+from .irfilter import IRFilter
+from .irbasemappinggroup import IRBaseMappingGroup
+
+if TYPE_CHECKING:
+    from .ir import IR
+
+
+class IRTapPolicy (IRFilter):
+    """
+    A TapPolicy becomes an Envoy tap filter of its own, which taps the requests to the
+    TapPolicy's Mappings that match its match predicates. The tap filter runs for every
+    request, so the Mappings' own route matches are part of the tap's match predicate.
+
+    A TapPolicy for TCPMappings instead wraps the transport socket of each TCPMapping's
+    filter chain in a tap transport socket, which records the raw bytes of every
+    connection. There are no requests to match, so it taps everything.
+
+    A TapPolicy with a sample gets a tap filter that's driven through Envoy's admin
+    interface instead, with the tap's ID as its config_id. The entrypoint's tap sampler
+    holds an admin tap open on it, and keeps the sample.
+
+    A TapPolicy's duration is enforced by the watcher, which simply stops handing us
+    the TapPolicy once it expires.
+    """
+
+    mapping_names: List[str]
+    groups: List[IRBaseMappingGroup]
+    tcp_mapping_names: List[str]
+    tcp_groups: List[IRBaseMappingGroup]
+    match_path_prefix: Optional[str]
+    request_headers: List[Dict[str, Any]]
+    response_headers: List[Dict[str, Any]]
+    max_buffered_bytes: Optional[int]
+    tap_id: str
+    output_sink: str
+    output_format: str
+    output_path_prefix: str
+    sample: bool
+
+    def __init__(self, ir: 'IR', config,
+                 kind: str = "IRTapPolicy",
+                 **kwargs) -> None:
+        del kwargs  # silence unused-variable warning
+
+        super().__init__(
+            ir=ir, aconf=config, rkey="ir.tappolicy.%s" % config.rkey, kind=kind,
+            name=config.name, namespace=config.get('namespace', None), location=config.location
+        )
+
+    def setup(self, ir: 'IR', config) -> bool:
+        self.groups = []
+        self.tcp_groups = []
+        self.add_dict_helper('groups', IRTapPolicy.helper_groups)
+        self.add_dict_helper('tcp_groups', IRTapPolicy.helper_groups)
+
+        self.mapping_names = config.get('mappings', [])
+        self.tcp_mapping_names = config.get('tcp_mappings', [])
+
+        if self.mapping_names and self.tcp_mapping_names:
+            self.post_error("TapPolicy %s: can tap Mappings or TCPMappings, but not both" % self.name)
+            return False
+
+        if not (self.mapping_names or self.tcp_mapping_names):
+            self.post_error("TapPolicy %s: mappings or tcp_mappings must name at least one Mapping" % self.name)
+            return False
+
+        match = config.get('match', None) or {}
+
+        if match and self.tcp_mapping_names:
+            self.post_error("TapPolicy %s: match only applies to Mappings, not TCPMappings" % self.name)
+            return False
+
+        self.match_path_prefix = match.get('path_prefix', None)
+        self.request_headers = match.get('request_headers', [])
+        self.response_headers = match.get('response_headers', [])
+
+        for header in self.request_headers + self.response_headers:
+            if len([ k for k in [ 'exact', 'prefix', 'regex' ] if k in header ]) > 1:
+                self.post_error("TapPolicy %s: header %s can have only one of exact, prefix, and regex" %
+                                (self.name, header.get('name')))
+                return False
+
+        self.max_buffered_bytes = config.get('max_buffered_bytes', None)
+
+        output = config.get('output', None) or {}
+
+        # The tap's ID, for TapDS and for streamed traces.
+        self.tap_id = f"{self.name}.{self.namespace}"
+
+        self.output_sink = output.get('sink', 'file')
+
+        if self.output_sink == 'grpc':
+            # Envoy's streaming_grpc tap sink is newer than the Envoy we ship.
+            self.post_error("TapPolicy %s: sink grpc is not supported by this version of Envoy" % self.name)
+            return False
+
+        self.output_format = output.get('format', 'json_body_as_string')
+        self.output_path_prefix = output.get('path_prefix', f"/tmp/ambassador-tap/{self.tap_id}")
+
+        self.sample = bool(config.get('sample', None))
+
+        if self.sample and self.tcp_mapping_names:
+            self.post_error("TapPolicy %s: only Mappings can be sampled, not TCPMappings" % self.name)
+            return False
+
+        if self.sample and not self.output_format.startswith('json_'):
+            # Envoy's admin interface only streams JSON.
+            self.post_error("TapPolicy %s: a sample must use a JSON format" % self.name)
+            return False
+
+        self.sourced_by(config)
+        self.referenced_by(config)
+
+        return True
+
+    @staticmethod
+    def helper_groups(res: 'IRTapPolicy', k: str):
+        return k, [ group.group_id for group in res[k] ]
+
+    def resolve_groups(self, ir: 'IR') -> None:
+        """
+        Find the groups that hold this TapPolicy's Mappings or TCPMappings. This has to
+        wait until all the Mappings are grouped.
+        """
+
+        found = set()
+
+        for group in ir.ordered_groups():
+            if group.get('host_redirect'):
+                continue
+
+            if group.get('kind') == 'IRHTTPMappingGroup':
+                names, groups = self.mapping_names, self.groups
+            elif group.get('kind') == 'IRTCPMappingGroup':
+                names, groups = self.tcp_mapping_names, self.tcp_groups
+            else:
+                continue
+
+            for mapping in group.get('mappings', []):
+                if (mapping.name in names) and (mapping.namespace == self.namespace):
+                    found.add(mapping.name)
+
+                    if not any(g is group for g in groups):
+                        groups.append(group)
+
+        for name in self.mapping_names:
+            if name not in found:
+                self.post_error("TapPolicy %s: no Mapping %s in namespace %s" % (self.name, name, self.namespace))
+
+        for name in self.tcp_mapping_names:
+            if name not in found:
+                self.post_error("TapPolicy %s: no TCPMapping %s in namespace %s" % (self.name, name, self.namespace))
+
+    def taps_tcp_group(self, group: IRBaseMappingGroup) -> bool:
+        return any(g is group for g in self.tcp_groups)
+
+
+class IRTapPolicyFactory:
+    @classmethod
+    def load_all(cls, ir: 'IR', aconf: Config) -> None:
+        policies = aconf.get_config('tap_policies')
+
+        if policies is not None:
+            for config in policies.values():
+                ir.save_filter(IRTapPolicy(ir, config))
+
+    @classmethod
+    def finalize(cls, ir: 'IR', aconf: Config) -> None:
+        for irfilter in ir.filters:
+            if isinstance(irfilter, IRTapPolicy):
+                irfilter.resolve_groups(ir)
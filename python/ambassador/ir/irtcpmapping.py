@@ -23,6 +23,7 @@ class IRTCPMapping (IRBaseMapping):
     AllowedKeys: ClassVar[Dict[str, bool]] = {
         "address": True,
         "circuit_breakers": False,
+        "connection_rate_limit": True,
         "enable_ipv4": True,
         "enable_ipv6": True,
         "host": True,
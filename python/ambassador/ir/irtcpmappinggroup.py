@@ -21,6 +21,8 @@ class IRTCPMappingGroup (IRBaseMappingGroup):
     CoreMappingKeys: ClassVar[Dict[str, bool]] = {
         'address': True,
         'circuit_breakers': True,
+        # The connection rate limit is per-listener, so every Mapping in the group must agree.
+        'connection_rate_limit': True,
         'enable_ipv4': True,
         'enable_ipv6': True,
         'group_id': True,
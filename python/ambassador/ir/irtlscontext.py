@@ -7,6 +7,7 @@ import os
 from ..utils import SavedSecret
 from ..config import Config
 from .irresource import IRResource
+from .irstatus import post_conditions, resource_conditions
 
 if TYPE_CHECKING:
     from .ir import IR
@@ -25,6 +26,7 @@ class IRTLSContext(IRResource):
 
     AllowedKeys: ClassVar = {
         '_ambassador_enabled',
+        '_istio_sds',
         '_legacy',
         "alpn_protocols",
         "cert_required",
@@ -168,6 +170,10 @@ class IRTLSContext(IRResource):
             self.ir.logger.debug("IRTLSContext skipping resolution of null context")
             return True
 
+        if self.get('_istio_sds', None):
+            self.ir.logger.debug("IRTLSContext skipping resolution of Istio SDS context")
+            return True
+
         # is_valid determines if the TLS context is valid
         is_valid = False
 
@@ -409,6 +415,7 @@ class TLSContextFactory:
         if tls_contexts is not None:
             for config in tls_contexts.values():
                 ctx = IRTLSContext(ir, aconf, **config)
+                post_conditions(ir, 'TLSContext', config, resource_conditions(ir, config, ctx.is_active()))
 
                 if ctx.is_active():
                     ctx.referenced_by(config)
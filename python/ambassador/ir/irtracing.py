@@ -15,6 +15,7 @@ class IRTracing(IRResource):
     driver: str
     driver_config: dict
     tag_headers: list
+    custom_tags: list
     host_rewrite: Optional[str]
     sampling: dict
 
@@ -71,6 +72,22 @@ class IRTracing(IRResource):
             driver = "envoy.tracers.datadog"
 
         driver_config = config.get("config", {})
+
+        if driver == "opentelemetry":
+            # Our Envoy doesn't have an OpenTelemetry tracer, but its OpenCensus tracer can
+            # export to the OpenCensus receiver of an OpenTelemetry Collector, so that's what
+            # we use. None of the config fields mean anything to it.
+            if driver_config:
+                self.post_error(RichStatus.fromError("config is not supported by the opentelemetry driver"))
+                return False
+
+            grpc = True
+            driver = "envoy.tracers.opencensus"
+            driver_config = {
+                'ocagent_exporter_enabled': True,
+                'incoming_trace_context': [ 'TRACE_CONTEXT', 'B3' ],
+                'outgoing_trace_context': [ 'TRACE_CONTEXT' ]
+            }
         if driver_config:
             if 'collector_endpoint_version' in driver_config:
                 if not driver_config['collector_endpoint_version'] in ['HTTP_JSON_V1', 'HTTP_JSON', 'HTTP_PROTO']:
@@ -86,6 +103,7 @@ class IRTracing(IRResource):
         self.cluster = None
         self.driver_config = driver_config
         self.tag_headers = config.get('tag_headers', [])
+        self.custom_tags = config.get('custom_tags', [])
         self.sampling = config.get('sampling', {})
 
         # XXX host_rewrite actually isn't in the schema right now.
@@ -115,4 +133,12 @@ class IRTracing(IRResource):
 
     def finalize(self):
         self.ir.logger.debug("tracing cluster envoy name: %s" % self.cluster.envoy_name)
-        self.driver_config['collector_cluster'] = self.cluster.envoy_name
+
+        if self.driver == "envoy.tracers.opencensus":
+            self.driver_config['ocagent_grpc_service'] = {
+                'envoy_grpc': {
+                    'cluster_name': self.cluster.envoy_name
+                }
+            }
+        else:
+            self.driver_config['collector_cluster'] = self.cluster.envoy_name
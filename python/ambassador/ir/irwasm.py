@@ -0,0 +1,143 @@
+from typing import List, Optional, TYPE_CHECKING
+
+import os
+
+from ..config import Config
+
+from .irfilter import IRFilter
+from .irbasemappinggroup import IRBaseMappingGroup
+
+if TYPE_CHECKING:
+    from .ir import IR
+
+
+class IRWasmFilter (IRFilter):
+    """
+    A WasmFilter becomes an Envoy Wasm HTTP filter of its own, which runs the WasmFilter's
+    module. We never touch the module ourselves: the entrypoint fetches and verifies it,
+    writes it to $AMBASSADOR_CONFIG_BASE_DIR/wasm/<sha256>.wasm, and only hands us the
+    WasmFilter once it's there, with module.sha256 set to its checksum.
+
+    Envoy can't turn a Wasm filter off per route, so a WasmFilter with Mappings still runs
+    for every request. The routes of its Mappings carry its filter ID in their metadata,
+    under getambassador.io/wasm, and the module checks its route_metadata for it.
+    """
+
+    filter_id: str
+    sha256: str
+    module_path: str
+    runtime: str
+    vm_id: str
+    allow_precompiled: bool
+    vm_configuration: Optional[str]
+    root_id: Optional[str]
+    configuration: Optional[str]
+    mapping_names: List[str]
+    groups: List[IRBaseMappingGroup]
+
+    def __init__(self, ir: 'IR', config,
+                 kind: str = "IRWasmFilter",
+                 **kwargs) -> None:
+        del kwargs  # silence unused-variable warning
+
+        super().__init__(
+            ir=ir, aconf=config, rkey="ir.wasmfilter.%s" % config.rkey, kind=kind,
+            name=config.name, namespace=config.get('namespace', None), location=config.location
+        )
+
+    def setup(self, ir: 'IR', config) -> bool:
+        self.groups = []
+        self.add_dict_helper('groups', IRWasmFilter.helper_groups)
+
+        # The filter's ID, for the routes of its Mappings and for Envoy's stats.
+        self.filter_id = f"{self.name}.{self.namespace}"
+
+        if not os.environ.get('AMBASSADOR_ENVOY_WASM', None):
+            # The Envoy we ship doesn't have the Wasm filter. AMBASSADOR_ENVOY_WASM says
+            # that the Envoy we're running with does.
+            self.post_error("WasmFilter %s: Wasm filters are not supported by this version of Envoy" % self.name)
+            return False
+
+        module = config.get('module', None) or {}
+
+        if bool(module.get('image', None)) == bool(module.get('config_map', None)):
+            self.post_error("WasmFilter %s: module must have exactly one of image and config_map" % self.name)
+            return False
+
+        self.sha256 = (module.get('sha256', None) or '').lower()
+
+        if not self.sha256:
+            self.post_error("WasmFilter %s: module has not been fetched" % self.name)
+            return False
+
+        base_dir = os.environ.get('AMBASSADOR_CONFIG_BASE_DIR', '/ambassador')
+        self.module_path = os.path.join(base_dir, 'wasm', f"{self.sha256}.wasm")
+
+        fc = getattr(ir, 'file_checker')
+        if not fc(self.module_path):
+            self.post_error("WasmFilter %s: module %s has not been fetched" % (self.name, self.sha256))
+            return False
+
+        vm_config = config.get('vm_config', None) or {}
+
+        self.runtime = vm_config.get('runtime', 'envoy.wasm.runtime.v8')
+        self.vm_id = vm_config.get('vm_id', self.filter_id)
+        self.allow_precompiled = bool(vm_config.get('allow_precompiled', False))
+        self.vm_configuration = vm_config.get('configuration', None)
+
+        self.root_id = config.get('root_id', None)
+        self.configuration = config.get('configuration', None)
+        self.mapping_names = config.get('mappings', [])
+
+        self.sourced_by(config)
+        self.referenced_by(config)
+
+        return True
+
+    @staticmethod
+    def helper_groups(res: 'IRWasmFilter', k: str):
+        return k, [ group.group_id for group in res[k] ]
+
+    def resolve_groups(self, ir: 'IR') -> None:
+        """
+        Find the groups that hold this WasmFilter's Mappings. This has to wait until all
+        the Mappings are grouped.
+        """
+
+        found = set()
+
+        for group in ir.ordered_groups():
+            if group.get('host_redirect') or (group.get('kind') != 'IRHTTPMappingGroup'):
+                continue
+
+            for mapping in group.get('mappings', []):
+                if (mapping.name in self.mapping_names) and (mapping.namespace == self.namespace):
+                    found.add(mapping.name)
+
+                    if not any(g is group for g in self.groups):
+                        self.groups.append(group)
+
+        for name in self.mapping_names:
+            if name not in found:
+                self.post_error("WasmFilter %s: no Mapping %s in namespace %s" % (self.name, name, self.namespace))
+
+    def applies_to_group(self, group: IRBaseMappingGroup) -> bool:
+        return any(g is group for g in self.groups)
+
+
+class IRWasmFilterFactory:
+    @classmethod
+    def load_all(cls, ir: 'IR', aconf: Config) -> None:
+        filters = aconf.get_config('wasm_filters')
+
+        if filters is not None:
+            # Each WasmFilter is a filter of its own, and the order of filters matters,
+            # so keep it stable.
+            for config in sorted(filters.values(), key=lambda c: (c.name, c.get('namespace', ''))):
+                ir.save_filter(IRWasmFilter(ir, config))
+
+    @classmethod
+    def finalize(cls, ir: 'IR', aconf: Config) -> None:
+        for irfilter in ir.filters:
+            if isinstance(irfilter, IRWasmFilter):
+                irfilter.resolve_groups(ir)
@@ -14,7 +14,7 @@
 # See the License for the specific language governing permissions and
 # limitations under the License
 
-from typing import Any, Dict, List, Optional, TextIO, TYPE_CHECKING
+from typing import Any, Dict, List, Optional, TextIO, Tuple, TYPE_CHECKING
 
 import binascii
 import hashlib
@@ -30,6 +30,7 @@ import tempfile
 import yaml
 
 from .VERSION import Version
+from contextlib import contextmanager
 from urllib.parse import urlparse
 from prometheus_client import Gauge
 
@@ -402,6 +403,106 @@ class Timer:
                     self.name, self.cycles, self.minimum, self.average, self.maximum
                )
 
+
+class TraceSpans:
+    """
+    TraceSpans collects OpenTelemetry spans for one reconfiguration, as
+    children of the span named by a W3C traceparent header (the Go side of
+    the control plane sends one with each reconfiguration), and exports them
+    to an OTLP/HTTP collector as JSON.
+
+    trace = TraceSpans(logger, endpoint, traceparent)
+
+    with trace.span("IR"):
+        something_to_be_traced()
+
+    trace.export()
+
+    If the endpoint or the traceparent is empty, no spans are recorded. How
+    long each span took is recorded either way, for server_timing.
+    """
+
+    def __init__(self, logger: logging.Logger, endpoint: Optional[str], traceparent: Optional[str]) -> None:
+        self.logger = logger
+        self.endpoint = endpoint
+        self.trace_id: Optional[str] = None
+        self.parent_id: Optional[str] = None
+        self.spans: List[Dict[str, Any]] = []
+        self.durations: List[Tuple[str, float]] = []
+
+        if endpoint and traceparent:
+            parts = traceparent.split('-')
+
+            if (len(parts) == 4) and (len(parts[1]) == 32) and (len(parts[2]) == 16):
+                self.trace_id = parts[1]
+                self.parent_id = parts[2]
+            else:
+                self.logger.debug("ignoring invalid traceparent %s" % traceparent)
+
+    def __bool__(self) -> bool:
+        return bool(self.trace_id)
+
+    @contextmanager
+    def span(self, name: str):
+        start = time.time_ns()
+
+        try:
+            yield
+        finally:
+            self.durations.append((name, (time.time_ns() - start) / 1e9))
+
+            if self:
+                self.spans.append({
+                    'traceId': self.trace_id,
+                    'spanId': binascii.hexlify(os.urandom(8)).decode('ascii'),
+                    'parentSpanId': self.parent_id,
+                    'name': name,
+                    'kind': 1,      # SPAN_KIND_INTERNAL
+                    'startTimeUnixNano': str(start),
+                    'endTimeUnixNano': str(time.time_ns()),
+                })
+
+    def server_timing(self) -> str:
+        """
+        Return how long each span took, as a Server-Timing header, like
+        "diagd.ir;dur=1234.567, diagd.econf;dur=89.012" (in milliseconds).
+        """
+
+        return ', '.join('%s;dur=%.3f' % (name, seconds * 1000) for name, seconds in self.durations)
+
+    def export(self) -> None:
+        """
+        Send the spans recorded so far to the collector, and forget them.
+        Errors are logged, never raised: tracing mustn't break reconfiguration.
+        """
+
+        if not self.spans:
+            return
+
+        body = {
+            'resourceSpans': [ {
+                'resource': {
+                    'attributes': [
+                        { 'key': 'service.name', 'value': { 'stringValue': 'ambassador-diagd' } }
+                    ]
+                },
+                'scopeSpans': [ {
+                    'scope': { 'name': 'ambassador.diagd' },
+                    'spans': self.spans
+                } ]
+            } ]
+        }
+
+        self.spans = []
+
+        try:
+            response = requests.post(self.endpoint, json=body, timeout=5)
+
+            if response.status_code // 100 != 2:
+                self.logger.debug("error sending trace to %s: %d" % (self.endpoint, response.status_code))
+        except Exception as e:
+            self.logger.debug("error sending trace to %s: %s" % (self.endpoint, e))
+
 class DelayTrigger (threading.Thread):
     def __init__(self, onfired, timeout=5, name=None):
         super().__init__()
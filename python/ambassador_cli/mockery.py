@@ -350,8 +350,9 @@ def main(k8s_yaml_paths: List[str], debug: bool, force_pod_labels: bool, update:
         source = [
             "Host", "service", "ingresses",
             "AuthService", "LogService", "Mapping", "Module", "RateLimitService",
-            "TCPMapping", "TLSContext", "TracingService",
-            "ConsulResolver", "KubernetesEndpointResolver", "KubernetesServiceResolver"
+            "StatsSink", "TapPolicy", "TCPMapping", "TLSContext", "TracingService", "WasmFilter",
+            "ConsulResolver", "DNSResolver", "KubernetesEndpointResolver", "KubernetesServiceResolver",
+            "MultiClusterResolver", "StaticResolver"
         ]
 
     if namespace:
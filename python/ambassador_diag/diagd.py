@@ -21,6 +21,9 @@ from typing import cast as typecast
 import datetime
 import difflib
 import functools
+import hashlib
+import hmac
+import http.client
 import json
 import logging
 import multiprocessing
@@ -28,6 +31,7 @@ import os
 import queue
 import re
 import signal
+import socket
 import sys
 import threading
 import time
@@ -37,7 +41,7 @@ import requests
 import jsonpatch
 
 from expiringdict import ExpiringDict
-from prometheus_client import CollectorRegistry, ProcessCollector, generate_latest, Info, Gauge
+from prometheus_client import CollectorRegistry, ProcessCollector, generate_latest, Info, Gauge, Counter
 
 import concurrent.futures
 
@@ -53,7 +57,8 @@ from ambassador import Cache, Config, IR, EnvoyConfig, Diagnostics, Scout, Versi
 from ambassador.reconfig_stats import ReconfigStats
 from ambassador.ir.irambassador import IRAmbassador
 from ambassador.ir.irbasemapping import IRBaseMapping
-from ambassador.utils import SystemInfo, Timer, PeriodicTrigger, SavedSecret, load_url_contents
+from ambassador.ir.irstatus import SHARED_STATUS_KINDS, stamp_conditions
+from ambassador.utils import SystemInfo, Timer, TraceSpans, PeriodicTrigger, SavedSecret, load_url_contents, parse_yaml
 from ambassador.utils import SecretHandler, KubewatchSecretHandler, FSSecretHandler
 from ambassador.fetch import ResourceFetcher
 
@@ -89,6 +94,9 @@ ambassador_targets = {
     'module': 'https://www.getambassador.io/reference/configuration#modules',
 }
 
+# The header that says which tenant the diagnostics and metrics are for.
+TenantHeader = 'X-Ambassador-Tenant'
+
 # envoy_targets = {
 #     'route': 'https://envoyproxy.github.io/envoy/configuration/http_conn_man/route_config/route.html',
 #     'cluster': 'https://envoyproxy.github.io/envoy/configuration/cluster_manager/cluster.html',
@@ -200,6 +208,14 @@ class DiagApp (Flask):
         self.diag_notices = Gauge(f'diagnostics_notices', f'Number of configuration notices',
                                  namespace='ambassador', registry=self.metrics_registry)
 
+        # ...and on what each resolver resolved for the active config
+        self.resolver_services = Gauge(f'resolver_resolved_services', f'Number of services resolved by each resolver',
+                                       ['kind', 'resolver'], namespace='ambassador', registry=self.metrics_registry)
+        self.resolver_endpoints = Gauge(f'resolver_resolved_endpoints', f'Number of endpoints resolved by each resolver',
+                                        ['kind', 'resolver'], namespace='ambassador', registry=self.metrics_registry)
+        self.resolver_empty_services = Gauge(f'resolver_empty_services', f'Number of services that each resolver resolved to no endpoints',
+                                             ['kind', 'resolver'], namespace='ambassador', registry=self.metrics_registry)
+
         if debug:
             self.logger.setLevel(logging.DEBUG)
             logging.getLogger('ambassador').setLevel(logging.DEBUG)
@@ -353,6 +369,19 @@ class DiagApp (Flask):
 
             return _diag
 
+    def update_resolver_metrics(self, ir: IR) -> None:
+        # Start over, so that resolvers that are gone don't linger.
+        for gauge in [ self.resolver_services, self.resolver_endpoints, self.resolver_empty_services ]:
+            gauge.clear()
+
+        for name, services in ir.resolved_services.items():
+            resolver = ir.resolvers.get(name)
+            kind = resolver.kind if resolver else ''
+
+            self.resolver_services.labels(kind=kind, resolver=name).set(len(services))
+            self.resolver_endpoints.labels(kind=kind, resolver=name).set(sum(services.values()))
+            self.resolver_empty_services.labels(kind=kind, resolver=name).set(len([ c for c in services.values() if not c ]))
+
     def check_scout(self, what: str) -> None:
         self.watcher.post("SCOUT", (what, self.ir))
 
@@ -615,6 +644,15 @@ def _is_local_request() -> bool:
     return request.environ.get("REMOTE_ADDR") == "127.0.0.1"
 
 
+def _request_tenant() -> Optional[str]:
+    """
+    Return the tenant that the diagnostics and metrics of this request are for, or None for
+    everything. This is only as safe as whatever sets the header: the idea is that each tenant
+    reaches diagd through a Mapping that overwrites it with add_request_headers.
+    """
+    return request.headers.get(TenantHeader, None) or None
+
+
 class Notices:
     def __init__(self, local_config_path: str) -> None:
         self.local_path = local_config_path
@@ -785,9 +823,20 @@ def handle_watt_update():
 
     app.logger.debug("Update requested: watt, %s" % url)
 
-    status, info = app.watcher.post('CONFIG', ( 'watt', url ))
+    # If the entrypoint is tracing this reconfiguration, it hands us the parent span.
+    traceparent = request.headers.get('traceparent', None)
+    trace = TraceSpans(app.logger, os.environ.get('AMBASSADOR_OTLP_ENDPOINT'), traceparent)
 
-    return info, status
+    status, info = app.watcher.post('CONFIG', ( 'watt', url, trace ))
+
+    # The entrypoint puts how long each phase took into its reconfiguration report.
+    headers = {}
+    timing = trace.server_timing()
+
+    if timing:
+        headers['Server-Timing'] = timing
+
+    return info, status, headers
 
 
 @app.route('/_internal/v0/fs', methods=[ 'POST' ])
@@ -838,11 +887,43 @@ def check_alive():
         return "ambassador seems to have died (%s)\n" % status['uptime'], 503
 
 
+def entrypoint_unready() -> Optional[str]:
+    """
+    Return why the Go entrypoint says Ambassador isn't ready, or None if it is: Envoy hasn't
+    ACKed the configuration that ambex gave it yet, or memory pressure is critical. Without the
+    entrypoint (under watt), AMBASSADOR_READINESS_URL isn't set and there's nothing to wait for.
+    """
+    url = os.environ.get("AMBASSADOR_READINESS_URL")
+
+    if not url:
+        return None
+
+    try:
+        response = requests.get(url, timeout=1)
+
+        if response.status_code == 200:
+            return None
+
+        pressure = response.json().get('memory_pressure')
+
+        if pressure:
+            return "memory pressure is %s" % pressure
+    except Exception as e:
+        app.logger.debug("could not get Envoy ACK status: %s" % e)
+
+    return "Envoy has not accepted its configuration yet"
+
+
 @app.route('/ambassador/v0/check_ready', methods=[ 'GET' ])
 def check_ready():
     if not app.ir:
         return "ambassador waiting for config\n", 503
 
+    unready = entrypoint_unready()
+
+    if unready:
+        return "ambassador not ready (%s)\n" % unready, 503
+
     status = envoy_status(app.estatsmgr.get_stats())
 
     if status['ready']:
@@ -880,7 +961,8 @@ def show_overview(reqid=None):
         app.logger.debug("%s" % json.dumps(diag.as_dict(), sort_keys=True, indent=4))
 
     estats = app.estatsmgr.get_stats()
-    ov = diag.overview(request, estats)
+    tenant = _request_tenant()
+    ov = diag.overview(request, estats, tenant=tenant)
 
     if app.verbose:
         app.logger.debug("OV %s: OV" % reqid)
@@ -888,6 +970,7 @@ def show_overview(reqid=None):
         app.logger.debug("OV %s: collecting errors" % reqid)
 
     ddict = collect_errors_and_notices(request, reqid, "overview", diag)
+    ddict['ambassador_resolvers'] = with_resolver_statuses(ddict.get('ambassador_resolvers', []))
 
     banner_content = None
     if app.banner_endpoint and app.ir and app.ir.edge_stack_allowed:
@@ -939,6 +1022,37 @@ def show_overview(reqid=None):
         return Response(render_template("overview.html", **tvars))
 
 
+def with_resolver_statuses(resolvers: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """
+    Return copies of the resolver diagnostics, with what the Go entrypoint knows about the
+    watches of ConsulResolvers and DNSResolvers added. The entrypoint isn't running when diagd
+    is driven by watt, so don't complain if it's not there.
+    """
+    try:
+        response = requests.get("http://localhost:9696/resolvers", timeout=1)
+        statuses = response.json() if (response.status_code == 200) else []
+    except Exception as e:
+        app.logger.debug("could not get resolver statuses: %s" % e)
+        statuses = []
+
+    by_name = { (s['kind'], s['name'], s['namespace']): s for s in statuses or [] }
+    result = []
+
+    for resolver in resolvers:
+        resolver = dict(resolver)
+        status = by_name.get((resolver['kind'], resolver['name'], resolver.get('namespace')))
+
+        if status:
+            resolver['watched_services'] = status['services']
+            resolver['watched_endpoints'] = status['endpoints']
+            resolver['watch_age'] = int(status['watch_age_seconds'])
+            resolver['errors'] = status['errors']
+
+        result.append(resolver)
+
+    return result
+
+
 def collect_errors_and_notices(request, reqid, what: str, diag: Diagnostics) -> Dict:
     loglevel = request.args.get('loglevel', None)
     notice = None
@@ -955,7 +1069,7 @@ def collect_errors_and_notices(request, reqid, what: str, diag: Diagnostics) ->
     # they work for the HTML rendering, and post the notices to app.notices. Then we
     # return the dict representation that our caller should work with.
 
-    ddict = diag.as_dict()
+    ddict = diag.as_dict(tenant=_request_tenant())
 
     # app.logger.debug("ddict %s" % json.dumps(ddict, indent=4, sort_keys=True))
 
@@ -1013,7 +1127,10 @@ def show_intermediate(source=None, reqid=None):
     resource = request.args.get('resource', None)
 
     estats = app.estatsmgr.get_stats()
-    result = diag.lookup(request, source, estats)
+    result = diag.lookup(request, source, estats, tenant=_request_tenant())
+
+    if result is None:
+        return Response("Not found\n", 404)
 
     if app.verbose:
         app.logger.debug("RESULT %s" % json.dumps(result, sort_keys=True, indent=4))
@@ -1080,6 +1197,15 @@ def get_prometheus_metrics(*args, **kwargs):
     # Envoy metrics
     envoy_metrics = app.estatsmgr.get_prometheus_stats()
 
+    # A tenant gets only the Envoy metrics for its own clusters and Mappings: the rest are
+    # about the whole Ambassador.
+    tenant = _request_tenant()
+
+    if tenant is not None:
+        tenant_metrics = app.diag.tenant_metrics(tenant, envoy_metrics) if app.ir else ''
+
+        return Response(tenant_metrics.encode('utf-8'), 200, mimetype="text/plain")
+
     # Ambassador OSS metrics
     ambassador_metrics = generate_latest(registry=app.metrics_registry).decode('utf-8')
 
@@ -1093,10 +1219,147 @@ def get_prometheus_metrics(*args, **kwargs):
         except Exception as e:
             app.logger.error("could not get metrics_endpoint: %s" % e)
 
-    return Response(''.join([envoy_metrics, ambassador_metrics, extra_metrics_content]).encode('utf-8'),
+    # Control plane metrics from the Go entrypoint, if it's running. It isn't when diagd
+    # is driven by watt, so don't complain if it's not there.
+    entrypoint_metrics_content = ''
+    try:
+        response = requests.get("http://localhost:9696/metrics", timeout=1)
+        if response.status_code == 200:
+            entrypoint_metrics_content = response.text
+    except Exception as e:
+        app.logger.debug("could not get entrypoint metrics: %s" % e)
+
+    return Response(''.join([envoy_metrics, ambassador_metrics, entrypoint_metrics_content,
+                             extra_metrics_content]).encode('utf-8'),
                     200, mimetype="text/plain")
 
 
+def load_tap_grants() -> Optional[List[Dict[str, Any]]]:
+    """
+    Read the tap proxy's grants from the YAML file named by AMBASSADOR_TAP_PROXY_GRANTS,
+    usually a mounted Secret. Each grant has a bearer token, the tap config_ids that the
+    token can tap, and the most seconds it can tap for at once. The file is reread for
+    every request, so rotating the Secret doesn't need a restart.
+
+    Returns None if tap proxying isn't enabled.
+    """
+    path = os.environ.get('AMBASSADOR_TAP_PROXY_GRANTS', None)
+
+    if not path:
+        return None
+
+    with open(path, "r") as f:
+        docs = parse_yaml(f.read())
+
+    grants = docs[0] if docs else None
+
+    if not isinstance(grants, list):
+        raise Exception(f"{path}: expected a list of grants")
+
+    return grants
+
+
+@app.route('/ambassador/v0/tap', methods=[ 'POST' ])
+@standard_handler
+def proxy_tap(reqid=None):
+    # This is Envoy's admin /tap, for people who can reach the diag port but not Envoy's
+    # admin interface: the body is an Envoy TapRequest, and Envoy's traces stream back.
+    # The tap lasts until the client goes away or its time is up, whichever is first.
+    try:
+        grants = load_tap_grants()
+    except Exception as e:
+        app.logger.error("TAP %s - could not read tap grants: %s" % (reqid, e))
+        return Response("tap proxy is misconfigured\n", 500)
+
+    if grants is None:
+        return Response("tap proxying is not enabled\n", 404)
+
+    auth = request.headers.get('Authorization', '')
+    token = auth[len('Bearer '):] if auth.startswith('Bearer ') else ''
+    grant: Optional[Dict[str, Any]] = None
+
+    for candidate in grants:
+        if token and hmac.compare_digest(str(candidate.get('token', '')).encode('utf-8'), token.encode('utf-8')):
+            grant = candidate
+
+    if not grant:
+        return Response("unauthorized\n", 401, headers={ 'WWW-Authenticate': 'Bearer' })
+
+    body = request.get_data()
+
+    try:
+        config_id = json.loads(body).get('config_id', '')
+    except (ValueError, AttributeError):
+        return Response("request body must be a JSON TapRequest\n", 400)
+
+    if config_id not in grant.get('config_ids', []):
+        app.logger.info("TAP %s - %s may not tap config_id %s" % (reqid, request.remote_addr, config_id))
+        return Response(f"not allowed to tap config_id {config_id}\n", 403)
+
+    max_seconds = float(grant.get('max_seconds', 300))
+    seconds = max_seconds
+
+    if 'duration' in request.args:
+        try:
+            seconds = min(float(request.args['duration']), max_seconds)
+        except ValueError:
+            return Response("duration must be a number of seconds\n", 400)
+
+    if seconds <= 0:
+        return Response("duration must be positive\n", 400)
+
+    conn = http.client.HTTPConnection('127.0.0.1', Constants.ADMIN_PORT, timeout=5)
+
+    try:
+        conn.request('POST', '/tap', body=body, headers={ 'Content-Type': 'application/json' })
+        upstream = conn.getresponse()
+    except (OSError, http.client.HTTPException) as e:
+        conn.close()
+        app.logger.error("TAP %s - could not reach Envoy: %s" % (reqid, e))
+        return Response("could not reach Envoy\n", 502)
+
+    if upstream.status != 200:
+        text = upstream.read()
+        conn.close()
+        return Response(text, upstream.status, mimetype="text/plain")
+
+    app.logger.info("TAP %s - %s tapping config_id %s for %gs" % (reqid, request.remote_addr, config_id, seconds))
+
+    # Envoy only sends when something's tapped, so wait for it as long as it takes, and
+    # expire the tap by shutting the socket down, which wakes up the read. Closing the
+    # connection to Envoy is what makes Envoy remove the tap.
+    sock = conn.sock
+    sock.settimeout(None)
+
+    def expire():
+        try:
+            sock.shutdown(socket.SHUT_RDWR)
+        except OSError:
+            pass
+
+    expiry = threading.Timer(seconds, expire)
+    expiry.daemon = True
+    expiry.start()
+
+    def stream():
+        try:
+            while True:
+                chunk = upstream.read1(65536)
+
+                if not chunk:
+                    break
+
+                yield chunk
+        except (OSError, http.client.HTTPException):
+            pass
+        finally:
+            expiry.cancel()
+            conn.close()
+            app.logger.info("TAP %s - tap of config_id %s finished" % (reqid, config_id))
+
+    return Response(stream(), 200, mimetype="application/json")
+
+
 def bool_fmt(b: bool) -> str:
     return 'T' if b else 'F'
 
@@ -1147,8 +1410,13 @@ class KubeStatus:
         self.logger = app.logger
         self.live: Dict[str,  bool] = {}
         self.current_status: Dict[str, str] = {}
+        self.posted_events: Dict[str, bool] = {}
+        self.pending: List[Dict[str, Any]] = []
         self.pool = concurrent.futures.ProcessPoolExecutor(max_workers=5)
 
+        self.write_failures = Counter('kubestatus_write_failures', 'Number of status updates that could not be written',
+                                      ['kind', 'reason'], namespace='ambassador', registry=app.metrics_registry)
+
     def mark_live(self, kind: str, name: str, namespace: str) -> None:
         key = f"{kind}/{name}.{namespace}"
 
@@ -1172,21 +1440,93 @@ class KubeStatus:
         key = f"{kind}/{name}.{namespace}"
         extant = self.current_status.get(key, None)
 
+        status = json.loads(text)
+
+        if isinstance(status, dict) and status.get('conditions'):
+            # Conditions that haven't changed since we last wrote them keep their
+            # lastTransitionTime, so that an unchanged status is still == extant.
+            now = datetime.datetime.utcnow().replace(microsecond=0).isoformat() + 'Z'
+            status = stamp_conditions(status, json.loads(extant) if extant else None, now)
+            text = json.dumps(status)
+
         if extant == text:
             # self.logger.info(f"KubeStatus MASTER {os.getpid()}: {key} == {text}")
             pass
         else:
             # self.logger.info(f"KubeStatus MASTER {os.getpid()}: {key} needs {text}")
 
-            # For now we're going to assume that this works.
+            # We assume that this works; if it doesn't, update_done forgets it, so that the
+            # next reconfigure posts it again.
             self.current_status[key] = text
-            f = self.pool.submit(kubestatus_update, kind, name, namespace, text)
-            f.add_done_callback(kubestatus_update_done)
+            self.pending.append({
+                'kind': kind,
+                'name': name,
+                'namespace': namespace,
+                'status': status,
+                'merge': kind in SHARED_STATUS_KINDS
+            })
+
+    def post_event(self, kind: str, name: str, namespace: str, reason: str, message: str) -> None:
+        # Events are named after what they say, so each one is posted once, and posting it again
+        # after a restart does nothing.
+        digest = hashlib.sha1(f"{kind}/{reason}/{message}".encode('utf-8')).hexdigest()[:16]
+        event_name = f"{name}.{digest}"
+        key = f"Event/{event_name}.{namespace}"
+
+        if self.posted_events.get(key):
+            return
+
+        # Much as with statuses, update_done forgets the Event if it couldn't be posted.
+        self.posted_events[key] = True
+        self.pending.append({
+            'kind': 'Event',
+            'name': event_name,
+            'namespace': namespace,
+            'event': {
+                'involvedObject': { 'kind': kind, 'name': name, 'namespace': namespace },
+                'type': 'Warning',
+                'reason': reason,
+                'message': message
+            }
+        })
+
+    def flush(self) -> None:
+        # Write everything posted since the last flush with a single kubestatus, which
+        # rate-limits the writes and retries conflicts for us.
+        if not self.pending:
+            return
+
+        updates = self.pending
+        self.pending = []
+
+        f = self.pool.submit(kubestatus_batch_update, updates)
+        f.add_done_callback(functools.partial(self.update_done, updates))
+
+    def update_done(self, updates: List[Dict[str, Any]], f: concurrent.futures.Future) -> None:
+        try:
+            results = f.result()
+        except Exception as e:
+            self.logger.error(f"KubeStatus: could not update status: {e}")
+            results = [ dict(update, result='error') for update in updates ]
+
+        for result in results:
+            if result.get('result') in ( 'ok', 'dry-run' ):
+                continue
+
+            kind = result.get('kind', '')
+            self.write_failures.labels(kind, result.get('result', 'error')).inc()
+            self.logger.debug(f"KubeStatus: {kind} {result.get('name')}.{result.get('namespace')}: {result.get('error', result.get('result'))}")
+
+            # Forget the status or Event, so that the next reconfigure tries it again.
+            key = f"{kind}/{result.get('name')}.{result.get('namespace')}"
+            self.current_status.pop(key, None)
+            self.posted_events.pop(key, None)
 
 
 # The KubeStatusNoMappings class clobbers the mark_live() method of the
 # KubeStatus class, so that updates to Mappings don't actually have any
-# effect, but updates to Ingress (for example) do.
+# effect, but updates to Ingress (for example) do. The status conditions
+# of Hosts and TLSContexts go along with those of Mappings.
 class KubeStatusNoMappings (KubeStatus):
     def mark_live(self, kind: str, name: str, namespace: str) -> None:
         pass
@@ -1197,28 +1537,47 @@ class KubeStatusNoMappings (KubeStatus):
         # straight here without mark_live being involved -- so short-circuit
         # here for Mappings, too.
 
-        if kind == 'Mapping':
+        if kind in ( 'Mapping', 'Host', 'TLSContext' ):
             return
 
         super().post(kind, name, namespace, text)
 
-def kubestatus_update(kind: str, name: str, namespace: str, text: str) -> str:
-    cmd = [ 'kubestatus', '--cache-dir', '/tmp/client-go-http-cache', kind, name, '-n', namespace, '-u', '/dev/fd/0' ]
+def kubestatus_batch_update(updates: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    cmd = [ 'kubestatus', '--cache-dir', '/tmp/client-go-http-cache', '--batch', '/dev/fd/0' ]
+
+    if os.environ.get("AMBASSADOR_KUBESTATUS_DRY_RUN", "false").lower() == "true":
+        cmd.append('--dry-run')
+
     # print(f"KubeStatus UPDATE {os.getpid()}: running command: {cmd}")
 
-    try:
-        rc = subprocess.run(cmd, input=text.encode('utf-8'), stdout=subprocess.PIPE, stderr=subprocess.STDOUT, timeout=5)
-        if rc.returncode == 0:
-            return f"{name}.{namespace}: update OK"
-        else:
-            return f"{name}.{namespace}: error {rc.returncode}"
+    # kubestatus writes at most 10 statuses a second, so give it time for all of them.
+    timeout = 5 + len(updates) // 10
 
+    try:
+        rc = subprocess.run(cmd, input=json.dumps(updates).encode('utf-8'),
+                            stdout=subprocess.PIPE, stderr=subprocess.PIPE, timeout=timeout)
+        output = rc.stdout.decode('utf-8')
+        missing = 'error'
     except subprocess.TimeoutExpired as e:
-        return f"{name}.{namespace}: timed out\n\n{e.output}"
+        output = (e.output or b'').decode('utf-8')
+        missing = 'timeout'
+
+    # kubestatus reports the result of each update on a line of its own. Anything that it
+    # didn't get to failed, or timed out.
+    results: Dict[str, Dict[str, Any]] = {}
+
+    for line in output.splitlines():
+        try:
+            result = json.loads(line)
+        except ValueError:
+            continue
 
-def kubestatus_update_done(f: concurrent.futures.Future) -> None:
-    # print(f"KubeStatus DONE {os.getpid()}: result {f.result()}")
-    pass
+        results[f"{result.get('kind')}/{result.get('name')}.{result.get('namespace')}"] = result
+
+    return [ results.get(f"{update['kind']}/{update['name']}.{update['namespace']}",
+                         { 'kind': update['kind'], 'name': update['name'], 'namespace': update['namespace'],
+                           'result': missing })
+             for update in updates ]
 
 class AmbassadorEventWatcher(threading.Thread):
     # The key for 'Actions' is chimed - chimed_ok - env_good. This will make more sense
@@ -1248,7 +1607,7 @@ class AmbassadorEventWatcher(threading.Thread):
         self.env_good = False       # Is our environment currently believed to be OK?
         self.failure_list: List[str] = [ 'unhealthy at boot' ]     # What's making our environment not OK?
 
-    def post(self, cmd: str, arg: Optional[Union[str, Tuple[str, Optional[IR]]]]) -> Tuple[int, str]:
+    def post(self, cmd: str, arg: Optional[Union[str, Tuple[str, Optional[IR]], Tuple[str, str, TraceSpans]]]) -> Tuple[int, str]:
         rqueue: queue.Queue = queue.Queue()
 
         self.events.put((cmd, arg, rqueue))
@@ -1277,11 +1636,11 @@ class AmbassadorEventWatcher(threading.Thread):
                     self.logger.exception(e)
                     self._respond(rqueue, 500, 'configuration from filesystem failed')
             elif cmd == 'CONFIG':
-                version, url = arg
+                version, url, trace = arg
 
                 try:
                     if version == 'watt':
-                        self.load_config_watt(rqueue, url)
+                        self.load_config_watt(rqueue, url, trace)
                     else:
                         raise RuntimeError("config from %s not supported" % version)
                 except Exception as e:
@@ -1400,8 +1759,12 @@ class AmbassadorEventWatcher(threading.Thread):
     # reconfiguring these days.
     #
     # BE CAREFUL ABOUT STOPPING THE RECONFIGURATION TIMER ONCE IT IS STARTED.
-    def load_config_watt(self, rqueue: queue.Queue, url: str):
+    def load_config_watt(self, rqueue: queue.Queue, url: str, trace: Optional[TraceSpans]=None):
         snapshot = url.split('/')[-1]
+
+        if trace is None:
+            trace = TraceSpans(self.logger, None, None)
+
         ss_path = os.path.join(app.snapshot_path, "snapshot-tmp.yaml")
 
         # OK, we're starting a reconfiguration. BE CAREFUL TO STOP THE TIMER
@@ -1425,7 +1788,7 @@ class AmbassadorEventWatcher(threading.Thread):
 
         # OK. Time the various configuration sections separately.
 
-        with self.app.fetcher_timer:
+        with self.app.fetcher_timer, trace.span("diagd.fetcher"):
             aconf = Config()
             fetcher = ResourceFetcher(app.logger, aconf)
 
@@ -1440,15 +1803,18 @@ class AmbassadorEventWatcher(threading.Thread):
             # 
             # IF YOU CHANGE THIS, BE CAREFUL TO STOP THE RECONFIGURATION TIMER.
 
-        self._load_ir(rqueue, aconf, fetcher, scc, snapshot)
+        self._load_ir(rqueue, aconf, fetcher, scc, snapshot, trace)
 
     # _load_ir is where the heavy lifting of a reconfigure happens. 
     #
     # AT THE POINT OF ENTRY, THE RECONFIGURATION TIMER IS RUNNING. DO NOT LEAVE
     # THIS METHOD WITHOUT STOPPING THE RECONFIGURATION TIMER.
     def _load_ir(self, rqueue: queue.Queue, aconf: Config, fetcher: ResourceFetcher,
-                 secret_handler: SecretHandler, snapshot: str) -> None:
-        with self.app.aconf_timer:
+                 secret_handler: SecretHandler, snapshot: str, trace: Optional[TraceSpans]=None) -> None:
+        if trace is None:
+            trace = TraceSpans(self.logger, None, None)
+
+        with self.app.aconf_timer, trace.span("diagd.aconf"):
             aconf.load_all(fetcher.sorted())
 
         aconf_path = os.path.join(app.snapshot_path, "aconf-tmp.json")
@@ -1514,13 +1880,13 @@ class AmbassadorEventWatcher(threading.Thread):
                 # OK, we're doing an incremental reconfigure. 
                 config_type = "incremental"
 
-        with self.app.ir_timer:
+        with self.app.ir_timer, trace.span("diagd.ir"):
             ir = IR(aconf, secret_handler=secret_handler, cache=self.app.cache)
 
         ir_path = os.path.join(app.snapshot_path, "ir-tmp.json")
         open(ir_path, "w").write(ir.as_json())
 
-        with self.app.econf_timer:
+        with self.app.econf_timer, trace.span("diagd.econf"):
             econf = EnvoyConfig.generate(ir, "V2", cache=self.app.cache)
 
         # DON'T generate the Diagnostics here, because that turns out to be expensive.
@@ -1529,7 +1895,10 @@ class AmbassadorEventWatcher(threading.Thread):
 
         bootstrap_config, ads_config = econf.split_config()
 
-        if not self.validate_envoy_config(ir, config=ads_config, retries=self.app.validation_retries):
+        with trace.span("diagd.validate"):
+            config_valid = self.validate_envoy_config(ir, config=ads_config, retries=self.app.validation_retries)
+
+        if not config_valid:
             self.logger.info("no updates were performed due to invalid envoy configuration, continuing with current configuration...")
 
             # Don't use app.check_scout; it will deadlock.
@@ -1537,6 +1906,7 @@ class AmbassadorEventWatcher(threading.Thread):
 
             # DO stop the reconfiguration timer before leaving.
             self.app.config_timer.stop()
+            trace.export()
             self._respond(rqueue, 500, 'ignoring: invalid Envoy configuration in snapshot %s' % snapshot)
             return
 
@@ -1583,6 +1953,10 @@ class AmbassadorEventWatcher(threading.Thread):
         with open(app.bootstrap_path, "w") as output:
             output.write(json.dumps(bootstrap_config, sort_keys=True, indent=4))
 
+        # ambex serves TapDS from the file next to the ADS config.
+        with open(os.path.join(os.path.dirname(app.ads_path), "tapds.json"), "w") as output:
+            output.write(json.dumps(econf.tapds_config(), sort_keys=True, indent=4))
+
         with open(app.ads_path, "w") as output:
             output.write(json.dumps(ads_config, sort_keys=True, indent=4))
 
@@ -1594,8 +1968,11 @@ class AmbassadorEventWatcher(threading.Thread):
             # Force app.diag to None so that it'll be regenerated on-demand.
             app.diag = None
 
+        app.update_resolver_metrics(ir)
+
         # We're finally done with the whole configuration process.
         self.app.config_timer.stop()
+        trace.export()
 
         if app.kick:
             self.logger.debug("running '%s'" % app.kick)
@@ -1627,6 +2004,10 @@ class AmbassadorEventWatcher(threading.Thread):
 
                 app.kubestatus.post(kind, resource_name, namespace, text)
 
+        for kind, name, namespace, reason, message in app.ir.k8s_events:
+            app.kubestatus.post_event(kind, name, namespace, reason, message)
+
+        app.kubestatus.flush()
 
         group_count = len(app.ir.groups)
         cluster_count = len(app.ir.clusters)
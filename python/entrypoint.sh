@@ -430,6 +430,18 @@ if [[ -z "${AMBASSADOR_NO_KUBEWATCH}" ]]; then
         watt_query_flags+=(-s LogService)
     fi
 
+    if [ ! -f "${AMBASSADOR_CONFIG_BASE_DIR}/.ambassador_ignore_crds_5" ]; then
+        watt_query_flags+=(-s IngressClassParameters)
+    fi
+
+    if [ ! -f "${AMBASSADOR_CONFIG_BASE_DIR}/.ambassador_ignore_crds_6" ]; then
+        watt_query_flags+=(-s RouteDelegation)
+    fi
+
+    if [ ! -f "${AMBASSADOR_CONFIG_BASE_DIR}/.ambassador_ignore_crds_7" ]; then
+        watt_query_flags+=(-s WasmFilter)
+    fi
+
     if [ -n "$AMBASSADOR_FIELD_SELECTOR" ] ; then
 	    watt_query_flags+=(--fields $AMBASSADOR_FIELD_SELECTOR)
     fi
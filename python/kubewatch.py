@@ -155,12 +155,18 @@ def check_ingress_classes():
 
             auth_settings = ['BearerToken']
 
-            api_client.call_api('/apis/networking.k8s.io/v1beta1/ingressclasses', 'GET',
-                                path_params,
-                                query_params,
-                                header_params,
-                                auth_settings=auth_settings)
-            status = True
+            # IngressClass is networking.k8s.io/v1 as of Kubernetes 1.19, and v1beta1 before that.
+            for version in [ 'v1', 'v1beta1' ]:
+                try:
+                    api_client.call_api(f'/apis/networking.k8s.io/{version}/ingressclasses', 'GET',
+                                        path_params,
+                                        query_params,
+                                        header_params,
+                                        auth_settings=auth_settings)
+                    status = True
+                    break
+                except ApiException as e:
+                    logger.debug(f'IngressClass {version} check got {e.status}')
         except ApiException as e:
             logger.debug(f'IngressClass check got {e.status}')
 
@@ -289,6 +295,24 @@ def main(debug):
                 [
                     'logservices.getambassador.io'
                 ]
+            ),
+            (
+                '.ambassador_ignore_crds_5', 'IngressClassParameters CRDs',
+                [
+                    'ingressclassparameters.getambassador.io'
+                ]
+            ),
+            (
+                '.ambassador_ignore_crds_6', 'RouteDelegation CRDs',
+                [
+                    'routedelegations.getambassador.io'
+                ]
+            ),
+            (
+                '.ambassador_ignore_crds_7', 'WasmFilter CRDs',
+                [
+                    'wasmfilters.getambassador.io'
+                ]
             )
         ]
 
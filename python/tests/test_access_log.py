@@ -0,0 +1,373 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_routes, get_http_access_logs
+
+mappings = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  prefix: /quote/
+  service: quote
+'''
+
+def _module(spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+{spec}
+'''
+
+def _file_access_logs(econf):
+    return [ al for al in get_http_access_logs(econf) if al['name'] == 'envoy.file_access_log' ]
+
+def test_envoy_log_fields():
+    ir, econf = get_envoy_config(mappings + _module('''
+    envoy_log_type: typed_json
+    envoy_log_fields:
+      status:
+        command: response_code
+      start:
+        command: START_TIME
+        format: "%s"
+      user:
+        request_header: x-user
+        fallback_header: x-anonymous-user
+        max_length: 32
+      location:
+        response_header: location
+      grpc_status:
+        response_trailer: grpc-status
+      subject:
+        dynamic_metadata:
+          filter: envoy.filters.http.jwt_authn
+          path: [ payload, sub ]
+'''))
+
+    assert get_errors(ir) == []
+
+    access_logs = _file_access_logs(econf)
+    assert len(access_logs) == 1
+
+    # typed_json keeps numbers as numbers.
+    assert 'json_format' not in access_logs[0]['typed_config']
+    assert access_logs[0]['typed_config']['typed_json_format'] == {
+        'status': '%RESPONSE_CODE%',
+        'start': '%START_TIME(%s)%',
+        'user': '%REQ(x-user?x-anonymous-user):32%',
+        'location': '%RESP(location)%',
+        'grpc_status': '%TRAILER(grpc-status)%',
+        'subject': '%DYNAMIC_METADATA(envoy.filters.http.jwt_authn:payload:sub)%'
+    }
+
+
+def test_envoy_log_fields_errors():
+    ir, econf = get_envoy_config(mappings + _module('''
+    envoy_log_type: json
+    envoy_log_fields:
+      good:
+        command: DURATION
+      unknown:
+        command: NOT_A_COMMAND
+      formatted:
+        command: DURATION
+        format: "%s"
+      truncated:
+        command: PROTOCOL
+        max_length: 10
+      ambiguous:
+        request_header: x-user
+        response_header: x-user
+'''))
+
+    assert sorted(get_errors(ir)) == [
+        "envoy_log_fields ambiguous: must have exactly one of command, request_header, "
+        "response_header, response_trailer, or dynamic_metadata",
+        "envoy_log_fields formatted: DURATION doesn't take a format",
+        "envoy_log_fields truncated: max_length can't be used with a command",
+        "envoy_log_fields unknown: unknown command NOT_A_COMMAND"
+    ]
+
+    # The bad fields are left out, but the rest of the format is kept.
+    assert _file_access_logs(econf)[0]['typed_config']['json_format'] == { 'good': '%DURATION%' }
+
+
+def test_envoy_log_fields_text():
+    ir, econf = get_envoy_config(mappings + _module('''
+    envoy_log_fields:
+      status:
+        command: RESPONSE_CODE
+'''))
+
+    assert get_errors(ir) == [ 'envoy_log_fields requires envoy_log_type json or typed_json, ignoring' ]
+
+    assert _file_access_logs(econf)[0]['typed_config']['format'].startswith('ACCESS [%START_TIME%]')
+
+
+def test_envoy_log_fields_and_format():
+    ir, econf = get_envoy_config(mappings + _module('''
+    envoy_log_type: json
+    envoy_log_format:
+      status: "%RESPONSE_CODE%"
+    envoy_log_fields:
+      duration:
+        command: DURATION
+'''))
+
+    assert get_errors(ir) == [ 'envoy_log_fields and envoy_log_format cannot both be set, ignoring envoy_log_fields' ]
+
+    assert _file_access_logs(econf)[0]['typed_config']['json_format'] == { 'status': '%RESPONSE_CODE%' }
+
+
+def test_envoy_access_logs():
+    ir, econf = get_envoy_config(mappings + _module('''
+    envoy_access_logs:
+    - path: /tmp/all.log
+    - sink: stderr
+      filter:
+        status_code_min: 500
+    - path: /tmp/slow.log
+      filter:
+        duration_min_ms: 1000
+        header: x-debug
+'''))
+
+    assert get_errors(ir) == []
+
+    access_logs = _file_access_logs(econf)
+
+    # Every sink has the same format.
+    assert [ al['typed_config']['path'] for al in access_logs ] == [ '/tmp/all.log', '/dev/stderr', '/tmp/slow.log' ]
+    assert len(set([ al['typed_config']['format'] for al in access_logs ])) == 1
+
+    assert 'filter' not in access_logs[0]
+
+    assert access_logs[1]['filter'] == {
+        'status_code_filter': {
+            'comparison': {
+                'op': 'GE',
+                'value': { 'default_value': 500, 'runtime_key': 'access_log.status_code_min' }
+            }
+        }
+    }
+
+    assert access_logs[2]['filter'] == {
+        'and_filter': {
+            'filters': [
+                {
+                    'duration_filter': {
+                        'comparison': {
+                            'op': 'GE',
+                            'value': { 'default_value': 1000, 'runtime_key': 'access_log.duration_min_ms' }
+                        }
+                    }
+                },
+                { 'header_filter': { 'header': { 'name': 'x-debug', 'present_match': True } } }
+            ]
+        }
+    }
+
+
+def test_envoy_access_logs_errors():
+    ir, econf = get_envoy_config(mappings + _module('''
+    envoy_access_logs:
+    - path: /tmp/good.log
+    - sink: opentelemetry
+    - sink: syslog
+    - sink: file
+    - path: /tmp/bad.log
+      filter:
+        status_code_min: -1
+'''))
+
+    assert get_errors(ir) == [
+        'envoy_access_logs: the opentelemetry sink is not supported by this version of Envoy, ignoring',
+        'envoy_access_logs: unknown sink syslog, ignoring',
+        'envoy_access_logs: a file sink needs a path, ignoring',
+        'envoy_access_logs /tmp/bad.log: access log filter: status_code_min must be a non-negative integer'
+    ]
+
+    # Only the good sink is left.
+    assert [ al['typed_config']['path'] for al in _file_access_logs(econf) ] == [ '/tmp/good.log' ]
+
+
+def test_default_access_log():
+    ir, econf = get_envoy_config(mappings)
+
+    assert get_errors(ir) == []
+
+    access_logs = _file_access_logs(econf)
+    assert [ al['typed_config']['path'] for al in access_logs ] == [ '/dev/fd/1' ]
+    assert 'filter' not in access_logs[0]
+
+
+def _status_range(op, value):
+    return {
+        'status_code_filter': {
+            'comparison': {
+                'op': op,
+                'value': {
+                    'default_value': value,
+                    'runtime_key': 'access_log.sampling.status_%s_%d' % (op.lower(), value)
+                }
+            }
+        }
+    }
+
+def _sampled(cls, percent):
+    return {
+        'runtime_filter': {
+            'runtime_key': 'access_log.sampling.%s' % cls,
+            'percent_sampled': { 'numerator': percent, 'denominator': 'HUNDRED' }
+        }
+    }
+
+
+def test_access_log_sampling():
+    ir, econf = get_envoy_config(mappings + _module('''
+    access_log_sampling:
+      2xx: 10
+      3xx: 0
+'''))
+
+    assert get_errors(ir) == []
+
+    # 3xx is never logged; everything not mentioned is always logged.
+    assert _file_access_logs(econf)[0]['filter'] == {
+        'or_filter': {
+            'filters': [
+                _status_range('LE', 199),
+                { 'and_filter': { 'filters': [ _status_range('GE', 200), _status_range('LE', 299), _sampled('2xx', 10) ] } },
+                { 'and_filter': { 'filters': [ _status_range('GE', 400), _status_range('LE', 499) ] } },
+                _status_range('GE', 500)
+            ]
+        }
+    }
+
+
+def test_access_log_sampling_with_sink_filter():
+    ir, econf = get_envoy_config(mappings + _module('''
+    access_log_sampling:
+      1xx: 0
+      2xx: 0
+      3xx: 0
+      4xx: 0
+      5xx: 0
+    envoy_access_logs:
+    - path: /tmp/errors.log
+      filter:
+        header: x-debug
+'''))
+
+    assert get_errors(ir) == []
+
+    # Sampling goes on top of the sink's own filter. Envoy can't say "never", so
+    # logging nothing at all is 0% sampling.
+    assert _file_access_logs(econf)[0]['filter'] == {
+        'and_filter': {
+            'filters': [
+                { 'header_filter': { 'header': { 'name': 'x-debug', 'present_match': True } } },
+                _sampled('none', 0)
+            ]
+        }
+    }
+
+
+def test_mapping_access_log_sampling():
+    ir, econf = get_envoy_config(mappings + '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: noisy
+  namespace: default
+spec:
+  prefix: /noisy/
+  service: noisy
+  access_log_sampling:
+    2xx: 1
+''')
+
+    assert get_errors(ir) == []
+
+    key = '1xx=100,2xx=1,3xx=100,4xx=100,5xx=100'
+
+    # The Mapping names its policy in a header...
+    noisy = get_routes(econf)['/noisy/']
+    assert { 'header': { 'key': 'x-ambassador-access-log-sampling', 'value': key },
+             'append': False } in noisy['request_headers_to_add']
+
+    assert 'request_headers_to_add' not in get_routes(econf)['/quote/']
+
+    # ...and the listener picks the policy by that header. Without a Module policy,
+    # everything else is logged.
+    assert _file_access_logs(econf)[0]['filter'] == {
+        'or_filter': {
+            'filters': [
+                {
+                    'and_filter': {
+                        'filters': [
+                            { 'header_filter': { 'header': { 'name': 'x-ambassador-access-log-sampling', 'exact_match': key } } },
+                            {
+                                'or_filter': {
+                                    'filters': [
+                                        _status_range('LE', 199),
+                                        { 'and_filter': { 'filters': [ _status_range('GE', 200), _status_range('LE', 299), _sampled('2xx', 1) ] } },
+                                        { 'and_filter': { 'filters': [ _status_range('GE', 300), _status_range('LE', 399) ] } },
+                                        { 'and_filter': { 'filters': [ _status_range('GE', 400), _status_range('LE', 499) ] } },
+                                        _status_range('GE', 500)
+                                    ]
+                                }
+                            }
+                        ]
+                    }
+                },
+                { 'header_filter': { 'header': { 'name': 'x-ambassador-access-log-sampling', 'present_match': True, 'invert_match': True } } }
+            ]
+        }
+    }
+
+
+def test_access_log_sampling_invalid():
+    ir, econf = get_envoy_config(mappings + _module('''
+    access_log_sampling:
+      2xx: 110
+      6xx: 10
+''') + '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: noisy
+  namespace: default
+spec:
+  prefix: /noisy/
+  service: noisy
+  access_log_sampling:
+    2xx: half
+''')
+
+    assert sorted(get_errors(ir)) == [
+        'access_log_sampling: 2xx must be a percentage from 0 to 100',
+        'access_log_sampling: 2xx must be a percentage from 0 to 100, ignoring',
+        'access_log_sampling: unknown status class 6xx, ignoring'
+    ]
+
+    # The Module's policy is ignored, and the Mapping is dropped.
+    assert 'filter' not in _file_access_logs(econf)[0]
+    assert '/noisy/' not in get_routes(econf)
@@ -0,0 +1,298 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_clusters, get_routes, get_http_filters
+
+mappings = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  prefix: /quote/
+  service: quote
+'''
+
+def _authservice(spec, name='auth'):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: AuthService
+metadata:
+  name: {name}
+  namespace: default
+spec:
+{spec}
+'''
+
+def _auth_filters(econf):
+    return [ f for f in get_http_filters(econf) if f['name'].startswith('envoy.ext_authz') ]
+
+def test_grpc_v2_is_default():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: grpc
+'''))
+
+    assert get_errors(ir) == []
+
+    filters = _auth_filters(econf)
+    assert len(filters) == 1
+
+    config = filters[0]['config']
+    assert 'typed_config' not in filters[0]
+    assert config['use_alpha'] == True
+    assert 'transport_api_version' not in config
+    assert config['grpc_service']['envoy_grpc']['cluster_name'].startswith('cluster_extauth_auth_3000')
+
+
+def test_grpc_v3():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: grpc
+  protocol_version: v3
+  metadata_context_namespaces:
+  - envoy.filters.http.jwt_authn
+'''))
+
+    assert get_errors(ir) == []
+
+    filters = _auth_filters(econf)
+    assert len(filters) == 1
+
+    # The v3 protocol needs the v3 filter config, which can only be a typed_config.
+    assert 'config' not in filters[0]
+
+    config = filters[0]['typed_config']
+    assert config['@type'] == 'type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz'
+    assert config['transport_api_version'] == 'V3'
+    assert 'use_alpha' not in config
+    assert config['clear_route_cache'] == True
+    assert config['metadata_context_namespaces'] == [ 'envoy.filters.http.jwt_authn' ]
+
+
+def test_http_allowed_client_headers():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: http
+  allowed_authorization_headers:
+  - X-Upstream
+  allowed_client_headers:
+  - X-Denied-Reason
+  - WWW-Authenticate
+'''))
+
+    assert get_errors(ir) == []
+
+    response = _auth_filters(econf)[0]['config']['http_service']['authorization_response']
+
+    upstream = [ p['exact'] for p in response['allowed_upstream_headers']['patterns'] ]
+    assert 'x-upstream' in upstream
+
+    # allowed_client_headers replaces the default set, rather than adding to it.
+    client = [ p['exact'] for p in response['allowed_client_headers']['patterns'] ]
+    assert client == [ 'www-authenticate', 'x-denied-reason' ]
+
+
+def test_http_allowed_client_headers_default():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: http
+  allowed_authorization_headers:
+  - X-Upstream
+'''))
+
+    assert get_errors(ir) == []
+
+    response = _auth_filters(econf)[0]['config']['http_service']['authorization_response']
+
+    assert response['allowed_client_headers'] == response['allowed_upstream_headers']
+
+
+def test_auth_context_extensions():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: grpc
+''') + '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: tenant
+  namespace: default
+spec:
+  prefix: /tenant/
+  service: tenant
+  auth_context_extensions:
+    tenant: acme
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: public
+  namespace: default
+spec:
+  prefix: /public/
+  service: public
+  bypass_auth: true
+  auth_context_extensions:
+    tenant: acme
+''')
+
+    assert get_errors(ir) == []
+
+    routes = get_routes(econf)
+
+    assert routes['/tenant/']['per_filter_config']['envoy.ext_authz'] == {
+        'check_settings': {
+            'context_extensions': { 'tenant': 'acme' }
+        }
+    }
+
+    # bypass_auth wins over auth_context_extensions.
+    assert routes['/public/']['per_filter_config']['envoy.ext_authz'] == { 'disabled': True }
+
+    assert 'per_filter_config' not in routes['/quote/']
+
+
+def test_circuit_breakers():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: grpc
+  circuit_breakers:
+  - max_connections: 10
+    max_pending_requests: 5
+  - priority: high
+    max_requests: 20
+'''))
+
+    assert get_errors(ir) == []
+
+    # The thresholds go on the auth service's own cluster, whose name says what they are.
+    cluster = get_clusters(econf)['cluster_extauth_auth_3000_default_cbnc10p5_cbhr20']
+    assert cluster['circuit_breakers'] == {
+        'thresholds': [
+            { 'priority': 'DEFAULT', 'max_connections': 10, 'max_pending_requests': 5 },
+            { 'priority': 'HIGH', 'max_requests': 20 }
+        ]
+    }
+
+    assert 'circuit_breakers' not in get_clusters(econf)['cluster_quote_default']
+
+
+def test_circuit_breakers_invalid():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: grpc
+  circuit_breakers:
+  - priority: urgent
+    max_connections: 10
+'''))
+
+    assert any('Invalid circuit_breakers' in e for e in get_errors(ir))
+
+    assert 'circuit_breakers' not in get_clusters(econf)['cluster_extauth_auth_3000_default']
+
+
+chained = '''
+---
+apiVersion: getambassador.io/v2
+kind: AuthService
+metadata:
+  name: admin
+  namespace: other
+spec:
+  auth_service: admin-auth:3000
+  proto: grpc
+  precedence: 10
+  mapping_prefixes:
+  - /admin/
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: admin
+  namespace: default
+spec:
+  prefix: /admin/
+  service: admin
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: public
+  namespace: default
+spec:
+  prefix: /public/
+  service: public
+  bypass_auth: true
+'''
+
+def test_chained():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: http
+''') + chained)
+
+    assert get_errors(ir) == []
+
+    # Chained filters run first, by descending precedence, and are named for their
+    # AuthService's own namespace.
+    filters = _auth_filters(econf)
+    assert [ f['name'] for f in filters ] == [ 'envoy.ext_authz.admin.other', 'envoy.ext_authz' ]
+
+    # Envoy finds a chained filter by the type of its config, since it doesn't know its name.
+    assert 'config' not in filters[0]
+    assert filters[0]['typed_config']['@type'] == 'type.googleapis.com/envoy.config.filter.http.ext_authz.v2.ExtAuthz'
+    assert filters[0]['typed_config']['grpc_service']['envoy_grpc']['cluster_name'].startswith('cluster_extauth_admin_auth_3000')
+    assert 'typed_config' not in filters[1]
+
+    routes = get_routes(econf)
+    per_route_type = 'type.googleapis.com/envoy.config.filter.http.ext_authz.v2.ExtAuthzPerRoute'
+
+    # Both filters check /admin/.
+    assert 'per_filter_config' not in routes['/admin/']
+    assert 'typed_per_filter_config' not in routes['/admin/']
+
+    # Only the default filter checks /quote/; its per-route config has to be typed too,
+    # since a route can't have both kinds.
+    assert 'per_filter_config' not in routes['/quote/']
+    assert routes['/quote/']['typed_per_filter_config'] == {
+        'envoy.ext_authz.admin.other': { '@type': per_route_type, 'disabled': True }
+    }
+
+    assert routes['/public/']['typed_per_filter_config'] == {
+        'envoy.ext_authz.admin.other': { '@type': per_route_type, 'disabled': True },
+        'envoy.ext_authz': { '@type': per_route_type, 'disabled': True }
+    }
+
+
+def test_chained_v3():
+    ir, econf = get_envoy_config(mappings + _authservice('''
+  auth_service: auth:3000
+  proto: grpc
+  protocol_version: v3
+''') + chained)
+
+    assert get_errors(ir) == []
+
+    routes = get_routes(econf)
+
+    # Each filter's per-route config has the version of the filter's own config.
+    assert routes['/public/']['typed_per_filter_config'] == {
+        'envoy.ext_authz.admin.other': {
+            '@type': 'type.googleapis.com/envoy.config.filter.http.ext_authz.v2.ExtAuthzPerRoute',
+            'disabled': True
+        },
+        'envoy.ext_authz': {
+            '@type': 'type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute',
+            'disabled': True
+        }
+    }
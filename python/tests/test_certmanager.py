@@ -0,0 +1,196 @@
+import logging
+import sys
+
+import pytest
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+logger = logging.getLogger("ambassador")
+
+from ambassador import Config, IR
+from ambassador.fetch import ResourceFetcher
+from ambassador.ir.irstatus import certificate_condition
+from ambassador.utils import NullSecretHandler
+
+yaml = '''
+---
+apiVersion: getambassador.io/v2
+kind: Host
+metadata:
+  name: shop
+  namespace: default
+  generation: 2
+spec:
+  hostname: shop.example.com
+  tlsSecret:
+    name: shop-cert
+---
+apiVersion: getambassador.io/v2
+kind: Host
+metadata:
+  name: new
+  namespace: default
+spec:
+  hostname: new.example.com
+  tlsSecret:
+    name: new-cert
+---
+apiVersion: getambassador.io/v2
+kind: Host
+metadata:
+  name: plain
+  namespace: default
+spec:
+  hostname: plain.example.com
+  tlsSecret:
+    name: plain-cert
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: shop
+  namespace: default
+spec:
+  secretName: shop-cert
+  dnsNames:
+  - shop.example.com
+status:
+  notAfter: "2021-01-30T00:00:00Z"
+  renewalTime: "2020-12-31T00:00:00Z"
+  conditions:
+  - type: Ready
+    status: "True"
+    reason: Ready
+    message: Certificate is up to date and has not expired
+---
+apiVersion: cert-manager.io/v1alpha2
+kind: Certificate
+metadata:
+  name: new
+  namespace: default
+spec:
+  secretName: new-cert
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+    reason: DoesNotExist
+    message: Issuing certificate as Secret does not exist
+  - type: Issuing
+    status: "True"
+    reason: DoesNotExist
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: elsewhere
+  namespace: other
+spec:
+  secretName: plain-cert
+'''
+
+
+class MissingSecretHandler (NullSecretHandler):
+    """
+    A secret handler that only has the Secrets it's told about, so that a Certificate that
+    hasn't been issued has no Secret.
+    """
+
+    def __init__(self, present) -> None:
+        super().__init__(logger, None, None, "0")
+        self.present = present
+
+    def load_secret(self, resource, secret_name, namespace):
+        if secret_name not in self.present:
+            return None
+
+        return super().load_secret(resource, secret_name, namespace)
+
+
+def _get_ir(yaml):
+    aconf = Config()
+
+    fetcher = ResourceFetcher(logger, aconf)
+    fetcher.parse_yaml(yaml, k8s=True)
+
+    aconf.load_all(fetcher.sorted())
+
+    ir = IR(aconf, file_checker=lambda path: True,
+            secret_handler=MissingSecretHandler({ 'shop-cert', 'plain-cert' }))
+
+    assert ir, "could not create an IR"
+
+    return ir
+
+
+def _conditions(ir, name):
+    kind, namespace, status = ir.k8s_status_updates[name]
+    assert kind == 'Host'
+
+    return { c['type']: c for c in status['conditions'] }
+
+
+def test_certificates():
+    ir = _get_ir(yaml)
+
+    assert set(ir.aconf.k8s_certificates.keys()) == { 'shop-cert.default', 'new-cert.default', 'plain-cert.other' }
+
+    # A ready Certificate says when it expires and when it'll be renewed.
+    shop = _conditions(ir, 'shop.default')
+    assert shop['Ready']['status'] == 'True'
+    assert shop['CertificateReady'] == {
+        'type': 'CertificateReady', 'status': 'True', 'reason': 'Ready', 'observedGeneration': 2,
+        'message': 'Certificate shop is valid until 2021-01-30T00:00:00Z and will be renewed at 2020-12-31T00:00:00Z'
+    }
+
+    # A Host that's waiting for its Certificate isn't ready, but it isn't invalid either.
+    new = _conditions(ir, 'new.default')
+    assert new['Accepted']['status'] == 'True'
+    assert new['Ready']['status'] == 'False'
+    assert new['Ready']['reason'] == 'CertificateNotReady'
+    assert new['CertificateReady']['status'] == 'False'
+    assert new['CertificateReady']['reason'] == 'DoesNotExist'
+
+    assert ir.k8s_events == [
+        ('Host', 'new', 'default', 'CertificateNotReady', 'Issuing certificate as Secret does not exist')
+    ]
+
+    # A Certificate in another namespace doesn't write this Host's Secret.
+    assert 'CertificateReady' not in _conditions(ir, 'plain.default')
+
+
+def test_certificate_condition():
+    cert = {
+        'name': 'shop', 'ready': True, 'reason': 'Ready', 'message': '',
+        'issuing': True, 'issuing_reason': 'Renewing', 'issuing_message': '',
+        'not_after': '2021-01-30T00:00:00Z', 'renewal_time': '2020-12-31T00:00:00Z',
+    }
+
+    renewing = certificate_condition(cert)
+    assert renewing['status'] == 'True'
+    assert renewing['reason'] == 'Renewing'
+
+    # A failed renewal leaves the old certificate in place, so it's still ready.
+    cert.update(issuing=False, issuing_reason='Failed', issuing_message='ACME order failed')
+    failed = certificate_condition(cert)
+    assert failed['status'] == 'True'
+    assert failed['reason'] == 'RenewalFailed'
+    assert failed['message'] == 'Certificate shop is valid until 2021-01-30T00:00:00Z; renewing it failed: ACME order failed'
+
+    # A Certificate that cert-manager hasn't looked at yet has no conditions.
+    pending = certificate_condition({
+        'name': 'new', 'ready': None, 'reason': '', 'message': '',
+        'issuing': None, 'issuing_reason': '', 'issuing_message': '', 'not_after': '', 'renewal_time': '',
+    })
+    assert pending == {
+        'type': 'CertificateReady', 'status': 'False', 'reason': 'Pending',
+        'message': 'Certificate new has not been issued yet'
+    }
+
+
+if __name__ == '__main__':
+    pytest.main(sys.argv)
@@ -378,5 +378,315 @@ service: test:9999""",
             assert self.manager.elements[0].get(key) == value
 
 
+class TestIngress:
+
+    def setup(self):
+        self.fetcher = ResourceFetcher(logger, Config(), skip_init_dir=True)
+
+        self.fetcher.handle_k8s(parse_yaml('''
+apiVersion: getambassador.io/v2
+kind: IngressClassParameters
+metadata:
+  name: external-lb-params
+spec:
+  rewrite: ""
+  timeout_ms: 5000
+  insecure_action: Redirect
+''')[0])
+
+        self.fetcher.handle_k8s(parse_yaml('''
+apiVersion: networking.k8s.io/v1
+kind: IngressClass
+metadata:
+  name: external-lb
+spec:
+  controller: getambassador.io/ingress-controller
+  parameters:
+    apiGroup: getambassador.io
+    kind: IngressClassParameters
+    name: external-lb-params
+''')[0])
+
+    def mappings(self):
+        return { e.name: e for e in self.fetcher.elements if e.kind == 'Mapping' }
+
+    def test_v1(self):
+        self.fetcher.handle_k8s(parse_yaml('''
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: test
+  namespace: default
+spec:
+  ingressClassName: external-lb
+  defaultBackend:
+    service:
+      name: fallback
+      port:
+        number: 8080
+  tls:
+  - hosts:
+    - example.com
+    secretName: example-tls
+  rules:
+  - host: example.com
+    http:
+      paths:
+      - path: /exact
+        pathType: Exact
+        backend:
+          service:
+            name: exact
+            port:
+              number: 80
+      - path: /prefix/
+        pathType: Prefix
+        backend:
+          service:
+            name: prefix
+            port:
+              name: http
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: root
+            port:
+              number: 80
+''')[0])
+
+        mappings = self.mappings()
+        assert sorted(mappings.keys()) == [ 'test-0-0', 'test-0-1', 'test-0-1-exact', 'test-0-2', 'test-default-backend' ]
+
+        assert mappings['test-default-backend'].service == 'fallback.default:8080'
+
+        assert mappings['test-0-0'].prefix == '/exact'
+        assert mappings['test-0-0'].prefix_exact
+        assert mappings['test-0-0'].precedence == 1
+
+        # A Prefix path matches whole path elements.
+        assert mappings['test-0-1'].prefix == '/prefix/'
+        assert not mappings['test-0-1'].prefix_exact
+        assert mappings['test-0-1-exact'].prefix == '/prefix'
+        assert mappings['test-0-1-exact'].prefix_exact
+        assert mappings['test-0-1'].service == 'prefix.default:http'
+
+        assert mappings['test-0-2'].prefix == '/'
+        assert mappings['test-0-2'].host == 'example.com'
+
+        # The IngressClassParameters apply to every Mapping, and Host.
+        for mapping in mappings.values():
+            assert mapping.rewrite == ''
+            assert mapping.timeout_ms == 5000
+
+        hosts = [ e for e in self.fetcher.elements if e.kind == 'Host' ]
+        assert len(hosts) == 1
+        assert hosts[0].requestPolicy['insecure']['action'] == 'Redirect'
+
+    def test_v1beta1(self):
+        self.fetcher.handle_k8s(parse_yaml('''
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: test
+  namespace: default
+  annotations:
+    kubernetes.io/ingress.class: ambassador
+spec:
+  backend:
+    serviceName: fallback
+    servicePort: 8080
+  rules:
+  - http:
+      paths:
+      - path: /legacy/
+        backend:
+          serviceName: legacy
+          servicePort: 80
+''')[0])
+
+        mappings = self.mappings()
+        assert sorted(mappings.keys()) == [ 'test-0-0', 'test-default-backend' ]
+        assert mappings['test-default-backend'].service == 'fallback.default:8080'
+        assert mappings['test-0-0'].prefix == '/legacy/'
+        assert mappings['test-0-0'].service == 'legacy.default:80'
+        assert 'timeout_ms' not in mappings['test-0-0']
+
+    def test_nginx_annotations(self):
+        self.fetcher.handle_k8s(parse_yaml('''
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: nginx
+  namespace: default
+  annotations:
+    kubernetes.io/ingress.class: ambassador
+    nginx.ingress.kubernetes.io/rewrite-target: /$2
+    nginx.ingress.kubernetes.io/ssl-redirect: "true"
+    nginx.ingress.kubernetes.io/proxy-body-size: 8m
+    nginx.ingress.kubernetes.io/affinity: cookie
+    nginx.ingress.kubernetes.io/session-cookie-name: sticky
+    nginx.ingress.kubernetes.io/session-cookie-max-age: "3600"
+    nginx.ingress.kubernetes.io/enable-cors: "true"
+spec:
+  tls:
+  - hosts:
+    - example.com
+    secretName: example-tls
+  rules:
+  - http:
+      paths:
+      - path: /api(/|$)(.*)
+        pathType: Prefix
+        backend:
+          service:
+            name: api
+            port:
+              number: 80
+''')[0])
+
+        mappings = self.mappings()
+        assert sorted(mappings.keys()) == [ 'nginx-0-0' ]
+
+        # A rewrite-target with capture groups makes the path a regex.
+        mapping = mappings['nginx-0-0']
+        assert mapping.prefix == '/api(/|$)(.*)'
+        assert mapping.prefix_regex
+        assert mapping.regex_rewrite == { 'pattern': '/api(/|$)(.*)', 'substitution': '/\\2' }
+
+        assert mapping.load_balancer == {
+            'policy': 'ring_hash',
+            'cookie': { 'name': 'sticky', 'ttl': '3600s' }
+        }
+        assert mapping.resolver == 'kubernetes-endpoint'
+
+        hosts = [ e for e in self.fetcher.elements if e.kind == 'Host' ]
+        assert len(hosts) == 1
+        assert hosts[0].requestPolicy['insecure']['action'] == 'Redirect'
+
+        # Annotations without an equivalent get an Event each.
+        events = self.fetcher.aconf.k8s_events
+        assert [ (kind, name, reason) for kind, name, namespace, reason, message in events ] == [
+            ('Ingress', 'nginx', 'UnsupportedAnnotation'),
+            ('Ingress', 'nginx', 'UnsupportedAnnotation')
+        ]
+        assert events[0][4].startswith('nginx.ingress.kubernetes.io/enable-cors:')
+        assert events[1][4].startswith('nginx.ingress.kubernetes.io/proxy-body-size:')
+
+    def test_nginx_rewrite_target(self):
+        self.fetcher.handle_k8s(parse_yaml('''
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: nginx
+  namespace: default
+  annotations:
+    kubernetes.io/ingress.class: ambassador
+    nginx.ingress.kubernetes.io/rewrite-target: /
+    nginx.ingress.kubernetes.io/proxy-body-size: "0"
+spec:
+  rules:
+  - http:
+      paths:
+      - path: /api
+        pathType: ImplementationSpecific
+        backend:
+          service:
+            name: api
+            port:
+              number: 80
+''')[0])
+
+        # Without capture groups, rewrite-target is a plain prefix rewrite.
+        mapping = self.mappings()['nginx-0-0']
+        assert mapping.prefix == '/api'
+        assert mapping.rewrite == '/'
+        assert 'regex_rewrite' not in mapping
+        assert not self.fetcher.aconf.k8s_events
+
+
+class TestRouteDelegation:
+
+    def fetch(self, yaml: str) -> ResourceFetcher:
+        fetcher = ResourceFetcher(logger, Config(), skip_init_dir=True)
+        fetcher.parse_yaml(yaml, k8s=True)
+
+        return fetcher
+
+    delegation = '''
+---
+apiVersion: getambassador.io/v2
+kind: RouteDelegation
+metadata:
+  name: shop
+  namespace: platform
+spec:
+  hostname: shop.example.com
+  prefix: /cart/
+  namespaces: [ cart ]
+'''
+
+    def mapping(self, name: str, namespace: str, prefix: str, host: str='shop.example.com') -> str:
+        return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: {name}
+  namespace: {namespace}
+spec:
+  host: {host}
+  prefix: {prefix}
+  service: {name}.{namespace}
+'''
+
+    def test_delegated_prefixes(self):
+        fetcher = self.fetch(self.delegation +
+                             self.mapping('cart', 'cart', '/cart/items/') +
+                             self.mapping('sneaky', 'cart', '/checkout/') +
+                             self.mapping('outsider', 'other', '/cart/') +
+                             self.mapping('frontend', 'platform', '/') +
+                             self.mapping('elsewhere', 'other', '/', host='other.example.com'))
+
+        mappings = sorted(e.name for e in fetcher.elements if e.kind == 'Mapping')
+        assert mappings == [ 'cart', 'elsewhere', 'frontend' ]
+
+        events = { name: (kind, namespace, reason, message)
+                   for kind, name, namespace, reason, message in fetcher.aconf.k8s_events }
+        assert sorted(events.keys()) == [ 'outsider', 'sneaky' ]
+        assert events['sneaky'][:3] == ('Mapping', 'cart', 'DelegationViolation')
+        assert '/checkout/ is not under the prefixes delegated to namespace cart' in events['sneaky'][3]
+        assert 'no RouteDelegation delegates shop.example.com to namespace other' in events['outsider'][3]
+
+    def test_hosts(self):
+        fetcher = self.fetch(self.delegation + '''
+---
+apiVersion: getambassador.io/v2
+kind: Host
+metadata:
+  name: shop
+  namespace: platform
+spec:
+  hostname: shop.example.com
+---
+apiVersion: getambassador.io/v2
+kind: Host
+metadata:
+  name: shop
+  namespace: cart
+spec:
+  hostname: shop.example.com
+''')
+
+        hosts = [ e.namespace for e in fetcher.elements if e.kind == 'Host' ]
+        assert hosts == [ 'platform' ]
+
+    def test_no_delegations(self):
+        fetcher = self.fetch(self.mapping('cart', 'cart', '/checkout/'))
+
+        assert [ e.name for e in fetcher.elements if e.kind == 'Mapping' ] == [ 'cart' ]
+        assert not fetcher.aconf.k8s_events
+
+
 if __name__ == '__main__':
     pytest.main(sys.argv)
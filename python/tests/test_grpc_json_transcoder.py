@@ -0,0 +1,107 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_http_filters
+
+mappings = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: bookstore
+  namespace: default
+spec:
+  prefix: /bookstore/
+  service: bookstore:8080
+  grpc: true
+'''
+
+def _module(config):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    grpc_json_transcoder:
+{config}
+'''
+
+def _transcoder(econf):
+    for f in get_http_filters(econf):
+        if f['name'] == 'envoy.filters.http.grpc_json_transcoder':
+            return f
+
+def test_transcoder_file():
+    ir, econf = get_envoy_config(mappings + _module("""
+      proto_descriptor: /etc/protos/bookstore.pb
+      services:
+      - bookstore.Bookstore
+      print_options:
+        add_whitespace: true
+      convert_grpc_status: true
+      not_a_transcoder_setting: true
+"""))
+
+    assert get_errors(ir) == []
+
+    assert _transcoder(econf) == {
+        'name': 'envoy.filters.http.grpc_json_transcoder',
+        'config': {
+            'proto_descriptor': '/etc/protos/bookstore.pb',
+            'services': [ 'bookstore.Bookstore' ],
+            'print_options': { 'add_whitespace': True },
+            'convert_grpc_status': True
+        }
+    }
+
+
+def test_transcoder_inline():
+    ir, econf = get_envoy_config(mappings + _module("""
+      proto_descriptor_bin: Cg5ib29rc3RvcmUucHJvdG8=
+      services:
+      - bookstore.Bookstore
+"""))
+
+    assert get_errors(ir) == []
+
+    config = _transcoder(econf)['config']
+    assert config['proto_descriptor_bin'] == 'Cg5ib29rc3RvcmUucHJvdG8='
+    assert 'proto_descriptor' not in config
+
+
+def test_transcoder_errors():
+    for config, error in [
+        ("""
+      proto_descriptor: /etc/protos/bookstore.pb
+      proto_descriptor_bin: Cg5ib29rc3RvcmUucHJvdG8=
+      services:
+      - bookstore.Bookstore
+""", 'exactly one of proto_descriptor or proto_descriptor_bin'),
+        ("""
+      services:
+      - bookstore.Bookstore
+""", 'exactly one of proto_descriptor or proto_descriptor_bin'),
+        ("""
+      proto_descriptor: /etc/protos/bookstore.pb
+""", 'at least one service'),
+    ]:
+        ir, econf = get_envoy_config(mappings + _module(config))
+
+        assert any(error in e for e in get_errors(ir)), config
+        assert _transcoder(econf) is None
+
+
+def test_no_transcoder():
+    ir, econf = get_envoy_config(mappings)
+
+    assert get_errors(ir) == []
+    assert _transcoder(econf) is None
@@ -0,0 +1,174 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_clusters
+
+def _mapping(name, spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: {name}
+  namespace: default
+spec:
+  prefix: /{name}/
+  service: {name}
+{spec}
+'''
+
+def _module(spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+{spec}
+'''
+
+def test_http():
+    ir, econf = get_envoy_config(_mapping('quote', '''
+  health_check:
+    timeout_ms: 500
+    interval_ms: 5000
+    unhealthy_threshold: 3
+    healthy_threshold: 2
+    http:
+      path: /healthz
+      host: quote.example.com
+      expected_statuses:
+      - min: 200
+        max: 299
+      - min: 418
+        max: 418
+'''))
+
+    assert get_errors(ir) == []
+
+    clusters = [ c for name, c in get_clusters(econf).items() if name.startswith('cluster_quote_default_hc') ]
+    assert len(clusters) == 1
+
+    # Our status ranges include max, but Envoy's stop just short of end.
+    assert clusters[0]['health_checks'] == [
+        {
+            'timeout': '0.500s',
+            'interval': '5.000s',
+            'unhealthy_threshold': 3,
+            'healthy_threshold': 2,
+            'http_health_check': {
+                'path': '/healthz',
+                'host': 'quote.example.com',
+                'expected_statuses': [
+                    { 'start': 200, 'end': 300 },
+                    { 'start': 418, 'end': 419 }
+                ]
+            }
+        }
+    ]
+
+
+def test_tcp_and_grpc():
+    ir, econf = get_envoy_config(_mapping('db', '''
+  health_check:
+    tcp: {}
+''') + _mapping('rpc', '''
+  grpc: true
+  health_check:
+    grpc:
+      service_name: rpc.Health
+'''))
+
+    assert get_errors(ir) == []
+
+    clusters = get_clusters(econf)
+    db = [ c for name, c in clusters.items() if name.startswith('cluster_db_default_hc') ][0]
+    rpc = [ c for name, c in clusters.items() if name.startswith('cluster_rpc_default_hc') ][0]
+
+    assert db['health_checks'] == [
+        {
+            'timeout': '1.000s',
+            'interval': '10.000s',
+            'unhealthy_threshold': 2,
+            'healthy_threshold': 1,
+            'tcp_health_check': {}
+        }
+    ]
+
+    assert rpc['health_checks'][0]['grpc_health_check'] == { 'service_name': 'rpc.Health' }
+
+
+def test_different_checks_do_not_share_a_cluster():
+    ir, econf = get_envoy_config(_mapping('quote', '''
+  health_check:
+    tcp: {}
+''') + '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote-http
+  namespace: default
+spec:
+  prefix: /quote-http/
+  service: quote
+  health_check:
+    http:
+      path: /healthz
+''')
+
+    assert get_errors(ir) == []
+
+    names = [ name for name in get_clusters(econf).keys() if name.startswith('cluster_quote_default') ]
+    assert len(names) == 2
+
+
+def test_module_default():
+    ir, econf = get_envoy_config(_mapping('quote', '') + _mapping('db', '''
+  health_check:
+    tcp: {}
+''') + _module('''
+    health_check:
+      http:
+        path: /healthz
+'''))
+
+    assert get_errors(ir) == []
+
+    clusters = get_clusters(econf)
+
+    # The Module's health check is the same for everyone, so it doesn't rename the cluster.
+    assert clusters['cluster_quote_default']['health_checks'][0]['http_health_check'] == { 'path': '/healthz' }
+
+    # A Mapping's own health check wins.
+    db = [ c for name, c in clusters.items() if name.startswith('cluster_db_default_hc') ][0]
+    assert 'tcp_health_check' in db['health_checks'][0]
+
+    # Ambassador's own Mappings don't get health checked.
+    assert 'health_checks' not in clusters['cluster_127_0_0_1_8877_default']
+
+
+def test_invalid():
+    ir, econf = get_envoy_config(_mapping('quote', '''
+  health_check:
+    http:
+      path: /healthz
+      expected_statuses:
+      - min: 300
+        max: 200
+'''))
+
+    assert get_errors(ir) == [
+        "Invalid health_check specified: expected_statuses needs min and max between 100 and 599: "
+        "{'min': 300, 'max': 200}, invalidating mapping"
+    ]
+
+    assert not any(name.startswith('cluster_quote_default') for name in get_clusters(econf).keys())
@@ -0,0 +1,53 @@
+import sys
+
+import pytest
+
+from ambassador.ir.irstatus import condition, stamp_conditions
+
+THEN = '2020-10-01T00:00:00Z'
+NOW = '2020-10-02T00:00:00Z'
+
+
+def test_condition():
+    assert condition('Ready', True, 'Ready', generation=3) == {
+        'type': 'Ready', 'status': 'True', 'reason': 'Ready', 'observedGeneration': 3
+    }
+
+    assert condition('Accepted', False, 'Invalid', 'no service') == {
+        'type': 'Accepted', 'status': 'False', 'reason': 'Invalid', 'message': 'no service'
+    }
+
+
+def test_stamp_conditions():
+    status = {
+        'state': 'Running',
+        'conditions': [
+            condition('Accepted', True, 'Accepted', generation=2),
+            condition('Ready', True, 'Ready', generation=2)
+        ]
+    }
+
+    # Nothing to go on: everything is new.
+    stamped = stamp_conditions(status, None, NOW)
+    assert [ c['lastTransitionTime'] for c in stamped['conditions'] ] == [ NOW, NOW ]
+    assert stamped['state'] == 'Running'
+    assert 'lastTransitionTime' not in status['conditions'][0]
+
+    # Accepted didn't change, so it keeps its time; Ready did.
+    previous = {
+        'conditions': [
+            dict(condition('Accepted', True, 'Accepted', generation=1), lastTransitionTime=THEN),
+            dict(condition('Ready', False, 'NotProgrammed', generation=1), lastTransitionTime=THEN)
+        ]
+    }
+
+    stamped = stamp_conditions(status, previous, NOW)
+    assert [ c['lastTransitionTime'] for c in stamped['conditions'] ] == [ THEN, NOW ]
+    assert stamped['conditions'][0]['observedGeneration'] == 2
+
+    # A status without conditions is left alone.
+    assert stamp_conditions({ 'state': 'Running' }, previous, NOW) == { 'state': 'Running' }
+
+
+if __name__ == '__main__':
+    pytest.main(sys.argv)
@@ -0,0 +1,183 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config
+
+mappings = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: mesh
+  namespace: default
+spec:
+  prefix: /mesh/
+  service: mesh
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: outside
+  namespace: default
+spec:
+  prefix: /outside/
+  service: outside
+  istio_mtls: false
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: asked
+  namespace: default
+spec:
+  prefix: /asked/
+  service: asked
+  istio_mtls: true
+'''
+
+def _module(config):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    istio_mtls: {config}
+'''
+
+def _tls(econf, service):
+    for cluster in econf.as_dict()['static_resources']['clusters']:
+        if cluster['name'].startswith(f'cluster_{service}_'):
+            socket = cluster.get('transport_socket', None)
+
+            return socket['typed_config'] if socket else None
+
+    assert False, f'no cluster for {service}'
+
+def _bootstrap_clusters(econf):
+    return { c['name']: c for c in econf.bootstrap['static_resources']['clusters'] }
+
+
+def test_istio_mtls_files():
+    ir, econf = get_envoy_config(_module('{ enabled: true, cert_dir: /etc/istio-output-certs/ }') + mappings)
+
+    common = _tls(econf, 'mesh')['common_tls_context']
+    assert common['alpn_protocols'] == [ 'istio-peer-exchange,istio' ]
+    assert common['tls_certificates'] == [ {
+        'certificate_chain': { 'filename': '/etc/istio-output-certs/cert-chain.pem' },
+        'private_key': { 'filename': '/etc/istio-output-certs/key.pem' },
+    } ]
+    assert common['validation_context'] == {
+        'trusted_ca': { 'filename': '/etc/istio-output-certs/root-cert.pem' }
+    }
+
+    # A Mapping can opt out of the Module's default.
+    assert _tls(econf, 'outside') is None
+    assert _tls(econf, 'asked') == _tls(econf, 'mesh')
+
+    # Ambassador's own probe and diagnostics Mappings never go into the mesh.
+    assert _tls(econf, '127_0_0_1_8877') is None
+
+    assert 'sds-grpc' not in _bootstrap_clusters(econf)
+
+
+def test_istio_mtls_per_mapping():
+    # Without a Module turning it on, only the Mapping that asks gets Istio mTLS, from the
+    # default cert_dir.
+    ir, econf = get_envoy_config(mappings)
+
+    assert _tls(econf, 'mesh') is None
+    assert _tls(econf, 'outside') is None
+
+    common = _tls(econf, 'asked')['common_tls_context']
+    assert common['tls_certificates'][0]['certificate_chain'] == { 'filename': '/etc/istio-certs/cert-chain.pem' }
+
+
+def test_istio_mtls_sds():
+    ir, econf = get_envoy_config(_module('{ enabled: true, sds_socket: /etc/istio/proxy/SDS }') + mappings)
+
+    sds_config = {
+        'api_config_source': {
+            'api_type': 'GRPC',
+            'grpc_services': [ { 'envoy_grpc': { 'cluster_name': 'sds-grpc' } } ]
+        }
+    }
+
+    common = _tls(econf, 'mesh')['common_tls_context']
+    assert common['alpn_protocols'] == [ 'istio-peer-exchange,istio' ]
+    assert 'tls_certificates' not in common
+    assert common['tls_certificate_sds_secret_configs'] == [ { 'name': 'default', 'sds_config': sds_config } ]
+    assert common['combined_validation_context'] == {
+        'default_validation_context': {},
+        'validation_context_sds_secret_config': { 'name': 'ROOTCA', 'sds_config': sds_config }
+    }
+
+    sds = _bootstrap_clusters(econf)['sds-grpc']
+    assert sds['http2_protocol_options'] == {}
+    assert sds['load_assignment']['endpoints'][0]['lb_endpoints'][0]['endpoint']['address'] == {
+        'pipe': { 'path': '/etc/istio/proxy/SDS' }
+    }
+
+
+def test_istio_mtls_tls_wins():
+    yaml = mappings + '''
+---
+apiVersion: getambassador.io/v2
+kind: TLSContext
+metadata:
+  name: own
+  namespace: default
+spec:
+  cert_chain_file: /certs/own.pem
+  private_key_file: /certs/own.key
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: own
+  namespace: default
+spec:
+  prefix: /own/
+  service: own
+  tls: own
+  istio_mtls: true
+'''
+
+    ir, econf = get_envoy_config(yaml)
+
+    common = _tls(econf, 'own')['common_tls_context']
+    assert common['tls_certificates'][0]['certificate_chain'] == { 'filename': '/certs/own.pem' }
+    assert 'alpn_protocols' not in common
+
+
+def test_istio_mtls_missing_certs():
+    # Without the certificates, Mappings that want Istio mTLS fail closed instead of sending
+    # cleartext into the mesh.
+    ir, econf = get_envoy_config(_module('{ enabled: true }') + mappings,
+                                  file_checker=lambda path: not path.startswith('/etc/istio-certs/'))
+
+    assert not ir.get_tls_context('istio-mtls')
+
+    errors = ir.aconf.errors
+    assert any('could not use the Istio certificates' in e['error']
+               for errs in errors.values() for e in errs), errors
+
+    clusters = [ c['name'] for c in econf.as_dict()['static_resources']['clusters'] ]
+    assert not any(c.startswith('cluster_mesh_') for c in clusters)
+    assert any(c.startswith('cluster_outside_') for c in clusters)
+
+
+def test_istio_mtls_conflict():
+    ir, econf = get_envoy_config(_module('{ cert_dir: /certs, sds_socket: /etc/istio/proxy/SDS }') + mappings)
+
+    errors = ir.aconf.errors
+    assert any('cert_dir and sds_socket may not both be set' in e['error']
+               for errs in errors.values() for e in errs), errors
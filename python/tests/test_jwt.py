@@ -0,0 +1,159 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_routes, get_http_filters
+
+module = '''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    jwt:
+      providers:
+      - name: auth0
+        issuer: https://example.auth0.com/
+        jwks_uri: https://example.auth0.com/.well-known/jwks.json
+      - name: okta
+        jwks_uri: https://example.okta.com/oauth2/v1/keys
+'''
+
+def _mapping(name, spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: {name}
+  namespace: default
+spec:
+  service: {name}
+{spec}
+'''
+
+def _jwt_filter(econf):
+    for f in get_http_filters(econf):
+        if f['name'] == 'envoy.filters.http.jwt_authn':
+            return f
+
+def _rules(econf, prefix):
+    return [ r for r in _jwt_filter(econf)['config']['rules'] if r['match'].get('prefix') == prefix ]
+
+
+def test_jwt_rules():
+    ir, econf = get_envoy_config(module +
+                                  _mapping('protected', '''
+  prefix: /api/
+  jwt_requirement:
+    providers: [ auth0, okta ]
+''') +
+                                  _mapping('open', '''
+  prefix: /open/
+'''))
+
+    assert get_errors(ir) == []
+
+    jwt = _jwt_filter(econf)['config']
+    assert sorted(jwt['providers'].keys()) == [ 'auth0', 'okta' ]
+    assert jwt['providers']['auth0']['issuer'] == 'https://example.auth0.com/'
+
+    assert _rules(econf, '/api/') == [ {
+        'match': { 'prefix': '/api/' },
+        'requires': {
+            'requires_any': {
+                'requirements': [ { 'provider_name': 'auth0' }, { 'provider_name': 'okta' } ]
+            }
+        }
+    } ]
+
+    # A Mapping without a jwt_requirement still gets a rule, with no requirement.
+    assert _rules(econf, '/open/') == [ { 'match': { 'prefix': '/open/' } } ]
+
+
+def test_jwt_rules_match_host_headers_and_method():
+    ir, econf = get_envoy_config(module +
+                                  _mapping('api-a', '''
+  prefix: /api/
+  host: a.example.com
+  jwt_requirement:
+    providers: [ auth0 ]
+''') +
+                                  _mapping('api-b', '''
+  prefix: /api/
+  host: b.example.com
+''') +
+                                  _mapping('api-post', '''
+  prefix: /api/
+  method: POST
+  headers:
+    x-tenant: acme
+  jwt_requirement:
+    providers: [ okta ]
+'''))
+
+    assert get_errors(ir) == []
+
+    rules = { tuple(sorted((h['name'], h.get('exact_match')) for h in r['match'].get('headers', []))): r
+              for r in _rules(econf, '/api/') }
+
+    assert len(rules) == 3
+
+    # Same prefix, different hosts: only a.example.com needs a JWT.
+    assert rules[((':authority', 'a.example.com'),)]['requires'] == { 'provider_name': 'auth0' }
+    assert 'requires' not in rules[((':authority', 'b.example.com'),)]
+
+    assert rules[((':method', 'POST'), ('x-tenant', 'acme'))]['requires'] == { 'provider_name': 'okta' }
+
+
+def test_jwt_requirement_unknown_provider():
+    ir, econf = get_envoy_config(module +
+                                  _mapping('protected', '''
+  prefix: /api/
+  jwt_requirement:
+    providers: [ auth0, google ]
+'''))
+
+    errors = get_errors(ir)
+    assert len(errors) == 1
+    assert 'unknown jwt provider google' in errors[0]
+
+    # The Mapping is dropped, rather than handing Envoy a provider it doesn't have.
+    assert '/api/' not in get_routes(econf)
+    assert _rules(econf, '/api/') == []
+
+
+def test_jwt_requirement_without_providers():
+    ir, econf = get_envoy_config(module +
+                                  _mapping('protected', '''
+  prefix: /api/
+  jwt_requirement:
+    allow_missing: true
+'''))
+
+    errors = get_errors(ir)
+    assert len(errors) == 1
+    assert 'requires at least one provider' in errors[0]
+    assert '/api/' not in get_routes(econf)
+
+
+def test_jwt_requirement_without_module():
+    ir, econf = get_envoy_config(_mapping('protected', '''
+  prefix: /api/
+  jwt_requirement:
+    providers: [ auth0 ]
+'''))
+
+    errors = get_errors(ir)
+    assert len(errors) == 1
+    assert 'JWT validation is not configured' in errors[0]
+
+    assert '/api/' not in get_routes(econf)
+    assert _jwt_filter(econf) is None
@@ -162,6 +162,99 @@ def test_knative_counters():
     assert feats['cluster_ingress_count'] == 0, f"Expected no Knative cluster ingresses, found at least one"
 
 
+knative_ingress_contract_example = """
+apiVersion: networking.internal.knative.dev/v1alpha1
+kind: Ingress
+metadata:
+  name: helloworld-go
+  namespace: default
+  generation: 3
+spec:
+  httpOption: Redirected
+  tls:
+  - hosts:
+    - helloworld-go.default.example.com
+    secretName: route-tls
+    secretNamespace: knative-serving
+  rules:
+  - hosts:
+    - helloworld-go.default.example.com
+    http:
+      paths:
+      - headers:
+          Knative-Serving-Tag:
+            exact: canary
+        rewriteHost: canary-helloworld-go.default.example.com
+        splits:
+        - percent: 100
+          serviceName: helloworld-go-canary
+          servicePort: 80
+      - splits:
+        - percent: 90
+          serviceName: helloworld-go-qf94m
+          servicePort: 80
+        - percent: 10
+          serviceName: helloworld-go-r5d2k
+          servicePort: 80
+    visibility: ExternalIP
+"""
+
+
+def fetch_knative(ingress_yaml: str):
+    aconf = Config()
+    fetcher = ResourceFetcher(logger, aconf)
+    fetcher.parse_yaml(ingress_yaml, k8s=True)
+
+    return aconf, fetcher
+
+
+def test_knative_ingress_contract():
+    aconf, fetcher = fetch_knative(knative_ingress_contract_example)
+
+    mappings = [ e for e in fetcher.elements if e.kind == 'Mapping' ]
+    assert len(mappings) == 3
+
+    # Every Mapping stamps probes with the hash of the Ingress.
+    hashes = { m.add_request_headers['K-Network-Hash']['value'] for m in mappings }
+    assert len(hashes) == 1
+    assert all(not m.add_request_headers['K-Network-Hash']['append'] for m in mappings)
+
+    canary = [ m for m in mappings if m.service == 'helloworld-go-canary.default:80' ][0]
+    assert canary.headers == { 'Knative-Serving-Tag': 'canary' }
+    assert canary.host_rewrite == 'canary-helloworld-go.default.example.com'
+
+    assert sorted(m.weight for m in mappings if 'headers' not in m) == [ 10, 90 ]
+
+    hosts = [ e for e in fetcher.elements if e.kind == 'Host' ]
+    assert len(hosts) == 1
+    assert hosts[0].namespace == 'knative-serving'
+    assert hosts[0].tlsSecret == { 'name': 'route-tls' }
+    assert hosts[0].requestPolicy['insecure']['action'] == 'Redirect'
+
+    kind, namespace, status = aconf.k8s_status_updates['helloworld-go.default']
+    assert status['observedGeneration'] == 3
+    assert { c['type']: c['status'] for c in status['conditions'] } == {
+        'LoadBalancerReady': 'True',
+        'NetworkConfigured': 'True',
+        'Ready': 'True'
+    }
+
+    # A different spec has a different hash.
+    _, other = fetch_knative(knative_ingress_contract_example.replace('percent: 90', 'percent: 80'))
+    assert { m.add_request_headers['K-Network-Hash']['value'] for m in other.elements if m.kind == 'Mapping' } != hashes
+
+
+def test_knative_ingress_invalid():
+    aconf, fetcher = fetch_knative(knative_ingress_example.replace('serviceName: helloworld-go-qf94m', 'serviceName: ""'))
+
+    kind, namespace, status = aconf.k8s_status_updates['helloworld-go.default']
+    conditions = { c['type']: c for c in status['conditions'] }
+    assert conditions['NetworkConfigured']['status'] == 'False'
+    assert conditions['NetworkConfigured']['reason'] == 'InvalidIngress'
+    assert 'no serviceName' in conditions['NetworkConfigured']['message']
+    assert conditions['Ready']['status'] == 'False'
+
+
 def test_knative():
     if is_knative():
         knative_test = KnativeTesting()
@@ -0,0 +1,158 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_http_access_logs
+
+mappings = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  prefix: /quote/
+  service: quote
+---
+apiVersion: getambassador.io/v2
+kind: TCPMapping
+metadata:
+  name: db
+  namespace: default
+spec:
+  port: 6789
+  service: db:5432
+'''
+
+def _logservice(spec, name='als'):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: LogService
+metadata:
+  name: {name}
+  namespace: default
+spec:
+  service: als:9001
+{spec}
+'''
+
+def _tcp_access_logs(econf, port):
+    for listener in econf.as_dict()['static_resources']['listeners']:
+        if listener['address']['socket_address']['port_value'] == port:
+            for chain in listener['filter_chains']:
+                for f in chain['filters']:
+                    if f['name'] == 'envoy.tcp_proxy':
+                        return f['config'].get('access_log')
+
+def _grpc_access_logs(access_logs):
+    return [ al for al in access_logs if al['name'] != 'envoy.file_access_log' ]
+
+def test_http_v2_is_default():
+    ir, econf = get_envoy_config(mappings + _logservice('''
+  driver: http
+  driver_config:
+    additional_log_headers:
+    - header_name: x-tenant
+      during_response: false
+'''))
+
+    assert get_errors(ir) == []
+
+    access_logs = _grpc_access_logs(get_http_access_logs(econf))
+    assert len(access_logs) == 1
+    assert access_logs[0]['name'] == 'envoy.http_grpc_access_log'
+    assert 'typed_config' not in access_logs[0]
+
+    config = access_logs[0]['config']
+    assert 'transport_api_version' not in config['common_config']
+    assert config['additional_request_headers_to_log'] == [ 'x-tenant' ]
+    assert config['additional_response_headers_to_log'] == []
+
+    # An http LogService doesn't see TCPMapping connections.
+    assert _tcp_access_logs(econf, 6789) is None
+
+
+def test_http_v3():
+    ir, econf = get_envoy_config(mappings + _logservice('''
+  driver: http
+  protocol_version: v3
+  driver_config: {}
+'''))
+
+    assert get_errors(ir) == []
+
+    access_logs = _grpc_access_logs(get_http_access_logs(econf))
+
+    # As with ext_authz, the v3 transport needs the v3 config, which has to be typed.
+    assert 'config' not in access_logs[0]
+
+    config = access_logs[0]['typed_config']
+    assert config['@type'] == 'type.googleapis.com/envoy.extensions.access_loggers.grpc.v3.HttpGrpcAccessLogConfig'
+    assert config['common_config']['transport_api_version'] == 'V3'
+    assert config['common_config']['grpc_service']['envoy_grpc']['cluster_name'] == 'cluster_logging_als_9001_default'
+
+
+def test_tcp_connections():
+    ir, econf = get_envoy_config(mappings + _logservice('''
+  driver: tcp
+  protocol_version: v3
+  driver_config: {}
+'''))
+
+    assert get_errors(ir) == []
+
+    # A tcp LogService gets the HTTP listener's connections...
+    access_logs = _grpc_access_logs(get_http_access_logs(econf))
+    assert [ al['name'] for al in access_logs ] == [ 'envoy.tcp_grpc_access_log' ]
+
+    # ...and every TCPMapping's, too.
+    tcp_access_logs = _tcp_access_logs(econf, 6789)
+    assert tcp_access_logs == access_logs
+
+    config = tcp_access_logs[0]['typed_config']
+    assert config['@type'] == 'type.googleapis.com/envoy.extensions.access_loggers.grpc.v3.TcpGrpcAccessLogConfig'
+    assert config['common_config']['transport_api_version'] == 'V3'
+    assert 'additional_request_headers_to_log' not in config
+
+
+def test_filter():
+    ir, econf = get_envoy_config(mappings + _logservice('''
+  driver: http
+  driver_config: {}
+  filter:
+    status_code_min: 400
+'''))
+
+    assert get_errors(ir) == []
+
+    access_logs = _grpc_access_logs(get_http_access_logs(econf))
+    assert access_logs[0]['filter'] == {
+        'status_code_filter': {
+            'comparison': {
+                'op': 'GE',
+                'value': { 'default_value': 400, 'runtime_key': 'access_log.status_code_min' }
+            }
+        }
+    }
+
+    # The file access log isn't filtered.
+    assert [ 'filter' in al for al in get_http_access_logs(econf) ] == [ True, False ]
+
+
+def test_filter_invalid():
+    ir, econf = get_envoy_config(mappings + _logservice('''
+  driver: http
+  driver_config: {}
+  filter:
+    status: 400
+'''))
+
+    assert get_errors(ir) == [ 'access log filter: unknown key status' ]
+
+    assert _grpc_access_logs(get_http_access_logs(econf)) == []
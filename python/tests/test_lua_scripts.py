@@ -0,0 +1,150 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_routes, get_http_filters
+
+# The entrypoint has already checked these, and left out bad.lua.
+config_map = '''
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: lua
+  namespace: default
+  labels:
+    getambassador.io/lua-scripts: "true"
+  annotations:
+    getambassador.io/lua-script-errors: '{"bad.lua": "bad.lua:2: ''end'' expected (to close ''function'' at line 1) near ''<eof>''"}'
+data:
+  default.lua: |
+    function envoy_on_request(request_handle)
+      request_handle:headers():add("x-lua", "default")
+    end
+  quote.lua: |
+    function envoy_on_request(request_handle)
+      request_handle:headers():add("x-lua", "quote")
+    end
+'''
+
+mappings = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  prefix: /quote/
+  service: quote
+  lua_script:
+    config_map: lua
+    key: quote.lua
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: health
+  namespace: default
+spec:
+  prefix: /health/
+  service: health
+  bypass_lua: true
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: other
+  namespace: default
+spec:
+  prefix: /other/
+  service: other
+'''
+
+module = '''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    lua_script:
+      config_map: lua
+      key: default.lua
+'''
+
+def _lua_filter(econf):
+    for f in get_http_filters(econf):
+        if f['name'] == 'envoy.lua':
+            return f
+
+def test_lua_scripts():
+    ir, econf = get_envoy_config(config_map + module + mappings)
+
+    assert get_errors(ir) == []
+
+    lua = _lua_filter(econf)['typed_config']
+    assert lua['@type'] == 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua'
+    assert '"default"' in lua['inline_code']
+    assert list(lua['source_codes'].keys()) == [ 'lua.default/quote.lua' ]
+    assert '"quote"' in lua['source_codes']['lua.default/quote.lua']['inline_string']
+
+    routes = get_routes(econf)
+    assert routes['/quote/']['typed_per_filter_config']['envoy.lua'] == {
+        '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+        'name': 'lua.default/quote.lua'
+    }
+    assert routes['/health/']['typed_per_filter_config']['envoy.lua'] == {
+        '@type': 'type.googleapis.com/envoy.extensions.filters.http.lua.v3.LuaPerRoute',
+        'disabled': True
+    }
+    assert 'typed_per_filter_config' not in routes['/other/']
+
+
+def test_lua_scripts_without_module_script():
+    # Without a script in the Module, only the Mappings with scripts run one.
+    ir, econf = get_envoy_config(config_map + mappings)
+
+    assert get_errors(ir) == []
+
+    lua = _lua_filter(econf)['typed_config']
+    assert lua['inline_code'] == '-- No Lua script for this route.\n'
+    assert list(lua['source_codes'].keys()) == [ 'lua.default/quote.lua' ]
+
+
+def test_lua_scripts_inline():
+    # The inline lua_scripts work as they always have, and no Lua filter without any script.
+    ir, econf = get_envoy_config(module.replace('''    lua_script:
+      config_map: lua
+      key: default.lua''', '''    lua_scripts: |
+      function envoy_on_response(response_handle) end'''))
+
+    assert get_errors(ir) == []
+    assert _lua_filter(econf)['config'] == { 'inline_code': 'function envoy_on_response(response_handle) end\n' }
+
+    ir, econf = get_envoy_config(mappings.replace('''  lua_script:
+    config_map: lua
+    key: quote.lua
+''', ''))
+    assert _lua_filter(econf) is None
+
+
+def test_lua_script_errors():
+    broken = mappings.replace('key: quote.lua', 'key: bad.lua')
+    ir, econf = get_envoy_config(config_map + broken)
+    assert any("lua_script: bad.lua in ConfigMap lua does not parse: bad.lua:2: 'end' expected" in e
+               for e in get_errors(ir))
+    assert '/quote/' not in get_routes(econf)
+
+    missing = mappings.replace('key: quote.lua', 'key: missing.lua')
+    ir, econf = get_envoy_config(config_map + missing)
+    assert any('lua_script: ConfigMap lua has no key missing.lua' in e for e in get_errors(ir))
+
+    ir, econf = get_envoy_config(mappings)
+    assert any('lua_script: no ConfigMap lua in namespace default' in e for e in get_errors(ir))
@@ -0,0 +1,116 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors
+
+def _mapping(name, spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: {name}
+  namespace: default
+spec:
+{spec}
+'''
+
+def _virtual_clusters(econf):
+    for listener in econf.as_dict()['static_resources']['listeners']:
+        for chain in listener['filter_chains']:
+            for f in chain['filters']:
+                if f['name'] == 'envoy.http_connection_manager':
+                    vhost = f['typed_config']['route_config']['virtual_hosts'][0]
+                    return { vc['name']: vc for vc in vhost.get('virtual_clusters', []) }
+
+def test_stats_name():
+    ir, econf = get_envoy_config(_mapping('quote', '''
+  prefix: /quote/
+  service: quote
+  stats_name: quote.api
+  headers:
+    x-tenant: acme
+''') + _mapping('other', '''
+  prefix: /other/
+  service: other
+'''))
+
+    assert get_errors(ir) == []
+
+    # The virtual cluster matches what the route matches, with the path as a :path header.
+    # Envoy doesn't allow dots in the name.
+    assert _virtual_clusters(econf) == {
+        'quote_api': {
+            'name': 'quote_api',
+            'headers': [
+                { 'name': ':path', 'prefix_match': '/quote/' },
+                { 'name': 'x-tenant', 'exact_match': 'acme' }
+            ]
+        }
+    }
+
+    assert econf.mapping_stats == {
+        'quote.default': [ 'vhost.ambassador-listener-8080-*.vcluster.quote_api.' ]
+    }
+
+
+def test_per_mapping_stats():
+    ir, econf = get_envoy_config(_mapping('quote', '''
+  prefix: /quote/
+  service: quote
+''') + _mapping('quote-canary', '''
+  prefix: /quote/
+  service: quote-canary
+  weight: 10
+''') + _mapping('regex', '''
+  prefix: "/re/[0-9]+"
+  prefix_regex: true
+  service: regex
+''') + '''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    per_mapping_stats: true
+''')
+
+    assert get_errors(ir) == []
+
+    virtual_clusters = _virtual_clusters(econf)
+
+    assert virtual_clusters['regex_default']['headers'] == [
+        {
+            'name': ':path',
+            'safe_regex_match': { 'google_re2': { 'max_program_size': 200 }, 'regex': '/re/[0-9]+' }
+        }
+    ]
+
+    # Mappings in the same group share a route match, so they share a virtual cluster,
+    # named for the group's first Mapping.
+    assert virtual_clusters['quote-canary_default']['headers'] == [ { 'name': ':path', 'prefix_match': '/quote/' } ]
+    assert 'quote_default' not in virtual_clusters
+
+    prefix = 'vhost.ambassador-listener-8080-*.vcluster.quote-canary_default.'
+    assert econf.mapping_stats['quote.default'] == [ prefix ]
+    assert econf.mapping_stats['quote-canary.default'] == [ prefix ]
+
+
+def test_no_stats():
+    ir, econf = get_envoy_config(_mapping('quote', '''
+  prefix: /quote/
+  service: quote
+'''))
+
+    assert get_errors(ir) == []
+
+    assert _virtual_clusters(econf) == {}
+    assert econf.mapping_stats == {}
@@ -0,0 +1,54 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_clusters, get_http_filters
+
+def _ratelimitservice(spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: RateLimitService
+metadata:
+  name: rls
+  namespace: default
+spec:
+  service: rls:8081
+{spec}
+'''
+
+def _ratelimit_filter(econf):
+    for f in get_http_filters(econf):
+        if f['name'] == 'envoy.rate_limit':
+            return f
+
+def test_failure_mode_deny_and_stat_prefix():
+    ir, econf = get_envoy_config(_ratelimitservice('''
+  failure_mode_deny: true
+  stat_prefix: edge_rls
+  timeout_ms: 50
+'''))
+
+    assert get_errors(ir) == []
+
+    config = _ratelimit_filter(econf)['config']
+    assert config['failure_mode_deny'] == True
+    assert config['timeout'] == '0.050s'
+
+    # The rate limit filter has no stat_prefix of its own, so it names the cluster's stats.
+    cluster = get_clusters(econf)['cluster_rls_8081_default']
+    assert config['rate_limit_service']['grpc_service']['envoy_grpc']['cluster_name'] == cluster['name']
+    assert cluster['alt_stat_name'] == 'edge_rls'
+
+
+def test_defaults():
+    ir, econf = get_envoy_config(_ratelimitservice(''))
+
+    assert get_errors(ir) == []
+
+    assert 'failure_mode_deny' not in _ratelimit_filter(econf)['config']
+    assert 'alt_stat_name' not in get_clusters(econf)['cluster_rls_8081_default']
@@ -0,0 +1,122 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_routes
+
+def _ratelimitservice(spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: RateLimitService
+metadata:
+  name: rls
+  namespace: default
+spec:
+  service: rls:8081
+{spec}
+'''
+
+def _mapping(labels, name='quote'):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: {name}
+  namespace: default
+spec:
+  prefix: /{name}/
+  service: {name}
+  labels:
+    ambassador:
+{labels}
+'''
+
+def _rate_limits(econf, prefix='/quote/'):
+    return get_routes(econf)[prefix]['route'].get('rate_limits', [])
+
+
+def test_labels():
+    ir, econf = get_envoy_config(_ratelimitservice('') + _mapping('''
+    - user:
+      - source_cluster
+      - remote_address
+      - x-user:
+          header: x-user
+    - tier:
+      - gold
+      - generic_key: premium
+'''))
+
+    assert get_errors(ir) == []
+
+    assert _rate_limits(econf) == [
+        {
+            'stage': 0,
+            'actions': [
+                { 'source_cluster': {} },
+                { 'remote_address': {} },
+                { 'request_headers': { 'header_name': 'x-user', 'descriptor_key': 'x-user' } }
+            ]
+        },
+        {
+            'stage': 0,
+            'actions': [
+                { 'generic_key': { 'descriptor_value': 'gold' } },
+                { 'generic_key': { 'descriptor_value': 'premium' } }
+            ]
+        }
+    ]
+
+
+def test_masked_remote_address_shorthand():
+    # A bare string is a generic_key, even one that names an action this Envoy doesn't have.
+    ir, econf = get_envoy_config(_ratelimitservice('') + _mapping('''
+    - subnet:
+      - masked_remote_address
+'''))
+
+    assert get_errors(ir) == []
+
+    assert _rate_limits(econf) == [
+        { 'stage': 0, 'actions': [ { 'generic_key': { 'descriptor_value': 'masked_remote_address' } } ] }
+    ]
+
+
+def test_v3_only_labels():
+    # Labels that need Envoy's v3 route config are reported and dropped, and the rest of
+    # the Mapping's labels still apply.
+    for label, error in [
+        ('''
+      - masked_remote_address:
+          v4_prefix_mask_len: 24
+''', 'masked_remote_address is not supported'),
+        ('''
+      - tenant:
+          metadata:
+            filter: envoy.filters.http.ext_authz
+            path: [ tenant ]
+''', "dynamic metadata label 'tenant' is not supported"),
+        ('''
+      - x-user:
+          header: x-user
+          skip_if_absent: true
+''', "skip_if_absent on label 'x-user' is not supported"),
+    ]:
+        ir, econf = get_envoy_config(_ratelimitservice('') + _mapping('''
+    - bad:
+      - remote_address''' + label + '''
+    - good:
+      - remote_address
+'''))
+
+        errors = get_errors(ir)
+        assert len(errors) == 1, label
+        assert error in errors[0], label
+
+        assert _rate_limits(econf) == [ { 'stage': 0, 'actions': [ { 'remote_address': {} } ] } ], label
@@ -0,0 +1,79 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config
+
+yaml = '''
+---
+apiVersion: getambassador.io/v2
+kind: StaticResolver
+metadata:
+  name: legacy
+  namespace: default
+spec:
+  services:
+  - name: legacy-db
+    endpoints:
+    - address: 10.0.0.1
+      port: 5432
+      weight: 3
+      locality: rack-a
+    - address: 10.0.0.2
+      port: 5432
+      locality: rack-b
+  - name: appliance
+    endpoints:
+    - address: 10.0.1.1
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: legacy-db
+  namespace: default
+spec:
+  prefix: /db/
+  service: legacy-db
+  resolver: legacy
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: appliance
+  namespace: default
+spec:
+  prefix: /appliance/
+  service: appliance:8080
+  resolver: legacy
+'''
+
+def _cluster(econf, prefix):
+    for cluster in econf.clusters:
+        if cluster['name'].startswith(prefix):
+            return cluster
+
+    assert False, f"no cluster {prefix}"
+
+def test_static_resolver():
+    ir, econf = get_envoy_config(yaml)
+
+    db = _cluster(econf, 'cluster_legacy_db')
+    assert db['common_lb_config'] == { 'locality_weighted_lb_config': {} }
+    assert [ (e['locality']['zone'], e['load_balancing_weight']) for e in db['load_assignment']['endpoints'] ] == [
+        ('rack-a', 3), ('rack-b', 1)
+    ]
+    endpoint = db['load_assignment']['endpoints'][0]['lb_endpoints'][0]
+    assert endpoint['endpoint']['address']['socket_address']['address'] == '10.0.0.1'
+    assert endpoint['endpoint']['address']['socket_address']['port_value'] == 5432
+    assert endpoint['load_balancing_weight'] == 3
+
+    # Without a port, the endpoint gets the port of the Mapping.
+    appliance = _cluster(econf, 'cluster_appliance')
+    assert 'common_lb_config' not in appliance
+    endpoint = appliance['load_assignment']['endpoints'][0]['lb_endpoints'][0]
+    assert endpoint['endpoint']['address']['socket_address']['address'] == '10.0.1.1'
+    assert endpoint['endpoint']['address']['socket_address']['port_value'] == 8080
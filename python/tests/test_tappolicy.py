@@ -0,0 +1,142 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors
+
+def _tcpmapping(name, spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: TCPMapping
+metadata:
+  name: {name}
+  namespace: default
+spec:
+  service: db:5432
+{spec}
+'''
+
+def _tappolicy(spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: TapPolicy
+metadata:
+  name: dbtap
+  namespace: default
+spec:
+{spec}
+'''
+
+def _filter_chains(econf, port):
+    for listener in econf.as_dict()['static_resources']['listeners']:
+        if listener['address']['socket_address']['port_value'] == port:
+            return listener['filter_chains']
+
+tap_config = {
+    'match_config': { 'any_match': True },
+    'output_config': {
+        'sinks': [
+            {
+                'format': 'JSON_BODY_AS_STRING',
+                'file_per_tap': { 'path_prefix': '/tmp/ambassador-tap/dbtap.default' }
+            }
+        ],
+        'max_buffered_rx_bytes': 1024,
+        'max_buffered_tx_bytes': 1024
+    }
+}
+
+def _tap(transport_socket):
+    return {
+        'name': 'envoy.transport_sockets.tap',
+        'typed_config': {
+            '@type': 'type.googleapis.com/envoy.config.transport_socket.tap.v2alpha.Tap',
+            'common_config': { 'static_config': tap_config },
+            'transport_socket': transport_socket
+        }
+    }
+
+
+def test_tap_tcp_mappings():
+    ir, econf = get_envoy_config(_tcpmapping('db', '''
+  port: 6789
+''') + _tcpmapping('untapped', '''
+  port: 6790
+''') + _tappolicy('''
+  tcp_mappings: [ db ]
+  max_buffered_bytes: 1024
+'''))
+
+    assert get_errors(ir) == []
+
+    chains = _filter_chains(econf, 6789)
+    assert chains[0]['transport_socket'] == _tap({ 'name': 'envoy.transport_sockets.raw_buffer' })
+
+    assert 'transport_socket' not in _filter_chains(econf, 6790)[0]
+
+    # The tap is served by TapDS, too.
+    assert econf.tap_resources == { 'dbtap.default': tap_config }
+
+
+def test_tap_tls_tcp_mapping():
+    ir, econf = get_envoy_config(_tcpmapping('db', '''
+  port: 6789
+  host: db.example.com
+''') + '''
+---
+apiVersion: getambassador.io/v2
+kind: TLSContext
+metadata:
+  name: db-tls
+  namespace: default
+spec:
+  hosts: [ db.example.com ]
+  secret: db-cert
+''' + _tappolicy('''
+  tcp_mappings: [ db ]
+  max_buffered_bytes: 1024
+'''))
+
+    assert get_errors(ir) == []
+
+    chain = _filter_chains(econf, 6789)[0]
+
+    # Envoy ignores tls_context once there's a transport_socket, so TLS moves inside
+    # the tap, which then sees the decrypted bytes.
+    assert 'tls_context' not in chain
+
+    transport_socket = chain['transport_socket']
+    assert transport_socket['name'] == 'envoy.transport_sockets.tap'
+
+    tls = transport_socket['typed_config']['transport_socket']
+    assert tls['name'] == 'envoy.transport_sockets.tls'
+    assert tls['typed_config']['@type'] == 'type.googleapis.com/envoy.api.v2.auth.DownstreamTlsContext'
+    assert 'tls_certificates' in tls['typed_config']['common_tls_context']
+
+
+def test_tap_errors():
+    for spec, error in [
+        ('''
+  mappings: [ quote ]
+  tcp_mappings: [ db ]
+''', 'TapPolicy dbtap: can tap Mappings or TCPMappings, but not both'),
+        ('''
+  tcp_mappings: [ db ]
+  match:
+    path_prefix: /
+''', 'TapPolicy dbtap: match only applies to Mappings, not TCPMappings'),
+        ('''
+  tcp_mappings: [ db, missing ]
+''', 'TapPolicy dbtap: no TCPMapping missing in namespace default'),
+    ]:
+        ir, econf = get_envoy_config(_tcpmapping('db', '''
+  port: 6789
+''') + _tappolicy(spec))
+
+        assert get_errors(ir) == [ error ]
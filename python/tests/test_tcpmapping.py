@@ -0,0 +1,83 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors
+
+def _tcpmapping(name, spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: TCPMapping
+metadata:
+  name: {name}
+  namespace: default
+spec:
+  service: db:5432
+{spec}
+'''
+
+def _tcp_filters(econf, port):
+    for listener in econf.as_dict()['static_resources']['listeners']:
+        if listener['address']['socket_address']['port_value'] == port:
+            return [ f for chain in listener['filter_chains'] for f in chain['filters'] ]
+
+def test_connection_rate_limit():
+    ir, econf = get_envoy_config(_tcpmapping('limited', '''
+  port: 6789
+  connection_rate_limit:
+    connections_per_second: 10
+    burst: 20
+''') + _tcpmapping('default-burst', '''
+  port: 6790
+  connection_rate_limit:
+    connections_per_second: 10
+''') + _tcpmapping('unlimited', '''
+  port: 6791
+'''))
+
+    assert get_errors(ir) == []
+
+    # The limit has to come before the tcp_proxy, so connections over it are closed before
+    # anything connects upstream.
+    filters = _tcp_filters(econf, 6789)
+    assert [ f['name'] for f in filters ] == [ 'envoy.filters.network.local_ratelimit', 'envoy.tcp_proxy' ]
+    assert filters[0]['typed_config'] == {
+        '@type': 'type.googleapis.com/envoy.config.filter.network.local_rate_limit.v2alpha.LocalRateLimit',
+        'stat_prefix': 'ingress_tcp_6789',
+        'token_bucket': {
+            'max_tokens': 20,
+            'tokens_per_fill': 10,
+            'fill_interval': '1s'
+        }
+    }
+
+    # Without a burst, the bucket holds one second's worth.
+    filters = _tcp_filters(econf, 6790)
+    assert filters[0]['typed_config']['token_bucket']['max_tokens'] == 10
+
+    assert [ f['name'] for f in _tcp_filters(econf, 6791) ] == [ 'envoy.tcp_proxy' ]
+
+
+def test_connection_rate_limit_mismatch():
+    # The limit belongs to the listener, so TCPMappings on the same port have to agree.
+    ir, econf = get_envoy_config(_tcpmapping('first', '''
+  port: 6789
+  connection_rate_limit:
+    connections_per_second: 10
+''') + _tcpmapping('second', '''
+  port: 6789
+  connection_rate_limit:
+    connections_per_second: 20
+'''))
+
+    errors = get_errors(ir)
+    assert len(errors) == 1
+    assert 'mismatched connection_rate_limit' in errors[0]
+
+    filters = _tcp_filters(econf, 6789)
+    assert filters[0]['typed_config']['token_bucket']['tokens_per_fill'] == 10
@@ -0,0 +1,147 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+logger = logging.getLogger("ambassador")
+
+from ambassador import Config, Diagnostics
+from ambassador.diagnostics import EnvoyStats
+from ambassador.fetch import ResourceFetcher
+from utils import get_envoy_config
+
+namespaces = '''
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: blue
+  annotations:
+    getambassador.io/tenants: "blue, shared"
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: green
+  annotations:
+    getambassador.io/tenants: green
+'''
+
+module = '''
+---
+apiVersion: getambassador.io/v2
+kind: Module
+metadata:
+  name: ambassador
+  namespace: default
+spec:
+  config:
+    per_mapping_stats: true
+'''
+
+def _mapping(name: str, namespace: str, tenant: str=None) -> str:
+    labels = f'''
+  labels:
+    getambassador.io/tenant: {tenant}''' if tenant else ''
+
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: {name}
+  namespace: {namespace}{labels}
+spec:
+  prefix: /{name}/
+  service: {name}.{namespace}
+'''
+
+def _fetch(yaml: str) -> ResourceFetcher:
+    fetcher = ResourceFetcher(logger, Config(), skip_init_dir=True)
+    fetcher.parse_yaml(yaml, k8s=True)
+
+    return fetcher
+
+def _diag(yaml: str) -> Diagnostics:
+    return Diagnostics(*get_envoy_config(yaml))
+
+
+def test_tenant_claims():
+    fetcher = _fetch(namespaces +
+                     _mapping('blue', 'blue', 'blue') +
+                     _mapping('shared', 'blue', 'shared') +
+                     _mapping('sneaky', 'blue', 'green') +
+                     _mapping('nowhere', 'red', 'red') +
+                     _mapping('untenanted', 'red'))
+
+    mappings = sorted(e.name for e in fetcher.elements if e.kind == 'Mapping')
+    assert mappings == [ 'blue', 'shared', 'untenanted' ]
+
+    events = { name: (kind, namespace, reason, message)
+               for kind, name, namespace, reason, message in fetcher.aconf.k8s_events }
+    assert sorted(events.keys()) == [ 'nowhere', 'sneaky' ]
+    assert events['sneaky'] == ('Mapping', 'blue', 'TenantViolation', 'namespace blue does not allow tenant green')
+    assert events['nowhere'] == ('Mapping', 'red', 'TenantViolation', 'namespace red does not allow tenant red')
+
+
+def test_tenant_diagnostics():
+    diag = _diag(namespaces + module +
+                 _mapping('blue', 'blue', 'blue') +
+                 _mapping('green', 'green', 'green') +
+                 _mapping('untenanted', 'green'))
+
+    everything = diag.as_dict()
+    blue = diag.as_dict(tenant='blue')
+
+    def mapping_names(ddict):
+        return sorted(m['name'] for g in ddict['groups'].values() for m in g['mappings'])
+
+    assert 'untenanted' in mapping_names(everything)
+    assert mapping_names(blue) == [ 'blue' ]
+    assert list(blue['mapping_stats'].keys()) == [ 'blue.blue' ]
+    assert list(blue['ambassador_elements'].keys()) == [ 'blue.blue.1' ]
+    assert not blue['ambassador_services']
+
+    # A tenant can only look up what it owns.
+    group_key = list(blue['groups'].keys())[0]
+    green_key = [ k for k in diag.groups.keys() if k not in blue['groups'] and diag.owned_by('green', k) ][0]
+
+    assert diag.owned_by('blue', group_key)
+    assert not diag.owned_by('blue', green_key)
+    assert not diag.owned_by('blue', 'no-such-key')
+
+    class FakeRequest:
+        headers = {}
+
+    routes = diag.overview(FakeRequest(), EnvoyStats(), tenant='blue')['route_info']
+    assert [ r['prefix'] for r in routes ] == [ '/blue/' ]
+
+
+def test_tenant_metrics():
+    diag = _diag(namespaces + module +
+                 _mapping('blue', 'blue', 'blue') +
+                 _mapping('green', 'green', 'green'))
+
+    blue_cluster = diag.clusters[[ k for k in diag.clusters.keys() if k.startswith('cluster_blue_') ][0]].envoy_name
+    green_cluster = diag.clusters[[ k for k in diag.clusters.keys() if k.startswith('cluster_green_') ][0]].envoy_name
+
+    metrics = f'''# TYPE envoy_cluster_upstream_rq_total counter
+envoy_cluster_upstream_rq_total{{envoy_cluster_name="{blue_cluster}"}} 3
+envoy_cluster_upstream_rq_total{{envoy_cluster_name="{green_cluster}"}} 5
+# TYPE envoy_vhost_vcluster_upstream_rq_total counter
+envoy_vhost_vcluster_upstream_rq_total{{envoy_virtual_cluster="green_green",envoy_virtual_host="backend"}} 5
+# TYPE envoy_server_live gauge
+envoy_server_live{{}} 1
+'''
+
+    assert diag.tenant_metrics('blue', metrics) == f'''# TYPE envoy_cluster_upstream_rq_total counter
+envoy_cluster_upstream_rq_total{{envoy_cluster_name="{blue_cluster}"}} 3
+'''
+
+    green = diag.tenant_metrics('green', metrics)
+    assert f'envoy_cluster_name="{green_cluster}"' in green
+    assert 'envoy_virtual_cluster="green_green"' in green
+    assert 'envoy_server_live' not in green
@@ -0,0 +1,46 @@
+import logging
+import re
+
+import pytest
+
+from ambassador.utils import TraceSpans
+
+logger = logging.getLogger("ambassador")
+
+
+def test_server_timing_without_tracing():
+    trace = TraceSpans(logger, None, None)
+
+    assert not trace, "trace must be off without an endpoint"
+
+    with trace.span("diagd.ir"):
+        pass
+
+    with pytest.raises(ValueError):
+        with trace.span("diagd.econf"):
+            raise ValueError("econf failed")
+
+    assert trace.spans == [], "no spans must be recorded"
+
+    timing = trace.server_timing()
+    assert re.fullmatch(r'diagd\.ir;dur=\d+\.\d{3}, diagd\.econf;dur=\d+\.\d{3}', timing), f"bad Server-Timing {timing}"
+
+
+def test_server_timing_with_tracing():
+    trace = TraceSpans(logger, "http://collector:4318/v1/traces",
+                       "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+    with trace.span("diagd.fetcher"):
+        pass
+
+    assert len(trace.spans) == 1
+    assert trace.spans[0]['traceId'] == '0af7651916cd43dd8448eb211c80319c'
+    assert trace.server_timing().startswith('diagd.fetcher;dur=')
+
+    # Exporting forgets the spans, but not their durations.
+    trace.spans = []
+    assert trace.server_timing().startswith('diagd.fetcher;dur=')
+
+
+def test_server_timing_empty():
+    assert TraceSpans(logger, None, None).server_timing() == ''
@@ -0,0 +1,183 @@
+import logging
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_routes
+
+mappings = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  prefix: /quote/
+  service: quote
+'''
+
+def _tracingservice(spec):
+    return f'''
+---
+apiVersion: getambassador.io/v2
+kind: TracingService
+metadata:
+  name: tracing
+  namespace: default
+spec:
+{spec}
+'''
+
+def _bootstrap_clusters(econf):
+    return { c['name']: c for c in econf.as_dict()['bootstrap']['static_resources']['clusters'] }
+
+def _hcm_tracing(econf):
+    for listener in econf.as_dict()['static_resources']['listeners']:
+        for chain in listener['filter_chains']:
+            for f in chain['filters']:
+                if f['name'] == 'envoy.http_connection_manager':
+                    return f['typed_config'].get('tracing')
+
+def _tracing(econf):
+    return econf.as_dict()['bootstrap'].get('tracing')
+
+
+def test_opentelemetry():
+    ir, econf = get_envoy_config(mappings + _tracingservice('''
+  service: otel-collector:55678
+  driver: opentelemetry
+'''))
+
+    assert get_errors(ir) == []
+
+    # Envoy has no OpenTelemetry tracer, so we talk to the collector's OpenCensus receiver.
+    assert _tracing(econf) == {
+        'http': {
+            'name': 'envoy.tracers.opencensus',
+            'config': {
+                'ocagent_exporter_enabled': True,
+                'incoming_trace_context': [ 'TRACE_CONTEXT', 'B3' ],
+                'outgoing_trace_context': [ 'TRACE_CONTEXT' ],
+                'ocagent_grpc_service': {
+                    'envoy_grpc': { 'cluster_name': 'cluster_tracing_otel_collector_55678_default' }
+                }
+            }
+        }
+    }
+
+    # ...which speaks gRPC.
+    cluster = _bootstrap_clusters(econf)['cluster_tracing_otel_collector_55678_default']
+    assert cluster['http2_protocol_options'] == {}
+
+
+def test_opentelemetry_config():
+    ir, econf = get_envoy_config(mappings + _tracingservice('''
+  service: otel-collector:55678
+  driver: opentelemetry
+  config:
+    service_name: quote
+'''))
+
+    assert 'config is not supported by the opentelemetry driver' in get_errors(ir)
+
+    assert _tracing(econf) is None
+
+
+def test_zipkin():
+    ir, econf = get_envoy_config(mappings + _tracingservice('''
+  service: zipkin:9411
+  driver: zipkin
+'''))
+
+    assert get_errors(ir) == []
+
+    # Every other driver finds its collector with collector_cluster.
+    config = _tracing(econf)['http']['config']
+    assert config['collector_cluster'] == 'cluster_tracing_zipkin_9411_default'
+    assert 'ocagent_grpc_service' not in config
+
+    assert 'http2_protocol_options' not in _bootstrap_clusters(econf)['cluster_tracing_zipkin_9411_default']
+
+
+def test_custom_tags():
+    ir, econf = get_envoy_config(mappings + _tracingservice('''
+  service: zipkin:9411
+  driver: zipkin
+  custom_tags:
+  - tag: cluster
+    literal: east
+  - tag: user
+    header: x-user
+    default: anonymous
+  - tag: subject
+    metadata:
+      filter: envoy.filters.http.jwt_authn
+      path: [ payload, sub ]
+'''))
+
+    assert get_errors(ir) == []
+
+    assert _hcm_tracing(econf)['custom_tags'] == [
+        { 'tag': 'cluster', 'literal': { 'value': 'east' } },
+        { 'tag': 'user', 'request_header': { 'name': 'x-user', 'default_value': 'anonymous' } },
+        {
+            'tag': 'subject',
+            'metadata': {
+                'kind': { 'request': {} },
+                'metadata_key': {
+                    'key': 'envoy.filters.http.jwt_authn',
+                    'path': [ { 'key': 'payload' }, { 'key': 'sub' } ]
+                }
+            }
+        }
+    ]
+
+
+mapping_tracing = '''
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: sampled
+  namespace: default
+spec:
+  prefix: /sampled/
+  service: sampled
+  tracing:
+    sampling:
+      random: 5
+      overall: 50
+    custom_tags:
+    - tag: route
+      literal: sampled
+'''
+
+def test_mapping_tracing():
+    ir, econf = get_envoy_config(mappings + mapping_tracing + _tracingservice('''
+  service: zipkin:9411
+  driver: zipkin
+'''))
+
+    assert get_errors(ir) == []
+
+    routes = get_routes(econf)
+
+    assert routes['/sampled/']['tracing'] == {
+        'random_sampling': { 'numerator': 5, 'denominator': 'HUNDRED' },
+        'overall_sampling': { 'numerator': 50, 'denominator': 'HUNDRED' },
+        'custom_tags': [ { 'tag': 'route', 'literal': { 'value': 'sampled' } } ]
+    }
+
+    assert 'tracing' not in routes['/quote/']
+
+
+def test_mapping_tracing_without_tracingservice():
+    ir, econf = get_envoy_config(mappings + mapping_tracing)
+
+    assert get_errors(ir) == []
+
+    assert 'tracing' not in get_routes(econf)['/sampled/']
@@ -0,0 +1,107 @@
+import logging
+import os
+
+logging.basicConfig(
+    level=logging.INFO,
+    format="%(asctime)s test %(levelname)s: %(message)s",
+    datefmt='%Y-%m-%d %H:%M:%S'
+)
+
+from utils import get_envoy_config, get_errors, get_routes, get_http_filters
+
+SHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+yaml = f'''
+---
+apiVersion: getambassador.io/v2
+kind: WasmFilter
+metadata:
+  name: headers
+  namespace: default
+spec:
+  module:
+    image: ghcr.io/example/headers:v1
+    sha256: {SHA256}
+  vm_config:
+    configuration: "vm"
+  root_id: add_header
+  configuration: '{{"header": "x-wasm"}}'
+  mappings:
+  - quote
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: quote
+  namespace: default
+spec:
+  prefix: /quote/
+  service: quote
+---
+apiVersion: getambassador.io/v2
+kind: Mapping
+metadata:
+  name: other
+  namespace: default
+spec:
+  prefix: /other/
+  service: other
+'''
+
+def test_wasm_filter():
+    os.environ['AMBASSADOR_ENVOY_WASM'] = 'true'
+
+    try:
+        ir, econf = get_envoy_config(yaml)
+    finally:
+        del os.environ['AMBASSADOR_ENVOY_WASM']
+
+    assert get_errors(ir) == []
+
+    filters = get_http_filters(econf)
+    names = [ f['name'] for f in filters ]
+    assert 'envoy.filters.http.wasm' in names
+    assert names.index('envoy.filters.http.wasm') < names.index('envoy.router')
+
+    wasm = filters[names.index('envoy.filters.http.wasm')]['typed_config']
+    assert wasm['@type'] == 'type.googleapis.com/udpa.type.v1.TypedStruct'
+    assert wasm['type_url'] == 'type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm'
+
+    config = wasm['value']['config']
+    assert config['name'] == 'headers.default'
+    assert config['root_id'] == 'add_header'
+    assert config['configuration'] == {
+        '@type': 'type.googleapis.com/google.protobuf.StringValue',
+        'value': '{"header": "x-wasm"}'
+    }
+    assert config['vm_config']['vm_id'] == 'headers.default'
+    assert config['vm_config']['runtime'] == 'envoy.wasm.runtime.v8'
+    assert config['vm_config']['code'] == { 'local': { 'filename': f'/ambassador/wasm/{SHA256}.wasm' } }
+    assert config['vm_config']['configuration']['value'] == 'vm'
+
+    routes = get_routes(econf)
+    assert routes['/quote/']['metadata'] == {
+        'filter_metadata': { 'getambassador.io/wasm': { 'filters': [ 'headers.default' ] } }
+    }
+    assert 'metadata' not in routes['/other/']
+
+
+def test_wasm_filter_errors():
+    # The Envoy we ship doesn't have the Wasm filter.
+    ir, econf = get_envoy_config(yaml)
+    assert 'envoy.filters.http.wasm' not in [ f['name'] for f in get_http_filters(econf) ]
+    assert any('not supported by this version of Envoy' in e for e in get_errors(ir))
+
+    os.environ['AMBASSADOR_ENVOY_WASM'] = 'true'
+
+    try:
+        # The entrypoint hasn't written the module yet.
+        ir, econf = get_envoy_config(yaml, file_checker=lambda path: False)
+        assert 'envoy.filters.http.wasm' not in [ f['name'] for f in get_http_filters(econf) ]
+        assert any(f'module {SHA256} has not been fetched' in e for e in get_errors(ir))
+
+        # The Mapping doesn't exist.
+        ir, econf = get_envoy_config(yaml.replace('- quote', '- missing'))
+        assert any('no Mapping missing in namespace default' in e for e in get_errors(ir))
+    finally:
+        del os.environ['AMBASSADOR_ENVOY_WASM']
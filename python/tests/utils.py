@@ -1,3 +1,4 @@
+import logging
 import os
 import subprocess
 import tempfile
@@ -5,9 +6,14 @@ from collections import namedtuple
 
 import yaml
 
+from ambassador import Config, IR, EnvoyConfig
+from ambassador.fetch import ResourceFetcher
+from ambassador.utils import NullSecretHandler
 from kat.utils import namespace_manifest
 from kat.harness import load_manifest, CLEARTEXT_HOST_YAML
 
+logger = logging.getLogger("ambassador")
+
 qotm_manifests = """
 ---
 apiVersion: v1
@@ -168,3 +174,73 @@ spec:
 """
 
     apply_kube_artifacts(namespace=namespace, artifacts=qotm_mapping)
+
+
+def get_envoy_config(manifests, file_checker=lambda path: True):
+    """
+    Builds the IR and the V2 Envoy config for the Kubernetes resources in manifests, without
+    a cluster, and returns both.
+    """
+
+    aconf = Config()
+
+    fetcher = ResourceFetcher(logger, aconf)
+    fetcher.parse_yaml(manifests, k8s=True)
+
+    aconf.load_all(fetcher.sorted())
+
+    secret_handler = NullSecretHandler(logger, None, None, "0")
+
+    ir = IR(aconf, file_checker=file_checker, secret_handler=secret_handler)
+
+    assert ir, "could not create an IR"
+
+    econf = EnvoyConfig.generate(ir, "V2")
+
+    assert econf, "could not create an econf"
+
+    return ir, econf
+
+
+def get_errors(ir):
+    return [ e['error'] for errors in ir.aconf.errors.values() for e in errors ]
+
+
+def get_clusters(econf):
+    return { c['name']: c for c in econf.as_dict()['static_resources']['clusters'] }
+
+
+def _http_connection_manager(econf):
+    for listener in econf.as_dict()['static_resources']['listeners']:
+        for chain in listener['filter_chains']:
+            for f in chain['filters']:
+                if f['name'] == 'envoy.http_connection_manager':
+                    return f['typed_config']
+
+
+def get_http_filters(econf):
+    return _http_connection_manager(econf)['http_filters']
+
+
+def get_http_access_logs(econf):
+    return _http_connection_manager(econf)['access_log']
+
+
+def get_routes(econf):
+    """
+    Returns the routes of every HTTP listener, by prefix.
+    """
+
+    routes = {}
+
+    for listener in econf.as_dict()['static_resources']['listeners']:
+        for chain in listener['filter_chains']:
+            for f in chain['filters']:
+                if f['name'] != 'envoy.http_connection_manager':
+                    continue
+
+                for vhost in f['typed_config']['route_config']['virtual_hosts']:
+                    for route in vhost['routes']:
+                        routes[route['match'].get('prefix')] = route
+
+    return routes
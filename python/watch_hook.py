@@ -193,16 +193,23 @@ class WatchHook:
                         self.logger.debug(f'Mapping {mname} uses Consul resolver {res_name}')
 
                         # At the moment, we stuff the resolver's datacenter into the association
-                        # ID for this watch. The ResourceFetcher relies on that.
+                        # ID for this watch. The ResourceFetcher relies on that. A resolver with
+                        # several datacenters gets a watch for each of them.
 
-                        self.consul_watches.append(
-                            {
-                                "id": resolver.datacenter,
+                        datacenters = [ dc['name'] for dc in resolver.get('datacenters') or [] ]
+
+                        for datacenter in datacenters or [ resolver.datacenter ]:
+                            watch = {
+                                "id": datacenter,
                                 "consul-address": resolver.address,
-                                "datacenter": resolver.datacenter,
+                                "datacenter": datacenter,
                                 "service-name": svc.hostname
                             }
-                        )
+
+                            if resolver.get('filter'):
+                                watch["filter"] = resolver.filter
+
+                            self.consul_watches.append(watch)
                     elif resolver.kind == 'KubernetesEndpointResolver':
                         host = svc.hostname
                         namespace = Config.ambassador_namespace